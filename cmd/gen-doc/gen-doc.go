@@ -16,12 +16,14 @@ package main
 
 import (
 	_ "embed"
+	"encoding/json"
 	"flag"
 	"html/template"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 
 	"github.com/giantswarm/crd-docs-generator/pkg/crd"
 	"github.com/giantswarm/crd-docs-generator/pkg/metadata"
@@ -38,31 +40,48 @@ func init() {
 	flag.StringVar(&repo, "repo", "", "path to the repository")
 }
 
+// GadgetData is used to render the documentation of the gadgets. It's also
+// written to explainFile, which is embedded in kubectl-gadget for the
+// "explain" command.
 type GadgetData struct {
-	Name        string
-	Description string
-	OutputModes []string
-	Operations  []GadgetOperation
-	Factory     gadgets.TraceFactory
+	Name        string                    `json:"name"`
+	Description string                    `json:"description"`
+	OutputModes []string                  `json:"outputModes"`
+	Operations  []GadgetOperation         `json:"operations"`
+	Parameters  []gadgets.GadgetParameter `json:"parameters,omitempty"`
+	Enforcement string                    `json:"enforcement,omitempty"`
+	Factory     gadgets.TraceFactory      `json:"-"`
 }
 
 type GadgetOperation struct {
-	Name  string
-	Doc   string
-	Order int
+	Name  string `json:"name"`
+	Doc   string `json:"doc"`
+	Order int    `json:"-"`
 }
 
+const explainFile = "cmd/kubectl-gadget/explain/gadgets.json"
+
 //go:embed gadget.template
 var gadgetTemplate string
 
 func getTraceFactories() (ret []GadgetData) {
 	for name, factory := range gadgetcollection.TraceFactories() {
-		ret = append(ret, GadgetData{
+		gadget := GadgetData{
 			Name:        name,
 			Description: factory.(gadgets.TraceFactoryWithDocumentation).Description(),
 			Factory:     factory,
-		})
+		}
+		if f, ok := factory.(gadgets.TraceFactoryWithParameters); ok {
+			gadget.Parameters = f.Parameters()
+		}
+		if f, ok := factory.(gadgets.TraceFactoryWithEnforcement); ok {
+			gadget.Enforcement = f.Enforcement()
+		}
+		ret = append(ret, gadget)
 	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Name < ret[j].Name
+	})
 	return ret
 }
 
@@ -97,13 +116,18 @@ func main() {
 	funcMap["raw"] = func(input string) template.HTML {
 		return template.HTML(input)
 	}
+	funcMap["join"] = strings.Join
 
 	tpl, err := template.New("gadget.template").Funcs(funcMap).Parse(gadgetTemplate)
 	if err != nil {
 		panic(err)
 	}
 
-	for _, gadget := range getTraceFactories() {
+	gadgetsData := getTraceFactories()
+
+	for i := range gadgetsData {
+		gadget := &gadgetsData[i]
+
 		outputModesSet := gadget.Factory.OutputModesSupported()
 		for k := range outputModesSet {
 			gadget.OutputModes = append(gadget.OutputModes, k)
@@ -138,6 +162,19 @@ func main() {
 		}
 	}
 
+	f, err := os.Create(filepath.Join(repo, explainFile))
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+
+	encoder := json.NewEncoder(f)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(gadgetsData); err != nil {
+		panic(err)
+	}
+
 	for _, c := range getCrds() {
 		err = output.WritePage(
 			&c,
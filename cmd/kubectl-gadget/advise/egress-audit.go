@@ -0,0 +1,296 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package advise
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	"github.com/kinvolk/inspektor-gadget/pkg/anonymizer"
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/egressaudit/types"
+	"github.com/kinvolk/inspektor-gadget/pkg/k8sutil"
+)
+
+var egressAuditTraceConfig = &utils.TraceConfig{
+	GadgetName:        "egress-audit",
+	TraceOutputMode:   "Status",
+	TraceOutputState:  "Completed",
+	TraceInitialState: "Started",
+	CommonFlags:       &params,
+}
+
+var egressAuditClusterCIDRs []string
+
+var egressAuditCmd = &cobra.Command{
+	Use:   "egress-audit",
+	Short: "Report the destinations outside of the cluster contacted by the workloads",
+}
+
+var egressAuditStartCmd = &cobra.Command{
+	Use:          "start",
+	Short:        "Start to record the destinations contacted by the pods",
+	RunE:         runEgressAuditStart,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+}
+
+var egressAuditStopCmd = &cobra.Command{
+	Use:          "stop <trace-id|name>",
+	Short:        "Stop recording and print the external destinations by workload",
+	RunE:         runEgressAuditStop,
+	SilenceUsage: true,
+}
+
+var egressAuditListCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "List existing egress-audit traces",
+	RunE:         runEgressAuditList,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+}
+
+func init() {
+	AdviseCmd.AddCommand(egressAuditCmd)
+	utils.RegisterGadgetCommand(egressAuditCmd, "egress-audit", types.Destination{})
+	utils.AddCommonFlags(egressAuditCmd, &params)
+
+	egressAuditCmd.AddCommand(egressAuditStartCmd)
+	utils.AddTraceNameFlag(egressAuditStartCmd, &egressAuditTraceConfig.TraceName)
+	utils.AddTraceGroupFlag(egressAuditStartCmd, &egressAuditTraceConfig.TraceGroup)
+
+	egressAuditCmd.AddCommand(egressAuditStopCmd)
+	egressAuditStopCmd.PersistentFlags().StringSliceVar(&egressAuditClusterCIDRs,
+		"cluster-cidr", []string{},
+		"CIDRs of the cluster to leave out of the report besides the addresses of the current pods, services and nodes, e.g. the pod and service CIDRs")
+
+	egressAuditCmd.AddCommand(egressAuditListCmd)
+}
+
+func runEgressAuditStart(cmd *cobra.Command, args []string) error {
+	egressAuditTraceConfig.Operation = "start"
+
+	traceID, err := utils.CreateTrace(egressAuditTraceConfig)
+	if err != nil {
+		return utils.WrapInErrRunGadget(err)
+	}
+
+	fmt.Printf("%s\n", traceID)
+
+	return nil
+}
+
+// getClusterNetworks returns the addresses of the pods, services and nodes
+// of the cluster, and the CIDRs given with --cluster-cidr.
+func getClusterNetworks() (*types.ClusterNetworks, error) {
+	cluster, err := types.NewClusterNetworks(egressAuditClusterCIDRs)
+	if err != nil {
+		return nil, utils.WrapInErrInvalidArg("--cluster-cidr", err)
+	}
+
+	client, err := k8sutil.NewClientsetFromConfigFlags(utils.KubernetesConfigFlags)
+	if err != nil {
+		return nil, utils.WrapInErrSetupK8sClient(err)
+	}
+
+	pods, err := client.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the pods: %w", err)
+	}
+	for _, pod := range pods.Items {
+		cluster.AddAddress(pod.Status.PodIP)
+		for _, ip := range pod.Status.PodIPs {
+			cluster.AddAddress(ip.IP)
+		}
+	}
+
+	services, err := client.CoreV1().Services("").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the services: %w", err)
+	}
+	for _, svc := range services.Items {
+		cluster.AddAddress(svc.Spec.ClusterIP)
+		for _, ip := range svc.Spec.ClusterIPs {
+			cluster.AddAddress(ip)
+		}
+	}
+
+	nodes, err := client.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, utils.WrapInErrListNodes(err)
+	}
+	for _, node := range nodes.Items {
+		for _, address := range node.Status.Addresses {
+			cluster.AddAddress(address.Address)
+		}
+	}
+
+	return cluster, nil
+}
+
+// printEgressAuditRules prints the destinations of each workload as the
+// rules of an egress firewall.
+func printEgressAuditRules(w *tabwriter.Writer, dsts []types.Destination) {
+	order := []string{}
+	rules := map[string][]string{}
+
+	for i := range dsts {
+		d := &dsts[i]
+		workload := d.Namespace + "/" + d.Workload
+		if _, ok := rules[workload]; !ok {
+			order = append(order, workload)
+		}
+		rules[workload] = append(rules[workload], d.Rules()...)
+	}
+
+	fmt.Fprintln(w, "WORKLOAD\tRULES")
+	for _, workload := range order {
+		fmt.Fprintf(w, "%s\t%s\n", workload, strings.Join(rules[workload], ","))
+	}
+}
+
+// anonymizeDestinations replaces the addresses and the names of the
+// destinations by pseudonyms, as --anonymize does for the events.
+func anonymizeDestinations(dsts []types.Destination) ([]types.Destination, error) {
+	anon, err := anonymizer.NewAnonymizer(params.AnonymizeKey)
+	if err != nil {
+		return nil, err
+	}
+	fields := anonymizer.GadgetFields("egress-audit")
+
+	for i := range dsts {
+		b, err := json.Marshal(dsts[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal destination: %w", err)
+		}
+
+		line := anon.Line(string(b), fields)
+
+		var d types.Destination
+		if err := json.Unmarshal([]byte(line), &d); err != nil {
+			return nil, utils.WrapInErrUnmarshalOutput(err, line)
+		}
+		dsts[i] = d
+	}
+
+	return dsts, nil
+}
+
+func runEgressAuditStop(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return utils.WrapInErrMissingArgs("<trace-id>")
+	}
+
+	// Check the flags before stopping the trace
+	if _, err := types.NewClusterNetworks(egressAuditClusterCIDRs); err != nil {
+		return utils.WrapInErrInvalidArg("--cluster-cidr", err)
+	}
+
+	traceID, err := utils.ResolveTraceID(args[0])
+	if err != nil {
+		return utils.WrapInErrStopGadget(err)
+	}
+
+	err = utils.SetTraceOperation(traceID, "stop")
+	if err != nil {
+		return utils.WrapInErrStopGadget(err)
+	}
+
+	displayResultsCallback := func(results []gadgetv1alpha1.Trace) error {
+		var dsts []types.Destination
+
+		for _, r := range results {
+			if r.Status.Output == "" {
+				continue
+			}
+
+			var nodeDsts []types.Destination
+			if err := json.Unmarshal([]byte(r.Status.Output), &nodeDsts); err != nil {
+				return utils.WrapInErrUnmarshalOutput(err, r.Status.Output)
+			}
+			dsts = append(dsts, nodeDsts...)
+		}
+
+		cluster, err := getClusterNetworks()
+		if err != nil {
+			return err
+		}
+		dsts = types.External(types.MergeDestinations(dsts), cluster)
+
+		if params.Anonymize {
+			dsts, err = anonymizeDestinations(dsts)
+			if err != nil {
+				return err
+			}
+		}
+
+		if params.OutputMode == utils.OutputModeJSON {
+			b, err := json.MarshalIndent(dsts, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to marshal destinations: %w", err)
+			}
+			fmt.Printf("%s\n", b)
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAMESPACE\tWORKLOAD\tPROTO\tPORT\tADDRESS\tNAMES\tSENT\tRECEIVED")
+
+		for _, d := range dsts {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\t%d\t%d\n",
+				d.Namespace, d.Workload, d.Protocol, d.Port, d.Address,
+				strings.Join(d.Names, ","), d.Sent, d.Received)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+
+		if len(dsts) == 0 {
+			return nil
+		}
+
+		fmt.Println()
+		printEgressAuditRules(w, dsts)
+
+		return w.Flush()
+	}
+
+	defer utils.DeleteTrace(traceID)
+
+	err = utils.PrintTraceOutputFromStatus(traceID,
+		egressAuditTraceConfig.TraceOutputState, displayResultsCallback)
+	if err != nil {
+		return utils.WrapInErrGetGadgetOutput(err)
+	}
+
+	return nil
+}
+
+func runEgressAuditList(cmd *cobra.Command, args []string) error {
+	err := utils.PrintAllTraces(egressAuditTraceConfig)
+	if err != nil {
+		return utils.WrapInErrListGadgetTraces(err)
+	}
+
+	return nil
+}
@@ -16,22 +16,27 @@ package advise
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"os/signal"
 	"strings"
-	"sync"
 	"syscall"
 
 	"github.com/spf13/cobra"
 
+	"k8s.io/apimachinery/pkg/api/resource"
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/networkpolicy/advisor"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/networkpolicy/types"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/sink"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/stream"
 	"github.com/kinvolk/inspektor-gadget/pkg/k8sutil"
 )
 
@@ -53,48 +58,70 @@ var networkPolicyReportCmd = &cobra.Command{
 }
 
 var (
-	inputFileName  string
+	inputFileNames []string
 	outputFileName string
 	namespaces     string
+
+	// Options of the output of the monitor
+	maxSize    string
+	maxBackups int
+	compress   bool
+	webhookURL string
+	kafkaURL   string
 )
 
 func init() {
 	AdviseCmd.AddCommand(networkPolicyCmd)
 
 	networkPolicyCmd.AddCommand(networkPolicyMonitorCmd)
-	networkPolicyMonitorCmd.PersistentFlags().StringVarP(&outputFileName, "output", "", "-", "File name output")
+	networkPolicyMonitorCmd.PersistentFlags().StringVarP(&outputFileName, "output", "", "-", "File name output, - for the standard output, empty to only send the network activity to --webhook or --kafka")
 	networkPolicyMonitorCmd.PersistentFlags().StringVarP(&namespaces, "namespaces", "", "", "Comma-separated list of namespaces to monitor")
+	networkPolicyMonitorCmd.PersistentFlags().StringVarP(&maxSize, "max-size", "", "0", "Size (e.g. 100Mi) after which the output file is rotated, 0 to never rotate it")
+	networkPolicyMonitorCmd.PersistentFlags().IntVarP(&maxBackups, "max-backups", "", 5, "Number of rotated output files to keep")
+	networkPolicyMonitorCmd.PersistentFlags().BoolVarP(&compress, "gzip", "", false, "Compress the rotated output files with gzip")
+	networkPolicyMonitorCmd.PersistentFlags().StringVarP(&webhookURL, "webhook", "", "", "URL the recorded network activity is also posted to, as newline-delimited JSON")
+	networkPolicyMonitorCmd.PersistentFlags().StringVarP(&kafkaURL, "kafka", "", "", "URL of a topic in a Kafka REST proxy (e.g. http://kafka-rest:8082/topics/network-activity) the recorded network activity is also produced to")
 
 	networkPolicyCmd.AddCommand(networkPolicyReportCmd)
-	networkPolicyReportCmd.PersistentFlags().StringVarP(&inputFileName, "input", "", "", "File with recorded network activity")
+	networkPolicyReportCmd.PersistentFlags().StringSliceVarP(&inputFileNames, "input", "", []string{}, "Files with recorded network activity, e.g. the current and rotated files of the monitor")
 	networkPolicyReportCmd.PersistentFlags().StringVarP(&outputFileName, "output", "", "-", "File name output")
 }
 
+// traceCollector publishes the events received from a node, once they
+// are complete lines.
 type traceCollector struct {
-	m      *sync.Mutex
-	writer *bufio.Writer
+	stream *stream.GadgetStream
 	node   string
+
+	// partial is the beginning of the line being received.
+	partial []byte
 }
 
-func (t traceCollector) Write(p []byte) (n int, err error) {
-	t.m.Lock()
-	defer t.m.Unlock()
+func (t *traceCollector) Write(p []byte) (n int, err error) {
+	t.partial = append(t.partial, p...)
+
+	for {
+		i := bytes.IndexByte(t.partial, '\n')
+		if i == -1 {
+			break
+		}
+		text := strings.TrimSpace(string(t.partial[:i]))
+		t.partial = t.partial[i+1:]
+
+		if len(text) == 0 {
+			continue
+		}
 
-	event := types.KubernetesConnectionEvent{}
-	text := strings.TrimSpace(string(p))
-	if len(text) != 0 {
+		event := types.KubernetesConnectionEvent{}
 		err := json.Unmarshal([]byte(text), &event)
 		if err == nil && event.Type == "ready" {
-			fmt.Printf("Node %q ready.\n", t.node)
+			fmt.Fprintf(os.Stderr, "Node %q ready.\n", t.node)
 		}
-	}
 
-	n, err = t.writer.Write(p)
-	if err != nil {
-		return
+		t.stream.Publish(text)
 	}
-	err = t.writer.Flush()
-	return
+
+	return len(p), nil
 }
 
 func newWriter(file string) (*bufio.Writer, func(), error) {
@@ -115,12 +142,93 @@ func newWriter(file string) (*bufio.Writer, func(), error) {
 	return w, closure, nil
 }
 
+// newMonitorSinks returns the configured outputs of the monitor, with
+// their configuration.
+func newMonitorSinks() ([]sink.Sink, []sink.Config, error) {
+	size, err := resource.ParseQuantity(maxSize)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid --max-size %q: %w", maxSize, err)
+	}
+	if outputFileName == "-" && (size.Value() != 0 || compress) {
+		return nil, nil, errors.New("--max-size and --gzip can't be used with the standard output")
+	}
+
+	sinks := []sink.Sink{}
+	configs := []sink.Config{}
+	closeSinks := func() {
+		for _, s := range sinks {
+			s.Close()
+		}
+	}
+
+	switch outputFileName {
+	case "":
+		// Only the remote sinks
+	case "-":
+		sinks = append(sinks, sink.NewWriter(os.Stdout))
+		configs = append(configs, sink.Config{Kind: sink.KindFile, Target: "standard output"})
+	default:
+		s, err := sink.NewRotatingFile(outputFileName, size.Value(), maxBackups, compress)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create file %q: %w", outputFileName, err)
+		}
+		sinks = append(sinks, s)
+		configs = append(configs, sink.Config{Kind: sink.KindFile, Target: outputFileName})
+	}
+
+	remotes := []sink.Config{}
+	if webhookURL != "" {
+		remotes = append(remotes, sink.Config{Kind: sink.KindWebhook, Target: webhookURL})
+	}
+	if kafkaURL != "" {
+		remotes = append(remotes, sink.Config{Kind: sink.KindKafka, Target: kafkaURL})
+	}
+	for _, config := range remotes {
+		u, err := url.Parse(config.Target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			closeSinks()
+			return nil, nil, fmt.Errorf("%q is not a valid http(s) URL for --%s", config.DisplayTarget(), config.Kind)
+		}
+		s, err := sink.New(config, sink.Trace{Name: "network-policy-monitor"})
+		if err != nil {
+			closeSinks()
+			return nil, nil, err
+		}
+		sinks = append(sinks, s)
+		configs = append(configs, config)
+	}
+
+	if len(sinks) == 0 {
+		return nil, nil, errors.New("no output: set --output, --webhook or --kafka")
+	}
+
+	return sinks, configs, nil
+}
+
 func runNetworkPolicyMonitor(cmd *cobra.Command, args []string) error {
-	w, closure, err := newWriter(outputFileName)
+	sinks, configs, err := newMonitorSinks()
 	if err != nil {
-		return fmt.Errorf("failed to create file %q: %w", outputFileName, err)
+		return err
 	}
-	defer closure()
+
+	// The events are written to the sinks from their own goroutines,
+	// through a stream, so that a slow webhook doesn't block the
+	// recording.
+	events := stream.NewGadgetStream()
+	runners := []*sink.Runner{}
+	for i, s := range sinks {
+		runners = append(runners, sink.Start(configs[i], s, events))
+	}
+	defer func() {
+		events.Close()
+		for _, r := range runners {
+			r.Drain()
+			if status := r.Status(); status.EventsDropped > 0 {
+				fmt.Fprintf(os.Stderr, "%d events not written to the %s output %s: %s\n",
+					status.EventsDropped, status.Kind, status.Target, status.LastError)
+			}
+		}
+	}()
 
 	client, err := k8sutil.NewClientsetFromConfigFlags(utils.KubernetesConfigFlags)
 	if err != nil {
@@ -141,10 +249,9 @@ func runNetworkPolicyMonitor(cmd *cobra.Command, args []string) error {
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
 	failure := make(chan string)
 
-	var m sync.Mutex
 	for _, node := range nodes.Items {
 		go func(nodeName string) {
-			collector := traceCollector{&m, w, nodeName}
+			collector := &traceCollector{stream: events, node: nodeName}
 			cmd := fmt.Sprintf("exec /opt/bcck8s/bcc-wrapper.sh --tracerid networkpolicyadvisor --nomanager --probecleanup --gadget /bin/networkpolicyadvisor -- %s",
 				namespaceFilter)
 			err := utils.ExecPod(client, nodeName, cmd, collector, os.Stderr)
@@ -173,12 +280,12 @@ func runNetworkPolicyMonitor(cmd *cobra.Command, args []string) error {
 }
 
 func runNetworkPolicyReport(cmd *cobra.Command, args []string) error {
-	if inputFileName == "" {
+	if len(inputFileNames) == 0 {
 		return utils.WrapInErrMissingArgs("--input")
 	}
 
 	adv := advisor.NewAdvisor()
-	err := adv.LoadFile(inputFileName)
+	err := adv.LoadFiles(inputFileNames)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,189 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package advise
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/resourcelimits/types"
+)
+
+var resourceLimitsTraceConfig = &utils.TraceConfig{
+	GadgetName:        "resource-limits",
+	TraceOutputMode:   "Status",
+	TraceOutputState:  "Completed",
+	TraceInitialState: "Started",
+	CommonFlags:       &params,
+}
+
+var resourceLimitsInterval int
+
+var resourceLimitsCmd = &cobra.Command{
+	Use:   "resource-limits",
+	Short: "Recommend resources requests and limits based on the observed CPU and memory usage",
+}
+
+var resourceLimitsStartCmd = &cobra.Command{
+	Use:          "start",
+	Short:        "Start to sample the CPU and memory usage of the containers",
+	RunE:         runResourceLimitsStart,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+}
+
+var resourceLimitsStopCmd = &cobra.Command{
+	Use:          "stop <trace-id|name>",
+	Short:        "Stop sampling and report the recommended requests and limits",
+	RunE:         runResourceLimitsStop,
+	SilenceUsage: true,
+}
+
+var resourceLimitsListCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "List existing resource-limits traces",
+	RunE:         runResourceLimitsList,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+}
+
+func init() {
+	AdviseCmd.AddCommand(resourceLimitsCmd)
+	utils.RegisterGadgetCommand(resourceLimitsCmd, "resource-limits", types.Recommendation{})
+	utils.AddCommonFlags(resourceLimitsCmd, &params)
+
+	resourceLimitsCmd.AddCommand(resourceLimitsStartCmd)
+	resourceLimitsStartCmd.PersistentFlags().IntVar(&resourceLimitsInterval,
+		"interval", types.IntervalDefault,
+		"Sampling interval in seconds")
+	utils.AddTraceNameFlag(resourceLimitsStartCmd, &resourceLimitsTraceConfig.TraceName)
+	utils.AddTraceGroupFlag(resourceLimitsStartCmd, &resourceLimitsTraceConfig.TraceGroup)
+
+	resourceLimitsCmd.AddCommand(resourceLimitsStopCmd)
+	resourceLimitsCmd.AddCommand(resourceLimitsListCmd)
+}
+
+func runResourceLimitsStart(cmd *cobra.Command, args []string) error {
+	if resourceLimitsInterval <= 0 {
+		return utils.WrapInErrInvalidArg("--interval", fmt.Errorf("must be a positive number of seconds"))
+	}
+
+	resourceLimitsTraceConfig.Operation = "start"
+	resourceLimitsTraceConfig.Parameters = map[string]string{
+		types.IntervalParam: strconv.Itoa(resourceLimitsInterval),
+	}
+
+	traceID, err := utils.CreateTrace(resourceLimitsTraceConfig)
+	if err != nil {
+		return utils.WrapInErrRunGadget(err)
+	}
+
+	fmt.Printf("%s\n", traceID)
+
+	return nil
+}
+
+func formatPercentiles(p types.Percentiles, format func(uint64) string) string {
+	return format(p.P50) + "/" + format(p.P95) + "/" + format(p.P99)
+}
+
+func formatMillicores(m uint64) string {
+	return fmt.Sprintf("%dm", m)
+}
+
+func formatMebibytes(b uint64) string {
+	return fmt.Sprintf("%dMi", (b+1024*1024-1)/(1024*1024))
+}
+
+func runResourceLimitsStop(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return utils.WrapInErrMissingArgs("<trace-id>")
+	}
+
+	traceID, err := utils.ResolveTraceID(args[0])
+	if err != nil {
+		return utils.WrapInErrStopGadget(err)
+	}
+
+	err = utils.SetTraceOperation(traceID, "stop")
+	if err != nil {
+		return utils.WrapInErrStopGadget(err)
+	}
+
+	displayResultsCallback := func(results []gadgetv1alpha1.Trace) error {
+		var recommendations []types.Recommendation
+
+		for _, r := range results {
+			if r.Status.Output == "" {
+				continue
+			}
+
+			var nodeRecommendations []types.Recommendation
+			if err := json.Unmarshal([]byte(r.Status.Output), &nodeRecommendations); err != nil {
+				return utils.WrapInErrUnmarshalOutput(err, r.Status.Output)
+			}
+			recommendations = append(recommendations, nodeRecommendations...)
+		}
+
+		if params.OutputMode == utils.OutputModeJSON {
+			b, err := json.MarshalIndent(recommendations, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to marshal recommendations: %w", err)
+			}
+			fmt.Printf("%s\n", b)
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NODE\tNAMESPACE\tPOD\tCONTAINER\tSAMPLES\tCPU(P50/P95/P99)\tMEMORY(P50/P95/P99)\tREQUESTS\tLIMITS")
+
+		for _, r := range recommendations {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%s\tcpu=%s,memory=%s\tcpu=%s,memory=%s\n",
+				r.Node, r.Namespace, r.Pod, r.Container, r.Samples,
+				formatPercentiles(r.CPU, formatMillicores),
+				formatPercentiles(r.Memory, formatMebibytes),
+				r.Requests.CPU, r.Requests.Memory,
+				r.Limits.CPU, r.Limits.Memory)
+		}
+
+		return w.Flush()
+	}
+
+	defer utils.DeleteTrace(traceID)
+
+	err = utils.PrintTraceOutputFromStatus(traceID,
+		resourceLimitsTraceConfig.TraceOutputState, displayResultsCallback)
+	if err != nil {
+		return utils.WrapInErrGetGadgetOutput(err)
+	}
+
+	return nil
+}
+
+func runResourceLimitsList(cmd *cobra.Command, args []string) error {
+	err := utils.PrintAllTraces(resourceLimitsTraceConfig)
+	if err != nil {
+		return utils.WrapInErrListGadgetTraces(err)
+	}
+
+	return nil
+}
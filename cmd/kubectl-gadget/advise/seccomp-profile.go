@@ -44,7 +44,7 @@ var seccompAdvisorStartCmd = &cobra.Command{
 }
 
 var seccompAdvisorStopCmd = &cobra.Command{
-	Use:          "stop <trace-id>",
+	Use:          "stop <trace-id|name>",
 	Short:        "Stop monitoring and report the policies",
 	RunE:         runSeccompAdvisorStop,
 	SilenceUsage: true,
@@ -60,11 +60,14 @@ var seccompAdvisorListCmd = &cobra.Command{
 var (
 	outputMode    string
 	profilePrefix string
+	traceName     string
+	traceGroup    string
 )
 
 func init() {
 	// Add generic information.
 	AdviseCmd.AddCommand(seccompAdvisorCmd)
+	utils.RegisterGadgetCommand(seccompAdvisorCmd, "seccomp", nil)
 	utils.AddCommonFlags(seccompAdvisorCmd, &params)
 
 	seccompAdvisorCmd.AddCommand(seccompAdvisorStartCmd)
@@ -76,6 +79,9 @@ func init() {
 		"profile-prefix", "",
 		"Name prefix of the seccomp profile to be created when using --output-mode=seccomp-profile.\nNamespace can be specified by using namespace/profile-prefix.")
 
+	utils.AddTraceNameFlag(seccompAdvisorStartCmd, &traceName)
+	utils.AddTraceGroupFlag(seccompAdvisorStartCmd, &traceGroup)
+
 	seccompAdvisorCmd.AddCommand(seccompAdvisorStopCmd)
 	seccompAdvisorCmd.AddCommand(seccompAdvisorListCmd)
 }
@@ -113,6 +119,8 @@ func runSeccompAdvisorStart(cmd *cobra.Command, args []string) error {
 		TraceOutputMode:   traceOutputMode,
 		TraceOutput:       profilePrefix,
 		TraceInitialState: "Started",
+		TraceName:         traceName,
+		TraceGroup:        traceGroup,
 		CommonFlags:       &params,
 	}
 
@@ -178,7 +186,10 @@ func runSeccompAdvisorStop(cmd *cobra.Command, args []string) error {
 		return utils.WrapInErrMissingArgs("<trace-id>")
 	}
 
-	traceID := args[0]
+	traceID, err := utils.ResolveTraceID(args[0])
+	if err != nil {
+		return utils.WrapInErrStopGadget(err)
+	}
 
 	callback := func(results []gadgetv1alpha1.Trace) error {
 		for _, i := range results {
@@ -211,7 +222,7 @@ func runSeccompAdvisorStop(cmd *cobra.Command, args []string) error {
 	// leaking a resource.
 	defer utils.DeleteTrace(traceID)
 
-	err := utils.SetTraceOperation(traceID, "generate")
+	err = utils.SetTraceOperation(traceID, "generate")
 	if err != nil {
 		return utils.WrapInErrGenGadgetOutput(err)
 	}
@@ -0,0 +1,228 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package advise
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/sidecarinjection/types"
+)
+
+var sidecarInjectionTraceConfig = &utils.TraceConfig{
+	GadgetName:        "sidecar-injection",
+	TraceOutputMode:   "Status",
+	TraceOutputState:  "Completed",
+	TraceInitialState: "Started",
+	CommonFlags:       &params,
+}
+
+var (
+	sidecarInjectionInterval int
+	sidecarInjectionMeshes   string
+)
+
+var sidecarInjectionCmd = &cobra.Command{
+	Use:   "sidecar-injection",
+	Short: "Report how the traffic of the pods would be affected by injecting a service mesh sidecar",
+}
+
+var sidecarInjectionStartCmd = &cobra.Command{
+	Use:          "start",
+	Short:        "Start to observe the sockets of the pods",
+	RunE:         runSidecarInjectionStart,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+}
+
+var sidecarInjectionStopCmd = &cobra.Command{
+	Use:          "stop <trace-id|name>",
+	Short:        "Stop observing and print the compatibility report",
+	RunE:         runSidecarInjectionStop,
+	SilenceUsage: true,
+}
+
+var sidecarInjectionListCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "List existing sidecar-injection traces",
+	RunE:         runSidecarInjectionList,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+}
+
+func init() {
+	AdviseCmd.AddCommand(sidecarInjectionCmd)
+	utils.RegisterGadgetCommand(sidecarInjectionCmd, "sidecar-injection", types.Finding{})
+	utils.AddCommonFlags(sidecarInjectionCmd, &params)
+
+	sidecarInjectionCmd.AddCommand(sidecarInjectionStartCmd)
+	sidecarInjectionStartCmd.PersistentFlags().IntVar(&sidecarInjectionInterval,
+		"interval", types.IntervalDefault,
+		"Sampling interval in seconds")
+	sidecarInjectionStartCmd.PersistentFlags().StringVar(&sidecarInjectionMeshes,
+		"mesh", "",
+		fmt.Sprintf("Comma-separated list of service meshes to check (%s), all by default", strings.Join(types.Meshes, ", ")))
+	utils.AddTraceNameFlag(sidecarInjectionStartCmd, &sidecarInjectionTraceConfig.TraceName)
+	utils.AddTraceGroupFlag(sidecarInjectionStartCmd, &sidecarInjectionTraceConfig.TraceGroup)
+
+	sidecarInjectionCmd.AddCommand(sidecarInjectionStopCmd)
+	sidecarInjectionCmd.AddCommand(sidecarInjectionListCmd)
+}
+
+func runSidecarInjectionStart(cmd *cobra.Command, args []string) error {
+	if sidecarInjectionInterval <= 0 {
+		return utils.WrapInErrInvalidArg("--interval", fmt.Errorf("must be a positive number of seconds"))
+	}
+
+	if _, err := types.ParseMeshes(sidecarInjectionMeshes); err != nil {
+		return utils.WrapInErrInvalidArg("--mesh", err)
+	}
+
+	sidecarInjectionTraceConfig.Operation = "start"
+	sidecarInjectionTraceConfig.Parameters = map[string]string{
+		types.IntervalParam: strconv.Itoa(sidecarInjectionInterval),
+		types.MeshParam:     sidecarInjectionMeshes,
+	}
+
+	traceID, err := utils.CreateTrace(sidecarInjectionTraceConfig)
+	if err != nil {
+		return utils.WrapInErrRunGadget(err)
+	}
+
+	fmt.Printf("%s\n", traceID)
+
+	return nil
+}
+
+// printSidecarInjectionSummary prints whether each pod keeps working with
+// the sidecar of each mesh, and the number of ports to change otherwise.
+func printSidecarInjectionSummary(w *tabwriter.Writer, findings []types.Finding) {
+	type podMesh struct {
+		pod  string
+		mesh string
+	}
+	order := []podMesh{}
+	blocking := map[podMesh]int{}
+
+	for i := range findings {
+		f := &findings[i]
+		key := podMesh{f.Namespace + "/" + f.Pod, f.Mesh}
+		if _, ok := blocking[key]; !ok {
+			order = append(order, key)
+			blocking[key] = 0
+		}
+		if !f.Compatible() {
+			blocking[key]++
+		}
+	}
+
+	fmt.Fprintln(w, "POD\tMESH\tCOMPATIBLE")
+	for _, key := range order {
+		verdict := "yes"
+		if n := blocking[key]; n > 0 {
+			verdict = fmt.Sprintf("no (%d port(s) to configure)", n)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", key.pod, key.mesh, verdict)
+	}
+}
+
+func runSidecarInjectionStop(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return utils.WrapInErrMissingArgs("<trace-id>")
+	}
+
+	traceID, err := utils.ResolveTraceID(args[0])
+	if err != nil {
+		return utils.WrapInErrStopGadget(err)
+	}
+
+	err = utils.SetTraceOperation(traceID, "stop")
+	if err != nil {
+		return utils.WrapInErrStopGadget(err)
+	}
+
+	displayResultsCallback := func(results []gadgetv1alpha1.Trace) error {
+		var findings []types.Finding
+
+		for _, r := range results {
+			if r.Status.Output == "" {
+				continue
+			}
+
+			var nodeFindings []types.Finding
+			if err := json.Unmarshal([]byte(r.Status.Output), &nodeFindings); err != nil {
+				return utils.WrapInErrUnmarshalOutput(err, r.Status.Output)
+			}
+			findings = append(findings, nodeFindings...)
+		}
+
+		if params.OutputMode == utils.OutputModeJSON {
+			b, err := json.MarshalIndent(findings, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to marshal findings: %w", err)
+			}
+			fmt.Printf("%s\n", b)
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NODE\tNAMESPACE\tPOD\tMESH\tDIRECTION\tPROTO\tPORT\tPEERS\tIMPACT\tREASON")
+
+		for _, f := range findings {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%d\t%d\t%s\t%s\n",
+				f.Node, f.Namespace, f.Pod, f.Mesh, f.Direction, f.Protocol,
+				f.Port, f.Peers, f.Impact, f.Reason)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+
+		if len(findings) == 0 {
+			return nil
+		}
+
+		fmt.Println()
+		printSidecarInjectionSummary(w, findings)
+
+		return w.Flush()
+	}
+
+	defer utils.DeleteTrace(traceID)
+
+	err = utils.PrintTraceOutputFromStatus(traceID,
+		sidecarInjectionTraceConfig.TraceOutputState, displayResultsCallback)
+	if err != nil {
+		return utils.WrapInErrGetGadgetOutput(err)
+	}
+
+	return nil
+}
+
+func runSidecarInjectionList(cmd *cobra.Command, args []string) error {
+	err := utils.PrintAllTraces(sidecarInjectionTraceConfig)
+	if err != nil {
+		return utils.WrapInErrListGadgetTraces(err)
+	}
+
+	return nil
+}
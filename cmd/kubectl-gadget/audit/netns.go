@@ -0,0 +1,162 @@
+// Copyright 2019-2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	types "github.com/kinvolk/inspektor-gadget/pkg/gadgets/audit-netns/types"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+var auditNetnsCmd = &cobra.Command{
+	Use:   "netns",
+	Short: "Audit the network namespaces and the containers sharing namespaces with the host",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// print header
+		switch params.OutputMode {
+		case utils.OutputModeCustomColumns:
+			fmt.Println(getCustomAuditNetnsColsHeader(params.CustomColumns))
+		case utils.OutputModeColumns:
+			fmt.Printf("%-16s %-16s %-16s %-16s %-16s %-10s %-6s %-16s %s\n",
+				"NODE", "NAMESPACE", "POD", "CONTAINER",
+				"KIND", "NETNS", "PID", "PCOMM", "HOSTNS")
+		}
+
+		config := &utils.TraceConfig{
+			GadgetName:       "audit-netns",
+			Operation:        "start",
+			TraceOutputMode:  "Stream",
+			TraceOutputState: "Started",
+			CommonFlags:      &params,
+		}
+
+		err := utils.RunTraceAndPrintStream(config, transformAuditNetnsLine)
+		if err != nil {
+			return utils.WrapInErrRunGadget(err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	AuditCmd.AddCommand(auditNetnsCmd)
+	utils.RegisterGadgetCommand(auditNetnsCmd, "audit-netns", types.Event{})
+	utils.AddCommonFlags(auditNetnsCmd, &params)
+}
+
+func transformAuditNetnsLine(line string) string {
+	var sb strings.Builder
+	var e types.Event
+
+	if err := json.Unmarshal([]byte(line), &e); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s", utils.WrapInErrUnmarshalOutput(err, line))
+		return ""
+	}
+
+	if e.Type == eventtypes.ERR || e.Type == eventtypes.WARN ||
+		e.Type == eventtypes.DEBUG || e.Type == eventtypes.INFO {
+		fmt.Fprintf(os.Stderr, "%s: node %s: %s", e.Type, e.Node, e.Message)
+		return ""
+	}
+
+	if e.Type != eventtypes.NORMAL {
+		return ""
+	}
+
+	hostns := strings.Join(e.HostNamespaces, ",")
+
+	switch params.OutputMode {
+	case utils.OutputModeColumns:
+		sb.WriteString(fmt.Sprintf("%-16s %-16s %-16s %-16s %-16s %-10d %-6d %-16s %s",
+			e.Node, e.Namespace, e.Pod, e.Container,
+			e.Kind, e.NetnsID, e.Pid, e.Comm, hostns))
+
+	case utils.OutputModeCustomColumns:
+		for _, col := range params.CustomColumns {
+			switch col {
+			case "node":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Node))
+			case "namespace":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Namespace))
+			case "pod":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Pod))
+			case "container":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Container))
+			case "kind":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Kind))
+			case "netns":
+				sb.WriteString(fmt.Sprintf("%-10d", e.NetnsID))
+			case "pid":
+				sb.WriteString(fmt.Sprintf("%-6d", e.Pid))
+			case "pcomm":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Comm))
+			case "hostns":
+				sb.WriteString(fmt.Sprintf("%-16s", hostns))
+			case "owner":
+				sb.WriteString(fmt.Sprintf("%-32s", formatAuditNetnsOwner(&e)))
+			}
+			sb.WriteRune(' ')
+		}
+	}
+
+	return sb.String()
+}
+
+func formatAuditNetnsOwner(e *types.Event) string {
+	if e.OwnerKind == "" {
+		return ""
+	}
+	return e.OwnerKind + "/" + e.OwnerName
+}
+
+func getCustomAuditNetnsColsHeader(cols []string) string {
+	var sb strings.Builder
+
+	for _, col := range cols {
+		switch col {
+		case "node":
+			sb.WriteString(fmt.Sprintf("%-16s", "NODE"))
+		case "namespace":
+			sb.WriteString(fmt.Sprintf("%-16s", "NAMESPACE"))
+		case "pod":
+			sb.WriteString(fmt.Sprintf("%-16s", "POD"))
+		case "container":
+			sb.WriteString(fmt.Sprintf("%-16s", "CONTAINER"))
+		case "kind":
+			sb.WriteString(fmt.Sprintf("%-16s", "KIND"))
+		case "netns":
+			sb.WriteString(fmt.Sprintf("%-10s", "NETNS"))
+		case "pid":
+			sb.WriteString(fmt.Sprintf("%-6s", "PID"))
+		case "pcomm":
+			sb.WriteString(fmt.Sprintf("%-16s", "PCOMM"))
+		case "hostns":
+			sb.WriteString(fmt.Sprintf("%-16s", "HOSTNS"))
+		case "owner":
+			sb.WriteString(fmt.Sprintf("%-32s", "OWNER"))
+		}
+		sb.WriteRune(' ')
+	}
+
+	return sb.String()
+}
@@ -60,6 +60,7 @@ var auditSeccompCmd = &cobra.Command{
 
 func init() {
 	AuditCmd.AddCommand(auditSeccompCmd)
+	utils.RegisterGadgetCommand(auditSeccompCmd, "audit-seccomp", types.Event{})
 	utils.AddCommonFlags(auditSeccompCmd, &params)
 }
 
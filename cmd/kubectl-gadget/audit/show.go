@@ -0,0 +1,152 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+)
+
+var (
+	showTraceID string
+	showGadget  string
+	showSince   time.Duration
+	showEvents  bool
+	showOutput  string
+)
+
+var auditShowCmd = &cobra.Command{
+	Use:          "show",
+	Short:        "Show the traces created, their operations and their deletions recorded by kubectl-gadget",
+	Args:         cobra.NoArgs,
+	RunE:         runAuditShow,
+	SilenceUsage: true,
+}
+
+func init() {
+	AuditCmd.AddCommand(auditShowCmd)
+
+	auditShowCmd.Flags().StringVar(
+		&showTraceID, "trace-id", "",
+		"Only show the records of this trace")
+	auditShowCmd.Flags().StringVar(
+		&showGadget, "gadget", "",
+		"Only show the records of this gadget, e.g. execsnoop")
+	auditShowCmd.Flags().DurationVar(
+		&showSince, "since", 0,
+		"Only show the records newer than this duration, e.g. 1h (all of them if 0)")
+	auditShowCmd.Flags().BoolVar(
+		&showEvents, "events", false,
+		"Show the records of the events created with --audit-events in the cluster instead of the local audit log")
+	auditShowCmd.Flags().StringVarP(
+		&showOutput, "output", "o", utils.OutputModeColumns,
+		fmt.Sprintf("Output format (%s, %s)", utils.OutputModeColumns, utils.OutputModeJSON),
+	)
+}
+
+func runAuditShow(cmd *cobra.Command, args []string) error {
+	if showOutput != utils.OutputModeColumns && showOutput != utils.OutputModeJSON {
+		return utils.WrapInErrInvalidArg("--output",
+			fmt.Errorf("%q is not %q or %q", showOutput, utils.OutputModeColumns, utils.OutputModeJSON))
+	}
+
+	var records []utils.AuditRecord
+	var err error
+	if showEvents {
+		records, err = utils.ListAuditEvents()
+	} else {
+		path := utils.AuditLogPath()
+		if path == "" {
+			return errors.New("the audit log is disabled: --audit-log is empty")
+		}
+		records, err = utils.ReadAuditLog(path)
+	}
+	if err != nil {
+		return err
+	}
+
+	records = filterAuditRecords(records, showTraceID, showGadget, showSince, time.Now())
+
+	if showOutput == utils.OutputModeJSON {
+		b, err := json.MarshalIndent(records, "", "  ")
+		if err != nil {
+			return utils.WrapInErrMarshalOutput(err)
+		}
+		fmt.Println(string(b))
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tUSER\tCONTEXT\tACTION\tTRACE ID\tGADGET\tOPERATION\tRESULT")
+	for _, r := range records {
+		result := "ok"
+		if r.Error != "" {
+			result = "error: " + r.Error
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			r.Time.Local().Format(time.RFC3339), dashIfEmpty(r.User), dashIfEmpty(r.Context), r.Action,
+			dashIfEmpty(r.TraceID), dashIfEmpty(r.Gadget), dashIfEmpty(r.Operation), result)
+	}
+	return w.Flush()
+}
+
+func dashIfEmpty(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// filterAuditRecords returns the records matching the filters. The gadget
+// of the records of the operations and deletions is taken from the
+// creation of their trace, as they don't always know it.
+func filterAuditRecords(records []utils.AuditRecord, traceID, gadget string,
+	since time.Duration, now time.Time) []utils.AuditRecord {
+	gadgets := map[string]string{}
+	for _, r := range records {
+		if r.Action == utils.AuditActionCreate && r.TraceID != "" {
+			gadgets[r.TraceID] = r.Gadget
+		}
+	}
+
+	ret := []utils.AuditRecord{}
+	for _, r := range records {
+		if r.Gadget == "" && r.TraceID != "" {
+			r.Gadget = gadgets[r.TraceID]
+		}
+
+		if traceID != "" && r.TraceID != traceID {
+			continue
+		}
+		if gadget != "" && r.Gadget != gadget {
+			continue
+		}
+		if since > 0 && r.Time.Before(now.Add(-since)) {
+			continue
+		}
+
+		ret = append(ret, r)
+	}
+
+	return ret
+}
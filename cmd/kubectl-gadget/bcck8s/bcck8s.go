@@ -21,6 +21,8 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"sort"
+	"strings"
 	"syscall"
 	"time"
 
@@ -38,9 +40,35 @@ func BccCmd(subCommand, bccScript string, params *utils.CommonFlags, gadgetSpeci
 			return utils.WrapInErrSetupK8sClient(err)
 		}
 
+		if len(params.Annotations) > 0 {
+			return utils.WrapInErrInvalidArg("--pod-annotation",
+				fmt.Errorf("not supported by %s", subCommand))
+		}
+
+		if params.PodUID != "" {
+			return utils.WrapInErrInvalidArg("--pod-uid",
+				fmt.Errorf("not supported by %s", subCommand))
+		}
+
+		if params.Sandbox != "" {
+			return utils.WrapInErrInvalidArg("--sandbox",
+				fmt.Errorf("not supported by %s", subCommand))
+		}
+
+		if params.FollowRestarts {
+			return utils.WrapInErrInvalidArg("--follow-restarts",
+				fmt.Errorf("not supported by %s", subCommand))
+		}
+
+		// Labels also contains the selector of --service and --ingress
 		labelFilter := ""
-		if params.LabelsRaw != "" {
-			labelFilter = fmt.Sprintf("--label %s", params.LabelsRaw)
+		if len(params.Labels) > 0 {
+			pairs := []string{}
+			for k, v := range params.Labels {
+				pairs = append(pairs, fmt.Sprintf("%s=%s", k, v))
+			}
+			sort.Strings(pairs)
+			labelFilter = fmt.Sprintf("--label %s", strings.Join(pairs, ","))
 		}
 
 		namespaceFilter := ""
@@ -67,6 +95,12 @@ func BccCmd(subCommand, bccScript string, params *utils.CommonFlags, gadgetSpeci
 			gadgetParams = "--containersmap /sys/fs/bpf/gadget/containers"
 		}
 
+		// symbolize the code generated by the JIT compilers of the
+		// containers
+		if subCommand == "profile" {
+			extraParams = "--perfmaps"
+		}
+
 		if params.OutputMode == utils.OutputModeCustomColumns {
 			table := utils.NewTableFormater(params.CustomColumns, map[string]int{})
 			fmt.Println(table.GetHeader())
@@ -0,0 +1,123 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+)
+
+func init() {
+	configCmd.AddCommand(setColumnsCmd)
+	configCmd.AddCommand(unsetColumnsCmd)
+	configCmd.AddCommand(getColumnsCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage the configuration of kubectl-gadget",
+}
+
+var setColumnsCmd = &cobra.Command{
+	Use:     "set-columns GADGET COLUMNS",
+	Short:   "Set the columns printed by default by a gadget",
+	Example: "  kubectl gadget config set-columns trace-exec pid,comm,args",
+	Args:    cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkGadgetID(args[0]); err != nil {
+			return err
+		}
+
+		cols, err := utils.ParseColumns(args[1])
+		if err != nil {
+			return utils.WrapInErrInvalidArg("COLUMNS", err)
+		}
+
+		return updateCLIConfig(func(config *utils.CLIConfig) {
+			config.SetColumns(args[0], cols)
+		})
+	},
+}
+
+var unsetColumnsCmd = &cobra.Command{
+	Use:   "unset-columns GADGET",
+	Short: "Restore the default columns of a gadget",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return updateCLIConfig(func(config *utils.CLIConfig) {
+			config.SetColumns(args[0], nil)
+		})
+	},
+}
+
+var getColumnsCmd = &cobra.Command{
+	Use:   "get-columns [GADGET]",
+	Short: "Show the columns saved for all the gadgets or for a given one",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, err := utils.LoadCLIConfig()
+		if err != nil {
+			return err
+		}
+
+		if len(args) == 1 {
+			fmt.Println(strings.Join(config.Columns[args[0]], ","))
+			return nil
+		}
+
+		gadgets := []string{}
+		for gadget := range config.Columns {
+			gadgets = append(gadgets, gadget)
+		}
+		sort.Strings(gadgets)
+
+		for _, gadget := range gadgets {
+			fmt.Printf("%s: %s\n", gadget, strings.Join(config.Columns[gadget], ","))
+		}
+		return nil
+	},
+}
+
+// checkGadgetID verifies that id, e.g. "trace-exec", refers to an existing
+// gadget command.
+func checkGadgetID(id string) error {
+	parts := strings.SplitN(id, "-", 2)
+	if len(parts) == 2 {
+		cmd, _, err := rootCmd.Find(parts)
+		if err == nil && utils.GadgetID(cmd) == id {
+			return nil
+		}
+	}
+
+	return utils.WrapInErrInvalidArg("GADGET",
+		fmt.Errorf("%q is not a gadget; use the command path joined with dashes, e.g. trace-exec", id))
+}
+
+func updateCLIConfig(update func(config *utils.CLIConfig)) error {
+	config, err := utils.LoadCLIConfig()
+	if err != nil {
+		return err
+	}
+
+	update(config)
+
+	return config.Save()
+}
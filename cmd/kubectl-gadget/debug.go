@@ -0,0 +1,270 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	"github.com/kinvolk/inspektor-gadget/pkg/k8sutil"
+	"github.com/kinvolk/inspektor-gadget/pkg/mapdump"
+)
+
+var debugCmd = &cobra.Command{
+	Use:   "debug",
+	Short: "Debug the Inspektor Gadget deployment",
+}
+
+var cleanPinsCmd = &cobra.Command{
+	Use:          "clean-pins",
+	Short:        "Remove the pinned BPF maps not used by any trace anymore",
+	RunE:         runCleanPins,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+}
+
+var dumpMapsCmd = &cobra.Command{
+	Use:   "dump-maps <trace-id>",
+	Short: "Dump the BPF maps of a trace to a file, e.g. to attach it to a bug report",
+	Long: `Dump the BPF maps of a trace to a file, e.g. to attach it to a bug report.
+
+The maps are dumped on each node the trace runs on. Only the gadgets keeping
+their state in BPF maps support it, e.g. biotop, filetop or tcptop. The file
+can be rendered again with "kubectl gadget debug render-maps".`,
+	RunE:         runDumpMaps,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+}
+
+var renderMapsCmd = &cobra.Command{
+	Use:          "render-maps <file>",
+	Short:        "Render the BPF maps dumped with dump-maps",
+	RunE:         runRenderMaps,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+}
+
+var setLogLevelCmd = &cobra.Command{
+	Use:   "set-log-level <gadget> <level>",
+	Short: "Change the log level of a gadget on the gadget pods",
+	Long: `Change the log level of a gadget on the gadget pods, without changing the one
+of the other gadgets nor restarting the pods.
+
+The level is a logrus level, like "debug" or "info", or "default" to use the
+level of the gadget pods again. While a gadget logs at the debug or trace
+level, a DEBUG heartbeat event is also published every few seconds in the
+stream of its traces, to check that the events reach kubectl-gadget. They are
+printed on the standard error, or kept with -o json.`,
+	RunE:         runSetLogLevel,
+	Args:         cobra.ExactArgs(2),
+	SilenceUsage: true,
+}
+
+var (
+	cleanPinsNode   string
+	dumpMapsOutput  string
+	setLogLevelNode string
+)
+
+func init() {
+	cleanPinsCmd.Flags().StringVar(&cleanPinsNode, "node", "", "Clean only the given node")
+	dumpMapsCmd.Flags().StringVarP(&dumpMapsOutput, "output", "o", "", "File the maps are written to (maps-<trace-id>.json if empty)")
+
+	debugCmd.AddCommand(cleanPinsCmd)
+	debugCmd.AddCommand(dumpMapsCmd)
+	setLogLevelCmd.Flags().StringVar(&setLogLevelNode, "node", "", "Change the level only on the given node")
+
+	debugCmd.AddCommand(renderMapsCmd)
+	debugCmd.AddCommand(setLogLevelCmd)
+	rootCmd.AddCommand(debugCmd)
+}
+
+func runCleanPins(cmd *cobra.Command, args []string) error {
+	client, err := k8sutil.NewClientsetFromConfigFlags(utils.KubernetesConfigFlags)
+	if err != nil {
+		return utils.WrapInErrSetupK8sClient(err)
+	}
+
+	nodes, err := client.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return utils.WrapInErrListNodes(err)
+	}
+
+	failed := false
+	for _, node := range nodes.Items {
+		if cleanPinsNode != "" && node.Name != cleanPinsNode {
+			continue
+		}
+
+		stdout, stderr, err := utils.ExecPodCapture(client, node.Name,
+			"gadgettracermanager -call clean-pins")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: node %q: %s%s\n", node.Name, err, stderr)
+			failed = true
+			continue
+		}
+
+		removed := strings.Fields(stdout)
+		if len(removed) == 0 {
+			fmt.Printf("%s: no stale pinned maps\n", node.Name)
+			continue
+		}
+		for _, path := range removed {
+			fmt.Printf("%s: removed %s\n", node.Name, path)
+		}
+	}
+
+	if failed {
+		return errors.New("failed to clean the pinned maps on some nodes")
+	}
+
+	return nil
+}
+
+func runDumpMaps(cmd *cobra.Command, args []string) error {
+	traceID := args[0]
+	output := dumpMapsOutput
+	if output == "" {
+		output = fmt.Sprintf("maps-%s.json", traceID)
+	}
+
+	client, err := k8sutil.NewClientsetFromConfigFlags(utils.KubernetesConfigFlags)
+	if err != nil {
+		return utils.WrapInErrSetupK8sClient(err)
+	}
+
+	traces, err := utils.ListTracesByID(traceID)
+	if err != nil {
+		return err
+	}
+	sort.Slice(traces, func(i, j int) bool {
+		return traces[i].Spec.Node < traces[j].Spec.Node
+	})
+
+	snapshots := []mapdump.Snapshot{}
+	failed := false
+	for _, trace := range traces {
+		stdout, stderr, err := utils.ExecPodCapture(client, trace.Spec.Node,
+			fmt.Sprintf("gadgettracermanager -call dump-maps -trace %s/%s", trace.Namespace, trace.Name))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: node %q: %s%s\n", trace.Spec.Node, err, stderr)
+			failed = true
+			continue
+		}
+
+		var snapshot mapdump.Snapshot
+		if err := json.Unmarshal([]byte(stdout), &snapshot); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: node %q: failed to decode the maps: %s\n", trace.Spec.Node, err)
+			failed = true
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	if len(snapshots) > 0 {
+		b, err := json.MarshalIndent(snapshots, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal the maps: %w", err)
+		}
+		if err := os.WriteFile(output, b, 0o644); err != nil {
+			return fmt.Errorf("failed to write the maps: %w", err)
+		}
+		fmt.Printf("Maps of %d node(s) written to %s\n", len(snapshots), output)
+	}
+
+	if failed {
+		return errors.New("failed to dump the maps on some nodes")
+	}
+
+	return nil
+}
+
+func runRenderMaps(cmd *cobra.Command, args []string) error {
+	b, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to read the maps: %w", err)
+	}
+
+	var snapshots []mapdump.Snapshot
+	if err := json.Unmarshal(b, &snapshots); err != nil {
+		return fmt.Errorf("failed to decode the maps: %w", err)
+	}
+
+	for i := range snapshots {
+		if i > 0 {
+			fmt.Println()
+		}
+		mapdump.Fprint(os.Stdout, &snapshots[i])
+	}
+
+	return nil
+}
+
+func runSetLogLevel(cmd *cobra.Command, args []string) error {
+	gadget, level := args[0], args[1]
+
+	known := false
+	for _, c := range utils.GadgetCommands() {
+		if c.Gadget == gadget {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return utils.WrapInErrInvalidArg("<gadget>", fmt.Errorf("unknown gadget %q", gadget))
+	}
+
+	client, err := k8sutil.NewClientsetFromConfigFlags(utils.KubernetesConfigFlags)
+	if err != nil {
+		return utils.WrapInErrSetupK8sClient(err)
+	}
+
+	nodes, err := client.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return utils.WrapInErrListNodes(err)
+	}
+
+	failed := false
+	for _, node := range nodes.Items {
+		if setLogLevelNode != "" && node.Name != setLogLevelNode {
+			continue
+		}
+
+		stdout, stderr, err := utils.ExecPodCapture(client, node.Name,
+			fmt.Sprintf("gadgettracermanager -call set-log-level -gadget %s -level %s", gadget, level))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: node %q: %s%s\n", node.Name, err, stderr)
+			failed = true
+			continue
+		}
+
+		fmt.Printf("%s: log level of %s set to %s (was %s)\n", node.Name, gadget, level, strings.TrimSpace(stdout))
+	}
+
+	if failed {
+		return errors.New("failed to set the log level on some nodes")
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	"github.com/kinvolk/inspektor-gadget/pkg/encryptedfile"
+)
+
+var privateKeyFile string
+
+func init() {
+	decryptCmd.Flags().StringVarP(
+		&privateKeyFile,
+		"private-key", "k",
+		"",
+		"PEM encoded RSA private key matching the public key given with --output-public-key",
+	)
+	rootCmd.AddCommand(decryptCmd)
+}
+
+var decryptCmd = &cobra.Command{
+	Use:   "decrypt FILE",
+	Short: "Print the events of a file encrypted on the nodes with --output-public-key",
+	Example: `  # Copy the file from the node and decrypt it
+  kubectl cp gadget/gadget-xxxxx:/host/var/log/gadget/events.json events.json
+  kubectl gadget decrypt --private-key private.pem events.json`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if privateKeyFile == "" {
+			return utils.WrapInErrMissingArgs("--private-key")
+		}
+
+		keyData, err := ioutil.ReadFile(privateKeyFile)
+		if err != nil {
+			return utils.WrapInErrInvalidArg("--private-key", err)
+		}
+		priv, err := encryptedfile.ParsePrivateKey(keyData)
+		if err != nil {
+			return utils.WrapInErrInvalidArg("--private-key", err)
+		}
+
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		// The events decrypted before an error, e.g. a file truncated
+		// while it was being written, are still printed.
+		if _, err := io.Copy(os.Stdout, encryptedfile.NewReader(f, priv)); err != nil {
+			return fmt.Errorf("decrypting %s: %w", args[0], err)
+		}
+
+		return nil
+	},
+}
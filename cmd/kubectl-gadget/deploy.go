@@ -15,13 +15,19 @@
 package main
 
 import (
+	"encoding/base64"
 	"fmt"
+	"net"
 	"os"
+	"strings"
 	"text/template"
 
 	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
 
 	"github.com/kinvolk/inspektor-gadget/pkg/resources"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
 )
 
 var deployCmd = &cobra.Command{
@@ -39,6 +45,15 @@ var (
 	hookMode            string
 	livenessProbe       bool
 	fallbackPodInformer bool
+	resourcesRequests   string
+	resourcesLimits     string
+	metricsAddress      string
+	kernelLog           string
+	nodeLabels          string
+	aggregator          bool
+	authorizeTraces     bool
+	unprivileged        bool
+	manifestVersion     string
 )
 
 func init() {
@@ -67,276 +82,54 @@ func init() {
 		"fallback-podinformer", "",
 		true,
 		"Use pod informer as a fallback for the main hook")
+	deployCmd.PersistentFlags().StringVarP(
+		&resourcesRequests,
+		"requests", "",
+		"",
+		"resources requests of the gadget pods, e.g. cpu=100m,memory=256Mi (see kubectl gadget status --resources)")
+	deployCmd.PersistentFlags().StringVarP(
+		&resourcesLimits,
+		"limits", "",
+		"",
+		"resources limits of the gadget pods, e.g. cpu=500m,memory=512Mi (see kubectl gadget status --resources)")
+	deployCmd.PersistentFlags().StringVarP(
+		&metricsAddress,
+		"metrics-address", "",
+		"",
+		"address the gadget pods serve their metrics on, e.g. :2224, needed by the prometheus sinks (disabled if empty)")
+	deployCmd.PersistentFlags().StringVarP(
+		&kernelLog,
+		"kernel-log", "",
+		"",
+		"source of the kernel log messages attached to the OOM kills of trace oomkill and the SIGSEGV of trace sigsnoop (journal, kmsg, disabled if empty)")
+	deployCmd.PersistentFlags().StringVarP(
+		&nodeLabels,
+		"node-labels", "",
+		strings.Join(eventtypes.DefaultNodeLabels, ","),
+		"comma-separated labels of the nodes added to the events, e.g. to see the traffic crossing zones (none if empty)")
+	deployCmd.PersistentFlags().BoolVarP(
+		&aggregator,
+		"aggregator", "",
+		false,
+		"deploy the gadget-aggregator service exposing the snapshots and advisors through a read-only HTTP API")
+	deployCmd.PersistentFlags().BoolVarP(
+		&authorizeTraces,
+		"authorize-traces", "",
+		false,
+		"reject the traces whose creator doesn't have the \"gadget.kinvolk.io/trace\" verb on the pods of the namespaces they trace")
+	deployCmd.PersistentFlags().BoolVarP(
+		&unprivileged,
+		"unprivileged", "",
+		false,
+		"deploy the gadget pods without the privileges needed by eBPF, only the gadgets reading /proc and the cgroups are available")
+	deployCmd.PersistentFlags().StringVarP(
+		&manifestVersion,
+		"manifest-version", "",
+		"",
+		"deploy the manifests of another version embedded in kubectl-gadget, \"list\" to list them (the ones of this kubectl-gadget if empty)")
 	rootCmd.AddCommand(deployCmd)
 }
 
-const deployYamlTmpl string = `
-apiVersion: v1
-kind: Namespace
-metadata:
-  name: gadget
----
-apiVersion: v1
-kind: ServiceAccount
-metadata:
-  name: gadget
-  namespace: gadget
----
-apiVersion: rbac.authorization.k8s.io/v1
-kind: Role
-metadata:
-  namespace: gadget
-  name: gadget-role
-rules:
-- apiGroups: [""]
-  resources: ["pods"]
-  # update is needed by traceloop gadget.
-  verbs: ["update"]
----
-apiVersion: rbac.authorization.k8s.io/v1
-kind: RoleBinding
-metadata:
-  name: gadget-role-binding
-  namespace: gadget
-subjects:
-- kind: ServiceAccount
-  name: gadget
-roleRef:
-  kind: Role
-  name: gadget-role
-  apiGroup: rbac.authorization.k8s.io
----
-apiVersion: rbac.authorization.k8s.io/v1
-kind: ClusterRole
-metadata:
-  name: gadget-cluster-role
-rules:
-- apiGroups: [""]
-  resources: ["namespaces", "nodes", "pods"]
-  verbs: ["get", "watch", "list"]
-- apiGroups: [""]
-  resources: ["services"]
-  # list services is needed by network-policy gadget.
-  verbs: ["list"]
-- apiGroups: ["gadget.kinvolk.io"]
-  resources: ["traces", "traces/status"]
-  # For traces, we need all rights on them as we define this resource.
-  verbs: ["delete", "deletecollection", "get", "list", "patch", "create", "update", "watch"]
-- apiGroups: ["*"]
-  resources: ["deployments", "replicasets", "statefulsets", "daemonsets", "jobs", "cronjobs", "replicationcontrollers"]
-  # Required to retrieve the owner references used by the seccomp gadget.
-  verbs: ["get"]
-- apiGroups: ["security-profiles-operator.x-k8s.io"]
-  resources: ["seccompprofiles"]
-  # Required for integration with the Kubernetes Security Profiles Operator
-  verbs: ["list", "watch", "create"]
-- apiGroups: ["security.openshift.io"]
-  # It is necessary to use the 'privileged' security context constraints to be
-  # able mount host directories as volumes, use the host networking, among others.
-  # This will be used only when running on OpenShift:
-  # https://docs.openshift.com/container-platform/4.9/authentication/managing-security-context-constraints.html#default-sccs_configuring-internal-oauth
-  resources: ["securitycontextconstraints"]
-  resourceNames: ["privileged"]
-  verbs: ["use"]
----
-kind: ClusterRoleBinding
-apiVersion: rbac.authorization.k8s.io/v1
-metadata:
-  name: gadget-cluster-role-binding
-subjects:
-- kind: ServiceAccount
-  name: gadget
-  namespace: gadget
-roleRef:
-  kind: ClusterRole
-  name: gadget-cluster-role
-  apiGroup: rbac.authorization.k8s.io
----
-apiVersion: apps/v1
-kind: DaemonSet
-metadata:
-  name: gadget
-  namespace: gadget
-  labels:
-    k8s-app: gadget
-spec:
-  selector:
-    matchLabels:
-      k8s-app: gadget
-  template:
-    metadata:
-      labels:
-        k8s-app: gadget
-      annotations:
-        # We need to set gadget container as unconfined so it is able to write
-        # /sys/fs/bpf as well as /sys/kernel/debug/tracing.
-        # Otherwise, we can have error like:
-        # "failed to create server failed to create folder for pinning bpf maps: mkdir /sys/fs/bpf/gadget: permission denied"
-        # (For reference, see: https://github.com/kinvolk/inspektor-gadget/runs/3966318270?check_suite_focus=true#step:20:221)
-        container.apparmor.security.beta.kubernetes.io/gadget: "unconfined"
-        inspektor-gadget.kinvolk.io/option-hook-mode: "{{.HookMode}}"
-    spec:
-      serviceAccount: gadget
-      hostPID: true
-      hostNetwork: true
-      containers:
-      - name: gadget
-        terminationMessagePolicy: FallbackToLogsOnError
-        image: {{.Image}}
-        imagePullPolicy: {{.ImagePullPolicy}}
-        command: [ "/entrypoint.sh" ]
-        lifecycle:
-          preStop:
-            exec:
-              command:
-                - "/cleanup.sh"
-{{if .LivenessProbe}}
-        livenessProbe:
-          initialDelaySeconds: 60
-          periodSeconds: 5
-          exec:
-            command:
-              - /bin/gadgettracermanager
-              - -liveness
-{{end}}
-        env:
-          - name: NODE_NAME
-            valueFrom:
-              fieldRef:
-                fieldPath: spec.nodeName
-          - name: GADGET_POD_UID
-            valueFrom:
-              fieldRef:
-                fieldPath: metadata.uid
-          - name: TRACELOOP_NODE_NAME
-            valueFrom:
-              fieldRef:
-                fieldPath: spec.nodeName
-          - name: TRACELOOP_POD_NAME
-            valueFrom:
-              fieldRef:
-                fieldPath: metadata.name
-          - name: TRACELOOP_POD_NAMESPACE
-            valueFrom:
-              fieldRef:
-                fieldPath: metadata.namespace
-          - name: GADGET_IMAGE
-            value: {{.Image}}
-          - name: INSPEKTOR_GADGET_VERSION
-            value: {{.Version}}
-          - name: INSPEKTOR_GADGET_OPTION_HOOK_MODE
-            value: "{{.HookMode}}"
-          - name: INSPEKTOR_GADGET_OPTION_FALLBACK_POD_INFORMER
-            value: "{{.FallbackPodInformer}}"
-        securityContext:
-          capabilities:
-            add:
-              # We need CAP_NET_ADMIN to be able to create BPF link.
-              # Indeed, link_create is called with prog->type which equals
-              # BPF_PROG_TYPE_CGROUP_SKB.
-              # This value is then checked in
-              # bpf_prog_attach_check_attach_type() which also checks if we have
-              # CAP_NET_ADMIN:
-              # https://elixir.bootlin.com/linux/v5.14.14/source/kernel/bpf/syscall.c#L4099
-              # https://elixir.bootlin.com/linux/v5.14.14/source/kernel/bpf/syscall.c#L2967
-              - NET_ADMIN
-
-              # We need CAP_SYS_ADMIN to use Python-BCC gadgets because bcc
-              # internally calls bpf_get_map_fd_by_id() which contains the
-              # following snippet:
-              # if (!capable(CAP_SYS_ADMIN))
-              # 	return -EPERM;
-              # (https://elixir.bootlin.com/linux/v5.10.73/source/kernel/bpf/syscall.c#L3254)
-              #
-              # Details about this are given in:
-              # > The important design decision is to allow ID->FD transition for
-              # CAP_SYS_ADMIN only. What it means that user processes can run
-              # with CAP_BPF and CAP_NET_ADMIN and they will not be able to affect each
-              # other unless they pass FDs via scm_rights or via pinning in bpffs.
-              # ID->FD is a mechanism for human override and introspection.
-              # An admin can do 'sudo bpftool prog ...'. It's possible to enforce via LSM that
-              # only bpftool binary does bpf syscall with CAP_SYS_ADMIN and the rest of user
-              # space processes do bpf syscall with CAP_BPF isolating bpf objects (progs, maps,
-              # links) that are owned by such processes from each other.
-              # (https://lwn.net/Articles/820560/)
-              #
-              # Note that even with a kernel providing CAP_BPF, the above
-              # statement is still true.
-              - SYS_ADMIN
-
-              # We need this capability to get addresses from /proc/kallsyms.
-              # Without it, addresses displayed when reading this file will be
-              # 0.
-              # Thus, bcc_procutils_each_ksym will never call callback, so KSyms
-              # syms_ vector will be empty and it will return false.
-              # As a consequence, no prefix will be found in
-              # get_syscall_prefix(), so a default prefix (_sys) will be
-              # returned.
-              # Sadly, this default prefix is not used by the running kernel,
-              # which instead uses: __x64_sys_
-              - SYSLOG
-
-              # traceloop gadget uses strace which in turns use ptrace()
-              # syscall.
-              # Within kernel code, ptrace() calls ptrace_attach() which in
-              # turns calls __ptrace_may_access() which calls ptrace_has_cap()
-              # where CAP_SYS_PTRACE is finally checked:
-              # https://elixir.bootlin.com/linux/v5.14.14/source/kernel/ptrace.c#L284
-              - SYS_PTRACE
-
-              # Needed by setrlimit in gadgettracermanager and by the traceloop
-              # gadget.
-              - SYS_RESOURCE
-
-              # Needed for gadgets that don't dumb the memory rlimit.
-              # (Currently only applies to BCC python-based gadgets)
-              - IPC_LOCK
-
-              # Needed by BCC python-based gadgets to load the kheaders module:
-              # https://github.com/iovisor/bcc/blob/v0.24.0/src/cc/frontends/clang/kbuild_helper.cc#L158
-              - SYS_MODULE
-
-              # Needed by gadgets that open a raw sock like dns and snisnoop
-              - NET_RAW
-        volumeMounts:
-        - name: host
-          mountPath: /host
-        - name: run
-          mountPath: /run
-        - name: modules
-          mountPath: /lib/modules
-        - name: debugfs
-          mountPath: /sys/kernel/debug
-        - name: cgroup
-          mountPath: /sys/fs/cgroup
-        - name: bpffs
-          mountPath: /sys/fs/bpf
-      tolerations:
-      - effect: NoSchedule
-        operator: Exists
-      - effect: NoExecute
-        operator: Exists
-      volumes:
-      - name: host
-        hostPath:
-          path: /
-      - name: run
-        hostPath:
-          path: /run
-      - name: cgroup
-        hostPath:
-          path: /sys/fs/cgroup
-      - name: modules
-        hostPath:
-          path: /lib/modules
-      - name: bpffs
-        hostPath:
-          path: /sys/fs/bpf
-      - name: debugfs
-        hostPath:
-          path: /sys/kernel/debug
-`
-
 type parameters struct {
 	Image               string
 	ImagePullPolicy     string
@@ -344,9 +137,98 @@ type parameters struct {
 	HookMode            string
 	LivenessProbe       bool
 	FallbackPodInformer bool
+	Requests            map[string]string
+	Limits              map[string]string
+	MetricsAddress      string
+	KernelLog           string
+	NodeLabels          string
+	Aggregator          bool
+	AuthorizeTraces     bool
+	Unprivileged        bool
+
+	// CreatorWebhookCA, CreatorWebhookCert and CreatorWebhookKey are the
+	// base64 encoded PEM certificates and key of the creator admission
+	// webhook, only set with --authorize-traces.
+	CreatorWebhookCA   string
+	CreatorWebhookCert string
+	CreatorWebhookKey  string
+}
+
+// parseResources parses resources given as in kubectl set resources, e.g.
+// cpu=100m,memory=256Mi.
+func parseResources(flag, value string) (map[string]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	resources := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid argument %q for --%s: expected <resource>=<quantity>", pair, flag)
+		}
+
+		name := strings.TrimSpace(kv[0])
+		if name != "cpu" && name != "memory" {
+			return nil, fmt.Errorf("invalid argument %q for --%s: resource must be cpu or memory", pair, flag)
+		}
+
+		quantity, err := resource.ParseQuantity(strings.TrimSpace(kv[1]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid argument %q for --%s: %w", pair, flag, err)
+		}
+		resources[name] = quantity.String()
+	}
+
+	return resources, nil
+}
+
+// listManifests prints the versions of the manifests embedded in
+// kubectl-gadget with their digests.
+func listManifests() error {
+	manifests, err := resources.Manifests(version)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%-16s %s\n", "VERSION", "SHA256")
+	for _, m := range manifests {
+		fmt.Printf("%-16s %s\n", m.Version, m.Digest())
+	}
+
+	return nil
+}
+
+// imageWithTag returns image with its tag replaced by tag.
+func imageWithTag(image, tag string) string {
+	// The registry can have a port: the tag follows the last colon only if
+	// it's after the last slash.
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	return image + ":" + tag
 }
 
 func runDeploy(cmd *cobra.Command, args []string) error {
+	if manifestVersion == "list" {
+		return listManifests()
+	}
+
+	manifest := resources.CurrentManifest(version)
+	deployImage := image
+	if manifestVersion != "" && manifestVersion != version {
+		var err error
+		manifest, err = resources.LookupManifest(manifestVersion, version)
+		if err != nil {
+			return fmt.Errorf("invalid argument %q for --manifest-version: %w", manifestVersion, err)
+		}
+
+		// The gadget pods have to run the image of the version pinned.
+		if !cmd.Flags().Changed("image") {
+			deployImage = imageWithTag(image, manifest.Version)
+		}
+	}
+
 	if hookMode != "auto" &&
 		hookMode != "crio" &&
 		hookMode != "podinformer" &&
@@ -355,21 +237,80 @@ func runDeploy(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("invalid argument %q for --hook-mode=[auto,crio,podinformer,nri,fanotify]", hookMode)
 	}
 
-	t, err := template.New("deploy.yaml").Parse(deployYamlTmpl)
+	// The hooks are installed on the hosts and fanotify needs
+	// CAP_SYS_ADMIN, the pod informer is the only way left to get the
+	// containers notifications.
+	if unprivileged && hookMode != "auto" && hookMode != "podinformer" {
+		return fmt.Errorf("--hook-mode=%s can't be used with --unprivileged, only auto and podinformer are supported", hookMode)
+	}
+	if unprivileged && kernelLog != "" {
+		return fmt.Errorf("--kernel-log can't be used with --unprivileged: the gadgets using the kernel log need eBPF")
+	}
+
+	requests, err := parseResources("requests", resourcesRequests)
+	if err != nil {
+		return err
+	}
+	limits, err := parseResources("limits", resourcesLimits)
+	if err != nil {
+		return err
+	}
+
+	if metricsAddress != "" {
+		if _, _, err := net.SplitHostPort(metricsAddress); err != nil {
+			return fmt.Errorf("invalid argument %q for --metrics-address: %w", metricsAddress, err)
+		}
+	}
+
+	if kernelLog != "" && kernelLog != "journal" && kernelLog != "kmsg" {
+		return fmt.Errorf("invalid argument %q for --kernel-log=[journal,kmsg]", kernelLog)
+	}
+
+	for _, label := range strings.Split(nodeLabels, ",") {
+		if label == "" {
+			continue
+		}
+		if errs := validation.IsQualifiedName(label); len(errs) > 0 {
+			return fmt.Errorf("invalid label %q for --node-labels: %s", label, strings.Join(errs, "; "))
+		}
+	}
+
+	t, err := template.New("deploy.yaml").Parse(manifest.DeployTemplate)
 	if err != nil {
 		return fmt.Errorf("failed to parse template: %w", err)
 	}
 
 	p := parameters{
-		image,
+		deployImage,
 		imagePullPolicy,
-		version,
+		manifest.Version,
 		hookMode,
 		livenessProbe,
 		fallbackPodInformer,
+		requests,
+		limits,
+		metricsAddress,
+		kernelLog,
+		nodeLabels,
+		aggregator,
+		authorizeTraces,
+		unprivileged,
+		"",
+		"",
+		"",
+	}
+
+	if authorizeTraces {
+		ca, cert, key, err := newWebhookCertificates("gadget-creator-webhook.gadget.svc")
+		if err != nil {
+			return fmt.Errorf("failed to generate the certificates of the creator webhook: %w", err)
+		}
+		p.CreatorWebhookCA = base64.StdEncoding.EncodeToString(ca)
+		p.CreatorWebhookCert = base64.StdEncoding.EncodeToString(cert)
+		p.CreatorWebhookKey = base64.StdEncoding.EncodeToString(key)
 	}
 
-	fmt.Printf("%s\n---\n", resources.TracesCustomResource)
+	fmt.Printf("%s\n---\n", manifest.TracesCustomResource)
 	err = t.Execute(os.Stdout, p)
 	if err != nil {
 		return fmt.Errorf("failed to generate deploy template: %w", err)
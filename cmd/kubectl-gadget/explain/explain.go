@@ -0,0 +1,388 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package explain
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+)
+
+// gadgets.json is generated by 'make generate-documentation' from the
+// gadget factories, so that the documentation printed here doesn't drift
+// from the gadgets.
+//
+//go:embed gadgets.json
+var gadgetsJSON []byte
+
+type gadgetParameter struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Default     string   `json:"default"`
+	Values      []string `json:"values"`
+	Required    bool     `json:"required"`
+}
+
+type gadgetOperation struct {
+	Name string `json:"name"`
+	Doc  string `json:"doc"`
+}
+
+type gadgetDoc struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	OutputModes []string          `json:"outputModes"`
+	Operations  []gadgetOperation `json:"operations"`
+	Parameters  []gadgetParameter `json:"parameters"`
+	Enforcement string            `json:"enforcement"`
+}
+
+type eventField struct {
+	Name string
+	Type string
+}
+
+const indent = "    "
+
+var ExplainCmd = &cobra.Command{
+	Use:   "explain [GADGET]",
+	Short: "Show the documentation of a gadget",
+	Long: `Show the documentation of a gadget: its description, parameters,
+operations, output fields and examples.
+
+GADGET is either the name of the gadget used in the Trace resources, e.g.
+tcptop, or the command running it joined with dashes, e.g. top-tcp. The
+gadgets are listed when it isn't given.`,
+	Example: `  kubectl gadget explain
+  kubectl gadget explain top-tcp`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		docs, err := loadGadgetDocs()
+		if err != nil {
+			return err
+		}
+
+		if len(args) == 0 {
+			printGadgetList(os.Stdout, docs)
+			return nil
+		}
+
+		doc := findGadgetDoc(docs, args[0])
+		if doc == nil {
+			return utils.WrapInErrInvalidArg("GADGET",
+				fmt.Errorf("%q is not a gadget; run 'kubectl gadget explain' to list them", args[0]))
+		}
+
+		printGadgetDoc(os.Stdout, doc)
+		return nil
+	},
+}
+
+func loadGadgetDocs() ([]gadgetDoc, error) {
+	var docs []gadgetDoc
+	if err := json.Unmarshal(gadgetsJSON, &docs); err != nil {
+		return nil, fmt.Errorf("failed to decode the documentation of the gadgets: %w", err)
+	}
+	return docs, nil
+}
+
+// findGadgetDoc looks up a gadget by its name or by the identifier of a
+// command running it, as returned by utils.GadgetID.
+func findGadgetDoc(docs []gadgetDoc, name string) *gadgetDoc {
+	for _, c := range utils.GadgetCommands() {
+		if utils.GadgetID(c.Command) == name {
+			name = c.Gadget
+			break
+		}
+	}
+
+	for i := range docs {
+		if docs[i].Name == name {
+			return &docs[i]
+		}
+	}
+	return nil
+}
+
+// gadgetCommands returns the commands running the given gadget, sorted by
+// command path.
+func gadgetCommands(gadget string) []utils.GadgetCommand {
+	commands := []utils.GadgetCommand{}
+	for _, c := range utils.GadgetCommands() {
+		if c.Gadget == gadget {
+			commands = append(commands, c)
+		}
+	}
+	sort.Slice(commands, func(i, j int) bool {
+		return commands[i].Command.CommandPath() < commands[j].Command.CommandPath()
+	})
+	return commands
+}
+
+// commandLine returns the command line running command, as typed by the
+// users of the kubectl plugin.
+func commandLine(command *cobra.Command) string {
+	// The first element is the name of the root command
+	names := strings.Fields(command.CommandPath())[1:]
+	return "kubectl gadget " + strings.Join(names, " ")
+}
+
+func printGadgetList(w io.Writer, docs []gadgetDoc) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	defer tw.Flush()
+
+	fmt.Fprintln(tw, "GADGET\tCOMMANDS")
+	for _, doc := range docs {
+		lines := []string{}
+		for _, c := range gadgetCommands(doc.Name) {
+			lines = append(lines, commandLine(c.Command))
+		}
+		if len(lines) == 0 {
+			lines = append(lines, "<none>")
+		}
+		fmt.Fprintf(tw, "%s\t%s\n", doc.Name, strings.Join(lines, ", "))
+	}
+}
+
+func printSection(w io.Writer, title string) {
+	fmt.Fprintf(w, "\n%s\n", title)
+}
+
+// printIndented prints text with each line prefixed by level indentations.
+func printIndented(w io.Writer, level int, text string) {
+	prefix := strings.Repeat(indent, level)
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		if line == "" {
+			fmt.Fprintln(w)
+			continue
+		}
+		fmt.Fprintf(w, "%s%s\n", prefix, line)
+	}
+}
+
+func printGadgetDoc(w io.Writer, doc *gadgetDoc) {
+	commands := gadgetCommands(doc.Name)
+
+	fmt.Fprintln(w, "GADGET")
+	printIndented(w, 1, doc.Name)
+
+	printSection(w, "DESCRIPTION")
+	printIndented(w, 1, doc.Description)
+
+	if len(commands) > 0 {
+		printSection(w, "COMMANDS")
+		for _, c := range commands {
+			printIndented(w, 1, commandLine(c.Command))
+			printIndented(w, 2, c.Command.Short)
+		}
+	}
+
+	if len(doc.Parameters) > 0 {
+		printSection(w, "PARAMETERS")
+		for _, param := range doc.Parameters {
+			name := param.Name
+			if param.Required {
+				name += " (required)"
+			}
+			printIndented(w, 1, name)
+			printIndented(w, 2, param.Description)
+			if len(param.Values) > 0 {
+				printIndented(w, 2, "Values: "+strings.Join(param.Values, ", "))
+			}
+			if param.Default != "" {
+				printIndented(w, 2, "Default: "+param.Default)
+			}
+		}
+	}
+
+	if doc.Enforcement != "" {
+		printSection(w, "ENFORCEMENT")
+		printIndented(w, 1, doc.Enforcement)
+	}
+
+	printSection(w, "OPERATIONS")
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for _, op := range doc.Operations {
+		fmt.Fprintf(tw, "%s%s\t%s\n", indent, op.Name, op.Doc)
+	}
+	tw.Flush()
+
+	printSection(w, "OUTPUT MODES")
+	printIndented(w, 1, strings.Join(doc.OutputModes, ", "))
+
+	for _, c := range commands {
+		if c.Event == nil {
+			continue
+		}
+
+		printSection(w, fmt.Sprintf("OUTPUT FIELDS OF '%s -o json'", commandLine(c.Command)))
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		for _, field := range eventFields(reflect.TypeOf(c.Event)) {
+			fmt.Fprintf(tw, "%s%s\t%s\n", indent, field.Name, field.Type)
+		}
+		tw.Flush()
+	}
+
+	printSection(w, "EXAMPLES")
+	for _, c := range commands {
+		if c.Command.Runnable() {
+			printIndented(w, 1, "# Run the gadget on the pods of the default namespace")
+			printIndented(w, 1, "$ "+commandLine(c.Command)+" -n default")
+			fmt.Fprintln(w)
+		} else {
+			for _, sub := range c.Command.Commands() {
+				printIndented(w, 1, "# "+sub.Short)
+				printIndented(w, 1, "$ "+commandLine(sub))
+				fmt.Fprintln(w)
+			}
+		}
+		if c.Command.Example != "" {
+			printIndented(w, 1, "# More examples of "+commandLine(c.Command))
+			printIndented(w, 1, strings.TrimSpace(c.Command.Example))
+			fmt.Fprintln(w)
+		}
+	}
+	printIndented(w, 1, "# Run the gadget with a Trace resource")
+	printIndented(w, 1, traceExample(doc))
+}
+
+// traceExample returns the commands creating a Trace resource running the
+// gadget on a node and starting it.
+func traceExample(doc *gadgetDoc) string {
+	var sb strings.Builder
+
+	// The output of the gadgets supporting the Status output mode can be
+	// read directly from the Trace resource.
+	outputMode := ""
+	for _, mode := range doc.OutputModes {
+		if outputMode == "" || mode == "Status" {
+			outputMode = mode
+		}
+	}
+
+	fmt.Fprintf(&sb, `$ kubectl apply -f - <<EOF
+apiVersion: gadget.kinvolk.io/v1alpha1
+kind: Trace
+metadata:
+  name: %[1]s
+  namespace: gadget
+spec:
+  node: <node>
+  gadget: %[1]s
+  runMode: Manual
+  outputMode: %[2]s
+  filter:
+    namespace: default
+`, doc.Name, outputMode)
+
+	params := []string{}
+	for _, param := range doc.Parameters {
+		switch {
+		case param.Default != "":
+			params = append(params, fmt.Sprintf("    %s: %q\n", param.Name, param.Default))
+		case param.Required && len(param.Values) > 0:
+			params = append(params, fmt.Sprintf("    %s: %q\n", param.Name, param.Values[0]))
+		case param.Required:
+			params = append(params, fmt.Sprintf("    %s: \"<%s>\"\n", param.Name, param.Name))
+		}
+	}
+	if len(params) > 0 {
+		sb.WriteString("  parameters:\n")
+		sb.WriteString(strings.Join(params, ""))
+	}
+	sb.WriteString("EOF\n")
+
+	operations := doc.Operations
+	if outputMode != "Status" && len(operations) > 1 {
+		operations = operations[:1]
+	}
+	for _, op := range operations {
+		fmt.Fprintf(&sb, "$ kubectl annotate -n gadget trace/%s gadget.kinvolk.io/operation=%s\n", doc.Name, op.Name)
+	}
+	if outputMode == "Status" {
+		fmt.Fprintf(&sb, "$ kubectl get -n gadget trace/%s -o jsonpath='{.status.output}'\n", doc.Name)
+	}
+
+	return sb.String()
+}
+
+// eventFields returns the fields of the JSON encoding of the values of type
+// t, including the ones of the embedded structs.
+func eventFields(t reflect.Type) []eventField {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	fields := []eventField{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" || (f.PkgPath != "" && !f.Anonymous) {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			fields = append(fields, eventFields(f.Type)...)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		fields = append(fields, eventField{Name: name, Type: jsonType(f.Type)})
+	}
+	return fields
+}
+
+// jsonType returns the JSON type of the encoding of the values of type t.
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return jsonType(t.Elem())
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// []byte is encoded as a base64 string
+			return "string"
+		}
+		return "array"
+	case reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	default:
+		return "any"
+	}
+}
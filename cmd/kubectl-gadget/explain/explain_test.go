@@ -0,0 +1,89 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package explain
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+func TestLoadGadgetDocs(t *testing.T) {
+	docs, err := loadGadgetDocs()
+	if err != nil {
+		t.Fatalf("Failed to load the documentation of the gadgets: %s", err)
+	}
+	if len(docs) == 0 {
+		t.Fatalf("No gadget documented")
+	}
+
+	for _, doc := range docs {
+		if doc.Description == "" || len(doc.Operations) == 0 || len(doc.OutputModes) == 0 {
+			t.Fatalf("Incomplete documentation of gadget %q: %+v", doc.Name, doc)
+		}
+	}
+}
+
+func TestFindGadgetDoc(t *testing.T) {
+	docs := []gadgetDoc{{Name: "execsnoop"}, {Name: "opensnoop"}}
+
+	root := &cobra.Command{Use: "kubectl-gadget"}
+	trace := &cobra.Command{Use: "trace"}
+	open := &cobra.Command{Use: "open"}
+	root.AddCommand(trace)
+	trace.AddCommand(open)
+	utils.RegisterGadgetCommand(open, "opensnoop", nil)
+
+	for _, name := range []string{"opensnoop", "trace-open"} {
+		doc := findGadgetDoc(docs, name)
+		if doc == nil || doc.Name != "opensnoop" {
+			t.Fatalf("Expected to find opensnoop with %q, got %v", name, doc)
+		}
+	}
+
+	if doc := findGadgetDoc(docs, "trace-exec"); doc != nil {
+		t.Fatalf("Expected no gadget for an unregistered command, got %v", doc)
+	}
+}
+
+func TestEventFields(t *testing.T) {
+	type event struct {
+		eventtypes.Event
+
+		Pid     uint32   `json:"pid"`
+		Comm    string   `json:"comm,omitempty"`
+		Args    []string `json:"args"`
+		Failed  bool     `json:"failed"`
+		Ignored string   `json:"-"`
+	}
+
+	fields := eventFields(reflect.TypeOf(&event{}))
+
+	names := []string{}
+	for _, field := range fields {
+		names = append(names, field.Name+":"+field.Type)
+	}
+
+	expected := "type:string message:string node:string namespace:string pod:string container:string sandbox:string nodeLabels:object schemaVersion:number " +
+		"pid:number comm:string args:array failed:boolean"
+	if strings.Join(names, " ") != expected {
+		t.Fatalf("Expected fields %q, got %q", expected, strings.Join(names, " "))
+	}
+}
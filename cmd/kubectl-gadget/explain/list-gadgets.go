@@ -0,0 +1,236 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package explain
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+)
+
+// kernelRequirements documents what a gadget needs from the kernel of the
+// nodes. Keep it in sync with docs/requirements.md.
+type kernelRequirements struct {
+	// MinVersion is the minimum kernel version known to work. When the
+	// gadget has both a BCC and a CO:RE implementation, it's the one of
+	// the BCC implementation and MinVersionCORE is the one of the CO:RE
+	// implementation.
+	MinVersion     string `json:"minVersion,omitempty"`
+	MinVersionCORE string `json:"minVersionCORE,omitempty"`
+
+	// Features lists the kernel features that must be enabled.
+	Features []string `json:"features,omitempty"`
+}
+
+// commandRequirements gives the kernel requirements of the commands, by
+// gadget identifier. The commands not listed here have no known
+// requirements.
+var commandRequirements = map[string]kernelRequirements{
+	"advise-sidecar-injection": {MinVersion: "5.10"},
+	"audit-netns":              {MinVersion: "5.4"},
+	"audit-seccomp":            {MinVersion: "5.4"},
+	"profile-block-io":         {MinVersion: "5.4"},
+	"profile-hardirqs":         {MinVersion: "5.4"},
+	"profile-memleak":          {MinVersion: "5.4"},
+	"profile-runqlat":          {MinVersion: "5.4"},
+	"profile-softirqs":         {MinVersion: "5.4"},
+	"snapshot-process":         {MinVersion: "5.10"},
+	"snapshot-socket":          {MinVersion: "5.10"},
+	"top-cache":                {MinVersion: "5.4"},
+	"top-dcache":               {MinVersion: "5.4"},
+	"top-file":                 {MinVersion: "5.4"},
+	"top-fs":                   {MinVersion: "5.4"},
+	"top-seccomp":              {MinVersion: "5.4"},
+	"top-steal":                {MinVersion: "5.4"},
+	"top-syn-backlog":          {MinVersion: "5.4"},
+	"top-tcp":                  {MinVersion: "4.15"},
+	"top-udp":                  {MinVersion: "5.4"},
+	"top-vfs":                  {MinVersion: "5.4"},
+	"trace-bashreadline":       {MinVersion: "5.5", Features: []string{"CONFIG_UPROBE_EVENTS"}},
+	"trace-bind":               {MinVersion: "4.15", MinVersionCORE: "5.4"},
+	"trace-block-io":           {MinVersion: "5.4"},
+	"trace-capabilities":       {MinVersion: "4.15"},
+	"trace-conntrack":          {MinVersion: "5.4"},
+	"trace-dns":                {MinVersion: "5.4"},
+	"trace-exec":               {MinVersion: "4.15", MinVersionCORE: "5.4"},
+	"trace-fsslower":           {MinVersion: "5.4"},
+	"trace-gethostlatency":     {MinVersion: "5.5", Features: []string{"CONFIG_UPROBE_EVENTS"}},
+	"trace-listen":             {MinVersion: "5.4"},
+	"trace-netdrops":           {MinVersion: "5.4"},
+	"trace-oomkill":            {MinVersion: "5.4"},
+	"trace-open":               {MinVersion: "4.15", MinVersionCORE: "5.4"},
+	"trace-ping":               {MinVersion: "5.4"},
+	"trace-runqslower":         {MinVersion: "5.4"},
+	"trace-signal":             {MinVersion: "5.4"},
+	"trace-stat":               {MinVersion: "5.4"},
+	"trace-suspicious-exec":    {MinVersion: "5.4"},
+	"trace-sync":               {MinVersion: "5.4"},
+	"trace-tcp":                {MinVersion: "4.15"},
+	"trace-tcpconnect":         {MinVersion: "4.15", MinVersionCORE: "5.8"},
+	"trace-tcpdrop":            {MinVersion: "5.5"},
+	"trace-tcplife":            {MinVersion: "5.4"},
+	"trace-tcpretrans":         {MinVersion: "5.4"},
+	"trace-udpsnoop":           {MinVersion: "5.4"},
+	"trace-uprobe":             {MinVersion: "5.5", Features: []string{"CONFIG_UPROBE_EVENTS"}},
+	"trace-usdt":               {MinVersion: "5.5", Features: []string{"CONFIG_UPROBE_EVENTS"}},
+	"traceloop":                {MinVersion: "4.15"},
+}
+
+// enforcementRequirements are the kernel requirements of the gadgets
+// supporting the enforcement, when it's enabled.
+var enforcementRequirements = kernelRequirements{
+	MinVersion: "5.7",
+	Features:   []string{"CONFIG_BPF_LSM", "CONFIG_DEBUG_INFO_BTF", "bpf LSM enabled"},
+}
+
+type commandInfo struct {
+	// Command is the command line running the gadget.
+	Command string `json:"command"`
+
+	// Category is the first command of the command line, e.g. trace or
+	// top.
+	Category string `json:"category"`
+
+	Requirements *kernelRequirements `json:"requirements,omitempty"`
+}
+
+type gadgetInfo struct {
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	OutputModes []string      `json:"outputModes"`
+	Commands    []commandInfo `json:"commands"`
+
+	// EnforcementRequirements is only set for the gadgets supporting the
+	// enforcement.
+	EnforcementRequirements *kernelRequirements `json:"enforcementRequirements,omitempty"`
+}
+
+// listGadgetsOutput is the value of the --output flag.
+var listGadgetsOutput string
+
+var ListGadgetsCmd = &cobra.Command{
+	Use:   "list-gadgets",
+	Short: "List the available gadgets",
+	Long: `List the available gadgets with the commands running them, their
+output modes and their kernel requirements.
+
+The JSON output is meant to be consumed by other tools, e.g. to build the
+menus of a user interface.`,
+	Example: `  kubectl gadget list-gadgets
+  kubectl gadget list-gadgets -o json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if listGadgetsOutput != utils.OutputModeColumns && listGadgetsOutput != utils.OutputModeJSON {
+			return utils.WrapInErrInvalidArg("--output / -o",
+				fmt.Errorf("%q is not a valid output format", listGadgetsOutput))
+		}
+
+		docs, err := loadGadgetDocs()
+		if err != nil {
+			return err
+		}
+
+		infos := gadgetInfos(docs)
+
+		if listGadgetsOutput == utils.OutputModeJSON {
+			b, err := json.MarshalIndent(infos, "", "  ")
+			if err != nil {
+				return utils.WrapInErrMarshalOutput(err)
+			}
+			fmt.Println(string(b))
+			return nil
+		}
+
+		printGadgetInfos(os.Stdout, infos)
+		return nil
+	},
+}
+
+func init() {
+	ListGadgetsCmd.Flags().StringVarP(
+		&listGadgetsOutput, "output", "o", utils.OutputModeColumns,
+		fmt.Sprintf("Output format (%s, %s)", utils.OutputModeColumns, utils.OutputModeJSON),
+	)
+}
+
+// gadgetInfos returns the description of the gadgets documented in docs,
+// with the commands running them.
+func gadgetInfos(docs []gadgetDoc) []gadgetInfo {
+	infos := make([]gadgetInfo, 0, len(docs))
+
+	for _, doc := range docs {
+		info := gadgetInfo{
+			Name:        doc.Name,
+			Description: doc.Description,
+			OutputModes: doc.OutputModes,
+			Commands:    []commandInfo{},
+		}
+
+		for _, c := range gadgetCommands(doc.Name) {
+			id := utils.GadgetID(c.Command)
+			command := commandInfo{
+				Command:  commandLine(c.Command),
+				Category: strings.Split(id, "-")[0],
+			}
+			if req, ok := commandRequirements[id]; ok {
+				command.Requirements = &req
+			}
+			info.Commands = append(info.Commands, command)
+		}
+
+		if doc.Enforcement != "" {
+			req := enforcementRequirements
+			info.EnforcementRequirements = &req
+		}
+
+		infos = append(infos, info)
+	}
+
+	return infos
+}
+
+func (r *kernelRequirements) String() string {
+	if r == nil || r.MinVersion == "" {
+		return "-"
+	}
+	if r.MinVersionCORE != "" {
+		return fmt.Sprintf("%s (BCC), %s (CO:RE)", r.MinVersion, r.MinVersionCORE)
+	}
+	return r.MinVersion
+}
+
+func printGadgetInfos(w io.Writer, infos []gadgetInfo) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	defer tw.Flush()
+
+	fmt.Fprintln(tw, "GADGET\tCOMMAND\tOUTPUT MODES\tMIN KERNEL")
+	for _, info := range infos {
+		outputModes := strings.Join(info.OutputModes, ",")
+		if len(info.Commands) == 0 {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", info.Name, "<none>", outputModes, "-")
+			continue
+		}
+		for _, c := range info.Commands {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", info.Name, c.Command, outputModes, c.Requirements)
+		}
+	}
+}
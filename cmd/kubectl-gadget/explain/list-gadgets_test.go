@@ -0,0 +1,90 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package explain
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+)
+
+func TestGadgetInfos(t *testing.T) {
+	docs := []gadgetDoc{
+		{Name: "bindsnoop", Description: "bind", OutputModes: []string{"Stream"}},
+		{Name: "escape-attempts", OutputModes: []string{"Stream"}, Enforcement: "Block them"},
+		{Name: "undocumented-command", OutputModes: []string{"Status"}},
+	}
+
+	root := &cobra.Command{Use: "kubectl-gadget"}
+	trace := &cobra.Command{Use: "trace"}
+	bind := &cobra.Command{Use: "bind"}
+	escape := &cobra.Command{Use: "escape-attempts"}
+	root.AddCommand(trace)
+	trace.AddCommand(bind, escape)
+	utils.RegisterGadgetCommand(bind, "bindsnoop", nil)
+	utils.RegisterGadgetCommand(escape, "escape-attempts", nil)
+
+	expected := []gadgetInfo{
+		{
+			Name:        "bindsnoop",
+			Description: "bind",
+			OutputModes: []string{"Stream"},
+			Commands: []commandInfo{
+				{
+					Command:      "kubectl gadget trace bind",
+					Category:     "trace",
+					Requirements: &kernelRequirements{MinVersion: "4.15", MinVersionCORE: "5.4"},
+				},
+			},
+		},
+		{
+			Name:        "escape-attempts",
+			OutputModes: []string{"Stream"},
+			Commands: []commandInfo{
+				{
+					Command:  "kubectl gadget trace escape-attempts",
+					Category: "trace",
+				},
+			},
+			EnforcementRequirements: &enforcementRequirements,
+		},
+		{
+			Name:        "undocumented-command",
+			OutputModes: []string{"Status"},
+			Commands:    []commandInfo{},
+		},
+	}
+
+	infos := gadgetInfos(docs)
+	if !reflect.DeepEqual(infos, expected) {
+		t.Fatalf("Expected %+v, got %+v", expected, infos)
+	}
+
+	var buf bytes.Buffer
+	printGadgetInfos(&buf, infos)
+
+	expectedText := `GADGET                COMMAND                               OUTPUT MODES  MIN KERNEL
+bindsnoop             kubectl gadget trace bind             Stream        4.15 (BCC), 5.4 (CO:RE)
+escape-attempts       kubectl gadget trace escape-attempts  Stream        -
+undocumented-command  <none>                                Status        -
+`
+	if buf.String() != expectedText {
+		t.Fatalf("Expected:\n%s\ngot:\n%s", expectedText, buf.String())
+	}
+}
@@ -0,0 +1,194 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package explain
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+)
+
+// symptoms.json maps the symptoms commonly investigated with the gadgets
+// to the commands to run, so that new symptoms can be added without
+// touching the code.
+//
+//go:embed symptoms.json
+var symptomsJSON []byte
+
+type suggestion struct {
+	// Command is the command running the gadget, without the
+	// "kubectl gadget" prefix, e.g. "trace dns".
+	Command string `json:"command"`
+
+	// Args are added to the command, before the filters of the user.
+	Args []string `json:"args"`
+
+	// Reason tells what the gadget shows about the symptom.
+	Reason string `json:"reason"`
+}
+
+type symptom struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Suggestions []suggestion `json:"suggestions"`
+}
+
+// suggestFilters are the filters prefilled in the suggested commands.
+type suggestFilters struct {
+	namespace     string
+	podname       string
+	allNamespaces bool
+}
+
+var (
+	// flags
+	suggestSymptom       string
+	suggestPodname       string
+	suggestAllNamespaces bool
+)
+
+var SuggestCmd = &cobra.Command{
+	Use:   "suggest",
+	Short: "Suggest the gadgets to run for a symptom",
+	Long: `Suggest the gadgets to run to investigate a symptom, with the commands
+prefilled with the namespace and the pod given.
+
+The known symptoms are listed when --symptom isn't given.`,
+	Example: `  kubectl gadget suggest
+  kubectl gadget suggest --symptom slow-dns -n default -p mypod`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		symptoms, err := loadSymptoms()
+		if err != nil {
+			return err
+		}
+
+		if suggestSymptom == "" {
+			printSymptomList(os.Stdout, symptoms)
+			return nil
+		}
+
+		s := findSymptom(symptoms, suggestSymptom)
+		if s == nil {
+			return utils.WrapInErrInvalidArg("--symptom",
+				fmt.Errorf("%q is not a known symptom; run 'kubectl gadget suggest' to list them", suggestSymptom))
+		}
+
+		filters := suggestFilters{
+			podname:       suggestPodname,
+			allNamespaces: suggestAllNamespaces,
+		}
+		// Only prefill the namespace given with -n: the commands use
+		// the one of the kubeconfig file by default, like this one.
+		if namespace, overridden := utils.GetNamespace(); overridden {
+			filters.namespace = namespace
+		}
+
+		printSuggestions(os.Stdout, s, &filters)
+		return nil
+	},
+}
+
+func init() {
+	SuggestCmd.Flags().StringVarP(
+		&suggestSymptom, "symptom", "", "",
+		"Symptom to investigate, run 'kubectl gadget suggest' to list them",
+	)
+	SuggestCmd.Flags().StringVarP(
+		&suggestPodname, "podname", "p", "",
+		"Prefill the commands with this pod name",
+	)
+	SuggestCmd.Flags().BoolVarP(
+		&suggestAllNamespaces, "all-namespaces", "A", false,
+		"Prefill the commands to show the pods of all the namespaces",
+	)
+}
+
+func loadSymptoms() ([]symptom, error) {
+	var symptoms []symptom
+	if err := json.Unmarshal(symptomsJSON, &symptoms); err != nil {
+		return nil, fmt.Errorf("failed to decode the symptoms: %w", err)
+	}
+	return symptoms, nil
+}
+
+func findSymptom(symptoms []symptom, name string) *symptom {
+	for i := range symptoms {
+		if symptoms[i].Name == name {
+			return &symptoms[i]
+		}
+	}
+	return nil
+}
+
+// commandID returns the identifier of the command of s, as returned by
+// utils.GadgetID.
+func (s *suggestion) commandID() string {
+	return strings.Join(strings.Fields(s.Command), "-")
+}
+
+// commandLine returns the command line suggested, with the filters.
+func (s *suggestion) commandLine(filters *suggestFilters) string {
+	words := append([]string{"kubectl", "gadget", s.Command}, s.Args...)
+
+	switch {
+	case filters.allNamespaces:
+		words = append(words, "-A")
+	case filters.namespace != "":
+		words = append(words, "-n", filters.namespace)
+	}
+	if filters.podname != "" {
+		words = append(words, "-p", filters.podname)
+	}
+
+	return strings.Join(words, " ")
+}
+
+func printSymptomList(w io.Writer, symptoms []symptom) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	defer tw.Flush()
+
+	fmt.Fprintln(tw, "SYMPTOM\tDESCRIPTION")
+	for _, s := range symptoms {
+		fmt.Fprintf(tw, "%s\t%s\n", s.Name, s.Description)
+	}
+}
+
+func printSuggestions(w io.Writer, s *symptom, filters *suggestFilters) {
+	fmt.Fprintln(w, "SYMPTOM")
+	printIndented(w, 1, s.Name)
+	printIndented(w, 2, s.Description)
+
+	printSection(w, "SUGGESTED COMMANDS")
+	for i, sug := range s.Suggestions {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		printIndented(w, 1, "# "+sug.Reason)
+		if req, ok := commandRequirements[sug.commandID()]; ok && req.MinVersion != "" {
+			printIndented(w, 1, "# Requires Linux "+req.String())
+		}
+		printIndented(w, 1, "$ "+sug.commandLine(filters))
+	}
+}
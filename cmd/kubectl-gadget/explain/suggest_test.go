@@ -0,0 +1,112 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package explain
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLoadSymptoms(t *testing.T) {
+	symptoms, err := loadSymptoms()
+	if err != nil {
+		t.Fatalf("Failed to load the symptoms: %s", err)
+	}
+
+	for _, name := range []string{"crashloop", "high-latency", "slow-dns"} {
+		if findSymptom(symptoms, name) == nil {
+			t.Fatalf("Symptom %q not found", name)
+		}
+	}
+
+	for _, s := range symptoms {
+		if s.Description == "" || len(s.Suggestions) == 0 {
+			t.Fatalf("Incomplete symptom %q: %+v", s.Name, s)
+		}
+		for _, sug := range s.Suggestions {
+			// All the commands running gadgets have their
+			// requirements documented: it catches the typos.
+			if _, ok := commandRequirements[sug.commandID()]; !ok {
+				t.Fatalf("Symptom %q suggests %q, which is not a known command", s.Name, sug.Command)
+			}
+			if sug.Reason == "" {
+				t.Fatalf("Symptom %q suggests %q without a reason", s.Name, sug.Command)
+			}
+		}
+	}
+}
+
+func TestSuggestionCommandLine(t *testing.T) {
+	sug := suggestion{Command: "profile block-io", Args: []string{"start", "--per-container"}}
+
+	tests := []struct {
+		name     string
+		filters  suggestFilters
+		expected string
+	}{
+		{
+			name:     "no filter",
+			expected: "kubectl gadget profile block-io start --per-container",
+		},
+		{
+			name:     "namespace and pod",
+			filters:  suggestFilters{namespace: "demo", podname: "mypod"},
+			expected: "kubectl gadget profile block-io start --per-container -n demo -p mypod",
+		},
+		{
+			name:     "all namespaces",
+			filters:  suggestFilters{namespace: "demo", allNamespaces: true},
+			expected: "kubectl gadget profile block-io start --per-container -A",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if line := sug.commandLine(&test.filters); line != test.expected {
+				t.Fatalf("Expected %q, got %q", test.expected, line)
+			}
+		})
+	}
+}
+
+func TestPrintSuggestions(t *testing.T) {
+	s := &symptom{
+		Name:        "slow-dns",
+		Description: "Slow resolutions.",
+		Suggestions: []suggestion{
+			{Command: "trace dns", Reason: "See the queries"},
+			{Command: "trace unknown", Reason: "Not a gadget"},
+		},
+	}
+
+	var buf bytes.Buffer
+	printSuggestions(&buf, s, &suggestFilters{podname: "mypod"})
+
+	expected := `SYMPTOM
+    slow-dns
+        Slow resolutions.
+
+SUGGESTED COMMANDS
+    # See the queries
+    # Requires Linux 5.4
+    $ kubectl gadget trace dns -p mypod
+
+    # Not a gadget
+    $ kubectl gadget trace unknown -p mypod
+`
+	if buf.String() != expected {
+		t.Fatalf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
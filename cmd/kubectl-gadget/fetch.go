@@ -0,0 +1,72 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+)
+
+var fetchSince string
+
+func init() {
+	fetchCmd.Flags().StringVar(
+		&fetchSince,
+		"since", "",
+		"Only print the events written since this duration (e.g. 10m) or RFC 3339 time (e.g. 2022-05-04T10:00:00Z)",
+	)
+	rootCmd.AddCommand(fetchCmd)
+}
+
+var fetchCmd = &cobra.Command{
+	Use:   "fetch TRACE",
+	Short: "Print the events written on the nodes to the file of --sink-file by a trace",
+	Long: `Print the events written on the nodes to the file of --sink-file by a trace,
+node by node. The trace is given by its ID, its name or the name of its Trace
+resource, e.g. for the traces created with kubectl apply.
+
+With --since, only the events written since then are printed, with a
+precision of 10 seconds: the files are indexed in segments of 10 seconds by
+the gadget pods. The encrypted files can't be read this way, copy them from
+the nodes and use the decrypt command instead.`,
+	Example: `  # Print the events written during the last 10 minutes by a trace created
+  # with kubectl apply
+  kubectl gadget fetch exec-audit --since 10m
+
+  # Print the events written since a given time
+  kubectl gadget fetch exec-audit --since 2022-05-04T10:00:00Z`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var since time.Time
+		if fetchSince != "" {
+			var err error
+			since, err = utils.ParseSince(fetchSince, time.Now())
+			if err != nil {
+				return utils.WrapInErrInvalidArg("--since", err)
+			}
+		}
+
+		traceID, err := utils.ResolveTraceID(args[0])
+		if err != nil {
+			return err
+		}
+
+		return utils.FetchSinkFile(traceID, since, os.Stdout)
+	},
+}
@@ -0,0 +1,123 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+)
+
+var groupCmd = &cobra.Command{
+	Use:   "group",
+	Short: "Manage the traces of a group together",
+	Long: `Manage the traces of a group together.
+
+The traces created with --group, e.g. by "kubectl gadget profile runqlat start
+--group <group>", belong to this group: they can be listed, stopped and
+deleted together.`,
+}
+
+var groupListCmd = &cobra.Command{
+	Use:          "list [group]",
+	Short:        "List the traces of all the groups or of the given one",
+	RunE:         runGroupList,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+}
+
+var groupStopCmd = &cobra.Command{
+	Use:   "stop <group>",
+	Short: "Stop all the traces of a group",
+	Long: `Stop all the traces of a group.
+
+The group is only stopped if all its traces are running, otherwise none of
+them is stopped. The traces are kept with their output until the group is
+deleted.`,
+	RunE:         runGroupStop,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+}
+
+var groupDeleteCmd = &cobra.Command{
+	Use:          "delete <group>",
+	Short:        "Delete all the traces of a group",
+	RunE:         runGroupDelete,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+}
+
+func init() {
+	groupCmd.AddCommand(groupListCmd)
+	groupCmd.AddCommand(groupStopCmd)
+	groupCmd.AddCommand(groupDeleteCmd)
+	rootCmd.AddCommand(groupCmd)
+}
+
+func printGroupMembers(out io.Writer, members []utils.TraceGroupMember) {
+	w := tabwriter.NewWriter(out, 0, 0, 4, ' ', 0)
+
+	fmt.Fprintln(w, "GROUP\tTRACEID\tNAME\tGADGET\tNODE(S)\tSTATE")
+	for _, member := range members {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", member.Group, member.TraceID, member.Name,
+			member.Gadget, strings.Join(member.Nodes, ","), strings.Join(member.States, ","))
+	}
+
+	w.Flush()
+}
+
+func runGroupList(cmd *cobra.Command, args []string) error {
+	group := ""
+	if len(args) == 1 {
+		group = args[0]
+	}
+
+	members, err := utils.ListTraceGroups(group)
+	if err != nil {
+		return utils.WrapInErrListGadgetTraces(err)
+	}
+
+	printGroupMembers(os.Stdout, members)
+
+	return nil
+}
+
+func runGroupStop(cmd *cobra.Command, args []string) error {
+	members, err := utils.StopTraceGroup(args[0])
+	if err != nil {
+		return utils.WrapInErrStopGadget(err)
+	}
+
+	fmt.Printf("Stopping %d trace(s) of group %q\n", len(members), args[0])
+
+	return nil
+}
+
+func runGroupDelete(cmd *cobra.Command, args []string) error {
+	members, err := utils.DeleteTraceGroup(args[0])
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Deleted %d trace(s) of group %q\n", len(members), args[0])
+
+	return nil
+}
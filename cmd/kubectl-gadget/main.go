@@ -21,6 +21,7 @@ import (
 
 	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/advise"
 	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/audit"
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/explain"
 	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/profile"
 	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/snapshot"
 	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/top"
@@ -42,8 +43,11 @@ func init() {
 
 	rootCmd.AddCommand(advise.AdviseCmd)
 	rootCmd.AddCommand(audit.AuditCmd)
+	rootCmd.AddCommand(explain.ExplainCmd)
+	rootCmd.AddCommand(explain.ListGadgetsCmd)
 	rootCmd.AddCommand(profile.ProfilerCmd)
 	rootCmd.AddCommand(snapshot.SnapshotCmd)
+	rootCmd.AddCommand(explain.SuggestCmd)
 	rootCmd.AddCommand(top.TopCmd)
 	rootCmd.AddCommand(trace.TraceCmd)
 }
@@ -17,11 +17,14 @@ package profile
 import (
 	"errors"
 	"fmt"
+	"os"
+	"strconv"
 
 	"github.com/spf13/cobra"
 
 	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
 	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/biolatency/types"
 )
 
 var biolatencyTraceConfig = &utils.TraceConfig{
@@ -32,6 +35,11 @@ var biolatencyTraceConfig = &utils.TraceConfig{
 	CommonFlags:       &params,
 }
 
+var (
+	biolatencyHumanReadable bool
+	biolatencyPerContainer  bool
+)
+
 var biolatencyCmd = &cobra.Command{
 	Use:   "block-io",
 	Short: "Analyze block I/O performance through a latency distribution",
@@ -44,8 +52,9 @@ var biolatencyStartCmd = &cobra.Command{
 	Args:         cobra.NoArgs,
 	SilenceUsage: true,
 	PreRunE: func(cmd *cobra.Command, args []string) error {
-		// Biolatency does not support filtering so we need to avoid adding
-		// the default namespace configured in the kubeconfig file.
+		// Biolatency traces the whole node unless a filter is given, so we
+		// need to avoid adding the default namespace configured in the
+		// kubeconfig file.
 		if params.Namespace != "" && !params.NamespaceOverridden {
 			params.Namespace = ""
 		}
@@ -54,7 +63,7 @@ var biolatencyStartCmd = &cobra.Command{
 }
 
 var biolatencyStopCmd = &cobra.Command{
-	Use:          "stop <trace-id>",
+	Use:          "stop <trace-id|name>",
 	Short:        "Stop monitoring and generate a report (a histogram graph) with the distribution of block device I/O latency",
 	RunE:         runBiolatencyStop,
 	SilenceUsage: true,
@@ -74,9 +83,21 @@ func init() {
 	biolatencyCmd.AddCommand(biolatencyListCmd)
 
 	ProfilerCmd.AddCommand(biolatencyCmd)
+	utils.RegisterGadgetCommand(biolatencyCmd, "biolatency", nil)
 
 	// Common flags are meaningless for list and stop sub-commands
 	utils.AddCommonFlags(biolatencyStartCmd, &params)
+	utils.AddTraceNameFlag(biolatencyStartCmd, &biolatencyTraceConfig.TraceName)
+	utils.AddTraceGroupFlag(biolatencyStartCmd, &biolatencyTraceConfig.TraceGroup)
+	utils.AddHumanReadableFlag(biolatencyStopCmd, &biolatencyHumanReadable)
+
+	biolatencyStartCmd.PersistentFlags().BoolVarP(
+		&biolatencyPerContainer,
+		"per-container",
+		"",
+		false,
+		"Give a histogram for each container instead of a single one",
+	)
 }
 
 func runBiolatencyStart(cmd *cobra.Command, args []string) error {
@@ -85,6 +106,9 @@ func runBiolatencyStart(cmd *cobra.Command, args []string) error {
 	}
 
 	biolatencyTraceConfig.Operation = "start"
+	biolatencyTraceConfig.Parameters = map[string]string{
+		types.PerContainerParam: strconv.FormatBool(biolatencyPerContainer),
+	}
 	traceID, err := utils.CreateTrace(biolatencyTraceConfig)
 	if err != nil {
 		return utils.WrapInErrRunGadget(err)
@@ -99,9 +123,13 @@ func runBiolatencyStop(cmd *cobra.Command, args []string) error {
 	if len(args) != 1 {
 		return utils.WrapInErrMissingArgs("<trace-id>")
 	}
-	traceID := args[0]
 
-	err := utils.SetTraceOperation(traceID, "stop")
+	traceID, err := utils.ResolveTraceID(args[0])
+	if err != nil {
+		return utils.WrapInErrStopGadget(err)
+	}
+
+	err = utils.SetTraceOperation(traceID, "stop")
 	if err != nil {
 		return utils.WrapInErrStopGadget(err)
 	}
@@ -111,7 +139,16 @@ func runBiolatencyStop(cmd *cobra.Command, args []string) error {
 			return errors.New("there should be only one result because biolatency runs on one node at a time")
 		}
 
-		fmt.Printf("%v", results[0].Status.Output)
+		output := results[0].Status.Output
+		if output == "" {
+			fmt.Fprintln(os.Stderr, "No block I/O request was completed")
+			return nil
+		}
+		if biolatencyHumanReadable {
+			output = utils.HumanizeHistogram(output)
+		}
+
+		fmt.Printf("%v", output)
 		return nil
 	}
 
@@ -0,0 +1,171 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/irqs/types"
+)
+
+// The hardirqs and softirqs commands only differ by the gadget they run.
+func init() {
+	addIrqsCmd(types.KindHard, "hard interrupts, by device")
+	addIrqsCmd(types.KindSoft, "soft interrupts, by vector")
+}
+
+func addIrqsCmd(kind, what string) {
+	config := &utils.TraceConfig{
+		GadgetName:        kind,
+		TraceOutputMode:   "Status",
+		TraceOutputState:  "Completed",
+		TraceInitialState: "Started",
+		CommonFlags:       &params,
+	}
+
+	cmd := &cobra.Command{
+		Use:   kind,
+		Short: fmt.Sprintf("Analyze the CPU time spent in the %s", what),
+	}
+
+	startCmd := &cobra.Command{
+		Use:          "start",
+		Short:        fmt.Sprintf("Start recording the time spent in the %s", what),
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			// The interrupts can't be attributed to containers, so we
+			// need to avoid adding the default namespace configured in
+			// the kubeconfig file.
+			if params.Namespace != "" && !params.NamespaceOverridden {
+				params.Namespace = ""
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if params.Node == "" {
+				return utils.WrapInErrMissingArgs("--node")
+			}
+
+			config.Operation = "start"
+			traceID, err := utils.CreateTrace(config)
+			if err != nil {
+				return utils.WrapInErrRunGadget(err)
+			}
+
+			fmt.Printf("%s\n", traceID)
+
+			return nil
+		},
+	}
+
+	stopCmd := &cobra.Command{
+		Use:          "stop <trace-id|name>",
+		Short:        fmt.Sprintf("Stop recording and report the time spent in the %s", what),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return utils.WrapInErrMissingArgs("<trace-id>")
+			}
+
+			traceID, err := utils.ResolveTraceID(args[0])
+			if err != nil {
+				return utils.WrapInErrStopGadget(err)
+			}
+
+			err = utils.SetTraceOperation(traceID, "stop")
+			if err != nil {
+				return utils.WrapInErrStopGadget(err)
+			}
+
+			defer utils.DeleteTrace(traceID)
+
+			err = utils.PrintTraceOutputFromStatus(traceID,
+				config.TraceOutputState, displayIrqsResults)
+			if err != nil {
+				return utils.WrapInErrGetGadgetOutput(err)
+			}
+
+			return nil
+		},
+	}
+
+	listCmd := &cobra.Command{
+		Use:          "list",
+		Short:        fmt.Sprintf("List the currently running %s traces", kind),
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := utils.PrintAllTraces(config)
+			if err != nil {
+				return utils.WrapInErrListGadgetTraces(err)
+			}
+			return nil
+		},
+	}
+
+	cmd.AddCommand(startCmd)
+	cmd.AddCommand(stopCmd)
+	cmd.AddCommand(listCmd)
+
+	ProfilerCmd.AddCommand(cmd)
+	utils.RegisterGadgetCommand(cmd, kind, nil)
+
+	// Common flags are meaningless for list and stop sub-commands
+	utils.AddCommonFlags(startCmd, &params)
+	utils.AddTraceNameFlag(startCmd, &config.TraceName)
+	utils.AddTraceGroupFlag(startCmd, &config.TraceGroup)
+}
+
+func displayIrqsResults(results []gadgetv1alpha1.Trace) error {
+	if len(results) != 1 {
+		return errors.New("there should be only one result because the interrupts are traced on one node at a time")
+	}
+
+	var report types.Report
+	if err := json.Unmarshal([]byte(results[0].Status.Output), &report); err != nil {
+		return utils.WrapInErrUnmarshalOutput(err, results[0].Status.Output)
+	}
+
+	if len(report.Entries) == 0 {
+		fmt.Fprintln(os.Stderr, "No interrupt was handled")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 4, ' ', 0)
+	fmt.Fprintf(w, "%s\tCOUNT\tTOTAL\tMAX\tCPU%%\t\n", strings.ToUpper(strings.TrimSuffix(report.Kind, "s")))
+	for _, e := range report.Entries {
+		fmt.Fprintf(w, "%s\t%d\t%v\t%v\t%.2f\t\n", e.Name, e.Count,
+			time.Duration(e.TotalNs), time.Duration(e.MaxNs), report.CPUPercent(e.TotalNs))
+	}
+	w.Flush()
+
+	total := report.TotalNs()
+	fmt.Printf("\nNode %s spent %v, %.2f%% of the time of its %d CPUs, in %s during %v\n",
+		report.Node, time.Duration(total), report.CPUPercent(total), report.CPUs,
+		report.Kind, report.Duration.Round(time.Second))
+
+	return nil
+}
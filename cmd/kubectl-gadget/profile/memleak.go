@@ -0,0 +1,253 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/memleak/types"
+)
+
+var memleakTraceConfig = &utils.TraceConfig{
+	GadgetName:        "memleak",
+	TraceOutputMode:   "Status",
+	TraceOutputState:  "Completed",
+	TraceInitialState: "Started",
+	CommonFlags:       &params,
+}
+
+var (
+	memleakMode          string
+	memleakLibrary       string
+	memleakMinAge        uint
+	memleakTop           uint
+	memleakHumanReadable bool
+)
+
+var memleakCmd = &cobra.Command{
+	Use:   "memleak",
+	Short: "Find memory leaks through the stacks of the allocations not freed yet",
+}
+
+var memleakStartCmd = &cobra.Command{
+	Use:          "start",
+	Short:        "Start tracking the memory allocations of the selected containers",
+	RunE:         runMemleakStart,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+}
+
+var memleakReportCmd = &cobra.Command{
+	Use:          "report <trace-id|name>",
+	Short:        "Report the stacks with the most bytes not freed yet and keep tracking the allocations",
+	RunE:         runMemleakReport,
+	SilenceUsage: true,
+}
+
+var memleakStopCmd = &cobra.Command{
+	Use:          "stop <trace-id|name>",
+	Short:        "Stop tracking the allocations and report the stacks with the most bytes not freed yet",
+	RunE:         runMemleakStop,
+	SilenceUsage: true,
+}
+
+var memleakListCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "List the currently running memleak traces",
+	RunE:         runMemleakList,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+}
+
+func init() {
+	memleakCmd.AddCommand(memleakStartCmd)
+	memleakCmd.AddCommand(memleakReportCmd)
+	memleakCmd.AddCommand(memleakStopCmd)
+	memleakCmd.AddCommand(memleakListCmd)
+
+	ProfilerCmd.AddCommand(memleakCmd)
+	utils.RegisterGadgetCommand(memleakCmd, "memleak", nil)
+
+	// Common flags are meaningless for list, report and stop sub-commands
+	utils.AddCommonFlags(memleakStartCmd, &params)
+	utils.AddTraceNameFlag(memleakStartCmd, &memleakTraceConfig.TraceName)
+	utils.AddTraceGroupFlag(memleakStartCmd, &memleakTraceConfig.TraceGroup)
+
+	memleakStartCmd.PersistentFlags().StringVar(
+		&memleakMode,
+		"mode",
+		types.ModeUser,
+		fmt.Sprintf("Allocations to track: %q for the ones of the C library, %q for the ones of the kernel or %q for both",
+			types.ModeUser, types.ModeKernel, types.ModeAll),
+	)
+	memleakStartCmd.PersistentFlags().StringVar(
+		&memleakLibrary,
+		"library",
+		types.LibraryDefault,
+		"Path, in the containers, of the C library whose allocation functions are traced",
+	)
+	memleakStartCmd.PersistentFlags().UintVar(
+		&memleakMinAge,
+		"min-age",
+		0,
+		"Only report the allocations older than this number of seconds",
+	)
+	memleakStartCmd.PersistentFlags().UintVar(
+		&memleakTop,
+		"top",
+		types.TopDefault,
+		"Number of stacks to report, 0 for all of them",
+	)
+
+	utils.AddHumanReadableFlag(memleakReportCmd, &memleakHumanReadable)
+	utils.AddHumanReadableFlag(memleakStopCmd, &memleakHumanReadable)
+}
+
+func runMemleakStart(cmd *cobra.Command, args []string) error {
+	if params.Node == "" {
+		return utils.WrapInErrMissingArgs("--node")
+	}
+
+	switch memleakMode {
+	case types.ModeUser, types.ModeKernel, types.ModeAll:
+	default:
+		return utils.WrapInErrInvalidArg("--mode",
+			fmt.Errorf("%q is not one of %q, %q or %q", memleakMode, types.ModeUser, types.ModeKernel, types.ModeAll))
+	}
+
+	memleakTraceConfig.Operation = "start"
+	memleakTraceConfig.Parameters = map[string]string{
+		"mode":    memleakMode,
+		"library": memleakLibrary,
+		"minage":  strconv.FormatUint(uint64(memleakMinAge), 10),
+		"top":     strconv.FormatUint(uint64(memleakTop), 10),
+	}
+
+	traceID, err := utils.CreateTrace(memleakTraceConfig)
+	if err != nil {
+		return utils.WrapInErrRunGadget(err)
+	}
+
+	fmt.Printf("%s\n", traceID)
+
+	return nil
+}
+
+func displayMemleakResults(results []gadgetv1alpha1.Trace) error {
+	if len(results) != 1 {
+		return errors.New("there should be only one result because memleak runs on one node at a time")
+	}
+
+	var report types.Report
+	if err := json.Unmarshal([]byte(results[0].Status.Output), &report); err != nil {
+		return utils.WrapInErrUnmarshalOutput(err, results[0].Status.Output)
+	}
+
+	fmt.Printf("Report %d of node %s: %s in %d allocations not freed yet\n",
+		report.Round, report.Node, memleakFormatBytes(report.TotalSize), report.TotalAllocations)
+
+	for _, s := range report.Stacks {
+		where := "kernel"
+		if !s.Kernel {
+			where = "user space"
+		}
+		fmt.Printf("\n%s in %d allocations from the %s stack of %s (pid %d)",
+			memleakFormatBytes(s.Size), s.Allocations, where, s.Comm, s.Pid)
+		if s.Pod != "" {
+			fmt.Printf(" in %s/%s/%s", s.Namespace, s.Pod, s.Container)
+		}
+		fmt.Println(":")
+
+		for _, frame := range s.Frames {
+			fmt.Printf("\t%s\n", frame)
+		}
+	}
+
+	return nil
+}
+
+func memleakFormatBytes(n uint64) string {
+	if memleakHumanReadable {
+		return utils.FormatBytes(n)
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
+
+func runMemleakReport(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return utils.WrapInErrMissingArgs("<trace-id>")
+	}
+
+	traceID, err := utils.ResolveTraceID(args[0])
+	if err != nil {
+		return utils.WrapInErrGenGadgetOutput(err)
+	}
+
+	err = utils.SetTraceOperation(traceID, "report")
+	if err != nil {
+		return utils.WrapInErrGenGadgetOutput(err)
+	}
+
+	// The trace stays in the Started state: the report is available once
+	// the operation is applied.
+	err = utils.PrintTraceOutputAfterOperations(traceID,
+		memleakTraceConfig.TraceInitialState, displayMemleakResults)
+	if err != nil {
+		return utils.WrapInErrGetGadgetOutput(err)
+	}
+
+	return nil
+}
+
+func runMemleakStop(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return utils.WrapInErrMissingArgs("<trace-id>")
+	}
+
+	traceID, err := utils.ResolveTraceID(args[0])
+	if err != nil {
+		return utils.WrapInErrStopGadget(err)
+	}
+
+	err = utils.SetTraceOperation(traceID, "stop")
+	if err != nil {
+		return utils.WrapInErrStopGadget(err)
+	}
+
+	defer utils.DeleteTrace(traceID)
+
+	err = utils.PrintTraceOutputFromStatus(traceID,
+		memleakTraceConfig.TraceOutputState, displayMemleakResults)
+	if err != nil {
+		return utils.WrapInErrGetGadgetOutput(err)
+	}
+
+	return nil
+}
+
+func runMemleakList(cmd *cobra.Command, args []string) error {
+	err := utils.PrintAllTraces(memleakTraceConfig)
+	if err != nil {
+		return utils.WrapInErrListGadgetTraces(err)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+)
+
+var runqlatTraceConfig = &utils.TraceConfig{
+	GadgetName:        "runqlat",
+	TraceOutputMode:   "Status",
+	TraceOutputState:  "Completed",
+	TraceInitialState: "Started",
+	CommonFlags:       &params,
+}
+
+var runqlatHumanReadable bool
+
+var runqlatCmd = &cobra.Command{
+	Use:   "runqlat",
+	Short: "Analyze scheduler performance through a run queue latency distribution",
+}
+
+var runqlatStartCmd = &cobra.Command{
+	Use:          "start",
+	Short:        "Start monitoring the time threads wait on a run queue and record its distribution",
+	RunE:         runRunqlatStart,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		// Runqlat traces the whole node unless a filter is given, so we
+		// need to avoid adding the default namespace configured in the
+		// kubeconfig file.
+		if params.Namespace != "" && !params.NamespaceOverridden {
+			params.Namespace = ""
+		}
+		return nil
+	},
+}
+
+var runqlatStopCmd = &cobra.Command{
+	Use:          "stop <trace-id|name>",
+	Short:        "Stop monitoring and generate a report (a histogram graph) with the distribution of run queue latency",
+	RunE:         runRunqlatStop,
+	SilenceUsage: true,
+}
+
+var runqlatListCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "List the currently running runqlat traces",
+	RunE:         runRunqlatList,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+}
+
+func init() {
+	runqlatCmd.AddCommand(runqlatStartCmd)
+	runqlatCmd.AddCommand(runqlatStopCmd)
+	runqlatCmd.AddCommand(runqlatListCmd)
+
+	ProfilerCmd.AddCommand(runqlatCmd)
+	utils.RegisterGadgetCommand(runqlatCmd, "runqlat", nil)
+
+	// Common flags are meaningless for list and stop sub-commands
+	utils.AddCommonFlags(runqlatStartCmd, &params)
+	utils.AddTraceNameFlag(runqlatStartCmd, &runqlatTraceConfig.TraceName)
+	utils.AddTraceGroupFlag(runqlatStartCmd, &runqlatTraceConfig.TraceGroup)
+	utils.AddHumanReadableFlag(runqlatStopCmd, &runqlatHumanReadable)
+}
+
+func runRunqlatStart(cmd *cobra.Command, args []string) error {
+	if params.Node == "" {
+		return utils.WrapInErrMissingArgs("--node")
+	}
+
+	runqlatTraceConfig.Operation = "start"
+	traceID, err := utils.CreateTrace(runqlatTraceConfig)
+	if err != nil {
+		return utils.WrapInErrRunGadget(err)
+	}
+
+	fmt.Printf("%s\n", traceID)
+
+	return nil
+}
+
+func runRunqlatStop(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return utils.WrapInErrMissingArgs("<trace-id>")
+	}
+
+	traceID, err := utils.ResolveTraceID(args[0])
+	if err != nil {
+		return utils.WrapInErrStopGadget(err)
+	}
+
+	err = utils.SetTraceOperation(traceID, "stop")
+	if err != nil {
+		return utils.WrapInErrStopGadget(err)
+	}
+
+	displayResultsCallback := func(results []gadgetv1alpha1.Trace) error {
+		if len(results) != 1 {
+			return errors.New("there should be only one result because runqlat runs on one node at a time")
+		}
+
+		output := results[0].Status.Output
+		if output == "" {
+			fmt.Fprintln(os.Stderr, "No thread was scheduled")
+			return nil
+		}
+		if runqlatHumanReadable {
+			output = utils.HumanizeHistogram(output)
+		}
+
+		fmt.Printf("%v", output)
+		return nil
+	}
+
+	defer utils.DeleteTrace(traceID)
+
+	err = utils.PrintTraceOutputFromStatus(traceID,
+		runqlatTraceConfig.TraceOutputState, displayResultsCallback)
+	if err != nil {
+		return utils.WrapInErrGetGadgetOutput(err)
+	}
+
+	return nil
+}
+
+func runRunqlatList(cmd *cobra.Command, args []string) error {
+	err := utils.PrintAllTraces(runqlatTraceConfig)
+	if err != nil {
+		return utils.WrapInErrListGadgetTraces(err)
+	}
+	return nil
+}
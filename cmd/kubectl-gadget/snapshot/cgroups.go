@@ -0,0 +1,216 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/cgroup-collector/types"
+	"github.com/kinvolk/inspektor-gadget/pkg/k8sutil"
+)
+
+// cgroup is the cgroup of a container with the resources whose limits
+// differ from the spec of the container.
+type cgroup struct {
+	types.Cgroup
+
+	// Drift is nil if the spec of the pod couldn't be read.
+	Drift []string `json:"drift"`
+}
+
+func cgroupsFromResults(results []gadgetv1alpha1.Trace) []cgroup {
+	allCgroups := []cgroup{}
+
+	for _, i := range results {
+		var cgroups []types.Cgroup
+		json.Unmarshal([]byte(i.Status.Output), &cgroups)
+		for _, c := range cgroups {
+			allCgroups = append(allCgroups, cgroup{Cgroup: c})
+		}
+	}
+
+	sort.Slice(allCgroups, func(i, j int) bool {
+		ci, cj := allCgroups[i], allCgroups[j]
+		switch {
+		case ci.Node != cj.Node:
+			return ci.Node < cj.Node
+		case ci.Namespace != cj.Namespace:
+			return ci.Namespace < cj.Namespace
+		case ci.Pod != cj.Pod:
+			return ci.Pod < cj.Pod
+		default:
+			return ci.Container < cj.Container
+		}
+	})
+
+	return allCgroups
+}
+
+// containerLimits returns the CPU limit in millicores and the memory limit
+// in bytes of each container of the pods, by namespace/pod/container.
+func containerLimits(pods []corev1.Pod) map[string][2]int64 {
+	limits := make(map[string][2]int64)
+
+	for _, pod := range pods {
+		for _, c := range pod.Spec.Containers {
+			var cpu, memory int64
+			if q, ok := c.Resources.Limits[corev1.ResourceCPU]; ok {
+				cpu = q.MilliValue()
+			}
+			if q, ok := c.Resources.Limits[corev1.ResourceMemory]; ok {
+				memory = q.Value()
+			}
+			limits[pod.Namespace+"/"+pod.Name+"/"+c.Name] = [2]int64{cpu, memory}
+		}
+	}
+
+	return limits
+}
+
+// setCgroupsDrift compares the limits enforced by the cgroups with the
+// limits of the specs of the pods.
+func setCgroupsDrift(cgroups []cgroup) {
+	client, err := k8sutil.NewClientsetFromConfigFlags(utils.KubernetesConfigFlags)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", utils.WrapInErrSetupK8sClient(err))
+		return
+	}
+
+	namespace := ""
+	if !params.AllNamespaces {
+		namespace = params.Namespace
+	}
+
+	pods, err := client.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to list pods, the limits aren't compared with their specs: %s\n", err)
+		return
+	}
+
+	limits := containerLimits(pods.Items)
+
+	for i := range cgroups {
+		c := &cgroups[i]
+		l, ok := limits[c.Namespace+"/"+c.Pod+"/"+c.Container]
+		if !ok {
+			continue
+		}
+		c.Drift = c.Cgroup.Drift(l[0], l[1])
+	}
+}
+
+func formatLimit(limit int64, format func(int64) string) string {
+	if limit == types.Unlimited {
+		return "max"
+	}
+	return format(limit)
+}
+
+func formatDrift(drift []string) string {
+	if drift == nil {
+		return "?"
+	}
+	if len(drift) == 0 {
+		return "-"
+	}
+	return strings.Join(drift, ",")
+}
+
+func printCgroups(allCgroups []cgroup) error {
+	switch params.OutputMode {
+	case utils.OutputModeJSON:
+		b, err := json.MarshalIndent(allCgroups, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error marshalling results: %w", err)
+		}
+		fmt.Printf("%s\n", b)
+	case utils.OutputModeCustomColumns:
+		table := utils.NewTableFormater(params.CustomColumns, map[string]int{})
+		fmt.Println(table.GetHeader())
+		transform := table.GetTransformFunc()
+
+		for _, c := range allCgroups {
+			b, err := json.Marshal(c)
+			if err != nil {
+				return fmt.Errorf("error marshalling results: %w", err)
+			}
+
+			fmt.Println(transform(string(b)))
+		}
+	default:
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 4, ' ', 0)
+
+		fmt.Fprintln(w, "NODE\tNAMESPACE\tPOD\tCONTAINER\tCPU-MAX\tMEMORY-MAX\tPIDS-MAX\tCPU-TIME\tMEMORY\tPIDS\tDRIFT\t")
+		for _, c := range allCgroups {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s\t\n",
+				c.Node,
+				c.Namespace,
+				c.Pod,
+				c.Container,
+				formatLimit(c.CPULimit(), func(m int64) string { return fmt.Sprintf("%dm", m) }),
+				formatLimit(c.MemoryMax, func(b int64) string { return utils.FormatBytes(uint64(b)) }),
+				formatLimit(c.PidsMax, func(n int64) string { return strconv.FormatInt(n, 10) }),
+				utils.FormatMicroseconds(c.CPUUsage),
+				utils.FormatBytes(c.MemoryCurrent),
+				c.PidsCurrent,
+				formatDrift(c.Drift),
+			)
+		}
+		w.Flush()
+	}
+
+	return nil
+}
+
+var cgroupCollectorCmd = &cobra.Command{
+	Use:   "cgroups",
+	Short: "Gather the limits and usage of the cgroups of the containers",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		callback := func(results []gadgetv1alpha1.Trace) error {
+			allCgroups := cgroupsFromResults(results)
+			setCgroupsDrift(allCgroups)
+			return printCgroups(allCgroups)
+		}
+
+		config := &utils.TraceConfig{
+			GadgetName:       "cgroup-collector",
+			Operation:        "collect",
+			TraceOutputMode:  "Status",
+			TraceOutputState: "Completed",
+			CommonFlags:      &params,
+		}
+
+		return runSnapshot(config, callback)
+	},
+}
+
+func init() {
+	SnapshotCmd.AddCommand(cgroupCollectorCmd)
+	utils.RegisterGadgetCommand(cgroupCollectorCmd, "cgroup-collector", cgroup{})
+	utils.AddCommonFlags(cgroupCollectorCmd, &params)
+}
@@ -29,111 +29,197 @@ import (
 
 var processCollectorParamThreads bool
 
-var processCollectorCmd = &cobra.Command{
-	Use:   "process",
-	Short: "Gather information about running processes",
-	RunE: func(cmd *cobra.Command, args []string) error {
-		callback := func(results []gadgetv1alpha1.Trace) error {
-			// Display results
-			type Process struct {
-				Tgid                int    `json:"tgid,omitempty"`
-				Pid                 int    `json:"pid,omitempty"`
-				Comm                string `json:"comm,omitempty"`
-				KubernetesNamespace string `json:"namespace,omitempty"`
-				KubernetesPod       string `json:"pod,omitempty"`
-				KubernetesContainer string `json:"container,omitempty"`
-				KubernetesNode      string `json:"node,omitempty"`
-			}
-			allProcesses := []Process{}
+type Process struct {
+	Tgid                int    `json:"tgid,omitempty"`
+	Pid                 int    `json:"pid,omitempty"`
+	Comm                string `json:"comm,omitempty"`
+	KubernetesNamespace string `json:"namespace,omitempty"`
+	KubernetesPod       string `json:"pod,omitempty"`
+	KubernetesContainer string `json:"container,omitempty"`
+	KubernetesNode      string `json:"node,omitempty"`
+
+	// Change is set in watch mode only, to "added" or "removed".
+	Change string `json:"change,omitempty"`
+}
 
-			for _, i := range results {
-				processes := []Process{}
-				json.Unmarshal([]byte(i.Status.Output), &processes)
-				allProcesses = append(allProcesses, processes...)
-			}
-			if !processCollectorParamThreads {
-				allProcessesTrimmed := []Process{}
-				for _, i := range allProcesses {
-					if i.Tgid == i.Pid {
-						allProcessesTrimmed = append(allProcessesTrimmed, i)
-					}
-				}
-				allProcesses = allProcessesTrimmed
+// key identifies the process across snapshots.
+func (p *Process) key() string {
+	return fmt.Sprintf("%s/%s/%s/%s/%d/%d/%s",
+		p.KubernetesNode, p.KubernetesNamespace, p.KubernetesPod,
+		p.KubernetesContainer, p.Tgid, p.Pid, p.Comm)
+}
+
+func processesFromResults(results []gadgetv1alpha1.Trace) []Process {
+	allProcesses := []Process{}
+
+	for _, i := range results {
+		processes := []Process{}
+		json.Unmarshal([]byte(i.Status.Output), &processes)
+		allProcesses = append(allProcesses, processes...)
+	}
+	if !processCollectorParamThreads {
+		allProcessesTrimmed := []Process{}
+		for _, i := range allProcesses {
+			if i.Tgid == i.Pid {
+				allProcessesTrimmed = append(allProcessesTrimmed, i)
 			}
+		}
+		allProcesses = allProcessesTrimmed
+	}
+
+	sort.Slice(allProcesses, func(i, j int) bool {
+		pi, pj := allProcesses[i], allProcesses[j]
+		switch {
+		case pi.KubernetesNode != pj.KubernetesNode:
+			return pi.KubernetesNode < pj.KubernetesNode
+		case pi.KubernetesNamespace != pj.KubernetesNamespace:
+			return pi.KubernetesNamespace < pj.KubernetesNamespace
+		case pi.KubernetesPod != pj.KubernetesPod:
+			return pi.KubernetesPod < pj.KubernetesPod
+		case pi.KubernetesContainer != pj.KubernetesContainer:
+			return pi.KubernetesContainer < pj.KubernetesContainer
+		case pi.Comm != pj.Comm:
+			return pi.Comm < pj.Comm
+		case pi.Tgid != pj.Tgid:
+			return pi.Tgid < pj.Tgid
+		default:
+			return pi.Pid < pj.Pid
 
-			sort.Slice(allProcesses, func(i, j int) bool {
-				pi, pj := allProcesses[i], allProcesses[j]
-				switch {
-				case pi.KubernetesNode != pj.KubernetesNode:
-					return pi.KubernetesNode < pj.KubernetesNode
-				case pi.KubernetesNamespace != pj.KubernetesNamespace:
-					return pi.KubernetesNamespace < pj.KubernetesNamespace
-				case pi.KubernetesPod != pj.KubernetesPod:
-					return pi.KubernetesPod < pj.KubernetesPod
-				case pi.KubernetesContainer != pj.KubernetesContainer:
-					return pi.KubernetesContainer < pj.KubernetesContainer
-				case pi.Comm != pj.Comm:
-					return pi.Comm < pj.Comm
-				case pi.Tgid != pj.Tgid:
-					return pi.Tgid < pj.Tgid
-				default:
-					return pi.Pid < pj.Pid
+		}
+	})
 
-				}
-			})
+	return allProcesses
+}
+
+// processesChanges returns the processes that were started and the ones
+// that exited between two snapshots, with their Change field set.
+func processesChanges(previous, current []Process) []Process {
+	previousKeys := make([]string, len(previous))
+	for i := range previous {
+		previousKeys[i] = previous[i].key()
+	}
+	currentKeys := make([]string, len(current))
+	for i := range current {
+		currentKeys[i] = current[i].key()
+	}
+
+	added, removed := diffKeys(previousKeys, currentKeys)
+
+	changes := []Process{}
+	for _, i := range removed {
+		p := previous[i]
+		p.Change = changeRemoved
+		changes = append(changes, p)
+	}
+	for _, i := range added {
+		p := current[i]
+		p.Change = changeAdded
+		changes = append(changes, p)
+	}
+
+	return changes
+}
 
-			switch params.OutputMode {
-			case utils.OutputModeJSON:
-				b, err := json.MarshalIndent(allProcesses, "", "  ")
+func printProcesses(allProcesses []Process) error {
+	switch params.OutputMode {
+	case utils.OutputModeJSON:
+		if watch {
+			// One line per process, like the gadgets streaming events.
+			for _, p := range allProcesses {
+				b, err := json.Marshal(p)
 				if err != nil {
 					return fmt.Errorf("error marshalling results: %w", err)
 				}
 				fmt.Printf("%s\n", b)
-			case utils.OutputModeCustomColumns:
-				table := utils.NewTableFormater(params.CustomColumns, map[string]int{})
-				fmt.Println(table.GetHeader())
-				transform := table.GetTransformFunc()
-
-				for _, p := range allProcesses {
-					b, err := json.Marshal(p)
-					if err != nil {
-						return fmt.Errorf("error marshalling results: %w", err)
-					}
-
-					fmt.Println(transform(string(b)))
+			}
+			return nil
+		}
+
+		b, err := json.MarshalIndent(allProcesses, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error marshalling results: %w", err)
+		}
+		fmt.Printf("%s\n", b)
+	case utils.OutputModeCustomColumns:
+		table := utils.NewTableFormater(params.CustomColumns, map[string]int{})
+		fmt.Println(table.GetHeader())
+		transform := table.GetTransformFunc()
+
+		for _, p := range allProcesses {
+			b, err := json.Marshal(p)
+			if err != nil {
+				return fmt.Errorf("error marshalling results: %w", err)
+			}
+
+			fmt.Println(transform(string(b)))
+		}
+	default:
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 4, ' ', 0)
+
+		changeHeader, changeFormat := "", ""
+		if watch {
+			changeHeader, changeFormat = "CHANGE\t", "%s\t"
+		}
+
+		if processCollectorParamThreads {
+			fmt.Fprintln(w, changeHeader+"NODE\tNAMESPACE\tPOD\tCONTAINER\tCOMM\tTGID\tPID\t")
+			for _, p := range allProcesses {
+				if watch {
+					fmt.Fprintf(w, changeFormat, p.Change)
 				}
-			default:
-				w := tabwriter.NewWriter(os.Stdout, 0, 0, 4, ' ', 0)
-				if processCollectorParamThreads {
-					fmt.Fprintln(w, "NODE\tNAMESPACE\tPOD\tCONTAINER\tCOMM\tTGID\tPID\t")
-					for _, p := range allProcesses {
-						fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%d\t\n",
-							p.KubernetesNode,
-							p.KubernetesNamespace,
-							p.KubernetesPod,
-							p.KubernetesContainer,
-							p.Comm,
-							p.Tgid,
-							p.Pid,
-						)
-					}
-				} else {
-					fmt.Fprintln(w, "NODE\tNAMESPACE\tPOD\tCONTAINER\tCOMM\tPID\t")
-					for _, p := range allProcesses {
-						fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t\n",
-							p.KubernetesNode,
-							p.KubernetesNamespace,
-							p.KubernetesPod,
-							p.KubernetesContainer,
-							p.Comm,
-							p.Pid,
-						)
-					}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%d\t\n",
+					p.KubernetesNode,
+					p.KubernetesNamespace,
+					p.KubernetesPod,
+					p.KubernetesContainer,
+					p.Comm,
+					p.Tgid,
+					p.Pid,
+				)
+			}
+		} else {
+			fmt.Fprintln(w, changeHeader+"NODE\tNAMESPACE\tPOD\tCONTAINER\tCOMM\tPID\t")
+			for _, p := range allProcesses {
+				if watch {
+					fmt.Fprintf(w, changeFormat, p.Change)
 				}
-				w.Flush()
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t\n",
+					p.KubernetesNode,
+					p.KubernetesNamespace,
+					p.KubernetesPod,
+					p.KubernetesContainer,
+					p.Comm,
+					p.Pid,
+				)
 			}
+		}
+		w.Flush()
+	}
 
-			return nil
+	return nil
+}
+
+var processCollectorCmd = &cobra.Command{
+	Use:   "process",
+	Short: "Gather information about running processes",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var previous []Process
+
+		callback := func(results []gadgetv1alpha1.Trace) error {
+			allProcesses := processesFromResults(results)
+			if !watch {
+				return printProcesses(allProcesses)
+			}
+
+			// In watch mode, the first snapshot is compared with an empty
+			// one: all the processes are printed as added.
+			changes := processesChanges(previous, allProcesses)
+			previous = allProcesses
+			if len(changes) == 0 {
+				return nil
+			}
+
+			return printProcesses(changes)
 		}
 
 		config := &utils.TraceConfig{
@@ -144,13 +230,15 @@ var processCollectorCmd = &cobra.Command{
 			CommonFlags:      &params,
 		}
 
-		return utils.RunTraceAndPrintStatusOutput(config, callback)
+		return runSnapshot(config, callback)
 	},
 }
 
 func init() {
 	SnapshotCmd.AddCommand(processCollectorCmd)
+	utils.RegisterGadgetCommand(processCollectorCmd, "process-collector", nil)
 	utils.AddCommonFlags(processCollectorCmd, &params)
+	addWatchFlags(processCollectorCmd)
 
 	processCollectorCmd.PersistentFlags().BoolVarP(
 		&processCollectorParamThreads,
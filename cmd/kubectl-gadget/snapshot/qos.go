@@ -0,0 +1,253 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/qos-collector/types"
+	"github.com/kinvolk/inspektor-gadget/pkg/k8sutil"
+)
+
+const (
+	ingressBandwidthAnnotation = "kubernetes.io/ingress-bandwidth"
+	egressBandwidthAnnotation  = "kubernetes.io/egress-bandwidth"
+)
+
+// qos is the traffic control configuration of a pod with its bandwidth
+// annotations, applied by the bandwidth CNI plugin.
+type qos struct {
+	types.QoS
+
+	IngressBandwidth string `json:"ingressBandwidth,omitempty"`
+	EgressBandwidth  string `json:"egressBandwidth,omitempty"`
+}
+
+func qosFromResults(results []gadgetv1alpha1.Trace) []qos {
+	allQoS := []qos{}
+
+	for _, i := range results {
+		var podsQoS []types.QoS
+		json.Unmarshal([]byte(i.Status.Output), &podsQoS)
+		for _, q := range podsQoS {
+			allQoS = append(allQoS, qos{QoS: q})
+		}
+	}
+
+	sort.Slice(allQoS, func(i, j int) bool {
+		qi, qj := allQoS[i], allQoS[j]
+		switch {
+		case qi.Node != qj.Node:
+			return qi.Node < qj.Node
+		case qi.Namespace != qj.Namespace:
+			return qi.Namespace < qj.Namespace
+		default:
+			return qi.Pod < qj.Pod
+		}
+	})
+
+	return allQoS
+}
+
+// setBandwidthAnnotations adds the bandwidth annotations of the pods.
+func setBandwidthAnnotations(allQoS []qos) {
+	client, err := k8sutil.NewClientsetFromConfigFlags(utils.KubernetesConfigFlags)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", utils.WrapInErrSetupK8sClient(err))
+		return
+	}
+
+	namespace := ""
+	if !params.AllNamespaces {
+		namespace = params.Namespace
+	}
+
+	pods, err := client.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to list pods, their bandwidth annotations aren't shown: %s\n", err)
+		return
+	}
+
+	podsByName := make(map[string]*corev1.Pod)
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		podsByName[pod.Namespace+"/"+pod.Name] = pod
+	}
+
+	for i := range allQoS {
+		q := &allQoS[i]
+		pod, ok := podsByName[q.Namespace+"/"+q.Pod]
+		if !ok {
+			continue
+		}
+		q.IngressBandwidth = pod.Annotations[ingressBandwidthAnnotation]
+		q.EgressBandwidth = pod.Annotations[egressBandwidthAnnotation]
+	}
+}
+
+func formatRate(rate uint64) string {
+	if rate == 0 {
+		return "-"
+	}
+	return utils.FormatBitRate(rate)
+}
+
+// formatAnnotations returns the bandwidth annotations of a pod, e.g.
+// "ingress=10M,egress=1M".
+func formatAnnotations(q *qos) string {
+	annotations := []string{}
+	if q.IngressBandwidth != "" {
+		annotations = append(annotations, "ingress="+q.IngressBandwidth)
+	}
+	if q.EgressBandwidth != "" {
+		annotations = append(annotations, "egress="+q.EgressBandwidth)
+	}
+	if len(annotations) == 0 {
+		return "-"
+	}
+	return strings.Join(annotations, ",")
+}
+
+// formatQdiscs returns the qdiscs of the interfaces of a pod, prefixed with
+// the side of the interface, e.g. "host:tbf,host:ingress,ifb:tbf". The
+// noqueue qdiscs, the default of the veth interfaces, are left out.
+func formatQdiscs(q *qos) string {
+	qdiscs := []string{}
+	for _, i := range q.Interfaces {
+		for _, qdisc := range i.Qdiscs {
+			if qdisc.Kind == "noqueue" {
+				continue
+			}
+			qdiscs = append(qdiscs, i.Side+":"+qdisc.Kind)
+		}
+	}
+	if len(qdiscs) == 0 {
+		return "-"
+	}
+	return strings.Join(qdiscs, ",")
+}
+
+// formatFilters returns the filters of the interfaces of a pod with what
+// they do, e.g. "host:u32(mirred egress redir to ifb0)".
+func formatFilters(q *qos) string {
+	filters := []string{}
+	for _, i := range q.Interfaces {
+		for _, f := range i.Filters {
+			filter := i.Side + ":" + f.Kind
+			if f.Action != "" {
+				filter += "(" + f.Action + ")"
+			}
+			filters = append(filters, filter)
+		}
+	}
+	if len(filters) == 0 {
+		return "-"
+	}
+	return strings.Join(filters, ",")
+}
+
+func hostInterface(q *qos) string {
+	for _, i := range q.Interfaces {
+		if i.Side == types.SideHost {
+			return i.Name
+		}
+	}
+	return "-"
+}
+
+func printQoS(allQoS []qos) error {
+	switch params.OutputMode {
+	case utils.OutputModeJSON:
+		b, err := json.MarshalIndent(allQoS, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error marshalling results: %w", err)
+		}
+		fmt.Printf("%s\n", b)
+	case utils.OutputModeCustomColumns:
+		table := utils.NewTableFormater(params.CustomColumns, map[string]int{})
+		fmt.Println(table.GetHeader())
+		transform := table.GetTransformFunc()
+
+		for _, q := range allQoS {
+			b, err := json.Marshal(q)
+			if err != nil {
+				return fmt.Errorf("error marshalling results: %w", err)
+			}
+
+			fmt.Println(transform(string(b)))
+		}
+	default:
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 4, ' ', 0)
+
+		fmt.Fprintln(w, "NODE\tNAMESPACE\tPOD\tHOST-INTERFACE\tINGRESS-RATE\tEGRESS-RATE\tANNOTATIONS\tQDISCS\tFILTERS\t")
+		for i := range allQoS {
+			q := &allQoS[i]
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t\n",
+				q.Node,
+				q.Namespace,
+				q.Pod,
+				hostInterface(q),
+				formatRate(q.IngressRate()),
+				formatRate(q.EgressRate()),
+				formatAnnotations(q),
+				formatQdiscs(q),
+				formatFilters(q),
+			)
+		}
+		w.Flush()
+	}
+
+	return nil
+}
+
+var qosCollectorCmd = &cobra.Command{
+	Use:   "qos",
+	Short: "Gather the traffic control configuration shaping the bandwidth of the pods",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		callback := func(results []gadgetv1alpha1.Trace) error {
+			allQoS := qosFromResults(results)
+			setBandwidthAnnotations(allQoS)
+			return printQoS(allQoS)
+		}
+
+		config := &utils.TraceConfig{
+			GadgetName:       "qos-collector",
+			Operation:        "collect",
+			TraceOutputMode:  "Status",
+			TraceOutputState: "Completed",
+			CommonFlags:      &params,
+		}
+
+		return runSnapshot(config, callback)
+	},
+}
+
+func init() {
+	SnapshotCmd.AddCommand(qosCollectorCmd)
+	utils.RegisterGadgetCommand(qosCollectorCmd, "qos-collector", qos{})
+	utils.AddCommonFlags(qosCollectorCmd, &params)
+}
@@ -15,7 +15,11 @@
 package snapshot
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
 
 	"github.com/spf13/cobra"
 )
@@ -24,7 +28,72 @@ import (
 // declare it here.
 var params utils.CommonFlags
 
+// Watch mode, shared by all the gadgets of this package too.
+var (
+	watch         bool
+	watchInterval time.Duration
+)
+
+const (
+	changeAdded   = "added"
+	changeRemoved = "removed"
+)
+
 var SnapshotCmd = &cobra.Command{
 	Use:   "snapshot",
 	Short: "Take a snapshot of a subsystem and print it",
 }
+
+func addWatchFlags(command *cobra.Command) {
+	command.PersistentFlags().BoolVarP(
+		&watch,
+		"watch",
+		"w",
+		false,
+		"Take a snapshot every --interval and print only what was added or removed since the previous one",
+	)
+	command.PersistentFlags().DurationVar(
+		&watchInterval,
+		"interval",
+		10*time.Second,
+		"Interval between two snapshots with --watch",
+	)
+}
+
+// runSnapshot takes a single snapshot, or one every watchInterval in watch
+// mode.
+func runSnapshot(config *utils.TraceConfig, callback func(results []gadgetv1alpha1.Trace) error) error {
+	if !watch {
+		return utils.RunTraceAndPrintStatusOutput(config, callback)
+	}
+
+	if watchInterval <= 0 {
+		return utils.WrapInErrInvalidArg("--interval", fmt.Errorf("must be positive"))
+	}
+
+	return utils.RunTraceAndWatchStatusOutput(config, watchInterval, callback)
+}
+
+// diffKeys compares the keys of the entries of two snapshots. It returns
+// the indexes in current of the entries that weren't in previous and the
+// indexes in previous of the entries that aren't in current anymore.
+func diffKeys(previous, current []string) (added, removed []int) {
+	previousSet := make(map[string]struct{}, len(previous))
+	for _, key := range previous {
+		previousSet[key] = struct{}{}
+	}
+	currentSet := make(map[string]struct{}, len(current))
+	for i, key := range current {
+		currentSet[key] = struct{}{}
+		if _, ok := previousSet[key]; !ok {
+			added = append(added, i)
+		}
+	}
+	for i, key := range previous {
+		if _, ok := currentSet[key]; !ok {
+			removed = append(removed, i)
+		}
+	}
+
+	return added, removed
+}
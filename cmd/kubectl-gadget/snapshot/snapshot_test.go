@@ -0,0 +1,123 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	socketcollectortypes "github.com/kinvolk/inspektor-gadget/pkg/gadgets/socket-collector/types"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+func TestDiffKeys(t *testing.T) {
+	table := []struct {
+		description     string
+		previous        []string
+		current         []string
+		expectedAdded   []int
+		expectedRemoved []int
+	}{
+		{
+			description:   "First snapshot",
+			current:       []string{"a", "b"},
+			expectedAdded: []int{0, 1},
+		},
+		{
+			description: "No change",
+			previous:    []string{"a", "b"},
+			current:     []string{"a", "b"},
+		},
+		{
+			description:     "Added and removed",
+			previous:        []string{"a", "b", "c"},
+			current:         []string{"b", "d"},
+			expectedAdded:   []int{1},
+			expectedRemoved: []int{0, 2},
+		},
+	}
+
+	for _, entry := range table {
+		added, removed := diffKeys(entry.previous, entry.current)
+		if !reflect.DeepEqual(added, entry.expectedAdded) {
+			t.Errorf("%s: expected added %v, got %v", entry.description, entry.expectedAdded, added)
+		}
+		if !reflect.DeepEqual(removed, entry.expectedRemoved) {
+			t.Errorf("%s: expected removed %v, got %v", entry.description, entry.expectedRemoved, removed)
+		}
+	}
+}
+
+func TestProcessesChanges(t *testing.T) {
+	previous := []Process{
+		{Tgid: 1, Pid: 1, Comm: "nginx", KubernetesPod: "web"},
+		{Tgid: 10, Pid: 10, Comm: "sh", KubernetesPod: "web"},
+	}
+	current := []Process{
+		{Tgid: 1, Pid: 1, Comm: "nginx", KubernetesPod: "web"},
+		{Tgid: 11, Pid: 11, Comm: "curl", KubernetesPod: "web"},
+	}
+
+	expected := []Process{
+		{Tgid: 10, Pid: 10, Comm: "sh", KubernetesPod: "web", Change: changeRemoved},
+		{Tgid: 11, Pid: 11, Comm: "curl", KubernetesPod: "web", Change: changeAdded},
+	}
+
+	changes := processesChanges(previous, current)
+	if !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("Expected %+v, got %+v", expected, changes)
+	}
+}
+
+func TestSocketsChanges(t *testing.T) {
+	listener := socketcollectortypes.Event{
+		Event:        eventtypes.Event{Pod: "web"},
+		Protocol:     "TCP",
+		LocalAddress: "0.0.0.0",
+		LocalPort:    80,
+		Status:       "LISTEN",
+		InodeNumber:  100,
+	}
+	// Same port, but a new socket.
+	newListener := listener
+	newListener.InodeNumber = 101
+
+	changes := socketsChanges(
+		[]socketcollectortypes.Event{listener},
+		[]socketcollectortypes.Event{newListener},
+	)
+
+	expected := []socketChange{
+		{Change: changeRemoved, Event: listener},
+		{Change: changeAdded, Event: newListener},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("Expected %+v, got %+v", expected, changes)
+	}
+
+	// The change is printed alongside the fields of the socket.
+	b, err := json.Marshal(changes[1])
+	if err != nil {
+		t.Fatalf("Failed to marshal change: %s", err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(b, &fields); err != nil {
+		t.Fatalf("Failed to unmarshal change: %s", err)
+	}
+	if fields["change"] != changeAdded || fields["inode_number"] != float64(101) {
+		t.Fatalf("Unexpected JSON for the change: %s", b)
+	}
+}
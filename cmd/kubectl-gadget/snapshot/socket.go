@@ -34,98 +34,186 @@ var (
 	socketCollectorParamExtended bool
 )
 
-var socketCollectorCmd = &cobra.Command{
-	Use:   "socket",
-	Short: "Gather information about TCP and UDP sockets",
-	RunE: func(cmd *cobra.Command, args []string) error {
-		callback := func(results []gadgetv1alpha1.Trace) error {
-			allSockets := []socketcollectortypes.Event{}
+// socketChange is a socket with, in watch mode only, whether it was added or
+// removed since the previous snapshot.
+type socketChange struct {
+	Change string `json:"change,omitempty"`
+	socketcollectortypes.Event
+}
 
-			for _, i := range results {
-				var sockets []socketcollectortypes.Event
-				json.Unmarshal([]byte(i.Status.Output), &sockets)
-				allSockets = append(allSockets, sockets...)
-			}
+// socketKey identifies the socket across snapshots.
+func socketKey(s *socketcollectortypes.Event) string {
+	return fmt.Sprintf("%s/%s/%s/%s/%s:%d/%s:%d/%s/%d",
+		s.Event.Node, s.Event.Namespace, s.Event.Pod, s.Protocol,
+		s.LocalAddress, s.LocalPort, s.RemoteAddress, s.RemotePort,
+		s.Status, s.InodeNumber)
+}
 
-			sort.Slice(allSockets, func(i, j int) bool {
-				si, sj := allSockets[i], allSockets[j]
-				switch {
-				case si.Event.Node != sj.Event.Node:
-					return si.Event.Node < sj.Event.Node
-				case si.Event.Namespace != sj.Event.Namespace:
-					return si.Event.Namespace < sj.Event.Namespace
-				case si.Event.Pod != sj.Event.Pod:
-					return si.Event.Pod < sj.Event.Pod
-				case si.Protocol != sj.Protocol:
-					return si.Protocol < sj.Protocol
-				case si.Status != sj.Status:
-					return si.Status < sj.Status
-				case si.LocalAddress != sj.LocalAddress:
-					return si.LocalAddress < sj.LocalAddress
-				case si.RemoteAddress != sj.RemoteAddress:
-					return si.RemoteAddress < sj.RemoteAddress
-				case si.LocalPort != sj.LocalPort:
-					return si.LocalPort < sj.LocalPort
-				case si.RemotePort != sj.RemotePort:
-					return si.RemotePort < sj.RemotePort
-				default:
-					return si.InodeNumber < sj.InodeNumber
-				}
-			})
+func socketsFromResults(results []gadgetv1alpha1.Trace) []socketcollectortypes.Event {
+	allSockets := []socketcollectortypes.Event{}
+
+	for _, i := range results {
+		var sockets []socketcollectortypes.Event
+		json.Unmarshal([]byte(i.Status.Output), &sockets)
+		allSockets = append(allSockets, sockets...)
+	}
+
+	sort.Slice(allSockets, func(i, j int) bool {
+		si, sj := allSockets[i], allSockets[j]
+		switch {
+		case si.Event.Node != sj.Event.Node:
+			return si.Event.Node < sj.Event.Node
+		case si.Event.Namespace != sj.Event.Namespace:
+			return si.Event.Namespace < sj.Event.Namespace
+		case si.Event.Pod != sj.Event.Pod:
+			return si.Event.Pod < sj.Event.Pod
+		case si.Protocol != sj.Protocol:
+			return si.Protocol < sj.Protocol
+		case si.Status != sj.Status:
+			return si.Status < sj.Status
+		case si.LocalAddress != sj.LocalAddress:
+			return si.LocalAddress < sj.LocalAddress
+		case si.RemoteAddress != sj.RemoteAddress:
+			return si.RemoteAddress < sj.RemoteAddress
+		case si.LocalPort != sj.LocalPort:
+			return si.LocalPort < sj.LocalPort
+		case si.RemotePort != sj.RemotePort:
+			return si.RemotePort < sj.RemotePort
+		default:
+			return si.InodeNumber < sj.InodeNumber
+		}
+	})
+
+	return allSockets
+}
+
+// socketsChanges returns the sockets that were opened and the ones that were
+// closed between two snapshots.
+func socketsChanges(previous, current []socketcollectortypes.Event) []socketChange {
+	previousKeys := make([]string, len(previous))
+	for i := range previous {
+		previousKeys[i] = socketKey(&previous[i])
+	}
+	currentKeys := make([]string, len(current))
+	for i := range current {
+		currentKeys[i] = socketKey(&current[i])
+	}
+
+	added, removed := diffKeys(previousKeys, currentKeys)
+
+	changes := []socketChange{}
+	for _, i := range removed {
+		changes = append(changes, socketChange{Change: changeRemoved, Event: previous[i]})
+	}
+	for _, i := range added {
+		changes = append(changes, socketChange{Change: changeAdded, Event: current[i]})
+	}
+
+	return changes
+}
 
-			switch params.OutputMode {
-			case utils.OutputModeJSON:
-				b, err := json.MarshalIndent(allSockets, "", "  ")
+func printSockets(allSockets []socketChange) error {
+	switch params.OutputMode {
+	case utils.OutputModeJSON:
+		if watch {
+			// One line per socket, like the gadgets streaming events.
+			for _, s := range allSockets {
+				b, err := json.Marshal(s)
 				if err != nil {
 					return fmt.Errorf("error marshalling results: %w", err)
 				}
 				fmt.Printf("%s\n", b)
-			case utils.OutputModeCustomColumns:
-				table := utils.NewTableFormater(params.CustomColumns, map[string]int{})
-				fmt.Println(table.GetHeader())
-				transform := table.GetTransformFunc()
-
-				for _, p := range allSockets {
-					b, err := json.Marshal(p)
-					if err != nil {
-						return fmt.Errorf("error marshalling results: %w", err)
-					}
-
-					fmt.Println(transform(string(b)))
-				}
-			default:
-				w := tabwriter.NewWriter(os.Stdout, 0, 0, 4, ' ', 0)
+			}
+			return nil
+		}
 
-				extendedHeader := "\n"
-				if socketCollectorParamExtended {
-					extendedHeader = "\tINODE\n"
-				}
+		b, err := json.MarshalIndent(allSockets, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error marshalling results: %w", err)
+		}
+		fmt.Printf("%s\n", b)
+	case utils.OutputModeCustomColumns:
+		table := utils.NewTableFormater(params.CustomColumns, map[string]int{})
+		fmt.Println(table.GetHeader())
+		transform := table.GetTransformFunc()
+
+		for _, s := range allSockets {
+			b, err := json.Marshal(s)
+			if err != nil {
+				return fmt.Errorf("error marshalling results: %w", err)
+			}
+
+			fmt.Println(transform(string(b)))
+		}
+	default:
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 4, ' ', 0)
+
+		changeHeader := ""
+		if watch {
+			changeHeader = "CHANGE\t"
+		}
+
+		extendedHeader := "\n"
+		if socketCollectorParamExtended {
+			extendedHeader = "\tINODE\n"
+		}
+
+		fmt.Fprintf(w, "%sNODE\tNAMESPACE\tPOD\tPROTOCOL\tLOCAL\tREMOTE\tSTATUS%s", changeHeader, extendedHeader)
+
+		for _, s := range allSockets {
+			if watch {
+				fmt.Fprintf(w, "%s\t", s.Change)
+			}
+
+			extendedInformation := "\n"
+			if socketCollectorParamExtended {
+				extendedInformation = fmt.Sprintf("\t%d\n", s.InodeNumber)
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s:%d\t%s:%d\t%s%s",
+				s.Event.Event.Node,
+				s.Event.Event.Namespace,
+				s.Event.Event.Pod,
+				s.Protocol,
+				s.LocalAddress,
+				s.LocalPort,
+				s.RemoteAddress,
+				s.RemotePort,
+				s.Status,
+				extendedInformation,
+			)
+		}
+		w.Flush()
+	}
 
-				fmt.Fprintf(w, "NODE\tNAMESPACE\tPOD\tPROTOCOL\tLOCAL\tREMOTE\tSTATUS%s", extendedHeader)
+	return nil
+}
+
+var socketCollectorCmd = &cobra.Command{
+	Use:   "socket",
+	Short: "Gather information about TCP and UDP sockets",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var previous []socketcollectortypes.Event
 
+		callback := func(results []gadgetv1alpha1.Trace) error {
+			allSockets := socketsFromResults(results)
+			if !watch {
+				sockets := make([]socketChange, 0, len(allSockets))
 				for _, s := range allSockets {
-					extendedInformation := "\n"
-					if socketCollectorParamExtended {
-						extendedInformation = fmt.Sprintf("\t%d\n", s.InodeNumber)
-					}
-
-					fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s:%d\t%s:%d\t%s%s",
-						s.Event.Node,
-						s.Event.Namespace,
-						s.Event.Pod,
-						s.Protocol,
-						s.LocalAddress,
-						s.LocalPort,
-						s.RemoteAddress,
-						s.RemotePort,
-						s.Status,
-						extendedInformation,
-					)
+					sockets = append(sockets, socketChange{Event: s})
 				}
-				w.Flush()
+				return printSockets(sockets)
 			}
 
-			return nil
+			// In watch mode, the first snapshot is compared with an empty
+			// one: all the sockets are printed as added.
+			changes := socketsChanges(previous, allSockets)
+			previous = allSockets
+			if len(changes) == 0 {
+				return nil
+			}
+
+			return printSockets(changes)
 		}
 
 		if _, err := socketcollectortypes.ParseProtocol(socketCollectorProtocol); err != nil {
@@ -143,13 +231,15 @@ var socketCollectorCmd = &cobra.Command{
 			},
 		}
 
-		return utils.RunTraceAndPrintStatusOutput(config, callback)
+		return runSnapshot(config, callback)
 	},
 }
 
 func init() {
 	SnapshotCmd.AddCommand(socketCollectorCmd)
+	utils.RegisterGadgetCommand(socketCollectorCmd, "socket-collector", socketcollectortypes.Event{})
 	utils.AddCommonFlags(socketCollectorCmd, &params)
+	addWatchFlags(socketCollectorCmd)
 
 	var protocols []string
 	for protocol := range socketcollectortypes.ProtocolsMap {
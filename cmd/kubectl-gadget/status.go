@@ -0,0 +1,245 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	resourcelimitstypes "github.com/kinvolk/inspektor-gadget/pkg/gadgets/resourcelimits/types"
+	"github.com/kinvolk/inspektor-gadget/pkg/k8sutil"
+	"github.com/kinvolk/inspektor-gadget/pkg/resourcestats"
+)
+
+var (
+	statusResources bool
+	statusOutput    string
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the status of the gadget pods",
+	Long: `Show the status of the gadget pods and the number of traces on each node.
+
+With --resources, show instead the CPU and memory used by the gadget pods
+over the last two hours, the additional usage observed while each gadget
+was running and the resources recommended for the gadget DaemonSet. They
+can be applied by deploying again with the --requests and --limits flags.`,
+	Example: `  kubectl gadget status
+  kubectl gadget status --resources
+  kubectl gadget status --resources -o json`,
+	Args: cobra.NoArgs,
+	RunE: runStatus,
+}
+
+func init() {
+	statusCmd.Flags().BoolVar(
+		&statusResources, "resources", false,
+		"Show the resources used by the gadget pods and recommend requests and limits")
+	statusCmd.Flags().StringVarP(
+		&statusOutput, "output", "o", utils.OutputModeColumns,
+		fmt.Sprintf("Output format (%s, %s)", utils.OutputModeColumns, utils.OutputModeJSON),
+	)
+	rootCmd.AddCommand(statusCmd)
+}
+
+// resourcesStatus is the output of kubectl gadget status --resources.
+type resourcesStatus struct {
+	Nodes    []resourcestats.NodeStats     `json:"nodes"`
+	Requests resourcelimitstypes.Resources `json:"requests"`
+	Limits   resourcelimitstypes.Resources `json:"limits"`
+}
+
+func runStatus(cmd *cobra.Command, args []string) error {
+	if statusOutput != utils.OutputModeColumns && statusOutput != utils.OutputModeJSON {
+		return utils.WrapInErrInvalidArg("--output / -o",
+			fmt.Errorf("%q is not a valid output format", statusOutput))
+	}
+
+	client, err := k8sutil.NewClientsetFromConfigFlags(utils.KubernetesConfigFlags)
+	if err != nil {
+		return utils.WrapInErrSetupK8sClient(err)
+	}
+
+	pods, err := client.CoreV1().Pods("gadget").List(context.TODO(), metav1.ListOptions{
+		LabelSelector: "k8s-app=gadget",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get gadget pods: %w", err)
+	}
+	if len(pods.Items) == 0 {
+		return errors.New("no gadget pods found")
+	}
+
+	sort.Slice(pods.Items, func(i, j int) bool {
+		return pods.Items[i].Spec.NodeName < pods.Items[j].Spec.NodeName
+	})
+
+	if statusResources {
+		return printResourcesStatus(os.Stdout, pods.Items)
+	}
+
+	traceClient, err := utils.GetTraceClient()
+	if err != nil {
+		return utils.WrapInErrSetupK8sClient(err)
+	}
+	traces, err := traceClient.GadgetV1alpha1().Traces("gadget").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return utils.WrapInErrListGadgetTraces(err)
+	}
+
+	tracesPerNode := map[string]int{}
+	for _, trace := range traces.Items {
+		tracesPerNode[trace.Spec.Node]++
+	}
+
+	type podStatus struct {
+		Node     string `json:"node"`
+		Pod      string `json:"pod"`
+		Phase    string `json:"phase"`
+		Ready    bool   `json:"ready"`
+		Restarts int32  `json:"restarts"`
+		Traces   int    `json:"traces"`
+	}
+
+	statuses := []podStatus{}
+	for _, pod := range pods.Items {
+		s := podStatus{
+			Node:   pod.Spec.NodeName,
+			Pod:    pod.Name,
+			Phase:  string(pod.Status.Phase),
+			Traces: tracesPerNode[pod.Spec.NodeName],
+		}
+		for _, c := range pod.Status.ContainerStatuses {
+			if c.Name == "gadget" {
+				s.Ready = c.Ready
+				s.Restarts = c.RestartCount
+			}
+		}
+		statuses = append(statuses, s)
+	}
+
+	if statusOutput == utils.OutputModeJSON {
+		b, err := json.MarshalIndent(statuses, "", "  ")
+		if err != nil {
+			return utils.WrapInErrMarshalOutput(err)
+		}
+		fmt.Println(string(b))
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NODE\tPOD\tPHASE\tREADY\tRESTARTS\tTRACES")
+	for _, s := range statuses {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%t\t%d\t%d\n", s.Node, s.Pod, s.Phase, s.Ready, s.Restarts, s.Traces)
+	}
+	return w.Flush()
+}
+
+func formatMillicores(m uint64) string {
+	return fmt.Sprintf("%dm", m)
+}
+
+func formatMebibytes(b uint64) string {
+	return fmt.Sprintf("%dMi", (b+resourcelimitstypes.Mebibyte-1)/resourcelimitstypes.Mebibyte)
+}
+
+func formatPercentiles(p resourcelimitstypes.Percentiles, format func(uint64) string) string {
+	return format(p.P50) + "/" + format(p.P95) + "/" + format(p.P99)
+}
+
+func printResourcesStatus(out io.Writer, pods []corev1.Pod) error {
+	status := resourcesStatus{
+		Nodes: []resourcestats.NodeStats{},
+	}
+
+	for _, pod := range pods {
+		data, ok := pod.Annotations[resourcestats.Annotation]
+		if !ok {
+			// Not sampled yet, or deployed by an older version.
+			continue
+		}
+
+		var stats resourcestats.NodeStats
+		if err := json.Unmarshal([]byte(data), &stats); err != nil {
+			return utils.WrapInErrUnmarshalOutput(err, data)
+		}
+		status.Nodes = append(status.Nodes, stats)
+	}
+
+	if len(status.Nodes) == 0 {
+		return errors.New("no resource usage reported by the gadget pods yet, please retry in a few minutes")
+	}
+
+	status.Requests, status.Limits = resourcestats.Recommend(status.Nodes)
+
+	if statusOutput == utils.OutputModeJSON {
+		b, err := json.MarshalIndent(status, "", "  ")
+		if err != nil {
+			return utils.WrapInErrMarshalOutput(err)
+		}
+		fmt.Fprintln(out, string(b))
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "NODE\tSAMPLES\tCPU(P50/P95/P99)\tMEMORY(P50/P95/P99)")
+	for _, n := range status.Nodes {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", n.Node, n.Samples,
+			formatPercentiles(n.CPU, formatMillicores),
+			formatPercentiles(n.Memory, formatMebibytes))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out)
+	fmt.Fprintln(w, "NODE\tGADGET\tSAMPLES\tCPU(+P95)\tMEMORY(+P95)")
+	for _, n := range status.Nodes {
+		for _, g := range n.Gadgets {
+			cpu, memory := "-", "-"
+			if g.Isolated {
+				cpu, memory = formatMillicores(g.CPU), formatMebibytes(g.Memory)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", n.Node, g.Gadget, g.Samples, cpu, memory)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	requests := fmt.Sprintf("cpu=%s,memory=%s", status.Requests.CPU, status.Requests.Memory)
+	limits := fmt.Sprintf("cpu=%s,memory=%s", status.Limits.CPU, status.Limits.Memory)
+
+	fmt.Fprintf(out, "\nRecommended resources for the gadget pods:\n")
+	fmt.Fprintf(out, "  requests: %s\n", requests)
+	fmt.Fprintf(out, "  limits:   %s\n", limits)
+	fmt.Fprintf(out, "To apply them, deploy again with:\n")
+	fmt.Fprintf(out, "  kubectl gadget deploy --requests %s --limits %s | kubectl apply -f -\n", requests, limits)
+
+	return nil
+}
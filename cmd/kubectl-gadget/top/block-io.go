@@ -20,10 +20,8 @@ import (
 	"os"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/spf13/cobra"
-	"golang.org/x/term"
 
 	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/biotop/types"
@@ -51,39 +49,30 @@ var blockIOCmd = &cobra.Command{
 			outputInterval = types.IntervalDefault
 		}
 
+		parameters := map[string]string{
+			types.IntervalParam: strconv.Itoa(outputInterval),
+			types.MaxRowsParam:  strconv.Itoa(maxRows),
+			types.SortByParam:   sortBy,
+		}
+
+		if err := addThresholdParameters(parameters, &types.Stats{}); err != nil {
+			return err
+		}
+
 		config := &utils.TraceConfig{
 			GadgetName:       "biotop",
 			Operation:        "start",
 			TraceOutputMode:  "Stream",
 			TraceOutputState: "Started",
 			CommonFlags:      &params,
-			Parameters: map[string]string{
-				types.IntervalParam: strconv.Itoa(outputInterval),
-				types.MaxRowsParam:  strconv.Itoa(maxRows),
-				types.SortByParam:   sortBy,
-			},
+			Parameters:       parameters,
 		}
 
-		// only wants to run for a given amount of time and print
-		// that result.
-		singleShot := params.Timeout == outputInterval
-
-		// start print loop if this is not a "single shoot" operation
-		if singleShot {
-			blockIOPrintHeader()
-		} else {
-			blockIOStartPrintLoop()
-		}
-
-		if err := utils.RunTraceStreamCallback(config, blockIOCallback); err != nil {
-			return utils.WrapInErrRunGadget(err)
-		}
-
-		if singleShot {
-			blockIOPrintEvents()
-		}
-
-		return nil
+		return runTop(config, &topPrinter{
+			callback:    blockIOCallback,
+			printHeader: blockIOPrintHeader,
+			printEvents: blockIOPrintEvents,
+		})
 	},
 	SilenceUsage: true,
 	PreRunE: func(cmd *cobra.Command, args []string) error {
@@ -100,6 +89,7 @@ var blockIOCmd = &cobra.Command{
 
 func init() {
 	addTopCommand(blockIOCmd, types.MaxRowsDefault, types.SortBySlice)
+	utils.RegisterGadgetCommand(blockIOCmd, "biotop", types.Stats{})
 }
 
 func blockIOCallback(line string, node string) {
@@ -118,38 +108,21 @@ func blockIOCallback(line string, node string) {
 		return
 	}
 
-	blockIONodeStats[node] = event.Stats
-}
+	printWarning(node, event.Warning)
 
-func blockIOStartPrintLoop() {
-	go func() {
-		ticker := time.NewTicker(time.Duration(outputInterval) * time.Second)
-		for {
-			_ = <-ticker.C
-			blockIOPrintHeader()
-			blockIOPrintEvents()
-		}
-	}()
+	blockIONodeStats[node] = event.Stats
 }
 
 func blockIOPrintHeader() {
 	switch params.OutputMode {
 	case utils.OutputModeColumns:
-		if term.IsTerminal(int(os.Stdout.Fd())) {
-			utils.ClearScreen()
-		} else {
-			fmt.Println("")
-		}
+		newInterval()
 
-		fmt.Printf("%-16s %-16s %-16s %-16s %-7s %-16s %-3s %-6s %-6s %-7s %-8s %s\n",
+		fmt.Printf("%-16s %-16s %-16s %-16s %-7s %-16s %-3s %-6s %-6s %-7s %-8s %s%s\n",
 			"NODE", "NAMESPACE", "POD", "CONTAINER",
-			"PID", "COMM", "R/W", "MAJOR", "MINOR", "BYTES", "TIME(µs)", "IOs")
+			"PID", "COMM", "R/W", "MAJOR", "MINOR", "BYTES", "TIME(µs)", "IOs", alertsHeader())
 	case utils.OutputModeCustomColumns:
-		if term.IsTerminal(int(os.Stdout.Fd())) {
-			utils.ClearScreen()
-		} else {
-			fmt.Println("")
-		}
+		newInterval()
 		fmt.Println(blockIOGetCustomColsHeader(params.CustomColumns))
 	}
 }
@@ -171,8 +144,8 @@ func blockIOPrintEvents() {
 	switch params.OutputMode {
 	case utils.OutputModeColumns:
 		for idx, event := range stats {
-			if idx == maxRows {
-				break
+			if idx >= maxRows && len(event.Alerts) == 0 {
+				continue
 			}
 
 			rw := 'R'
@@ -180,10 +153,10 @@ func blockIOPrintEvents() {
 				rw = 'W'
 			}
 
-			fmt.Printf("%-16s %-16s %-16s %-16s %-7d %-16s %-3c %-6d %-6d %-7d %-8d %d\n",
+			fmt.Printf("%-16s %-16s %-16s %-16s %-7d %-16s %-3c %-6d %-6d %-7s %-8s %d%s\n",
 				event.Node, event.Namespace, event.Pod, event.Container,
-				event.Pid, event.Comm, rw, event.Major, event.Minor, event.Bytes,
-				event.MicroSecs, event.Operations)
+				event.Pid, event.Comm, rw, event.Major, event.Minor, formatBytes(event.Bytes, 1),
+				formatMicroseconds(event.MicroSecs), event.Operations, formatAlerts(event.Alerts))
 		}
 	case utils.OutputModeJSON:
 		b, err := json.Marshal(stats)
@@ -194,8 +167,8 @@ func blockIOPrintEvents() {
 		fmt.Println(string(b))
 	case utils.OutputModeCustomColumns:
 		for idx, stat := range stats {
-			if idx == maxRows {
-				break
+			if idx >= maxRows && len(stat.Alerts) == 0 {
+				continue
 			}
 			fmt.Println(blockIOFormatEventCustomCols(&stat, params.CustomColumns))
 		}
@@ -231,6 +204,8 @@ func blockIOGetCustomColsHeader(cols []string) string {
 			sb.WriteString(fmt.Sprintf("%-8s", "TIME(µs)"))
 		case "ios":
 			sb.WriteString(fmt.Sprintf("%-8s", "IOs"))
+		case "alerts":
+			sb.WriteString("ALERTS")
 		}
 		sb.WriteRune(' ')
 	}
@@ -267,11 +242,13 @@ func blockIOFormatEventCustomCols(stats *types.Stats, cols []string) string {
 		case "minor":
 			sb.WriteString(fmt.Sprintf("%-6d", stats.Minor))
 		case "bytes":
-			sb.WriteString(fmt.Sprintf("%-7d", stats.Bytes))
+			sb.WriteString(fmt.Sprintf("%-7s", formatBytes(stats.Bytes, 1)))
 		case "time":
-			sb.WriteString(fmt.Sprintf("%-8d", stats.MicroSecs))
+			sb.WriteString(fmt.Sprintf("%-8s", formatMicroseconds(stats.MicroSecs)))
 		case "ios":
 			sb.WriteString(fmt.Sprintf("%-8d", stats.Operations))
+		case "alerts":
+			sb.WriteString(strings.Join(stats.Alerts, ","))
 		}
 		sb.WriteRune(' ')
 	}
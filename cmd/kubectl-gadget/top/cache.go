@@ -0,0 +1,295 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package top
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/cachestat/types"
+)
+
+var cacheNodeStats map[string][]types.Stats
+
+var (
+	// flags
+	cacheSortBy      types.SortBy
+	cacheFilteredPid uint
+	cachePerProcess  bool
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   fmt.Sprintf("cache [interval=%d]", types.IntervalDefault),
+	Short: "Periodically report page cache hits and misses by container",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var err error
+
+		cacheNodeStats = make(map[string][]types.Stats)
+
+		if len(args) == 1 {
+			outputInterval, err = strconv.Atoi(args[0])
+			if err != nil {
+				return utils.WrapInErrInvalidArg("<interval>",
+					fmt.Errorf("%q is not a valid value", args[0]))
+			}
+		} else {
+			outputInterval = types.IntervalDefault
+		}
+
+		parameters := map[string]string{
+			types.MaxRowsParam:  strconv.Itoa(maxRows),
+			types.IntervalParam: strconv.Itoa(outputInterval),
+			types.SortByParam:   sortBy,
+		}
+
+		if cacheFilteredPid != 0 {
+			parameters[types.PidParam] = strconv.FormatUint(uint64(cacheFilteredPid), 10)
+		}
+		if cachePerProcess {
+			parameters[types.PerProcessParam] = "true"
+		}
+
+		if err := addThresholdParameters(parameters, &types.Stats{}); err != nil {
+			return err
+		}
+
+		config := &utils.TraceConfig{
+			GadgetName:       "cachestat",
+			Operation:        "start",
+			TraceOutputMode:  "Stream",
+			TraceOutputState: "Started",
+			CommonFlags:      &params,
+			Parameters:       parameters,
+		}
+
+		return runTop(config, &topPrinter{
+			callback:    cacheCallback,
+			printHeader: cachePrintHeader,
+			printEvents: cachePrintEvents,
+		})
+	},
+	SilenceUsage: true,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		var err error
+		cacheSortBy, err = types.ParseSortBy(sortBy)
+		if err != nil {
+			return utils.WrapInErrInvalidArg("--sort", err)
+		}
+
+		return nil
+	},
+	Args: cobra.MaximumNArgs(1),
+}
+
+func init() {
+	cacheCmd.PersistentFlags().UintVarP(
+		&cacheFilteredPid,
+		"pid",
+		"",
+		0,
+		"Show only page cache accesses by this particular PID",
+	)
+	cacheCmd.PersistentFlags().BoolVarP(
+		&cachePerProcess,
+		"per-process",
+		"",
+		false,
+		"Show the page cache accesses of each process instead of each container",
+	)
+
+	addTopCommand(cacheCmd, types.MaxRowsDefault, types.SortBySlice)
+	utils.RegisterGadgetCommand(cacheCmd, "cachestat", types.Stats{})
+}
+
+func cacheCallback(line string, node string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	var event types.Event
+
+	if err := json.Unmarshal([]byte(line), &event); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s", utils.WrapInErrUnmarshalOutput(err, line))
+		return
+	}
+
+	if event.Error != "" {
+		fmt.Fprintf(os.Stderr, "Error: failed on node %q: %s", event.Node, event.Error)
+		return
+	}
+
+	printWarning(node, event.Warning)
+
+	cacheNodeStats[node] = event.Stats
+}
+
+func cachePrintHeader() {
+	switch params.OutputMode {
+	case utils.OutputModeColumns:
+		newInterval()
+		fmt.Printf("%-16s %-16s %-16s %-16s %s%-9s %-9s %-9s %-6s%s\n",
+			"NODE", "NAMESPACE", "POD", "CONTAINER", cacheProcessHeader(),
+			"HITS", "MISSES", "DIRTIES", "RATIO", alertsHeader())
+	case utils.OutputModeCustomColumns:
+		newInterval()
+		fmt.Println(cacheGetCustomColsHeader(params.CustomColumns))
+	}
+}
+
+func cachePrintEvents() {
+	// sort and print events
+	mutex.Lock()
+
+	stats := []types.Stats{}
+	for _, stat := range cacheNodeStats {
+		stats = append(stats, stat...)
+	}
+	cacheNodeStats = make(map[string][]types.Stats)
+
+	mutex.Unlock()
+
+	types.SortStats(stats, cacheSortBy)
+
+	switch params.OutputMode {
+	case utils.OutputModeColumns:
+		for idx, event := range stats {
+			if idx >= maxRows && len(event.Alerts) == 0 {
+				continue
+			}
+			fmt.Printf("%-16s %-16s %-16s %-16s %s%-9d %-9d %-9d %-6s%s\n",
+				event.Node, event.Namespace, event.Pod, event.Container,
+				cacheProcess(&event), event.Hits, event.Misses, event.Dirties, formatRatio(&event),
+				formatAlerts(event.Alerts))
+		}
+	case utils.OutputModeJSON:
+		b, err := json.Marshal(stats)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s", utils.WrapInErrMarshalOutput(err))
+			return
+		}
+		fmt.Println(string(b))
+	case utils.OutputModeCustomColumns:
+		for idx, stat := range stats {
+			if idx >= maxRows && len(stat.Alerts) == 0 {
+				continue
+			}
+			fmt.Println(cacheFormatEventCustomCols(&stat, params.CustomColumns))
+		}
+	}
+}
+
+func cacheGetCustomColsHeader(cols []string) string {
+	var sb strings.Builder
+
+	for _, col := range cols {
+		switch col {
+		case "node":
+			sb.WriteString(fmt.Sprintf("%-16s", "NODE"))
+		case "namespace":
+			sb.WriteString(fmt.Sprintf("%-16s", "NAMESPACE"))
+		case "pod":
+			sb.WriteString(fmt.Sprintf("%-16s", "POD"))
+		case "container":
+			sb.WriteString(fmt.Sprintf("%-16s", "CONTAINER"))
+		case "mntns":
+			sb.WriteString(fmt.Sprintf("%-12s", "MNTNS"))
+		case "pid":
+			sb.WriteString(fmt.Sprintf("%-7s", "PID"))
+		case "comm":
+			sb.WriteString(fmt.Sprintf("%-16s", "COMM"))
+		case "hits":
+			sb.WriteString(fmt.Sprintf("%-9s", "HITS"))
+		case "misses":
+			sb.WriteString(fmt.Sprintf("%-9s", "MISSES"))
+		case "dirties":
+			sb.WriteString(fmt.Sprintf("%-9s", "DIRTIES"))
+		case "ratio":
+			sb.WriteString(fmt.Sprintf("%-7s", "RATIO"))
+		case "alerts":
+			sb.WriteString("ALERTS")
+		}
+		sb.WriteRune(' ')
+	}
+
+	return sb.String()
+}
+
+func cacheFormatEventCustomCols(stats *types.Stats, cols []string) string {
+	var sb strings.Builder
+
+	for _, col := range cols {
+		switch col {
+		case "node":
+			sb.WriteString(fmt.Sprintf("%-16s", stats.Node))
+		case "namespace":
+			sb.WriteString(fmt.Sprintf("%-16s", stats.Namespace))
+		case "pod":
+			sb.WriteString(fmt.Sprintf("%-16s", stats.Pod))
+		case "container":
+			sb.WriteString(fmt.Sprintf("%-16s", stats.Container))
+		case "mntns":
+			sb.WriteString(fmt.Sprintf("%-12d", stats.MountNsID))
+		case "pid":
+			sb.WriteString(fmt.Sprintf("%-7d", stats.Pid))
+		case "comm":
+			sb.WriteString(fmt.Sprintf("%-16s", stats.Comm))
+		case "hits":
+			sb.WriteString(fmt.Sprintf("%-9d", stats.Hits))
+		case "misses":
+			sb.WriteString(fmt.Sprintf("%-9d", stats.Misses))
+		case "dirties":
+			sb.WriteString(fmt.Sprintf("%-9d", stats.Dirties))
+		case "ratio":
+			sb.WriteString(fmt.Sprintf("%-7s", formatRatio(stats)))
+		case "alerts":
+			sb.WriteString(strings.Join(stats.Alerts, ","))
+		}
+		sb.WriteRune(' ')
+	}
+
+	return sb.String()
+}
+
+// formatRatio returns the hit ratio of stats, or "-" when there was no
+// access to the page cache during the interval.
+func formatRatio(stats *types.Stats) string {
+	if stats.Hits+stats.Misses == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", stats.Ratio)
+}
+
+// cacheProcessHeader returns the headers of the columns of the processes,
+// which are only printed with --per-process.
+func cacheProcessHeader() string {
+	if !cachePerProcess {
+		return ""
+	}
+	return fmt.Sprintf("%-7s %-16s ", "PID", "COMM")
+}
+
+// cacheProcess returns the columns of the process of stats, which are only
+// printed with --per-process.
+func cacheProcess(stats *types.Stats) string {
+	if !cachePerProcess {
+		return ""
+	}
+	return fmt.Sprintf("%-7d %-16s ", stats.Pid, stats.Comm)
+}
@@ -0,0 +1,258 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package top
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/dcstat/types"
+)
+
+var dcacheNodeStats map[string][]types.Stats
+
+var (
+	// flags
+	dcacheSortBy      types.SortBy
+	dcacheFilteredPid uint
+)
+
+var dcacheCmd = &cobra.Command{
+	Use:   fmt.Sprintf("dcache [interval=%d]", types.IntervalDefault),
+	Short: "Periodically report the dentry cache lookups and hit ratio by container",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var err error
+
+		dcacheNodeStats = make(map[string][]types.Stats)
+
+		if len(args) == 1 {
+			outputInterval, err = strconv.Atoi(args[0])
+			if err != nil {
+				return utils.WrapInErrInvalidArg("<interval>",
+					fmt.Errorf("%q is not a valid value", args[0]))
+			}
+		} else {
+			outputInterval = types.IntervalDefault
+		}
+
+		parameters := map[string]string{
+			types.MaxRowsParam:  strconv.Itoa(maxRows),
+			types.IntervalParam: strconv.Itoa(outputInterval),
+			types.SortByParam:   sortBy,
+		}
+
+		if dcacheFilteredPid != 0 {
+			parameters[types.PidParam] = strconv.FormatUint(uint64(dcacheFilteredPid), 10)
+		}
+
+		if err := addThresholdParameters(parameters, &types.Stats{}); err != nil {
+			return err
+		}
+
+		config := &utils.TraceConfig{
+			GadgetName:       "dcstat",
+			Operation:        "start",
+			TraceOutputMode:  "Stream",
+			TraceOutputState: "Started",
+			CommonFlags:      &params,
+			Parameters:       parameters,
+		}
+
+		return runTop(config, &topPrinter{
+			callback:    dcacheCallback,
+			printHeader: dcachePrintHeader,
+			printEvents: dcachePrintEvents,
+		})
+	},
+	SilenceUsage: true,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		var err error
+		dcacheSortBy, err = types.ParseSortBy(sortBy)
+		if err != nil {
+			return utils.WrapInErrInvalidArg("--sort", err)
+		}
+
+		return nil
+	},
+	Args: cobra.MaximumNArgs(1),
+}
+
+func init() {
+	dcacheCmd.PersistentFlags().UintVarP(
+		&dcacheFilteredPid,
+		"pid",
+		"",
+		0,
+		"Show only the dentry cache lookups made by this particular PID",
+	)
+
+	addTopCommand(dcacheCmd, types.MaxRowsDefault, types.SortBySlice)
+	utils.RegisterGadgetCommand(dcacheCmd, "dcstat", types.Stats{})
+}
+
+func dcacheCallback(line string, node string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	var event types.Event
+
+	if err := json.Unmarshal([]byte(line), &event); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s", utils.WrapInErrUnmarshalOutput(err, line))
+		return
+	}
+
+	if event.Error != "" {
+		fmt.Fprintf(os.Stderr, "Error: failed on node %q: %s", event.Node, event.Error)
+		return
+	}
+
+	printWarning(node, event.Warning)
+
+	dcacheNodeStats[node] = event.Stats
+}
+
+func dcachePrintHeader() {
+	switch params.OutputMode {
+	case utils.OutputModeColumns:
+		newInterval()
+		fmt.Printf("%-16s %-16s %-16s %-16s %-9s %-9s %-9s %-6s%s\n",
+			"NODE", "NAMESPACE", "POD", "CONTAINER",
+			"REFS", "SLOW", "MISSES", "RATIO", alertsHeader())
+	case utils.OutputModeCustomColumns:
+		newInterval()
+		fmt.Println(dcacheGetCustomColsHeader(params.CustomColumns))
+	}
+}
+
+func dcachePrintEvents() {
+	// sort and print events
+	mutex.Lock()
+
+	stats := []types.Stats{}
+	for _, stat := range dcacheNodeStats {
+		stats = append(stats, stat...)
+	}
+	dcacheNodeStats = make(map[string][]types.Stats)
+
+	mutex.Unlock()
+
+	types.SortStats(stats, dcacheSortBy)
+
+	switch params.OutputMode {
+	case utils.OutputModeColumns:
+		for idx, event := range stats {
+			if idx >= maxRows && len(event.Alerts) == 0 {
+				continue
+			}
+			fmt.Printf("%-16s %-16s %-16s %-16s %-9d %-9d %-9d %-6s%s\n",
+				event.Node, event.Namespace, event.Pod, event.Container,
+				event.Refs, event.Slow, event.Misses, dcacheFormatRatio(&event),
+				formatAlerts(event.Alerts))
+		}
+	case utils.OutputModeJSON:
+		b, err := json.Marshal(stats)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s", utils.WrapInErrMarshalOutput(err))
+			return
+		}
+		fmt.Println(string(b))
+	case utils.OutputModeCustomColumns:
+		for idx, stat := range stats {
+			if idx >= maxRows && len(stat.Alerts) == 0 {
+				continue
+			}
+			fmt.Println(dcacheFormatEventCustomCols(&stat, params.CustomColumns))
+		}
+	}
+}
+
+func dcacheGetCustomColsHeader(cols []string) string {
+	var sb strings.Builder
+
+	for _, col := range cols {
+		switch col {
+		case "node":
+			sb.WriteString(fmt.Sprintf("%-16s", "NODE"))
+		case "namespace":
+			sb.WriteString(fmt.Sprintf("%-16s", "NAMESPACE"))
+		case "pod":
+			sb.WriteString(fmt.Sprintf("%-16s", "POD"))
+		case "container":
+			sb.WriteString(fmt.Sprintf("%-16s", "CONTAINER"))
+		case "mntns":
+			sb.WriteString(fmt.Sprintf("%-12s", "MNTNS"))
+		case "refs":
+			sb.WriteString(fmt.Sprintf("%-9s", "REFS"))
+		case "slow":
+			sb.WriteString(fmt.Sprintf("%-9s", "SLOW"))
+		case "misses":
+			sb.WriteString(fmt.Sprintf("%-9s", "MISSES"))
+		case "ratio":
+			sb.WriteString(fmt.Sprintf("%-7s", "RATIO"))
+		case "alerts":
+			sb.WriteString("ALERTS")
+		}
+		sb.WriteRune(' ')
+	}
+
+	return sb.String()
+}
+
+func dcacheFormatEventCustomCols(stats *types.Stats, cols []string) string {
+	var sb strings.Builder
+
+	for _, col := range cols {
+		switch col {
+		case "node":
+			sb.WriteString(fmt.Sprintf("%-16s", stats.Node))
+		case "namespace":
+			sb.WriteString(fmt.Sprintf("%-16s", stats.Namespace))
+		case "pod":
+			sb.WriteString(fmt.Sprintf("%-16s", stats.Pod))
+		case "container":
+			sb.WriteString(fmt.Sprintf("%-16s", stats.Container))
+		case "mntns":
+			sb.WriteString(fmt.Sprintf("%-12d", stats.MountNsID))
+		case "refs":
+			sb.WriteString(fmt.Sprintf("%-9d", stats.Refs))
+		case "slow":
+			sb.WriteString(fmt.Sprintf("%-9d", stats.Slow))
+		case "misses":
+			sb.WriteString(fmt.Sprintf("%-9d", stats.Misses))
+		case "ratio":
+			sb.WriteString(fmt.Sprintf("%-7s", dcacheFormatRatio(stats)))
+		case "alerts":
+			sb.WriteString(strings.Join(stats.Alerts, ","))
+		}
+		sb.WriteRune(' ')
+	}
+
+	return sb.String()
+}
+
+// dcacheFormatRatio returns the hit ratio of stats, or "-" when there was
+// no lookup in the dentry cache during the interval.
+func dcacheFormatRatio(stats *types.Stats) string {
+	if stats.Refs == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", stats.Ratio)
+}
@@ -20,10 +20,8 @@ import (
 	"os"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/spf13/cobra"
-	"golang.org/x/term"
 
 	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/filetop/types"
@@ -55,42 +53,31 @@ var fileCmd = &cobra.Command{
 			outputInterval = types.IntervalDefault
 		}
 
+		parameters := map[string]string{
+			types.MaxRowsParam:  strconv.Itoa(maxRows),
+			types.IntervalParam: strconv.Itoa(outputInterval),
+			types.SortByParam:   sortBy,
+			types.AllFilesParam: strconv.FormatBool(fileAllFiles),
+		}
+
+		if err := addThresholdParameters(parameters, &types.Stats{}); err != nil {
+			return err
+		}
+
 		config := &utils.TraceConfig{
 			GadgetName:       "filetop",
 			Operation:        "start",
 			TraceOutputMode:  "Stream",
 			TraceOutputState: "Started",
 			CommonFlags:      &params,
-			Parameters: map[string]string{
-				types.MaxRowsParam:  strconv.Itoa(maxRows),
-				types.IntervalParam: strconv.Itoa(outputInterval),
-				types.SortByParam:   sortBy,
-				types.AllFilesParam: strconv.FormatBool(fileAllFiles),
-			},
-		}
-
-		// when params.Timeout == interval it means the user
-		// only wants to run for a given amount of time and print
-		// that result.
-		singleShot := params.Timeout == outputInterval
-
-		// start print loop if this is not a "single shoot" operation
-		if singleShot {
-			filePrintHeader()
-		} else {
-			fileStartOutputLoop()
-		}
-
-		err = utils.RunTraceStreamCallback(config, fileCallback)
-		if err != nil {
-			return utils.WrapInErrRunGadget(err)
+			Parameters:       parameters,
 		}
 
-		if singleShot {
-			filePrintEvents()
-		}
-
-		return nil
+		return runTop(config, &topPrinter{
+			callback:    fileCallback,
+			printHeader: filePrintHeader,
+			printEvents: filePrintEvents,
+		})
 	},
 	SilenceUsage: true,
 	PreRunE: func(cmd *cobra.Command, args []string) error {
@@ -109,6 +96,7 @@ func init() {
 	fileCmd.Flags().BoolVarP(&fileAllFiles, "all-files", "a", types.AllFilesDefault, "Include non-regular file types (sockets, FIFOs, etc)")
 
 	addTopCommand(fileCmd, types.MaxRowsDefault, types.SortBySlice)
+	utils.RegisterGadgetCommand(fileCmd, "filetop", types.Stats{})
 }
 
 func fileCallback(line string, node string) {
@@ -127,38 +115,20 @@ func fileCallback(line string, node string) {
 		return
 	}
 
-	fileNodeStats[node] = event.Stats
-}
+	printWarning(node, event.Warning)
 
-func fileStartOutputLoop() {
-	go func() {
-		ticker := time.NewTicker(time.Duration(outputInterval) * time.Second)
-		filePrintHeader()
-		for {
-			_ = <-ticker.C
-			filePrintHeader()
-			filePrintEvents()
-		}
-	}()
+	fileNodeStats[node] = event.Stats
 }
 
 func filePrintHeader() {
 	switch params.OutputMode {
 	case utils.OutputModeColumns:
-		if term.IsTerminal(int(os.Stdout.Fd())) {
-			utils.ClearScreen()
-		} else {
-			fmt.Println("")
-		}
-		fmt.Printf("%-16s %-16s %-16s %-16s %-7s %-16s %-6s %-6s %-7s %-7s %1s %s\n",
+		newInterval()
+		fmt.Printf("%-16s %-16s %-16s %-16s %-7s %-16s %-6s %-6s %-7s %-7s %1s %s%s\n",
 			"NODE", "NAMESPACE", "POD", "CONTAINER",
-			"PID", "COMM", "READS", "WRITES", "R_Kb", "W_Kb", "T", "FILE")
+			"PID", "COMM", "READS", "WRITES", "R_Kb", "W_Kb", "T", "FILE", alertsHeader())
 	case utils.OutputModeCustomColumns:
-		if term.IsTerminal(int(os.Stdout.Fd())) {
-			utils.ClearScreen()
-		} else {
-			fmt.Println("")
-		}
+		newInterval()
 		fmt.Println(fileGetCustomColsHeader(params.CustomColumns))
 	}
 }
@@ -180,13 +150,14 @@ func filePrintEvents() {
 	switch params.OutputMode {
 	case utils.OutputModeColumns:
 		for idx, event := range stats {
-			if idx == maxRows {
-				break
+			if idx >= maxRows && len(event.Alerts) == 0 {
+				continue
 			}
-			fmt.Printf("%-16s %-16s %-16s %-16s %-7d %-16s %-6d %-6d %-7d %-7d %c %s\n",
+			fmt.Printf("%-16s %-16s %-16s %-16s %-7d %-16s %-6d %-6d %-7s %-7s %c %s%s\n",
 				event.Node, event.Namespace, event.Pod, event.Container,
-				event.Pid, event.Comm, event.Reads, event.Writes, event.ReadBytes/1024,
-				event.WriteBytes/1024, event.FileType, event.Filename)
+				event.Pid, event.Comm, event.Reads, event.Writes, formatBytes(event.ReadBytes, 1024),
+				formatBytes(event.WriteBytes, 1024), event.FileType, event.Filename,
+				formatAlerts(event.Alerts))
 		}
 	case utils.OutputModeJSON:
 		b, err := json.Marshal(stats)
@@ -197,8 +168,8 @@ func filePrintEvents() {
 		fmt.Println(string(b))
 	case utils.OutputModeCustomColumns:
 		for idx, stat := range stats {
-			if idx == maxRows {
-				break
+			if idx >= maxRows && len(stat.Alerts) == 0 {
+				continue
 			}
 			fmt.Println(fileFormatEventCostumCols(&stat, params.CustomColumns))
 		}
@@ -236,6 +207,8 @@ func fileGetCustomColsHeader(cols []string) string {
 			sb.WriteString(fmt.Sprintf("%s", "T"))
 		case "file":
 			sb.WriteString(fmt.Sprintf("%s", "FILE"))
+		case "alerts":
+			sb.WriteString("ALERTS")
 		}
 		sb.WriteRune(' ')
 	}
@@ -267,13 +240,15 @@ func fileFormatEventCostumCols(stats *types.Stats, cols []string) string {
 		case "writes":
 			sb.WriteString(fmt.Sprintf("%-6d", stats.Writes))
 		case "r_kb":
-			sb.WriteString(fmt.Sprintf("%-7d", stats.ReadBytes))
+			sb.WriteString(fmt.Sprintf("%-7s", formatBytes(stats.ReadBytes, 1)))
 		case "w_kb":
-			sb.WriteString(fmt.Sprintf("%-7d", stats.WriteBytes))
+			sb.WriteString(fmt.Sprintf("%-7s", formatBytes(stats.WriteBytes, 1)))
 		case "t":
 			sb.WriteString(fmt.Sprintf("%c", stats.FileType))
 		case "file":
 			sb.WriteString(fmt.Sprintf("%s", stats.Filename))
+		case "alerts":
+			sb.WriteString(strings.Join(stats.Alerts, ","))
 		}
 		sb.WriteRune(' ')
 	}
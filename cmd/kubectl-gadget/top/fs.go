@@ -0,0 +1,258 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package top
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/fstop/types"
+)
+
+var fsNodeStats map[string][]types.Stats
+
+var (
+	// flags
+	fsSortBy      types.SortBy
+	fsFilteredPid uint
+)
+
+var fsCmd = &cobra.Command{
+	Use:   fmt.Sprintf("fs [interval=%d]", types.IntervalDefault),
+	Short: "Periodically report filesystem activity by container",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var err error
+
+		fsNodeStats = make(map[string][]types.Stats)
+
+		if len(args) == 1 {
+			outputInterval, err = strconv.Atoi(args[0])
+			if err != nil {
+				return utils.WrapInErrInvalidArg("<interval>",
+					fmt.Errorf("%q is not a valid value", args[0]))
+			}
+		} else {
+			outputInterval = types.IntervalDefault
+		}
+
+		parameters := map[string]string{
+			types.MaxRowsParam:  strconv.Itoa(maxRows),
+			types.IntervalParam: strconv.Itoa(outputInterval),
+			types.SortByParam:   sortBy,
+		}
+
+		if fsFilteredPid != 0 {
+			parameters[types.PidParam] = strconv.FormatUint(uint64(fsFilteredPid), 10)
+		}
+
+		if err := addThresholdParameters(parameters, &types.Stats{}); err != nil {
+			return err
+		}
+
+		config := &utils.TraceConfig{
+			GadgetName:       "fstop",
+			Operation:        "start",
+			TraceOutputMode:  "Stream",
+			TraceOutputState: "Started",
+			CommonFlags:      &params,
+			Parameters:       parameters,
+		}
+
+		return runTop(config, &topPrinter{
+			callback:    fsCallback,
+			printHeader: fsPrintHeader,
+			printEvents: fsPrintEvents,
+		})
+	},
+	SilenceUsage: true,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		var err error
+		fsSortBy, err = types.ParseSortBy(sortBy)
+		if err != nil {
+			return utils.WrapInErrInvalidArg("--sort", err)
+		}
+
+		return nil
+	},
+	Args: cobra.MaximumNArgs(1),
+}
+
+func init() {
+	fsCmd.PersistentFlags().UintVarP(
+		&fsFilteredPid,
+		"pid",
+		"",
+		0,
+		"Show only filesystem activity generated by this particular PID",
+	)
+
+	addTopCommand(fsCmd, types.MaxRowsDefault, types.SortBySlice)
+	utils.RegisterGadgetCommand(fsCmd, "fstop", types.Stats{})
+}
+
+func fsCallback(line string, node string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	var event types.Event
+
+	if err := json.Unmarshal([]byte(line), &event); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s", utils.WrapInErrUnmarshalOutput(err, line))
+		return
+	}
+
+	if event.Error != "" {
+		fmt.Fprintf(os.Stderr, "Error: failed on node %q: %s", event.Node, event.Error)
+		return
+	}
+
+	printWarning(node, event.Warning)
+
+	fsNodeStats[node] = event.Stats
+}
+
+func fsPrintHeader() {
+	switch params.OutputMode {
+	case utils.OutputModeColumns:
+		newInterval()
+		fmt.Printf("%-16s %-16s %-16s %-16s %-7s %-7s %-8s %-8s %-7s %s%s\n",
+			"NODE", "NAMESPACE", "POD", "CONTAINER",
+			"READS", "WRITES", "R_KB", "W_KB", "OPENS", "FSYNCS", alertsHeader())
+	case utils.OutputModeCustomColumns:
+		newInterval()
+		fmt.Println(fsGetCustomColsHeader(params.CustomColumns))
+	}
+}
+
+func fsPrintEvents() {
+	// sort and print events
+	mutex.Lock()
+
+	stats := []types.Stats{}
+	for _, stat := range fsNodeStats {
+		stats = append(stats, stat...)
+	}
+	fsNodeStats = make(map[string][]types.Stats)
+
+	mutex.Unlock()
+
+	types.SortStats(stats, fsSortBy)
+
+	switch params.OutputMode {
+	case utils.OutputModeColumns:
+		for idx, event := range stats {
+			if idx >= maxRows && len(event.Alerts) == 0 {
+				continue
+			}
+			fmt.Printf("%-16s %-16s %-16s %-16s %-7d %-7d %-8s %-8s %-7d %d%s\n",
+				event.Node, event.Namespace, event.Pod, event.Container,
+				event.Reads, event.Writes, formatBytes(event.ReadBytes, 1024),
+				formatBytes(event.WriteBytes, 1024), event.Opens, event.Fsyncs,
+				formatAlerts(event.Alerts))
+		}
+	case utils.OutputModeJSON:
+		b, err := json.Marshal(stats)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s", utils.WrapInErrMarshalOutput(err))
+			return
+		}
+		fmt.Println(string(b))
+	case utils.OutputModeCustomColumns:
+		for idx, stat := range stats {
+			if idx >= maxRows && len(stat.Alerts) == 0 {
+				continue
+			}
+			fmt.Println(fsFormatEventCustomCols(&stat, params.CustomColumns))
+		}
+	}
+}
+
+func fsGetCustomColsHeader(cols []string) string {
+	var sb strings.Builder
+
+	for _, col := range cols {
+		switch col {
+		case "node":
+			sb.WriteString(fmt.Sprintf("%-16s", "NODE"))
+		case "namespace":
+			sb.WriteString(fmt.Sprintf("%-16s", "NAMESPACE"))
+		case "pod":
+			sb.WriteString(fmt.Sprintf("%-16s", "POD"))
+		case "container":
+			sb.WriteString(fmt.Sprintf("%-16s", "CONTAINER"))
+		case "mntns":
+			sb.WriteString(fmt.Sprintf("%-12s", "MNTNS"))
+		case "reads":
+			sb.WriteString(fmt.Sprintf("%-7s", "READS"))
+		case "writes":
+			sb.WriteString(fmt.Sprintf("%-7s", "WRITES"))
+		case "r_kb":
+			sb.WriteString(fmt.Sprintf("%-8s", "R_KB"))
+		case "w_kb":
+			sb.WriteString(fmt.Sprintf("%-8s", "W_KB"))
+		case "opens":
+			sb.WriteString(fmt.Sprintf("%-7s", "OPENS"))
+		case "fsyncs":
+			sb.WriteString(fmt.Sprintf("%-7s", "FSYNCS"))
+		case "alerts":
+			sb.WriteString("ALERTS")
+		}
+		sb.WriteRune(' ')
+	}
+
+	return sb.String()
+}
+
+func fsFormatEventCustomCols(stats *types.Stats, cols []string) string {
+	var sb strings.Builder
+
+	for _, col := range cols {
+		switch col {
+		case "node":
+			sb.WriteString(fmt.Sprintf("%-16s", stats.Node))
+		case "namespace":
+			sb.WriteString(fmt.Sprintf("%-16s", stats.Namespace))
+		case "pod":
+			sb.WriteString(fmt.Sprintf("%-16s", stats.Pod))
+		case "container":
+			sb.WriteString(fmt.Sprintf("%-16s", stats.Container))
+		case "mntns":
+			sb.WriteString(fmt.Sprintf("%-12d", stats.MountNsID))
+		case "reads":
+			sb.WriteString(fmt.Sprintf("%-7d", stats.Reads))
+		case "writes":
+			sb.WriteString(fmt.Sprintf("%-7d", stats.Writes))
+		case "r_kb":
+			sb.WriteString(fmt.Sprintf("%-8s", formatBytes(stats.ReadBytes, 1024)))
+		case "w_kb":
+			sb.WriteString(fmt.Sprintf("%-8s", formatBytes(stats.WriteBytes, 1024)))
+		case "opens":
+			sb.WriteString(fmt.Sprintf("%-7d", stats.Opens))
+		case "fsyncs":
+			sb.WriteString(fmt.Sprintf("%-7d", stats.Fsyncs))
+		case "alerts":
+			sb.WriteString(strings.Join(stats.Alerts, ","))
+		}
+		sb.WriteRune(' ')
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,243 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package top
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/grpctop/types"
+)
+
+var grpcNodeStats map[string][]types.Stats
+
+// flags
+var grpcSortBy types.SortBy
+
+var grpcCmd = &cobra.Command{
+	Use:   fmt.Sprintf("grpc [interval=%d]", types.IntervalDefault),
+	Short: "Periodically report the HTTP/2 streams, resets and goaways by server",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var err error
+
+		grpcNodeStats = make(map[string][]types.Stats)
+
+		if len(args) == 1 {
+			outputInterval, err = strconv.Atoi(args[0])
+			if err != nil {
+				return utils.WrapInErrInvalidArg("<interval>",
+					fmt.Errorf("%q is not a valid value", args[0]))
+			}
+		} else {
+			outputInterval = types.IntervalDefault
+		}
+
+		parameters := map[string]string{
+			types.MaxRowsParam:  strconv.Itoa(maxRows),
+			types.IntervalParam: strconv.Itoa(outputInterval),
+			types.SortByParam:   sortBy,
+		}
+
+		if err := addThresholdParameters(parameters, &types.Stats{}); err != nil {
+			return err
+		}
+
+		config := &utils.TraceConfig{
+			GadgetName:       "grpctop",
+			Operation:        "start",
+			TraceOutputMode:  "Stream",
+			TraceOutputState: "Started",
+			CommonFlags:      &params,
+			Parameters:       parameters,
+		}
+
+		return runTop(config, &topPrinter{
+			callback:    grpcCallback,
+			printHeader: grpcPrintHeader,
+			printEvents: grpcPrintEvents,
+		})
+	},
+	SilenceUsage: true,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		var err error
+		grpcSortBy, err = types.ParseSortBy(sortBy)
+		if err != nil {
+			return utils.WrapInErrInvalidArg("--sort", err)
+		}
+
+		return nil
+	},
+	Args: cobra.MaximumNArgs(1),
+}
+
+func init() {
+	addTopCommand(grpcCmd, types.MaxRowsDefault, types.SortBySlice)
+	utils.RegisterGadgetCommand(grpcCmd, "grpctop", types.Stats{})
+}
+
+func grpcCallback(line string, node string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	var event types.Event
+
+	if err := json.Unmarshal([]byte(line), &event); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s", utils.WrapInErrUnmarshalOutput(err, line))
+		return
+	}
+
+	if event.Error != "" {
+		fmt.Fprintf(os.Stderr, "Error: failed on node %q: %s", event.Node, event.Error)
+		return
+	}
+
+	printWarning(node, event.Warning)
+
+	grpcNodeStats[node] = event.Stats
+}
+
+func grpcPrintHeader() {
+	switch params.OutputMode {
+	case utils.OutputModeColumns:
+		newInterval()
+		fmt.Printf("%-16s %-16s %-16s %-22s %-7s %-9s %-7s %-7s%s\n",
+			"NODE", "NAMESPACE", "POD", "SERVER",
+			"CONNS", "STREAMS", "RESETS", "GOAWAYS", alertsHeader())
+	case utils.OutputModeCustomColumns:
+		newInterval()
+		fmt.Println(grpcGetCustomColsHeader(params.CustomColumns))
+	}
+}
+
+func grpcPrintEvents() {
+	// sort and print events
+	mutex.Lock()
+
+	stats := []types.Stats{}
+	for _, stat := range grpcNodeStats {
+		stats = append(stats, stat...)
+	}
+	grpcNodeStats = make(map[string][]types.Stats)
+
+	mutex.Unlock()
+
+	types.SortStats(stats, grpcSortBy)
+
+	switch params.OutputMode {
+	case utils.OutputModeColumns:
+		for idx, event := range stats {
+			if idx >= maxRows && len(event.Alerts) == 0 {
+				continue
+			}
+			fmt.Printf("%-16s %-16s %-16s %-22s %-7d %-9d %-7d %-7d%s\n",
+				event.Node, event.Namespace, event.Pod, grpcServer(&event),
+				event.Connections, event.Streams, event.Resets, event.GoAways,
+				formatAlerts(event.Alerts))
+		}
+	case utils.OutputModeJSON:
+		b, err := json.Marshal(stats)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s", utils.WrapInErrMarshalOutput(err))
+			return
+		}
+		fmt.Println(string(b))
+	case utils.OutputModeCustomColumns:
+		for idx, stat := range stats {
+			if idx >= maxRows && len(stat.Alerts) == 0 {
+				continue
+			}
+			fmt.Println(grpcFormatEventCustomCols(&stat, params.CustomColumns))
+		}
+	}
+}
+
+func grpcServer(stats *types.Stats) string {
+	return net.JoinHostPort(stats.Daddr, strconv.Itoa(int(stats.Dport)))
+}
+
+func grpcGetCustomColsHeader(cols []string) string {
+	var sb strings.Builder
+
+	for _, col := range cols {
+		switch col {
+		case "node":
+			sb.WriteString(fmt.Sprintf("%-16s", "NODE"))
+		case "namespace":
+			sb.WriteString(fmt.Sprintf("%-16s", "NAMESPACE"))
+		case "pod":
+			sb.WriteString(fmt.Sprintf("%-16s", "POD"))
+		case "server":
+			sb.WriteString(fmt.Sprintf("%-22s", "SERVER"))
+		case "daddr":
+			sb.WriteString(fmt.Sprintf("%-16s", "DADDR"))
+		case "dport":
+			sb.WriteString(fmt.Sprintf("%-7s", "DPORT"))
+		case "connections":
+			sb.WriteString(fmt.Sprintf("%-7s", "CONNS"))
+		case "streams":
+			sb.WriteString(fmt.Sprintf("%-9s", "STREAMS"))
+		case "resets":
+			sb.WriteString(fmt.Sprintf("%-7s", "RESETS"))
+		case "goaways":
+			sb.WriteString(fmt.Sprintf("%-7s", "GOAWAYS"))
+		case "alerts":
+			sb.WriteString("ALERTS")
+		}
+		sb.WriteRune(' ')
+	}
+
+	return sb.String()
+}
+
+func grpcFormatEventCustomCols(stats *types.Stats, cols []string) string {
+	var sb strings.Builder
+
+	for _, col := range cols {
+		switch col {
+		case "node":
+			sb.WriteString(fmt.Sprintf("%-16s", stats.Node))
+		case "namespace":
+			sb.WriteString(fmt.Sprintf("%-16s", stats.Namespace))
+		case "pod":
+			sb.WriteString(fmt.Sprintf("%-16s", stats.Pod))
+		case "server":
+			sb.WriteString(fmt.Sprintf("%-22s", grpcServer(stats)))
+		case "daddr":
+			sb.WriteString(fmt.Sprintf("%-16s", stats.Daddr))
+		case "dport":
+			sb.WriteString(fmt.Sprintf("%-7d", stats.Dport))
+		case "connections":
+			sb.WriteString(fmt.Sprintf("%-7d", stats.Connections))
+		case "streams":
+			sb.WriteString(fmt.Sprintf("%-9d", stats.Streams))
+		case "resets":
+			sb.WriteString(fmt.Sprintf("%-7d", stats.Resets))
+		case "goaways":
+			sb.WriteString(fmt.Sprintf("%-7d", stats.GoAways))
+		case "alerts":
+			sb.WriteString(strings.Join(stats.Alerts, ","))
+		}
+		sb.WriteRune(' ')
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,115 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package top
+
+import (
+	"strings"
+)
+
+// sparkTicks are the characters of the sparklines, from the lowest value to
+// the highest one.
+var sparkTicks = []rune("▁▂▃▄▅▆▇█")
+
+// history keeps the values of the rows of the last intervals, to show their
+// trend even when the output isn't a terminal.
+type history struct {
+	size int
+
+	// values contains the values of each row in the last intervals, the
+	// oldest first. It has at most size values.
+	values map[string][]uint64
+}
+
+func newHistory(size int) *history {
+	return &history{
+		size:   size,
+		values: make(map[string][]uint64),
+	}
+}
+
+// add records the values of the rows in a new interval. The rows missing
+// from the interval get a zero value, and are forgotten once they only have
+// zero values.
+func (h *history) add(values map[string]uint64) {
+	for key, row := range h.values {
+		if _, ok := values[key]; ok {
+			continue
+		}
+		row = h.push(row, 0)
+		if isZero(row) {
+			delete(h.values, key)
+			continue
+		}
+		h.values[key] = row
+	}
+
+	for key, value := range values {
+		h.values[key] = h.push(h.values[key], value)
+	}
+}
+
+func (h *history) push(row []uint64, value uint64) []uint64 {
+	row = append(row, value)
+	if len(row) > h.size {
+		row = row[len(row)-h.size:]
+	}
+	return row
+}
+
+func isZero(row []uint64) bool {
+	for _, v := range row {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// sparkline returns the trend of the row over the last intervals, scaled to
+// its highest value. It's padded on the left to the size of the history.
+func (h *history) sparkline(key string) string {
+	row := h.values[key]
+
+	var max uint64
+	for _, v := range row {
+		if v > max {
+			max = v
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(strings.Repeat(" ", h.size-len(row)))
+	for _, v := range row {
+		tick := 0
+		if max > 0 {
+			tick = int(v * uint64(len(sparkTicks)-1) / max)
+		}
+		sb.WriteRune(sparkTicks[tick])
+	}
+	return sb.String()
+}
+
+// delta returns the change of the value of the row since the previous
+// interval.
+func (h *history) delta(key string) int64 {
+	row := h.values[key]
+	switch len(row) {
+	case 0:
+		return 0
+	case 1:
+		return int64(row[0])
+	}
+	return int64(row[len(row)-1]) - int64(row[len(row)-2])
+}
@@ -0,0 +1,59 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package top
+
+import (
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	h := newHistory(4)
+
+	h.add(map[string]uint64{"a": 0, "b": 10})
+	h.add(map[string]uint64{"a": 70, "b": 5})
+	h.add(map[string]uint64{"a": 35})
+
+	if s := h.sparkline("a"); s != " ▁█▄" {
+		t.Errorf("expected sparkline %q for a, got %q", " ▁█▄", s)
+	}
+	if s := h.sparkline("b"); s != " █▄▁" {
+		t.Errorf("expected sparkline %q for b, got %q", " █▄▁", s)
+	}
+	if d := h.delta("a"); d != -35 {
+		t.Errorf("expected delta -35 for a, got %d", d)
+	}
+	if d := h.delta("b"); d != -5 {
+		t.Errorf("expected delta -5 for b, got %d", d)
+	}
+
+	h.add(map[string]uint64{"a": 1, "c": 3})
+	h.add(map[string]uint64{"a": 1})
+
+	if s := h.sparkline("a"); s != "█▄▁▁" {
+		t.Errorf("expected sparkline %q for a, got %q", "█▄▁▁", s)
+	}
+	if d := h.delta("c"); d != -3 {
+		t.Errorf("expected delta -3 for c, got %d", d)
+	}
+
+	h.add(map[string]uint64{"a": 1})
+
+	if _, ok := h.values["b"]; ok {
+		t.Errorf("expected b to be forgotten after 4 intervals without values")
+	}
+	if s := h.sparkline("unknown"); s != "    " {
+		t.Errorf("expected empty sparkline for an unknown row, got %q", s)
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package top
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/seccomptop/types"
+)
+
+var seccompNodeStats map[string][]types.Stats
+
+var (
+	// flags
+	seccompSortBy types.SortBy
+)
+
+var seccompCmd = &cobra.Command{
+	Use:   fmt.Sprintf("seccomp [interval=%d]", types.IntervalDefault),
+	Short: "Periodically report the syscalls audited by seccomp by pod, profile and syscall",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var err error
+
+		seccompNodeStats = make(map[string][]types.Stats)
+
+		if len(args) == 1 {
+			outputInterval, err = strconv.Atoi(args[0])
+			if err != nil {
+				return utils.WrapInErrInvalidArg("<interval>",
+					fmt.Errorf("%q is not a valid value", args[0]))
+			}
+		} else {
+			outputInterval = types.IntervalDefault
+		}
+
+		parameters := map[string]string{
+			types.MaxRowsParam:  strconv.Itoa(maxRows),
+			types.IntervalParam: strconv.Itoa(outputInterval),
+			types.SortByParam:   sortBy,
+		}
+
+		if err := addThresholdParameters(parameters, &types.Stats{}); err != nil {
+			return err
+		}
+
+		config := &utils.TraceConfig{
+			GadgetName:       "seccomptop",
+			Operation:        "start",
+			TraceOutputMode:  "Stream",
+			TraceOutputState: "Started",
+			CommonFlags:      &params,
+			Parameters:       parameters,
+		}
+
+		return runTop(config, &topPrinter{
+			callback:    seccompCallback,
+			printHeader: seccompPrintHeader,
+			printEvents: seccompPrintEvents,
+		})
+	},
+	SilenceUsage: true,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		var err error
+		seccompSortBy, err = types.ParseSortBy(sortBy)
+		if err != nil {
+			return utils.WrapInErrInvalidArg("--sort", err)
+		}
+
+		return nil
+	},
+	Args: cobra.MaximumNArgs(1),
+}
+
+func init() {
+	addTopCommand(seccompCmd, types.MaxRowsDefault, types.SortBySlice)
+	utils.RegisterGadgetCommand(seccompCmd, "seccomptop", types.Stats{})
+}
+
+func seccompCallback(line string, node string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	var event types.Event
+
+	if err := json.Unmarshal([]byte(line), &event); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s", utils.WrapInErrUnmarshalOutput(err, line))
+		return
+	}
+
+	if event.Error != "" {
+		fmt.Fprintf(os.Stderr, "Error: failed on node %q: %s", event.Node, event.Error)
+		return
+	}
+
+	printWarning(node, event.Warning)
+
+	seccompNodeStats[node] = event.Stats
+}
+
+func seccompPrintHeader() {
+	switch params.OutputMode {
+	case utils.OutputModeColumns:
+		newInterval()
+		fmt.Printf("%-16s %-16s %-16s %-16s %-32s %-16s %-14s %-7s%s\n",
+			"NODE", "NAMESPACE", "POD", "CONTAINER",
+			"PROFILE", "SYSCALL", "CODE", "COUNT", alertsHeader())
+	case utils.OutputModeCustomColumns:
+		newInterval()
+		fmt.Println(seccompGetCustomColsHeader(params.CustomColumns))
+	}
+}
+
+func seccompPrintEvents() {
+	// sort and print events
+	mutex.Lock()
+
+	stats := []types.Stats{}
+	for _, stat := range seccompNodeStats {
+		stats = append(stats, stat...)
+	}
+	seccompNodeStats = make(map[string][]types.Stats)
+
+	mutex.Unlock()
+
+	types.SortStats(stats, seccompSortBy)
+
+	switch params.OutputMode {
+	case utils.OutputModeColumns:
+		for idx, event := range stats {
+			if idx >= maxRows && len(event.Alerts) == 0 {
+				continue
+			}
+			fmt.Printf("%-16s %-16s %-16s %-16s %-32s %-16s %-14s %-7d%s\n",
+				event.Node, event.Namespace, event.Pod, event.Container,
+				event.Profile, event.Syscall, event.Code, event.Count,
+				formatAlerts(event.Alerts))
+		}
+	case utils.OutputModeJSON:
+		b, err := json.Marshal(stats)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s", utils.WrapInErrMarshalOutput(err))
+			return
+		}
+		fmt.Println(string(b))
+	case utils.OutputModeCustomColumns:
+		for idx, stat := range stats {
+			if idx >= maxRows && len(stat.Alerts) == 0 {
+				continue
+			}
+			fmt.Println(seccompFormatEventCustomCols(&stat, params.CustomColumns))
+		}
+	}
+}
+
+func seccompGetCustomColsHeader(cols []string) string {
+	var sb strings.Builder
+
+	for _, col := range cols {
+		switch col {
+		case "node":
+			sb.WriteString(fmt.Sprintf("%-16s", "NODE"))
+		case "namespace":
+			sb.WriteString(fmt.Sprintf("%-16s", "NAMESPACE"))
+		case "pod":
+			sb.WriteString(fmt.Sprintf("%-16s", "POD"))
+		case "container":
+			sb.WriteString(fmt.Sprintf("%-16s", "CONTAINER"))
+		case "profile":
+			sb.WriteString(fmt.Sprintf("%-32s", "PROFILE"))
+		case "syscall":
+			sb.WriteString(fmt.Sprintf("%-16s", "SYSCALL"))
+		case "code":
+			sb.WriteString(fmt.Sprintf("%-14s", "CODE"))
+		case "count":
+			sb.WriteString(fmt.Sprintf("%-7s", "COUNT"))
+		case "alerts":
+			sb.WriteString("ALERTS")
+		}
+		sb.WriteRune(' ')
+	}
+
+	return sb.String()
+}
+
+func seccompFormatEventCustomCols(stats *types.Stats, cols []string) string {
+	var sb strings.Builder
+
+	for _, col := range cols {
+		switch col {
+		case "node":
+			sb.WriteString(fmt.Sprintf("%-16s", stats.Node))
+		case "namespace":
+			sb.WriteString(fmt.Sprintf("%-16s", stats.Namespace))
+		case "pod":
+			sb.WriteString(fmt.Sprintf("%-16s", stats.Pod))
+		case "container":
+			sb.WriteString(fmt.Sprintf("%-16s", stats.Container))
+		case "profile":
+			sb.WriteString(fmt.Sprintf("%-32s", stats.Profile))
+		case "syscall":
+			sb.WriteString(fmt.Sprintf("%-16s", stats.Syscall))
+		case "code":
+			sb.WriteString(fmt.Sprintf("%-14s", stats.Code))
+		case "count":
+			sb.WriteString(fmt.Sprintf("%-7d", stats.Count))
+		case "alerts":
+			sb.WriteString(strings.Join(stats.Alerts, ","))
+		}
+		sb.WriteRune(' ')
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,234 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package top
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/stealtop/types"
+)
+
+var stealNodeStats map[string][]types.Stats
+
+// flags
+var stealSortBy types.SortBy
+
+var stealCmd = &cobra.Command{
+	Use:   fmt.Sprintf("steal [interval=%d]", types.IntervalDefault),
+	Short: "Periodically report the CPU time stolen by the hypervisor by container",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var err error
+
+		stealNodeStats = make(map[string][]types.Stats)
+
+		if len(args) == 1 {
+			outputInterval, err = strconv.Atoi(args[0])
+			if err != nil {
+				return utils.WrapInErrInvalidArg("<interval>",
+					fmt.Errorf("%q is not a valid value", args[0]))
+			}
+		} else {
+			outputInterval = types.IntervalDefault
+		}
+
+		parameters := map[string]string{
+			types.MaxRowsParam:  strconv.Itoa(maxRows),
+			types.IntervalParam: strconv.Itoa(outputInterval),
+			types.SortByParam:   sortBy,
+		}
+
+		if err := addThresholdParameters(parameters, &types.Stats{}); err != nil {
+			return err
+		}
+
+		config := &utils.TraceConfig{
+			GadgetName:       "stealtop",
+			Operation:        "start",
+			TraceOutputMode:  "Stream",
+			TraceOutputState: "Started",
+			CommonFlags:      &params,
+			Parameters:       parameters,
+		}
+
+		return runTop(config, &topPrinter{
+			callback:    stealCallback,
+			printHeader: stealPrintHeader,
+			printEvents: stealPrintEvents,
+		})
+	},
+	SilenceUsage: true,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		var err error
+		stealSortBy, err = types.ParseSortBy(sortBy)
+		if err != nil {
+			return utils.WrapInErrInvalidArg("--sort", err)
+		}
+
+		return nil
+	},
+	Args: cobra.MaximumNArgs(1),
+}
+
+func init() {
+	addTopCommand(stealCmd, types.MaxRowsDefault, types.SortBySlice)
+	utils.RegisterGadgetCommand(stealCmd, "stealtop", types.Stats{})
+}
+
+func stealCallback(line string, node string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	var event types.Event
+
+	if err := json.Unmarshal([]byte(line), &event); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s", utils.WrapInErrUnmarshalOutput(err, line))
+		return
+	}
+
+	if event.Error != "" {
+		fmt.Fprintf(os.Stderr, "Error: failed on node %q: %s", event.Node, event.Error)
+		return
+	}
+
+	printWarning(node, event.Warning)
+
+	stealNodeStats[node] = event.Stats
+}
+
+func stealPrintHeader() {
+	switch params.OutputMode {
+	case utils.OutputModeColumns:
+		newInterval()
+		fmt.Printf("%-16s %-16s %-16s %-16s %-10s %-10s %-7s %-10s%s\n",
+			"NODE", "NAMESPACE", "POD", "CONTAINER",
+			"CPU(µs)", "STEAL(µs)", "STEAL%", "NODESTEAL%", alertsHeader())
+	case utils.OutputModeCustomColumns:
+		newInterval()
+		fmt.Println(stealGetCustomColsHeader(params.CustomColumns))
+	}
+}
+
+func stealPrintEvents() {
+	// sort and print events
+	mutex.Lock()
+
+	stats := []types.Stats{}
+	for _, stat := range stealNodeStats {
+		stats = append(stats, stat...)
+	}
+	stealNodeStats = make(map[string][]types.Stats)
+
+	mutex.Unlock()
+
+	types.SortStats(stats, stealSortBy)
+
+	switch params.OutputMode {
+	case utils.OutputModeColumns:
+		for idx, event := range stats {
+			if idx >= maxRows && len(event.Alerts) == 0 {
+				continue
+			}
+			fmt.Printf("%-16s %-16s %-16s %-16s %-10s %-10s %-7.1f %-10.1f%s\n",
+				event.Node, event.Namespace, event.Pod, event.Container,
+				formatMicroseconds(event.CPUTime), formatMicroseconds(event.Steal),
+				event.StealPercent, event.NodeSteal, formatAlerts(event.Alerts))
+		}
+	case utils.OutputModeJSON:
+		b, err := json.Marshal(stats)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s", utils.WrapInErrMarshalOutput(err))
+			return
+		}
+		fmt.Println(string(b))
+	case utils.OutputModeCustomColumns:
+		for idx, stat := range stats {
+			if idx >= maxRows && len(stat.Alerts) == 0 {
+				continue
+			}
+			fmt.Println(stealFormatEventCustomCols(&stat, params.CustomColumns))
+		}
+	}
+}
+
+func stealGetCustomColsHeader(cols []string) string {
+	var sb strings.Builder
+
+	for _, col := range cols {
+		switch col {
+		case "node":
+			sb.WriteString(fmt.Sprintf("%-16s", "NODE"))
+		case "namespace":
+			sb.WriteString(fmt.Sprintf("%-16s", "NAMESPACE"))
+		case "pod":
+			sb.WriteString(fmt.Sprintf("%-16s", "POD"))
+		case "container":
+			sb.WriteString(fmt.Sprintf("%-16s", "CONTAINER"))
+		case "mntns":
+			sb.WriteString(fmt.Sprintf("%-12s", "MNTNS"))
+		case "cputime":
+			sb.WriteString(fmt.Sprintf("%-10s", "CPU(µs)"))
+		case "steal":
+			sb.WriteString(fmt.Sprintf("%-10s", "STEAL(µs)"))
+		case "stealpct":
+			sb.WriteString(fmt.Sprintf("%-7s", "STEAL%"))
+		case "nodesteal":
+			sb.WriteString(fmt.Sprintf("%-10s", "NODESTEAL%"))
+		case "alerts":
+			sb.WriteString("ALERTS")
+		}
+		sb.WriteRune(' ')
+	}
+
+	return sb.String()
+}
+
+func stealFormatEventCustomCols(stats *types.Stats, cols []string) string {
+	var sb strings.Builder
+
+	for _, col := range cols {
+		switch col {
+		case "node":
+			sb.WriteString(fmt.Sprintf("%-16s", stats.Node))
+		case "namespace":
+			sb.WriteString(fmt.Sprintf("%-16s", stats.Namespace))
+		case "pod":
+			sb.WriteString(fmt.Sprintf("%-16s", stats.Pod))
+		case "container":
+			sb.WriteString(fmt.Sprintf("%-16s", stats.Container))
+		case "mntns":
+			sb.WriteString(fmt.Sprintf("%-12d", stats.MountNsID))
+		case "cputime":
+			sb.WriteString(fmt.Sprintf("%-10s", formatMicroseconds(stats.CPUTime)))
+		case "steal":
+			sb.WriteString(fmt.Sprintf("%-10s", formatMicroseconds(stats.Steal)))
+		case "stealpct":
+			sb.WriteString(fmt.Sprintf("%-7.1f", stats.StealPercent))
+		case "nodesteal":
+			sb.WriteString(fmt.Sprintf("%-10.1f", stats.NodeSteal))
+		case "alerts":
+			sb.WriteString(strings.Join(stats.Alerts, ","))
+		}
+		sb.WriteRune(' ')
+	}
+
+	return sb.String()
+}
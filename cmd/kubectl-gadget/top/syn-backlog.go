@@ -0,0 +1,258 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package top
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/tcpsynbl/types"
+)
+
+var synBacklogNodeStats map[string][]types.Stats
+
+var (
+	// flags
+	synBacklogSortBy types.SortBy
+)
+
+var synBacklogCmd = &cobra.Command{
+	Use:   fmt.Sprintf("syn-backlog [interval=%d]", types.IntervalDefault),
+	Short: "Periodically report the accept backlog usage and overflows of the listening TCP sockets",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var err error
+
+		synBacklogNodeStats = make(map[string][]types.Stats)
+
+		if len(args) == 1 {
+			outputInterval, err = strconv.Atoi(args[0])
+			if err != nil {
+				return utils.WrapInErrInvalidArg("<interval>",
+					fmt.Errorf("%q is not a valid value", args[0]))
+			}
+		} else {
+			outputInterval = types.IntervalDefault
+		}
+
+		parameters := map[string]string{
+			types.MaxRowsParam:  strconv.Itoa(maxRows),
+			types.IntervalParam: strconv.Itoa(outputInterval),
+			types.SortByParam:   sortBy,
+		}
+
+		if err := addThresholdParameters(parameters, &types.Stats{}); err != nil {
+			return err
+		}
+
+		config := &utils.TraceConfig{
+			GadgetName:       "tcpsynbl",
+			Operation:        "start",
+			TraceOutputMode:  "Stream",
+			TraceOutputState: "Started",
+			CommonFlags:      &params,
+			Parameters:       parameters,
+		}
+
+		return runTop(config, &topPrinter{
+			callback:    synBacklogCallback,
+			printHeader: synBacklogPrintHeader,
+			printEvents: synBacklogPrintEvents,
+		})
+	},
+	SilenceUsage: true,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		var err error
+		synBacklogSortBy, err = types.ParseSortBy(sortBy)
+		if err != nil {
+			return utils.WrapInErrInvalidArg("--sort", err)
+		}
+
+		return nil
+	},
+	Args: cobra.MaximumNArgs(1),
+}
+
+func init() {
+	addTopCommand(synBacklogCmd, types.MaxRowsDefault, types.SortBySlice)
+	utils.RegisterGadgetCommand(synBacklogCmd, "tcpsynbl", types.Stats{})
+}
+
+func synBacklogCallback(line string, node string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	var event types.Event
+
+	if err := json.Unmarshal([]byte(line), &event); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s", utils.WrapInErrUnmarshalOutput(err, line))
+		return
+	}
+
+	if event.Error != "" {
+		fmt.Fprintf(os.Stderr, "Error: failed on node %q: %s", event.Node, event.Error)
+		return
+	}
+
+	printWarning(node, event.Warning)
+
+	synBacklogNodeStats[node] = event.Stats
+}
+
+func synBacklogPrintHeader() {
+	switch params.OutputMode {
+	case utils.OutputModeColumns:
+		newInterval()
+		fmt.Printf("%-16s %-16s %-16s %-16s %-3s %-6s %-9s %-9s %-6s %-6s %-7s%s\n",
+			"NODE", "NAMESPACE", "POD", "CONTAINER",
+			"IP", "PORT", "CONNS", "DROPS", "PEAK", "LIMIT", "USAGE", alertsHeader())
+	case utils.OutputModeCustomColumns:
+		newInterval()
+		fmt.Println(synBacklogGetCustomColsHeader(params.CustomColumns))
+	}
+}
+
+func synBacklogPrintEvents() {
+	// sort and print events
+	mutex.Lock()
+
+	stats := []types.Stats{}
+	for _, stat := range synBacklogNodeStats {
+		stats = append(stats, stat...)
+	}
+	synBacklogNodeStats = make(map[string][]types.Stats)
+
+	mutex.Unlock()
+
+	types.SortStats(stats, synBacklogSortBy)
+
+	switch params.OutputMode {
+	case utils.OutputModeColumns:
+		for idx, event := range stats {
+			if idx >= maxRows && len(event.Alerts) == 0 {
+				continue
+			}
+			fmt.Printf("%-16s %-16s %-16s %-16s %-3d %-6d %-9d %-9d %-6d %-6d %-7s%s\n",
+				event.Node, event.Namespace, event.Pod, event.Container,
+				event.IPVersion, event.Port, event.Conns, event.Drops,
+				event.Peak, event.Limit, synBacklogFormatUsage(&event),
+				formatAlerts(event.Alerts))
+		}
+	case utils.OutputModeJSON:
+		b, err := json.Marshal(stats)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s", utils.WrapInErrMarshalOutput(err))
+			return
+		}
+		fmt.Println(string(b))
+	case utils.OutputModeCustomColumns:
+		for idx, stat := range stats {
+			if idx >= maxRows && len(stat.Alerts) == 0 {
+				continue
+			}
+			fmt.Println(synBacklogFormatEventCustomCols(&stat, params.CustomColumns))
+		}
+	}
+}
+
+func synBacklogGetCustomColsHeader(cols []string) string {
+	var sb strings.Builder
+
+	for _, col := range cols {
+		switch col {
+		case "node":
+			sb.WriteString(fmt.Sprintf("%-16s", "NODE"))
+		case "namespace":
+			sb.WriteString(fmt.Sprintf("%-16s", "NAMESPACE"))
+		case "pod":
+			sb.WriteString(fmt.Sprintf("%-16s", "POD"))
+		case "container":
+			sb.WriteString(fmt.Sprintf("%-16s", "CONTAINER"))
+		case "netns":
+			sb.WriteString(fmt.Sprintf("%-12s", "NETNS"))
+		case "ip":
+			sb.WriteString(fmt.Sprintf("%-3s", "IP"))
+		case "port":
+			sb.WriteString(fmt.Sprintf("%-6s", "PORT"))
+		case "conns":
+			sb.WriteString(fmt.Sprintf("%-9s", "CONNS"))
+		case "drops":
+			sb.WriteString(fmt.Sprintf("%-9s", "DROPS"))
+		case "peak":
+			sb.WriteString(fmt.Sprintf("%-6s", "PEAK"))
+		case "limit":
+			sb.WriteString(fmt.Sprintf("%-6s", "LIMIT"))
+		case "usage":
+			sb.WriteString(fmt.Sprintf("%-7s", "USAGE"))
+		case "alerts":
+			sb.WriteString("ALERTS")
+		}
+		sb.WriteRune(' ')
+	}
+
+	return sb.String()
+}
+
+func synBacklogFormatEventCustomCols(stats *types.Stats, cols []string) string {
+	var sb strings.Builder
+
+	for _, col := range cols {
+		switch col {
+		case "node":
+			sb.WriteString(fmt.Sprintf("%-16s", stats.Node))
+		case "namespace":
+			sb.WriteString(fmt.Sprintf("%-16s", stats.Namespace))
+		case "pod":
+			sb.WriteString(fmt.Sprintf("%-16s", stats.Pod))
+		case "container":
+			sb.WriteString(fmt.Sprintf("%-16s", stats.Container))
+		case "netns":
+			sb.WriteString(fmt.Sprintf("%-12d", stats.Netns))
+		case "ip":
+			sb.WriteString(fmt.Sprintf("%-3d", stats.IPVersion))
+		case "port":
+			sb.WriteString(fmt.Sprintf("%-6d", stats.Port))
+		case "conns":
+			sb.WriteString(fmt.Sprintf("%-9d", stats.Conns))
+		case "drops":
+			sb.WriteString(fmt.Sprintf("%-9d", stats.Drops))
+		case "peak":
+			sb.WriteString(fmt.Sprintf("%-6d", stats.Peak))
+		case "limit":
+			sb.WriteString(fmt.Sprintf("%-6d", stats.Limit))
+		case "usage":
+			sb.WriteString(fmt.Sprintf("%-7s", synBacklogFormatUsage(stats)))
+		case "alerts":
+			sb.WriteString(strings.Join(stats.Alerts, ","))
+		}
+		sb.WriteRune(' ')
+	}
+
+	return sb.String()
+}
+
+// synBacklogFormatUsage returns the usage of the accept backlog at its
+// peak, or "-" when the backlog size is 0.
+func synBacklogFormatUsage(stats *types.Stats) string {
+	if stats.Limit == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f%%", stats.Usage)
+}
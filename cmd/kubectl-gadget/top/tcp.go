@@ -21,10 +21,8 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
-	"time"
 
 	"github.com/spf13/cobra"
-	"golang.org/x/term"
 
 	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/tcptop/types"
@@ -37,8 +35,16 @@ var (
 	tcpSortBy      types.SortBy
 	tcpFilteredPid uint
 	tcpFamily      uint
+	tcpHistorySize int
 )
 
+// tcpHistory keeps the traffic of the connections in the last intervals, to
+// show their trend with --history.
+var tcpHistory *history
+
+// maxHistorySize is the maximum number of intervals kept with --history.
+const maxHistorySize = 60
+
 var tcpCmd = &cobra.Command{
 	Use:   fmt.Sprintf("tcp [interval=%d]", types.IntervalDefault),
 	Short: "Periodically report TCP activity",
@@ -47,6 +53,14 @@ var tcpCmd = &cobra.Command{
 
 		nodeTCPStats = make(map[string][]types.Stats)
 
+		if tcpHistorySize < 0 || tcpHistorySize > maxHistorySize {
+			return utils.WrapInErrInvalidArg("--history",
+				fmt.Errorf("must be between 0 and %d", maxHistorySize))
+		}
+		if tcpHistorySize > 0 {
+			tcpHistory = newHistory(tcpHistorySize)
+		}
+
 		if len(args) == 1 {
 			outputInterval, err = strconv.Atoi(args[0])
 			if err != nil {
@@ -70,6 +84,10 @@ var tcpCmd = &cobra.Command{
 			parameters[types.PidParam] = strconv.FormatUint(uint64(tcpFilteredPid), 10)
 		}
 
+		if err := addThresholdParameters(parameters, &types.Stats{}); err != nil {
+			return err
+		}
+
 		config := &utils.TraceConfig{
 			GadgetName:       "tcptop",
 			Operation:        "start",
@@ -79,26 +97,11 @@ var tcpCmd = &cobra.Command{
 			Parameters:       parameters,
 		}
 
-		// only wants to run for a given amount of time and print
-		// that result.
-		singleShot := params.Timeout == outputInterval
-
-		// start print loop if this is not a "single shoot" operation
-		if singleShot {
-			tcpPrintHeader()
-		} else {
-			tcpStartPrintLoop()
-		}
-
-		if err := utils.RunTraceStreamCallback(config, tcpCallback); err != nil {
-			return fmt.Errorf("error running trace: %w", err)
-		}
-
-		if singleShot {
-			tcpPrintEvents()
-		}
-
-		return nil
+		return runTop(config, &topPrinter{
+			callback:    tcpCallback,
+			printHeader: tcpPrintHeader,
+			printEvents: tcpPrintEvents,
+		})
 	},
 	SilenceUsage: true,
 	PreRunE: func(cmd *cobra.Command, args []string) error {
@@ -128,8 +131,16 @@ func init() {
 		0,
 		"Show only TCP events for this IP version: either 4 or 6 (by default all will be printed)",
 	)
+	tcpCmd.PersistentFlags().IntVarP(
+		&tcpHistorySize,
+		"history",
+		"",
+		0,
+		"Keep the traffic of the last N intervals and show its trend and its change since the previous interval for each connection",
+	)
 
 	addTopCommand(tcpCmd, types.MaxRowsDefault, types.SortBySlice)
+	utils.RegisterGadgetCommand(tcpCmd, "tcptop", types.Stats{})
 }
 
 func tcpCallback(line string, node string) {
@@ -148,38 +159,21 @@ func tcpCallback(line string, node string) {
 		return
 	}
 
-	nodeTCPStats[node] = event.Stats
-}
+	printWarning(node, event.Warning)
 
-func tcpStartPrintLoop() {
-	go func() {
-		ticker := time.NewTicker(time.Duration(outputInterval) * time.Second)
-		tcpPrintHeader()
-		for {
-			_ = <-ticker.C
-			tcpPrintHeader()
-			tcpPrintEvents()
-		}
-	}()
+	nodeTCPStats[node] = event.Stats
 }
 
 func tcpPrintHeader() {
 	switch params.OutputMode {
 	case utils.OutputModeColumns:
-		if term.IsTerminal(int(os.Stdout.Fd())) {
-			utils.ClearScreen()
-		} else {
-			fmt.Println("")
-		}
-		fmt.Printf("%-16s %-16s %-16s %-16s %-7s %-16s %-3s %-51s %-51s %-7s %s\n",
+		newInterval()
+		fmt.Printf("%-16s %-16s %-16s %-16s %-7s %-16s %-3s %-51s %-51s%s %-7s %s%s\n",
 			"NODE", "NAMESPACE", "POD", "CONTAINER",
-			"PID", "COMM", "IPv", "LADDR", "RADDR", "RX_KB", "TX_KB")
+			"PID", "COMM", "IPv", "LADDR", "RADDR", tcpHistoryHeader(),
+			"RX_KB", "TX_KB", alertsHeader())
 	case utils.OutputModeCustomColumns:
-		if term.IsTerminal(int(os.Stdout.Fd())) {
-			utils.ClearScreen()
-		} else {
-			fmt.Println("")
-		}
+		newInterval()
 		fmt.Println(tcpGetCustomColsHeaders(params.CustomColumns))
 	}
 }
@@ -198,11 +192,19 @@ func tcpPrintEvents() {
 
 	types.SortStats(stats, tcpSortBy)
 
+	if tcpHistory != nil {
+		values := make(map[string]uint64, len(stats))
+		for _, stat := range stats {
+			values[tcpHistoryKey(&stat)] += stat.Sent + stat.Received
+		}
+		tcpHistory.add(values)
+	}
+
 	switch params.OutputMode {
 	case utils.OutputModeColumns:
 		for idx, event := range stats {
-			if idx == maxRows {
-				break
+			if idx >= maxRows && len(event.Alerts) == 0 {
+				continue
 			}
 
 			tcpFamily := 4
@@ -210,12 +212,14 @@ func tcpPrintEvents() {
 				tcpFamily = 6
 			}
 
-			fmt.Printf("%-16s %-16s %-16s %-16s %-7d %-16s %-3d %-51s %-51s %-7d %d\n",
+			fmt.Printf("%-16s %-16s %-16s %-16s %-7d %-16s %-3d %-51s %-51s%s %-7s %s%s\n",
 				event.Node, event.Namespace, event.Pod, event.Container,
 				event.Pid, event.Comm, tcpFamily,
 				fmt.Sprintf("%s:%d", event.Saddr, event.Sport),
 				fmt.Sprintf("%s:%d", event.Daddr, event.Dport),
-				event.Received/1048, event.Sent/1048)
+				tcpHistoryColumns(&event),
+				formatBytes(event.Received, 1048), formatBytes(event.Sent, 1048),
+				formatAlerts(event.Alerts))
 		}
 	case utils.OutputModeJSON:
 		b, err := json.Marshal(stats)
@@ -226,8 +230,8 @@ func tcpPrintEvents() {
 		fmt.Println(string(b))
 	case utils.OutputModeCustomColumns:
 		for idx, stat := range stats {
-			if idx == maxRows {
-				break
+			if idx >= maxRows && len(stat.Alerts) == 0 {
+				continue
 			}
 			fmt.Println(tcpFormatEventCustomCols(&stat, params.CustomColumns))
 		}
@@ -261,6 +265,12 @@ func tcpGetCustomColsHeaders(cols []string) string {
 			sb.WriteString(fmt.Sprintf("%-7s", "TX_KB"))
 		case "received":
 			sb.WriteString(fmt.Sprintf("%-7s", "RX_KB"))
+		case "trend":
+			sb.WriteString(fmt.Sprintf("%-*s", tcpHistorySize, "TREND"))
+		case "delta":
+			sb.WriteString(fmt.Sprintf("%-8s", "DELTA_KB"))
+		case "alerts":
+			sb.WriteString("ALERTS")
 		}
 		sb.WriteRune(' ')
 	}
@@ -297,12 +307,49 @@ func tcpFormatEventCustomCols(stats *types.Stats, cols []string) string {
 		case "daddr":
 			sb.WriteString(fmt.Sprintf("%-51s", fmt.Sprintf("%s:%d", stats.Daddr, stats.Dport)))
 		case "sent":
-			sb.WriteString(fmt.Sprintf("%-7d", stats.Sent))
+			sb.WriteString(fmt.Sprintf("%-7s", formatBytes(stats.Sent, 1)))
 		case "received":
-			sb.WriteString(fmt.Sprintf("%d", stats.Received))
+			sb.WriteString(formatBytes(stats.Received, 1))
+		case "trend":
+			if tcpHistory != nil {
+				sb.WriteString(tcpHistory.sparkline(tcpHistoryKey(stats)))
+			}
+		case "delta":
+			if tcpHistory != nil {
+				sb.WriteString(fmt.Sprintf("%-8s", formatDelta(tcpHistory.delta(tcpHistoryKey(stats)), 1)))
+			}
+		case "alerts":
+			sb.WriteString(strings.Join(stats.Alerts, ","))
 		}
 		sb.WriteRune(' ')
 	}
 
 	return sb.String()
 }
+
+// tcpHistoryKey identifies the connection of a row across the intervals.
+func tcpHistoryKey(stats *types.Stats) string {
+	return fmt.Sprintf("%s/%d/%s:%d/%s:%d", stats.Node, stats.Pid,
+		stats.Saddr, stats.Sport, stats.Daddr, stats.Dport)
+}
+
+// tcpHistoryHeader returns the headers of the TREND and DELTA_KB columns,
+// only printed with --history.
+func tcpHistoryHeader() string {
+	if tcpHistory == nil {
+		return ""
+	}
+	return fmt.Sprintf(" %-*s %-8s", tcpHistorySize, "TREND", "DELTA_KB")
+}
+
+// tcpHistoryColumns returns the TREND and DELTA_KB columns of a row: the
+// traffic of the connection in the last intervals and its change since the
+// previous one.
+func tcpHistoryColumns(stats *types.Stats) string {
+	if tcpHistory == nil {
+		return ""
+	}
+	key := tcpHistoryKey(stats)
+	return fmt.Sprintf(" %-*s %-8s", tcpHistorySize, tcpHistory.sparkline(key),
+		formatDelta(tcpHistory.delta(key), 1024))
+}
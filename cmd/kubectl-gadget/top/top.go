@@ -15,13 +15,21 @@
 package top
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"strconv"
 	"strings"
 	"sync"
-
-	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	"time"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/threshold"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/stream"
 )
 
 var (
@@ -31,6 +39,15 @@ var (
 	outputInterval int
 	maxRows        int
 	sortBy         string
+	humanReadable  bool
+
+	traceName         string
+	attachTrace       string
+	previousIntervals int
+
+	thresholds       string
+	thresholdWarn    bool
+	thresholdWebhook string
 )
 
 var TopCmd = &cobra.Command{
@@ -42,6 +59,254 @@ func addTopCommand(command *cobra.Command, defaultMaxRows int, sortBySlice []str
 	command.Flags().IntVarP(&maxRows, "maxRows", "r", defaultMaxRows, "Maximum rows to print")
 	command.Flags().StringVarP(&sortBy, "sort", "", sortBySlice[0], fmt.Sprintf("Sort column, possible values are: %s", strings.Join(sortBySlice, ", ")))
 
+	command.Flags().StringVarP(&attachTrace, "attach", "", "", "Attach to the running top trace with this name or ID instead of creating a new one")
+	command.Flags().IntVarP(&previousIntervals, "previous", "", 0,
+		fmt.Sprintf("Print the last N intervals (up to %d) of the trace given with --attach before the new ones", stream.HistorySize))
+
+	command.Flags().StringVarP(&thresholds, "threshold", "", "",
+		"Comma-separated list of thresholds like sent>10MB or wbytes>=1MiB/s. The rows crossing them are marked and printed even beyond --maxRows")
+	command.Flags().BoolVarP(&thresholdWarn, "threshold-warn", "", false, "Print a warning for the intervals where thresholds are crossed")
+	command.Flags().StringVarP(&thresholdWebhook, "threshold-webhook", "", "",
+		"URL the rows crossing the thresholds are posted to, as JSON, from the nodes")
+
 	utils.AddCommonFlags(command, &params)
+	utils.AddHumanReadableFlag(command, &humanReadable)
+	utils.AddTraceNameFlag(command, &traceName)
 	TopCmd.AddCommand(command)
 }
+
+// topPrinter holds the functions of a top command handling the intervals
+// received from the tracers.
+type topPrinter struct {
+	// callback stores the stats of an interval received from a node.
+	callback func(line string, node string)
+
+	// printHeader and printEvents print the stats stored since the last
+	// call.
+	printHeader func()
+	printEvents func()
+}
+
+// runTop runs the trace described by config and prints the stats it
+// reports every interval. With --attach, the output of the trace given by
+// the user is printed instead, and the trace is left running on exit so
+// that several users can follow it.
+func runTop(config *utils.TraceConfig, printer *topPrinter) error {
+	if attachTrace == "" {
+		if previousIntervals != 0 {
+			return utils.WrapInErrInvalidArg("--previous", errors.New("can only be used with --attach"))
+		}
+
+		config.TraceName = traceName
+
+		// when params.Timeout == interval it means the user
+		// only wants to run for a given amount of time and print
+		// that result.
+		singleShot := params.Timeout == outputInterval
+
+		// start print loop if this is not a "single shoot" operation
+		if singleShot {
+			printer.printHeader()
+		} else {
+			startPrintLoop(printer)
+		}
+
+		if err := utils.RunTraceStreamCallback(config, printer.callback); err != nil {
+			return utils.WrapInErrRunGadget(err)
+		}
+
+		if singleShot {
+			printer.printEvents()
+		}
+
+		return nil
+	}
+
+	if traceName != "" {
+		return utils.WrapInErrInvalidArg("--name", errors.New("can't be used with --attach"))
+	}
+	if thresholds != "" {
+		return utils.WrapInErrInvalidArg("--threshold", errors.New("can't be used with --attach"))
+	}
+	if previousIntervals < 0 || previousIntervals > stream.HistorySize {
+		return utils.WrapInErrInvalidArg("--previous",
+			fmt.Errorf("must be between 0 and %d", stream.HistorySize))
+	}
+
+	traceID, err := utils.ResolveTraceID(attachTrace)
+	if err != nil {
+		return err
+	}
+
+	traces, err := utils.ListTracesByID(traceID)
+	if err != nil {
+		return err
+	}
+	for _, trace := range traces {
+		if trace.Spec.Gadget != config.GadgetName {
+			return fmt.Errorf("trace %q uses the %s gadget, not %s", attachTrace, trace.Spec.Gadget, config.GadgetName)
+		}
+		// Print at the pace of the attached trace.
+		if val, ok := trace.Spec.Parameters["interval"]; ok {
+			if interval, err := strconv.Atoi(val); err == nil {
+				outputInterval = interval
+			}
+		}
+		// Show the alerts of the attached trace.
+		if val, ok := trace.Spec.Parameters[threshold.Param]; ok {
+			thresholds = val
+		}
+	}
+
+	callback := printer.callback
+	if previousIntervals > 0 {
+		// The previous intervals are received all at once: print each
+		// interval as soon as it's received instead of only the last
+		// one every interval.
+		var printMutex sync.Mutex
+		callback = func(line string, node string) {
+			printMutex.Lock()
+			defer printMutex.Unlock()
+
+			printer.callback(line, node)
+			printIntervalTitle(line, node)
+			printer.printHeader()
+			printer.printEvents()
+		}
+	} else {
+		startPrintLoop(printer)
+	}
+
+	if err := utils.AttachTraceStreamCallback(traceID, previousIntervals, &params, callback); err != nil {
+		return utils.WrapInErrRunGadget(err)
+	}
+
+	return nil
+}
+
+// addThresholdParameters validates the thresholds given by the user against
+// row, a row of the output of the gadget, and adds them to the parameters of
+// the trace.
+func addThresholdParameters(parameters map[string]string, row interface{}) error {
+	if thresholds == "" {
+		if thresholdWarn || thresholdWebhook != "" {
+			return utils.WrapInErrMissingArgs("--threshold")
+		}
+		return nil
+	}
+
+	parsed, err := threshold.Parse(thresholds)
+	if err != nil {
+		return utils.WrapInErrInvalidArg("--threshold", err)
+	}
+	if err := threshold.Validate(parsed, row); err != nil {
+		return utils.WrapInErrInvalidArg("--threshold", err)
+	}
+
+	parameters[threshold.Param] = thresholds
+	parameters[threshold.WarnParam] = strconv.FormatBool(thresholdWarn)
+	if thresholdWebhook != "" {
+		parameters[threshold.WebhookParam] = thresholdWebhook
+	}
+
+	return nil
+}
+
+// printWarning prints the warning sent by a node with an interval where
+// thresholds are crossed.
+func printWarning(node string, warning string) {
+	if warning != "" {
+		fmt.Fprintf(os.Stderr, "Warning: node %q: %s\n", node, warning)
+	}
+}
+
+// alertsHeader returns the header of the ALERTS column, which is the last
+// one of the columns output when thresholds are set.
+func alertsHeader() string {
+	if thresholds == "" {
+		return ""
+	}
+	return " ALERTS"
+}
+
+// formatAlerts formats the thresholds crossed by a row for the ALERTS
+// column.
+func formatAlerts(alerts []string) string {
+	if thresholds == "" || len(alerts) == 0 {
+		return ""
+	}
+	return " " + strings.Join(alerts, ",")
+}
+
+func startPrintLoop(printer *topPrinter) {
+	go func() {
+		ticker := time.NewTicker(time.Duration(outputInterval) * time.Second)
+		printer.printHeader()
+		for {
+			_ = <-ticker.C
+			printer.printHeader()
+			printer.printEvents()
+		}
+	}()
+}
+
+// printIntervalTitle prints the node and the end time of an interval when
+// the intervals are printed one after the other.
+func printIntervalTitle(line string, node string) {
+	if params.OutputMode == utils.OutputModeJSON {
+		return
+	}
+
+	var event struct {
+		Timestamp int64 `json:"timestamp"`
+	}
+	if err := json.Unmarshal([]byte(line), &event); err != nil || event.Timestamp == 0 {
+		fmt.Printf("\nNode %s:\n", node)
+		return
+	}
+
+	fmt.Printf("\nNode %s, interval ending at %s:\n", node,
+		time.Unix(0, event.Timestamp).Format(time.RFC3339))
+}
+
+// newInterval prepares the screen for printing a new interval: the terminal
+// is cleared, unless the intervals are printed one after the other because
+// previous intervals were requested.
+func newInterval() {
+	if previousIntervals > 0 {
+		return
+	}
+
+	if term.IsTerminal(int(os.Stdout.Fd())) {
+		utils.ClearScreen()
+	} else {
+		fmt.Println("")
+	}
+}
+
+// formatBytes formats a size for the columns output: with units when
+// --human-readable is set, otherwise as the raw number divided by unit.
+func formatBytes(n uint64, unit uint64) string {
+	if humanReadable {
+		return utils.FormatBytes(n)
+	}
+	return strconv.FormatUint(n/unit, 10)
+}
+
+// formatDelta formats the change of a size between two intervals like
+// formatBytes, with its sign.
+func formatDelta(delta int64, unit uint64) string {
+	if delta < 0 {
+		return "-" + formatBytes(uint64(-delta), unit)
+	}
+	return "+" + formatBytes(uint64(delta), unit)
+}
+
+// formatMicroseconds formats a duration for the columns output: with units
+// when --human-readable is set, otherwise as the raw number.
+func formatMicroseconds(us uint64) string {
+	if humanReadable {
+		return utils.FormatMicroseconds(us)
+	}
+	return strconv.FormatUint(us, 10)
+}
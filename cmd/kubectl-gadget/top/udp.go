@@ -0,0 +1,280 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package top
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/udptop/types"
+)
+
+var nodeUDPStats map[string][]types.Stats
+
+var (
+	// flags
+	udpSortBy      types.SortBy
+	udpFilteredPid uint
+	udpFamily      uint
+)
+
+var udpCmd = &cobra.Command{
+	Use:   fmt.Sprintf("udp [interval=%d]", types.IntervalDefault),
+	Short: "Periodically report the UDP traffic by process",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var err error
+
+		nodeUDPStats = make(map[string][]types.Stats)
+
+		if len(args) == 1 {
+			outputInterval, err = strconv.Atoi(args[0])
+			if err != nil {
+				return utils.WrapInErrInvalidArg("<interval>",
+					fmt.Errorf("%q is not a valid value", args[0]))
+			}
+		} else {
+			outputInterval = types.IntervalDefault
+		}
+
+		parameters := map[string]string{
+			types.MaxRowsParam:  strconv.Itoa(maxRows),
+			types.IntervalParam: strconv.Itoa(outputInterval),
+			types.SortByParam:   sortBy,
+		}
+
+		if udpFamily != 0 {
+			parameters[types.FamilyParam] = strconv.FormatUint(uint64(udpFamily), 10)
+		}
+
+		if udpFilteredPid != 0 {
+			parameters[types.PidParam] = strconv.FormatUint(uint64(udpFilteredPid), 10)
+		}
+
+		if err := addThresholdParameters(parameters, &types.Stats{}); err != nil {
+			return err
+		}
+
+		config := &utils.TraceConfig{
+			GadgetName:       "udptop",
+			Operation:        "start",
+			TraceOutputMode:  "Stream",
+			TraceOutputState: "Started",
+			CommonFlags:      &params,
+			Parameters:       parameters,
+		}
+
+		return runTop(config, &topPrinter{
+			callback:    udpCallback,
+			printHeader: udpPrintHeader,
+			printEvents: udpPrintEvents,
+		})
+	},
+	SilenceUsage: true,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		var err error
+		udpSortBy, err = types.ParseSortBy(sortBy)
+		if err != nil {
+			return utils.WrapInErrInvalidArg("--sort", err)
+		}
+
+		return nil
+	},
+	Args: cobra.MaximumNArgs(1),
+}
+
+func init() {
+	udpCmd.PersistentFlags().UintVarP(
+		&udpFilteredPid,
+		"pid",
+		"",
+		0,
+		"Show only the UDP traffic of this particular PID",
+	)
+	udpCmd.PersistentFlags().UintVarP(
+		&udpFamily,
+		"family",
+		"f",
+		0,
+		"Show only the UDP traffic for this IP version: either 4 or 6 (by default all will be printed)",
+	)
+
+	addTopCommand(udpCmd, types.MaxRowsDefault, types.SortBySlice)
+	utils.RegisterGadgetCommand(udpCmd, "udptop", types.Stats{})
+}
+
+func udpCallback(line string, node string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	var event types.Event
+
+	if err := json.Unmarshal([]byte(line), &event); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s", utils.WrapInErrUnmarshalOutput(err, line))
+		return
+	}
+
+	if event.Error != "" {
+		fmt.Fprintf(os.Stderr, "Error: failed on node %q: %s", event.Node, event.Error)
+		return
+	}
+
+	printWarning(node, event.Warning)
+
+	nodeUDPStats[node] = event.Stats
+}
+
+func udpPrintHeader() {
+	switch params.OutputMode {
+	case utils.OutputModeColumns:
+		newInterval()
+		fmt.Printf("%-16s %-16s %-16s %-16s %-7s %-16s %-3s %-7s %-7s %-7s %s%s\n",
+			"NODE", "NAMESPACE", "POD", "CONTAINER",
+			"PID", "COMM", "IPv", "RX_KB", "TX_KB", "RX_PKTS", "TX_PKTS", alertsHeader())
+	case utils.OutputModeCustomColumns:
+		newInterval()
+		fmt.Println(udpGetCustomColsHeaders(params.CustomColumns))
+	}
+}
+
+func udpPrintEvents() {
+	// sort and print events
+	mutex.Lock()
+
+	stats := []types.Stats{}
+	for _, stat := range nodeUDPStats {
+		stats = append(stats, stat...)
+	}
+	nodeUDPStats = make(map[string][]types.Stats)
+
+	mutex.Unlock()
+
+	types.SortStats(stats, udpSortBy)
+
+	switch params.OutputMode {
+	case utils.OutputModeColumns:
+		for idx, event := range stats {
+			if idx >= maxRows && len(event.Alerts) == 0 {
+				continue
+			}
+
+			fmt.Printf("%-16s %-16s %-16s %-16s %-7d %-16s %-3d %-7s %-7s %-7d %-7d%s\n",
+				event.Node, event.Namespace, event.Pod, event.Container,
+				event.Pid, event.Comm, udpIPVersion(&event),
+				formatBytes(event.Received, 1024), formatBytes(event.Sent, 1024),
+				event.ReceivedPackets, event.SentPackets,
+				formatAlerts(event.Alerts))
+		}
+	case utils.OutputModeJSON:
+		b, err := json.Marshal(stats)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s", utils.WrapInErrMarshalOutput(err))
+			return
+		}
+		fmt.Println(string(b))
+	case utils.OutputModeCustomColumns:
+		for idx, stat := range stats {
+			if idx >= maxRows && len(stat.Alerts) == 0 {
+				continue
+			}
+			fmt.Println(udpFormatEventCustomCols(&stat, params.CustomColumns))
+		}
+	}
+}
+
+func udpGetCustomColsHeaders(cols []string) string {
+	var sb strings.Builder
+
+	for _, col := range cols {
+		switch col {
+		case "node":
+			sb.WriteString(fmt.Sprintf("%-16s", "NODE"))
+		case "namespace":
+			sb.WriteString(fmt.Sprintf("%-16s", "NAMESPACE"))
+		case "pod":
+			sb.WriteString(fmt.Sprintf("%-16s", "POD"))
+		case "container":
+			sb.WriteString(fmt.Sprintf("%-16s", "CONTAINER"))
+		case "pid":
+			sb.WriteString(fmt.Sprintf("%-7s", "PID"))
+		case "comm":
+			sb.WriteString(fmt.Sprintf("%-16s", "COMM"))
+		case "family":
+			sb.WriteString(fmt.Sprintf("%-3s", "IPv"))
+		case "sent":
+			sb.WriteString(fmt.Sprintf("%-7s", "TX_KB"))
+		case "received":
+			sb.WriteString(fmt.Sprintf("%-7s", "RX_KB"))
+		case "sentpackets":
+			sb.WriteString(fmt.Sprintf("%-7s", "TX_PKTS"))
+		case "receivedpackets":
+			sb.WriteString(fmt.Sprintf("%-7s", "RX_PKTS"))
+		case "alerts":
+			sb.WriteString("ALERTS")
+		}
+		sb.WriteRune(' ')
+	}
+
+	return sb.String()
+}
+
+func udpFormatEventCustomCols(stats *types.Stats, cols []string) string {
+	var sb strings.Builder
+
+	for _, col := range cols {
+		switch col {
+		case "node":
+			sb.WriteString(fmt.Sprintf("%-16s", stats.Node))
+		case "namespace":
+			sb.WriteString(fmt.Sprintf("%-16s", stats.Namespace))
+		case "pod":
+			sb.WriteString(fmt.Sprintf("%-16s", stats.Pod))
+		case "container":
+			sb.WriteString(fmt.Sprintf("%-16s", stats.Container))
+		case "pid":
+			sb.WriteString(fmt.Sprintf("%-7d", stats.Pid))
+		case "comm":
+			sb.WriteString(fmt.Sprintf("%-16s", stats.Comm))
+		case "family":
+			sb.WriteString(fmt.Sprintf("%-3d", udpIPVersion(stats)))
+		case "sent":
+			sb.WriteString(fmt.Sprintf("%-7s", formatBytes(stats.Sent, 1024)))
+		case "received":
+			sb.WriteString(fmt.Sprintf("%-7s", formatBytes(stats.Received, 1024)))
+		case "sentpackets":
+			sb.WriteString(fmt.Sprintf("%-7d", stats.SentPackets))
+		case "receivedpackets":
+			sb.WriteString(fmt.Sprintf("%-7d", stats.ReceivedPackets))
+		case "alerts":
+			sb.WriteString(strings.Join(stats.Alerts, ","))
+		}
+		sb.WriteRune(' ')
+	}
+
+	return sb.String()
+}
+
+func udpIPVersion(stats *types.Stats) int {
+	if stats.Family == syscall.AF_INET6 {
+		return 6
+	}
+	return 4
+}
@@ -0,0 +1,262 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package top
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/vfsstat/types"
+)
+
+var vfsNodeStats map[string][]types.Stats
+
+var (
+	// flags
+	vfsSortBy      types.SortBy
+	vfsFilteredPid uint
+)
+
+var vfsCmd = &cobra.Command{
+	Use:   fmt.Sprintf("vfs [interval=%d]", types.IntervalDefault),
+	Short: "Periodically report the VFS calls by container",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var err error
+
+		vfsNodeStats = make(map[string][]types.Stats)
+
+		if len(args) == 1 {
+			outputInterval, err = strconv.Atoi(args[0])
+			if err != nil {
+				return utils.WrapInErrInvalidArg("<interval>",
+					fmt.Errorf("%q is not a valid value", args[0]))
+			}
+		} else {
+			outputInterval = types.IntervalDefault
+		}
+
+		parameters := map[string]string{
+			types.MaxRowsParam:  strconv.Itoa(maxRows),
+			types.IntervalParam: strconv.Itoa(outputInterval),
+			types.SortByParam:   sortBy,
+		}
+
+		if vfsFilteredPid != 0 {
+			parameters[types.PidParam] = strconv.FormatUint(uint64(vfsFilteredPid), 10)
+		}
+
+		if err := addThresholdParameters(parameters, &types.Stats{}); err != nil {
+			return err
+		}
+
+		config := &utils.TraceConfig{
+			GadgetName:       "vfsstat",
+			Operation:        "start",
+			TraceOutputMode:  "Stream",
+			TraceOutputState: "Started",
+			CommonFlags:      &params,
+			Parameters:       parameters,
+		}
+
+		return runTop(config, &topPrinter{
+			callback:    vfsCallback,
+			printHeader: vfsPrintHeader,
+			printEvents: vfsPrintEvents,
+		})
+	},
+	SilenceUsage: true,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		var err error
+		vfsSortBy, err = types.ParseSortBy(sortBy)
+		if err != nil {
+			return utils.WrapInErrInvalidArg("--sort", err)
+		}
+
+		return nil
+	},
+	Args: cobra.MaximumNArgs(1),
+}
+
+func init() {
+	vfsCmd.PersistentFlags().UintVarP(
+		&vfsFilteredPid,
+		"pid",
+		"",
+		0,
+		"Show only the VFS calls made by this particular PID",
+	)
+
+	addTopCommand(vfsCmd, types.MaxRowsDefault, types.SortBySlice)
+	utils.RegisterGadgetCommand(vfsCmd, "vfsstat", types.Stats{})
+}
+
+func vfsCallback(line string, node string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	var event types.Event
+
+	if err := json.Unmarshal([]byte(line), &event); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s", utils.WrapInErrUnmarshalOutput(err, line))
+		return
+	}
+
+	if event.Error != "" {
+		fmt.Fprintf(os.Stderr, "Error: failed on node %q: %s", event.Node, event.Error)
+		return
+	}
+
+	printWarning(node, event.Warning)
+
+	vfsNodeStats[node] = event.Stats
+}
+
+func vfsPrintHeader() {
+	switch params.OutputMode {
+	case utils.OutputModeColumns:
+		newInterval()
+		fmt.Printf("%-16s %-16s %-16s %-16s %-8s %-8s %-7s %-7s %-7s %-7s %s%s\n",
+			"NODE", "NAMESPACE", "POD", "CONTAINER",
+			"READS", "WRITES", "FSYNCS", "OPENS", "UNLINKS", "MKDIRS", "RMDIRS", alertsHeader())
+	case utils.OutputModeCustomColumns:
+		newInterval()
+		fmt.Println(vfsGetCustomColsHeader(params.CustomColumns))
+	}
+}
+
+func vfsPrintEvents() {
+	// sort and print events
+	mutex.Lock()
+
+	stats := []types.Stats{}
+	for _, stat := range vfsNodeStats {
+		stats = append(stats, stat...)
+	}
+	vfsNodeStats = make(map[string][]types.Stats)
+
+	mutex.Unlock()
+
+	types.SortStats(stats, vfsSortBy)
+
+	switch params.OutputMode {
+	case utils.OutputModeColumns:
+		for idx, event := range stats {
+			if idx >= maxRows && len(event.Alerts) == 0 {
+				continue
+			}
+			fmt.Printf("%-16s %-16s %-16s %-16s %-8d %-8d %-7d %-7d %-7d %-7d %d%s\n",
+				event.Node, event.Namespace, event.Pod, event.Container,
+				event.Reads, event.Writes, event.Fsyncs, event.Opens,
+				event.Unlinks, event.Mkdirs, event.Rmdirs,
+				formatAlerts(event.Alerts))
+		}
+	case utils.OutputModeJSON:
+		b, err := json.Marshal(stats)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s", utils.WrapInErrMarshalOutput(err))
+			return
+		}
+		fmt.Println(string(b))
+	case utils.OutputModeCustomColumns:
+		for idx, stat := range stats {
+			if idx >= maxRows && len(stat.Alerts) == 0 {
+				continue
+			}
+			fmt.Println(vfsFormatEventCustomCols(&stat, params.CustomColumns))
+		}
+	}
+}
+
+func vfsGetCustomColsHeader(cols []string) string {
+	var sb strings.Builder
+
+	for _, col := range cols {
+		switch col {
+		case "node":
+			sb.WriteString(fmt.Sprintf("%-16s", "NODE"))
+		case "namespace":
+			sb.WriteString(fmt.Sprintf("%-16s", "NAMESPACE"))
+		case "pod":
+			sb.WriteString(fmt.Sprintf("%-16s", "POD"))
+		case "container":
+			sb.WriteString(fmt.Sprintf("%-16s", "CONTAINER"))
+		case "mntns":
+			sb.WriteString(fmt.Sprintf("%-12s", "MNTNS"))
+		case "reads":
+			sb.WriteString(fmt.Sprintf("%-8s", "READS"))
+		case "writes":
+			sb.WriteString(fmt.Sprintf("%-8s", "WRITES"))
+		case "fsyncs":
+			sb.WriteString(fmt.Sprintf("%-7s", "FSYNCS"))
+		case "opens":
+			sb.WriteString(fmt.Sprintf("%-7s", "OPENS"))
+		case "unlinks":
+			sb.WriteString(fmt.Sprintf("%-7s", "UNLINKS"))
+		case "mkdirs":
+			sb.WriteString(fmt.Sprintf("%-7s", "MKDIRS"))
+		case "rmdirs":
+			sb.WriteString(fmt.Sprintf("%-7s", "RMDIRS"))
+		case "alerts":
+			sb.WriteString("ALERTS")
+		}
+		sb.WriteRune(' ')
+	}
+
+	return sb.String()
+}
+
+func vfsFormatEventCustomCols(stats *types.Stats, cols []string) string {
+	var sb strings.Builder
+
+	for _, col := range cols {
+		switch col {
+		case "node":
+			sb.WriteString(fmt.Sprintf("%-16s", stats.Node))
+		case "namespace":
+			sb.WriteString(fmt.Sprintf("%-16s", stats.Namespace))
+		case "pod":
+			sb.WriteString(fmt.Sprintf("%-16s", stats.Pod))
+		case "container":
+			sb.WriteString(fmt.Sprintf("%-16s", stats.Container))
+		case "mntns":
+			sb.WriteString(fmt.Sprintf("%-12d", stats.MountNsID))
+		case "reads":
+			sb.WriteString(fmt.Sprintf("%-8d", stats.Reads))
+		case "writes":
+			sb.WriteString(fmt.Sprintf("%-8d", stats.Writes))
+		case "fsyncs":
+			sb.WriteString(fmt.Sprintf("%-7d", stats.Fsyncs))
+		case "opens":
+			sb.WriteString(fmt.Sprintf("%-7d", stats.Opens))
+		case "unlinks":
+			sb.WriteString(fmt.Sprintf("%-7d", stats.Unlinks))
+		case "mkdirs":
+			sb.WriteString(fmt.Sprintf("%-7d", stats.Mkdirs))
+		case "rmdirs":
+			sb.WriteString(fmt.Sprintf("%-7d", stats.Rmdirs))
+		case "alerts":
+			sb.WriteString(strings.Join(stats.Alerts, ","))
+		}
+		sb.WriteRune(' ')
+	}
+
+	return sb.String()
+}
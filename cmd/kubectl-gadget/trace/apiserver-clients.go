@@ -0,0 +1,212 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/apiserverclients/types"
+	"github.com/kinvolk/inspektor-gadget/pkg/k8sutil"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+var apiserverClientsCmd = &cobra.Command{
+	Use:   "apiserver-clients",
+	Short: "Trace connections to the Kubernetes API server with their latency and failures",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		endpoints, err := getAPIServerEndpoints()
+		if err != nil {
+			return utils.WrapInErrRunGadget(err)
+		}
+
+		// print header
+		switch params.OutputMode {
+		case utils.OutputModeCustomColumns:
+			fmt.Println(getCustomAPIServerClientsColsHeader(params.CustomColumns))
+		case utils.OutputModeColumns:
+			fmt.Printf("%-16s %-16s %-16s %-16s %-6s %-16s %-16s %-22s %-8s %s\n",
+				"NODE", "NAMESPACE", "POD", "CONTAINER",
+				"PID", "COMM", "SADDR", "DADDR", "LAT(ms)", "STATUS")
+		}
+
+		config := &utils.TraceConfig{
+			GadgetName:       "apiserver-clients",
+			Operation:        "start",
+			TraceOutputMode:  "Stream",
+			TraceOutputState: "Started",
+			CommonFlags:      &params,
+			Parameters: map[string]string{
+				"endpoints": strings.Join(endpoints, ","),
+			},
+		}
+
+		err = utils.RunTraceAndPrintStream(config, apiserverClientsTransformLine)
+		if err != nil {
+			return utils.WrapInErrRunGadget(err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	TraceCmd.AddCommand(apiserverClientsCmd)
+	utils.RegisterGadgetCommand(apiserverClientsCmd, "apiserver-clients", types.Event{})
+	utils.AddCommonFlags(apiserverClientsCmd, &params)
+}
+
+// getAPIServerEndpoints returns the addresses the API server can be reached
+// at from the pods: the ClusterIP of the "kubernetes" service and the
+// addresses of its endpoints, used by pods running in the host network.
+func getAPIServerEndpoints() ([]string, error) {
+	client, err := k8sutil.NewClientsetFromConfigFlags(utils.KubernetesConfigFlags)
+	if err != nil {
+		return nil, utils.WrapInErrSetupK8sClient(err)
+	}
+
+	svc, err := client.CoreV1().Services("default").Get(context.TODO(), "kubernetes", metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the kubernetes service: %w", err)
+	}
+
+	endpoints := []string{}
+
+	for _, port := range svc.Spec.Ports {
+		endpoints = append(endpoints, net.JoinHostPort(svc.Spec.ClusterIP, strconv.Itoa(int(port.Port))))
+	}
+
+	ep, err := client.CoreV1().Endpoints("default").Get(context.TODO(), "kubernetes", metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the kubernetes endpoints: %w", err)
+	}
+
+	for _, subset := range ep.Subsets {
+		for _, addr := range subset.Addresses {
+			for _, port := range subset.Ports {
+				endpoints = append(endpoints, net.JoinHostPort(addr.IP, strconv.Itoa(int(port.Port))))
+			}
+		}
+	}
+
+	return endpoints, nil
+}
+
+func apiserverClientsStatus(e *types.Event) string {
+	if e.Failed {
+		return "failed"
+	}
+	return "ok"
+}
+
+// apiserverClientsTransformLine is called to transform an event to columns
+// format according to the parameters
+func apiserverClientsTransformLine(line string) string {
+	var sb strings.Builder
+	var e types.Event
+
+	if err := json.Unmarshal([]byte(line), &e); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s", utils.WrapInErrUnmarshalOutput(err, line))
+		return ""
+	}
+
+	if e.Type == eventtypes.ERR || e.Type == eventtypes.WARN ||
+		e.Type == eventtypes.DEBUG || e.Type == eventtypes.INFO {
+		fmt.Fprintf(os.Stderr, "%s: node %q: %s", e.Type, e.Node, e.Message)
+		return ""
+	}
+
+	if e.Type != eventtypes.NORMAL {
+		return ""
+	}
+
+	daddr := net.JoinHostPort(e.Daddr, strconv.Itoa(int(e.Dport)))
+
+	switch params.OutputMode {
+	case utils.OutputModeColumns:
+		sb.WriteString(fmt.Sprintf("%-16s %-16s %-16s %-16s %-6d %-16s %-16s %-22s %-8.2f %s",
+			e.Node, e.Namespace, e.Pod, e.Container,
+			e.Pid, e.Comm, e.Saddr, daddr, e.Latency, apiserverClientsStatus(&e)))
+	case utils.OutputModeCustomColumns:
+		for _, col := range params.CustomColumns {
+			switch col {
+			case "node":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Node))
+			case "namespace":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Namespace))
+			case "pod":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Pod))
+			case "container":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Container))
+			case "pid":
+				sb.WriteString(fmt.Sprintf("%-6d", e.Pid))
+			case "comm":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Comm))
+			case "saddr":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Saddr))
+			case "daddr":
+				sb.WriteString(fmt.Sprintf("%-22s", daddr))
+			case "lat":
+				sb.WriteString(fmt.Sprintf("%-8.2f", e.Latency))
+			case "status":
+				sb.WriteString(fmt.Sprintf("%-6s", apiserverClientsStatus(&e)))
+			}
+			sb.WriteRune(' ')
+		}
+	}
+
+	return sb.String()
+}
+
+func getCustomAPIServerClientsColsHeader(cols []string) string {
+	var sb strings.Builder
+
+	for _, col := range cols {
+		switch col {
+		case "node":
+			sb.WriteString(fmt.Sprintf("%-16s", "NODE"))
+		case "namespace":
+			sb.WriteString(fmt.Sprintf("%-16s", "NAMESPACE"))
+		case "pod":
+			sb.WriteString(fmt.Sprintf("%-16s", "POD"))
+		case "container":
+			sb.WriteString(fmt.Sprintf("%-16s", "CONTAINER"))
+		case "pid":
+			sb.WriteString(fmt.Sprintf("%-6s", "PID"))
+		case "comm":
+			sb.WriteString(fmt.Sprintf("%-16s", "COMM"))
+		case "saddr":
+			sb.WriteString(fmt.Sprintf("%-16s", "SADDR"))
+		case "daddr":
+			sb.WriteString(fmt.Sprintf("%-22s", "DADDR"))
+		case "lat":
+			sb.WriteString(fmt.Sprintf("%-8s", "LAT(ms)"))
+		case "status":
+			sb.WriteString(fmt.Sprintf("%-6s", "STATUS"))
+		}
+		sb.WriteRune(' ')
+	}
+
+	return sb.String()
+}
@@ -76,6 +76,7 @@ var bindsnoopCmd = &cobra.Command{
 
 func init() {
 	TraceCmd.AddCommand(bindsnoopCmd)
+	utils.RegisterGadgetCommand(bindsnoopCmd, "bindsnoop", types.Event{})
 	utils.AddCommonFlags(bindsnoopCmd, &params)
 
 	bindsnoopCmd.PersistentFlags().UintVarP(
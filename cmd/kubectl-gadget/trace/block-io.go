@@ -0,0 +1,161 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/biosnoop/types"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+var blockIOCmd = &cobra.Command{
+	Use:   "block-io",
+	Short: "Trace block device I/O with their latency",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// print header
+		switch params.OutputMode {
+		case utils.OutputModeCustomColumns:
+			fmt.Println(getCustomBlockIOColsHeader(params.CustomColumns))
+		case utils.OutputModeColumns:
+			fmt.Printf("%-16s %-16s %-16s %-16s %-6s %-16s %-12s %-7s %-12s %-8s %s\n",
+				"NODE", "NAMESPACE", "POD", "CONTAINER", "PID", "COMM", "OP", "DISK",
+				"SECTOR", "BYTES", "LAT(ms)")
+		}
+
+		config := &utils.TraceConfig{
+			GadgetName:       "biosnoop",
+			Operation:        "start",
+			TraceOutputMode:  "Stream",
+			TraceOutputState: "Started",
+			CommonFlags:      &params,
+		}
+
+		err := utils.RunTraceAndPrintStream(config, blockIOTransformLine)
+		if err != nil {
+			return utils.WrapInErrRunGadget(err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	TraceCmd.AddCommand(blockIOCmd)
+	utils.RegisterGadgetCommand(blockIOCmd, "biosnoop", types.Event{})
+	utils.AddCommonFlags(blockIOCmd, &params)
+}
+
+// blockIOTransformLine is called to transform an event to columns
+// format according to the parameters
+func blockIOTransformLine(line string) string {
+	var sb strings.Builder
+	var e types.Event
+
+	if err := json.Unmarshal([]byte(line), &e); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s", utils.WrapInErrUnmarshalOutput(err, line))
+		return ""
+	}
+
+	if e.Type == eventtypes.ERR || e.Type == eventtypes.WARN ||
+		e.Type == eventtypes.DEBUG || e.Type == eventtypes.INFO {
+		fmt.Fprintf(os.Stderr, "%s: node %q: %s", e.Type, e.Node, e.Message)
+		return ""
+	}
+
+	if e.Type != eventtypes.NORMAL {
+		return ""
+	}
+
+	disk := fmt.Sprintf("%d:%d", e.Major, e.Minor)
+	latency := float64(e.Latency) / 1000.0
+
+	switch params.OutputMode {
+	case utils.OutputModeColumns:
+		sb.WriteString(fmt.Sprintf("%-16s %-16s %-16s %-16s %-6d %-16s %-12s %-7s %-12d %-8d %.2f",
+			e.Node, e.Namespace, e.Pod, e.Container, e.Pid, e.Comm, e.Op, disk,
+			e.Sector, e.Bytes, latency))
+	case utils.OutputModeCustomColumns:
+		for _, col := range params.CustomColumns {
+			switch col {
+			case "node":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Node))
+			case "namespace":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Namespace))
+			case "pod":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Pod))
+			case "container":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Container))
+			case "pid":
+				sb.WriteString(fmt.Sprintf("%-6d", e.Pid))
+			case "comm":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Comm))
+			case "op":
+				sb.WriteString(fmt.Sprintf("%-12s", e.Op))
+			case "disk":
+				sb.WriteString(fmt.Sprintf("%-7s", disk))
+			case "sector":
+				sb.WriteString(fmt.Sprintf("%-12d", e.Sector))
+			case "bytes":
+				sb.WriteString(fmt.Sprintf("%-8d", e.Bytes))
+			case "latency":
+				sb.WriteString(fmt.Sprintf("%-8.2f", latency))
+			}
+			sb.WriteRune(' ')
+		}
+	}
+
+	return sb.String()
+}
+
+func getCustomBlockIOColsHeader(cols []string) string {
+	var sb strings.Builder
+
+	for _, col := range cols {
+		switch col {
+		case "node":
+			sb.WriteString(fmt.Sprintf("%-16s", "NODE"))
+		case "namespace":
+			sb.WriteString(fmt.Sprintf("%-16s", "NAMESPACE"))
+		case "pod":
+			sb.WriteString(fmt.Sprintf("%-16s", "POD"))
+		case "container":
+			sb.WriteString(fmt.Sprintf("%-16s", "CONTAINER"))
+		case "pid":
+			sb.WriteString(fmt.Sprintf("%-6s", "PID"))
+		case "comm":
+			sb.WriteString(fmt.Sprintf("%-16s", "COMM"))
+		case "op":
+			sb.WriteString(fmt.Sprintf("%-12s", "OP"))
+		case "disk":
+			sb.WriteString(fmt.Sprintf("%-7s", "DISK"))
+		case "sector":
+			sb.WriteString(fmt.Sprintf("%-12s", "SECTOR"))
+		case "bytes":
+			sb.WriteString(fmt.Sprintf("%-8s", "BYTES"))
+		case "latency":
+			sb.WriteString(fmt.Sprintf("%-8s", "LAT(ms)"))
+		}
+		sb.WriteRune(' ')
+	}
+
+	return sb.String()
+}
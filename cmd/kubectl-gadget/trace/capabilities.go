@@ -18,6 +18,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
@@ -26,6 +27,9 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// flags
+var capabilitiesDedupWindow uint
+
 var capabilitiesCmd = &cobra.Command{
 	Use:   "capabilities",
 	Short: "Trace security capability checks",
@@ -33,11 +37,11 @@ var capabilitiesCmd = &cobra.Command{
 		// print header
 		switch params.OutputMode {
 		case utils.OutputModeCustomColumns:
-			fmt.Println(getCustomOpensnoopColsHeader(params.CustomColumns))
+			fmt.Println(getCustomCapabilitiesColsHeader(params.CustomColumns))
 		case utils.OutputModeColumns:
-			fmt.Printf("%-16s %-16s %-16s %-16s %-6s %-6s %-16s %-4s %-16s %-6s\n",
+			fmt.Printf("%-16s %-16s %-16s %-16s %-6s %-6s %-16s %-4s %-16s %-6s %-6s\n",
 				"NODE", "NAMESPACE", "POD", "CONTAINER",
-				"UID", "PID", "COMM", "CAP", "NAME", "AUDIT")
+				"UID", "PID", "COMM", "CAP", "NAME", "AUDIT", "COUNT")
 		}
 
 		config := &utils.TraceConfig{
@@ -46,6 +50,9 @@ var capabilitiesCmd = &cobra.Command{
 			TraceOutputMode:  "Stream",
 			TraceOutputState: "Started",
 			CommonFlags:      &params,
+			Parameters: map[string]string{
+				types.DedupWindowParam: strconv.FormatUint(uint64(capabilitiesDedupWindow), 10),
+			},
 		}
 
 		err := utils.RunTraceAndPrintStream(config, capabilitiesTransformLine)
@@ -58,10 +65,25 @@ var capabilitiesCmd = &cobra.Command{
 }
 
 func init() {
+	capabilitiesCmd.Flags().UintVarP(
+		&capabilitiesDedupWindow, "dedup-window", "", types.DedupWindowDefault,
+		"Report identical capability checks only once every this number of seconds, with the number of suppressed checks. 0 disables it",
+	)
+
 	TraceCmd.AddCommand(capabilitiesCmd)
+	utils.RegisterGadgetCommand(capabilitiesCmd, "capabilities", types.Event{})
 	utils.AddCommonFlags(capabilitiesCmd, &params)
 }
 
+// capabilitiesCount returns the number of suppressed capability checks for
+// summary events, or an empty string for normal ones.
+func capabilitiesCount(e *types.Event) string {
+	if e.Count == 0 {
+		return ""
+	}
+	return fmt.Sprintf("+%d", e.Count)
+}
+
 // capabilitiesTransformLine is called to transform an event to columns
 // format according to the parameters
 func capabilitiesTransformLine(line string) string {
@@ -85,9 +107,9 @@ func capabilitiesTransformLine(line string) string {
 
 	switch params.OutputMode {
 	case utils.OutputModeColumns:
-		sb.WriteString(fmt.Sprintf("%-16s %-16s %-16s %-16s %-6d %-6d %-16s %-4d %-16s %-6d",
+		sb.WriteString(fmt.Sprintf("%-16s %-16s %-16s %-16s %-6d %-6d %-16s %-4d %-16s %-6d %-6s",
 			e.Node, e.Namespace, e.Pod, e.Container,
-			e.UID, e.Pid, e.Comm, e.Cap, e.CapName, e.Audit))
+			e.UID, e.Pid, e.Comm, e.Cap, e.CapName, e.Audit, capabilitiesCount(&e)))
 	case utils.OutputModeCustomColumns:
 		for _, col := range params.CustomColumns {
 			switch col {
@@ -111,6 +133,8 @@ func capabilitiesTransformLine(line string) string {
 				sb.WriteString(fmt.Sprintf("%-16s", e.CapName))
 			case "audit":
 				sb.WriteString(fmt.Sprintf("%-6d", e.Audit))
+			case "count":
+				sb.WriteString(fmt.Sprintf("%-6s", capabilitiesCount(&e)))
 			}
 			sb.WriteRune(' ')
 		}
@@ -144,6 +168,8 @@ func getCustomCapabilitiesColsHeader(cols []string) string {
 			sb.WriteString(fmt.Sprintf("%-16s", "NAME"))
 		case "audit":
 			sb.WriteString(fmt.Sprintf("%-6s", "AUDIT"))
+		case "count":
+			sb.WriteString(fmt.Sprintf("%-6s", "COUNT"))
 		}
 		sb.WriteRune(' ')
 	}
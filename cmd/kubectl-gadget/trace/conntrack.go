@@ -0,0 +1,210 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/conntrack/types"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+// flags
+var conntrackThreshold uint
+
+var conntrackCmd = &cobra.Command{
+	Use:   "conntrack",
+	Short: "Trace the packets dropped by conntrack and warn when its table is getting full",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if conntrackThreshold == 0 || conntrackThreshold > 100 {
+			return utils.WrapInErrInvalidArg("--threshold",
+				fmt.Errorf("must be between 1 and 100"))
+		}
+
+		// print header
+		switch params.OutputMode {
+		case utils.OutputModeCustomColumns:
+			fmt.Println(getCustomConntrackColsHeader(params.CustomColumns))
+		case utils.OutputModeColumns:
+			fmt.Printf("%-16s %-16s %-16s %-13s %-16s %-27s %s\n",
+				"NODE", "NAMESPACE", "POD", "KIND",
+				"SADDR", "DESTINATION", "DETAILS")
+		}
+
+		config := &utils.TraceConfig{
+			GadgetName:       "conntrack",
+			Operation:        "start",
+			TraceOutputMode:  "Stream",
+			TraceOutputState: "Started",
+			CommonFlags:      &params,
+			Parameters: map[string]string{
+				"threshold": strconv.FormatUint(uint64(conntrackThreshold), 10),
+			},
+		}
+
+		err := utils.RunTraceAndPrintStream(config, conntrackTransformLine)
+		if err != nil {
+			return utils.WrapInErrRunGadget(err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	conntrackCmd.Flags().UintVarP(
+		&conntrackThreshold, "threshold", "", types.ThresholdDefault,
+		"Usage of the conntrack table, in percent, above which a warning is reported",
+	)
+
+	TraceCmd.AddCommand(conntrackCmd)
+	utils.RegisterGadgetCommand(conntrackCmd, "conntrack", types.Event{})
+	utils.AddCommonFlags(conntrackCmd, &params)
+}
+
+func conntrackDestination(e *types.Event) string {
+	if e.Daddr == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s %s", e.Protocol, net.JoinHostPort(e.Daddr, strconv.Itoa(int(e.Dport))))
+}
+
+func conntrackDetails(e *types.Event) string {
+	switch e.Kind {
+	case types.KindTableFull:
+		return fmt.Sprintf("%d/%d entries, %d packets dropped", e.Entries, e.Max, e.Dropped)
+	case types.KindTableUsage:
+		usage := uint64(0)
+		if e.Max != 0 {
+			usage = e.Entries * 100 / e.Max
+		}
+		return fmt.Sprintf("%d%% used (%d/%d entries)", usage, e.Entries, e.Max)
+	}
+
+	details := fmt.Sprintf("%d packets dropped", e.Count)
+	if e.Comm != "" {
+		details += fmt.Sprintf(" (%s %d)", e.Comm, e.Pid)
+	}
+	return details
+}
+
+// conntrackTransformLine is called to transform an event to columns format
+// according to the parameters
+func conntrackTransformLine(line string) string {
+	var sb strings.Builder
+	var e types.Event
+
+	if err := json.Unmarshal([]byte(line), &e); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s", utils.WrapInErrUnmarshalOutput(err, line))
+		return ""
+	}
+
+	if e.Type == eventtypes.ERR || e.Type == eventtypes.WARN ||
+		e.Type == eventtypes.DEBUG || e.Type == eventtypes.INFO {
+		fmt.Fprintf(os.Stderr, "%s: node %q: %s", e.Type, e.Node, e.Message)
+		return ""
+	}
+
+	if e.Type != eventtypes.NORMAL {
+		return ""
+	}
+
+	switch params.OutputMode {
+	case utils.OutputModeColumns:
+		sb.WriteString(fmt.Sprintf("%-16s %-16s %-16s %-13s %-16s %-27s %s",
+			e.Node, e.Namespace, e.Pod, e.Kind,
+			e.Saddr, conntrackDestination(&e), conntrackDetails(&e)))
+	case utils.OutputModeCustomColumns:
+		for _, col := range params.CustomColumns {
+			switch col {
+			case "node":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Node))
+			case "namespace":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Namespace))
+			case "pod":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Pod))
+			case "container":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Container))
+			case "kind":
+				sb.WriteString(fmt.Sprintf("%-13s", e.Kind))
+			case "proto":
+				sb.WriteString(fmt.Sprintf("%-6s", e.Protocol))
+			case "saddr":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Saddr))
+			case "daddr":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Daddr))
+			case "dport":
+				sb.WriteString(fmt.Sprintf("%-6d", e.Dport))
+			case "count":
+				sb.WriteString(fmt.Sprintf("%-6d", e.Count))
+			case "pid":
+				sb.WriteString(fmt.Sprintf("%-7d", e.Pid))
+			case "comm":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Comm))
+			case "details":
+				sb.WriteString(conntrackDetails(&e))
+			}
+			sb.WriteRune(' ')
+		}
+	}
+
+	return sb.String()
+}
+
+func getCustomConntrackColsHeader(cols []string) string {
+	var sb strings.Builder
+
+	for _, col := range cols {
+		switch col {
+		case "node":
+			sb.WriteString(fmt.Sprintf("%-16s", "NODE"))
+		case "namespace":
+			sb.WriteString(fmt.Sprintf("%-16s", "NAMESPACE"))
+		case "pod":
+			sb.WriteString(fmt.Sprintf("%-16s", "POD"))
+		case "container":
+			sb.WriteString(fmt.Sprintf("%-16s", "CONTAINER"))
+		case "kind":
+			sb.WriteString(fmt.Sprintf("%-13s", "KIND"))
+		case "proto":
+			sb.WriteString(fmt.Sprintf("%-6s", "PROTO"))
+		case "saddr":
+			sb.WriteString(fmt.Sprintf("%-16s", "SADDR"))
+		case "daddr":
+			sb.WriteString(fmt.Sprintf("%-16s", "DADDR"))
+		case "dport":
+			sb.WriteString(fmt.Sprintf("%-6s", "DPORT"))
+		case "count":
+			sb.WriteString(fmt.Sprintf("%-6s", "COUNT"))
+		case "pid":
+			sb.WriteString(fmt.Sprintf("%-7s", "PID"))
+		case "comm":
+			sb.WriteString(fmt.Sprintf("%-16s", "COMM"))
+		case "details":
+			sb.WriteString("DETAILS")
+		}
+		sb.WriteRune(' ')
+	}
+
+	return sb.String()
+}
@@ -85,6 +85,7 @@ var dnsCmd = &cobra.Command{
 
 func init() {
 	TraceCmd.AddCommand(dnsCmd)
+	utils.RegisterGadgetCommand(dnsCmd, "dns", dnstypes.Event{})
 	utils.AddCommonFlags(dnsCmd, &params)
 }
 
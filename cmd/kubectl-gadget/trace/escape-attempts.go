@@ -0,0 +1,181 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/escapeattempts/types"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+var escapeAttemptsEnforce bool
+
+var escapeAttemptsCmd = &cobra.Command{
+	Use:   "escape-attempts",
+	Short: "Trace actions typical of an attempt to escape from a container to the host",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// print header
+		switch params.OutputMode {
+		case utils.OutputModeCustomColumns:
+			fmt.Println(getCustomEscapeAttemptsColsHeader(params.CustomColumns))
+		case utils.OutputModeColumns:
+			fmt.Printf("%-16s %-16s %-16s %-16s %-6s %-16s %-8s %-12s %-4s %s\n",
+				"NODE", "NAMESPACE", "POD", "CONTAINER",
+				"PID", "COMM", "SEVERITY", "INDICATOR", "RET", "DETAILS")
+		}
+
+		config := &utils.TraceConfig{
+			GadgetName:       "escape-attempts",
+			Operation:        "start",
+			TraceOutputMode:  "Stream",
+			TraceOutputState: "Started",
+			CommonFlags:      &params,
+		}
+
+		if escapeAttemptsEnforce {
+			config.Parameters = map[string]string{
+				"enforce": "true",
+			}
+			config.Annotations = map[string]string{
+				utils.AllowEnforcement: "true",
+			}
+		}
+
+		err := utils.RunTraceAndPrintStream(config, escapeAttemptsTransformLine)
+		if err != nil {
+			return utils.WrapInErrRunGadget(err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	TraceCmd.AddCommand(escapeAttemptsCmd)
+	utils.RegisterGadgetCommand(escapeAttemptsCmd, "escape-attempts", types.Event{})
+	utils.AddCommonFlags(escapeAttemptsCmd, &params)
+
+	escapeAttemptsCmd.PersistentFlags().BoolVarP(
+		&escapeAttemptsEnforce,
+		"enforce",
+		"",
+		false,
+		"Block the mounts of devices and the accesses to /dev/mem and core_pattern instead of only reporting them. Requires a kernel with BPF LSM",
+	)
+}
+
+// escapeAttemptsTransformLine is called to transform an event to columns
+// format according to the parameters
+func escapeAttemptsTransformLine(line string) string {
+	var sb strings.Builder
+	var e types.Event
+
+	if err := json.Unmarshal([]byte(line), &e); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s", utils.WrapInErrUnmarshalOutput(err, line))
+		return ""
+	}
+
+	if e.Type == eventtypes.ERR || e.Type == eventtypes.WARN ||
+		e.Type == eventtypes.DEBUG || e.Type == eventtypes.INFO {
+		fmt.Fprintf(os.Stderr, "%s: node %q: %s", e.Type, e.Node, e.Message)
+		return ""
+	}
+
+	if e.Type != eventtypes.NORMAL {
+		return ""
+	}
+
+	switch params.OutputMode {
+	case utils.OutputModeColumns:
+		details := e.Details
+		if e.Blocked {
+			details += " (blocked)"
+		}
+		sb.WriteString(fmt.Sprintf("%-16s %-16s %-16s %-16s %-6d %-16s %-8s %-12s %-4d %s",
+			e.Node, e.Namespace, e.Pod, e.Container,
+			e.Pid, e.Comm, e.Severity, e.Indicator, e.Retval, details))
+	case utils.OutputModeCustomColumns:
+		for _, col := range params.CustomColumns {
+			switch col {
+			case "node":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Node))
+			case "namespace":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Namespace))
+			case "pod":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Pod))
+			case "container":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Container))
+			case "pid":
+				sb.WriteString(fmt.Sprintf("%-6d", e.Pid))
+			case "comm":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Comm))
+			case "severity":
+				sb.WriteString(fmt.Sprintf("%-8s", e.Severity))
+			case "indicator":
+				sb.WriteString(fmt.Sprintf("%-12s", e.Indicator))
+			case "ret":
+				sb.WriteString(fmt.Sprintf("%-4d", e.Retval))
+			case "details":
+				sb.WriteString(e.Details)
+			case "blocked":
+				sb.WriteString(fmt.Sprintf("%-7t", e.Blocked))
+			}
+			sb.WriteRune(' ')
+		}
+	}
+
+	return sb.String()
+}
+
+func getCustomEscapeAttemptsColsHeader(cols []string) string {
+	var sb strings.Builder
+
+	for _, col := range cols {
+		switch col {
+		case "node":
+			sb.WriteString(fmt.Sprintf("%-16s", "NODE"))
+		case "namespace":
+			sb.WriteString(fmt.Sprintf("%-16s", "NAMESPACE"))
+		case "pod":
+			sb.WriteString(fmt.Sprintf("%-16s", "POD"))
+		case "container":
+			sb.WriteString(fmt.Sprintf("%-16s", "CONTAINER"))
+		case "pid":
+			sb.WriteString(fmt.Sprintf("%-6s", "PID"))
+		case "comm":
+			sb.WriteString(fmt.Sprintf("%-16s", "COMM"))
+		case "severity":
+			sb.WriteString(fmt.Sprintf("%-8s", "SEVERITY"))
+		case "indicator":
+			sb.WriteString(fmt.Sprintf("%-12s", "INDICATOR"))
+		case "ret":
+			sb.WriteString(fmt.Sprintf("%-4s", "RET"))
+		case "details":
+			sb.WriteString("DETAILS")
+		case "blocked":
+			sb.WriteString(fmt.Sprintf("%-7s", "BLOCKED"))
+		}
+		sb.WriteRune(' ')
+	}
+
+	return sb.String()
+}
@@ -59,6 +59,7 @@ var execsnoopCmd = &cobra.Command{
 
 func init() {
 	TraceCmd.AddCommand(execsnoopCmd)
+	utils.RegisterGadgetCommand(execsnoopCmd, "execsnoop", types.Event{})
 	utils.AddCommonFlags(execsnoopCmd, &params)
 }
 
@@ -103,6 +103,7 @@ func init() {
 	)
 
 	TraceCmd.AddCommand(fsslowerCmd)
+	utils.RegisterGadgetCommand(fsslowerCmd, "fsslower", types.Event{})
 	utils.AddCommonFlags(fsslowerCmd, &params)
 }
 
@@ -0,0 +1,165 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/gethostlatency/types"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+var gethostlatencyLibrary string
+
+var gethostlatencyCmd = &cobra.Command{
+	Use:   "gethostlatency",
+	Short: "Trace host name resolutions of the C library with their latency",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// print header
+		switch params.OutputMode {
+		case utils.OutputModeCustomColumns:
+			fmt.Println(getCustomGethostlatencyColsHeader(params.CustomColumns))
+		case utils.OutputModeColumns:
+			fmt.Printf("%-16s %-16s %-16s %-16s %-6s %-16s %-14s %-8s %-14s %s\n",
+				"NODE", "NAMESPACE", "POD", "CONTAINER",
+				"PID", "COMM", "FUNCTION", "LAT(ms)", "ERROR", "HOST")
+		}
+
+		config := &utils.TraceConfig{
+			GadgetName:       "gethostlatency",
+			Operation:        "start",
+			TraceOutputMode:  "Stream",
+			TraceOutputState: "Started",
+			CommonFlags:      &params,
+			Parameters: map[string]string{
+				"library": gethostlatencyLibrary,
+			},
+		}
+
+		err := utils.RunTraceAndPrintStream(config, gethostlatencyTransformLine)
+		if err != nil {
+			return utils.WrapInErrRunGadget(err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	TraceCmd.AddCommand(gethostlatencyCmd)
+	utils.RegisterGadgetCommand(gethostlatencyCmd, "gethostlatency", types.Event{})
+	utils.AddCommonFlags(gethostlatencyCmd, &params)
+
+	gethostlatencyCmd.PersistentFlags().StringVar(
+		&gethostlatencyLibrary,
+		"library",
+		types.LibraryDefault,
+		"Path, in the containers, of the C library whose resolver functions are traced",
+	)
+}
+
+// gethostlatencyTransformLine is called to transform an event to columns
+// format according to the parameters
+func gethostlatencyTransformLine(line string) string {
+	var sb strings.Builder
+	var e types.Event
+
+	if err := json.Unmarshal([]byte(line), &e); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s", utils.WrapInErrUnmarshalOutput(err, line))
+		return ""
+	}
+
+	if e.Type == eventtypes.ERR || e.Type == eventtypes.WARN ||
+		e.Type == eventtypes.DEBUG || e.Type == eventtypes.INFO {
+		fmt.Fprintf(os.Stderr, "%s: node %q: %s", e.Type, e.Node, e.Message)
+		return ""
+	}
+
+	if e.Type != eventtypes.NORMAL {
+		return ""
+	}
+
+	switch params.OutputMode {
+	case utils.OutputModeColumns:
+		sb.WriteString(fmt.Sprintf("%-16s %-16s %-16s %-16s %-6d %-16s %-14s %-8.2f %-14s %s",
+			e.Node, e.Namespace, e.Pod, e.Container,
+			e.Pid, e.Comm, e.Function, float64(e.Latency)/1000.0, e.Error, e.Host))
+	case utils.OutputModeCustomColumns:
+		for _, col := range params.CustomColumns {
+			switch col {
+			case "node":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Node))
+			case "namespace":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Namespace))
+			case "pod":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Pod))
+			case "container":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Container))
+			case "pid":
+				sb.WriteString(fmt.Sprintf("%-6d", e.Pid))
+			case "comm":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Comm))
+			case "function":
+				sb.WriteString(fmt.Sprintf("%-14s", e.Function))
+			case "lat":
+				sb.WriteString(fmt.Sprintf("%-8.2f", float64(e.Latency)/1000.0))
+			case "error":
+				sb.WriteString(fmt.Sprintf("%-14s", e.Error))
+			case "host":
+				sb.WriteString(fmt.Sprintf("%-32s", e.Host))
+			}
+			sb.WriteRune(' ')
+		}
+	}
+
+	return sb.String()
+}
+
+func getCustomGethostlatencyColsHeader(cols []string) string {
+	var sb strings.Builder
+
+	for _, col := range cols {
+		switch col {
+		case "node":
+			sb.WriteString(fmt.Sprintf("%-16s", "NODE"))
+		case "namespace":
+			sb.WriteString(fmt.Sprintf("%-16s", "NAMESPACE"))
+		case "pod":
+			sb.WriteString(fmt.Sprintf("%-16s", "POD"))
+		case "container":
+			sb.WriteString(fmt.Sprintf("%-16s", "CONTAINER"))
+		case "pid":
+			sb.WriteString(fmt.Sprintf("%-6s", "PID"))
+		case "comm":
+			sb.WriteString(fmt.Sprintf("%-16s", "COMM"))
+		case "function":
+			sb.WriteString(fmt.Sprintf("%-14s", "FUNCTION"))
+		case "lat":
+			sb.WriteString(fmt.Sprintf("%-8s", "LAT(ms)"))
+		case "error":
+			sb.WriteString(fmt.Sprintf("%-14s", "ERROR"))
+		case "host":
+			sb.WriteString(fmt.Sprintf("%-32s", "HOST"))
+		}
+		sb.WriteRune(' ')
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,163 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	httptypes "github.com/kinvolk/inspektor-gadget/pkg/gadgets/httpsnoop/types"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+const (
+	FmtAllHTTPSnoop   = "%-16.16s %-16.16s %-16.16s %-22.22s %-7.7s %-6.6s %-8.8s %s"
+	FmtShortHTTPSnoop = "%-16.16s %-22.22s %-7.7s %-6.6s %-8.8s %s"
+)
+
+var colHTTPSnoopLens = map[string]int{
+	"saddr":   16,
+	"daddr":   16,
+	"method":  7,
+	"path":    40,
+	"version": 8,
+	"host":    30,
+	"status":  6,
+	"latency": 10,
+}
+
+var httpPorts string
+
+var httpsnoopCmd = &cobra.Command{
+	Use:   "http",
+	Short: "Trace plaintext HTTP requests with their status code and latency",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		transform := httpsnoopTransformLine
+
+		switch {
+		case params.OutputMode == utils.OutputModeJSON: // don't print any header
+		case params.OutputMode == utils.OutputModeCustomColumns:
+			table := utils.NewTableFormater(params.CustomColumns, colHTTPSnoopLens)
+			fmt.Println(table.GetHeader())
+			transform = table.GetTransformFunc()
+		case params.AllNamespaces:
+			fmt.Printf(FmtAllHTTPSnoop+"\n",
+				"NODE",
+				"NAMESPACE",
+				"POD",
+				"SERVER",
+				"METHOD",
+				"STATUS",
+				"LAT(ms)",
+				"PATH",
+			)
+		default:
+			fmt.Printf(FmtShortHTTPSnoop+"\n",
+				"POD",
+				"SERVER",
+				"METHOD",
+				"STATUS",
+				"LAT(ms)",
+				"PATH",
+			)
+		}
+
+		config := &utils.TraceConfig{
+			GadgetName:       "httpsnoop",
+			Operation:        "start",
+			TraceOutputMode:  "Stream",
+			TraceOutputState: "Started",
+			CommonFlags:      &params,
+			Parameters: map[string]string{
+				httptypes.PortsParam: httpPorts,
+			},
+		}
+
+		err := utils.RunTraceAndPrintStream(config, transform)
+		if err != nil {
+			return utils.WrapInErrRunGadget(err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	TraceCmd.AddCommand(httpsnoopCmd)
+	utils.RegisterGadgetCommand(httpsnoopCmd, "httpsnoop", httptypes.Event{})
+	utils.AddCommonFlags(httpsnoopCmd, &params)
+
+	httpsnoopCmd.PersistentFlags().StringVarP(
+		&httpPorts,
+		"ports",
+		"",
+		"",
+		"Comma-separated list of TCP ports of the HTTP servers (default all the ports)",
+	)
+}
+
+func httpsnoopTransformLine(line string) string {
+	event := &httptypes.Event{}
+	if err := json.Unmarshal([]byte(line), event); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s", utils.WrapInErrUnmarshalOutput(err, line))
+		return ""
+	}
+
+	podMsgSuffix := ""
+	if event.Namespace != "" && event.Pod != "" {
+		podMsgSuffix = ", pod " + event.Namespace + "/" + event.Pod
+	}
+
+	switch event.Type {
+	case eventtypes.ERR:
+		return fmt.Sprintf("Error on node %s%s: %s", event.Node, podMsgSuffix, event.Message)
+	case eventtypes.WARN:
+		return fmt.Sprintf("Warning on node %s%s: %s", event.Node, podMsgSuffix, event.Message)
+	case eventtypes.DEBUG:
+		if !params.Verbose {
+			return ""
+		}
+		return fmt.Sprintf("Debug on node %s%s: %s", event.Node, podMsgSuffix, event.Message)
+	case eventtypes.NORMAL:
+	default:
+		return ""
+	}
+
+	server := net.JoinHostPort(event.Daddr, strconv.Itoa(int(event.Dport)))
+
+	// Requests without response are reported after a timeout
+	status, latency := "-", "-"
+	if event.Status != 0 {
+		status = strconv.Itoa(event.Status)
+		latency = fmt.Sprintf("%.3f", float64(event.Latency)/1000.0)
+	}
+
+	path := event.Path
+	if event.Host != "" {
+		path = event.Host + path
+	}
+
+	if params.AllNamespaces {
+		return fmt.Sprintf(FmtAllHTTPSnoop, event.Node, event.Namespace, event.Pod,
+			server, event.Method, status, latency, path)
+	}
+	return fmt.Sprintf(FmtShortHTTPSnoop, event.Pod, server, event.Method, status, latency, path)
+}
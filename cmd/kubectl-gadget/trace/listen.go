@@ -0,0 +1,175 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/solisten/types"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+var solistenCmd = &cobra.Command{
+	Use:   "listen",
+	Short: "Trace the TCP and UDP sockets starting to listen on a port",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// print header
+		switch params.OutputMode {
+		case utils.OutputModeCustomColumns:
+			fmt.Println(getCustomSolistenColsHeader(params.CustomColumns))
+		case utils.OutputModeColumns:
+			fmt.Printf("%-16s %-16s %-16s %-16s %-6s %-16s %-5s %-5s %-7s %s\n",
+				"NODE", "NAMESPACE", "POD", "CONTAINER",
+				"PID", "COMM", "PROTO", "PORT", "BACKLOG", "ADDR")
+		}
+
+		config := &utils.TraceConfig{
+			GadgetName:       "solisten",
+			Operation:        "start",
+			TraceOutputMode:  "Stream",
+			TraceOutputState: "Started",
+			CommonFlags:      &params,
+		}
+
+		err := utils.RunTraceAndPrintStream(config, solistenTransformLine)
+		if err != nil {
+			return utils.WrapInErrRunGadget(err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	TraceCmd.AddCommand(solistenCmd)
+	utils.RegisterGadgetCommand(solistenCmd, "solisten", types.Event{})
+	utils.AddCommonFlags(solistenCmd, &params)
+}
+
+// solistenFormatBacklog returns the size of the accept backlog used by the
+// kernel, or "-" for the UDP sockets which don't have one.
+func solistenFormatBacklog(e *types.Event) string {
+	if e.Protocol != "TCP" {
+		return "-"
+	}
+	return strconv.FormatUint(uint64(e.MaxBacklog), 10)
+}
+
+// solistenTransformLine is called to transform an event to columns
+// format according to the parameters
+func solistenTransformLine(line string) string {
+	var sb strings.Builder
+	var e types.Event
+
+	if err := json.Unmarshal([]byte(line), &e); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s", utils.WrapInErrUnmarshalOutput(err, line))
+		return ""
+	}
+
+	if e.Type == eventtypes.ERR || e.Type == eventtypes.WARN ||
+		e.Type == eventtypes.DEBUG || e.Type == eventtypes.INFO {
+		fmt.Fprintf(os.Stderr, "%s: node %q: %s", e.Type, e.Node, e.Message)
+		return ""
+	}
+
+	if e.Type != eventtypes.NORMAL {
+		return ""
+	}
+
+	switch params.OutputMode {
+	case utils.OutputModeColumns:
+		sb.WriteString(fmt.Sprintf("%-16s %-16s %-16s %-16s %-6d %-16s %-5s %-5d %-7s %s",
+			e.Node, e.Namespace, e.Pod, e.Container,
+			e.Pid, e.Comm, e.Protocol, e.Port, solistenFormatBacklog(&e), e.Addr))
+	case utils.OutputModeCustomColumns:
+		for _, col := range params.CustomColumns {
+			switch col {
+			case "node":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Node))
+			case "namespace":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Namespace))
+			case "pod":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Pod))
+			case "container":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Container))
+			case "pid":
+				sb.WriteString(fmt.Sprintf("%-6d", e.Pid))
+			case "uid":
+				sb.WriteString(fmt.Sprintf("%-6d", e.UID))
+			case "comm":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Comm))
+			case "proto":
+				sb.WriteString(fmt.Sprintf("%-5s", e.Protocol))
+			case "ip":
+				sb.WriteString(fmt.Sprintf("%-2d", e.IPVersion))
+			case "addr":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Addr))
+			case "port":
+				sb.WriteString(fmt.Sprintf("%-5d", e.Port))
+			case "backlog":
+				sb.WriteString(fmt.Sprintf("%-7d", e.Backlog))
+			case "maxbacklog":
+				sb.WriteString(fmt.Sprintf("%-10s", solistenFormatBacklog(&e)))
+			}
+			sb.WriteRune(' ')
+		}
+	}
+
+	return sb.String()
+}
+
+func getCustomSolistenColsHeader(cols []string) string {
+	var sb strings.Builder
+
+	for _, col := range cols {
+		switch col {
+		case "node":
+			sb.WriteString(fmt.Sprintf("%-16s", "NODE"))
+		case "namespace":
+			sb.WriteString(fmt.Sprintf("%-16s", "NAMESPACE"))
+		case "pod":
+			sb.WriteString(fmt.Sprintf("%-16s", "POD"))
+		case "container":
+			sb.WriteString(fmt.Sprintf("%-16s", "CONTAINER"))
+		case "pid":
+			sb.WriteString(fmt.Sprintf("%-6s", "PID"))
+		case "uid":
+			sb.WriteString(fmt.Sprintf("%-6s", "UID"))
+		case "comm":
+			sb.WriteString(fmt.Sprintf("%-16s", "COMM"))
+		case "proto":
+			sb.WriteString(fmt.Sprintf("%-5s", "PROTO"))
+		case "ip":
+			sb.WriteString(fmt.Sprintf("%-2s", "IP"))
+		case "addr":
+			sb.WriteString(fmt.Sprintf("%-16s", "ADDR"))
+		case "port":
+			sb.WriteString(fmt.Sprintf("%-5s", "PORT"))
+		case "backlog":
+			sb.WriteString(fmt.Sprintf("%-7s", "BACKLOG"))
+		case "maxbacklog":
+			sb.WriteString(fmt.Sprintf("%-10s", "MAXBACKLOG"))
+		}
+		sb.WriteRune(' ')
+	}
+
+	return sb.String()
+}
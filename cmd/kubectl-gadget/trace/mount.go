@@ -59,6 +59,7 @@ var mountsnoopCmd = &cobra.Command{
 
 func init() {
 	TraceCmd.AddCommand(mountsnoopCmd)
+	utils.RegisterGadgetCommand(mountsnoopCmd, "mountsnoop", types.Event{})
 	utils.AddCommonFlags(mountsnoopCmd, &params)
 }
 
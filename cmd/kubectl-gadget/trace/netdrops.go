@@ -0,0 +1,161 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/netdrops/types"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+var netdropsCmd = &cobra.Command{
+	Use:   "netdrops",
+	Short: "Trace the packets dropped on the network interfaces of pods",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// print header
+		switch params.OutputMode {
+		case utils.OutputModeCustomColumns:
+			fmt.Println(getCustomNetdropsColsHeader(params.CustomColumns))
+		case utils.OutputModeColumns:
+			fmt.Printf("%-16s %-16s %-16s %-16s %-9s %-24s %-8s %-10s %s\n",
+				"NODE", "NAMESPACE", "POD", "INTERFACE", "DIRECTION",
+				"REASON", "PACKETS", "BYTES", "TOTAL")
+		}
+
+		config := &utils.TraceConfig{
+			GadgetName:       "netdrops",
+			Operation:        "start",
+			TraceOutputMode:  "Stream",
+			TraceOutputState: "Started",
+			CommonFlags:      &params,
+		}
+
+		err := utils.RunTraceAndPrintStream(config, netdropsTransformLine)
+		if err != nil {
+			return utils.WrapInErrRunGadget(err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	TraceCmd.AddCommand(netdropsCmd)
+	utils.RegisterGadgetCommand(netdropsCmd, "netdrops", types.Event{})
+	utils.AddCommonFlags(netdropsCmd, &params)
+}
+
+func netdropsReason(e *types.Event) string {
+	if e.Reason == "" {
+		return "-"
+	}
+	return e.Reason
+}
+
+// netdropsTransformLine is called to transform an event to columns format
+// according to the parameters
+func netdropsTransformLine(line string) string {
+	var sb strings.Builder
+	var e types.Event
+
+	if err := json.Unmarshal([]byte(line), &e); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s", utils.WrapInErrUnmarshalOutput(err, line))
+		return ""
+	}
+
+	if e.Type == eventtypes.ERR || e.Type == eventtypes.WARN ||
+		e.Type == eventtypes.DEBUG || e.Type == eventtypes.INFO {
+		fmt.Fprintf(os.Stderr, "%s: node %q: %s", e.Type, e.Node, e.Message)
+		return ""
+	}
+
+	if e.Type != eventtypes.NORMAL {
+		return ""
+	}
+
+	switch params.OutputMode {
+	case utils.OutputModeColumns:
+		sb.WriteString(fmt.Sprintf("%-16s %-16s %-16s %-16s %-9s %-24s %-8d %-10d %d",
+			e.Node, e.Namespace, e.Pod, e.Interface, e.Direction,
+			netdropsReason(&e), e.Packets, e.Bytes, e.TotalPackets))
+	case utils.OutputModeCustomColumns:
+		for _, col := range params.CustomColumns {
+			switch col {
+			case "node":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Node))
+			case "namespace":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Namespace))
+			case "pod":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Pod))
+			case "interface":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Interface))
+			case "direction":
+				sb.WriteString(fmt.Sprintf("%-9s", e.Direction))
+			case "reason":
+				sb.WriteString(fmt.Sprintf("%-24s", netdropsReason(&e)))
+			case "packets":
+				sb.WriteString(fmt.Sprintf("%-8d", e.Packets))
+			case "bytes":
+				sb.WriteString(fmt.Sprintf("%-10d", e.Bytes))
+			case "totalpackets":
+				sb.WriteString(fmt.Sprintf("%-12d", e.TotalPackets))
+			case "totalbytes":
+				sb.WriteString(fmt.Sprintf("%-12d", e.TotalBytes))
+			}
+			sb.WriteRune(' ')
+		}
+	}
+
+	return sb.String()
+}
+
+func getCustomNetdropsColsHeader(cols []string) string {
+	var sb strings.Builder
+
+	for _, col := range cols {
+		switch col {
+		case "node":
+			sb.WriteString(fmt.Sprintf("%-16s", "NODE"))
+		case "namespace":
+			sb.WriteString(fmt.Sprintf("%-16s", "NAMESPACE"))
+		case "pod":
+			sb.WriteString(fmt.Sprintf("%-16s", "POD"))
+		case "interface":
+			sb.WriteString(fmt.Sprintf("%-16s", "INTERFACE"))
+		case "direction":
+			sb.WriteString(fmt.Sprintf("%-9s", "DIRECTION"))
+		case "reason":
+			sb.WriteString(fmt.Sprintf("%-24s", "REASON"))
+		case "packets":
+			sb.WriteString(fmt.Sprintf("%-8s", "PACKETS"))
+		case "bytes":
+			sb.WriteString(fmt.Sprintf("%-10s", "BYTES"))
+		case "totalpackets":
+			sb.WriteString(fmt.Sprintf("%-12s", "TOTALPACKETS"))
+		case "totalbytes":
+			sb.WriteString(fmt.Sprintf("%-12s", "TOTALBYTES"))
+		}
+		sb.WriteRune(' ')
+	}
+
+	return sb.String()
+}
@@ -59,6 +59,7 @@ var oomkillCmd = &cobra.Command{
 
 func init() {
 	TraceCmd.AddCommand(oomkillCmd)
+	utils.RegisterGadgetCommand(oomkillCmd, "oomkill", types.Event{})
 	utils.AddCommonFlags(oomkillCmd, &params)
 }
 
@@ -88,6 +89,9 @@ func oomkillTransformLine(line string) string {
 		sb.WriteString(fmt.Sprintf("%-16s %-16s %-16s %-16s %-6d %-16s %-6d %-6d %-16s",
 			e.Node, e.Namespace, e.Pod, e.Container,
 			e.KilledPid, e.KilledComm, e.Pages, e.TriggeredPid, e.TriggeredComm))
+		for _, line := range e.KernelLog {
+			sb.WriteString(fmt.Sprintf("\n    %s", line))
+		}
 	case utils.OutputModeCustomColumns:
 		for _, col := range params.CustomColumns {
 			switch col {
@@ -59,6 +59,7 @@ var opensnoopCmd = &cobra.Command{
 
 func init() {
 	TraceCmd.AddCommand(opensnoopCmd)
+	utils.RegisterGadgetCommand(opensnoopCmd, "opensnoop", types.Event{})
 	utils.AddCommonFlags(opensnoopCmd, &params)
 }
 
@@ -0,0 +1,188 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/ping/types"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+// flags
+var pingTimeout uint
+
+var pingCmd = &cobra.Command{
+	Use:   "ping",
+	Short: "Trace ICMP echo requests with their latency and failures",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if pingTimeout == 0 {
+			return utils.WrapInErrInvalidArg("--reply-timeout",
+				fmt.Errorf("must be greater than 0"))
+		}
+
+		// print header
+		switch params.OutputMode {
+		case utils.OutputModeCustomColumns:
+			fmt.Println(getCustomPingColsHeader(params.CustomColumns))
+		case utils.OutputModeColumns:
+			fmt.Printf("%-16s %-16s %-16s %-3s %-16s %-16s %-6s %-6s %-8s %s\n",
+				"NODE", "NAMESPACE", "POD", "DIR",
+				"SADDR", "DADDR", "ID", "SEQ", "LAT(ms)", "STATUS")
+		}
+
+		config := &utils.TraceConfig{
+			GadgetName:       "ping",
+			Operation:        "start",
+			TraceOutputMode:  "Stream",
+			TraceOutputState: "Started",
+			CommonFlags:      &params,
+			Parameters: map[string]string{
+				"timeout": strconv.FormatUint(uint64(pingTimeout), 10),
+			},
+		}
+
+		err := utils.RunTraceAndPrintStream(config, pingTransformLine)
+		if err != nil {
+			return utils.WrapInErrRunGadget(err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	pingCmd.Flags().UintVarP(
+		&pingTimeout, "reply-timeout", "", types.TimeoutDefault,
+		"Time in seconds after which an echo request without reply is reported as failed",
+	)
+
+	TraceCmd.AddCommand(pingCmd)
+	utils.RegisterGadgetCommand(pingCmd, "ping", types.Event{})
+	utils.AddCommonFlags(pingCmd, &params)
+}
+
+func pingStatus(e *types.Event) string {
+	if !e.Failed {
+		return "ok"
+	}
+	if e.Reporter != "" {
+		return fmt.Sprintf("%s (from %s)", e.Reason, e.Reporter)
+	}
+	return e.Reason
+}
+
+func pingLatency(e *types.Event) string {
+	if e.Failed {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f", e.Latency)
+}
+
+// pingTransformLine is called to transform an event to columns format
+// according to the parameters
+func pingTransformLine(line string) string {
+	var sb strings.Builder
+	var e types.Event
+
+	if err := json.Unmarshal([]byte(line), &e); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s", utils.WrapInErrUnmarshalOutput(err, line))
+		return ""
+	}
+
+	if e.Type == eventtypes.ERR || e.Type == eventtypes.WARN ||
+		e.Type == eventtypes.DEBUG || e.Type == eventtypes.INFO {
+		fmt.Fprintf(os.Stderr, "%s: node %q: %s", e.Type, e.Node, e.Message)
+		return ""
+	}
+
+	if e.Type != eventtypes.NORMAL {
+		return ""
+	}
+
+	switch params.OutputMode {
+	case utils.OutputModeColumns:
+		sb.WriteString(fmt.Sprintf("%-16s %-16s %-16s %-3s %-16s %-16s %-6d %-6d %-8s %s",
+			e.Node, e.Namespace, e.Pod, e.Direction,
+			e.Saddr, e.Daddr, e.ID, e.Seq, pingLatency(&e), pingStatus(&e)))
+	case utils.OutputModeCustomColumns:
+		for _, col := range params.CustomColumns {
+			switch col {
+			case "node":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Node))
+			case "namespace":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Namespace))
+			case "pod":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Pod))
+			case "dir":
+				sb.WriteString(fmt.Sprintf("%-3s", e.Direction))
+			case "saddr":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Saddr))
+			case "daddr":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Daddr))
+			case "id":
+				sb.WriteString(fmt.Sprintf("%-6d", e.ID))
+			case "seq":
+				sb.WriteString(fmt.Sprintf("%-6d", e.Seq))
+			case "lat":
+				sb.WriteString(fmt.Sprintf("%-8s", pingLatency(&e)))
+			case "status":
+				sb.WriteString(fmt.Sprintf("%-6s", pingStatus(&e)))
+			}
+			sb.WriteRune(' ')
+		}
+	}
+
+	return sb.String()
+}
+
+func getCustomPingColsHeader(cols []string) string {
+	var sb strings.Builder
+
+	for _, col := range cols {
+		switch col {
+		case "node":
+			sb.WriteString(fmt.Sprintf("%-16s", "NODE"))
+		case "namespace":
+			sb.WriteString(fmt.Sprintf("%-16s", "NAMESPACE"))
+		case "pod":
+			sb.WriteString(fmt.Sprintf("%-16s", "POD"))
+		case "dir":
+			sb.WriteString(fmt.Sprintf("%-3s", "DIR"))
+		case "saddr":
+			sb.WriteString(fmt.Sprintf("%-16s", "SADDR"))
+		case "daddr":
+			sb.WriteString(fmt.Sprintf("%-16s", "DADDR"))
+		case "id":
+			sb.WriteString(fmt.Sprintf("%-6s", "ID"))
+		case "seq":
+			sb.WriteString(fmt.Sprintf("%-6s", "SEQ"))
+		case "lat":
+			sb.WriteString(fmt.Sprintf("%-8s", "LAT(ms)"))
+		case "status":
+			sb.WriteString(fmt.Sprintf("%-6s", "STATUS"))
+		}
+		sb.WriteRune(' ')
+	}
+
+	return sb.String()
+}
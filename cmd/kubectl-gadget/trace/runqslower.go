@@ -0,0 +1,174 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/runqslower/types"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+// flags
+var runqslowerMinLatency uint
+
+var runqslowerCmd = &cobra.Command{
+	Use:   "runqslower",
+	Short: "Trace threads waiting on the run queue longer than a threshold",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// print header
+		switch params.OutputMode {
+		case utils.OutputModeCustomColumns:
+			fmt.Println(getCustomRunqslowerColsHeader(params.CustomColumns))
+		case utils.OutputModeColumns:
+			fmt.Printf("%-16s %-16s %-16s %-16s %-6s %-16s %-10s %-16s %s\n",
+				"NODE", "NAMESPACE", "POD", "CONTAINER", "PID", "COMM", "LAT(µs)",
+				"PREV-POD", "PREV-COMM")
+		}
+
+		config := &utils.TraceConfig{
+			GadgetName:       "runqslower",
+			Operation:        "start",
+			TraceOutputMode:  "Stream",
+			TraceOutputState: "Started",
+			CommonFlags:      &params,
+			Parameters: map[string]string{
+				"minlatency": strconv.FormatUint(uint64(runqslowerMinLatency), 10),
+			},
+		}
+
+		err := utils.RunTraceAndPrintStream(config, runqslowerTransformLine)
+		if err != nil {
+			return utils.WrapInErrRunGadget(err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	runqslowerCmd.Flags().UintVarP(
+		&runqslowerMinLatency, "min", "m", types.MinLatencyDefault,
+		"Min latency to trace, in µs",
+	)
+
+	TraceCmd.AddCommand(runqslowerCmd)
+	utils.RegisterGadgetCommand(runqslowerCmd, "runqslower", types.Event{})
+	utils.AddCommonFlags(runqslowerCmd, &params)
+}
+
+// runqslowerTransformLine is called to transform an event to columns
+// format according to the parameters
+func runqslowerTransformLine(line string) string {
+	var sb strings.Builder
+	var e types.Event
+
+	if err := json.Unmarshal([]byte(line), &e); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s", utils.WrapInErrUnmarshalOutput(err, line))
+		return ""
+	}
+
+	if e.Type == eventtypes.ERR || e.Type == eventtypes.WARN ||
+		e.Type == eventtypes.DEBUG || e.Type == eventtypes.INFO {
+		fmt.Fprintf(os.Stderr, "%s: node %q: %s", e.Type, e.Node, e.Message)
+		return ""
+	}
+
+	if e.Type != eventtypes.NORMAL {
+		return ""
+	}
+
+	switch params.OutputMode {
+	case utils.OutputModeColumns:
+		sb.WriteString(fmt.Sprintf("%-16s %-16s %-16s %-16s %-6d %-16s %-10d %-16s %s",
+			e.Node, e.Namespace, e.Pod, e.Container, e.Pid, e.Comm, e.Latency,
+			e.PrevPod, e.PrevComm))
+	case utils.OutputModeCustomColumns:
+		for _, col := range params.CustomColumns {
+			switch col {
+			case "node":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Node))
+			case "namespace":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Namespace))
+			case "pod":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Pod))
+			case "container":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Container))
+			case "pid":
+				sb.WriteString(fmt.Sprintf("%-6d", e.Pid))
+			case "comm":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Comm))
+			case "latency":
+				sb.WriteString(fmt.Sprintf("%-10d", e.Latency))
+			case "prevnamespace":
+				sb.WriteString(fmt.Sprintf("%-16s", e.PrevNamespace))
+			case "prevpod":
+				sb.WriteString(fmt.Sprintf("%-16s", e.PrevPod))
+			case "prevcontainer":
+				sb.WriteString(fmt.Sprintf("%-16s", e.PrevContainer))
+			case "prevpid":
+				sb.WriteString(fmt.Sprintf("%-6d", e.PrevPid))
+			case "prevcomm":
+				sb.WriteString(fmt.Sprintf("%-16s", e.PrevComm))
+			}
+			sb.WriteRune(' ')
+		}
+	}
+
+	return sb.String()
+}
+
+func getCustomRunqslowerColsHeader(cols []string) string {
+	var sb strings.Builder
+
+	for _, col := range cols {
+		switch col {
+		case "node":
+			sb.WriteString(fmt.Sprintf("%-16s", "NODE"))
+		case "namespace":
+			sb.WriteString(fmt.Sprintf("%-16s", "NAMESPACE"))
+		case "pod":
+			sb.WriteString(fmt.Sprintf("%-16s", "POD"))
+		case "container":
+			sb.WriteString(fmt.Sprintf("%-16s", "CONTAINER"))
+		case "pid":
+			sb.WriteString(fmt.Sprintf("%-6s", "PID"))
+		case "comm":
+			sb.WriteString(fmt.Sprintf("%-16s", "COMM"))
+		case "latency":
+			sb.WriteString(fmt.Sprintf("%-10s", "LAT(µs)"))
+		case "prevnamespace":
+			sb.WriteString(fmt.Sprintf("%-16s", "PREV-NAMESPACE"))
+		case "prevpod":
+			sb.WriteString(fmt.Sprintf("%-16s", "PREV-POD"))
+		case "prevcontainer":
+			sb.WriteString(fmt.Sprintf("%-16s", "PREV-CONTAINER"))
+		case "prevpid":
+			sb.WriteString(fmt.Sprintf("%-6s", "PREV-PID"))
+		case "prevcomm":
+			sb.WriteString(fmt.Sprintf("%-16s", "PREV-COMM"))
+		}
+		sb.WriteRune(' ')
+	}
+
+	return sb.String()
+}
@@ -22,29 +22,38 @@ import (
 	"strings"
 
 	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/sigsnoop/tracer"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/sigsnoop/types"
 	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
 	"github.com/spf13/cobra"
 )
 
 var (
-	pid    uint
-	sig    string
-	failed bool
+	pid       uint
+	sig       string
+	failed    bool
+	direction string
 )
 
 var sigsnoopCmd = &cobra.Command{
 	Use:   "signal",
 	Short: "Trace signals received by processes",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		switch tracer.Direction(direction) {
+		case tracer.DirectionAll, tracer.DirectionSent, tracer.DirectionReceived:
+		default:
+			return utils.WrapInErrInvalidArg("--direction",
+				fmt.Errorf("valid values are %s", strings.Join(tracer.Directions, ", ")))
+		}
+
 		switch params.OutputMode {
 		case utils.OutputModeJSON: // don't print any header
 		case utils.OutputModeCustomColumns:
 			fmt.Println(getCustomSigsnoopColsHeader(params.CustomColumns))
 		case utils.OutputModeColumns:
-			fmt.Printf("%-16s %-16s %-16s %-16s %-6s %-16s %-9s %-6s %-6s\n",
+			fmt.Printf("%-16s %-16s %-16s %-16s %-6s %-16s %-9s %-6s %-6s %-8s\n",
 				"NODE", "NAMESPACE", "POD", "CONTAINER",
-				"PID", "COMM", "SIGNAL", "TPID", "RET")
+				"PID", "COMM", "SIGNAL", "TPID", "RET", "ORIGIN")
 		}
 
 		config := &utils.TraceConfig{
@@ -54,9 +63,10 @@ var sigsnoopCmd = &cobra.Command{
 			TraceOutputState: "Started",
 			CommonFlags:      &params,
 			Parameters: map[string]string{
-				"signal": sig,
-				"pid":    strconv.FormatUint(uint64(pid), 10),
-				"failed": strconv.FormatBool(failed),
+				"signal":    sig,
+				"pid":       strconv.FormatUint(uint64(pid), 10),
+				"failed":    strconv.FormatBool(failed),
+				"direction": direction,
 			},
 		}
 
@@ -71,6 +81,7 @@ var sigsnoopCmd = &cobra.Command{
 
 func init() {
 	TraceCmd.AddCommand(sigsnoopCmd)
+	utils.RegisterGadgetCommand(sigsnoopCmd, "sigsnoop", types.Event{})
 	utils.AddCommonFlags(sigsnoopCmd, &params)
 
 	sigsnoopCmd.PersistentFlags().UintVarP(
@@ -94,6 +105,13 @@ func init() {
 		false,
 		`Show only events where the syscall sending a signal failed`,
 	)
+	sigsnoopCmd.PersistentFlags().StringVarP(
+		&direction,
+		"direction",
+		"",
+		string(tracer.DirectionAll),
+		fmt.Sprintf("Show the signals sent, received or both by the selected pods. Possible values are: %s", strings.Join(tracer.Directions, ", ")),
+	)
 }
 
 func sigsnoopTransformLine(line string) string {
@@ -117,9 +135,12 @@ func sigsnoopTransformLine(line string) string {
 
 	switch params.OutputMode {
 	case utils.OutputModeColumns:
-		sb.WriteString(fmt.Sprintf("%-16s %-16s %-16s %-16s %-6d %-16s %-9s %-6d %-6d",
+		sb.WriteString(fmt.Sprintf("%-16s %-16s %-16s %-16s %-6d %-16s %-9s %-6d %-6d %-8s",
 			e.Node, e.Namespace, e.Pod, e.Container, e.Pid, e.Comm,
-			e.Signal, e.TargetPid, e.Retval))
+			e.Signal, e.TargetPid, e.Retval, e.Origin))
+		for _, line := range e.KernelLog {
+			sb.WriteString(fmt.Sprintf("\n    %s", line))
+		}
 	case utils.OutputModeCustomColumns:
 		for _, col := range params.CustomColumns {
 			switch col {
@@ -141,6 +162,14 @@ func sigsnoopTransformLine(line string) string {
 				sb.WriteString(fmt.Sprintf("%-6d", e.TargetPid))
 			case "ret":
 				sb.WriteString(fmt.Sprintf("%-6d", e.Retval))
+			case "tnamespace":
+				sb.WriteString(fmt.Sprintf("%-16s", e.TargetNamespace))
+			case "tpod":
+				sb.WriteString(fmt.Sprintf("%-16s", e.TargetPod))
+			case "tcontainer":
+				sb.WriteString(fmt.Sprintf("%-16s", e.TargetContainer))
+			case "origin":
+				sb.WriteString(fmt.Sprintf("%-8s", e.Origin))
 			}
 			sb.WriteRune(' ')
 		}
@@ -172,6 +201,14 @@ func getCustomSigsnoopColsHeader(cols []string) string {
 			sb.WriteString(fmt.Sprintf("%-6s", "TPID"))
 		case "ret":
 			sb.WriteString(fmt.Sprintf("%-6s", "RET"))
+		case "tnamespace":
+			sb.WriteString(fmt.Sprintf("%-16s", "TNAMESPACE"))
+		case "tpod":
+			sb.WriteString(fmt.Sprintf("%-16s", "TPOD"))
+		case "tcontainer":
+			sb.WriteString(fmt.Sprintf("%-16s", "TCONTAINER"))
+		case "origin":
+			sb.WriteString(fmt.Sprintf("%-8s", "ORIGIN"))
 		}
 		sb.WriteRune(' ')
 	}
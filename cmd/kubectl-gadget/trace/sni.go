@@ -80,6 +80,7 @@ var snisnoopCmd = &cobra.Command{
 
 func init() {
 	TraceCmd.AddCommand(snisnoopCmd)
+	utils.RegisterGadgetCommand(snisnoopCmd, "snisnoop", snitypes.Event{})
 	utils.AddCommonFlags(snisnoopCmd, &params)
 }
 
@@ -0,0 +1,175 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/suspicious-exec/types"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+var suspiciousExecNoHash bool
+
+var suspiciousExecCmd = &cobra.Command{
+	Use:   "suspicious-exec",
+	Short: "Trace executions of binaries from world-writable directories or tmpfs",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// print header
+		switch params.OutputMode {
+		case utils.OutputModeCustomColumns:
+			fmt.Println(getCustomSuspiciousExecColsHeader(params.CustomColumns))
+		case utils.OutputModeColumns:
+			fmt.Printf("%-16s %-16s %-16s %-16s %-6s %-6s %-6s %-16s %-32s %s\n",
+				"NODE", "NAMESPACE", "POD", "CONTAINER",
+				"PID", "PPID", "UID", "COMM", "REASONS", "PATH")
+		}
+
+		config := &utils.TraceConfig{
+			GadgetName:       "suspicious-exec",
+			Operation:        "start",
+			TraceOutputMode:  "Stream",
+			TraceOutputState: "Started",
+			CommonFlags:      &params,
+			Parameters: map[string]string{
+				"hash": strconv.FormatBool(!suspiciousExecNoHash),
+			},
+		}
+
+		err := utils.RunTraceAndPrintStream(config, suspiciousExecTransformLine)
+		if err != nil {
+			return utils.WrapInErrRunGadget(err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	TraceCmd.AddCommand(suspiciousExecCmd)
+	utils.RegisterGadgetCommand(suspiciousExecCmd, "suspicious-exec", types.Event{})
+	utils.AddCommonFlags(suspiciousExecCmd, &params)
+
+	suspiciousExecCmd.PersistentFlags().BoolVarP(
+		&suspiciousExecNoHash,
+		"no-hash",
+		"",
+		false,
+		`Don't compute the SHA-256 of the binaries executed`,
+	)
+}
+
+// suspiciousExecTransformLine is called to transform an event to columns
+// format according to the parameters
+func suspiciousExecTransformLine(line string) string {
+	var sb strings.Builder
+	var e types.Event
+
+	if err := json.Unmarshal([]byte(line), &e); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s", utils.WrapInErrUnmarshalOutput(err, line))
+		return ""
+	}
+
+	if e.Type == eventtypes.ERR || e.Type == eventtypes.WARN ||
+		e.Type == eventtypes.DEBUG || e.Type == eventtypes.INFO {
+		fmt.Fprintf(os.Stderr, "%s: node %q: %s", e.Type, e.Node, e.Message)
+		return ""
+	}
+
+	if e.Type != eventtypes.NORMAL {
+		return ""
+	}
+
+	switch params.OutputMode {
+	case utils.OutputModeColumns:
+		sb.WriteString(fmt.Sprintf("%-16s %-16s %-16s %-16s %-6d %-6d %-6d %-16s %-32s %s",
+			e.Node, e.Namespace, e.Pod, e.Container,
+			e.Pid, e.Ppid, e.UID, e.Comm, strings.Join(e.Reasons, ","), e.Path))
+	case utils.OutputModeCustomColumns:
+		for _, col := range params.CustomColumns {
+			switch col {
+			case "node":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Node))
+			case "namespace":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Namespace))
+			case "pod":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Pod))
+			case "container":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Container))
+			case "pid":
+				sb.WriteString(fmt.Sprintf("%-6d", e.Pid))
+			case "ppid":
+				sb.WriteString(fmt.Sprintf("%-6d", e.Ppid))
+			case "uid":
+				sb.WriteString(fmt.Sprintf("%-6d", e.UID))
+			case "comm":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Comm))
+			case "reasons":
+				sb.WriteString(fmt.Sprintf("%-32s", strings.Join(e.Reasons, ",")))
+			case "mode":
+				sb.WriteString(fmt.Sprintf("%-10s", os.FileMode(e.Mode&0o777)))
+			case "path":
+				sb.WriteString(fmt.Sprintf("%-24s", e.Path))
+			case "sha256":
+				sb.WriteString(fmt.Sprintf("%-64s", e.Sha256))
+			}
+			sb.WriteRune(' ')
+		}
+	}
+
+	return sb.String()
+}
+
+func getCustomSuspiciousExecColsHeader(cols []string) string {
+	var sb strings.Builder
+
+	for _, col := range cols {
+		switch col {
+		case "node":
+			sb.WriteString(fmt.Sprintf("%-16s", "NODE"))
+		case "namespace":
+			sb.WriteString(fmt.Sprintf("%-16s", "NAMESPACE"))
+		case "pod":
+			sb.WriteString(fmt.Sprintf("%-16s", "POD"))
+		case "container":
+			sb.WriteString(fmt.Sprintf("%-16s", "CONTAINER"))
+		case "pid":
+			sb.WriteString(fmt.Sprintf("%-6s", "PID"))
+		case "ppid":
+			sb.WriteString(fmt.Sprintf("%-6s", "PPID"))
+		case "uid":
+			sb.WriteString(fmt.Sprintf("%-6s", "UID"))
+		case "comm":
+			sb.WriteString(fmt.Sprintf("%-16s", "COMM"))
+		case "reasons":
+			sb.WriteString(fmt.Sprintf("%-32s", "REASONS"))
+		case "mode":
+			sb.WriteString(fmt.Sprintf("%-10s", "MODE"))
+		case "path":
+			sb.WriteString(fmt.Sprintf("%-24s", "PATH"))
+		case "sha256":
+			sb.WriteString(fmt.Sprintf("%-64s", "SHA256"))
+		}
+		sb.WriteRune(' ')
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,175 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/syncsnoop/types"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+var syncsnoopMinLatency uint
+
+var syncsnoopCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Trace sync, syncfs, fsync and fdatasync system calls with their latency",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// print header
+		switch params.OutputMode {
+		case utils.OutputModeCustomColumns:
+			fmt.Println(getCustomSyncsnoopColsHeader(params.CustomColumns))
+		case utils.OutputModeColumns:
+			fmt.Printf("%-16s %-16s %-16s %-16s %-6s %-16s %-9s %-8s %3s %s\n",
+				"NODE", "NAMESPACE", "POD", "CONTAINER",
+				"PID", "COMM", "SYSCALL", "LAT(ms)", "ERR", "FILE")
+		}
+
+		config := &utils.TraceConfig{
+			GadgetName:       "syncsnoop",
+			Operation:        "start",
+			TraceOutputMode:  "Stream",
+			TraceOutputState: "Started",
+			CommonFlags:      &params,
+			Parameters: map[string]string{
+				"minlatency": strconv.FormatUint(uint64(syncsnoopMinLatency), 10),
+			},
+		}
+
+		err := utils.RunTraceAndPrintStream(config, syncsnoopTransformLine)
+		if err != nil {
+			return utils.WrapInErrRunGadget(err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	TraceCmd.AddCommand(syncsnoopCmd)
+	utils.RegisterGadgetCommand(syncsnoopCmd, "syncsnoop", types.Event{})
+	utils.AddCommonFlags(syncsnoopCmd, &params)
+
+	syncsnoopCmd.PersistentFlags().UintVarP(
+		&syncsnoopMinLatency,
+		"min",
+		"m",
+		types.MinLatencyDefault,
+		"Min latency to trace, in ms",
+	)
+}
+
+// syncsnoopTransformLine is called to transform an event to columns
+// format according to the parameters
+func syncsnoopTransformLine(line string) string {
+	var sb strings.Builder
+	var e types.Event
+
+	if err := json.Unmarshal([]byte(line), &e); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s", utils.WrapInErrUnmarshalOutput(err, line))
+		return ""
+	}
+
+	if e.Type == eventtypes.ERR || e.Type == eventtypes.WARN ||
+		e.Type == eventtypes.DEBUG || e.Type == eventtypes.INFO {
+		fmt.Fprintf(os.Stderr, "%s: node %q: %s", e.Type, e.Node, e.Message)
+		return ""
+	}
+
+	if e.Type != eventtypes.NORMAL {
+		return ""
+	}
+
+	switch params.OutputMode {
+	case utils.OutputModeColumns:
+		sb.WriteString(fmt.Sprintf("%-16s %-16s %-16s %-16s %-6d %-16s %-9s %-8.2f %3d %s",
+			e.Node, e.Namespace, e.Pod, e.Container,
+			e.Pid, e.Comm, e.Syscall, float64(e.Latency)/1000.0, e.Err, e.File))
+	case utils.OutputModeCustomColumns:
+		for _, col := range params.CustomColumns {
+			switch col {
+			case "node":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Node))
+			case "namespace":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Namespace))
+			case "pod":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Pod))
+			case "container":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Container))
+			case "pid":
+				sb.WriteString(fmt.Sprintf("%-6d", e.Pid))
+			case "comm":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Comm))
+			case "syscall":
+				sb.WriteString(fmt.Sprintf("%-9s", e.Syscall))
+			case "fd":
+				sb.WriteString(fmt.Sprintf("%-3d", e.Fd))
+			case "lat":
+				sb.WriteString(fmt.Sprintf("%-8.2f", float64(e.Latency)/1000.0))
+			case "ret":
+				sb.WriteString(fmt.Sprintf("%-3d", e.Ret))
+			case "err":
+				sb.WriteString(fmt.Sprintf("%-3d", e.Err))
+			case "file":
+				sb.WriteString(fmt.Sprintf("%-24s", e.File))
+			}
+			sb.WriteRune(' ')
+		}
+	}
+
+	return sb.String()
+}
+
+func getCustomSyncsnoopColsHeader(cols []string) string {
+	var sb strings.Builder
+
+	for _, col := range cols {
+		switch col {
+		case "node":
+			sb.WriteString(fmt.Sprintf("%-16s", "NODE"))
+		case "namespace":
+			sb.WriteString(fmt.Sprintf("%-16s", "NAMESPACE"))
+		case "pod":
+			sb.WriteString(fmt.Sprintf("%-16s", "POD"))
+		case "container":
+			sb.WriteString(fmt.Sprintf("%-16s", "CONTAINER"))
+		case "pid":
+			sb.WriteString(fmt.Sprintf("%-6s", "PID"))
+		case "comm":
+			sb.WriteString(fmt.Sprintf("%-16s", "COMM"))
+		case "syscall":
+			sb.WriteString(fmt.Sprintf("%-9s", "SYSCALL"))
+		case "fd":
+			sb.WriteString(fmt.Sprintf("%-3s", "FD"))
+		case "lat":
+			sb.WriteString(fmt.Sprintf("%-8s", "LAT(ms)"))
+		case "ret":
+			sb.WriteString(fmt.Sprintf("%-3s", "RET"))
+		case "err":
+			sb.WriteString(fmt.Sprintf("%-3s", "ERR"))
+		case "file":
+			sb.WriteString(fmt.Sprintf("%-24s", "FILE"))
+		}
+		sb.WriteRune(' ')
+	}
+
+	return sb.String()
+}
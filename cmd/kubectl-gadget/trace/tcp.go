@@ -59,6 +59,7 @@ var tcptracerCmd = &cobra.Command{
 
 func init() {
 	TraceCmd.AddCommand(tcptracerCmd)
+	utils.RegisterGadgetCommand(tcptracerCmd, "tcptracer", types.Event{})
 	utils.AddCommonFlags(tcptracerCmd, &params)
 }
 
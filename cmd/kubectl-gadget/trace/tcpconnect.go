@@ -59,6 +59,7 @@ var tcpconnectCmd = &cobra.Command{
 
 func init() {
 	TraceCmd.AddCommand(tcpconnectCmd)
+	utils.RegisterGadgetCommand(tcpconnectCmd, "tcpconnect", types.Event{})
 	utils.AddCommonFlags(tcpconnectCmd, &params)
 }
 
@@ -0,0 +1,171 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/tcplife/types"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+var tcplifeCmd = &cobra.Command{
+	Use:   "tcplife",
+	Short: "Trace TCP connections with their duration and bytes transferred",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// print header
+		switch params.OutputMode {
+		case utils.OutputModeCustomColumns:
+			fmt.Println(getCustomTcplifeColsHeader(params.CustomColumns))
+		case utils.OutputModeColumns:
+			fmt.Printf("%-16s %-16s %-16s %-16s %-6s %-16s %-2s %-39s %-5s %-39s %-5s %-8s %-8s %s\n",
+				"NODE", "NAMESPACE", "POD", "CONTAINER", "PID", "COMM", "IP",
+				"SADDR", "SPORT", "DADDR", "DPORT", "TX_KB", "RX_KB", "MS")
+		}
+
+		config := &utils.TraceConfig{
+			GadgetName:       "tcplife",
+			Operation:        "start",
+			TraceOutputMode:  "Stream",
+			TraceOutputState: "Started",
+			CommonFlags:      &params,
+		}
+
+		err := utils.RunTraceAndPrintStream(config, tcplifeTransformLine)
+		if err != nil {
+			return utils.WrapInErrRunGadget(err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	TraceCmd.AddCommand(tcplifeCmd)
+	utils.RegisterGadgetCommand(tcplifeCmd, "tcplife", types.Event{})
+	utils.AddCommonFlags(tcplifeCmd, &params)
+}
+
+// tcplifeTransformLine is called to transform an event to columns
+// format according to the parameters
+func tcplifeTransformLine(line string) string {
+	var sb strings.Builder
+	var e types.Event
+
+	if err := json.Unmarshal([]byte(line), &e); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s", utils.WrapInErrUnmarshalOutput(err, line))
+		return ""
+	}
+
+	if e.Type == eventtypes.ERR || e.Type == eventtypes.WARN ||
+		e.Type == eventtypes.DEBUG || e.Type == eventtypes.INFO {
+		fmt.Fprintf(os.Stderr, "%s: node %q: %s", e.Type, e.Node, e.Message)
+		return ""
+	}
+
+	if e.Type != eventtypes.NORMAL {
+		return ""
+	}
+
+	switch params.OutputMode {
+	case utils.OutputModeColumns:
+		sb.WriteString(fmt.Sprintf("%-16s %-16s %-16s %-16s %-6d %-16s %-2d %-39s %-5d %-39s %-5d %-8d %-8d %.2f",
+			e.Node, e.Namespace, e.Pod, e.Container, e.Pid, e.Comm, e.IPVersion,
+			e.Saddr, e.Sport, e.Daddr, e.Dport, e.Sent/1024, e.Received/1024,
+			float64(e.Duration)/1000.0))
+	case utils.OutputModeCustomColumns:
+		for _, col := range params.CustomColumns {
+			switch col {
+			case "node":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Node))
+			case "namespace":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Namespace))
+			case "pod":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Pod))
+			case "container":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Container))
+			case "pid":
+				sb.WriteString(fmt.Sprintf("%-6d", e.Pid))
+			case "comm":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Comm))
+			case "ip":
+				sb.WriteString(fmt.Sprintf("%-2d", e.IPVersion))
+			case "saddr":
+				sb.WriteString(fmt.Sprintf("%-39s", e.Saddr))
+			case "sport":
+				sb.WriteString(fmt.Sprintf("%-5d", e.Sport))
+			case "daddr":
+				sb.WriteString(fmt.Sprintf("%-39s", e.Daddr))
+			case "dport":
+				sb.WriteString(fmt.Sprintf("%-5d", e.Dport))
+			case "sent":
+				sb.WriteString(fmt.Sprintf("%-10d", e.Sent))
+			case "received":
+				sb.WriteString(fmt.Sprintf("%-10d", e.Received))
+			case "duration":
+				sb.WriteString(fmt.Sprintf("%-10.2f", float64(e.Duration)/1000.0))
+			}
+			sb.WriteRune(' ')
+		}
+	}
+
+	return sb.String()
+}
+
+func getCustomTcplifeColsHeader(cols []string) string {
+	var sb strings.Builder
+
+	for _, col := range cols {
+		switch col {
+		case "node":
+			sb.WriteString(fmt.Sprintf("%-16s", "NODE"))
+		case "namespace":
+			sb.WriteString(fmt.Sprintf("%-16s", "NAMESPACE"))
+		case "pod":
+			sb.WriteString(fmt.Sprintf("%-16s", "POD"))
+		case "container":
+			sb.WriteString(fmt.Sprintf("%-16s", "CONTAINER"))
+		case "pid":
+			sb.WriteString(fmt.Sprintf("%-6s", "PID"))
+		case "comm":
+			sb.WriteString(fmt.Sprintf("%-16s", "COMM"))
+		case "ip":
+			sb.WriteString(fmt.Sprintf("%-2s", "IP"))
+		case "saddr":
+			sb.WriteString(fmt.Sprintf("%-39s", "SADDR"))
+		case "sport":
+			sb.WriteString(fmt.Sprintf("%-5s", "SPORT"))
+		case "daddr":
+			sb.WriteString(fmt.Sprintf("%-39s", "DADDR"))
+		case "dport":
+			sb.WriteString(fmt.Sprintf("%-5s", "DPORT"))
+		case "sent":
+			sb.WriteString(fmt.Sprintf("%-10s", "SENT"))
+		case "received":
+			sb.WriteString(fmt.Sprintf("%-10s", "RECEIVED"))
+		case "duration":
+			sb.WriteString(fmt.Sprintf("%-10s", "MS"))
+		}
+		sb.WriteRune(' ')
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,158 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/tcpretrans/types"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+var tcpretransCmd = &cobra.Command{
+	Use:   "tcpretrans",
+	Short: "Trace TCP retransmissions",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// print header
+		switch params.OutputMode {
+		case utils.OutputModeCustomColumns:
+			fmt.Println(getCustomTcpretransColsHeader(params.CustomColumns))
+		case utils.OutputModeColumns:
+			fmt.Printf("%-16s %-16s %-16s %-16s %-2s %-39s %-5s %-39s %-5s %s\n",
+				"NODE", "NAMESPACE", "POD", "CONTAINER", "IP",
+				"SADDR", "SPORT", "DADDR", "DPORT", "STATE")
+		}
+
+		config := &utils.TraceConfig{
+			GadgetName:       "tcpretrans",
+			Operation:        "start",
+			TraceOutputMode:  "Stream",
+			TraceOutputState: "Started",
+			CommonFlags:      &params,
+		}
+
+		err := utils.RunTraceAndPrintStream(config, tcpretransTransformLine)
+		if err != nil {
+			return utils.WrapInErrRunGadget(err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	TraceCmd.AddCommand(tcpretransCmd)
+	utils.RegisterGadgetCommand(tcpretransCmd, "tcpretrans", types.Event{})
+	utils.AddCommonFlags(tcpretransCmd, &params)
+}
+
+// tcpretransTransformLine is called to transform an event to columns
+// format according to the parameters
+func tcpretransTransformLine(line string) string {
+	var sb strings.Builder
+	var e types.Event
+
+	if err := json.Unmarshal([]byte(line), &e); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s", utils.WrapInErrUnmarshalOutput(err, line))
+		return ""
+	}
+
+	if e.Type == eventtypes.ERR || e.Type == eventtypes.WARN ||
+		e.Type == eventtypes.DEBUG || e.Type == eventtypes.INFO {
+		fmt.Fprintf(os.Stderr, "%s: node %q: %s", e.Type, e.Node, e.Message)
+		return ""
+	}
+
+	if e.Type != eventtypes.NORMAL {
+		return ""
+	}
+
+	switch params.OutputMode {
+	case utils.OutputModeColumns:
+		sb.WriteString(fmt.Sprintf("%-16s %-16s %-16s %-16s %-2d %-39s %-5d %-39s %-5d %s",
+			e.Node, e.Namespace, e.Pod, e.Container, e.IPVersion,
+			e.Saddr, e.Sport, e.Daddr, e.Dport, e.State))
+	case utils.OutputModeCustomColumns:
+		for _, col := range params.CustomColumns {
+			switch col {
+			case "node":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Node))
+			case "namespace":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Namespace))
+			case "pod":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Pod))
+			case "container":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Container))
+			case "ip":
+				sb.WriteString(fmt.Sprintf("%-2d", e.IPVersion))
+			case "saddr":
+				sb.WriteString(fmt.Sprintf("%-39s", e.Saddr))
+			case "sport":
+				sb.WriteString(fmt.Sprintf("%-5d", e.Sport))
+			case "daddr":
+				sb.WriteString(fmt.Sprintf("%-39s", e.Daddr))
+			case "dport":
+				sb.WriteString(fmt.Sprintf("%-5d", e.Dport))
+			case "state":
+				sb.WriteString(fmt.Sprintf("%-12s", e.State))
+			case "netns":
+				sb.WriteString(fmt.Sprintf("%-10d", e.Netns))
+			}
+			sb.WriteRune(' ')
+		}
+	}
+
+	return sb.String()
+}
+
+func getCustomTcpretransColsHeader(cols []string) string {
+	var sb strings.Builder
+
+	for _, col := range cols {
+		switch col {
+		case "node":
+			sb.WriteString(fmt.Sprintf("%-16s", "NODE"))
+		case "namespace":
+			sb.WriteString(fmt.Sprintf("%-16s", "NAMESPACE"))
+		case "pod":
+			sb.WriteString(fmt.Sprintf("%-16s", "POD"))
+		case "container":
+			sb.WriteString(fmt.Sprintf("%-16s", "CONTAINER"))
+		case "ip":
+			sb.WriteString(fmt.Sprintf("%-2s", "IP"))
+		case "saddr":
+			sb.WriteString(fmt.Sprintf("%-39s", "SADDR"))
+		case "sport":
+			sb.WriteString(fmt.Sprintf("%-5s", "SPORT"))
+		case "daddr":
+			sb.WriteString(fmt.Sprintf("%-39s", "DADDR"))
+		case "dport":
+			sb.WriteString(fmt.Sprintf("%-5s", "DPORT"))
+		case "state":
+			sb.WriteString(fmt.Sprintf("%-12s", "STATE"))
+		case "netns":
+			sb.WriteString(fmt.Sprintf("%-10s", "NETNS"))
+		}
+		sb.WriteRune(' ')
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,160 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	tlstypes "github.com/kinvolk/inspektor-gadget/pkg/gadgets/tlssnoop/types"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+const (
+	FmtAllTLSSnoop   = "%-16.16s %-16.16s %-16.16s %-22.22s %-30.30s %-9.9s %s"
+	FmtShortTLSSnoop = "%-16.16s %-22.22s %-30.30s %-9.9s %s"
+)
+
+var colTLSSnoopLens = map[string]int{
+	"saddr":   16,
+	"daddr":   16,
+	"name":    30,
+	"version": 10,
+	"cipher":  40,
+}
+
+var tlsPorts string
+
+var tlssnoopCmd = &cobra.Command{
+	Use:   "tls",
+	Short: "Trace TLS handshakes and plaintext HTTP requests sent to TLS ports",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		transform := tlssnoopTransformLine
+
+		switch {
+		case params.OutputMode == utils.OutputModeJSON: // don't print any header
+		case params.OutputMode == utils.OutputModeCustomColumns:
+			table := utils.NewTableFormater(params.CustomColumns, colTLSSnoopLens)
+			fmt.Println(table.GetHeader())
+			transform = table.GetTransformFunc()
+		case params.AllNamespaces:
+			fmt.Printf(FmtAllTLSSnoop+"\n",
+				"NODE",
+				"NAMESPACE",
+				"POD",
+				"SERVER",
+				"NAME",
+				"VERSION",
+				"CIPHER",
+			)
+		default:
+			fmt.Printf(FmtShortTLSSnoop+"\n",
+				"POD",
+				"SERVER",
+				"NAME",
+				"VERSION",
+				"CIPHER",
+			)
+		}
+
+		config := &utils.TraceConfig{
+			GadgetName:       "tlssnoop",
+			Operation:        "start",
+			TraceOutputMode:  "Stream",
+			TraceOutputState: "Started",
+			CommonFlags:      &params,
+			Parameters: map[string]string{
+				tlstypes.PortsParam: tlsPorts,
+			},
+		}
+
+		err := utils.RunTraceAndPrintStream(config, transform)
+		if err != nil {
+			return utils.WrapInErrRunGadget(err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	TraceCmd.AddCommand(tlssnoopCmd)
+	utils.RegisterGadgetCommand(tlssnoopCmd, "tlssnoop", tlstypes.Event{})
+	utils.AddCommonFlags(tlssnoopCmd, &params)
+
+	tlssnoopCmd.PersistentFlags().StringVarP(
+		&tlsPorts,
+		"ports",
+		"",
+		tlstypes.PortsDefault,
+		"Comma-separated list of TCP ports where TLS is expected",
+	)
+}
+
+// dashIfEmpty is used to keep the columns aligned when a field is missing.
+func dashIfEmpty(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func tlssnoopTransformLine(line string) string {
+	event := &tlstypes.Event{}
+	if err := json.Unmarshal([]byte(line), event); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s", utils.WrapInErrUnmarshalOutput(err, line))
+		return ""
+	}
+
+	podMsgSuffix := ""
+	if event.Namespace != "" && event.Pod != "" {
+		podMsgSuffix = ", pod " + event.Namespace + "/" + event.Pod
+	}
+
+	switch event.Type {
+	case eventtypes.ERR:
+		return fmt.Sprintf("Error on node %s%s: %s", event.Node, podMsgSuffix, event.Message)
+	case eventtypes.WARN:
+		return fmt.Sprintf("Warning on node %s%s: %s", event.Node, podMsgSuffix, event.Message)
+	case eventtypes.DEBUG:
+		if !params.Verbose {
+			return ""
+		}
+		return fmt.Sprintf("Debug on node %s%s: %s", event.Node, podMsgSuffix, event.Message)
+	case eventtypes.NORMAL:
+	default:
+		return ""
+	}
+
+	server := net.JoinHostPort(event.Daddr, strconv.Itoa(int(event.Dport)))
+	version := dashIfEmpty(event.Version)
+	cipher := dashIfEmpty(event.Cipher)
+	if event.Plaintext {
+		version = "PLAINTEXT"
+		cipher = "HTTP " + event.Method
+	}
+
+	if params.AllNamespaces {
+		return fmt.Sprintf(FmtAllTLSSnoop, event.Node, event.Namespace, event.Pod,
+			server, dashIfEmpty(event.Name), version, cipher)
+	}
+	return fmt.Sprintf(FmtShortTLSSnoop, event.Pod, server, dashIfEmpty(event.Name), version, cipher)
+}
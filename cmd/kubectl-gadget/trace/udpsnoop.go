@@ -0,0 +1,179 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/udpsnoop/types"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+var udpsnoopCmd = &cobra.Command{
+	Use:   "udpsnoop",
+	Short: "Trace UDP datagrams sent and received",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// print header
+		switch params.OutputMode {
+		case utils.OutputModeCustomColumns:
+			fmt.Println(getCustomUdpsnoopColsHeader(params.CustomColumns))
+		case utils.OutputModeColumns:
+			fmt.Printf("%-16s %-16s %-16s %-16s %-6s %-16s %-4s %-2s %-39s %-5s %-39s %-5s %-6s %s\n",
+				"NODE", "NAMESPACE", "POD", "CONTAINER", "PID", "COMM", "OP", "IP",
+				"SADDR", "SPORT", "DADDR", "DPORT", "BYTES", "ERROR")
+		}
+
+		config := &utils.TraceConfig{
+			GadgetName:       "udpsnoop",
+			Operation:        "start",
+			TraceOutputMode:  "Stream",
+			TraceOutputState: "Started",
+			CommonFlags:      &params,
+		}
+
+		err := utils.RunTraceAndPrintStream(config, udpsnoopTransformLine)
+		if err != nil {
+			return utils.WrapInErrRunGadget(err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	TraceCmd.AddCommand(udpsnoopCmd)
+	utils.RegisterGadgetCommand(udpsnoopCmd, "udpsnoop", types.Event{})
+	utils.AddCommonFlags(udpsnoopCmd, &params)
+}
+
+// udpsnoopTransformLine is called to transform an event to columns
+// format according to the parameters
+func udpsnoopTransformLine(line string) string {
+	var sb strings.Builder
+	var e types.Event
+
+	if err := json.Unmarshal([]byte(line), &e); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s", utils.WrapInErrUnmarshalOutput(err, line))
+		return ""
+	}
+
+	if e.Type == eventtypes.ERR || e.Type == eventtypes.WARN ||
+		e.Type == eventtypes.DEBUG || e.Type == eventtypes.INFO {
+		fmt.Fprintf(os.Stderr, "%s: node %q: %s", e.Type, e.Node, e.Message)
+		return ""
+	}
+
+	if e.Type != eventtypes.NORMAL {
+		return ""
+	}
+
+	switch params.OutputMode {
+	case utils.OutputModeColumns:
+		sb.WriteString(fmt.Sprintf("%-16s %-16s %-16s %-16s %-6d %-16s %-4s %-2d %-39s %-5d %-39s %-5d %-6d %s",
+			e.Node, e.Namespace, e.Pod, e.Container, e.Pid, e.Comm, e.Op, e.IPVersion,
+			e.Saddr, e.Sport, e.Daddr, e.Dport, e.Bytes, udpsnoopError(e)))
+	case utils.OutputModeCustomColumns:
+		for _, col := range params.CustomColumns {
+			switch col {
+			case "node":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Node))
+			case "namespace":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Namespace))
+			case "pod":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Pod))
+			case "container":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Container))
+			case "pid":
+				sb.WriteString(fmt.Sprintf("%-6d", e.Pid))
+			case "comm":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Comm))
+			case "ip":
+				sb.WriteString(fmt.Sprintf("%-2d", e.IPVersion))
+			case "saddr":
+				sb.WriteString(fmt.Sprintf("%-39s", e.Saddr))
+			case "sport":
+				sb.WriteString(fmt.Sprintf("%-5d", e.Sport))
+			case "daddr":
+				sb.WriteString(fmt.Sprintf("%-39s", e.Daddr))
+			case "dport":
+				sb.WriteString(fmt.Sprintf("%-5d", e.Dport))
+			case "op":
+				sb.WriteString(fmt.Sprintf("%-4s", e.Op))
+			case "bytes":
+				sb.WriteString(fmt.Sprintf("%-6d", e.Bytes))
+			case "error":
+				sb.WriteString(fmt.Sprintf("%-10s", udpsnoopError(e)))
+			}
+			sb.WriteRune(' ')
+		}
+	}
+
+	return sb.String()
+}
+
+// udpsnoopError returns the error of the system call, or TRUNCATED when
+// the datagram didn't fit in the buffer of the receiver.
+func udpsnoopError(e types.Event) string {
+	if e.Truncated {
+		return "TRUNCATED"
+	}
+	return e.Error
+}
+
+func getCustomUdpsnoopColsHeader(cols []string) string {
+	var sb strings.Builder
+
+	for _, col := range cols {
+		switch col {
+		case "node":
+			sb.WriteString(fmt.Sprintf("%-16s", "NODE"))
+		case "namespace":
+			sb.WriteString(fmt.Sprintf("%-16s", "NAMESPACE"))
+		case "pod":
+			sb.WriteString(fmt.Sprintf("%-16s", "POD"))
+		case "container":
+			sb.WriteString(fmt.Sprintf("%-16s", "CONTAINER"))
+		case "pid":
+			sb.WriteString(fmt.Sprintf("%-6s", "PID"))
+		case "comm":
+			sb.WriteString(fmt.Sprintf("%-16s", "COMM"))
+		case "ip":
+			sb.WriteString(fmt.Sprintf("%-2s", "IP"))
+		case "saddr":
+			sb.WriteString(fmt.Sprintf("%-39s", "SADDR"))
+		case "sport":
+			sb.WriteString(fmt.Sprintf("%-5s", "SPORT"))
+		case "daddr":
+			sb.WriteString(fmt.Sprintf("%-39s", "DADDR"))
+		case "dport":
+			sb.WriteString(fmt.Sprintf("%-5s", "DPORT"))
+		case "op":
+			sb.WriteString(fmt.Sprintf("%-4s", "OP"))
+		case "bytes":
+			sb.WriteString(fmt.Sprintf("%-6s", "BYTES"))
+		case "error":
+			sb.WriteString(fmt.Sprintf("%-10s", "ERROR"))
+		}
+		sb.WriteRune(' ')
+	}
+
+	return sb.String()
+}
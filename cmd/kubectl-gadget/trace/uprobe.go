@@ -0,0 +1,183 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/uprobe/types"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+// flags
+var (
+	uprobeBinary   string
+	uprobeSymbol   string
+	uprobeTemplate string
+)
+
+var uprobeCmd = &cobra.Command{
+	Use:   "uprobe",
+	Short: "Trace the calls to a function of an executable or a shared library of the containers",
+	Example: `  # Print the lines read by bash
+  kubectl gadget trace uprobe -n default --binary /bin/bash --symbol readline --template '{symbol}() = {ret:str}'
+
+  # Print the files opened through the libc
+  kubectl gadget trace uprobe -n default --binary /lib/x86_64-linux-gnu/libc.so.6 --symbol fopen --template 'fopen({arg0:str}, {arg1:str})'`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if uprobeBinary == "" {
+			return utils.WrapInErrMissingArgs("--binary")
+		}
+		if uprobeSymbol == "" {
+			return utils.WrapInErrMissingArgs("--symbol")
+		}
+
+		// print header
+		switch params.OutputMode {
+		case utils.OutputModeCustomColumns:
+			fmt.Println(getCustomUprobeColsHeader(params.CustomColumns))
+		case utils.OutputModeColumns:
+			fmt.Printf("%-16s %-16s %-16s %-16s %-7s %-16s %s\n",
+				"NODE", "NAMESPACE", "POD", "CONTAINER",
+				"PID", "COMM", "OUTPUT")
+		}
+
+		config := &utils.TraceConfig{
+			GadgetName:       "uprobe",
+			Operation:        "start",
+			TraceOutputMode:  "Stream",
+			TraceOutputState: "Started",
+			CommonFlags:      &params,
+			Parameters: map[string]string{
+				"binary": uprobeBinary,
+				"symbol": uprobeSymbol,
+				"output": uprobeTemplate,
+			},
+		}
+
+		err := utils.RunTraceAndPrintStream(config, uprobeTransformLine)
+		if err != nil {
+			return utils.WrapInErrRunGadget(err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	uprobeCmd.Flags().StringVarP(
+		&uprobeBinary, "binary", "", "",
+		"Absolute path, in the containers, of the executable or shared library containing the function",
+	)
+	uprobeCmd.Flags().StringVarP(
+		&uprobeSymbol, "symbol", "", "",
+		"Name of the function to trace",
+	)
+	uprobeCmd.Flags().StringVarP(
+		&uprobeTemplate, "template", "", "{symbol}({arg0:x}, {arg1:x}, {arg2:x})",
+		"Output of the calls with the {arg0} to {arg4}, {ret} and {symbol} placeholders and the :d, :u, :x and :str formats",
+	)
+
+	TraceCmd.AddCommand(uprobeCmd)
+	utils.RegisterGadgetCommand(uprobeCmd, "uprobe", types.Event{})
+	utils.AddCommonFlags(uprobeCmd, &params)
+}
+
+// uprobeTransformLine is called to transform an event to columns format
+// according to the parameters
+func uprobeTransformLine(line string) string {
+	var sb strings.Builder
+	var e types.Event
+
+	if err := json.Unmarshal([]byte(line), &e); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s", utils.WrapInErrUnmarshalOutput(err, line))
+		return ""
+	}
+
+	if e.Type == eventtypes.ERR || e.Type == eventtypes.WARN ||
+		e.Type == eventtypes.DEBUG || e.Type == eventtypes.INFO {
+		fmt.Fprintf(os.Stderr, "%s: node %q: %s", e.Type, e.Node, e.Message)
+		return ""
+	}
+
+	if e.Type != eventtypes.NORMAL {
+		return ""
+	}
+
+	switch params.OutputMode {
+	case utils.OutputModeColumns:
+		sb.WriteString(fmt.Sprintf("%-16s %-16s %-16s %-16s %-7d %-16s %s",
+			e.Node, e.Namespace, e.Pod, e.Container,
+			e.Pid, e.Comm, e.Output))
+	case utils.OutputModeCustomColumns:
+		for _, col := range params.CustomColumns {
+			switch col {
+			case "node":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Node))
+			case "namespace":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Namespace))
+			case "pod":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Pod))
+			case "container":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Container))
+			case "pid":
+				sb.WriteString(fmt.Sprintf("%-7d", e.Pid))
+			case "tid":
+				sb.WriteString(fmt.Sprintf("%-7d", e.Tid))
+			case "comm":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Comm))
+			case "output":
+				sb.WriteString(e.Output)
+			}
+			sb.WriteRune(' ')
+		}
+	}
+
+	return sb.String()
+}
+
+func getCustomUprobeColsHeader(cols []string) string {
+	var sb strings.Builder
+
+	for _, col := range cols {
+		switch col {
+		case "node":
+			sb.WriteString(fmt.Sprintf("%-16s", "NODE"))
+		case "namespace":
+			sb.WriteString(fmt.Sprintf("%-16s", "NAMESPACE"))
+		case "pod":
+			sb.WriteString(fmt.Sprintf("%-16s", "POD"))
+		case "container":
+			sb.WriteString(fmt.Sprintf("%-16s", "CONTAINER"))
+		case "pid":
+			sb.WriteString(fmt.Sprintf("%-7s", "PID"))
+		case "tid":
+			sb.WriteString(fmt.Sprintf("%-7s", "TID"))
+		case "comm":
+			sb.WriteString(fmt.Sprintf("%-16s", "COMM"))
+		case "output":
+			sb.WriteString("OUTPUT")
+		}
+		sb.WriteRune(' ')
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,269 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/usdt/types"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+// flags
+var (
+	usdtBinary     string
+	usdtProbe      string
+	usdtStringArgs string
+	usdtList       bool
+)
+
+var usdtCmd = &cobra.Command{
+	Use:   "usdt",
+	Short: "List and trace the USDT probes of an executable or a shared library of the containers",
+	Example: `  # List the probes of python
+  kubectl gadget trace usdt -n default --binary /usr/local/bin/python3 --list
+
+  # Print the python functions called, with their file and name
+  kubectl gadget trace usdt -n default --binary /usr/local/bin/python3 --probe python:function__entry --string-args 0,1`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if usdtBinary == "" {
+			return utils.WrapInErrMissingArgs("--binary")
+		}
+
+		if usdtList {
+			config := &utils.TraceConfig{
+				GadgetName:       "usdt",
+				Operation:        "list",
+				TraceOutputMode:  "Status",
+				TraceOutputState: "Completed",
+				CommonFlags:      &params,
+				Parameters: map[string]string{
+					"binary": usdtBinary,
+				},
+			}
+
+			err := utils.RunTraceAndPrintStatusOutput(config, usdtPrintProbes)
+			if err != nil {
+				return utils.WrapInErrRunGadget(err)
+			}
+
+			return nil
+		}
+
+		if usdtProbe == "" {
+			return utils.WrapInErrMissingArgs("--probe")
+		}
+
+		// print header
+		switch params.OutputMode {
+		case utils.OutputModeCustomColumns:
+			fmt.Println(getCustomUsdtColsHeader(params.CustomColumns))
+		case utils.OutputModeColumns:
+			fmt.Printf("%-16s %-16s %-16s %-16s %-7s %-16s %-24s %s\n",
+				"NODE", "NAMESPACE", "POD", "CONTAINER",
+				"PID", "COMM", "PROBE", "ARGS")
+		}
+
+		config := &utils.TraceConfig{
+			GadgetName:       "usdt",
+			Operation:        "start",
+			TraceOutputMode:  "Stream",
+			TraceOutputState: "Started",
+			CommonFlags:      &params,
+			Parameters: map[string]string{
+				"binary":      usdtBinary,
+				"probe":       usdtProbe,
+				"string_args": usdtStringArgs,
+			},
+		}
+
+		err := utils.RunTraceAndPrintStream(config, usdtTransformLine)
+		if err != nil {
+			return utils.WrapInErrRunGadget(err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	usdtCmd.Flags().StringVarP(
+		&usdtBinary, "binary", "", "",
+		"Absolute path, in the containers, of the executable or shared library containing the probes",
+	)
+	usdtCmd.Flags().StringVarP(
+		&usdtProbe, "probe", "", "",
+		"Probe to trace, as provider:name",
+	)
+	usdtCmd.Flags().StringVarP(
+		&usdtStringArgs, "string-args", "", "",
+		"Comma-separated indexes of the arguments printed as strings, e.g. 0,2",
+	)
+	usdtCmd.Flags().BoolVarP(
+		&usdtList, "list", "", false,
+		"List the probes of the binary instead of tracing one of them",
+	)
+
+	TraceCmd.AddCommand(usdtCmd)
+	utils.RegisterGadgetCommand(usdtCmd, "usdt", types.Event{})
+	utils.AddCommonFlags(usdtCmd, &params)
+}
+
+// usdtTransformLine is called to transform an event to columns format
+// according to the parameters
+func usdtTransformLine(line string) string {
+	var sb strings.Builder
+	var e types.Event
+
+	if err := json.Unmarshal([]byte(line), &e); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s", utils.WrapInErrUnmarshalOutput(err, line))
+		return ""
+	}
+
+	if e.Type == eventtypes.ERR || e.Type == eventtypes.WARN ||
+		e.Type == eventtypes.DEBUG || e.Type == eventtypes.INFO {
+		fmt.Fprintf(os.Stderr, "%s: node %q: %s", e.Type, e.Node, e.Message)
+		return ""
+	}
+
+	if e.Type != eventtypes.NORMAL {
+		return ""
+	}
+
+	probe := e.Provider + ":" + e.Name
+	args := strings.Join(e.Args, ", ")
+
+	switch params.OutputMode {
+	case utils.OutputModeColumns:
+		sb.WriteString(fmt.Sprintf("%-16s %-16s %-16s %-16s %-7d %-16s %-24s %s",
+			e.Node, e.Namespace, e.Pod, e.Container,
+			e.Pid, e.Comm, probe, args))
+	case utils.OutputModeCustomColumns:
+		for _, col := range params.CustomColumns {
+			switch col {
+			case "node":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Node))
+			case "namespace":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Namespace))
+			case "pod":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Pod))
+			case "container":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Container))
+			case "pid":
+				sb.WriteString(fmt.Sprintf("%-7d", e.Pid))
+			case "tid":
+				sb.WriteString(fmt.Sprintf("%-7d", e.Tid))
+			case "comm":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Comm))
+			case "probe":
+				sb.WriteString(fmt.Sprintf("%-24s", probe))
+			case "args":
+				sb.WriteString(args)
+			}
+			sb.WriteRune(' ')
+		}
+	}
+
+	return sb.String()
+}
+
+func getCustomUsdtColsHeader(cols []string) string {
+	var sb strings.Builder
+
+	for _, col := range cols {
+		switch col {
+		case "node":
+			sb.WriteString(fmt.Sprintf("%-16s", "NODE"))
+		case "namespace":
+			sb.WriteString(fmt.Sprintf("%-16s", "NAMESPACE"))
+		case "pod":
+			sb.WriteString(fmt.Sprintf("%-16s", "POD"))
+		case "container":
+			sb.WriteString(fmt.Sprintf("%-16s", "CONTAINER"))
+		case "pid":
+			sb.WriteString(fmt.Sprintf("%-7s", "PID"))
+		case "tid":
+			sb.WriteString(fmt.Sprintf("%-7s", "TID"))
+		case "comm":
+			sb.WriteString(fmt.Sprintf("%-16s", "COMM"))
+		case "probe":
+			sb.WriteString(fmt.Sprintf("%-24s", "PROBE"))
+		case "args":
+			sb.WriteString("ARGS")
+		}
+		sb.WriteRune(' ')
+	}
+
+	return sb.String()
+}
+
+// usdtPrintProbes prints the probes found by the list operation on all the
+// nodes.
+func usdtPrintProbes(results []gadgetv1alpha1.Trace) error {
+	allProbes := []types.Probe{}
+
+	for _, i := range results {
+		probes := []types.Probe{}
+		json.Unmarshal([]byte(i.Status.Output), &probes)
+		allProbes = append(allProbes, probes...)
+	}
+
+	sort.Slice(allProbes, func(i, j int) bool {
+		pi, pj := allProbes[i], allProbes[j]
+		switch {
+		case pi.Node != pj.Node:
+			return pi.Node < pj.Node
+		case pi.Namespace != pj.Namespace:
+			return pi.Namespace < pj.Namespace
+		case pi.Pod != pj.Pod:
+			return pi.Pod < pj.Pod
+		case pi.Container != pj.Container:
+			return pi.Container < pj.Container
+		case pi.Provider != pj.Provider:
+			return pi.Provider < pj.Provider
+		default:
+			return pi.Name < pj.Name
+		}
+	})
+
+	if params.OutputMode == utils.OutputModeJSON {
+		b, err := json.MarshalIndent(allProbes, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error marshalling results: %w", err)
+		}
+		fmt.Printf("%s\n", b)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 4, ' ', 0)
+	fmt.Fprintln(w, "NODE\tNAMESPACE\tPOD\tCONTAINER\tPROBE\tLOCATIONS\tSEMAPHORE\tARGS\t")
+	for _, p := range allProbes {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s:%s\t%d\t%t\t%s\t\n",
+			p.Node, p.Namespace, p.Pod, p.Container,
+			p.Provider, p.Name, p.Locations, p.Semaphore, p.Args)
+	}
+	w.Flush()
+
+	return nil
+}
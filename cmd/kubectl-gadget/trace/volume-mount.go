@@ -0,0 +1,269 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/kinvolk/inspektor-gadget/cmd/kubectl-gadget/utils"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/volumemount/types"
+	"github.com/kinvolk/inspektor-gadget/pkg/k8sutil"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+var volumeMountRootDir string
+
+var volumeMountCmd = &cobra.Command{
+	Use:   "volume-mount",
+	Short: "Trace the mount operations of kubelet and the CSI plugins on pod volumes",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := k8sutil.NewClientsetFromConfigFlags(utils.KubernetesConfigFlags)
+		if err != nil {
+			return utils.WrapInErrSetupK8sClient(err)
+		}
+		volumeMountPods = newPodsByUID(client)
+
+		// print header
+		switch params.OutputMode {
+		case utils.OutputModeCustomColumns:
+			fmt.Println(getCustomVolumeMountColsHeader(params.CustomColumns))
+		case utils.OutputModeColumns:
+			fmt.Printf("%-16s %-16s %-16s %-16s %-6s %-6s %-24s %-24s %-10s %s\n",
+				"NODE", "NAMESPACE", "POD", "COMM", "PID", "OP",
+				"PLUGIN", "VOLUME", "FS", "ERROR")
+		}
+
+		config := &utils.TraceConfig{
+			GadgetName:       "volume-mount",
+			Operation:        "start",
+			TraceOutputMode:  "Stream",
+			TraceOutputState: "Started",
+			CommonFlags:      &params,
+			Parameters: map[string]string{
+				types.RootDirParam: volumeMountRootDir,
+			},
+		}
+
+		err = utils.RunTraceAndPrintStream(config, volumeMountTransformLine)
+		if err != nil {
+			return utils.WrapInErrRunGadget(err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	TraceCmd.AddCommand(volumeMountCmd)
+	utils.RegisterGadgetCommand(volumeMountCmd, "volume-mount", types.Event{})
+	utils.AddCommonFlags(volumeMountCmd, &params)
+
+	volumeMountCmd.PersistentFlags().StringVarP(
+		&volumeMountRootDir,
+		"root-dir",
+		"",
+		types.RootDirDefault,
+		"Directory where kubelet stores its data on the nodes",
+	)
+}
+
+// podsByUIDRefreshInterval is the minimal time between two listings of the
+// pods when an unknown pod UID is found.
+const podsByUIDRefreshInterval = 5 * time.Second
+
+// podsByUID resolves the pod UIDs found in the volume paths to the pod
+// namespace and name. The volumes are mounted before the containers are
+// created, so the gadget can't do it from the containers it knows about.
+type podsByUID struct {
+	client *kubernetes.Clientset
+
+	mu          sync.Mutex
+	pods        map[string]metav1.ObjectMeta
+	lastRefresh time.Time
+}
+
+var volumeMountPods *podsByUID
+
+func newPodsByUID(client *kubernetes.Clientset) *podsByUID {
+	return &podsByUID{
+		client: client,
+		pods:   make(map[string]metav1.ObjectMeta),
+	}
+}
+
+func (p *podsByUID) lookup(uid string) (namespace, name string) {
+	if uid == "" {
+		return "", ""
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if pod, ok := p.pods[uid]; ok {
+		return pod.Namespace, pod.Name
+	}
+
+	if time.Since(p.lastRefresh) < podsByUIDRefreshInterval {
+		return "", ""
+	}
+	p.lastRefresh = time.Now()
+
+	pods, err := p.client.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to list pods: %s\n", err)
+		return "", ""
+	}
+
+	for _, pod := range pods.Items {
+		p.pods[string(pod.UID)] = pod.ObjectMeta
+	}
+
+	pod := p.pods[uid]
+	return pod.Namespace, pod.Name
+}
+
+// volumeMountTransformLine is called to transform an event to columns
+// format according to the parameters
+func volumeMountTransformLine(line string) string {
+	var sb strings.Builder
+	var e types.Event
+
+	if err := json.Unmarshal([]byte(line), &e); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s", utils.WrapInErrUnmarshalOutput(err, line))
+		return ""
+	}
+
+	if e.Type == eventtypes.ERR || e.Type == eventtypes.WARN ||
+		e.Type == eventtypes.DEBUG || e.Type == eventtypes.INFO {
+		fmt.Fprintf(os.Stderr, "%s: node %q: %s", e.Type, e.Node, e.Message)
+		return ""
+	}
+
+	if e.Type != eventtypes.NORMAL {
+		return ""
+	}
+
+	// The pod is the one owning the volume, not the one performing the
+	// mount operation (e.g. a CSI plugin).
+	namespace, pod := volumeMountPods.lookup(e.PodUID)
+	if pod == "" {
+		pod = e.PodUID
+	}
+
+	switch params.OutputMode {
+	case utils.OutputModeColumns:
+		sb.WriteString(fmt.Sprintf("%-16s %-16s %-16s %-16s %-6d %-6s %-24s %-24s %-10s %s",
+			e.Node, namespace, pod, e.Comm, e.Pid, e.Operation,
+			e.Plugin, e.Volume, e.Fs, e.Error))
+	case utils.OutputModeCustomColumns:
+		for _, col := range params.CustomColumns {
+			switch col {
+			case "node":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Node))
+			case "namespace":
+				sb.WriteString(fmt.Sprintf("%-16s", namespace))
+			case "pod":
+				sb.WriteString(fmt.Sprintf("%-16s", pod))
+			case "poduid":
+				sb.WriteString(fmt.Sprintf("%-36s", e.PodUID))
+			case "comm":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Comm))
+			case "pid":
+				sb.WriteString(fmt.Sprintf("%-6d", e.Pid))
+			case "op":
+				sb.WriteString(fmt.Sprintf("%-6s", e.Operation))
+			case "kind":
+				sb.WriteString(fmt.Sprintf("%-8s", e.Kind))
+			case "plugin":
+				sb.WriteString(fmt.Sprintf("%-24s", e.Plugin))
+			case "volume":
+				sb.WriteString(fmt.Sprintf("%-24s", e.Volume))
+			case "fs":
+				sb.WriteString(fmt.Sprintf("%-10s", e.Fs))
+			case "src":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Source))
+			case "target":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Target))
+			case "flags":
+				sb.WriteString(fmt.Sprintf("%-16s", strings.Join(e.Flags, " | ")))
+			case "ret":
+				sb.WriteString(fmt.Sprintf("%-4d", e.Retval))
+			case "lat":
+				sb.WriteString(fmt.Sprintf("%-8d", e.Latency/1000))
+			case "error":
+				sb.WriteString(fmt.Sprintf("%-16s", e.Error))
+			}
+			sb.WriteRune(' ')
+		}
+	}
+
+	return sb.String()
+}
+
+func getCustomVolumeMountColsHeader(cols []string) string {
+	var sb strings.Builder
+
+	for _, col := range cols {
+		switch col {
+		case "node":
+			sb.WriteString(fmt.Sprintf("%-16s", "NODE"))
+		case "namespace":
+			sb.WriteString(fmt.Sprintf("%-16s", "NAMESPACE"))
+		case "pod":
+			sb.WriteString(fmt.Sprintf("%-16s", "POD"))
+		case "poduid":
+			sb.WriteString(fmt.Sprintf("%-36s", "POD UID"))
+		case "comm":
+			sb.WriteString(fmt.Sprintf("%-16s", "COMM"))
+		case "pid":
+			sb.WriteString(fmt.Sprintf("%-6s", "PID"))
+		case "op":
+			sb.WriteString(fmt.Sprintf("%-6s", "OP"))
+		case "kind":
+			sb.WriteString(fmt.Sprintf("%-8s", "KIND"))
+		case "plugin":
+			sb.WriteString(fmt.Sprintf("%-24s", "PLUGIN"))
+		case "volume":
+			sb.WriteString(fmt.Sprintf("%-24s", "VOLUME"))
+		case "fs":
+			sb.WriteString(fmt.Sprintf("%-10s", "FS"))
+		case "src":
+			sb.WriteString(fmt.Sprintf("%-16s", "SRC"))
+		case "target":
+			sb.WriteString(fmt.Sprintf("%-16s", "TARGET"))
+		case "flags":
+			sb.WriteString(fmt.Sprintf("%-16s", "FLAGS"))
+		case "ret":
+			sb.WriteString(fmt.Sprintf("%-4s", "RET"))
+		case "lat":
+			sb.WriteString(fmt.Sprintf("%-8s", "LAT(us)"))
+		case "error":
+			sb.WriteString(fmt.Sprintf("%-16s", "ERROR"))
+		}
+		sb.WriteRune(' ')
+	}
+
+	return sb.String()
+}
@@ -94,6 +94,7 @@ var (
 
 func init() {
 	rootCmd.AddCommand(traceloopCmd)
+	utils.RegisterGadgetCommand(traceloopCmd, "traceloop", nil)
 	traceloopCmd.AddCommand(traceloopStartCmd)
 	traceloopCmd.AddCommand(traceloopStopCmd)
 	traceloopCmd.AddCommand(traceloopListCmd)
@@ -164,6 +164,36 @@ again:
 		)
 	}
 
+	// gadget-aggregator cluster role binding and cluster role, only
+	// deployed with --aggregator.
+	err = k8sClient.RbacV1().ClusterRoleBindings().Delete(
+		context.TODO(), "gadget-aggregator-auth-delegator", metav1.DeleteOptions{},
+	)
+	if err != nil && !errors.IsNotFound(err) {
+		errs = append(
+			errs, fmt.Sprintf("failed to remove \"gadget-aggregator\" cluster role binding: %s", err),
+		)
+	}
+	err = k8sClient.RbacV1().ClusterRoles().Delete(
+		context.TODO(), "gadget-aggregator-reader", metav1.DeleteOptions{},
+	)
+	if err != nil && !errors.IsNotFound(err) {
+		errs = append(
+			errs, fmt.Sprintf("failed to remove \"gadget-aggregator\" cluster role: %s", err),
+		)
+	}
+
+	// gadget-creator mutating webhook configuration, only deployed with
+	// --authorize-traces.
+	err = k8sClient.AdmissionregistrationV1().MutatingWebhookConfigurations().Delete(
+		context.TODO(), "gadget-creator", metav1.DeleteOptions{},
+	)
+	if err != nil && !errors.IsNotFound(err) {
+		errs = append(
+			errs, fmt.Sprintf("failed to remove \"gadget-creator\" mutating webhook configuration: %s", err),
+		)
+	}
+
 	// Let's try to remove components of IG versions before v0.5.0,
 	// just in case somebody has a newer CLI but is trying to remove
 	// an old version of Inspektor Gadget from the cluster. Given
@@ -233,7 +263,9 @@ again:
 
 		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
 		defer cancel()
+		progress := utils.NewProgress("Removing namespace")
 		watchtools.Until(ctx, list.ResourceVersion, watcher, conditionFunc)
+		progress.Stop()
 	}
 
 out:
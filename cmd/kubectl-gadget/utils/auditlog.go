@@ -0,0 +1,312 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	"github.com/kinvolk/inspektor-gadget/pkg/k8sutil"
+)
+
+// The actions recorded in the audit log.
+const (
+	AuditActionCreate    = "create"
+	AuditActionOperation = "operation"
+	AuditActionDelete    = "delete"
+)
+
+// AuditLabel is the label of the Kubernetes events recording the actions
+// of kubectl-gadget on the traces, with --audit-events.
+const AuditLabel = "gadget.kinvolk.io/audit"
+
+// auditReasons are the reasons of the audit events of each action.
+var auditReasons = map[string]string{
+	AuditActionCreate:    "TraceCreated",
+	AuditActionOperation: "TraceOperation",
+	AuditActionDelete:    "TraceDeleted",
+}
+
+var (
+	// auditLogPath is the value of the --audit-log flag.
+	auditLogPath string
+
+	// auditEvents is the value of the --audit-events flag.
+	auditEvents bool
+)
+
+// AuditRecord is an entry of the audit log: an action of kubectl-gadget
+// on the traces, with its outcome.
+type AuditRecord struct {
+	Time time.Time `json:"time"`
+
+	// User is the local user running kubectl-gadget and Context the
+	// kubeconfig context used.
+	User    string `json:"user,omitempty"`
+	Context string `json:"context,omitempty"`
+
+	Action    string                          `json:"action"`
+	TraceID   string                          `json:"traceID,omitempty"`
+	Gadget    string                          `json:"gadget,omitempty"`
+	Operation string                          `json:"operation,omitempty"`
+	Node      string                          `json:"node,omitempty"`
+	Group     string                          `json:"group,omitempty"`
+	Filter    *gadgetv1alpha1.ContainerFilter `json:"filter,omitempty"`
+
+	// Parameters are the parameters of the trace created, without the
+	// secrets they can contain.
+	Parameters map[string]string `json:"parameters,omitempty"`
+
+	// Error is the error of the action, empty if it succeeded.
+	Error string `json:"error,omitempty"`
+}
+
+// DefaultAuditLogPath returns the default path of the audit log, next to
+// the configuration file of kubectl-gadget.
+func DefaultAuditLogPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "kubectl-gadget", "audit.log")
+}
+
+// AuditLogPath returns the path of the audit log given by --audit-log,
+// empty if it's disabled.
+func AuditLogPath() string {
+	return auditLogPath
+}
+
+// auditParameters returns a copy of the parameters of a trace to record
+// in the audit log: the credentials of the webhook sink are removed and
+// the public key of the outputs isn't copied.
+func auditParameters(parameters map[string]string) map[string]string {
+	if len(parameters) == 0 {
+		return nil
+	}
+
+	ret := make(map[string]string, len(parameters))
+	for k, v := range parameters {
+		switch k {
+		case gadgetv1alpha1.SinkWebhookParam:
+			if u, err := url.Parse(v); err == nil {
+				v = u.Redacted()
+			}
+		case gadgetv1alpha1.OutputPublicKeyParam:
+			v = "<redacted>"
+		}
+		ret[k] = v
+	}
+	return ret
+}
+
+// newAuditRecord returns a record of the action.
+func newAuditRecord(action, traceID string) *AuditRecord {
+	return &AuditRecord{
+		Time:    time.Now().UTC(),
+		Action:  action,
+		TraceID: traceID,
+	}
+}
+
+// audit records the outcome of the action in the audit log and, with
+// --audit-events, as a Kubernetes event, along with the user and the
+// kubeconfig context. Failing to record it doesn't fail the action: a
+// warning is printed instead.
+func audit(record *AuditRecord, err error) {
+	if auditLogPath == "" && !auditEvents {
+		return
+	}
+
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	if u, err := user.Current(); err == nil {
+		record.User = u.Username
+	}
+
+	if KubernetesConfigFlags.Context != nil && *KubernetesConfigFlags.Context != "" {
+		record.Context = *KubernetesConfigFlags.Context
+	} else if raw, err := KubernetesConfigFlags.ToRawKubeConfigLoader().RawConfig(); err == nil {
+		record.Context = raw.CurrentContext
+	}
+
+	if auditLogPath != "" {
+		if err := appendAuditRecord(auditLogPath, record); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to write the audit log: %s\n", err)
+		}
+	}
+
+	if auditEvents {
+		if err := createAuditEvent(record); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to record the audit event: %s\n", err)
+		}
+	}
+}
+
+func appendAuditRecord(path string, record *AuditRecord) error {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("marshalling audit record: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("creating audit log directory: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// A single write per record, so the records of concurrent
+	// kubectl-gadget commands don't interleave.
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+// ReadAuditLog reads the records of the audit log at path, from the oldest
+// to the newest.
+func ReadAuditLog(path string) ([]AuditRecord, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	records := []AuditRecord{}
+	scanner := bufio.NewScanner(f)
+	// The parameters of a trace can be long, e.g. a seccomp profile.
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var record AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("parsing line %d of %s: %w", line, path, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	return records, nil
+}
+
+func createAuditEvent(record *AuditRecord) error {
+	client, err := k8sutil.NewClientsetFromConfigFlags(KubernetesConfigFlags)
+	if err != nil {
+		return WrapInErrSetupK8sClient(err)
+	}
+
+	message, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("marshalling audit record: %w", err)
+	}
+
+	eventType := corev1.EventTypeNormal
+	reason := auditReasons[record.Action]
+	if record.Error != "" {
+		eventType = corev1.EventTypeWarning
+	}
+
+	labels := map[string]string{
+		AuditLabel: "true",
+	}
+	if record.TraceID != "" {
+		labels[GlobalTraceID] = record.TraceID
+	}
+
+	now := metav1.NewTime(record.Time)
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "kubectl-gadget-audit-",
+			Namespace:    "gadget",
+			Labels:       labels,
+		},
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion: "v1",
+			Kind:       "Namespace",
+			Name:       "gadget",
+		},
+		Reason:         reason,
+		Message:        string(message),
+		Type:           eventType,
+		Source:         corev1.EventSource{Component: "kubectl-gadget"},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	}
+
+	_, err = client.CoreV1().Events("gadget").Create(context.TODO(), event, metav1.CreateOptions{})
+	return err
+}
+
+// ListAuditEvents returns the records of the audit events created with
+// --audit-events that are still kept by the cluster.
+func ListAuditEvents() ([]AuditRecord, error) {
+	client, err := k8sutil.NewClientsetFromConfigFlags(KubernetesConfigFlags)
+	if err != nil {
+		return nil, WrapInErrSetupK8sClient(err)
+	}
+
+	events, err := client.CoreV1().Events("gadget").List(context.TODO(), metav1.ListOptions{
+		LabelSelector: AuditLabel + "=true",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing audit events: %w", err)
+	}
+
+	records := []AuditRecord{}
+	for _, event := range events.Items {
+		var record AuditRecord
+		if err := json.Unmarshal([]byte(event.Message), &record); err != nil {
+			continue
+		}
+		records = append(records, record)
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Time.Before(records[j].Time)
+	})
+
+	return records, nil
+}
@@ -0,0 +1,166 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/kinvolk/inspektor-gadget/pkg/k8sutil"
+)
+
+// The pods backing a service or an ingress are selected with the label
+// selector of the service: unlike a list of pods taken from the endpoints,
+// it keeps matching the pods created after the trace, e.g. when the
+// deployment is scaled or rolled out.
+
+// serviceSelector returns the label selector of the pods backing the
+// service.
+func serviceSelector(client kubernetes.Interface, namespace, name string) (map[string]string, error) {
+	svc, err := client.CoreV1().Services(namespace).Get(context.TODO(), name, metaV1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service %q: %w", name, err)
+	}
+
+	if len(svc.Spec.Selector) == 0 {
+		return nil, fmt.Errorf("service %q has no selector: its endpoints are not managed by Kubernetes", name)
+	}
+
+	return svc.Spec.Selector, nil
+}
+
+// ingressServices returns the sorted names of the services an ingress
+// routes to.
+func ingressServices(ingress *networkingv1.Ingress) []string {
+	set := make(map[string]struct{})
+
+	addBackend := func(backend *networkingv1.IngressBackend) {
+		if backend != nil && backend.Service != nil {
+			set[backend.Service.Name] = struct{}{}
+		}
+	}
+
+	addBackend(ingress.Spec.DefaultBackend)
+	for _, rule := range ingress.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		for i := range rule.HTTP.Paths {
+			addBackend(&rule.HTTP.Paths[i].Backend)
+		}
+	}
+
+	services := make([]string, 0, len(set))
+	for svc := range set {
+		services = append(services, svc)
+	}
+	sort.Strings(services)
+
+	return services
+}
+
+// ingressSelector returns the label selector of the pods backing the
+// services an ingress routes to. The services must select the same pods
+// because the filter of a trace takes a single label selector.
+func ingressSelector(client kubernetes.Interface, namespace, name string) (map[string]string, error) {
+	ingress, err := client.NetworkingV1().Ingresses(namespace).Get(context.TODO(), name, metaV1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ingress %q: %w", name, err)
+	}
+
+	services := ingressServices(ingress)
+	if len(services) == 0 {
+		return nil, fmt.Errorf("ingress %q doesn't route to any service", name)
+	}
+
+	var selector map[string]string
+	for _, svc := range services {
+		s, err := serviceSelector(client, namespace, svc)
+		if err != nil {
+			return nil, err
+		}
+		if selector != nil && !reflect.DeepEqual(selector, s) {
+			return nil, fmt.Errorf("ingress %q routes to services selecting different pods (%s), use --service to choose one",
+				name, strings.Join(services, ", "))
+		}
+		selector = s
+	}
+
+	return selector, nil
+}
+
+// mergeSelector adds the labels of selector to labels, failing if they
+// require different values for the same label.
+func mergeSelector(labels, selector map[string]string) (map[string]string, error) {
+	merged := make(map[string]string, len(labels)+len(selector))
+	for k, v := range labels {
+		merged[k] = v
+	}
+
+	for k, v := range selector {
+		if old, ok := merged[k]; ok && old != v {
+			return nil, fmt.Errorf("the pods are selected with %s=%s, which conflicts with %s=%s", k, v, k, old)
+		}
+		merged[k] = v
+	}
+
+	return merged, nil
+}
+
+// resolveBackends adds the label selector of the pods backing the service
+// or the ingress given by the user to the labels of the filter.
+func resolveBackends(params *CommonFlags) error {
+	flag := "--service"
+	if params.Ingress != "" {
+		flag = "--ingress"
+	}
+
+	if params.Service != "" && params.Ingress != "" {
+		return WrapInErrInvalidArg("--service", errors.New("can't be used with --ingress"))
+	}
+	if params.AllNamespaces {
+		return WrapInErrInvalidArg(flag, errors.New("can't be used with --all-namespaces"))
+	}
+
+	client, err := k8sutil.NewClientsetFromConfigFlags(KubernetesConfigFlags)
+	if err != nil {
+		return WrapInErrSetupK8sClient(err)
+	}
+
+	var selector map[string]string
+	if params.Service != "" {
+		selector, err = serviceSelector(client, params.Namespace, params.Service)
+	} else {
+		selector, err = ingressSelector(client, params.Namespace, params.Ingress)
+	}
+	if err != nil {
+		return WrapInErrInvalidArg(flag, err)
+	}
+
+	params.Labels, err = mergeSelector(params.Labels, selector)
+	if err != nil {
+		return WrapInErrInvalidArg(flag, err)
+	}
+
+	return nil
+}
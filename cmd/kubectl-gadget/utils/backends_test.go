@@ -0,0 +1,87 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+func serviceBackend(name string) networkingv1.IngressBackend {
+	return networkingv1.IngressBackend{
+		Service: &networkingv1.IngressServiceBackend{Name: name},
+	}
+}
+
+func TestIngressServices(t *testing.T) {
+	defaultBackend := serviceBackend("default")
+	ingress := &networkingv1.Ingress{
+		Spec: networkingv1.IngressSpec{
+			DefaultBackend: &defaultBackend,
+			Rules: []networkingv1.IngressRule{
+				{
+					IngressRuleValue: networkingv1.IngressRuleValue{
+						HTTP: &networkingv1.HTTPIngressRuleValue{
+							Paths: []networkingv1.HTTPIngressPath{
+								{Path: "/checkout", Backend: serviceBackend("checkout")},
+								{Path: "/cart", Backend: serviceBackend("cart")},
+								{Path: "/pay", Backend: serviceBackend("checkout")},
+							},
+						},
+					},
+				},
+				// Rules without HTTP paths are ignored
+				{Host: "example.com"},
+			},
+		},
+	}
+
+	expected := []string{"cart", "checkout", "default"}
+	if services := ingressServices(ingress); !reflect.DeepEqual(services, expected) {
+		t.Fatalf("Expected %v, got %v", expected, services)
+	}
+
+	if services := ingressServices(&networkingv1.Ingress{}); len(services) != 0 {
+		t.Fatalf("Expected no services, got %v", services)
+	}
+}
+
+func TestMergeSelector(t *testing.T) {
+	merged, err := mergeSelector(
+		map[string]string{"tier": "backend"},
+		map[string]string{"app": "checkout", "tier": "backend"},
+	)
+	if err != nil {
+		t.Fatalf("Failed to merge selectors: %s", err)
+	}
+
+	expected := map[string]string{"app": "checkout", "tier": "backend"}
+	if !reflect.DeepEqual(merged, expected) {
+		t.Fatalf("Expected %v, got %v", expected, merged)
+	}
+
+	if merged, err := mergeSelector(nil, map[string]string{"app": "checkout"}); err != nil || merged["app"] != "checkout" {
+		t.Fatalf("Failed to merge selector without labels: %v, %v", merged, err)
+	}
+
+	if _, err := mergeSelector(
+		map[string]string{"app": "cart"},
+		map[string]string{"app": "checkout"},
+	); err == nil {
+		t.Fatalf("Expected an error merging conflicting selectors")
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+// CLIConfig is the configuration of kubectl-gadget stored in the
+// configuration directory of the user.
+type CLIConfig struct {
+	// Columns are the columns used by default by each gadget, indexed by
+	// the gadget identifier returned by GadgetID.
+	Columns map[string][]string `json:"columns,omitempty"`
+}
+
+// CLIConfigPath returns the path of the configuration file of kubectl-gadget.
+func CLIConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user's config directory: %w", err)
+	}
+	return filepath.Join(dir, "kubectl-gadget", "config.yaml"), nil
+}
+
+// LoadCLIConfig reads the configuration file of kubectl-gadget. An empty
+// configuration is returned if the file doesn't exist.
+func LoadCLIConfig() (*CLIConfig, error) {
+	path, err := CLIConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	return loadCLIConfig(path)
+}
+
+func loadCLIConfig(path string) (*CLIConfig, error) {
+	config := &CLIConfig{}
+
+	b, err := ioutil.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	if err := yaml.Unmarshal(b, config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %q: %w", path, err)
+	}
+
+	return config, nil
+}
+
+// Save writes the configuration to the configuration file of kubectl-gadget.
+func (c *CLIConfig) Save() error {
+	path, err := CLIConfigPath()
+	if err != nil {
+		return err
+	}
+	return c.save(path)
+}
+
+func (c *CLIConfig) save(path string) error {
+	b, err := yaml.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	if err := ioutil.WriteFile(path, b, 0600); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	return nil
+}
+
+// SetColumns saves the columns to use by default for a gadget. An empty
+// list removes the saved columns.
+func (c *CLIConfig) SetColumns(gadget string, cols []string) {
+	if len(cols) == 0 {
+		delete(c.Columns, gadget)
+		return
+	}
+
+	if c.Columns == nil {
+		c.Columns = make(map[string][]string)
+	}
+	c.Columns[gadget] = cols
+}
+
+// GadgetID returns the identifier of the gadget run by cmd, made of the
+// names of the commands leading to it, e.g. "trace-exec".
+func GadgetID(cmd *cobra.Command) string {
+	// The first element is the name of the root command
+	names := strings.Fields(cmd.CommandPath())[1:]
+	return strings.Join(names, "-")
+}
+
+// ParseColumns parses a comma separated list of columns.
+func ParseColumns(raw string) ([]string, error) {
+	cols := strings.Split(strings.ToLower(raw), ",")
+	for _, col := range cols {
+		if len(col) == 0 {
+			return nil, errors.New("column can't be empty")
+		}
+	}
+	return cols, nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCLIConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubectl-gadget-config")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "kubectl-gadget", "config.yaml")
+
+	config, err := loadCLIConfig(path)
+	if err != nil {
+		t.Fatalf("Failed to load missing config file: %s", err)
+	}
+	if len(config.Columns) != 0 {
+		t.Fatalf("Expected no columns from missing config file, got %v", config.Columns)
+	}
+
+	config.SetColumns("trace-exec", []string{"pid", "comm", "args"})
+	config.SetColumns("trace-open", []string{"pid", "path"})
+	config.SetColumns("trace-open", nil)
+
+	if err := config.save(path); err != nil {
+		t.Fatalf("Failed to save config file: %s", err)
+	}
+
+	config, err = loadCLIConfig(path)
+	if err != nil {
+		t.Fatalf("Failed to load config file: %s", err)
+	}
+
+	expected := map[string][]string{
+		"trace-exec": {"pid", "comm", "args"},
+	}
+	if !reflect.DeepEqual(config.Columns, expected) {
+		t.Fatalf("Expected columns %v, got %v", expected, config.Columns)
+	}
+}
+
+func TestParseColumns(t *testing.T) {
+	cols, err := ParseColumns("PID,comm,args")
+	if err != nil {
+		t.Fatalf("Failed to parse columns: %s", err)
+	}
+	if !reflect.DeepEqual(cols, []string{"pid", "comm", "args"}) {
+		t.Fatalf("Unexpected columns %v", cols)
+	}
+
+	if _, err := ParseColumns("pid,,comm"); err == nil {
+		t.Fatalf("Expected an error for an empty column")
+	}
+}
@@ -44,6 +44,17 @@ func ExecPodCapture(client *kubernetes.Clientset, node string, podCmd string) (s
 }
 
 func ExecPod(client *kubernetes.Clientset, node string, podCmd string, cmdStdout io.Writer, cmdStderr io.Writer) error {
+	return execPod(client, node, podCmd, cmdStdout, cmdStderr, true)
+}
+
+// ExecPodRaw is like ExecPod without a terminal, so that the output of
+// podCmd isn't altered, e.g. its line feeds converted to CRLF, and its
+// standard error is kept apart.
+func ExecPodRaw(client *kubernetes.Clientset, node string, podCmd string, cmdStdout io.Writer, cmdStderr io.Writer) error {
+	return execPod(client, node, podCmd, cmdStdout, cmdStderr, false)
+}
+
+func execPod(client *kubernetes.Clientset, node string, podCmd string, cmdStdout io.Writer, cmdStderr io.Writer, tty bool) error {
 	listOptions := metav1.ListOptions{
 		LabelSelector: "k8s-app=gadget",
 		FieldSelector: "spec.nodeName=" + node + ",status.phase=Running",
@@ -82,7 +93,7 @@ func ExecPod(client *kubernetes.Clientset, node string, podCmd string, cmdStdout
 			Stdin:     false,
 			Stdout:    true,
 			Stderr:    true,
-			TTY:       true,
+			TTY:       tty,
 		}, scheme.ParameterCodec)
 
 	exec, err := remotecommand.NewSPDYExecutor(restConfig, "POST", req.URL())
@@ -94,7 +105,7 @@ func ExecPod(client *kubernetes.Clientset, node string, podCmd string, cmdStdout
 		Stdin:  nil,
 		Stdout: cmdStdout,
 		Stderr: cmdStderr,
-		Tty:    true,
+		Tty:    tty,
 	})
 	return err
 }
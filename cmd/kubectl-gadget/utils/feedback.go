@@ -0,0 +1,133 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+// NodeFeedback is an error or a warning reported by the trace of a node.
+type NodeFeedback struct {
+	Node    string `json:"node"`
+	Message string `json:"message"`
+}
+
+// TraceFeedback aggregates the errors and warnings reported by the traces
+// of a gadget on the different nodes while waiting for them. It's returned
+// to the callers instead of being printed, so that they can handle it, for
+// instance by printing it in JSON format.
+type TraceFeedback struct {
+	Errors   []NodeFeedback `json:"errors,omitempty"`
+	Warnings []NodeFeedback `json:"warnings,omitempty"`
+
+	// TotalNodes is the number of nodes where the trace was created.
+	TotalNodes int `json:"totalNodes"`
+
+	// ReadyNodes is the number of nodes where the trace reached the
+	// expected condition.
+	ReadyNodes int `json:"readyNodes"`
+
+	// TimedOut is true if some of the traces didn't reach the expected
+	// condition before the timeout.
+	TimedOut bool `json:"timedOut,omitempty"`
+}
+
+// newNodeFeedbacks returns the messages of m, given by node, sorted by node.
+func newNodeFeedbacks(m map[string]string) []NodeFeedback {
+	feedbacks := make([]NodeFeedback, 0, len(m))
+	for node, msg := range m {
+		feedbacks = append(feedbacks, NodeFeedback{Node: node, Message: msg})
+	}
+	sort.Slice(feedbacks, func(i, j int) bool {
+		return feedbacks[i].Node < feedbacks[j].Node
+	})
+	return feedbacks
+}
+
+// Empty tells if there is nothing to report.
+func (f *TraceFeedback) Empty() bool {
+	return f == nil || (len(f.Errors) == 0 && len(f.printedWarnings()) == 0 && !f.TimedOut)
+}
+
+// printedWarnings returns the warnings worth printing: they are only
+// printed if the trace isn't ready on any node because they are expected
+// otherwise, e.g. when a gadget isn't supported on some nodes.
+func (f *TraceFeedback) printedWarnings() []NodeFeedback {
+	if f.ReadyNodes > 0 {
+		return nil
+	}
+	return f.Warnings
+}
+
+// Fprint prints the feedback to w. With the JSON output mode, each message
+// is printed as an event of type "err" or "warn", like the ones sent by the
+// gadgets; otherwise it's printed as text.
+func (f *TraceFeedback) Fprint(w io.Writer, params *CommonFlags) {
+	if f.Empty() {
+		return
+	}
+
+	if params != nil && params.OutputMode == OutputModeJSON {
+		for _, e := range f.Errors {
+			fmt.Fprintln(w, eventtypes.EventString(eventtypes.Err(e.Message, e.Node)))
+		}
+		for _, e := range f.printedWarnings() {
+			fmt.Fprintln(w, eventtypes.EventString(eventtypes.Warn(e.Message, e.Node)))
+		}
+		if f.TimedOut {
+			fmt.Fprintln(w, eventtypes.EventString(eventtypes.Warn(f.timeoutMessage(), "")))
+		}
+		return
+	}
+
+	fprintNodeFeedbacks(w, "Error", f.Errors, f.TotalNodes)
+	fprintNodeFeedbacks(w, "Warn", f.printedWarnings(), f.TotalNodes)
+	if f.TimedOut {
+		fmt.Fprintf(w, "Warn: %s\n", f.timeoutMessage())
+	}
+}
+
+func (f *TraceFeedback) timeoutMessage() string {
+	return fmt.Sprintf("trace is ready on %d node(s) out of %d, continuing with them",
+		f.ReadyNodes, f.TotalNodes)
+}
+
+func fprintNodeFeedbacks(w io.Writer, prefix string, feedbacks []NodeFeedback, totalNodes int) {
+	// Do not print the same message from all the nodes several times
+	if len(feedbacks) > 1 && len(feedbacks) == totalNodes {
+		identical := true
+		for _, f := range feedbacks[1:] {
+			if f.Message != feedbacks[0].Message {
+				identical = false
+				break
+			}
+		}
+		if identical {
+			fmt.Fprintf(w, "%s: %s\n",
+				prefix, WrapInErrRunGadgetOnAllNode(errors.New(feedbacks[0].Message)))
+			return
+		}
+	}
+
+	for _, f := range feedbacks {
+		fmt.Fprintf(w, "%s: %s\n",
+			prefix, WrapInErrRunGadgetOnNode(f.Node, errors.New(f.Message)))
+	}
+}
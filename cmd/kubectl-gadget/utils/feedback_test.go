@@ -0,0 +1,129 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTraceFeedbackFprint(t *testing.T) {
+	table := []struct {
+		description string
+		feedback    *TraceFeedback
+		params      *CommonFlags
+		expected    string
+	}{
+		{
+			description: "nil feedback",
+			feedback:    nil,
+			expected:    "",
+		},
+		{
+			description: "single error",
+			feedback: &TraceFeedback{
+				Errors:     []NodeFeedback{{Node: "node", Message: "Err Message"}},
+				TotalNodes: 1,
+			},
+			expected: "Error: failed to run gadget on node \"node\": Err Message\n",
+		},
+		{
+			description: "different errors",
+			feedback: &TraceFeedback{
+				Errors: []NodeFeedback{
+					{Node: "node1", Message: "Err Message 1"},
+					{Node: "node2", Message: "Err Message 2"},
+					{Node: "node3", Message: "Err Message 2"},
+				},
+				TotalNodes: 3,
+			},
+			expected: "Error: failed to run gadget on node \"node1\": Err Message 1\n" +
+				"Error: failed to run gadget on node \"node2\": Err Message 2\n" +
+				"Error: failed to run gadget on node \"node3\": Err Message 2\n",
+		},
+		{
+			// The message is the same but a node didn't report it, so it
+			// must not say it failed on all nodes.
+			description: "same error not on all nodes",
+			feedback: &TraceFeedback{
+				Errors: []NodeFeedback{
+					{Node: "node2", Message: "Err Message"},
+					{Node: "node3", Message: "Err Message"},
+				},
+				TotalNodes: 3,
+			},
+			expected: "Error: failed to run gadget on node \"node2\": Err Message\n" +
+				"Error: failed to run gadget on node \"node3\": Err Message\n",
+		},
+		{
+			description: "same error on all nodes",
+			feedback: &TraceFeedback{
+				Errors: []NodeFeedback{
+					{Node: "node1", Message: "Err Message"},
+					{Node: "node2", Message: "Err Message"},
+				},
+				TotalNodes: 2,
+			},
+			expected: "Error: failed to run gadget on all nodes: Err Message\n",
+		},
+		{
+			description: "warnings are not printed if a node is ready",
+			feedback: &TraceFeedback{
+				Warnings:   []NodeFeedback{{Node: "node1", Message: "Warn Message"}},
+				TotalNodes: 2,
+				ReadyNodes: 1,
+			},
+			expected: "",
+		},
+		{
+			description: "warnings are printed if no node is ready",
+			feedback: &TraceFeedback{
+				Warnings:   []NodeFeedback{{Node: "node1", Message: "Warn Message"}},
+				TotalNodes: 2,
+			},
+			expected: "Warn: failed to run gadget on node \"node1\": Warn Message\n",
+		},
+		{
+			description: "timeout",
+			feedback: &TraceFeedback{
+				TotalNodes: 3,
+				ReadyNodes: 2,
+				TimedOut:   true,
+			},
+			expected: "Warn: trace is ready on 2 node(s) out of 3, continuing with them\n",
+		},
+		{
+			description: "json output",
+			feedback: &TraceFeedback{
+				Errors:     []NodeFeedback{{Node: "node1", Message: "Err Message"}},
+				TotalNodes: 2,
+				ReadyNodes: 1,
+				TimedOut:   true,
+			},
+			params: &CommonFlags{OutputMode: OutputModeJSON},
+			expected: `{"type":"err","message":"Err Message","node":"node1"}` + "\n" +
+				`{"type":"warn","message":"trace is ready on 1 node(s) out of 2, continuing with them"}` + "\n",
+		},
+	}
+
+	for _, entry := range table {
+		var out bytes.Buffer
+
+		entry.feedback.Fprint(&out, entry.params)
+		if out.String() != entry.expected {
+			t.Fatalf("%s: '%v' != '%v'", entry.description, out.String(), entry.expected)
+		}
+	}
+}
@@ -18,9 +18,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"strings"
 
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	"github.com/kinvolk/inspektor-gadget/pkg/encryptedfile"
 	"github.com/kinvolk/inspektor-gadget/pkg/k8sutil"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -32,6 +36,54 @@ var KubernetesConfigFlags = genericclioptions.NewConfigFlags(false)
 func FlagInit(rootCmd *cobra.Command) {
 	cobra.OnInitialize(cobraInit)
 	KubernetesConfigFlags.AddFlags(rootCmd.PersistentFlags())
+	rootCmd.PersistentFlags().DurationVar(
+		&traceTimeout,
+		"trace-timeout",
+		TraceTimeout,
+		fmt.Sprintf("Time to wait for the traces to be ready. It is increased by this value every %d nodes", TraceTimeoutNodesStep),
+	)
+	rootCmd.PersistentFlags().DurationVar(
+		&completionTimeout,
+		"completion-timeout",
+		CompletionTimeout,
+		fmt.Sprintf("Time to wait for the gadgets printing their results once, like the snapshot ones, to complete. It is increased by this value every %d nodes", TraceTimeoutNodesStep),
+	)
+	rootCmd.PersistentFlags().IntVar(
+		&minNodes,
+		"min-nodes",
+		0,
+		"Minimum number of nodes where the trace must be ready to proceed (default: at least one)",
+	)
+	rootCmd.PersistentFlags().BoolVar(
+		&requireAllNodes,
+		"require-all-nodes",
+		false,
+		"Fail if the trace is not ready on all the nodes instead of proceeding with the healthy ones",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&nodeStatusFile,
+		"node-status-file",
+		"",
+		"Write the status of the trace on each node in JSON format to this file",
+	)
+	rootCmd.PersistentFlags().BoolVarP(
+		&quiet,
+		"quiet", "q",
+		false,
+		"Do not display the progress while waiting for the traces or the resources",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&auditLogPath,
+		"audit-log",
+		DefaultAuditLogPath(),
+		"File where the traces created, their operations and their deletions are recorded (disabled if empty)",
+	)
+	rootCmd.PersistentFlags().BoolVar(
+		&auditEvents,
+		"audit-events",
+		false,
+		"Also record the traces created, their operations and their deletions as Kubernetes events in the gadget namespace",
+	)
 	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
 }
 
@@ -57,6 +109,27 @@ type CommonFlags struct {
 	// Labels is a parsed representation of LabelsRaw
 	Labels map[string]string
 
+	// AnnotationsRaw allows to filter containers by the annotations of
+	// their pod. Each element has the following format: key=value.
+	AnnotationsRaw []string
+
+	// Annotations is a parsed representation of AnnotationsRaw
+	Annotations map[string]string
+
+	// Service allows to filter the pods backing this service. It's
+	// resolved to the label selector of the service.
+	Service string
+
+	// Ingress allows to filter the pods backing the services this ingress
+	// routes to.
+	Ingress string
+
+	// FromManifest allows to filter the pods of the workload defined in
+	// this manifest file, "-" being the standard input. It's resolved
+	// to the namespace, the label selector and the container of the
+	// workload.
+	FromManifest string
+
 	// Node allows to filter containers by node name
 	Node string
 
@@ -75,9 +148,20 @@ type CommonFlags struct {
 	// Podname allows to filter containers by the pod name
 	Podname string
 
+	// PodUID allows to filter containers by the UID of their pod. Unlike
+	// the name, it's not reused by a pod recreated with the same name.
+	PodUID string
+
+	// FollowRestarts rebinds the filter to the pod replacing the one
+	// selected with Podname or PodUID when it's deleted
+	FollowRestarts bool
+
 	// Containername allows to filter containers by name
 	Containername string
 
+	// Sandbox allows to filter containers by the sandbox they run in
+	Sandbox string
+
 	// OutputMode specifies the way output should be printed
 	OutputMode string
 
@@ -89,6 +173,93 @@ type CommonFlags struct {
 
 	// Number of seconds that the gadget will run for
 	Timeout int
+
+	// Anonymize replaces IP addresses and hostnames in the events by
+	// stable pseudonyms
+	Anonymize bool
+
+	// AnonymizeKey is the key used to generate the pseudonyms. A random
+	// key is used if it's empty.
+	AnonymizeKey string
+
+	// SinkFile, SinkWebhook and SinkPrometheus write the events to other
+	// outputs from the nodes, see the sink parameters of the Trace.
+	SinkFile       string
+	SinkWebhook    string
+	SinkPrometheus bool
+
+	// OutputPublicKey is the file of the public key the files written on
+	// the nodes are encrypted for.
+	OutputPublicKey string
+}
+
+// SinkParameters returns the trace parameters of the sinks given by the
+// flags.
+func (params *CommonFlags) SinkParameters() (map[string]string, error) {
+	sinks := map[string]string{}
+	if params.OutputPublicKey != "" {
+		if params.SinkFile == "" {
+			return nil, errors.New("--output-public-key can only be used with --sink-file")
+		}
+
+		publicKey, err := ioutil.ReadFile(params.OutputPublicKey)
+		if err != nil {
+			return nil, WrapInErrInvalidArg("--output-public-key", err)
+		}
+		if _, err := encryptedfile.ParsePublicKey(publicKey); err != nil {
+			return nil, WrapInErrInvalidArg("--output-public-key", err)
+		}
+		sinks[gadgetv1alpha1.OutputPublicKeyParam] = string(publicKey)
+	}
+	if params.SinkFile != "" {
+		sinks[gadgetv1alpha1.SinkFileParam] = params.SinkFile
+	}
+	if params.SinkWebhook != "" {
+		sinks[gadgetv1alpha1.SinkWebhookParam] = params.SinkWebhook
+	}
+	if params.SinkPrometheus {
+		sinks[gadgetv1alpha1.SinkPrometheusParam] = "true"
+	}
+	return sinks, nil
+}
+
+// AddTraceNameFlag adds the --name flag to the commands creating traces
+// that outlive the command, like the start sub-command of multi-rounds
+// gadgets. The name can then be used instead of the trace ID.
+func AddTraceNameFlag(command *cobra.Command, name *string) {
+	command.PersistentFlags().StringVarP(
+		name,
+		"name",
+		"",
+		"",
+		"Name of the trace, it can be used instead of the trace ID in the other commands",
+	)
+}
+
+// AddTraceGroupFlag adds the --group flag to the commands creating traces
+// that outlive the command. The traces of a group can then be listed,
+// stopped and deleted together with the group command.
+func AddTraceGroupFlag(command *cobra.Command, group *string) {
+	command.PersistentFlags().StringVarP(
+		group,
+		"group",
+		"",
+		"",
+		"Group of the trace, the traces of a group are managed together with \"kubectl gadget group\"",
+	)
+}
+
+// AddHumanReadableFlag adds the --human-readable flag to the commands
+// printing sizes and durations. It has no effect on the JSON output, which
+// always contains the raw values.
+func AddHumanReadableFlag(command *cobra.Command, humanReadable *bool) {
+	command.PersistentFlags().BoolVarP(
+		humanReadable,
+		"human-readable",
+		"",
+		false,
+		"Print sizes and durations with units (e.g. 1.5 MiB, 2.3 ms)",
+	)
 }
 
 // GetNamespace returns the namespace specified by '-n' or the default
@@ -120,6 +291,54 @@ func AddCommonFlags(command *cobra.Command, params *CommonFlags) {
 			}
 		}
 
+		// Annotations
+		if len(params.AnnotationsRaw) > 0 {
+			params.Annotations = make(map[string]string)
+			for _, pair := range params.AnnotationsRaw {
+				kv := strings.SplitN(pair, "=", 2)
+				if len(kv) != 2 || kv[0] == "" {
+					return WrapInErrInvalidArg("--pod-annotation",
+						fmt.Errorf("%q should be a key-value pair (key=value)", pair))
+				}
+				params.Annotations[kv[0]] = kv[1]
+			}
+		}
+
+		// Workload manifest
+		if params.FromManifest != "" {
+			if err := resolveManifest(params); err != nil {
+				return err
+			}
+		}
+
+		// Services and ingresses
+		if params.Service != "" || params.Ingress != "" {
+			if err := resolveBackends(params); err != nil {
+				return err
+			}
+		}
+
+		// Sandbox
+		switch params.Sandbox {
+		case "", eventtypes.SandboxNone, eventtypes.SandboxGVisor, eventtypes.SandboxKata:
+		default:
+			return WrapInErrInvalidArg("--sandbox",
+				fmt.Errorf("%q is not a sandbox, use %q, %q or %q", params.Sandbox,
+					eventtypes.SandboxGVisor, eventtypes.SandboxKata, eventtypes.SandboxNone))
+		}
+
+		// Pod restarts
+		if params.FollowRestarts {
+			if params.Podname == "" && params.PodUID == "" {
+				return WrapInErrInvalidArg("--follow-restarts",
+					errors.New("requires --podname or --pod-uid"))
+			}
+			if params.AllNamespaces {
+				return WrapInErrInvalidArg("--follow-restarts",
+					errors.New("can't be used with --all-namespaces"))
+			}
+		}
+
 		// Verify if the node specified in the filter actually exist. This check
 		// will be removed when we will support the addition/deletion of nodes.
 		if params.Node != "" {
@@ -147,6 +366,18 @@ func AddCommonFlags(command *cobra.Command, params *CommonFlags) {
 			}
 		}
 
+		// Columns saved with "kubectl gadget config set-columns" are
+		// used unless the output mode is given explicitly.
+		if !cmd.Flags().Changed("output") {
+			config, err := LoadCLIConfig()
+			if err != nil {
+				return err
+			}
+			if cols, ok := config.Columns[GadgetID(cmd)]; ok {
+				params.OutputMode = OutputModeCustomColumns + "=" + strings.Join(cols, ",")
+			}
+		}
+
 		// Output Mode
 		switch {
 		case params.OutputMode == OutputModeColumns:
@@ -160,12 +391,9 @@ func AddCommonFlags(command *cobra.Command, params *CommonFlags) {
 					errors.New("expects a comma separated list of columns to use"))
 			}
 
-			cols := strings.Split(strings.ToLower(parts[1]), ",")
-			for _, col := range cols {
-				if len(col) == 0 {
-					return WrapInErrInvalidArg(OutputModeCustomColumns,
-						errors.New("column can't be empty"))
-				}
+			cols, err := ParseColumns(parts[1])
+			if err != nil {
+				return WrapInErrInvalidArg(OutputModeCustomColumns, err)
 			}
 
 			params.CustomColumns = cols
@@ -191,6 +419,34 @@ func AddCommonFlags(command *cobra.Command, params *CommonFlags) {
 		"Labels selector to filter on. Only '=' is supported (e.g. key1=value1,key2=value2).",
 	)
 
+	command.PersistentFlags().StringArrayVar(
+		&params.AnnotationsRaw,
+		"pod-annotation",
+		[]string{},
+		"Show only data from pods with this annotation (e.g. key=value). It can be repeated to require several annotations.",
+	)
+
+	command.PersistentFlags().StringVar(
+		&params.Service,
+		"service",
+		"",
+		"Show only data from pods backing this service, including the ones created later",
+	)
+
+	command.PersistentFlags().StringVar(
+		&params.Ingress,
+		"ingress",
+		"",
+		"Show only data from pods backing the services this ingress routes to, including the ones created later",
+	)
+
+	command.PersistentFlags().StringVar(
+		&params.FromManifest,
+		"from-manifest",
+		"",
+		"Show only data from pods of the workload defined in this manifest file, or - for the standard input, including the ones created later",
+	)
+
 	command.PersistentFlags().StringVar(
 		&params.Node,
 		"node",
@@ -206,6 +462,20 @@ func AddCommonFlags(command *cobra.Command, params *CommonFlags) {
 		"Show only data from pods with that name",
 	)
 
+	command.PersistentFlags().StringVar(
+		&params.PodUID,
+		"pod-uid",
+		"",
+		"Show only data from the pod with that UID",
+	)
+
+	command.PersistentFlags().BoolVar(
+		&params.FollowRestarts,
+		"follow-restarts",
+		false,
+		"Keep tracing the pod selected with --podname or --pod-uid when it's replaced by a new pod of the same workload",
+	)
+
 	command.PersistentFlags().StringVarP(
 		&params.Containername,
 		"containername",
@@ -214,6 +484,14 @@ func AddCommonFlags(command *cobra.Command, params *CommonFlags) {
 		"Show only data from containers with that name",
 	)
 
+	command.PersistentFlags().StringVar(
+		&params.Sandbox,
+		"sandbox",
+		"",
+		fmt.Sprintf("Show only data from containers running in that sandbox (%q or %q), or %q for containers running on the host kernel",
+			eventtypes.SandboxGVisor, eventtypes.SandboxKata, eventtypes.SandboxNone),
+	)
+
 	command.PersistentFlags().BoolVarP(
 		&params.AllNamespaces,
 		"all-namespaces",
@@ -245,4 +523,52 @@ func AddCommonFlags(command *cobra.Command, params *CommonFlags) {
 		0,
 		"Number of seconds that the gadget will run for",
 	)
+
+	command.PersistentFlags().BoolVarP(
+		&params.Anonymize,
+		"anonymize",
+		"",
+		false,
+		"Replace IP addresses and hostnames in the events by prefix-preserving pseudonyms",
+	)
+
+	command.PersistentFlags().StringVarP(
+		&params.AnonymizeKey,
+		"anonymize-key",
+		"",
+		"",
+		"Key used by --anonymize. Use the same key to get the same pseudonyms across runs (default: random)",
+	)
+
+	command.PersistentFlags().StringVarP(
+		&params.SinkFile,
+		"sink-file",
+		"",
+		"",
+		"Also append the events to this file of /var/log/gadget on the nodes",
+	)
+
+	command.PersistentFlags().StringVarP(
+		&params.SinkWebhook,
+		"sink-webhook",
+		"",
+		"",
+		"Also post the events from the nodes to this URL, as newline-delimited JSON",
+	)
+
+	command.PersistentFlags().BoolVarP(
+		&params.SinkPrometheus,
+		"sink-prometheus",
+		"",
+		false,
+		"Also count the events in the inspektor_gadget_trace_events_total metric of the gadget pods",
+	)
+
+	command.PersistentFlags().StringVarP(
+		&params.OutputPublicKey,
+		"output-public-key",
+		"",
+		"",
+		"Encrypt the file of --sink-file for this PEM encoded RSA public key, see the decrypt command",
+	)
 }
@@ -0,0 +1,409 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/kinvolk/inspektor-gadget/pkg/k8sutil"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+// followRetryInterval is the time to wait before watching the pods again
+// when the watch fails.
+const followRetryInterval = 2 * time.Second
+
+// workloadRef identifies the workload a pod belongs to. Pods of a
+// ReplicaSet belong to its Deployment, so that a pod created by a rollout
+// is seen as the replacement of a pod of the previous ReplicaSet. A pod
+// without a controller is its own workload: it can only be replaced by a
+// pod with the same name.
+type workloadRef struct {
+	Kind string
+	Name string
+}
+
+// podFollower implements --follow-restarts: when the pod selected with
+// --podname or --pod-uid is deleted, it looks for the pod replacing it in
+// the same workload and rebinds the filter of the trace to that pod.
+type podFollower struct {
+	client kubernetes.Interface
+	params *CommonFlags
+	w      io.Writer
+
+	target   *v1.Pod
+	workload workloadRef
+
+	// siblings are the pods of the workload running alongside the target
+	// when it was bound: they are not replacing it.
+	siblings map[types.UID]struct{}
+
+	// pods are the pods of the namespace, kept up to date by the watch.
+	pods map[types.UID]*v1.Pod
+
+	// deleted is true between the deletion of the target and the
+	// rebinding to its replacement.
+	deleted bool
+
+	// replicaSets caches the workload of the ReplicaSets.
+	replicaSets map[string]workloadRef
+}
+
+// newPodFollower returns nil if --follow-restarts is not used. Otherwise it
+// looks up the target pod so that an invalid target is reported before the
+// trace is created.
+func newPodFollower(params *CommonFlags) (*podFollower, error) {
+	if !params.FollowRestarts {
+		return nil, nil
+	}
+
+	client, err := k8sutil.NewClientsetFromConfigFlags(KubernetesConfigFlags)
+	if err != nil {
+		return nil, WrapInErrSetupK8sClient(err)
+	}
+
+	f := &podFollower{
+		client:      client,
+		params:      params,
+		w:           os.Stderr,
+		pods:        make(map[types.UID]*v1.Pod),
+		replicaSets: make(map[string]workloadRef),
+	}
+
+	ctx := context.TODO()
+
+	pods, err := client.CoreV1().Pods(params.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if params.PodUID != "" && string(pod.UID) != params.PodUID {
+			continue
+		}
+		if params.Podname != "" && pod.Name != params.Podname {
+			continue
+		}
+		f.target = pod
+		break
+	}
+	if f.target == nil {
+		return nil, WrapInErrInvalidArg("--follow-restarts",
+			fmt.Errorf("no pod matching the filter in namespace %q", params.Namespace))
+	}
+
+	f.workload, err = f.workloadOf(ctx, f.target)
+	if err != nil {
+		return nil, err
+	}
+	f.setPods(pods.Items)
+	f.siblings = f.workloadPods(ctx)
+
+	return f, nil
+}
+
+// start follows the target pod for the traces traceID until the returned
+// function is called.
+func (f *podFollower) start(traceID string) func() {
+	if f == nil {
+		return func() {}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		for {
+			if err := f.watch(ctx, traceID); err != nil && ctx.Err() == nil {
+				f.warn(fmt.Sprintf("failed to watch pods: %s", err))
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(followRetryInterval):
+			}
+		}
+	}()
+
+	return cancel
+}
+
+// watch lists the pods of the namespace, then keeps them up to date until
+// the watch is closed. The list catches a deletion of the target that
+// happened while the pods weren't watched.
+func (f *podFollower) watch(ctx context.Context, traceID string) error {
+	pods, err := f.client.CoreV1().Pods(f.target.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	f.setPods(pods.Items)
+	if _, ok := f.pods[f.target.UID]; !ok {
+		f.targetDeleted()
+	}
+	f.rebindIfReplaced(ctx, traceID)
+
+	watcher, err := f.client.CoreV1().Pods(f.target.Namespace).Watch(ctx, metav1.ListOptions{
+		ResourceVersion: pods.ResourceVersion,
+	})
+	if err != nil {
+		return err
+	}
+	defer watcher.Stop()
+
+	for event := range watcher.ResultChan() {
+		switch event.Type {
+		case watch.Added, watch.Modified:
+			if pod, ok := event.Object.(*v1.Pod); ok {
+				f.pods[pod.UID] = pod
+			}
+		case watch.Deleted:
+			if pod, ok := event.Object.(*v1.Pod); ok {
+				delete(f.pods, pod.UID)
+				if pod.UID == f.target.UID {
+					f.targetDeleted()
+				}
+			}
+		case watch.Error:
+			return apierrors.FromObject(event.Object)
+		}
+
+		f.rebindIfReplaced(ctx, traceID)
+	}
+
+	return nil
+}
+
+func (f *podFollower) setPods(pods []v1.Pod) {
+	f.pods = make(map[types.UID]*v1.Pod, len(pods))
+	for i := range pods {
+		f.pods[pods[i].UID] = &pods[i]
+	}
+}
+
+func (f *podFollower) targetDeleted() {
+	if f.deleted {
+		return
+	}
+	f.deleted = true
+	f.info(fmt.Sprintf("pod %q was deleted, waiting for the pod replacing it",
+		f.target.Namespace+"/"+f.target.Name))
+}
+
+// workloadOf returns the workload pod belongs to.
+func (f *podFollower) workloadOf(ctx context.Context, pod *v1.Pod) (workloadRef, error) {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return workloadRef{Kind: "Pod", Name: pod.Name}, nil
+	}
+	if owner.Kind != "ReplicaSet" {
+		return workloadRef{Kind: owner.Kind, Name: owner.Name}, nil
+	}
+
+	if ref, ok := f.replicaSets[owner.Name]; ok {
+		return ref, nil
+	}
+
+	ref := workloadRef{Kind: owner.Kind, Name: owner.Name}
+	rs, err := f.client.AppsV1().ReplicaSets(pod.Namespace).Get(ctx, owner.Name, metav1.GetOptions{})
+	if err != nil {
+		return workloadRef{}, fmt.Errorf("failed to get the replicaset of pod %q: %w", pod.Name, err)
+	}
+	if rsOwner := metav1.GetControllerOf(rs); rsOwner != nil {
+		ref = workloadRef{Kind: rsOwner.Kind, Name: rsOwner.Name}
+	}
+	f.replicaSets[owner.Name] = ref
+
+	return ref, nil
+}
+
+// workloadPods returns the pods of the workload of the target, except the
+// target itself.
+func (f *podFollower) workloadPods(ctx context.Context) map[types.UID]struct{} {
+	uids := make(map[types.UID]struct{})
+	for uid, pod := range f.pods {
+		if uid == f.target.UID {
+			continue
+		}
+		if ref, err := f.workloadOf(ctx, pod); err == nil && ref == f.workload {
+			uids[uid] = struct{}{}
+		}
+	}
+	return uids
+}
+
+// rebindIfReplaced rebinds the trace once the target is deleted and a pod
+// replacing it exists. A failed rebinding is retried on the next event.
+func (f *podFollower) rebindIfReplaced(ctx context.Context, traceID string) {
+	if !f.deleted {
+		return
+	}
+
+	candidates := []*v1.Pod{}
+	for uid, pod := range f.pods {
+		if _, ok := f.siblings[uid]; ok {
+			continue
+		}
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		if f.params.Node != "" && pod.Spec.NodeName != f.params.Node {
+			continue
+		}
+		if ref, err := f.workloadOf(ctx, pod); err != nil || ref != f.workload {
+			continue
+		}
+		candidates = append(candidates, pod)
+	}
+
+	replacement := pickReplacement(f.target, candidates)
+	if replacement == nil {
+		return
+	}
+
+	if err := f.rebind(ctx, traceID, replacement); err != nil {
+		f.warn(fmt.Sprintf("failed to follow pod %q: %s", replacement.Name, err))
+		return
+	}
+
+	f.info(fmt.Sprintf("pod %q was replaced by pod %q, tracing it now",
+		f.target.Namespace+"/"+f.target.Name, replacement.Name))
+
+	f.target = replacement
+	f.deleted = false
+	f.siblings = f.workloadPods(ctx)
+}
+
+// pickReplacement chooses deterministically the pod replacing old among
+// the candidates of the same workload: a pod with the same name first, as
+// created by a StatefulSet, then a pod on the same node, as created by a
+// DaemonSet, then the oldest pod. The name breaks the remaining ties.
+func pickReplacement(old *v1.Pod, candidates []*v1.Pod) *v1.Pod {
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	rank := func(pod *v1.Pod) int {
+		switch {
+		case pod.Name == old.Name:
+			return 0
+		case pod.Spec.NodeName != "" && pod.Spec.NodeName == old.Spec.NodeName:
+			return 1
+		}
+		return 2
+	}
+
+	sorted := append([]*v1.Pod{}, candidates...)
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if ra, rb := rank(a), rank(b); ra != rb {
+			return ra < rb
+		}
+		if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+			return a.CreationTimestamp.Before(&b.CreationTimestamp)
+		}
+		return a.Name < b.Name
+	})
+
+	return sorted[0]
+}
+
+// filterPatch returns the JSON merge patch binding the traces to pod. Only
+// the fields used to select the initial pod are changed.
+func filterPatch(params *CommonFlags, pod *v1.Pod) ([]byte, error) {
+	filter := map[string]string{}
+	labels := map[string]string{}
+	if params.Podname != "" {
+		filter["podname"] = pod.Name
+		labels["podName"] = pod.Name
+	}
+	if params.PodUID != "" {
+		filter["podUID"] = string(pod.UID)
+	}
+
+	patch := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"filter": filter,
+		},
+	}
+	if len(labels) > 0 {
+		patch["metadata"] = map[string]interface{}{
+			"labels": labels,
+		}
+	}
+
+	return json.Marshal(patch)
+}
+
+// rebind updates the filter of the traces. The gadget tracer manager then
+// updates the containers of the tracers. The gadgets are also restarted
+// because some of them, like dns, only look at the filter when they start.
+func (f *podFollower) rebind(ctx context.Context, traceID string, pod *v1.Pod) error {
+	patch, err := filterPatch(f.params, pod)
+	if err != nil {
+		return err
+	}
+
+	traceClient, err := getTraceClient()
+	if err != nil {
+		return err
+	}
+
+	traces, err := getTraceListFromID(traceID)
+	if err != nil {
+		return err
+	}
+
+	for _, trace := range traces.Items {
+		_, err := traceClient.GadgetV1alpha1().Traces(trace.Namespace).Patch(
+			ctx, trace.Name, types.MergePatchType, patch, metav1.PatchOptions{},
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update the filter of trace %q: %w", trace.Name, err)
+		}
+	}
+
+	if err := SetTraceOperation(traceID, "stop"); err != nil {
+		return err
+	}
+	return SetTraceOperation(traceID, "start")
+}
+
+func (f *podFollower) info(msg string) {
+	f.print(eventtypes.Info(msg, ""), "Info")
+}
+
+func (f *podFollower) warn(msg string) {
+	f.print(eventtypes.Warn(msg, ""), "Warn")
+}
+
+func (f *podFollower) print(event eventtypes.Event, prefix string) {
+	if f.params.OutputMode == OutputModeJSON {
+		fmt.Fprintln(f.w, eventtypes.EventString(event))
+		return
+	}
+	fmt.Fprintf(f.w, "%s: %s\n", prefix, event.Message)
+}
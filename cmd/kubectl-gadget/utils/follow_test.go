@@ -0,0 +1,144 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func testPod(name, node string, created time.Time) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "default",
+			UID:               types.UID(name + "-uid"),
+			CreationTimestamp: metav1.NewTime(created),
+		},
+		Spec: v1.PodSpec{NodeName: node},
+	}
+}
+
+func TestPickReplacement(t *testing.T) {
+	now := time.Now()
+	old := testPod("web-0", "node-1", now.Add(-time.Hour))
+
+	table := []struct {
+		description string
+		candidates  []*v1.Pod
+		expected    string
+	}{
+		{
+			description: "No candidate",
+			expected:    "",
+		},
+		{
+			description: "Same name first",
+			candidates: []*v1.Pod{
+				testPod("web-1", "node-1", now.Add(-time.Minute)),
+				testPod("web-0", "node-2", now),
+			},
+			expected: "web-0",
+		},
+		{
+			description: "Same node before older pod",
+			candidates: []*v1.Pod{
+				testPod("web-7f9c-abcde", "node-2", now.Add(-time.Minute)),
+				testPod("web-7f9c-fghij", "node-1", now),
+			},
+			expected: "web-7f9c-fghij",
+		},
+		{
+			description: "Oldest pod",
+			candidates: []*v1.Pod{
+				testPod("web-7f9c-fghij", "node-2", now),
+				testPod("web-7f9c-abcde", "node-3", now.Add(-time.Minute)),
+			},
+			expected: "web-7f9c-abcde",
+		},
+		{
+			description: "Name breaks ties",
+			candidates: []*v1.Pod{
+				testPod("web-7f9c-zzzzz", "node-2", now),
+				testPod("web-7f9c-aaaaa", "node-3", now),
+			},
+			expected: "web-7f9c-aaaaa",
+		},
+	}
+
+	for _, entry := range table {
+		result := pickReplacement(old, entry.candidates)
+		name := ""
+		if result != nil {
+			name = result.Name
+		}
+		if name != entry.expected {
+			t.Fatalf("Failed test %q: got %q, expected %q", entry.description, name, entry.expected)
+		}
+	}
+}
+
+func TestFilterPatch(t *testing.T) {
+	pod := testPod("web-7f9c-abcde", "node-1", time.Now())
+
+	table := []struct {
+		description string
+		params      *CommonFlags
+		expected    map[string]interface{}
+	}{
+		{
+			description: "Pod name",
+			params:      &CommonFlags{Podname: "web-7f9c-fghij"},
+			expected: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"filter": map[string]interface{}{"podname": "web-7f9c-abcde"},
+				},
+				"metadata": map[string]interface{}{
+					"labels": map[string]interface{}{"podName": "web-7f9c-abcde"},
+				},
+			},
+		},
+		{
+			description: "Pod UID",
+			params:      &CommonFlags{PodUID: "web-7f9c-fghij-uid"},
+			expected: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"filter": map[string]interface{}{"podUID": "web-7f9c-abcde-uid"},
+				},
+			},
+		},
+	}
+
+	for _, entry := range table {
+		b, err := filterPatch(entry.params, pod)
+		if err != nil {
+			t.Fatalf("Failed test %q: %s", entry.description, err)
+		}
+
+		var patch map[string]interface{}
+		if err := json.Unmarshal(b, &patch); err != nil {
+			t.Fatalf("Failed test %q: %s", entry.description, err)
+		}
+		if !reflect.DeepEqual(patch, entry.expected) {
+			t.Fatalf("Failed test %q: got %s", entry.description, b)
+		}
+	}
+}
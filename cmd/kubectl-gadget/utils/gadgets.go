@@ -0,0 +1,50 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// GadgetCommand links a command to the gadget it runs. It's used by
+// "kubectl gadget explain" to document them together.
+type GadgetCommand struct {
+	Command *cobra.Command
+
+	// Gadget is the name of the gadget used in the Trace resources.
+	Gadget string
+
+	// Event is a value of the type printed by the command with
+	// "-o json". It's nil when the command doesn't print events.
+	Event interface{}
+}
+
+var gadgetCommands []GadgetCommand
+
+// RegisterGadgetCommand records that command runs gadget. It must be
+// called from the init() function of the command.
+func RegisterGadgetCommand(command *cobra.Command, gadget string, event interface{}) {
+	gadgetCommands = append(gadgetCommands, GadgetCommand{
+		Command: command,
+		Gadget:  gadget,
+		Event:   event,
+	})
+}
+
+// GadgetCommands returns the commands registered with
+// RegisterGadgetCommand.
+func GadgetCommands() []GadgetCommand {
+	return gadgetCommands
+}
@@ -0,0 +1,243 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	"github.com/kinvolk/inspektor-gadget/pkg/operationqueue"
+)
+
+// TraceGroupMember is a trace of a group, with the state of its copies on
+// the different nodes.
+type TraceGroupMember struct {
+	Group   string
+	TraceID string
+	Name    string
+	Gadget  string
+	Nodes   []string
+	// States are the different states of the copies of the trace, "Error"
+	// for the ones whose last operation failed.
+	States []string
+}
+
+// groupMembers gathers the traces, one per node, into the members of their
+// groups, sorted by group and trace ID. The traces without group are
+// ignored.
+func groupMembers(traces []gadgetv1alpha1.Trace) []TraceGroupMember {
+	members := map[string]*TraceGroupMember{}
+
+	for _, trace := range traces {
+		group := trace.ObjectMeta.Labels[TraceGroup]
+		id := trace.ObjectMeta.Labels[GlobalTraceID]
+		if group == "" || id == "" {
+			continue
+		}
+
+		member, ok := members[id]
+		if !ok {
+			member = &TraceGroupMember{
+				Group:   group,
+				TraceID: id,
+				Name:    trace.ObjectMeta.Labels[TraceName],
+				Gadget:  trace.Spec.Gadget,
+			}
+			members[id] = member
+		}
+
+		if trace.Spec.Node != "" {
+			member.Nodes = append(member.Nodes, trace.Spec.Node)
+		}
+
+		state := trace.Status.State
+		if trace.Status.OperationError != "" {
+			state = "Error"
+		}
+		if state != "" && !containsString(member.States, state) {
+			member.States = append(member.States, state)
+		}
+	}
+
+	ret := make([]TraceGroupMember, 0, len(members))
+	for _, member := range members {
+		sort.Strings(member.Nodes)
+		sort.Strings(member.States)
+		ret = append(ret, *member)
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Group != ret[j].Group {
+			return ret[i].Group < ret[j].Group
+		}
+		return ret[i].TraceID < ret[j].TraceID
+	})
+
+	return ret
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// notStoppable returns the members which can't be stopped because some of
+// their copies aren't running.
+func notStoppable(members []TraceGroupMember) []string {
+	var ret []string
+	for _, member := range members {
+		if len(member.States) != 1 || member.States[0] != "Started" {
+			ret = append(ret, fmt.Sprintf("%s (%s)", member.TraceID, strings.Join(member.States, ",")))
+		}
+	}
+	return ret
+}
+
+// getTraceGroup returns the traces of the group, an error if it has none.
+func getTraceGroup(group string) ([]gadgetv1alpha1.Trace, error) {
+	if errs := validation.IsValidLabelValue(group); len(errs) > 0 {
+		return nil, WrapInErrInvalidArg("<group>", errors.New(strings.Join(errs, ", ")))
+	}
+
+	traces, err := getTraceListFromOptions(metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", TraceGroup, group),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get traces of group %q: %w", group, err)
+	}
+
+	if len(traces.Items) == 0 {
+		return nil, fmt.Errorf("no traces found in group %q", group)
+	}
+
+	return traces.Items, nil
+}
+
+// ListTraceGroups returns the members of all the groups, or only of the
+// given one if it isn't empty.
+func ListTraceGroups(group string) ([]TraceGroupMember, error) {
+	if group != "" {
+		traces, err := getTraceGroup(group)
+		if err != nil {
+			return nil, err
+		}
+		return groupMembers(traces), nil
+	}
+
+	traces, err := getTraceListFromOptions(metav1.ListOptions{
+		LabelSelector: TraceGroup,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get traces with a group: %w", err)
+	}
+
+	return groupMembers(traces.Items), nil
+}
+
+// StopTraceGroup stops all the traces of the group. The group is only
+// stopped if all its traces are running: otherwise none is touched and the
+// ones not running are given in the error.
+func StopTraceGroup(group string) ([]TraceGroupMember, error) {
+	traceClient, err := getTraceClient()
+	if err != nil {
+		return nil, err
+	}
+
+	traces, err := getTraceGroup(group)
+	if err != nil {
+		return nil, err
+	}
+
+	members := groupMembers(traces)
+	if ids := notStoppable(members); len(ids) > 0 {
+		return nil, fmt.Errorf("group %q can't be stopped, some of its traces aren't running: %s",
+			group, strings.Join(ids, ", "))
+	}
+
+	errs := map[string]error{}
+	for _, trace := range traces {
+		id := trace.ObjectMeta.Labels[GlobalTraceID]
+		localError := operationqueue.Append(context.TODO(), traceClient,
+			trace.ObjectMeta.Namespace, trace.ObjectMeta.Name, "stop")
+		if localError != nil && errs[id] == nil {
+			errs[id] = localError
+		}
+	}
+
+	var failed []string
+	for _, member := range members {
+		record := newAuditRecord(AuditActionOperation, member.TraceID)
+		record.Gadget = member.Gadget
+		record.Operation = "stop"
+		record.Group = group
+		audit(record, errs[member.TraceID])
+
+		if localError := errs[member.TraceID]; localError != nil {
+			failed = append(failed, fmt.Sprintf("%s: %s", member.TraceID, localError))
+		}
+	}
+
+	if len(failed) > 0 {
+		return members, fmt.Errorf("failed to stop traces of group %q: %s", group, strings.Join(failed, "; "))
+	}
+
+	return members, nil
+}
+
+// DeleteTraceGroup deletes all the traces of the group at once.
+func DeleteTraceGroup(group string) ([]TraceGroupMember, error) {
+	traceClient, err := getTraceClient()
+	if err != nil {
+		return nil, err
+	}
+
+	traces, err := getTraceGroup(group)
+	if err != nil {
+		return nil, err
+	}
+
+	// A single request deletes the whole group, so that the traces created
+	// in the meantime are deleted too.
+	err = traceClient.GadgetV1alpha1().Traces("gadget").DeleteCollection(
+		context.TODO(), metav1.DeleteOptions{}, metav1.ListOptions{
+			LabelSelector: fmt.Sprintf("%s=%s", TraceGroup, group),
+		},
+	)
+
+	members := groupMembers(traces)
+	for _, member := range members {
+		record := newAuditRecord(AuditActionDelete, member.TraceID)
+		record.Gadget = member.Gadget
+		record.Group = group
+		audit(record, err)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete traces of group %q: %w", group, err)
+	}
+
+	return members, nil
+}
@@ -0,0 +1,64 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+)
+
+func newGroupTrace(group, id, gadget, node, state, operationError string) gadgetv1alpha1.Trace {
+	trace := gadgetv1alpha1.Trace{}
+	trace.ObjectMeta.Labels = map[string]string{
+		GlobalTraceID: id,
+	}
+	if group != "" {
+		trace.ObjectMeta.Labels[TraceGroup] = group
+	}
+	trace.Spec.Gadget = gadget
+	trace.Spec.Node = node
+	trace.Status.State = state
+	trace.Status.OperationError = operationError
+	return trace
+}
+
+func TestGroupMembers(t *testing.T) {
+	traces := []gadgetv1alpha1.Trace{
+		newGroupTrace("incident", "id2", "runqlat", "node2", "Started", ""),
+		newGroupTrace("incident", "id1", "biolatency", "node2", "Started", ""),
+		newGroupTrace("audit", "id3", "seccomp", "node1", "Started", ""),
+		newGroupTrace("incident", "id1", "biolatency", "node1", "Started", "failed"),
+		newGroupTrace("", "id4", "memleak", "node1", "Started", ""),
+	}
+	traces[0].ObjectMeta.Labels[TraceName] = "sched"
+
+	expected := []TraceGroupMember{
+		{Group: "audit", TraceID: "id3", Gadget: "seccomp", Nodes: []string{"node1"}, States: []string{"Started"}},
+		{Group: "incident", TraceID: "id1", Gadget: "biolatency", Nodes: []string{"node1", "node2"}, States: []string{"Error", "Started"}},
+		{Group: "incident", TraceID: "id2", Name: "sched", Gadget: "runqlat", Nodes: []string{"node2"}, States: []string{"Started"}},
+	}
+
+	members := groupMembers(traces)
+	if !reflect.DeepEqual(members, expected) {
+		t.Fatalf("groupMembers() = %+v, expected %+v", members, expected)
+	}
+
+	notRunning := notStoppable(members)
+	if !reflect.DeepEqual(notRunning, []string{"id1 (Error,Started)"}) {
+		t.Fatalf("notStoppable() = %v, expected only id1", notRunning)
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var byteUnits = []string{"KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+
+// FormatBytes returns n bytes in a human-readable format using binary
+// prefixes, e.g. "1.5 KiB".
+func FormatBytes(n uint64) string {
+	if n < 1024 {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	value := float64(n) / 1024
+	unit := 0
+	for value >= 1024 && unit < len(byteUnits)-1 {
+		value /= 1024
+		unit++
+	}
+
+	return fmt.Sprintf("%.1f %s", value, byteUnits[unit])
+}
+
+var bitRateUnits = []string{"kbit/s", "Mbit/s", "Gbit/s", "Tbit/s"}
+
+// FormatBitRate returns a rate given in bytes per second in bits per second
+// in a human-readable format using decimal prefixes, as the bandwidth
+// annotations of the pods, e.g. "10.0 Mbit/s".
+func FormatBitRate(bytesPerSecond uint64) string {
+	bits := bytesPerSecond * 8
+	if bits < 1000 {
+		return fmt.Sprintf("%d bit/s", bits)
+	}
+
+	value := float64(bits) / 1000
+	unit := 0
+	for value >= 1000 && unit < len(bitRateUnits)-1 {
+		value /= 1000
+		unit++
+	}
+
+	return fmt.Sprintf("%.1f %s", value, bitRateUnits[unit])
+}
+
+// FormatMicroseconds returns a duration given in microseconds in a
+// human-readable format, e.g. "850 µs" or "1.2 ms".
+func FormatMicroseconds(us uint64) string {
+	switch {
+	case us < 1000:
+		return fmt.Sprintf("%d µs", us)
+	case us < 1000*1000:
+		return fmt.Sprintf("%.1f ms", float64(us)/1000)
+	default:
+		return fmt.Sprintf("%.1f s", float64(us)/(1000*1000))
+	}
+}
+
+// histogramHeaderRegex and histogramLineRegex match the lines of the
+// histograms printed by the BCC tools, e.g.
+//
+//	usecs               : count     distribution
+//	 1024 -> 2047       : 12       |****      |
+var (
+	histogramHeaderRegex = regexp.MustCompile(`^(\s*)(nsecs|usecs|msecs)(\s*:.*)$`)
+	histogramLineRegex   = regexp.MustCompile(`^(\s*)(\d+) -> (\d+)(\s*:.*)$`)
+)
+
+// histogramIndent is the number of spaces before the widest label.
+const histogramIndent = 4
+
+// histogramUnits are the units used by the BCC tools in the header of the
+// histograms, converted to microseconds.
+var histogramUnits = map[string]float64{
+	"nsecs": 0.001,
+	"usecs": 1,
+	"msecs": 1000,
+}
+
+// HumanizeHistogram rewrites the bucket ranges of the latency histograms
+// printed by the BCC tools with human-readable durations. Other lines are
+// kept as is.
+func HumanizeHistogram(output string) string {
+	type row struct {
+		label, rest string
+	}
+
+	lines := strings.Split(output, "\n")
+	rows := make(map[int]row)
+
+	// The labels are right-aligned on the widest one, like the BCC tools do.
+	// The width is counted in runes because of the "µ" in the labels.
+	width := 0
+	factor := 0.0
+
+	for i, line := range lines {
+		var r row
+
+		if matches := histogramHeaderRegex.FindStringSubmatch(line); matches != nil {
+			factor = histogramUnits[matches[2]]
+			r = row{label: "latency", rest: matches[3]}
+		} else if matches := histogramLineRegex.FindStringSubmatch(line); matches != nil && factor != 0 {
+			low, _ := strconv.ParseUint(matches[2], 10, 64)
+			high, _ := strconv.ParseUint(matches[3], 10, 64)
+
+			r = row{
+				label: FormatMicroseconds(uint64(float64(low)*factor)) + " -> " +
+					FormatMicroseconds(uint64(float64(high)*factor)),
+				rest: matches[4],
+			}
+		} else {
+			continue
+		}
+
+		if w := utf8.RuneCountInString(r.label); w > width {
+			width = w
+		}
+		rows[i] = r
+	}
+
+	for i, r := range rows {
+		lines[i] = fmt.Sprintf("%*s %s", width+histogramIndent, r.label, strings.TrimLeft(r.rest, " "))
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,90 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"testing"
+)
+
+func TestFormatBytes(t *testing.T) {
+	table := map[uint64]string{
+		0:                "0 B",
+		1023:             "1023 B",
+		1024:             "1.0 KiB",
+		1536:             "1.5 KiB",
+		10 * 1024 * 1024: "10.0 MiB",
+		3 << 30:          "3.0 GiB",
+		1<<64 - 1:        "16.0 EiB",
+	}
+
+	for n, expected := range table {
+		if s := FormatBytes(n); s != expected {
+			t.Fatalf("FormatBytes(%d): expected %q, got %q", n, expected, s)
+		}
+	}
+}
+
+func TestFormatBitRate(t *testing.T) {
+	table := map[uint64]string{
+		0:          "0 bit/s",
+		124:        "992 bit/s",
+		125:        "1.0 kbit/s",
+		125000:     "1.0 Mbit/s",
+		1250000:    "10.0 Mbit/s",
+		1250000000: "10.0 Gbit/s",
+	}
+
+	for n, expected := range table {
+		if s := FormatBitRate(n); s != expected {
+			t.Fatalf("FormatBitRate(%d): expected %q, got %q", n, expected, s)
+		}
+	}
+}
+
+func TestFormatMicroseconds(t *testing.T) {
+	table := map[uint64]string{
+		0:       "0 µs",
+		999:     "999 µs",
+		1500:    "1.5 ms",
+		2500000: "2.5 s",
+	}
+
+	for us, expected := range table {
+		if s := FormatMicroseconds(us); s != expected {
+			t.Fatalf("FormatMicroseconds(%d): expected %q, got %q", us, expected, s)
+		}
+	}
+}
+
+func TestHumanizeHistogram(t *testing.T) {
+	output := `Tracing block device I/O... Hit Ctrl-C to end.
+
+     usecs               : count     distribution
+         0 -> 1          : 0        |                                        |
+       512 -> 1023       : 4        |**********                              |
+      1024 -> 2047       : 16       |****************************************|
+`
+	expected := `Tracing block device I/O... Hit Ctrl-C to end.
+
+             latency : count     distribution
+        0 µs -> 1 µs : 0        |                                        |
+    512 µs -> 1.0 ms : 4        |**********                              |
+    1.0 ms -> 2.0 ms : 16       |****************************************|
+`
+
+	if s := HumanizeHistogram(output); s != expected {
+		t.Fatalf("HumanizeHistogram: expected\n%s\ngot\n%s", expected, s)
+	}
+}
@@ -0,0 +1,254 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// manifestWorkload is the part of a workload of a manifest used to select
+// its pods before they are created.
+type manifestWorkload struct {
+	kind      string
+	name      string
+	namespace string
+
+	// labels are the ones of the selector of the workload, or of its
+	// pod template when it has no selector. Unlike the other labels of
+	// the template, they don't change across rollouts.
+	labels map[string]string
+
+	// containers are the names of the containers of the pods, including
+	// the init ones.
+	containers []string
+}
+
+func (w *manifestWorkload) String() string {
+	return fmt.Sprintf("%s/%s", strings.ToLower(w.kind), w.name)
+}
+
+func newManifestWorkload(kind string, meta *metaV1.ObjectMeta, selector *metaV1.LabelSelector,
+	template *corev1.PodTemplateSpec,
+) (*manifestWorkload, error) {
+	w := &manifestWorkload{
+		kind:      kind,
+		name:      meta.Name,
+		namespace: meta.Namespace,
+	}
+
+	if selector != nil && len(selector.MatchExpressions) > 0 {
+		return nil, fmt.Errorf("%s selects its pods with expressions, which are not supported", w)
+	}
+
+	if selector != nil && len(selector.MatchLabels) > 0 {
+		w.labels = selector.MatchLabels
+	} else {
+		w.labels = template.Labels
+	}
+	if len(w.labels) == 0 {
+		return nil, fmt.Errorf("the pods of %s have no labels", w)
+	}
+
+	for _, c := range template.Spec.InitContainers {
+		w.containers = append(w.containers, c.Name)
+	}
+	for _, c := range template.Spec.Containers {
+		w.containers = append(w.containers, c.Name)
+	}
+
+	return w, nil
+}
+
+// objectWorkload returns the workload of a manifest object, or nil if the
+// object doesn't create pods.
+func objectWorkload(obj runtime.Object) (*manifestWorkload, error) {
+	switch o := obj.(type) {
+	case *corev1.Pod:
+		template := &corev1.PodTemplateSpec{ObjectMeta: o.ObjectMeta, Spec: o.Spec}
+		return newManifestWorkload("Pod", &o.ObjectMeta, nil, template)
+	case *corev1.ReplicationController:
+		var selector *metaV1.LabelSelector
+		if len(o.Spec.Selector) > 0 {
+			selector = &metaV1.LabelSelector{MatchLabels: o.Spec.Selector}
+		}
+		if o.Spec.Template == nil {
+			return nil, fmt.Errorf("replicationcontroller/%s has no pod template", o.Name)
+		}
+		return newManifestWorkload("ReplicationController", &o.ObjectMeta, selector, o.Spec.Template)
+	case *appsv1.Deployment:
+		return newManifestWorkload("Deployment", &o.ObjectMeta, o.Spec.Selector, &o.Spec.Template)
+	case *appsv1.StatefulSet:
+		return newManifestWorkload("StatefulSet", &o.ObjectMeta, o.Spec.Selector, &o.Spec.Template)
+	case *appsv1.DaemonSet:
+		return newManifestWorkload("DaemonSet", &o.ObjectMeta, o.Spec.Selector, &o.Spec.Template)
+	case *appsv1.ReplicaSet:
+		return newManifestWorkload("ReplicaSet", &o.ObjectMeta, o.Spec.Selector, &o.Spec.Template)
+	case *batchv1.Job:
+		// The selector of the jobs is usually generated from their
+		// UID, the labels of the template are used instead.
+		return newManifestWorkload("Job", &o.ObjectMeta, nil, &o.Spec.Template)
+	case *batchv1.CronJob:
+		return newManifestWorkload("CronJob", &o.ObjectMeta, nil, &o.Spec.JobTemplate.Spec.Template)
+	case *batchv1beta1.CronJob:
+		return newManifestWorkload("CronJob", &o.ObjectMeta, nil, &o.Spec.JobTemplate.Spec.Template)
+	}
+
+	return nil, nil
+}
+
+// parseManifestWorkloads returns the workloads of a manifest made of one or
+// several YAML or JSON documents. The other objects, like the services or
+// the custom resources, are ignored.
+func parseManifestWorkloads(r io.Reader) ([]*manifestWorkload, error) {
+	workloads := []*manifestWorkload{}
+	decoder := scheme.Codecs.UniversalDeserializer()
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(r))
+
+	for {
+		doc, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// Skip the empty documents, e.g. the ones with only comments
+		var typeMeta metaV1.TypeMeta
+		if err := utilyaml.Unmarshal(doc, &typeMeta); err != nil {
+			return nil, err
+		}
+		if typeMeta.Kind == "" && typeMeta.APIVersion == "" {
+			continue
+		}
+
+		obj, _, err := decoder.Decode(doc, nil, nil)
+		if runtime.IsNotRegisteredError(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		w, err := objectWorkload(obj)
+		if err != nil {
+			return nil, err
+		}
+		if w != nil {
+			workloads = append(workloads, w)
+		}
+	}
+
+	return workloads, nil
+}
+
+// manifestFilter returns the workload of the manifest to trace, checking
+// the container given by the user is part of it.
+func manifestFilter(r io.Reader, containerName string) (*manifestWorkload, error) {
+	workloads, err := parseManifestWorkloads(r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(workloads) {
+	case 0:
+		return nil, errors.New("no workload found")
+	case 1:
+	default:
+		names := make([]string, 0, len(workloads))
+		for _, w := range workloads {
+			names = append(names, w.String())
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("several workloads found (%s), only one is supported",
+			strings.Join(names, ", "))
+	}
+
+	w := workloads[0]
+	if containerName == "" {
+		return w, nil
+	}
+
+	for _, c := range w.containers {
+		if c == containerName {
+			return w, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%s has no container %q (containers: %s)",
+		w, containerName, strings.Join(w.containers, ", "))
+}
+
+// resolveManifest fills the filter with the namespace, labels and container
+// of the workload of the manifest given by the user. As the pods are
+// selected with labels, the pods created after the trace are traced too.
+func resolveManifest(params *CommonFlags) error {
+	if params.AllNamespaces {
+		return WrapInErrInvalidArg("--from-manifest", errors.New("can't be used with --all-namespaces"))
+	}
+
+	var r io.Reader = os.Stdin
+	if params.FromManifest != "-" {
+		f, err := os.Open(params.FromManifest)
+		if err != nil {
+			return WrapInErrInvalidArg("--from-manifest", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	w, err := manifestFilter(r, params.Containername)
+	if err != nil {
+		return WrapInErrInvalidArg("--from-manifest", err)
+	}
+
+	// The namespace is often set when applying the manifest, e.g. by
+	// kustomize, the one of the command line is used in this case.
+	if w.namespace != "" {
+		if params.NamespaceOverridden && params.Namespace != w.namespace {
+			return WrapInErrInvalidArg("--from-manifest",
+				fmt.Errorf("%s is in namespace %q, not %q", w, w.namespace, params.Namespace))
+		}
+		params.Namespace = w.namespace
+	}
+
+	params.Labels, err = mergeSelector(params.Labels, w.labels)
+	if err != nil {
+		return WrapInErrInvalidArg("--from-manifest", err)
+	}
+
+	// When the pods have a single container, the sidecars injected at
+	// admission, e.g. by a service mesh, are left out.
+	if params.Containername == "" && len(w.containers) == 1 {
+		params.Containername = w.containers[0]
+	}
+
+	return nil
+}
@@ -0,0 +1,207 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const manifestDeployment = `# The workload of the shop
+apiVersion: v1
+kind: Service
+metadata:
+  name: checkout
+spec:
+  selector:
+    app: checkout
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: checkout
+  namespace: shop
+spec:
+  selector:
+    matchLabels:
+      app: checkout
+  template:
+    metadata:
+      labels:
+        app: checkout
+        version: v2
+    spec:
+      initContainers:
+      - name: migrate
+        image: checkout-migrations
+      containers:
+      - name: checkout
+        image: checkout
+---
+apiVersion: monitoring.coreos.com/v1
+kind: ServiceMonitor
+metadata:
+  name: checkout
+`
+
+const manifestCronJob = `{
+  "apiVersion": "batch/v1",
+  "kind": "CronJob",
+  "metadata": {"name": "report"},
+  "spec": {
+    "schedule": "@hourly",
+    "jobTemplate": {
+      "spec": {
+        "template": {
+          "metadata": {"labels": {"job": "report"}},
+          "spec": {"containers": [{"name": "report", "image": "report"}]}
+        }
+      }
+    }
+  }
+}`
+
+const manifestTwoWorkloads = `apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+spec:
+  selector:
+    matchLabels:
+      app: db
+  template:
+    metadata:
+      labels:
+        app: db
+    spec:
+      containers:
+      - name: db
+        image: db
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: client
+  labels:
+    app: client
+spec:
+  containers:
+  - name: client
+    image: client
+`
+
+const manifestExpressions = `apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: agent
+spec:
+  selector:
+    matchExpressions:
+    - key: app
+      operator: In
+      values: [agent]
+  template:
+    metadata:
+      labels:
+        app: agent
+    spec:
+      containers:
+      - name: agent
+        image: agent
+`
+
+func TestManifestFilter(t *testing.T) {
+	tests := []struct {
+		name          string
+		manifest      string
+		containerName string
+		expected      *manifestWorkload
+		expectedError string
+	}{
+		{
+			name:     "deployment",
+			manifest: manifestDeployment,
+			expected: &manifestWorkload{
+				kind:       "Deployment",
+				name:       "checkout",
+				namespace:  "shop",
+				labels:     map[string]string{"app": "checkout"},
+				containers: []string{"migrate", "checkout"},
+			},
+		},
+		{
+			name:          "deployment with container",
+			manifest:      manifestDeployment,
+			containerName: "migrate",
+			expected: &manifestWorkload{
+				kind:       "Deployment",
+				name:       "checkout",
+				namespace:  "shop",
+				labels:     map[string]string{"app": "checkout"},
+				containers: []string{"migrate", "checkout"},
+			},
+		},
+		{
+			name:          "unknown container",
+			manifest:      manifestDeployment,
+			containerName: "proxy",
+			expectedError: `deployment/checkout has no container "proxy" (containers: migrate, checkout)`,
+		},
+		{
+			name:     "cronjob",
+			manifest: manifestCronJob,
+			expected: &manifestWorkload{
+				kind:       "CronJob",
+				name:       "report",
+				labels:     map[string]string{"job": "report"},
+				containers: []string{"report"},
+			},
+		},
+		{
+			name:          "several workloads",
+			manifest:      manifestTwoWorkloads,
+			expectedError: "several workloads found (pod/client, statefulset/db), only one is supported",
+		},
+		{
+			name:          "selector with expressions",
+			manifest:      manifestExpressions,
+			expectedError: "daemonset/agent selects its pods with expressions, which are not supported",
+		},
+		{
+			name:          "no workload",
+			manifest:      "---\n# nothing\n---\n",
+			expectedError: "no workload found",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w, err := manifestFilter(strings.NewReader(test.manifest), test.containerName)
+			if test.expectedError != "" {
+				if err == nil || err.Error() != test.expectedError {
+					t.Fatalf("Expected error %q, got %v", test.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(w, test.expected) {
+				t.Fatalf("Expected %+v, got %+v", test.expected, w)
+			}
+		})
+	}
+}
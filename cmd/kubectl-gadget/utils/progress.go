@@ -0,0 +1,133 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// ProgressRefreshInterval is the interval at which the progress line is
+// redrawn.
+const ProgressRefreshInterval = 100 * time.Millisecond
+
+// quiet is the value of the --quiet flag.
+var quiet bool
+
+var spinnerFrames = []string{"|", "/", "-", "\\"}
+
+// Progress displays a spinner with a message, the number of nodes ready
+// and the elapsed time while waiting for something that can take a while.
+// It's only displayed if stderr is a terminal and --quiet wasn't given, so
+// that it doesn't pollute the output of scripts.
+type Progress struct {
+	w       io.Writer
+	message string
+	start   time.Time
+
+	mu    sync.Mutex
+	frame int
+	ready int
+	total int
+
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// NewProgress starts displaying the progress of a wait described by
+// message. Stop must be called once the wait is over.
+func NewProgress(message string) *Progress {
+	enabled := !quiet && term.IsTerminal(int(os.Stderr.Fd()))
+	return newProgress(os.Stderr, message, enabled)
+}
+
+func newProgress(w io.Writer, message string, enabled bool) *Progress {
+	p := &Progress{
+		w:       w,
+		message: message,
+		start:   time.Now(),
+		total:   -1,
+	}
+
+	if !enabled {
+		return p
+	}
+
+	p.done = make(chan struct{})
+	p.stopped = make(chan struct{})
+
+	go func() {
+		defer close(p.stopped)
+
+		ticker := time.NewTicker(ProgressRefreshInterval)
+		defer ticker.Stop()
+
+		for {
+			p.draw()
+
+			select {
+			case <-p.done:
+				// Clear the line for the next messages
+				fmt.Fprint(p.w, "\r\033[K")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return p
+}
+
+// SetNodes updates the number of nodes ready out of total.
+func (p *Progress) SetNodes(ready, total int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.ready = ready
+	p.total = total
+}
+
+// Stop stops displaying the progress and clears it.
+func (p *Progress) Stop() {
+	if p.done == nil {
+		return
+	}
+
+	close(p.done)
+	<-p.stopped
+	p.done = nil
+}
+
+func (p *Progress) draw() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	fmt.Fprintf(p.w, "\r\033[K%s", p.line(time.Since(p.start)))
+	p.frame = (p.frame + 1) % len(spinnerFrames)
+}
+
+// line returns the progress line to display after elapsed.
+func (p *Progress) line(elapsed time.Duration) string {
+	line := fmt.Sprintf("%s %s", spinnerFrames[p.frame], p.message)
+	if p.total >= 0 {
+		line += fmt.Sprintf(": %d/%d node(s) ready", p.ready, p.total)
+	}
+	return fmt.Sprintf("%s (%s)", line, elapsed.Truncate(time.Second))
+}
@@ -0,0 +1,63 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgressLine(t *testing.T) {
+	p := newProgress(nil, "Waiting for the traces", false)
+
+	expected := "| Waiting for the traces (1s)"
+	if line := p.line(1500 * time.Millisecond); line != expected {
+		t.Fatalf("%q != %q", line, expected)
+	}
+
+	p.SetNodes(2, 3)
+	p.frame = 1
+	expected = "/ Waiting for the traces: 2/3 node(s) ready (1m5s)"
+	if line := p.line(65 * time.Second); line != expected {
+		t.Fatalf("%q != %q", line, expected)
+	}
+}
+
+func TestProgressDisabled(t *testing.T) {
+	var out bytes.Buffer
+
+	p := newProgress(&out, "Waiting for the traces", false)
+	p.SetNodes(1, 1)
+	p.Stop()
+
+	if out.Len() != 0 {
+		t.Fatalf("Disabled progress printed %q", out.String())
+	}
+}
+
+func TestProgressStop(t *testing.T) {
+	var out bytes.Buffer
+
+	p := newProgress(&out, "Waiting for the traces", true)
+	p.Stop()
+	// Stopping twice must not fail
+	p.Stop()
+
+	if !strings.HasSuffix(out.String(), "\r\033[K") {
+		t.Fatalf("Progress line not cleared: %q", out.String())
+	}
+}
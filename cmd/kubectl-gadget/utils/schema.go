@@ -0,0 +1,87 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+// schemaChecker compares the schema version of the events sent by the
+// gadget pods with the one kubectl-gadget was built with. It warns once per
+// node when they differ, because the columns printed for the events of that
+// node might then be empty or wrong.
+type schemaChecker struct {
+	w      io.Writer
+	params *CommonFlags
+
+	mu      sync.Mutex
+	checked map[string]struct{}
+}
+
+func newSchemaChecker(w io.Writer, params *CommonFlags) *schemaChecker {
+	return &schemaChecker{
+		w:       w,
+		params:  params,
+		checked: make(map[string]struct{}),
+	}
+}
+
+// schemaMismatchMessage returns the warning to print for the events of node
+// using the given schema version, or an empty string if the version is the
+// expected one. Events sent by gadget pods older than the schema versioning
+// don't have a version and are handled as version 0.
+func schemaMismatchMessage(node string, version int) string {
+	switch {
+	case version > eventtypes.EventSchemaVersion:
+		return fmt.Sprintf("events of node %q use schema version %d but kubectl-gadget only supports version %d: some columns might be empty or wrong, please upgrade kubectl-gadget",
+			node, version, eventtypes.EventSchemaVersion)
+	case version < eventtypes.EventSchemaVersion:
+		return fmt.Sprintf("events of node %q use schema version %d but kubectl-gadget expects version %d: some columns might be empty or wrong, please deploy the gadget with this version of kubectl-gadget",
+			node, version, eventtypes.EventSchemaVersion)
+	}
+	return ""
+}
+
+// check looks at the schema version of the first event of each node. Only
+// the events of type "normal" are considered: the other ones, like errors,
+// are not rendered with the gadget-specific columns.
+func (c *schemaChecker) check(event *eventtypes.Event) {
+	if event.Type != eventtypes.NORMAL {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.checked[event.Node]; ok {
+		return
+	}
+	c.checked[event.Node] = struct{}{}
+
+	msg := schemaMismatchMessage(event.Node, event.SchemaVersion)
+	if msg == "" {
+		return
+	}
+
+	if c.params != nil && c.params.OutputMode == OutputModeJSON {
+		fmt.Fprintln(c.w, eventtypes.EventString(eventtypes.Warn(msg, event.Node)))
+		return
+	}
+	fmt.Fprintf(c.w, "Warn: %s\n", msg)
+}
@@ -0,0 +1,98 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+func TestSchemaChecker(t *testing.T) {
+	table := []struct {
+		description string
+		lines       []string
+		params      *CommonFlags
+		expected    string
+	}{
+		{
+			description: "same version",
+			lines: []string{
+				fmt.Sprintf(`{"type":"normal","node":"node1","schemaVersion":%d}`, eventtypes.EventSchemaVersion),
+			},
+			expected: "",
+		},
+		{
+			description: "newer version",
+			lines: []string{
+				fmt.Sprintf(`{"type":"normal","node":"node1","schemaVersion":%d}`, eventtypes.EventSchemaVersion+1),
+			},
+			expected: "Warn: " + schemaMismatchMessage("node1", eventtypes.EventSchemaVersion+1) + "\n",
+		},
+		{
+			description: "no version",
+			lines: []string{
+				`{"type":"normal","node":"node1"}`,
+			},
+			expected: "Warn: " + schemaMismatchMessage("node1", 0) + "\n",
+		},
+		{
+			description: "warn once per node",
+			lines: []string{
+				`{"type":"normal","node":"node1"}`,
+				`{"type":"normal","node":"node1"}`,
+				`{"type":"normal","node":"node2"}`,
+			},
+			expected: "Warn: " + schemaMismatchMessage("node1", 0) + "\n" +
+				"Warn: " + schemaMismatchMessage("node2", 0) + "\n",
+		},
+		{
+			description: "only normal events are checked",
+			lines: []string{
+				`{"type":"err","node":"node1","message":"failed"}`,
+				`not json`,
+			},
+			expected: "",
+		},
+		{
+			description: "json output",
+			lines: []string{
+				`{"type":"normal","node":"node1"}`,
+			},
+			params:   &CommonFlags{OutputMode: OutputModeJSON},
+			expected: eventtypes.EventString(eventtypes.Warn(schemaMismatchMessage("node1", 0), "node1")) + "\n",
+		},
+	}
+
+	for _, entry := range table {
+		var buf bytes.Buffer
+
+		checker := newSchemaChecker(&buf, entry.params)
+		for _, line := range entry.lines {
+			var event eventtypes.Event
+			if err := json.Unmarshal([]byte(line), &event); err != nil {
+				continue
+			}
+			checker.check(&event)
+		}
+
+		if buf.String() != entry.expected {
+			t.Fatalf("%s: got %q, expected %q", entry.description, buf.String(), entry.expected)
+		}
+	}
+}
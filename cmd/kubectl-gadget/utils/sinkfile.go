@@ -0,0 +1,112 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	"github.com/kinvolk/inspektor-gadget/pkg/k8sutil"
+)
+
+// ParseSince parses the --since flag: a duration before now, e.g. 10m, or
+// an RFC 3339 time, e.g. 2022-05-04T10:00:00Z.
+func ParseSince(since string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(since); err == nil {
+		if d < 0 {
+			return time.Time{}, errors.New("the duration can't be negative")
+		}
+		return now.Add(-d), nil
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, since)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither a duration (e.g. 10m) nor an RFC 3339 time (e.g. 2022-05-04T10:00:00Z)", since)
+	}
+	return t, nil
+}
+
+// shellQuote quotes s to be used as a single argument of a shell command.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// sinkFileTraces returns the traces with the given ID, or the Trace
+// resource with this name, e.g. for the traces created with kubectl apply.
+func sinkFileTraces(traceID string) ([]gadgetv1alpha1.Trace, error) {
+	traces, err := ListTracesByID(traceID)
+	if err == nil {
+		return traces, nil
+	}
+
+	traceClient, clientErr := getTraceClient()
+	if clientErr != nil {
+		return nil, clientErr
+	}
+	trace, getErr := traceClient.GadgetV1alpha1().Traces("gadget").Get(context.TODO(), traceID, metav1.GetOptions{})
+	if getErr != nil {
+		return nil, err
+	}
+
+	return []gadgetv1alpha1.Trace{*trace}, nil
+}
+
+// FetchSinkFile copies to w the events written since the given time, or
+// all of them if since is zero, to the file of the file sink of the trace
+// on each node.
+func FetchSinkFile(traceID string, since time.Time, w io.Writer) error {
+	traces, err := sinkFileTraces(traceID)
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(traces, func(i, j int) bool {
+		return traces[i].Spec.Node < traces[j].Spec.Node
+	})
+
+	client, err := k8sutil.NewClientsetFromConfigFlags(KubernetesConfigFlags)
+	if err != nil {
+		return WrapInErrSetupK8sClient(err)
+	}
+
+	for _, trace := range traces {
+		file := trace.Spec.Parameters[gadgetv1alpha1.SinkFileParam]
+		if file == "" {
+			return fmt.Errorf("trace %q doesn't write its events to a file, see --sink-file", traceID)
+		}
+
+		cmd := fmt.Sprintf("exec gadgettracermanager -call read-sink-file -file %s", shellQuote(file))
+		if !since.IsZero() {
+			cmd += " -since " + since.UTC().Format(time.RFC3339Nano)
+		}
+
+		var stderr bytes.Buffer
+		if err := ExecPodRaw(client, trace.Spec.Node, cmd, w, &stderr); err != nil {
+			return WrapInErrRunGadgetOnNode(trace.Spec.Node,
+				fmt.Errorf("reading %s: %w: %s", file, err, strings.TrimSpace(stderr.String())))
+		}
+	}
+
+	return nil
+}
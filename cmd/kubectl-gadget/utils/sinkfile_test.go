@@ -0,0 +1,59 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2022, 5, 4, 10, 0, 0, 0, time.UTC)
+
+	table := []struct {
+		since    string
+		expected time.Time
+		err      bool
+	}{
+		{since: "10m", expected: now.Add(-10 * time.Minute)},
+		{since: "1h30m", expected: now.Add(-90 * time.Minute)},
+		{since: "2022-05-04T08:00:00Z", expected: time.Date(2022, 5, 4, 8, 0, 0, 0, time.UTC)},
+		{since: "-10m", err: true},
+		{since: "yesterday", err: true},
+	}
+
+	for _, entry := range table {
+		since, err := ParseSince(entry.since, now)
+		if entry.err {
+			if err == nil {
+				t.Errorf("%q: expected an error", entry.since)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", entry.since, err)
+			continue
+		}
+		if !since.Equal(entry.expected) {
+			t.Errorf("%q: expected %s, got %s", entry.since, entry.expected, since)
+		}
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	if quoted := shellQuote("it's.json"); quoted != `'it'\''s.json'` {
+		t.Fatalf("unexpected quoting: %s", quoted)
+	}
+}
@@ -15,31 +15,39 @@
 package utils
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"math/rand"
 	"os"
 	"os/signal"
 	"sort"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"syscall"
 	"text/tabwriter"
 	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	types "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/apimachinery/pkg/watch"
 	watchtools "k8s.io/client-go/tools/watch"
 
+	"github.com/kinvolk/inspektor-gadget/pkg/anonymizer"
 	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
 	clientset "github.com/kinvolk/inspektor-gadget/pkg/client/clientset/versioned"
 	"github.com/kinvolk/inspektor-gadget/pkg/k8sutil"
+	"github.com/kinvolk/inspektor-gadget/pkg/operationqueue"
+	"github.com/kinvolk/inspektor-gadget/pkg/snapshotcache"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
 )
 
 const (
@@ -47,9 +55,72 @@ const (
 	// We name it "global" as if one trace is created on several nodes, then each
 	// copy of the trace on each node will share the same id.
 	GlobalTraceID = "global-trace-id"
-	TraceTimeout  = 5 * time.Second
+
+	// TraceName is the label holding the name given by the user with
+	// --name, it can be used instead of the trace ID.
+	TraceName = "trace-name"
+
+	// TraceGroup is the label holding the group given by the user with
+	// --group, the traces of a group are managed together by the group
+	// command.
+	TraceGroup = "trace-group"
+
+	// AllowEnforcement is the annotation required on the traces of the
+	// gadgets run with the "enforce" parameter.
+	AllowEnforcement = "gadget.kinvolk.io/allow-enforcement"
+
+	// TraceTimeout is the default time to wait for the traces to reach a
+	// given state. It can be changed with the --trace-timeout flag.
+	TraceTimeout = 5 * time.Second
+
+	// TraceTimeoutNodesStep is the number of nodes after which the timeout
+	// is increased by TraceTimeout, to give enough time to the traces of
+	// big clusters to reach the expected state.
+	TraceTimeoutNodesStep = 100
+
+	// CompletionTimeout is the default time to wait for the traces of the
+	// gadgets printing their output from the status, like the collector
+	// ones, to complete. It can be changed with the --completion-timeout
+	// flag and it is also increased every TraceTimeoutNodesStep nodes.
+	CompletionTimeout = 30 * time.Second
+
+	// MaxConcurrentTraceCreations is the maximum number of traces that are
+	// created in parallel by createTraces.
+	MaxConcurrentTraceCreations = 16
+
+	// TraceWatchRetries is the number of times the watch on traces is
+	// retried if it fails before the timeout.
+	TraceWatchRetries = 3
+
+	// maxWatchedNodes bounds the number of outputs kept in watch mode, one
+	// per node: it's the maximum number of nodes supported by Kubernetes.
+	maxWatchedNodes = 5000
+)
+
+var (
+	// traceTimeout is the value of the --trace-timeout flag.
+	traceTimeout = TraceTimeout
+
+	// completionTimeout is the value of the --completion-timeout flag.
+	completionTimeout = CompletionTimeout
+
+	// minNodes is the value of the --min-nodes flag.
+	minNodes int
+
+	// requireAllNodes is the value of the --require-all-nodes flag.
+	requireAllNodes bool
+
+	// nodeStatusFile is the value of the --node-status-file flag.
+	nodeStatusFile string
 )
 
+// getTraceTimeout returns the time to wait for tracesNumber traces to reach
+// a given state, timeout being the time to wait for the first
+// TraceTimeoutNodesStep traces.
+func getTraceTimeout(timeout time.Duration, tracesNumber int) time.Duration {
+	return timeout * time.Duration(1+tracesNumber/TraceTimeoutNodesStep)
+}
+
 // TraceConfig is used to contain information used to manage a trace.
 type TraceConfig struct {
 	// GadgetName is gadget name, e.g. socket-collector.
@@ -83,11 +154,28 @@ type TraceConfig struct {
 	// This field is only used by "multi-rounds gadgets" like biolatency.
 	TraceInitialState string
 
+	// TraceName is an optional name given by the user to the trace. It can
+	// be used instead of the trace ID by the commands taking one.
+	TraceName string
+
+	// TraceGroup is an optional group the trace belongs to. The traces of a
+	// group can be listed, stopped and deleted together.
+	TraceGroup string
+
 	// CommonFlags is used to hold parameters given on the command line interface.
 	CommonFlags *CommonFlags
 
 	// Parameters is used to pass specific gadget configurations.
 	Parameters map[string]string
+
+	// Annotations are added to the annotations of the traces, e.g. to allow
+	// the enforcement.
+	Annotations map[string]string
+
+	// NodeAnnotations, if set, returns the annotations added to the trace
+	// of a node only, e.g. the ETag of the output already received from
+	// it.
+	NodeAnnotations func(node string) map[string]string
 }
 
 func init() {
@@ -107,40 +195,7 @@ func randomTraceID() string {
 	return string(output)
 }
 
-// If all the elements in the map have the same value, it is returned.
-// Otherwise, an empty string is returned.
-func getIdenticalValue(m map[string]string) string {
-	value := ""
-	for _, v := range m {
-		if value == "" {
-			value = v
-		} else if value != v {
-			return ""
-		}
-	}
-	return value
-}
-
-// If there are more than one element in the map and the Error/Warning is
-// the same for all the nodes, printTraceFeedback will print it only once.
-func printTraceFeedback(prefix string, m map[string]string, totalNodes int) {
-	// Do not print `len(m)` times the same message if it's the same from all nodes
-	if len(m) > 1 && len(m) == totalNodes {
-		value := getIdenticalValue(m)
-		if value != "" {
-			fmt.Fprintf(os.Stderr, "%s: %s\n",
-				prefix, WrapInErrRunGadgetOnAllNode(errors.New(value)))
-			return
-		}
-	}
-
-	for node, msg := range m {
-		fmt.Fprintf(os.Stderr, "%s: %s\n",
-			prefix, WrapInErrRunGadgetOnNode(node, errors.New(msg)))
-	}
-}
-
-func deleteTraces(traceClient *clientset.Clientset, traceID string) {
+func deleteTraces(traceClient *clientset.Clientset, traceID string) error {
 	listTracesOptions := metav1.ListOptions{
 		LabelSelector: fmt.Sprintf("%s=%s", GlobalTraceID, traceID),
 	}
@@ -151,6 +206,8 @@ func deleteTraces(traceClient *clientset.Clientset, traceID string) {
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error deleting traces: %q", err)
 	}
+
+	return err
 }
 
 func GetTraceClient() (*clientset.Clientset, error) {
@@ -174,7 +231,7 @@ func getTraceClient() (*clientset.Clientset, error) {
 // createTraces creates a trace using Kubernetes REST API.
 // Note that, this function will create the trace on all existing node if
 // trace.Spec.Node is empty.
-func createTraces(trace *gadgetv1alpha1.Trace) error {
+func createTraces(trace *gadgetv1alpha1.Trace, nodeAnnotations func(node string) map[string]string) error {
 	client, err := k8sutil.NewClientsetFromConfigFlags(KubernetesConfigFlags)
 	if err != nil {
 		return WrapInErrSetupK8sClient(err)
@@ -191,70 +248,67 @@ func createTraces(trace *gadgetv1alpha1.Trace) error {
 	}
 
 	traceNode := trace.Spec.Node
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+
+	// Bound the number of concurrent requests to the API server.
+	sem := make(chan struct{}, MaxConcurrentTraceCreations)
+
 	for _, node := range nodes.Items {
 		if traceNode != "" && node.Name != traceNode {
 			continue
 		}
+
+		nodeTrace := trace.DeepCopy()
+
 		// If no particular node was given, we need to apply this trace on all
 		// available nodes.
 		if traceNode == "" {
-			trace.Spec.Node = node.Name
+			nodeTrace.Spec.Node = node.Name
 		}
 
-		_, err := traceClient.GadgetV1alpha1().Traces("gadget").Create(
-			context.TODO(), trace, metav1.CreateOptions{},
-		)
-		if err != nil {
-			traceID, present := trace.ObjectMeta.Labels[GlobalTraceID]
-			if present {
-				// Clean before exiting!
-				deleteTraces(traceClient, traceID)
+		if nodeAnnotations != nil {
+			for k, v := range nodeAnnotations(node.Name) {
+				nodeTrace.ObjectMeta.Annotations[k] = v
 			}
-
-			return fmt.Errorf("failed to create trace on node %q: %w", node.Name, err)
 		}
-	}
 
-	return nil
-}
-
-// updateTraceOperation updates operation for an already existing trace using
-// Kubernetes REST API.
-func updateTraceOperation(trace *gadgetv1alpha1.Trace, operation string) error {
-	traceClient, err := getTraceClient()
-	if err != nil {
-		return err
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(nodeName string, nodeTrace *gadgetv1alpha1.Trace) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			_, err := traceClient.GadgetV1alpha1().Traces("gadget").Create(
+				context.TODO(), nodeTrace, metav1.CreateOptions{},
+			)
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = fmt.Errorf("failed to create trace on node %q: %w", nodeName, err)
+				}
+				mu.Unlock()
+			}
+		}(node.Name, nodeTrace)
 	}
 
-	// This trace will be used as JSON merge patch to update GADGET_OPERATION,
-	// see:
-	// https://datatracker.ietf.org/doc/html/rfc6902
-	// https://datatracker.ietf.org/doc/html/rfc7386
-	type Annotations map[string]string
-	type ObjectMeta struct {
-		Annotations Annotations `json:"annotations"`
-	}
-	type JSONMergePatch struct {
-		ObjectMeta ObjectMeta `json:"metadata"`
-	}
-	patch := JSONMergePatch{
-		ObjectMeta: ObjectMeta{
-			Annotations{
-				GadgetOperation: operation,
-			},
-		},
-	}
+	wg.Wait()
 
-	patchBytes, err := json.Marshal(patch)
-	if err != nil {
-		return fmt.Errorf("failed to marshal the operation annotations: %w", err)
-	}
+	if firstErr != nil {
+		traceID, present := trace.ObjectMeta.Labels[GlobalTraceID]
+		if present {
+			// Clean before exiting!
+			deleteTraces(traceClient, traceID)
+		}
 
-	_, err = traceClient.GadgetV1alpha1().Traces("gadget").Patch(
-		context.TODO(), trace.ObjectMeta.Name, types.MergePatchType, patchBytes, metav1.PatchOptions{},
-	)
+		return firstErr
+	}
 
-	return err
+	return nil
 }
 
 // CreateTrace initializes a trace object with its field according to the given
@@ -269,19 +323,73 @@ func updateTraceOperation(trace *gadgetv1alpha1.Trace, operation string) error {
 func CreateTrace(config *TraceConfig) (string, error) {
 	traceID := randomTraceID()
 
+	record := newAuditRecord(AuditActionCreate, traceID)
+	record.Gadget = config.GadgetName
+	record.Operation = config.Operation
+	record.Node = config.CommonFlags.Node
+	record.Group = config.TraceGroup
+
+	err := createTrace(traceID, config, record)
+	audit(record, err)
+	if err != nil {
+		return "", err
+	}
+
+	return traceID, nil
+}
+
+// createTrace creates the traces of CreateTrace, recording their filter
+// and parameters in the audit record.
+func createTrace(traceID string, config *TraceConfig, record *AuditRecord) error {
+
+	if config.TraceName != "" {
+		if err := checkTraceName(config.TraceName); err != nil {
+			return err
+		}
+	}
+
+	if config.TraceGroup != "" {
+		if errs := validation.IsValidLabelValue(config.TraceGroup); len(errs) > 0 {
+			return WrapInErrInvalidArg("--group", errors.New(strings.Join(errs, ", ")))
+		}
+	}
+
 	var filter *gadgetv1alpha1.ContainerFilter
 
 	// Keep Filter field empty if it is not really used
 	if config.CommonFlags.Namespace != "" || config.CommonFlags.Podname != "" ||
-		config.CommonFlags.Containername != "" || len(config.CommonFlags.Labels) > 0 {
+		config.CommonFlags.PodUID != "" || config.CommonFlags.Containername != "" || len(config.CommonFlags.Labels) > 0 ||
+		len(config.CommonFlags.Annotations) > 0 || config.CommonFlags.Sandbox != "" {
 		filter = &gadgetv1alpha1.ContainerFilter{
 			Namespace:     config.CommonFlags.Namespace,
 			Podname:       config.CommonFlags.Podname,
+			PodUID:        config.CommonFlags.PodUID,
 			ContainerName: config.CommonFlags.Containername,
 			Labels:        config.CommonFlags.Labels,
+			Annotations:   config.CommonFlags.Annotations,
+			Sandbox:       config.CommonFlags.Sandbox,
+		}
+	}
+
+	sinks, err := config.CommonFlags.SinkParameters()
+	if err != nil {
+		return err
+	}
+
+	parameters := config.Parameters
+	if len(sinks) > 0 {
+		parameters = make(map[string]string)
+		for k, v := range config.Parameters {
+			parameters[k] = v
+		}
+		for k, v := range sinks {
+			parameters[k] = v
 		}
 	}
 
+	record.Filter = filter
+	record.Parameters = auditParameters(parameters)
+
 	trace := &gadgetv1alpha1.Trace{
 		ObjectMeta: metav1.ObjectMeta{
 			GenerateName: config.GadgetName + "-",
@@ -310,19 +418,31 @@ func CreateTrace(config *TraceConfig) (string, error) {
 			RunMode:    "Manual",
 			OutputMode: config.TraceOutputMode,
 			Output:     config.TraceOutput,
-			Parameters: config.Parameters,
+			Parameters: parameters,
 		},
 	}
 
-	err := createTraces(trace)
+	if config.TraceName != "" {
+		trace.ObjectMeta.Labels[TraceName] = config.TraceName
+	}
+
+	if config.TraceGroup != "" {
+		trace.ObjectMeta.Labels[TraceGroup] = config.TraceGroup
+	}
+
+	for k, v := range config.Annotations {
+		trace.ObjectMeta.Annotations[k] = v
+	}
+
+	err = createTraces(trace, config.NodeAnnotations)
 	if err != nil {
-		return "", err
+		return err
 	}
 
 	if config.TraceInitialState != "" {
 		// Once the traces are created, we wait for them to be in
 		// config.TraceInitialState state, so they are ready to be used by the user.
-		_, err = waitForTraceState(traceID, config.TraceInitialState)
+		_, err = waitForTraceState(traceID, config.TraceInitialState, config.CommonFlags)
 		if err != nil {
 			deleteError := DeleteTrace(traceID)
 
@@ -330,11 +450,11 @@ func CreateTrace(config *TraceConfig) (string, error) {
 				fmt.Fprintf(os.Stderr, "%v\n", err)
 			}
 
-			return "", err
+			return err
 		}
 	}
 
-	return traceID, nil
+	return nil
 }
 
 // getTraceListFromOptions returns a list of traces corresponding to the given
@@ -370,24 +490,94 @@ func getTraceListFromID(traceID string) (*gadgetv1alpha1.TraceList, error) {
 	return traces, nil
 }
 
-// SetTraceOperation sets the operation of an existing trace.
-// If trace does not exist an error is returned.
+// checkTraceName verifies that name can be used as a trace name: it has to
+// be a valid label value, not look like a trace ID and not be already used
+// by another trace.
+func checkTraceName(name string) error {
+	if errs := validation.IsValidLabelValue(name); len(errs) > 0 {
+		return WrapInErrInvalidArg("--name", errors.New(strings.Join(errs, ", ")))
+	}
+
+	traces, err := getTraceListFromOptions(metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", GlobalTraceID, name),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get traces with ID %q: %w", name, err)
+	}
+	if len(traces.Items) > 0 {
+		return WrapInErrInvalidArg("--name",
+			fmt.Errorf("%q is already the ID of an existing trace", name))
+	}
+
+	traces, err = getTraceListFromOptions(metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", TraceName, name),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get traces named %q: %w", name, err)
+	}
+	if len(traces.Items) > 0 {
+		return WrapInErrInvalidArg("--name",
+			fmt.Errorf("a trace named %q already exists", name))
+	}
+
+	return nil
+}
+
+// ResolveTraceID returns the ID of the trace named idOrName. If there is no
+// such trace, idOrName is considered to be a trace ID and returned as is.
+func ResolveTraceID(idOrName string) (string, error) {
+	// Trace names are label values, anything else can only be an ID.
+	if len(validation.IsValidLabelValue(idOrName)) > 0 {
+		return idOrName, nil
+	}
+
+	traces, err := getTraceListFromOptions(metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", TraceName, idOrName),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get traces named %q: %w", idOrName, err)
+	}
+
+	for _, trace := range traces.Items {
+		if id, ok := trace.ObjectMeta.Labels[GlobalTraceID]; ok {
+			return id, nil
+		}
+	}
+
+	return idOrName, nil
+}
+
+// SetTraceOperation appends the operation to the queue of operations of the
+// traces with the given ID. The trace controller applies them in order, so
+// there is no need to wait for the previous operation to be applied.
 func SetTraceOperation(traceID string, operation string) error {
-	// We have to wait for the previous operation to start before changing the
-	// trace operation.
-	// The trace controller deletes the GADGET_OPERATION field from Annotations
-	// when it is about to deal with an operation.
-	// Thus, to avoid losing operations, we need to wait for GADGET_OPERATION to
-	// be deleted before changing to the current operation.
-	// It is the same like when you are in the restaurant, you need to wait for
-	// the chef to cook the main dishes before ordering the dessert.
-	traces, err := waitForNoOperation(traceID)
+	record := newAuditRecord(AuditActionOperation, traceID)
+	record.Operation = operation
+
+	err := setTraceOperation(traceID, operation, record)
+	audit(record, err)
+
+	return err
+}
+
+func setTraceOperation(traceID string, operation string, record *AuditRecord) error {
+	traceClient, err := getTraceClient()
 	if err != nil {
 		return err
 	}
 
+	traces, err := getTraceListFromID(traceID)
+	if err != nil {
+		return err
+	}
+
+	if len(traces.Items) > 0 {
+		record.Gadget = traces.Items[0].Spec.Gadget
+	}
+
 	for _, trace := range traces.Items {
-		localError := updateTraceOperation(&trace, operation)
+		localError := operationqueue.Append(context.TODO(), traceClient,
+			trace.ObjectMeta.Namespace, trace.ObjectMeta.Name, operation)
 		if localError != nil {
 			err = fmt.Errorf("%w\nError updating trace operation for %q: %s", err, traceID, localError)
 		}
@@ -465,14 +655,43 @@ func getTraceWatcher(traceID, resourceVersion string) (watch.Interface, error) {
 	return watcher, nil
 }
 
-// waitForCondition waits for the traces with the ID received as parameter to
-// satisfy the conditionFunction received as parameter.
-func waitForCondition(traceID string, conditionFunction func(*gadgetv1alpha1.Trace) bool) (*gadgetv1alpha1.TraceList, error) {
-	satisfiedTraces := make(map[string]*gadgetv1alpha1.Trace)
-	erroredTraces := make(map[string]*gadgetv1alpha1.Trace)
-	var returnedTraces gadgetv1alpha1.TraceList
-	nodeWarnings := make(map[string]string)
-	nodeErrors := make(map[string]string)
+// TraceNodeStatus is the status of a trace on a given node once
+// waitForCondition returns. It is printed in JSON format to the file given by
+// --node-status-file.
+type TraceNodeStatus struct {
+	Node    string `json:"node"`
+	Trace   string `json:"trace"`
+	State   string `json:"state,omitempty"`
+	Ready   bool   `json:"ready"`
+	Error   string `json:"error,omitempty"`
+	Warning string `json:"warning,omitempty"`
+}
+
+// waitResult contains the traces classified by waitForConditionOnce.
+type waitResult struct {
+	satisfiedTraces map[string]*gadgetv1alpha1.Trace
+	erroredTraces   map[string]*gadgetv1alpha1.Trace
+	// lastSeenTraces contains the last version received of all the traces.
+	lastSeenTraces map[string]*gadgetv1alpha1.Trace
+	nodeWarnings   map[string]string
+	tracesNumber   int
+}
+
+// waitForConditionOnce watches the traces with the ID received as parameter
+// until they all satisfy conditionFunction, have an error or the timeout is
+// reached. The timeout is computed by getTraceTimeout from the number of
+// traces. The number of traces satisfying the condition is reported to
+// progress.
+func waitForConditionOnce(traceID string, conditionFunction func(*gadgetv1alpha1.Trace) bool, timeout time.Duration, progress *Progress) (*waitResult, error) {
+	result := &waitResult{
+		satisfiedTraces: make(map[string]*gadgetv1alpha1.Trace),
+		erroredTraces:   make(map[string]*gadgetv1alpha1.Trace),
+		lastSeenTraces:  make(map[string]*gadgetv1alpha1.Trace),
+		nodeWarnings:    make(map[string]string),
+	}
+	satisfiedTraces := result.satisfiedTraces
+	erroredTraces := result.erroredTraces
+	nodeWarnings := result.nodeWarnings
 
 	traceList, err := getTraceListFromID(traceID)
 	if err != nil {
@@ -481,6 +700,8 @@ func waitForCondition(traceID string, conditionFunction func(*gadgetv1alpha1.Tra
 
 	// Maybe some traces already satisfy conditionFunction?
 	for i, trace := range traceList.Items {
+		result.lastSeenTraces[trace.ObjectMeta.Name] = &traceList.Items[i]
+
 		if trace.Status.OperationWarning != "" {
 			// The trace can have a warning but satisfies conditionFunction.
 			// So, we do not add it to the map here.
@@ -500,11 +721,12 @@ func waitForCondition(traceID string, conditionFunction func(*gadgetv1alpha1.Tra
 		satisfiedTraces[trace.ObjectMeta.Name] = &traceList.Items[i]
 	}
 
-	tracesNumber := len(traceList.Items)
+	result.tracesNumber = len(traceList.Items)
+	progress.SetNodes(len(satisfiedTraces), result.tracesNumber)
 
 	// We only watch the traces if there are some which did not already satisfy
 	// the conditionFunction.
-	if len(satisfiedTraces)+len(erroredTraces) < tracesNumber {
+	if len(satisfiedTraces)+len(erroredTraces) < result.tracesNumber {
 		var watcher watch.Interface
 
 		// We will need to watch events on them.
@@ -520,8 +742,12 @@ func waitForCondition(traceID string, conditionFunction func(*gadgetv1alpha1.Tra
 			return nil, err
 		}
 
-		ctx, cancel := watchtools.ContextWithOptionalTimeout(context.Background(), TraceTimeout)
+		ctx, cancel := watchtools.ContextWithOptionalTimeout(context.Background(), getTraceTimeout(timeout, result.tracesNumber))
 		_, err = untilWithoutRetry(ctx, watcher, func(event watch.Event) (bool, error) {
+			defer func() {
+				progress.SetNodes(len(satisfiedTraces), result.tracesNumber)
+			}()
+
 			// This function will be executed until:
 			// 1. The number of watched traces equals the number of traces to watch,
 			// i.e. we dealt with the traces which interest us.
@@ -536,7 +762,7 @@ func waitForCondition(traceID string, conditionFunction func(*gadgetv1alpha1.Tra
 				// decrementing the tracesNumber.
 				// Otherwise we would still wait for the old number and we would
 				// timeout.
-				tracesNumber--
+				result.tracesNumber--
 
 				trace, _ := event.Object.(*gadgetv1alpha1.Trace)
 				traceName := trace.ObjectMeta.Name
@@ -545,6 +771,7 @@ func waitForCondition(traceID string, conditionFunction func(*gadgetv1alpha1.Tra
 				// and timeing out.
 				delete(satisfiedTraces, traceName)
 				delete(erroredTraces, traceName)
+				delete(result.lastSeenTraces, traceName)
 
 				return false, nil
 			case watch.Modified:
@@ -553,7 +780,8 @@ func waitForCondition(traceID string, conditionFunction func(*gadgetv1alpha1.Tra
 				// Deal particularly with error.
 				return false, fmt.Errorf("received event is an error one: %v", event)
 			case watch.Added:
-				// createTraces() creates traces synchronously.
+				// createTraces() waits for all the traces to be created before
+				// returning.
 				// So, if a watch.Added event occurs it means there is a problem (e.g.
 				// the user creates a trace by snooping on the traceID of existing
 				// traces).
@@ -564,6 +792,7 @@ func waitForCondition(traceID string, conditionFunction func(*gadgetv1alpha1.Tra
 			}
 
 			trace, _ := event.Object.(*gadgetv1alpha1.Trace)
+			result.lastSeenTraces[trace.ObjectMeta.Name] = trace
 
 			if trace.Status.OperationWarning != "" {
 				// The trace can have a warning but satisfies conditionFunction.
@@ -578,7 +807,7 @@ func waitForCondition(traceID string, conditionFunction func(*gadgetv1alpha1.Tra
 				// has an error.
 				delete(satisfiedTraces, trace.ObjectMeta.Name)
 
-				return len(satisfiedTraces)+len(erroredTraces) == tracesNumber, nil
+				return len(satisfiedTraces)+len(erroredTraces) == result.tracesNumber, nil
 			}
 
 			// If the trace does not satisfy the condition function, we are not
@@ -589,53 +818,217 @@ func waitForCondition(traceID string, conditionFunction func(*gadgetv1alpha1.Tra
 
 			satisfiedTraces[trace.ObjectMeta.Name] = trace
 
-			return len(satisfiedTraces)+len(erroredTraces) == tracesNumber, nil
+			return len(satisfiedTraces)+len(erroredTraces) == result.tracesNumber, nil
 		})
 		cancel()
 	}
 
-	for _, trace := range erroredTraces {
-		nodeErrors[trace.Spec.Node] = trace.Status.OperationError
+	return result, err
+}
+
+// checkNodesPolicy verifies that enough traces satisfied the condition
+// according to the --min-nodes and --require-all-nodes flags.
+func checkNodesPolicy(satisfied, total int) error {
+	switch {
+	case requireAllNodes && satisfied < total:
+		return fmt.Errorf("trace is ready on %d node(s) out of %d and --require-all-nodes was given",
+			satisfied, total)
+	case minNodes > 0 && satisfied < minNodes:
+		return fmt.Errorf("trace is ready on %d node(s) but --min-nodes is %d",
+			satisfied, minNodes)
+	case satisfied == 0 && total > 0:
+		return errors.New("trace is not ready on any node")
 	}
 
-	// We print errors whatever happened.
-	printTraceFeedback("Error", nodeErrors, tracesNumber)
+	return nil
+}
 
-	// We print warnings only if all trace failed.
-	if len(satisfiedTraces) == 0 {
-		printTraceFeedback("Warn", nodeWarnings, tracesNumber)
+// writeNodeStatus writes the status of the traces on each node in JSON format
+// to the file given by --node-status-file.
+func writeNodeStatus(result *waitResult) error {
+	if nodeStatusFile == "" {
+		return nil
+	}
+
+	statuses := []TraceNodeStatus{}
+	for name, trace := range result.lastSeenTraces {
+		_, ready := result.satisfiedTraces[name]
+		statuses = append(statuses, TraceNodeStatus{
+			Node:    trace.Spec.Node,
+			Trace:   name,
+			State:   trace.Status.State,
+			Ready:   ready,
+			Error:   trace.Status.OperationError,
+			Warning: trace.Status.OperationWarning,
+		})
 	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Node < statuses[j].Node
+	})
 
+	b, err := json.MarshalIndent(statuses, "", "  ")
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("failed to marshal node status: %w", err)
+	}
+
+	if err := os.WriteFile(nodeStatusFile, append(b, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write node status: %w", err)
+	}
+
+	return nil
+}
+
+// waitForCondition waits for the traces with the ID received as parameter to
+// satisfy the conditionFunction received as parameter, for at most timeout
+// (see getTraceTimeout).
+// If the watch on the traces fails, it is retried up to TraceWatchRetries
+// times. If some of the traces didn't satisfy the condition, the traces
+// which did are returned as long as the --min-nodes and --require-all-nodes
+// policy is respected.
+// The errors and warnings reported by the traces are returned in a
+// TraceFeedback, also when an error is returned, for the caller to print
+// them.
+func waitForCondition(traceID string, conditionFunction func(*gadgetv1alpha1.Trace) bool, timeout time.Duration) (*gadgetv1alpha1.TraceList, *TraceFeedback, error) {
+	var returnedTraces gadgetv1alpha1.TraceList
+	var result *waitResult
+	var err error
+
+	progress := NewProgress("Waiting for the traces")
+
+	for attempt := 0; ; attempt++ {
+		result, err = waitForConditionOnce(traceID, conditionFunction, timeout, progress)
+		if result == nil {
+			progress.Stop()
+			return nil, nil, err
+		}
+
+		// There is no need to retry if all the traces were dealt with or if
+		// the timeout was reached.
+		if err == nil || errors.Is(err, wait.ErrWaitTimeout) || attempt == TraceWatchRetries {
+			break
+		}
+	}
+	progress.Stop()
+
+	nodeErrors := make(map[string]string)
+	for _, trace := range result.erroredTraces {
+		nodeErrors[trace.Spec.Node] = trace.Status.OperationError
+	}
+
+	feedback := &TraceFeedback{
+		Errors:     newNodeFeedbacks(nodeErrors),
+		Warnings:   newNodeFeedbacks(result.nodeWarnings),
+		TotalNodes: result.tracesNumber,
+		ReadyNodes: len(result.satisfiedTraces),
+	}
+
+	if statusErr := writeNodeStatus(result); statusErr != nil {
+		fmt.Fprintf(os.Stderr, "Warn: %s\n", statusErr)
+	}
+
+	// Proceed with the healthy subset of nodes if the timeout was reached,
+	// as long as it respects the nodes policy.
+	if err != nil && !errors.Is(err, wait.ErrWaitTimeout) {
+		return nil, feedback, err
+	}
+	if policyErr := checkNodesPolicy(len(result.satisfiedTraces), result.tracesNumber); policyErr != nil {
+		if err != nil {
+			return nil, feedback, fmt.Errorf("%w: %s", err, policyErr)
+		}
+		return nil, feedback, policyErr
 	}
+	feedback.TimedOut = err != nil
 
-	for _, trace := range satisfiedTraces {
+	for _, trace := range result.satisfiedTraces {
 		returnedTraces.Items = append(returnedTraces.Items, *trace)
 	}
 
-	return &returnedTraces, nil
+	return &returnedTraces, feedback, nil
+}
+
+// WaitForTraceState waits for the traces with the ID received as parameter to
+// be in the expected state. Contrary to the other functions of this package,
+// it doesn't print the errors and warnings reported by the traces but returns
+// them.
+func WaitForTraceState(traceID string, expectedState string) (*gadgetv1alpha1.TraceList, *TraceFeedback, error) {
+	return waitForCondition(traceID, stateCondition(expectedState), traceTimeout)
 }
 
-// waitForTraceState waits for the traces with the ID received as parameter to
-// be in the expected state.
-func waitForTraceState(traceID string, expectedState string) (*gadgetv1alpha1.TraceList, error) {
-	return waitForCondition(traceID, func(trace *gadgetv1alpha1.Trace) bool {
+// stateCondition returns a condition satisfied by the traces in the
+// expectedState.
+func stateCondition(expectedState string) func(*gadgetv1alpha1.Trace) bool {
+	return func(trace *gadgetv1alpha1.Trace) bool {
 		return trace.Status.State == expectedState
-	})
+	}
 }
 
-// waitForNoOperation waits for the traces with the ID received as parameter to
-// not have an operation.
-func waitForNoOperation(traceID string) (*gadgetv1alpha1.TraceList, error) {
-	return waitForCondition(traceID, func(trace *gadgetv1alpha1.Trace) bool {
-		if trace.ObjectMeta.Annotations == nil {
-			return true
-		}
+// appliedCondition returns a condition satisfied by the traces in the
+// expectedState once the operations queued on them are applied. Unlike
+// stateCondition, it tells when the output of an operation keeping the state
+// of the traces, like the report of a gadget still running, is available.
+func appliedCondition(expectedState string) func(*gadgetv1alpha1.Trace) bool {
+	return func(trace *gadgetv1alpha1.Trace) bool {
+		return !operationqueue.Pending(trace.ObjectMeta.Annotations) &&
+			trace.Status.State == expectedState
+	}
+}
 
-		_, present := trace.ObjectMeta.Annotations[GadgetOperation]
-		return !present
-	})
+// TraceCompletion is the completion of the traces with a given ID, i.e. them
+// reaching the state in which their output is available. It's waited for in
+// the background by watching the traces.
+type TraceCompletion struct {
+	done chan struct{}
+
+	// The fields below are only set once done is closed.
+	traces   *gadgetv1alpha1.TraceList
+	feedback *TraceFeedback
+	err      error
+}
+
+// WatchTraceCompletion starts waiting in the background for the traces with
+// the ID received as parameter to be in the expected state. The traces which
+// didn't complete before deadline are given up, the ones which did are
+// returned by Wait() as long as the --min-nodes and --require-all-nodes
+// policy is respected. Like with --trace-timeout, deadline is increased every
+// TraceTimeoutNodesStep nodes.
+func WatchTraceCompletion(traceID string, expectedState string, deadline time.Duration) *TraceCompletion {
+	return watchTraceCondition(traceID, stateCondition(expectedState), deadline)
+}
+
+func watchTraceCondition(traceID string, conditionFunction func(*gadgetv1alpha1.Trace) bool, deadline time.Duration) *TraceCompletion {
+	c := &TraceCompletion{
+		done: make(chan struct{}),
+	}
+
+	go func() {
+		defer close(c.done)
+		c.traces, c.feedback, c.err = waitForCondition(traceID, conditionFunction, deadline)
+	}()
+
+	return c
+}
+
+// Done returns a channel closed when the traces completed or the deadline
+// was reached.
+func (c *TraceCompletion) Done() <-chan struct{} {
+	return c.done
+}
+
+// Wait blocks until the traces completed or the deadline was reached and
+// returns the traces which completed. Like WaitForTraceState, it returns the
+// errors and warnings reported by the traces in a TraceFeedback, which
+// tells with TimedOut if the results are partial.
+func (c *TraceCompletion) Wait() (*gadgetv1alpha1.TraceList, *TraceFeedback, error) {
+	<-c.done
+	return c.traces, c.feedback, c.err
+}
+
+// waitForTraceState is like WaitForTraceState but prints the feedback of the
+// traces to stderr, in JSON format if requested by params.
+func waitForTraceState(traceID string, expectedState string, params *CommonFlags) (*gadgetv1alpha1.TraceList, error) {
+	traces, feedback, err := WaitForTraceState(traceID, expectedState)
+	feedback.Fprint(os.Stderr, params)
+	return traces, err
 }
 
 var sigIntReceivedNumber = 0
@@ -683,7 +1076,7 @@ func sigHandler(traceID *string) {
 func PrintTraceOutputFromStream(traceID string, expectedState string, params *CommonFlags,
 	transformLine func(string) string,
 ) error {
-	traces, err := waitForTraceState(traceID, expectedState)
+	traces, err := waitForTraceState(traceID, expectedState, params)
 	if err != nil {
 		return err
 	}
@@ -694,23 +1087,85 @@ func PrintTraceOutputFromStream(traceID string, expectedState string, params *Co
 // PrintTraceOutputFromStatus is used to print trace output using function
 // pointer provided by caller.
 // It will parse trace.Spec.Output and print it calling the function pointer.
+// It waits for the traces to be in expectedState for the time given by
+// --completion-timeout and only prints the output of the nodes where they
+// did, with a warning for the others.
 func PrintTraceOutputFromStatus(traceID string, expectedState string, customResultsDisplay func(results []gadgetv1alpha1.Trace) error) error {
-	traces, err := waitForTraceState(traceID, expectedState)
+	return printTraceOutputFromStatus(traceID, stateCondition(expectedState), customResultsDisplay)
+}
+
+// PrintTraceOutputAfterOperations is like PrintTraceOutputFromStatus but
+// also waits for the operations queued on the traces to be applied. It's
+// used to print the output of an operation which doesn't change the state of
+// the traces.
+func PrintTraceOutputAfterOperations(traceID string, expectedState string, customResultsDisplay func(results []gadgetv1alpha1.Trace) error) error {
+	return printTraceOutputFromStatus(traceID, appliedCondition(expectedState), customResultsDisplay)
+}
+
+func printTraceOutputFromStatus(traceID string, conditionFunction func(*gadgetv1alpha1.Trace) bool, customResultsDisplay func(results []gadgetv1alpha1.Trace) error) error {
+	traces, feedback, err := watchTraceCondition(traceID, conditionFunction, completionTimeout).Wait()
+	feedback.Fprint(os.Stderr, nil)
 	if err != nil {
 		return err
 	}
 
+	if err := loadStoredOutputs(traces.Items); err != nil {
+		return err
+	}
+
 	return customResultsDisplay(traces.Items)
 }
 
+// loadStoredOutputs reads from the nodes the outputs of the traces that were
+// too large to be written in their status.
+func loadStoredOutputs(traces []gadgetv1alpha1.Trace) error {
+	var client *kubernetes.Clientset
+	for i := range traces {
+		trace := &traces[i]
+		if trace.Status.OutputRef == nil {
+			continue
+		}
+
+		if client == nil {
+			var err error
+			client, err = k8sutil.NewClientsetFromConfigFlags(KubernetesConfigFlags)
+			if err != nil {
+				return WrapInErrSetupK8sClient(err)
+			}
+		}
+
+		cmd := fmt.Sprintf("exec gadgettracermanager -call read-output -tracerid trace_%s_%s",
+			trace.ObjectMeta.Namespace, trace.ObjectMeta.Name)
+		var stdout, stderr bytes.Buffer
+		err := ExecPodRaw(client, trace.Spec.Node, cmd, &stdout, &stderr)
+		if err != nil {
+			return WrapInErrRunGadgetOnNode(trace.Spec.Node,
+				fmt.Errorf("reading the output stored on the node: %w: %s", err, stderr.String()))
+		}
+		if int64(stdout.Len()) != trace.Status.OutputRef.Size {
+			return WrapInErrRunGadgetOnNode(trace.Spec.Node,
+				fmt.Errorf("read %d bytes of the output stored on the node, expected %d",
+					stdout.Len(), trace.Status.OutputRef.Size))
+		}
+		trace.Status.Output = stdout.String()
+	}
+
+	return nil
+}
+
 // DeleteTrace deletes the traces for the given trace ID using RESTClient.
+// The failure to delete them is printed and recorded in the audit log, but
+// not returned.
 func DeleteTrace(traceID string) error {
+	record := newAuditRecord(AuditActionDelete, traceID)
+
 	traceClient, err := getTraceClient()
 	if err != nil {
+		audit(record, err)
 		return err
 	}
 
-	deleteTraces(traceClient, traceID)
+	audit(record, deleteTraces(traceClient, traceID))
 
 	return nil
 }
@@ -767,15 +1222,19 @@ func PrintAllTraces(config *TraceConfig) error {
 	}
 
 	type printingInformation struct {
+		name          string
 		namespace     string
 		nodes         []string
 		podname       string
 		containerName string
+		eventsEmitted int64
+		eventsDropped int64
+		subscribers   int
 	}
 
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 4, ' ', 0)
 
-	fmt.Fprintln(w, "NAMESPACE\tNODE(S)\tPOD\tCONTAINER\tTRACEID")
+	fmt.Fprintln(w, "NAMESPACE\tNODE(S)\tPOD\tCONTAINER\tTRACEID\tNAME\tEVENTS\tDROPPED\tSUBSCRIBERS")
 
 	printingMap := map[string]*printingInformation{}
 
@@ -800,6 +1259,7 @@ func PrintAllTraces(config *TraceConfig) error {
 			// Otherwise, we simply create a new entry.
 			if filter := trace.Spec.Filter; filter != nil {
 				printingMap[id] = &printingInformation{
+					name:          trace.ObjectMeta.Labels[TraceName],
 					namespace:     filter.Namespace,
 					nodes:         []string{node},
 					podname:       filter.Podname,
@@ -807,15 +1267,22 @@ func PrintAllTraces(config *TraceConfig) error {
 				}
 			} else {
 				printingMap[id] = &printingInformation{
+					name:  trace.ObjectMeta.Labels[TraceName],
 					nodes: []string{node},
 				}
 			}
 		}
+
+		// The counters are per node, sum them up for the whole trace.
+		printingMap[id].eventsEmitted += trace.Status.EventsEmitted
+		printingMap[id].eventsDropped += trace.Status.EventsDropped
+		printingMap[id].subscribers += trace.Status.Subscribers
 	}
 
 	for id, info := range printingMap {
 		sort.Strings(info.nodes)
-		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n", info.namespace, strings.Join(info.nodes, ","), info.podname, info.containerName, id)
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n", info.namespace, strings.Join(info.nodes, ","), info.podname, info.containerName, id, info.name,
+			info.eventsEmitted, info.eventsDropped, info.subscribers)
 	}
 
 	w.Flush()
@@ -838,12 +1305,18 @@ func RunTraceAndPrintStream(config *TraceConfig, transformLine func(string) stri
 		return errors.New("TraceOutputMode must be Stream. Otherwise, call RunTraceAndPrintStatusOutput")
 	}
 
-	traceID, err := CreateTrace(config)
+	follower, err := newPodFollower(config.CommonFlags)
+	if err != nil {
+		return err
+	}
+
+	traceID, err = CreateTrace(config)
 	if err != nil {
 		return fmt.Errorf("error creating trace: %w", err)
 	}
 
 	defer DeleteTrace(traceID)
+	defer follower.start(traceID)()
 
 	return PrintTraceOutputFromStream(traceID, config.TraceOutputState, config.CommonFlags, transformLine)
 }
@@ -859,19 +1332,47 @@ func RunTraceStreamCallback(config *TraceConfig, callback func(line string, node
 		return errors.New("TraceOutputMode must be Stream")
 	}
 
-	traceID, err := CreateTrace(config)
+	follower, err := newPodFollower(config.CommonFlags)
+	if err != nil {
+		return err
+	}
+
+	traceID, err = CreateTrace(config)
 	if err != nil {
 		return fmt.Errorf("error creating trace: %w", err)
 	}
 
 	defer DeleteTrace(traceID)
+	defer follower.start(traceID)()
+
+	traces, err := waitForTraceState(traceID, config.TraceOutputState, config.CommonFlags)
+	if err != nil {
+		return err
+	}
 
-	traces, err := waitForTraceState(traceID, config.TraceOutputState)
+	return genericStreams(config.CommonFlags, traces, callback, nil, -1)
+}
+
+// AttachTraceStreamCallback calls callback each time one of the tracers of
+// the existing stream trace traceID produces a new line on any of the
+// nodes. The last previous lines produced by each tracer before attaching
+// are received first. Unlike RunTraceStreamCallback, the trace is left
+// running when the command exits.
+func AttachTraceStreamCallback(traceID string, previous int, params *CommonFlags,
+	callback func(line string, node string),
+) error {
+	traces, err := getTraceListFromID(traceID)
 	if err != nil {
 		return err
 	}
 
-	return genericStreams(config.CommonFlags, traces, callback, nil)
+	for _, trace := range traces.Items {
+		if trace.Spec.OutputMode != "Stream" {
+			return fmt.Errorf("trace %q doesn't have the Stream output mode", traceID)
+		}
+	}
+
+	return genericStreams(params, traces, callback, nil, previous)
 }
 
 // RunTraceAndPrintStatusOutput creates a trace, prints its output and deletes
@@ -899,6 +1400,57 @@ func RunTraceAndPrintStatusOutput(config *TraceConfig, customResultsDisplay func
 	return PrintTraceOutputFromStatus(traceID, config.TraceOutputState, customResultsDisplay)
 }
 
+// RunTraceAndWatchStatusOutput is like RunTraceAndPrintStatusOutput but runs
+// the gadget again every interval, until the command is interrupted. A new
+// trace is created each time because the gadgets don't update the status of
+// a trace when the output doesn't change, so there would be no way to know
+// when the output of an operation run again is available.
+func RunTraceAndWatchStatusOutput(config *TraceConfig, interval time.Duration, customResultsDisplay func(results []gadgetv1alpha1.Trace) error) error {
+	var traceID string
+
+	sigHandler(&traceID)
+
+	if config.TraceOutputMode == "Stream" {
+		return errors.New("TraceOutputMode must not be Stream. Otherwise, call RunTraceAndPrintStream")
+	}
+
+	// The last output of each node is kept: the gadget pods leave it out of
+	// the traces when it didn't change, which spares transferring the same
+	// snapshot of a large node again and again.
+	outputs := snapshotcache.New(maxWatchedNodes)
+	watchConfig := *config
+	watchConfig.NodeAnnotations = outputs.Annotations
+	display := func(results []gadgetv1alpha1.Trace) error {
+		for i := range results {
+			if !outputs.Update(results[i].Spec.Node, &results[i]) {
+				return WrapInErrRunGadgetOnNode(results[i].Spec.Node,
+					errors.New("output not modified but not cached anymore"))
+			}
+		}
+		return customResultsDisplay(results)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		id, err := CreateTrace(&watchConfig)
+		if err != nil {
+			return fmt.Errorf("error creating trace: %w", err)
+		}
+		traceID = id
+
+		err = PrintTraceOutputFromStatus(traceID, config.TraceOutputState, display)
+		DeleteTrace(traceID)
+		traceID = ""
+		if err != nil {
+			return err
+		}
+
+		<-ticker.C
+	}
+}
+
 func genericStreamsDisplay(
 	params *CommonFlags,
 	results *gadgetv1alpha1.TraceList,
@@ -911,7 +1463,79 @@ func genericStreamsDisplay(
 		return transformLine(line)
 	}
 
-	return genericStreams(params, results, nil, transform)
+	return genericStreams(params, results, nil, transform, -1)
+}
+
+// isContainerReadyEvent tells if event is one of the READY events published
+// by the gadget tracer manager for each container a tracer is attached to.
+func isContainerReadyEvent(event *eventtypes.Event) bool {
+	return event.Type == eventtypes.READY && event.Container != ""
+}
+
+// resumedEventMessage returns the message of event if it's the RESUMED
+// event published when a trace is started again after a restart of the
+// gadget pod.
+func resumedEventMessage(event *eventtypes.Event) (string, bool) {
+	if event.Type != eventtypes.RESUMED {
+		return "", false
+	}
+	return fmt.Sprintf("node %q: %s", event.Node, event.Message), true
+}
+
+// debugEventMessage returns the message of event if it's a DEBUG event, like
+// the heartbeats published while a gadget logs at the debug level.
+func debugEventMessage(event *eventtypes.Event) (string, bool) {
+	if event.Type != eventtypes.DEBUG {
+		return "", false
+	}
+	return fmt.Sprintf("node %q: %s", event.Node, event.Message), true
+}
+
+// streamLineHandler processes the lines received from the gadget pods
+// before giving them to the gadget. Each line is decoded once and the
+// decision to drop it, print it as a warning or check its schema version is
+// taken on the decoded event.
+type streamLineHandler struct {
+	// skipInternal drops the READY events published when a tracer is
+	// attached to a container, which are only useful to the consumers of
+	// the JSON output, and prints the RESUMED events as warnings and the
+	// DEBUG ones as debug messages.
+	skipInternal bool
+	warn         io.Writer
+	schema       *schemaChecker
+
+	// anon is only set with --anonymize. It decodes the line again, as
+	// the fields to anonymize are specific to each gadget and not part of
+	// eventtypes.Event.
+	anon   *anonymizer.Anonymizer
+	fields anonymizer.Fields
+}
+
+// handle returns the line to give to the gadget, or false if it must be
+// dropped.
+func (h *streamLineHandler) handle(line string) (string, bool) {
+	var event eventtypes.Event
+	if err := json.Unmarshal([]byte(line), &event); err == nil {
+		if h.skipInternal {
+			if msg, ok := resumedEventMessage(&event); ok {
+				fmt.Fprintf(h.warn, "Warn: %s\n", msg)
+				return "", false
+			}
+			if msg, ok := debugEventMessage(&event); ok {
+				fmt.Fprintf(h.warn, "Debug: %s\n", msg)
+				return "", false
+			}
+			if isContainerReadyEvent(&event) {
+				return "", false
+			}
+		}
+		h.schema.check(&event)
+	}
+
+	if h.anon != nil {
+		line = h.anon.Line(line, h.fields)
+	}
+	return line, true
 }
 
 func genericStreams(
@@ -919,6 +1543,7 @@ func genericStreams(
 	results *gadgetv1alpha1.TraceList,
 	callback func(line string, node string),
 	transform func(line string) string,
+	previous int,
 ) error {
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
@@ -929,6 +1554,48 @@ func genericStreams(
 		return WrapInErrSetupK8sClient(err)
 	}
 
+	handler := &streamLineHandler{
+		skipInternal: params.OutputMode != OutputModeJSON,
+		warn:         os.Stderr,
+		schema:       newSchemaChecker(os.Stderr, params),
+	}
+
+	if params.Anonymize {
+		handler.anon, err = anonymizer.NewAnonymizer(params.AnonymizeKey)
+		if err != nil {
+			return err
+		}
+
+		// All the traces run the same gadget.
+		if len(results.Items) > 0 {
+			handler.fields = anonymizer.GadgetFields(results.Items[0].Spec.Gadget)
+		}
+	}
+
+	if callback != nil {
+		origCallback := callback
+		callback = func(line string, node string) {
+			line, ok := handler.handle(line)
+			if !ok {
+				return
+			}
+			if handler.anon != nil {
+				node = handler.anon.Hostname(node)
+			}
+			origCallback(line, node)
+		}
+	}
+	if transform != nil {
+		origTransform := transform
+		transform = func(line string) string {
+			line, ok := handler.handle(line)
+			if !ok {
+				return ""
+			}
+			return origTransform(line)
+		}
+	}
+
 	verbose := false
 	// verbose only when not json is used
 	if params.Verbose && params.OutputMode != OutputModeJSON {
@@ -953,8 +1620,8 @@ func genericStreams(
 		}
 		atomic.AddInt32(&streamCount, 1)
 		go func(nodeName, namespace, name string, index int) {
-			cmd := fmt.Sprintf("exec gadgettracermanager -call receive-stream -tracerid trace_%s_%s",
-				namespace, name)
+			cmd := fmt.Sprintf("exec gadgettracermanager -call receive-stream -tracerid trace_%s_%s -previous %d",
+				namespace, name, previous)
 			postProcess.OutStreams[index].Node = nodeName
 			err := ExecPod(client, nodeName, cmd,
 				postProcess.OutStreams[index], postProcess.ErrStreams[index])
@@ -995,8 +1662,12 @@ func genericStreams(
 
 // DeleteTracesByGadgetName removes all traces with this gadget name
 func DeleteTracesByGadgetName(gadget string) error {
+	record := newAuditRecord(AuditActionDelete, "")
+	record.Gadget = gadget
+
 	traceClient, err := getTraceClient()
 	if err != nil {
+		audit(record, err)
 		return err
 	}
 
@@ -1004,9 +1675,12 @@ func DeleteTracesByGadgetName(gadget string) error {
 		LabelSelector: fmt.Sprintf("gadgetName=%s", gadget),
 	}
 
-	return traceClient.GadgetV1alpha1().Traces("gadget").DeleteCollection(
+	err = traceClient.GadgetV1alpha1().Traces("gadget").DeleteCollection(
 		context.TODO(), metav1.DeleteOptions{}, listTracesOptions,
 	)
+	audit(record, err)
+
+	return err
 }
 
 func ListTracesByGadgetName(gadget string) ([]gadgetv1alpha1.Trace, error) {
@@ -1021,3 +1695,13 @@ func ListTracesByGadgetName(gadget string) ([]gadgetv1alpha1.Trace, error) {
 
 	return traces.Items, nil
 }
+
+// ListTracesByID returns the traces, one per node, with the given ID.
+func ListTracesByID(traceID string) ([]gadgetv1alpha1.Trace, error) {
+	traces, err := getTraceListFromID(traceID)
+	if err != nil {
+		return nil, err
+	}
+
+	return traces.Items, nil
+}
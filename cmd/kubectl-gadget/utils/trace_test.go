@@ -15,129 +15,204 @@
 package utils
 
 import (
+	"bytes"
 	"fmt"
-	"io/ioutil"
-	"os"
-	"strings"
 	"testing"
-)
-
-func TestGetIdenticalValue(t *testing.T) {
-	if v := getIdenticalValue(nil); v != "" {
-		t.Fatalf("Invalid identical value '%s' from nil map", v)
-	}
-
-	m := make(map[string]string)
-	if v := getIdenticalValue(m); v != "" {
-		t.Fatalf("Invalid identical value '%s' from %+v", v, m)
-	}
-
-	m = map[string]string{
-		"key1": "value1",
-		"key2": "value2",
-	}
-	if v := getIdenticalValue(m); v != "" {
-		t.Fatalf("Invalid identical value '%s' from %+v", v, m)
-	}
-
-	m = map[string]string{
-		"key1": "value1",
-		"key2": "value1",
-		"key3": "value2",
-		"key4": "value2",
-	}
-	if v := getIdenticalValue(m); v != "" {
-		t.Fatalf("Invalid identical value '%s' from %+v", v, m)
-	}
-
-	m = map[string]string{
-		"key": "value",
-	}
-	if v := getIdenticalValue(m); v != "value" {
-		t.Fatalf("Invalid identical value '%s' from %+v", v, m)
-	}
+	"time"
 
-	m = map[string]string{
-		"key1": "value",
-		"key2": "value",
-	}
-	if v := getIdenticalValue(m); v != "value" {
-		t.Fatalf("Invalid identical value '%s' from %+v", v, m)
-	}
-}
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	"github.com/kinvolk/inspektor-gadget/pkg/operationqueue"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
 
 func (mock *mockWriter) Printf(format string, args ...interface{}) {
 	mock.output = append(mock.output, []byte(fmt.Sprintf(format, args...))...)
 }
 
-func TestPrintTraceFeedback(t *testing.T) {
-	// get reference to original stderr and restore on exit
-	originalStderr := os.Stderr
-	defer func() { os.Stderr = originalStderr }()
-
-	var out string
-
-	runprintTraceFeedback := func(p string, m map[string]string, n int) string {
-		r, w, _ := os.Pipe()
-		os.Stderr = w
-		printTraceFeedback(p, m, n)
-		w.Close()
-		out, _ := ioutil.ReadAll(r)
-		os.Stderr = originalStderr
-
-		return string(out)
+func TestGetTraceTimeout(t *testing.T) {
+	table := []struct {
+		timeout  time.Duration
+		nodes    int
+		expected time.Duration
+	}{
+		{TraceTimeout, 1, TraceTimeout},
+		{TraceTimeout, TraceTimeoutNodesStep - 1, TraceTimeout},
+		{TraceTimeout, TraceTimeoutNodesStep, 2 * TraceTimeout},
+		{TraceTimeout, 500, 6 * TraceTimeout},
+		{CompletionTimeout, 1, CompletionTimeout},
+		{CompletionTimeout, 250, 3 * CompletionTimeout},
+	}
+
+	for _, entry := range table {
+		if timeout := getTraceTimeout(entry.timeout, entry.nodes); timeout != entry.expected {
+			t.Fatalf("Invalid timeout for %d nodes: %v != %v", entry.nodes, timeout, entry.expected)
+		}
 	}
+}
 
-	// Test one single element in the array.
-	m := map[string]string{
-		"node": "Err/Warn Message",
+func TestCheckNodesPolicy(t *testing.T) {
+	defer func() {
+		minNodes = 0
+		requireAllNodes = false
+	}()
+
+	table := []struct {
+		minNodes        int
+		requireAllNodes bool
+		satisfied       int
+		total           int
+		expectedErr     bool
+	}{
+		{0, false, 3, 3, false},
+		{0, false, 1, 3, false},
+		{0, false, 0, 3, true},
+		{0, true, 2, 3, true},
+		{0, true, 3, 3, false},
+		{2, false, 2, 3, false},
+		{2, false, 1, 3, true},
+	}
+
+	for _, entry := range table {
+		minNodes = entry.minNodes
+		requireAllNodes = entry.requireAllNodes
+
+		err := checkNodesPolicy(entry.satisfied, entry.total)
+		if (err != nil) != entry.expectedErr {
+			t.Fatalf("Unexpected result for %+v: %v", entry, err)
+		}
 	}
+}
 
-	out = runprintTraceFeedback("MyPrefix", m, 1)
-	expected := "MyPrefix: failed to run gadget on node \"node\": Err/Warn Message\n"
-	if expected != out {
-		t.Fatalf("'%v' != '%v'", out, expected)
+func TestAppliedCondition(t *testing.T) {
+	table := []struct {
+		annotations map[string]string
+		state       string
+		expected    bool
+	}{
+		{nil, "Started", true},
+		{map[string]string{operationqueue.Annotation: `[]`}, "Started", true},
+		{map[string]string{operationqueue.Annotation: `["report"]`}, "Started", false},
+		{nil, "Stopped", false},
+	}
+
+	condition := appliedCondition("Started")
+	for _, entry := range table {
+		trace := &gadgetv1alpha1.Trace{}
+		trace.ObjectMeta.Annotations = entry.annotations
+		trace.Status.State = entry.state
+
+		if ret := condition(trace); ret != entry.expected {
+			t.Fatalf("appliedCondition(%+v) = %v, expected %v", entry, ret, entry.expected)
+		}
 	}
+}
 
-	// It should print all the messages because they are not all the same.
-	m = map[string]string{
-		"node1": "Err/Warn Message 1",
-		"node2": "Err/Warn Message 2",
-		"node3": "Err/Warn Message 2",
+func TestIsContainerReadyEvent(t *testing.T) {
+	table := []struct {
+		event    eventtypes.Event
+		expected bool
+	}{
+		{eventtypes.Event{Type: eventtypes.READY, Namespace: "default", Pod: "mypod", Container: "mycontainer"}, true},
+		// READY event sent by a gadget itself, not for a container
+		{eventtypes.Event{Type: eventtypes.READY, Node: "node1"}, false},
+		{eventtypes.Event{Type: eventtypes.NORMAL, Namespace: "default", Pod: "mypod", Container: "mycontainer"}, false},
 	}
-	out = runprintTraceFeedback("MyPrefix2", m, 3)
-	for node, msg := range m {
-		expected = fmt.Sprintf("MyPrefix2: failed to run gadget on node \"%s\": %s", node, msg)
-		if !strings.Contains(out, expected) {
-			t.Fatalf("Output '%v' does not contain '%v'", out, expected)
+
+	for _, entry := range table {
+		if ret := isContainerReadyEvent(&entry.event); ret != entry.expected {
+			t.Fatalf("isContainerReadyEvent(%+v) = %v, expected %v", entry.event, ret, entry.expected)
 		}
 	}
+}
 
-	// It should print all the messages because even if they are all the same,
-	// there was a node that didn't report an error. Therefore, the final error
-	// message can say "failed to run gadget on all nodes" but only on the ones
-	// that it really failed.
-	m = map[string]string{
-		"node2": "Err/Warn Message 2",
-		"node3": "Err/Warn Message 2",
+func TestResumedEventMessage(t *testing.T) {
+	table := []struct {
+		event    eventtypes.Event
+		expected string
+		ok       bool
+	}{
+		{eventtypes.Event{Type: eventtypes.RESUMED, Node: "node1", Message: "trace resumed"}, `node "node1": trace resumed`, true},
+		{eventtypes.Event{Type: eventtypes.READY, Node: "node1"}, "", false},
 	}
-	out = runprintTraceFeedback("MyPrefix3", m, 3)
-	for node, msg := range m {
-		expected = fmt.Sprintf("MyPrefix3: failed to run gadget on node \"%s\": %s", node, msg)
-		if !strings.Contains(out, expected) {
-			t.Fatalf("Output '%v' does not contain '%v'", out, expected)
+
+	for _, entry := range table {
+		msg, ok := resumedEventMessage(&entry.event)
+		if msg != entry.expected || ok != entry.ok {
+			t.Fatalf("resumedEventMessage(%+v) = %q, %v, expected %q, %v", entry.event, msg, ok, entry.expected, entry.ok)
 		}
 	}
+}
 
-	// It should print one single message because they are the same.
-	m = map[string]string{
-		"node1": "Err/Warn Message",
-		"node2": "Err/Warn Message",
-		"node3": "Err/Warn Message",
-	}
-	out = runprintTraceFeedback("MyPrefix4", m, 3)
-	expected = "MyPrefix4: failed to run gadget on all nodes: Err/Warn Message\n"
-	if expected != out {
-		t.Fatalf("'%v' != '%v'", out, expected)
+func TestStreamLineHandler(t *testing.T) {
+	table := []struct {
+		description  string
+		skipInternal bool
+		line         string
+		expected     string
+		ok           bool
+		warning      string
+	}{
+		{
+			description: "normal event",
+			line:        `{"type":"normal","node":"node1","schemaVersion":1}`,
+			expected:    `{"type":"normal","node":"node1","schemaVersion":1}`,
+			ok:          true,
+		},
+		{
+			description:  "container ready event",
+			skipInternal: true,
+			line:         `{"type":"ready","node":"node1","container":"mycontainer"}`,
+			ok:           false,
+		},
+		{
+			description: "container ready event with json output",
+			line:        `{"type":"ready","node":"node1","container":"mycontainer"}`,
+			expected:    `{"type":"ready","node":"node1","container":"mycontainer"}`,
+			ok:          true,
+		},
+		{
+			description:  "resumed event",
+			skipInternal: true,
+			line:         `{"type":"resumed","node":"node1","message":"trace resumed"}`,
+			ok:           false,
+			warning:      "Warn: node \"node1\": trace resumed\n",
+		},
+		{
+			description:  "heartbeat event",
+			skipInternal: true,
+			line:         `{"type":"debug","node":"node1","message":"heartbeat 1 of gadget execsnoop"}`,
+			ok:           false,
+			warning:      "Debug: node \"node1\": heartbeat 1 of gadget execsnoop\n",
+		},
+		{
+			description: "heartbeat event with json output",
+			line:        `{"type":"debug","node":"node1","message":"heartbeat 1 of gadget execsnoop"}`,
+			expected:    `{"type":"debug","node":"node1","message":"heartbeat 1 of gadget execsnoop"}`,
+			ok:          true,
+		},
+		{
+			description:  "not json",
+			skipInternal: true,
+			line:         `not json`,
+			expected:     `not json`,
+			ok:           true,
+		},
+	}
+
+	for _, entry := range table {
+		var buf bytes.Buffer
+
+		handler := &streamLineHandler{
+			skipInternal: entry.skipInternal,
+			warn:         &buf,
+			schema:       newSchemaChecker(&buf, nil),
+		}
+		line, ok := handler.handle(entry.line)
+		if line != entry.expected || ok != entry.ok {
+			t.Fatalf("%s: got %q, %v, expected %q, %v", entry.description, line, ok, entry.expected, entry.ok)
+		}
+		if buf.String() != entry.warning {
+			t.Fatalf("%s: got warning %q, expected %q", entry.description, buf.String(), entry.warning)
+		}
 	}
 }
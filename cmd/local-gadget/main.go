@@ -70,6 +70,7 @@ func newRootCmd() *cobra.Command {
 			Use:   "exit",
 			Short: "Exit",
 			Run: func(cmd *cobra.Command, args []string) {
+				localGadgetManager.Close()
 				os.Exit(0)
 			},
 		}
@@ -233,6 +234,14 @@ func newRootCmd() *cobra.Command {
 			},
 		}
 
+		statusCmd = &cobra.Command{
+			Use:   "status",
+			Short: "Show the metrics of the traces",
+			Run: func(cmd *cobra.Command, args []string) {
+				fmt.Print(localGadgetManager.Status())
+			},
+		}
+
 		dumpCmd = &cobra.Command{
 			Use:   "dump",
 			Short: "Dump internal data",
@@ -258,6 +267,7 @@ func newRootCmd() *cobra.Command {
 		showCmd,
 		streamCmd,
 		deleteCmd,
+		statusCmd,
 		dumpCmd,
 		versionCmd,
 		exitCmd,
@@ -287,10 +297,18 @@ func newRootCmd() *cobra.Command {
 func runLocalGadget(cmd *cobra.Command, args []string) error {
 	var err error
 
+	// Only the runtimes found on WSL2 are kept when none is asked for.
+	if len(runtimeConfigs) == 0 {
+		return errors.New("no container runtime found: enable the WSL integration " +
+			"of this distribution in the settings of Docker Desktop, or give the " +
+			"path of the socket of the runtime with --docker-socketpath")
+	}
+
 	localGadgetManager, err = localgadgetmanager.NewManager(runtimeConfigs)
 	if err != nil {
 		return fmt.Errorf("failed to initialize manager: %w", err)
 	}
+	defer localGadgetManager.Close()
 
 	homedir, err := os.UserHomeDir()
 	if err != nil {
@@ -364,6 +382,7 @@ func runLocalGadget(cmd *cobra.Command, args []string) error {
 				return localGadgetManager.ListTraces()
 			}),
 		),
+		readline.PcItem("status"),
 		readline.PcItem("dump"),
 		readline.PcItem("version"),
 		readline.PcItem("exit"),
@@ -424,25 +443,55 @@ func main() {
 				log.StandardLogger().SetLevel(log.DebugLevel)
 			}
 
+			wslVersion := containerutils.DetectWSL()
+			if wslVersion == containerutils.WSL1 {
+				return errors.New("WSL1 doesn't run a Linux kernel and can't run eBPF programs: " +
+					"convert the distribution to WSL2 with \"wsl --set-version <distribution> 2\"")
+			}
+
 			parts := strings.Split(runtimes, ",")
 
 		partsLoop:
 			for _, p := range parts {
 				runtimeName := strings.TrimSpace(p)
 				socketPath := ""
+				socketFlag := ""
 
 				switch runtimeName {
 				case docker.Name:
 					socketPath = dockerSocketPath
+					socketFlag = "docker-socketpath"
 				case containerd.Name:
 					socketPath = containerdSocketPath
+					socketFlag = "containerd-socketpath"
 				case crio.Name:
 					socketPath = crioSocketPath
+					socketFlag = "crio-socketpath"
 				default:
 					return utils.WrapInErrInvalidArg("--runtime / -r",
 						fmt.Errorf("runtime %q is not supported", p))
 				}
 
+				// On WSL2, the runtimes are usually the ones of Docker
+				// Desktop, whose sockets aren't always at the default
+				// paths. The runtimes not found are left out unless
+				// they were asked for.
+				if wslVersion == containerutils.WSL2 && !cmd.Flags().Changed(socketFlag) {
+					candidates := []string{socketPath}
+					if runtimeName == docker.Name {
+						candidates = append(candidates, containerutils.WSL2DockerSocketPaths...)
+					}
+					found := containerutils.FindSocketPath(candidates...)
+					if found == "" && !cmd.Flags().Changed("runtimes") {
+						log.Debugf("Ignoring runtime %q: no socket found at %s",
+							runtimeName, strings.Join(candidates, ", "))
+						continue
+					}
+					if found != "" {
+						socketPath = found
+					}
+				}
+
 				for _, r := range runtimeConfigs {
 					if r.Name == runtimeName {
 						log.Infof("Ignoring duplicated runtime %q from %q",
@@ -42,13 +42,28 @@ env | grep '^INSPEKTOR_GADGET_OPTION_.*='
 echo -n "Inspektor Gadget version: "
 echo $INSPEKTOR_GADGET_VERSION
 
+# Without the privileges needed by eBPF, the gadget pods can't mount bpffs nor
+# install the hooks on the host: the containers are discovered with the pod
+# informer and only the gadgets not using eBPF are available.
+UNPRIVILEGED=0
+if [ "$INSPEKTOR_GADGET_OPTION_UNPRIVILEGED" = "true" ] ; then
+  echo "Unprivileged mode: only the gadgets not using eBPF are available"
+  UNPRIVILEGED=1
+
+  # /run is private to the pod: link the sockets of the container runtimes
+  # from the read-only /run of the host, at their default paths.
+  for SOCKET in containerd crio docker.sock ; do
+    ln -sfn /host/run/$SOCKET /run/$SOCKET
+  done
+fi
+
 # Workaround for Minikube with the Docker driver:
 # Since it starts an outer docker container with a read-only /sys without bpf
 # mounted, passing /sys/fs/bpf from the pseudo-host does not work.
 # See also:
 # https://github.com/kubernetes/minikube/blob/99a0c91459f17ad8c83c80fc37a9ded41e34370c/deploy/kicbase/entrypoint#L76-L81
 BPF_MOUNTPOINT_TYPE="`stat -f -c %T /sys/fs/bpf`"
-if [ "$BPF_MOUNTPOINT_TYPE" != "bpf_fs" ] ; then
+if [ "$UNPRIVILEGED" = 0 ] && [ "$BPF_MOUNTPOINT_TYPE" != "bpf_fs" ] ; then
   echo "/sys/fs/bpf is of type $BPF_MOUNTPOINT_TYPE. Remounting."
   mount -t bpf bpf /sys/fs/bpf/
 fi
@@ -62,7 +77,7 @@ fi
 # In the gadget image, /usr/src is a symlink to /host/usr/src (see gadget-*.Dockerfile).
 # If the kernel headers are already available on the gadget pod via the symlink,
 # no need to download them.
-if [ "$ID" = "rhcos" ] && [ ! -d "/usr/src/kernels/$KERNEL" ]; then
+if [ "$UNPRIVILEGED" = 0 ] && [ "$ID" = "rhcos" ] && [ ! -d "/usr/src/kernels/$KERNEL" ]; then
   # Using Centos RPMs because RHCOS ones are not publicly available (they are the same).
   echo "Fetching kernel-devel from CentOS Vault Mirror..."
 
@@ -126,6 +141,10 @@ fi
 # Choose what hook mode to use based on the configuration detected
 HOOK_MODE="$INSPEKTOR_GADGET_OPTION_HOOK_MODE"
 
+if [ "$UNPRIVILEGED" = 1 ] ; then
+  HOOK_MODE="podinformer"
+fi
+
 if [ "$HOOK_MODE" = "auto" ] || [ -z "$HOOK_MODE" ] ; then
   if [ "$CRIO" = 1 ] ; then
     echo "Hook mode CRI-O detected"
@@ -191,7 +210,9 @@ echo "Gadget Tracer Manager hook mode: ${GADGET_TRACER_MANAGER_HOOK_MODE}"
 
 # Use BTFHub if needed
 ARCH=$(uname -m)
-if test -f /sys/kernel/btf/vmlinux; then
+if [ "$UNPRIVILEGED" = 1 ] ; then
+  echo "Unprivileged mode: BTF not needed"
+elif test -f /sys/kernel/btf/vmlinux; then
   echo "Kernel provided BTF is available at /sys/kernel/btf/vmlinux"
 else
   echo "Kernel provided BTF is not available: Trying shipped BTF files"
@@ -225,4 +246,10 @@ echo "Starting the Gadget Tracer Manager..."
 cd /
 rm -f /run/gadgettracermanager.socket
 exec /bin/gadgettracermanager -serve -hook-mode=$GADGET_TRACER_MANAGER_HOOK_MODE \
-    -controller -fallback-podinformer=$INSPEKTOR_GADGET_OPTION_FALLBACK_POD_INFORMER
+    -controller -fallback-podinformer=$INSPEKTOR_GADGET_OPTION_FALLBACK_POD_INFORMER \
+    -metrics-address="$INSPEKTOR_GADGET_OPTION_METRICS_ADDRESS" \
+    -kernel-log="$INSPEKTOR_GADGET_OPTION_KERNEL_LOG" \
+    -node-labels="$INSPEKTOR_GADGET_OPTION_NODE_LABELS" \
+    -authorize-traces="${INSPEKTOR_GADGET_OPTION_AUTHORIZE_TRACES:-false}" \
+    -creator-webhook-address="$INSPEKTOR_GADGET_OPTION_CREATOR_WEBHOOK_ADDRESS" \
+    -creator-proxies="$INSPEKTOR_GADGET_OPTION_CREATOR_PROXIES"
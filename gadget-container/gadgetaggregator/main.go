@@ -0,0 +1,111 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/tls"
+	"flag"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/kinvolk/inspektor-gadget/pkg/aggregator"
+	clientset "github.com/kinvolk/inspektor-gadget/pkg/client/clientset/versioned"
+	"github.com/kinvolk/inspektor-gadget/pkg/k8sutil"
+)
+
+var (
+	address     string
+	kubeconfig  string
+	cacheTTL    time.Duration
+	timeout     time.Duration
+	tlsCertFile string
+	tlsKeyFile  string
+)
+
+// selfSignedHosts are the names of the service the self-signed certificate
+// is generated for.
+var selfSignedHosts = []string{
+	"localhost",
+	"gadget-aggregator",
+	"gadget-aggregator.gadget",
+	"gadget-aggregator.gadget.svc",
+}
+
+func init() {
+	flag.StringVar(&address, "address", ":8443", "Address the API is served on")
+	flag.StringVar(&kubeconfig, "kubeconfig", "", "Path to the kubeconfig, the service account of the pod is used if empty")
+	flag.DurationVar(&cacheTTL, "cache-ttl", 10*time.Second, "How long the results of a snapshot are reused for the same request (disabled if 0)")
+	flag.DurationVar(&timeout, "timeout", 30*time.Second, "How long the gadgets can take to run on the nodes")
+	flag.StringVar(&tlsCertFile, "tls-cert-file", "", "Path to the certificate the API is served with, a self-signed one is generated if empty")
+	flag.StringVar(&tlsKeyFile, "tls-key-file", "", "Path to the key of the certificate given with -tls-cert-file")
+}
+
+func main() {
+	flag.Parse()
+
+	config, err := k8sutil.NewConfig(kubeconfig)
+	if err != nil {
+		log.Fatalf("failed to get the cluster configuration: %v", err)
+	}
+
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("failed to set up the Kubernetes client: %v", err)
+	}
+
+	traceClient, err := clientset.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("failed to set up the trace client: %v", err)
+	}
+
+	agg := aggregator.New(client, traceClient, timeout)
+	agg.SetOutputReader(aggregator.NewExecOutputReader(config, client))
+
+	if (tlsCertFile == "") != (tlsKeyFile == "") {
+		log.Fatalf("-tls-cert-file and -tls-key-file must be given together")
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if tlsCertFile == "" {
+		log.Warnf("no certificate given, serving the API with a self-signed one")
+		cert, err := aggregator.SelfSignedCertificate(selfSignedHosts, 365*24*time.Hour)
+		if err != nil {
+			log.Fatalf("failed to generate the certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	server := &http.Server{
+		Addr:      address,
+		Handler:   aggregator.NewAuthorizer(client, aggregator.NewServer(agg, cacheTTL)),
+		TLSConfig: tlsConfig,
+
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		// The advisors run for up to aggregator.MaxDuration before
+		// writing their results.
+		WriteTimeout:   aggregator.MaxDuration + 2*timeout + time.Minute,
+		IdleTimeout:    2 * time.Minute,
+		MaxHeaderBytes: 64 << 10,
+	}
+
+	log.Printf("Serving the API on %s", address)
+	if err := server.ListenAndServeTLS(tlsCertFile, tlsKeyFile); err != nil {
+		log.Fatalf("failed to serve the API: %v", err)
+	}
+}
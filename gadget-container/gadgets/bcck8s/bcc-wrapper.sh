@@ -18,6 +18,7 @@ set -e
 
 MANAGER=true
 PROBECLEANUP=false
+PERFMAPS=false
 
 while [[ $# -gt 0 ]]
 do
@@ -41,6 +42,10 @@ case $key in
         PROBECLEANUP=true
         shift
         ;;
+    --perfmaps)
+        PERFMAPS=true
+        shift
+        ;;
     --gadget)
         GADGET="$2"
         shift
@@ -136,7 +141,15 @@ if [ "$MANAGER" = "true" ] ; then
     MODE="--cgroupmap"
     MAPPATH=$BPFDIR/gadget/cgroupidset_$TRACERID
   fi
+  # BCC symbolizes the code generated by the JIT compilers with the perf
+  # maps found in its /tmp: link the ones of the selected containers.
+  if [ "$PERFMAPS" = "true" ] ; then
+    $GADGETTRACERMANAGER -call link-perf-maps -tracerid "$TRACERID" || true
+  fi
   exec $GADGET $MODE $MAPPATH "$@"
 else
+  if [ "$PERFMAPS" = "true" ] ; then
+    $GADGETTRACERMANAGER -call link-perf-maps || true
+  fi
   exec $GADGET "$@"
 fi
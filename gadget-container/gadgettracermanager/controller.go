@@ -16,6 +16,7 @@ package main
 
 import (
 	"os"
+	"path/filepath"
 
 	log "github.com/sirupsen/logrus"
 
@@ -28,16 +29,26 @@ import (
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 
 	gadgetkinvolkiov1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
 	"github.com/kinvolk/inspektor-gadget/pkg/controllers"
 	gadgetcollection "github.com/kinvolk/inspektor-gadget/pkg/gadget-collection"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/outputstore"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/sink"
+	"github.com/kinvolk/inspektor-gadget/pkg/kernellog"
 	//+kubebuilder:scaffold:imports
 )
 
-func startController(node string, tracerManager *gadgettracermanager.GadgetTracerManager) {
+// newOutputStore returns the store of the outputs too large for the status
+// of the traces, in a directory of the node.
+func newOutputStore() *outputstore.Store {
+	return outputstore.New(filepath.Join(sink.HostRoot, outputstore.Dir))
+}
+
+func startController(node string, tracerManager *gadgettracermanager.GadgetTracerManager, metricsAddress string, kernelLog *kernellog.Log, authorizeTraces, withoutBPF bool) {
 	scheme := runtime.NewScheme()
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
@@ -45,6 +56,9 @@ func startController(node string, tracerManager *gadgettracermanager.GadgetTrace
 	//+kubebuilder:scaffold:scheme
 
 	traceFactories := gadgetcollection.TraceFactories()
+	if withoutBPF {
+		traceFactories = gadgetcollection.TraceFactoriesWithoutBPF()
+	}
 
 	for _, factory := range traceFactories {
 		factoryWithScheme, ok := factory.(gadgets.TraceFactoryWithScheme)
@@ -53,9 +67,20 @@ func startController(node string, tracerManager *gadgettracermanager.GadgetTrace
 		}
 	}
 
+	// TCP port can be set to "0" to disable the metrics serving
+	metricsBindAddress := "0"
+	if metricsAddress != "" {
+		metricsBindAddress = metricsAddress
+
+		if err := sink.RegisterMetrics(metrics.Registry); err != nil {
+			log.Errorf("unable to register the metrics of the prometheus sinks: %s", err)
+			os.Exit(1)
+		}
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:             scheme,
-		MetricsBindAddress: "0", // TCP port can be set to "0" to disable the metrics serving
+		MetricsBindAddress: metricsBindAddress,
 	})
 	if err != nil {
 		log.Errorf("unable to start manager: %s", err)
@@ -64,20 +89,67 @@ func startController(node string, tracerManager *gadgettracermanager.GadgetTrace
 
 	for _, factory := range traceFactories {
 		factory.Initialize(tracerManager, mgr.GetClient())
+
+		if kernelLog == nil {
+			continue
+		}
+		if factoryWithKernelLog, ok := factory.(gadgets.TraceFactoryWithKernelLog); ok {
+			factoryWithKernelLog.SetKernelLog(kernelLog)
+		}
 	}
 
-	if err = (&controllers.TraceReconciler{
-		Client:         mgr.GetClient(),
-		Scheme:         mgr.GetScheme(),
-		Node:           node,
-		TraceFactories: traceFactories,
-		TracerManager:  tracerManager,
-	}).SetupWithManager(mgr); err != nil {
+	traceReconciler := &controllers.TraceReconciler{
+		Client:          mgr.GetClient(),
+		Scheme:          mgr.GetScheme(),
+		Node:            node,
+		TraceFactories:  traceFactories,
+		TracerManager:   tracerManager,
+		AuthorizeTraces: authorizeTraces,
+		OutputStore:     newOutputStore(),
+		WithoutBPF:      withoutBPF,
+	}
+	if err = traceReconciler.SetupWithManager(mgr); err != nil {
 		log.Errorf("unable to create trace controller: %s", err)
 		os.Exit(1)
 	}
+	if tracerManager != nil {
+		tracerManager.SetMapsDumper(traceReconciler.DumpMaps)
+	}
 	//+kubebuilder:scaffold:builder
 
+	if tracerManager != nil {
+		if err := mgr.Add(&controllers.TraceStatsUpdater{
+			Client:        mgr.GetClient(),
+			Node:          node,
+			TracerManager: tracerManager,
+		}); err != nil {
+			log.Errorf("unable to create trace stats updater: %s", err)
+			os.Exit(1)
+		}
+		if err := mgr.Add(&controllers.HeartbeatPublisher{
+			Client:        mgr.GetClient(),
+			Node:          node,
+			TracerManager: tracerManager,
+		}); err != nil {
+			log.Errorf("unable to create heartbeat publisher: %s", err)
+			os.Exit(1)
+		}
+	}
+
+	// The pod name isn't set when running outside of the DaemonSet created
+	// by kubectl gadget deploy.
+	if podName := os.Getenv("GADGET_POD_NAME"); podName != "" {
+		if err := mgr.Add(&controllers.ResourceStatsUpdater{
+			Client:    mgr.GetClient(),
+			Node:      node,
+			PodName:   podName,
+			Namespace: os.Getenv("GADGET_POD_NAMESPACE"),
+		}); err != nil {
+			log.Errorf("unable to create resource stats updater: %s", err)
+			os.Exit(1)
+		}
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		log.Errorf("unable to set up health check: %s", err)
 		os.Exit(1)
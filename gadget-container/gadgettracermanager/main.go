@@ -16,17 +16,21 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strings"
 	"syscall"
 	"time"
 
+	"github.com/cilium/ebpf"
 	log "github.com/sirupsen/logrus"
 
 	"google.golang.org/grpc"
@@ -35,8 +39,14 @@ import (
 	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/status"
 
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager"
 	pb "github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/api"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/sink"
+	"github.com/kinvolk/inspektor-gadget/pkg/kernellog"
+	"github.com/kinvolk/inspektor-gadget/pkg/perfmap"
+	"github.com/kinvolk/inspektor-gadget/pkg/tracecreator"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
 )
 
 var (
@@ -46,10 +56,17 @@ var (
 	liveness            bool
 	fallbackPodInformer bool
 	hookMode            string
+	metricsAddress      string
+	kernelLogSource     string
+	authorizeTraces     bool
+	creatorWebhook      string
+	creatorWebhookCerts string
+	creatorProxies      string
 	socketfile          string
 	method              string
 	label               string
 	tracerid            string
+	previous            int
 	containerID         string
 	cgroupPath          string
 	cgroupID            uint64
@@ -57,6 +74,13 @@ var (
 	podname             string
 	containername       string
 	containerPid        uint
+	sinkFile            string
+	since               string
+	perfMapDir          string
+	traceName           string
+	gadgetName          string
+	logLevel            string
+	nodeLabels          string
 )
 
 const (
@@ -70,9 +94,10 @@ func init() {
 	flag.BoolVar(&serve, "serve", false, "Start server")
 	flag.BoolVar(&controller, "controller", false, "Enable the controller for custom resources")
 
-	flag.StringVar(&method, "call", "", "Call a method (add-tracer, remove-tracer, receive-stream, add-container, remove-container)")
+	flag.StringVar(&method, "call", "", "Call a method (add-tracer, remove-tracer, receive-stream, add-container, remove-container, clean-pins, read-output, read-sink-file, link-perf-maps, dump-maps, set-log-level)")
 	flag.StringVar(&label, "label", "", "key=value,key=value labels to use in add-tracer")
-	flag.StringVar(&tracerid, "tracerid", "", "tracerid to use in remove-tracer")
+	flag.StringVar(&tracerid, "tracerid", "", "tracerid to use in remove-tracer, receive-stream, read-output or link-perf-maps")
+	flag.IntVar(&previous, "previous", -1, "number of previously published lines to receive first in receive-stream (negative for all)")
 	flag.StringVar(&containerID, "containerid", "", "container id to use in add-container or remove-container")
 	flag.StringVar(&cgroupPath, "cgrouppath", "", "cgroup path to use in add-container")
 	flag.Uint64Var(&cgroupID, "cgroupid", 0, "cgroup id to use in add-container")
@@ -80,10 +105,24 @@ func init() {
 	flag.StringVar(&podname, "podname", "", "podname to use in add-container")
 	flag.StringVar(&containername, "containername", "", "container name to use in add-container")
 	flag.UintVar(&containerPid, "containerpid", 0, "container PID to use in add-container")
+	flag.StringVar(&sinkFile, "file", "", "name of the file of a file sink to use in read-sink-file")
+	flag.StringVar(&since, "since", "", "RFC 3339 time since which the events are read in read-sink-file (all the events if empty)")
+	flag.StringVar(&traceName, "trace", "", "namespace/name of the trace whose maps are dumped in dump-maps")
+	flag.StringVar(&gadgetName, "gadget", "", "gadget whose log level is changed in set-log-level")
+	flag.StringVar(&logLevel, "level", "", "log level to use in set-log-level, e.g. debug, or default")
+
+	flag.StringVar(&perfMapDir, "perf-map-dir", "/tmp", "directory where link-perf-maps links the perf maps of the processes")
 
 	flag.BoolVar(&dump, "dump", false, "Dump state for debugging")
 	flag.BoolVar(&liveness, "liveness", false, "Execute as client and perform liveness probe")
 	flag.BoolVar(&fallbackPodInformer, "fallback-podinformer", true, "Use pod informer as a fallback for main hook")
+	flag.StringVar(&kernelLogSource, "kernel-log", "", "Source of the kernel log messages attached to the events of some gadgets (journal, kmsg, disabled if empty)")
+	flag.BoolVar(&authorizeTraces, "authorize-traces", false, "Reject the traces whose creator doesn't have the "+gadgets.TraceVerb+" verb on the pods they select")
+	flag.StringVar(&creatorWebhook, "creator-webhook-address", "", "Address the admission webhook recording the creator of the traces is served on, e.g. :9444 (disabled if empty)")
+	flag.StringVar(&creatorWebhookCerts, "creator-webhook-cert-dir", "/etc/gadget/creator-webhook", "Directory with the tls.crt and tls.key files the admission webhook is served with")
+	flag.StringVar(&creatorProxies, "creator-proxies", "", "Comma-separated users creating traces on behalf of other users, whose creator annotations are kept by the admission webhook")
+	flag.StringVar(&nodeLabels, "node-labels", strings.Join(eventtypes.DefaultNodeLabels, ","), "Comma-separated labels of the node added to the events (none if empty)")
+	flag.StringVar(&metricsAddress, "metrics-address", "", "Address the metrics of the controller and of the prometheus sinks are served on, e.g. :2224 (disabled if empty)")
 }
 
 func main() {
@@ -156,8 +195,9 @@ func main() {
 		os.Exit(0)
 
 	case "receive-stream":
-		stream, err := client.ReceiveStream(context.Background(), &pb.TracerID{
-			Id: tracerid,
+		stream, err := client.ReceiveStream(context.Background(), &pb.ReceiveStreamRequest{
+			Id:       tracerid,
+			Previous: int32(previous),
 		})
 		if err != nil {
 			log.Fatalf("%v", err)
@@ -200,6 +240,84 @@ func main() {
 		}
 		os.Exit(0)
 
+	case "clean-pins":
+		out, err := client.CleanPins(ctx, &pb.CleanPinsRequest{})
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		for _, path := range out.Removed {
+			fmt.Println(path)
+		}
+		os.Exit(0)
+
+	case "dump-maps":
+		parts := strings.SplitN(traceName, "/", 2)
+		if len(parts) != 2 {
+			log.Fatalf("invalid -trace %q: expected namespace/name", traceName)
+		}
+		out, err := client.DumpMaps(ctx, &pb.DumpMapsRequest{
+			Namespace: parts[0],
+			Name:      parts[1],
+		})
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		fmt.Println(string(out.Snapshot))
+		os.Exit(0)
+
+	case "set-log-level":
+		out, err := client.SetLogLevel(ctx, &pb.SetLogLevelRequest{
+			Gadget: gadgetName,
+			Level:  logLevel,
+		})
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		fmt.Println(out.Previous)
+		os.Exit(0)
+
+	case "read-sink-file":
+		// The files are read from the node, without the server.
+		var sinceTime time.Time
+		if since != "" {
+			var err error
+			sinceTime, err = time.Parse(time.RFC3339Nano, since)
+			if err != nil {
+				log.Fatalf("invalid -since: %v", err)
+			}
+		}
+		if err := sink.ReadFile(sinkFile, sinceTime, os.Stdout); err != nil {
+			log.Fatalf("%v", err)
+		}
+		os.Exit(0)
+
+	case "read-output":
+		// The outputs are read from the node, without the server.
+		if err := newOutputStore().Read(tracerid, os.Stdout); err != nil {
+			log.Fatalf("%v", err)
+		}
+		os.Exit(0)
+
+	case "link-perf-maps":
+		// The perf maps are linked for the processes of the containers
+		// selected by the tracer, or for all the processes without it.
+		var mntns map[uint64]struct{}
+		if tracerid != "" {
+			var err error
+			mntns, err = tracerMountNamespaces(tracerid)
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
+		}
+		result, err := perfmap.Link(perfMapDir, mntns)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		for _, name := range result.Ambiguous {
+			fmt.Fprintf(os.Stderr, "Warning: %s not linked: several containers have a process with this PID\n", name)
+		}
+		os.Exit(0)
+
 	default:
 		fmt.Printf("invalid method %q\n", method)
 		flag.PrintDefaults()
@@ -252,12 +370,25 @@ func main() {
 		var opts []grpc.ServerOption
 		grpcServer := grpc.NewServer(opts...)
 
+		// Restricted clusters may not allow the gadget pods to load eBPF
+		// programs: only the gadgets not using eBPF are available then.
+		withBPF, err := gadgets.HasBPFPrivileges()
+		if err != nil {
+			log.Warnf("failed to check the privileges needed by eBPF, assuming they are available: %s", err)
+			withBPF = true
+		}
+		if !withBPF {
+			log.Warnf("the privileges needed by eBPF are missing: only the gadgets not using eBPF are available")
+		}
+
 		var tracerManager *gadgettracermanager.GadgetTracerManager
 
 		tracerManager, err = gadgettracermanager.NewServer(&gadgettracermanager.Conf{
 			NodeName:            node,
 			HookMode:            hookMode,
 			FallbackPodInformer: fallbackPodInformer,
+			WithoutBPF:          !withBPF,
+			NodeLabels:          splitNodeLabels(nodeLabels),
 		})
 
 		if err != nil {
@@ -272,8 +403,21 @@ func main() {
 		log.Printf("Serving on gRPC socket %s", socketfile)
 		go grpcServer.Serve(lis)
 
+		var kernelLog *kernellog.Log
+		if kernelLogSource != "" {
+			kernelLog, err = newKernelLog(kernelLogSource)
+			if err != nil {
+				log.Fatalf("failed to read the kernel log: %v", err)
+			}
+			defer kernelLog.Close()
+		}
+
 		if controller {
-			go startController(node, tracerManager)
+			go startController(node, tracerManager, metricsAddress, kernelLog, authorizeTraces, !withBPF)
+		}
+
+		if creatorWebhook != "" {
+			go serveCreatorWebhook()
 		}
 
 		exitSignal := make(chan os.Signal, 1)
@@ -283,3 +427,82 @@ func main() {
 		tracerManager.Close()
 	}
 }
+
+// newKernelLog starts reading the kernel log from the given source. The
+// journal is read with the journalctl of the host, mounted at /host in the
+// gadget pods.
+func newKernelLog(source string) (*kernellog.Log, error) {
+	var s kernellog.Source
+	var err error
+
+	switch source {
+	case "journal":
+		s, err = kernellog.NewJournalSource("/host")
+	case "kmsg":
+		s, err = kernellog.NewKmsgSource()
+	default:
+		return nil, fmt.Errorf("invalid kernel log source %q", source)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return kernellog.New(s), nil
+}
+
+// tracerMountNamespaces returns the mount namespaces of the containers
+// selected by a tracer, from the map pinned by the server.
+func tracerMountNamespaces(id string) (map[uint64]struct{}, error) {
+	m, err := ebpf.LoadPinnedMap(filepath.Join(gadgets.PinPath, gadgets.MountMapPrefix+id), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the mount namespaces of tracer %q: %w", id, err)
+	}
+	defer m.Close()
+
+	mntns := make(map[uint64]struct{})
+	var key uint64
+	var value uint32
+	iter := m.Iterate()
+	for iter.Next(&key, &value) {
+		mntns[key] = struct{}{}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read the mount namespaces of tracer %q: %w", id, err)
+	}
+
+	return mntns, nil
+}
+
+// serveCreatorWebhook serves the admission webhook recording the creator of
+// the traces, called by the API server through the gadget-creator-webhook
+// service.
+func serveCreatorWebhook() {
+	var proxies []string
+	if creatorProxies != "" {
+		proxies = strings.Split(creatorProxies, ",")
+	}
+
+	server := &http.Server{
+		Addr:              creatorWebhook,
+		Handler:           tracecreator.NewWebhook(proxies),
+		TLSConfig:         &tls.Config{MinVersion: tls.VersionTLS12},
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      30 * time.Second,
+	}
+
+	log.Printf("Serving the creator admission webhook on %s", creatorWebhook)
+	err := server.ListenAndServeTLS(filepath.Join(creatorWebhookCerts, "tls.crt"), filepath.Join(creatorWebhookCerts, "tls.key"))
+	log.Fatalf("failed to serve the creator admission webhook: %v", err)
+}
+
+// splitNodeLabels splits the value of -node-labels.
+func splitNodeLabels(value string) []string {
+	labels := []string{}
+	for _, label := range strings.Split(value, ",") {
+		if label = strings.TrimSpace(label); label != "" {
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
@@ -27,9 +27,10 @@ import (
 const (
 	K8sDistroARO        = "aro"
 	K8sDistroMinikubeGH = "minikube-github"
+	K8sDistroKind       = "kind"
 )
 
-var supportedK8sDistros = []string{K8sDistroARO, K8sDistroMinikubeGH}
+var supportedK8sDistros = []string{K8sDistroARO, K8sDistroMinikubeGH, K8sDistroKind}
 
 var (
 	integration = flag.Bool("integration", false, "run integration tests")
@@ -43,6 +44,8 @@ var (
 	k8sDistro = flag.String("k8s-distro", "", "allows to skip tests that are not supported on a given Kubernetes distribution")
 
 	skipNoCORE = flag.Bool("skip-no-co-re", false, "skip tests which do not have a CO-RE version")
+
+	doNotVerifyCleanup = flag.Bool("no-verify-cleanup", false, "don't fail the tests leaving namespaces, traces, tracers or pinned maps behind")
 )
 
 func runCommands(cmds []*command, t *testing.T) {
@@ -91,7 +94,13 @@ func testMain(m *testing.M) int {
 	}
 
 	if *image != "" {
-		os.Setenv("GADGET_IMAGE_FLAG", "--image "+*image)
+		imageFlag := "--image " + *image
+		if *k8sDistro == K8sDistroKind {
+			// The image is loaded in the nodes with "kind load
+			// docker-image", it isn't necessarily in a registry.
+			imageFlag += " --image-pull-policy=Never"
+		}
+		os.Setenv("GADGET_IMAGE_FLAG", imageFlag)
 	}
 
 	if *k8sDistro != "" {
@@ -110,6 +119,13 @@ func testMain(m *testing.M) int {
 		}
 	}
 
+	if *k8sDistro == K8sDistroKind {
+		// The nodes of kind are containers running on the kernel of
+		// the host, which doesn't have headers for it in them, so only
+		// the CO-RE gadgets work there.
+		*skipNoCORE = true
+	}
+
 	seed := time.Now().UTC().UnixNano()
 	rand.Seed(seed)
 	fmt.Printf("using random seed: %d\n", seed)
@@ -162,12 +178,54 @@ func TestMain(m *testing.M) {
 	os.Exit(testMain(m))
 }
 
+func TestAuditNetns(t *testing.T) {
+	ns := newTestNamespace(t, "test-audit-netns")
+
+	t.Parallel()
+
+	auditNetnsCmd := &command{
+		name:           "Start audit-netns gadget",
+		cmd:            fmt.Sprintf("$KUBECTL_GADGET audit netns -n %s", ns),
+		expectedRegexp: fmt.Sprintf(`%s\s+test-pod\s+test-pod\s+host-namespaces\s+.*network`, ns),
+		startAndStop:   true,
+	}
+
+	commands := []*command{
+		createTestNamespaceCommand(ns),
+		auditNetnsCmd,
+		{
+			name: "Run test pod with hostNetwork",
+			cmd: fmt.Sprintf(`
+				kubectl apply -f - <<EOF
+apiVersion: v1
+kind: Pod
+metadata:
+  name: test-pod
+  namespace: %s
+spec:
+  hostNetwork: true
+  restartPolicy: Never
+  containers:
+  - name: test-pod
+    image: busybox
+    command: ["sleep", "inf"]
+EOF
+			`, ns),
+			expectedRegexp: "pod/test-pod created",
+		},
+		waitUntilTestPodReadyCommand(ns),
+		deleteTestNamespaceCommand(ns),
+	}
+
+	runCommands(commands, t)
+}
+
 func TestAuditSeccomp(t *testing.T) {
 	if *k8sDistro == K8sDistroARO {
 		t.Skip("Skip running audit-seccomp gadget on ARO: see issue #631")
 	}
 
-	ns := generateTestNamespaceName("test-audit-seccomp")
+	ns := newTestNamespace(t, "test-audit-seccomp")
 
 	t.Parallel()
 
@@ -235,8 +293,54 @@ EOF
 	runCommands(commands, t)
 }
 
+func TestBashreadline(t *testing.T) {
+	ns := newTestNamespace(t, "test-bashreadline")
+
+	t.Parallel()
+
+	// busybox doesn't have bash. An interactive bash reads its commands
+	// with readline() even when its standard input isn't a terminal.
+	bashreadlineCmd := &command{
+		name:           "Start bashreadline gadget",
+		cmd:            fmt.Sprintf("$KUBECTL_GADGET trace bashreadline -n %s", ns),
+		expectedRegexp: fmt.Sprintf(`%s\s+test-pod\s+test-pod\s+\d+\s+0\s+bash\s+echo test-bashreadline`, ns),
+		startAndStop:   true,
+	}
+
+	commands := []*command{
+		createTestNamespaceCommand(ns),
+		bashreadlineCmd,
+		{
+			name: "Run test pod typing commands in bash",
+			cmd: fmt.Sprintf(`
+				kubectl apply -f - <<EOF
+apiVersion: v1
+kind: Pod
+metadata:
+  name: test-pod
+  namespace: %s
+spec:
+  restartPolicy: Never
+  terminationGracePeriodSeconds: 0
+  containers:
+  - name: test-pod
+    image: debian:bullseye-slim
+    command: ["/bin/sh", "-c"]
+    args:
+    - while true; do echo 'echo test-bashreadline' | bash -i; sleep 0.1; done
+EOF
+			`, ns),
+			expectedRegexp: "pod/test-pod created",
+		},
+		waitUntilTestPodReadyCommand(ns),
+		deleteTestNamespaceCommand(ns),
+	}
+
+	runCommands(commands, t)
+}
+
 func TestBindsnoop(t *testing.T) {
-	ns := generateTestNamespaceName("test-bindsnoop")
+	ns := newTestNamespace(t, "test-bindsnoop")
 
 	t.Parallel()
 
@@ -259,10 +363,6 @@ func TestBindsnoop(t *testing.T) {
 }
 
 func TestBiolatency(t *testing.T) {
-	if *skipNoCORE {
-		t.Skip("'profile block-io' does not have a CO-RE version")
-	}
-
 	t.Parallel()
 
 	commands := []*command{
@@ -276,12 +376,61 @@ func TestBiolatency(t *testing.T) {
 	runCommands(commands, t)
 }
 
+func TestBiolatencyPerContainer(t *testing.T) {
+	ns := newTestNamespace(t, "test-biolatency")
+
+	t.Parallel()
+
+	// oflag=direct makes dd write the data to the disk itself instead of
+	// leaving it to the kernel writeback threads.
+	biolatencyCmd := &command{
+		name:           "Run biolatency gadget per container",
+		cmd:            fmt.Sprintf("id=$($KUBECTL_GADGET profile block-io start -n %s --per-container --node $(kubectl get pod -n %s test-pod -o jsonpath='{.spec.nodeName}')); sleep 15; $KUBECTL_GADGET profile block-io stop $id", ns, ns),
+		expectedRegexp: fmt.Sprintf(`container = %s/test-pod/test-pod\s+usecs\s+:\s+count\s+distribution`, ns),
+	}
+
+	commands := []*command{
+		createTestNamespaceCommand(ns),
+		busyboxPodRepeatCommand(ns, "dd if=/dev/zero of=/tmp/test bs=4096 count=256 oflag=direct"),
+		waitUntilTestPodReadyCommand(ns),
+		biolatencyCmd,
+		deleteTestNamespaceCommand(ns),
+	}
+
+	runCommands(commands, t)
+}
+
+func TestBiosnoop(t *testing.T) {
+	ns := newTestNamespace(t, "test-biosnoop")
+
+	t.Parallel()
+
+	// conv=fsync makes dd write the data to the disk itself instead of
+	// leaving it to the kernel writeback threads.
+	biosnoopCmd := &command{
+		name:           "Start biosnoop gadget",
+		cmd:            fmt.Sprintf("$KUBECTL_GADGET trace block-io -n %s", ns),
+		expectedRegexp: fmt.Sprintf(`%s\s+test-pod\s+test-pod\s+\d+\s+dd\s+write\s+\d+:\d+\s+\d+\s+\d+`, ns),
+		startAndStop:   true,
+	}
+
+	commands := []*command{
+		createTestNamespaceCommand(ns),
+		biosnoopCmd,
+		busyboxPodRepeatCommand(ns, "dd if=/dev/zero of=/tmp/test bs=4096 count=256 conv=fsync"),
+		waitUntilTestPodReadyCommand(ns),
+		deleteTestNamespaceCommand(ns),
+	}
+
+	runCommands(commands, t)
+}
+
 func TestBiotop(t *testing.T) {
 	if *k8sDistro == K8sDistroARO {
 		t.Skip("Skip running biotop gadget on ARO: see issue #589")
 	}
 
-	ns := generateTestNamespaceName("test-biotop")
+	ns := newTestNamespace(t, "test-biotop")
 
 	t.Parallel()
 
@@ -303,12 +452,35 @@ func TestBiotop(t *testing.T) {
 	runCommands(commands, t)
 }
 
+func TestCachestat(t *testing.T) {
+	ns := newTestNamespace(t, "test-cachestat")
+
+	t.Parallel()
+
+	cachestatCmd := &command{
+		name:           "Start cachestat gadget",
+		cmd:            fmt.Sprintf("$KUBECTL_GADGET top cache -n %s", ns),
+		expectedRegexp: fmt.Sprintf(`%s\s+test-pod\s+test-pod\s+\d+\s+\d+\s+\d+\s+\S+`, ns),
+		startAndStop:   true,
+	}
+
+	commands := []*command{
+		createTestNamespaceCommand(ns),
+		cachestatCmd,
+		busyboxPodRepeatCommand(ns, "cat /bin/busybox > /dev/null"),
+		waitUntilTestPodReadyCommand(ns),
+		deleteTestNamespaceCommand(ns),
+	}
+
+	runCommands(commands, t)
+}
+
 func TestCapabilities(t *testing.T) {
 	if *skipNoCORE {
 		t.Skip("'trace capabilities' does not have a CO-RE version")
 	}
 
-	ns := generateTestNamespaceName("test-capabilities")
+	ns := newTestNamespace(t, "test-capabilities")
 
 	t.Parallel()
 
@@ -330,8 +502,31 @@ func TestCapabilities(t *testing.T) {
 	runCommands(commands, t)
 }
 
+func TestDcstat(t *testing.T) {
+	ns := newTestNamespace(t, "test-dcstat")
+
+	t.Parallel()
+
+	dcstatCmd := &command{
+		name:           "Start dcstat gadget",
+		cmd:            fmt.Sprintf("$KUBECTL_GADGET top dcache -n %s", ns),
+		expectedRegexp: fmt.Sprintf(`%s\s+test-pod\s+test-pod\s+\d+\s+\d+\s+\d+\s+\d+\.\d%%`, ns),
+		startAndStop:   true,
+	}
+
+	commands := []*command{
+		createTestNamespaceCommand(ns),
+		dcstatCmd,
+		busyboxPodRepeatCommand(ns, "find / > /dev/null 2>&1"),
+		waitUntilTestPodReadyCommand(ns),
+		deleteTestNamespaceCommand(ns),
+	}
+
+	runCommands(commands, t)
+}
+
 func TestDns(t *testing.T) {
-	ns := generateTestNamespaceName("test-dns")
+	ns := newTestNamespace(t, "test-dns")
 
 	t.Parallel()
 
@@ -354,7 +549,7 @@ func TestDns(t *testing.T) {
 }
 
 func TestExecsnoop(t *testing.T) {
-	ns := generateTestNamespaceName("test-execsnoop")
+	ns := newTestNamespace(t, "test-execsnoop")
 
 	t.Parallel()
 
@@ -377,7 +572,7 @@ func TestExecsnoop(t *testing.T) {
 }
 
 func TestFiletop(t *testing.T) {
-	ns := generateTestNamespaceName("test-filetop")
+	ns := newTestNamespace(t, "test-filetop")
 
 	t.Parallel()
 
@@ -399,13 +594,36 @@ func TestFiletop(t *testing.T) {
 	runCommands(commands, t)
 }
 
+func TestFstop(t *testing.T) {
+	ns := newTestNamespace(t, "test-fstop")
+
+	t.Parallel()
+
+	fstopCmd := &command{
+		name:           "Start fstop gadget",
+		cmd:            fmt.Sprintf("$KUBECTL_GADGET top fs -n %s", ns),
+		expectedRegexp: fmt.Sprintf(`%s\s+test-pod\s+test-pod\s+\d+\s+\d+\s+\d+\s+\d+\s+\d+\s+\d+`, ns),
+		startAndStop:   true,
+	}
+
+	commands := []*command{
+		createTestNamespaceCommand(ns),
+		fstopCmd,
+		busyboxPodRepeatCommand(ns, "echo date >> /tmp/date.txt && sync"),
+		waitUntilTestPodReadyCommand(ns),
+		deleteTestNamespaceCommand(ns),
+	}
+
+	runCommands(commands, t)
+}
+
 func TestFsslower(t *testing.T) {
 	fsType := "ext4"
 	if *k8sDistro == K8sDistroARO {
 		fsType = "xfs"
 	}
 
-	ns := generateTestNamespaceName("test-fsslower")
+	ns := newTestNamespace(t, "test-fsslower")
 
 	t.Parallel()
 
@@ -427,8 +645,116 @@ func TestFsslower(t *testing.T) {
 	runCommands(commands, t)
 }
 
+func TestHardirqs(t *testing.T) {
+	t.Parallel()
+
+	commands := []*command{
+		{
+			name:           "Run hardirqs gadget",
+			cmd:            "id=$($KUBECTL_GADGET profile hardirqs start --node $(kubectl get node --no-headers | cut -d' ' -f1 | head -1)); sleep 15; $KUBECTL_GADGET profile hardirqs stop $id",
+			expectedRegexp: `HARDIRQ\s+COUNT\s+TOTAL\s+MAX\s+CPU%`,
+		},
+	}
+
+	runCommands(commands, t)
+}
+
+func TestGethostlatency(t *testing.T) {
+	ns := newTestNamespace(t, "test-gethostlatency")
+
+	t.Parallel()
+
+	// The busybox image is statically linked, the resolutions are done
+	// with the C library of a Debian image.
+	gethostlatencyCmd := &command{
+		name:           "Start gethostlatency gadget",
+		cmd:            fmt.Sprintf("$KUBECTL_GADGET trace gethostlatency -n %s", ns),
+		expectedRegexp: fmt.Sprintf(`%s\s+test-pod\s+test-pod\s+\d+\s+getent\s+getaddrinfo\s+\d+\.\d+\s+EAI_NONAME\s+nonexistent.invalid`, ns),
+		startAndStop:   true,
+	}
+
+	commands := []*command{
+		createTestNamespaceCommand(ns),
+		gethostlatencyCmd,
+		{
+			name: "Run test pod resolving host names",
+			cmd: fmt.Sprintf(`
+				kubectl apply -f - <<EOF
+apiVersion: v1
+kind: Pod
+metadata:
+  name: test-pod
+  namespace: %s
+spec:
+  restartPolicy: Never
+  terminationGracePeriodSeconds: 0
+  containers:
+  - name: test-pod
+    image: debian:bullseye-slim
+    command: ["/bin/sh", "-c"]
+    args:
+    - while true; do getent ahosts nonexistent.invalid; sleep 0.1; done
+EOF
+			`, ns),
+			expectedRegexp: "pod/test-pod created",
+		},
+		waitUntilTestPodReadyCommand(ns),
+		deleteTestNamespaceCommand(ns),
+	}
+
+	runCommands(commands, t)
+}
+
+func TestHttpsnoop(t *testing.T) {
+	ns := newTestNamespace(t, "test-httpsnoop")
+
+	t.Parallel()
+
+	httpsnoopCmd := &command{
+		name:           "Start httpsnoop gadget",
+		cmd:            fmt.Sprintf("$KUBECTL_GADGET trace http -n %s --ports 80", ns),
+		expectedRegexp: `test-pod\s+\S+:80\s+GET\s+\d{3}\s+\S+\s+kinvolk.io/`,
+		startAndStop:   true,
+	}
+
+	commands := []*command{
+		createTestNamespaceCommand(ns),
+		httpsnoopCmd,
+		busyboxPodRepeatCommand(ns, "wget -q -O /dev/null http://kinvolk.io/"),
+		waitUntilTestPodReadyCommand(ns),
+		deleteTestNamespaceCommand(ns),
+	}
+
+	runCommands(commands, t)
+}
+
+func TestMemleak(t *testing.T) {
+	ns := newTestNamespace(t, "test-memleak")
+
+	t.Parallel()
+
+	// The busybox image is statically linked, so only the kernel
+	// allocations of the test pod are tracked.
+	memleakCmd := &command{
+		name: "Run memleak gadget",
+		cmd: fmt.Sprintf("id=$($KUBECTL_GADGET profile memleak start --mode kernel -n %s --node $(kubectl get pod -n %s test-pod -o jsonpath='{.spec.nodeName}')); sleep 15; $KUBECTL_GADGET profile memleak report $id; $KUBECTL_GADGET profile memleak stop $id",
+			ns, ns),
+		expectedRegexp: `Report 2 of node \S+: \d+ bytes in \d+ allocations not freed yet`,
+	}
+
+	commands := []*command{
+		createTestNamespaceCommand(ns),
+		busyboxPodRepeatCommand(ns, "cat /proc/self/status"),
+		waitUntilTestPodReadyCommand(ns),
+		memleakCmd,
+		deleteTestNamespaceCommand(ns),
+	}
+
+	runCommands(commands, t)
+}
+
 func TestMountsnoop(t *testing.T) {
-	ns := generateTestNamespaceName("test-mountsnoop")
+	ns := newTestNamespace(t, "test-mountsnoop")
 
 	t.Parallel()
 
@@ -450,8 +776,31 @@ func TestMountsnoop(t *testing.T) {
 	runCommands(commands, t)
 }
 
+func TestNetdrops(t *testing.T) {
+	ns := newTestNamespace(t, "test-netdrops")
+
+	t.Parallel()
+
+	netdropsCmd := &command{
+		name:           "Start netdrops gadget",
+		cmd:            fmt.Sprintf("$KUBECTL_GADGET trace netdrops -n %s", ns),
+		expectedRegexp: fmt.Sprintf(`%s\s+test-pod\s+\S+\s+received\s+\S+\s+\d+\s+\d+\s+\d+`, ns),
+		startAndStop:   true,
+	}
+
+	commands := []*command{
+		createTestNamespaceCommand(ns),
+		netdropsCmd,
+		busyboxPodRepeatCommand(ns, "echo hello | nc -u -w 1 127.0.0.1 4242"),
+		waitUntilTestPodReadyCommand(ns),
+		deleteTestNamespaceCommand(ns),
+	}
+
+	runCommands(commands, t)
+}
+
 func TestNetworkpolicy(t *testing.T) {
-	ns := generateTestNamespaceName("test-networkpolicy")
+	ns := newTestNamespace(t, "test-networkpolicy")
 
 	t.Parallel()
 
@@ -471,7 +820,7 @@ func TestNetworkpolicy(t *testing.T) {
 }
 
 func TestOomkill(t *testing.T) {
-	ns := generateTestNamespaceName("test-oomkill")
+	ns := newTestNamespace(t, "test-oomkill")
 
 	t.Parallel()
 
@@ -516,7 +865,7 @@ spec:
 }
 
 func TestOpensnoop(t *testing.T) {
-	ns := generateTestNamespaceName("test-opensnoop")
+	ns := newTestNamespace(t, "test-opensnoop")
 
 	t.Parallel()
 
@@ -538,12 +887,35 @@ func TestOpensnoop(t *testing.T) {
 	runCommands(commands, t)
 }
 
+func TestPing(t *testing.T) {
+	ns := newTestNamespace(t, "test-ping")
+
+	t.Parallel()
+
+	pingCmd := &command{
+		name:           "Start ping gadget",
+		cmd:            fmt.Sprintf("$KUBECTL_GADGET trace ping -n %s", ns),
+		expectedRegexp: `test-pod\s+out\s+127.0.0.1\s+127.0.0.1\s+\d+\s+\d+\s+\d+\.\d+\s+ok`,
+		startAndStop:   true,
+	}
+
+	commands := []*command{
+		createTestNamespaceCommand(ns),
+		pingCmd,
+		busyboxPodRepeatCommand(ns, "ping -c 1 127.0.0.1"),
+		waitUntilTestPodReadyCommand(ns),
+		deleteTestNamespaceCommand(ns),
+	}
+
+	runCommands(commands, t)
+}
+
 func TestProcessCollector(t *testing.T) {
 	if *k8sDistro == K8sDistroARO {
 		t.Skip("Skip running process-collector gadget on ARO: iterators are not supported on kernel 4.18.0-305.19.1.el8_4.x86_64")
 	}
 
-	ns := generateTestNamespaceName("test-process-collector")
+	ns := newTestNamespace(t, "test-process-collector")
 
 	t.Parallel()
 
@@ -567,7 +939,7 @@ func TestProfile(t *testing.T) {
 		t.Skip("'profile cpu' does not have a CO-RE version")
 	}
 
-	ns := generateTestNamespaceName("test-profile")
+	ns := newTestNamespace(t, "test-profile")
 
 	t.Parallel()
 
@@ -586,8 +958,69 @@ func TestProfile(t *testing.T) {
 	runCommands(commands, t)
 }
 
+func TestQosCollector(t *testing.T) {
+	ns := newTestNamespace(t, "test-qos-collector")
+
+	t.Parallel()
+
+	// Whether the traffic is shaped depends on the CNI: only check that
+	// the pod and the host side interface are reported.
+	commands := []*command{
+		createTestNamespaceCommand(ns),
+		busyboxPodCommand(ns, "sleep inf"),
+		waitUntilTestPodReadyCommand(ns),
+		{
+			name:           "Run qos-collector gadget",
+			cmd:            fmt.Sprintf("$KUBECTL_GADGET snapshot qos -n %s", ns),
+			expectedRegexp: fmt.Sprintf(`%s\s+test-pod\s+\S+\s+`, ns),
+		},
+		deleteTestNamespaceCommand(ns),
+	}
+
+	runCommands(commands, t)
+}
+
+func TestRunqlat(t *testing.T) {
+	t.Parallel()
+
+	commands := []*command{
+		{
+			name:           "Run runqlat gadget",
+			cmd:            "id=$($KUBECTL_GADGET profile runqlat start --node $(kubectl get node --no-headers | cut -d' ' -f1 | head -1)); sleep 15; $KUBECTL_GADGET profile runqlat stop $id",
+			expectedRegexp: `usecs\s+:\s+count\s+distribution`,
+		},
+	}
+
+	runCommands(commands, t)
+}
+
+func TestRunqslower(t *testing.T) {
+	ns := newTestNamespace(t, "test-runqslower")
+
+	t.Parallel()
+
+	// Two busy loops on a single CPU keep each other waiting on its run
+	// queue.
+	runqslowerCmd := &command{
+		name:           "Start runqslower gadget",
+		cmd:            fmt.Sprintf("$KUBECTL_GADGET trace runqslower -n %s -m 1000", ns),
+		expectedRegexp: fmt.Sprintf(`%s\s+test-pod\s+test-pod\s+\d+\s+sh\s+\d+`, ns),
+		startAndStop:   true,
+	}
+
+	commands := []*command{
+		createTestNamespaceCommand(ns),
+		runqslowerCmd,
+		busyboxPodCommand(ns, "taskset -p 1 $$ && (while true; do :; done &) && while true; do :; done"),
+		waitUntilTestPodReadyCommand(ns),
+		deleteTestNamespaceCommand(ns),
+	}
+
+	runCommands(commands, t)
+}
+
 func TestSeccompadvisor(t *testing.T) {
-	ns := generateTestNamespaceName("test-seccomp-advisor")
+	ns := newTestNamespace(t, "test-seccomp-advisor")
 
 	t.Parallel()
 
@@ -607,7 +1040,7 @@ func TestSeccompadvisor(t *testing.T) {
 }
 
 func TestSigsnoop(t *testing.T) {
-	ns := generateTestNamespaceName("test-sigsnoop")
+	ns := newTestNamespace(t, "test-sigsnoop")
 
 	t.Parallel()
 
@@ -630,7 +1063,7 @@ func TestSigsnoop(t *testing.T) {
 }
 
 func TestSnisnoop(t *testing.T) {
-	ns := generateTestNamespaceName("test-snisnoop")
+	ns := newTestNamespace(t, "test-snisnoop")
 
 	t.Parallel()
 
@@ -657,7 +1090,7 @@ func TestSocketCollector(t *testing.T) {
 		t.Skip("Skip running socket-collector gadget on ARO: iterators are not supported on kernel 4.18.0-305.19.1.el8_4.x86_64")
 	}
 
-	ns := generateTestNamespaceName("test-socket-collector")
+	ns := newTestNamespace(t, "test-socket-collector")
 
 	t.Parallel()
 
@@ -676,8 +1109,141 @@ func TestSocketCollector(t *testing.T) {
 	runCommands(commands, t)
 }
 
+func TestSoftirqs(t *testing.T) {
+	t.Parallel()
+
+	commands := []*command{
+		{
+			name:           "Run softirqs gadget",
+			cmd:            "id=$($KUBECTL_GADGET profile softirqs start --node $(kubectl get node --no-headers | cut -d' ' -f1 | head -1)); sleep 15; $KUBECTL_GADGET profile softirqs stop $id",
+			expectedRegexp: `SOFTIRQ\s+COUNT\s+TOTAL\s+MAX\s+CPU%\s+timer\s+\d+`,
+		},
+	}
+
+	runCommands(commands, t)
+}
+
+func TestSolisten(t *testing.T) {
+	ns := newTestNamespace(t, "test-solisten")
+
+	t.Parallel()
+
+	solistenCmd := &command{
+		name:           "Start solisten gadget",
+		cmd:            fmt.Sprintf("$KUBECTL_GADGET trace listen -n %s", ns),
+		expectedRegexp: fmt.Sprintf(`%s\s+test-pod\s+test-pod\s+\d+\s+nc\s+TCP\s+9090\s+\d+\s+`, ns),
+		startAndStop:   true,
+	}
+
+	commands := []*command{
+		createTestNamespaceCommand(ns),
+		solistenCmd,
+		busyboxPodRepeatCommand(ns, "timeout 1 nc -l -p 9090 ; true"),
+		waitUntilTestPodReadyCommand(ns),
+		deleteTestNamespaceCommand(ns),
+	}
+
+	runCommands(commands, t)
+}
+
+func TestStatsnoop(t *testing.T) {
+	ns := newTestNamespace(t, "test-statsnoop")
+
+	t.Parallel()
+
+	statsnoopCmd := &command{
+		name:           "Start statsnoop gadget",
+		cmd:            fmt.Sprintf("$KUBECTL_GADGET trace stat -n %s --failed-only", ns),
+		expectedRegexp: fmt.Sprintf(`%s\s+test-pod\s+test-pod\s+\d+\s+stat\s+\w+\s+2\s+/nonexistent`, ns),
+		startAndStop:   true,
+	}
+
+	commands := []*command{
+		createTestNamespaceCommand(ns),
+		statsnoopCmd,
+		busyboxPodRepeatCommand(ns, "stat /nonexistent"),
+		waitUntilTestPodReadyCommand(ns),
+		deleteTestNamespaceCommand(ns),
+	}
+
+	runCommands(commands, t)
+}
+
+func TestStealtop(t *testing.T) {
+	ns := newTestNamespace(t, "test-stealtop")
+
+	t.Parallel()
+
+	// The steal time depends on the hypervisor, only check that the
+	// CPU time of the pod is reported.
+	stealtopCmd := &command{
+		name:           "Start stealtop gadget",
+		cmd:            fmt.Sprintf("$KUBECTL_GADGET top steal -n %s --sort cputime", ns),
+		expectedRegexp: fmt.Sprintf(`%s\s+test-pod\s+test-pod\s+\d+\s+\d+\s+\d+\.\d\s+\d+\.\d`, ns),
+		startAndStop:   true,
+	}
+
+	commands := []*command{
+		createTestNamespaceCommand(ns),
+		stealtopCmd,
+		busyboxPodRepeatCommand(ns, "cat /bin/busybox > /dev/null"),
+		waitUntilTestPodReadyCommand(ns),
+		deleteTestNamespaceCommand(ns),
+	}
+
+	runCommands(commands, t)
+}
+
+func TestSuspiciousExec(t *testing.T) {
+	ns := newTestNamespace(t, "test-suspicious-exec")
+
+	t.Parallel()
+
+	// busybox runs the applet named after the executable: /tmp/true
+	// runs true.
+	suspiciousExecCmd := &command{
+		name:           "Start suspicious-exec gadget",
+		cmd:            fmt.Sprintf("$KUBECTL_GADGET trace suspicious-exec -n %s", ns),
+		expectedRegexp: fmt.Sprintf(`%s\s+test-pod\s+test-pod\s+\d+\s+\d+\s+\d+\s+true\s+world-writable-dir\S*\s+/tmp/true`, ns),
+		startAndStop:   true,
+	}
+
+	commands := []*command{
+		createTestNamespaceCommand(ns),
+		suspiciousExecCmd,
+		busyboxPodRepeatCommand(ns, "cp /bin/busybox /tmp/true && /tmp/true"),
+		waitUntilTestPodReadyCommand(ns),
+		deleteTestNamespaceCommand(ns),
+	}
+
+	runCommands(commands, t)
+}
+
+func TestSyncsnoop(t *testing.T) {
+	ns := newTestNamespace(t, "test-syncsnoop")
+
+	t.Parallel()
+
+	syncsnoopCmd := &command{
+		name:           "Start syncsnoop gadget",
+		cmd:            fmt.Sprintf("$KUBECTL_GADGET trace sync -n %s", ns),
+		expectedRegexp: fmt.Sprintf(`%s\s+test-pod\s+test-pod\s+\d+\s+dd\s+fsync\s+\d+\.\d+\s+0\s+data`, ns),
+		startAndStop:   true,
+	}
+
+	commands := []*command{
+		createTestNamespaceCommand(ns),
+		syncsnoopCmd,
+		busyboxPodRepeatCommand(ns, "dd if=/dev/zero of=/tmp/data bs=4k count=1 conv=fsync"),
+		waitUntilTestPodReadyCommand(ns),
+		deleteTestNamespaceCommand(ns),
+	}
+
+	runCommands(commands, t)
+}
+
 func TestTcpconnect(t *testing.T) {
-	ns := generateTestNamespaceName("test-tcpconnect")
+	ns := newTestNamespace(t, "test-tcpconnect")
 
 	t.Parallel()
 
@@ -699,12 +1265,104 @@ func TestTcpconnect(t *testing.T) {
 	runCommands(commands, t)
 }
 
+func TestTcpdrop(t *testing.T) {
+	ns := newTestNamespace(t, "test-tcpdrop")
+
+	t.Parallel()
+
+	tcpdropCmd := &command{
+		name:           "Start tcpdrop gadget",
+		cmd:            fmt.Sprintf("$KUBECTL_GADGET trace tcpdrop -n %s", ns),
+		expectedRegexp: fmt.Sprintf(`%s\s+test-pod\s+test-pod\s+4\s+127\.0\.0\.1\s+\d+\s+127\.0\.0\.1\s+9\s+`, ns),
+		startAndStop:   true,
+	}
+
+	commands := []*command{
+		createTestNamespaceCommand(ns),
+		tcpdropCmd,
+		busyboxPodRepeatCommand(ns, "nc 127.0.0.1 9"),
+		waitUntilTestPodReadyCommand(ns),
+		deleteTestNamespaceCommand(ns),
+	}
+
+	runCommands(commands, t)
+}
+
+func TestTcplife(t *testing.T) {
+	ns := newTestNamespace(t, "test-tcplife")
+
+	t.Parallel()
+
+	tcplifeCmd := &command{
+		name:           "Start tcplife gadget",
+		cmd:            fmt.Sprintf("$KUBECTL_GADGET trace tcplife -n %s", ns),
+		expectedRegexp: fmt.Sprintf(`%s\s+test-pod\s+test-pod\s+\d+\s+wget\s+4\s+\S+\s+\d+\s+1\.1\.1\.1\s+80`, ns),
+		startAndStop:   true,
+	}
+
+	commands := []*command{
+		createTestNamespaceCommand(ns),
+		tcplifeCmd,
+		busyboxPodRepeatCommand(ns, "wget -q -O /dev/null -T 3 http://1.1.1.1"),
+		waitUntilTestPodReadyCommand(ns),
+		deleteTestNamespaceCommand(ns),
+	}
+
+	runCommands(commands, t)
+}
+
+func TestTcpretrans(t *testing.T) {
+	ns := newTestNamespace(t, "test-tcpretrans")
+
+	t.Parallel()
+
+	tcpretransCmd := &command{
+		name:           "Start tcpretrans gadget",
+		cmd:            fmt.Sprintf("$KUBECTL_GADGET trace tcpretrans -n %s", ns),
+		expectedRegexp: fmt.Sprintf(`%s\s+test-pod\s+test-pod\s+4\s+\S+\s+\d+\s+10\.255\.255\.1\s+80\s+SYN_SENT`, ns),
+		startAndStop:   true,
+	}
+
+	commands := []*command{
+		createTestNamespaceCommand(ns),
+		tcpretransCmd,
+		busyboxPodRepeatCommand(ns, "nc -w 5 10.255.255.1 80"),
+		waitUntilTestPodReadyCommand(ns),
+		deleteTestNamespaceCommand(ns),
+	}
+
+	runCommands(commands, t)
+}
+
+func TestTcpsynbl(t *testing.T) {
+	ns := newTestNamespace(t, "test-tcpsynbl")
+
+	t.Parallel()
+
+	tcpsynblCmd := &command{
+		name:           "Start tcpsynbl gadget",
+		cmd:            fmt.Sprintf("$KUBECTL_GADGET top syn-backlog -n %s", ns),
+		expectedRegexp: fmt.Sprintf(`%s\s+test-pod\s+test-pod\s+[46]\s+9090\s+\d+\s+0\s+\d+\s+\d+\s+\d+%%`, ns),
+		startAndStop:   true,
+	}
+
+	commands := []*command{
+		createTestNamespaceCommand(ns),
+		tcpsynblCmd,
+		busyboxPodCommand(ns, "nc -lk -p 9090 -e true & while true; do nc -w 1 127.0.0.1 9090 < /dev/null; sleep 0.1; done"),
+		waitUntilTestPodReadyCommand(ns),
+		deleteTestNamespaceCommand(ns),
+	}
+
+	runCommands(commands, t)
+}
+
 func TestTcptracer(t *testing.T) {
 	if *skipNoCORE {
 		t.Skip("'trace tcp' does not have a CO-RE version")
 	}
 
-	ns := generateTestNamespaceName("test-tcptracer")
+	ns := newTestNamespace(t, "test-tcptracer")
 
 	t.Parallel()
 
@@ -727,7 +1385,7 @@ func TestTcptracer(t *testing.T) {
 }
 
 func TestTcptop(t *testing.T) {
-	ns := generateTestNamespaceName("test-tcptop")
+	ns := newTestNamespace(t, "test-tcptop")
 
 	t.Parallel()
 
@@ -750,7 +1408,7 @@ func TestTcptop(t *testing.T) {
 }
 
 func TestTraceloop(t *testing.T) {
-	ns := generateTestNamespaceName("test-traceloop")
+	ns := newTestNamespace(t, "test-traceloop")
 
 	t.Parallel()
 
@@ -803,3 +1461,72 @@ func TestTraceloop(t *testing.T) {
 
 	runCommands(commands, t)
 }
+
+func TestUdpsnoop(t *testing.T) {
+	ns := newTestNamespace(t, "test-udpsnoop")
+
+	t.Parallel()
+
+	udpsnoopCmd := &command{
+		name:           "Start udpsnoop gadget",
+		cmd:            fmt.Sprintf("$KUBECTL_GADGET trace udpsnoop -n %s", ns),
+		expectedRegexp: fmt.Sprintf(`%s\s+test-pod\s+test-pod\s+\d+\s+nslookup\s+send\s+\d\s+\S+\s+\d+\s+\S+\s+53\s+\d+`, ns),
+		startAndStop:   true,
+	}
+
+	commands := []*command{
+		createTestNamespaceCommand(ns),
+		udpsnoopCmd,
+		busyboxPodRepeatCommand(ns, "nslookup microsoft.com"),
+		waitUntilTestPodReadyCommand(ns),
+		deleteTestNamespaceCommand(ns),
+	}
+
+	runCommands(commands, t)
+}
+
+func TestUdptop(t *testing.T) {
+	ns := newTestNamespace(t, "test-udptop")
+
+	t.Parallel()
+
+	udptopCmd := &command{
+		name:           "Start udptop gadget",
+		cmd:            fmt.Sprintf("$KUBECTL_GADGET top udp -n %s", ns),
+		expectedRegexp: fmt.Sprintf(`%s\s+test-pod\s+test-pod\s+\d+\s+nslookup\s+\d\s+\d+\s+\d+\s+\d+\s+[1-9]\d*`, ns),
+		startAndStop:   true,
+	}
+
+	commands := []*command{
+		createTestNamespaceCommand(ns),
+		udptopCmd,
+		busyboxPodRepeatCommand(ns, "nslookup microsoft.com"),
+		waitUntilTestPodReadyCommand(ns),
+		deleteTestNamespaceCommand(ns),
+	}
+
+	runCommands(commands, t)
+}
+
+func TestVfsstat(t *testing.T) {
+	ns := newTestNamespace(t, "test-vfsstat")
+
+	t.Parallel()
+
+	vfsstatCmd := &command{
+		name:           "Start vfsstat gadget",
+		cmd:            fmt.Sprintf("$KUBECTL_GADGET top vfs -n %s", ns),
+		expectedRegexp: fmt.Sprintf(`%s\s+test-pod\s+test-pod\s+\d+\s+\d+\s+\d+\s+\d+\s+\d+\s+\d+\s+\d+`, ns),
+		startAndStop:   true,
+	}
+
+	commands := []*command{
+		createTestNamespaceCommand(ns),
+		vfsstatCmd,
+		busyboxPodRepeatCommand(ns, "mkdir /tmp/d && echo data > /tmp/d/f && rm -r /tmp/d"),
+		waitUntilTestPodReadyCommand(ns),
+		deleteTestNamespaceCommand(ns),
+	}
+
+	runCommands(commands, t)
+}
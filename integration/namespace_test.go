@@ -0,0 +1,229 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// leakCheckTimeout is how long the resources of a test can take to be
+// removed after it finished before they are reported as leaked.
+const leakCheckTimeout = 30 * time.Second
+
+// namespacePool gives unique namespaces to the tests running in parallel
+// and verifies that no resource is left behind once each of them finished.
+type namespacePool struct {
+	mu sync.Mutex
+
+	// namespaces are the namespaces given to the running tests.
+	namespaces map[string]struct{}
+
+	// reportedPins are the stale pinned maps already reported by a test,
+	// to report them only once: they can't be linked to a namespace.
+	reportedPins map[string]struct{}
+}
+
+var testNamespaces = &namespacePool{
+	namespaces:   make(map[string]struct{}),
+	reportedPins: make(map[string]struct{}),
+}
+
+// newTestNamespace returns a namespace name for a test, unique among the
+// running tests. Unless -no-verify-cleanup is given, the test fails if the
+// namespace, the traces filtering it, their tracers or pinned maps remain
+// once the test finished.
+func newTestNamespace(t *testing.T, prefix string) string {
+	ns := testNamespaces.get(prefix)
+
+	t.Cleanup(func() {
+		defer testNamespaces.release(ns)
+
+		if *doNotVerifyCleanup {
+			return
+		}
+		if err := testNamespaces.verifyCleanup(ns); err != nil {
+			t.Errorf("Test leaked resources: %s", err)
+		}
+	})
+
+	return ns
+}
+
+func (p *namespacePool) get(prefix string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for {
+		ns := generateTestNamespaceName(prefix)
+		if _, ok := p.namespaces[ns]; !ok {
+			p.namespaces[ns] = struct{}{}
+			return ns
+		}
+	}
+}
+
+func (p *namespacePool) release(ns string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.namespaces, ns)
+}
+
+// verifyCleanup waits for the resources of the test using the namespace to
+// be removed and returns an error listing the ones still there after
+// leakCheckTimeout.
+func (p *namespacePool) verifyCleanup(ns string) error {
+	var leaks []string
+	var err error
+
+	deadline := time.Now().Add(leakCheckTimeout)
+	for {
+		leaks, err = p.findLeaks(ns)
+		if err == nil && len(leaks) == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(2 * time.Second)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to verify the cleanup of namespace %q: %w", ns, err)
+	}
+
+	p.mu.Lock()
+	for _, leak := range leaks {
+		if strings.HasPrefix(leak, "stale pinned map ") {
+			p.reportedPins[leak] = struct{}{}
+		}
+	}
+	p.mu.Unlock()
+
+	return fmt.Errorf("namespace %q:\n%s\n%s", ns, strings.Join(leaks, "\n"), getInspektorGadgetLogs())
+}
+
+// findLeaks returns the resources left by the test using the namespace.
+func (p *namespacePool) findLeaks(ns string) ([]string, error) {
+	leaks := []string{}
+
+	out, err := exec.Command("kubectl", "get", "ns", ns, "--ignore-not-found", "-o", "name").Output()
+	if err != nil {
+		return nil, fmt.Errorf("getting namespace: %w", err)
+	}
+	if strings.TrimSpace(string(out)) != "" {
+		leaks = append(leaks, fmt.Sprintf("namespace %s", ns))
+	}
+
+	// The state of the nodes is read before the traces: a tracer found on
+	// a node while its trace doesn't exist anymore is leaked.
+	pods, err := exec.Command("kubectl", "get", "pod", "-n", "gadget", "-l", "k8s-app=gadget", "-o", "name").Output()
+	if err != nil {
+		return nil, fmt.Errorf("listing gadget pods: %w", err)
+	}
+	for _, pod := range strings.Fields(string(pods)) {
+		dump, err := exec.Command("kubectl", "exec", "-n", "gadget", pod, "--",
+			"gadgettracermanager", "-dump").Output()
+		if err != nil {
+			return nil, fmt.Errorf("dumping state of %s: %w", pod, err)
+		}
+
+		tracers, stalePins, err := parseDump(string(dump), ns)
+		if err != nil {
+			return nil, fmt.Errorf("parsing state of %s: %w", pod, err)
+		}
+		for _, tracer := range tracers {
+			leaks = append(leaks, fmt.Sprintf("tracer %s on %s", tracer, pod))
+		}
+
+		p.mu.Lock()
+		for _, pin := range stalePins {
+			leak := fmt.Sprintf("stale pinned map %s on %s", pin, pod)
+			if _, ok := p.reportedPins[leak]; !ok {
+				leaks = append(leaks, leak)
+			}
+		}
+		p.mu.Unlock()
+	}
+
+	out, err = exec.Command("kubectl", "get", "traces", "-n", "gadget", "-o", "json").Output()
+	if err != nil {
+		return nil, fmt.Errorf("listing traces: %w", err)
+	}
+	var traces struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+			Spec struct {
+				Filter *struct {
+					Namespace string `json:"namespace"`
+				} `json:"filter"`
+			} `json:"spec"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(out, &traces); err != nil {
+		return nil, fmt.Errorf("decoding traces: %w", err)
+	}
+	for _, trace := range traces.Items {
+		if trace.Spec.Filter != nil && trace.Spec.Filter.Namespace == ns {
+			leaks = append(leaks, fmt.Sprintf("trace %s", trace.Metadata.Name))
+		}
+	}
+
+	return leaks, nil
+}
+
+// tracerLineRegexp matches the lines of the tracers in the output of
+// gadgettracermanager -dump, e.g. `trace_gadget_foo -> "ns"/"pod" (name) Labels:`.
+var tracerLineRegexp = regexp.MustCompile(`^(\S+) -> "([^"]*)"/`)
+
+// parseDump returns the tracers selecting the namespace and the stale
+// pinned maps found in the output of gadgettracermanager -dump.
+func parseDump(dump, ns string) (tracers, stalePins []string, err error) {
+	section := ""
+
+	for _, line := range strings.Split(dump, "\n") {
+		if strings.HasPrefix(line, "List of ") || strings.HasSuffix(line, "metrics:") {
+			section = line
+			continue
+		}
+
+		switch section {
+		case "List of tracers:":
+			m := tracerLineRegexp.FindStringSubmatch(line)
+			if m != nil && m[2] == ns {
+				tracers = append(tracers, m[1])
+			}
+		case "List of stale pinned maps:":
+			line = strings.TrimSpace(line)
+			if strings.HasPrefix(line, "Error: ") {
+				return nil, nil, fmt.Errorf("listing stale pinned maps: %s", strings.TrimPrefix(line, "Error: "))
+			}
+			if line != "" {
+				stalePins = append(stalePins, line)
+			}
+		}
+	}
+
+	return tracers, stalePins, nil
+}
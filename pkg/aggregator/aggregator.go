@@ -0,0 +1,407 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package aggregator runs the snapshot and advisor gadgets on all the nodes
+// and merges their results, to serve them through a read-only HTTP API.
+package aggregator
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	clientset "github.com/kinvolk/inspektor-gadget/pkg/client/clientset/versioned"
+	"github.com/kinvolk/inspektor-gadget/pkg/operationqueue"
+	"github.com/kinvolk/inspektor-gadget/pkg/snapshotcache"
+)
+
+const (
+	gadgetNamespace = "gadget"
+
+	// gadgetOperation is the annotation telling the gadget pods the
+	// operation to apply on a trace.
+	gadgetOperation = "gadget.kinvolk.io/operation"
+
+	// creator and creatorGroups record the user the traces are created
+	// for, for the gadget pods deployed with the authorization of the
+	// traces. The admission webhook recording the creator of the traces
+	// keeps them for the service account of the aggregator only.
+	creator       = "gadget.kinvolk.io/creator"
+	creatorGroups = "gadget.kinvolk.io/creator-groups"
+
+	// aggregatorID is the label identifying the traces created for a
+	// request, like the global-trace-id label of kubectl gadget.
+	aggregatorID = "gadget-aggregator-id"
+
+	pollInterval = 500 * time.Millisecond
+
+	// maxCachedOutputs bounds the number of outputs of the nodes kept to
+	// be reused when they didn't change.
+	maxCachedOutputs = 256
+)
+
+// Request describes a gadget to run on the nodes.
+type Request struct {
+	// Gadget is the name of the gadget, e.g. process-collector.
+	Gadget string
+
+	// Advisor is true for the gadgets started, running for Duration, and
+	// then stopped to get their results. The other gadgets are collected.
+	Advisor  bool
+	Duration time.Duration
+
+	// Node restricts the request to a node, all the nodes running a
+	// gadget pod if empty.
+	Node string
+
+	Filter     *gadgetv1alpha1.ContainerFilter
+	Parameters map[string]string
+
+	// User is the user of the API the gadget is run for, if
+	// authenticated, recorded as the creator of the traces.
+	User *authenticationv1.UserInfo
+}
+
+// Result is the results of all the nodes merged.
+type Result struct {
+	// Items are the entries of the results of the nodes, e.g. the
+	// processes for the process-collector gadget.
+	Items []json.RawMessage `json:"items"`
+
+	// Errors are the errors of the nodes whose results are missing.
+	Errors []NodeError `json:"errors,omitempty"`
+}
+
+// NodeError is the error of a node.
+type NodeError struct {
+	Node  string `json:"node"`
+	Error string `json:"error"`
+}
+
+// Aggregator runs the gadgets on the nodes by creating a trace for each
+// one, like kubectl gadget does.
+type Aggregator struct {
+	client      kubernetes.Interface
+	traceClient clientset.Interface
+
+	// timeout is how long the traces can take to reach a state.
+	timeout time.Duration
+
+	// readOutput reads the outputs stored on the nodes.
+	readOutput OutputReader
+
+	// outputs are the last outputs of the snapshots on each node: the
+	// gadget pods leave them out of the traces when they didn't change.
+	outputs *snapshotcache.Cache
+}
+
+// New returns an aggregator waiting up to timeout for the gadgets to run on
+// the nodes.
+func New(client kubernetes.Interface, traceClient clientset.Interface, timeout time.Duration) *Aggregator {
+	return &Aggregator{
+		client:      client,
+		traceClient: traceClient,
+		timeout:     timeout,
+		outputs:     snapshotcache.New(maxCachedOutputs),
+	}
+}
+
+// SetOutputReader sets the reader of the outputs too large for the Trace
+// status, stored on the nodes. Without it, these outputs are reported as
+// errors.
+func (a *Aggregator) SetOutputReader(readOutput OutputReader) {
+	a.readOutput = readOutput
+}
+
+// Run runs the gadget on the nodes and returns their merged results. The
+// traces are always deleted before returning.
+func (a *Aggregator) Run(ctx context.Context, req *Request) (*Result, error) {
+	nodes, err := a.nodes(ctx, req.Node)
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 {
+		if req.Node != "" {
+			return nil, fmt.Errorf("no gadget pod running on node %q", req.Node)
+		}
+		return nil, fmt.Errorf("no gadget pod running")
+	}
+
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("generating request ID: %w", err)
+	}
+	id := fmt.Sprintf("%x", b)
+	defer a.deleteTraces(id)
+
+	operation, state := "collect", "Completed"
+	if req.Advisor {
+		operation, state = "start", "Started"
+	}
+
+	if err := a.createTraces(ctx, id, nodes, req, operation); err != nil {
+		return nil, err
+	}
+
+	traces, err := a.waitForState(ctx, id, state)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Advisor {
+		select {
+		case <-time.After(req.Duration):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		for _, trace := range traces {
+			if trace.Status.State != "Started" {
+				continue
+			}
+			if err := a.setOperation(ctx, trace.Name, "stop"); err != nil {
+				return nil, fmt.Errorf("stopping trace on node %q: %w", trace.Spec.Node, err)
+			}
+		}
+
+		state = "Completed"
+		traces, err = a.waitForState(ctx, id, state)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	a.loadStoredOutputs(ctx, traces)
+	if !req.Advisor {
+		a.reuseOutputs(req, traces)
+	}
+
+	return mergeResults(nodes, traces, state), nil
+}
+
+// nodes returns the nodes running a gadget pod.
+func (a *Aggregator) nodes(ctx context.Context, node string) ([]string, error) {
+	opts := metav1.ListOptions{LabelSelector: "k8s-app=gadget"}
+	if node != "" {
+		opts.FieldSelector = "spec.nodeName=" + node
+	}
+
+	pods, err := a.client.CoreV1().Pods(gadgetNamespace).List(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("listing gadget pods: %w", err)
+	}
+
+	nodes := []string{}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodRunning || pod.Spec.NodeName == "" ||
+			(node != "" && pod.Spec.NodeName != node) {
+			continue
+		}
+		nodes = append(nodes, pod.Spec.NodeName)
+	}
+	sort.Strings(nodes)
+
+	return nodes, nil
+}
+
+func (a *Aggregator) createTraces(ctx context.Context, id string, nodes []string, req *Request, operation string) error {
+	for _, node := range nodes {
+		trace := &gadgetv1alpha1.Trace{
+			ObjectMeta: metav1.ObjectMeta{
+				GenerateName: req.Gadget + "-",
+				Namespace:    gadgetNamespace,
+				Annotations: map[string]string{
+					gadgetOperation: operation,
+				},
+				Labels: map[string]string{
+					aggregatorID: id,
+					"gadgetName": req.Gadget,
+					"nodeName":   node,
+				},
+			},
+			Spec: gadgetv1alpha1.TraceSpec{
+				Node:       node,
+				Gadget:     req.Gadget,
+				Filter:     req.Filter,
+				RunMode:    "Manual",
+				OutputMode: "Status",
+				Parameters: req.Parameters,
+			},
+		}
+
+		if !req.Advisor {
+			for k, v := range a.outputs.Annotations(outputKey(req, node)) {
+				trace.ObjectMeta.Annotations[k] = v
+			}
+		}
+
+		if req.User != nil {
+			trace.ObjectMeta.Annotations[creator] = req.User.Username
+			if len(req.User.Groups) > 0 {
+				trace.ObjectMeta.Annotations[creatorGroups] = strings.Join(req.User.Groups, ",")
+			}
+		}
+
+		_, err := a.traceClient.GadgetV1alpha1().Traces(gadgetNamespace).Create(ctx, trace, metav1.CreateOptions{})
+		if err != nil {
+			return fmt.Errorf("creating trace on node %q: %w", node, err)
+		}
+	}
+
+	return nil
+}
+
+// outputKey identifies the output of a node for a request. The outputs
+// aren't shared between the users: the gadget pods can check their
+// permissions.
+func outputKey(req *Request, node string) string {
+	filter, _ := json.Marshal(req.Filter)
+	parameters, _ := json.Marshal(req.Parameters)
+	user := ""
+	if req.User != nil {
+		user = req.User.Username
+	}
+
+	return strings.Join([]string{user, req.Gadget, node, string(filter), string(parameters)}, " ")
+}
+
+// reuseOutputs sets the output of the traces whose gadget pod left it out
+// because it didn't change since the previous request, and caches the
+// others.
+func (a *Aggregator) reuseOutputs(req *Request, traces []gadgetv1alpha1.Trace) {
+	for i := range traces {
+		trace := &traces[i]
+		if trace.Status.OperationError != "" || trace.Status.State != "Completed" {
+			continue
+		}
+
+		if !a.outputs.Update(outputKey(req, trace.Spec.Node), trace) {
+			// The next request runs the gadget without the
+			// annotation.
+			trace.Status.OperationError = "output not modified but not cached anymore"
+		}
+	}
+}
+
+func (a *Aggregator) setOperation(ctx context.Context, name, operation string) error {
+	return operationqueue.Append(ctx, a.traceClient, gadgetNamespace, name, operation)
+}
+
+// waitForState waits for the traces of the request to be in the given
+// state or to fail. It returns them as they are on timeout, the nodes
+// whose trace is still in another state being reported in the errors.
+func (a *Aggregator) waitForState(ctx context.Context, id, state string) ([]gadgetv1alpha1.Trace, error) {
+	ctx, cancel := context.WithTimeout(ctx, a.timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		list, err := a.traceClient.GadgetV1alpha1().Traces(gadgetNamespace).List(
+			ctx, metav1.ListOptions{LabelSelector: aggregatorID + "=" + id},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("listing traces: %w", err)
+		}
+
+		done := true
+		for _, trace := range list.Items {
+			if trace.Status.OperationError != "" {
+				continue
+			}
+
+			// The operations are removed from the annotations once
+			// they are applied.
+			_, pending := trace.Annotations[gadgetOperation]
+			if pending || operationqueue.Pending(trace.Annotations) || trace.Status.State != state {
+				done = false
+				break
+			}
+		}
+		if done {
+			return list.Items, nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				return list.Items, nil
+			}
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (a *Aggregator) deleteTraces(id string) {
+	// The traces are deleted even if the request was canceled.
+	ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+	defer cancel()
+
+	a.traceClient.GadgetV1alpha1().Traces(gadgetNamespace).DeleteCollection(
+		ctx, metav1.DeleteOptions{}, metav1.ListOptions{LabelSelector: aggregatorID + "=" + id},
+	)
+}
+
+// mergeResults concatenates the entries of the output of the traces that
+// reached the state, the output being a JSON array. An error is reported
+// for the other nodes.
+func mergeResults(nodes []string, traces []gadgetv1alpha1.Trace, state string) *Result {
+	result := &Result{
+		Items: []json.RawMessage{},
+	}
+
+	byNode := make(map[string]*gadgetv1alpha1.Trace, len(traces))
+	for i := range traces {
+		byNode[traces[i].Spec.Node] = &traces[i]
+	}
+
+	for _, node := range nodes {
+		trace, ok := byNode[node]
+		switch {
+		case !ok:
+			result.Errors = append(result.Errors, NodeError{Node: node, Error: "trace not found"})
+		case trace.Status.OperationError != "":
+			result.Errors = append(result.Errors, NodeError{Node: node, Error: trace.Status.OperationError})
+		case trace.Status.State != state:
+			result.Errors = append(result.Errors, NodeError{
+				Node:  node,
+				Error: fmt.Sprintf("timed out waiting for the trace to be %s", state),
+			})
+		case trace.Status.Output != "":
+			var items []json.RawMessage
+			if err := json.Unmarshal([]byte(trace.Status.Output), &items); err != nil {
+				result.Errors = append(result.Errors, NodeError{
+					Node:  node,
+					Error: fmt.Sprintf("invalid output: %s", err),
+				})
+				continue
+			}
+			result.Items = append(result.Items, items...)
+		}
+	}
+
+	return result
+}
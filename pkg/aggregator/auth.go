@@ -0,0 +1,155 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aggregator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Authorizer only lets the requests of the users allowed to get their path
+// reach the server. The users are authenticated by their bearer token, e.g.
+// the token of a service account, checked with a TokenReview, and their
+// permission is checked with a SubjectAccessReview on the non-resource URL,
+// i.e. granted with a ClusterRole like:
+//
+//	rules:
+//	- nonResourceURLs: ["/v1", "/v1/*"]
+//	  verbs: ["get"]
+type Authorizer struct {
+	client  kubernetes.Interface
+	handler http.Handler
+}
+
+// NewAuthorizer returns an authorizer checking the requests with client
+// before giving them to handler. The health check isn't authenticated.
+func NewAuthorizer(client kubernetes.Interface, handler http.Handler) *Authorizer {
+	return &Authorizer{
+		client:  client,
+		handler: handler,
+	}
+}
+
+func (a *Authorizer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/healthz" {
+		a.handler.ServeHTTP(w, r)
+		return
+	}
+
+	token := bearerToken(r)
+	if token == "" {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="gadget-aggregator"`)
+		writeError(w, http.StatusUnauthorized, errors.New("missing bearer token"))
+		return
+	}
+
+	user, err := a.authenticate(r, token)
+	if err != nil {
+		log.Errorf("authenticating request: %s", err)
+		writeError(w, http.StatusInternalServerError, errors.New("failed to authenticate the request"))
+		return
+	}
+	if user == nil {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="gadget-aggregator"`)
+		writeError(w, http.StatusUnauthorized, errors.New("invalid bearer token"))
+		return
+	}
+
+	allowed, err := a.authorize(r, user)
+	if err != nil {
+		log.Errorf("authorizing request of %q: %s", user.Username, err)
+		writeError(w, http.StatusInternalServerError, errors.New("failed to authorize the request"))
+		return
+	}
+	if !allowed {
+		writeError(w, http.StatusForbidden, fmt.Errorf("user %q cannot get path %q", user.Username, r.URL.Path))
+		return
+	}
+
+	a.handler.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey{}, user)))
+}
+
+type userKey struct{}
+
+// requestUser returns the user authenticated by the Authorizer for the
+// request, or nil.
+func requestUser(r *http.Request) *authenticationv1.UserInfo {
+	user, _ := r.Context().Value(userKey{}).(*authenticationv1.UserInfo)
+	return user
+}
+
+func bearerToken(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	const prefix = "Bearer "
+	if len(auth) <= len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return ""
+	}
+	return strings.TrimSpace(auth[len(prefix):])
+}
+
+// authenticate returns the user of the token, or nil if the token isn't
+// valid.
+func (a *Authorizer) authenticate(r *http.Request, token string) (*authenticationv1.UserInfo, error) {
+	review := &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	}
+
+	review, err := a.client.AuthenticationV1().TokenReviews().Create(r.Context(), review, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if !review.Status.Authenticated {
+		return nil, nil
+	}
+
+	return &review.Status.User, nil
+}
+
+// authorize tells if user can get the path of the request.
+func (a *Authorizer) authorize(r *http.Request, user *authenticationv1.UserInfo) (bool, error) {
+	extra := make(map[string]authorizationv1.ExtraValue, len(user.Extra))
+	for k, v := range user.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   user.Username,
+			UID:    user.UID,
+			Groups: user.Groups,
+			Extra:  extra,
+			NonResourceAttributes: &authorizationv1.NonResourceAttributes{
+				Path: r.URL.Path,
+				Verb: "get",
+			},
+		},
+	}
+
+	review, err := a.client.AuthorizationV1().SubjectAccessReviews().Create(r.Context(), review, metav1.CreateOptions{})
+	if err != nil {
+		return false, err
+	}
+
+	return review.Status.Allowed, nil
+}
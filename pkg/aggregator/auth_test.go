@@ -0,0 +1,148 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aggregator
+
+import (
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestAuthorizer(t *testing.T) {
+	client := kubefake.NewSimpleClientset()
+
+	// "reader-token" is the token of "reader", allowed to get /v1/*, and
+	// "other-token" the one of "other", allowed to get nothing.
+	client.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview).DeepCopy()
+		switch review.Spec.Token {
+		case "reader-token":
+			review.Status = authenticationv1.TokenReviewStatus{
+				Authenticated: true,
+				User:          authenticationv1.UserInfo{Username: "reader", Groups: []string{"readers"}},
+			}
+		case "other-token":
+			review.Status = authenticationv1.TokenReviewStatus{
+				Authenticated: true,
+				User:          authenticationv1.UserInfo{Username: "other"},
+			}
+		}
+		return true, review, nil
+	})
+	client.PrependReactor("create", "subjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview).DeepCopy()
+		attrs := review.Spec.NonResourceAttributes
+		review.Status.Allowed = review.Spec.User == "reader" && len(review.Spec.Groups) == 1 &&
+			attrs != nil && attrs.Verb == "get" && attrs.Path == "/v1/snapshot/process"
+		return true, review, nil
+	})
+
+	handler := NewAuthorizer(client, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" {
+			if user := requestUser(r); user == nil || user.Username != "reader" {
+				t.Errorf("unexpected user %+v given to the server", user)
+			}
+		}
+		w.Write([]byte("served"))
+	}))
+
+	table := []struct {
+		description string
+		path        string
+		token       string
+		status      int
+	}{
+		{
+			description: "health check",
+			path:        "/healthz",
+			status:      http.StatusOK,
+		},
+		{
+			description: "no token",
+			path:        "/v1/snapshot/process",
+			status:      http.StatusUnauthorized,
+		},
+		{
+			description: "invalid token",
+			path:        "/v1/snapshot/process",
+			token:       "invalid",
+			status:      http.StatusUnauthorized,
+		},
+		{
+			description: "allowed",
+			path:        "/v1/snapshot/process",
+			token:       "reader-token",
+			status:      http.StatusOK,
+		},
+		{
+			description: "other path",
+			path:        "/v1/snapshot/socket",
+			token:       "reader-token",
+			status:      http.StatusForbidden,
+		},
+		{
+			description: "other user",
+			path:        "/v1/snapshot/process",
+			token:       "other-token",
+			status:      http.StatusForbidden,
+		},
+	}
+
+	for _, entry := range table {
+		req := httptest.NewRequest(http.MethodGet, entry.path, nil)
+		if entry.token != "" {
+			req.Header.Set("Authorization", "Bearer "+entry.token)
+		}
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != entry.status {
+			t.Fatalf("%s: got status %d, expected %d: %s", entry.description, rec.Code, entry.status, rec.Body.String())
+		}
+		if entry.status == http.StatusOK && rec.Body.String() != "served" {
+			t.Fatalf("%s: the request wasn't served", entry.description)
+		}
+		if entry.status == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+			t.Fatalf("%s: missing WWW-Authenticate header", entry.description)
+		}
+	}
+}
+
+func TestSelfSignedCertificate(t *testing.T) {
+	cert, err := SelfSignedCertificate([]string{"gadget-aggregator.gadget.svc"}, time.Hour)
+	if err != nil {
+		t.Fatalf("generating certificate: %s", err)
+	}
+
+	parsed, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("parsing certificate: %s", err)
+	}
+	if err := parsed.VerifyHostname("gadget-aggregator.gadget.svc"); err != nil {
+		t.Fatalf("verifying hostname: %s", err)
+	}
+	if time.Until(parsed.NotAfter) > time.Hour {
+		t.Fatalf("certificate valid until %s, expected at most an hour", parsed.NotAfter)
+	}
+}
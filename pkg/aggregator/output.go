@@ -0,0 +1,114 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aggregator
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+)
+
+// OutputReader reads the output of a trace stored on its node because it
+// was too large for the Trace status.
+type OutputReader func(ctx context.Context, trace *gadgetv1alpha1.Trace) ([]byte, error)
+
+// NewExecOutputReader returns an OutputReader running
+// "gadgettracermanager -call read-output" in the gadget pod of the node of
+// the trace, like kubectl gadget does.
+func NewExecOutputReader(config *rest.Config, client kubernetes.Interface) OutputReader {
+	return func(ctx context.Context, trace *gadgetv1alpha1.Trace) ([]byte, error) {
+		pods, err := client.CoreV1().Pods(gadgetNamespace).List(ctx, metav1.ListOptions{
+			LabelSelector: "k8s-app=gadget",
+			FieldSelector: "spec.nodeName=" + trace.Spec.Node + ",status.phase=Running",
+		})
+		if err != nil {
+			return nil, fmt.Errorf("listing gadget pods: %w", err)
+		}
+		if len(pods.Items) != 1 {
+			return nil, fmt.Errorf("found %d gadget pods running on the node", len(pods.Items))
+		}
+
+		req := client.CoreV1().RESTClient().Post().
+			Resource("pods").
+			Name(pods.Items[0].Name).
+			Namespace(gadgetNamespace).
+			SubResource("exec").
+			VersionedParams(&corev1.PodExecOptions{
+				Container: "gadget",
+				Command: []string{
+					"gadgettracermanager", "-call", "read-output",
+					"-tracerid", fmt.Sprintf("trace_%s_%s", trace.Namespace, trace.Name),
+				},
+				Stdout: true,
+				Stderr: true,
+			}, scheme.ParameterCodec)
+
+		exec, err := remotecommand.NewSPDYExecutor(config, "POST", req.URL())
+		if err != nil {
+			return nil, err
+		}
+
+		var stdout, stderr bytes.Buffer
+		err = exec.Stream(remotecommand.StreamOptions{
+			Stdout: &stdout,
+			Stderr: &stderr,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", err, stderr.String())
+		}
+
+		return stdout.Bytes(), nil
+	}
+}
+
+// loadStoredOutputs sets the output of the traces stored on their node
+// with the reader. The traces are the copies listed by the aggregator:
+// the output is only loaded in them, and the traces whose output can't be
+// read get an error, reported for their node by mergeResults.
+func (a *Aggregator) loadStoredOutputs(ctx context.Context, traces []gadgetv1alpha1.Trace) {
+	for i := range traces {
+		trace := &traces[i]
+		if trace.Status.OutputRef == nil || trace.Status.OperationError != "" {
+			continue
+		}
+
+		if a.readOutput == nil {
+			trace.Status.OperationError = fmt.Sprintf("output of %d bytes too large for the Trace, stored on the node",
+				trace.Status.OutputRef.Size)
+			continue
+		}
+
+		output, err := a.readOutput(ctx, trace)
+		switch {
+		case err != nil:
+			trace.Status.OperationError = fmt.Sprintf("reading the output stored on the node: %s", err)
+		case int64(len(output)) != trace.Status.OutputRef.Size:
+			trace.Status.OperationError = fmt.Sprintf("read %d bytes of the output stored on the node, expected %d",
+				len(output), trace.Status.OutputRef.Size)
+		default:
+			trace.Status.Output = string(output)
+			trace.Status.OutputRef = nil
+		}
+	}
+}
@@ -0,0 +1,340 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aggregator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	"github.com/kinvolk/inspektor-gadget/pkg/snapshotcache"
+)
+
+const (
+	// DefaultDuration is how long the advisors run when the duration
+	// isn't given.
+	DefaultDuration = 30 * time.Second
+
+	// MaxDuration is the maximum duration accepted for the advisors.
+	MaxDuration = 10 * time.Minute
+
+	// maxConcurrentRuns bounds the number of gadgets run at the same time,
+	// the other requests waiting for their turn.
+	maxConcurrentRuns = 4
+)
+
+// endpoint describes a gadget served by the API.
+type endpoint struct {
+	gadget  string
+	advisor bool
+
+	// parameters are the parameters of the gadget accepted in the query.
+	parameters []string
+}
+
+// endpoints are the gadgets served, by path below /v1/.
+var endpoints = map[string]endpoint{
+	"snapshot/process": {gadget: "process-collector"},
+	"snapshot/socket":  {gadget: "socket-collector", parameters: []string{"protocol"}},
+	"snapshot/cgroups": {gadget: "cgroup-collector"},
+	"advise/resource-limits": {
+		gadget:     "resource-limits",
+		advisor:    true,
+		parameters: []string{"interval"},
+	},
+	"advise/sidecar-injection": {
+		gadget:     "sidecar-injection",
+		advisor:    true,
+		parameters: []string{"interval", "mesh"},
+	},
+}
+
+// filterParameters are the query parameters selecting the containers.
+var filterParameters = []string{"node", "namespace", "pod", "container"}
+
+type cacheEntry struct {
+	result  *Result
+	expires time.Time
+}
+
+// Server serves the results of the snapshots and advisors aggregated from
+// all the nodes. It's read-only: the traces it creates are deleted once
+// their results are retrieved.
+type Server struct {
+	aggregator *Aggregator
+
+	// cacheTTL is how long the results of a snapshot are reused for the
+	// same request, to avoid running the gadgets on all the nodes each
+	// time a dashboard is refreshed.
+	cacheTTL time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+
+	runs chan struct{}
+}
+
+// NewServer returns a server running the gadgets with aggregator.
+func NewServer(aggregator *Aggregator, cacheTTL time.Duration) *Server {
+	return &Server{
+		aggregator: aggregator,
+		cacheTTL:   cacheTTL,
+		cache:      make(map[string]cacheEntry),
+		runs:       make(chan struct{}, maxConcurrentRuns),
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/healthz" {
+		w.Write([]byte("ok"))
+		return
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+
+	if r.URL.Path == "/v1" || r.URL.Path == "/v1/" {
+		writeJSON(w, http.StatusOK, map[string][]string{"endpoints": endpointPaths()})
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/v1/")
+	e, ok := endpoints[path]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown endpoint %q", r.URL.Path))
+		return
+	}
+
+	req, err := parseRequest(e, r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// The results are recorded as created by the user of the request,
+	// so that the gadget pods deployed with the authorization of the
+	// traces check its permissions: they can't be shared with other users.
+	req.User = requestUser(r)
+	key := path + "?" + r.URL.Query().Encode()
+	if req.User != nil {
+		key = req.User.Username + " " + key
+	}
+	if result := s.cached(key); result != nil {
+		writeResult(w, r, result)
+		return
+	}
+
+	result, err := s.run(r.Context(), req)
+	if err != nil {
+		log.Errorf("running %s: %s", req.Gadget, err)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// The advisors aren't cached: the client chose how long to observe.
+	if !req.Advisor {
+		s.store(key, result)
+	}
+
+	writeResult(w, r, result)
+}
+
+func (s *Server) run(ctx context.Context, req *Request) (*Result, error) {
+	select {
+	case s.runs <- struct{}{}:
+		defer func() { <-s.runs }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	return s.aggregator.Run(ctx, req)
+}
+
+func (s *Server) cached(key string) *Result {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.cache[key]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(entry.expires) {
+		delete(s.cache, key)
+		return nil
+	}
+
+	return entry.result
+}
+
+func (s *Server) store(key string, result *Result) {
+	if s.cacheTTL <= 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for k, entry := range s.cache {
+		if now.After(entry.expires) {
+			delete(s.cache, k)
+		}
+	}
+
+	s.cache[key] = cacheEntry{
+		result:  result,
+		expires: now.Add(s.cacheTTL),
+	}
+}
+
+// parseRequest returns the request to run the gadget of the endpoint with
+// the given query parameters.
+func parseRequest(e endpoint, query url.Values) (*Request, error) {
+	req := &Request{
+		Gadget:  e.gadget,
+		Advisor: e.advisor,
+	}
+
+	accepted := map[string]bool{}
+	for _, name := range filterParameters {
+		accepted[name] = true
+	}
+	for _, name := range e.parameters {
+		accepted[name] = true
+	}
+	if e.advisor {
+		accepted["duration"] = true
+	}
+
+	for name, values := range query {
+		if !accepted[name] {
+			return nil, fmt.Errorf("unknown parameter %q", name)
+		}
+		if len(values) != 1 {
+			return nil, fmt.Errorf("parameter %q given %d times", name, len(values))
+		}
+	}
+
+	req.Node = query.Get("node")
+
+	if query.Get("namespace") != "" || query.Get("pod") != "" || query.Get("container") != "" {
+		req.Filter = &gadgetv1alpha1.ContainerFilter{
+			Namespace:     query.Get("namespace"),
+			Podname:       query.Get("pod"),
+			ContainerName: query.Get("container"),
+		}
+	}
+
+	for _, name := range e.parameters {
+		if value := query.Get(name); value != "" {
+			if req.Parameters == nil {
+				req.Parameters = map[string]string{}
+			}
+			req.Parameters[name] = value
+		}
+	}
+
+	if e.advisor {
+		req.Duration = DefaultDuration
+		if value := query.Get("duration"); value != "" {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid duration %q: %w", value, err)
+			}
+			if d <= 0 || d > MaxDuration {
+				return nil, fmt.Errorf("invalid duration %q: must be positive and at most %s", value, MaxDuration)
+			}
+			req.Duration = d
+		}
+	}
+
+	return req, nil
+}
+
+func endpointPaths() []string {
+	paths := make([]string, 0, len(endpoints))
+	for path := range endpoints {
+		paths = append(paths, "/v1/"+path)
+	}
+	sort.Strings(paths)
+
+	return paths
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("marshalling response: %w", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(b)
+}
+
+// writeResult writes the result with its content hash as ETag, or only
+// 304 Not Modified when it's the one given in If-None-Match: the client
+// already has it.
+func writeResult(w http.ResponseWriter, r *http.Request, result *Result) {
+	b, err := json.Marshal(result)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("marshalling response: %w", err))
+		return
+	}
+
+	etag := `"` + snapshotcache.ETag(string(b)) + `"`
+	w.Header().Set("ETag", etag)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+// etagMatches tells if etag is in the list of entity tags of an
+// If-None-Match header, compared weakly as required by RFC 7232.
+func etagMatches(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	b, _ := json.Marshal(map[string]string{"error": err.Error()})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(b)
+}
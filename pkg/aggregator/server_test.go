@@ -0,0 +1,455 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aggregator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	tracefake "github.com/kinvolk/inspektor-gadget/pkg/client/clientset/versioned/fake"
+	"github.com/kinvolk/inspektor-gadget/pkg/operationqueue"
+	"github.com/kinvolk/inspektor-gadget/pkg/snapshotcache"
+)
+
+func TestParseRequest(t *testing.T) {
+	table := []struct {
+		description string
+		path        string
+		query       string
+		expected    *Request
+	}{
+		{
+			description: "snapshot with filter",
+			path:        "snapshot/process",
+			query:       "namespace=default&pod=mypod",
+			expected: &Request{
+				Gadget: "process-collector",
+				Filter: &gadgetv1alpha1.ContainerFilter{Namespace: "default", Podname: "mypod"},
+			},
+		},
+		{
+			description: "snapshot with parameter",
+			path:        "snapshot/socket",
+			query:       "protocol=tcp&node=node-1",
+			expected: &Request{
+				Gadget:     "socket-collector",
+				Node:       "node-1",
+				Parameters: map[string]string{"protocol": "tcp"},
+			},
+		},
+		{
+			description: "advisor with default duration",
+			path:        "advise/resource-limits",
+			expected: &Request{
+				Gadget:   "resource-limits",
+				Advisor:  true,
+				Duration: DefaultDuration,
+			},
+		},
+		{
+			description: "advisor with duration",
+			path:        "advise/resource-limits",
+			query:       "duration=1m&interval=5",
+			expected: &Request{
+				Gadget:     "resource-limits",
+				Advisor:    true,
+				Duration:   time.Minute,
+				Parameters: map[string]string{"interval": "5"},
+			},
+		},
+		{
+			description: "unknown parameter",
+			path:        "snapshot/process",
+			query:       "sink_file=/tmp/foo",
+		},
+		{
+			description: "duration of a snapshot",
+			path:        "snapshot/process",
+			query:       "duration=1m",
+		},
+		{
+			description: "parameter given twice",
+			path:        "snapshot/process",
+			query:       "namespace=a&namespace=b",
+		},
+		{
+			description: "duration too long",
+			path:        "advise/resource-limits",
+			query:       "duration=1h",
+		},
+	}
+
+	for _, entry := range table {
+		query, err := url.ParseQuery(entry.query)
+		if err != nil {
+			t.Fatalf("%s: parsing query: %s", entry.description, err)
+		}
+
+		req, err := parseRequest(endpoints[entry.path], query)
+		if entry.expected == nil {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %+v", entry.description, req)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", entry.description, err)
+			continue
+		}
+		if !reflect.DeepEqual(req, entry.expected) {
+			t.Errorf("%s: expected %+v, got %+v", entry.description, entry.expected, req)
+		}
+	}
+}
+
+func gadgetPod(name, node string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: gadgetNamespace,
+			Labels:    map[string]string{"k8s-app": "gadget"},
+		},
+		Spec:   corev1.PodSpec{NodeName: node},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+}
+
+// runGadgetPods applies the operations on the traces like the gadget pods
+// do, the trace of node-2 failing.
+func runGadgetPods(ctx context.Context, client *tracefake.Clientset) {
+	traces := client.GadgetV1alpha1().Traces(gadgetNamespace)
+
+	for ctx.Err() == nil {
+		time.Sleep(10 * time.Millisecond)
+
+		list, err := traces.List(ctx, metav1.ListOptions{})
+		if err != nil {
+			continue
+		}
+
+		for _, trace := range list.Items {
+			op, ok := trace.Annotations[gadgetOperation]
+			if ok {
+				delete(trace.Annotations, gadgetOperation)
+			} else {
+				ops, _ := operationqueue.Parse(trace.Annotations)
+				if len(ops) == 0 {
+					continue
+				}
+				op = ops[0]
+				queue, _ := json.Marshal(ops[1:])
+				trace.Annotations[operationqueue.Annotation] = string(queue)
+			}
+
+			switch {
+			case trace.Spec.Node == "node-2":
+				trace.Status.OperationError = "gadget failed"
+			case op == "collect" || op == "stop":
+				trace.Status.State = "Completed"
+				trace.Status.Output = `[{"node":"` + trace.Spec.Node + `","op":"` + op + `"}]`
+				snapshotcache.SetETag(&trace)
+				if trace.Spec.Node == "node-3" && trace.Status.Output != "" {
+					// Stored on the node, read by storedOutputReader.
+					trace.Status.OutputRef = &gadgetv1alpha1.OutputReference{Size: int64(len(trace.Status.Output))}
+					trace.Status.Output = ""
+				}
+			case op == "start":
+				trace.Status.State = "Started"
+			}
+
+			traces.Update(ctx, &trace, metav1.UpdateOptions{})
+		}
+	}
+}
+
+// newTraceClient returns a fake trace client. The fake clientset generated
+// uses the "gadget" group for the traces, so it's set up with a scheme
+// registering them in this group. The objects created get a name from their
+// generated name and the traces can be deleted by label, like with the API
+// server.
+func newTraceClient() *tracefake.Clientset {
+	gv := schema.GroupVersion{Group: "gadget", Version: "v1alpha1"}
+	gvr := gv.WithResource("traces")
+
+	scheme := runtime.NewScheme()
+	scheme.AddKnownTypes(gv, &gadgetv1alpha1.Trace{}, &gadgetv1alpha1.TraceList{})
+	metav1.AddToGroupVersion(scheme, gv)
+	tracker := k8stesting.NewObjectTracker(scheme, serializer.NewCodecFactory(scheme).UniversalDecoder())
+
+	client := &tracefake.Clientset{}
+	client.AddReactor("*", "*", k8stesting.ObjectReaction(tracker))
+
+	created := 0
+	client.PrependReactor("create", "traces", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		trace := action.(k8stesting.CreateAction).GetObject().(*gadgetv1alpha1.Trace)
+		if trace.Name == "" {
+			created++
+			trace.Name = fmt.Sprintf("%s%d", trace.GenerateName, created)
+		}
+		return false, nil, nil
+	})
+
+	client.PrependReactor("delete-collection", "traces", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		selector := action.(k8stesting.DeleteCollectionAction).GetListRestrictions().Labels
+		list, err := tracker.List(gvr, gv.WithKind("Trace"), gadgetNamespace)
+		if err != nil {
+			return true, nil, err
+		}
+		for _, trace := range list.(*gadgetv1alpha1.TraceList).Items {
+			if selector.Matches(labels.Set(trace.Labels)) {
+				if err := tracker.Delete(gvr, gadgetNamespace, trace.Name); err != nil {
+					return true, nil, err
+				}
+			}
+		}
+		return true, nil, nil
+	})
+
+	return client
+}
+
+// storedOutputReader reads the outputs of the traces of node-3 as if they
+// were stored on the node.
+func storedOutputReader(ctx context.Context, trace *gadgetv1alpha1.Trace) ([]byte, error) {
+	op := "collect"
+	if trace.Labels["gadgetName"] == "resource-limits" {
+		op = "stop"
+	}
+	return []byte(`[{"node":"` + trace.Spec.Node + `","op":"` + op + `"}]`), nil
+}
+
+func TestStoredOutputs(t *testing.T) {
+	client := kubefake.NewSimpleClientset(gadgetPod("gadget-3", "node-3"))
+
+	traceClient := newTraceClient()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go runGadgetPods(ctx, traceClient)
+
+	agg := New(client, traceClient, 5*time.Second)
+	req := &Request{Gadget: "process-collector"}
+
+	result, err := agg.Run(ctx, req)
+	if err != nil {
+		t.Fatalf("running without output reader: %s", err)
+	}
+	expected := []NodeError{{Node: "node-3", Error: "output of 34 bytes too large for the Trace, stored on the node"}}
+	if len(result.Items) != 0 || !reflect.DeepEqual(result.Errors, expected) {
+		t.Fatalf("expected error %v without output reader, got %+v", expected, result)
+	}
+
+	agg.SetOutputReader(storedOutputReader)
+	result, err = agg.Run(ctx, req)
+	if err != nil {
+		t.Fatalf("running with output reader: %s", err)
+	}
+	items, _ := json.Marshal(result.Items)
+	if string(items) != `[{"node":"node-3","op":"collect"}]` || len(result.Errors) != 0 {
+		t.Fatalf("expected the stored output, got %s %v", items, result.Errors)
+	}
+
+	// A new aggregator, which doesn't have the output of node-3 cached
+	// yet, reads it again.
+	agg = New(client, traceClient, 5*time.Second)
+	agg.SetOutputReader(func(ctx context.Context, trace *gadgetv1alpha1.Trace) ([]byte, error) {
+		return []byte("[]"), nil
+	})
+	result, err = agg.Run(ctx, req)
+	if err != nil {
+		t.Fatalf("running with truncated output: %s", err)
+	}
+	expected = []NodeError{{Node: "node-3", Error: "read 2 bytes of the output stored on the node, expected 34"}}
+	if len(result.Items) != 0 || !reflect.DeepEqual(result.Errors, expected) {
+		t.Fatalf("expected error %v with truncated output, got %+v", expected, result)
+	}
+}
+
+func TestReusedOutputs(t *testing.T) {
+	client := kubefake.NewSimpleClientset(
+		gadgetPod("gadget-1", "node-1"),
+		gadgetPod("gadget-3", "node-3"),
+	)
+
+	traceClient := newTraceClient()
+	var notModified int32
+	traceClient.PrependReactor("update", "traces", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		trace := action.(k8stesting.UpdateAction).GetObject().(*gadgetv1alpha1.Trace)
+		if trace.Status.OutputNotModified {
+			atomic.AddInt32(&notModified, 1)
+		}
+		return false, nil, nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go runGadgetPods(ctx, traceClient)
+
+	agg := New(client, traceClient, 5*time.Second)
+	agg.SetOutputReader(storedOutputReader)
+	req := &Request{Gadget: "process-collector"}
+
+	expected := `[{"node":"node-1","op":"collect"},{"node":"node-3","op":"collect"}]`
+	for i := 0; i < 2; i++ {
+		result, err := agg.Run(ctx, req)
+		if err != nil {
+			t.Fatalf("run %d: %s", i, err)
+		}
+		items, _ := json.Marshal(result.Items)
+		if string(items) != expected || len(result.Errors) != 0 {
+			t.Fatalf("run %d: expected %s, got %s %v", i, expected, items, result.Errors)
+		}
+	}
+
+	// The outputs of the second run were left out by the gadget pods.
+	if n := atomic.LoadInt32(&notModified); n != 2 {
+		t.Fatalf("expected 2 outputs not modified, got %d", n)
+	}
+
+	// The outputs aren't reused for another filter.
+	result, err := agg.Run(ctx, &Request{
+		Gadget: "process-collector",
+		Filter: &gadgetv1alpha1.ContainerFilter{Namespace: "default"},
+	})
+	if err != nil {
+		t.Fatalf("running with filter: %s", err)
+	}
+	if n := atomic.LoadInt32(&notModified); len(result.Items) != 2 || n != 2 {
+		t.Fatalf("expected 2 outputs modified with filter, got %d items, %d not modified", len(result.Items), n)
+	}
+}
+
+func TestServer(t *testing.T) {
+	client := kubefake.NewSimpleClientset(
+		gadgetPod("gadget-1", "node-1"),
+		gadgetPod("gadget-2", "node-2"),
+	)
+
+	traceClient := newTraceClient()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go runGadgetPods(ctx, traceClient)
+
+	server := httptest.NewServer(NewServer(New(client, traceClient, 5*time.Second), time.Minute))
+	defer server.Close()
+
+	table := []struct {
+		path     string
+		status   int
+		expected string
+	}{
+		{
+			path:     "/v1/snapshot/process?namespace=default",
+			status:   http.StatusOK,
+			expected: `{"items":[{"node":"node-1","op":"collect"}],"errors":[{"node":"node-2","error":"gadget failed"}]}`,
+		},
+		{
+			path:     "/v1/advise/resource-limits?duration=10ms",
+			status:   http.StatusOK,
+			expected: `{"items":[{"node":"node-1","op":"stop"}],"errors":[{"node":"node-2","error":"gadget failed"}]}`,
+		},
+		{
+			path:     "/v1/snapshot/process?node=node-3",
+			status:   http.StatusInternalServerError,
+			expected: `{"error":"no gadget pod running on node \"node-3\""}`,
+		},
+		{
+			path:     "/v1/snapshot/foo",
+			status:   http.StatusNotFound,
+			expected: `{"error":"unknown endpoint \"/v1/snapshot/foo\""}`,
+		},
+		{
+			path:     "/v1/snapshot/process?sink_file=foo",
+			status:   http.StatusBadRequest,
+			expected: `{"error":"unknown parameter \"sink_file\""}`,
+		},
+	}
+
+	for _, entry := range table {
+		resp, err := http.Get(server.URL + entry.path)
+		if err != nil {
+			t.Fatalf("GET %s: %s", entry.path, err)
+		}
+
+		var body json.RawMessage
+		err = json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("GET %s: decoding response: %s", entry.path, err)
+		}
+
+		if resp.StatusCode != entry.status || string(body) != entry.expected {
+			t.Errorf("GET %s: expected %d %s, got %d %s", entry.path,
+				entry.status, entry.expected, resp.StatusCode, body)
+		}
+	}
+
+	path := server.URL + "/v1/snapshot/process?namespace=default"
+	resp, err := http.Get(path)
+	if err != nil {
+		t.Fatalf("GET %s: %s", path, err)
+	}
+	resp.Body.Close()
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		t.Fatalf("GET %s: expected an ETag", path)
+	}
+
+	get, _ := http.NewRequest(http.MethodGet, path, nil)
+	get.Header.Set("If-None-Match", `"other", `+etag)
+	resp, err = http.DefaultClient.Do(get)
+	if err != nil {
+		t.Fatalf("GET %s with If-None-Match: %s", path, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotModified || resp.Header.Get("ETag") != etag {
+		t.Fatalf("GET %s with If-None-Match: expected 304 with ETag %s, got %d %s",
+			path, etag, resp.StatusCode, resp.Header.Get("ETag"))
+	}
+
+	list, err := traceClient.GadgetV1alpha1().Traces(gadgetNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("listing traces: %s", err)
+	}
+	if len(list.Items) != 0 {
+		t.Fatalf("expected the traces to be deleted, got %d traces", len(list.Items))
+	}
+
+	resp, err = http.Post(server.URL+"/v1/snapshot/process", "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatalf("POST: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("expected POST to be refused, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,243 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package anonymizer replaces IP addresses and hostnames found in events
+// by stable pseudonyms, so that captures can be shared without leaking the
+// internal topology of a cluster.
+//
+// IP addresses are anonymized with a prefix-preserving scheme in the
+// spirit of Crypto-PAn: two addresses sharing a prefix of n bits are
+// mapped to two anonymized addresses sharing a prefix of exactly n bits.
+// Subnets are then still recognisable as such in the anonymized output.
+//
+// Hostnames are anonymized label by label, so that names in the same
+// domain still share the same anonymized suffix.
+package anonymizer
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+// Fields are the fields of the events of a gadget to anonymize.
+type Fields struct {
+	// Addresses are the fields containing IP addresses.
+	Addresses []string
+
+	// Hostnames are the fields containing hostnames or lists of
+	// hostnames.
+	Hostnames []string
+}
+
+// commonHostnameFields are the fields containing hostnames in the events of
+// all the gadgets.
+var commonHostnameFields = []string{"node"}
+
+// gadgetFields are the fields to anonymize in the events of each gadget,
+// besides the common ones. The JSON keys are only meaningful for the
+// gadget they belong to: "name" is a DNS name in the dns events but a probe
+// name in the usdt ones.
+var gadgetFields = map[string]Fields{
+	"apiserver-clients":      {Addresses: []string{"saddr", "daddr"}},
+	"bindsnoop":              {Addresses: []string{"addr"}},
+	"conntrack":              {Addresses: []string{"saddr", "daddr"}},
+	"dns":                    {Hostnames: []string{"name"}},
+	"egress-audit":           {Addresses: []string{"address"}, Hostnames: []string{"names"}},
+	"gethostlatency":         {Hostnames: []string{"host"}},
+	"grpctop":                {Addresses: []string{"daddr"}},
+	"httpsnoop":              {Addresses: []string{"saddr", "daddr"}, Hostnames: []string{"host"}},
+	"network-policy-advisor": {Addresses: []string{"remote_other"}},
+	"ping":                   {Addresses: []string{"saddr", "daddr", "reporter"}},
+	"snisnoop":               {Hostnames: []string{"name"}},
+	"socket-collector":       {Addresses: []string{"local_address", "remote_address"}},
+	"solisten":               {Addresses: []string{"addr"}},
+	"tcpconnect":             {Addresses: []string{"saddr", "daddr"}},
+	"tcpdrop":                {Addresses: []string{"saddr", "daddr"}},
+	"tcplife":                {Addresses: []string{"saddr", "daddr"}},
+	"tcpretrans":             {Addresses: []string{"saddr", "daddr"}},
+	"tcptop":                 {Addresses: []string{"saddr", "daddr"}},
+	"tcptracer":              {Addresses: []string{"saddr", "daddr"}},
+	"tlssnoop":               {Addresses: []string{"saddr", "daddr"}, Hostnames: []string{"name"}},
+	"udpsnoop":               {Addresses: []string{"saddr", "daddr"}},
+}
+
+// GadgetFields returns the fields to anonymize in the events of gadget.
+// Only the common ones are returned for the gadgets whose events don't
+// contain addresses or hostnames.
+func GadgetFields(gadget string) Fields {
+	fields := gadgetFields[gadget]
+	return Fields{
+		Addresses: fields.Addresses,
+		Hostnames: append(append([]string{}, commonHostnameFields...), fields.Hostnames...),
+	}
+}
+
+type Anonymizer struct {
+	key []byte
+
+	mu    sync.Mutex
+	cache map[string]string
+}
+
+// NewAnonymizer creates an anonymizer using the given key. The same key
+// always produces the same pseudonyms, which allows to correlate captures
+// taken at different times. If key is empty, a random one is generated.
+func NewAnonymizer(key string) (*Anonymizer, error) {
+	k := []byte(key)
+	if len(k) == 0 {
+		k = make([]byte, 32)
+		if _, err := rand.Read(k); err != nil {
+			return nil, fmt.Errorf("failed to generate anonymization key: %w", err)
+		}
+	}
+
+	return &Anonymizer{
+		key:   k,
+		cache: make(map[string]string),
+	}, nil
+}
+
+// prf returns a pseudo-random bit for the first n bits of addr.
+func (a *Anonymizer) prf(addr []byte, n int) byte {
+	prefix := make([]byte, len(addr)+1)
+	for i := 0; i < n; i++ {
+		prefix[i/8] |= addr[i/8] & (0x80 >> (i % 8))
+	}
+	prefix[len(addr)] = byte(n)
+
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write(prefix)
+	return mac.Sum(nil)[0] >> 7
+}
+
+func (a *Anonymizer) anonymizeBytes(addr []byte) []byte {
+	out := make([]byte, len(addr))
+	for i := 0; i < len(addr)*8; i++ {
+		bit := (addr[i/8] >> (7 - i%8)) & 1
+		bit ^= a.prf(addr, i)
+		out[i/8] |= bit << (7 - i%8)
+	}
+	return out
+}
+
+// IP returns the anonymized version of ip. Strings that can't be parsed
+// as an IP address are returned unmodified.
+func (a *Anonymizer) IP(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ip
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if anon, ok := a.cache[ip]; ok {
+		return anon
+	}
+
+	var anon string
+	if v4 := parsed.To4(); v4 != nil {
+		anon = net.IP(a.anonymizeBytes(v4)).String()
+	} else {
+		anon = net.IP(a.anonymizeBytes(parsed.To16())).String()
+	}
+	a.cache[ip] = anon
+
+	return anon
+}
+
+// Hostname returns the anonymized version of name. Each label is replaced
+// by a pseudonym; the top-level domain and a possible trailing dot are
+// kept as is.
+func (a *Anonymizer) Hostname(name string) string {
+	if name == "" {
+		return name
+	}
+
+	// An IP address can also be used where a hostname is expected.
+	if net.ParseIP(name) != nil {
+		return a.IP(name)
+	}
+
+	trailingDot := strings.HasSuffix(name, ".")
+	labels := strings.Split(strings.TrimSuffix(name, "."), ".")
+	for i, label := range labels {
+		if label == "" || (i == len(labels)-1 && len(labels) > 1) {
+			continue
+		}
+
+		mac := hmac.New(sha256.New, a.key)
+		mac.Write([]byte(strings.ToLower(label)))
+		labels[i] = hex.EncodeToString(mac.Sum(nil)[:4])
+	}
+
+	anon := strings.Join(labels, ".")
+	if trailingDot {
+		anon += "."
+	}
+	return anon
+}
+
+// Line anonymizes the given fields of an event encoded as JSON. Lines that
+// are not JSON objects are returned unmodified.
+func (a *Anonymizer) Line(line string, fields Fields) string {
+	event := make(map[string]interface{})
+
+	// Use json.Number to avoid losing precision on fields like mountnsid
+	// when the event is encoded again.
+	decoder := json.NewDecoder(strings.NewReader(line))
+	decoder.UseNumber()
+	if err := decoder.Decode(&event); err != nil {
+		return line
+	}
+
+	modified := false
+	for _, field := range fields.Addresses {
+		if val, ok := event[field].(string); ok {
+			event[field] = a.IP(val)
+			modified = true
+		}
+	}
+	for _, field := range fields.Hostnames {
+		switch val := event[field].(type) {
+		case string:
+			event[field] = a.Hostname(val)
+			modified = true
+		case []interface{}:
+			for i, name := range val {
+				if name, ok := name.(string); ok {
+					val[i] = a.Hostname(name)
+					modified = true
+				}
+			}
+		}
+	}
+
+	if !modified {
+		return line
+	}
+
+	b, err := json.Marshal(event)
+	if err != nil {
+		return line
+	}
+	return string(b)
+}
@@ -0,0 +1,180 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anonymizer
+
+import (
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+)
+
+func commonPrefixLen(a, b net.IP) int {
+	for i := 0; i < len(a)*8; i++ {
+		if (a[i/8]>>(7-i%8))&1 != (b[i/8]>>(7-i%8))&1 {
+			return i
+		}
+	}
+	return len(a) * 8
+}
+
+func TestIPPrefixPreserving(t *testing.T) {
+	a, err := NewAnonymizer("key")
+	if err != nil {
+		t.Fatalf("failed to create anonymizer: %s", err)
+	}
+
+	table := []struct {
+		ip1 string
+		ip2 string
+	}{
+		{"10.0.0.1", "10.0.0.2"},
+		{"10.0.0.1", "10.0.1.1"},
+		{"10.0.0.1", "192.168.0.1"},
+		{"fd00::1", "fd00::2"},
+		{"fd00::1", "2001:db8::1"},
+	}
+
+	for _, entry := range table {
+		orig1, orig2 := net.ParseIP(entry.ip1), net.ParseIP(entry.ip2)
+		anon1, anon2 := net.ParseIP(a.IP(entry.ip1)), net.ParseIP(a.IP(entry.ip2))
+		if anon1 == nil || anon2 == nil {
+			t.Fatalf("anonymized addresses of %s and %s are not valid", entry.ip1, entry.ip2)
+		}
+
+		if v4 := orig1.To4(); v4 != nil {
+			orig1, orig2 = v4, orig2.To4()
+			anon1, anon2 = anon1.To4(), anon2.To4()
+		}
+
+		if commonPrefixLen(orig1, orig2) != commonPrefixLen(anon1, anon2) {
+			t.Fatalf("prefix not preserved: %s/%s anonymized as %s/%s",
+				entry.ip1, entry.ip2, anon1, anon2)
+		}
+		if orig1.Equal(anon1) {
+			t.Fatalf("%s was not anonymized", entry.ip1)
+		}
+	}
+}
+
+func TestStableWithKey(t *testing.T) {
+	a1, _ := NewAnonymizer("key")
+	a2, _ := NewAnonymizer("key")
+	a3, _ := NewAnonymizer("other-key")
+
+	if a1.IP("10.0.0.1") != a2.IP("10.0.0.1") {
+		t.Fatalf("same key should give the same pseudonym")
+	}
+	if a1.IP("10.0.0.1") == a3.IP("10.0.0.1") {
+		t.Fatalf("different keys should give different pseudonyms")
+	}
+	if a1.Hostname("foo.example.com") != a2.Hostname("foo.example.com") {
+		t.Fatalf("same key should give the same hostname pseudonym")
+	}
+}
+
+func TestHostname(t *testing.T) {
+	a, _ := NewAnonymizer("key")
+
+	anon1 := a.Hostname("foo.example.com.")
+	anon2 := a.Hostname("bar.example.com.")
+
+	if !strings.HasSuffix(anon1, ".com.") {
+		t.Fatalf("top-level domain and trailing dot should be kept: %q", anon1)
+	}
+	if strings.Contains(anon1, "foo") || strings.Contains(anon1, "example") {
+		t.Fatalf("hostname was not anonymized: %q", anon1)
+	}
+
+	suffix1 := anon1[strings.Index(anon1, "."):]
+	suffix2 := anon2[strings.Index(anon2, "."):]
+	if suffix1 != suffix2 {
+		t.Fatalf("names in the same domain should share the same suffix: %q %q", anon1, anon2)
+	}
+}
+
+func TestLine(t *testing.T) {
+	a, _ := NewAnonymizer("key")
+
+	line := `{"type":"normal","node":"worker-1","saddr":"10.0.0.1","daddr":"1.1.1.1","dport":80,"mountnsid":4026532505123456789}`
+	out := a.Line(line, GadgetFields("tcpconnect"))
+
+	event := make(map[string]interface{})
+	decoder := json.NewDecoder(strings.NewReader(out))
+	decoder.UseNumber()
+	if err := decoder.Decode(&event); err != nil {
+		t.Fatalf("anonymized line is not valid JSON: %s", err)
+	}
+
+	if event["saddr"] != a.IP("10.0.0.1") || event["daddr"] != a.IP("1.1.1.1") {
+		t.Fatalf("addresses were not anonymized: %s", out)
+	}
+	if event["node"] != a.Hostname("worker-1") {
+		t.Fatalf("node was not anonymized: %s", out)
+	}
+	if event["mountnsid"].(json.Number).String() != "4026532505123456789" {
+		t.Fatalf("other fields should be kept as is: %s", out)
+	}
+
+	if a.Line("not json", GadgetFields("tcpconnect")) != "not json" {
+		t.Fatalf("non JSON lines should be kept as is")
+	}
+}
+
+func TestLineGadgetFields(t *testing.T) {
+	a, _ := NewAnonymizer("key")
+
+	// "name" is a hostname in the dns events but not in the usdt ones.
+	dns := `{"type":"normal","node":"worker-1","name":"example.com."}`
+	if out := a.Line(dns, GadgetFields("dns")); !strings.Contains(out, a.Hostname("example.com.")) {
+		t.Fatalf("DNS name was not anonymized: %s", out)
+	}
+
+	usdt := `{"type":"normal","node":"worker-1","name":"gc__begin","saddr":"10.0.0.1"}`
+	out := a.Line(usdt, GadgetFields("usdt"))
+	if !strings.Contains(out, `"name":"gc__begin"`) || !strings.Contains(out, `"saddr":"10.0.0.1"`) {
+		t.Fatalf("only the node of the usdt events should be anonymized: %s", out)
+	}
+	if strings.Contains(out, "worker-1") {
+		t.Fatalf("node was not anonymized: %s", out)
+	}
+}
+
+func TestLineHostnameList(t *testing.T) {
+	a, _ := NewAnonymizer("key")
+
+	line := `{"namespace":"default","address":"1.1.1.1","names":["example.com","www.example.com"],"port":443}`
+	out := a.Line(line, GadgetFields("egress-audit"))
+
+	var dst struct {
+		Address string   `json:"address"`
+		Names   []string `json:"names"`
+		Port    int      `json:"port"`
+	}
+	if err := json.Unmarshal([]byte(out), &dst); err != nil {
+		t.Fatalf("anonymized line is not valid JSON: %s", err)
+	}
+
+	if dst.Address != a.IP("1.1.1.1") {
+		t.Fatalf("address was not anonymized: %s", out)
+	}
+	expected := []string{a.Hostname("example.com"), a.Hostname("www.example.com")}
+	if len(dst.Names) != 2 || dst.Names[0] != expected[0] || dst.Names[1] != expected[1] {
+		t.Fatalf("names were not anonymized: %s", out)
+	}
+	if dst.Port != 443 {
+		t.Fatalf("other fields should be kept as is: %s", out)
+	}
+}
@@ -29,11 +29,22 @@ type ContainerFilter struct {
 	// Podname selects events from this pod name
 	Podname string `json:"podname,omitempty"`
 
+	// PodUID selects events from the pod with this UID
+	PodUID string `json:"podUID,omitempty"`
+
 	// Labels selects events from pods with these labels
 	Labels map[string]string `json:"labels,omitempty"`
 
+	// Annotations selects events from pods with these annotations
+	Annotations map[string]string `json:"annotations,omitempty"`
+
 	// ContainerName selects events from containers with this name
 	ContainerName string `json:"containerName,omitempty"`
+
+	// Sandbox selects events from the containers running in this sandbox,
+	// like "gvisor" or "kata", or "none" for the containers running on the
+	// host kernel
+	Sandbox string `json:"sandbox,omitempty"`
 }
 
 // TraceSpec defines the desired state of Trace
@@ -53,7 +64,7 @@ type TraceSpec struct {
 	RunMode string `json:"runMode,omitempty"`
 
 	// Filter is to tell the gadget to filter events based on namespace,
-	// pod name, labels or container name
+	// pod name, labels, annotations or container name
 	Filter *ContainerFilter `json:"filter,omitempty"`
 
 	// OutputMode is "Status", "Stream", "File" or "ExternalResource"
@@ -73,10 +84,70 @@ type TraceSpec struct {
 	// TODO: Ideally it should be a map[string]interface{} but it's not
 	// supported: https://github.com/kubernetes-sigs/controller-tools/issues/636
 
-	// Parameters contains gadget specific configurations.
+	// Parameters contains gadget specific configurations. The parameters
+	// sink_file, sink_webhook and sink_prometheus also write the events
+	// to other outputs than the stream, output_public_key encrypts the
+	// files written on the nodes.
 	Parameters map[string]string `json:"parameters,omitempty"`
 }
 
+// Parameters of the sinks, the outputs besides the stream to which the
+// events of a trace are written from the nodes
+const (
+	// SinkFileParam is the name of the file of the node, in
+	// /var/log/gadget, the events are appended to.
+	SinkFileParam = "sink_file"
+
+	// SinkWebhookParam is the URL the events are posted to.
+	SinkWebhookParam = "sink_webhook"
+
+	// SinkPrometheusParam counts the events in a prometheus metric if
+	// true.
+	SinkPrometheusParam = "sink_prometheus"
+
+	// OutputPublicKeyParam is a PEM encoded RSA public key the files
+	// written on the nodes are encrypted for.
+	OutputPublicKeyParam = "output_public_key"
+)
+
+// IfNoneMatchAnnotation is set by the clients polling a snapshot gadget to
+// the OutputETag of the output they already have for the node of the trace:
+// the output is left out of the status when it didn't change.
+const IfNoneMatchAnnotation = "gadget.kinvolk.io/if-none-match"
+
+// SinkStatus is the health of an output, besides the stream, to which the
+// events of the trace are written
+type SinkStatus struct {
+	// Kind is "file", "webhook" or "prometheus"
+	Kind string `json:"kind"`
+
+	// Target is the file or the URL the events are written to
+	Target string `json:"target,omitempty"`
+
+	// Healthy tells if the last events were written successfully
+	Healthy bool `json:"healthy"`
+
+	// EventsWritten is the number of events written to the sink
+	EventsWritten int64 `json:"eventsWritten,omitempty"`
+
+	// EventsDropped is the number of events that couldn't be written to
+	// the sink
+	EventsDropped int64 `json:"eventsDropped,omitempty"`
+
+	// LastError is the last error writing the events to the sink
+	LastError string `json:"lastError,omitempty"`
+}
+
+// OutputReference locates the output of a trace stored on its node because
+// it was too large to be written in the status of the Trace
+type OutputReference struct {
+	// Size is the size of the output in bytes
+	Size int64 `json:"size"`
+
+	// Chunks is the number of files the output is split into
+	Chunks int `json:"chunks"`
+}
+
 // TraceStatus defines the observed state of Trace
 type TraceStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
@@ -89,6 +160,21 @@ type TraceStatus struct {
 	// Output is the output of the gadget
 	Output string `json:"output,omitempty"`
 
+	// OutputRef is set instead of Output when the output is too large to
+	// be written in the Trace: it's stored on the node of the trace and
+	// can be read with "gadgettracermanager -call read-output" in the
+	// gadget pod
+	OutputRef *OutputReference `json:"outputRef,omitempty"`
+
+	// OutputETag is the content hash of the output of a completed trace,
+	// computed on the node before the output is moved to OutputRef
+	OutputETag string `json:"outputETag,omitempty"`
+
+	// OutputNotModified is true when the output was left out because its
+	// OutputETag is the one of the gadget.kinvolk.io/if-none-match
+	// annotation: the client already has it
+	OutputNotModified bool `json:"outputNotModified,omitempty"`
+
 	// OperationError is the error returned by the gadget when applying the
 	// annotation gadget.kinvolk.io/operation=
 	OperationError string `json:"operationError,omitempty"`
@@ -98,11 +184,33 @@ type TraceStatus struct {
 	// OperationError that represents a fatal error, the OperationWarning could
 	// be ignored according to the context.
 	OperationWarning string `json:"operationWarning,omitempty"`
+
+	// EventsEmitted is the number of events produced by the gadget on the
+	// node since the trace was started
+	EventsEmitted int64 `json:"eventsEmitted,omitempty"`
+
+	// EventsDropped is the number of events that couldn't be delivered to
+	// a subscriber because it didn't keep up
+	EventsDropped int64 `json:"eventsDropped,omitempty"`
+
+	// Subscribers is the number of clients currently receiving the events
+	// of the trace
+	Subscribers int `json:"subscribers,omitempty"`
+
+	// Sinks is the health of the sinks configured with the parameters
+	Sinks []SinkStatus `json:"sinks,omitempty"`
 }
 
 // +genclient
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Gadget",type=string,JSONPath=`.spec.gadget`
+//+kubebuilder:printcolumn:name="Node",type=string,JSONPath=`.spec.node`
+//+kubebuilder:printcolumn:name="State",type=string,JSONPath=`.status.state`
+//+kubebuilder:printcolumn:name="Events",type=integer,JSONPath=`.status.eventsEmitted`
+//+kubebuilder:printcolumn:name="Dropped",type=integer,JSONPath=`.status.eventsDropped`
+//+kubebuilder:printcolumn:name="Subscribers",type=integer,JSONPath=`.status.subscribers`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // Trace is the Schema for the traces API
 type Trace struct {
@@ -0,0 +1,146 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bpferror turns the errors returned when loading or attaching the
+// eBPF programs of the gadgets into messages users can act upon: the
+// verifier log is cut to its last lines and a hint about the likely cause,
+// like a kernel too old or without BTF information, is added.
+package bpferror
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cilium/ebpf"
+
+	containerutils "github.com/kinvolk/inspektor-gadget/pkg/container-utils"
+)
+
+// maxLogLines is the number of lines of the verifier log kept in the
+// description. The verifier explains why it rejected a program at the end
+// of its log, the lines before are the instructions it went through.
+const maxLogLines = 5
+
+// requirementsHint points users to the kernel requirements of the gadgets.
+const requirementsHint = "see the kernel requirements of the gadgets in docs/requirements.md"
+
+// isWSL2 tells if the gadgets run on WSL2, whose default kernel lacks
+// some of the features they need. It's a variable for the tests.
+var isWSL2 = func() bool {
+	return containerutils.DetectWSL() == containerutils.WSL2
+}
+
+var (
+	insnLimitRegex   = regexp.MustCompile(`processed (\d+) insns \(limit (\d+)\)`)
+	unknownFuncRegex = regexp.MustCompile(`(?:invalid|unknown) func (\w+)#\d+`)
+)
+
+// Describe returns a description of err suited for Status.OperationError.
+// Errors not coming from the verifier are described as is, with a hint
+// when their cause is known.
+func Describe(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	lines := strings.Split(strings.TrimSpace(err.Error()), "\n")
+
+	var b strings.Builder
+	b.WriteString(lines[0])
+
+	// The lines after the first one are the verifier log.
+	logLines := lines[1:]
+	if omitted := len(logLines) - maxLogLines; omitted > 0 {
+		fmt.Fprintf(&b, "\n\t(%d line(s) of the verifier log omitted)", omitted)
+		logLines = logLines[omitted:]
+	}
+	for _, line := range logLines {
+		fmt.Fprintf(&b, "\n\t%s", strings.TrimSpace(line))
+	}
+
+	if hint := Hint(err); hint != "" {
+		fmt.Fprintf(&b, "\nhint: %s", hint)
+	}
+
+	return b.String()
+}
+
+// Hint returns a hint about the cause of err, or an empty string if it
+// isn't known.
+func Hint(err error) string {
+	h, kernel := hint(err)
+	if kernel && isWSL2() {
+		h += "; " + containerutils.WSL2KernelHint
+	}
+	return h
+}
+
+// hint returns a hint about the cause of err and whether it's a feature
+// missing in the kernel.
+func hint(err error) (string, bool) {
+	if err == nil {
+		return "", false
+	}
+
+	msg := err.Error()
+
+	switch {
+	case strings.Contains(msg, "no BTF found") || strings.Contains(msg, "load kernel spec"):
+		return "the BTF information of the kernel isn't available: it's neither exposed " +
+			"by the kernel in /sys/kernel/btf/vmlinux (CONFIG_DEBUG_INFO_BTF) nor shipped " +
+			"in the gadget container image or available in BTFHub", true
+	case strings.Contains(msg, "BPF program is too large"):
+		return "the program is too large for the verifier of this kernel, which accepts " +
+			"up to 4096 instructions before Linux 5.2: " + requirementsHint, true
+	case strings.Contains(msg, "back-edge from insn"):
+		return "the program uses bounded loops, which are only supported from Linux 5.3: " +
+			requirementsHint, true
+	}
+
+	if m := insnLimitRegex.FindStringSubmatch(msg); m != nil {
+		processed, _ := strconv.Atoi(m[1])
+		limit, _ := strconv.Atoi(m[2])
+		if processed >= limit {
+			return fmt.Sprintf("the verifier of this kernel reached its limit of %d "+
+				"instructions: kernels older than 5.2 verify up to 131072 instructions "+
+				"against 1 million for the recent ones, %s", limit, requirementsHint), true
+		}
+	}
+
+	if m := unknownFuncRegex.FindStringSubmatch(msg); m != nil {
+		return fmt.Sprintf("the kernel doesn't provide the %s BPF helper, it's too old "+
+			"for this gadget: %s", m[1], requirementsHint), true
+	}
+
+	switch {
+	case errors.Is(err, ebpf.ErrNotSupported):
+		return "the kernel doesn't support a BPF feature needed by this gadget: " +
+			requirementsHint, true
+	case errors.Is(err, os.ErrNotExist) &&
+		(strings.Contains(msg, "kprobe") || strings.Contains(msg, "symbol") ||
+			strings.Contains(msg, "tracepoint") || strings.Contains(msg, "trace event")):
+		return "a kernel function or tracepoint traced by this gadget doesn't exist in " +
+			"this kernel, it may have been renamed or inlined: " + requirementsHint, true
+	case strings.Contains(msg, "operation not permitted"):
+		return "the gadget isn't allowed to load eBPF programs: it needs CAP_SYS_ADMIN, " +
+			"or CAP_BPF and CAP_PERFMON from Linux 5.8, and a RLIMIT_MEMLOCK large " +
+			"enough for its maps before Linux 5.11", false
+	}
+
+	return "", false
+}
@@ -0,0 +1,133 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bpferror
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/cilium/ebpf"
+
+	containerutils "github.com/kinvolk/inspektor-gadget/pkg/container-utils"
+)
+
+func TestDescribe(t *testing.T) {
+	var log []string
+	for i := 0; i < 300; i++ {
+		log = append(log, fmt.Sprintf("%d: (b7) r1 = 0", i))
+	}
+	log = append(log, "R1 invalid mem access 'inv'", "processed 301 insns (limit 1000000)")
+
+	err := fmt.Errorf("loading objects: program ig_execve_e: load program: permission denied: %s",
+		strings.Join(log, "\n"))
+	expected := "loading objects: program ig_execve_e: load program: permission denied: 0: (b7) r1 = 0\n" +
+		"\t(296 line(s) of the verifier log omitted)\n" +
+		"\t297: (b7) r1 = 0\n" +
+		"\t298: (b7) r1 = 0\n" +
+		"\t299: (b7) r1 = 0\n" +
+		"\tR1 invalid mem access 'inv'\n" +
+		"\tprocessed 301 insns (limit 1000000)"
+	if desc := Describe(err); desc != expected {
+		t.Fatalf("expected %q, got %q", expected, desc)
+	}
+
+	err = errors.New("load program: invalid argument: 0: (85) call bpf_get_current_task_btf#158\nunknown func bpf_get_current_task_btf#158")
+	expected = "load program: invalid argument: 0: (85) call bpf_get_current_task_btf#158\n" +
+		"\tunknown func bpf_get_current_task_btf#158\n" +
+		"hint: the kernel doesn't provide the bpf_get_current_task_btf BPF helper, it's too old for this gadget: " +
+		requirementsHint
+	if desc := Describe(err); desc != expected {
+		t.Fatalf("expected %q, got %q", expected, desc)
+	}
+
+	if desc := Describe(errors.New("creating perf buffer: no space left on device")); desc != "creating perf buffer: no space left on device" {
+		t.Fatalf("unexpected description %q", desc)
+	}
+}
+
+func TestHint(t *testing.T) {
+	table := []struct {
+		err      error
+		expected string
+	}{
+		{
+			err:      fmt.Errorf("loading objects: load kernel spec: no BTF found for kernel version 4.19.0: %w", ebpf.ErrNotSupported),
+			expected: "the BTF information of the kernel isn't available",
+		},
+		{
+			err:      errors.New("load program: argument list too long: BPF program is too large. Processed 4097 insn"),
+			expected: "the program is too large for the verifier of this kernel",
+		},
+		{
+			err:      errors.New("load program: permission denied: 12: (05) goto pc-4\nback-edge from insn 12 to 9"),
+			expected: "the program uses bounded loops",
+		},
+		{
+			err:      errors.New("load program: permission denied: 0: (bf) r6 = r1\nThe sequence of 8193 jumps is too complex.\nprocessed 131072 insns (limit 131072)"),
+			expected: "the verifier of this kernel reached its limit of 131072 instructions",
+		},
+		{
+			err:      errors.New("load program: permission denied: R1 invalid mem access 'inv'\nprocessed 42 insns (limit 1000000)"),
+			expected: "",
+		},
+		{
+			err:      fmt.Errorf("attaching program: %w", fmt.Errorf("map type ringbuf: %w", ebpf.ErrNotSupported)),
+			expected: "the kernel doesn't support a BPF feature needed by this gadget",
+		},
+		{
+			err:      fmt.Errorf("attaching kprobe: symbol vfs_fsync_range: %w", os.ErrNotExist),
+			expected: "a kernel function or tracepoint traced by this gadget doesn't exist",
+		},
+		{
+			err:      fmt.Errorf("reading config: %w", os.ErrNotExist),
+			expected: "",
+		},
+		{
+			err:      errors.New("creating map: operation not permitted"),
+			expected: "the gadget isn't allowed to load eBPF programs",
+		},
+	}
+
+	for _, entry := range table {
+		hint := Hint(entry.err)
+		if entry.expected == "" {
+			if hint != "" {
+				t.Errorf("expected no hint for %q, got %q", entry.err, hint)
+			}
+			continue
+		}
+		if !strings.HasPrefix(hint, entry.expected) {
+			t.Errorf("expected hint for %q to start with %q, got %q", entry.err, entry.expected, hint)
+		}
+	}
+}
+
+func TestHintWSL2(t *testing.T) {
+	defer func(f func() bool) { isWSL2 = f }(isWSL2)
+	isWSL2 = func() bool { return true }
+
+	hint := Hint(fmt.Errorf("attaching kprobe: symbol vfs_fsync_range: %w", os.ErrNotExist))
+	if !strings.HasSuffix(hint, "; "+containerutils.WSL2KernelHint) {
+		t.Errorf("expected the WSL2 hint, got %q", hint)
+	}
+
+	hint = Hint(errors.New("creating map: operation not permitted"))
+	if strings.Contains(hint, containerutils.WSL2KernelHint) {
+		t.Errorf("expected no WSL2 hint for a missing privilege, got %q", hint)
+	}
+}
@@ -51,6 +51,11 @@ type ContainerCollection struct {
 
 	// initialized tells if ContainerCollectionInitialize has been called.
 	initialized bool
+
+	// closeFuncs are functions registered by the functional options to
+	// release their resources, like the goroutines watching containers.
+	// They are called by ContainerCollectionClose.
+	closeFuncs []func()
 }
 
 // ContainerCollectionOption are options to pass to
@@ -97,6 +102,17 @@ initialContainersLoop:
 	return nil
 }
 
+// ContainerCollectionClose stops the container watchers and releases the
+// resources set up by the functional options. Like
+// ContainerCollectionInitialize, it is namespaced because ContainerCollection
+// is typically embedded as an anonymous struct.
+func (cc *ContainerCollection) ContainerCollectionClose() {
+	for i := len(cc.closeFuncs) - 1; i >= 0; i-- {
+		cc.closeFuncs[i]()
+	}
+	cc.closeFuncs = nil
+}
+
 // GetContainer looks up a container by the container id and return it if
 // found, or return nil if not found.
 func (cc *ContainerCollection) GetContainer(id string) *pb.ContainerDefinition {
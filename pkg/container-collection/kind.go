@@ -0,0 +1,281 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package containercollection
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	containerutils "github.com/kinvolk/inspektor-gadget/pkg/container-utils"
+	"github.com/kinvolk/inspektor-gadget/pkg/container-utils/containerd"
+	runtimeclient "github.com/kinvolk/inspektor-gadget/pkg/container-utils/runtime-client"
+
+	pb "github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/api"
+)
+
+// kindPollInterval is how often the containers nested in the kind nodes are
+// listed. runcfanotify can't be used for them since the runc binary of the
+// nodes isn't the one of the host.
+const kindPollInterval = time.Second
+
+// kindNodes keeps track of the nodes of the kind clusters running on the
+// docker of the host, and of a client to the containerd of each of them.
+type kindNodes struct {
+	mu sync.Mutex
+
+	docker runtimeclient.ContainerRuntimeClient
+
+	// Keys:   ID of the docker container of the node
+	// Values: client to the containerd of the node
+	clients map[string]runtimeclient.ContainerRuntimeClient
+}
+
+// refresh updates the list of nodes from the docker containers of the host.
+func (k *kindNodes) refresh() error {
+	containers, err := k.docker.GetContainers()
+	if err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	running := make(map[string]struct{})
+	for _, container := range containers {
+		if !container.Running || !containerutils.IsKindNode(container.Labels) {
+			continue
+		}
+		running[container.ID] = struct{}{}
+
+		if _, ok := k.clients[container.ID]; ok {
+			continue
+		}
+
+		pid, err := k.docker.PidFromContainerID(container.ID)
+		if err != nil {
+			log.Debugf("Kind enricher: Skip node %q (ID: %s): couldn't find pid: %s",
+				container.Name, container.ID, err)
+			continue
+		}
+
+		client, err := containerd.NewContainerdClient(containerutils.KindNodeContainerdSocket(pid))
+		if err != nil {
+			log.Debugf("Kind enricher: Skip node %q (ID: %s): failed to connect to containerd: %s",
+				container.Name, container.ID, err)
+			continue
+		}
+		k.clients[container.ID] = client
+	}
+
+	for id, client := range k.clients {
+		if _, ok := running[id]; !ok {
+			client.Close()
+			delete(k.clients, id)
+		}
+	}
+
+	return nil
+}
+
+// client returns the client to the containerd of a node, or nil if it isn't
+// a known node.
+func (k *kindNodes) client(nodeID string) runtimeclient.ContainerRuntimeClient {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return k.clients[nodeID]
+}
+
+// nestedContainers returns the running containers of the nodes, with the pid
+// of their first process in the pid namespace of the host.
+func (k *kindNodes) nestedContainers() (map[string]int, error) {
+	pids, err := containerutils.NestedContainersPids()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the pids of the nested containers: %w", err)
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	ret := make(map[string]int)
+	for nodeID, client := range k.clients {
+		containers, err := client.GetContainers()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the containers of node %s: %w",
+				nodeID, err)
+		}
+
+		// The sandboxes of the pods are also nested containers but
+		// they aren't listed by CRI, so they are left out.
+		for _, container := range containers {
+			if !container.Running {
+				continue
+			}
+			if pid, ok := pids[container.ID]; ok {
+				ret[container.ID] = pid
+			}
+		}
+	}
+
+	return ret, nil
+}
+
+// enrich sets the ID, name, namespace and pod name of the containers nested
+// in the nodes, from the cgroup set by the cgroup enricher and the labels
+// the containerd of the node sets from the pods.
+func (k *kindNodes) enrich(container *pb.ContainerDefinition) bool {
+	path := container.CgroupV2
+	if path == "" {
+		path = container.CgroupV1
+	}
+
+	ids := containerutils.ContainerIDsFromCgroupPath(path)
+	if len(ids) < 2 {
+		return true
+	}
+
+	client := k.client(ids[0])
+	if client == nil {
+		return true
+	}
+
+	c, err := client.GetContainer(ids[len(ids)-1])
+	if err != nil {
+		log.Debugf("Kind enricher: failed to get container: %s", err)
+		return true
+	}
+
+	container.Id = c.ID
+	container.Name = c.Name
+	container.Namespace = c.Labels[runtimeclient.PodNamespaceLabel]
+	container.Podname = c.Labels[runtimeclient.PodNameLabel]
+
+	return true
+}
+
+func (k *kindNodes) close() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	for id, client := range k.clients {
+		client.Close()
+		delete(k.clients, id)
+	}
+	k.docker.Close()
+}
+
+// WithKindEnrichment adds the containers of the pods of the kind (Kubernetes
+// in Docker) clusters running on the docker of the host, and enriches them
+// with the metadata of their pod. The container runtime of the nodes isn't
+// reachable from the host through its usual socket, so
+// WithContainerRuntimeEnrichment() can't do it.
+//
+// It has to be passed after WithCgroupEnrichment() and before
+// WithContainerRuntimeEnrichment(), so the runtime enricher doesn't set the
+// metadata first. It doesn't fail if docker isn't available.
+//
+// ContainerCollection.ContainerCollectionInitialize(WithKindEnrichment(*RuntimeConfig))
+func WithKindEnrichment(runtime *containerutils.RuntimeConfig) ContainerCollectionOption {
+	return func(cc *ContainerCollection) error {
+		dockerClient, err := containerutils.NewContainerRuntimeClient(runtime)
+		if err != nil {
+			log.Warnf("Kind enricher: failed to initialize container runtime %s: %s",
+				runtime.Name, err)
+			return nil
+		}
+
+		k := &kindNodes{
+			docker:  dockerClient,
+			clients: make(map[string]runtimeclient.ContainerRuntimeClient),
+		}
+		if err := k.refresh(); err != nil {
+			log.Warnf("Kind enricher: failed to get the kind nodes: %s", err)
+		}
+
+		cc.containerEnrichers = append(cc.containerEnrichers, k.enrich)
+
+		// known is only used by the goroutine below once the initial
+		// containers are gathered.
+		known, err := k.nestedContainers()
+		if err != nil {
+			log.Warnf("Kind enricher: %s", err)
+			known = make(map[string]int)
+		}
+		for id, pid := range known {
+			cc.initialContainers = append(cc.initialContainers,
+				&pb.ContainerDefinition{
+					Id:  id,
+					Pid: uint32(pid),
+				})
+		}
+
+		done := make(chan struct{})
+		finished := make(chan struct{})
+		cc.closeFuncs = append(cc.closeFuncs, func() {
+			close(done)
+			<-finished
+			k.close()
+		})
+
+		go func() {
+			defer close(finished)
+
+			ticker := time.NewTicker(kindPollInterval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+				}
+
+				if err := k.refresh(); err != nil {
+					log.Debugf("Kind enricher: failed to get the kind nodes: %s", err)
+					continue
+				}
+
+				// Keep the containers as they are when they can't be
+				// listed, instead of removing all of them.
+				current, err := k.nestedContainers()
+				if err != nil {
+					log.Debugf("Kind enricher: %s", err)
+					continue
+				}
+
+				for id := range known {
+					if _, ok := current[id]; !ok {
+						cc.RemoveContainer(id)
+					}
+				}
+				for id, pid := range current {
+					if _, ok := known[id]; ok {
+						continue
+					}
+					cc.AddContainer(&pb.ContainerDefinition{
+						Id:  id,
+						Pid: uint32(pid),
+					})
+				}
+				known = current
+			}
+		}()
+
+		return nil
+	}
+}
@@ -16,6 +16,7 @@ package containercollection
 
 import (
 	pb "github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/api"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
 )
 
 // ContainerSelectorMatches tells if a container matches the criteria in a
@@ -27,12 +28,39 @@ func ContainerSelectorMatches(s *pb.ContainerSelector, c *pb.ContainerDefinition
 	if s.Podname != "" && s.Podname != c.Podname {
 		return false
 	}
+	if s.PodUid != "" && s.PodUid != c.PodUid {
+		return false
+	}
+	if s.Sandbox != "" && !sandboxMatches(s.Sandbox, c.Sandbox) {
+		return false
+	}
 	if s.Name != "" && s.Name != c.Name {
 		return false
 	}
-	for _, l := range s.Labels {
+	if !labelsMatch(s.Labels, c.Labels) {
+		return false
+	}
+	if !labelsMatch(s.Annotations, c.Annotations) {
+		return false
+	}
+
+	return true
+}
+
+// sandboxMatches tells if a container running in sandbox is selected by the
+// selector value.
+func sandboxMatches(selector, sandbox string) bool {
+	if selector == eventtypes.SandboxNone {
+		return sandbox == ""
+	}
+	return selector == sandbox
+}
+
+// labelsMatch tells if all the key-value pairs of selector are in labels.
+func labelsMatch(selector, labels []*pb.Label) bool {
+	for _, l := range selector {
 		found := false
-		for _, cl := range c.Labels {
+		for _, cl := range labels {
 			if cl.Key == l.Key && cl.Value == l.Value {
 				found = true
 				break
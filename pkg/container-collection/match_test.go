@@ -73,6 +73,86 @@ func TestSelector(t *testing.T) {
 				Name:      "this-container",
 			},
 		},
+		{
+			description: "Pod UID matches",
+			match:       true,
+			selector: &pb.ContainerSelector{
+				Namespace: "this-namespace",
+				PodUid:    "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+			},
+			container: &pb.ContainerDefinition{
+				Namespace: "this-namespace",
+				Podname:   "this-pod",
+				PodUid:    "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+				Name:      "this-container",
+			},
+		},
+		{
+			description: "Pod UID of a recreated pod does not match",
+			match:       false,
+			selector: &pb.ContainerSelector{
+				Namespace: "this-namespace",
+				Podname:   "this-pod",
+				PodUid:    "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+			},
+			container: &pb.ContainerDefinition{
+				Namespace: "this-namespace",
+				Podname:   "this-pod",
+				PodUid:    "9b2e4f1c-3a7d-4e8b-a2c1-5d6f7e8a9b0c",
+				Name:      "this-container",
+			},
+		},
+		{
+			description: "Sandbox matches",
+			match:       true,
+			selector: &pb.ContainerSelector{
+				Sandbox: "gvisor",
+			},
+			container: &pb.ContainerDefinition{
+				Namespace: "this-namespace",
+				Podname:   "this-pod",
+				Name:      "this-container",
+				Sandbox:   "gvisor",
+			},
+		},
+		{
+			description: "Sandbox does not match",
+			match:       false,
+			selector: &pb.ContainerSelector{
+				Sandbox: "kata",
+			},
+			container: &pb.ContainerDefinition{
+				Namespace: "this-namespace",
+				Podname:   "this-pod",
+				Name:      "this-container",
+				Sandbox:   "gvisor",
+			},
+		},
+		{
+			description: "Sandbox none matches containers on the host kernel",
+			match:       true,
+			selector: &pb.ContainerSelector{
+				Sandbox: "none",
+			},
+			container: &pb.ContainerDefinition{
+				Namespace: "this-namespace",
+				Podname:   "this-pod",
+				Name:      "this-container",
+			},
+		},
+		{
+			description: "Sandbox none does not match sandboxed containers",
+			match:       false,
+			selector: &pb.ContainerSelector{
+				Sandbox: "none",
+			},
+			container: &pb.ContainerDefinition{
+				Namespace: "this-namespace",
+				Podname:   "this-pod",
+				Name:      "this-container",
+				Sandbox:   "kata",
+			},
+		},
 		{
 			description: "One label doesn't match",
 			match:       false,
@@ -95,6 +175,41 @@ func TestSelector(t *testing.T) {
 				},
 			},
 		},
+		{
+			description: "Annotations match",
+			match:       true,
+			selector: &pb.ContainerSelector{
+				Annotations: []*pb.Label{
+					{Key: "team", Value: "payments"},
+				},
+			},
+			container: &pb.ContainerDefinition{
+				Namespace: "this-namespace",
+				Podname:   "this-pod",
+				Name:      "this-container",
+				Annotations: []*pb.Label{
+					{Key: "unrelated-annotation", Value: "here"},
+					{Key: "team", Value: "payments"},
+				},
+			},
+		},
+		{
+			description: "Annotation is a label",
+			match:       false,
+			selector: &pb.ContainerSelector{
+				Annotations: []*pb.Label{
+					{Key: "team", Value: "payments"},
+				},
+			},
+			container: &pb.ContainerDefinition{
+				Namespace: "this-namespace",
+				Podname:   "this-pod",
+				Name:      "this-container",
+				Labels: []*pb.Label{
+					{Key: "team", Value: "payments"},
+				},
+			},
+		},
 	}
 
 	for i, entry := range table {
@@ -179,11 +179,20 @@ func withPodInformer(nodeName string, fallbackMode bool) ContainerCollectionOpti
 		createdChan := make(chan *v1.Pod)
 		deletedChan := make(chan string)
 
-		_, err = k8s.NewPodInformer(nodeName, createdChan, deletedChan)
+		podInformer, err := k8s.NewPodInformer(nodeName, createdChan, deletedChan)
 		if err != nil {
 			return fmt.Errorf("failed to create pod informer: %w", err)
 		}
 
+		done := make(chan struct{})
+		cc.closeFuncs = append(cc.closeFuncs, func() {
+			// Stop the informer first: its worker gives up
+			// sending the pods once it's stopped, so it doesn't
+			// block on the channels after the reader exits.
+			podInformer.Stop()
+			close(done)
+		})
+
 		go func() {
 			// containerIDsByKey keeps track of container ids for each key. This is
 			// necessary because messages from deletedChan only gives the key
@@ -195,6 +204,8 @@ func withPodInformer(nodeName string, fallbackMode bool) ContainerCollectionOpti
 
 			for {
 				select {
+				case <-done:
+					return
 				case d := <-deletedChan:
 					if containerIDs, ok := containerIDsByKey[d]; ok {
 						for containerID := range containerIDs {
@@ -246,8 +257,6 @@ func withPodInformer(nodeName string, fallbackMode bool) ContainerCollectionOpti
 					}
 				}
 			}
-			// TODO: ContainerCollection does not have a Stop() method
-			// podInformer.Stop()
 		}()
 
 		return nil
@@ -456,6 +465,7 @@ func WithKubernetesEnrichment(nodeName string) ContainerCollectionOption {
 		if err != nil {
 			return fmt.Errorf("cannot start Kubernetes client: %w", err)
 		}
+		sandboxDetector := containerutils.NewSandboxDetector(clientset)
 
 		// Future containers
 		cc.containerEnrichers = append(cc.containerEnrichers, func(containerDefinition *pb.ContainerDefinition) bool {
@@ -485,8 +495,11 @@ func WithKubernetesEnrichment(nodeName string) ContainerCollectionOption {
 			// Fill Kubernetes fields
 			namespace := ""
 			podname := ""
+			podUID := ""
+			sandbox := ""
 			containerName := ""
 			labels := []*pb.Label{}
+			annotations := []*pb.Label{}
 			var podOwnerRef []metav1.OwnerReference
 			for _, pod := range pods.Items {
 				uid := string(pod.ObjectMeta.UID)
@@ -502,10 +515,15 @@ func WithKubernetesEnrichment(nodeName string) ContainerCollectionOption {
 
 				namespace = pod.ObjectMeta.Namespace
 				podname = pod.ObjectMeta.Name
+				podUID = uid
+				sandbox = sandboxDetector.PodSandbox(&pod)
 
 				for k, v := range pod.ObjectMeta.Labels {
 					labels = append(labels, &pb.Label{Key: k, Value: v})
 				}
+				for k, v := range pod.ObjectMeta.Annotations {
+					annotations = append(annotations, &pb.Label{Key: k, Value: v})
+				}
 
 				containers := append([]v1.Container{}, pod.Spec.InitContainers...)
 				containers = append(containers, pod.Spec.Containers...)
@@ -526,8 +544,11 @@ func WithKubernetesEnrichment(nodeName string) ContainerCollectionOption {
 
 			containerDefinition.Namespace = namespace
 			containerDefinition.Podname = podname
+			containerDefinition.PodUid = podUID
+			containerDefinition.Sandbox = sandbox
 			containerDefinition.Name = containerName
 			containerDefinition.Labels = labels
+			containerDefinition.Annotations = annotations
 
 			// drop pause containers
 			if containerDefinition.Podname != "" && containerName == "" {
@@ -575,6 +596,8 @@ func WithRuncFanotify() ContainerCollectionOption {
 			return fmt.Errorf("cannot start runc fanotify: %w", err)
 		}
 
+		cc.closeFuncs = append(cc.closeFuncs, runcNotifier.Close)
+
 		// Future containers
 		cc.containerEnrichers = append(cc.containerEnrichers, func(container *pb.ContainerDefinition) bool {
 			err := runcNotifier.AddWatchContainerTermination(container.Id, int(container.Pid))
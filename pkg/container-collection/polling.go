@@ -0,0 +1,158 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package containercollection
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	containerutils "github.com/kinvolk/inspektor-gadget/pkg/container-utils"
+	runtimeclient "github.com/kinvolk/inspektor-gadget/pkg/container-utils/runtime-client"
+
+	pb "github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/api"
+)
+
+// runtimePollInterval is how often the containers are listed by
+// WithContainerRuntimePolling().
+const runtimePollInterval = time.Second
+
+// runningContainers returns the pid of the running containers of a runtime,
+// by their ID. The containers whose pid isn't visible from the pid
+// namespace of the caller are left out.
+func runningContainers(runtimeName string, client runtimeclient.ContainerRuntimeClient) (map[string]int, error) {
+	containers, err := client.GetContainers()
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(map[string]int)
+	for _, container := range containers {
+		if !container.Running {
+			continue
+		}
+
+		pid, err := client.PidFromContainerID(container.ID)
+		if err != nil {
+			log.Debugf("Runtime polling (%s): Skip container %q (ID: %s): couldn't find pid: %s",
+				runtimeName, container.Name, container.ID, err)
+			continue
+		}
+		if _, err := os.Stat(fmt.Sprintf("/proc/%d", pid)); err != nil {
+			log.Debugf("Runtime polling (%s): Skip container %q (ID: %s): pid %d isn't visible from this pid namespace",
+				runtimeName, container.Name, container.ID, pid)
+			continue
+		}
+
+		ret[container.ID] = pid
+	}
+
+	return ret, nil
+}
+
+// WithContainerRuntimePolling adds and removes the containers by listing
+// them periodically from the container runtimes. It's an alternative to
+// WithRuncFanotify() when the runc binary used by the runtimes isn't
+// reachable, like the one of Docker Desktop from the WSL2 distributions.
+// The containers running at initialization are left to
+// WithContainerRuntimeEnrichment(), which has to be passed too.
+//
+// ContainerCollection.ContainerCollectionInitialize(WithContainerRuntimePolling([]*RuntimeConfig))
+func WithContainerRuntimePolling(runtimes []*containerutils.RuntimeConfig) ContainerCollectionOption {
+	return func(cc *ContainerCollection) error {
+		clients := make(map[string]runtimeclient.ContainerRuntimeClient)
+		for _, r := range runtimes {
+			client, err := containerutils.NewContainerRuntimeClient(r)
+			if err != nil {
+				log.Warnf("Runtime polling (%s): failed to initialize container runtime: %s",
+					r.Name, err)
+				continue
+			}
+			clients[r.Name] = client
+		}
+		if len(clients) == 0 {
+			return errors.New("no container runtime to poll")
+		}
+
+		// known is only used by the goroutine below once the initial
+		// containers are gathered, by runtime.
+		known := make(map[string]map[string]int)
+		for name, client := range clients {
+			current, err := runningContainers(name, client)
+			if err != nil {
+				log.Warnf("Runtime polling (%s): failed to get current containers: %s", name, err)
+				current = make(map[string]int)
+			}
+			known[name] = current
+		}
+
+		done := make(chan struct{})
+		finished := make(chan struct{})
+		cc.closeFuncs = append(cc.closeFuncs, func() {
+			close(done)
+			<-finished
+			for _, client := range clients {
+				client.Close()
+			}
+		})
+
+		go func() {
+			defer close(finished)
+
+			ticker := time.NewTicker(runtimePollInterval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+				}
+
+				for name, client := range clients {
+					// Keep the containers as they are when they
+					// can't be listed, instead of removing all of
+					// them.
+					current, err := runningContainers(name, client)
+					if err != nil {
+						log.Debugf("Runtime polling (%s): failed to get current containers: %s", name, err)
+						continue
+					}
+
+					for id := range known[name] {
+						if _, ok := current[id]; !ok {
+							cc.RemoveContainer(id)
+						}
+					}
+					for id, pid := range current {
+						if _, ok := known[name][id]; ok {
+							continue
+						}
+						cc.AddContainer(&pb.ContainerDefinition{
+							Id:  id,
+							Pid: uint32(pid),
+						})
+					}
+					known[name] = current
+				}
+			}
+		}()
+
+		return nil
+	}
+}
@@ -193,6 +193,14 @@ func GetNetNs(pid int) (uint64, error) {
 	return getNamespaceInode(pid, "net")
 }
 
+func GetPidNs(pid int) (uint64, error) {
+	return getNamespaceInode(pid, "pid")
+}
+
+func GetIpcNs(pid int) (uint64, error) {
+	return getNamespaceInode(pid, "ipc")
+}
+
 func ParseOCIState(stateBuf []byte) (id string, pid int, err error) {
 	ociState := &ocispec.State{}
 	err = json.Unmarshal(stateBuf, ociState)
@@ -120,6 +120,7 @@ func (c *DockerClient) GetContainers() ([]*runtimeclient.ContainerData, error) {
 			ID:      container.ID,
 			Name:    strings.TrimPrefix(containers[i].Names[0], "/"),
 			Running: container.State == "running",
+			Labels:  container.Labels,
 		}
 	}
 
@@ -147,6 +148,7 @@ func (c *DockerClient) GetContainer(containerID string) (*runtimeclient.Containe
 		ID:      containers[0].ID,
 		Name:    strings.TrimPrefix(containers[0].Names[0], "/"),
 		Running: containers[0].State == "running",
+		Labels:  containers[0].Labels,
 	}, nil
 }
 
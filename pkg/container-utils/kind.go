@@ -0,0 +1,115 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package containerutils
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/kinvolk/inspektor-gadget/pkg/container-utils/containerd"
+)
+
+// The nodes of the kind (Kubernetes in Docker) clusters are docker
+// containers of the host, running containerd. The containers of the pods
+// are therefore nested in them: the docker of the host only knows the
+// nodes, and their containerd has to be asked for the pods.
+
+// KindClusterLabel is set by kind on the docker containers of the nodes,
+// to the name of their cluster.
+const KindClusterLabel = "io.x-k8s.kind.cluster"
+
+// IsKindNode tells if a docker container is a node of a kind cluster, from
+// its labels.
+func IsKindNode(labels map[string]string) bool {
+	_, ok := labels[KindClusterLabel]
+	return ok
+}
+
+// KindNodeContainerdSocket returns the path, seen from the host, of the
+// socket of the containerd of a kind node whose first process is pid.
+func KindNodeContainerdSocket(pid int) string {
+	return filepath.Join("/proc", strconv.Itoa(pid), "root", containerd.DefaultSocketPath)
+}
+
+// containerIDRegexp matches the components of the cgroup paths naming a
+// container, with the cgroupfs driver (the ID alone) or the systemd one
+// (e.g. docker-<ID>.scope or cri-containerd-<ID>.scope).
+var containerIDRegexp = regexp.MustCompile(`^(?:(?:docker|cri-containerd|crio|libpod)-)?([0-9a-f]{64})(?:\.scope)?$`)
+
+// ContainerIDsFromCgroupPath returns the IDs of the containers a cgroup path
+// belongs to, from the outermost to the innermost. A process of a pod of a
+// kind cluster belongs to the container of the node and to the container
+// of the pod, e.g.:
+//
+//	/system.slice/docker-<node ID>.scope/kubelet.slice/.../cri-containerd-<ID>.scope
+//	/docker/<node ID>/kubelet/kubepods/besteffort/pod<UID>/<ID>
+func ContainerIDsFromCgroupPath(path string) []string {
+	ids := []string{}
+	for _, component := range strings.Split(path, "/") {
+		if match := containerIDRegexp.FindStringSubmatch(component); match != nil {
+			ids = append(ids, match[1])
+		}
+	}
+	return ids
+}
+
+// NestedContainersPids returns the pid, in the pid namespace of the
+// caller, of the first process of the containers nested in other
+// containers, like the ones of the pods of kind clusters, by their ID. The
+// pids given by the runtime of the nested containers are in the pid
+// namespace of their parent container, so they can't be used from the
+// host.
+func NestedContainersPids() (map[string]int, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+
+	pids := make(map[string]int)
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		cgroupPathV1, cgroupPathV2, err := GetCgroupPaths(pid)
+		if err != nil {
+			// The process exited in the meantime
+			continue
+		}
+
+		path := cgroupPathV2
+		if path == "" {
+			path = cgroupPathV1
+		}
+
+		ids := ContainerIDsFromCgroupPath(path)
+		if len(ids) < 2 {
+			continue
+		}
+
+		// The first process of the container is the one with the
+		// lowest pid, unless the pids wrapped around.
+		id := ids[len(ids)-1]
+		if existing, ok := pids[id]; !ok || pid < existing {
+			pids[id] = pid
+		}
+	}
+
+	return pids, nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package containerutils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestContainerIDsFromCgroupPath(t *testing.T) {
+	node := strings.Repeat("a", 64)
+	pod := strings.Repeat("b", 64)
+
+	table := []struct {
+		description string
+		path        string
+		expected    []string
+	}{
+		{
+			description: "host process",
+			path:        "/user.slice/user-1000.slice/session-2.scope",
+			expected:    []string{},
+		},
+		{
+			description: "docker container with systemd",
+			path:        "/system.slice/docker-" + node + ".scope",
+			expected:    []string{node},
+		},
+		{
+			description: "kind pod with systemd",
+			path: "/system.slice/docker-" + node + ".scope/kubelet.slice/kubelet-kubepods.slice/" +
+				"kubelet-kubepods-besteffort.slice/kubelet-kubepods-besteffort-pod1234.slice/cri-containerd-" + pod + ".scope",
+			expected: []string{node, pod},
+		},
+		{
+			description: "kind pod with cgroupfs",
+			path:        "/docker/" + node + "/kubelet/kubepods/besteffort/pod1234/" + pod,
+			expected:    []string{node, pod},
+		},
+		{
+			description: "short ID",
+			path:        "/docker/abcdef/kubepods",
+			expected:    []string{},
+		},
+	}
+
+	for _, entry := range table {
+		ids := ContainerIDsFromCgroupPath(entry.path)
+		if !reflect.DeepEqual(ids, entry.expected) {
+			t.Errorf("%s: expected %v, got %v", entry.description, entry.expected, ids)
+		}
+	}
+}
+
+func TestIsKindNode(t *testing.T) {
+	if !IsKindNode(map[string]string{KindClusterLabel: "kind", "io.x-k8s.kind.role": "control-plane"}) {
+		t.Errorf("expected a kind node")
+	}
+	if IsKindNode(map[string]string{"maintainer": "someone"}) || IsKindNode(nil) {
+		t.Errorf("expected not a kind node")
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package containerutils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolvePathInRoot(t *testing.T) {
+	root := t.TempDir()
+
+	// A container root where /lib is an absolute link to /usr/lib and
+	// libc.so.6 a relative link.
+	for _, dir := range []string{"usr/lib", "bin"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, "usr/lib/libc-2.31.so"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for target, link := range map[string]string{
+		"/usr/lib":     "lib",
+		"libc-2.31.so": "usr/lib/libc.so.6",
+		"loop2":        "bin/loop",
+		"loop":         "bin/loop2",
+		"/":            "bin/root",
+	} {
+		if err := os.Symlink(target, filepath.Join(root, link)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	table := []struct {
+		path     string
+		resolved string
+		err      bool
+	}{
+		{path: "/lib/libc.so.6", resolved: "/usr/lib/libc-2.31.so"},
+		{path: "/bin/root/../../usr/./lib/libc.so.6", resolved: "/usr/lib/libc-2.31.so"},
+		{path: "/bin/root/lib", resolved: "/usr/lib"},
+		{path: "/lib/missing", err: true},
+		{path: "/bin/loop", err: true},
+	}
+
+	for _, entry := range table {
+		resolved, err := ResolvePathInRoot(root, entry.path)
+		if entry.err {
+			if err == nil {
+				t.Errorf("%s: expected an error", entry.path)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", entry.path, err)
+			continue
+		}
+		if expected := filepath.Join(root, entry.resolved); resolved != expected {
+			t.Errorf("%s: expected %s, got %s", entry.path, expected, resolved)
+		}
+	}
+}
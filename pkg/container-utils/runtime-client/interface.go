@@ -28,6 +28,13 @@ import (
 	pb "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
 )
 
+// Labels set by the CRI runtimes on the containers of the pods
+const (
+	PodNameLabel       = "io.kubernetes.pod.name"
+	PodNamespaceLabel  = "io.kubernetes.pod.namespace"
+	ContainerNameLabel = "io.kubernetes.container.name"
+)
+
 type ContainerData struct {
 	// ID is the container ID without the container runtime prefix. For
 	// instance, "cri-o://" for CRI-O.
@@ -39,6 +46,11 @@ type ContainerData struct {
 
 	// Running defines whether or not the container is in the running state
 	Running bool
+
+	// Labels are the labels of the container. For the containers of the
+	// pods, the CRI runtimes set the name and namespace of the pod in
+	// the io.kubernetes.pod.* labels.
+	Labels map[string]string
 }
 
 // ContainerRuntimeClient defines the interface to communicate with the
@@ -194,6 +206,7 @@ func (c *CRIClient) GetContainers() ([]*ContainerData, error) {
 			ID:      container.Id,
 			Name:    strings.TrimPrefix(container.GetMetadata().Name, "/"),
 			Running: container.GetState() == pb.ContainerState_CONTAINER_RUNNING,
+			Labels:  container.Labels,
 		}
 	}
 
@@ -220,6 +233,7 @@ func (c *CRIClient) GetContainer(containerID string) (*ContainerData, error) {
 		ID:      containers[0].Id,
 		Name:    strings.TrimPrefix(containers[0].GetMetadata().Name, "/"),
 		Running: containers[0].GetState() == pb.ContainerState_CONTAINER_RUNNING,
+		Labels:  containers[0].Labels,
 	}, nil
 }
 
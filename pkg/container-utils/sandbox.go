@@ -0,0 +1,93 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package containerutils
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+// SandboxFromRuntimeHandler returns the sandbox the containers created with
+// the given RuntimeClass handler run in, or an empty string if they run
+// directly on the host kernel.
+func SandboxFromRuntimeHandler(handler string) string {
+	handler = strings.ToLower(handler)
+	switch {
+	case handler == "runsc" || strings.HasPrefix(handler, "runsc-") ||
+		strings.Contains(handler, "gvisor"):
+		return eventtypes.SandboxGVisor
+	case strings.HasPrefix(handler, "kata"):
+		return eventtypes.SandboxKata
+	}
+	return ""
+}
+
+// SandboxDetector detects the sandbox of the pods from their RuntimeClass.
+// The handlers of the RuntimeClasses are cached: they can't be changed once
+// the RuntimeClass is created.
+type SandboxDetector struct {
+	client kubernetes.Interface
+
+	mu       sync.Mutex
+	handlers map[string]string
+}
+
+func NewSandboxDetector(client kubernetes.Interface) *SandboxDetector {
+	return &SandboxDetector{
+		client:   client,
+		handlers: make(map[string]string),
+	}
+}
+
+// PodSandbox returns the sandbox the containers of pod run in, or an empty
+// string if they run directly on the host kernel.
+func (d *SandboxDetector) PodSandbox(pod *v1.Pod) string {
+	if pod.Spec.RuntimeClassName == nil || *pod.Spec.RuntimeClassName == "" {
+		return ""
+	}
+	return SandboxFromRuntimeHandler(d.handler(*pod.Spec.RuntimeClassName))
+}
+
+func (d *SandboxDetector) handler(runtimeClassName string) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if handler, ok := d.handlers[runtimeClassName]; ok {
+		return handler
+	}
+
+	runtimeClass, err := d.client.NodeV1().RuntimeClasses().Get(context.TODO(),
+		runtimeClassName, metav1.GetOptions{})
+	if err != nil {
+		// The RuntimeClasses are usually named after their handler:
+		// fall back on the name without caching it, to try again with
+		// the next pod.
+		log.Debugf("Sandbox detector: failed to get RuntimeClass %q: %s",
+			runtimeClassName, err)
+		return runtimeClassName
+	}
+
+	d.handlers[runtimeClassName] = runtimeClass.Handler
+	return runtimeClass.Handler
+}
@@ -0,0 +1,87 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package containerutils
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	nodev1 "k8s.io/api/node/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+func TestSandboxFromRuntimeHandler(t *testing.T) {
+	tests := map[string]string{
+		"":            "",
+		"runc":        "",
+		"crun":        "",
+		"runsc":       eventtypes.SandboxGVisor,
+		"runsc-kvm":   eventtypes.SandboxGVisor,
+		"gvisor":      eventtypes.SandboxGVisor,
+		"kata":        eventtypes.SandboxKata,
+		"kata-qemu":   eventtypes.SandboxKata,
+		"kata-fc":     eventtypes.SandboxKata,
+		"Kata-Clh":    eventtypes.SandboxKata,
+		"nvidia-runc": "",
+	}
+	for handler, expected := range tests {
+		if sandbox := SandboxFromRuntimeHandler(handler); sandbox != expected {
+			t.Errorf("handler %q: expected sandbox %q, got %q", handler, expected, sandbox)
+		}
+	}
+}
+
+func TestSandboxDetector(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&nodev1.RuntimeClass{ObjectMeta: metav1.ObjectMeta{Name: "sandboxed"}, Handler: "runsc"},
+		&nodev1.RuntimeClass{ObjectMeta: metav1.ObjectMeta{Name: "fast"}, Handler: "crun"},
+	)
+	d := NewSandboxDetector(client)
+
+	pod := func(runtimeClassName string) *v1.Pod {
+		p := &v1.Pod{}
+		if runtimeClassName != "" {
+			p.Spec.RuntimeClassName = &runtimeClassName
+		}
+		return p
+	}
+
+	tests := []struct {
+		runtimeClassName string
+		expected         string
+	}{
+		{"", ""},
+		{"sandboxed", eventtypes.SandboxGVisor},
+		{"fast", ""},
+		// Not found: the name is used as the handler
+		{"kata-qemu", eventtypes.SandboxKata},
+	}
+	for _, test := range tests {
+		if sandbox := d.PodSandbox(pod(test.runtimeClassName)); sandbox != test.expected {
+			t.Errorf("RuntimeClass %q: expected sandbox %q, got %q",
+				test.runtimeClassName, test.expected, sandbox)
+		}
+	}
+
+	// The handlers found are cached
+	actions := len(client.Actions())
+	d.PodSandbox(pod("sandboxed"))
+	if len(client.Actions()) != actions {
+		t.Errorf("expected the handler of %q to be cached", "sandboxed")
+	}
+}
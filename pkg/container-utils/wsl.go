@@ -0,0 +1,90 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package containerutils
+
+import (
+	"os"
+	"strings"
+)
+
+// The Windows Subsystem for Linux runs the Linux distributions of Windows
+// hosts. WSL1 translates the syscalls of the processes and has no Linux
+// kernel, so it can't run eBPF programs. WSL2 runs the distributions in a
+// lightweight VM with the Microsoft Linux kernel, which Docker Desktop also
+// uses to run its containers.
+
+type WSLVersion int
+
+const (
+	NotWSL WSLVersion = iota
+	WSL1
+	WSL2
+)
+
+// WSL2KernelHint explains how to provide the kernel features the default
+// WSL2 kernel can lack.
+const WSL2KernelHint = "the default kernel of WSL2 can lack the features needed by " +
+	"the gadgets: build one with the options of docs/requirements.md and set it with " +
+	"the kernel option of the .wslconfig file of Windows"
+
+// WSL2DockerSocketPaths are the paths where the docker socket is looked for
+// on WSL2, after the default one. The docker of Docker Desktop is reachable
+// from the distributions with the WSL integration enabled through
+// /var/run/docker.sock, which isn't always the same as /run/docker.sock
+// as some distributions don't link /var/run to /run.
+var WSL2DockerSocketPaths = []string{
+	"/var/run/docker.sock",
+}
+
+// wslVersionFromRelease tells if a kernel release, as given by
+// /proc/sys/kernel/osrelease, is the one of WSL, e.g.
+// "5.15.90.1-microsoft-standard-WSL2" for WSL2 or "4.4.0-19041-Microsoft"
+// for WSL1. The releases of the kernels built by the users from the
+// Microsoft sources keep the WSL2 suffix.
+func wslVersionFromRelease(release string) WSLVersion {
+	release = strings.TrimSpace(release)
+	switch {
+	case strings.HasSuffix(strings.ToLower(release), "-wsl2"):
+		return WSL2
+	case strings.HasSuffix(release, "-Microsoft"):
+		return WSL1
+	case strings.Contains(strings.ToLower(release), "microsoft"):
+		// The releases of the WSL2 kernels before 5.10 lack the
+		// suffix, e.g. "4.19.128-microsoft-standard".
+		return WSL2
+	}
+	return NotWSL
+}
+
+// DetectWSL tells if the caller runs in a distribution of WSL, and which
+// version of it.
+func DetectWSL() WSLVersion {
+	release, err := os.ReadFile("/proc/sys/kernel/osrelease")
+	if err != nil {
+		return NotWSL
+	}
+	return wslVersionFromRelease(string(release))
+}
+
+// FindSocketPath returns the first of the paths which exists, or an empty
+// string if none does.
+func FindSocketPath(paths ...string) string {
+	for _, path := range paths {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
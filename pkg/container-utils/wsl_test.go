@@ -0,0 +1,57 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package containerutils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWSLVersionFromRelease(t *testing.T) {
+	table := []struct {
+		release  string
+		expected WSLVersion
+	}{
+		{"5.15.90.1-microsoft-standard-WSL2\n", WSL2},
+		{"5.10.102.1-microsoft-standard-WSL2+", WSL2},
+		{"5.15.68-custom-WSL2", WSL2},
+		{"4.19.128-microsoft-standard", WSL2},
+		{"4.4.0-19041-Microsoft", WSL1},
+		{"5.19.0-76051900-generic", NotWSL},
+		{"", NotWSL},
+	}
+
+	for _, entry := range table {
+		if version := wslVersionFromRelease(entry.release); version != entry.expected {
+			t.Errorf("%q: expected %d, got %d", entry.release, entry.expected, version)
+		}
+	}
+}
+
+func TestFindSocketPath(t *testing.T) {
+	dir := t.TempDir()
+	socket := filepath.Join(dir, "docker.sock")
+	if err := os.WriteFile(socket, nil, 0o600); err != nil {
+		t.Fatalf("creating %s: %s", socket, err)
+	}
+
+	if path := FindSocketPath(filepath.Join(dir, "missing.sock"), socket); path != socket {
+		t.Errorf("expected %s, got %q", socket, path)
+	}
+	if path := FindSocketPath(filepath.Join(dir, "missing.sock")); path != "" {
+		t.Errorf("expected no path, got %q", path)
+	}
+}
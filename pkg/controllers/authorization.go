@@ -0,0 +1,88 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+)
+
+//+kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+
+// traceAccessReview returns the SubjectAccessReview checking that the
+// creator of trace has the gadgets.TraceVerb verb on the pods of the
+// namespace selected by its filter, or of all the namespaces if it doesn't
+// select any.
+func traceAccessReview(trace *gadgetv1alpha1.Trace) (*authorizationv1.SubjectAccessReview, error) {
+	username, groups := gadgets.Creator(trace)
+	if username == "" {
+		return nil, fmt.Errorf("the trace doesn't have the %q annotation, required as the traces are authorized",
+			gadgets.CreatorAnnotation)
+	}
+
+	namespace := ""
+	if trace.Spec.Filter != nil {
+		namespace = trace.Spec.Filter.Namespace
+	}
+
+	return &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   username,
+			Groups: groups,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: namespace,
+				Verb:      gadgets.TraceVerb,
+				Resource:  "pods",
+			},
+		},
+	}, nil
+}
+
+// authorizeTrace verifies that the creator of trace is allowed to trace the
+// pods it selects, see traceAccessReview. It returns why the trace is
+// denied, or an error if the permissions couldn't be checked.
+func authorizeTrace(ctx context.Context, cli client.Client, trace *gadgetv1alpha1.Trace) (string, error) {
+	review, err := traceAccessReview(trace)
+	if err != nil {
+		return err.Error(), nil
+	}
+
+	if err := cli.Create(ctx, review); err != nil {
+		return "", fmt.Errorf("failed to check the permissions of %q: %w", review.Spec.User, err)
+	}
+
+	if review.Status.Allowed {
+		return "", nil
+	}
+
+	denied := fmt.Sprintf("user %q is not allowed to trace the pods", review.Spec.User)
+	if ns := review.Spec.ResourceAttributes.Namespace; ns != "" {
+		denied += fmt.Sprintf(" of namespace %q", ns)
+	} else {
+		denied += " of all the namespaces"
+	}
+	denied += fmt.Sprintf(": missing the %q verb on pods", gadgets.TraceVerb)
+	if review.Status.Reason != "" {
+		denied += fmt.Sprintf(" (%s)", review.Status.Reason)
+	}
+
+	return denied, nil
+}
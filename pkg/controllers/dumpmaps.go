@@ -0,0 +1,64 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	"github.com/kinvolk/inspektor-gadget/pkg/mapdump"
+)
+
+// DumpMaps dumps the BPF maps of the trace running on this node, for
+// "kubectl gadget debug dump-maps". The operations on the gadgets are
+// blocked while the maps are dumped, so that they aren't closed meanwhile.
+func (r *TraceReconciler) DumpMaps(ctx context.Context, namespace, name string) (*mapdump.Snapshot, error) {
+	namespacedName := types.NamespacedName{Namespace: namespace, Name: name}
+
+	trace := &gadgetv1alpha1.Trace{}
+	if err := r.Client.Get(ctx, namespacedName, trace); err != nil {
+		return nil, fmt.Errorf("failed to get trace %s: %w", namespacedName, err)
+	}
+	if trace.Spec.Node != r.Node {
+		return nil, fmt.Errorf("trace %s doesn't run on node %q", namespacedName, r.Node)
+	}
+
+	factory, ok := r.TraceFactories[trace.Spec.Gadget].(gadgets.TraceFactoryWithMaps)
+	if !ok {
+		return nil, fmt.Errorf("gadget %q doesn't support dumping its maps", trace.Spec.Gadget)
+	}
+
+	r.opMu.Lock()
+	defer r.opMu.Unlock()
+
+	maps := factory.Maps(namespacedName.String())
+	if maps == nil {
+		return nil, fmt.Errorf("trace %s isn't running on node %q", namespacedName, r.Node)
+	}
+
+	return &mapdump.Snapshot{
+		Node:      r.Node,
+		Namespace: namespace,
+		Trace:     name,
+		Gadget:    trace.Spec.Gadget,
+		Time:      time.Now(),
+		Maps:      mapdump.Dump(maps, mapdump.DefaultMaxEntries),
+	}, nil
+}
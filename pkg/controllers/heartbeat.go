@@ -0,0 +1,116 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+// DefaultHeartbeatInterval is the default period at which the heartbeat
+// events are published.
+const DefaultHeartbeatInterval = 5 * time.Second
+
+// HeartbeatPublisher periodically publishes a DEBUG event in the stream of
+// the traces running on this node whose gadget logs at the debug level, see
+// gadgets.SetLogLevel. The heartbeats tell that the events go all the way
+// from the gadget pod to kubectl-gadget, even when the gadget doesn't
+// produce any.
+type HeartbeatPublisher struct {
+	Client        client.Client
+	Node          string
+	TracerManager *gadgettracermanager.GadgetTracerManager
+	Interval      time.Duration
+}
+
+// Start implements manager.Runnable.
+func (p *HeartbeatPublisher) Start(ctx context.Context) error {
+	interval := p.Interval
+	if interval == 0 {
+		interval = DefaultHeartbeatInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// seq numbers the heartbeats of each trace, so that the lost ones can
+	// be noticed.
+	seq := map[string]uint64{}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			seq = p.publish(ctx, seq)
+		}
+	}
+}
+
+func (p *HeartbeatPublisher) publish(ctx context.Context, seq map[string]uint64) map[string]uint64 {
+	next := map[string]uint64{}
+
+	debugGadgets := gadgets.DebugGadgets()
+	if len(debugGadgets) == 0 {
+		return next
+	}
+	isDebug := make(map[string]bool, len(debugGadgets))
+	for _, gadget := range debugGadgets {
+		isDebug[gadget] = true
+	}
+
+	traces := &gadgetv1alpha1.TraceList{}
+	if err := p.Client.List(ctx, traces); err != nil {
+		log.Errorf("Failed to list traces: %s", err)
+		return seq
+	}
+
+	for i := range traces.Items {
+		trace := &traces.Items[i]
+		if trace.Spec.Node != p.Node || !trace.ObjectMeta.DeletionTimestamp.IsZero() ||
+			!isDebug[trace.Spec.Gadget] {
+			continue
+		}
+
+		traceName := gadgets.TraceName(trace.ObjectMeta.Namespace, trace.ObjectMeta.Name)
+
+		// Traces without tracer, e.g. not reconciled yet, are skipped.
+		stats, err := p.TracerManager.StreamStats(traceName)
+		if err != nil {
+			continue
+		}
+
+		n := seq[traceName] + 1
+		next[traceName] = n
+
+		logger := gadgets.Logger(trace.Spec.Gadget)
+		msg := fmt.Sprintf("heartbeat %d of gadget %s (log level %s): %d events emitted, %d dropped",
+			n, trace.Spec.Gadget, logger.Logger.GetLevel(), stats.EventsEmitted, stats.EventsDropped)
+		if err := p.TracerManager.PublishEvent(traceName, eventtypes.EventString(eventtypes.Debug(msg, p.Node))); err != nil {
+			logger.Debugf("Failed to publish heartbeat of trace %s: %s", traceName, err)
+		}
+	}
+
+	return next
+}
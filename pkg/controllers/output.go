@@ -0,0 +1,92 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/types"
+
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/outputstore"
+)
+
+// loadOutput puts back in the status of the trace its output stored on the
+// node, so that the gadgets see the same status whatever the size of their
+// output. It returns the output loaded.
+func (r *TraceReconciler) loadOutput(namespacedName types.NamespacedName, trace *gadgetv1alpha1.Trace) string {
+	if r.OutputStore == nil || trace.Status.OutputRef == nil {
+		return ""
+	}
+
+	output, err := r.OutputStore.ReadString(gadgets.TraceNameFromNamespacedName(namespacedName))
+	if err != nil {
+		log.Warnf("Failed to read the stored output of trace %s: %s", namespacedName, err)
+		return ""
+	}
+	trace.Status.Output = output
+	return output
+}
+
+// storeOutput moves the output of the trace to the node when it's too large
+// to be written in its status, which would make the update of the status
+// fail. loaded is the output returned by loadOutput before the operation.
+func (r *TraceReconciler) storeOutput(namespacedName types.NamespacedName, trace *gadgetv1alpha1.Trace, loaded string) {
+	if r.OutputStore == nil {
+		return
+	}
+
+	id := gadgets.TraceNameFromNamespacedName(namespacedName)
+
+	switch {
+	case trace.Status.OutputRef != nil && trace.Status.Output == loaded:
+		// The operation didn't change the stored output.
+	case len(trace.Status.Output) <= outputstore.MaxStatusSize:
+		if trace.Status.OutputRef != nil {
+			trace.Status.OutputRef = nil
+			if err := r.OutputStore.Remove(id); err != nil {
+				log.Warnf("Failed to remove the stored output of trace %s: %s", namespacedName, err)
+			}
+		}
+		return
+	default:
+		ref, err := r.OutputStore.Write(id, trace.Status.Output)
+		if err != nil {
+			trace.Status.OperationError = fmt.Sprintf("output of %d bytes too large for the Trace couldn't be stored on the node: %s",
+				len(trace.Status.Output), err)
+			trace.Status.OutputRef = nil
+		} else {
+			log.Infof("Output of trace %s (%d bytes) stored on the node in %d chunk(s)",
+				namespacedName, ref.Size, ref.Chunks)
+			trace.Status.OutputRef = ref
+		}
+	}
+
+	trace.Status.Output = ""
+}
+
+// removeOutput removes the output of a deleted trace stored on the node.
+func (r *TraceReconciler) removeOutput(namespacedName types.NamespacedName) {
+	if r.OutputStore == nil {
+		return
+	}
+
+	err := r.OutputStore.Remove(gadgets.TraceNameFromNamespacedName(namespacedName))
+	if err != nil {
+		log.Errorf("Failed to remove the stored output of trace %s: %s", namespacedName, err)
+	}
+}
@@ -0,0 +1,241 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	resourcelimitstracer "github.com/kinvolk/inspektor-gadget/pkg/gadgets/resourcelimits/tracer"
+	"github.com/kinvolk/inspektor-gadget/pkg/resourcestats"
+)
+
+//+kubebuilder:rbac:groups="",resources=pods,verbs=patch
+
+const (
+	// DefaultResourceStatsInterval is the default period at which the
+	// usage of the gadget pod is sampled.
+	DefaultResourceStatsInterval = 10 * time.Second
+
+	// resourceStatsWindow is the number of samples kept, two hours with
+	// the default interval.
+	resourceStatsWindow = 720
+
+	// resourceStatsPublishEvery is the number of samples between two
+	// updates of the annotation of the gadget pod.
+	resourceStatsPublishEvery = 6
+)
+
+// ResourceStatsUpdater periodically samples the CPU and memory used by the
+// processes of the gadget container, along with the gadgets running on
+// this node, and stores their summary in an annotation of the gadget pod
+// so that kubectl gadget status --resources can recommend resources for
+// the DaemonSet.
+type ResourceStatsUpdater struct {
+	Client    client.Client
+	Node      string
+	PodName   string
+	Namespace string
+	Interval  time.Duration
+
+	samples   []resourcestats.Sample
+	prevTicks map[int]uint64
+	prevTime  time.Time
+}
+
+// Start implements manager.Runnable.
+func (u *ResourceStatsUpdater) Start(ctx context.Context) error {
+	interval := u.Interval
+	if interval == 0 {
+		interval = DefaultResourceStatsInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for taken := 0; ; {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if !u.sample(ctx) {
+				continue
+			}
+			taken++
+			if taken%resourceStatsPublishEvery == 0 {
+				u.publish(ctx)
+			}
+		}
+	}
+}
+
+// gadgetPids returns the processes of the gadget container: the ones
+// sharing our mount namespace. This includes the processes started by the
+// gadgets, like the BCC-based ones.
+func gadgetPids() ([]int, error) {
+	self, err := os.Readlink("/proc/self/ns/mnt")
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := ioutil.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+
+	pids := []int{}
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		mntns, err := os.Readlink(fmt.Sprintf("/proc/%d/ns/mnt", pid))
+		if err != nil || mntns != self {
+			continue
+		}
+		pids = append(pids, pid)
+	}
+
+	return pids, nil
+}
+
+// runningGadgets returns the sorted names of the gadgets having a started
+// trace on this node.
+func (u *ResourceStatsUpdater) runningGadgets(ctx context.Context) ([]string, error) {
+	traces := &gadgetv1alpha1.TraceList{}
+	if err := u.Client.List(ctx, traces); err != nil {
+		return nil, err
+	}
+
+	set := map[string]struct{}{}
+	for _, trace := range traces.Items {
+		if trace.Spec.Node != u.Node || trace.Status.State != "Started" {
+			continue
+		}
+		set[trace.Spec.Gadget] = struct{}{}
+	}
+
+	gadgets := make([]string, 0, len(set))
+	for gadget := range set {
+		gadgets = append(gadgets, gadget)
+	}
+	sort.Strings(gadgets)
+
+	return gadgets, nil
+}
+
+// sample records the usage of the gadget container since the previous
+// call. It returns false if no sample was recorded, e.g. on the first call
+// as the CPU usage is computed from the difference between two calls.
+func (u *ResourceStatsUpdater) sample(ctx context.Context) bool {
+	pids, err := gadgetPids()
+	if err != nil {
+		log.Warnf("Failed to list the processes of the gadget container: %s", err)
+		return false
+	}
+
+	gadgets, err := u.runningGadgets(ctx)
+	if err != nil {
+		log.Warnf("Failed to list traces: %s", err)
+		return false
+	}
+
+	now := time.Now()
+	var ticks, rss uint64
+	currTicks := make(map[int]uint64, len(pids))
+
+	for _, pid := range pids {
+		s, err := resourcelimitstracer.ReadProcessSample(pid)
+		if err != nil {
+			// The process terminated in the meantime.
+			continue
+		}
+
+		rss += s.RSS
+		currTicks[pid] = s.Ticks
+
+		// Processes started since the previous sample used all their CPU
+		// time during the interval.
+		if prev := u.prevTicks[pid]; s.Ticks >= prev {
+			ticks += s.Ticks - prev
+		}
+	}
+
+	prevTicks, prevTime := u.prevTicks, u.prevTime
+	u.prevTicks, u.prevTime = currTicks, now
+	if prevTicks == nil {
+		return false
+	}
+
+	millicores := float64(ticks) / resourcelimitstracer.UserHZ / now.Sub(prevTime).Seconds() * 1000
+
+	u.samples = append(u.samples, resourcestats.Sample{
+		CPU:     uint64(math.Round(millicores)),
+		Memory:  rss,
+		Gadgets: gadgets,
+	})
+	if len(u.samples) > resourceStatsWindow {
+		u.samples = u.samples[len(u.samples)-resourceStatsWindow:]
+	}
+
+	return true
+}
+
+// publish stores the summary of the samples in an annotation of the gadget
+// pod.
+func (u *ResourceStatsUpdater) publish(ctx context.Context) {
+	stats, err := json.Marshal(resourcestats.Summarize(u.Node, u.samples))
+	if err != nil {
+		log.Errorf("Failed to marshal resource stats: %s", err)
+		return
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{
+				resourcestats.Annotation: string(stats),
+			},
+		},
+	})
+	if err != nil {
+		log.Errorf("Failed to marshal resource stats patch: %s", err)
+		return
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      u.PodName,
+			Namespace: u.Namespace,
+		},
+	}
+	if err := u.Client.Patch(ctx, pod, client.RawPatch(types.MergePatchType, patch)); err != nil {
+		log.Errorf("Failed to update the resource stats of pod %s/%s: %s",
+			u.Namespace, u.PodName, err)
+	}
+}
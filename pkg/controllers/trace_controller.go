@@ -20,11 +20,13 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"sync"
 
 	log "github.com/sirupsen/logrus"
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -33,6 +35,11 @@ import (
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager"
 	pb "github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/api"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/outputstore"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/sink"
+	"github.com/kinvolk/inspektor-gadget/pkg/operationqueue"
+	"github.com/kinvolk/inspektor-gadget/pkg/snapshotcache"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
 )
 
 const (
@@ -53,6 +60,29 @@ type TraceReconciler struct {
 	// TraceFactories contains the trace factories keyed by the gadget name
 	TraceFactories map[string]gadgets.TraceFactory
 	TracerManager  *gadgettracermanager.GadgetTracerManager
+
+	// WithoutBPF is set when the gadget pods don't have the privileges
+	// needed by eBPF, TraceFactories only containing the gadgets able to
+	// run without them.
+	WithoutBPF bool
+
+	// AuthorizeTraces rejects the traces whose creator isn't allowed to
+	// trace the pods they select, see authorizeTrace.
+	AuthorizeTraces bool
+
+	// OutputStore stores the outputs too large to be written in the status
+	// of the traces. They are written as is if it's nil.
+	OutputStore *outputstore.Store
+
+	// seenTraces contains the traces reconciled by this instance, to
+	// resume the ones started by the previous one, see resumeTrace.
+	seenMu     sync.Mutex
+	seenTraces map[string]struct{}
+
+	// opMu serializes the operations on the gadgets with the dumps of
+	// their maps, so that the maps aren't closed while being dumped, see
+	// DumpMaps.
+	opMu sync.Mutex
 }
 
 func updateTraceStatus(ctx context.Context, cli client.Client,
@@ -127,7 +157,9 @@ func (r *TraceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 			// Inform the factory (if valid gadget) that the trace is being deleted
 			factory, ok := r.TraceFactories[trace.Spec.Gadget]
 			if ok {
+				r.opMu.Lock()
 				factory.Delete(req.NamespacedName.String())
+				r.opMu.Unlock()
 			}
 
 			if r.TracerManager != nil {
@@ -139,6 +171,9 @@ func (r *TraceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 				}
 			}
 
+			r.forgetTrace(req.NamespacedName.String())
+			r.removeOutput(req.NamespacedName)
+
 			// Remove our finalizer
 			controllerutil.RemoveFinalizer(trace, GadgetFinalizer)
 			if err := r.Client.Update(ctx, trace); err != nil {
@@ -156,8 +191,12 @@ func (r *TraceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 	// the Reconcile() from being called again and again by the controller.
 	factory, ok := r.TraceFactories[trace.Spec.Gadget]
 	if !ok {
-		setTraceOpError(ctx, r.Client, req.NamespacedName.String(),
-			trace, fmt.Sprintf("Unknown gadget %q", trace.Spec.Gadget))
+		msg := fmt.Sprintf("Unknown gadget %q", trace.Spec.Gadget)
+		if r.WithoutBPF {
+			msg = fmt.Sprintf("Gadget %q is not available: the gadget pod on node %q "+
+				"doesn't have the privileges needed by eBPF", trace.Spec.Gadget, r.Node)
+		}
+		setTraceOpError(ctx, r.Client, req.NamespacedName.String(), trace, msg)
 
 		return ctrl.Result{}, nil
 	}
@@ -176,6 +215,25 @@ func (r *TraceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 
 		return ctrl.Result{}, nil
 	}
+	if err := gadgets.CheckEnforcement(factory, trace); err != nil {
+		setTraceOpError(ctx, r.Client, req.NamespacedName.String(),
+			trace, err.Error())
+
+		return ctrl.Result{}, nil
+	}
+	if r.AuthorizeTraces {
+		denied, err := authorizeTrace(ctx, r.Client, trace)
+		if err != nil {
+			log.Errorf("Failed to authorize trace %q: %s", req.NamespacedName, err)
+			return ctrl.Result{}, err
+		}
+		if denied != "" {
+			setTraceOpError(ctx, r.Client, req.NamespacedName.String(),
+				trace, denied)
+
+			return ctrl.Result{}, nil
+		}
+	}
 
 	// The Trace is not being deleted and specs are valid, we can register our finalizer
 	beforeFinalizer := trace.DeepCopy()
@@ -196,6 +254,32 @@ func (r *TraceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 			log.Errorf("Failed to add tracer BPF map: %s", err)
 			return ctrl.Result{}, err
 		}
+
+		// The events are written to the sinks whatever the operation, so
+		// they are set up, or updated, before it.
+		configs, err := sink.ParseConfigs(trace.Spec.Parameters)
+		if err == nil {
+			err = r.TracerManager.SetSinks(
+				gadgets.TraceNameFromNamespacedName(req.NamespacedName),
+				sink.Trace{
+					Namespace: trace.ObjectMeta.Namespace,
+					Name:      trace.ObjectMeta.Name,
+					Gadget:    trace.Spec.Gadget,
+					Node:      trace.Spec.Node,
+				},
+				configs,
+			)
+		}
+		if err != nil {
+			setTraceOpError(ctx, r.Client, req.NamespacedName.String(),
+				trace, fmt.Sprintf("Invalid sinks: %s", err))
+
+			return ctrl.Result{}, nil
+		}
+	}
+
+	if r.firstSeen(req.NamespacedName.String()) {
+		r.resumeTrace(ctx, req.NamespacedName, trace, factory)
 	}
 
 	// Lookup annotations
@@ -204,38 +288,17 @@ func (r *TraceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 		return ctrl.Result{}, nil
 	}
 
-	// For now, only support control via the GADGET_OPERATION
-	var op string
-	if op, ok = trace.ObjectMeta.Annotations[GadgetOperation]; !ok {
+	op, err := r.takeOperation(ctx, req.NamespacedName, trace)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if op == "" {
 		log.Info("No operation annotation. Nothing to do.")
 		return ctrl.Result{}, nil
 	}
 
-	params := make(map[string]string)
-	for k, v := range trace.ObjectMeta.Annotations {
-		if !strings.HasPrefix(k, GadgetOperation+"-") {
-			continue
-		}
-		params[strings.TrimPrefix(k, GadgetOperation+"-")] = v
-	}
-
 	log.Infof("Gadget %s operation %q on %s", trace.Spec.Gadget, op, req.NamespacedName)
 
-	// Remove annotations first to avoid another execution in the next
-	// reconciliation loop.
-	withAnnotation := trace.DeepCopy()
-	annotations := trace.GetAnnotations()
-	delete(annotations, GadgetOperation)
-	for k := range params {
-		delete(annotations, GadgetOperation+"-"+k)
-	}
-	trace.SetAnnotations(annotations)
-	err = r.Client.Patch(ctx, trace, client.MergeFrom(withAnnotation))
-	if err != nil {
-		log.Errorf("Failed to update trace: %s", err)
-		return ctrl.Result{}, err
-	}
-
 	// Check operation is supported for this specific gadget
 	gadgetOperation, ok := factory.Operations()[op]
 	if !ok {
@@ -251,7 +314,19 @@ func (r *TraceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 	trace.Status.OperationError = ""
 	trace.Status.OperationWarning = ""
 	patch := client.MergeFrom(traceBeforeOperation)
+	loadedOutput := r.loadOutput(req.NamespacedName, trace)
+	logger := gadgets.Logger(trace.Spec.Gadget)
+	logger.Debugf("Calling operation %q on %s with parameters %v", op, req.NamespacedName, trace.Spec.Parameters)
+	r.opMu.Lock()
 	gadgetOperation.Operation(req.NamespacedName.String(), trace)
+	r.opMu.Unlock()
+	logger.Debugf("Operation %q on %s returned state %q, error %q, warning %q", op, req.NamespacedName,
+		trace.Status.State, trace.Status.OperationError, trace.Status.OperationWarning)
+	snapshotcache.SetETag(trace)
+	r.storeOutput(req.NamespacedName, trace, loadedOutput)
+	if op != "stop" && trace.Status.OperationError == "" {
+		r.warnSandboxes(req.NamespacedName, factory, trace)
+	}
 
 	if apiequality.Semantic.DeepEqual(traceBeforeOperation.Status, trace.Status) {
 		log.Info("Gadget completed operation without changing the trace status")
@@ -263,6 +338,173 @@ func (r *TraceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 	return ctrl.Result{}, nil
 }
 
+// takeOperation returns the next operation to apply on the trace, and
+// removes it from the annotations to avoid another execution in the next
+// reconciliation loop. The operation set with the GADGET_OPERATION
+// annotation, e.g. when the trace is created, comes before the ones queued
+// by the clients. It returns an empty string if there isn't any.
+func (r *TraceReconciler) takeOperation(ctx context.Context,
+	namespacedName types.NamespacedName,
+	trace *gadgetv1alpha1.Trace,
+) (string, error) {
+	annotations := trace.GetAnnotations()
+
+	if op, ok := annotations[GadgetOperation]; ok {
+		withAnnotation := trace.DeepCopy()
+		delete(annotations, GadgetOperation)
+		for k := range annotations {
+			if strings.HasPrefix(k, GadgetOperation+"-") {
+				delete(annotations, k)
+			}
+		}
+		trace.SetAnnotations(annotations)
+		if err := r.Client.Patch(ctx, trace, client.MergeFrom(withAnnotation)); err != nil {
+			log.Errorf("Failed to update trace: %s", err)
+			return "", err
+		}
+		return op, nil
+	}
+
+	if _, ok := annotations[operationqueue.Annotation]; !ok {
+		return "", nil
+	}
+
+	// The patch fails if a client appended an operation in the meantime:
+	// the trace is then reconciled again with the updated queue.
+	ops, parseErr := operationqueue.Parse(annotations)
+	patch, err := operationqueue.PopPatch(annotations)
+	if err != nil {
+		return "", err
+	}
+	if err := r.Client.Patch(ctx, trace, client.RawPatch(types.JSONPatchType, patch)); err != nil {
+		log.Errorf("Failed to remove operation from the queue of trace %q: %s", namespacedName, err)
+		return "", err
+	}
+
+	if parseErr != nil {
+		setTraceOpError(ctx, r.Client, namespacedName.String(), trace, parseErr.Error())
+		return "", nil
+	}
+	if len(ops) == 0 {
+		return "", nil
+	}
+	return ops[0], nil
+}
+
+// firstSeen tells if the trace is reconciled for the first time by this
+// instance.
+func (r *TraceReconciler) firstSeen(name string) bool {
+	r.seenMu.Lock()
+	defer r.seenMu.Unlock()
+
+	if r.seenTraces == nil {
+		r.seenTraces = make(map[string]struct{})
+	}
+	if _, ok := r.seenTraces[name]; ok {
+		return false
+	}
+	r.seenTraces[name] = struct{}{}
+	return true
+}
+
+func (r *TraceReconciler) forgetTrace(name string) {
+	r.seenMu.Lock()
+	defer r.seenMu.Unlock()
+
+	delete(r.seenTraces, name)
+}
+
+// resumeTrace starts again a trace which was started when the previous
+// instance of the gadget pod stopped, e.g. because it was upgraded or
+// killed by the OOM killer. The trace spec is stored in the Trace resource
+// and the pinned maps of the tracer are created again when it's registered,
+// so the gadget only has to be started again. A RESUMED event is published
+// to tell the consumers of the stream that the events in between were
+// lost.
+func (r *TraceReconciler) resumeTrace(ctx context.Context,
+	namespacedName types.NamespacedName,
+	trace *gadgetv1alpha1.Trace,
+	factory gadgets.TraceFactory,
+) {
+	if trace.Status.State != "Started" {
+		return
+	}
+	// The pending operation, if any, takes precedence.
+	if _, ok := trace.ObjectMeta.Annotations[GadgetOperation]; ok {
+		return
+	}
+	if operationqueue.Pending(trace.ObjectMeta.Annotations) {
+		return
+	}
+	startOperation, ok := factory.Operations()["start"]
+	if !ok {
+		return
+	}
+
+	log.Infof("Resuming trace %s (gadget %s) started before the restart of the gadget pod",
+		namespacedName, trace.Spec.Gadget)
+
+	patch := client.MergeFrom(trace.DeepCopy())
+	trace.Status.OperationError = ""
+	trace.Status.OperationWarning = ""
+	r.opMu.Lock()
+	startOperation.Operation(namespacedName.String(), trace)
+	r.opMu.Unlock()
+
+	if trace.Status.OperationError == "" {
+		msg := "trace resumed after a restart of the gadget pod: " +
+			"the events in between and the data collected before were lost"
+		if trace.Status.OperationWarning != "" {
+			trace.Status.OperationWarning += "; "
+		}
+		trace.Status.OperationWarning += msg
+
+		if r.TracerManager != nil {
+			event := eventtypes.Event{
+				Type:    eventtypes.RESUMED,
+				Node:    r.Node,
+				Message: msg,
+			}
+			err := r.TracerManager.PublishEvent(gadgets.TraceNameFromNamespacedName(namespacedName),
+				eventtypes.EventString(event))
+			if err != nil {
+				log.Warnf("Failed to publish RESUMED event of trace %s: %s", namespacedName, err)
+			}
+		}
+	}
+
+	updateTraceStatus(ctx, r.Client, namespacedName.String(), trace, patch)
+}
+
+// warnSandboxes tells when the trace selects containers running in a
+// sandbox the gadget can't observe, so that users don't wonder why these
+// containers don't produce any data. The warning is added to the status of
+// the trace and published as a WARN event for the gadgets streaming their
+// events.
+func (r *TraceReconciler) warnSandboxes(namespacedName types.NamespacedName,
+	factory gadgets.TraceFactory,
+	trace *gadgetv1alpha1.Trace,
+) {
+	if r.TracerManager == nil {
+		return
+	}
+	msg := gadgets.SandboxWarning(factory, r.TracerManager, trace)
+	if msg == "" {
+		return
+	}
+
+	if trace.Status.OperationWarning != "" {
+		trace.Status.OperationWarning += "; "
+	}
+	trace.Status.OperationWarning += msg
+
+	err := r.TracerManager.PublishEvent(gadgets.TraceNameFromNamespacedName(namespacedName),
+		eventtypes.EventString(eventtypes.Warn(msg, r.Node)))
+	if err != nil {
+		log.Debugf("Failed to publish the sandbox warning of trace %s: %s", namespacedName, err)
+	}
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *TraceReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
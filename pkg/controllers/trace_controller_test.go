@@ -28,6 +28,7 @@ import (
 
 	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	"github.com/kinvolk/inspektor-gadget/pkg/operationqueue"
 )
 
 // FakeFactory is a fake implementation of the TraceFactory interface for
@@ -36,6 +37,9 @@ type FakeFactory struct {
 	gadgets.BaseFactory
 	mu    sync.Mutex
 	calls map[string]struct{}
+
+	// applied lists the operations applied, in order
+	applied []string
 }
 
 func NewFakeFactory() gadgets.TraceFactory {
@@ -69,18 +73,29 @@ func (f *FakeFactory) Operations() map[string]gadgets.TraceOperation {
 				f.LookupOrCreate(name, n).(*FakeFactory).Magic(trace)
 			},
 		},
+		"trick": {
+			Doc: "Do nothing but being recorded",
+			Operation: func(name string, trace *gadgetv1alpha1.Trace) {
+				f.LookupOrCreate(name, n).(*FakeFactory).record(trace, "trick")
+			},
+		},
 	}
 }
 
-func (f *FakeFactory) Magic(trace *gadgetv1alpha1.Trace) {
+func (f *FakeFactory) record(trace *gadgetv1alpha1.Trace, operation string) {
 	f.mu.Lock()
 	key := fmt.Sprintf("operation/%s/%s/%s/",
 		trace.ObjectMeta.Namespace,
 		trace.ObjectMeta.Name,
-		"magic",
+		operation,
 	)
 	f.calls[key] = struct{}{}
+	f.applied = append(f.applied, operation)
 	f.mu.Unlock()
+}
+
+func (f *FakeFactory) Magic(trace *gadgetv1alpha1.Trace) {
+	f.record(trace, "magic")
 
 	trace.Status.OperationError = "FakeError"
 	trace.Status.OperationWarning = "FakeWarning"
@@ -111,6 +126,24 @@ func OperationMethodHasBeenCalled(factory gadgets.TraceFactory, name, operation
 	}
 }
 
+// AppliedOperations returns a Gomega assertion returning the operations
+// applied on the gadget, in order
+func AppliedOperations(factory gadgets.TraceFactory) func() []string {
+	fakeGadget := factory.(*FakeFactory)
+	return func() []string {
+		fakeGadget.mu.Lock()
+		defer fakeGadget.mu.Unlock()
+		return append([]string{}, fakeGadget.applied...)
+	}
+}
+
+// forgetAppliedOperations forgets the operations applied on the gadget
+func (f *FakeFactory) forgetAppliedOperations() {
+	f.mu.Lock()
+	f.applied = nil
+	f.mu.Unlock()
+}
+
 // DeleteMethodHasBeenCalled returns a Gomega assertion checking if the method
 // Delete() has been called
 func DeleteMethodHasBeenCalled(factory gadgets.TraceFactory, name string) func() bool {
@@ -254,5 +287,47 @@ var _ = Context("Controller with a fake gadget", func() {
 			Eventually(DeleteMethodHasBeenCalled(fakeFactory, traceObjectKey.String())).Should(BeTrue())
 			Consistently(DeleteMethodHasBeenCalled(fakeFactory, traceObjectKey.String())).Should(BeFalse())
 		})
+
+		It("should apply the queued operations in order", func() {
+			traceObjectKey := client.ObjectKey{
+				Name:      "myqueuedtrace",
+				Namespace: ns.Name,
+			}
+
+			myTrace := &gadgetv1alpha1.Trace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      traceObjectKey.Name,
+					Namespace: traceObjectKey.Namespace,
+					Annotations: map[string]string{
+						GadgetOperation:           "trick",
+						operationqueue.Annotation: `["magic","trick"]`,
+					},
+				},
+				Spec: gadgetv1alpha1.TraceSpec{
+					Node:       "fake-node",
+					Gadget:     "fakegadget",
+					RunMode:    "Manual",
+					OutputMode: "Status",
+				},
+			}
+
+			// Forget the operations applied by the previous tests
+			fakeFactory.(*FakeFactory).forgetAppliedOperations()
+
+			err := k8sClient.Create(ctx, myTrace)
+			Expect(err).NotTo(HaveOccurred(), "failed to create test Trace resource")
+
+			Eventually(UpdatedTrace(ctx, traceObjectKey)).Should(SatisfyAll(
+				HaveState("Completed"),
+				HaveAnnotation(GadgetOperation, ""),
+				HaveAnnotation(operationqueue.Annotation, ""),
+			))
+			Eventually(AppliedOperations(fakeFactory)).Should(Equal([]string{"trick", "magic", "trick"}))
+
+			err = k8sClient.Delete(ctx, myTrace)
+			Expect(err).NotTo(HaveOccurred(), "failed to delete test Trace resource")
+
+			Eventually(DeleteMethodHasBeenCalled(fakeFactory, traceObjectKey.String())).Should(BeTrue())
+		})
 	})
 })
@@ -0,0 +1,127 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/sink"
+)
+
+// DefaultTraceStatsInterval is the default period at which the event
+// counters of the traces are updated.
+const DefaultTraceStatsInterval = 5 * time.Second
+
+// TraceStatsUpdater periodically copies the event counters of the tracers
+// running on this node into the status of the corresponding Trace
+// resources, so that users can tell if a trace is producing data.
+type TraceStatsUpdater struct {
+	Client        client.Client
+	Node          string
+	TracerManager *gadgettracermanager.GadgetTracerManager
+	Interval      time.Duration
+}
+
+// Start implements manager.Runnable.
+func (u *TraceStatsUpdater) Start(ctx context.Context) error {
+	interval := u.Interval
+	if interval == 0 {
+		interval = DefaultTraceStatsInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			u.update(ctx)
+		}
+	}
+}
+
+func (u *TraceStatsUpdater) update(ctx context.Context) {
+	traces := &gadgetv1alpha1.TraceList{}
+	if err := u.Client.List(ctx, traces); err != nil {
+		log.Errorf("Failed to list traces: %s", err)
+		return
+	}
+
+	for i := range traces.Items {
+		trace := &traces.Items[i]
+		if trace.Spec.Node != u.Node || !trace.ObjectMeta.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		// Traces without tracer, e.g. not reconciled yet, are skipped.
+		stats, err := u.TracerManager.StreamStats(gadgets.TraceName(trace.ObjectMeta.Namespace, trace.ObjectMeta.Name))
+		if err != nil {
+			continue
+		}
+
+		sinks := sinkStatuses(u.TracerManager.SinkStatuses(
+			gadgets.TraceName(trace.ObjectMeta.Namespace, trace.ObjectMeta.Name)))
+
+		if trace.Status.EventsEmitted == int64(stats.EventsEmitted) &&
+			trace.Status.EventsDropped == int64(stats.EventsDropped) &&
+			trace.Status.Subscribers == stats.Subscribers &&
+			apiequality.Semantic.DeepEqual(trace.Status.Sinks, sinks) {
+			continue
+		}
+
+		patch := client.MergeFrom(trace.DeepCopy())
+		trace.Status.EventsEmitted = int64(stats.EventsEmitted)
+		trace.Status.EventsDropped = int64(stats.EventsDropped)
+		trace.Status.Subscribers = stats.Subscribers
+		trace.Status.Sinks = sinks
+
+		if err := u.Client.Status().Patch(ctx, trace, patch); err != nil {
+			log.Errorf("Failed to update trace %s/%s event counters: %s",
+				trace.ObjectMeta.Namespace, trace.ObjectMeta.Name, err)
+		}
+	}
+}
+
+// sinkStatuses converts the health of the sinks of a tracer to the one of
+// the trace status. It returns nil if the tracer has no sinks, so that the
+// field is omitted.
+func sinkStatuses(statuses []sink.Status) []gadgetv1alpha1.SinkStatus {
+	if len(statuses) == 0 {
+		return nil
+	}
+
+	sinks := make([]gadgetv1alpha1.SinkStatus, 0, len(statuses))
+	for _, s := range statuses {
+		sinks = append(sinks, gadgetv1alpha1.SinkStatus{
+			Kind:          s.Kind,
+			Target:        s.Target,
+			Healthy:       s.Healthy,
+			EventsWritten: int64(s.EventsWritten),
+			EventsDropped: int64(s.EventsDropped),
+			LastError:     s.LastError,
+		})
+	}
+	return sinks
+}
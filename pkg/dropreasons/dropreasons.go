@@ -0,0 +1,88 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package dropreasons gives the names of the reasons of the packet drops,
+// as reported by the kfree_skb tracepoint.
+package dropreasons
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// kfreeSkbFormats are the paths of the format of the kfree_skb tracepoint,
+// depending on where tracefs is mounted.
+var kfreeSkbFormats = []string{
+	"/sys/kernel/debug/tracing/events/skb/kfree_skb/format",
+	"/sys/kernel/tracing/events/skb/kfree_skb/format",
+}
+
+var reasonRegexp = regexp.MustCompile(`\{\s*(\d+)\s*,\s*"(\w+)"\s*\}`)
+
+// Read returns the names of the reasons of the drops, by their value in
+// the running kernel. The values aren't stable across kernel versions, so
+// they are taken from the format of the tracepoint rather than from a
+// hardcoded list. It returns an empty map on kernels not giving the reason
+// of the drops.
+func Read() (map[uint32]string, error) {
+	for _, path := range kfreeSkbFormats {
+		format, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return parse(string(format))
+	}
+
+	return nil, fmt.Errorf("format of the kfree_skb tracepoint not found in %v", kfreeSkbFormats)
+}
+
+// parse parses the __print_symbolic() call printing the reason in the
+// format of the kfree_skb tracepoint.
+func parse(format string) (map[uint32]string, error) {
+	reasons := make(map[uint32]string)
+
+	i := strings.Index(format, "__print_symbolic(REC->reason")
+	if i == -1 {
+		return reasons, nil
+	}
+
+	for _, match := range reasonRegexp.FindAllStringSubmatch(format[i:], -1) {
+		value, err := strconv.ParseUint(match[1], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("parsing the value of the drop reason %s: %w", match[2], err)
+		}
+		reasons[uint32(value)] = match[2]
+	}
+
+	return reasons, nil
+}
+
+// Name returns the name of a reason of drops, as given by the kernel.
+func Name(reasons map[uint32]string, reason uint32) string {
+	if name, ok := reasons[reason]; ok {
+		return name
+	}
+	if reason == 0 && len(reasons) == 0 {
+		// The kernel doesn't give the reason of the drops
+		return ""
+	}
+	return strconv.FormatUint(uint64(reason), 10)
+}
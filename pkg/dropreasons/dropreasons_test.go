@@ -0,0 +1,88 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dropreasons
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDropReasons(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		expected map[uint32]string
+	}{
+		{
+			name: "without reason",
+			format: `name: kfree_skb
+ID: 1430
+format:
+	field:void * skbaddr;	offset:8;	size:8;	signed:0;
+	field:void * location;	offset:16;	size:8;	signed:0;
+	field:unsigned short protocol;	offset:24;	size:2;	signed:0;
+
+print fmt: "skbaddr=%p protocol=%u location=%p", REC->skbaddr, REC->protocol, REC->location
+`,
+			expected: map[uint32]string{},
+		},
+		{
+			name: "with reason",
+			format: `name: kfree_skb
+ID: 1473
+format:
+	field:void * skbaddr;	offset:8;	size:8;	signed:0;
+	field:void * location;	offset:16;	size:8;	signed:0;
+	field:unsigned short protocol;	offset:24;	size:2;	signed:0;
+	field:enum skb_drop_reason reason;	offset:28;	size:4;	signed:0;
+
+print fmt: "skbaddr=%p protocol=%u location=%p reason: %s", REC->skbaddr, REC->protocol, REC->location, __print_symbolic(REC->reason, { 1, "NOT_SPECIFIED" }, { 2, "NO_SOCKET" }, { 3, "PKT_TOO_SMALL" }, { 13,"NETFILTER_DROP" }, { 67, "MAX" })
+`,
+			expected: map[uint32]string{
+				1:  "NOT_SPECIFIED",
+				2:  "NO_SOCKET",
+				3:  "PKT_TOO_SMALL",
+				13: "NETFILTER_DROP",
+				67: "MAX",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reasons, err := parse(test.format)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(reasons, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, reasons)
+			}
+		})
+	}
+}
+
+func TestReasonName(t *testing.T) {
+	reasons := map[uint32]string{1: "NOT_SPECIFIED", 2: "NO_SOCKET"}
+
+	if name := Name(reasons, 2); name != "NO_SOCKET" {
+		t.Fatalf("expected NO_SOCKET, got %q", name)
+	}
+	if name := Name(reasons, 42); name != "42" {
+		t.Fatalf("expected 42, got %q", name)
+	}
+	if name := Name(map[uint32]string{}, 0); name != "" {
+		t.Fatalf("expected no reason, got %q", name)
+	}
+}
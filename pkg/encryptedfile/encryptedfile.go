@@ -0,0 +1,348 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package encryptedfile implements the format of the files written
+// encrypted on the nodes, so that the events of sensitive gadgets aren't
+// stored in plaintext there. Only the holder of the private key matching
+// the public key given to the trace can read them.
+//
+// A file is made of segments, one for each time it was opened for writing,
+// so that it can be appended to by several traces:
+//
+//	header: "IGE1" | segment ID (uint64) | key length (uint16) | key
+//	record: 'D' | segment ID (uint64) | data length (uint32) | data
+//
+// The key of a segment is a random AES-256 key encrypted with RSA-OAEP
+// (SHA-256) and each record holds the data of a Write encrypted with
+// AES-GCM, the nonce being the index of the record in the segment. The
+// segment ID is random: traces writing to the same file at the same time
+// interleave the headers and records of their segments, the ID telling to
+// which segment each record belongs.
+package encryptedfile
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// Magic starts each segment of an encrypted file.
+	Magic = "IGE1"
+
+	recordData = 'D'
+
+	segmentIDSize = 8
+
+	keySize = 32
+
+	// maxRecordSize limits the memory used to read a corrupted file.
+	maxRecordSize = 64 << 20
+
+	// minRSABits is the minimum size of the RSA keys accepted.
+	minRSABits = 2048
+)
+
+var oaepLabel = []byte("inspektor-gadget")
+
+// ParsePublicKey parses a PEM encoded RSA public key, in the PKIX format
+// ("PUBLIC KEY") as written by "openssl rsa -pubout" or in the PKCS #1
+// format ("RSA PUBLIC KEY").
+func ParsePublicKey(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM encoded public key found")
+	}
+
+	var pub *rsa.PublicKey
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing public key: %w", err)
+		}
+		var ok bool
+		if pub, ok = key.(*rsa.PublicKey); !ok {
+			return nil, errors.New("only RSA public keys are supported")
+		}
+	case "RSA PUBLIC KEY":
+		var err error
+		if pub, err = x509.ParsePKCS1PublicKey(block.Bytes); err != nil {
+			return nil, fmt.Errorf("parsing public key: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unexpected PEM block %q, expected a public key", block.Type)
+	}
+
+	if pub.N.BitLen() < minRSABits {
+		return nil, fmt.Errorf("RSA keys must be at least %d bits long", minRSABits)
+	}
+
+	return pub, nil
+}
+
+// ParsePrivateKey parses a PEM encoded RSA private key, in the PKCS #8
+// ("PRIVATE KEY") or PKCS #1 ("RSA PRIVATE KEY") format.
+func ParsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM encoded private key found")
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing private key: %w", err)
+		}
+		priv, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("only RSA private keys are supported")
+		}
+		return priv, nil
+	case "RSA PRIVATE KEY":
+		priv, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing private key: %w", err)
+		}
+		return priv, nil
+	}
+
+	return nil, fmt.Errorf("unexpected PEM block %q, expected a private key", block.Type)
+}
+
+func nonce(aead cipher.AEAD, index uint64) []byte {
+	n := make([]byte, aead.NonceSize())
+	binary.BigEndian.PutUint64(n[len(n)-8:], index)
+	return n
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Writer encrypts the data written to it as a segment of an encrypted
+// file.
+type Writer struct {
+	w     io.Writer
+	id    [segmentIDSize]byte
+	aead  cipher.AEAD
+	index uint64
+}
+
+// NewWriter starts a new segment encrypted for pub in w.
+func NewWriter(w io.Writer, pub *rsa.PublicKey) (*Writer, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+
+	encryptedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, key, oaepLabel)
+	if err != nil {
+		return nil, fmt.Errorf("encrypting key: %w", err)
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	writer := &Writer{w: w, aead: aead}
+	if _, err := rand.Read(writer.id[:]); err != nil {
+		return nil, err
+	}
+
+	// Like the records, the header is written with a single call.
+	header := make([]byte, 0, len(Magic)+segmentIDSize+2+len(encryptedKey))
+	header = append(header, Magic...)
+	header = append(header, writer.id[:]...)
+	header = append(header, byte(len(encryptedKey)>>8), byte(len(encryptedKey)))
+	header = append(header, encryptedKey...)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+
+	return writer, nil
+}
+
+// Write writes p as a single encrypted record. The record is written with
+// a single call to the underlying writer, so that the records written to a
+// file opened with O_APPEND aren't interleaved. The segment ID is
+// authenticated with the data, so that records can't be moved from a
+// segment to another.
+func (w *Writer) Write(p []byte) (int, error) {
+	const prefixSize = 1 + segmentIDSize + 4
+
+	record := make([]byte, prefixSize, prefixSize+len(p)+w.aead.Overhead())
+	record[0] = recordData
+	copy(record[1:], w.id[:])
+	record = w.aead.Seal(record, nonce(w.aead, w.index), p, w.id[:])
+	binary.BigEndian.PutUint32(record[1+segmentIDSize:prefixSize], uint32(len(record)-prefixSize))
+
+	if _, err := w.w.Write(record); err != nil {
+		return 0, err
+	}
+	w.index++
+
+	return len(p), nil
+}
+
+// segment is the state of the reader for one of the segments of a file.
+type segment struct {
+	aead  cipher.AEAD
+	index uint64
+}
+
+// Reader decrypts the segments of an encrypted file. The records of the
+// segments written at the same time are returned in the order they were
+// written in the file.
+type Reader struct {
+	r    *bufio.Reader
+	priv *rsa.PrivateKey
+
+	segments map[[segmentIDSize]byte]*segment
+
+	// buf is the decrypted data not read yet.
+	buf []byte
+}
+
+// NewReader returns a reader decrypting the file read from r with priv.
+func NewReader(r io.Reader, priv *rsa.PrivateKey) *Reader {
+	return &Reader{
+		r:        bufio.NewReader(r),
+		priv:     priv,
+		segments: make(map[[segmentIDSize]byte]*segment),
+	}
+}
+
+// IsEncrypted tells if the given beginning of a file is the one of an
+// encrypted file.
+func IsEncrypted(prefix []byte) bool {
+	return len(prefix) >= len(Magic) && string(prefix[:len(Magic)]) == Magic
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if err := r.next(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// next reads the next record, or segment header, of the file.
+func (r *Reader) next() error {
+	t, err := r.r.ReadByte()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case t == Magic[0]:
+		return r.readHeader()
+	case t == recordData && len(r.segments) != 0:
+		return r.readRecord()
+	}
+
+	return errors.New("not an encrypted file or corrupted file")
+}
+
+func (r *Reader) readHeader() error {
+	header := make([]byte, len(Magic)-1+segmentIDSize+2)
+	if _, err := io.ReadFull(r.r, header); err != nil {
+		return unexpectedEOF(err)
+	}
+	if string(header[:len(Magic)-1]) != Magic[1:] {
+		return errors.New("not an encrypted file or corrupted file")
+	}
+
+	var id [segmentIDSize]byte
+	copy(id[:], header[len(Magic)-1:])
+
+	encryptedKey := make([]byte, binary.BigEndian.Uint16(header[len(Magic)-1+segmentIDSize:]))
+	if _, err := io.ReadFull(r.r, encryptedKey); err != nil {
+		return unexpectedEOF(err)
+	}
+
+	key, err := rsa.DecryptOAEP(sha256.New(), nil, r.priv, encryptedKey, oaepLabel)
+	if err != nil {
+		return errors.New("decrypting the key of the file: the file wasn't encrypted for this private key")
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+	r.segments[id] = &segment{aead: aead}
+
+	return nil
+}
+
+func (r *Reader) readRecord() error {
+	var prefix [segmentIDSize + 4]byte
+	if _, err := io.ReadFull(r.r, prefix[:]); err != nil {
+		return unexpectedEOF(err)
+	}
+
+	var id [segmentIDSize]byte
+	copy(id[:], prefix[:segmentIDSize])
+	seg, ok := r.segments[id]
+	if !ok {
+		return errors.New("record of an unknown segment: the file is corrupted")
+	}
+
+	size := binary.BigEndian.Uint32(prefix[segmentIDSize:])
+	if size > maxRecordSize {
+		return fmt.Errorf("record too large (%d bytes), the file is corrupted", size)
+	}
+
+	record := make([]byte, size)
+	if _, err := io.ReadFull(r.r, record); err != nil {
+		return unexpectedEOF(err)
+	}
+
+	data, err := seg.aead.Open(record[:0], nonce(seg.aead, seg.index), record, id[:])
+	if err != nil {
+		return errors.New("decrypting record: the file is corrupted")
+	}
+	seg.index++
+	r.buf = data
+
+	return nil
+}
+
+// unexpectedEOF makes an EOF in the middle of a record or header an
+// error, the file being truncated.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
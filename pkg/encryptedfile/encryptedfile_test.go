@@ -0,0 +1,185 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encryptedfile
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+func generateKey(t *testing.T) (*rsa.PrivateKey, []byte) {
+	t.Helper()
+
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating key: %s", err)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	if err != nil {
+		t.Fatalf("marshaling public key: %s", err)
+	}
+
+	return priv, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func TestRoundTrip(t *testing.T) {
+	priv, pubPEM := generateKey(t)
+
+	pub, err := ParsePublicKey(pubPEM)
+	if err != nil {
+		t.Fatalf("parsing public key: %s", err)
+	}
+
+	var file bytes.Buffer
+
+	// Two segments, as written by two traces appending to the same file.
+	for _, writes := range [][]string{
+		{"{\"a\":1}\n", "{\"a\":2}\n"},
+		{"{\"a\":3}\n"},
+	} {
+		w, err := NewWriter(&file, pub)
+		if err != nil {
+			t.Fatalf("creating writer: %s", err)
+		}
+		for _, s := range writes {
+			if _, err := w.Write([]byte(s)); err != nil {
+				t.Fatalf("writing: %s", err)
+			}
+		}
+	}
+
+	if !IsEncrypted(file.Bytes()) {
+		t.Fatalf("expected the file to be detected as encrypted")
+	}
+	if bytes.Contains(file.Bytes(), []byte("\"a\"")) {
+		t.Fatalf("the file contains plaintext")
+	}
+
+	data, err := ioutil.ReadAll(NewReader(bytes.NewReader(file.Bytes()), priv))
+	if err != nil {
+		t.Fatalf("reading: %s", err)
+	}
+	if expected := "{\"a\":1}\n{\"a\":2}\n{\"a\":3}\n"; string(data) != expected {
+		t.Fatalf("expected %q, got %q", expected, data)
+	}
+
+	// A truncated file is reported.
+	truncated := file.Bytes()[:file.Len()-3]
+	if _, err := ioutil.ReadAll(NewReader(bytes.NewReader(truncated), priv)); err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected %v, got %v", io.ErrUnexpectedEOF, err)
+	}
+
+	// A modified file is reported.
+	modified := append([]byte{}, file.Bytes()...)
+	modified[len(modified)-1] ^= 1
+	if _, err := ioutil.ReadAll(NewReader(bytes.NewReader(modified), priv)); err == nil {
+		t.Fatalf("expected an error reading a modified file")
+	}
+
+	// Another private key can't read it.
+	other, _ := generateKey(t)
+	if _, err := ioutil.ReadAll(NewReader(bytes.NewReader(file.Bytes()), other)); err == nil {
+		t.Fatalf("expected an error reading with another key")
+	}
+}
+
+func TestInterleavedSegments(t *testing.T) {
+	priv, pubPEM := generateKey(t)
+
+	pub, err := ParsePublicKey(pubPEM)
+	if err != nil {
+		t.Fatalf("parsing public key: %s", err)
+	}
+
+	var file bytes.Buffer
+
+	// Two traces writing to the same file at the same time.
+	w1, err := NewWriter(&file, pub)
+	if err != nil {
+		t.Fatalf("creating writer: %s", err)
+	}
+	w2, err := NewWriter(&file, pub)
+	if err != nil {
+		t.Fatalf("creating writer: %s", err)
+	}
+	for _, write := range []struct {
+		w *Writer
+		s string
+	}{
+		{w1, "{\"a\":1}\n"},
+		{w2, "{\"b\":1}\n"},
+		{w2, "{\"b\":2}\n"},
+		{w1, "{\"a\":2}\n"},
+	} {
+		if _, err := write.w.Write([]byte(write.s)); err != nil {
+			t.Fatalf("writing: %s", err)
+		}
+	}
+
+	// A third one starting after the first records.
+	w3, err := NewWriter(&file, pub)
+	if err != nil {
+		t.Fatalf("creating writer: %s", err)
+	}
+	if _, err := w3.Write([]byte("{\"c\":1}\n")); err != nil {
+		t.Fatalf("writing: %s", err)
+	}
+	if _, err := w1.Write([]byte("{\"a\":3}\n")); err != nil {
+		t.Fatalf("writing: %s", err)
+	}
+
+	data, err := ioutil.ReadAll(NewReader(bytes.NewReader(file.Bytes()), priv))
+	if err != nil {
+		t.Fatalf("reading: %s", err)
+	}
+	if expected := "{\"a\":1}\n{\"b\":1}\n{\"b\":2}\n{\"a\":2}\n{\"c\":1}\n{\"a\":3}\n"; string(data) != expected {
+		t.Fatalf("expected %q, got %q", expected, data)
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	priv, pubPEM := generateKey(t)
+
+	privPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(priv)})
+	if _, err := ParsePrivateKey(privPEM); err != nil {
+		t.Fatalf("parsing private key: %s", err)
+	}
+
+	if _, err := ParsePublicKey(privPEM); err == nil {
+		t.Fatalf("expected an error parsing a private key as a public key")
+	}
+	if _, err := ParsePrivateKey(pubPEM); err == nil {
+		t.Fatalf("expected an error parsing a public key as a private key")
+	}
+	if _, err := ParsePublicKey([]byte("not a key")); err == nil {
+		t.Fatalf("expected an error parsing garbage")
+	}
+
+	small, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("generating key: %s", err)
+	}
+	smallPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&small.PublicKey)})
+	if _, err := ParsePublicKey(smallPEM); err == nil {
+		t.Fatalf("expected an error parsing a 1024 bits key")
+	}
+}
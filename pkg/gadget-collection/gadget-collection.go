@@ -16,63 +16,162 @@ package gadgetcollection
 
 import (
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/apiserverclients"
+	auditnetns "github.com/kinvolk/inspektor-gadget/pkg/gadgets/audit-netns"
 	auditseccomp "github.com/kinvolk/inspektor-gadget/pkg/gadgets/audit-seccomp"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/bashreadline"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/bindsnoop"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/biolatency"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/biosnoop"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/biotop"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/cachestat"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/capabilities"
+	cgroupcollector "github.com/kinvolk/inspektor-gadget/pkg/gadgets/cgroup-collector"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/conntrack"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/dcstat"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/dns"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/egressaudit"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/escapeattempts"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/execsnoop"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/filetop"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/fsslower"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/fstop"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/gethostlatency"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/grpctop"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/httpsnoop"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/irqs"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/memleak"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/mountsnoop"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/netdrops"
 	networkpolicyadvisor "github.com/kinvolk/inspektor-gadget/pkg/gadgets/networkpolicy"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/oomkill"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/opensnoop"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/ping"
 	processcollector "github.com/kinvolk/inspektor-gadget/pkg/gadgets/process-collector"
+	qoscollector "github.com/kinvolk/inspektor-gadget/pkg/gadgets/qos-collector"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/resourcelimits"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/runqlat"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/runqslower"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/seccomp"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/seccomptop"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/sidecarinjection"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/sigsnoop"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/snisnoop"
 	socketcollector "github.com/kinvolk/inspektor-gadget/pkg/gadgets/socket-collector"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/solisten"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/statsnoop"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/stealtop"
+	suspiciousexec "github.com/kinvolk/inspektor-gadget/pkg/gadgets/suspicious-exec"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/syncsnoop"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/tcpconnect"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/tcpdrop"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/tcplife"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/tcpretrans"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/tcpsynbl"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/tcptop"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/tcptracer"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/tlssnoop"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/traceloop"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/udpsnoop"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/udptop"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/uprobe"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/usdt"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/vfsstat"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/volumemount"
 )
 
 func TraceFactories() map[string]gadgets.TraceFactory {
 	return map[string]gadgets.TraceFactory{
+		"apiserver-clients":      apiserverclients.NewFactory(),
+		"audit-netns":            auditnetns.NewFactory(),
 		"audit-seccomp":          auditseccomp.NewFactory(),
+		"bashreadline":           bashreadline.NewFactory(),
 		"bindsnoop":              bindsnoop.NewFactory(),
 		"biolatency":             biolatency.NewFactory(),
+		"biosnoop":               biosnoop.NewFactory(),
 		"biotop":                 biotop.NewFactory(),
+		"cachestat":              cachestat.NewFactory(),
 		"capabilities":           capabilities.NewFactory(),
+		"cgroup-collector":       cgroupcollector.NewFactory(),
+		"conntrack":              conntrack.NewFactory(),
+		"dcstat":                 dcstat.NewFactory(),
 		"dns":                    dns.NewFactory(),
+		"egress-audit":           egressaudit.NewFactory(),
+		"escape-attempts":        escapeattempts.NewFactory(),
 		"execsnoop":              execsnoop.NewFactory(),
 		"filetop":                filetop.NewFactory(),
 		"fsslower":               fsslower.NewFactory(),
+		"fstop":                  fstop.NewFactory(),
+		"gethostlatency":         gethostlatency.NewFactory(),
+		"grpctop":                grpctop.NewFactory(),
+		"hardirqs":               irqs.NewHardirqsFactory(),
+		"httpsnoop":              httpsnoop.NewFactory(),
 		"opensnoop":              opensnoop.NewFactory(),
+		"memleak":                memleak.NewFactory(),
 		"mountsnoop":             mountsnoop.NewFactory(),
+		"netdrops":               netdrops.NewFactory(),
 		"network-policy-advisor": networkpolicyadvisor.NewFactory(),
 		"oomkill":                oomkill.NewFactory(),
+		"ping":                   ping.NewFactory(),
 		"process-collector":      processcollector.NewFactory(),
+		"qos-collector":          qoscollector.NewFactory(),
+		"resource-limits":        resourcelimits.NewFactory(),
+		"runqlat":                runqlat.NewFactory(),
+		"runqslower":             runqslower.NewFactory(),
 		"seccomp":                seccomp.NewFactory(),
+		"seccomptop":             seccomptop.NewFactory(),
+		"sidecar-injection":      sidecarinjection.NewFactory(),
 		"sigsnoop":               sigsnoop.NewFactory(),
 		"snisnoop":               snisnoop.NewFactory(),
+		"solisten":               solisten.NewFactory(),
 		"socket-collector":       socketcollector.NewFactory(),
+		"softirqs":               irqs.NewSoftirqsFactory(),
+		"statsnoop":              statsnoop.NewFactory(),
+		"stealtop":               stealtop.NewFactory(),
+		"suspicious-exec":        suspiciousexec.NewFactory(),
+		"syncsnoop":              syncsnoop.NewFactory(),
 		"tcpconnect":             tcpconnect.NewFactory(),
+		"tcpdrop":                tcpdrop.NewFactory(),
+		"tcplife":                tcplife.NewFactory(),
+		"tcpretrans":             tcpretrans.NewFactory(),
+		"tcpsynbl":               tcpsynbl.NewFactory(),
 		"tcptop":                 tcptop.NewFactory(),
 		"tcptracer":              tcptracer.NewFactory(),
+		"tlssnoop":               tlssnoop.NewFactory(),
 		"traceloop":              traceloop.NewFactory(),
+		"udpsnoop":               udpsnoop.NewFactory(),
+		"udptop":                 udptop.NewFactory(),
+		"uprobe":                 uprobe.NewFactory(),
+		"usdt":                   usdt.NewFactory(),
+		"vfsstat":                vfsstat.NewFactory(),
+		"volume-mount":           volumemount.NewFactory(),
 	}
 }
 
+// TraceFactoriesWithoutBPF returns the gadgets that can run when the gadget
+// pods don't have the privileges needed by eBPF.
+func TraceFactoriesWithoutBPF() map[string]gadgets.TraceFactory {
+	factories := map[string]gadgets.TraceFactory{}
+	for name, factory := range TraceFactories() {
+		factoryWithoutBPF, ok := factory.(gadgets.TraceFactoryWithoutBPF)
+		if !ok {
+			continue
+		}
+		factoryWithoutBPF.SetWithoutBPF()
+		factories[name] = factory
+	}
+	return factories
+}
+
 func TraceFactoriesForLocalGadget() map[string]gadgets.TraceFactory {
 	return map[string]gadgets.TraceFactory{
 		"audit-seccomp":    auditseccomp.NewFactory(),
 		"dns":              dns.NewFactory(),
+		"httpsnoop":        httpsnoop.NewFactory(),
+		"ping":             ping.NewFactory(),
 		"socket-collector": socketcollector.NewFactory(),
 		"seccomp":          seccomp.NewFactory(),
 		"snisnoop":         snisnoop.NewFactory(),
+		"tlssnoop":         tlssnoop.NewFactory(),
 	}
 }
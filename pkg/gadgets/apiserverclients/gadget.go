@@ -0,0 +1,178 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apiserverclients
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/kinvolk/inspektor-gadget/pkg/bpferror"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/apiserverclients/tracer"
+
+	standardtracer "github.com/kinvolk/inspektor-gadget/pkg/gadgets/apiserverclients/tracer/standard"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/apiserverclients/types"
+
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+)
+
+type Trace struct {
+	resolver gadgets.Resolver
+
+	started bool
+	tracer  tracer.Tracer
+}
+
+type TraceFactory struct {
+	gadgets.BaseFactory
+}
+
+func NewFactory() gadgets.TraceFactory {
+	return &TraceFactory{
+		BaseFactory: gadgets.BaseFactory{DeleteTrace: deleteTrace},
+	}
+}
+
+func (f *TraceFactory) Description() string {
+	return `apiserver-clients traces the TCP connections to the Kubernetes API server, with their latency and failures`
+}
+
+func (f *TraceFactory) Parameters() []gadgets.GadgetParameter {
+	return []gadgets.GadgetParameter{
+		{
+			Name:        "endpoints",
+			Description: "Comma-separated list of ip:port the API server is reachable at",
+			Required:    true,
+		},
+	}
+}
+
+func (f *TraceFactory) OutputModesSupported() map[string]struct{} {
+	return map[string]struct{}{
+		"Stream": {},
+	}
+}
+
+func deleteTrace(name string, t interface{}) {
+	trace := t.(*Trace)
+	if trace.tracer != nil {
+		trace.tracer.Stop()
+	}
+}
+
+func (f *TraceFactory) Operations() map[string]gadgets.TraceOperation {
+	n := func() interface{} {
+		return &Trace{
+			resolver: f.Resolver,
+		}
+	}
+
+	return map[string]gadgets.TraceOperation{
+		"start": {
+			Doc: "Start apiserver-clients gadget",
+			Operation: func(name string, trace *gadgetv1alpha1.Trace) {
+				f.LookupOrCreate(name, n).(*Trace).Start(trace)
+			},
+		},
+		"stop": {
+			Doc: "Stop apiserver-clients gadget",
+			Operation: func(name string, trace *gadgetv1alpha1.Trace) {
+				f.LookupOrCreate(name, n).(*Trace).Stop(trace)
+			},
+		},
+	}
+}
+
+func parseEndpoints(val string) (map[string]struct{}, error) {
+	endpoints := make(map[string]struct{})
+
+	for _, endpoint := range strings.Split(val, ",") {
+		host, port, err := net.SplitHostPort(endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a valid endpoint: %w", endpoint, err)
+		}
+
+		ip := net.ParseIP(host)
+		if ip == nil {
+			return nil, fmt.Errorf("%q is not a valid IP address", host)
+		}
+
+		// Use the same representation as the events
+		endpoints[net.JoinHostPort(ip.String(), port)] = struct{}{}
+	}
+
+	return endpoints, nil
+}
+
+func (t *Trace) Start(trace *gadgetv1alpha1.Trace) {
+	if t.started {
+		trace.Status.State = "Started"
+		return
+	}
+
+	if trace.Spec.Parameters == nil || trace.Spec.Parameters["endpoints"] == "" {
+		trace.Status.OperationError = "missing endpoints"
+		return
+	}
+
+	endpoints, err := parseEndpoints(trace.Spec.Parameters["endpoints"])
+	if err != nil {
+		trace.Status.OperationError = fmt.Sprintf("invalid endpoints: %s", err)
+		return
+	}
+
+	traceName := gadgets.TraceName(trace.ObjectMeta.Namespace, trace.ObjectMeta.Name)
+
+	eventCallback := func(event types.Event) {
+		r, err := json.Marshal(event)
+		if err != nil {
+			log.Warnf("Gadget %s: error marshalling event: %s", trace.Spec.Gadget, err)
+			return
+		}
+		t.resolver.PublishEvent(traceName, string(r))
+	}
+
+	config := &tracer.Config{
+		MountnsMap: gadgets.TracePinPath(trace.ObjectMeta.Namespace, trace.ObjectMeta.Name),
+		Endpoints:  endpoints,
+	}
+
+	t.tracer, err = standardtracer.NewTracer(config, t.resolver, eventCallback, trace.Spec.Node)
+	if err != nil {
+		trace.Status.OperationError = fmt.Sprintf("failed to create tracer: %s", bpferror.Describe(err))
+		return
+	}
+
+	t.started = true
+
+	trace.Status.State = "Started"
+}
+
+func (t *Trace) Stop(trace *gadgetv1alpha1.Trace) {
+	if !t.started {
+		trace.Status.OperationError = "Not started"
+		return
+	}
+
+	t.tracer.Stop()
+	t.tracer = nil
+	t.started = false
+
+	trace.Status.State = "Stopped"
+}
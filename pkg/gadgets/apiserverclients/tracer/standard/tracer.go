@@ -0,0 +1,233 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	containercollection "github.com/kinvolk/inspektor-gadget/pkg/container-collection"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/apiserverclients/tracer"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/apiserverclients/types"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+// ConnectTimeout is the time after which a connection attempt that didn't
+// reach the ESTABLISHED state is reported as failed.
+const ConnectTimeout = 10 * time.Second
+
+// connectionKey identifies the connection attempts of a process to an
+// endpoint. tcpconnect doesn't report the source port so several attempts
+// can share the same key; they are handled in FIFO order.
+type connectionKey struct {
+	pid   uint32
+	daddr string
+	dport uint16
+}
+
+type attempt struct {
+	event     types.Event
+	timestamp time.Time
+}
+
+// Tracer correlates the connection attempts reported by tcpconnect with the
+// established connections reported by tcptracer: attempts without a
+// matching established connection after ConnectTimeout are failures. Both
+// tools are the ones used by the trace tcpconnect and tcptracer gadgets.
+// The latency is the time between the reception of the two events, it's
+// then a bit higher than the one measured in the kernel.
+type Tracer struct {
+	config        *tracer.Config
+	resolver      containercollection.ContainerResolver
+	eventCallback func(types.Event)
+	node          string
+
+	connectTracer *gadgets.StandardTracerBase
+	tcpTracer     *gadgets.StandardTracerBase
+
+	mu      sync.Mutex
+	pending map[connectionKey][]attempt
+	done    chan struct{}
+}
+
+func NewTracer(config *tracer.Config, resolver containercollection.ContainerResolver,
+	eventCallback func(types.Event), node string) (*Tracer, error,
+) {
+	t := &Tracer{
+		config:        config,
+		resolver:      resolver,
+		eventCallback: eventCallback,
+		node:          node,
+		pending:       make(map[connectionKey][]attempt),
+		done:          make(chan struct{}),
+	}
+
+	var err error
+
+	t.connectTracer, err = gadgets.NewStandardTracer(t.connectLineCallback,
+		"/usr/share/bcc/tools/tcpconnect",
+		"--json", "--mntnsmap", config.MountnsMap,
+		"--containersmap", "/sys/fs/bpf/gadget/containers")
+	if err != nil {
+		return nil, err
+	}
+
+	t.tcpTracer, err = gadgets.NewStandardTracer(t.establishedLineCallback,
+		"/usr/share/bcc/tools/tcptracer",
+		"--json", "--mntnsmap", config.MountnsMap,
+		"--containersmap", "/sys/fs/bpf/gadget/containers")
+	if err != nil {
+		t.connectTracer.Stop()
+		return nil, err
+	}
+
+	go t.expire()
+
+	return t, nil
+}
+
+// parseLine decodes a line printed by tcpconnect or tcptracer and tells
+// if it's a connection to the API server.
+func (t *Tracer) parseLine(line string) (types.Event, bool) {
+	event := types.Event{}
+	event.Type = eventtypes.NORMAL
+
+	// "Hack" to avoid changing the BCC tool implementation
+	line = strings.ReplaceAll(line, `"ip"`, `"ipversion"`)
+
+	if err := json.Unmarshal([]byte(line), &event); err != nil {
+		msg := fmt.Sprintf("failed to unmarshal event: %s", err)
+		t.eventCallback(types.Base(eventtypes.Warn(msg, t.node)))
+		return event, false
+	}
+
+	endpoint := net.JoinHostPort(event.Daddr, strconv.Itoa(int(event.Dport)))
+	if _, ok := t.config.Endpoints[endpoint]; !ok {
+		return event, false
+	}
+
+	event.Node = t.node
+
+	return event, true
+}
+
+func (t *Tracer) connectLineCallback(line string) {
+	event, ok := t.parseLine(line)
+	if !ok {
+		return
+	}
+
+	key := connectionKey{pid: event.Pid, daddr: event.Daddr, dport: event.Dport}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.pending[key] = append(t.pending[key], attempt{event: event, timestamp: time.Now()})
+}
+
+func (t *Tracer) establishedLineCallback(line string) {
+	// tcptracer also reports the accepted and closed connections, in the
+	// "type" field.
+	line = strings.ReplaceAll(line, `"type"`, `"operation"`)
+	var operation struct {
+		Operation string `json:"operation"`
+	}
+	if err := json.Unmarshal([]byte(line), &operation); err != nil || operation.Operation != "connect" {
+		return
+	}
+
+	event, ok := t.parseLine(line)
+	if !ok {
+		return
+	}
+
+	key := connectionKey{pid: event.Pid, daddr: event.Daddr, dport: event.Dport}
+
+	t.mu.Lock()
+	if attempts := t.pending[key]; len(attempts) > 0 {
+		// Prefer the data collected by tcpconnect when the
+		// connection was initiated.
+		event.Latency = float64(time.Since(attempts[0].timestamp)) / float64(time.Millisecond)
+		event.MountNsID = attempts[0].event.MountNsID
+		event.Namespace = attempts[0].event.Namespace
+		event.Pod = attempts[0].event.Pod
+		event.Container = attempts[0].event.Container
+
+		if len(attempts) == 1 {
+			delete(t.pending, key)
+		} else {
+			t.pending[key] = attempts[1:]
+		}
+	}
+	t.mu.Unlock()
+
+	t.eventCallback(event)
+}
+
+// expire periodically reports the connection attempts that didn't complete
+// within ConnectTimeout.
+func (t *Tracer) expire() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.done:
+			return
+		case now := <-ticker.C:
+			var failed []types.Event
+
+			t.mu.Lock()
+			for key, attempts := range t.pending {
+				i := 0
+				for ; i < len(attempts); i++ {
+					if now.Sub(attempts[i].timestamp) < ConnectTimeout {
+						break
+					}
+					attempts[i].event.Failed = true
+					failed = append(failed, attempts[i].event)
+				}
+
+				if i == len(attempts) {
+					delete(t.pending, key)
+				} else {
+					t.pending[key] = attempts[i:]
+				}
+			}
+			t.mu.Unlock()
+
+			for _, event := range failed {
+				t.eventCallback(event)
+			}
+		}
+	}
+}
+
+func (t *Tracer) Stop() {
+	close(t.done)
+
+	if err := t.connectTracer.Stop(); err != nil {
+		t.eventCallback(types.Base(eventtypes.Warn(err.Error(), t.node)))
+	}
+	if err := t.tcpTracer.Stop(); err != nil {
+		t.eventCallback(types.Base(eventtypes.Warn(err.Error(), t.node)))
+	}
+}
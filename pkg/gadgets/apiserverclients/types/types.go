@@ -0,0 +1,42 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+type Event struct {
+	eventtypes.Event
+
+	MountNsID uint64  `json:"mountnsid,omitempty"`
+	Pid       uint32  `json:"pid,omitempty"`
+	Comm      string  `json:"comm,omitempty"`
+	IPVersion int     `json:"ipversion,omitempty"`
+	Saddr     string  `json:"saddr,omitempty"`
+	Daddr     string  `json:"daddr,omitempty"`
+	Dport     uint16  `json:"dport,omitempty"`
+	Latency   float64 `json:"lat_ms,omitempty"`
+
+	// Failed is set when the connection didn't reach the ESTABLISHED
+	// state, e.g. it was refused or it timed out.
+	Failed bool `json:"failed,omitempty"`
+}
+
+func Base(ev eventtypes.Event) Event {
+	return Event{
+		Event: ev,
+	}
+}
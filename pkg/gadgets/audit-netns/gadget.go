@@ -0,0 +1,219 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auditnetns
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	"github.com/kinvolk/inspektor-gadget/pkg/bpferror"
+	containerutils "github.com/kinvolk/inspektor-gadget/pkg/container-utils"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	auditnetnstracer "github.com/kinvolk/inspektor-gadget/pkg/gadgets/audit-netns/tracer"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/audit-netns/types"
+	pb "github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/api"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/pubsub"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+type Trace struct {
+	resolver gadgets.Resolver
+	tracer   *auditnetnstracer.Tracer
+
+	hostNamespaces types.Namespaces
+
+	started bool
+}
+
+type TraceFactory struct {
+	gadgets.BaseFactory
+
+	hostNamespaces types.Namespaces
+}
+
+// namespacesOf returns the namespaces of a process, the ones which can't
+// be read are left to 0.
+func namespacesOf(pid int) types.Namespaces {
+	var ns types.Namespaces
+	ns.Net, _ = containerutils.GetNetNs(pid)
+	ns.Pid, _ = containerutils.GetPidNs(pid)
+	ns.Ipc, _ = containerutils.GetIpcNs(pid)
+	return ns
+}
+
+func NewFactory() gadgets.TraceFactory {
+	return &TraceFactory{
+		BaseFactory: gadgets.BaseFactory{DeleteTrace: deleteTrace},
+		// The gadget pod runs with hostPID: the first process is the
+		// init of the host.
+		hostNamespaces: namespacesOf(1),
+	}
+}
+
+func (f *TraceFactory) Description() string {
+	return `The Audit Netns gadget provides a stream of events about the network
+namespaces and the namespaces shared with the host:
+
+* The creation of network namespaces, with the process and container which
+  created them.
+* The deletion of network namespaces, with the pod they belonged to or the
+  container which created them.
+* The containers sharing the network, PID or IPC namespace of the host, like
+  the ones of the pods with hostNetwork, hostPID or hostIPC, when they start
+  and, for the ones already running, when the gadget starts.
+`
+}
+
+func (f *TraceFactory) OutputModesSupported() map[string]struct{} {
+	return map[string]struct{}{
+		"Stream": {},
+	}
+}
+
+func (f *TraceFactory) NewEvent() gadgets.Event {
+	return &types.Event{}
+}
+
+func deleteTrace(name string, t interface{}) {
+	trace := t.(*Trace)
+	if trace.started {
+		trace.resolver.Unsubscribe(genPubSubKey(name))
+		trace.tracer.Stop()
+		trace.tracer = nil
+	}
+}
+
+func (f *TraceFactory) Operations() map[string]gadgets.TraceOperation {
+	n := func() interface{} {
+		return &Trace{
+			resolver:       f.Resolver,
+			hostNamespaces: f.hostNamespaces,
+		}
+	}
+	return map[string]gadgets.TraceOperation{
+		"start": {
+			Doc: "Start audit netns",
+			Operation: func(name string, trace *gadgetv1alpha1.Trace) {
+				f.LookupOrCreate(name, n).(*Trace).Start(trace)
+			},
+		},
+		"stop": {
+			Doc: "Stop audit netns",
+			Operation: func(name string, trace *gadgetv1alpha1.Trace) {
+				f.LookupOrCreate(name, n).(*Trace).Stop(trace)
+			},
+		},
+	}
+}
+
+type pubSubKey string
+
+func genPubSubKey(name string) pubSubKey {
+	return pubSubKey(fmt.Sprintf("gadget/audit-netns/%s", name))
+}
+
+func (t *Trace) Start(trace *gadgetv1alpha1.Trace) {
+	if t.started {
+		trace.Status.State = "Started"
+		return
+	}
+
+	traceName := gadgets.TraceName(trace.ObjectMeta.Namespace, trace.ObjectMeta.Name)
+	eventCallback := func(event types.Event) {
+		t.resolver.PublishEvent(
+			traceName,
+			eventtypes.EventString(event),
+		)
+	}
+
+	var err error
+	config := &auditnetnstracer.Config{
+		MountnsMap: gadgets.TracePinPath(trace.ObjectMeta.Namespace, trace.ObjectMeta.Name),
+		HostNetns:  t.hostNamespaces.Net,
+	}
+	t.tracer, err = auditnetnstracer.NewTracer(config, t.resolver, eventCallback, trace.Spec.Node)
+	if err != nil {
+		trace.Status.OperationError = fmt.Sprintf("Failed to start audit netns tracer: %s", bpferror.Describe(err))
+		return
+	}
+
+	addContainer := func(container *pb.ContainerDefinition) {
+		if err := t.tracer.AddContainer(container); err != nil {
+			log.Warnf("Gadget %s: %s", trace.Spec.Gadget, err)
+		}
+
+		shared := types.SharedNamespaces(namespacesOf(int(container.Pid)), t.hostNamespaces)
+		if len(shared) == 0 {
+			return
+		}
+
+		event := types.Event{
+			Event: eventtypes.Event{
+				Type:      eventtypes.NORMAL,
+				Node:      trace.Spec.Node,
+				Namespace: container.Namespace,
+				Pod:       container.Podname,
+				Container: container.Name,
+				Sandbox:   container.Sandbox,
+			},
+			Kind:           types.KindHostNamespaces,
+			Pid:            container.Pid,
+			MountNsID:      container.Mntns,
+			HostNamespaces: shared,
+		}
+		if container.OwnerReference != nil {
+			event.OwnerKind = container.OwnerReference.Kind
+			event.OwnerName = container.OwnerReference.Name
+		}
+		eventCallback(event)
+	}
+
+	containerEventCallback := func(event pubsub.PubSubEvent) {
+		if event.Type == pubsub.EventTypeAddContainer {
+			addContainer(&event.Container)
+		}
+	}
+
+	existingContainers := t.resolver.Subscribe(
+		genPubSubKey(trace.ObjectMeta.Namespace+"/"+trace.ObjectMeta.Name),
+		*gadgets.ContainerSelectorFromContainerFilter(trace.Spec.Filter),
+		containerEventCallback,
+	)
+
+	for _, c := range existingContainers {
+		addContainer(c)
+	}
+
+	t.started = true
+
+	trace.Status.State = "Started"
+}
+
+func (t *Trace) Stop(trace *gadgetv1alpha1.Trace) {
+	if !t.started {
+		trace.Status.OperationError = "Not started"
+		return
+	}
+
+	t.resolver.Unsubscribe(genPubSubKey(trace.ObjectMeta.Namespace + "/" + trace.ObjectMeta.Name))
+	t.tracer.Stop()
+	t.tracer = nil
+
+	t.started = false
+
+	trace.Status.State = "Stopped"
+}
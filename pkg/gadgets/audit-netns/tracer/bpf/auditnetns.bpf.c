@@ -0,0 +1,120 @@
+// SPDX-License-Identifier: GPL-2.0
+#include <vmlinux/vmlinux.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_tracing.h>
+
+#include "auditnetns.h"
+
+#define MAX_ENTRIES 10240
+#define MAX_ERRNO 4095
+
+struct {
+	__uint(type, BPF_MAP_TYPE_PERF_EVENT_ARRAY);
+	__uint(key_size, sizeof(u32));
+	__uint(value_size, sizeof(u32));
+} events SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, 1024);
+	__uint(key_size, sizeof(u64));
+	__uint(value_size, sizeof(u32));
+} mount_ns_set SEC(".maps");
+
+/* The network namespace of the callers of copy_net_ns(), by thread: a new
+ * one was created when it returns another one. */
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, u32);
+	__type(value, u64);
+} old_nets SEC(".maps");
+
+/* The creators of the network namespaces, by inode. The network namespaces
+ * of the pods are added from user space: they are created by the container
+ * runtime on the host. */
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, u64);
+	__type(value, struct creator);
+} creators SEC(".maps");
+
+const volatile bool filter_by_mnt_ns = false;
+
+SEC("kprobe/copy_net_ns")
+int BPF_KPROBE(ig_copy_net_ns_e, unsigned long flags, struct user_namespace *user_ns, struct net *old_net)
+{
+	u32 tid = (u32) bpf_get_current_pid_tgid();
+	struct task_struct *task;
+	u64 mntns_id;
+	u64 old = (u64) old_net;
+
+	task = (struct task_struct *) bpf_get_current_task();
+	mntns_id = (u64) BPF_CORE_READ(task, nsproxy, mnt_ns, ns.inum);
+
+	if (filter_by_mnt_ns && !bpf_map_lookup_elem(&mount_ns_set, &mntns_id))
+		return 0;
+
+	bpf_map_update_elem(&old_nets, &tid, &old, BPF_ANY);
+	return 0;
+}
+
+SEC("kretprobe/copy_net_ns")
+int BPF_KRETPROBE(ig_copy_net_ns_x, struct net *net)
+{
+	u64 pid_tgid = bpf_get_current_pid_tgid();
+	u32 tid = (u32) pid_tgid;
+	struct task_struct *task;
+	struct event event = {};
+	u64 *old;
+
+	old = bpf_map_lookup_elem(&old_nets, &tid);
+	if (!old)
+		return 0;
+
+	/* Without CLONE_NEWNET, the namespace of the caller is returned */
+	if ((u64) net == *old || (unsigned long) net >= (unsigned long) -MAX_ERRNO)
+		goto cleanup;
+
+	task = (struct task_struct *) bpf_get_current_task();
+
+	event.type = EVENT_NETNS_CREATE;
+	event.netns_id = (u64) BPF_CORE_READ(net, ns.inum);
+	event.creator.mntns_id = (u64) BPF_CORE_READ(task, nsproxy, mnt_ns, ns.inum);
+	event.creator.pid = pid_tgid >> 32;
+	bpf_get_current_comm(&event.creator.comm, sizeof(event.creator.comm));
+
+	bpf_map_update_elem(&creators, &event.netns_id, &event.creator, BPF_ANY);
+	bpf_perf_event_output(ctx, &events, BPF_F_CURRENT_CPU, &event, sizeof(event));
+
+cleanup:
+	bpf_map_delete_elem(&old_nets, &tid);
+	return 0;
+}
+
+/* __put_net() is called when the last reference to a network namespace is
+ * dropped, before it's cleaned up. */
+SEC("kprobe/__put_net")
+int BPF_KPROBE(ig_put_net, struct net *net)
+{
+	struct event event = {};
+	struct creator *creator;
+
+	event.type = EVENT_NETNS_DELETE;
+	event.netns_id = (u64) BPF_CORE_READ(net, ns.inum);
+
+	creator = bpf_map_lookup_elem(&creators, &event.netns_id);
+	if (creator) {
+		event.creator = *creator;
+		bpf_map_delete_elem(&creators, &event.netns_id);
+	} else if (filter_by_mnt_ns) {
+		return 0;
+	}
+
+	bpf_perf_event_output(ctx, &events, BPF_F_CURRENT_CPU, &event, sizeof(event));
+	return 0;
+}
+
+char LICENSE[] SEC("license") = "GPL";
@@ -0,0 +1,24 @@
+/* SPDX-License-Identifier: (LGPL-2.1 OR BSD-2-Clause) */
+#ifndef __AUDITNETNS_H
+#define __AUDITNETNS_H
+
+#define TASK_COMM_LEN 16
+
+#define EVENT_NETNS_CREATE 0
+#define EVENT_NETNS_DELETE 1
+
+/* The process which created a network namespace, pid is 0 when it's not
+ * known. */
+struct creator {
+	__u64 mntns_id;
+	__u32 pid;
+	char comm[TASK_COMM_LEN];
+};
+
+struct event {
+	__u64 netns_id;
+	__u32 type;
+	struct creator creator;
+};
+
+#endif /* __AUDITNETNS_H */
@@ -0,0 +1,269 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+// #include <linux/types.h>
+// #include "./bpf/auditnetns.h"
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"unsafe"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+	"github.com/cilium/ebpf/perf"
+
+	containercollection "github.com/kinvolk/inspektor-gadget/pkg/container-collection"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/audit-netns/types"
+	pb "github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/api"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+//go:generate sh -c "GOOS=$(go env GOHOSTOS) GOARCH=$(go env GOHOSTARCH) go run github.com/cilium/ebpf/cmd/bpf2go -target bpfel -cc clang auditnetns ./bpf/auditnetns.bpf.c -- -I./bpf/ -I../../.. -target bpf -D__TARGET_ARCH_x86"
+
+type Config struct {
+	// TODO: Make it a *ebpf.Map once
+	// https://github.com/cilium/ebpf/issues/515 and
+	// https://github.com/cilium/ebpf/issues/517 are fixed
+	MountnsMap string
+
+	// HostNetns is the network namespace of the host, which is never
+	// deleted.
+	HostNetns uint64
+}
+
+// pod is the pod a network namespace belongs to.
+type pod struct {
+	namespace string
+	name      string
+	ownerKind string
+	ownerName string
+}
+
+type Tracer struct {
+	config        *Config
+	objs          auditnetnsObjects
+	links         []link.Link
+	reader        *perf.Reader
+	resolver      containercollection.ContainerResolver
+	eventCallback func(types.Event)
+	node          string
+
+	mu sync.Mutex
+	// pods are the pods of the network namespaces not deleted yet. The
+	// containers are removed before their network namespace is deleted.
+	pods map[uint64]pod
+}
+
+func NewTracer(c *Config, resolver containercollection.ContainerResolver, eventCallback func(types.Event), node string) (*Tracer, error) {
+	t := &Tracer{
+		config:        c,
+		resolver:      resolver,
+		eventCallback: eventCallback,
+		node:          node,
+		pods:          make(map[uint64]pod),
+	}
+
+	if err := t.start(); err != nil {
+		t.Stop()
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (t *Tracer) Stop() {
+	for i := range t.links {
+		t.links[i] = gadgets.CloseLink(t.links[i])
+	}
+	t.links = nil
+
+	if t.reader != nil {
+		t.reader.Close()
+		t.reader = nil
+	}
+
+	t.objs.Close()
+}
+
+func (t *Tracer) start() error {
+	spec, err := loadAuditnetns()
+	if err != nil {
+		return fmt.Errorf("failed to load ebpf program: %w", err)
+	}
+
+	filterByMntNs := false
+	opts := ebpf.CollectionOptions{}
+
+	if t.config.MountnsMap != "" {
+		filterByMntNs = true
+		m := spec.Maps["mount_ns_set"]
+		m.Pinning = ebpf.PinByName
+		m.Name = filepath.Base(t.config.MountnsMap)
+		opts.Maps.PinPath = filepath.Dir(t.config.MountnsMap)
+	}
+
+	consts := map[string]interface{}{
+		"filter_by_mnt_ns": filterByMntNs,
+	}
+
+	if err := spec.RewriteConstants(consts); err != nil {
+		return fmt.Errorf("error RewriteConstants: %w", err)
+	}
+
+	if err := spec.LoadAndAssign(&t.objs, &opts); err != nil {
+		return fmt.Errorf("failed to load ebpf program: %w", err)
+	}
+
+	probes := []struct {
+		symbol string
+		prog   *ebpf.Program
+		ret    bool
+	}{
+		{"copy_net_ns", t.objs.IgCopyNetNsE, false},
+		{"copy_net_ns", t.objs.IgCopyNetNsX, true},
+		{"__put_net", t.objs.IgPutNet, false},
+	}
+
+	for _, p := range probes {
+		var l link.Link
+		if p.ret {
+			l, err = link.Kretprobe(p.symbol, p.prog, nil)
+		} else {
+			l, err = link.Kprobe(p.symbol, p.prog, nil)
+		}
+		if err != nil {
+			return fmt.Errorf("error opening kprobe %s: %w", p.symbol, err)
+		}
+		t.links = append(t.links, l)
+	}
+
+	reader, err := perf.NewReader(t.objs.auditnetnsMaps.Events, gadgets.PerfBufferPages*os.Getpagesize())
+	if err != nil {
+		return fmt.Errorf("error creating perf ring buffer: %w", err)
+	}
+	t.reader = reader
+
+	go t.run()
+
+	return nil
+}
+
+// AddContainer records the pod of the network namespace of the container,
+// so that its deletion is reported with the pod, even when the containers
+// are filtered: it's created by the container runtime on the host.
+func (t *Tracer) AddContainer(c *pb.ContainerDefinition) error {
+	if c.Netns == 0 || c.Netns == t.config.HostNetns {
+		return nil
+	}
+
+	p := pod{
+		namespace: c.Namespace,
+		name:      c.Podname,
+	}
+	if c.OwnerReference != nil {
+		p.ownerKind = c.OwnerReference.Kind
+		p.ownerName = c.OwnerReference.Name
+	}
+
+	t.mu.Lock()
+	t.pods[c.Netns] = p
+	t.mu.Unlock()
+
+	// The creator recorded when the network namespace was created, if
+	// any, is kept.
+	var creator C.struct_creator
+	err := t.objs.Creators.Update(c.Netns, unsafe.Pointer(&creator), ebpf.UpdateNoExist)
+	if err != nil && !errors.Is(err, ebpf.ErrKeyExist) {
+		return fmt.Errorf("error recording network namespace %d: %w", c.Netns, err)
+	}
+
+	return nil
+}
+
+func (t *Tracer) run() {
+	for {
+		record, err := t.reader.Read()
+		if err != nil {
+			if errors.Is(err, perf.ErrClosed) {
+				return
+			}
+
+			msg := fmt.Sprintf("Error reading perf ring buffer: %s", err)
+			t.eventCallback(types.Base(eventtypes.Err(msg, t.node)))
+			return
+		}
+
+		if record.LostSamples > 0 {
+			msg := fmt.Sprintf("lost %d samples", record.LostSamples)
+			t.eventCallback(types.Base(eventtypes.Warn(msg, t.node)))
+			continue
+		}
+
+		eventC := (*C.struct_event)(unsafe.Pointer(&record.RawSample[0]))
+
+		event := types.Event{
+			Event: eventtypes.Event{
+				Type: eventtypes.NORMAL,
+				Node: t.node,
+			},
+			NetnsID:   uint64(eventC.netns_id),
+			Pid:       uint32(eventC.creator.pid),
+			Comm:      C.GoString(&eventC.creator.comm[0]),
+			MountNsID: uint64(eventC.creator.mntns_id),
+		}
+
+		switch eventC._type {
+		case C.EVENT_NETNS_CREATE:
+			event.Kind = types.KindNetnsCreate
+		case C.EVENT_NETNS_DELETE:
+			event.Kind = types.KindNetnsDelete
+
+			t.mu.Lock()
+			p, ok := t.pods[event.NetnsID]
+			delete(t.pods, event.NetnsID)
+			t.mu.Unlock()
+
+			if ok {
+				event.Namespace = p.namespace
+				event.Pod = p.name
+				event.OwnerKind = p.ownerKind
+				event.OwnerName = p.ownerName
+				t.eventCallback(event)
+				continue
+			}
+		default:
+			continue
+		}
+
+		if event.MountNsID != 0 {
+			container := t.resolver.LookupContainerByMntns(event.MountNsID)
+			if container != nil {
+				event.Namespace = container.Namespace
+				event.Pod = container.Podname
+				event.Container = container.Name
+				event.Sandbox = container.Sandbox
+			}
+		}
+
+		t.eventCallback(event)
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+// Kinds of the events of the gadget.
+const (
+	// KindNetnsCreate is the creation of a network namespace.
+	KindNetnsCreate = "netns-create"
+
+	// KindNetnsDelete is the deletion of a network namespace.
+	KindNetnsDelete = "netns-delete"
+
+	// KindHostNamespaces is a container sharing namespaces with the host,
+	// like the ones of the pods with hostNetwork, hostPID or hostIPC.
+	KindHostNamespaces = "host-namespaces"
+)
+
+// Namespaces shared with the host in Event.HostNamespaces.
+const (
+	HostNetwork = "network"
+	HostPID     = "pid"
+	HostIPC     = "ipc"
+)
+
+type Event struct {
+	eventtypes.Event
+
+	Kind string `json:"kind,omitempty"`
+
+	// NetnsID is the network namespace created or deleted.
+	NetnsID uint64 `json:"netnsid,omitempty"`
+
+	// Pid, Comm and MountNsID are the process which created the network
+	// namespace, when it's known.
+	Pid       uint32 `json:"pid,omitempty"`
+	Comm      string `json:"pcomm,omitempty"`
+	MountNsID uint64 `json:"mountnsid,omitempty"`
+
+	// HostNamespaces are the namespaces of the host shared by the
+	// container: HostNetwork, HostPID and HostIPC.
+	HostNamespaces []string `json:"hostNamespaces,omitempty"`
+
+	// OwnerKind and OwnerName are the owner of the pod, e.g. its
+	// ReplicaSet or DaemonSet.
+	OwnerKind string `json:"ownerKind,omitempty"`
+	OwnerName string `json:"ownerName,omitempty"`
+}
+
+func Base(ev eventtypes.Event) Event {
+	return Event{
+		Event: ev,
+	}
+}
+
+// Namespaces are the inodes of the namespaces of a process, 0 when they
+// aren't known.
+type Namespaces struct {
+	Net uint64
+	Pid uint64
+	Ipc uint64
+}
+
+// SharedNamespaces returns the namespaces of the host shared by a
+// container. The namespaces not known aren't compared.
+func SharedNamespaces(container, host Namespaces) []string {
+	var shared []string
+	same := func(c, h uint64) bool {
+		return c != 0 && c == h
+	}
+	if same(container.Net, host.Net) {
+		shared = append(shared, HostNetwork)
+	}
+	if same(container.Pid, host.Pid) {
+		shared = append(shared, HostPID)
+	}
+	if same(container.Ipc, host.Ipc) {
+		shared = append(shared, HostIPC)
+	}
+	return shared
+}
@@ -0,0 +1,64 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSharedNamespaces(t *testing.T) {
+	host := Namespaces{Net: 1, Pid: 2, Ipc: 3}
+
+	tests := []struct {
+		name      string
+		container Namespaces
+		expected  []string
+	}{
+		{
+			name:      "no namespace shared",
+			container: Namespaces{Net: 4, Pid: 5, Ipc: 6},
+		},
+		{
+			name:      "host network",
+			container: Namespaces{Net: 1, Pid: 5, Ipc: 6},
+			expected:  []string{HostNetwork},
+		},
+		{
+			name:      "all namespaces shared",
+			container: host,
+			expected:  []string{HostNetwork, HostPID, HostIPC},
+		},
+		{
+			name:      "unknown namespaces",
+			container: Namespaces{Net: 1},
+			expected:  []string{HostNetwork},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			shared := SharedNamespaces(test.container, host)
+			if !reflect.DeepEqual(shared, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, shared)
+			}
+		})
+	}
+
+	if shared := SharedNamespaces(Namespaces{}, Namespaces{}); shared != nil {
+		t.Fatalf("expected no namespace shared when none is known, got %v", shared)
+	}
+}
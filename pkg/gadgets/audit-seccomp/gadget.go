@@ -19,6 +19,7 @@ import (
 	"path/filepath"
 
 	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	"github.com/kinvolk/inspektor-gadget/pkg/bpferror"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
 	auditseccomptracer "github.com/kinvolk/inspektor-gadget/pkg/gadgets/audit-seccomp/tracer"
 	types "github.com/kinvolk/inspektor-gadget/pkg/gadgets/audit-seccomp/types"
@@ -61,6 +62,10 @@ func (f *TraceFactory) OutputModesSupported() map[string]struct{} {
 	}
 }
 
+func (f *TraceFactory) NewEvent() gadgets.Event {
+	return &types.Event{}
+}
+
 func deleteTrace(name string, t interface{}) {
 	trace := t.(*Trace)
 	if trace.started {
@@ -112,7 +117,7 @@ func (t *Trace) Start(trace *gadgetv1alpha1.Trace) {
 	}
 	t.tracer, err = auditseccomptracer.NewTracer(config, eventCallback, trace.Spec.Node)
 	if err != nil {
-		trace.Status.OperationError = fmt.Sprintf("Failed to start audit seccomp tracer: %s", err)
+		trace.Status.OperationError = fmt.Sprintf("Failed to start audit seccomp tracer: %s", bpferror.Describe(err))
 		return
 	}
 	t.started = true
@@ -0,0 +1,195 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bashreadline
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	"github.com/kinvolk/inspektor-gadget/pkg/bpferror"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/bashreadline/tracer"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/bashreadline/types"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/containerbinary"
+	pb "github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/api"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/pubsub"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+type Trace struct {
+	resolver gadgets.Resolver
+
+	started bool
+	tracer  *tracer.Tracer
+}
+
+type TraceFactory struct {
+	gadgets.BaseFactory
+}
+
+func NewFactory() gadgets.TraceFactory {
+	return &TraceFactory{
+		BaseFactory: gadgets.BaseFactory{DeleteTrace: deleteTrace},
+	}
+}
+
+func (f *TraceFactory) Description() string {
+	return `bashreadline traces the command lines typed in the interactive bash shells of the containers, like the ones opened with kubectl exec, by attaching a uretprobe to the readline() function of bash. The scripts and the commands run with bash -c don't use readline() and aren't reported.`
+}
+
+func (f *TraceFactory) Parameters() []gadgets.GadgetParameter {
+	return []gadgets.GadgetParameter{
+		{
+			Name:        "binary",
+			Description: "Absolute path, in the containers, of the bash executable or of the readline library it uses",
+			Default:     types.BinaryDefault,
+		},
+	}
+}
+
+func (f *TraceFactory) OutputModesSupported() map[string]struct{} {
+	return map[string]struct{}{
+		"Stream": {},
+	}
+}
+
+func (f *TraceFactory) NewEvent() gadgets.Event {
+	return &types.Event{}
+}
+
+func deleteTrace(name string, t interface{}) {
+	trace := t.(*Trace)
+	if trace.started {
+		trace.resolver.Unsubscribe(genPubSubKey(name))
+		trace.tracer.Stop()
+		trace.tracer = nil
+	}
+}
+
+func (f *TraceFactory) Operations() map[string]gadgets.TraceOperation {
+	n := func() interface{} {
+		return &Trace{
+			resolver: f.Resolver,
+		}
+	}
+
+	return map[string]gadgets.TraceOperation{
+		"start": {
+			Doc: "Start bashreadline gadget",
+			Operation: func(name string, trace *gadgetv1alpha1.Trace) {
+				f.LookupOrCreate(name, n).(*Trace).Start(trace)
+			},
+		},
+		"stop": {
+			Doc: "Stop bashreadline gadget",
+			Operation: func(name string, trace *gadgetv1alpha1.Trace) {
+				f.LookupOrCreate(name, n).(*Trace).Stop(trace)
+			},
+		},
+	}
+}
+
+type pubSubKey string
+
+func genPubSubKey(name string) pubSubKey {
+	return pubSubKey(fmt.Sprintf("gadget/bashreadline/%s", name))
+}
+
+func (t *Trace) Start(trace *gadgetv1alpha1.Trace) {
+	if t.started {
+		trace.Status.State = "Started"
+		return
+	}
+
+	binary := types.BinaryDefault
+	if val, ok := trace.Spec.Parameters["binary"]; ok {
+		if !filepath.IsAbs(val) {
+			trace.Status.OperationError = "binary must be set to an absolute path"
+			return
+		}
+		binary = filepath.Clean(val)
+	}
+
+	traceName := gadgets.TraceName(trace.ObjectMeta.Namespace, trace.ObjectMeta.Name)
+
+	eventCallback := func(event types.Event) {
+		t.resolver.PublishEvent(traceName, eventtypes.EventString(event))
+	}
+
+	var err error
+
+	config := &tracer.Config{
+		MountnsMap: gadgets.TracePinPath(trace.ObjectMeta.Namespace, trace.ObjectMeta.Name),
+		Binary:     binary,
+	}
+	t.tracer, err = tracer.NewTracer(config, t.resolver, eventCallback, trace.Spec.Node)
+	if err != nil {
+		trace.Status.OperationError = fmt.Sprintf("failed to create tracer: %s", bpferror.Describe(err))
+		return
+	}
+
+	addContainer := func(container *pb.ContainerDefinition) {
+		err := t.tracer.AddContainer(container)
+		if err != nil && !errors.Is(err, containerbinary.ErrBinaryNotFound) {
+			msg := fmt.Sprintf("failed to trace container %s/%s/%s: %s",
+				container.Namespace, container.Podname, container.Name, err)
+			eventCallback(types.Base(eventtypes.Warn(msg, trace.Spec.Node)))
+		}
+	}
+
+	containerEventCallback := func(event pubsub.PubSubEvent) {
+		switch event.Type {
+		case pubsub.EventTypeAddContainer:
+			addContainer(&event.Container)
+		case pubsub.EventTypeRemoveContainer:
+			t.tracer.RemoveContainer(&event.Container)
+		}
+	}
+
+	existingContainers := t.resolver.Subscribe(
+		genPubSubKey(trace.ObjectMeta.Namespace+"/"+trace.ObjectMeta.Name),
+		*gadgets.ContainerSelectorFromContainerFilter(trace.Spec.Filter),
+		containerEventCallback,
+	)
+
+	for _, c := range existingContainers {
+		addContainer(c)
+	}
+
+	if t.tracer.Attached() == 0 {
+		msg := fmt.Sprintf("%s not found in the selected containers, waiting for new ones", binary)
+		eventCallback(types.Base(eventtypes.Info(msg, trace.Spec.Node)))
+	}
+
+	t.started = true
+
+	trace.Status.State = "Started"
+}
+
+func (t *Trace) Stop(trace *gadgetv1alpha1.Trace) {
+	if !t.started {
+		trace.Status.OperationError = "Not started"
+		return
+	}
+
+	t.resolver.Unsubscribe(genPubSubKey(trace.ObjectMeta.Namespace + "/" + trace.ObjectMeta.Name))
+	t.tracer.Stop()
+	t.tracer = nil
+	t.started = false
+
+	trace.Status.State = "Stopped"
+}
@@ -0,0 +1,59 @@
+// SPDX-License-Identifier: GPL-2.0
+// Copyright (c) 2022 The Inspektor Gadget authors
+// Based on bashreadline(8) from BCC by Brendan Gregg.
+#include <vmlinux/vmlinux.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_tracing.h>
+#include "bashreadline.h"
+
+const volatile bool filter_by_mnt_ns = false;
+
+struct {
+	__uint(type, BPF_MAP_TYPE_PERF_EVENT_ARRAY);
+	__uint(key_size, sizeof(u32));
+	__uint(value_size, sizeof(u32));
+} events SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, 1024);
+	__uint(key_size, sizeof(u64));
+	__uint(value_size, sizeof(u32));
+} mount_ns_set SEC(".maps");
+
+static __always_inline u64 get_mntns_id(void)
+{
+	struct task_struct *task;
+
+	task = (struct task_struct *) bpf_get_current_task();
+	return (u64) BPF_CORE_READ(task, nsproxy, mnt_ns, ns.inum);
+}
+
+/* readline() returns the line typed, without the final newline, or NULL
+ * on EOF. */
+SEC("uretprobe/readline")
+int BPF_KRETPROBE(ig_readline_x, const char *ret)
+{
+	struct event event = {};
+	u64 mntns_id;
+
+	if (!ret)
+		return 0;
+
+	mntns_id = get_mntns_id();
+	if (filter_by_mnt_ns && !bpf_map_lookup_elem(&mount_ns_set, &mntns_id))
+		return 0;
+
+	event.mntns_id = mntns_id;
+	event.pid = bpf_get_current_pid_tgid() >> 32;
+	event.uid = (u32) bpf_get_current_uid_gid();
+	bpf_get_current_comm(&event.comm, sizeof(event.comm));
+	bpf_probe_read_user_str(&event.line, sizeof(event.line), ret);
+
+	bpf_perf_event_output(ctx, &events, BPF_F_CURRENT_CPU,
+			      &event, sizeof(event));
+	return 0;
+}
+
+char LICENSE[] SEC("license") = "GPL";
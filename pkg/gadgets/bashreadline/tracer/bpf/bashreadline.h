@@ -0,0 +1,16 @@
+/* SPDX-License-Identifier: (LGPL-2.1 OR BSD-2-Clause) */
+#ifndef __BASHREADLINE_H
+#define __BASHREADLINE_H
+
+#define TASK_COMM_LEN 16
+#define LINE_LEN 256
+
+struct event {
+	__u64 mntns_id;
+	__u32 pid;
+	__u32 uid;
+	char comm[TASK_COMM_LEN];
+	char line[LINE_LEN];
+};
+
+#endif /* __BASHREADLINE_H */
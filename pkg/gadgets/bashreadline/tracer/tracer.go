@@ -0,0 +1,219 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+// #include <linux/types.h>
+// #include "./bpf/bashreadline.h"
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+	"github.com/cilium/ebpf/perf"
+
+	containercollection "github.com/kinvolk/inspektor-gadget/pkg/container-collection"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/bashreadline/types"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/containerbinary"
+	pb "github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/api"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+//go:generate sh -c "GOOS=$(go env GOHOSTOS) GOARCH=$(go env GOHOSTARCH) go run github.com/cilium/ebpf/cmd/bpf2go -target bpfel -cc clang bashreadline ./bpf/bashreadline.bpf.c -- -I./bpf/ -I../../.. -target bpf -D__TARGET_ARCH_x86"
+
+type Config struct {
+	// TODO: Make it a *ebpf.Map once
+	// https://github.com/cilium/ebpf/issues/515 and
+	// https://github.com/cilium/ebpf/issues/517 are fixed
+	MountnsMap string
+
+	// Binary is the path, in the containers, of the executable or library
+	// providing readline().
+	Binary string
+}
+
+// probe is the uretprobe attached to readline() in a binary.
+type probe struct {
+	link link.Link
+}
+
+func (p *probe) Close() {
+	gadgets.CloseLink(p.link)
+}
+
+type Tracer struct {
+	config        *Config
+	resolver      containercollection.ContainerResolver
+	eventCallback func(types.Event)
+	node          string
+
+	objs     bashreadlineObjects
+	reader   *perf.Reader
+	attacher *containerbinary.Attacher
+}
+
+func NewTracer(config *Config, resolver containercollection.ContainerResolver,
+	eventCallback func(types.Event), node string) (*Tracer, error) {
+	t := &Tracer{
+		config:        config,
+		resolver:      resolver,
+		eventCallback: eventCallback,
+		node:          node,
+	}
+	t.attacher = containerbinary.NewAttacher(config.Binary, t.attach)
+
+	if err := t.start(); err != nil {
+		t.Stop()
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (t *Tracer) Stop() {
+	t.attacher.Close()
+
+	if t.reader != nil {
+		t.reader.Close()
+		t.reader = nil
+	}
+
+	t.objs.Close()
+}
+
+func (t *Tracer) start() error {
+	spec, err := loadBashreadline()
+	if err != nil {
+		return fmt.Errorf("failed to load ebpf program: %w", err)
+	}
+
+	filterByMntNs := false
+	opts := ebpf.CollectionOptions{}
+
+	if t.config.MountnsMap != "" {
+		filterByMntNs = true
+		m := spec.Maps["mount_ns_set"]
+		m.Pinning = ebpf.PinByName
+		m.Name = filepath.Base(t.config.MountnsMap)
+		opts.Maps.PinPath = filepath.Dir(t.config.MountnsMap)
+	}
+
+	consts := map[string]interface{}{
+		"filter_by_mnt_ns": filterByMntNs,
+	}
+
+	if err := spec.RewriteConstants(consts); err != nil {
+		return fmt.Errorf("error RewriteConstants: %w", err)
+	}
+
+	if err := spec.LoadAndAssign(&t.objs, &opts); err != nil {
+		return fmt.Errorf("failed to load ebpf program: %w", err)
+	}
+
+	reader, err := perf.NewReader(t.objs.bashreadlineMaps.Events, gadgets.PerfBufferPages*os.Getpagesize())
+	if err != nil {
+		return fmt.Errorf("error creating perf ring buffer: %w", err)
+	}
+	t.reader = reader
+
+	go t.run()
+
+	return nil
+}
+
+// AddContainer attaches the uretprobe to readline() in the binary of the
+// container if it isn't attached to the same file yet. It returns
+// containerbinary.ErrBinaryNotFound if the container doesn't have the
+// binary.
+func (t *Tracer) AddContainer(c *pb.ContainerDefinition) error {
+	return t.attacher.AddContainer(c.Pid, c.Mntns)
+}
+
+// RemoveContainer detaches the uretprobe from the binary of the container
+// when no other container uses it.
+func (t *Tracer) RemoveContainer(c *pb.ContainerDefinition) {
+	t.attacher.RemoveContainer(c.Mntns)
+}
+
+// Attached returns the number of binaries the uretprobe is attached to.
+func (t *Tracer) Attached() int {
+	return t.attacher.Attached()
+}
+
+func (t *Tracer) attach(path string) (containerbinary.Probes, error) {
+	ex, err := link.OpenExecutable(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", t.config.Binary, err)
+	}
+
+	l, err := ex.Uretprobe("readline", t.objs.IgReadlineX, nil)
+	if err != nil {
+		return nil, fmt.Errorf("attaching to readline in %s: %w", t.config.Binary, err)
+	}
+
+	return &probe{link: l}, nil
+}
+
+func (t *Tracer) run() {
+	for {
+		record, err := t.reader.Read()
+		if err != nil {
+			if errors.Is(err, perf.ErrClosed) {
+				// nothing to do, we're done
+				return
+			}
+
+			msg := fmt.Sprintf("Error reading perf ring buffer: %s", err)
+			t.eventCallback(types.Base(eventtypes.Err(msg, t.node)))
+			return
+		}
+
+		if record.LostSamples > 0 {
+			msg := fmt.Sprintf("lost %d samples", record.LostSamples)
+			t.eventCallback(types.Base(eventtypes.Warn(msg, t.node)))
+			continue
+		}
+
+		eventC := (*C.struct_event)(unsafe.Pointer(&record.RawSample[0]))
+
+		event := types.Event{
+			Event: eventtypes.Event{
+				Type: eventtypes.NORMAL,
+				Node: t.node,
+			},
+			MountNsID: uint64(eventC.mntns_id),
+			Pid:       uint32(eventC.pid),
+			UID:       uint32(eventC.uid),
+			Comm:      C.GoString(&eventC.comm[0]),
+			Line:      C.GoString(&eventC.line[0]),
+		}
+
+		container := t.resolver.LookupContainerByMntns(event.MountNsID)
+		if container != nil {
+			event.Container = container.Name
+			event.Pod = container.Podname
+			event.Sandbox = container.Sandbox
+			event.Namespace = container.Namespace
+		}
+
+		t.eventCallback(event)
+	}
+}
@@ -22,10 +22,10 @@ import (
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/kinvolk/inspektor-gadget/pkg/bpferror"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/bindsnoop/tracer"
 
-	coretracer "github.com/kinvolk/inspektor-gadget/pkg/gadgets/bindsnoop/tracer/core"
 	standardtracer "github.com/kinvolk/inspektor-gadget/pkg/gadgets/bindsnoop/tracer/standard"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/bindsnoop/types"
 
@@ -53,6 +53,24 @@ func (f *TraceFactory) Description() string {
 	return `bindsnoop traces the kernel functions performing socket binding.`
 }
 
+func (f *TraceFactory) Parameters() []gadgets.GadgetParameter {
+	return []gadgets.GadgetParameter{
+		{
+			Name:        "pid",
+			Description: "Only trace the bind calls of this PID",
+		},
+		{
+			Name:        "ports",
+			Description: "Comma-separated list of ports to trace",
+		},
+		{
+			Name:        "ignore_errors",
+			Description: "Ignore the failed bind calls",
+			Default:     "false",
+		},
+	}
+}
+
 func (f *TraceFactory) OutputModesSupported() map[string]struct{} {
 	return map[string]struct{}{
 		"Stream": {},
@@ -153,19 +171,13 @@ func (t *Trace) Start(trace *gadgetv1alpha1.Trace) {
 		TargetPorts:  targetPorts,
 		IgnoreErrors: ignoreErrors,
 	}
-	t.tracer, err = coretracer.NewTracer(config, t.resolver, eventCallback, trace.Spec.Node)
+	t.tracer, err = NewTracer(config, t.resolver, eventCallback, trace.Spec.Node)
 	if err != nil {
+		trace.Status.OperationError = fmt.Sprintf("failed to create tracer: %s", bpferror.Describe(err))
+		return
+	}
+	if _, ok := t.tracer.(*standardtracer.Tracer); ok {
 		trace.Status.OperationWarning = fmt.Sprint("failed to create core tracer. Falling back to standard one")
-
-		// fallback to standard tracer
-		log.Infof("Gadget %s: falling back to standard tracer. CO-RE tracer failed: %s",
-			trace.Spec.Gadget, err)
-
-		t.tracer, err = standardtracer.NewTracer(config, t.resolver, eventCallback, trace.Spec.Node)
-		if err != nil {
-			trace.Status.OperationError = fmt.Sprintf("failed to create tracer: %s", err)
-			return
-		}
 	}
 
 	t.started = true
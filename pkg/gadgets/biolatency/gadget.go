@@ -15,20 +15,24 @@
 package biolatency
 
 import (
-	"bytes"
 	"fmt"
-	"os/exec"
-	"syscall"
+	"strconv"
+
+	"github.com/cilium/ebpf"
 
 	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	"github.com/kinvolk/inspektor-gadget/pkg/bpferror"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	biolatencytracer "github.com/kinvolk/inspektor-gadget/pkg/gadgets/biolatency/tracer"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/biolatency/types"
 )
 
 type Trace struct {
-	started bool
-	cmd     *exec.Cmd
-	stdout  bytes.Buffer
-	stderr  bytes.Buffer
+	resolver gadgets.Resolver
+
+	started      bool
+	perContainer bool
+	tracer       *biolatencytracer.Tracer
 }
 
 type TraceFactory struct {
@@ -44,7 +48,18 @@ func NewFactory() gadgets.TraceFactory {
 func (f *TraceFactory) Description() string {
 	return `The biolatency gadget traces block device I/O (disk I/O), and records the
 distribution of I/O latency (time), giving this as a histogram when it is
-stopped.`
+stopped. The requests of the whole node are traced unless a filter selects
+containers, and a histogram can be given for each container.`
+}
+
+func (f *TraceFactory) Parameters() []gadgets.GadgetParameter {
+	return []gadgets.GadgetParameter{
+		{
+			Name:        types.PerContainerParam,
+			Description: "Give a histogram for each container instead of a single one",
+			Default:     "false",
+		},
+	}
 }
 
 func (f *TraceFactory) OutputModesSupported() map[string]struct{} {
@@ -53,17 +68,26 @@ func (f *TraceFactory) OutputModesSupported() map[string]struct{} {
 	}
 }
 
+func (f *TraceFactory) Maps(name string) map[string]*ebpf.Map {
+	t, ok := f.LookupOrCreate(name, nil).(*Trace)
+	if !ok || !t.started {
+		return nil
+	}
+	return t.tracer.Maps()
+}
+
 func deleteTrace(name string, t interface{}) {
 	trace := t.(*Trace)
-	if trace.started {
-		trace.cmd.Process.Kill()
-		trace.cmd.Wait()
+	if trace.tracer != nil {
+		trace.tracer.Stop()
 	}
 }
 
 func (f *TraceFactory) Operations() map[string]gadgets.TraceOperation {
 	n := func() interface{} {
-		return &Trace{}
+		return &Trace{
+			resolver: f.Resolver,
+		}
 	}
 
 	return map[string]gadgets.TraceOperation{
@@ -83,31 +107,38 @@ func (f *TraceFactory) Operations() map[string]gadgets.TraceOperation {
 }
 
 func (t *Trace) Start(trace *gadgetv1alpha1.Trace) {
-	if trace.Spec.Filter != nil {
-		trace.Status.OperationError = "Invalid filter: Filtering is not supported"
-		return
-	}
-
 	if t.started {
 		trace.Status.State = "Started"
 		return
 	}
 
-	t.cmd = exec.Command("/usr/share/bcc/tools/biolatency")
-	t.stdout.Reset()
-	t.stderr.Reset()
-	t.cmd.Stdout = &t.stdout
-	t.cmd.Stderr = &t.stderr
-	err := t.cmd.Start()
+	perContainer := false
+	if val, ok := trace.Spec.Parameters[types.PerContainerParam]; ok {
+		var err error
+		perContainer, err = strconv.ParseBool(val)
+		if err != nil {
+			trace.Status.OperationError = fmt.Sprintf("%q is not valid for %s", val, types.PerContainerParam)
+			return
+		}
+	}
+
+	config := &biolatencytracer.Config{}
+	if trace.Spec.Filter != nil {
+		config.MountnsMap = gadgets.TracePinPath(trace.ObjectMeta.Namespace, trace.ObjectMeta.Name)
+	}
+
+	tracer, err := biolatencytracer.NewTracer(config, t.resolver)
 	if err != nil {
-		trace.Status.OperationError = fmt.Sprintf("Failed to start: %s", err)
+		trace.Status.OperationError = fmt.Sprintf("Failed to start: %s", bpferror.Describe(err))
 		return
 	}
+
+	t.tracer = tracer
+	t.perContainer = perContainer
 	t.started = true
 
 	trace.Status.Output = ""
 	trace.Status.State = "Started"
-	return
 }
 
 func (t *Trace) Stop(trace *gadgetv1alpha1.Trace) {
@@ -115,33 +146,18 @@ func (t *Trace) Stop(trace *gadgetv1alpha1.Trace) {
 		trace.Status.OperationError = "Not started"
 		return
 	}
-	err := t.cmd.Process.Signal(syscall.SIGINT)
-	if err != nil {
-		trace.Status.OperationError = fmt.Sprintf(
-			"Failed to send SIGINT to process: %s (stdout: %q stderr: %q)",
-			err,
-			t.stdout.String(),
-			t.stderr.String(),
-		)
-		return
-	}
 
-	err = t.cmd.Wait()
+	histograms, err := t.tracer.Histograms()
+
+	t.tracer.Stop()
+	t.tracer = nil
+	t.started = false
+
 	if err != nil {
-		trace.Status.OperationError = fmt.Sprintf(
-			"Failed to wait for process: %s (stdout: %q stderr: %q)",
-			err,
-			t.stdout.String(),
-			t.stderr.String(),
-		)
+		trace.Status.OperationError = fmt.Sprintf("Failed to read results: %s", err)
 		return
 	}
-	t.cmd = nil
-	t.started = false
-
-	output := t.stdout.String()
 
-	trace.Status.Output = output
+	trace.Status.Output = types.Report(histograms, t.perContainer)
 	trace.Status.State = "Completed"
-	return
 }
@@ -0,0 +1,145 @@
+// SPDX-License-Identifier: GPL-2.0
+// Copyright (c) 2022 The Inspektor Gadget authors
+// Based on biolatency(8) from libbpf-tools, Copyright (c) 2020 Wenbo Zhang
+#include <vmlinux/vmlinux.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_tracing.h>
+#include "biolatency.h"
+
+#define MAX_ENTRIES	10240
+
+const volatile bool filter_by_mnt_ns = false;
+
+static struct hist zero_hist = {};
+
+/* Mount namespace of the process which created the request */
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, struct request *);
+	__type(value, u64);
+} mntnsbyreq SEC(".maps");
+
+/* When the request was issued to the device */
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, struct request *);
+	__type(value, u64);
+} starts SEC(".maps");
+
+/* The histograms, by mount namespace */
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, u64);
+	__type(value, struct hist);
+} hists SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, 1024);
+	__uint(key_size, sizeof(u64));
+	__uint(value_size, sizeof(u32));
+} mount_ns_set SEC(".maps");
+
+static __always_inline u32 log2(u32 v)
+{
+	u32 shift, r;
+
+	r = (v > 0xFFFF) << 4; v >>= r;
+	shift = (v > 0xFF) << 3; v >>= shift; r |= shift;
+	shift = (v > 0xF) << 2; v >>= shift; r |= shift;
+	shift = (v > 0x3) << 1; v >>= shift; r |= shift;
+	r |= (v >> 1);
+
+	return r;
+}
+
+static __always_inline u32 log2l(u64 v)
+{
+	u32 hi = v >> 32;
+
+	if (hi)
+		return log2(hi) + 32;
+	return log2(v);
+}
+
+SEC("kprobe/blk_account_io_start")
+int BPF_KPROBE(ig_bio_start, struct request *req)
+{
+	struct task_struct *task = (struct task_struct*)bpf_get_current_task();
+	u64 mntns_id;
+
+	mntns_id = (u64) BPF_CORE_READ(task, nsproxy, mnt_ns, ns.inum);
+
+	if (filter_by_mnt_ns && !bpf_map_lookup_elem(&mount_ns_set, &mntns_id))
+		return 0;
+
+	bpf_map_update_elem(&mntnsbyreq, &req, &mntns_id, 0);
+
+	return 0;
+}
+
+SEC("kprobe/blk_mq_start_request")
+int BPF_KPROBE(ig_bio_issue, struct request *req)
+{
+	u64 ts;
+
+	/*
+	 * The request can be issued from another context than the one of the
+	 * process which created it, e.g. a kworker, so filter on the mount
+	 * namespace saved by ig_bio_start.
+	 */
+	if (filter_by_mnt_ns && !bpf_map_lookup_elem(&mntnsbyreq, &req))
+		return 0;
+
+	ts = bpf_ktime_get_ns();
+	bpf_map_update_elem(&starts, &req, &ts, 0);
+
+	return 0;
+}
+
+SEC("kprobe/blk_account_io_done")
+int BPF_KPROBE(ig_bio_done, struct request *req)
+{
+	u64 *tsp, *mntnsp, mntns_id = 0;
+	struct hist *histp;
+	u64 delta;
+	u32 slot;
+
+	tsp = bpf_map_lookup_elem(&starts, &req);
+	if (!tsp)
+		goto cleanup;	/* missed the issue of the request */
+
+	/* The requests created before the gadget started don't have a mount
+	 * namespace: they are counted with the key 0. */
+	mntnsp = bpf_map_lookup_elem(&mntnsbyreq, &req);
+	if (mntnsp)
+		mntns_id = *mntnsp;
+	else if (filter_by_mnt_ns)
+		goto cleanup;
+
+	delta = (bpf_ktime_get_ns() - *tsp) / 1000;
+
+	histp = bpf_map_lookup_elem(&hists, &mntns_id);
+	if (!histp) {
+		bpf_map_update_elem(&hists, &mntns_id, &zero_hist, BPF_NOEXIST);
+		histp = bpf_map_lookup_elem(&hists, &mntns_id);
+		if (!histp)
+			goto cleanup;
+	}
+
+	slot = log2l(delta);
+	if (slot >= MAX_SLOTS)
+		slot = MAX_SLOTS - 1;
+	__sync_fetch_and_add(&histp->slots[slot], 1);
+
+cleanup:
+	bpf_map_delete_elem(&starts, &req);
+	bpf_map_delete_elem(&mntnsbyreq, &req);
+	return 0;
+}
+
+char LICENSE[] SEC("license") = "GPL";
@@ -0,0 +1,17 @@
+/* SPDX-License-Identifier: (LGPL-2.1 OR BSD-2-Clause) */
+#ifndef __BIOLATENCY_H
+#define __BIOLATENCY_H
+
+/*
+ * The I/O latencies are counted in power-of-two buckets of microseconds:
+ * slot i is [2^i, 2^(i+1) - 1], slot 0 also holds 0 and the last one all
+ * the latencies longer than 2^(MAX_SLOTS - 1) µs.
+ */
+#define MAX_SLOTS	27
+
+/* The distribution of the latencies of a mount namespace */
+struct hist {
+	__u64 slots[MAX_SLOTS];
+};
+
+#endif /* __BIOLATENCY_H */
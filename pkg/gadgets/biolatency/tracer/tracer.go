@@ -0,0 +1,165 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+
+	containercollection "github.com/kinvolk/inspektor-gadget/pkg/container-collection"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/biolatency/types"
+	runqlattypes "github.com/kinvolk/inspektor-gadget/pkg/gadgets/runqlat/types"
+	"github.com/kinvolk/inspektor-gadget/pkg/mapdump"
+)
+
+// #include <linux/types.h>
+// #include "./bpf/biolatency.h"
+import "C"
+
+//go:generate sh -c "GOOS=$(go env GOHOSTOS) GOARCH=$(go env GOHOSTARCH) go run github.com/cilium/ebpf/cmd/bpf2go -target bpfel -cc clang biolatency ./bpf/biolatency.bpf.c -- -I./bpf/ -I../../.. -target bpf -D__TARGET_ARCH_x86"
+
+type Config struct {
+	// MountnsMap is the path of the pinned map of the mount namespaces of
+	// the containers to trace. The requests of all the processes of the
+	// node are traced when it's empty.
+	// TODO: Make it a *ebpf.Map once
+	// https://github.com/cilium/ebpf/issues/515 and
+	// https://github.com/cilium/ebpf/issues/517 are fixed
+	MountnsMap string
+}
+
+type Tracer struct {
+	config   *Config
+	resolver containercollection.ContainerResolver
+	objs     biolatencyObjects
+	links    []link.Link
+}
+
+func NewTracer(config *Config, resolver containercollection.ContainerResolver) (*Tracer, error) {
+	t := &Tracer{
+		config:   config,
+		resolver: resolver,
+	}
+
+	if err := t.start(); err != nil {
+		t.Stop()
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (t *Tracer) Stop() {
+	for i := range t.links {
+		t.links[i] = gadgets.CloseLink(t.links[i])
+	}
+	t.links = nil
+
+	t.objs.Close()
+}
+
+// Maps returns the BPF maps of the tracer, so they can be dumped for
+// debugging.
+func (t *Tracer) Maps() map[string]*ebpf.Map {
+	return mapdump.MapsOf(&t.objs)
+}
+
+func (t *Tracer) start() error {
+	spec, err := loadBiolatency()
+	if err != nil {
+		return fmt.Errorf("failed to load ebpf program: %w", err)
+	}
+
+	filterByMntNs := false
+	opts := ebpf.CollectionOptions{}
+
+	if t.config.MountnsMap != "" {
+		filterByMntNs = true
+		m := spec.Maps["mount_ns_set"]
+		m.Pinning = ebpf.PinByName
+		m.Name = filepath.Base(t.config.MountnsMap)
+		opts.Maps.PinPath = filepath.Dir(t.config.MountnsMap)
+	}
+
+	consts := map[string]interface{}{
+		"filter_by_mnt_ns": filterByMntNs,
+	}
+
+	if err := spec.RewriteConstants(consts); err != nil {
+		return fmt.Errorf("error RewriteConstants: %w", err)
+	}
+
+	if err := spec.LoadAndAssign(&t.objs, &opts); err != nil {
+		return fmt.Errorf("failed to load ebpf program: %w", err)
+	}
+
+	kprobes := []struct {
+		symbol string
+		prog   *ebpf.Program
+	}{
+		{"blk_account_io_start", t.objs.IgBioStart},
+		{"blk_mq_start_request", t.objs.IgBioIssue},
+		{"blk_account_io_done", t.objs.IgBioDone},
+	}
+
+	for _, kp := range kprobes {
+		l, err := link.Kprobe(kp.symbol, kp.prog, nil)
+		if err != nil {
+			return fmt.Errorf("error opening kprobe %s: %w", kp.symbol, err)
+		}
+		t.links = append(t.links, l)
+	}
+
+	return nil
+}
+
+// Histograms returns the distributions of the I/O latencies recorded since
+// the tracer was started, by mount namespace.
+func (t *Tracer) Histograms() ([]types.ContainerHistogram, error) {
+	histograms := []types.ContainerHistogram{}
+
+	var mntnsID uint64
+	// The value is a struct hist.
+	var slots [C.MAX_SLOTS]uint64
+
+	iter := t.objs.Hists.Iterate()
+	for iter.Next(&mntnsID, &slots) {
+		h := types.ContainerHistogram{
+			MountNsID: mntnsID,
+			Histogram: &runqlattypes.Histogram{
+				Unit:  "usecs",
+				Slots: append([]uint64(nil), slots[:]...),
+			},
+		}
+
+		container := t.resolver.LookupContainerByMntns(mntnsID)
+		if container != nil {
+			h.Namespace = container.Namespace
+			h.Pod = container.Podname
+			h.Container = container.Name
+		}
+
+		histograms = append(histograms, h)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("error reading histograms: %w", err)
+	}
+
+	return histograms, nil
+}
@@ -0,0 +1,104 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	runqlattypes "github.com/kinvolk/inspektor-gadget/pkg/gadgets/runqlat/types"
+)
+
+// PerContainerParam tells if a histogram is reported for each container
+// instead of a single one for all the requests traced.
+const PerContainerParam = "per_container"
+
+// ContainerHistogram is the distribution of the latencies of the block
+// I/O requests created by a container, i.e. a mount namespace.
+type ContainerHistogram struct {
+	MountNsID uint64
+	Namespace string
+	Pod       string
+	Container string
+
+	Histogram *runqlattypes.Histogram
+}
+
+// label identifies the container of h in the reports, like the disks in
+// the reports of biolatency -D from BCC.
+func (h *ContainerHistogram) label() string {
+	if h.Container != "" {
+		return fmt.Sprintf("container = %s/%s/%s", h.Namespace, h.Pod, h.Container)
+	}
+	if h.MountNsID == 0 {
+		// The requests created before the gadget started.
+		return "mntns = unknown"
+	}
+	return fmt.Sprintf("mntns = %d", h.MountNsID)
+}
+
+// Report prints the histograms in the format of the BCC tools. Without
+// perContainer, the histograms are summed up into a single one. It returns
+// an empty string when there is no request.
+func Report(histograms []ContainerHistogram, perContainer bool) string {
+	if !perContainer {
+		var total *runqlattypes.Histogram
+		for _, h := range histograms {
+			if total == nil {
+				total = &runqlattypes.Histogram{
+					Unit:  h.Histogram.Unit,
+					Slots: make([]uint64, len(h.Histogram.Slots)),
+				}
+			}
+			for i, count := range h.Histogram.Slots {
+				total.Slots[i] += count
+			}
+		}
+		if total == nil {
+			return ""
+		}
+		return total.String()
+	}
+
+	// The containers first, by name, then the other mount namespaces.
+	sorted := make([]ContainerHistogram, len(histograms))
+	copy(sorted, histograms)
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if (a.Container == "") != (b.Container == "") {
+			return b.Container == ""
+		}
+		if a.Container == "" {
+			return a.MountNsID < b.MountNsID
+		}
+		return a.label() < b.label()
+	})
+
+	var sb strings.Builder
+	for _, h := range sorted {
+		s := h.Histogram.String()
+		if s == "" {
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(h.label() + "\n")
+		sb.WriteString(s)
+	}
+
+	return sb.String()
+}
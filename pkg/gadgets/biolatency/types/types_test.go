@@ -0,0 +1,72 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"testing"
+
+	runqlattypes "github.com/kinvolk/inspektor-gadget/pkg/gadgets/runqlat/types"
+)
+
+func TestReport(t *testing.T) {
+	histograms := []ContainerHistogram{
+		{
+			MountNsID: 4026531840,
+			Histogram: &runqlattypes.Histogram{Unit: "usecs", Slots: []uint64{0, 1, 0}},
+		},
+		{
+			MountNsID: 4026532400,
+			Namespace: "default",
+			Pod:       "db",
+			Container: "postgres",
+			Histogram: &runqlattypes.Histogram{Unit: "usecs", Slots: []uint64{2, 0, 4}},
+		},
+		{
+			MountNsID: 4026532500,
+			Namespace: "default",
+			Pod:       "idle",
+			Container: "idle",
+			Histogram: &runqlattypes.Histogram{Unit: "usecs", Slots: []uint64{0, 0, 0}},
+		},
+	}
+
+	expected := `     usecs               : count    distribution
+         0 -> 1          : 2        |********************                    |
+         2 -> 3          : 1        |**********                              |
+         4 -> 7          : 4        |****************************************|
+`
+	if s := Report(histograms, false); s != expected {
+		t.Fatalf("unexpected report:\n%s\nexpected:\n%s", s, expected)
+	}
+
+	expected = `container = default/db/postgres
+     usecs               : count    distribution
+         0 -> 1          : 2        |********************                    |
+         2 -> 3          : 0        |                                        |
+         4 -> 7          : 4        |****************************************|
+
+mntns = 4026531840
+     usecs               : count    distribution
+         0 -> 1          : 0        |                                        |
+         2 -> 3          : 1        |****************************************|
+`
+	if s := Report(histograms, true); s != expected {
+		t.Fatalf("unexpected report:\n%s\nexpected:\n%s", s, expected)
+	}
+
+	if s := Report(nil, false); s != "" {
+		t.Fatalf("empty report should be an empty string, got %q", s)
+	}
+}
@@ -0,0 +1,155 @@
+// SPDX-License-Identifier: GPL-2.0
+#include <vmlinux/vmlinux.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_tracing.h>
+
+#include "biosnoop.h"
+
+#define REQ_OP_BITS	8
+#define REQ_OP_MASK	((1 << REQ_OP_BITS) - 1)
+
+#define MAX_ENTRIES	10240
+
+const volatile bool filter_by_mnt_ns = false;
+
+/* Process issuing the request, saved when the request is created */
+struct who {
+	__u64 mntns_id;
+	__u32 pid;
+	char task[TASK_COMM_LEN];
+};
+
+/* Saved when the request is issued to the device */
+struct start {
+	__u64 ts;
+	__u64 sector;
+	__u32 len;
+};
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, struct request *);
+	__type(value, struct who);
+} whobyreq SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, struct request *);
+	__type(value, struct start);
+} starts SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_PERF_EVENT_ARRAY);
+	__uint(key_size, sizeof(u32));
+	__uint(value_size, sizeof(u32));
+} events SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, 1024);
+	__uint(key_size, sizeof(u64));
+	__uint(value_size, sizeof(u32));
+} mount_ns_set SEC(".maps");
+
+/* The disk moved from the request to its queue in 5.17 */
+struct request_queue___x {
+	struct gendisk *disk;
+} __attribute__((preserve_access_index));
+
+struct request___x {
+	struct request_queue___x *q;
+	struct gendisk *rq_disk;
+} __attribute__((preserve_access_index));
+
+static __always_inline struct gendisk *get_disk(void *req)
+{
+	struct request___x *r = req;
+
+	if (bpf_core_field_exists(r->rq_disk))
+		return BPF_CORE_READ(r, rq_disk);
+	return BPF_CORE_READ(r, q, disk);
+}
+
+SEC("kprobe/blk_account_io_start")
+int BPF_KPROBE(ig_bio_start, struct request *req)
+{
+	struct task_struct *task = (struct task_struct*)bpf_get_current_task();
+	struct who who = {};
+	u64 mntns_id;
+
+	mntns_id = (u64) BPF_CORE_READ(task, nsproxy, mnt_ns, ns.inum);
+
+	if (filter_by_mnt_ns && !bpf_map_lookup_elem(&mount_ns_set, &mntns_id))
+		return 0;
+
+	who.mntns_id = mntns_id;
+	who.pid = bpf_get_current_pid_tgid() >> 32;
+	bpf_get_current_comm(&who.task, sizeof(who.task));
+	bpf_map_update_elem(&whobyreq, &req, &who, 0);
+
+	return 0;
+}
+
+SEC("kprobe/blk_mq_start_request")
+int BPF_KPROBE(ig_bio_issue, struct request *req)
+{
+	struct start start = {};
+
+	/*
+	 * The request can be issued from another context than the one of the
+	 * process which created it, e.g. a kworker, so filter on the process
+	 * saved by ig_bio_start.
+	 */
+	if (filter_by_mnt_ns && !bpf_map_lookup_elem(&whobyreq, &req))
+		return 0;
+
+	start.ts = bpf_ktime_get_ns();
+	/* Both are decreased while the request is completed */
+	start.sector = BPF_CORE_READ(req, __sector);
+	start.len = BPF_CORE_READ(req, __data_len);
+	bpf_map_update_elem(&starts, &req, &start, 0);
+
+	return 0;
+}
+
+SEC("kprobe/blk_account_io_done")
+int BPF_KPROBE(ig_bio_done, struct request *req)
+{
+	struct event event = {};
+	struct gendisk *disk;
+	struct start *startp;
+	struct who *whop;
+
+	startp = bpf_map_lookup_elem(&starts, &req);
+	if (!startp)
+		goto cleanup;	/* missed the issue of the request */
+
+	whop = bpf_map_lookup_elem(&whobyreq, &req);
+	if (whop) {
+		event.mntns_id = whop->mntns_id;
+		event.pid = whop->pid;
+		__builtin_memcpy(&event.task, whop->task, sizeof(event.task));
+	} else if (filter_by_mnt_ns) {
+		goto cleanup;
+	}
+
+	disk = get_disk(req);
+	event.major = BPF_CORE_READ(disk, major);
+	event.minor = BPF_CORE_READ(disk, first_minor);
+	event.op = BPF_CORE_READ(req, cmd_flags) & REQ_OP_MASK;
+	event.sector = startp->sector;
+	event.len = startp->len;
+	event.delta_us = (bpf_ktime_get_ns() - startp->ts) / 1000;
+
+	bpf_perf_event_output(ctx, &events, BPF_F_CURRENT_CPU, &event, sizeof(event));
+
+cleanup:
+	bpf_map_delete_elem(&starts, &req);
+	bpf_map_delete_elem(&whobyreq, &req);
+	return 0;
+}
+
+char LICENSE[] SEC("license") = "GPL";
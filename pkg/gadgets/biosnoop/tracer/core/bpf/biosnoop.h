@@ -0,0 +1,19 @@
+/* SPDX-License-Identifier: (LGPL-2.1 OR BSD-2-Clause) */
+#ifndef __BIOSNOOP_H
+#define __BIOSNOOP_H
+
+#define TASK_COMM_LEN	16
+
+struct event {
+	__u64 mntns_id;
+	__u64 sector;
+	__u64 delta_us;
+	__u32 pid;
+	__u32 len;
+	__u32 major;
+	__u32 minor;
+	__u32 op;
+	char task[TASK_COMM_LEN];
+};
+
+#endif /* __BIOSNOOP_H */
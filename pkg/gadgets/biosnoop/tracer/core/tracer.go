@@ -0,0 +1,228 @@
+//go:build linux
+// +build linux
+
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+// #include <linux/types.h>
+// #include "./bpf/biosnoop.h"
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+	"github.com/cilium/ebpf/perf"
+
+	containercollection "github.com/kinvolk/inspektor-gadget/pkg/container-collection"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/biosnoop/tracer"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/biosnoop/types"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+//go:generate sh -c "GOOS=$(go env GOHOSTOS) GOARCH=$(go env GOHOSTARCH) go run github.com/cilium/ebpf/cmd/bpf2go -no-global-types -target bpfel -cc clang biosnoop ./bpf/biosnoop.bpf.c -- -I./bpf/ -I../../../../ -target bpf -D__TARGET_ARCH_x86"
+
+type Tracer struct {
+	config        *tracer.Config
+	resolver      containercollection.ContainerResolver
+	eventCallback func(types.Event)
+	node          string
+
+	objs   biosnoopObjects
+	links  []link.Link
+	reader *perf.Reader
+}
+
+func NewTracer(config *tracer.Config, resolver containercollection.ContainerResolver,
+	eventCallback func(types.Event), node string) (*Tracer, error) {
+	t := &Tracer{
+		config:        config,
+		resolver:      resolver,
+		eventCallback: eventCallback,
+		node:          node,
+	}
+
+	if err := t.start(); err != nil {
+		t.Stop()
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (t *Tracer) Stop() {
+	for i := range t.links {
+		t.links[i] = gadgets.CloseLink(t.links[i])
+	}
+	t.links = nil
+
+	if t.reader != nil {
+		t.reader.Close()
+		t.reader = nil
+	}
+
+	t.objs.Close()
+}
+
+// kprobeWithFallback attaches prog to the first of symbols which exists.
+func kprobeWithFallback(symbols []string, prog *ebpf.Program) (link.Link, error) {
+	var err error
+	for _, symbol := range symbols {
+		var l link.Link
+		l, err = link.Kprobe(symbol, prog, nil)
+		if err == nil {
+			return l, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			break
+		}
+	}
+	return nil, err
+}
+
+func (t *Tracer) start() error {
+	spec, err := loadBiosnoop()
+	if err != nil {
+		return fmt.Errorf("failed to load ebpf program: %w", err)
+	}
+
+	filterByMntNs := false
+
+	if t.config.MountnsMap != "" {
+		filterByMntNs = true
+		m := spec.Maps["mount_ns_set"]
+		m.Pinning = ebpf.PinByName
+		m.Name = filepath.Base(t.config.MountnsMap)
+	}
+
+	consts := map[string]interface{}{
+		"filter_by_mnt_ns": filterByMntNs,
+	}
+
+	if err := spec.RewriteConstants(consts); err != nil {
+		return fmt.Errorf("error RewriteConstants: %w", err)
+	}
+
+	opts := ebpf.CollectionOptions{
+		Maps: ebpf.MapOptions{
+			PinPath: filepath.Dir(t.config.MountnsMap),
+		},
+	}
+
+	if err := spec.LoadAndAssign(&t.objs, &opts); err != nil {
+		return fmt.Errorf("failed to load ebpf program: %w", err)
+	}
+
+	// Like for biotop, __blk_account_io_start and __blk_account_io_done
+	// are used when blk_account_io_start and blk_account_io_done are
+	// inlined, since 5.16.
+	kprobes := []struct {
+		symbols []string
+		prog    *ebpf.Program
+	}{
+		{[]string{"__blk_account_io_start", "blk_account_io_start"}, t.objs.IgBioStart},
+		{[]string{"blk_mq_start_request"}, t.objs.IgBioIssue},
+		{[]string{"__blk_account_io_done", "blk_account_io_done"}, t.objs.IgBioDone},
+	}
+	for _, k := range kprobes {
+		l, err := kprobeWithFallback(k.symbols, k.prog)
+		if err != nil {
+			return fmt.Errorf("error attaching program: %w", err)
+		}
+		t.links = append(t.links, l)
+	}
+
+	t.reader, err = perf.NewReader(t.objs.biosnoopMaps.Events, gadgets.PerfBufferPages*os.Getpagesize())
+	if err != nil {
+		return fmt.Errorf("error creating perf ring buffer: %w", err)
+	}
+
+	go t.run()
+
+	return nil
+}
+
+// ops are the names of the REQ_OP_* operations of the requests.
+var ops = map[uint32]string{
+	0: "read",
+	1: "write",
+	2: "flush",
+	3: "discard",
+	5: "secure-erase",
+	9: "write-zeroes",
+}
+
+func opName(op uint32) string {
+	if name, ok := ops[op]; ok {
+		return name
+	}
+	return fmt.Sprintf("op-%d", op)
+}
+
+func (t *Tracer) run() {
+	for {
+		record, err := t.reader.Read()
+		if err != nil {
+			if errors.Is(err, perf.ErrClosed) {
+				// nothing to do, we're done
+				return
+			}
+			msg := fmt.Sprintf("Error reading perf ring buffer: %s", err)
+			t.eventCallback(types.Base(eventtypes.Err(msg, t.node)))
+			return
+		}
+
+		if record.LostSamples > 0 {
+			msg := fmt.Sprintf("lost %d samples", record.LostSamples)
+			t.eventCallback(types.Base(eventtypes.Warn(msg, t.node)))
+			continue
+		}
+
+		eventC := (*C.struct_event)(unsafe.Pointer(&record.RawSample[0]))
+
+		event := types.Event{
+			Event: eventtypes.Event{
+				Type: eventtypes.NORMAL,
+				Node: t.node,
+			},
+			MountNsID: uint64(eventC.mntns_id),
+			Pid:       uint32(eventC.pid),
+			Comm:      C.GoString(&eventC.task[0]),
+			Op:        opName(uint32(eventC.op)),
+			Major:     int(eventC.major),
+			Minor:     int(eventC.minor),
+			Sector:    uint64(eventC.sector),
+			Bytes:     uint32(eventC.len),
+			Latency:   uint64(eventC.delta_us),
+		}
+
+		container := t.resolver.LookupContainerByMntns(event.MountNsID)
+		if container != nil {
+			event.Container = container.Name
+			event.Pod = container.Podname
+			event.Sandbox = container.Sandbox
+			event.Namespace = container.Namespace
+		}
+
+		t.eventCallback(event)
+	}
+}
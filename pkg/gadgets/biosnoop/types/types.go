@@ -0,0 +1,47 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+type Event struct {
+	eventtypes.Event
+
+	// MountNsID, Pid and Comm are the ones of the process which issued
+	// the I/O.
+	MountNsID uint64 `json:"mountnsid,omitempty"`
+	Pid       uint32 `json:"pid,omitempty"`
+	Comm      string `json:"comm,omitempty"`
+
+	// Op is the operation of the I/O, e.g. "read" or "write".
+	Op    string `json:"op,omitempty"`
+	Major int    `json:"major,omitempty"`
+	Minor int    `json:"minor,omitempty"`
+
+	Sector uint64 `json:"sector,omitempty"`
+	Bytes  uint32 `json:"bytes,omitempty"`
+
+	// Latency is the time in microseconds between the issue of the I/O to
+	// the device and its completion.
+	Latency uint64 `json:"latency,omitempty"`
+}
+
+func Base(ev eventtypes.Event) Event {
+	return Event{
+		Event: ev,
+	}
+}
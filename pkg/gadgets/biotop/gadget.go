@@ -18,14 +18,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"strconv"
-	"strings"
 	"time"
 
+	"github.com/cilium/ebpf"
 	log "github.com/sirupsen/logrus"
 
+	"github.com/kinvolk/inspektor-gadget/pkg/bpferror"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
 	biotoptracer "github.com/kinvolk/inspektor-gadget/pkg/gadgets/biotop/tracer"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/biotop/types"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/threshold"
 
 	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
 )
@@ -48,15 +50,29 @@ func NewFactory() gadgets.TraceFactory {
 }
 
 func (f *TraceFactory) Description() string {
-	t := `biotop shows command generating block I/O, with container details.
-
-The following parameters are supported:
- - %s: Output interval, in seconds. (default %d)
- - %s: Maximum rows to print. (default %d)
- - %s: The field to sort the results by (%s). (default %s)`
-	return fmt.Sprintf(t, types.IntervalParam, types.IntervalDefault,
-		types.MaxRowsParam, types.MaxRowsDefault,
-		types.SortByParam, strings.Join(types.SortBySlice, ","), types.SortByDefault)
+	return `biotop shows command generating block I/O, with container details.`
+}
+
+func (f *TraceFactory) Parameters() []gadgets.GadgetParameter {
+	params := []gadgets.GadgetParameter{
+		{
+			Name:        types.IntervalParam,
+			Description: "Output interval, in seconds",
+			Default:     strconv.Itoa(types.IntervalDefault),
+		},
+		{
+			Name:        types.MaxRowsParam,
+			Description: "Maximum rows to print",
+			Default:     strconv.Itoa(types.MaxRowsDefault),
+		},
+		{
+			Name:        types.SortByParam,
+			Description: "The field to sort the results by",
+			Default:     types.SortByDefault.String(),
+			Values:      types.SortBySlice,
+		},
+	}
+	return append(params, gadgets.ThresholdParameters()...)
 }
 
 func (f *TraceFactory) OutputModesSupported() map[string]struct{} {
@@ -65,6 +81,14 @@ func (f *TraceFactory) OutputModesSupported() map[string]struct{} {
 	}
 }
 
+func (f *TraceFactory) Maps(name string) map[string]*ebpf.Map {
+	t, ok := f.LookupOrCreate(name, nil).(*Trace)
+	if !ok || !t.started {
+		return nil
+	}
+	return t.tracer.Maps()
+}
+
 func deleteTrace(name string, t interface{}) {
 	trace := t.(*Trace)
 	if trace.tracer != nil {
@@ -136,18 +160,43 @@ func (t *Trace) Start(trace *gadgetv1alpha1.Trace) {
 		}
 	}
 
+	thresholds, err := threshold.ParseParameters(trace.Spec.Parameters, &types.Stats{})
+	if err != nil {
+		trace.Status.OperationError = err.Error()
+		return
+	}
+
 	config := &biotoptracer.Config{
 		MaxRows:    maxRows,
 		Interval:   time.Second * time.Duration(intervalSeconds),
 		SortBy:     sortBy,
 		MountnsMap: gadgets.TracePinPath(trace.ObjectMeta.Namespace, trace.ObjectMeta.Name),
 		Node:       trace.Spec.Node,
+		Thresholds: thresholds,
 	}
 
 	statsCallback := func(stats []types.Stats) {
 		ev := types.Event{
-			Node:  trace.Spec.Node,
-			Stats: stats,
+			Node:      trace.Spec.Node,
+			Timestamp: time.Now().UnixNano(),
+			Stats:     stats,
+		}
+
+		var alerted []types.Stats
+		for _, s := range stats {
+			if len(s.Alerts) > 0 {
+				alerted = append(alerted, s)
+			}
+		}
+		if len(alerted) > 0 {
+			ev.Warning = thresholds.Warning(len(alerted))
+			thresholds.Post(threshold.Alert{
+				Gadget:    trace.Spec.Gadget,
+				Trace:     trace.ObjectMeta.Namespace + "/" + trace.ObjectMeta.Name,
+				Node:      trace.Spec.Node,
+				Timestamp: ev.Timestamp,
+				Rows:      alerted,
+			})
 		}
 
 		r, err := json.Marshal(ev)
@@ -173,7 +222,7 @@ func (t *Trace) Start(trace *gadgetv1alpha1.Trace) {
 
 	tracer, err := biotoptracer.NewTracer(config, t.resolver, statsCallback, errorCallback)
 	if err != nil {
-		trace.Status.OperationError = fmt.Sprintf("failed to create tracer: %s", err)
+		trace.Status.OperationError = fmt.Sprintf("failed to create tracer: %s", bpferror.Describe(err))
 		return
 	}
 
@@ -70,9 +70,17 @@ func ParseSortBy(sortBy string) (SortBy, error) {
 type Event struct {
 	Error string `json:"error,omitempty"`
 
+	// Warning is set when rows crossed the thresholds during the interval
+	// and the warnings are enabled.
+	Warning string `json:"warning,omitempty"`
+
 	// Node where the event comes from.
 	Node string `json:"node,omitempty"`
 
+	// Timestamp is when the interval ended, in nanoseconds since the
+	// epoch.
+	Timestamp int64 `json:"timestamp,omitempty"`
+
 	Stats []Stats `json:"stats,omitempty"`
 }
 
@@ -92,6 +100,9 @@ type Stats struct {
 	MountNsID  uint64 `json:"mountnsid,omitempty"`
 	Pid        int32  `json:"pid,omitempty"`
 	Comm       string `json:"comm,omitempty"`
+
+	// Alerts are the thresholds crossed by the row during the interval.
+	Alerts []string `json:"alerts,omitempty"`
 }
 
 func SortStats(stats []Stats, sortBy SortBy) {
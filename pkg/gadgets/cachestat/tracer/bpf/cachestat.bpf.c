@@ -0,0 +1,116 @@
+// SPDX-License-Identifier: GPL-2.0
+// Copyright (c) 2022 The Inspektor Gadget authors
+#include <vmlinux/vmlinux.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_tracing.h>
+#include "cachestat.h"
+
+#define MAX_ENTRIES	10240
+
+const volatile pid_t target_pid = 0;
+const volatile bool filter_by_mnt_ns = false;
+const volatile bool per_process = false;
+static struct cache_stat zero_value = {};
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, struct cache_key);
+	__type(value, struct cache_stat);
+} entries SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, 1024);
+	__uint(key_size, sizeof(u64));
+	__uint(value_size, sizeof(u32));
+} mount_ns_set SEC(".maps");
+
+static int count(enum op op)
+{
+	__u64 pid_tgid = bpf_get_current_pid_tgid();
+	__u32 pid = pid_tgid >> 32;
+	struct cache_key key = {};
+	struct cache_stat *valuep;
+	struct task_struct *task;
+	u64 mntns_id;
+
+	if (target_pid && target_pid != pid)
+		return 0;
+
+	task = (struct task_struct*)bpf_get_current_task();
+	mntns_id = (u64) BPF_CORE_READ(task, nsproxy, mnt_ns, ns.inum);
+
+	if (filter_by_mnt_ns && !bpf_map_lookup_elem(&mount_ns_set, &mntns_id))
+		return 0;
+
+	key.mntns_id = mntns_id;
+	if (per_process) {
+		key.pid = pid;
+		bpf_get_current_comm(&key.comm, sizeof(key.comm));
+	}
+
+	valuep = bpf_map_lookup_elem(&entries, &key);
+	if (!valuep) {
+		bpf_map_update_elem(&entries, &key, &zero_value, BPF_NOEXIST);
+		valuep = bpf_map_lookup_elem(&entries, &key);
+		if (!valuep)
+			return 0;
+	}
+
+	switch (op) {
+	case ACCESSED:
+		__sync_fetch_and_add(&valuep->accessed, 1);
+		break;
+	case ADDED:
+		__sync_fetch_and_add(&valuep->added, 1);
+		break;
+	case DIRTIED:
+		__sync_fetch_and_add(&valuep->dirtied, 1);
+		break;
+	case BUFFER_DIRTIED:
+		__sync_fetch_and_add(&valuep->buffers_dirtied, 1);
+		break;
+	}
+
+	return 0;
+}
+
+/*
+ * The functions were converted to folios in 5.16, the tracer attaches the
+ * programs to the ones the running kernel has, see tracer.go.
+ */
+
+SEC("kprobe/mark_page_accessed")
+int BPF_KPROBE(page_accessed)
+{
+	return count(ACCESSED);
+}
+
+SEC("kprobe/add_to_page_cache_lru")
+int BPF_KPROBE(page_added)
+{
+	return count(ADDED);
+}
+
+SEC("kprobe/account_page_dirtied")
+int BPF_KPROBE(page_dirtied)
+{
+	return count(DIRTIED);
+}
+
+/* folio_account_dirtied() is inlined on recent kernels */
+SEC("tracepoint/writeback/writeback_dirty_folio")
+int page_dirtied_tp(void *ctx)
+{
+	return count(DIRTIED);
+}
+
+SEC("kprobe/mark_buffer_dirty")
+int BPF_KPROBE(buffer_dirtied)
+{
+	return count(BUFFER_DIRTIED);
+}
+
+char LICENSE[] SEC("license") = "GPL";
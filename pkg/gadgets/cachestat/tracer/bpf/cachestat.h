@@ -0,0 +1,35 @@
+/* SPDX-License-Identifier: (LGPL-2.1 OR BSD-2-Clause) */
+#ifndef __CACHESTAT_H
+#define __CACHESTAT_H
+
+#define TASK_COMM_LEN	16
+
+enum op {
+	ACCESSED,
+	ADDED,
+	DIRTIED,
+	BUFFER_DIRTIED,
+};
+
+/*
+ * The counters are aggregated by mount namespace, and also by process when
+ * per_process is set.
+ */
+struct cache_key {
+	__u64 mntns_id;
+	__u32 pid;
+	char comm[TASK_COMM_LEN];
+};
+
+/*
+ * Raw counters of the page cache functions, the hits and misses are
+ * computed from them in user space.
+ */
+struct cache_stat {
+	__u64 accessed;
+	__u64 added;
+	__u64 dirtied;
+	__u64 buffers_dirtied;
+};
+
+#endif /* __CACHESTAT_H */
@@ -0,0 +1,287 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+	"unsafe"
+
+	containercollection "github.com/kinvolk/inspektor-gadget/pkg/container-collection"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/cachestat/types"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/threshold"
+	"github.com/kinvolk/inspektor-gadget/pkg/mapdump"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+)
+
+// #include <linux/types.h>
+// #include "./bpf/cachestat.h"
+import "C"
+
+//go:generate sh -c "GOOS=$(go env GOHOSTOS) GOARCH=$(go env GOHOSTARCH) go run github.com/cilium/ebpf/cmd/bpf2go -target bpfel -cc clang cachestat ./bpf/cachestat.bpf.c -- -I./bpf/ -I../../.. -target bpf -D__TARGET_ARCH_x86"
+
+type Config struct {
+	TargetPid  int
+	PerProcess bool
+	MaxRows    int
+	Interval   time.Duration
+	SortBy     types.SortBy
+	// TODO: Make it a *ebpf.Map once
+	// https://github.com/cilium/ebpf/issues/515 and
+	// https://github.com/cilium/ebpf/issues/517 are fixed
+	MountnsMap string
+	Node       string
+
+	// Thresholds marks the rows crossing thresholds. These rows are
+	// reported even if they are not part of the first MaxRows ones.
+	Thresholds *threshold.Config
+}
+
+type Tracer struct {
+	config        *Config
+	objs          cachestatObjects
+	links         []link.Link
+	resolver      containercollection.ContainerResolver
+	statsCallback func([]types.Stats)
+	errorCallback func(error)
+	done          chan bool
+}
+
+func NewTracer(config *Config, resolver containercollection.ContainerResolver,
+	statsCallback func([]types.Stats), errorCallback func(error)) (*Tracer, error) {
+	t := &Tracer{
+		config:        config,
+		resolver:      resolver,
+		statsCallback: statsCallback,
+		errorCallback: errorCallback,
+		done:          make(chan bool),
+	}
+
+	if err := t.start(); err != nil {
+		t.Stop()
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (t *Tracer) Stop() {
+	close(t.done)
+
+	for i := range t.links {
+		t.links[i] = gadgets.CloseLink(t.links[i])
+	}
+
+	t.objs.Close()
+}
+
+// Maps returns the BPF maps of the tracer, so they can be dumped for
+// debugging.
+func (t *Tracer) Maps() map[string]*ebpf.Map {
+	return mapdump.MapsOf(&t.objs)
+}
+
+func (t *Tracer) start() error {
+	spec, err := loadCachestat()
+	if err != nil {
+		return fmt.Errorf("failed to load ebpf program: %w", err)
+	}
+
+	filterByMntNs := false
+
+	if t.config.MountnsMap != "" {
+		filterByMntNs = true
+		m := spec.Maps["mount_ns_set"]
+		m.Pinning = ebpf.PinByName
+		m.Name = filepath.Base(t.config.MountnsMap)
+	}
+
+	consts := map[string]interface{}{
+		"target_pid":       uint32(t.config.TargetPid),
+		"filter_by_mnt_ns": filterByMntNs,
+		"per_process":      t.config.PerProcess,
+	}
+
+	if err := spec.RewriteConstants(consts); err != nil {
+		return fmt.Errorf("error RewriteConstants: %w", err)
+	}
+
+	opts := ebpf.CollectionOptions{
+		Maps: ebpf.MapOptions{
+			PinPath: filepath.Dir(t.config.MountnsMap),
+		},
+	}
+
+	if err := spec.LoadAndAssign(&t.objs, &opts); err != nil {
+		return fmt.Errorf("failed to load ebpf program: %w", err)
+	}
+
+	// The functions of the page cache were renamed when they were
+	// converted to folios, the first one existing is used.
+	kprobes := []struct {
+		symbols []string
+		prog    *ebpf.Program
+	}{
+		{[]string{"folio_mark_accessed", "mark_page_accessed"}, t.objs.PageAccessed},
+		{[]string{"filemap_add_folio", "add_to_page_cache_lru"}, t.objs.PageAdded},
+		{[]string{"mark_buffer_dirty"}, t.objs.BufferDirtied},
+	}
+
+	for _, kp := range kprobes {
+		if err := t.attachKprobe(kp.prog, kp.symbols); err != nil {
+			return err
+		}
+	}
+
+	if err := t.attachKprobe(t.objs.PageDirtied, []string{"folio_account_dirtied", "account_page_dirtied"}); err != nil {
+		// folio_account_dirtied() is inlined on recent kernels, use
+		// the tracepoint it calls instead.
+		l, tpErr := link.Tracepoint("writeback", "writeback_dirty_folio", t.objs.PageDirtiedTp, nil)
+		if tpErr != nil {
+			return err
+		}
+		t.links = append(t.links, l)
+	}
+
+	t.run()
+
+	return nil
+}
+
+// attachKprobe attaches prog to the first of symbols existing in the
+// running kernel.
+func (t *Tracer) attachKprobe(prog *ebpf.Program, symbols []string) error {
+	for _, symbol := range symbols {
+		l, err := link.Kprobe(symbol, prog, nil)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("error opening kprobe %s: %w", symbol, err)
+		}
+		t.links = append(t.links, l)
+		return nil
+	}
+
+	return fmt.Errorf("none of the kernel functions %v exist", symbols)
+}
+
+func (t *Tracer) nextStats() ([]types.Stats, error) {
+	stats := []types.Stats{}
+
+	var prev *C.struct_cache_key = nil
+	key := C.struct_cache_key{}
+	entries := t.objs.Entries
+
+	defer func() {
+		// delete elements
+		err := entries.NextKey(nil, unsafe.Pointer(&key))
+		if err != nil {
+			return
+		}
+
+		for {
+			if err := entries.Delete(key); err != nil {
+				return
+			}
+
+			prev = &key
+			if err := entries.NextKey(unsafe.Pointer(prev), unsafe.Pointer(&key)); err != nil {
+				return
+			}
+		}
+	}()
+
+	// gather elements
+	err := entries.NextKey(nil, unsafe.Pointer(&key))
+	if err != nil {
+		if errors.Is(err, ebpf.ErrKeyNotExist) {
+			return stats, nil
+		}
+		return nil, fmt.Errorf("error getting next key: %w", err)
+	}
+
+	for {
+		cacheStat := C.struct_cache_stat{}
+		if err := entries.Lookup(key, unsafe.Pointer(&cacheStat)); err != nil {
+			return nil, err
+		}
+
+		stat := types.Stats{
+			MountNsID: uint64(key.mntns_id),
+			Pid:       uint32(key.pid),
+			Comm:      C.GoString(&key.comm[0]),
+			Node:      t.config.Node,
+		}
+		stat.SetCounters(uint64(cacheStat.accessed), uint64(cacheStat.added),
+			uint64(cacheStat.dirtied), uint64(cacheStat.buffers_dirtied))
+
+		container := t.resolver.LookupContainerByMntns(stat.MountNsID)
+		if container != nil {
+			stat.Container = container.Name
+			stat.Pod = container.Podname
+			stat.Namespace = container.Namespace
+		}
+
+		stats = append(stats, stat)
+
+		prev = &key
+		if err := entries.NextKey(unsafe.Pointer(prev), unsafe.Pointer(&key)); err != nil {
+			if errors.Is(err, ebpf.ErrKeyNotExist) {
+				break
+			}
+			return nil, fmt.Errorf("error getting next key: %w", err)
+		}
+	}
+
+	types.SortStats(stats, t.config.SortBy)
+
+	return stats, nil
+}
+
+func (t *Tracer) run() {
+	ticker := time.NewTicker(t.config.Interval)
+
+	go func() {
+		for {
+			select {
+			case <-t.done:
+				ticker.Stop()
+				return
+			case <-ticker.C:
+				stats, err := t.nextStats()
+				if err != nil {
+					t.errorCallback(err)
+					return
+				}
+
+				rows := []types.Stats{}
+				for i := range stats {
+					stats[i].Alerts = t.config.Thresholds.Check(&stats[i], t.config.Interval)
+					if i < t.config.MaxRows || len(stats[i].Alerts) > 0 {
+						rows = append(rows, stats[i])
+					}
+				}
+				t.statsCallback(rows)
+			}
+		}
+	}()
+}
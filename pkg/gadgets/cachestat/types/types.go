@@ -0,0 +1,161 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"fmt"
+	"sort"
+)
+
+type SortBy int
+
+const (
+	MISSES SortBy = iota
+	HITS
+	DIRTIES
+	RATIO
+)
+
+const (
+	MaxRowsDefault  = 20
+	IntervalDefault = 1
+	SortByDefault   = MISSES
+)
+
+const (
+	IntervalParam = "interval"
+	MaxRowsParam  = "max_rows"
+	SortByParam   = "sort_by"
+	PidParam      = "pid"
+
+	// PerProcessParam tells if the page cache accesses are reported by
+	// process instead of by container.
+	PerProcessParam = "per_process"
+)
+
+var SortBySlice = []string{
+	"misses",
+	"hits",
+	"dirties",
+	"ratio",
+}
+
+func (s SortBy) String() string {
+	if int(s) < 0 || int(s) >= len(SortBySlice) {
+		return "INVALID"
+	}
+
+	return SortBySlice[int(s)]
+}
+
+func ParseSortBy(sortby string) (SortBy, error) {
+	for i, v := range SortBySlice {
+		if v == sortby {
+			return SortBy(i), nil
+		}
+	}
+	return MISSES, fmt.Errorf("%q is not a valid sort by value", sortby)
+}
+
+// Event is the information the gadget sends to the client each capture
+// interval
+type Event struct {
+	Error string `json:"error,omitempty"`
+
+	// Warning is set when rows crossed the thresholds during the interval
+	// and the warnings are enabled.
+	Warning string `json:"warning,omitempty"`
+
+	// Node where the event comes from.
+	Node string `json:"node,omitempty"`
+
+	// Timestamp is when the interval ended, in nanoseconds since the
+	// epoch.
+	Timestamp int64 `json:"timestamp,omitempty"`
+
+	Stats []Stats `json:"stats,omitempty"`
+}
+
+// Stats represents the page cache accesses of a single container, i.e. a
+// mount namespace, or of a single process of a container when the
+// per_process parameter is set
+type Stats struct {
+	Node      string `json:"node,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Pod       string `json:"pod,omitempty"`
+	Container string `json:"container,omitempty"`
+
+	MountNsID uint64 `json:"mountnsid,omitempty"`
+	Pid       uint32 `json:"pid,omitempty"`
+	Comm      string `json:"comm,omitempty"`
+	Hits      uint64 `json:"hits,omitempty"`
+	Misses    uint64 `json:"misses,omitempty"`
+	Dirties   uint64 `json:"dirties,omitempty"`
+
+	// Ratio is the percentage of the accesses hitting the page cache.
+	Ratio float64 `json:"ratio"`
+
+	// Alerts are the thresholds crossed by the row during the interval.
+	Alerts []string `json:"alerts,omitempty"`
+}
+
+// SetCounters computes the hits and misses from the number of calls to the
+// page cache functions, like the cachestat tool of BCC: the pages accessed
+// are the hits and misses, except the buffers dirtied, and the pages added
+// to the page cache are the misses, except the ones added to be written.
+func (s *Stats) SetCounters(accessed, added, dirtied, buffersDirtied uint64) {
+	total := int64(accessed) - int64(buffersDirtied)
+	misses := int64(added) - int64(dirtied)
+
+	if misses < 0 {
+		misses = 0
+	}
+	if total < misses {
+		// Pages can be added without being accessed, e.g. by the read ahead.
+		total = misses
+	}
+
+	s.Hits = uint64(total - misses)
+	s.Misses = uint64(misses)
+	s.Dirties = buffersDirtied
+
+	s.Ratio = 0
+	if total > 0 {
+		s.Ratio = float64(s.Hits) / float64(total) * 100
+	}
+}
+
+func SortStats(stats []Stats, sortBy SortBy) {
+	sort.Slice(stats, func(i, j int) bool {
+		a := stats[i]
+		b := stats[j]
+
+		switch sortBy {
+		case HITS:
+			return a.Hits > b.Hits
+		case DIRTIES:
+			return a.Dirties > b.Dirties
+		case RATIO:
+			// The containers thrashing the page cache first, the
+			// ones without accesses last.
+			if (a.Hits+a.Misses == 0) != (b.Hits+b.Misses == 0) {
+				return b.Hits+b.Misses == 0
+			}
+			return a.Ratio < b.Ratio
+		default:
+			return a.Misses > b.Misses
+		}
+	})
+}
@@ -0,0 +1,100 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"testing"
+)
+
+func TestSetCounters(t *testing.T) {
+	tests := []struct {
+		name                                     string
+		accessed, added, dirtied, buffersDirtied uint64
+		hits, misses, dirties                    uint64
+		ratio                                    float64
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:     "hits only",
+			accessed: 100,
+			hits:     100,
+			ratio:    100,
+		},
+		{
+			name:     "hits and misses",
+			accessed: 100,
+			added:    25,
+			hits:     75,
+			misses:   25,
+			ratio:    75,
+		},
+		{
+			name:           "writes",
+			accessed:       110,
+			added:          35,
+			dirtied:        10,
+			buffersDirtied: 10,
+			hits:           75,
+			misses:         25,
+			dirties:        10,
+			ratio:          75,
+		},
+		{
+			name:    "more pages dirtied than added",
+			added:   5,
+			dirtied: 10,
+		},
+		{
+			name:     "read ahead",
+			accessed: 10,
+			added:    40,
+			misses:   40,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var s Stats
+			s.SetCounters(test.accessed, test.added, test.dirtied, test.buffersDirtied)
+
+			if s.Hits != test.hits || s.Misses != test.misses || s.Dirties != test.dirties {
+				t.Fatalf("got %d hits, %d misses and %d dirties, expected %d, %d and %d",
+					s.Hits, s.Misses, s.Dirties, test.hits, test.misses, test.dirties)
+			}
+			if s.Ratio != test.ratio {
+				t.Fatalf("got a ratio of %f, expected %f", s.Ratio, test.ratio)
+			}
+		})
+	}
+}
+
+func TestSortStats(t *testing.T) {
+	stats := []Stats{
+		{Container: "idle"},
+		{Container: "cached", Hits: 100, Ratio: 100},
+		{Container: "thrashing", Hits: 10, Misses: 90, Ratio: 10},
+	}
+
+	SortStats(stats, RATIO)
+
+	expected := []string{"thrashing", "cached", "idle"}
+	for i, s := range stats {
+		if s.Container != expected[i] {
+			t.Fatalf("got %q at %d, expected %q", s.Container, i, expected[i])
+		}
+	}
+}
@@ -17,9 +17,12 @@ package capabilities
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/kinvolk/inspektor-gadget/pkg/bpferror"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/capabilities/tracer"
 
@@ -47,7 +50,17 @@ func NewFactory() gadgets.TraceFactory {
 }
 
 func (f *TraceFactory) Description() string {
-	return `capabilities traces security capability checks"`
+	return `capabilities traces security capability checks`
+}
+
+func (f *TraceFactory) Parameters() []gadgets.GadgetParameter {
+	return []gadgets.GadgetParameter{
+		{
+			Name:        types.DedupWindowParam,
+			Description: "Report identical capability checks only once per window of this number of seconds, with a summary of the number of suppressed checks at the end of the window. 0 disables it",
+			Default:     strconv.Itoa(types.DedupWindowDefault),
+		},
+	}
 }
 
 func (f *TraceFactory) OutputModesSupported() map[string]struct{} {
@@ -105,13 +118,26 @@ func (t *Trace) Start(trace *gadgetv1alpha1.Trace) {
 
 	var err error
 
+	dedupWindow := types.DedupWindowDefault
+
+	if trace.Spec.Parameters != nil {
+		if val, ok := trace.Spec.Parameters[types.DedupWindowParam]; ok {
+			dedupWindow, err = strconv.Atoi(val)
+			if err != nil || dedupWindow < 0 {
+				trace.Status.OperationError = fmt.Sprintf("%q is not valid for %q", val, types.DedupWindowParam)
+				return
+			}
+		}
+	}
+
 	config := &tracer.Config{
-		MountnsMap: gadgets.TracePinPath(trace.ObjectMeta.Namespace, trace.ObjectMeta.Name),
+		MountnsMap:  gadgets.TracePinPath(trace.ObjectMeta.Namespace, trace.ObjectMeta.Name),
+		DedupWindow: time.Duration(dedupWindow) * time.Second,
 	}
 
 	t.tracer, err = standardtracer.NewTracer(config, t.resolver, eventCallback, trace.Spec.Node)
 	if err != nil {
-		trace.Status.OperationError = fmt.Sprintf("failed to create tracer: %s", err)
+		trace.Status.OperationError = fmt.Sprintf("failed to create tracer: %s", bpferror.Describe(err))
 		return
 	}
 
@@ -0,0 +1,160 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/capabilities/types"
+)
+
+// DedupMaxEntries is the maximum number of different capability checks
+// tracked during a window. The least recently seen one is evicted when
+// this limit is reached.
+const DedupMaxEntries = 1024
+
+// dedupKey identifies identical capability checks. The audit flag and the
+// insetid verdict are part of it so that a check with another result isn't
+// counted as a repetition of the first one.
+type dedupKey struct {
+	mntnsid uint64
+	cap     int
+	comm    string
+	audit   int
+	insetid string
+}
+
+type dedupEntry struct {
+	key   dedupKey
+	event types.Event
+	count uint64
+}
+
+// Deduplicator reports only the first occurrence of identical capability
+// checks during a time window. At the end of each window, it reports how
+// many occurrences were suppressed with a summary event.
+//
+// The deduplication is done on the events printed by the BCC capable tool:
+// its BPF program is part of the tool, which comes with the BCC image and
+// isn't built from this repository, so it can't use an LRU map to drop the
+// repetitions in the kernel. The events are then still sent through the
+// perf buffer, only the ones reported by the gadget are reduced.
+type Deduplicator struct {
+	eventCallback func(types.Event)
+
+	mu      sync.Mutex
+	entries map[dedupKey]*list.Element
+	lru     *list.List
+
+	ticker *time.Ticker
+	done   chan struct{}
+}
+
+func NewDeduplicator(window time.Duration, eventCallback func(types.Event)) *Deduplicator {
+	d := &Deduplicator{
+		eventCallback: eventCallback,
+		entries:       make(map[dedupKey]*list.Element),
+		lru:           list.New(),
+		ticker:        time.NewTicker(window),
+		done:          make(chan struct{}),
+	}
+
+	go d.run()
+
+	return d
+}
+
+func (d *Deduplicator) run() {
+	for {
+		select {
+		case <-d.done:
+			return
+		case <-d.ticker.C:
+			d.Flush()
+		}
+	}
+}
+
+func summary(entry *dedupEntry) types.Event {
+	event := entry.event
+	event.Count = entry.count
+	return event
+}
+
+// Add reports the event if it's the first occurrence during the current
+// window, otherwise it's only counted.
+func (d *Deduplicator) Add(event types.Event) {
+	key := dedupKey{
+		mntnsid: event.MountNsID,
+		cap:     event.Cap,
+		comm:    event.Comm,
+		audit:   event.Audit,
+		insetid: event.InsetID,
+	}
+
+	d.mu.Lock()
+
+	if elem, ok := d.entries[key]; ok {
+		elem.Value.(*dedupEntry).count++
+		d.lru.MoveToFront(elem)
+		d.mu.Unlock()
+		return
+	}
+
+	var evicted *dedupEntry
+	if d.lru.Len() >= DedupMaxEntries {
+		elem := d.lru.Back()
+		evicted = elem.Value.(*dedupEntry)
+		d.lru.Remove(elem)
+		delete(d.entries, evicted.key)
+	}
+
+	d.entries[key] = d.lru.PushFront(&dedupEntry{key: key, event: event})
+
+	d.mu.Unlock()
+
+	if evicted != nil && evicted.count > 0 {
+		d.eventCallback(summary(evicted))
+	}
+	d.eventCallback(event)
+}
+
+// Flush reports the number of suppressed occurrences of each capability
+// check and starts a new window.
+func (d *Deduplicator) Flush() {
+	d.mu.Lock()
+	var summaries []types.Event
+	for elem := d.lru.Back(); elem != nil; elem = elem.Prev() {
+		if entry := elem.Value.(*dedupEntry); entry.count > 0 {
+			summaries = append(summaries, summary(entry))
+		}
+	}
+	d.entries = make(map[dedupKey]*list.Element)
+	d.lru.Init()
+	d.mu.Unlock()
+
+	for _, event := range summaries {
+		d.eventCallback(event)
+	}
+}
+
+// Stop stops the deduplicator and reports the pending counts.
+func (d *Deduplicator) Stop() {
+	d.ticker.Stop()
+	close(d.done)
+	d.Flush()
+}
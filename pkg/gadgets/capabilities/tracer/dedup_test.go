@@ -0,0 +1,116 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/capabilities/types"
+)
+
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []types.Event
+}
+
+func (r *eventRecorder) callback(event types.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func TestDeduplicator(t *testing.T) {
+	r := &eventRecorder{}
+
+	// Use a long window to flush manually
+	d := NewDeduplicator(time.Hour, r.callback)
+	defer d.Stop()
+
+	ev := types.Event{MountNsID: 1, Cap: 21, Comm: "runc"}
+	other := types.Event{MountNsID: 1, Cap: 12, Comm: "runc"}
+
+	for i := 0; i < 5; i++ {
+		d.Add(ev)
+	}
+	d.Add(other)
+
+	if len(r.events) != 2 {
+		t.Fatalf("expected 2 events before flush, got %d", len(r.events))
+	}
+	if r.events[0].Count != 0 || r.events[1].Count != 0 {
+		t.Fatalf("first occurrences should not have a count: %+v", r.events)
+	}
+
+	d.Flush()
+
+	if len(r.events) != 3 {
+		t.Fatalf("expected 3 events after flush, got %d", len(r.events))
+	}
+	if r.events[2].Cap != 21 || r.events[2].Count != 4 {
+		t.Fatalf("unexpected summary event: %+v", r.events[2])
+	}
+
+	// A new window starts after the flush
+	d.Add(ev)
+	if len(r.events) != 4 || r.events[3].Count != 0 {
+		t.Fatalf("first occurrence in new window should be reported: %+v", r.events)
+	}
+}
+
+func TestDeduplicatorEviction(t *testing.T) {
+	r := &eventRecorder{}
+
+	d := NewDeduplicator(time.Hour, r.callback)
+	defer d.Stop()
+
+	first := types.Event{MountNsID: 1, Cap: 0, Comm: "first"}
+	d.Add(first)
+	d.Add(first)
+
+	for i := 1; i <= DedupMaxEntries; i++ {
+		d.Add(types.Event{MountNsID: uint64(i + 1), Cap: 0, Comm: "other"})
+	}
+
+	// first was the least recently used entry: it is evicted and its
+	// count reported.
+	found := false
+	for _, ev := range r.events {
+		if ev.Comm == "first" && ev.Count == 1 {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("summary of evicted entry was not reported")
+	}
+}
+
+func TestDeduplicatorVerdict(t *testing.T) {
+	r := &eventRecorder{}
+
+	d := NewDeduplicator(time.Hour, r.callback)
+	defer d.Stop()
+
+	allowed := types.Event{MountNsID: 1, Cap: 21, Comm: "runc", Audit: 1, InsetID: "yes"}
+	denied := types.Event{MountNsID: 1, Cap: 21, Comm: "runc", Audit: 1, InsetID: "no"}
+
+	d.Add(allowed)
+	d.Add(denied)
+
+	if len(r.events) != 2 || r.events[1].InsetID != "no" {
+		t.Fatalf("a check with another verdict should be reported: %+v", r.events)
+	}
+}
@@ -14,6 +14,10 @@
 
 package tracer
 
+import (
+	"time"
+)
+
 type Tracer interface {
 	Stop()
 }
@@ -23,4 +27,8 @@ type Config struct {
 	// https://github.com/cilium/ebpf/issues/515 and
 	// https://github.com/cilium/ebpf/issues/517 are fixed
 	MountnsMap string
+
+	// DedupWindow is the duration of the window during which identical
+	// capability checks are reported only once. Zero disables it.
+	DedupWindow time.Duration
 }
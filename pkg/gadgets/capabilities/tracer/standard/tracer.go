@@ -31,11 +31,22 @@ type Tracer struct {
 	resolver      containercollection.ContainerResolver
 	eventCallback func(types.Event)
 	node          string
+	dedup         *tracer.Deduplicator
 }
 
 func NewTracer(config *tracer.Config, resolver containercollection.ContainerResolver,
 	eventCallback func(types.Event), node string) (*Tracer, error,
 ) {
+	var dedup *tracer.Deduplicator
+
+	// Identical capability checks can be very frequent, e.g. CAP_SYS_ADMIN
+	// checks done by some runtimes. Only report them once per window.
+	publish := eventCallback
+	if config.DedupWindow > 0 {
+		dedup = tracer.NewDeduplicator(config.DedupWindow, eventCallback)
+		publish = dedup.Add
+	}
+
 	lineCallback := func(line string) {
 		event := types.Event{}
 		event.Type = eventtypes.NORMAL
@@ -46,7 +57,7 @@ func NewTracer(config *tracer.Config, resolver containercollection.ContainerReso
 			return
 		}
 
-		eventCallback(event)
+		publish(event)
 	}
 
 	baseTracer, err := gadgets.NewStandardTracer(lineCallback,
@@ -54,6 +65,9 @@ func NewTracer(config *tracer.Config, resolver containercollection.ContainerReso
 		"--json", "--mntnsmap", config.MountnsMap,
 		"--containersmap", "/sys/fs/bpf/gadget/containers")
 	if err != nil {
+		if dedup != nil {
+			dedup.Stop()
+		}
 		return nil, err
 	}
 
@@ -62,6 +76,7 @@ func NewTracer(config *tracer.Config, resolver containercollection.ContainerReso
 		eventCallback:      eventCallback,
 		resolver:           resolver, // not used right now but could be useful in the future
 		node:               node,
+		dedup:              dedup,
 	}, nil
 }
 
@@ -69,4 +84,8 @@ func (t *Tracer) Stop() {
 	if err := t.StandardTracerBase.Stop(); err != nil {
 		t.eventCallback(types.Base(eventtypes.Warn(err.Error(), t.node)))
 	}
+
+	if t.dedup != nil {
+		t.dedup.Stop()
+	}
 }
@@ -29,8 +29,23 @@ type Event struct {
 	Cap       int    `json:"cap,omitempty"`
 	Audit     int    `json:"audit,omitempty"`
 	InsetID   string `json:"insetid,omitempty"`
+
+	// Count is the number of identical capability checks (same mount
+	// namespace, capability, command, audit flag and verdict) that were
+	// suppressed during the deduplication window. It's only set on the
+	// summary events.
+	Count uint64 `json:"count,omitempty"`
 }
 
+const (
+	// DedupWindowParam is the duration, in seconds, of the window during
+	// which identical capability checks are only reported once. Zero
+	// disables the deduplication.
+	DedupWindowParam = "dedup_window"
+
+	DedupWindowDefault = 0
+)
+
 func Base(ev eventtypes.Event) Event {
 	return Event{
 		Event: ev,
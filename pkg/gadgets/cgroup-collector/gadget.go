@@ -0,0 +1,98 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cgroupcollector
+
+import (
+	"encoding/json"
+	"fmt"
+
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/cgroup-collector/tracer"
+)
+
+type Trace struct {
+	resolver gadgets.Resolver
+}
+
+type TraceFactory struct {
+	gadgets.BaseFactory
+}
+
+func NewFactory() gadgets.TraceFactory {
+	return &TraceFactory{}
+}
+
+// SetWithoutBPF does nothing: the gadget only reads /proc and the cgroup
+// filesystem.
+func (f *TraceFactory) SetWithoutBPF() {}
+
+// ObservesSandboxes tells that the cgroups of the sandboxed containers are
+// on the host.
+func (f *TraceFactory) ObservesSandboxes() {}
+
+func (f *TraceFactory) Description() string {
+	return `The cgroup-collector gadget reads the CPU, memory and pids limits enforced by the cgroups of the containers and their current usage`
+}
+
+func (f *TraceFactory) OutputModesSupported() map[string]struct{} {
+	return map[string]struct{}{
+		"Status": {},
+	}
+}
+
+func (f *TraceFactory) Operations() map[string]gadgets.TraceOperation {
+	n := func() interface{} {
+		return &Trace{
+			resolver: f.Resolver,
+		}
+	}
+
+	return map[string]gadgets.TraceOperation{
+		"collect": {
+			Doc: "Create a snapshot of the cgroups of the containers. " +
+				"Once taken, the snapshot is not updated automatically. " +
+				"However one can call the collect operation again at any time to update the snapshot.",
+			Operation: func(name string, trace *gadgetv1alpha1.Trace) {
+				f.LookupOrCreate(name, n).(*Trace).Collect(trace)
+			},
+		},
+	}
+}
+
+func (t *Trace) Collect(trace *gadgetv1alpha1.Trace) {
+	selector := gadgets.ContainerSelectorFromContainerFilter(trace.Spec.Filter)
+
+	cgroups, err := tracer.RunCollector(t.resolver, trace.Spec.Node, selector)
+	if err != nil {
+		trace.Status.OperationError = err.Error()
+		return
+	}
+
+	if len(cgroups) == 0 {
+		trace.Status.OperationWarning = "No container matches the requested filter"
+		trace.Status.State = "Completed"
+		return
+	}
+
+	output, err := json.MarshalIndent(cgroups, "", " ")
+	if err != nil {
+		trace.Status.OperationError = fmt.Sprintf("failed marshalling cgroups: %s", err)
+		return
+	}
+
+	trace.Status.Output = string(output)
+	trace.Status.State = "Completed"
+}
@@ -0,0 +1,256 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	containercollection "github.com/kinvolk/inspektor-gadget/pkg/container-collection"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/cgroup-collector/types"
+	pb "github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/api"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+const cgroupRoot = "/sys/fs/cgroup"
+
+// memoryUnlimitedV1 is the lowest value of memory.limit_in_bytes meaning
+// no limit: the maximum number of pages, rounded to the page size.
+const memoryUnlimitedV1 = 1 << 62
+
+// RunCollector reads the limits and usage of the cgroups of the
+// containers selected by the filter.
+func RunCollector(resolver containercollection.ContainerResolver, node string, selector *pb.ContainerSelector) ([]types.Cgroup, error) {
+	cgroups := []types.Cgroup{}
+
+	for _, c := range resolver.GetContainersBySelector(selector) {
+		procCgroup, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/cgroup", c.Pid))
+		if err != nil {
+			// The container likely terminated in the meantime.
+			continue
+		}
+
+		cgroup, err := ReadCgroup(cgroupRoot, string(procCgroup))
+		if err != nil {
+			return nil, fmt.Errorf("container %s/%s/%s: %w", c.Namespace, c.Podname, c.Name, err)
+		}
+
+		cgroup.Event = eventtypes.Event{
+			Type:      eventtypes.NORMAL,
+			Node:      node,
+			Namespace: c.Namespace,
+			Pod:       c.Podname,
+			Container: c.Name,
+		}
+		cgroups = append(cgroups, *cgroup)
+	}
+
+	return cgroups, nil
+}
+
+// ReadCgroup reads the limits and usage of the cgroup of a process, from
+// the content of its /proc/<pid>/cgroup file and the cgroup filesystems
+// mounted in root.
+func ReadCgroup(root, procCgroup string) (*types.Cgroup, error) {
+	// Paths of the cgroups of the process, by controller, the cgroup
+	// v2 one having the empty name.
+	paths := make(map[string]string)
+
+	for _, line := range strings.Split(procCgroup, "\n") {
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if parts[1] == "" {
+			paths[""] = filepath.Join(root, parts[2])
+			continue
+		}
+		for _, controller := range strings.Split(parts[1], ",") {
+			paths[controller] = filepath.Join(root, parts[1], parts[2])
+		}
+	}
+
+	// The cgroup v2 hierarchy is mounted on root when it's the only one,
+	// on root/unified next to the v1 ones otherwise.
+	if _, err := os.Stat(filepath.Join(root, "cgroup.controllers")); err == nil {
+		path, ok := paths[""]
+		if !ok {
+			return nil, errors.New("cgroup v2 path not found")
+		}
+		return readCgroupV2(path)
+	}
+
+	return readCgroupV1(paths)
+}
+
+func readCgroupV2(path string) (*types.Cgroup, error) {
+	c := &types.Cgroup{Version: 2}
+
+	cpuMax, err := readFields(path, "cpu.max", "max 100000")
+	if err != nil {
+		return nil, err
+	}
+	if len(cpuMax) != 2 {
+		return nil, fmt.Errorf("invalid cpu.max: %q", strings.Join(cpuMax, " "))
+	}
+	if c.CPUQuota, err = parseLimit(cpuMax[0]); err != nil {
+		return nil, fmt.Errorf("invalid cpu.max: %w", err)
+	}
+	if c.CPUPeriod, err = strconv.ParseUint(cpuMax[1], 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid cpu.max: %w", err)
+	}
+
+	if c.MemoryMax, err = readLimit(path, "memory.max"); err != nil {
+		return nil, err
+	}
+	if c.PidsMax, err = readLimit(path, "pids.max"); err != nil {
+		return nil, err
+	}
+
+	cpuStat, err := readFields(path, "cpu.stat", "")
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i+1 < len(cpuStat); i += 2 {
+		if cpuStat[i] == "usage_usec" {
+			if c.CPUUsage, err = strconv.ParseUint(cpuStat[i+1], 10, 64); err != nil {
+				return nil, fmt.Errorf("invalid cpu.stat: %w", err)
+			}
+		}
+	}
+
+	if c.MemoryCurrent, err = readUint(path, "memory.current"); err != nil {
+		return nil, err
+	}
+	if c.PidsCurrent, err = readUint(path, "pids.current"); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func readCgroupV1(paths map[string]string) (*types.Cgroup, error) {
+	c := &types.Cgroup{Version: 1}
+	var err error
+
+	cpu, memory, pids := paths["cpu"], paths["memory"], paths["pids"]
+	cpuacct := paths["cpuacct"]
+	if cpu == "" && memory == "" && pids == "" {
+		return nil, errors.New("cgroup v1 paths not found")
+	}
+
+	c.CPUQuota, c.MemoryMax, c.PidsMax = types.Unlimited, types.Unlimited, types.Unlimited
+
+	if cpu != "" {
+		if c.CPUQuota, err = readLimit(cpu, "cpu.cfs_quota_us"); err != nil {
+			return nil, err
+		}
+		if c.CPUPeriod, err = readUint(cpu, "cpu.cfs_period_us"); err != nil {
+			return nil, err
+		}
+	}
+	if cpuacct != "" {
+		usage, err := readUint(cpuacct, "cpuacct.usage")
+		if err != nil {
+			return nil, err
+		}
+		c.CPUUsage = usage / 1000
+	}
+
+	if memory != "" {
+		if c.MemoryMax, err = readLimit(memory, "memory.limit_in_bytes"); err != nil {
+			return nil, err
+		}
+		if c.MemoryMax >= memoryUnlimitedV1 {
+			c.MemoryMax = types.Unlimited
+		}
+		if c.MemoryCurrent, err = readUint(memory, "memory.usage_in_bytes"); err != nil {
+			return nil, err
+		}
+	}
+
+	if pids != "" {
+		if c.PidsMax, err = readLimit(pids, "pids.max"); err != nil {
+			return nil, err
+		}
+		if c.PidsCurrent, err = readUint(pids, "pids.current"); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// readFields returns the fields of a file of a cgroup, or of def if the
+// file doesn't exist because the controller isn't enabled.
+func readFields(path, name, def string) ([]string, error) {
+	data, err := ioutil.ReadFile(filepath.Join(path, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return strings.Fields(def), nil
+	} else if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(data)), nil
+}
+
+func readLimit(path, name string) (int64, error) {
+	fields, err := readFields(path, name, "max")
+	if err != nil {
+		return 0, err
+	}
+	if len(fields) != 1 {
+		return 0, fmt.Errorf("invalid %s: %q", name, strings.Join(fields, " "))
+	}
+	limit, err := parseLimit(fields[0])
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return limit, nil
+}
+
+// parseLimit parses a limit, "max" or -1 meaning no limit.
+func parseLimit(s string) (int64, error) {
+	if s == "max" {
+		return types.Unlimited, nil
+	}
+	limit, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if limit < 0 {
+		return types.Unlimited, nil
+	}
+	return limit, nil
+}
+
+func readUint(path, name string) (uint64, error) {
+	fields, err := readFields(path, name, "0")
+	if err != nil {
+		return 0, err
+	}
+	if len(fields) != 1 {
+		return 0, fmt.Errorf("invalid %s: %q", name, strings.Join(fields, " "))
+	}
+	value, err := strconv.ParseUint(fields[0], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return value, nil
+}
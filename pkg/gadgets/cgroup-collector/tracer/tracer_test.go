@@ -0,0 +1,160 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/cgroup-collector/types"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Failed to create directory: %s", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %s", name, err)
+		}
+	}
+}
+
+func TestReadCgroupV2(t *testing.T) {
+	root := t.TempDir()
+	path := "kubepods.slice/kubepods-burstable.slice/cri-containerd-1234.scope"
+
+	writeFiles(t, root, map[string]string{
+		"cgroup.controllers":      "cpu memory pids\n",
+		path + "/cpu.max":         "50000 100000\n",
+		path + "/memory.max":      "268435456\n",
+		path + "/pids.max":        "max\n",
+		path + "/cpu.stat":        "usage_usec 123456\nuser_usec 100000\nsystem_usec 23456\n",
+		path + "/memory.current":  "10485760\n",
+		path + "/pids.current":    "3\n",
+		"other/cpu.max":           "max 100000\n",
+		"other/memory.max":        "max\n",
+		"other/pids.max":          "100\n",
+		"other/cpu.stat":          "usage_usec 0\n",
+		"other/memory.current":    "0\n",
+		"other/pids.current":      "0\n",
+		"nocontrollers/cpu.stat":  "usage_usec 42\n",
+		"nocontrollers/pids.max":  "invalid\n",
+		"nocontrollers2/cpu.stat": "usage_usec 42\n",
+	})
+
+	cgroup, err := ReadCgroup(root, "0::/"+path+"\n")
+	if err != nil {
+		t.Fatalf("Failed to read cgroup: %s", err)
+	}
+	expected := &types.Cgroup{
+		Version:       2,
+		CPUQuota:      50000,
+		CPUPeriod:     100000,
+		MemoryMax:     256 * 1024 * 1024,
+		PidsMax:       types.Unlimited,
+		CPUUsage:      123456,
+		MemoryCurrent: 10 * 1024 * 1024,
+		PidsCurrent:   3,
+	}
+	if !reflect.DeepEqual(cgroup, expected) {
+		t.Fatalf("Expected %+v, got %+v", expected, cgroup)
+	}
+	if cgroup.CPULimit() != 500 {
+		t.Fatalf("Expected a CPU limit of 500m, got %d", cgroup.CPULimit())
+	}
+
+	cgroup, err = ReadCgroup(root, "0::/other\n")
+	if err != nil {
+		t.Fatalf("Failed to read cgroup: %s", err)
+	}
+	if cgroup.CPUQuota != types.Unlimited || cgroup.MemoryMax != types.Unlimited || cgroup.PidsMax != 100 {
+		t.Fatalf("Wrong limits: %+v", cgroup)
+	}
+
+	// The files of the controllers not enabled don't exist.
+	cgroup, err = ReadCgroup(root, "0::/nocontrollers2\n")
+	if err != nil {
+		t.Fatalf("Failed to read cgroup: %s", err)
+	}
+	if cgroup.CPUQuota != types.Unlimited || cgroup.MemoryMax != types.Unlimited || cgroup.CPUUsage != 42 {
+		t.Fatalf("Wrong limits: %+v", cgroup)
+	}
+
+	if _, err := ReadCgroup(root, "0::/nocontrollers\n"); err == nil {
+		t.Fatalf("Expected error with invalid pids.max")
+	}
+	if _, err := ReadCgroup(root, "1:name=systemd:/foo\n"); err == nil {
+		t.Fatalf("Expected error without cgroup v2 path")
+	}
+}
+
+func TestReadCgroupV1(t *testing.T) {
+	root := t.TempDir()
+	path := "kubepods/burstable/pod1234/5678"
+
+	writeFiles(t, root, map[string]string{
+		"cpu,cpuacct/" + path + "/cpu.cfs_quota_us":  "-1\n",
+		"cpu,cpuacct/" + path + "/cpu.cfs_period_us": "100000\n",
+		"cpu,cpuacct/" + path + "/cpuacct.usage":     "5000000\n",
+		"memory/" + path + "/memory.limit_in_bytes":  "9223372036854771712\n",
+		"memory/" + path + "/memory.usage_in_bytes":  "4096\n",
+		"pids/" + path + "/pids.max":                 "1024\n",
+		"pids/" + path + "/pids.current":             "2\n",
+		"unified/" + path + "/cgroup.procs":          "",
+		"systemd/" + path + "/cgroup.procs":          "",
+		"cpu,cpuacct/limited/cpu.cfs_quota_us":       "200000\n",
+		"cpu,cpuacct/limited/cpu.cfs_period_us":      "100000\n",
+		"memory/limited/memory.limit_in_bytes":       "134217728\n",
+		"memory/limited/memory.usage_in_bytes":       "0\n",
+	})
+
+	procCgroup := `12:pids:/` + path + `
+4:cpu,cpuacct:/` + path + `
+3:memory:/` + path + `
+1:name=systemd:/` + path + `
+0::/` + path + `
+`
+
+	cgroup, err := ReadCgroup(root, procCgroup)
+	if err != nil {
+		t.Fatalf("Failed to read cgroup: %s", err)
+	}
+	expected := &types.Cgroup{
+		Version:       1,
+		CPUQuota:      types.Unlimited,
+		CPUPeriod:     100000,
+		MemoryMax:     types.Unlimited,
+		PidsMax:       1024,
+		CPUUsage:      5000,
+		MemoryCurrent: 4096,
+		PidsCurrent:   2,
+	}
+	if !reflect.DeepEqual(cgroup, expected) {
+		t.Fatalf("Expected %+v, got %+v", expected, cgroup)
+	}
+
+	cgroup, err = ReadCgroup(root, "4:cpu,cpuacct:/limited\n3:memory:/limited\n")
+	if err != nil {
+		t.Fatalf("Failed to read cgroup: %s", err)
+	}
+	if cgroup.CPULimit() != 2000 || cgroup.MemoryMax != 128*1024*1024 || cgroup.PidsMax != types.Unlimited {
+		t.Fatalf("Wrong limits: %+v", cgroup)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+// Unlimited is the value of the limits that aren't set, "max" in the
+// cgroup files.
+const Unlimited = -1
+
+// maxPageSize is the largest page size of the nodes: the kernel rounds the
+// memory limit down to a multiple of the page size.
+const maxPageSize = 64 * 1024
+
+// MinCPUQuota is the minimum CPU quota in microseconds set by the kubelet,
+// and by the kernel, whatever the CPU limit.
+const MinCPUQuota = 1000
+
+// Cgroup contains the limits enforced by the cgroup of a container and its
+// current usage, as read on the node.
+type Cgroup struct {
+	eventtypes.Event
+
+	// Version is the version of the cgroup hierarchy: 1 or 2.
+	Version int `json:"version"`
+
+	// CPUQuota is the CPU time in microseconds the container can use
+	// every CPUPeriod microseconds, or Unlimited.
+	CPUQuota  int64  `json:"cpuQuota"`
+	CPUPeriod uint64 `json:"cpuPeriod"`
+
+	// MemoryMax is the memory limit in bytes and PidsMax the maximum
+	// number of tasks, or Unlimited.
+	MemoryMax int64 `json:"memoryMax"`
+	PidsMax   int64 `json:"pidsMax"`
+
+	// CPUUsage is the CPU time in microseconds used by the container since
+	// it started, MemoryCurrent the memory used in bytes and PidsCurrent
+	// the number of tasks.
+	CPUUsage      uint64 `json:"cpuUsage"`
+	MemoryCurrent uint64 `json:"memoryCurrent"`
+	PidsCurrent   uint64 `json:"pidsCurrent"`
+}
+
+// CPULimit returns the CPU limit in millicores, or Unlimited.
+func (c *Cgroup) CPULimit() int64 {
+	if c.CPUQuota == Unlimited || c.CPUPeriod == 0 {
+		return Unlimited
+	}
+	return c.CPUQuota * 1000 / int64(c.CPUPeriod)
+}
+
+// ExpectedCPUQuota returns the CPU quota the kubelet sets for a CPU limit
+// in millicores, 0 meaning no limit.
+func ExpectedCPUQuota(millicores int64, period uint64) int64 {
+	if millicores <= 0 {
+		return Unlimited
+	}
+	quota := millicores * int64(period) / 1000
+	if quota < MinCPUQuota {
+		quota = MinCPUQuota
+	}
+	return quota
+}
+
+// Drift returns the resources, "cpu" and "memory", whose limits enforced by
+// the cgroup differ from the limits of the spec of the container, in
+// millicores and bytes, 0 meaning no limit.
+func (c *Cgroup) Drift(cpuLimit, memoryLimit int64) []string {
+	drift := []string{}
+
+	if c.CPUQuota != ExpectedCPUQuota(cpuLimit, c.CPUPeriod) {
+		drift = append(drift, "cpu")
+	}
+
+	if memoryLimit <= 0 {
+		if c.MemoryMax != Unlimited {
+			drift = append(drift, "memory")
+		}
+	} else if c.MemoryMax == Unlimited || c.MemoryMax > memoryLimit || memoryLimit-c.MemoryMax >= maxPageSize {
+		drift = append(drift, "memory")
+	}
+
+	return drift
+}
@@ -0,0 +1,76 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDrift(t *testing.T) {
+	table := []struct {
+		description string
+		cgroup      Cgroup
+		cpuLimit    int64
+		memoryLimit int64
+		drift       []string
+	}{
+		{
+			description: "no limits",
+			cgroup:      Cgroup{CPUQuota: Unlimited, CPUPeriod: 100000, MemoryMax: Unlimited},
+			drift:       []string{},
+		},
+		{
+			description: "limits enforced",
+			cgroup:      Cgroup{CPUQuota: 50000, CPUPeriod: 100000, MemoryMax: 256 * 1024 * 1024},
+			cpuLimit:    500,
+			memoryLimit: 256 * 1024 * 1024,
+			drift:       []string{},
+		},
+		{
+			description: "minimal CPU quota and memory rounded to the page size",
+			cgroup:      Cgroup{CPUQuota: MinCPUQuota, CPUPeriod: 100000, MemoryMax: 1000 * 4096},
+			cpuLimit:    1,
+			memoryLimit: 1000*4096 + 100,
+			drift:       []string{},
+		},
+		{
+			description: "limits not enforced",
+			cgroup:      Cgroup{CPUQuota: Unlimited, CPUPeriod: 100000, MemoryMax: Unlimited},
+			cpuLimit:    500,
+			memoryLimit: 256 * 1024 * 1024,
+			drift:       []string{"cpu", "memory"},
+		},
+		{
+			description: "limits changed on the node",
+			cgroup:      Cgroup{CPUQuota: 100000, CPUPeriod: 100000, MemoryMax: 512 * 1024 * 1024},
+			cpuLimit:    500,
+			memoryLimit: 256 * 1024 * 1024,
+			drift:       []string{"cpu", "memory"},
+		},
+		{
+			description: "limits not in the spec",
+			cgroup:      Cgroup{CPUQuota: 50000, CPUPeriod: 100000, MemoryMax: Unlimited},
+			drift:       []string{"cpu"},
+		},
+	}
+
+	for _, entry := range table {
+		drift := entry.cgroup.Drift(entry.cpuLimit, entry.memoryLimit)
+		if !reflect.DeepEqual(drift, entry.drift) {
+			t.Fatalf("%s: expected drift %v, got %v", entry.description, entry.drift, drift)
+		}
+	}
+}
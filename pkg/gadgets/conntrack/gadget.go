@@ -0,0 +1,208 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conntrack
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	"github.com/kinvolk/inspektor-gadget/pkg/bpferror"
+	containerutils "github.com/kinvolk/inspektor-gadget/pkg/container-utils"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/conntrack/tracer"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/conntrack/types"
+	pb "github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/api"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/pubsub"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+type Trace struct {
+	resolver gadgets.Resolver
+
+	started bool
+	tracer  *tracer.Tracer
+
+	netnsHost uint64
+}
+
+type TraceFactory struct {
+	gadgets.BaseFactory
+
+	netnsHost uint64
+}
+
+func NewFactory() gadgets.TraceFactory {
+	netnsHost, _ := containerutils.GetNetNs(os.Getpid())
+	return &TraceFactory{
+		BaseFactory: gadgets.BaseFactory{DeleteTrace: deleteTrace},
+		netnsHost:   netnsHost,
+	}
+}
+
+func (f *TraceFactory) Description() string {
+	return `The conntrack gadget traces the packets dropped by conntrack because of insertion failures, source NAT port clashes or a full table, with the pod sending them, and warns when the table is getting full.`
+}
+
+func (f *TraceFactory) Parameters() []gadgets.GadgetParameter {
+	return []gadgets.GadgetParameter{
+		{
+			Name:        "threshold",
+			Description: "Usage of the conntrack table, in percent, above which a warning is reported",
+			Default:     strconv.Itoa(types.ThresholdDefault),
+		},
+	}
+}
+
+func (f *TraceFactory) OutputModesSupported() map[string]struct{} {
+	return map[string]struct{}{
+		"Stream": {},
+	}
+}
+
+func deleteTrace(name string, t interface{}) {
+	trace := t.(*Trace)
+	if trace.started {
+		trace.resolver.Unsubscribe(genPubSubKey(name))
+		trace.tracer.Stop()
+		trace.tracer = nil
+	}
+}
+
+func (f *TraceFactory) Operations() map[string]gadgets.TraceOperation {
+	n := func() interface{} {
+		return &Trace{
+			resolver:  f.Resolver,
+			netnsHost: f.netnsHost,
+		}
+	}
+
+	return map[string]gadgets.TraceOperation{
+		"start": {
+			Doc: "Start conntrack gadget",
+			Operation: func(name string, trace *gadgetv1alpha1.Trace) {
+				f.LookupOrCreate(name, n).(*Trace).Start(trace)
+			},
+		},
+		"stop": {
+			Doc: "Stop conntrack gadget",
+			Operation: func(name string, trace *gadgetv1alpha1.Trace) {
+				f.LookupOrCreate(name, n).(*Trace).Stop(trace)
+			},
+		},
+	}
+}
+
+type pubSubKey string
+
+func genPubSubKey(name string) pubSubKey {
+	return pubSubKey(fmt.Sprintf("gadget/conntrack/%s", name))
+}
+
+// filterIsEmpty returns true if the trace selects all the pods of the node.
+func filterIsEmpty(f *gadgetv1alpha1.ContainerFilter) bool {
+	return f == nil || (f.Namespace == "" && f.Podname == "" && f.PodUID == "" &&
+		len(f.Labels) == 0 && len(f.Annotations) == 0 && f.ContainerName == "" &&
+		f.Sandbox == "")
+}
+
+func (t *Trace) Start(trace *gadgetv1alpha1.Trace) {
+	if t.started {
+		trace.Status.State = "Started"
+		return
+	}
+
+	threshold := uint64(types.ThresholdDefault)
+
+	if val, ok := trace.Spec.Parameters["threshold"]; ok {
+		parsed, err := strconv.ParseUint(val, 10, 64)
+		if err != nil || parsed == 0 || parsed > 100 {
+			trace.Status.OperationError = fmt.Sprintf("%q is not valid for threshold", val)
+			return
+		}
+		threshold = parsed
+	}
+
+	traceName := gadgets.TraceName(trace.ObjectMeta.Namespace, trace.ObjectMeta.Name)
+
+	eventCallback := func(event types.Event) {
+		r, err := json.Marshal(event)
+		if err != nil {
+			fmt.Printf("error marshalling event: %s\n", err)
+			return
+		}
+		t.resolver.PublishEvent(traceName, string(r))
+	}
+
+	config := &tracer.Config{
+		Threshold:          threshold,
+		ReportUnattributed: filterIsEmpty(trace.Spec.Filter),
+	}
+
+	var err error
+	t.tracer, err = tracer.NewTracer(config, eventCallback, trace.Spec.Node)
+	if err != nil {
+		trace.Status.OperationError = fmt.Sprintf("failed to create tracer: %s", bpferror.Describe(err))
+		return
+	}
+
+	addContainer := func(container *pb.ContainerDefinition) {
+		err := t.tracer.AddContainer(container, container.Netns == t.netnsHost)
+		if err != nil {
+			msg := fmt.Sprintf("failed to add container %s/%s/%s: %s",
+				container.Namespace, container.Podname, container.Name, err)
+			eventCallback(types.Base(eventtypes.Warn(msg, trace.Spec.Node)))
+		}
+	}
+
+	containerEventCallback := func(event pubsub.PubSubEvent) {
+		switch event.Type {
+		case pubsub.EventTypeAddContainer:
+			addContainer(&event.Container)
+		case pubsub.EventTypeRemoveContainer:
+			t.tracer.RemoveContainer(&event.Container)
+		}
+	}
+
+	existingContainers := t.resolver.Subscribe(
+		genPubSubKey(trace.ObjectMeta.Namespace+"/"+trace.ObjectMeta.Name),
+		*gadgets.ContainerSelectorFromContainerFilter(trace.Spec.Filter),
+		containerEventCallback,
+	)
+
+	for _, c := range existingContainers {
+		addContainer(c)
+	}
+
+	t.started = true
+
+	trace.Status.State = "Started"
+}
+
+func (t *Trace) Stop(trace *gadgetv1alpha1.Trace) {
+	if !t.started {
+		trace.Status.OperationError = "Not started"
+		return
+	}
+
+	t.resolver.Unsubscribe(genPubSubKey(trace.ObjectMeta.Namespace + "/" + trace.ObjectMeta.Name))
+	t.tracer.Stop()
+	t.tracer = nil
+	t.started = false
+
+	trace.Status.State = "Stopped"
+}
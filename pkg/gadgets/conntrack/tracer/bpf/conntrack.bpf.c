@@ -0,0 +1,85 @@
+// SPDX-License-Identifier: GPL-2.0
+#include <vmlinux/vmlinux.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_tracing.h>
+
+#include "conntrack.h"
+
+#define MAX_ENTRIES	10240
+
+/* Defined here because of conflicts with include files */
+#define NF_DROP		0
+#define NFCT_INFOMASK	7UL
+#define IPS_SRC_NAT	(1 << 4)
+
+struct {
+	__uint(type, BPF_MAP_TYPE_PERF_EVENT_ARRAY);
+	__uint(key_size, sizeof(u32));
+	__uint(value_size, sizeof(u32));
+} events SEC(".maps");
+
+/* skb being confirmed by each thread, between the entry and the exit of
+ * __nf_conntrack_confirm() */
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, u32);
+	__type(value, struct sk_buff *);
+	__uint(map_flags, BPF_F_NO_PREALLOC);
+} skbs SEC(".maps");
+
+SEC("kprobe/__nf_conntrack_confirm")
+int BPF_KPROBE(ig_ct_confirm_e, struct sk_buff *skb)
+{
+	u32 tid = (u32) bpf_get_current_pid_tgid();
+
+	bpf_map_update_elem(&skbs, &tid, &skb, BPF_ANY);
+	return 0;
+}
+
+SEC("kretprobe/__nf_conntrack_confirm")
+int BPF_KRETPROBE(ig_ct_confirm_x, int ret)
+{
+	u32 tid = (u32) bpf_get_current_pid_tgid();
+	const struct nf_conntrack_tuple *tuple;
+	struct event_t event = {};
+	struct sk_buff **skbp;
+	struct task_struct *task;
+	struct nf_conn *ct;
+	unsigned long status;
+
+	skbp = bpf_map_lookup_elem(&skbs, &tid);
+	if (!skbp)
+		return 0;
+
+	ct = (struct nf_conn *) (BPF_CORE_READ(*skbp, _nfct) & ~NFCT_INFOMASK);
+	bpf_map_delete_elem(&skbs, &tid);
+
+	if (ret != NF_DROP || !ct)
+		return 0;
+
+	status = BPF_CORE_READ(ct, status);
+	event.kind = status & IPS_SRC_NAT ? KIND_SNAT_CLASH : KIND_INSERT_FAILED;
+
+	tuple = &ct->tuplehash[IP_CT_DIR_ORIGINAL].tuple;
+	bpf_probe_read_kernel(&event.saddr, sizeof(event.saddr), &tuple->src.u3.all);
+	bpf_probe_read_kernel(&event.daddr, sizeof(event.daddr), &tuple->dst.u3.all);
+	event.sport = BPF_CORE_READ(tuple, src.u.all);
+	event.dport = BPF_CORE_READ(tuple, dst.u.all);
+	event.l3proto = BPF_CORE_READ(tuple, src.l3num);
+	event.proto = BPF_CORE_READ(tuple, dst.protonum);
+
+	/* The packets are often confirmed in the context of the task sending
+	 * them, but not always: the userspace relies on the source address to
+	 * find the container and only uses these fields as a fallback. */
+	task = (struct task_struct *) bpf_get_current_task();
+	event.mount_ns_id = (u64) BPF_CORE_READ(task, nsproxy, mnt_ns, ns.inum);
+	event.pid = bpf_get_current_pid_tgid() >> 32;
+	bpf_get_current_comm(&event.comm, sizeof(event.comm));
+
+	bpf_perf_event_output(ctx, &events, BPF_F_CURRENT_CPU, &event, sizeof(event));
+	return 0;
+}
+
+char LICENSE[] SEC("license") = "GPL";
@@ -0,0 +1,28 @@
+/* SPDX-License-Identifier: (LGPL-2.1 OR BSD-2-Clause) */
+#ifndef __CONNTRACK_H
+#define __CONNTRACK_H
+
+#define TASK_COMM_LEN 16
+
+/* The connection was not inserted in the table because an entry with the
+ * same tuple was inserted in the meantime, typically by a concurrent UDP
+ * packet of the same socket. */
+#define KIND_INSERT_FAILED 1
+/* Same as KIND_INSERT_FAILED but the connection was source NATed: the port
+ * chosen by the NAT clashes with the one of another connection. */
+#define KIND_SNAT_CLASH 2
+
+struct event_t {
+	__u8 saddr[16];
+	__u8 daddr[16];
+	__u64 mount_ns_id;
+	__u32 pid;
+	__u16 sport;
+	__u16 dport;
+	__u16 l3proto;
+	__u8 proto;
+	__u8 kind;
+	char comm[TASK_COMM_LEN];
+};
+
+#endif /* __CONNTRACK_H */
@@ -0,0 +1,82 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/conntrack/types"
+)
+
+type dropKey struct {
+	kind      string
+	namespace string
+	pod       string
+	container string
+	protocol  string
+	saddr     string
+	daddr     string
+	dport     uint16
+}
+
+// drops aggregates the dropped packets with the same kind, container,
+// source and destination, to avoid reporting an event per packet when the
+// drops happen in bursts. The source port isn't part of the key as each
+// retry of a connection usually uses a different one.
+type drops struct {
+	events map[dropKey]*types.Event
+	keys   []dropKey
+}
+
+func newDrops() *drops {
+	return &drops{
+		events: make(map[dropKey]*types.Event),
+	}
+}
+
+func (d *drops) add(event *types.Event) {
+	key := dropKey{
+		kind:      event.Kind,
+		namespace: event.Namespace,
+		pod:       event.Pod,
+		container: event.Container,
+		protocol:  event.Protocol,
+		saddr:     event.Saddr,
+		daddr:     event.Daddr,
+		dport:     event.Dport,
+	}
+
+	if e, ok := d.events[key]; ok {
+		e.Count++
+		return
+	}
+
+	e := *event
+	e.Count = 1
+	d.events[key] = &e
+	d.keys = append(d.keys, key)
+}
+
+// flush returns the aggregated events in the order in which the first
+// packet of each of them was dropped, and resets the aggregation.
+func (d *drops) flush() []types.Event {
+	events := make([]types.Event, 0, len(d.keys))
+	for _, key := range d.keys {
+		events = append(events, *d.events[key])
+	}
+
+	d.events = make(map[dropKey]*types.Event)
+	d.keys = nil
+
+	return events
+}
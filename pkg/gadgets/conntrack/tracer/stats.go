@@ -0,0 +1,157 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	countPath = "/proc/sys/net/netfilter/nf_conntrack_count"
+	maxPath   = "/proc/sys/net/netfilter/nf_conntrack_max"
+	statPath  = "/proc/net/stat/nf_conntrack"
+
+	// usageHysteresis is how much the usage of the table, in percent,
+	// has to go below the threshold before being reported as back to
+	// normal. It avoids reporting an event at each poll when the usage
+	// stays around the threshold.
+	usageHysteresis = 5
+)
+
+// tableStats are the statistics of the conntrack table of the host network
+// namespace.
+type tableStats struct {
+	entries uint64
+	max     uint64
+
+	// Counters summed over all the CPUs
+	drop      uint64
+	earlyDrop uint64
+}
+
+func readUint(path string) (uint64, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(content)), 10, 64)
+}
+
+func readTableStats() (*tableStats, error) {
+	f, err := os.Open(statPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	stats, err := parseStat(f)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", statPath, err)
+	}
+
+	if stats.entries, err = readUint(countPath); err != nil {
+		return nil, err
+	}
+	if stats.max, err = readUint(maxPath); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// parseStat parses the per-CPU counters of /proc/net/stat/nf_conntrack.
+// The first line gives the names of the columns, which depend on the
+// kernel version, and the other ones the values in hexadecimal for each
+// CPU.
+func parseStat(r io.Reader) (*tableStats, error) {
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("missing header")
+	}
+	header := strings.Fields(scanner.Text())
+
+	stats := &tableStats{}
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != len(header) {
+			return nil, fmt.Errorf("expected %d columns, got %d", len(header), len(fields))
+		}
+
+		for i, name := range header {
+			var counter *uint64
+			switch name {
+			case "drop":
+				counter = &stats.drop
+			case "early_drop":
+				counter = &stats.earlyDrop
+			default:
+				continue
+			}
+
+			value, err := strconv.ParseUint(fields[i], 16, 64)
+			if err != nil {
+				return nil, fmt.Errorf("parsing %s: %w", name, err)
+			}
+			*counter += value
+		}
+	}
+
+	return stats, scanner.Err()
+}
+
+// usage returns the usage of the table in percent.
+func (s *tableStats) usage() uint64 {
+	if s.max == 0 {
+		return 0
+	}
+	return s.entries * 100 / s.max
+}
+
+// full returns true if the table was full between previous and s: it has
+// no room left or entries were evicted to make room for new ones.
+func (s *tableStats) full(previous *tableStats) bool {
+	return (s.max != 0 && s.entries >= s.max) || s.earlyDrop > previous.earlyDrop
+}
+
+// usageAlert tells when the usage of the table crosses the threshold.
+type usageAlert struct {
+	threshold uint64
+	above     bool
+}
+
+// update returns true if the usage went above the threshold or back below
+// it since the previous call.
+func (a *usageAlert) update(usage uint64) bool {
+	if !a.above && usage >= a.threshold {
+		a.above = true
+		return true
+	}
+	if a.above && usage+usageHysteresis < a.threshold {
+		a.above = false
+		return true
+	}
+	return false
+}
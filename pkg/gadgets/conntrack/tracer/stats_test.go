@@ -0,0 +1,141 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/conntrack/types"
+)
+
+func TestParseStat(t *testing.T) {
+	content := `entries  clashres found new invalid ignore delete delete_list insert insert_failed drop early_drop error  expect_new expect_create expect_delete search_restart
+000000a2  00000001 00000000 00000000 00000003 00000010 00000000 00000000 00000000 00000002 00000004 00000001 00000000  00000000 00000000 00000000 00000000
+000000a2  00000000 00000000 00000000 00000000 00000020 00000000 00000000 00000000 00000000 0000000c 00000000 00000000  00000000 00000000 00000000 00000000
+`
+
+	stats, err := parseStat(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("parsing: %s", err)
+	}
+
+	expected := &tableStats{drop: 16, earlyDrop: 1}
+	if !reflect.DeepEqual(stats, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, stats)
+	}
+
+	if _, err := parseStat(strings.NewReader("entries drop\n00000001\n")); err == nil {
+		t.Fatalf("expected an error for a line with missing columns")
+	}
+	if _, err := parseStat(strings.NewReader("")); err == nil {
+		t.Fatalf("expected an error for a missing header")
+	}
+}
+
+func TestTableFull(t *testing.T) {
+	previous := &tableStats{entries: 900, max: 1000, earlyDrop: 3}
+
+	table := []struct {
+		description string
+		stats       *tableStats
+		full        bool
+	}{
+		{"room left", &tableStats{entries: 999, max: 1000, earlyDrop: 3}, false},
+		{"no room left", &tableStats{entries: 1000, max: 1000, earlyDrop: 3}, true},
+		{"entries evicted", &tableStats{entries: 990, max: 1000, earlyDrop: 5}, true},
+		{"no limit", &tableStats{entries: 1000, earlyDrop: 3}, false},
+	}
+
+	for _, entry := range table {
+		if full := entry.stats.full(previous); full != entry.full {
+			t.Errorf("%s: expected full %v, got %v", entry.description, entry.full, full)
+		}
+	}
+}
+
+func TestUsageAlert(t *testing.T) {
+	alert := &usageAlert{threshold: 80}
+
+	table := []struct {
+		usage  uint64
+		update bool
+	}{
+		{50, false},
+		{80, true},
+		{95, false},
+		{77, false},
+		{74, true},
+		{79, false},
+		{81, true},
+	}
+
+	for _, entry := range table {
+		if update := alert.update(entry.usage); update != entry.update {
+			t.Errorf("usage %d%%: expected update %v, got %v", entry.usage, entry.update, update)
+		}
+	}
+}
+
+func TestDrops(t *testing.T) {
+	dns := types.Event{
+		Kind:      types.KindInsertFailed,
+		Protocol:  "UDP",
+		Saddr:     "10.0.0.5",
+		Daddr:     "10.96.0.10",
+		Dport:     53,
+		Pid:       42,
+		Comm:      "curl",
+		MountNsID: 4026532578,
+	}
+	dns.Namespace = "default"
+	dns.Pod = "client"
+
+	clash := types.Event{
+		Kind:     types.KindSNATClash,
+		Protocol: "TCP",
+		Saddr:    "10.0.0.6",
+		Daddr:    "203.0.113.7",
+		Dport:    443,
+	}
+	clash.Namespace = "default"
+	clash.Pod = "worker"
+
+	d := newDrops()
+	d.add(&dns)
+	d.add(&clash)
+	d.add(&dns)
+
+	// The aggregated events keep the task of the first drop
+	other := dns
+	other.Pid = 43
+	d.add(&other)
+
+	expectedDNS := dns
+	expectedDNS.Count = 3
+	expectedClash := clash
+	expectedClash.Count = 1
+
+	events := d.flush()
+	expected := []types.Event{expectedDNS, expectedClash}
+	if !reflect.DeepEqual(events, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, events)
+	}
+
+	if events := d.flush(); len(events) != 0 {
+		t.Fatalf("expected no events after flush, got %+v", events)
+	}
+}
@@ -0,0 +1,410 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+// #include <linux/types.h>
+// #include <arpa/inet.h>
+// #include "./bpf/conntrack.h"
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/cilium/ebpf/link"
+	"github.com/cilium/ebpf/perf"
+	"golang.org/x/sys/unix"
+
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/conntrack/types"
+	pb "github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/api"
+	"github.com/kinvolk/inspektor-gadget/pkg/netnsenter"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+//go:generate sh -c "GOOS=$(go env GOHOSTOS) GOARCH=$(go env GOHOSTARCH) go run github.com/cilium/ebpf/cmd/bpf2go -target bpfel -cc clang conntrack ./bpf/conntrack.bpf.c -- -I./bpf/ -I../../.. -target bpf -D__TARGET_ARCH_x86"
+
+// pollInterval is the interval at which the statistics of the table are
+// read and the aggregated drops are reported.
+const pollInterval = time.Second
+
+type Config struct {
+	// Threshold is the usage of the conntrack table, in percent, above
+	// which a warning is reported.
+	Threshold uint64
+
+	// ReportUnattributed reports the drops that can't be attributed to
+	// any of the added containers.
+	ReportUnattributed bool
+}
+
+type container struct {
+	namespace   string
+	pod         string
+	name        string
+	hostNetwork bool
+	addrs       []string
+}
+
+// podAddr is an address of a pod. It's shared by all the containers of the
+// pod.
+type podAddr struct {
+	namespace string
+	pod       string
+	users     int
+}
+
+type Tracer struct {
+	config        *Config
+	objs          conntrackObjects
+	entryLink     link.Link
+	exitLink      link.Link
+	reader        *perf.Reader
+	eventCallback func(types.Event)
+	node          string
+
+	mu sync.Mutex
+	// containers by mount namespace
+	containers map[uint64]*container
+	// pods by address, for the pods not using the host network
+	addrs map[string]*podAddr
+	drops *drops
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewTracer(c *Config, eventCallback func(types.Event), node string) (*Tracer, error) {
+	t := &Tracer{
+		config:        c,
+		eventCallback: eventCallback,
+		node:          node,
+		containers:    make(map[uint64]*container),
+		addrs:         make(map[string]*podAddr),
+		drops:         newDrops(),
+		done:          make(chan struct{}),
+	}
+
+	if err := t.start(); err != nil {
+		t.Stop()
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (t *Tracer) Stop() {
+	t.stop()
+}
+
+func (t *Tracer) stop() {
+	t.entryLink = gadgets.CloseLink(t.entryLink)
+	t.exitLink = gadgets.CloseLink(t.exitLink)
+
+	if t.reader != nil {
+		t.reader.Close()
+		t.reader = nil
+	}
+
+	if t.done != nil {
+		close(t.done)
+		t.done = nil
+	}
+	t.wg.Wait()
+
+	t.objs.Close()
+}
+
+func (t *Tracer) start() error {
+	stats, err := readTableStats()
+	if err != nil {
+		return fmt.Errorf("failed to read the statistics of the conntrack table: %w", err)
+	}
+
+	spec, err := loadConntrack()
+	if err != nil {
+		return fmt.Errorf("failed to load ebpf program: %w", err)
+	}
+
+	if err := spec.LoadAndAssign(&t.objs, nil); err != nil {
+		return fmt.Errorf("failed to load ebpf program: %w", err)
+	}
+
+	t.entryLink, err = link.Kprobe("__nf_conntrack_confirm", t.objs.IgCtConfirmE, nil)
+	if err != nil {
+		return fmt.Errorf("error opening kprobe: %w", err)
+	}
+
+	t.exitLink, err = link.Kretprobe("__nf_conntrack_confirm", t.objs.IgCtConfirmX, nil)
+	if err != nil {
+		return fmt.Errorf("error opening kretprobe: %w", err)
+	}
+
+	reader, err := perf.NewReader(t.objs.conntrackMaps.Events, gadgets.PerfBufferPages*os.Getpagesize())
+	if err != nil {
+		return fmt.Errorf("error creating perf ring buffer: %w", err)
+	}
+	t.reader = reader
+
+	t.wg.Add(2)
+	go t.run()
+	go t.poll(stats)
+
+	return nil
+}
+
+// AddContainer makes the drops of the container's pod attributed to it.
+// The drops of the pods not using the host network are attributed using
+// their addresses, the ones of the pods using the host network using the
+// mount namespace of the task confirming the connection.
+func (t *Tracer) AddContainer(c *pb.ContainerDefinition, hostNetwork bool) error {
+	cont := &container{
+		namespace:   c.Namespace,
+		pod:         c.Podname,
+		name:        c.Name,
+		hostNetwork: hostNetwork,
+	}
+
+	if !hostNetwork {
+		err := netnsenter.NetnsEnter(int(c.Pid), func() error {
+			var err error
+			cont.addrs, err = interfaceAddrs()
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("getting the addresses of the pod: %w", err)
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.containers[c.Mntns] = cont
+	for _, addr := range cont.addrs {
+		p, ok := t.addrs[addr]
+		if !ok || p.namespace != cont.namespace || p.pod != cont.pod {
+			p = &podAddr{namespace: cont.namespace, pod: cont.pod}
+			t.addrs[addr] = p
+		}
+		p.users++
+	}
+
+	return nil
+}
+
+func (t *Tracer) RemoveContainer(c *pb.ContainerDefinition) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	cont, ok := t.containers[c.Mntns]
+	if !ok {
+		return
+	}
+	delete(t.containers, c.Mntns)
+
+	for _, addr := range cont.addrs {
+		p, ok := t.addrs[addr]
+		if !ok || p.namespace != cont.namespace || p.pod != cont.pod {
+			continue
+		}
+		p.users--
+		if p.users == 0 {
+			delete(t.addrs, addr)
+		}
+	}
+}
+
+// interfaceAddrs returns the addresses of the interfaces of the current
+// network namespace, except the loopback and link-local ones.
+func interfaceAddrs() ([]string, error) {
+	ifaceAddrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, err
+	}
+
+	addrs := []string{}
+	for _, a := range ifaceAddrs {
+		ipnet, ok := a.(*net.IPNet)
+		if !ok || ipnet.IP.IsLoopback() || ipnet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		addrs = append(addrs, ipnet.IP.String())
+	}
+
+	return addrs, nil
+}
+
+// attribute fills the pod and container of event. The task confirming the
+// connection is only kept when it belongs to the pod, as the connections
+// are sometimes confirmed while processing the packets of other tasks.
+func (t *Tracer) attribute(event *types.Event) bool {
+	cont := t.containers[event.MountNsID]
+
+	if p, ok := t.addrs[event.Saddr]; ok {
+		event.Namespace = p.namespace
+		event.Pod = p.pod
+		if cont != nil && cont.namespace == p.namespace && cont.pod == p.pod {
+			event.Container = cont.name
+			return true
+		}
+	} else if cont != nil && cont.hostNetwork {
+		event.Namespace = cont.namespace
+		event.Pod = cont.pod
+		event.Container = cont.name
+		return true
+	}
+
+	event.Pid = 0
+	event.Comm = ""
+	event.MountNsID = 0
+
+	return event.Pod != ""
+}
+
+func protocolName(proto uint8) string {
+	switch proto {
+	case unix.IPPROTO_ICMP:
+		return "ICMP"
+	case unix.IPPROTO_TCP:
+		return "TCP"
+	case unix.IPPROTO_UDP:
+		return "UDP"
+	case unix.IPPROTO_ICMPV6:
+		return "ICMPv6"
+	case unix.IPPROTO_SCTP:
+		return "SCTP"
+	}
+	return strconv.Itoa(int(proto))
+}
+
+func addrString(l3proto uint16, addr []byte) string {
+	if l3proto == unix.AF_INET {
+		return net.IP(addr[:net.IPv4len]).String()
+	}
+	return net.IP(addr).String()
+}
+
+func (t *Tracer) run() {
+	defer t.wg.Done()
+
+	for {
+		record, err := t.reader.Read()
+		if err != nil {
+			if errors.Is(err, perf.ErrClosed) {
+				return
+			}
+
+			msg := fmt.Sprintf("Error reading perf ring buffer: %s", err)
+			t.eventCallback(types.Base(eventtypes.Err(msg, t.node)))
+			return
+		}
+
+		eventC := (*C.struct_event_t)(unsafe.Pointer(&record.RawSample[0]))
+
+		kind := types.KindInsertFailed
+		if eventC.kind == C.KIND_SNAT_CLASH {
+			kind = types.KindSNATClash
+		}
+
+		l3proto := uint16(eventC.l3proto)
+		event := types.Event{
+			Event: eventtypes.Event{
+				Type: eventtypes.NORMAL,
+				Node: t.node,
+			},
+			Kind:      kind,
+			Protocol:  protocolName(uint8(eventC.proto)),
+			Saddr:     addrString(l3proto, C.GoBytes(unsafe.Pointer(&eventC.saddr[0]), 16)),
+			Daddr:     addrString(l3proto, C.GoBytes(unsafe.Pointer(&eventC.daddr[0]), 16)),
+			Dport:     uint16(C.htons(eventC.dport)),
+			Pid:       uint32(eventC.pid),
+			Comm:      C.GoString(&eventC.comm[0]),
+			MountNsID: uint64(eventC.mount_ns_id),
+		}
+
+		t.mu.Lock()
+		if t.attribute(&event) || t.config.ReportUnattributed {
+			t.drops.add(&event)
+		}
+		t.mu.Unlock()
+	}
+}
+
+// poll reports the aggregated drops and the events about the usage of the
+// table at each interval.
+func (t *Tracer) poll(previous *tableStats) {
+	defer t.wg.Done()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	alert := &usageAlert{threshold: t.config.Threshold}
+	done := t.done
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		t.mu.Lock()
+		events := t.drops.flush()
+		t.mu.Unlock()
+
+		for _, event := range events {
+			t.eventCallback(event)
+		}
+
+		stats, err := readTableStats()
+		if err != nil {
+			msg := fmt.Sprintf("failed to read the statistics of the conntrack table: %s", err)
+			t.eventCallback(types.Base(eventtypes.Warn(msg, t.node)))
+			continue
+		}
+
+		if stats.full(previous) {
+			event := t.tableEvent(types.KindTableFull, stats)
+			if stats.drop > previous.drop {
+				event.Dropped = stats.drop - previous.drop
+			}
+			t.eventCallback(event)
+		}
+
+		if alert.update(stats.usage()) {
+			t.eventCallback(t.tableEvent(types.KindTableUsage, stats))
+		}
+
+		previous = stats
+	}
+}
+
+func (t *Tracer) tableEvent(kind string, stats *tableStats) types.Event {
+	return types.Event{
+		Event: eventtypes.Event{
+			Type: eventtypes.NORMAL,
+			Node: t.node,
+		},
+		Kind:    kind,
+		Entries: stats.entries,
+		Max:     stats.max,
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+// Kinds of the events reported by the conntrack gadget.
+const (
+	// KindInsertFailed is reported when a connection can't be inserted
+	// in the conntrack table because an entry with the same tuple was
+	// inserted in the meantime. The packet is dropped.
+	KindInsertFailed = "insert-failed"
+
+	// KindSNATClash is reported when the port chosen to source NAT a
+	// connection clashes with the one of another connection. The packet
+	// is dropped.
+	KindSNATClash = "snat-clash"
+
+	// KindTableFull is reported when the conntrack table is full and
+	// new connections are dropped.
+	KindTableFull = "table-full"
+
+	// KindTableUsage is reported when the usage of the conntrack table
+	// goes above or back below the threshold.
+	KindTableUsage = "table-usage"
+)
+
+// ThresholdDefault is the default usage of the conntrack table, in
+// percent, above which a warning is reported.
+const ThresholdDefault = 80
+
+type Event struct {
+	eventtypes.Event
+
+	Kind string `json:"kind,omitempty"`
+
+	// Fields of the insert-failed and snat-clash events. Count is the
+	// number of dropped packets, aggregated by source and destination,
+	// since the previous event.
+	Protocol  string `json:"protocol,omitempty"`
+	Saddr     string `json:"saddr,omitempty"`
+	Daddr     string `json:"daddr,omitempty"`
+	Dport     uint16 `json:"dport,omitempty"`
+	Pid       uint32 `json:"pid,omitempty"`
+	Comm      string `json:"comm,omitempty"`
+	MountNsID uint64 `json:"mountnsid,omitempty"`
+	Count     uint64 `json:"count,omitempty"`
+
+	// Fields of the table-full and table-usage events
+	Entries uint64 `json:"entries,omitempty"`
+	Max     uint64 `json:"max,omitempty"`
+	Dropped uint64 `json:"dropped,omitempty"`
+}
+
+func Base(ev eventtypes.Event) Event {
+	return Event{
+		Event: ev,
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package containerbinary attaches probes, like uprobes or USDT probes, to
+// a binary of the containers. The probes are attached to the file rather
+// than to the containers: they are shared by the containers using the same
+// file, e.g. started from the same image, and detached when the last of
+// them is removed.
+package containerbinary
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+
+	containerutils "github.com/kinvolk/inspektor-gadget/pkg/container-utils"
+)
+
+// ErrBinaryNotFound is returned by AddContainer when the binary doesn't
+// exist in the container.
+var ErrBinaryNotFound = errors.New("binary not found in the container")
+
+// Probes are the probes attached to a file.
+type Probes interface {
+	Close()
+}
+
+// AttachFunc attaches the probes to the file at path, as seen from the
+// gadget pod.
+type AttachFunc func(path string) (Probes, error)
+
+// fileID identifies the binary of a container.
+type fileID struct {
+	dev uint64
+	ino uint64
+}
+
+type attached struct {
+	probes Probes
+
+	// refs is the number of containers using the file.
+	refs int
+}
+
+// Attacher keeps track of the files the probes are attached to for the
+// containers.
+type Attacher struct {
+	binary string
+	attach AttachFunc
+
+	mu sync.Mutex
+
+	// files are the probes attached to each binary.
+	files map[fileID]*attached
+
+	// containers are the binaries used by each container, by mount
+	// namespace.
+	containers map[uint64]fileID
+}
+
+// NewAttacher returns an attacher calling attach for the binary at the
+// given path in the containers.
+func NewAttacher(binary string, attach AttachFunc) *Attacher {
+	return &Attacher{
+		binary:     binary,
+		attach:     attach,
+		files:      make(map[fileID]*attached),
+		containers: make(map[uint64]fileID),
+	}
+}
+
+// AddContainer attaches the probes to the binary of the container, found
+// from its pid, if they aren't attached to the same file yet. It returns
+// ErrBinaryNotFound if the container doesn't have the binary.
+func (a *Attacher) AddContainer(pid uint32, mntns uint64) error {
+	path, err := containerutils.ResolvePathInRoot(fmt.Sprintf("/proc/%d/root", pid), a.binary)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrBinaryNotFound
+	} else if err != nil {
+		return err
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !fi.Mode().IsRegular() {
+		return fmt.Errorf("%s is not a regular file", a.binary)
+	}
+	stat, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fmt.Errorf("cannot get the inode of %s", a.binary)
+	}
+	id := fileID{dev: uint64(stat.Dev), ino: stat.Ino}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, ok := a.containers[mntns]; ok {
+		return nil
+	}
+
+	if f, ok := a.files[id]; ok {
+		f.refs++
+		a.containers[mntns] = id
+		return nil
+	}
+
+	probes, err := a.attach(path)
+	if err != nil {
+		return err
+	}
+
+	a.files[id] = &attached{probes: probes, refs: 1}
+	a.containers[mntns] = id
+
+	return nil
+}
+
+// RemoveContainer detaches the probes of the binary of the container when
+// no other container uses it.
+func (a *Attacher) RemoveContainer(mntns uint64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	id, ok := a.containers[mntns]
+	if !ok {
+		return
+	}
+	delete(a.containers, mntns)
+
+	f := a.files[id]
+	f.refs--
+	if f.refs == 0 {
+		f.probes.Close()
+		delete(a.files, id)
+	}
+}
+
+// Attached returns the number of binaries the probes are attached to.
+func (a *Attacher) Attached() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return len(a.files)
+}
+
+// Close detaches all the probes.
+func (a *Attacher) Close() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for id, f := range a.files {
+		f.probes.Close()
+		delete(a.files, id)
+	}
+	a.containers = make(map[uint64]fileID)
+}
@@ -0,0 +1,90 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package containerbinary
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type fakeProbes struct {
+	closed *int
+}
+
+func (p fakeProbes) Close() {
+	*p.closed++
+}
+
+func TestAttacher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "containerbinary")
+	if err != nil {
+		t.Fatalf("creating directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	binary := filepath.Join(dir, "binary")
+	if err := ioutil.WriteFile(binary, nil, 0o755); err != nil {
+		t.Fatalf("creating binary: %s", err)
+	}
+
+	attached, closed := 0, 0
+	a := NewAttacher(binary, func(path string) (Probes, error) {
+		attached++
+		return fakeProbes{closed: &closed}, nil
+	})
+
+	// The containers of the tests are all this process, so they use the
+	// same file.
+	pid := uint32(os.Getpid())
+	for _, mntns := range []uint64{1, 2, 2} {
+		if err := a.AddContainer(pid, mntns); err != nil {
+			t.Fatalf("adding container %d: %s", mntns, err)
+		}
+	}
+	if attached != 1 || a.Attached() != 1 {
+		t.Fatalf("expected the probes to be attached once, got %d attach calls, %d attached", attached, a.Attached())
+	}
+
+	a.RemoveContainer(1)
+	if closed != 0 {
+		t.Fatalf("the probes were detached while still used")
+	}
+	a.RemoveContainer(2)
+	if closed != 1 || a.Attached() != 0 {
+		t.Fatalf("expected the probes to be detached, got %d close calls, %d attached", closed, a.Attached())
+	}
+
+	// Removing an unknown container is ignored.
+	a.RemoveContainer(3)
+
+	if err := a.AddContainer(pid, 4); err != nil {
+		t.Fatalf("adding container: %s", err)
+	}
+	a.Close()
+	if closed != 2 || a.Attached() != 0 {
+		t.Fatalf("expected the probes to be detached by Close, got %d close calls, %d attached", closed, a.Attached())
+	}
+
+	other := NewAttacher(filepath.Join(dir, "missing"), func(path string) (Probes, error) {
+		t.Fatalf("unexpected attach to %s", path)
+		return nil, nil
+	})
+	if err := other.AddContainer(pid, 1); !errors.Is(err, ErrBinaryNotFound) {
+		t.Fatalf("expected %v, got %v", ErrBinaryNotFound, err)
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgets
+
+import (
+	"strings"
+
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+)
+
+const (
+	// CreatorAnnotation is the name of the user who created the Trace, as
+	// authenticated by the API server. The creator webhook of the gadget
+	// pods sets it when the Trace is created and keeps it unchanged
+	// afterwards.
+	CreatorAnnotation = "gadget.kinvolk.io/creator"
+
+	// CreatorGroupsAnnotation is the comma-separated list of the groups
+	// of the user who created the Trace.
+	CreatorGroupsAnnotation = "gadget.kinvolk.io/creator-groups"
+
+	// TraceVerb is the RBAC verb the creator of a Trace must have on the
+	// pods of the namespaces it traces when the gadget pods are deployed
+	// with the authorization of the traces.
+	TraceVerb = "gadget.kinvolk.io/trace"
+)
+
+// Creator returns the user who created trace, as recorded in its
+// annotations. The username is empty if it wasn't recorded.
+func Creator(trace *gadgetv1alpha1.Trace) (username string, groups []string) {
+	username = trace.ObjectMeta.Annotations[CreatorAnnotation]
+	if val := trace.ObjectMeta.Annotations[CreatorGroupsAnnotation]; val != "" {
+		groups = strings.Split(val, ",")
+	}
+	return username, groups
+}
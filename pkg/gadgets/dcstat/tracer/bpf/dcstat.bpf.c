@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: GPL-2.0
+// Copyright (c) 2022 The Inspektor Gadget authors
+// Based on dcstat(8) from BCC by Brendan Gregg.
+#include <vmlinux/vmlinux.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_tracing.h>
+#include "dcstat.h"
+
+/* The lookups are counted by mount namespace, i.e. by container. */
+#define MAX_ENTRIES	10240
+
+enum stat_kind {
+	REFS,
+	SLOW,
+	MISSES,
+};
+
+const volatile pid_t target_pid = 0;
+const volatile bool filter_by_mnt_ns = false;
+static struct dc_stat zero_value = {};
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, u64);
+	__type(value, struct dc_stat);
+} entries SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, 1024);
+	__uint(key_size, sizeof(u64));
+	__uint(value_size, sizeof(u32));
+} mount_ns_set SEC(".maps");
+
+static __always_inline int count(enum stat_kind kind)
+{
+	__u32 pid = bpf_get_current_pid_tgid() >> 32;
+	struct dc_stat *valuep;
+	struct task_struct *task;
+	u64 mntns_id;
+
+	if (target_pid && target_pid != pid)
+		return 0;
+
+	task = (struct task_struct*)bpf_get_current_task();
+	mntns_id = (u64) BPF_CORE_READ(task, nsproxy, mnt_ns, ns.inum);
+
+	if (filter_by_mnt_ns && !bpf_map_lookup_elem(&mount_ns_set, &mntns_id))
+		return 0;
+
+	valuep = bpf_map_lookup_elem(&entries, &mntns_id);
+	if (!valuep) {
+		bpf_map_update_elem(&entries, &mntns_id, &zero_value, BPF_NOEXIST);
+		valuep = bpf_map_lookup_elem(&entries, &mntns_id);
+		if (!valuep)
+			return 0;
+	}
+
+	switch (kind) {
+	case REFS:
+		__sync_fetch_and_add(&valuep->refs, 1);
+		break;
+	case SLOW:
+		__sync_fetch_and_add(&valuep->slow, 1);
+		break;
+	case MISSES:
+		__sync_fetch_and_add(&valuep->misses, 1);
+		break;
+	}
+
+	return 0;
+}
+
+/* lookup_fast() is called for each component of the paths walked. */
+SEC("kprobe/lookup_fast")
+int BPF_KPROBE(ig_lookup_fast_e)
+{
+	return count(REFS);
+}
+
+SEC("kprobe/d_lookup")
+int BPF_KPROBE(ig_d_lookup_e)
+{
+	return count(SLOW);
+}
+
+/* d_lookup() returns NULL when the dentry isn't in the cache: the
+ * filesystem has to look it up. */
+SEC("kretprobe/d_lookup")
+int BPF_KRETPROBE(ig_d_lookup_x, struct dentry *ret)
+{
+	if (ret)
+		return 0;
+
+	return count(MISSES);
+}
+
+char LICENSE[] SEC("license") = "GPL";
@@ -0,0 +1,14 @@
+/* SPDX-License-Identifier: (LGPL-2.1 OR BSD-2-Clause) */
+#ifndef __DCSTAT_H
+#define __DCSTAT_H
+
+struct dc_stat {
+	/* Path components looked up in the dentry cache. */
+	__u64 refs;
+	/* Lookups which missed the lockless fast path and took d_lookup(). */
+	__u64 slow;
+	/* Lookups d_lookup() didn't find in the cache either. */
+	__u64 misses;
+};
+
+#endif /* __DCSTAT_H */
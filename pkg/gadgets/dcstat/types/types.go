@@ -0,0 +1,153 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"fmt"
+	"sort"
+)
+
+type SortBy int
+
+const (
+	REFS SortBy = iota
+	SLOW
+	MISSES
+	RATIO
+)
+
+const (
+	MaxRowsDefault  = 20
+	IntervalDefault = 1
+	SortByDefault   = REFS
+)
+
+const (
+	IntervalParam = "interval"
+	MaxRowsParam  = "max_rows"
+	SortByParam   = "sort_by"
+	PidParam      = "pid"
+)
+
+var SortBySlice = []string{
+	"refs",
+	"slow",
+	"misses",
+	"ratio",
+}
+
+func (s SortBy) String() string {
+	if int(s) < 0 || int(s) >= len(SortBySlice) {
+		return "INVALID"
+	}
+
+	return SortBySlice[int(s)]
+}
+
+func ParseSortBy(sortby string) (SortBy, error) {
+	for i, v := range SortBySlice {
+		if v == sortby {
+			return SortBy(i), nil
+		}
+	}
+	return REFS, fmt.Errorf("%q is not a valid sort by value", sortby)
+}
+
+// Event is the information the gadget sends to the client each capture
+// interval
+type Event struct {
+	Error string `json:"error,omitempty"`
+
+	// Warning is set when rows crossed the thresholds during the interval
+	// and the warnings are enabled.
+	Warning string `json:"warning,omitempty"`
+
+	// Node where the event comes from.
+	Node string `json:"node,omitempty"`
+
+	// Timestamp is when the interval ended, in nanoseconds since the
+	// epoch.
+	Timestamp int64 `json:"timestamp,omitempty"`
+
+	Stats []Stats `json:"stats,omitempty"`
+}
+
+// Stats represents the dentry cache lookups made by a single container,
+// i.e. a mount namespace, during the interval
+type Stats struct {
+	Node      string `json:"node,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Pod       string `json:"pod,omitempty"`
+	Container string `json:"container,omitempty"`
+
+	MountNsID uint64 `json:"mountnsid,omitempty"`
+
+	// Refs is the number of path components looked up in the dentry
+	// cache.
+	Refs uint64 `json:"refs,omitempty"`
+	// Slow is the number of lookups which took the slow path, d_lookup().
+	Slow uint64 `json:"slow,omitempty"`
+	// Misses is the number of lookups not found in the dentry cache,
+	// which the filesystem had to resolve.
+	Misses uint64 `json:"misses,omitempty"`
+
+	// Ratio is the percentage of the lookups hitting the dentry cache.
+	Ratio float64 `json:"ratio"`
+
+	// Alerts are the thresholds crossed by the row during the interval.
+	Alerts []string `json:"alerts,omitempty"`
+}
+
+// SetCounters sets the counters and computes the hit ratio, like the
+// dcstat tool of BCC: the lookups not missed are hits.
+func (s *Stats) SetCounters(refs, slow, misses uint64) {
+	// The counters are read while they are updated: the miss of a
+	// lookup can be counted before its ref.
+	if refs < misses {
+		refs = misses
+	}
+
+	s.Refs = refs
+	s.Slow = slow
+	s.Misses = misses
+
+	s.Ratio = 0
+	if refs > 0 {
+		s.Ratio = float64(refs-misses) / float64(refs) * 100
+	}
+}
+
+func SortStats(stats []Stats, sortBy SortBy) {
+	sort.Slice(stats, func(i, j int) bool {
+		a := stats[i]
+		b := stats[j]
+
+		switch sortBy {
+		case SLOW:
+			return a.Slow > b.Slow
+		case MISSES:
+			return a.Misses > b.Misses
+		case RATIO:
+			// The containers missing the dentry cache first, the
+			// ones without lookups last.
+			if (a.Refs == 0) != (b.Refs == 0) {
+				return b.Refs == 0
+			}
+			return a.Ratio < b.Ratio
+		default:
+			return a.Refs > b.Refs
+		}
+	})
+}
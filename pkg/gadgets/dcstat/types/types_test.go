@@ -0,0 +1,84 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"testing"
+)
+
+func TestSetCounters(t *testing.T) {
+	tests := []struct {
+		name               string
+		refs, slow, misses uint64
+		expectedRefs       uint64
+		ratio              float64
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:         "hits only",
+			refs:         100,
+			slow:         10,
+			expectedRefs: 100,
+			ratio:        100,
+		},
+		{
+			name:         "hits and misses",
+			refs:         100,
+			slow:         40,
+			misses:       25,
+			expectedRefs: 100,
+			ratio:        75,
+		},
+		{
+			name:         "miss counted before its ref",
+			misses:       2,
+			expectedRefs: 2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var s Stats
+			s.SetCounters(test.refs, test.slow, test.misses)
+
+			if s.Refs != test.expectedRefs || s.Slow != test.slow || s.Misses != test.misses {
+				t.Fatalf("got %d refs, %d slow and %d misses, expected %d, %d and %d",
+					s.Refs, s.Slow, s.Misses, test.expectedRefs, test.slow, test.misses)
+			}
+			if s.Ratio != test.ratio {
+				t.Fatalf("got a ratio of %f, expected %f", s.Ratio, test.ratio)
+			}
+		})
+	}
+}
+
+func TestSortStats(t *testing.T) {
+	stats := []Stats{
+		{Container: "idle"},
+		{Container: "cached", Refs: 100, Ratio: 100},
+		{Container: "scanning", Refs: 100, Misses: 90, Ratio: 10},
+	}
+
+	SortStats(stats, RATIO)
+
+	expected := []string{"scanning", "cached", "idle"}
+	for i, s := range stats {
+		if s.Container != expected[i] {
+			t.Fatalf("got %q at %d, expected %q", s.Container, i, expected[i])
+		}
+	}
+}
@@ -0,0 +1,49 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"testing"
+)
+
+// The fuzz targets are run with Go 1.18 or later:
+// go test -fuzz=FuzzParseDNSEvent ./pkg/gadgets/dns/tracer/
+
+func FuzzParseDNSEvent(f *testing.F) {
+	event := make([]byte, 258)
+	copy(event, []byte{3, 'w', 'w', 'w', 7, 'k', 'i', 'n', 'v', 'o', 'l', 'k', 2, 'i', 'o', 0})
+	f.Add(event)
+	f.Add([]byte{3, 'w', 'w', 'w', 255})
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, raw []byte) {
+		name, _, _, err := ParseDNSEvent(raw)
+		if err != nil {
+			return
+		}
+		// Each byte of the name is at most escaped as \DDD.
+		if len(name) > 4*len(raw) {
+			t.Fatalf("name %q too long for an event of %d bytes", name, len(raw))
+		}
+		for _, c := range []byte(name) {
+			if c <= ' ' || c > '~' {
+				t.Fatalf("name %q contains the unprintable byte %d", name, c)
+			}
+		}
+	})
+}
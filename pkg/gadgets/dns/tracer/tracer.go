@@ -18,12 +18,14 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"sync"
 	"syscall"
 	"unsafe"
 
 	"github.com/cilium/ebpf"
 	"github.com/cilium/ebpf/perf"
+	log "github.com/sirupsen/logrus"
 	"golang.org/x/sys/unix"
 
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
@@ -234,28 +236,29 @@ var qTypeNames = map[uint]string{
 	32769: "DLV",
 }
 
-func parseDNSEvent(rawSample []byte) (ret string, pktType string, qType string) {
-	// Convert name into a string with dots
-	name := make([]byte, C.MAX_DNS_NAME)
-	copy(name, rawSample)
+// maxDNSLabelLen is the maximum length of a label of a DNS name, see
+// RFC 1035 section 2.3.4.
+const maxDNSLabelLen = 63
+
+// ParseDNSEvent parses an event sent by the BPF program: the name queried,
+// in the wire format of DNS, followed by the packet type and the query
+// type. The events come from the packets seen on the network, so they are
+// parsed strictly: an error is returned if the name is malformed, and the
+// bytes of the labels which aren't printable are escaped as \DDD.
+func ParseDNSEvent(rawSample []byte) (name string, pktType string, qType string, err error) {
+	var event C.struct_event_t
+	if len(rawSample) < int(unsafe.Sizeof(event)) {
+		return "", "", "", fmt.Errorf("event too short: %d bytes", len(rawSample))
+	}
 
-	for i := 0; i < C.MAX_DNS_NAME; i++ {
-		length := int(name[i])
-		if length == 0 {
-			break
-		}
-		if i+1+length < C.MAX_DNS_NAME {
-			ret += string(name[i+1:i+1+length]) + "."
-		}
-		i += length
+	name, err = ParseDNSName(rawSample[:C.MAX_DNS_NAME])
+	if err != nil {
+		return "", "", "", err
 	}
 
 	// Parse the packet type
-	pktType = "UNKNOWN"
 	dnsEvent := (*C.struct_event_t)(unsafe.Pointer(&rawSample[0]))
-	if len(rawSample) < int(unsafe.Sizeof(*dnsEvent)) {
-		return
-	}
+	pktType = "UNKNOWN"
 	pktTypeUint := uint(dnsEvent.pkt_type)
 	if pktTypeUint < uint(len(pktTypeNames)) {
 		pktType = pktTypeNames[pktTypeUint]
@@ -267,7 +270,41 @@ func parseDNSEvent(rawSample []byte) (ret string, pktType string, qType string)
 		qType = "UNASSIGNED"
 	}
 
-	return
+	return name, pktType, qType, nil
+}
+
+// ParseDNSName converts a name in the wire format of DNS, a sequence of
+// labels prefixed by their length and terminated by an empty one, into a
+// string with dots. Compression pointers aren't supported, as the name is
+// copied by the BPF program without the rest of the packet.
+func ParseDNSName(b []byte) (string, error) {
+	var sb strings.Builder
+
+	for i := 0; i < len(b); {
+		length := int(b[i])
+		if length == 0 {
+			return sb.String(), nil
+		}
+		if length > maxDNSLabelLen {
+			return "", fmt.Errorf("invalid label length %d at offset %d", length, i)
+		}
+		if i+1+length > len(b) {
+			return "", fmt.Errorf("label at offset %d exceeds the name", i)
+		}
+
+		for _, c := range b[i+1 : i+1+length] {
+			if c <= ' ' || c > '~' || c == '.' || c == '\\' {
+				fmt.Fprintf(&sb, "\\%03d", c)
+				continue
+			}
+			sb.WriteByte(c)
+		}
+		sb.WriteByte('.')
+
+		i += 1 + length
+	}
+
+	return "", errors.New("name not terminated")
 }
 
 func (t *Tracer) listen(
@@ -294,7 +331,11 @@ func (t *Tracer) listen(
 			continue
 		}
 
-		name, pktType, qType := parseDNSEvent(record.RawSample)
+		name, pktType, qType, err := ParseDNSEvent(record.RawSample)
+		if err != nil {
+			log.Debugf("Ignoring malformed DNS event (%s): %s", key, err)
+			continue
+		}
 
 		// TODO: Ideally, messages with name=="" should not be emitted
 		// by the BPF program (see TODO in dns.c).
@@ -31,6 +31,7 @@ func TestParsing(t *testing.T) {
 	table := []struct {
 		input  []byte
 		output string
+		err    bool
 	}{
 		{
 			input: []byte{
@@ -46,9 +47,34 @@ func TestParsing(t *testing.T) {
 		{
 			input: []byte{
 				3, 'w', 'w', 'w',
-				255, // overflow
+				5, 'a', 'b', // overflow
 			},
-			output: "www.",
+			err: true,
+		},
+		{
+			input: []byte{
+				3, 'w', 'w', 'w',
+				64, // longer than allowed by RFC 1035
+			},
+			err: true,
+		},
+		{
+			input: []byte{
+				3, 'w', 'w', 'w',
+				// not terminated
+			},
+			err: true,
+		},
+		{
+			input: []byte{
+				4, 'a', '.', 0x1b, '\\',
+				0,
+			},
+			output: "a\\046\\027\\092.",
+		},
+		{
+			input:  []byte{0},
+			output: "",
 		},
 		{
 			input: append(word250,
@@ -61,9 +87,38 @@ func TestParsing(t *testing.T) {
 	}
 
 	for _, entry := range table {
-		output, _, _ := parseDNSEvent(entry.input)
+		output, err := ParseDNSName(entry.input)
+		if entry.err {
+			if err == nil {
+				t.Fatalf("Parsing %v: expected an error, got %q", entry.input, output)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Failed to parse DNS string %v: %s", entry.input, err)
+		}
 		if output != entry.output {
 			t.Fatalf("Failed to parse DNS string: got %q, expected %q", output, entry.output)
 		}
 	}
 }
+
+func TestParseDNSEvent(t *testing.T) {
+	for _, raw := range [][]byte{nil, {3, 'w', 'w', 'w', 0}} {
+		if _, _, _, err := ParseDNSEvent(raw); err == nil {
+			t.Fatalf("Parsing %v: expected an error for a short event", raw)
+		}
+	}
+
+	raw := make([]byte, 258)
+	copy(raw, []byte{3, 'w', 'w', 'w', 0})
+	raw[255] = 4  // OUTGOING
+	raw[256] = 28 // AAAA
+	name, pktType, qType, err := ParseDNSEvent(raw)
+	if err != nil {
+		t.Fatalf("Failed to parse DNS event: %s", err)
+	}
+	if name != "www." || pktType != "OUTGOING" || qType != "AAAA" {
+		t.Fatalf("Failed to parse DNS event: got %q %q %q", name, pktType, qType)
+	}
+}
@@ -0,0 +1,233 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package egressaudit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	containerutils "github.com/kinvolk/inspektor-gadget/pkg/container-utils"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/egressaudit/tracer"
+	pb "github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/api"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/pubsub"
+)
+
+type Trace struct {
+	resolver gadgets.Resolver
+
+	started bool
+	tracer  *tracer.Tracer
+
+	netnsHost uint64
+
+	mu sync.Mutex
+	// problems are the pods which couldn't be audited, reported when the
+	// trace is stopped.
+	problems map[string]string
+}
+
+type TraceFactory struct {
+	gadgets.BaseFactory
+
+	netnsHost uint64
+}
+
+func NewFactory() gadgets.TraceFactory {
+	netnsHost, _ := containerutils.GetNetNs(os.Getpid())
+	return &TraceFactory{
+		BaseFactory: gadgets.BaseFactory{DeleteTrace: deleteTrace},
+		netnsHost:   netnsHost,
+	}
+}
+
+func (f *TraceFactory) Description() string {
+	return `The egress-audit gadget records the destinations contacted by the pods and,
+when it is stopped, reports them by workload with their ports, protocols, the
+DNS names resolved by the pods and the bytes exchanged. The destinations
+inside the cluster are filtered out by kubectl gadget advise egress-audit.`
+}
+
+func (f *TraceFactory) OutputModesSupported() map[string]struct{} {
+	return map[string]struct{}{
+		"Status": {},
+	}
+}
+
+func deleteTrace(name string, t interface{}) {
+	trace := t.(*Trace)
+	if trace.started {
+		trace.resolver.Unsubscribe(genPubSubKey(name))
+		trace.tracer.Close()
+		trace.tracer = nil
+	}
+}
+
+func (f *TraceFactory) Operations() map[string]gadgets.TraceOperation {
+	n := func() interface{} {
+		return &Trace{
+			resolver:  f.Resolver,
+			netnsHost: f.netnsHost,
+		}
+	}
+
+	return map[string]gadgets.TraceOperation{
+		"start": {
+			Doc: "Start recording the destinations contacted by the pods",
+			Operation: func(name string, trace *gadgetv1alpha1.Trace) {
+				f.LookupOrCreate(name, n).(*Trace).Start(trace)
+			},
+		},
+		"stop": {
+			Doc: "Stop recording and store the destinations",
+			Operation: func(name string, trace *gadgetv1alpha1.Trace) {
+				f.LookupOrCreate(name, n).(*Trace).Stop(trace)
+			},
+		},
+	}
+}
+
+type pubSubKey string
+
+func genPubSubKey(name string) pubSubKey {
+	return pubSubKey(fmt.Sprintf("gadget/egress-audit/%s", name))
+}
+
+// workload returns the top-level owner of the pod of a container, or the
+// pod itself.
+func workload(container *pb.ContainerDefinition) string {
+	if owner := container.OwnerReference; owner != nil && owner.Kind != "" {
+		return owner.Kind + "/" + owner.Name
+	}
+	return "Pod/" + container.Podname
+}
+
+func (t *Trace) addProblem(container *pb.ContainerDefinition, problem string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.problems[container.Namespace+"/"+container.Podname] = problem
+}
+
+func (t *Trace) Start(trace *gadgetv1alpha1.Trace) {
+	if t.started {
+		trace.Status.State = "Started"
+		return
+	}
+
+	t.tracer = tracer.NewTracer()
+	t.problems = make(map[string]string)
+
+	attachContainerFunc := func(container *pb.ContainerDefinition) {
+		if container.Netns == t.netnsHost {
+			t.addProblem(container, "uses the host network")
+			return
+		}
+
+		errorCallback := func(err error) {
+			log.Warnf("egress-audit: pod %s/%s: %s", container.Namespace, container.Podname, err)
+			t.addProblem(container, err.Error())
+		}
+
+		err := t.tracer.Attach(container.Namespace, container.Podname,
+			workload(container), container.Pid, errorCallback)
+		if err != nil {
+			errorCallback(err)
+		}
+	}
+
+	detachContainerFunc := func(container *pb.ContainerDefinition) {
+		if container.Netns == t.netnsHost {
+			return
+		}
+		if err := t.tracer.Detach(container.Namespace, container.Podname); err != nil {
+			log.Debugf("egress-audit: %s", err)
+		}
+	}
+
+	containerEventCallback := func(event pubsub.PubSubEvent) {
+		switch event.Type {
+		case pubsub.EventTypeAddContainer:
+			attachContainerFunc(&event.Container)
+		case pubsub.EventTypeRemoveContainer:
+			detachContainerFunc(&event.Container)
+		}
+	}
+
+	existingContainers := t.resolver.Subscribe(
+		genPubSubKey(trace.ObjectMeta.Namespace+"/"+trace.ObjectMeta.Name),
+		*gadgets.ContainerSelectorFromContainerFilter(trace.Spec.Filter),
+		containerEventCallback,
+	)
+
+	for _, c := range existingContainers {
+		attachContainerFunc(c)
+	}
+
+	t.started = true
+
+	trace.Status.Output = ""
+	trace.Status.State = "Started"
+}
+
+func (t *Trace) Stop(trace *gadgetv1alpha1.Trace) {
+	if !t.started {
+		trace.Status.OperationError = "Not started"
+		return
+	}
+
+	t.resolver.Unsubscribe(genPubSubKey(trace.ObjectMeta.Namespace + "/" + trace.ObjectMeta.Name))
+	t.tracer.Close()
+	destinations, dropped := t.tracer.Destinations()
+	t.tracer = nil
+	t.started = false
+
+	warnings := []string{}
+	if len(destinations) == 0 {
+		warnings = append(warnings, "No traffic observed in the pods matching the requested filter")
+	}
+	if dropped > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d destinations weren't recorded: too many destinations", dropped))
+	}
+
+	t.mu.Lock()
+	pods := make([]string, 0, len(t.problems))
+	for pod := range t.problems {
+		pods = append(pods, pod)
+	}
+	sort.Strings(pods)
+	for _, pod := range pods {
+		warnings = append(warnings, fmt.Sprintf("pod %s wasn't fully audited: %s", pod, t.problems[pod]))
+	}
+	t.mu.Unlock()
+
+	trace.Status.OperationWarning = strings.Join(warnings, "; ")
+
+	output, err := json.Marshal(destinations)
+	if err != nil {
+		trace.Status.OperationError = fmt.Sprintf("failed marshalling destinations: %s", err)
+		return
+	}
+
+	trace.Status.Output = string(output)
+	trace.Status.State = "Completed"
+}
@@ -0,0 +1,135 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"strings"
+)
+
+const (
+	dnsHeaderLen = 12
+
+	dnsTypeA    = 1
+	dnsTypeAAAA = 28
+
+	// maxDNSPointers bounds the compression pointers followed while
+	// reading a name, to stop on loops.
+	maxDNSPointers = 16
+)
+
+var errDNSTruncated = errors.New("truncated DNS message")
+
+// readDNSName reads the name at off in msg, following the compression
+// pointers. It returns the name and the offset after it in msg.
+func readDNSName(msg []byte, off int) (string, int, error) {
+	var labels []string
+	next := -1
+	pointers := 0
+
+	for {
+		if off >= len(msg) {
+			return "", 0, errDNSTruncated
+		}
+		length := int(msg[off])
+
+		switch length & 0xc0 {
+		case 0x00:
+			if length == 0 {
+				if next == -1 {
+					next = off + 1
+				}
+				return strings.Join(labels, "."), next, nil
+			}
+			if off+1+length > len(msg) {
+				return "", 0, errDNSTruncated
+			}
+			labels = append(labels, string(msg[off+1:off+1+length]))
+			off += 1 + length
+		case 0xc0:
+			if off+2 > len(msg) {
+				return "", 0, errDNSTruncated
+			}
+			pointers++
+			if pointers > maxDNSPointers {
+				return "", 0, errors.New("too many compression pointers")
+			}
+			if next == -1 {
+				next = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(msg[off:off+2]) & 0x3fff)
+		default:
+			return "", 0, errors.New("invalid label type")
+		}
+	}
+}
+
+// parseDNSAnswers parses a DNS response and returns the name of its
+// question and the IPv4 and IPv6 addresses of its answers. The addresses
+// at the end of a chain of CNAME records are given for the question too.
+func parseDNSAnswers(msg []byte) (string, []net.IP, error) {
+	if len(msg) < dnsHeaderLen {
+		return "", nil, errDNSTruncated
+	}
+
+	flags := binary.BigEndian.Uint16(msg[2:4])
+	qdcount := binary.BigEndian.Uint16(msg[4:6])
+	ancount := binary.BigEndian.Uint16(msg[6:8])
+
+	// Only the responses without error to a single question
+	if flags&0x8000 == 0 || flags&0x000f != 0 || qdcount != 1 {
+		return "", nil, nil
+	}
+
+	question, off, err := readDNSName(msg, dnsHeaderLen)
+	if err != nil {
+		return "", nil, err
+	}
+	// QTYPE and QCLASS
+	off += 4
+
+	addresses := []net.IP{}
+	for i := 0; i < int(ancount); i++ {
+		_, next, err := readDNSName(msg, off)
+		if err != nil {
+			return "", nil, err
+		}
+		off = next
+
+		// TYPE, CLASS, TTL and RDLENGTH
+		if off+10 > len(msg) {
+			return "", nil, errDNSTruncated
+		}
+		typ := binary.BigEndian.Uint16(msg[off : off+2])
+		rdlength := int(binary.BigEndian.Uint16(msg[off+8 : off+10]))
+		off += 10
+		if off+rdlength > len(msg) {
+			return "", nil, errDNSTruncated
+		}
+		rdata := msg[off : off+rdlength]
+		off += rdlength
+
+		switch {
+		case typ == dnsTypeA && rdlength == net.IPv4len:
+			addresses = append(addresses, net.IP(append([]byte{}, rdata...)))
+		case typ == dnsTypeAAAA && rdlength == net.IPv6len:
+			addresses = append(addresses, net.IP(append([]byte{}, rdata...)))
+		}
+	}
+
+	return question, addresses, nil
+}
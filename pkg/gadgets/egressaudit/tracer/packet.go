@@ -0,0 +1,117 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"encoding/binary"
+	"net"
+
+	"github.com/kinvolk/inspektor-gadget/pkg/rawsock"
+)
+
+const dnsPort = 53
+
+// packet is a TCP segment or UDP datagram captured on a raw socket. The
+// frames are truncated by the socket filter, except the DNS responses:
+// the length is taken from the IP header.
+type packet struct {
+	protocol uint8
+	saddr    net.IP
+	daddr    net.IP
+	sport    uint16
+	dport    uint16
+	tcpFlags uint8
+
+	// length is the length of the IP packet.
+	length int
+
+	// payload is the UDP payload, only set for the complete datagrams.
+	payload []byte
+}
+
+// parsePacket decodes the Ethernet, IP and TCP or UDP headers of a frame.
+// It returns false for the frames that aren't TCP or UDP over IPv4 or
+// IPv6 and for the IPv4 fragments not carrying the header of the
+// transport protocol. IPv6 extension headers are not supported.
+func parsePacket(frame []byte) (*packet, bool) {
+	if len(frame) < rawsock.EthernetHeaderLen {
+		return nil, false
+	}
+
+	p := &packet{}
+	var l4 []byte
+	var l4Len int
+
+	ip := frame[rawsock.EthernetHeaderLen:]
+	switch binary.BigEndian.Uint16(frame[12:14]) {
+	case rawsock.EtherTypeIPv4:
+		if len(ip) < 20 || ip[0]>>4 != 4 {
+			return nil, false
+		}
+		ihl := int(ip[0]&0x0f) * 4
+		p.length = int(binary.BigEndian.Uint16(ip[2:4]))
+		if ihl < 20 || p.length < ihl || len(ip) < ihl {
+			return nil, false
+		}
+		// Fragment offset
+		if binary.BigEndian.Uint16(ip[6:8])&0x1fff != 0 {
+			return nil, false
+		}
+		p.protocol = ip[9]
+		p.saddr = net.IP(ip[12:16])
+		p.daddr = net.IP(ip[16:20])
+		l4 = ip[ihl:]
+		l4Len = p.length - ihl
+	case rawsock.EtherTypeIPv6:
+		if len(ip) < rawsock.IPv6HeaderLen || ip[0]>>4 != 6 {
+			return nil, false
+		}
+		l4Len = int(binary.BigEndian.Uint16(ip[4:6]))
+		p.length = rawsock.IPv6HeaderLen + l4Len
+		p.protocol = ip[6]
+		p.saddr = net.IP(ip[8:24])
+		p.daddr = net.IP(ip[24:40])
+		l4 = ip[rawsock.IPv6HeaderLen:]
+	default:
+		return nil, false
+	}
+
+	if len(l4) > l4Len {
+		l4 = l4[:l4Len]
+	}
+
+	switch p.protocol {
+	case rawsock.ProtocolTCP:
+		if len(l4) < 14 {
+			return nil, false
+		}
+		p.tcpFlags = l4[13]
+	case rawsock.ProtocolUDP:
+		if len(l4) < 8 {
+			return nil, false
+		}
+		udpLen := int(binary.BigEndian.Uint16(l4[4:6]))
+		if udpLen >= 8 && udpLen <= len(l4) {
+			p.payload = l4[8:udpLen]
+		}
+	default:
+		return nil, false
+	}
+
+	p.sport = binary.BigEndian.Uint16(l4[0:2])
+	p.dport = binary.BigEndian.Uint16(l4[2:4])
+
+	return p, true
+}
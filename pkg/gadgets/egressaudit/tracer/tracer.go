@@ -0,0 +1,366 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"fmt"
+	"net"
+	"sync"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/egressaudit/types"
+	"github.com/kinvolk/inspektor-gadget/pkg/rawsock"
+)
+
+const (
+	// headerLen is the length of the frames copied to user space, except
+	// the DNS responses: enough for the Ethernet, IP and TCP or UDP
+	// headers.
+	headerLen = 128
+
+	// The limits of the state kept for each workload, so that the memory
+	// doesn't grow without bound with the pods talking to the whole
+	// internet.
+	maxFlows        = 16384
+	maxNames        = 4096
+	maxDestinations = 4096
+)
+
+// filter is a classic BPF program only accepting TCP and UDP over IPv4 or
+// IPv6. Only the headers are copied to user space, except for the DNS
+// responses which are parsed to get the names of the destinations.
+var filter = []unix.SockFilter{
+	// ldh [12] (EtherType)
+	{Code: unix.BPF_LD | unix.BPF_H | unix.BPF_ABS, K: 12},
+	// jeq #0x800, ipv4, next
+	{Code: unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K, Jt: 0, Jf: 5, K: rawsock.EtherTypeIPv4},
+	// ipv4: ldb [23] (protocol)
+	{Code: unix.BPF_LD | unix.BPF_B | unix.BPF_ABS, K: rawsock.EthernetHeaderLen + 9},
+	// jeq #17, ipv4udp, header
+	{Code: unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K, Jt: 0, Jf: 9, K: rawsock.ProtocolUDP},
+	// ipv4udp: ldxb 4*([14]&0xf) (IP header length)
+	{Code: unix.BPF_LDX | unix.BPF_B | unix.BPF_MSH, K: rawsock.EthernetHeaderLen},
+	// ldh [x + 14] (source port)
+	{Code: unix.BPF_LD | unix.BPF_H | unix.BPF_IND, K: rawsock.EthernetHeaderLen},
+	// jeq #53, all, header
+	{Code: unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K, Jt: 5, Jf: 6, K: dnsPort},
+	// next: jeq #0x86dd, ipv6, drop
+	{Code: unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K, Jt: 0, Jf: 6, K: rawsock.EtherTypeIPv6},
+	// ipv6: ldb [20] (next header)
+	{Code: unix.BPF_LD | unix.BPF_B | unix.BPF_ABS, K: rawsock.EthernetHeaderLen + 6},
+	// jeq #17, ipv6udp, header
+	{Code: unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K, Jt: 0, Jf: 3, K: rawsock.ProtocolUDP},
+	// ipv6udp: ldh [54] (source port)
+	{Code: unix.BPF_LD | unix.BPF_H | unix.BPF_ABS, K: rawsock.EthernetHeaderLen + rawsock.IPv6HeaderLen},
+	// jeq #53, all, header
+	{Code: unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K, Jt: 0, Jf: 1, K: dnsPort},
+	// all: ret #MaxFrameLen
+	{Code: unix.BPF_RET | unix.BPF_K, K: rawsock.MaxFrameLen},
+	// header: ret #headerLen
+	{Code: unix.BPF_RET | unix.BPF_K, K: headerLen},
+	// drop: ret #0
+	{Code: unix.BPF_RET | unix.BPF_K, K: 0},
+}
+
+// flowKey identifies a flow from the pod's point of view.
+type flowKey struct {
+	protocol   uint8
+	localPort  uint16
+	remoteAddr string
+	remotePort uint16
+}
+
+type destinationKey struct {
+	address  string
+	protocol uint8
+	port     uint16
+}
+
+// audit is the traffic of the pods of a workload on this node.
+type audit struct {
+	mu sync.Mutex
+
+	namespace string
+	workload  string
+
+	// flows tells if the flows were initiated by the pods
+	flows map[flowKey]bool
+
+	// names are the DNS names resolved by the pods, by address
+	names map[string]map[string]struct{}
+
+	destinations map[destinationKey]*types.Destination
+
+	// dropped counts the destinations not recorded because of
+	// maxDestinations.
+	dropped int
+}
+
+type link struct {
+	listener *rawsock.Listener
+
+	// users count how many containers of the pod called Attach()
+	users int
+}
+
+// Tracer records the destinations contacted by the pods from the packets
+// seen on raw sockets opened in their network namespaces. Unlike the
+// gadgets using eBPF, it works the same way for TCP and UDP and gets the
+// names of the destinations from the DNS responses received by the pods.
+type Tracer struct {
+	mu sync.Mutex
+
+	// key: namespace/podname
+	attachments map[string]*link
+
+	// key: namespace/workload. The audits are kept after the pods are
+	// detached to report their traffic.
+	audits map[string]*audit
+}
+
+func NewTracer() *Tracer {
+	return &Tracer{
+		attachments: make(map[string]*link),
+		audits:      make(map[string]*audit),
+	}
+}
+
+// Attach starts recording the traffic of the pod of a container. errorCallback is
+// called if the capture fails after being started.
+func (t *Tracer) Attach(namespace, pod, workload string, pid uint32, errorCallback func(error)) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := namespace + "/" + pod
+	if l, ok := t.attachments[key]; ok {
+		l.users++
+		return nil
+	}
+
+	listener, err := rawsock.NewListener(pid, filter)
+	if err != nil {
+		return err
+	}
+
+	auditKey := namespace + "/" + workload
+	a, ok := t.audits[auditKey]
+	if !ok {
+		a = &audit{
+			namespace:    namespace,
+			workload:     workload,
+			flows:        make(map[flowKey]bool),
+			names:        make(map[string]map[string]struct{}),
+			destinations: make(map[destinationKey]*types.Destination),
+		}
+		t.audits[auditKey] = a
+	}
+
+	l := &link{
+		listener: listener,
+		users:    1,
+	}
+	t.attachments[key] = l
+
+	go listen(l, a, errorCallback)
+
+	return nil
+}
+
+// listen records the frames received on the raw socket until the link is
+// released.
+func listen(l *link, a *audit, errorCallback func(error)) {
+	handleFrame := func(frame []byte, pktType uint8) {
+		var outgoing bool
+		switch pktType {
+		case unix.PACKET_OUTGOING:
+			outgoing = true
+		case unix.PACKET_HOST:
+			outgoing = false
+		default:
+			return
+		}
+
+		p, ok := parsePacket(frame)
+		if !ok {
+			return
+		}
+
+		a.record(p, outgoing)
+	}
+
+	if err := l.listener.Run(nil, handleFrame); err != nil {
+		errorCallback(err)
+	}
+}
+
+// record adds a packet to the audit if it belongs to a flow initiated by
+// the pod.
+func (a *audit) record(p *packet, outgoing bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key := flowKey{protocol: p.protocol}
+	var remoteAddr net.IP
+	if outgoing {
+		key.localPort, key.remotePort = p.sport, p.dport
+		remoteAddr = p.daddr
+	} else {
+		key.localPort, key.remotePort = p.dport, p.sport
+		remoteAddr = p.saddr
+	}
+	if remoteAddr.IsLoopback() {
+		return
+	}
+	key.remoteAddr = remoteAddr.String()
+
+	egress, ok := a.flows[key]
+	if !ok {
+		switch {
+		case p.protocol == rawsock.ProtocolTCP && p.tcpFlags&(rawsock.TCPFlagSyn|rawsock.TCPFlagAck) == rawsock.TCPFlagSyn:
+			egress = outgoing
+		case p.protocol == rawsock.ProtocolTCP && p.tcpFlags&(rawsock.TCPFlagSyn|rawsock.TCPFlagAck) == rawsock.TCPFlagSyn|rawsock.TCPFlagAck:
+			egress = !outgoing
+		case p.protocol == rawsock.ProtocolTCP:
+			// The connection was established before the audit:
+			// guess that the ephemeral port is the highest.
+			egress = key.remotePort < key.localPort
+		default:
+			// The first datagram of the flow
+			egress = outgoing
+		}
+
+		if len(a.flows) >= maxFlows {
+			a.flows = make(map[flowKey]bool)
+		}
+		a.flows[key] = egress
+	}
+
+	if !egress {
+		return
+	}
+
+	if !outgoing && p.protocol == rawsock.ProtocolUDP && p.sport == dnsPort && p.payload != nil {
+		a.recordDNSResponse(p.payload)
+	}
+
+	dstKey := destinationKey{address: key.remoteAddr, protocol: p.protocol, port: key.remotePort}
+	d, ok := a.destinations[dstKey]
+	if !ok {
+		if len(a.destinations) >= maxDestinations {
+			a.dropped++
+			return
+		}
+
+		protocol := types.ProtocolTCP
+		if p.protocol == rawsock.ProtocolUDP {
+			protocol = types.ProtocolUDP
+		}
+		d = &types.Destination{
+			Namespace: a.namespace,
+			Workload:  a.workload,
+			Address:   key.remoteAddr,
+			Protocol:  protocol,
+			Port:      key.remotePort,
+		}
+		a.destinations[dstKey] = d
+	}
+
+	if outgoing {
+		d.Sent += uint64(p.length)
+	} else {
+		d.Received += uint64(p.length)
+	}
+}
+
+// recordDNSResponse records the name of the addresses of a DNS response.
+// It must be called with a.mu held.
+func (a *audit) recordDNSResponse(msg []byte) {
+	name, addresses, err := parseDNSAnswers(msg)
+	if err != nil || name == "" {
+		return
+	}
+
+	for _, ip := range addresses {
+		address := ip.String()
+		names, ok := a.names[address]
+		if !ok {
+			if len(a.names) >= maxNames {
+				continue
+			}
+			names = make(map[string]struct{})
+			a.names[address] = names
+		}
+		names[name] = struct{}{}
+	}
+}
+
+// releaseLink stops the listener of the link. It must be called with t.mu
+// held.
+func (t *Tracer) releaseLink(key string, l *link) {
+	l.listener.Close()
+	delete(t.attachments, key)
+}
+
+func (t *Tracer) Detach(namespace, pod string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := namespace + "/" + pod
+	l, ok := t.attachments[key]
+	if !ok {
+		return fmt.Errorf("pod not attached: %q", key)
+	}
+
+	l.users--
+	if l.users == 0 {
+		t.releaseLink(key, l)
+	}
+	return nil
+}
+
+func (t *Tracer) Close() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for key, l := range t.attachments {
+		t.releaseLink(key, l)
+	}
+}
+
+// Destinations returns the destinations contacted by the pods, and the
+// number of the ones which weren't recorded because of the limits.
+func (t *Tracer) Destinations() ([]types.Destination, int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	dsts := []types.Destination{}
+	dropped := 0
+
+	for _, a := range t.audits {
+		a.mu.Lock()
+		for _, d := range a.destinations {
+			dst := *d
+			for name := range a.names[d.Address] {
+				dst.Names = append(dst.Names, name)
+			}
+			dsts = append(dsts, dst)
+		}
+		dropped += a.dropped
+		a.mu.Unlock()
+	}
+
+	return types.MergeDestinations(dsts), dropped
+}
@@ -0,0 +1,211 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"encoding/binary"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/egressaudit/types"
+	"github.com/kinvolk/inspektor-gadget/pkg/rawsock"
+)
+
+// ipv4Frame builds an Ethernet frame with an IPv4 packet.
+func ipv4Frame(protocol uint8, saddr, daddr string, l4 []byte) []byte {
+	frame := make([]byte, rawsock.EthernetHeaderLen+20)
+	binary.BigEndian.PutUint16(frame[12:14], rawsock.EtherTypeIPv4)
+	ip := frame[rawsock.EthernetHeaderLen:]
+	ip[0] = 0x45
+	binary.BigEndian.PutUint16(ip[2:4], uint16(20+len(l4)))
+	ip[9] = protocol
+	copy(ip[12:16], net.ParseIP(saddr).To4())
+	copy(ip[16:20], net.ParseIP(daddr).To4())
+	return append(frame, l4...)
+}
+
+func tcpSegment(sport, dport uint16, flags uint8, payloadLen int) []byte {
+	tcp := make([]byte, 20+payloadLen)
+	binary.BigEndian.PutUint16(tcp[0:2], sport)
+	binary.BigEndian.PutUint16(tcp[2:4], dport)
+	tcp[12] = 5 << 4
+	tcp[13] = flags
+	return tcp
+}
+
+func udpDatagram(sport, dport uint16, payload []byte) []byte {
+	udp := make([]byte, 8)
+	binary.BigEndian.PutUint16(udp[0:2], sport)
+	binary.BigEndian.PutUint16(udp[2:4], dport)
+	binary.BigEndian.PutUint16(udp[4:6], uint16(8+len(payload)))
+	return append(udp, payload...)
+}
+
+// dnsResponse builds a response to a query of name with a CNAME to alias
+// and A records for the addresses, using compression pointers.
+func dnsResponse(name, alias string, addresses ...string) []byte {
+	encode := func(name string) []byte {
+		b := []byte{}
+		for _, label := range splitLabels(name) {
+			b = append(b, byte(len(label)))
+			b = append(b, label...)
+		}
+		return append(b, 0)
+	}
+
+	msg := make([]byte, dnsHeaderLen)
+	binary.BigEndian.PutUint16(msg[2:4], 0x8180)
+	binary.BigEndian.PutUint16(msg[4:6], 1)
+	binary.BigEndian.PutUint16(msg[6:8], uint16(1+len(addresses)))
+
+	// Question
+	msg = append(msg, encode(name)...)
+	msg = append(msg, 0, 1, 0, 1)
+
+	// CNAME: pointer to the question, rdata is the alias
+	rdata := encode(alias)
+	aliasOff := len(msg) + 12
+	msg = append(msg, 0xc0, dnsHeaderLen, 0, 5, 0, 1, 0, 0, 0, 60, 0, byte(len(rdata)))
+	msg = append(msg, rdata...)
+
+	// A records: pointer to the alias
+	for _, address := range addresses {
+		msg = append(msg, 0xc0, byte(aliasOff), 0, dnsTypeA, 0, 1, 0, 0, 0, 60, 0, 4)
+		msg = append(msg, net.ParseIP(address).To4()...)
+	}
+
+	return msg
+}
+
+func splitLabels(name string) []string {
+	labels := []string{}
+	start := 0
+	for i := 0; i <= len(name); i++ {
+		if i == len(name) || name[i] == '.' {
+			labels = append(labels, name[start:i])
+			start = i + 1
+		}
+	}
+	return labels
+}
+
+func TestParsePacket(t *testing.T) {
+	p, ok := parsePacket(ipv4Frame(rawsock.ProtocolTCP, "10.0.0.1", "1.1.1.1", tcpSegment(40000, 443, rawsock.TCPFlagSyn, 0)))
+	if !ok {
+		t.Fatalf("TCP: not parsed")
+	}
+	if p.protocol != rawsock.ProtocolTCP || p.saddr.String() != "10.0.0.1" || p.daddr.String() != "1.1.1.1" ||
+		p.sport != 40000 || p.dport != 443 || p.tcpFlags != rawsock.TCPFlagSyn || p.length != 40 {
+		t.Fatalf("TCP: got %+v", p)
+	}
+
+	// Truncated by the socket filter: the length comes from the header
+	frame := ipv4Frame(rawsock.ProtocolTCP, "10.0.0.1", "1.1.1.1", tcpSegment(40000, 443, rawsock.TCPFlagAck, 1000))
+	p, ok = parsePacket(frame[:headerLen])
+	if !ok || p.length != 1040 {
+		t.Fatalf("Truncated TCP: got %+v, %t", p, ok)
+	}
+
+	p, ok = parsePacket(ipv4Frame(rawsock.ProtocolUDP, "10.96.0.10", "10.0.0.1", udpDatagram(53, 50000, []byte("data"))))
+	if !ok || p.protocol != rawsock.ProtocolUDP || string(p.payload) != "data" {
+		t.Fatalf("UDP: got %+v, %t", p, ok)
+	}
+
+	if _, ok := parsePacket(ipv4Frame(1, "10.0.0.1", "1.1.1.1", make([]byte, 8))); ok {
+		t.Fatalf("ICMP: expected not to be parsed")
+	}
+
+	if _, ok := parsePacket(frame[:rawsock.EthernetHeaderLen+10]); ok {
+		t.Fatalf("Short frame: expected not to be parsed")
+	}
+}
+
+func TestParseDNSAnswers(t *testing.T) {
+	name, addresses, err := parseDNSAnswers(dnsResponse("api.example.com", "lb.example.net", "93.184.216.34", "93.184.216.35"))
+	if err != nil {
+		t.Fatalf("parseDNSAnswers: %s", err)
+	}
+	if name != "api.example.com" {
+		t.Fatalf("got name %q", name)
+	}
+	if len(addresses) != 2 || addresses[0].String() != "93.184.216.34" || addresses[1].String() != "93.184.216.35" {
+		t.Fatalf("got addresses %v", addresses)
+	}
+
+	// Loop of compression pointers
+	msg := dnsResponse("api.example.com", "lb.example.net")
+	msg[dnsHeaderLen] = 0xc0
+	msg[dnsHeaderLen+1] = dnsHeaderLen
+	if _, _, err := parseDNSAnswers(msg); err == nil {
+		t.Fatalf("Pointer loop: expected an error")
+	}
+
+	msg = dnsResponse("api.example.com", "lb.example.net", "93.184.216.34")
+	if _, _, err := parseDNSAnswers(msg[:len(msg)-2]); err == nil {
+		t.Fatalf("Truncated message: expected an error")
+	}
+}
+
+func TestAuditRecord(t *testing.T) {
+	a := &audit{
+		namespace:    "default",
+		workload:     "Deployment/web",
+		flows:        make(map[flowKey]bool),
+		names:        make(map[string]map[string]struct{}),
+		destinations: make(map[destinationKey]*types.Destination),
+	}
+
+	record := func(frame []byte, outgoing bool) {
+		p, ok := parsePacket(frame)
+		if !ok {
+			t.Fatalf("frame not parsed")
+		}
+		a.record(p, outgoing)
+	}
+
+	// DNS query to the cluster DNS and its response
+	record(ipv4Frame(rawsock.ProtocolUDP, "10.244.0.5", "10.96.0.10", udpDatagram(50000, 53, make([]byte, 30))), true)
+	record(ipv4Frame(rawsock.ProtocolUDP, "10.96.0.10", "10.244.0.5", udpDatagram(53, 50000,
+		dnsResponse("api.example.com", "lb.example.net", "93.184.216.34"))), false)
+
+	// Connection initiated by the pod
+	record(ipv4Frame(rawsock.ProtocolTCP, "10.244.0.5", "93.184.216.34", tcpSegment(40000, 443, rawsock.TCPFlagSyn, 0)), true)
+	record(ipv4Frame(rawsock.ProtocolTCP, "93.184.216.34", "10.244.0.5", tcpSegment(443, 40000, rawsock.TCPFlagSyn|rawsock.TCPFlagAck, 0)), false)
+	record(ipv4Frame(rawsock.ProtocolTCP, "10.244.0.5", "93.184.216.34", tcpSegment(40000, 443, rawsock.TCPFlagAck, 100)), true)
+
+	// Connection accepted by the pod
+	record(ipv4Frame(rawsock.ProtocolTCP, "8.8.8.8", "10.244.0.5", tcpSegment(50000, 8080, rawsock.TCPFlagSyn, 0)), false)
+	record(ipv4Frame(rawsock.ProtocolTCP, "10.244.0.5", "8.8.8.8", tcpSegment(8080, 50000, rawsock.TCPFlagSyn|rawsock.TCPFlagAck, 0)), true)
+
+	// Connection established before the audit
+	record(ipv4Frame(rawsock.ProtocolTCP, "1.1.1.1", "10.244.0.5", tcpSegment(443, 41000, rawsock.TCPFlagAck, 10)), false)
+
+	tracer := NewTracer()
+	tracer.audits["default/Deployment/web"] = a
+	dsts, dropped := tracer.Destinations()
+	if dropped != 0 {
+		t.Fatalf("got %d dropped destinations", dropped)
+	}
+
+	expected := []types.Destination{
+		{Namespace: "default", Workload: "Deployment/web", Address: "1.1.1.1", Protocol: types.ProtocolTCP, Port: 443, Received: 50},
+		{Namespace: "default", Workload: "Deployment/web", Address: "10.96.0.10", Protocol: types.ProtocolUDP, Port: 53, Sent: 58, Received: 28 + uint64(len(dnsResponse("api.example.com", "lb.example.net", "93.184.216.34")))},
+		{Namespace: "default", Workload: "Deployment/web", Address: "93.184.216.34", Names: []string{"api.example.com"}, Protocol: types.ProtocolTCP, Port: 443, Sent: 180, Received: 40},
+	}
+	if !reflect.DeepEqual(dsts, expected) {
+		t.Fatalf("got %+v, expected %+v", dsts, expected)
+	}
+}
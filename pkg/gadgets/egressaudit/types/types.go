@@ -0,0 +1,203 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+)
+
+const (
+	ProtocolTCP = "TCP"
+	ProtocolUDP = "UDP"
+)
+
+// Destination is a remote endpoint contacted by the pods of a workload,
+// with the traffic exchanged with it during the audit.
+type Destination struct {
+	Namespace string `json:"namespace"`
+
+	// Workload is the top-level owner of the pods, e.g. Deployment/web,
+	// or Pod/<name> for the pods without owner.
+	Workload string `json:"workload"`
+
+	Address string `json:"address"`
+
+	// Names are the DNS names resolved to the address by the pods of the
+	// workload during the audit.
+	Names []string `json:"names,omitempty"`
+
+	Protocol string `json:"protocol"`
+	Port     uint16 `json:"port"`
+
+	// Sent and Received are the bytes of the IP packets exchanged with
+	// the destination.
+	Sent     uint64 `json:"sent"`
+	Received uint64 `json:"received"`
+}
+
+// Rules returns the destination as the rules of an egress firewall, by
+// DNS name when the names are known: name:port/protocol.
+func (d *Destination) Rules() []string {
+	hosts := d.Names
+	if len(hosts) == 0 {
+		hosts = []string{d.Address}
+	}
+
+	rules := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		rules = append(rules, fmt.Sprintf("%s/%s", net.JoinHostPort(host, strconv.Itoa(int(d.Port))), d.Protocol))
+	}
+	return rules
+}
+
+type destinationKey struct {
+	namespace string
+	workload  string
+	address   string
+	protocol  string
+	port      uint16
+}
+
+// MergeDestinations merges the destinations of the same workload reported
+// by several nodes, adding their traffic and joining their names. The
+// result is sorted by workload, address, protocol and port.
+func MergeDestinations(dsts []Destination) []Destination {
+	merged := map[destinationKey]*Destination{}
+	names := map[destinationKey]map[string]struct{}{}
+
+	for _, d := range dsts {
+		key := destinationKey{d.Namespace, d.Workload, d.Address, d.Protocol, d.Port}
+		m, ok := merged[key]
+		if !ok {
+			m = &Destination{
+				Namespace: d.Namespace,
+				Workload:  d.Workload,
+				Address:   d.Address,
+				Protocol:  d.Protocol,
+				Port:      d.Port,
+			}
+			merged[key] = m
+			names[key] = map[string]struct{}{}
+		}
+		m.Sent += d.Sent
+		m.Received += d.Received
+		for _, name := range d.Names {
+			names[key][name] = struct{}{}
+		}
+	}
+
+	out := make([]Destination, 0, len(merged))
+	for key, m := range merged {
+		for name := range names[key] {
+			m.Names = append(m.Names, name)
+		}
+		sort.Strings(m.Names)
+		out = append(out, *m)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		a, b := &out[i], &out[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Workload != b.Workload {
+			return a.Workload < b.Workload
+		}
+		if a.Address != b.Address {
+			return a.Address < b.Address
+		}
+		if a.Protocol != b.Protocol {
+			return a.Protocol < b.Protocol
+		}
+		return a.Port < b.Port
+	})
+
+	return out
+}
+
+// ClusterNetworks are the addresses belonging to the cluster: the ones of
+// the pods, services and nodes, and the CIDRs given by the user, e.g. the
+// pod and service CIDRs to include the addresses of the pods deleted
+// during the audit.
+type ClusterNetworks struct {
+	addresses map[string]struct{}
+	cidrs     []*net.IPNet
+}
+
+func NewClusterNetworks(cidrs []string) (*ClusterNetworks, error) {
+	c := &ClusterNetworks{
+		addresses: map[string]struct{}{},
+	}
+
+	for _, cidr := range cidrs {
+		_, ipnet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+		}
+		c.cidrs = append(c.cidrs, ipnet)
+	}
+
+	return c, nil
+}
+
+// AddAddress adds an address of a pod, service or node.
+func (c *ClusterNetworks) AddAddress(address string) {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return
+	}
+	c.addresses[ip.String()] = struct{}{}
+}
+
+// Contains tells if the address belongs to the cluster. The loopback,
+// link-local, multicast and unspecified addresses are considered as part
+// of the cluster as they never go through an egress gateway.
+func (c *ClusterNetworks) Contains(address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsMulticast() ||
+		ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+		return true
+	}
+
+	if _, ok := c.addresses[ip.String()]; ok {
+		return true
+	}
+
+	for _, ipnet := range c.cidrs {
+		if ipnet.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// External returns the destinations outside of the cluster.
+func External(dsts []Destination, cluster *ClusterNetworks) []Destination {
+	out := []Destination{}
+	for _, d := range dsts {
+		if !cluster.Contains(d.Address) {
+			out = append(out, d)
+		}
+	}
+	return out
+}
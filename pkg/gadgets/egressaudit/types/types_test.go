@@ -0,0 +1,89 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeDestinations(t *testing.T) {
+	dsts := []Destination{
+		{Namespace: "default", Workload: "Deployment/web", Address: "1.1.1.1", Protocol: ProtocolTCP, Port: 443, Sent: 10, Received: 100, Names: []string{"one.one.one.one"}},
+		{Namespace: "default", Workload: "Deployment/web", Address: "1.1.1.1", Protocol: ProtocolUDP, Port: 53, Sent: 1, Received: 2},
+		{Namespace: "default", Workload: "Deployment/api", Address: "8.8.8.8", Protocol: ProtocolTCP, Port: 443, Sent: 5, Received: 6},
+		{Namespace: "default", Workload: "Deployment/web", Address: "1.1.1.1", Protocol: ProtocolTCP, Port: 443, Sent: 20, Received: 200, Names: []string{"cloudflare-dns.com", "one.one.one.one"}},
+	}
+
+	expected := []Destination{
+		{Namespace: "default", Workload: "Deployment/api", Address: "8.8.8.8", Protocol: ProtocolTCP, Port: 443, Sent: 5, Received: 6},
+		{Namespace: "default", Workload: "Deployment/web", Address: "1.1.1.1", Protocol: ProtocolTCP, Port: 443, Sent: 30, Received: 300, Names: []string{"cloudflare-dns.com", "one.one.one.one"}},
+		{Namespace: "default", Workload: "Deployment/web", Address: "1.1.1.1", Protocol: ProtocolUDP, Port: 53, Sent: 1, Received: 2},
+	}
+
+	if merged := MergeDestinations(dsts); !reflect.DeepEqual(merged, expected) {
+		t.Fatalf("got %+v, expected %+v", merged, expected)
+	}
+}
+
+func TestRules(t *testing.T) {
+	d := Destination{Address: "2606:4700::1111", Protocol: ProtocolTCP, Port: 443}
+	if rules := d.Rules(); !reflect.DeepEqual(rules, []string{"[2606:4700::1111]:443/TCP"}) {
+		t.Fatalf("Without names: got %v", rules)
+	}
+
+	d.Names = []string{"cloudflare-dns.com", "one.one.one.one"}
+	if rules := d.Rules(); !reflect.DeepEqual(rules, []string{"cloudflare-dns.com:443/TCP", "one.one.one.one:443/TCP"}) {
+		t.Fatalf("With names: got %v", rules)
+	}
+}
+
+func TestClusterNetworks(t *testing.T) {
+	if _, err := NewClusterNetworks([]string{"10.0.0.0/33"}); err == nil {
+		t.Fatalf("Invalid CIDR: expected an error")
+	}
+
+	cluster, err := NewClusterNetworks([]string{"10.244.0.0/16", "fd00::/64"})
+	if err != nil {
+		t.Fatalf("NewClusterNetworks: %s", err)
+	}
+	cluster.AddAddress("10.96.0.10")
+	cluster.AddAddress("192.168.1.2")
+
+	for address, expected := range map[string]bool{
+		"10.244.3.4":  true,
+		"fd00::1":     true,
+		"10.96.0.10":  true,
+		"192.168.1.2": true,
+		"127.0.0.1":   true,
+		"::1":         true,
+		"169.254.1.1": true,
+		"224.0.0.251": true,
+		"10.96.0.11":  false,
+		"192.168.1.3": false,
+		"1.1.1.1":     false,
+		"2606:4700::": false,
+		"not an ip":   false,
+	} {
+		if got := cluster.Contains(address); got != expected {
+			t.Errorf("Contains(%q): got %t, expected %t", address, got, expected)
+		}
+	}
+
+	dsts := []Destination{{Address: "10.244.3.4"}, {Address: "1.1.1.1"}}
+	if external := External(dsts, cluster); !reflect.DeepEqual(external, dsts[1:]) {
+		t.Fatalf("External: got %+v", external)
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgets
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+)
+
+const (
+	// EnforceParam is the parameter asking a gadget to block the operations
+	// it reports instead of only reporting them.
+	EnforceParam = "enforce"
+
+	// EnforcementAnnotation must be set to "true" on a Trace for the
+	// EnforceParam parameter to be accepted. Blocking operations of the
+	// workloads is more sensitive than observing them, so cluster
+	// administrators can restrict who is allowed to set this annotation
+	// with an admission policy, independently of the RBAC rules on the
+	// Trace resources.
+	EnforcementAnnotation = "gadget.kinvolk.io/allow-enforcement"
+
+	// lsmFile lists the active Linux Security Modules.
+	lsmFile = "/sys/kernel/security/lsm"
+)
+
+// TraceFactoryWithEnforcement is implemented by the gadgets able to block the
+// operations they report when the EnforceParam parameter is "true".
+type TraceFactoryWithEnforcement interface {
+	// Enforcement describes the operations blocked by the gadget. It is
+	// used to generate the documentation.
+	Enforcement() string
+}
+
+// EnforceParameter documents the EnforceParam parameter.
+func EnforceParameter() GadgetParameter {
+	return GadgetParameter{
+		Name: EnforceParam,
+		Description: "Block the reported operations instead of only reporting them. Requires a kernel with BPF LSM and the " +
+			EnforcementAnnotation + " annotation on the Trace",
+		Default: "false",
+		Values:  []string{"true", "false"},
+	}
+}
+
+// EnforcementEnabled tells if the trace asks for the enforcement.
+func EnforcementEnabled(trace *gadgetv1alpha1.Trace) bool {
+	return trace.Spec.Parameters[EnforceParam] == "true"
+}
+
+// CheckEnforcement verifies that the enforcement, if requested by the trace,
+// is supported by the gadget and allowed by the annotation of the trace.
+func CheckEnforcement(factory TraceFactory, trace *gadgetv1alpha1.Trace) error {
+	switch val := trace.Spec.Parameters[EnforceParam]; val {
+	case "", "false":
+		return nil
+	case "true":
+	default:
+		return fmt.Errorf("invalid value %q for parameter %q: should be \"true\" or \"false\"",
+			val, EnforceParam)
+	}
+
+	if _, ok := factory.(TraceFactoryWithEnforcement); !ok {
+		return fmt.Errorf("gadget %q doesn't support enforcement", trace.Spec.Gadget)
+	}
+
+	if trace.ObjectMeta.Annotations[EnforcementAnnotation] != "true" {
+		return fmt.Errorf("enforcement requires the %q annotation to be set to \"true\"",
+			EnforcementAnnotation)
+	}
+
+	return nil
+}
+
+// BPFLSMSupported returns an error if the BPF LSM isn't active on this node,
+// i.e. if "bpf" isn't part of the lsm= kernel parameter or if the kernel
+// wasn't built with CONFIG_BPF_LSM.
+func BPFLSMSupported() error {
+	content, err := os.ReadFile(lsmFile)
+	if err != nil {
+		return fmt.Errorf("failed to read the active security modules: %w", err)
+	}
+
+	for _, lsm := range strings.Split(strings.TrimSpace(string(content)), ",") {
+		if lsm == "bpf" {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("BPF LSM is not active on this node (active security modules: %s): add \"bpf\" to the lsm= kernel parameter",
+		strings.TrimSpace(string(content)))
+}
@@ -0,0 +1,150 @@
+// SPDX-License-Identifier: GPL-2.0
+// Copyright (c) 2022 The Inspektor Gadget authors
+#include <vmlinux/vmlinux.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_tracing.h>
+
+#define EPERM		1
+
+#define FMODE_WRITE	0x2
+#define S_IFMT		00170000
+#define S_IFCHR		0020000
+#define S_ISCHR(m)	(((m) & S_IFMT) == S_IFCHR)
+
+/* Kernel internal encoding of the device numbers, see include/linux/kdev_t.h */
+#define MINORBITS	20
+#define MKDEV(ma, mi)	(((ma) << MINORBITS) | (mi))
+
+#define PROC_SUPER_MAGIC	0x9fa0
+
+#define NAME_MAX_LEN	16
+
+/*
+ * The operations are only blocked for the containers selected by the trace,
+ * whose mount namespaces are in this map. The host is never affected.
+ */
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, 1024);
+	__uint(key_size, sizeof(u64));
+	__uint(value_size, sizeof(u32));
+} mount_ns_set SEC(".maps");
+
+static __always_inline bool enforced(void)
+{
+	struct task_struct *task;
+	u64 mntns_id;
+
+	task = (struct task_struct*)bpf_get_current_task();
+	mntns_id = (u64) BPF_CORE_READ(task, nsproxy, mnt_ns, ns.inum);
+
+	return bpf_map_lookup_elem(&mount_ns_set, &mntns_id) != NULL;
+}
+
+static __always_inline bool has_prefix(const char *s, const char *prefix, int len)
+{
+	for (int i = 0; i < len; i++) {
+		if (s[i] != prefix[i])
+			return false;
+	}
+	return true;
+}
+
+/* s is dir or a path under dir, dir being len characters long */
+static __always_inline bool is_dir_or_child(const char *s, const char *dir, int len)
+{
+	return has_prefix(s, dir, len) && (s[len] == '\0' || s[len] == '/');
+}
+
+/* /dev/mem, /dev/kmem and /dev/port */
+static __always_inline bool is_mem_device(struct inode *inode)
+{
+	umode_t mode = BPF_CORE_READ(inode, i_mode);
+	dev_t rdev = BPF_CORE_READ(inode, i_rdev);
+
+	if (!S_ISCHR(mode))
+		return false;
+
+	return rdev == MKDEV(1, 1) || rdev == MKDEV(1, 2) || rdev == MKDEV(1, 4);
+}
+
+/* /proc/sys/kernel/core_pattern, wherever procfs is mounted */
+static __always_inline bool is_core_pattern(struct file *file)
+{
+	static const char core_pattern[] = "core_pattern";
+	static const char kernel[] = "kernel";
+	struct dentry *dentry;
+	char name[NAME_MAX_LEN];
+
+	if (BPF_CORE_READ(file, f_inode, i_sb, s_magic) != PROC_SUPER_MAGIC)
+		return false;
+
+	dentry = BPF_CORE_READ(file, f_path.dentry);
+	if (bpf_probe_read_kernel_str(name, sizeof(name), BPF_CORE_READ(dentry, d_name.name)) < 0)
+		return false;
+	if (!has_prefix(name, core_pattern, sizeof(core_pattern)))
+		return false;
+
+	if (bpf_probe_read_kernel_str(name, sizeof(name), BPF_CORE_READ(dentry, d_parent, d_name.name)) < 0)
+		return false;
+
+	return has_prefix(name, kernel, sizeof(kernel));
+}
+
+SEC("lsm/file_open")
+int BPF_PROG(ig_escape_file_open, struct file *file, int ret)
+{
+	/* Don't override the decision of another security module */
+	if (ret)
+		return ret;
+
+	if (!enforced())
+		return 0;
+
+	if (is_mem_device(BPF_CORE_READ(file, f_inode)))
+		return -EPERM;
+
+	if ((BPF_CORE_READ(file, f_mode) & FMODE_WRITE) && is_core_pattern(file))
+		return -EPERM;
+
+	return 0;
+}
+
+/*
+ * Same heuristic as the host-mount indicator: the mount of anything under
+ * /dev but the pseudo filesystems usually mounted in containers.
+ */
+SEC("lsm/sb_mount")
+int BPF_PROG(ig_escape_sb_mount, const char *dev_name, const struct path *path,
+	     const char *type, unsigned long flags, void *data, int ret)
+{
+	static const char dev[] = "/dev/";
+	static const char hugepages[] = "/dev/hugepages";
+	static const char mqueue[] = "/dev/mqueue";
+	static const char pts[] = "/dev/pts";
+	static const char shm[] = "/dev/shm";
+	char source[NAME_MAX_LEN];
+
+	if (ret)
+		return ret;
+
+	if (!dev_name || !enforced())
+		return 0;
+
+	if (bpf_probe_read_kernel_str(source, sizeof(source), dev_name) < 0)
+		return 0;
+
+	/* The sizes include the NUL byte */
+	if (!has_prefix(source, dev, sizeof(dev) - 1))
+		return 0;
+	if (is_dir_or_child(source, hugepages, sizeof(hugepages) - 1) ||
+	    is_dir_or_child(source, mqueue, sizeof(mqueue) - 1) ||
+	    is_dir_or_child(source, pts, sizeof(pts) - 1) ||
+	    is_dir_or_child(source, shm, sizeof(shm) - 1))
+		return 0;
+
+	return -EPERM;
+}
+
+char LICENSE[] SEC("license") = "GPL";
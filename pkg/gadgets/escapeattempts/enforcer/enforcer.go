@@ -0,0 +1,103 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package enforcer blocks the actions reported by the escape-attempts gadget
+// with BPF LSM programs.
+package enforcer
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+)
+
+//go:generate sh -c "GOOS=$(go env GOHOSTOS) GOARCH=$(go env GOHOSTARCH) go run github.com/cilium/ebpf/cmd/bpf2go -target bpfel -cc clang enforcer ./bpf/enforcer.bpf.c -- -I./bpf/ -I../../.. -target bpf -D__TARGET_ARCH_x86"
+
+type Config struct {
+	// MountnsMap is the path of the pinned map with the mount namespaces
+	// of the containers where the actions are blocked.
+	MountnsMap string
+}
+
+// Enforcer denies the opening of /dev/mem, /dev/kmem and /dev/port, the
+// opening of /proc/sys/kernel/core_pattern for writing and the mount of
+// devices to the processes of the selected containers.
+type Enforcer struct {
+	objs  enforcerObjects
+	links []link.Link
+}
+
+func NewEnforcer(config *Config) (*Enforcer, error) {
+	if config.MountnsMap == "" {
+		// Never block the actions of the whole node.
+		return nil, fmt.Errorf("enforcement requires the mount namespace map of the trace")
+	}
+
+	if err := gadgets.BPFLSMSupported(); err != nil {
+		return nil, err
+	}
+
+	e := &Enforcer{}
+
+	if err := e.start(config); err != nil {
+		e.Stop()
+		return nil, err
+	}
+
+	return e, nil
+}
+
+func (e *Enforcer) start(config *Config) error {
+	spec, err := loadEnforcer()
+	if err != nil {
+		return fmt.Errorf("failed to load ebpf program: %w", err)
+	}
+
+	m := spec.Maps["mount_ns_set"]
+	m.Pinning = ebpf.PinByName
+	m.Name = filepath.Base(config.MountnsMap)
+
+	opts := ebpf.CollectionOptions{
+		Maps: ebpf.MapOptions{
+			PinPath: filepath.Dir(config.MountnsMap),
+		},
+	}
+
+	if err := spec.LoadAndAssign(&e.objs, &opts); err != nil {
+		return fmt.Errorf("failed to load ebpf program: %w", err)
+	}
+
+	for _, prog := range []*ebpf.Program{e.objs.IgEscapeFileOpen, e.objs.IgEscapeSbMount} {
+		l, err := link.AttachLSM(link.LSMOptions{Program: prog})
+		if err != nil {
+			return fmt.Errorf("error attaching LSM program %s: %w", prog, err)
+		}
+		e.links = append(e.links, l)
+	}
+
+	return nil
+}
+
+// Stop stops blocking the actions.
+func (e *Enforcer) Stop() {
+	for i := range e.links {
+		e.links[i] = gadgets.CloseLink(e.links[i])
+	}
+
+	e.objs.Close()
+}
@@ -0,0 +1,169 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package escapeattempts
+
+import (
+	"fmt"
+
+	containercollection "github.com/kinvolk/inspektor-gadget/pkg/container-collection"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/escapeattempts/enforcer"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/escapeattempts/tracer"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/escapeattempts/types"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/execsnoop"
+	execsnooptracer "github.com/kinvolk/inspektor-gadget/pkg/gadgets/execsnoop/tracer"
+	execsnoopstandardtracer "github.com/kinvolk/inspektor-gadget/pkg/gadgets/execsnoop/tracer/standard"
+	execsnooptypes "github.com/kinvolk/inspektor-gadget/pkg/gadgets/execsnoop/types"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/mountsnoop"
+	mountsnooptracer "github.com/kinvolk/inspektor-gadget/pkg/gadgets/mountsnoop/tracer"
+	mountsnoopstandardtracer "github.com/kinvolk/inspektor-gadget/pkg/gadgets/mountsnoop/tracer/standard"
+	mountsnooptypes "github.com/kinvolk/inspektor-gadget/pkg/gadgets/mountsnoop/types"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/opensnoop"
+	opensnooptracer "github.com/kinvolk/inspektor-gadget/pkg/gadgets/opensnoop/tracer"
+	opensnoopstandardtracer "github.com/kinvolk/inspektor-gadget/pkg/gadgets/opensnoop/tracer/standard"
+	opensnooptypes "github.com/kinvolk/inspektor-gadget/pkg/gadgets/opensnoop/types"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+// Tracer combines the execsnoop, opensnoop and mountsnoop tracers and
+// reports the events that look like an attempt to escape to the host.
+// With the enforcement, some of these actions are also blocked.
+type Tracer struct {
+	tracers  []gadgets.Tracer
+	enforcer *enforcer.Enforcer
+}
+
+// NewTracer creates an escape-attempts tracer calling eventCallback for
+// each suspicious action.
+func NewTracer(config *tracer.Config, resolver containercollection.ContainerResolver,
+	eventCallback func(types.Event), node string,
+) (*Tracer, error) {
+	// Errors and warnings of the underlying tracers are forwarded as is.
+	forward := func(event eventtypes.Event) bool {
+		if event.Type == eventtypes.NORMAL {
+			return false
+		}
+		eventCallback(types.Base(event))
+		return true
+	}
+
+	report := func(e types.Event) {
+		if config.Enforce {
+			tracer.MarkBlocked(&e)
+		}
+		eventCallback(e)
+	}
+
+	execCallback := func(event execsnooptypes.Event) {
+		if forward(event.Event) {
+			return
+		}
+		if e, ok := tracer.FromExec(&event); ok {
+			report(e)
+		}
+	}
+	openCallback := func(event opensnooptypes.Event) {
+		if forward(event.Event) {
+			return
+		}
+		if e, ok := tracer.FromOpen(&event); ok {
+			report(e)
+		}
+	}
+	mountCallback := func(event mountsnooptypes.Event) {
+		if forward(event.Event) {
+			return
+		}
+		if e, ok := tracer.FromMount(&event); ok {
+			report(e)
+		}
+	}
+
+	starters := []struct {
+		name  string
+		start func() (gadgets.Tracer, error)
+	}{
+		{
+			name: "execsnoop",
+			start: func() (gadgets.Tracer, error) {
+				return execsnoop.NewTracer(&execsnooptracer.Config{MountnsMap: config.MountnsMap},
+					resolver, execCallback, node)
+			},
+		},
+		{
+			name: "opensnoop",
+			start: func() (gadgets.Tracer, error) {
+				return opensnoop.NewTracer(&opensnooptracer.Config{MountnsMap: config.MountnsMap},
+					resolver, openCallback, node)
+			},
+		},
+		{
+			name: "mountsnoop",
+			start: func() (gadgets.Tracer, error) {
+				return mountsnoop.NewTracer(&mountsnooptracer.Config{MountnsMap: config.MountnsMap},
+					resolver, mountCallback, node)
+			},
+		},
+	}
+
+	t := &Tracer{}
+
+	// Start blocking before reporting so that no reported action is
+	// missed by the enforcement.
+	if config.Enforce {
+		var err error
+		t.enforcer, err = enforcer.NewEnforcer(&enforcer.Config{MountnsMap: config.MountnsMap})
+		if err != nil {
+			return nil, fmt.Errorf("failed to enable enforcement: %w", err)
+		}
+	}
+
+	for _, s := range starters {
+		tr, err := s.start()
+		if err != nil {
+			t.Stop()
+			return nil, fmt.Errorf("failed to create %s tracer: %w", s.name, err)
+		}
+		t.tracers = append(t.tracers, tr)
+	}
+
+	return t, nil
+}
+
+// usesStandardTracer tells if one of the underlying tracers had to fall
+// back to the BCC implementation.
+func (t *Tracer) usesStandardTracer() bool {
+	for _, tr := range t.tracers {
+		switch tr.(type) {
+		case *execsnoopstandardtracer.Tracer,
+			*opensnoopstandardtracer.Tracer,
+			*mountsnoopstandardtracer.Tracer:
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Tracer) Stop() {
+	for _, tr := range t.tracers {
+		tr.Stop()
+	}
+	t.tracers = nil
+
+	if t.enforcer != nil {
+		t.enforcer.Stop()
+		t.enforcer = nil
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tracer implements the heuristics of the escape-attempts gadget on
+// top of the events of the execsnoop, opensnoop and mountsnoop tracers.
+package tracer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/escapeattempts/types"
+	execsnooptypes "github.com/kinvolk/inspektor-gadget/pkg/gadgets/execsnoop/types"
+	mountsnooptypes "github.com/kinvolk/inspektor-gadget/pkg/gadgets/mountsnoop/types"
+	opensnooptypes "github.com/kinvolk/inspektor-gadget/pkg/gadgets/opensnoop/types"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+const corePatternPath = "/proc/sys/kernel/core_pattern"
+
+// memDevices give access to the physical or kernel memory.
+var memDevices = map[string]struct{}{
+	"/dev/mem":  {},
+	"/dev/kmem": {},
+	"/dev/port": {},
+}
+
+// pseudoDevices are the directories of /dev that can be mounted without
+// giving access to a disk of the host.
+var pseudoDevices = map[string]struct{}{
+	"/dev/hugepages": {},
+	"/dev/mqueue":    {},
+	"/dev/pts":       {},
+	"/dev/shm":       {},
+}
+
+func newEvent(ev eventtypes.Event, indicator, details string) types.Event {
+	return types.Event{
+		Event:     ev,
+		Indicator: indicator,
+		Severity:  types.SeverityHigh,
+		Details:   details,
+	}
+}
+
+// isNsenterHost tells if args is the command line of nsenter targeting the
+// init process of the host.
+func isNsenterHost(args []string) bool {
+	if len(args) == 0 || filepath.Base(args[0]) != "nsenter" {
+		return false
+	}
+
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "-t" || arg == "--target":
+			if i+1 < len(args) && args[i+1] == "1" {
+				return true
+			}
+		case arg == "-t1" || arg == "--target=1":
+			return true
+		}
+	}
+
+	return false
+}
+
+// FromExec returns the escape attempt indicated by an exec event, if any.
+func FromExec(e *execsnooptypes.Event) (types.Event, bool) {
+	if e.Type != eventtypes.NORMAL || !isNsenterHost(e.Args) {
+		return types.Event{}, false
+	}
+
+	event := newEvent(e.Event, types.IndicatorNsenterHost, strings.Join(e.Args, " "))
+	event.MountNsID = e.MountNsID
+	event.Pid = e.Pid
+	event.Comm = e.Comm
+	event.Retval = e.Retval
+
+	return event, true
+}
+
+// FromOpen returns the escape attempt indicated by an open event, if any.
+func FromOpen(e *opensnooptypes.Event) (types.Event, bool) {
+	if e.Type != eventtypes.NORMAL {
+		return types.Event{}, false
+	}
+
+	var event types.Event
+
+	if _, ok := memDevices[e.Path]; ok {
+		event = newEvent(e.Event, types.IndicatorDevMem, e.Path)
+	} else if e.Path == corePatternPath && e.Flags&unix.O_ACCMODE != unix.O_RDONLY {
+		event = newEvent(e.Event, types.IndicatorCorePattern, e.Path)
+	} else {
+		return types.Event{}, false
+	}
+
+	event.MountNsID = e.MountNsID
+	event.Pid = e.Pid
+	event.Comm = e.Comm
+	event.Retval = e.Ret
+
+	return event, true
+}
+
+// Enforceable tells if the actions of the indicator are blocked when the
+// enforcement is enabled. nsenter can't be blocked without denying setns()
+// to all the processes of the container, so it's only reported.
+func Enforceable(indicator string) bool {
+	switch indicator {
+	case types.IndicatorHostMount, types.IndicatorDevMem, types.IndicatorCorePattern:
+		return true
+	}
+	return false
+}
+
+// MarkBlocked marks the event as blocked if the enforcement denied the
+// action, which then failed with EPERM.
+func MarkBlocked(e *types.Event) {
+	e.Blocked = Enforceable(e.Indicator) && e.Retval == -int(unix.EPERM)
+}
+
+// FromMount returns the escape attempt indicated by a mount event, if any.
+func FromMount(e *mountsnooptypes.Event) (types.Event, bool) {
+	if e.Type != eventtypes.NORMAL || e.Operation != "mount" {
+		return types.Event{}, false
+	}
+
+	if !strings.HasPrefix(e.Source, "/dev/") {
+		return types.Event{}, false
+	}
+	for dir := range pseudoDevices {
+		if e.Source == dir || strings.HasPrefix(e.Source, dir+"/") {
+			return types.Event{}, false
+		}
+	}
+
+	details := fmt.Sprintf("%s on %s", e.Source, e.Target)
+	if e.Fs != "" {
+		details += fmt.Sprintf(" type %s", e.Fs)
+	}
+
+	event := newEvent(e.Event, types.IndicatorHostMount, details)
+	event.MountNsID = e.MountNsID
+	event.Pid = e.Pid
+	event.Comm = e.Comm
+	event.Retval = e.Retval
+
+	return event, true
+}
@@ -0,0 +1,146 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"testing"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/escapeattempts/types"
+	execsnooptypes "github.com/kinvolk/inspektor-gadget/pkg/gadgets/execsnoop/types"
+	mountsnooptypes "github.com/kinvolk/inspektor-gadget/pkg/gadgets/mountsnoop/types"
+	opensnooptypes "github.com/kinvolk/inspektor-gadget/pkg/gadgets/opensnoop/types"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+var normal = eventtypes.Event{Type: eventtypes.NORMAL}
+
+func TestFromExec(t *testing.T) {
+	table := []struct {
+		args      []string
+		indicator string
+	}{
+		{args: []string{"/usr/bin/nsenter", "-t", "1", "-a", "sh"}, indicator: types.IndicatorNsenterHost},
+		{args: []string{"nsenter", "--mount", "--target=1"}, indicator: types.IndicatorNsenterHost},
+		{args: []string{"nsenter", "-t1", "-m"}, indicator: types.IndicatorNsenterHost},
+		{args: []string{"nsenter", "-t", "42", "-n"}},
+		{args: []string{"/bin/echo", "-t", "1"}},
+		{args: []string{"nsenter", "-t"}},
+		{},
+	}
+
+	for _, entry := range table {
+		event, ok := FromExec(&execsnooptypes.Event{Event: normal, Args: entry.args})
+		if ok != (entry.indicator != "") || event.Indicator != entry.indicator {
+			t.Fatalf("%v: got indicator %q (%t), expected %q", entry.args, event.Indicator, ok, entry.indicator)
+		}
+		if ok && event.Severity != types.SeverityHigh {
+			t.Fatalf("%v: got severity %q", entry.args, event.Severity)
+		}
+	}
+}
+
+func TestFromOpen(t *testing.T) {
+	table := []struct {
+		path      string
+		flags     int
+		indicator string
+	}{
+		{path: "/dev/mem", flags: unix.O_RDONLY, indicator: types.IndicatorDevMem},
+		{path: "/dev/kmem", flags: unix.O_RDWR, indicator: types.IndicatorDevMem},
+		{path: "/dev/port", flags: unix.O_WRONLY, indicator: types.IndicatorDevMem},
+		{path: "/proc/sys/kernel/core_pattern", flags: unix.O_WRONLY | unix.O_TRUNC, indicator: types.IndicatorCorePattern},
+		{path: "/proc/sys/kernel/core_pattern", flags: unix.O_RDONLY},
+		{path: "/dev/null", flags: unix.O_RDWR},
+	}
+
+	for _, entry := range table {
+		event, ok := FromOpen(&opensnooptypes.Event{Event: normal, Path: entry.path, Flags: entry.flags})
+		if ok != (entry.indicator != "") || event.Indicator != entry.indicator {
+			t.Fatalf("%s (%#x): got indicator %q (%t), expected %q", entry.path, entry.flags, event.Indicator, ok, entry.indicator)
+		}
+	}
+}
+
+func TestFromMount(t *testing.T) {
+	table := []struct {
+		operation string
+		source    string
+		indicator string
+	}{
+		{operation: "mount", source: "/dev/sda1", indicator: types.IndicatorHostMount},
+		{operation: "mount", source: "/dev/mapper/root", indicator: types.IndicatorHostMount},
+		{operation: "umount", source: "/dev/sda1"},
+		{operation: "mount", source: "/dev/shm"},
+		{operation: "mount", source: "/dev/pts/0"},
+		{operation: "mount", source: "tmpfs"},
+		{operation: "mount", source: "/devices"},
+	}
+
+	for _, entry := range table {
+		event, ok := FromMount(&mountsnooptypes.Event{
+			Event:     normal,
+			Operation: entry.operation,
+			Source:    entry.source,
+			Target:    "/mnt",
+		})
+		if ok != (entry.indicator != "") || event.Indicator != entry.indicator {
+			t.Fatalf("%s %s: got indicator %q (%t), expected %q", entry.operation, entry.source, event.Indicator, ok, entry.indicator)
+		}
+	}
+}
+
+func TestNotNormal(t *testing.T) {
+	warn := eventtypes.Warn("warning", "node")
+
+	if _, ok := FromExec(&execsnooptypes.Event{Event: warn, Args: []string{"nsenter", "-t", "1"}}); ok {
+		t.Fatalf("exec: warning reported as an escape attempt")
+	}
+	if _, ok := FromOpen(&opensnooptypes.Event{Event: warn, Path: "/dev/mem"}); ok {
+		t.Fatalf("open: warning reported as an escape attempt")
+	}
+	if _, ok := FromMount(&mountsnooptypes.Event{Event: warn, Operation: "mount", Source: "/dev/sda1"}); ok {
+		t.Fatalf("mount: warning reported as an escape attempt")
+	}
+}
+
+func TestMarkBlocked(t *testing.T) {
+	eperm := -int(unix.EPERM)
+
+	table := []struct {
+		indicator string
+		retval    int
+		blocked   bool
+	}{
+		{indicator: types.IndicatorHostMount, retval: eperm, blocked: true},
+		{indicator: types.IndicatorDevMem, retval: eperm, blocked: true},
+		{indicator: types.IndicatorCorePattern, retval: eperm, blocked: true},
+		{indicator: types.IndicatorDevMem, retval: 3},
+		{indicator: types.IndicatorHostMount, retval: -int(unix.ENOENT)},
+		// nsenter isn't blocked by the enforcement, it can fail for
+		// other reasons.
+		{indicator: types.IndicatorNsenterHost, retval: eperm},
+	}
+
+	for _, entry := range table {
+		event := types.Event{Indicator: entry.indicator, Retval: entry.retval}
+		MarkBlocked(&event)
+		if event.Blocked != entry.blocked {
+			t.Fatalf("%s with ret %d: got blocked %t, expected %t",
+				entry.indicator, entry.retval, event.Blocked, entry.blocked)
+		}
+	}
+}
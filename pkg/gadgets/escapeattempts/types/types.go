@@ -0,0 +1,69 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+// Indicators of a container escape attempt reported by the gadget.
+const (
+	// IndicatorNsenterHost is the execution of nsenter targeting the
+	// init process of the host, e.g. "nsenter -t 1 -a".
+	IndicatorNsenterHost = "nsenter-host"
+
+	// IndicatorHostMount is the mount of a block device or of the root
+	// directory, usually to access the filesystem of the host.
+	IndicatorHostMount = "host-mount"
+
+	// IndicatorDevMem is the access to the physical or kernel memory
+	// through /dev/mem, /dev/kmem or /dev/port.
+	IndicatorDevMem = "dev-mem"
+
+	// IndicatorCorePattern is the opening for writing of
+	// /proc/sys/kernel/core_pattern, which allows to run a program on
+	// the host when a process crashes.
+	IndicatorCorePattern = "core-pattern"
+)
+
+// SeverityHigh is the severity of all the indicators: none of them is
+// expected from a regular workload.
+const SeverityHigh = "high"
+
+type Event struct {
+	eventtypes.Event
+
+	MountNsID uint64 `json:"mountnsid,omitempty"`
+	Pid       uint32 `json:"pid,omitempty"`
+	Comm      string `json:"comm,omitempty"`
+	Retval    int    `json:"ret,omitempty"`
+
+	Indicator string `json:"indicator,omitempty"`
+	Severity  string `json:"severity,omitempty"`
+
+	// Details describes what triggered the indicator, e.g. the command
+	// line or the path.
+	Details string `json:"details,omitempty"`
+
+	// Blocked is true if the action was denied by the gadget because the
+	// enforcement is enabled.
+	Blocked bool `json:"blocked,omitempty"`
+}
+
+func Base(ev eventtypes.Event) Event {
+	return Event{
+		Event: ev,
+	}
+}
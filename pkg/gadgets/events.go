@@ -0,0 +1,56 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgets
+
+import (
+	"encoding/json"
+	"fmt"
+
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+// Event is an event published by a gadget, decoded into the type of the
+// events of the gadget, like *tlssnoop/types.Event. These types embed
+// eventtypes.Event, which implements this interface.
+type Event interface {
+	GetBaseEvent() *eventtypes.Event
+}
+
+// EventDecoder decodes a line published by a gadget into an Event.
+type EventDecoder func(line string) (Event, error)
+
+// NewEventDecoder returns a decoder unmarshalling the lines into the events
+// returned by newEvent.
+func NewEventDecoder(newEvent func() Event) EventDecoder {
+	return func(line string) (Event, error) {
+		event := newEvent()
+		if err := json.Unmarshal([]byte(line), event); err != nil {
+			return nil, fmt.Errorf("decoding event %q: %w", line, err)
+		}
+		return event, nil
+	}
+}
+
+// EventDecoders returns the decoders of the events of the factories
+// implementing TraceFactoryWithEvents, by gadget name.
+func EventDecoders(factories map[string]TraceFactory) map[string]EventDecoder {
+	decoders := make(map[string]EventDecoder)
+	for name, factory := range factories {
+		if f, ok := factory.(TraceFactoryWithEvents); ok {
+			decoders[name] = NewEventDecoder(f.NewEvent)
+		}
+	}
+	return decoders
+}
@@ -0,0 +1,82 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgets
+
+import (
+	"testing"
+
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+type testEvent struct {
+	eventtypes.Event
+
+	Comm string `json:"comm,omitempty"`
+}
+
+type eventsFactory struct {
+	BaseFactory
+}
+
+func (f *eventsFactory) NewEvent() Event {
+	return &testEvent{}
+}
+
+type noEventsFactory struct {
+	BaseFactory
+}
+
+func TestEventDecoders(t *testing.T) {
+	decoders := EventDecoders(map[string]TraceFactory{
+		"events":    &eventsFactory{},
+		"no-events": &noEventsFactory{},
+	})
+	if len(decoders) != 1 {
+		t.Fatalf("expected a single decoder, got %d", len(decoders))
+	}
+
+	decoder, ok := decoders["events"]
+	if !ok {
+		t.Fatalf("missing decoder of the gadget implementing TraceFactoryWithEvents")
+	}
+
+	event, err := decoder(`{"type":"normal","node":"node1","pod":"mypod","comm":"cat"}`)
+	if err != nil {
+		t.Fatalf("failed to decode event: %s", err)
+	}
+	e, ok := event.(*testEvent)
+	if !ok {
+		t.Fatalf("expected a *testEvent, got %T", event)
+	}
+	if e.Comm != "cat" || e.Pod != "mypod" {
+		t.Fatalf("event not decoded correctly: %+v", e)
+	}
+	if base := event.GetBaseEvent(); base.Type != eventtypes.NORMAL || base.Node != "node1" {
+		t.Fatalf("wrong base event: %+v", base)
+	}
+
+	// Each line is decoded into a new event
+	other, err := decoder(`{"type":"warn","message":"lost 1 samples"}`)
+	if err != nil {
+		t.Fatalf("failed to decode event: %s", err)
+	}
+	if other == event || other.GetBaseEvent().Type != eventtypes.WARN || e.Comm != "cat" {
+		t.Fatalf("events not decoded independently: %+v, %+v", e, other)
+	}
+
+	if _, err := decoder("---"); err == nil {
+		t.Fatalf("expected an error decoding a non-JSON line")
+	}
+}
@@ -190,6 +190,7 @@ func (t *Tracer) run() {
 		if container != nil {
 			event.Container = container.Name
 			event.Pod = container.Podname
+			event.Sandbox = container.Sandbox
 			event.Namespace = container.Namespace
 		}
 
@@ -18,14 +18,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"strconv"
-	"strings"
 	"time"
 
+	"github.com/cilium/ebpf"
 	log "github.com/sirupsen/logrus"
 
+	"github.com/kinvolk/inspektor-gadget/pkg/bpferror"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
 	filetoptracer "github.com/kinvolk/inspektor-gadget/pkg/gadgets/filetop/tracer"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/filetop/types"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/threshold"
 
 	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
 )
@@ -48,17 +50,34 @@ func NewFactory() gadgets.TraceFactory {
 }
 
 func (f *TraceFactory) Description() string {
-	t := `filetop shows reads and writes by file, with container details.
-
-The following parameters are supported:
- - %s: Output interval, in seconds. (default %d)
- - %s: Maximum rows to print. (default %d)
- - %s: The field to sort the results by (%s). (default %s)
- - %s: Show all files. (default %v, i.e. show regular files only)`
-	return fmt.Sprintf(t, types.IntervalParam, types.IntervalDefault,
-		types.MaxRowsParam, types.MaxRowsDefault,
-		types.SortByParam, strings.Join(types.SortBySlice, ","), types.SortByDefault,
-		types.AllFilesParam, types.AllFilesDefault)
+	return `filetop shows reads and writes by file, with container details.`
+}
+
+func (f *TraceFactory) Parameters() []gadgets.GadgetParameter {
+	params := []gadgets.GadgetParameter{
+		{
+			Name:        types.IntervalParam,
+			Description: "Output interval, in seconds",
+			Default:     strconv.Itoa(types.IntervalDefault),
+		},
+		{
+			Name:        types.MaxRowsParam,
+			Description: "Maximum rows to print",
+			Default:     strconv.Itoa(types.MaxRowsDefault),
+		},
+		{
+			Name:        types.SortByParam,
+			Description: "The field to sort the results by",
+			Default:     types.SortByDefault.String(),
+			Values:      types.SortBySlice,
+		},
+		{
+			Name:        types.AllFilesParam,
+			Description: "Show all files and not only regular files",
+			Default:     strconv.FormatBool(types.AllFilesDefault),
+		},
+	}
+	return append(params, gadgets.ThresholdParameters()...)
 }
 
 func (f *TraceFactory) OutputModesSupported() map[string]struct{} {
@@ -67,6 +86,14 @@ func (f *TraceFactory) OutputModesSupported() map[string]struct{} {
 	}
 }
 
+func (f *TraceFactory) Maps(name string) map[string]*ebpf.Map {
+	t, ok := f.LookupOrCreate(name, nil).(*Trace)
+	if !ok || !t.started {
+		return nil
+	}
+	return t.tracer.Maps()
+}
+
 func deleteTrace(name string, t interface{}) {
 	trace := t.(*Trace)
 	if trace.tracer != nil {
@@ -147,6 +174,12 @@ func (t *Trace) Start(trace *gadgetv1alpha1.Trace) {
 		}
 	}
 
+	thresholds, err := threshold.ParseParameters(trace.Spec.Parameters, &types.Stats{})
+	if err != nil {
+		trace.Status.OperationError = err.Error()
+		return
+	}
+
 	config := &filetoptracer.Config{
 		AllFiles:   allFiles,
 		MaxRows:    maxRows,
@@ -154,12 +187,31 @@ func (t *Trace) Start(trace *gadgetv1alpha1.Trace) {
 		SortBy:     sortBy,
 		MountnsMap: gadgets.TracePinPath(trace.ObjectMeta.Namespace, trace.ObjectMeta.Name),
 		Node:       trace.Spec.Node,
+		Thresholds: thresholds,
 	}
 
 	statsCallback := func(stats []types.Stats) {
 		ev := types.Event{
-			Node:  trace.Spec.Node,
-			Stats: stats,
+			Node:      trace.Spec.Node,
+			Timestamp: time.Now().UnixNano(),
+			Stats:     stats,
+		}
+
+		var alerted []types.Stats
+		for _, s := range stats {
+			if len(s.Alerts) > 0 {
+				alerted = append(alerted, s)
+			}
+		}
+		if len(alerted) > 0 {
+			ev.Warning = thresholds.Warning(len(alerted))
+			thresholds.Post(threshold.Alert{
+				Gadget:    trace.Spec.Gadget,
+				Trace:     trace.ObjectMeta.Namespace + "/" + trace.ObjectMeta.Name,
+				Node:      trace.Spec.Node,
+				Timestamp: ev.Timestamp,
+				Rows:      alerted,
+			})
 		}
 
 		r, err := json.Marshal(ev)
@@ -185,7 +237,7 @@ func (t *Trace) Start(trace *gadgetv1alpha1.Trace) {
 
 	tracer, err := filetoptracer.NewTracer(config, t.resolver, statsCallback, errorCallback)
 	if err != nil {
-		trace.Status.OperationError = fmt.Sprintf("failed to create tracer: %s", err)
+		trace.Status.OperationError = fmt.Sprintf("failed to create tracer: %s", bpferror.Describe(err))
 		return
 	}
 
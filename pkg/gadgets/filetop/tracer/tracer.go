@@ -27,6 +27,8 @@ import (
 	containercollection "github.com/kinvolk/inspektor-gadget/pkg/container-collection"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/filetop/types"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/threshold"
+	"github.com/kinvolk/inspektor-gadget/pkg/mapdump"
 
 	"github.com/cilium/ebpf"
 	"github.com/cilium/ebpf/link"
@@ -49,6 +51,10 @@ type Config struct {
 	// https://github.com/cilium/ebpf/issues/517 are fixed
 	MountnsMap string
 	Node       string
+
+	// Thresholds marks the rows crossing thresholds. These rows are
+	// reported even if they are not part of the first MaxRows ones.
+	Thresholds *threshold.Config
 }
 
 type Tracer struct {
@@ -89,6 +95,12 @@ func (t *Tracer) Stop() {
 	t.objs.Close()
 }
 
+// Maps returns the BPF maps of the tracer, so they can be dumped for
+// debugging.
+func (t *Tracer) Maps() map[string]*ebpf.Map {
+	return mapdump.MapsOf(&t.objs)
+}
+
 func (t *Tracer) start() error {
 	spec, err := loadFiletop()
 	if err != nil {
@@ -233,11 +245,14 @@ func (t *Tracer) run() {
 					return
 				}
 
-				n := len(stats)
-				if n > t.config.MaxRows {
-					n = t.config.MaxRows
+				rows := []types.Stats{}
+				for i := range stats {
+					stats[i].Alerts = t.config.Thresholds.Check(&stats[i], t.config.Interval)
+					if i < t.config.MaxRows || len(stats[i].Alerts) > 0 {
+						rows = append(rows, stats[i])
+					}
 				}
-				t.statsCallback(stats[:n])
+				t.statsCallback(rows)
 			}
 		}
 	}()
@@ -73,9 +73,17 @@ func ParseSortBy(sortby string) (SortBy, error) {
 type Event struct {
 	Error string `json:"error,omitempty"`
 
+	// Warning is set when rows crossed the thresholds during the interval
+	// and the warnings are enabled.
+	Warning string `json:"warning,omitempty"`
+
 	// Node where the event comes from.
 	Node string `json:"node,omitempty"`
 
+	// Timestamp is when the interval ended, in nanoseconds since the
+	// epoch.
+	Timestamp int64 `json:"timestamp,omitempty"`
+
 	Stats []Stats `json:"stats,omitempty"`
 }
 
@@ -96,6 +104,9 @@ type Stats struct {
 	Filename   string `json:"filename,omitempty"`
 	Comm       string `json:"comm,omitempty"`
 	FileType   byte   `json:"file_type,omitempty"`
+
+	// Alerts are the thresholds crossed by the row during the interval.
+	Alerts []string `json:"alerts,omitempty"`
 }
 
 func SortStats(stats []Stats, sortBy SortBy) {
@@ -18,8 +18,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"strconv"
-	"strings"
 
+	"github.com/kinvolk/inspektor-gadget/pkg/bpferror"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/fsslower/tracer"
 
@@ -49,13 +49,23 @@ func NewFactory() gadgets.TraceFactory {
 }
 
 func (f *TraceFactory) Description() string {
-	t := `fsslower shows open, read, write and fsync operations slower than a threshold
-
-The following parameters are supported:
-- filesystem: Which filesystem to trace [%s]
-- minlatency: Min latency to trace, in ms. (default %d)`
+	return `fsslower shows open, read, write and fsync operations slower than a threshold`
+}
 
-	return fmt.Sprintf(t, strings.Join(validFilesystems, ", "), types.MinLatencyDefault)
+func (f *TraceFactory) Parameters() []gadgets.GadgetParameter {
+	return []gadgets.GadgetParameter{
+		{
+			Name:        "filesystem",
+			Description: "Which filesystem to trace",
+			Values:      validFilesystems,
+			Required:    true,
+		},
+		{
+			Name:        "minlatency",
+			Description: "Min latency to trace, in ms",
+			Default:     strconv.FormatUint(uint64(types.MinLatencyDefault), 10),
+		},
+	}
 }
 
 func (f *TraceFactory) OutputModesSupported() map[string]struct{} {
@@ -145,7 +155,7 @@ func (t *Trace) Start(trace *gadgetv1alpha1.Trace) {
 	}
 	t.tracer, err = coretracer.NewTracer(config, t.resolver, eventCallback, trace.Spec.Node)
 	if err != nil {
-		trace.Status.OperationError = fmt.Sprintf("failed to create tracer: %s", err)
+		trace.Status.OperationError = fmt.Sprintf("failed to create tracer: %s", bpferror.Describe(err))
 		return
 	}
 
@@ -0,0 +1,260 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fstop
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/cilium/ebpf"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/kinvolk/inspektor-gadget/pkg/bpferror"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	fstoptracer "github.com/kinvolk/inspektor-gadget/pkg/gadgets/fstop/tracer"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/fstop/types"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/threshold"
+
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+)
+
+type Trace struct {
+	resolver gadgets.Resolver
+
+	started bool
+	tracer  *fstoptracer.Tracer
+}
+
+type TraceFactory struct {
+	gadgets.BaseFactory
+}
+
+func NewFactory() gadgets.TraceFactory {
+	return &TraceFactory{
+		BaseFactory: gadgets.BaseFactory{DeleteTrace: deleteTrace},
+	}
+}
+
+func (f *TraceFactory) Description() string {
+	return `fstop shows the reads, writes, opens and fsyncs of each container, with the amount of data read and written.`
+}
+
+func (f *TraceFactory) Parameters() []gadgets.GadgetParameter {
+	params := []gadgets.GadgetParameter{
+		{
+			Name:        types.IntervalParam,
+			Description: "Output interval, in seconds",
+			Default:     strconv.Itoa(types.IntervalDefault),
+		},
+		{
+			Name:        types.MaxRowsParam,
+			Description: "Maximum rows to print",
+			Default:     strconv.Itoa(types.MaxRowsDefault),
+		},
+		{
+			Name:        types.SortByParam,
+			Description: "The field to sort the results by",
+			Default:     types.SortByDefault.String(),
+			Values:      types.SortBySlice,
+		},
+		{
+			Name:        types.PidParam,
+			Description: "Only get events for this PID, all the processes by default",
+		},
+	}
+	return append(params, gadgets.ThresholdParameters()...)
+}
+
+func (f *TraceFactory) OutputModesSupported() map[string]struct{} {
+	return map[string]struct{}{
+		"Stream": {},
+	}
+}
+
+func (f *TraceFactory) Maps(name string) map[string]*ebpf.Map {
+	t, ok := f.LookupOrCreate(name, nil).(*Trace)
+	if !ok || !t.started {
+		return nil
+	}
+	return t.tracer.Maps()
+}
+
+func deleteTrace(name string, t interface{}) {
+	trace := t.(*Trace)
+	if trace.tracer != nil {
+		trace.tracer.Stop()
+	}
+}
+
+func (f *TraceFactory) Operations() map[string]gadgets.TraceOperation {
+	n := func() interface{} {
+		return &Trace{
+			resolver: f.Resolver,
+		}
+	}
+
+	return map[string]gadgets.TraceOperation{
+		"start": {
+			Doc: "Start fstop gadget",
+			Operation: func(name string, trace *gadgetv1alpha1.Trace) {
+				f.LookupOrCreate(name, n).(*Trace).Start(trace)
+			},
+		},
+		"stop": {
+			Doc: "Stop fstop gadget",
+			Operation: func(name string, trace *gadgetv1alpha1.Trace) {
+				f.LookupOrCreate(name, n).(*Trace).Stop(trace)
+			},
+		},
+	}
+}
+
+func (t *Trace) Start(trace *gadgetv1alpha1.Trace) {
+	if t.started {
+		trace.Status.State = "Started"
+		return
+	}
+
+	traceName := gadgets.TraceName(trace.ObjectMeta.Namespace, trace.ObjectMeta.Name)
+
+	maxRows := types.MaxRowsDefault
+	intervalSeconds := types.IntervalDefault
+	sortBy := types.SortByDefault
+	targetPid := 0
+
+	if trace.Spec.Parameters != nil {
+		params := trace.Spec.Parameters
+		var err error
+
+		if val, ok := params[types.MaxRowsParam]; ok {
+			maxRows, err = strconv.Atoi(val)
+			if err != nil {
+				trace.Status.OperationError = fmt.Sprintf("%q is not valid for %s: %v", val, types.MaxRowsParam, err)
+				return
+			}
+		}
+
+		if val, ok := params[types.IntervalParam]; ok {
+			intervalSeconds, err = strconv.Atoi(val)
+			if err != nil {
+				trace.Status.OperationError = fmt.Sprintf("%q is not valid for %s: %v", val, types.IntervalParam, err)
+				return
+			}
+		}
+
+		if val, ok := params[types.SortByParam]; ok {
+			sortBy, err = types.ParseSortBy(val)
+			if err != nil {
+				trace.Status.OperationError = fmt.Sprintf("%q is not valid for %s: %v", val, types.SortByParam, err)
+				return
+			}
+		}
+
+		if val, ok := params[types.PidParam]; ok {
+			targetPid, err = strconv.Atoi(val)
+			if err != nil {
+				trace.Status.OperationError = fmt.Sprintf("%q is not valid for %s: %v", val, types.PidParam, err)
+				return
+			}
+		}
+	}
+
+	thresholds, err := threshold.ParseParameters(trace.Spec.Parameters, &types.Stats{})
+	if err != nil {
+		trace.Status.OperationError = err.Error()
+		return
+	}
+
+	config := &fstoptracer.Config{
+		TargetPid:  targetPid,
+		MaxRows:    maxRows,
+		Interval:   time.Second * time.Duration(intervalSeconds),
+		SortBy:     sortBy,
+		MountnsMap: gadgets.TracePinPath(trace.ObjectMeta.Namespace, trace.ObjectMeta.Name),
+		Node:       trace.Spec.Node,
+		Thresholds: thresholds,
+	}
+
+	statsCallback := func(stats []types.Stats) {
+		ev := types.Event{
+			Node:      trace.Spec.Node,
+			Timestamp: time.Now().UnixNano(),
+			Stats:     stats,
+		}
+
+		var alerted []types.Stats
+		for _, s := range stats {
+			if len(s.Alerts) > 0 {
+				alerted = append(alerted, s)
+			}
+		}
+		if len(alerted) > 0 {
+			ev.Warning = thresholds.Warning(len(alerted))
+			thresholds.Post(threshold.Alert{
+				Gadget:    trace.Spec.Gadget,
+				Trace:     trace.ObjectMeta.Namespace + "/" + trace.ObjectMeta.Name,
+				Node:      trace.Spec.Node,
+				Timestamp: ev.Timestamp,
+				Rows:      alerted,
+			})
+		}
+
+		r, err := json.Marshal(ev)
+		if err != nil {
+			log.Warnf("Gadget %s: Failed to marshall event: %s", trace.Spec.Gadget, err)
+			return
+		}
+		t.resolver.PublishEvent(traceName, string(r))
+	}
+
+	errorCallback := func(err error) {
+		ev := types.Event{
+			Error: fmt.Sprintf("Gadget failed with: %v", err),
+			Node:  trace.Spec.Node,
+		}
+		r, err := json.Marshal(&ev)
+		if err != nil {
+			log.Warnf("Gadget %s: Failed to marshall event: %s", trace.Spec.Gadget, err)
+			return
+		}
+		t.resolver.PublishEvent(traceName, string(r))
+	}
+
+	tracer, err := fstoptracer.NewTracer(config, t.resolver, statsCallback, errorCallback)
+	if err != nil {
+		trace.Status.OperationError = fmt.Sprintf("failed to create tracer: %s", bpferror.Describe(err))
+		return
+	}
+
+	t.tracer = tracer
+	t.started = true
+
+	trace.Status.State = "Started"
+}
+
+func (t *Trace) Stop(trace *gadgetv1alpha1.Trace) {
+	if !t.started {
+		trace.Status.OperationError = "Not started"
+		return
+	}
+
+	t.tracer.Stop()
+	t.tracer = nil
+	t.started = false
+
+	trace.Status.State = "Stopped"
+}
@@ -0,0 +1,109 @@
+// SPDX-License-Identifier: GPL-2.0
+// Copyright (c) 2022 The Inspektor Gadget authors
+#include <vmlinux/vmlinux.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_tracing.h>
+#include "fstop.h"
+#include "stat.h"
+
+/* The operations are aggregated by mount namespace, i.e. by container. */
+#define MAX_ENTRIES	10240
+
+const volatile pid_t target_pid = 0;
+const volatile bool filter_by_mnt_ns = false;
+static struct fs_stat zero_value = {};
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, u64);
+	__type(value, struct fs_stat);
+} entries SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, 1024);
+	__uint(key_size, sizeof(u64));
+	__uint(value_size, sizeof(u32));
+} mount_ns_set SEC(".maps");
+
+static int probe_entry(struct file *file, size_t count, enum op op)
+{
+	__u64 pid_tgid = bpf_get_current_pid_tgid();
+	__u32 pid = pid_tgid >> 32;
+	struct fs_stat *valuep;
+	struct task_struct *task;
+	u64 mntns_id;
+	int mode;
+
+	if (target_pid && target_pid != pid)
+		return 0;
+
+	task = (struct task_struct*)bpf_get_current_task();
+	mntns_id = (u64) BPF_CORE_READ(task, nsproxy, mnt_ns, ns.inum);
+
+	if (filter_by_mnt_ns && !bpf_map_lookup_elem(&mount_ns_set, &mntns_id))
+		return 0;
+
+	/* Reads and writes on sockets and pipes are not filesystem I/O. */
+	if (op == READ || op == WRITE) {
+		mode = BPF_CORE_READ(file, f_inode, i_mode);
+		if (!S_ISREG(mode))
+			return 0;
+	}
+
+	valuep = bpf_map_lookup_elem(&entries, &mntns_id);
+	if (!valuep) {
+		bpf_map_update_elem(&entries, &mntns_id, &zero_value, BPF_NOEXIST);
+		valuep = bpf_map_lookup_elem(&entries, &mntns_id);
+		if (!valuep)
+			return 0;
+	}
+
+	switch (op) {
+	case READ:
+		__sync_fetch_and_add(&valuep->reads, 1);
+		__sync_fetch_and_add(&valuep->read_bytes, count);
+		break;
+	case WRITE:
+		__sync_fetch_and_add(&valuep->writes, 1);
+		__sync_fetch_and_add(&valuep->write_bytes, count);
+		break;
+	case OPEN:
+		__sync_fetch_and_add(&valuep->opens, 1);
+		break;
+	case FSYNC:
+		__sync_fetch_and_add(&valuep->fsyncs, 1);
+		break;
+	}
+
+	return 0;
+}
+
+SEC("kprobe/vfs_read")
+int BPF_KPROBE(vfs_read_entry, struct file *file, char *buf, size_t count, loff_t *pos)
+{
+	return probe_entry(file, count, READ);
+}
+
+SEC("kprobe/vfs_write")
+int BPF_KPROBE(vfs_write_entry, struct file *file, const char *buf, size_t count, loff_t *pos)
+{
+	return probe_entry(file, count, WRITE);
+}
+
+SEC("kprobe/vfs_open")
+int BPF_KPROBE(vfs_open_entry, const struct path *path, struct file *file)
+{
+	/* The inode of file is not set yet: only count the open. */
+	return probe_entry(file, 0, OPEN);
+}
+
+SEC("kprobe/vfs_fsync_range")
+int BPF_KPROBE(vfs_fsync_range_entry, struct file *file, loff_t start, loff_t end, int datasync)
+{
+	return probe_entry(file, 0, FSYNC);
+}
+
+char LICENSE[] SEC("license") = "GPL";
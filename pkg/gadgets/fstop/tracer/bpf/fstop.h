@@ -0,0 +1,21 @@
+/* SPDX-License-Identifier: (LGPL-2.1 OR BSD-2-Clause) */
+#ifndef __FSTOP_H
+#define __FSTOP_H
+
+enum op {
+	READ,
+	WRITE,
+	OPEN,
+	FSYNC,
+};
+
+struct fs_stat {
+	__u64 reads;
+	__u64 read_bytes;
+	__u64 writes;
+	__u64 write_bytes;
+	__u64 opens;
+	__u64 fsyncs;
+};
+
+#endif /* __FSTOP_H */
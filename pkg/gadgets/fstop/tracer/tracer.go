@@ -0,0 +1,259 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+	"unsafe"
+
+	containercollection "github.com/kinvolk/inspektor-gadget/pkg/container-collection"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/fstop/types"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/threshold"
+	"github.com/kinvolk/inspektor-gadget/pkg/mapdump"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+)
+
+// #include <linux/types.h>
+// #include "./bpf/fstop.h"
+import "C"
+
+//go:generate sh -c "GOOS=$(go env GOHOSTOS) GOARCH=$(go env GOHOSTARCH) go run github.com/cilium/ebpf/cmd/bpf2go -target bpfel -cc clang fstop ./bpf/fstop.bpf.c -- -I./bpf/ -I../../.. -target bpf -D__TARGET_ARCH_x86"
+
+type Config struct {
+	TargetPid int
+	MaxRows   int
+	Interval  time.Duration
+	SortBy    types.SortBy
+	// TODO: Make it a *ebpf.Map once
+	// https://github.com/cilium/ebpf/issues/515 and
+	// https://github.com/cilium/ebpf/issues/517 are fixed
+	MountnsMap string
+	Node       string
+
+	// Thresholds marks the rows crossing thresholds. These rows are
+	// reported even if they are not part of the first MaxRows ones.
+	Thresholds *threshold.Config
+}
+
+type Tracer struct {
+	config        *Config
+	objs          fstopObjects
+	links         []link.Link
+	resolver      containercollection.ContainerResolver
+	statsCallback func([]types.Stats)
+	errorCallback func(error)
+	done          chan bool
+}
+
+func NewTracer(config *Config, resolver containercollection.ContainerResolver,
+	statsCallback func([]types.Stats), errorCallback func(error)) (*Tracer, error) {
+	t := &Tracer{
+		config:        config,
+		resolver:      resolver,
+		statsCallback: statsCallback,
+		errorCallback: errorCallback,
+		done:          make(chan bool),
+	}
+
+	if err := t.start(); err != nil {
+		t.Stop()
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (t *Tracer) Stop() {
+	close(t.done)
+
+	for i := range t.links {
+		t.links[i] = gadgets.CloseLink(t.links[i])
+	}
+
+	t.objs.Close()
+}
+
+// Maps returns the BPF maps of the tracer, so they can be dumped for
+// debugging.
+func (t *Tracer) Maps() map[string]*ebpf.Map {
+	return mapdump.MapsOf(&t.objs)
+}
+
+func (t *Tracer) start() error {
+	spec, err := loadFstop()
+	if err != nil {
+		return fmt.Errorf("failed to load ebpf program: %w", err)
+	}
+
+	filterByMntNs := false
+
+	if t.config.MountnsMap != "" {
+		filterByMntNs = true
+		m := spec.Maps["mount_ns_set"]
+		m.Pinning = ebpf.PinByName
+		m.Name = filepath.Base(t.config.MountnsMap)
+	}
+
+	consts := map[string]interface{}{
+		"target_pid":       uint32(t.config.TargetPid),
+		"filter_by_mnt_ns": filterByMntNs,
+	}
+
+	if err := spec.RewriteConstants(consts); err != nil {
+		return fmt.Errorf("error RewriteConstants: %w", err)
+	}
+
+	opts := ebpf.CollectionOptions{
+		Maps: ebpf.MapOptions{
+			PinPath: filepath.Dir(t.config.MountnsMap),
+		},
+	}
+
+	if err := spec.LoadAndAssign(&t.objs, &opts); err != nil {
+		return fmt.Errorf("failed to load ebpf program: %w", err)
+	}
+
+	kprobes := []struct {
+		symbol string
+		prog   *ebpf.Program
+	}{
+		{"vfs_read", t.objs.VfsReadEntry},
+		{"vfs_write", t.objs.VfsWriteEntry},
+		{"vfs_open", t.objs.VfsOpenEntry},
+		{"vfs_fsync_range", t.objs.VfsFsyncRangeEntry},
+	}
+
+	for _, kp := range kprobes {
+		l, err := link.Kprobe(kp.symbol, kp.prog, nil)
+		if err != nil {
+			return fmt.Errorf("error opening kprobe %s: %w", kp.symbol, err)
+		}
+		t.links = append(t.links, l)
+	}
+
+	t.run()
+
+	return nil
+}
+
+func (t *Tracer) nextStats() ([]types.Stats, error) {
+	stats := []types.Stats{}
+
+	var prev *uint64 = nil
+	key := uint64(0)
+	entries := t.objs.Entries
+
+	defer func() {
+		// delete elements
+		err := entries.NextKey(nil, unsafe.Pointer(&key))
+		if err != nil {
+			return
+		}
+
+		for {
+			if err := entries.Delete(key); err != nil {
+				return
+			}
+
+			prev = &key
+			if err := entries.NextKey(unsafe.Pointer(prev), unsafe.Pointer(&key)); err != nil {
+				return
+			}
+		}
+	}()
+
+	// gather elements
+	err := entries.NextKey(nil, unsafe.Pointer(&key))
+	if err != nil {
+		if errors.Is(err, ebpf.ErrKeyNotExist) {
+			return stats, nil
+		}
+		return nil, fmt.Errorf("error getting next key: %w", err)
+	}
+
+	for {
+		fsStat := C.struct_fs_stat{}
+		if err := entries.Lookup(key, unsafe.Pointer(&fsStat)); err != nil {
+			return nil, err
+		}
+
+		stat := types.Stats{
+			MountNsID:  key,
+			Reads:      uint64(fsStat.reads),
+			Writes:     uint64(fsStat.writes),
+			ReadBytes:  uint64(fsStat.read_bytes),
+			WriteBytes: uint64(fsStat.write_bytes),
+			Opens:      uint64(fsStat.opens),
+			Fsyncs:     uint64(fsStat.fsyncs),
+			Node:       t.config.Node,
+		}
+
+		container := t.resolver.LookupContainerByMntns(stat.MountNsID)
+		if container != nil {
+			stat.Container = container.Name
+			stat.Pod = container.Podname
+			stat.Namespace = container.Namespace
+		}
+
+		stats = append(stats, stat)
+
+		prev = &key
+		if err := entries.NextKey(unsafe.Pointer(prev), unsafe.Pointer(&key)); err != nil {
+			if errors.Is(err, ebpf.ErrKeyNotExist) {
+				break
+			}
+			return nil, fmt.Errorf("error getting next key: %w", err)
+		}
+	}
+
+	types.SortStats(stats, t.config.SortBy)
+
+	return stats, nil
+}
+
+func (t *Tracer) run() {
+	ticker := time.NewTicker(t.config.Interval)
+
+	go func() {
+		for {
+			select {
+			case <-t.done:
+				ticker.Stop()
+				return
+			case <-ticker.C:
+				stats, err := t.nextStats()
+				if err != nil {
+					t.errorCallback(err)
+					return
+				}
+
+				rows := []types.Stats{}
+				for i := range stats {
+					stats[i].Alerts = t.config.Thresholds.Check(&stats[i], t.config.Interval)
+					if i < t.config.MaxRows || len(stats[i].Alerts) > 0 {
+						rows = append(rows, stats[i])
+					}
+				}
+				t.statsCallback(rows)
+			}
+		}
+	}()
+}
@@ -0,0 +1,135 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"fmt"
+	"sort"
+)
+
+type SortBy int
+
+const (
+	ALL SortBy = iota
+	READS
+	WRITES
+	RBYTES
+	WBYTES
+	OPENS
+	FSYNCS
+)
+
+const (
+	MaxRowsDefault  = 20
+	IntervalDefault = 1
+	SortByDefault   = ALL
+)
+
+const (
+	IntervalParam = "interval"
+	MaxRowsParam  = "max_rows"
+	SortByParam   = "sort_by"
+	PidParam      = "pid"
+)
+
+var SortBySlice = []string{
+	"all",
+	"reads",
+	"writes",
+	"rbytes",
+	"wbytes",
+	"opens",
+	"fsyncs",
+}
+
+func (s SortBy) String() string {
+	if int(s) < 0 || int(s) >= len(SortBySlice) {
+		return "INVALID"
+	}
+
+	return SortBySlice[int(s)]
+}
+
+func ParseSortBy(sortby string) (SortBy, error) {
+	for i, v := range SortBySlice {
+		if v == sortby {
+			return SortBy(i), nil
+		}
+	}
+	return ALL, fmt.Errorf("%q is not a valid sort by value", sortby)
+}
+
+// Event is the information the gadget sends to the client each capture
+// interval
+type Event struct {
+	Error string `json:"error,omitempty"`
+
+	// Warning is set when rows crossed the thresholds during the interval
+	// and the warnings are enabled.
+	Warning string `json:"warning,omitempty"`
+
+	// Node where the event comes from.
+	Node string `json:"node,omitempty"`
+
+	// Timestamp is when the interval ended, in nanoseconds since the
+	// epoch.
+	Timestamp int64 `json:"timestamp,omitempty"`
+
+	Stats []Stats `json:"stats,omitempty"`
+}
+
+// Stats represents the filesystem operations performed by a single
+// container, i.e. a mount namespace
+type Stats struct {
+	Node      string `json:"node,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Pod       string `json:"pod,omitempty"`
+	Container string `json:"container,omitempty"`
+
+	MountNsID  uint64 `json:"mountnsid,omitempty"`
+	Reads      uint64 `json:"reads,omitempty"`
+	Writes     uint64 `json:"writes,omitempty"`
+	ReadBytes  uint64 `json:"rbytes,omitempty"`
+	WriteBytes uint64 `json:"wbytes,omitempty"`
+	Opens      uint64 `json:"opens,omitempty"`
+	Fsyncs     uint64 `json:"fsyncs,omitempty"`
+
+	// Alerts are the thresholds crossed by the row during the interval.
+	Alerts []string `json:"alerts,omitempty"`
+}
+
+func SortStats(stats []Stats, sortBy SortBy) {
+	sort.Slice(stats, func(i, j int) bool {
+		a := stats[i]
+		b := stats[j]
+
+		switch sortBy {
+		case READS:
+			return a.Reads > b.Reads
+		case WRITES:
+			return a.Writes > b.Writes
+		case RBYTES:
+			return a.ReadBytes > b.ReadBytes
+		case WBYTES:
+			return a.WriteBytes > b.WriteBytes
+		case OPENS:
+			return a.Opens > b.Opens
+		case FSYNCS:
+			return a.Fsyncs > b.Fsyncs
+		default:
+			return a.ReadBytes+a.WriteBytes > b.ReadBytes+b.WriteBytes
+		}
+	})
+}
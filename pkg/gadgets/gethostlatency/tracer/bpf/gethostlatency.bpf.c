@@ -0,0 +1,116 @@
+// SPDX-License-Identifier: GPL-2.0
+// Copyright (c) 2022 The Inspektor Gadget authors
+// Based on gethostlatency(8) from BCC by Brendan Gregg.
+#include <vmlinux/vmlinux.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_tracing.h>
+#include "gethostlatency.h"
+
+const volatile bool filter_by_mnt_ns = false;
+
+/* Resolution started by each thread, between the entry and the return of
+ * a resolver function of the C library */
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, 10240);
+	__type(key, u32);
+	__type(value, struct start_t);
+} start SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_PERF_EVENT_ARRAY);
+	__uint(key_size, sizeof(u32));
+	__uint(value_size, sizeof(u32));
+} events SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, 1024);
+	__uint(key_size, sizeof(u64));
+	__uint(value_size, sizeof(u32));
+} mount_ns_set SEC(".maps");
+
+static __always_inline u64 get_mntns_id(void)
+{
+	struct task_struct *task;
+
+	task = (struct task_struct *) bpf_get_current_task();
+	return (u64) BPF_CORE_READ(task, nsproxy, mnt_ns, ns.inum);
+}
+
+static __always_inline int probe_entry(const char *host, int function)
+{
+	u32 tid = bpf_get_current_pid_tgid();
+	struct start_t s = {};
+	u64 mntns_id;
+
+	mntns_id = get_mntns_id();
+	if (filter_by_mnt_ns && !bpf_map_lookup_elem(&mount_ns_set, &mntns_id))
+		return 0;
+
+	s.ts = bpf_ktime_get_ns();
+	s.function = function;
+	bpf_probe_read_user_str(&s.host, sizeof(s.host), host);
+	bpf_map_update_elem(&start, &tid, &s, BPF_ANY);
+	return 0;
+}
+
+static __always_inline int probe_return(struct pt_regs *ctx, int ret)
+{
+	u64 pid_tgid = bpf_get_current_pid_tgid();
+	u32 tid = pid_tgid;
+	struct event event = {};
+	struct start_t *s;
+
+	s = bpf_map_lookup_elem(&start, &tid);
+	if (!s)
+		return 0;	/* missed entry */
+
+	event.delta_us = (bpf_ktime_get_ns() - s->ts) / 1000;
+	event.mntns_id = get_mntns_id();
+	event.pid = pid_tgid >> 32;
+	event.function = s->function;
+	event.ret = ret;
+	bpf_get_current_comm(&event.comm, sizeof(event.comm));
+	__builtin_memcpy(&event.host, s->host, sizeof(event.host));
+
+	bpf_perf_event_output(ctx, &events, BPF_F_CURRENT_CPU,
+			      &event, sizeof(event));
+
+	bpf_map_delete_elem(&start, &tid);
+	return 0;
+}
+
+SEC("uprobe/getaddrinfo")
+int BPF_KPROBE(ig_getaddrinfo_e, const char *node)
+{
+	return probe_entry(node, FUNCTION_GETADDRINFO);
+}
+
+SEC("uretprobe/getaddrinfo")
+int BPF_KRETPROBE(ig_getaddrinfo_x, int ret)
+{
+	return probe_return(ctx, ret);
+}
+
+SEC("uprobe/gethostbyname")
+int BPF_KPROBE(ig_gethostbyname_e, const char *name)
+{
+	return probe_entry(name, FUNCTION_GETHOSTBYNAME);
+}
+
+SEC("uprobe/gethostbyname2")
+int BPF_KPROBE(ig_gethostbyname2_e, const char *name)
+{
+	return probe_entry(name, FUNCTION_GETHOSTBYNAME2);
+}
+
+/* gethostbyname() and gethostbyname2() return a pointer, NULL on failure */
+SEC("uretprobe/gethostbyname")
+int BPF_KRETPROBE(ig_gethostbyname_x, void *ret)
+{
+	return probe_return(ctx, ret ? 0 : -1);
+}
+
+char LICENSE[] SEC("license") = "GPL";
@@ -0,0 +1,33 @@
+/* SPDX-License-Identifier: (LGPL-2.1 OR BSD-2-Clause) */
+#ifndef __GETHOSTLATENCY_H
+#define __GETHOSTLATENCY_H
+
+#define TASK_COMM_LEN 16
+#define HOST_LEN 80
+
+enum function {
+	FUNCTION_GETADDRINFO,
+	FUNCTION_GETHOSTBYNAME,
+	FUNCTION_GETHOSTBYNAME2,
+};
+
+struct start_t {
+	__u64 ts;
+	int function;
+	char host[HOST_LEN];
+};
+
+struct event {
+	__u64 delta_us;
+	__u64 mntns_id;
+	__u32 pid;
+	int function;
+	/* Return value of getaddrinfo() or, for gethostbyname() and
+	 * gethostbyname2(), -1 when they returned NULL.
+	 */
+	int ret;
+	char comm[TASK_COMM_LEN];
+	char host[HOST_LEN];
+};
+
+#endif /* __GETHOSTLATENCY_H */
@@ -0,0 +1,263 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+// #include <linux/types.h>
+// #include "./bpf/gethostlatency.h"
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+	"github.com/cilium/ebpf/perf"
+
+	containercollection "github.com/kinvolk/inspektor-gadget/pkg/container-collection"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/containerbinary"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/gethostlatency/types"
+	pb "github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/api"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+//go:generate sh -c "GOOS=$(go env GOHOSTOS) GOARCH=$(go env GOHOSTARCH) go run github.com/cilium/ebpf/cmd/bpf2go -target bpfel -cc clang gethostlatency ./bpf/gethostlatency.bpf.c -- -I./bpf/ -I../../.. -target bpf -D__TARGET_ARCH_x86"
+
+type Config struct {
+	// TODO: Make it a *ebpf.Map once
+	// https://github.com/cilium/ebpf/issues/515 and
+	// https://github.com/cilium/ebpf/issues/517 are fixed
+	MountnsMap string
+
+	// Library is the path of the C library in the containers.
+	Library string
+}
+
+var functionNames = map[C.int]string{
+	C.FUNCTION_GETADDRINFO:    "getaddrinfo",
+	C.FUNCTION_GETHOSTBYNAME:  "gethostbyname",
+	C.FUNCTION_GETHOSTBYNAME2: "gethostbyname2",
+}
+
+// probe is the uprobes and uretprobes attached to the resolver functions
+// of a C library.
+type probe []link.Link
+
+func (p probe) Close() {
+	for _, l := range p {
+		gadgets.CloseLink(l)
+	}
+}
+
+type Tracer struct {
+	config        *Config
+	resolver      containercollection.ContainerResolver
+	eventCallback func(types.Event)
+	node          string
+
+	objs     gethostlatencyObjects
+	reader   *perf.Reader
+	attacher *containerbinary.Attacher
+}
+
+func NewTracer(config *Config, resolver containercollection.ContainerResolver,
+	eventCallback func(types.Event), node string) (*Tracer, error) {
+	t := &Tracer{
+		config:        config,
+		resolver:      resolver,
+		eventCallback: eventCallback,
+		node:          node,
+	}
+	t.attacher = containerbinary.NewAttacher(config.Library, t.attach)
+
+	if err := t.start(); err != nil {
+		t.Stop()
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (t *Tracer) Stop() {
+	t.attacher.Close()
+
+	if t.reader != nil {
+		t.reader.Close()
+		t.reader = nil
+	}
+
+	t.objs.Close()
+}
+
+func (t *Tracer) start() error {
+	spec, err := loadGethostlatency()
+	if err != nil {
+		return fmt.Errorf("failed to load ebpf program: %w", err)
+	}
+
+	filterByMntNs := false
+	opts := ebpf.CollectionOptions{}
+
+	if t.config.MountnsMap != "" {
+		filterByMntNs = true
+		m := spec.Maps["mount_ns_set"]
+		m.Pinning = ebpf.PinByName
+		m.Name = filepath.Base(t.config.MountnsMap)
+		opts.Maps.PinPath = filepath.Dir(t.config.MountnsMap)
+	}
+
+	consts := map[string]interface{}{
+		"filter_by_mnt_ns": filterByMntNs,
+	}
+
+	if err := spec.RewriteConstants(consts); err != nil {
+		return fmt.Errorf("error RewriteConstants: %w", err)
+	}
+
+	if err := spec.LoadAndAssign(&t.objs, &opts); err != nil {
+		return fmt.Errorf("failed to load ebpf program: %w", err)
+	}
+
+	reader, err := perf.NewReader(t.objs.gethostlatencyMaps.Events, gadgets.PerfBufferPages*os.Getpagesize())
+	if err != nil {
+		return fmt.Errorf("error creating perf ring buffer: %w", err)
+	}
+	t.reader = reader
+
+	go t.run()
+
+	return nil
+}
+
+// AddContainer attaches the probes to the C library of the container if
+// they aren't attached to the same file yet. It returns
+// containerbinary.ErrBinaryNotFound if the container doesn't have the
+// library.
+func (t *Tracer) AddContainer(c *pb.ContainerDefinition) error {
+	return t.attacher.AddContainer(c.Pid, c.Mntns)
+}
+
+// RemoveContainer detaches the probes of the C library of the container
+// when no other container uses it.
+func (t *Tracer) RemoveContainer(c *pb.ContainerDefinition) {
+	t.attacher.RemoveContainer(c.Mntns)
+}
+
+// Attached returns the number of C libraries the probes are attached to.
+func (t *Tracer) Attached() int {
+	return t.attacher.Attached()
+}
+
+func (t *Tracer) attach(path string) (containerbinary.Probes, error) {
+	ex, err := link.OpenExecutable(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", t.config.Library, err)
+	}
+
+	// gethostbyname() and gethostbyname2() are obsolete: they are only
+	// traced when the library still has them.
+	probes := []struct {
+		symbol   string
+		prog     *ebpf.Program
+		ret      bool
+		optional bool
+	}{
+		{"getaddrinfo", t.objs.IgGetaddrinfoE, false, false},
+		{"getaddrinfo", t.objs.IgGetaddrinfoX, true, false},
+		{"gethostbyname", t.objs.IgGethostbynameE, false, true},
+		{"gethostbyname", t.objs.IgGethostbynameX, true, true},
+		{"gethostbyname2", t.objs.IgGethostbyname2E, false, true},
+		{"gethostbyname2", t.objs.IgGethostbynameX, true, true},
+	}
+
+	var p probe
+	for _, up := range probes {
+		var l link.Link
+		if up.ret {
+			l, err = ex.Uretprobe(up.symbol, up.prog, nil)
+		} else {
+			l, err = ex.Uprobe(up.symbol, up.prog, nil)
+		}
+		if err != nil {
+			if up.optional && errors.Is(err, link.ErrNoSymbol) {
+				continue
+			}
+			p.Close()
+			return nil, fmt.Errorf("attaching to %s in %s: %w", up.symbol, t.config.Library, err)
+		}
+		p = append(p, l)
+	}
+
+	return p, nil
+}
+
+func (t *Tracer) run() {
+	for {
+		record, err := t.reader.Read()
+		if err != nil {
+			if errors.Is(err, perf.ErrClosed) {
+				// nothing to do, we're done
+				return
+			}
+
+			msg := fmt.Sprintf("Error reading perf ring buffer: %s", err)
+			t.eventCallback(types.Base(eventtypes.Err(msg, t.node)))
+			return
+		}
+
+		if record.LostSamples > 0 {
+			msg := fmt.Sprintf("lost %d samples", record.LostSamples)
+			t.eventCallback(types.Base(eventtypes.Warn(msg, t.node)))
+			continue
+		}
+
+		eventC := (*C.struct_event)(unsafe.Pointer(&record.RawSample[0]))
+
+		event := types.Event{
+			Event: eventtypes.Event{
+				Type: eventtypes.NORMAL,
+				Node: t.node,
+			},
+			MountNsID: uint64(eventC.mntns_id),
+			Pid:       uint32(eventC.pid),
+			Comm:      C.GoString(&eventC.comm[0]),
+			Function:  functionNames[eventC.function],
+			Host:      C.GoString(&eventC.host[0]),
+			Latency:   uint64(eventC.delta_us),
+		}
+
+		if ret := int(eventC.ret); ret != 0 {
+			if eventC.function == C.FUNCTION_GETADDRINFO {
+				event.Error = types.EAIName(ret)
+			} else {
+				event.Error = "failed"
+			}
+		}
+
+		container := t.resolver.LookupContainerByMntns(event.MountNsID)
+		if container != nil {
+			event.Container = container.Name
+			event.Pod = container.Podname
+			event.Sandbox = container.Sandbox
+			event.Namespace = container.Namespace
+		}
+
+		t.eventCallback(event)
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+const (
+	// LibraryDefault is the default path, in the containers, of the C
+	// library whose resolver functions are traced.
+	LibraryDefault = "/lib/x86_64-linux-gnu/libc.so.6"
+)
+
+type Event struct {
+	eventtypes.Event
+
+	MountNsID uint64 `json:"mountnsid,omitempty"`
+	Pid       uint32 `json:"pid,omitempty"`
+	Comm      string `json:"pcomm,omitempty"`
+
+	// Function is the resolver function called: "getaddrinfo",
+	// "gethostbyname" or "gethostbyname2", and Host the name resolved.
+	Function string `json:"function,omitempty"`
+	Host     string `json:"host,omitempty"`
+
+	// Latency is the time spent in the function, in µs.
+	Latency uint64 `json:"latency,omitempty"`
+
+	// Error is the error of getaddrinfo(), e.g. "EAI_NONAME", or "failed"
+	// when gethostbyname() or gethostbyname2() returned NULL.
+	Error string `json:"error,omitempty"`
+}
+
+func Base(ev eventtypes.Event) Event {
+	return Event{
+		Event: ev,
+	}
+}
+
+// eaiNames are the names of the errors of getaddrinfo(), whose values are
+// the same in glibc and musl.
+var eaiNames = map[int]string{
+	-1:  "EAI_BADFLAGS",
+	-2:  "EAI_NONAME",
+	-3:  "EAI_AGAIN",
+	-4:  "EAI_FAIL",
+	-5:  "EAI_NODATA",
+	-6:  "EAI_FAMILY",
+	-7:  "EAI_SOCKTYPE",
+	-8:  "EAI_SERVICE",
+	-9:  "EAI_ADDRFAMILY",
+	-10: "EAI_MEMORY",
+	-11: "EAI_SYSTEM",
+	-12: "EAI_OVERFLOW",
+}
+
+// EAIName returns the name of an error returned by getaddrinfo().
+func EAIName(ret int) string {
+	if name, ok := eaiNames[ret]; ok {
+		return name
+	}
+	return "EAI_UNKNOWN"
+}
@@ -0,0 +1,120 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"encoding/binary"
+)
+
+// The frames of HTTP/2 are described in RFC 7540, section 6.
+const (
+	frameHeaderLen = 9
+
+	// defaultMaxFrameSize is the largest frame a peer can send unless the
+	// other peer allowed larger ones. Larger frames are rare and are not
+	// used to detect HTTP/2.
+	defaultMaxFrameSize = 1 << 14
+
+	frameTypeData         = 0x0
+	frameTypeHeaders      = 0x1
+	frameTypePriority     = 0x2
+	frameTypeRSTStream    = 0x3
+	frameTypeSettings     = 0x4
+	frameTypePushPromise  = 0x5
+	frameTypePing         = 0x6
+	frameTypeGoAway       = 0x7
+	frameTypeWindowUpdate = 0x8
+	frameTypeContinuation = 0x9
+)
+
+// clientPreface is sent by the client at the beginning of each HTTP/2
+// connection, before its first frame.
+var clientPreface = []byte("PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n")
+
+type frameHeader struct {
+	length   uint32
+	typ      uint8
+	flags    uint8
+	streamID uint32
+}
+
+// valid tells if the stream identifier and the length of a frame are the
+// ones expected for its type. Frames of unknown types are not valid.
+func (h *frameHeader) valid() bool {
+	switch h.typ {
+	case frameTypeData, frameTypeHeaders, frameTypeContinuation:
+		return h.streamID != 0
+	case frameTypePriority:
+		return h.streamID != 0 && h.length == 5
+	case frameTypeRSTStream:
+		return h.streamID != 0 && h.length == 4
+	case frameTypeSettings:
+		return h.streamID == 0 && h.length%6 == 0
+	case frameTypePushPromise:
+		return h.streamID != 0 && h.length >= 4
+	case frameTypePing:
+		return h.streamID == 0 && h.length == 8
+	case frameTypeGoAway:
+		return h.streamID == 0 && h.length >= 8
+	case frameTypeWindowUpdate:
+		return h.length == 4
+	}
+	return false
+}
+
+// parseFrames returns the headers of the frames of a TCP payload starting
+// with a frame header, and how many bytes of the last frame come after the
+// payload. It returns false if the header of the last frame is truncated.
+//
+// When strict is set, it also returns false if a header isn't valid or
+// uses the reserved bit: it's used to find out if a payload is HTTP/2
+// without seeing the beginning of the connection.
+func parseFrames(payload []byte, strict bool) ([]frameHeader, uint32, bool) {
+	var frames []frameHeader
+
+	for len(payload) > 0 {
+		if len(payload) < frameHeaderLen {
+			return frames, 0, false
+		}
+
+		h := frameHeader{
+			length:   uint32(payload[0])<<16 | uint32(payload[1])<<8 | uint32(payload[2]),
+			typ:      payload[3],
+			flags:    payload[4],
+			streamID: binary.BigEndian.Uint32(payload[5:9]),
+		}
+		if strict && (h.streamID&(1<<31) != 0 || h.length > defaultMaxFrameSize || !h.valid()) {
+			return frames, 0, false
+		}
+		h.streamID &^= 1 << 31
+		frames = append(frames, h)
+
+		end := frameHeaderLen + int(h.length)
+		if end > len(payload) {
+			return frames, uint32(end - len(payload)), true
+		}
+		payload = payload[end:]
+	}
+
+	return frames, 0, true
+}
+
+// isHTTP2 tells if a TCP payload is made of complete HTTP/2 frames. A
+// connection whose preface wasn't seen is only recognised by a segment
+// like this, which has a small chance to be found in other traffic.
+func isHTTP2(payload []byte) bool {
+	frames, remaining, ok := parseFrames(payload, true)
+	return ok && len(frames) > 0 && remaining == 0
+}
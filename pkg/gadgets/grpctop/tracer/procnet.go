@@ -0,0 +1,148 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// tcpListen is the state of the listening sockets in /proc/net/tcp.
+const tcpListen = 0x0a
+
+// socket is a TCP socket of a network namespace.
+type socket struct {
+	local     endpoint
+	remote    endpoint
+	listening bool
+}
+
+// readSockets returns the TCP sockets of the network namespace of a
+// process.
+func readSockets(pid uint32) ([]socket, error) {
+	var sockets []socket
+
+	for _, name := range []string{"tcp", "tcp6"} {
+		f, err := os.Open(fmt.Sprintf("/proc/%d/net/%s", pid, name))
+		if err != nil {
+			return nil, err
+		}
+		s, err := parseProcNetTCP(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse /proc/%d/net/%s: %w", pid, name, err)
+		}
+		sockets = append(sockets, s...)
+	}
+
+	return sockets, nil
+}
+
+// parseProcNetTCP parses the sockets of /proc/net/tcp or /proc/net/tcp6.
+func parseProcNetTCP(r io.Reader) ([]socket, error) {
+	var sockets []socket
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		// sl local_address rem_address st ...
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || fields[0] == "sl" {
+			continue
+		}
+
+		local, err := parseProcNetEndpoint(fields[1])
+		if err != nil {
+			return nil, err
+		}
+		remote, err := parseProcNetEndpoint(fields[2])
+		if err != nil {
+			return nil, err
+		}
+		state, err := strconv.ParseUint(fields[3], 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid state %q", fields[3])
+		}
+
+		sockets = append(sockets, socket{
+			local:     local,
+			remote:    remote,
+			listening: state == tcpListen,
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return sockets, nil
+}
+
+// parseProcNetEndpoint parses an address like 0100007F:1F90. The address
+// is made of 32-bit words in host byte order.
+func parseProcNetEndpoint(s string) (endpoint, error) {
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 {
+		return endpoint{}, fmt.Errorf("invalid address %q", s)
+	}
+
+	b, err := hex.DecodeString(parts[0])
+	if err != nil || (len(b) != net.IPv4len && len(b) != net.IPv6len) {
+		return endpoint{}, fmt.Errorf("invalid address %q", s)
+	}
+	ip := make(net.IP, len(b))
+	for i := 0; i < len(b); i += 4 {
+		binary.BigEndian.PutUint32(ip[i:], binary.LittleEndian.Uint32(b[i:]))
+	}
+
+	port, err := strconv.ParseUint(parts[1], 16, 16)
+	if err != nil {
+		return endpoint{}, fmt.Errorf("invalid port in %q", s)
+	}
+
+	return endpoint{addr: ip.String(), port: uint16(port)}, nil
+}
+
+// isClient tells if src is the client of the connection between src and
+// dst, given the sockets of the network namespace where the connection was
+// seen: the local endpoint is the server if its port is a listening one.
+// The peer using the highest port, likely an ephemeral one, is assumed to
+// be the client if the socket isn't found.
+func isClient(sockets []socket, src, dst endpoint) bool {
+	listening := make(map[uint16]bool)
+	for _, s := range sockets {
+		if s.listening {
+			listening[s.local.port] = true
+		}
+	}
+
+	for _, s := range sockets {
+		switch {
+		case s.listening:
+		case s.local == src && s.remote == dst:
+			return !listening[src.port]
+		case s.local == dst && s.remote == src:
+			return listening[dst.port]
+		}
+	}
+
+	return src.port > dst.port
+}
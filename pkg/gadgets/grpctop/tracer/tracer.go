@@ -0,0 +1,445 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/grpctop/types"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/threshold"
+	"github.com/kinvolk/inspektor-gadget/pkg/rawsock"
+)
+
+const (
+	// IdleTimeout is the time after which a connection without traffic
+	// is forgotten. It's recognised again if it's used later.
+	IdleTimeout = 5 * time.Minute
+
+	// maxConnections is the maximum number of connections followed in
+	// each network namespace.
+	maxConnections = 16384
+)
+
+type Config struct {
+	MaxRows  int
+	Interval time.Duration
+	SortBy   types.SortBy
+	Node     string
+
+	// Thresholds marks the rows crossing thresholds. These rows are
+	// reported even if they are not part of the first MaxRows ones.
+	Thresholds *threshold.Config
+}
+
+type endpoint struct {
+	addr string
+	port uint16
+}
+
+// connection identifies a TCP connection by its client and server
+// endpoints.
+type connection struct {
+	client endpoint
+	server endpoint
+}
+
+// direction is the position in the byte stream sent by a peer.
+type direction struct {
+	// synced is set when next is known. It's unset when a segment is
+	// lost, until a segment made of complete frames is seen.
+	synced bool
+
+	// next is the sequence number where the next frame header starts.
+	next uint32
+}
+
+type connState struct {
+	id connection
+
+	toServer direction
+	toClient direction
+
+	// lastStreamID is the highest stream identifier opened by the client.
+	lastStreamID uint32
+
+	lastSeen time.Time
+}
+
+// counts are the frames seen in a segment the stats are interested in.
+type counts struct {
+	connections uint64
+	streams     uint64
+	resets      uint64
+	goaways     uint64
+}
+
+type link struct {
+	listener *rawsock.Listener
+
+	// users count how many users called Attach(). This can happen for two reasons:
+	// 1. several containers in a pod (sharing the netns)
+	// 2. pods with networkHost=true
+	users int
+
+	// tracker follows the HTTP/2 connections seen in the network
+	// namespace. It's only used by the listener.
+	tracker *connTracker
+
+	// stats are the counters of the current interval by server. They are
+	// protected by Tracer.mu.
+	stats map[endpoint]*types.Stats
+}
+
+// Tracer follows the HTTP/2 connections seen on raw sockets opened in the
+// network namespaces of the pods, and counts the streams, RST_STREAM and
+// GOAWAY frames of each server. The frames are found by following the TCP
+// sequence numbers: only the frame headers are parsed, the payloads,
+// which are HPACK compressed, are skipped. Encrypted traffic can't be
+// parsed.
+type Tracer struct {
+	mu sync.Mutex
+
+	config        *Config
+	statsCallback func([]types.Stats)
+	errorCallback func(error)
+	done          chan bool
+
+	// key: namespace/podname
+	// value: link
+	attachments map[string]*link
+}
+
+func NewTracer(config *Config, statsCallback func([]types.Stats), errorCallback func(error)) (*Tracer, error) {
+	t := &Tracer{
+		config:        config,
+		statsCallback: statsCallback,
+		errorCallback: errorCallback,
+		done:          make(chan bool),
+		attachments:   make(map[string]*link),
+	}
+
+	t.run()
+
+	return t, nil
+}
+
+func (t *Tracer) Attach(key string, pid uint32) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if l, ok := t.attachments[key]; ok {
+		l.users++
+		return nil
+	}
+
+	listener, err := rawsock.NewListener(pid, rawsock.TCPFilter)
+	if err != nil {
+		return err
+	}
+
+	l := &link{
+		listener: listener,
+		users:    1,
+		tracker:  newConnTracker(pid),
+		stats:    make(map[endpoint]*types.Stats),
+	}
+	t.attachments[key] = l
+
+	go t.listen(key, l)
+
+	return nil
+}
+
+// listen reads the frames received on the raw socket until the link is
+// released.
+func (t *Tracer) listen(key string, l *link) {
+	lastExpiration := time.Now()
+
+	expire := func() {
+		if now := time.Now(); now.Sub(lastExpiration) >= time.Minute {
+			l.tracker.expire(now)
+			lastExpiration = now
+		}
+	}
+	handleFrame := func(frame []byte, _ uint8) {
+		p, ok := rawsock.ParsePacket(frame)
+		if !ok {
+			return
+		}
+		server, c := l.tracker.handleSegment(p, time.Now())
+		if c != (counts{}) {
+			t.mu.Lock()
+			addCounts(l.stats, server, c)
+			t.mu.Unlock()
+		}
+	}
+
+	if err := l.listener.Run(expire, handleFrame); err != nil {
+		t.errorCallback(fmt.Errorf("failed to listen on raw socket (%s): %w", key, err))
+	}
+}
+
+func addCounts(stats map[endpoint]*types.Stats, server endpoint, c counts) {
+	s, ok := stats[server]
+	if !ok {
+		s = &types.Stats{
+			Daddr: server.addr,
+			Dport: server.port,
+		}
+		stats[server] = s
+	}
+
+	s.Connections += c.connections
+	s.Streams += c.streams
+	s.Resets += c.resets
+	s.GoAways += c.goaways
+}
+
+// connTracker follows the HTTP/2 connections of a network namespace.
+type connTracker struct {
+	conns map[connection]*connState
+
+	// sockets returns the TCP sockets of the network namespace. They are
+	// only read to find the client of the connections whose beginning
+	// wasn't seen.
+	sockets func() ([]socket, error)
+}
+
+func newConnTracker(pid uint32) *connTracker {
+	return &connTracker{
+		conns: make(map[connection]*connState),
+		sockets: func() ([]socket, error) {
+			return readSockets(pid)
+		},
+	}
+}
+
+// isClient tells if src is the client of the connection between src and
+// dst.
+func (ct *connTracker) isClient(src, dst endpoint) bool {
+	sockets, err := ct.sockets()
+	if err != nil {
+		sockets = nil
+	}
+	return isClient(sockets, src, dst)
+}
+
+// expire forgets the connections without traffic for IdleTimeout.
+func (ct *connTracker) expire(now time.Time) {
+	for id, c := range ct.conns {
+		if now.Sub(c.lastSeen) >= IdleTimeout {
+			delete(ct.conns, id)
+		}
+	}
+}
+
+// handleSegment follows the HTTP/2 connection of a TCP segment and returns
+// the server of the connection and the frames of the segment to count.
+func (ct *connTracker) handleSegment(p *rawsock.Packet, now time.Time) (endpoint, counts) {
+
+	var cnt counts
+
+	src := endpoint{addr: p.Saddr.String(), port: p.Sport}
+	dst := endpoint{addr: p.Daddr.String(), port: p.Dport}
+
+	fromClient := true
+	c := ct.conns[connection{client: src, server: dst}]
+	if c == nil {
+		fromClient = false
+		c = ct.conns[connection{client: dst, server: src}]
+	}
+
+	if p.Flags&(rawsock.TCPFlagFin|rawsock.TCPFlagRst) != 0 {
+		if c != nil {
+			delete(ct.conns, c.id)
+		}
+		return endpoint{}, cnt
+	}
+
+	payload := p.Payload
+	seq := p.Seq
+	if len(payload) == 0 {
+		return endpoint{}, cnt
+	}
+
+	if c == nil {
+		if len(ct.conns) >= maxConnections {
+			return endpoint{}, cnt
+		}
+
+		switch {
+		case bytes.HasPrefix(payload, clientPreface):
+			c = &connState{id: connection{client: src, server: dst}}
+			c.toServer = direction{synced: true, next: seq + uint32(len(clientPreface))}
+			fromClient = true
+			cnt.connections++
+		case isHTTP2(payload):
+			// The beginning of the connection wasn't seen.
+			fromClient = ct.isClient(src, dst)
+			if fromClient {
+				c = &connState{id: connection{client: src, server: dst}}
+			} else {
+				c = &connState{id: connection{client: dst, server: src}}
+			}
+		default:
+			return endpoint{}, cnt
+		}
+
+		ct.conns[c.id] = c
+	}
+	c.lastSeen = now
+
+	d := &c.toClient
+	if fromClient {
+		d = &c.toServer
+	}
+
+	if !d.synced {
+		if !isHTTP2(payload) {
+			return c.id.server, cnt
+		}
+		*d = direction{synced: true, next: seq}
+	}
+
+	// Segments before next are retransmissions, the frames they hold were
+	// already counted. A gap after next means that a segment was lost.
+	offset := int32(d.next - seq)
+	if offset < 0 {
+		d.synced = false
+		return c.id.server, cnt
+	}
+	if int(offset) >= len(payload) {
+		return c.id.server, cnt
+	}
+
+	frames, remaining, ok := parseFrames(payload[offset:], false)
+	if ok {
+		d.next = seq + uint32(len(payload)) + remaining
+	} else {
+		d.synced = false
+	}
+
+	for _, f := range frames {
+		switch f.typ {
+		case frameTypeHeaders:
+			// Streams opened by the client have odd identifiers, which
+			// increase with each new stream.
+			if fromClient && f.streamID%2 == 1 && f.streamID > c.lastStreamID {
+				c.lastStreamID = f.streamID
+				cnt.streams++
+			}
+		case frameTypeRSTStream:
+			cnt.resets++
+		case frameTypeGoAway:
+			cnt.goaways++
+		}
+	}
+
+	return c.id.server, cnt
+}
+
+// splitKey returns the namespace and the name of the pod of an attachment
+// key. Both are empty for the host network namespace.
+func splitKey(key string) (string, string) {
+	keyParts := strings.SplitN(key, "/", 2)
+	if len(keyParts) != 2 {
+		return "", ""
+	}
+	return keyParts[0], keyParts[1]
+}
+
+func (t *Tracer) nextStats() []types.Stats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stats := []types.Stats{}
+
+	for key, l := range t.attachments {
+		namespace, pod := splitKey(key)
+		for _, s := range l.stats {
+			s.Node = t.config.Node
+			s.Namespace = namespace
+			s.Pod = pod
+			stats = append(stats, *s)
+		}
+		l.stats = make(map[endpoint]*types.Stats)
+	}
+
+	types.SortStats(stats, t.config.SortBy)
+
+	return stats
+}
+
+func (t *Tracer) run() {
+	ticker := time.NewTicker(t.config.Interval)
+
+	go func() {
+		for {
+			select {
+			case <-t.done:
+				ticker.Stop()
+				return
+			case <-ticker.C:
+				stats := t.nextStats()
+
+				rows := []types.Stats{}
+				for i := range stats {
+					stats[i].Alerts = t.config.Thresholds.Check(&stats[i], t.config.Interval)
+					if i < t.config.MaxRows || len(stats[i].Alerts) > 0 {
+						rows = append(rows, stats[i])
+					}
+				}
+				t.statsCallback(rows)
+			}
+		}
+	}()
+}
+
+// releaseLink stops the listener of the link. It must be called with t.mu
+// held.
+func (t *Tracer) releaseLink(key string, l *link) {
+	l.listener.Close()
+	delete(t.attachments, key)
+}
+
+func (t *Tracer) Detach(key string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if l, ok := t.attachments[key]; ok {
+		l.users--
+		if l.users == 0 {
+			t.releaseLink(key, l)
+		}
+		return nil
+	} else {
+		return fmt.Errorf("key not attached: %q", key)
+	}
+}
+
+func (t *Tracer) Stop() {
+	close(t.done)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for key, l := range t.attachments {
+		t.releaseLink(key, l)
+	}
+}
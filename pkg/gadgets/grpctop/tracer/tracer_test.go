@@ -0,0 +1,204 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kinvolk/inspektor-gadget/pkg/rawsock"
+)
+
+// frame returns an HTTP/2 frame with a payload of length zeros.
+func frame(typ uint8, streamID uint32, length int) []byte {
+	b := []byte{
+		byte(length >> 16), byte(length >> 8), byte(length),
+		typ, 0,
+		byte(streamID >> 24), byte(streamID >> 16), byte(streamID >> 8), byte(streamID),
+	}
+	return append(b, make([]byte, length)...)
+}
+
+func concat(parts ...[]byte) []byte {
+	var b []byte
+	for _, p := range parts {
+		b = append(b, p...)
+	}
+	return b
+}
+
+func TestParseFrames(t *testing.T) {
+	payload := concat(frame(frameTypeSettings, 0, 12), frame(frameTypeHeaders, 1, 20), frame(frameTypeData, 1, 100))
+
+	frames, remaining, ok := parseFrames(payload[:len(payload)-40], false)
+	if !ok || len(frames) != 3 || remaining != 40 {
+		t.Fatalf("unexpected result: %+v, %d, %t", frames, remaining, ok)
+	}
+	if frames[1].typ != frameTypeHeaders || frames[1].streamID != 1 || frames[1].length != 20 {
+		t.Fatalf("unexpected HEADERS frame: %+v", frames[1])
+	}
+
+	// Truncated header
+	if _, _, ok := parseFrames(payload[:25], false); ok {
+		t.Fatalf("truncated frame header should fail")
+	}
+
+	if !isHTTP2(payload) {
+		t.Fatalf("complete frames should be recognised as HTTP/2")
+	}
+	if isHTTP2(payload[:len(payload)-1]) {
+		t.Fatalf("incomplete frames shouldn't be recognised as HTTP/2")
+	}
+	if isHTTP2(frame(frameTypeSettings, 1, 12)) || isHTTP2(frame(frameTypeRSTStream, 1, 5)) || isHTTP2(frame(42, 0, 0)) {
+		t.Fatalf("invalid frames shouldn't be recognised as HTTP/2")
+	}
+	if isHTTP2([]byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n")) {
+		t.Fatalf("HTTP/1.1 shouldn't be recognised as HTTP/2")
+	}
+}
+
+type segment struct {
+	fromClient bool
+	seq        uint32
+	flags      uint8
+	payload    []byte
+}
+
+func runSegments(t *testing.T, ct *connTracker, clientPort uint16, segments []segment) counts {
+	var total counts
+
+	client, server := net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2")
+	for _, s := range segments {
+		p := &rawsock.Packet{
+			Saddr: client, Sport: clientPort,
+			Daddr: server, Dport: 50051,
+			Seq: s.seq, Flags: s.flags, Payload: s.payload,
+		}
+		if !s.fromClient {
+			p.Saddr, p.Sport, p.Daddr, p.Dport = server, 50051, client, clientPort
+		}
+
+		ep, c := ct.handleSegment(p, time.Now())
+		if c != (counts{}) && (ep.addr != "10.0.0.2" || ep.port != 50051) {
+			t.Fatalf("unexpected server %+v", ep)
+		}
+		total.connections += c.connections
+		total.streams += c.streams
+		total.resets += c.resets
+		total.goaways += c.goaways
+	}
+
+	return total
+}
+
+func TestHandleSegment(t *testing.T) {
+	// The client connects to a server in another pod: the server port
+	// isn't listening in the network namespace.
+	ct := &connTracker{
+		conns: make(map[connection]*connState),
+		sockets: func() ([]socket, error) {
+			return []socket{
+				{local: endpoint{"0.0.0.0", 8080}, listening: true},
+				{local: endpoint{"10.0.0.1", 34568}, remote: endpoint{"10.0.0.2", 50051}},
+			}, nil
+		},
+	}
+
+	data := frame(frameTypeData, 1, 30)
+	clientStart := uint32(1000)
+	first := concat(clientPreface, frame(frameTypeSettings, 0, 0), frame(frameTypeHeaders, 1, 10), data[:20])
+	second := concat(data[20:], frame(frameTypeHeaders, 3, 10), frame(frameTypeRSTStream, 3, 4))
+	third := concat(frame(frameTypeHeaders, 5, 10))
+
+	total := runSegments(t, ct, 34567, []segment{
+		{fromClient: true, seq: clientStart, payload: first},
+		{fromClient: false, seq: 5000, payload: concat(frame(frameTypeSettings, 0, 6), frame(frameTypeHeaders, 1, 10))},
+		{fromClient: true, seq: clientStart + uint32(len(first)), payload: second},
+		// Retransmission
+		{fromClient: true, seq: clientStart + uint32(len(first)), payload: second},
+		// Lost segment: the client direction isn't followed anymore
+		{fromClient: true, seq: clientStart + uint32(len(first)+len(second)+len(third)), payload: frame(frameTypeRSTStream, 5, 4)[:10]},
+		// Resynchronised on a segment made of complete frames
+		{fromClient: true, seq: 9000, payload: frame(frameTypeHeaders, 7, 10)},
+		{fromClient: false, seq: 5000 + 6 + 9 + 10 + 9, payload: frame(frameTypeGoAway, 0, 8)},
+	})
+
+	expected := counts{connections: 1, streams: 3, resets: 1, goaways: 1}
+	if total != expected {
+		t.Fatalf("unexpected counts %+v, expected %+v", total, expected)
+	}
+
+	runSegments(t, ct, 34567, []segment{{fromClient: false, seq: 6000, flags: rawsock.TCPFlagFin}})
+	if len(ct.conns) != 0 {
+		t.Fatalf("connection should be forgotten after FIN: %+v", ct.conns)
+	}
+
+	// Connection whose beginning wasn't seen
+	total = runSegments(t, ct, 34568, []segment{
+		{fromClient: true, seq: 100, payload: []byte("not http/2")},
+		{fromClient: false, seq: 200, payload: frame(frameTypeRSTStream, 9, 4)},
+		{fromClient: true, seq: 300, payload: frame(frameTypeHeaders, 11, 10)},
+	})
+	expected = counts{streams: 1, resets: 1}
+	if total != expected {
+		t.Fatalf("unexpected counts %+v, expected %+v", total, expected)
+	}
+	for id := range ct.conns {
+		if id.client.port != 34568 {
+			t.Fatalf("the client should be the local socket: %+v", id)
+		}
+	}
+
+	ct.expire(time.Now().Add(IdleTimeout))
+	if len(ct.conns) != 0 {
+		t.Fatalf("idle connection should be forgotten: %+v", ct.conns)
+	}
+}
+
+func TestParseProcNetTCP(t *testing.T) {
+	procNetTCP := `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:C383 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 21387 1 0000000000000000 100 0 0 10 0
+   1: 0100007F:C383 0100007F:A7F8 01 00000000:00000000 00:00000000 00000000     0        0 21388 1 0000000000000000 20 4 30 10 -1
+`
+	sockets, err := parseProcNetTCP(strings.NewReader(procNetTCP))
+	if err != nil {
+		t.Fatalf("failed to parse: %s", err)
+	}
+	expected := []socket{
+		{local: endpoint{"0.0.0.0", 50051}, remote: endpoint{"0.0.0.0", 0}, listening: true},
+		{local: endpoint{"127.0.0.1", 50051}, remote: endpoint{"127.0.0.1", 43000}},
+	}
+	if len(sockets) != len(expected) || sockets[0] != expected[0] || sockets[1] != expected[1] {
+		t.Fatalf("unexpected sockets %+v, expected %+v", sockets, expected)
+	}
+
+	// Both ends of the connection are in the network namespace
+	sockets = append(sockets, socket{local: endpoint{"127.0.0.1", 43000}, remote: endpoint{"127.0.0.1", 50051}})
+	server, client := endpoint{"127.0.0.1", 50051}, endpoint{"127.0.0.1", 43000}
+	if isClient(sockets, server, client) || !isClient(sockets, client, server) {
+		t.Fatalf("the client should be the peer not using the listening port")
+	}
+
+	ep, err := parseProcNetEndpoint("0000000000000000FFFF00000100007F:1F90")
+	if err != nil || ep != (endpoint{"127.0.0.1", 8080}) {
+		t.Fatalf("unexpected IPv4-mapped endpoint %+v: %v", ep, err)
+	}
+	ep, err = parseProcNetEndpoint("B80D0120000000000000000001000000:0050")
+	if err != nil || ep != (endpoint{"2001:db8::1", 80}) {
+		t.Fatalf("unexpected IPv6 endpoint %+v: %v", ep, err)
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"fmt"
+	"sort"
+)
+
+type SortBy int
+
+const (
+	STREAMS SortBy = iota
+	RESETS
+	GOAWAYS
+	CONNECTIONS
+)
+
+const (
+	MaxRowsDefault  = 20
+	IntervalDefault = 1
+	SortByDefault   = STREAMS
+)
+
+const (
+	IntervalParam = "interval"
+	MaxRowsParam  = "max_rows"
+	SortByParam   = "sort_by"
+)
+
+var SortBySlice = []string{
+	"streams",
+	"resets",
+	"goaways",
+	"connections",
+}
+
+func (s SortBy) String() string {
+	if int(s) < 0 || int(s) >= len(SortBySlice) {
+		return "INVALID"
+	}
+
+	return SortBySlice[int(s)]
+}
+
+func ParseSortBy(sortby string) (SortBy, error) {
+	for i, v := range SortBySlice {
+		if v == sortby {
+			return SortBy(i), nil
+		}
+	}
+	return STREAMS, fmt.Errorf("%q is not a valid sort by value", sortby)
+}
+
+// Event is the information the gadget sends to the client each capture
+// interval
+type Event struct {
+	Error string `json:"error,omitempty"`
+
+	// Warning is set when rows crossed the thresholds during the interval
+	// and the warnings are enabled.
+	Warning string `json:"warning,omitempty"`
+
+	// Node where the event comes from.
+	Node string `json:"node,omitempty"`
+
+	// Timestamp is when the interval ended, in nanoseconds since the
+	// epoch.
+	Timestamp int64 `json:"timestamp,omitempty"`
+
+	Stats []Stats `json:"stats,omitempty"`
+}
+
+// Stats represents the HTTP/2 activity seen in the network namespace of a
+// pod with a single server during the interval.
+type Stats struct {
+	Node      string `json:"node,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Pod       string `json:"pod,omitempty"`
+
+	// Daddr and Dport identify the server. The pod is either a client of
+	// this server or the server itself.
+	Daddr string `json:"daddr,omitempty"`
+	Dport uint16 `json:"dport,omitempty"`
+
+	// Connections is the number of new HTTP/2 connections, i.e. the
+	// number of client connection prefaces.
+	Connections uint64 `json:"connections"`
+
+	// Streams is the number of streams opened by the clients, i.e. the
+	// number of requests.
+	Streams uint64 `json:"streams"`
+
+	// Resets is the number of RST_STREAM frames sent by the clients or
+	// the server, each one cancelling a stream.
+	Resets uint64 `json:"resets"`
+
+	// GoAways is the number of GOAWAY frames, sent to shut down a
+	// connection.
+	GoAways uint64 `json:"goaways"`
+
+	// Alerts are the thresholds crossed by the row during the interval.
+	Alerts []string `json:"alerts,omitempty"`
+}
+
+func SortStats(stats []Stats, sortBy SortBy) {
+	sort.Slice(stats, func(i, j int) bool {
+		a := stats[i]
+		b := stats[j]
+
+		switch sortBy {
+		case RESETS:
+			return a.Resets > b.Resets
+		case GOAWAYS:
+			return a.GoAways > b.GoAways
+		case CONNECTIONS:
+			return a.Connections > b.Connections
+		default:
+			return a.Streams > b.Streams
+		}
+	})
+}
@@ -19,6 +19,7 @@ import (
 
 	"github.com/cilium/ebpf/link"
 	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/threshold"
 	pb "github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/api"
 	"k8s.io/apimachinery/pkg/types"
 )
@@ -58,11 +59,18 @@ func ContainerSelectorFromContainerFilter(f *gadgetv1alpha1.ContainerFilter) *pb
 	for k, v := range f.Labels {
 		labels = append(labels, &pb.Label{Key: k, Value: v})
 	}
+	annotations := []*pb.Label{}
+	for k, v := range f.Annotations {
+		annotations = append(annotations, &pb.Label{Key: k, Value: v})
+	}
 	return &pb.ContainerSelector{
-		Namespace: f.Namespace,
-		Podname:   f.Podname,
-		Labels:    labels,
-		Name:      f.ContainerName,
+		Namespace:   f.Namespace,
+		Podname:     f.Podname,
+		PodUid:      f.PodUID,
+		Sandbox:     f.Sandbox,
+		Labels:      labels,
+		Annotations: annotations,
+		Name:        f.ContainerName,
 	}
 }
 
@@ -73,3 +81,23 @@ func CloseLink(l link.Link) link.Link {
 	}
 	return nil
 }
+
+// ThresholdParameters documents the parameters of the thresholds supported
+// by the top gadgets, see the threshold package.
+func ThresholdParameters() []GadgetParameter {
+	return []GadgetParameter{
+		{
+			Name:        threshold.Param,
+			Description: "Comma-separated list of thresholds like sent>10MB or wbytes>=1MiB/s. The rows crossing them are marked and reported even beyond max_rows",
+		},
+		{
+			Name:        threshold.WarnParam,
+			Description: "Send a warning with the intervals where thresholds are crossed",
+			Default:     "false",
+		},
+		{
+			Name:        threshold.WebhookParam,
+			Description: "URL the rows crossing the thresholds are posted to, as JSON, from the nodes",
+		},
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// maxPathLen is the maximum length of the paths reported, longer ones are
+// truncated.
+const maxPathLen = 256
+
+var httpMethods = []string{
+	"GET", "HEAD", "POST", "PUT", "DELETE", "CONNECT", "OPTIONS", "TRACE", "PATCH",
+}
+
+// request holds the fields of a plaintext HTTP/1.x request the tracer is
+// interested in.
+type request struct {
+	method  string
+	path    string
+	version string
+	host    string
+}
+
+// firstLine returns the first line of a TCP payload without the line
+// terminator, and the rest of the payload.
+func firstLine(payload []byte) ([]byte, []byte, bool) {
+	i := bytes.IndexByte(payload, '\n')
+	if i < 0 {
+		return nil, nil, false
+	}
+	return bytes.TrimSuffix(payload[:i], []byte("\r")), payload[i+1:], true
+}
+
+// parseRequest parses the request line and the Host header of the HTTP/1.x
+// request at the beginning of a TCP payload. It returns nil if the payload
+// doesn't start with a request line. Only the headers in the same segment
+// as the request line are looked at.
+func parseRequest(payload []byte) *request {
+	line, headers, ok := firstLine(payload)
+	if !ok {
+		return nil
+	}
+
+	// method SP request-target SP HTTP-version
+	fields := bytes.Split(line, []byte(" "))
+	if len(fields) != 3 || !bytes.HasPrefix(fields[2], []byte("HTTP/1.")) {
+		return nil
+	}
+
+	req := &request{}
+	for _, method := range httpMethods {
+		if string(fields[0]) == method {
+			req.method = method
+			break
+		}
+	}
+	if req.method == "" || len(fields[1]) == 0 {
+		return nil
+	}
+
+	path := fields[1]
+	if len(path) > maxPathLen {
+		path = path[:maxPathLen]
+	}
+	req.path = string(path)
+	req.version = string(fields[2])
+
+	for {
+		var header []byte
+		header, headers, ok = firstLine(headers)
+		if !ok || len(header) == 0 {
+			break
+		}
+		i := bytes.IndexByte(header, ':')
+		if i >= 0 && bytes.EqualFold(header[:i], []byte("Host")) {
+			req.host = string(bytes.TrimSpace(header[i+1:]))
+			break
+		}
+	}
+
+	return req
+}
+
+// parseStatus returns the status code of the HTTP/1.x response at the
+// beginning of a TCP payload, or 0 if the payload doesn't start with a
+// status line.
+func parseStatus(payload []byte) int {
+	line, _, ok := firstLine(payload)
+	if !ok {
+		return 0
+	}
+
+	// HTTP-version SP status-code SP [reason-phrase]
+	fields := bytes.SplitN(line, []byte(" "), 3)
+	if len(fields) < 2 || !bytes.HasPrefix(fields[0], []byte("HTTP/1.")) || len(fields[1]) != 3 {
+		return 0
+	}
+
+	status, err := strconv.Atoi(string(fields[1]))
+	if err != nil || status < 100 {
+		return 0
+	}
+
+	return status
+}
@@ -0,0 +1,279 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/httpsnoop/types"
+	"github.com/kinvolk/inspektor-gadget/pkg/rawsock"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+const (
+	// ResponseTimeout is the time after which a request without response
+	// is reported without status code.
+	ResponseTimeout = 10 * time.Second
+
+	// maxPendingRequests is the maximum number of pipelined requests
+	// waiting for a response on a connection. The oldest one is reported
+	// without status code when it's reached.
+	maxPendingRequests = 16
+)
+
+type Config struct {
+	// Ports are the TCP ports of the HTTP servers. All the ports are
+	// inspected when it's empty.
+	Ports map[uint16]struct{}
+}
+
+// connection identifies a TCP connection by its client and server
+// endpoints.
+type connection struct {
+	client string
+	server string
+}
+
+type pendingRequest struct {
+	event     types.Event
+	timestamp time.Time
+}
+
+type link struct {
+	listener *rawsock.Listener
+
+	// users count how many users called Attach(). This can happen for two reasons:
+	// 1. several containers in a pod (sharing the netns)
+	// 2. pods with networkHost=true
+	users int
+}
+
+// Tracer parses the plaintext HTTP/1.x requests and responses seen on raw
+// sockets opened in the network namespaces of the containers. A response is
+// matched with the oldest request of the same connection without response,
+// as HTTP/1.x servers answer pipelined requests in order.
+type Tracer struct {
+	mu sync.Mutex
+
+	config *Config
+
+	// key: namespace/podname
+	// value: link
+	attachments map[string]*link
+}
+
+func NewTracer(config *Config) (*Tracer, error) {
+	t := &Tracer{
+		config:      config,
+		attachments: make(map[string]*link),
+	}
+
+	return t, nil
+}
+
+func (t *Tracer) Attach(
+	key string,
+	pid uint32,
+	eventCallback func(types.Event),
+	node string,
+) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if l, ok := t.attachments[key]; ok {
+		l.users++
+		return nil
+	}
+
+	listener, err := rawsock.NewListener(pid, rawsock.TCPFilter)
+	if err != nil {
+		return err
+	}
+
+	l := &link{
+		listener: listener,
+		users:    1,
+	}
+	t.attachments[key] = l
+
+	go t.listen(key, l, eventCallback, node)
+
+	return nil
+}
+
+func joinHostPort(ip net.IP, port uint16) string {
+	return net.JoinHostPort(ip.String(), strconv.Itoa(int(port)))
+}
+
+// listen reads the frames received on the raw socket until the link is
+// released.
+func (t *Tracer) listen(
+	key string,
+	l *link,
+	eventCallback func(types.Event),
+	node string,
+) {
+	pending := make(map[connection][]pendingRequest)
+
+	expire := func() {
+		expireRequests(pending, time.Now(), eventCallback)
+	}
+	handleFrame := func(frame []byte, _ uint8) {
+		t.handleFrame(frame, time.Now(), pending, eventCallback, node)
+	}
+
+	if err := l.listener.Run(expire, handleFrame); err != nil {
+		msg := fmt.Sprintf("failed to listen on raw socket (%s): %s", key, err)
+		eventCallback(types.Base(eventtypes.Err(msg, node)))
+	}
+}
+
+// expireRequests reports the requests which didn't get a response within
+// ResponseTimeout.
+func expireRequests(
+	pending map[connection][]pendingRequest,
+	now time.Time,
+	eventCallback func(types.Event),
+) {
+	for conn, requests := range pending {
+		for len(requests) > 0 && now.Sub(requests[0].timestamp) >= ResponseTimeout {
+			eventCallback(requests[0].event)
+			requests = requests[1:]
+		}
+		if len(requests) == 0 {
+			delete(pending, conn)
+		} else {
+			pending[conn] = requests
+		}
+	}
+}
+
+func (t *Tracer) handleFrame(
+	frame []byte,
+	now time.Time,
+	pending map[connection][]pendingRequest,
+	eventCallback func(types.Event),
+	node string,
+) {
+	p, ok := rawsock.ParsePacket(frame)
+	if !ok || len(p.Payload) == 0 {
+		return
+	}
+
+	if len(t.config.Ports) > 0 {
+		_, toServer := t.config.Ports[p.Dport]
+		_, fromServer := t.config.Ports[p.Sport]
+		if !toServer && !fromServer {
+			return
+		}
+	}
+
+	if req := parseRequest(p.Payload); req != nil {
+		conn := connection{
+			client: joinHostPort(p.Saddr, p.Sport),
+			server: joinHostPort(p.Daddr, p.Dport),
+		}
+		event := types.Event{
+			Event: eventtypes.Event{
+				Type: eventtypes.NORMAL,
+				Node: node,
+			},
+			Saddr:   p.Saddr.String(),
+			Sport:   p.Sport,
+			Daddr:   p.Daddr.String(),
+			Dport:   p.Dport,
+			Method:  req.method,
+			Path:    req.path,
+			Version: req.version,
+			Host:    req.host,
+		}
+
+		requests := pending[conn]
+		if len(requests) == maxPendingRequests {
+			eventCallback(requests[0].event)
+			requests = requests[1:]
+		}
+		pending[conn] = append(requests, pendingRequest{event: event, timestamp: now})
+		return
+	}
+
+	status := parseStatus(p.Payload)
+	if status == 0 {
+		return
+	}
+
+	// Responses are sent by the server: the connection is the other way
+	// around. Responses to requests sent before the tracer was attached
+	// are ignored.
+	conn := connection{
+		client: joinHostPort(p.Daddr, p.Dport),
+		server: joinHostPort(p.Saddr, p.Sport),
+	}
+	requests := pending[conn]
+	if len(requests) == 0 {
+		return
+	}
+
+	// 1xx responses are interim: the final response will follow.
+	if status < 200 {
+		return
+	}
+
+	event := requests[0].event
+	event.Status = status
+	event.Latency = uint64(now.Sub(requests[0].timestamp).Microseconds())
+	eventCallback(event)
+
+	if len(requests) == 1 {
+		delete(pending, conn)
+	} else {
+		pending[conn] = requests[1:]
+	}
+}
+
+// releaseLink stops the listener of the link. It must be called with t.mu
+// held.
+func (t *Tracer) releaseLink(key string, l *link) {
+	l.listener.Close()
+	delete(t.attachments, key)
+}
+
+func (t *Tracer) Detach(key string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if l, ok := t.attachments[key]; ok {
+		l.users--
+		if l.users == 0 {
+			t.releaseLink(key, l)
+		}
+		return nil
+	} else {
+		return fmt.Errorf("key not attached: %q", key)
+	}
+}
+
+func (t *Tracer) Close() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for key, l := range t.attachments {
+		t.releaseLink(key, l)
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/httpsnoop/types"
+	"github.com/kinvolk/inspektor-gadget/pkg/rawsock"
+)
+
+func TestParseRequest(t *testing.T) {
+	table := []struct {
+		payload  string
+		expected *request
+	}{
+		{
+			"GET /index.html HTTP/1.1\r\nUser-Agent: curl\r\nhost: example.com\r\n\r\n",
+			&request{method: "GET", path: "/index.html", version: "HTTP/1.1", host: "example.com"},
+		},
+		{
+			"POST /api HTTP/1.0\r\n\r\nHost: body.example.com\r\n",
+			&request{method: "POST", path: "/api", version: "HTTP/1.0"},
+		},
+		{"HTTP/1.1 200 OK\r\n\r\n", nil},
+		{"GETTING / HTTP/1.1\r\n", nil},
+		{"GET / HTTP/2\r\n", nil},
+		{"GET / HTTP/1.1", nil},
+		{"\x16\x03\x01\x02\x00\x01\x00\x01\xfc\x03\x03", nil},
+	}
+
+	for _, entry := range table {
+		req := parseRequest([]byte(entry.payload))
+		if entry.expected == nil {
+			if req != nil {
+				t.Fatalf("parseRequest(%q) = %+v, expected nil", entry.payload, req)
+			}
+			continue
+		}
+		if req == nil || *req != *entry.expected {
+			t.Fatalf("parseRequest(%q) = %+v, expected %+v", entry.payload, req, entry.expected)
+		}
+	}
+}
+
+func TestParseStatus(t *testing.T) {
+	table := []struct {
+		payload string
+		status  int
+	}{
+		{"HTTP/1.1 200 OK\r\nContent-Length: 0\r\n\r\n", 200},
+		{"HTTP/1.0 404 Not Found\r\n", 404},
+		{"HTTP/1.1 204\r\n", 204},
+		{"HTTP/1.1 2000 OK\r\n", 0},
+		{"HTTP/1.1 abc OK\r\n", 0},
+		{"GET / HTTP/1.1\r\n", 0},
+	}
+
+	for _, entry := range table {
+		if status := parseStatus([]byte(entry.payload)); status != entry.status {
+			t.Fatalf("parseStatus(%q) = %d, expected %d", entry.payload, status, entry.status)
+		}
+	}
+}
+
+// newFrame builds an Ethernet frame holding a TCP over IPv4 segment.
+func newFrame(saddr string, sport uint16, daddr string, dport uint16, payload string) []byte {
+	tcp := make([]byte, 20)
+	binary.BigEndian.PutUint16(tcp[0:2], sport)
+	binary.BigEndian.PutUint16(tcp[2:4], dport)
+	tcp[12] = 5 << 4
+	tcp = append(tcp, payload...)
+
+	ipv4 := make([]byte, 20)
+	ipv4[0] = 0x45
+	binary.BigEndian.PutUint16(ipv4[2:4], uint16(len(ipv4)+len(tcp)))
+	ipv4[9] = rawsock.ProtocolTCP
+	copy(ipv4[12:16], net.ParseIP(saddr).To4())
+	copy(ipv4[16:20], net.ParseIP(daddr).To4())
+
+	frame := make([]byte, rawsock.EthernetHeaderLen)
+	binary.BigEndian.PutUint16(frame[12:14], rawsock.EtherTypeIPv4)
+	frame = append(frame, ipv4...)
+	return append(frame, tcp...)
+}
+
+func TestHandleFrame(t *testing.T) {
+	tracer, _ := NewTracer(&Config{})
+	pending := make(map[connection][]pendingRequest)
+	var events []types.Event
+	callback := func(event types.Event) {
+		events = append(events, event)
+	}
+
+	start := time.Now()
+	frames := []struct {
+		frame []byte
+		delay time.Duration
+	}{
+		// Two pipelined requests
+		{newFrame("10.0.0.1", 34567, "10.0.0.2", 80, "GET /a HTTP/1.1\r\nHost: a\r\n\r\n"), 0},
+		{newFrame("10.0.0.1", 34567, "10.0.0.2", 80, "GET /b HTTP/1.1\r\nHost: a\r\n\r\n"), time.Millisecond},
+		// Response to a request sent on another connection
+		{newFrame("10.0.0.2", 80, "10.0.0.1", 34568, "HTTP/1.1 500 Error\r\n\r\n"), 2 * time.Millisecond},
+		{newFrame("10.0.0.2", 80, "10.0.0.1", 34567, "HTTP/1.1 100 Continue\r\n\r\n"), 3 * time.Millisecond},
+		{newFrame("10.0.0.2", 80, "10.0.0.1", 34567, "HTTP/1.1 200 OK\r\n\r\n"), 5 * time.Millisecond},
+		{newFrame("10.0.0.2", 80, "10.0.0.1", 34567, "HTTP/1.1 404 Not Found\r\n\r\n"), 8 * time.Millisecond},
+		// Request without response
+		{newFrame("10.0.0.1", 34569, "10.0.0.2", 80, "DELETE /c HTTP/1.1\r\n\r\n"), 9 * time.Millisecond},
+	}
+	for _, f := range frames {
+		tracer.handleFrame(f.frame, start.Add(f.delay), pending, callback, "node")
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %+v", events)
+	}
+	if events[0].Path != "/a" || events[0].Status != 200 || events[0].Latency != 5000 ||
+		events[0].Host != "a" || events[0].Saddr != "10.0.0.1" || events[0].Dport != 80 {
+		t.Fatalf("unexpected first event: %+v", events[0])
+	}
+	if events[1].Path != "/b" || events[1].Status != 404 || events[1].Latency != 7000 {
+		t.Fatalf("unexpected second event: %+v", events[1])
+	}
+
+	expireRequests(pending, start.Add(ResponseTimeout), callback)
+	if len(events) != 2 {
+		t.Fatalf("request expired too early")
+	}
+	expireRequests(pending, start.Add(ResponseTimeout+9*time.Millisecond), callback)
+	if len(events) != 3 || events[2].Method != "DELETE" || events[2].Status != 0 {
+		t.Fatalf("unexpected events after expiration: %+v", events)
+	}
+	if len(pending) != 0 {
+		t.Fatalf("pending requests left: %+v", pending)
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+const (
+	// PortsParam is the trace parameter holding the comma-separated list
+	// of TCP ports of the HTTP servers. The requests sent to any port are
+	// reported when it's empty.
+	PortsParam = "ports"
+)
+
+type Event struct {
+	eventtypes.Event
+
+	// Saddr and Sport identify the client side of the connection, Daddr
+	// and Dport the server side.
+	Saddr string `json:"saddr,omitempty"`
+	Sport uint16 `json:"sport,omitempty"`
+	Daddr string `json:"daddr,omitempty"`
+	Dport uint16 `json:"dport,omitempty"`
+
+	// Method, Path and Version come from the request line, Host from the
+	// Host header of the request.
+	Method  string `json:"method,omitempty"`
+	Path    string `json:"path,omitempty"`
+	Version string `json:"version,omitempty"`
+	Host    string `json:"host,omitempty"`
+
+	// Status is the status code of the response. It's 0 when the server
+	// didn't answer the request.
+	Status int `json:"status,omitempty"`
+
+	// Latency is the time in microseconds between the request and the
+	// response, as seen by the node.
+	Latency uint64 `json:"latency,omitempty"`
+}
+
+func Base(ev eventtypes.Event) Event {
+	return Event{
+		Event: ev,
+	}
+}
@@ -17,8 +17,10 @@ package gadgets
 import (
 	"sync"
 
+	"github.com/cilium/ebpf"
 	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
 	containercollection "github.com/kinvolk/inspektor-gadget/pkg/container-collection"
+	"github.com/kinvolk/inspektor-gadget/pkg/kernellog"
 
 	log "github.com/sirupsen/logrus"
 	apimachineryruntime "k8s.io/apimachinery/pkg/runtime"
@@ -53,10 +55,67 @@ type TraceFactoryWithScheme interface {
 	AddToScheme(*apimachineryruntime.Scheme)
 }
 
+// TraceFactoryWithKernelLog is implemented by the gadgets attaching the
+// messages of the kernel log about their events to them. SetKernelLog is
+// only called when the gadget pods are deployed with a kernel log source.
+type TraceFactoryWithKernelLog interface {
+	SetKernelLog(*kernellog.Log)
+}
+
+// TraceFactoryWithoutBPF is implemented by the gadgets that can run when the
+// gadget pods don't have the privileges needed by eBPF, reading /proc, the
+// cgroup filesystem or the container runtime instead. Only these gadgets are
+// registered in that case, and SetWithoutBPF is called before Initialize.
+type TraceFactoryWithoutBPF interface {
+	SetWithoutBPF()
+}
+
 type TraceFactoryWithDocumentation interface {
 	Description() string
 }
 
+// TraceFactoryWithParameters is implemented by the gadgets accepting
+// parameters in the Parameters field of the Trace resource. It is used to
+// generate the documentation and "kubectl gadget explain".
+type TraceFactoryWithParameters interface {
+	Parameters() []GadgetParameter
+}
+
+// TraceFactoryWithMaps is implemented by the gadgets keeping their state in
+// BPF maps (histograms, counters...). Maps returns the maps of the given
+// trace so they can be dumped with "kubectl gadget debug dump-maps", or nil
+// if the trace isn't running on this node.
+type TraceFactoryWithMaps interface {
+	Maps(name string) map[string]*ebpf.Map
+}
+
+// TraceFactoryWithEvents is implemented by the gadgets publishing JSON
+// events in the Stream output mode. NewEvent returns a pointer to a new
+// event of the type they publish, to decode them with NewEventDecoder.
+type TraceFactoryWithEvents interface {
+	NewEvent() Event
+}
+
+// GadgetParameter documents a parameter of a gadget.
+type GadgetParameter struct {
+	// Name is the key of the parameter in the Parameters field.
+	Name string `json:"name"`
+
+	// Description is a one-sentence description of the parameter.
+	Description string `json:"description"`
+
+	// Default is the value used when the parameter isn't set. It's empty
+	// when the parameter has no default value.
+	Default string `json:"default,omitempty"`
+
+	// Values lists the accepted values when only a fixed set of values
+	// is accepted.
+	Values []string `json:"values,omitempty"`
+
+	// Required is true when the gadget can't run without the parameter.
+	Required bool `json:"required,omitempty"`
+}
+
 // TraceOperation packages an operation on a gadget that users can call via the
 // annotation gadget.kinvolk.io/operation.
 type TraceOperation struct {
@@ -0,0 +1,163 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package irqs
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cilium/ebpf"
+
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	"github.com/kinvolk/inspektor-gadget/pkg/bpferror"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	irqstracer "github.com/kinvolk/inspektor-gadget/pkg/gadgets/irqs/tracer"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/irqs/types"
+)
+
+type Trace struct {
+	started bool
+	tracer  *irqstracer.Tracer
+}
+
+// TraceFactory implements the hardirqs and softirqs gadgets, which only
+// differ by the kind of interrupts traced.
+type TraceFactory struct {
+	gadgets.BaseFactory
+
+	kind string
+}
+
+func NewHardirqsFactory() gadgets.TraceFactory {
+	return &TraceFactory{
+		BaseFactory: gadgets.BaseFactory{DeleteTrace: deleteTrace},
+		kind:        types.KindHard,
+	}
+}
+
+func NewSoftirqsFactory() gadgets.TraceFactory {
+	return &TraceFactory{
+		BaseFactory: gadgets.BaseFactory{DeleteTrace: deleteTrace},
+		kind:        types.KindSoft,
+	}
+}
+
+func (f *TraceFactory) Description() string {
+	if f.kind == types.KindSoft {
+		return `The softirqs gadget records the time the CPUs of the node spend in the
+handlers of the soft interrupts, by vector (net_rx, timer, block...), and
+reports it when it is stopped along with the part of the CPU time of the node
+it represents. The interrupts aren't attributed to containers.`
+	}
+	return `The hardirqs gadget records the time the CPUs of the node spend in the
+handlers of the hard interrupts, by device, and reports it when it is stopped
+along with the part of the CPU time of the node it represents. The interrupts
+aren't attributed to containers.`
+}
+
+func (f *TraceFactory) OutputModesSupported() map[string]struct{} {
+	return map[string]struct{}{
+		"Status": {},
+	}
+}
+
+func (f *TraceFactory) Maps(name string) map[string]*ebpf.Map {
+	t, ok := f.LookupOrCreate(name, nil).(*Trace)
+	if !ok || !t.started {
+		return nil
+	}
+	return t.tracer.Maps()
+}
+
+func deleteTrace(name string, t interface{}) {
+	trace := t.(*Trace)
+	if trace.tracer != nil {
+		trace.tracer.Stop()
+	}
+}
+
+func (f *TraceFactory) Operations() map[string]gadgets.TraceOperation {
+	n := func() interface{} {
+		return &Trace{}
+	}
+
+	return map[string]gadgets.TraceOperation{
+		"start": {
+			Doc: fmt.Sprintf("Start %s", f.kind),
+			Operation: func(name string, trace *gadgetv1alpha1.Trace) {
+				f.LookupOrCreate(name, n).(*Trace).Start(trace, f.kind)
+			},
+		},
+		"stop": {
+			Doc: fmt.Sprintf("Stop %s and store results", f.kind),
+			Operation: func(name string, trace *gadgetv1alpha1.Trace) {
+				f.LookupOrCreate(name, n).(*Trace).Stop(trace)
+			},
+		},
+	}
+}
+
+func (t *Trace) Start(trace *gadgetv1alpha1.Trace, kind string) {
+	if t.started {
+		trace.Status.State = "Started"
+		return
+	}
+
+	if trace.Spec.Filter != nil {
+		trace.Status.OperationError = "The interrupts can't be attributed to containers: no filter is supported"
+		return
+	}
+
+	tracer, err := irqstracer.NewTracer(&irqstracer.Config{Kind: kind})
+	if err != nil {
+		trace.Status.OperationError = fmt.Sprintf("Failed to start: %s", bpferror.Describe(err))
+		return
+	}
+
+	t.tracer = tracer
+	t.started = true
+
+	trace.Status.Output = ""
+	trace.Status.State = "Started"
+}
+
+func (t *Trace) Stop(trace *gadgetv1alpha1.Trace) {
+	if !t.started {
+		trace.Status.OperationError = "Not started"
+		return
+	}
+
+	report, err := t.tracer.Report()
+
+	t.tracer.Stop()
+	t.tracer = nil
+	t.started = false
+
+	if err != nil {
+		trace.Status.OperationError = fmt.Sprintf("Failed to read results: %s", err)
+		return
+	}
+
+	report.Node = trace.Spec.Node
+
+	output, err := json.Marshal(report)
+	if err != nil {
+		trace.Status.OperationError = fmt.Sprintf("failed marshalling report: %s", err)
+		return
+	}
+
+	trace.Status.Output = string(output)
+	trace.Status.State = "Completed"
+}
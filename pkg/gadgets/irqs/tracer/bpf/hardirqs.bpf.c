@@ -0,0 +1,73 @@
+// SPDX-License-Identifier: GPL-2.0
+// Based on hardirqs(8) from libbpf-tools, Copyright (c) 2020 Wenbo Zhang
+#include <vmlinux/vmlinux.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_tracing.h>
+#include "irqs.h"
+
+#define MAX_ENTRIES	256
+
+/* When the handler started on the CPU */
+struct {
+	__uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+	__uint(max_entries, 1);
+	__type(key, u32);
+	__type(value, u64);
+} start SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, struct irq_key);
+	__type(value, struct irq_info);
+} infos SEC(".maps");
+
+static struct irq_info zero;
+
+SEC("raw_tracepoint/irq_handler_entry")
+int ig_irq_handler_entry(struct bpf_raw_tracepoint_args *ctx)
+{
+	u64 ts = bpf_ktime_get_ns();
+	u32 key = 0;
+
+	bpf_map_update_elem(&start, &key, &ts, BPF_ANY);
+	return 0;
+}
+
+SEC("raw_tracepoint/irq_handler_exit")
+int ig_irq_handler_exit(struct bpf_raw_tracepoint_args *ctx)
+{
+	struct irqaction *action = (struct irqaction *)ctx->args[1];
+	struct irq_key ikey = {};
+	struct irq_info *info;
+	u64 delta, *tsp;
+	u32 key = 0;
+
+	tsp = bpf_map_lookup_elem(&start, &key);
+	if (!tsp || *tsp == 0)
+		return 0;
+
+	delta = bpf_ktime_get_ns() - *tsp;
+	*tsp = 0;
+
+	bpf_probe_read_str(&ikey.name, sizeof(ikey.name), BPF_CORE_READ(action, name));
+
+	info = bpf_map_lookup_elem(&infos, &ikey);
+	if (!info) {
+		bpf_map_update_elem(&infos, &ikey, &zero, BPF_NOEXIST);
+		info = bpf_map_lookup_elem(&infos, &ikey);
+		if (!info)
+			return 0;
+	}
+
+	__sync_fetch_and_add(&info->count, 1);
+	__sync_fetch_and_add(&info->total_ns, delta);
+	/* Racy but good enough, like the BCC tools */
+	if (delta > info->max_ns)
+		info->max_ns = delta;
+
+	return 0;
+}
+
+char LICENSE[] SEC("license") = "GPL";
@@ -0,0 +1,19 @@
+/* SPDX-License-Identifier: (LGPL-2.1 OR BSD-2-Clause) */
+#ifndef __IRQS_H
+#define __IRQS_H
+
+#define IRQ_NAME_LEN	32
+#define NR_SOFTIRQS	10
+
+/* The hard interrupts are counted by name of the device handling them */
+struct irq_key {
+	char name[IRQ_NAME_LEN];
+};
+
+struct irq_info {
+	__u64 count;
+	__u64 total_ns;
+	__u64 max_ns;
+};
+
+#endif /* __IRQS_H */
@@ -0,0 +1,65 @@
+// SPDX-License-Identifier: GPL-2.0
+// Based on softirqs(8) from libbpf-tools, Copyright (c) 2020 Wenbo Zhang
+#include <vmlinux/vmlinux.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_tracing.h>
+#include "irqs.h"
+
+/* When the handler started on the CPU */
+struct {
+	__uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+	__uint(max_entries, 1);
+	__type(key, u32);
+	__type(value, u64);
+} start SEC(".maps");
+
+/* The soft interrupts are counted by vector number */
+struct {
+	__uint(type, BPF_MAP_TYPE_ARRAY);
+	__uint(max_entries, NR_SOFTIRQS);
+	__type(key, u32);
+	__type(value, struct irq_info);
+} infos SEC(".maps");
+
+SEC("raw_tracepoint/softirq_entry")
+int ig_softirq_entry(struct bpf_raw_tracepoint_args *ctx)
+{
+	u64 ts = bpf_ktime_get_ns();
+	u32 key = 0;
+
+	bpf_map_update_elem(&start, &key, &ts, BPF_ANY);
+	return 0;
+}
+
+SEC("raw_tracepoint/softirq_exit")
+int ig_softirq_exit(struct bpf_raw_tracepoint_args *ctx)
+{
+	u32 vec_nr = (u32)ctx->args[0];
+	struct irq_info *info;
+	u64 delta, *tsp;
+	u32 key = 0;
+
+	if (vec_nr >= NR_SOFTIRQS)
+		return 0;
+
+	tsp = bpf_map_lookup_elem(&start, &key);
+	if (!tsp || *tsp == 0)
+		return 0;
+
+	delta = bpf_ktime_get_ns() - *tsp;
+	*tsp = 0;
+
+	info = bpf_map_lookup_elem(&infos, &vec_nr);
+	if (!info)
+		return 0;
+
+	__sync_fetch_and_add(&info->count, 1);
+	__sync_fetch_and_add(&info->total_ns, delta);
+	/* Racy but good enough, like the BCC tools */
+	if (delta > info->max_ns)
+		info->max_ns = delta;
+
+	return 0;
+}
+
+char LICENSE[] SEC("license") = "GPL";
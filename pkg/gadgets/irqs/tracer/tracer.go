@@ -0,0 +1,183 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/irqs/types"
+	"github.com/kinvolk/inspektor-gadget/pkg/mapdump"
+)
+
+// #include <linux/types.h>
+// #include "./bpf/irqs.h"
+import "C"
+
+//go:generate sh -c "GOOS=$(go env GOHOSTOS) GOARCH=$(go env GOHOSTARCH) go run github.com/cilium/ebpf/cmd/bpf2go -target bpfel -cc clang hardirqs ./bpf/hardirqs.bpf.c -- -I./bpf/ -I../../.. -target bpf -D__TARGET_ARCH_x86"
+//go:generate sh -c "GOOS=$(go env GOHOSTOS) GOARCH=$(go env GOHOSTARCH) go run github.com/cilium/ebpf/cmd/bpf2go -target bpfel -cc clang softirqs ./bpf/softirqs.bpf.c -- -I./bpf/ -I../../.. -target bpf -D__TARGET_ARCH_x86"
+
+type Config struct {
+	// Kind is types.KindHard to trace the hard interrupts or
+	// types.KindSoft for the soft ones.
+	Kind string
+}
+
+type irqKey struct {
+	Name [C.IRQ_NAME_LEN]byte
+}
+
+type irqInfo struct {
+	Count   uint64
+	TotalNs uint64
+	MaxNs   uint64
+}
+
+type Tracer struct {
+	config   *Config
+	hardObjs hardirqsObjects
+	softObjs softirqsObjects
+
+	entryLink link.Link
+	exitLink  link.Link
+
+	started time.Time
+}
+
+func NewTracer(config *Config) (*Tracer, error) {
+	t := &Tracer{
+		config: config,
+	}
+
+	if err := t.start(); err != nil {
+		t.Stop()
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (t *Tracer) Stop() {
+	t.entryLink = gadgets.CloseLink(t.entryLink)
+	t.exitLink = gadgets.CloseLink(t.exitLink)
+
+	t.hardObjs.Close()
+	t.softObjs.Close()
+}
+
+// Maps returns the BPF maps of the tracer, so they can be dumped for
+// debugging.
+func (t *Tracer) Maps() map[string]*ebpf.Map {
+	if t.config.Kind == types.KindSoft {
+		return mapdump.MapsOf(&t.softObjs)
+	}
+	return mapdump.MapsOf(&t.hardObjs)
+}
+
+func (t *Tracer) start() error {
+	var entryProg, exitProg *ebpf.Program
+	var entry, exit string
+
+	switch t.config.Kind {
+	case types.KindHard:
+		spec, err := loadHardirqs()
+		if err != nil {
+			return fmt.Errorf("failed to load ebpf program: %w", err)
+		}
+		if err := spec.LoadAndAssign(&t.hardObjs, nil); err != nil {
+			return fmt.Errorf("failed to load ebpf program: %w", err)
+		}
+		entryProg, exitProg = t.hardObjs.IgIrqHandlerEntry, t.hardObjs.IgIrqHandlerExit
+		entry, exit = "irq_handler_entry", "irq_handler_exit"
+	case types.KindSoft:
+		spec, err := loadSoftirqs()
+		if err != nil {
+			return fmt.Errorf("failed to load ebpf program: %w", err)
+		}
+		if err := spec.LoadAndAssign(&t.softObjs, nil); err != nil {
+			return fmt.Errorf("failed to load ebpf program: %w", err)
+		}
+		entryProg, exitProg = t.softObjs.IgSoftirqEntry, t.softObjs.IgSoftirqExit
+		entry, exit = "softirq_entry", "softirq_exit"
+	default:
+		return fmt.Errorf("unknown kind of interrupts %q", t.config.Kind)
+	}
+
+	var err error
+	t.entryLink, err = link.AttachRawTracepoint(link.RawTracepointOptions{
+		Name:    entry,
+		Program: entryProg,
+	})
+	if err != nil {
+		return fmt.Errorf("error opening raw tracepoint %s: %w", entry, err)
+	}
+
+	t.exitLink, err = link.AttachRawTracepoint(link.RawTracepointOptions{
+		Name:    exit,
+		Program: exitProg,
+	})
+	if err != nil {
+		return fmt.Errorf("error opening raw tracepoint %s: %w", exit, err)
+	}
+
+	t.started = time.Now()
+
+	return nil
+}
+
+// Report returns the time spent in the interrupts since the tracer was
+// started.
+func (t *Tracer) Report() (*types.Report, error) {
+	var entries []types.Entry
+
+	if t.config.Kind == types.KindSoft {
+		for i, name := range types.SoftirqNames {
+			var info irqInfo
+			if err := t.softObjs.Infos.Lookup(uint32(i), &info); err != nil {
+				return nil, fmt.Errorf("error reading softirq %s: %w", name, err)
+			}
+			entries = append(entries, types.Entry{
+				Name:    name,
+				Count:   info.Count,
+				TotalNs: info.TotalNs,
+				MaxNs:   info.MaxNs,
+			})
+		}
+	} else {
+		var key irqKey
+		var info irqInfo
+		iter := t.hardObjs.Infos.Iterate()
+		for iter.Next(&key, &info) {
+			entries = append(entries, types.Entry{
+				Name:    string(bytes.TrimRight(key.Name[:], "\x00")),
+				Count:   info.Count,
+				TotalNs: info.TotalNs,
+				MaxNs:   info.MaxNs,
+			})
+		}
+		if err := iter.Err(); err != nil {
+			return nil, fmt.Errorf("error reading hardirqs: %w", err)
+		}
+	}
+
+	// The gadget pods aren't restricted to a subset of the CPUs.
+	return types.NewReport(t.config.Kind, entries, time.Since(t.started), runtime.NumCPU()), nil
+}
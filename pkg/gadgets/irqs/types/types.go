@@ -0,0 +1,103 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	KindHard = "hardirqs"
+	KindSoft = "softirqs"
+)
+
+// SoftirqNames are the names of the softirq vectors, in the order of their
+// numbers in the kernel.
+var SoftirqNames = []string{
+	"hi", "timer", "net_tx", "net_rx", "block", "irq_poll", "tasklet", "sched", "hrtimer", "rcu",
+}
+
+// Entry is the time spent in the handlers of an interrupt, identified by
+// the name of the device for the hard ones and of the vector for the soft
+// ones.
+type Entry struct {
+	Name    string `json:"name"`
+	Count   uint64 `json:"count"`
+	TotalNs uint64 `json:"totalNs"`
+	MaxNs   uint64 `json:"maxNs"`
+}
+
+// Report is the time spent in the interrupts of a node since the gadget was
+// started.
+type Report struct {
+	Node string `json:"node"`
+
+	// Kind is KindHard or KindSoft.
+	Kind string `json:"kind"`
+
+	// Duration is how long the interrupts were traced, and CPUs the
+	// number of CPUs of the node, to give the part of the CPU time spent
+	// in the interrupts.
+	Duration time.Duration `json:"duration"`
+	CPUs     int           `json:"cpus"`
+
+	// Entries are sorted by decreasing total time.
+	Entries []Entry `json:"entries"`
+}
+
+// NewReport returns the report of the entries traced during duration on
+// cpus CPUs. The entries without any interrupt are left out.
+func NewReport(kind string, entries []Entry, duration time.Duration, cpus int) *Report {
+	r := &Report{
+		Kind:     kind,
+		Duration: duration,
+		CPUs:     cpus,
+		Entries:  []Entry{},
+	}
+
+	for _, e := range entries {
+		if e.Count > 0 {
+			r.Entries = append(r.Entries, e)
+		}
+	}
+
+	sort.SliceStable(r.Entries, func(i, j int) bool {
+		if r.Entries[i].TotalNs != r.Entries[j].TotalNs {
+			return r.Entries[i].TotalNs > r.Entries[j].TotalNs
+		}
+		return r.Entries[i].Name < r.Entries[j].Name
+	})
+
+	return r
+}
+
+// TotalNs is the time spent in all the interrupts.
+func (r *Report) TotalNs() uint64 {
+	var total uint64
+	for _, e := range r.Entries {
+		total += e.TotalNs
+	}
+	return total
+}
+
+// CPUPercent returns the part of the CPU time of the node spent in the
+// handlers, i.e. not available to the processes.
+func (r *Report) CPUPercent(ns uint64) float64 {
+	if r.Duration <= 0 || r.CPUs <= 0 {
+		return 0
+	}
+	return float64(ns) * 100 / (float64(r.Duration.Nanoseconds()) * float64(r.CPUs))
+}
@@ -0,0 +1,55 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewReport(t *testing.T) {
+	entries := []Entry{
+		{Name: "hi"},
+		{Name: "timer", Count: 10, TotalNs: 2000, MaxNs: 500},
+		{Name: "net_rx", Count: 4, TotalNs: 8000, MaxNs: 4000},
+		{Name: "block", Count: 1, TotalNs: 2000, MaxNs: 2000},
+	}
+
+	r := NewReport(KindSoft, entries, time.Second, 2)
+
+	expected := []Entry{
+		{Name: "net_rx", Count: 4, TotalNs: 8000, MaxNs: 4000},
+		{Name: "block", Count: 1, TotalNs: 2000, MaxNs: 2000},
+		{Name: "timer", Count: 10, TotalNs: 2000, MaxNs: 500},
+	}
+	if !reflect.DeepEqual(r.Entries, expected) {
+		t.Fatalf("expected entries %+v, got %+v", expected, r.Entries)
+	}
+
+	if total := r.TotalNs(); total != 12000 {
+		t.Fatalf("expected a total of 12000ns, got %d", total)
+	}
+
+	// 12µs out of 2 CPUs during 1s
+	if pct := r.CPUPercent(r.TotalNs()); pct != 0.0006 {
+		t.Fatalf("expected 0.0006%% of the CPU time, got %v", pct)
+	}
+
+	empty := NewReport(KindHard, nil, 0, 2)
+	if len(empty.Entries) != 0 || empty.CPUPercent(10) != 0 {
+		t.Fatalf("expected an empty report, got %+v", empty)
+	}
+}
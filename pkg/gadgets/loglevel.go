@@ -0,0 +1,103 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgets
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultLogLevel is the level given to SetLogLevel to log the messages of
+// a gadget with the level of the gadget pod again.
+const DefaultLogLevel = "default"
+
+var (
+	// loggersMu protects loggers.
+	loggersMu sync.Mutex
+
+	// loggers are the loggers of the gadgets whose level was changed
+	// with "kubectl gadget debug set-log-level", by gadget name.
+	loggers = map[string]*log.Logger{}
+)
+
+// SetLogLevel changes the level of the messages logged by gadget through
+// Logger, e.g. to "debug" while investigating an issue, without changing
+// the one of the other gadgets. level is the name of a logrus level, or
+// DefaultLogLevel. It returns the level the gadget was using before.
+func SetLogLevel(gadget, level string) (string, error) {
+	loggersMu.Lock()
+	defer loggersMu.Unlock()
+
+	previous := DefaultLogLevel
+	if l, ok := loggers[gadget]; ok {
+		previous = l.GetLevel().String()
+	}
+
+	if level == DefaultLogLevel {
+		delete(loggers, gadget)
+		return previous, nil
+	}
+
+	lvl, err := log.ParseLevel(level)
+	if err != nil {
+		return "", fmt.Errorf("invalid level %q: expected %s or a logrus level", level, DefaultLogLevel)
+	}
+
+	// The logger writes like the standard one, only its level differs.
+	std := log.StandardLogger()
+	loggers[gadget] = &log.Logger{
+		Out:          std.Out,
+		Hooks:        std.Hooks,
+		Formatter:    std.Formatter,
+		ReportCaller: std.ReportCaller,
+		Level:        lvl,
+		ExitFunc:     std.ExitFunc,
+	}
+
+	return previous, nil
+}
+
+// Logger returns the logger of gadget, which uses the level set with
+// SetLogLevel, or the level of the gadget pod if none was set. The messages
+// have a "gadget" field.
+func Logger(gadget string) *log.Entry {
+	loggersMu.Lock()
+	l, ok := loggers[gadget]
+	loggersMu.Unlock()
+
+	if !ok {
+		l = log.StandardLogger()
+	}
+	return l.WithField("gadget", gadget)
+}
+
+// DebugGadgets returns the names of the gadgets whose level was set to
+// debug or trace with SetLogLevel, sorted.
+func DebugGadgets() []string {
+	loggersMu.Lock()
+	defer loggersMu.Unlock()
+
+	gadgets := []string{}
+	for gadget, l := range loggers {
+		if l.IsLevelEnabled(log.DebugLevel) {
+			gadgets = append(gadgets, gadget)
+		}
+	}
+	sort.Strings(gadgets)
+	return gadgets
+}
@@ -0,0 +1,70 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgets
+
+import (
+	"reflect"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestSetLogLevel(t *testing.T) {
+	defer SetLogLevel("execsnoop", DefaultLogLevel)
+	defer SetLogLevel("opensnoop", DefaultLogLevel)
+
+	if _, err := SetLogLevel("execsnoop", "verbose"); err == nil {
+		t.Fatalf("SetLogLevel accepted an invalid level")
+	}
+
+	previous, err := SetLogLevel("execsnoop", "debug")
+	if err != nil {
+		t.Fatalf("SetLogLevel failed: %s", err)
+	}
+	if previous != DefaultLogLevel {
+		t.Fatalf("got previous level %q, expected %q", previous, DefaultLogLevel)
+	}
+	if _, err := SetLogLevel("opensnoop", "error"); err != nil {
+		t.Fatalf("SetLogLevel failed: %s", err)
+	}
+
+	if level := Logger("execsnoop").Logger.GetLevel(); level != log.DebugLevel {
+		t.Fatalf("got level %s for execsnoop, expected debug", level)
+	}
+	if level := Logger("opensnoop").Logger.GetLevel(); level != log.ErrorLevel {
+		t.Fatalf("got level %s for opensnoop, expected error", level)
+	}
+	if logger := Logger("tcptop").Logger; logger != log.StandardLogger() {
+		t.Fatalf("tcptop doesn't use the standard logger")
+	}
+	if field := Logger("execsnoop").Data["gadget"]; field != "execsnoop" {
+		t.Fatalf("got gadget field %v, expected execsnoop", field)
+	}
+
+	if gadgets := DebugGadgets(); !reflect.DeepEqual(gadgets, []string{"execsnoop"}) {
+		t.Fatalf("got debug gadgets %v, expected [execsnoop]", gadgets)
+	}
+
+	previous, err = SetLogLevel("execsnoop", DefaultLogLevel)
+	if err != nil {
+		t.Fatalf("SetLogLevel failed: %s", err)
+	}
+	if previous != "debug" {
+		t.Fatalf("got previous level %q, expected debug", previous)
+	}
+	if gadgets := DebugGadgets(); len(gadgets) != 0 {
+		t.Fatalf("got debug gadgets %v, expected none", gadgets)
+	}
+}
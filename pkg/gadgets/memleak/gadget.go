@@ -0,0 +1,328 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memleak
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cilium/ebpf"
+	log "github.com/sirupsen/logrus"
+
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	"github.com/kinvolk/inspektor-gadget/pkg/bpferror"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/containerbinary"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/memleak/tracer"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/memleak/types"
+	pb "github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/api"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/pubsub"
+)
+
+type Trace struct {
+	resolver gadgets.Resolver
+
+	started bool
+	tracer  *tracer.Tracer
+
+	minAge time.Duration
+	top    int
+
+	// round is the number of reports generated since the trace was
+	// started.
+	round int
+}
+
+type TraceFactory struct {
+	gadgets.BaseFactory
+}
+
+func NewFactory() gadgets.TraceFactory {
+	return &TraceFactory{
+		BaseFactory: gadgets.BaseFactory{DeleteTrace: deleteTrace},
+	}
+}
+
+func (f *TraceFactory) Description() string {
+	return `The memleak gadget tracks the memory allocated and not freed yet by the
+processes of the selected containers, with the C library functions (malloc,
+calloc, realloc and free) and, optionally, the kernel slab allocator. Each
+report gives the stacks with the most bytes outstanding: a stack growing from
+a report to the next one points to a leak. Reports can be generated as many
+times as needed while the gadget keeps tracing, until it's stopped.`
+}
+
+func (f *TraceFactory) Parameters() []gadgets.GadgetParameter {
+	return []gadgets.GadgetParameter{
+		{
+			Name:        "mode",
+			Description: "Allocations to track: the ones of the C library, of the kernel or both",
+			Default:     types.ModeUser,
+			Values:      []string{types.ModeUser, types.ModeKernel, types.ModeAll},
+		},
+		{
+			Name:        "library",
+			Description: "Absolute path, in the containers, of the C library whose allocation functions are traced",
+			Default:     types.LibraryDefault,
+		},
+		{
+			Name:        "minage",
+			Description: "Minimum age in seconds of the allocations reported, to leave out the short-lived ones",
+			Default:     "0",
+		},
+		{
+			Name:        "top",
+			Description: "Number of stacks in a report, 0 for all of them",
+			Default:     strconv.Itoa(types.TopDefault),
+		},
+	}
+}
+
+func (f *TraceFactory) OutputModesSupported() map[string]struct{} {
+	return map[string]struct{}{
+		"Status": {},
+	}
+}
+
+func (f *TraceFactory) Maps(name string) map[string]*ebpf.Map {
+	t, ok := f.LookupOrCreate(name, nil).(*Trace)
+	if !ok || !t.started {
+		return nil
+	}
+	return t.tracer.Maps()
+}
+
+func deleteTrace(name string, t interface{}) {
+	trace := t.(*Trace)
+	if trace.started {
+		trace.resolver.Unsubscribe(genPubSubKey(name))
+		trace.tracer.Stop()
+		trace.tracer = nil
+	}
+}
+
+func (f *TraceFactory) Operations() map[string]gadgets.TraceOperation {
+	n := func() interface{} {
+		return &Trace{
+			resolver: f.Resolver,
+		}
+	}
+
+	return map[string]gadgets.TraceOperation{
+		"start": {
+			Doc: "Start tracking the allocations",
+			Operation: func(name string, trace *gadgetv1alpha1.Trace) {
+				f.LookupOrCreate(name, n).(*Trace).Start(trace)
+			},
+			Order: 1,
+		},
+		"report": {
+			Doc: "Report the stacks with the most bytes not freed yet and keep tracking the allocations",
+			Operation: func(name string, trace *gadgetv1alpha1.Trace) {
+				f.LookupOrCreate(name, n).(*Trace).Report(trace)
+			},
+			Order: 2,
+		},
+		"stop": {
+			Doc: "Report the stacks with the most bytes not freed yet and stop tracking the allocations",
+			Operation: func(name string, trace *gadgetv1alpha1.Trace) {
+				f.LookupOrCreate(name, n).(*Trace).Stop(trace)
+			},
+			Order: 3,
+		},
+	}
+}
+
+type pubSubKey string
+
+func genPubSubKey(name string) pubSubKey {
+	return pubSubKey(fmt.Sprintf("gadget/memleak/%s", name))
+}
+
+func (t *Trace) Start(trace *gadgetv1alpha1.Trace) {
+	if t.started {
+		trace.Status.State = "Started"
+		return
+	}
+
+	params := trace.Spec.Parameters
+
+	mode := types.ModeUser
+	if val, ok := params["mode"]; ok {
+		mode = val
+	}
+	config := &tracer.Config{
+		MountnsMap: gadgets.TracePinPath(trace.ObjectMeta.Namespace, trace.ObjectMeta.Name),
+		Library:    types.LibraryDefault,
+	}
+	switch mode {
+	case types.ModeUser:
+		config.User = true
+	case types.ModeKernel:
+		config.Kernel = true
+	case types.ModeAll:
+		config.User = true
+		config.Kernel = true
+	default:
+		trace.Status.OperationError = fmt.Sprintf("%q is not valid for mode", mode)
+		return
+	}
+
+	if val, ok := params["library"]; ok {
+		if !filepath.IsAbs(val) {
+			trace.Status.OperationError = "library must be set to an absolute path"
+			return
+		}
+		config.Library = filepath.Clean(val)
+	}
+
+	t.minAge = 0
+	if val, ok := params["minage"]; ok {
+		minAge, err := strconv.ParseUint(val, 10, 32)
+		if err != nil {
+			trace.Status.OperationError = fmt.Sprintf("%q is not valid for minage", val)
+			return
+		}
+		t.minAge = time.Duration(minAge) * time.Second
+	}
+
+	t.top = types.TopDefault
+	if val, ok := params["top"]; ok {
+		top, err := strconv.ParseUint(val, 10, 16)
+		if err != nil {
+			trace.Status.OperationError = fmt.Sprintf("%q is not valid for top", val)
+			return
+		}
+		t.top = int(top)
+	}
+
+	var err error
+	t.tracer, err = tracer.NewTracer(config, t.resolver)
+	if err != nil {
+		trace.Status.OperationError = fmt.Sprintf("failed to create tracer: %s", bpferror.Describe(err))
+		return
+	}
+
+	addContainer := func(container *pb.ContainerDefinition) error {
+		err := t.tracer.AddContainer(container)
+		if err != nil && !errors.Is(err, containerbinary.ErrBinaryNotFound) {
+			return fmt.Errorf("failed to trace container %s/%s/%s: %w",
+				container.Namespace, container.Podname, container.Name, err)
+		}
+		return nil
+	}
+
+	containerEventCallback := func(event pubsub.PubSubEvent) {
+		switch event.Type {
+		case pubsub.EventTypeAddContainer:
+			if err := addContainer(&event.Container); err != nil {
+				log.Warnf("Gadget %s: %s", trace.Spec.Gadget, err)
+			}
+		case pubsub.EventTypeRemoveContainer:
+			t.tracer.RemoveContainer(&event.Container)
+		}
+	}
+
+	existingContainers := t.resolver.Subscribe(
+		genPubSubKey(trace.ObjectMeta.Namespace+"/"+trace.ObjectMeta.Name),
+		*gadgets.ContainerSelectorFromContainerFilter(trace.Spec.Filter),
+		containerEventCallback,
+	)
+
+	// The failures on the containers existing when the trace starts are
+	// reported in the trace, the ones on the containers created afterwards
+	// in the logs.
+	var failures []string
+	for _, c := range existingContainers {
+		if err := addContainer(c); err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+
+	switch {
+	case len(failures) > 0:
+		trace.Status.OperationWarning = strings.Join(failures, "\n")
+	case config.User && t.tracer.Attached() == 0:
+		trace.Status.OperationWarning = fmt.Sprintf("%s not found in the selected containers, waiting for new ones", config.Library)
+	}
+
+	t.started = true
+	t.round = 0
+
+	trace.Status.Output = ""
+	trace.Status.State = "Started"
+}
+
+// report writes in the status of the trace the stacks with the most bytes
+// not freed yet.
+func (t *Trace) report(trace *gadgetv1alpha1.Trace) error {
+	report, err := t.tracer.Report(t.minAge, t.top)
+	if err != nil {
+		return fmt.Errorf("failed to read the allocations: %w", err)
+	}
+
+	t.round++
+	report.Node = trace.Spec.Node
+	report.Round = t.round
+
+	output, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed marshalling report: %w", err)
+	}
+
+	trace.Status.Output = string(output)
+
+	return nil
+}
+
+func (t *Trace) Report(trace *gadgetv1alpha1.Trace) {
+	if !t.started {
+		trace.Status.OperationError = "Not started"
+		return
+	}
+
+	if err := t.report(trace); err != nil {
+		trace.Status.OperationError = err.Error()
+		return
+	}
+
+	trace.Status.State = "Started"
+}
+
+func (t *Trace) Stop(trace *gadgetv1alpha1.Trace) {
+	if !t.started {
+		trace.Status.OperationError = "Not started"
+		return
+	}
+
+	err := t.report(trace)
+
+	t.resolver.Unsubscribe(genPubSubKey(trace.ObjectMeta.Namespace + "/" + trace.ObjectMeta.Name))
+	t.tracer.Stop()
+	t.tracer = nil
+	t.started = false
+
+	if err != nil {
+		trace.Status.OperationError = err.Error()
+		return
+	}
+
+	trace.Status.State = "Completed"
+}
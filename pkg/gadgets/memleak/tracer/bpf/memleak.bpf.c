@@ -0,0 +1,221 @@
+// SPDX-License-Identifier: GPL-2.0
+// Copyright (c) 2022 The Inspektor Gadget authors
+// Based on memleak(8) from libbpf-tools, Copyright (c) 2022 Sony Group Corporation
+#include <vmlinux/vmlinux.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_tracing.h>
+#include "memleak.h"
+
+#define MAX_ALLOCS	65536
+#define MAX_STACKS	10240
+
+const volatile bool filter_by_mnt_ns = false;
+
+/* Size requested by each thread between the entry and the return of an
+ * allocation function of the C library */
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, 10240);
+	__type(key, u64);
+	__type(value, u64);
+} sizes SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, MAX_ALLOCS);
+	__type(key, struct alloc_key);
+	__type(value, struct alloc_info);
+	__uint(map_flags, BPF_F_NO_PREALLOC);
+} allocs SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_STACK_TRACE);
+	__uint(max_entries, MAX_STACKS);
+	__uint(key_size, sizeof(u32));
+	__uint(value_size, PERF_MAX_STACK_DEPTH * sizeof(u64));
+} stack_traces SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, 1024);
+	__uint(key_size, sizeof(u64));
+	__uint(value_size, sizeof(u32));
+} mount_ns_set SEC(".maps");
+
+/*
+ * The fields of the kmem tracepoints read by the gadget. They are at the
+ * same offsets in the kmem_alloc event class and in the kmalloc and
+ * kmem_cache_alloc events replacing it in Linux 6.2, and in the kfree and
+ * kmem_cache_free events, so the structures generated in vmlinux.h, whose
+ * names changed between versions, aren't used.
+ */
+struct kmem_alloc_args {
+	u64 common;
+	unsigned long call_site;
+	const void *ptr;
+	size_t bytes_req;
+	size_t bytes_alloc;
+};
+
+struct kmem_free_args {
+	u64 common;
+	unsigned long call_site;
+	const void *ptr;
+};
+
+static __always_inline bool filtered(u64 *mntns_id)
+{
+	struct task_struct *task;
+
+	task = (struct task_struct *) bpf_get_current_task();
+	*mntns_id = (u64) BPF_CORE_READ(task, nsproxy, mnt_ns, ns.inum);
+
+	return filter_by_mnt_ns && !bpf_map_lookup_elem(&mount_ns_set, mntns_id);
+}
+
+static __always_inline void record_alloc(void *ctx, u64 address, u64 size, bool kernel)
+{
+	struct alloc_key key = {};
+	struct alloc_info info = {};
+	u64 mntns_id;
+
+	if (!address || filtered(&mntns_id))
+		return;
+
+	key.address = address;
+	key.pid = kernel ? 0 : bpf_get_current_pid_tgid() >> 32;
+
+	info.size = size;
+	info.timestamp_ns = bpf_ktime_get_ns();
+	info.mntns_id = mntns_id;
+	info.pid = bpf_get_current_pid_tgid() >> 32;
+	info.stack_id = bpf_get_stackid(ctx, &stack_traces, kernel ? 0 : BPF_F_USER_STACK);
+	info.kernel = kernel;
+	bpf_get_current_comm(&info.comm, sizeof(info.comm));
+
+	bpf_map_update_elem(&allocs, &key, &info, BPF_ANY);
+}
+
+static __always_inline int alloc_enter(size_t size)
+{
+	u64 pid_tgid = bpf_get_current_pid_tgid();
+	u64 mntns_id;
+
+	if (filtered(&mntns_id))
+		return 0;
+
+	bpf_map_update_elem(&sizes, &pid_tgid, &size, BPF_ANY);
+	return 0;
+}
+
+static __always_inline int alloc_exit(struct pt_regs *ctx)
+{
+	u64 pid_tgid = bpf_get_current_pid_tgid();
+	u64 *size;
+
+	size = bpf_map_lookup_elem(&sizes, &pid_tgid);
+	if (!size)
+		return 0;
+
+	record_alloc(ctx, PT_REGS_RC(ctx), *size, false);
+	bpf_map_delete_elem(&sizes, &pid_tgid);
+
+	return 0;
+}
+
+static __always_inline int free_enter(const void *address, bool kernel)
+{
+	struct alloc_key key = {};
+
+	key.address = (u64) address;
+	key.pid = kernel ? 0 : bpf_get_current_pid_tgid() >> 32;
+
+	bpf_map_delete_elem(&allocs, &key);
+	return 0;
+}
+
+SEC("uprobe/malloc")
+int BPF_KPROBE(ig_malloc_e, size_t size)
+{
+	return alloc_enter(size);
+}
+
+SEC("uretprobe/malloc")
+int BPF_KRETPROBE(ig_malloc_x)
+{
+	return alloc_exit(ctx);
+}
+
+SEC("uprobe/calloc")
+int BPF_KPROBE(ig_calloc_e, size_t nmemb, size_t size)
+{
+	return alloc_enter(nmemb * size);
+}
+
+SEC("uretprobe/calloc")
+int BPF_KRETPROBE(ig_calloc_x)
+{
+	return alloc_exit(ctx);
+}
+
+SEC("uprobe/realloc")
+int BPF_KPROBE(ig_realloc_e, void *ptr, size_t size)
+{
+	free_enter(ptr, false);
+	return alloc_enter(size);
+}
+
+SEC("uretprobe/realloc")
+int BPF_KRETPROBE(ig_realloc_x)
+{
+	return alloc_exit(ctx);
+}
+
+SEC("uprobe/free")
+int BPF_KPROBE(ig_free_e, void *ptr)
+{
+	return free_enter(ptr, false);
+}
+
+SEC("tracepoint/kmem/kmalloc")
+int ig_kmalloc(struct kmem_alloc_args *args)
+{
+	record_alloc(args, (u64) args->ptr, args->bytes_alloc, true);
+	return 0;
+}
+
+SEC("tracepoint/kmem/kmalloc_node")
+int ig_kmalloc_node(struct kmem_alloc_args *args)
+{
+	record_alloc(args, (u64) args->ptr, args->bytes_alloc, true);
+	return 0;
+}
+
+SEC("tracepoint/kmem/kfree")
+int ig_kfree(struct kmem_free_args *args)
+{
+	return free_enter(args->ptr, true);
+}
+
+SEC("tracepoint/kmem/kmem_cache_alloc")
+int ig_kmem_cache_alloc(struct kmem_alloc_args *args)
+{
+	record_alloc(args, (u64) args->ptr, args->bytes_alloc, true);
+	return 0;
+}
+
+SEC("tracepoint/kmem/kmem_cache_alloc_node")
+int ig_kmem_cache_alloc_node(struct kmem_alloc_args *args)
+{
+	record_alloc(args, (u64) args->ptr, args->bytes_alloc, true);
+	return 0;
+}
+
+SEC("tracepoint/kmem/kmem_cache_free")
+int ig_kmem_cache_free(struct kmem_free_args *args)
+{
+	return free_enter(args->ptr, true);
+}
+
+char LICENSE[] SEC("license") = "GPL";
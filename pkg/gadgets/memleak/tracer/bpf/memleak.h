@@ -0,0 +1,31 @@
+/* SPDX-License-Identifier: (LGPL-2.1 OR BSD-2-Clause) */
+#ifndef __MEMLEAK_H
+#define __MEMLEAK_H
+
+#define TASK_COMM_LEN		16
+#define PERF_MAX_STACK_DEPTH	127
+
+/*
+ * The address of an allocation. The same address can be allocated by
+ * several processes, so the user space allocations are keyed by pid too;
+ * pid is 0 for the kernel ones.
+ */
+struct alloc_key {
+	__u64 address;
+	__u32 pid;
+	__u32 pad;
+};
+
+/* An allocation not freed yet */
+struct alloc_info {
+	__u64 size;
+	/* When it was allocated, from bpf_ktime_get_ns() */
+	__u64 timestamp_ns;
+	__u64 mntns_id;
+	__u32 pid;
+	__s32 stack_id;
+	__u8 kernel;
+	__u8 comm[TASK_COMM_LEN];
+};
+
+#endif /* __MEMLEAK_H */
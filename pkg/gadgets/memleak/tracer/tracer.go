@@ -0,0 +1,328 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+// #include <linux/types.h>
+// #include "./bpf/memleak.h"
+import "C"
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+	"unsafe"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+	"golang.org/x/sys/unix"
+
+	containercollection "github.com/kinvolk/inspektor-gadget/pkg/container-collection"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/containerbinary"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/memleak/types"
+	pb "github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/api"
+	"github.com/kinvolk/inspektor-gadget/pkg/mapdump"
+	"github.com/kinvolk/inspektor-gadget/pkg/symbolizer"
+)
+
+//go:generate sh -c "GOOS=$(go env GOHOSTOS) GOARCH=$(go env GOHOSTARCH) go run github.com/cilium/ebpf/cmd/bpf2go -target bpfel -cc clang memleak ./bpf/memleak.bpf.c -- -I./bpf/ -I../../.. -target bpf -D__TARGET_ARCH_x86"
+
+type Config struct {
+	// MountnsMap is the path of the pinned map of the mount namespaces of
+	// the containers to trace. All the processes of the node are traced
+	// when it's empty.
+	// TODO: Make it a *ebpf.Map once
+	// https://github.com/cilium/ebpf/issues/515 and
+	// https://github.com/cilium/ebpf/issues/517 are fixed
+	MountnsMap string
+
+	// User traces the allocation functions of Library, the path of the C
+	// library in the containers, and Kernel the slab allocator.
+	User    bool
+	Kernel  bool
+	Library string
+}
+
+type allocKey struct {
+	Address uint64
+	Pid     uint32
+	_       uint32
+}
+
+// probe is the uprobes and uretprobes attached to the allocation functions
+// of a C library.
+type probe []link.Link
+
+func (p probe) Close() {
+	for _, l := range p {
+		gadgets.CloseLink(l)
+	}
+}
+
+type Tracer struct {
+	config   *Config
+	objs     memleakObjects
+	resolver containercollection.ContainerResolver
+
+	kernelLinks []link.Link
+	attacher    *containerbinary.Attacher
+}
+
+func NewTracer(config *Config, resolver containercollection.ContainerResolver) (*Tracer, error) {
+	t := &Tracer{
+		config:   config,
+		resolver: resolver,
+	}
+	t.attacher = containerbinary.NewAttacher(config.Library, t.attach)
+
+	if err := t.start(); err != nil {
+		t.Stop()
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (t *Tracer) Stop() {
+	t.attacher.Close()
+
+	for i := range t.kernelLinks {
+		t.kernelLinks[i] = gadgets.CloseLink(t.kernelLinks[i])
+	}
+	t.kernelLinks = nil
+
+	t.objs.Close()
+}
+
+// Maps returns the BPF maps of the tracer, so they can be dumped for
+// debugging.
+func (t *Tracer) Maps() map[string]*ebpf.Map {
+	return mapdump.MapsOf(&t.objs)
+}
+
+func (t *Tracer) start() error {
+	spec, err := loadMemleak()
+	if err != nil {
+		return fmt.Errorf("failed to load ebpf program: %w", err)
+	}
+
+	filterByMntNs := false
+	opts := ebpf.CollectionOptions{}
+
+	if t.config.MountnsMap != "" {
+		filterByMntNs = true
+		m := spec.Maps["mount_ns_set"]
+		m.Pinning = ebpf.PinByName
+		m.Name = filepath.Base(t.config.MountnsMap)
+		opts.Maps.PinPath = filepath.Dir(t.config.MountnsMap)
+	}
+
+	consts := map[string]interface{}{
+		"filter_by_mnt_ns": filterByMntNs,
+	}
+
+	if err := spec.RewriteConstants(consts); err != nil {
+		return fmt.Errorf("error RewriteConstants: %w", err)
+	}
+
+	if err := spec.LoadAndAssign(&t.objs, &opts); err != nil {
+		return fmt.Errorf("failed to load ebpf program: %w", err)
+	}
+
+	if !t.config.Kernel {
+		return nil
+	}
+
+	tracepoints := []struct {
+		name     string
+		prog     *ebpf.Program
+		optional bool
+	}{
+		{"kmalloc", t.objs.IgKmalloc, false},
+		{"kfree", t.objs.IgKfree, false},
+		{"kmem_cache_alloc", t.objs.IgKmemCacheAlloc, false},
+		{"kmem_cache_free", t.objs.IgKmemCacheFree, false},
+		// The _node variants were merged in the others in Linux 6.1
+		{"kmalloc_node", t.objs.IgKmallocNode, true},
+		{"kmem_cache_alloc_node", t.objs.IgKmemCacheAllocNode, true},
+	}
+
+	for _, tp := range tracepoints {
+		l, err := link.Tracepoint("kmem", tp.name, tp.prog, nil)
+		if err != nil {
+			if tp.optional {
+				continue
+			}
+			return fmt.Errorf("error opening tracepoint kmem:%s: %w", tp.name, err)
+		}
+		t.kernelLinks = append(t.kernelLinks, l)
+	}
+
+	return nil
+}
+
+// AddContainer attaches the probes to the C library of the container if
+// they aren't attached to the same file yet. It returns
+// containerbinary.ErrBinaryNotFound if the container doesn't have the
+// library.
+func (t *Tracer) AddContainer(c *pb.ContainerDefinition) error {
+	if !t.config.User {
+		return nil
+	}
+	return t.attacher.AddContainer(c.Pid, c.Mntns)
+}
+
+// RemoveContainer detaches the probes of the C library of the container
+// when no other container uses it.
+func (t *Tracer) RemoveContainer(c *pb.ContainerDefinition) {
+	t.attacher.RemoveContainer(c.Mntns)
+}
+
+// Attached returns the number of C libraries the probes are attached to.
+func (t *Tracer) Attached() int {
+	return t.attacher.Attached()
+}
+
+func (t *Tracer) attach(path string) (containerbinary.Probes, error) {
+	ex, err := link.OpenExecutable(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", t.config.Library, err)
+	}
+
+	probes := []struct {
+		symbol string
+		prog   *ebpf.Program
+		ret    bool
+	}{
+		{"malloc", t.objs.IgMallocE, false},
+		{"malloc", t.objs.IgMallocX, true},
+		{"calloc", t.objs.IgCallocE, false},
+		{"calloc", t.objs.IgCallocX, true},
+		{"realloc", t.objs.IgReallocE, false},
+		{"realloc", t.objs.IgReallocX, true},
+		{"free", t.objs.IgFreeE, false},
+	}
+
+	var p probe
+	for _, up := range probes {
+		var l link.Link
+		if up.ret {
+			l, err = ex.Uretprobe(up.symbol, up.prog, nil)
+		} else {
+			l, err = ex.Uprobe(up.symbol, up.prog, nil)
+		}
+		if err != nil {
+			p.Close()
+			return nil, fmt.Errorf("attaching to %s in %s: %w", up.symbol, t.config.Library, err)
+		}
+		p = append(p, l)
+	}
+
+	return p, nil
+}
+
+// Report returns the top stacks with the most bytes allocated and not
+// freed yet, considering only the allocations older than minAge.
+func (t *Tracer) Report(minAge time.Duration, top int) (*types.Report, error) {
+	var ts unix.Timespec
+	if err := unix.ClockGettime(unix.CLOCK_MONOTONIC, &ts); err != nil {
+		return nil, fmt.Errorf("error reading the time: %w", err)
+	}
+	// bpf_ktime_get_ns() uses the monotonic clock too
+	now := uint64(ts.Nano())
+
+	allocs := []types.Allocation{}
+	var key allocKey
+	var info C.struct_alloc_info
+	iter := t.objs.Allocs.Iterate()
+	for iter.Next(&key, unsafe.Pointer(&info)) {
+		if uint64(info.timestamp_ns)+uint64(minAge.Nanoseconds()) > now {
+			continue
+		}
+		allocs = append(allocs, types.Allocation{
+			MountNsID: uint64(info.mntns_id),
+			Pid:       uint32(info.pid),
+			Comm:      C.GoString((*C.char)(unsafe.Pointer(&info.comm[0]))),
+			Kernel:    info.kernel != 0,
+			StackID:   int32(info.stack_id),
+			Size:      uint64(info.size),
+		})
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("error reading allocations: %w", err)
+	}
+
+	report := types.NewReport(allocs, top)
+
+	var kernel *symbolizer.Kernel
+	processes := make(map[uint32]*symbolizer.Process)
+
+	for i := range report.Stacks {
+		s := &report.Stacks[i]
+
+		container := t.resolver.LookupContainerByMntns(s.MountNsID)
+		if container != nil {
+			s.Namespace = container.Namespace
+			s.Pod = container.Podname
+			s.Container = container.Name
+		}
+
+		if s.StackID < 0 {
+			s.Frames = []string{"[missing stack]"}
+			continue
+		}
+
+		var addresses [C.PERF_MAX_STACK_DEPTH]uint64
+		if err := t.objs.StackTraces.Lookup(uint32(s.StackID), &addresses); err != nil {
+			s.Frames = []string{"[missing stack]"}
+			continue
+		}
+
+		var resolve func(uint64) string
+		if s.Kernel {
+			if kernel == nil {
+				var err error
+				if kernel, err = symbolizer.NewKernel(); err != nil {
+					return nil, fmt.Errorf("error reading kernel symbols: %w", err)
+				}
+			}
+			resolve = kernel.Resolve
+		} else {
+			process, ok := processes[s.Pid]
+			if !ok {
+				// The process can be gone: its addresses are
+				// given in hexadecimal.
+				process, _ = symbolizer.NewProcess(s.Pid)
+				processes[s.Pid] = process
+			}
+			if process != nil {
+				resolve = process.Resolve
+			} else {
+				resolve = func(address uint64) string {
+					return fmt.Sprintf("0x%x", address)
+				}
+			}
+		}
+
+		for _, address := range addresses {
+			if address == 0 {
+				break
+			}
+			s.Frames = append(s.Frames, resolve(address))
+		}
+	}
+
+	return report, nil
+}
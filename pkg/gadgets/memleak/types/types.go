@@ -0,0 +1,147 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"sort"
+)
+
+const (
+	// ModeUser traces the allocations of the C library, ModeKernel the
+	// ones of the kernel slab allocator and ModeAll both.
+	ModeUser   = "user"
+	ModeKernel = "kernel"
+	ModeAll    = "all"
+
+	// TopDefault is the default number of stacks in a report.
+	TopDefault = 10
+
+	// LibraryDefault is the default path, in the containers, of the C
+	// library whose allocation functions are traced.
+	LibraryDefault = "/lib/x86_64-linux-gnu/libc.so.6"
+)
+
+// Allocation is an allocation not freed yet, as recorded by the tracer.
+type Allocation struct {
+	MountNsID uint64
+	Pid       uint32
+	Comm      string
+	Kernel    bool
+	// StackID identifies the stack in the stack trace map, it's negative
+	// when the stack couldn't be recorded.
+	StackID int32
+	Size    uint64
+}
+
+// Stack sums up the allocations not freed yet of a process made from the
+// same stack.
+type Stack struct {
+	Namespace string `json:"namespace,omitempty"`
+	Pod       string `json:"pod,omitempty"`
+	Container string `json:"container,omitempty"`
+
+	MountNsID uint64 `json:"mountnsid,omitempty"`
+	Pid       uint32 `json:"pid,omitempty"`
+	Comm      string `json:"comm,omitempty"`
+	Kernel    bool   `json:"kernel,omitempty"`
+
+	// Size is the number of bytes allocated and not freed yet, in
+	// Allocations allocations.
+	Size        uint64 `json:"size"`
+	Allocations uint64 `json:"allocations"`
+
+	// Frames are the symbolized addresses of the stack, innermost first.
+	Frames []string `json:"frames,omitempty"`
+
+	StackID int32 `json:"-"`
+}
+
+// Report lists the stacks with the most bytes not freed yet.
+type Report struct {
+	Node string `json:"node"`
+
+	// Round is the number of the report since the trace was started,
+	// starting from 1.
+	Round int `json:"round"`
+
+	// TotalSize and TotalAllocations count all the allocations not freed
+	// yet, not only the ones of the stacks reported.
+	TotalSize        uint64 `json:"totalSize"`
+	TotalAllocations uint64 `json:"totalAllocations"`
+
+	Stacks []Stack `json:"stacks,omitempty"`
+}
+
+type stackKey struct {
+	pid     uint32
+	kernel  bool
+	stackID int32
+}
+
+// NewReport sums up allocs by process and stack, and keeps the top stacks
+// with the most bytes not freed yet. All the stacks are kept when top is 0.
+// Frames are left empty.
+func NewReport(allocs []Allocation, top int) *Report {
+	report := &Report{}
+	stacks := make(map[stackKey]*Stack)
+
+	for _, a := range allocs {
+		report.TotalSize += a.Size
+		report.TotalAllocations++
+
+		key := stackKey{pid: a.Pid, kernel: a.Kernel, stackID: a.StackID}
+		s, ok := stacks[key]
+		if !ok {
+			s = &Stack{
+				MountNsID: a.MountNsID,
+				Pid:       a.Pid,
+				Comm:      a.Comm,
+				Kernel:    a.Kernel,
+				StackID:   a.StackID,
+			}
+			stacks[key] = s
+		}
+		s.Size += a.Size
+		s.Allocations++
+	}
+
+	report.Stacks = make([]Stack, 0, len(stacks))
+	for _, s := range stacks {
+		report.Stacks = append(report.Stacks, *s)
+	}
+
+	sort.Slice(report.Stacks, func(i, j int) bool {
+		si, sj := &report.Stacks[i], &report.Stacks[j]
+		if si.Size != sj.Size {
+			return si.Size > sj.Size
+		}
+		if si.Allocations != sj.Allocations {
+			return si.Allocations > sj.Allocations
+		}
+		if si.Pid != sj.Pid {
+			return si.Pid < sj.Pid
+		}
+		if si.Kernel != sj.Kernel {
+			return sj.Kernel
+		}
+		return si.StackID < sj.StackID
+	})
+
+	if top > 0 && len(report.Stacks) > top {
+		report.Stacks = report.Stacks[:top]
+	}
+
+	return report
+}
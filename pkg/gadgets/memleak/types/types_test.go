@@ -0,0 +1,55 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewReport(t *testing.T) {
+	allocs := []Allocation{
+		{Pid: 10, Comm: "app", StackID: 1, Size: 100},
+		{Pid: 10, Comm: "app", StackID: 1, Size: 50},
+		{Pid: 10, Comm: "app", StackID: 2, Size: 400},
+		{Pid: 10, Comm: "app", StackID: 2, Size: 4096, Kernel: true},
+		{Pid: 20, Comm: "db", StackID: 1, Size: 150},
+		{Pid: 20, Comm: "db", StackID: -14, Size: 8},
+	}
+
+	report := NewReport(allocs, 3)
+
+	if report.TotalSize != 4804 || report.TotalAllocations != 6 {
+		t.Fatalf("got totals %d bytes in %d allocations, expected 4804 bytes in 6 allocations",
+			report.TotalSize, report.TotalAllocations)
+	}
+
+	expected := []Stack{
+		{Pid: 10, Comm: "app", StackID: 2, Size: 4096, Allocations: 1, Kernel: true},
+		{Pid: 10, Comm: "app", StackID: 2, Size: 400, Allocations: 1},
+		{Pid: 10, Comm: "app", StackID: 1, Size: 150, Allocations: 2},
+	}
+	if !reflect.DeepEqual(report.Stacks, expected) {
+		t.Fatalf("got stacks %+v, expected %+v", report.Stacks, expected)
+	}
+
+	if all := NewReport(allocs, 0); len(all.Stacks) != 5 {
+		t.Fatalf("got %d stacks without a limit, expected 5", len(all.Stacks))
+	}
+
+	if empty := NewReport(nil, 3); empty.TotalSize != 0 || len(empty.Stacks) != 0 {
+		t.Fatalf("got %+v for no allocation, expected an empty report", empty)
+	}
+}
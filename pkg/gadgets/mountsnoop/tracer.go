@@ -0,0 +1,40 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mountsnoop
+
+import (
+	containercollection "github.com/kinvolk/inspektor-gadget/pkg/container-collection"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/mountsnoop/tracer"
+	coretracer "github.com/kinvolk/inspektor-gadget/pkg/gadgets/mountsnoop/tracer/core"
+	standardtracer "github.com/kinvolk/inspektor-gadget/pkg/gadgets/mountsnoop/tracer/standard"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/mountsnoop/types"
+)
+
+// NewTracer creates a mountsnoop tracer calling eventCallback for each event. It
+// uses the CO-RE implementation if possible and falls back to the BCC one
+// otherwise.
+func NewTracer(config *tracer.Config, resolver containercollection.ContainerResolver,
+	eventCallback func(types.Event), node string,
+) (tracer.Tracer, error) {
+	return gadgets.NewTracerWithFallback("mountsnoop",
+		func() (gadgets.Tracer, error) {
+			return coretracer.NewTracer(config, resolver, eventCallback, node)
+		},
+		func() (gadgets.Tracer, error) {
+			return standardtracer.NewTracer(config, resolver, eventCallback, node)
+		},
+	)
+}
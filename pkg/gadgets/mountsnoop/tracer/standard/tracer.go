@@ -57,10 +57,15 @@ func NewTracer(config *tracer.Config, resolver containercollection.ContainerReso
 		eventCallback(event)
 	}
 
+	args := []string{"--json", "--containersmap", "/sys/fs/bpf/gadget/containers"}
+
+	// Without a mount namespace map, all the processes are traced.
+	if config.MountnsMap != "" {
+		args = append(args, "--mntnsmap", config.MountnsMap)
+	}
+
 	baseTracer, err := gadgets.NewStandardTracer(lineCallback,
-		"/usr/share/bcc/tools/mountsnoop",
-		"--json", "--mntnsmap", config.MountnsMap,
-		"--containersmap", "/sys/fs/bpf/gadget/containers")
+		"/usr/share/bcc/tools/mountsnoop", args...)
 	if err != nil {
 		return nil, err
 	}
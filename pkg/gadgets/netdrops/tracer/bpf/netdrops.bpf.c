@@ -0,0 +1,146 @@
+// SPDX-License-Identifier: GPL-2.0
+#include <vmlinux/vmlinux.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_tracing.h>
+
+#include "netdrops.h"
+
+#define MAX_ENTRIES	10240
+
+/* Defined here because of conflicts with include files */
+#define TC_ACT_UNSPEC	-1
+
+/* The reason of the drops was added in Linux 5.17 */
+struct trace_event_raw_kfree_skb___reason {
+	int reason;
+} __attribute__((preserve_access_index));
+
+/* Interfaces of the traced pods, filled by the userspace */
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, struct iface_key);
+	__type(value, struct iface);
+} ifaces SEC(".maps");
+
+/* Drops by pod, direction and reason, read and reset by the userspace at
+ * each interval */
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, struct drop_key);
+	__type(value, struct counter);
+} drops SEC(".maps");
+
+/* Packets going through the host side interfaces of the pods */
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, struct counter_key);
+	__type(value, struct counter);
+} counters SEC(".maps");
+
+static __always_inline void add(struct counter *c, __u64 bytes)
+{
+	__sync_fetch_and_add(&c->packets, 1);
+	__sync_fetch_and_add(&c->bytes, bytes);
+}
+
+static __always_inline void count(void *map, void *key, __u64 bytes)
+{
+	struct counter zero = {}, *c;
+
+	c = bpf_map_lookup_elem(map, key);
+	if (!c) {
+		bpf_map_update_elem(map, key, &zero, BPF_NOEXIST);
+		c = bpf_map_lookup_elem(map, key);
+		if (!c)
+			return;
+	}
+	add(c, bytes);
+}
+
+static __always_inline int count_packet(struct __sk_buff *skb, __u32 direction)
+{
+	struct counter_key key = {
+		.ifindex = skb->ifindex,
+		.direction = direction,
+	};
+
+	count(&counters, &key, skb->len);
+
+	/* Let the other programs attached to the interface, e.g. by the CNI,
+	 * decide what to do with the packet. */
+	return TC_ACT_UNSPEC;
+}
+
+/* Attached to the ingress of the host side interface: the packets sent by
+ * the pod */
+SEC("classifier")
+int ig_nd_tc_ingress(struct __sk_buff *skb)
+{
+	return count_packet(skb, DIRECTION_SENT);
+}
+
+/* Attached to the egress of the host side interface: the packets received
+ * by the pod */
+SEC("classifier")
+int ig_nd_tc_egress(struct __sk_buff *skb)
+{
+	return count_packet(skb, DIRECTION_RECEIVED);
+}
+
+SEC("tracepoint/skb/kfree_skb")
+int ig_nd_kfree_skb(struct trace_event_raw_kfree_skb *ctx)
+{
+	struct trace_event_raw_kfree_skb___reason *ctx_reason = (void *) ctx;
+	struct sk_buff *skb = ctx->skbaddr;
+	struct iface_key iface_key = {};
+	struct drop_key drop_key = {};
+	struct net_device *dev;
+	struct iface *iface;
+	int iif;
+
+	dev = BPF_CORE_READ(skb, dev);
+	if (dev) {
+		iface_key.netns = BPF_CORE_READ(dev, nd_net.net, ns.inum);
+		iface_key.ifindex = BPF_CORE_READ(dev, ifindex);
+	} else {
+		iface_key.netns = BPF_CORE_READ(skb, sk, __sk_common.skc_net.net, ns.inum);
+	}
+	if (!iface_key.netns)
+		return 0;
+
+	/* skb_iif is the interface the packet was received from, 0 for the
+	 * packets sent by the local stack. */
+	iif = BPF_CORE_READ(skb, skb_iif);
+
+	iface = bpf_map_lookup_elem(&ifaces, &iface_key);
+	if (iface) {
+		if (iface->side == SIDE_POD)
+			drop_key.direction = iif == iface_key.ifindex ?
+				DIRECTION_RECEIVED : DIRECTION_SENT;
+		else
+			drop_key.direction = iif == iface_key.ifindex ?
+				DIRECTION_SENT : DIRECTION_RECEIVED;
+	} else {
+		/* Another interface of the pod, e.g. the loopback one, or a
+		 * packet not associated to an interface yet. */
+		iface_key.ifindex = 0;
+		iface = bpf_map_lookup_elem(&ifaces, &iface_key);
+		if (!iface)
+			return 0;
+		drop_key.direction = iif ? DIRECTION_RECEIVED : DIRECTION_SENT;
+	}
+
+	drop_key.pod = iface->pod;
+	if (bpf_core_field_exists(ctx_reason->reason))
+		drop_key.reason = BPF_CORE_READ(ctx_reason, reason);
+
+	count(&drops, &drop_key, BPF_CORE_READ(skb, len));
+
+	return 0;
+}
+
+char LICENSE[] SEC("license") = "GPL";
@@ -0,0 +1,43 @@
+/* SPDX-License-Identifier: (LGPL-2.1 OR BSD-2-Clause) */
+#ifndef __NETDROPS_H
+#define __NETDROPS_H
+
+/* Packets sent by the pod */
+#define DIRECTION_SENT 1
+/* Packets received by the pod */
+#define DIRECTION_RECEIVED 2
+
+/* The interface is in the network namespace of the pod */
+#define SIDE_POD 1
+/* The interface is the peer of the pod's one in the host network namespace */
+#define SIDE_HOST 2
+
+/* An interface of a pod, or the whole network namespace of the pod when
+ * ifindex is 0. */
+struct iface_key {
+	__u32 netns;
+	__u32 ifindex;
+};
+
+struct iface {
+	__u32 pod;
+	__u32 side;
+};
+
+struct drop_key {
+	__u32 pod;
+	__u32 direction;
+	__u32 reason;
+};
+
+struct counter_key {
+	__u32 ifindex;
+	__u32 direction;
+};
+
+struct counter {
+	__u64 packets;
+	__u64 bytes;
+};
+
+#endif /* __NETDROPS_H */
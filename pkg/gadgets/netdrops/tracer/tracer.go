@@ -0,0 +1,516 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+// #include <linux/types.h>
+// #include "./bpf/netdrops.h"
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+
+	"github.com/kinvolk/inspektor-gadget/pkg/dropreasons"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/netdrops/types"
+	pb "github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/api"
+	"github.com/kinvolk/inspektor-gadget/pkg/netnsenter"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+//go:generate sh -c "GOOS=$(go env GOHOSTOS) GOARCH=$(go env GOHOSTARCH) go run github.com/cilium/ebpf/cmd/bpf2go -target bpfel -cc clang netdrops ./bpf/netdrops.bpf.c -- -I./bpf/ -I../../.. -target bpf -D__TARGET_ARCH_x86"
+
+// pollInterval is the interval at which the drops and the counters are
+// read and reported.
+const pollInterval = time.Second
+
+const (
+	// filterName is the name of the tc filters attached to the
+	// interfaces of the pods.
+	filterName = "ig-netdrops"
+
+	// filterPriority is the priority of the tc filters. They run before
+	// the ones of the CNI, which could redirect the packets, and let
+	// them decide what to do with the packets.
+	filterPriority = 1
+)
+
+// nextFilterHandle is the handle of the tc filters of the next tracer, so
+// that several tracers can be attached to the same interface.
+var (
+	nextFilterHandleMu sync.Mutex
+	nextFilterHandle   uint32 = 0xf00
+)
+
+type Config struct {
+	// NetnsHost is the network namespace of the host, where the peers of
+	// the interfaces of the pods are.
+	NetnsHost uint64
+}
+
+type ifaceKey struct {
+	Netns   uint32
+	Ifindex uint32
+}
+
+type iface struct {
+	Pod  uint32
+	Side uint32
+}
+
+type dropKey struct {
+	Pod       uint32
+	Direction uint32
+	Reason    uint32
+}
+
+type counterKey struct {
+	Ifindex   uint32
+	Direction uint32
+}
+
+type counter struct {
+	Packets uint64
+	Bytes   uint64
+}
+
+// pod is a traced pod, shared by its containers.
+type pod struct {
+	id        uint32
+	namespace string
+	name      string
+	netns     uint64
+	users     int
+
+	podIfindex  int
+	hostIfindex int
+	hostIface   string
+
+	filters []*netlink.BpfFilter
+
+	// counters of the interface and drops at the previous interval
+	counters map[counterKey]counter
+	drops    map[dropKey]counter
+}
+
+type Tracer struct {
+	config        *Config
+	objs          netdropsObjects
+	kfreeSkbLink  link.Link
+	eventCallback func(types.Event)
+	node          string
+	reasons       map[uint32]string
+	filterHandle  uint32
+
+	mu sync.Mutex
+	// pods by network namespace
+	pods   map[uint64]*pod
+	nextID uint32
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewTracer(c *Config, eventCallback func(types.Event), node string) (*Tracer, error) {
+	nextFilterHandleMu.Lock()
+	filterHandle := nextFilterHandle
+	nextFilterHandle++
+	nextFilterHandleMu.Unlock()
+
+	t := &Tracer{
+		config:        c,
+		eventCallback: eventCallback,
+		node:          node,
+		filterHandle:  filterHandle,
+		pods:          make(map[uint64]*pod),
+		nextID:        1,
+		done:          make(chan struct{}),
+	}
+
+	if err := t.start(); err != nil {
+		t.Stop()
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (t *Tracer) Stop() {
+	if t.done != nil {
+		close(t.done)
+		t.done = nil
+	}
+	t.wg.Wait()
+
+	t.mu.Lock()
+	for netns, p := range t.pods {
+		t.removeFilters(p)
+		delete(t.pods, netns)
+	}
+	t.mu.Unlock()
+
+	t.kfreeSkbLink = gadgets.CloseLink(t.kfreeSkbLink)
+
+	t.objs.Close()
+}
+
+func (t *Tracer) start() error {
+	var err error
+
+	t.reasons, err = dropreasons.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read the reasons of the drops: %w", err)
+	}
+
+	spec, err := loadNetdrops()
+	if err != nil {
+		return fmt.Errorf("failed to load ebpf program: %w", err)
+	}
+
+	if err := spec.LoadAndAssign(&t.objs, nil); err != nil {
+		return fmt.Errorf("failed to load ebpf program: %w", err)
+	}
+
+	t.kfreeSkbLink, err = link.Tracepoint("skb", "kfree_skb", t.objs.IgNdKfreeSkb, nil)
+	if err != nil {
+		return fmt.Errorf("error opening tracepoint: %w", err)
+	}
+
+	t.wg.Add(1)
+	go t.poll()
+
+	return nil
+}
+
+// AddContainer starts tracing the interface of the container's pod. The
+// pod is only traced once, whatever the number of its containers.
+func (t *Tracer) AddContainer(c *pb.ContainerDefinition) error {
+	if c.Netns == t.config.NetnsHost {
+		return errors.New("the pod uses the host network")
+	}
+
+	t.mu.Lock()
+	if p, ok := t.pods[c.Netns]; ok {
+		p.users++
+		t.mu.Unlock()
+		return nil
+	}
+	t.mu.Unlock()
+
+	p := &pod{
+		namespace: c.Namespace,
+		name:      c.Podname,
+		netns:     c.Netns,
+		users:     1,
+		counters:  make(map[counterKey]counter),
+		drops:     make(map[dropKey]counter),
+	}
+
+	err := netnsenter.NetnsEnter(int(c.Pid), func() error {
+		var err error
+		p.podIfindex, p.hostIfindex, err = vethIfindexes()
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("getting the interface of the pod: %w", err)
+	}
+
+	hostLink, err := netlink.LinkByIndex(p.hostIfindex)
+	if err != nil {
+		return fmt.Errorf("getting the peer of the interface of the pod: %w", err)
+	}
+	p.hostIface = hostLink.Attrs().Name
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// Another container of the pod was added in the meantime
+	if other, ok := t.pods[c.Netns]; ok {
+		other.users++
+		return nil
+	}
+
+	p.id = t.nextID
+	t.nextID++
+
+	if err := t.addFilters(p, hostLink); err != nil {
+		t.removeFilters(p)
+		return err
+	}
+
+	ifaces := map[ifaceKey]iface{
+		{Netns: uint32(p.netns), Ifindex: uint32(p.podIfindex)}:             {Pod: p.id, Side: C.SIDE_POD},
+		{Netns: uint32(p.netns), Ifindex: 0}:                                {Pod: p.id, Side: C.SIDE_POD},
+		{Netns: uint32(t.config.NetnsHost), Ifindex: uint32(p.hostIfindex)}: {Pod: p.id, Side: C.SIDE_HOST},
+	}
+	for key, value := range ifaces {
+		if err := t.objs.Ifaces.Put(key, value); err != nil {
+			t.removeFilters(p)
+			t.deleteIfaces(p)
+			return fmt.Errorf("adding the interfaces of the pod: %w", err)
+		}
+	}
+
+	t.pods[c.Netns] = p
+
+	return nil
+}
+
+func (t *Tracer) RemoveContainer(c *pb.ContainerDefinition) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.pods[c.Netns]
+	if !ok {
+		return
+	}
+
+	p.users--
+	if p.users > 0 {
+		return
+	}
+	delete(t.pods, c.Netns)
+
+	t.removeFilters(p)
+	t.deleteIfaces(p)
+
+	// The drops of the pod not read yet are deleted too
+	keys := []dropKey{}
+	var key dropKey
+	var value counter
+	iter := t.objs.Drops.Iterate()
+	for iter.Next(&key, &value) {
+		if key.Pod == p.id {
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range keys {
+		t.objs.Drops.Delete(key)
+	}
+	for _, direction := range []uint32{C.DIRECTION_SENT, C.DIRECTION_RECEIVED} {
+		t.objs.Counters.Delete(counterKey{Ifindex: uint32(p.hostIfindex), Direction: direction})
+	}
+}
+
+// vethIfindexes returns the index of the veth interface of the current
+// network namespace and the one of its peer.
+func vethIfindexes() (int, int, error) {
+	links, err := netlink.LinkList()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, l := range links {
+		if _, ok := l.(*netlink.Veth); !ok {
+			continue
+		}
+		attrs := l.Attrs()
+		if attrs.ParentIndex == 0 {
+			continue
+		}
+		return attrs.Index, attrs.ParentIndex, nil
+	}
+
+	return 0, 0, errors.New("no veth interface found")
+}
+
+// addFilters attaches the programs counting the packets to the host side
+// interface of the pod, adding the clsact qdisc when needed. The qdisc is
+// kept afterwards as the CNI could use it too.
+func (t *Tracer) addFilters(p *pod, hostLink netlink.Link) error {
+	qdisc := &netlink.GenericQdisc{
+		QdiscAttrs: netlink.QdiscAttrs{
+			LinkIndex: hostLink.Attrs().Index,
+			Handle:    netlink.MakeHandle(0xffff, 0),
+			Parent:    netlink.HANDLE_CLSACT,
+		},
+		QdiscType: "clsact",
+	}
+	if err := netlink.QdiscAdd(qdisc); err != nil && !errors.Is(err, unix.EEXIST) {
+		return fmt.Errorf("adding clsact qdisc to %s: %w", p.hostIface, err)
+	}
+
+	programs := []struct {
+		parent uint32
+		prog   *ebpf.Program
+	}{
+		{netlink.HANDLE_MIN_INGRESS, t.objs.IgNdTcIngress},
+		{netlink.HANDLE_MIN_EGRESS, t.objs.IgNdTcEgress},
+	}
+
+	for _, program := range programs {
+		filter := &netlink.BpfFilter{
+			FilterAttrs: netlink.FilterAttrs{
+				LinkIndex: hostLink.Attrs().Index,
+				Parent:    program.parent,
+				Handle:    t.filterHandle,
+				Protocol:  unix.ETH_P_ALL,
+				Priority:  filterPriority,
+			},
+			Fd:           program.prog.FD(),
+			Name:         filterName,
+			DirectAction: true,
+		}
+
+		err := netlink.FilterAdd(filter)
+		if errors.Is(err, unix.EEXIST) {
+			// Left by a gadget pod that didn't stop cleanly
+			netlink.FilterDel(filter)
+			err = netlink.FilterAdd(filter)
+		}
+		if err != nil {
+			return fmt.Errorf("adding tc filter to %s: %w", p.hostIface, err)
+		}
+		p.filters = append(p.filters, filter)
+	}
+
+	return nil
+}
+
+func (t *Tracer) removeFilters(p *pod) {
+	for _, filter := range p.filters {
+		// The interface is gone with the pod most of the time
+		netlink.FilterDel(filter)
+	}
+	p.filters = nil
+}
+
+func (t *Tracer) deleteIfaces(p *pod) {
+	t.objs.Ifaces.Delete(ifaceKey{Netns: uint32(p.netns), Ifindex: uint32(p.podIfindex)})
+	t.objs.Ifaces.Delete(ifaceKey{Netns: uint32(p.netns), Ifindex: 0})
+	t.objs.Ifaces.Delete(ifaceKey{Netns: uint32(t.config.NetnsHost), Ifindex: uint32(p.hostIfindex)})
+}
+
+func directionName(direction uint32) string {
+	if direction == C.DIRECTION_SENT {
+		return types.DirectionSent
+	}
+	return types.DirectionReceived
+}
+
+// delta returns the packets and bytes counted since the previous value.
+func delta(current, previous counter) counter {
+	if current.Packets < previous.Packets || current.Bytes < previous.Bytes {
+		return current
+	}
+	return counter{
+		Packets: current.Packets - previous.Packets,
+		Bytes:   current.Bytes - previous.Bytes,
+	}
+}
+
+// poll reports the drops of the traced pods at each interval, with the
+// number of packets going through their interface in the same direction.
+func (t *Tracer) poll() {
+	defer t.wg.Done()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	done := t.done
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		t.mu.Lock()
+		events, err := t.nextEvents()
+		t.mu.Unlock()
+
+		if err != nil {
+			msg := fmt.Sprintf("failed to read the drops: %s", err)
+			t.eventCallback(types.Base(eventtypes.Warn(msg, t.node)))
+			continue
+		}
+
+		for _, event := range events {
+			t.eventCallback(event)
+		}
+	}
+}
+
+func (t *Tracer) nextEvents() ([]types.Event, error) {
+	pods := make(map[uint32]*pod, len(t.pods))
+	for _, p := range t.pods {
+		pods[p.id] = p
+	}
+
+	totals := make(map[uint32]map[uint32]counter)
+	for _, p := range t.pods {
+		totals[p.id] = make(map[uint32]counter)
+		for _, direction := range []uint32{C.DIRECTION_SENT, C.DIRECTION_RECEIVED} {
+			key := counterKey{Ifindex: uint32(p.hostIfindex), Direction: direction}
+			var current counter
+			if err := t.objs.Counters.Lookup(key, &current); err != nil {
+				if errors.Is(err, ebpf.ErrKeyNotExist) {
+					continue
+				}
+				return nil, err
+			}
+			totals[p.id][direction] = delta(current, p.counters[key])
+			p.counters[key] = current
+		}
+	}
+
+	events := []types.Event{}
+
+	var key dropKey
+	var current counter
+	iter := t.objs.Drops.Iterate()
+	for iter.Next(&key, &current) {
+		p, ok := pods[key.Pod]
+		if !ok {
+			continue
+		}
+
+		dropped := delta(current, p.drops[key])
+		p.drops[key] = current
+		if dropped.Packets == 0 {
+			continue
+		}
+
+		total := totals[p.id][key.Direction]
+		events = append(events, types.Event{
+			Event: eventtypes.Event{
+				Type:      eventtypes.NORMAL,
+				Node:      t.node,
+				Namespace: p.namespace,
+				Pod:       p.name,
+			},
+			Interface:    p.hostIface,
+			Direction:    directionName(key.Direction),
+			Reason:       dropreasons.Name(t.reasons, key.Reason),
+			Packets:      dropped.Packets,
+			Bytes:        dropped.Bytes,
+			TotalPackets: total.Packets,
+			TotalBytes:   total.Bytes,
+		})
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
@@ -0,0 +1,54 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+// Directions of the packets, seen from the pod.
+const (
+	DirectionSent     = "sent"
+	DirectionReceived = "received"
+)
+
+type Event struct {
+	eventtypes.Event
+
+	// Interface is the name of the interface of the pod on the host
+	// side, e.g. the veth peer created by the CNI.
+	Interface string `json:"interface,omitempty"`
+	Direction string `json:"direction,omitempty"`
+
+	// Reason is the reason given by the kernel when dropping the
+	// packets, available since Linux 5.17.
+	Reason string `json:"reason,omitempty"`
+
+	// Packets and Bytes are the dropped packets since the previous
+	// event.
+	Packets uint64 `json:"packets,omitempty"`
+	Bytes   uint64 `json:"bytes,omitempty"`
+
+	// TotalPackets and TotalBytes are the packets going through the
+	// interface in the same direction during the same interval.
+	TotalPackets uint64 `json:"totalPackets,omitempty"`
+	TotalBytes   uint64 `json:"totalBytes,omitempty"`
+}
+
+func Base(ev eventtypes.Event) Event {
+	return Event{
+		Event: ev,
+	}
+}
@@ -17,6 +17,7 @@ package advisor
 import (
 	"bufio"
 	"bytes"
+	"compress/gzip"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -52,14 +53,50 @@ func NewAdvisor() *NetworkPolicyAdvisor {
 	}
 }
 
+// LoadFile loads the events recorded in filename, compressed with gzip
+// or not.
 func (a *NetworkPolicyAdvisor) LoadFile(filename string) error {
-	buf, err := ioutil.ReadFile(filename)
+	buf, err := readFile(filename)
 	if err != nil {
 		return err
 	}
 	return a.LoadBuffer(buf)
 }
 
+// LoadFiles loads the events recorded in several files, e.g. the files
+// rotated by the monitor.
+func (a *NetworkPolicyAdvisor) LoadFiles(filenames []string) error {
+	events := []types.KubernetesConnectionEvent{}
+	for _, filename := range filenames {
+		if err := a.LoadFile(filename); err != nil {
+			return fmt.Errorf("loading %s: %w", filename, err)
+		}
+		events = append(events, a.Events...)
+	}
+	a.Events = events
+
+	return nil
+}
+
+func readFile(filename string) ([]byte, error) {
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.HasPrefix(buf, []byte{0x1f, 0x8b}) {
+		return buf, nil
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(buf))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return ioutil.ReadAll(r)
+}
+
 func (a *NetworkPolicyAdvisor) LoadBuffer(buf []byte) error {
 	/* Try to read the file as an array */
 	events := []types.KubernetesConnectionEvent{}
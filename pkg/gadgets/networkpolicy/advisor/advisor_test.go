@@ -15,8 +15,12 @@
 package advisor
 
 import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
 	"io/ioutil"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -49,3 +53,53 @@ func TestLoad(t *testing.T) {
 		}
 	}
 }
+
+func TestLoadFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	a := NewAdvisor()
+	if err := a.LoadFile("../testdata/multiple.input"); err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Events) < 2 {
+		t.Fatalf("expected several events in ../testdata/multiple.input")
+	}
+	a.GeneratePolicies()
+	expected := a.FormatPolicies()
+
+	// The monitor writes an event per line.
+	lines := []string{}
+	for _, event := range a.Events {
+		line, err := json.Marshal(event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, string(line)+"\n")
+	}
+
+	// The first half of the events in a rotated and compressed file, the
+	// other one in the current file.
+	var compressed bytes.Buffer
+	w := gzip.NewWriter(&compressed)
+	w.Write([]byte(strings.Join(lines[:len(lines)/2], "")))
+	w.Close()
+
+	rotated := filepath.Join(dir, "networktrace.log.1.gz")
+	current := filepath.Join(dir, "networktrace.log")
+	if err := ioutil.WriteFile(rotated, compressed.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(current, []byte(strings.Join(lines[len(lines)/2:], "")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	a = NewAdvisor()
+	if err := a.LoadFiles([]string{rotated, current}); err != nil {
+		t.Fatal(err)
+	}
+	a.GeneratePolicies()
+
+	if output := a.FormatPolicies(); output != expected {
+		t.Errorf("Unexpected policy:\n%s\nExpected:\n%s\n", output, expected)
+	}
+}
@@ -25,6 +25,7 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	"github.com/kinvolk/inspektor-gadget/pkg/bpferror"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/networkpolicy/advisor"
 )
@@ -47,6 +48,10 @@ func NewFactory() gadgets.TraceFactory {
 	}
 }
 
+// ObservesSandboxes tells that the traffic of the sandboxed pods goes through
+// their network namespace on the host.
+func (f *TraceFactory) ObservesSandboxes() {}
+
 func (f *TraceFactory) Description() string {
 	return `The network-policy gadget monitor the network activity in order to generate Kubernetes network policies.`
 }
@@ -124,7 +129,7 @@ func (f *Trace) Start(trace *gadgetv1alpha1.Trace) {
 	f.cmd.Stdout = &f.out
 	err := f.cmd.Start()
 	if err != nil {
-		trace.Status.OperationError = fmt.Sprintf("Failed to start: %s", err)
+		trace.Status.OperationError = fmt.Sprintf("Failed to start: %s", bpferror.Describe(err))
 		return
 	}
 	f.started = true
@@ -18,16 +18,20 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/kinvolk/inspektor-gadget/pkg/bpferror"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
 
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/oomkill/tracer"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/oomkill/types"
+	"github.com/kinvolk/inspektor-gadget/pkg/kernellog"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
 
 	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
 )
 
 type Trace struct {
-	resolver gadgets.Resolver
+	resolver  gadgets.Resolver
+	kernelLog *kernellog.Log
 
 	started bool
 	tracer  *tracer.Tracer
@@ -35,6 +39,8 @@ type Trace struct {
 
 type TraceFactory struct {
 	gadgets.BaseFactory
+
+	kernelLog *kernellog.Log
 }
 
 func NewFactory() gadgets.TraceFactory {
@@ -47,6 +53,10 @@ func (f *TraceFactory) Description() string {
 	return `oomkill monitors when OOM killer is triggered and kills a process.`
 }
 
+func (f *TraceFactory) SetKernelLog(l *kernellog.Log) {
+	f.kernelLog = l
+}
+
 func (f *TraceFactory) OutputModesSupported() map[string]struct{} {
 	return map[string]struct{}{
 		"Stream": {},
@@ -63,7 +73,8 @@ func deleteTrace(name string, t interface{}) {
 func (f *TraceFactory) Operations() map[string]gadgets.TraceOperation {
 	n := func() interface{} {
 		return &Trace{
-			resolver: f.Resolver,
+			resolver:  f.Resolver,
+			kernelLog: f.kernelLog,
 		}
 	}
 
@@ -92,7 +103,7 @@ func (t *Trace) Start(trace *gadgetv1alpha1.Trace) {
 
 	traceName := gadgets.TraceName(trace.ObjectMeta.Namespace, trace.ObjectMeta.Name)
 
-	eventCallback := func(event types.Event) {
+	publishEvent := func(event types.Event) {
 		r, err := json.Marshal(event)
 		if err != nil {
 			fmt.Printf("error marshalling event: %s\n", err)
@@ -101,6 +112,20 @@ func (t *Trace) Start(trace *gadgetv1alpha1.Trace) {
 		t.resolver.PublishEvent(traceName, string(r))
 	}
 
+	eventCallback := func(event types.Event) {
+		if t.kernelLog == nil || event.Type != eventtypes.NORMAL {
+			publishEvent(event)
+			return
+		}
+
+		// The kernel logs the OOM kill after the event is sent, wait for
+		// it without blocking the next events.
+		go func() {
+			event.KernelLog = t.kernelLog.Find(kernellog.OOMKill(event.KilledPid))
+			publishEvent(event)
+		}()
+	}
+
 	var err error
 
 	config := &tracer.Config{
@@ -108,7 +133,7 @@ func (t *Trace) Start(trace *gadgetv1alpha1.Trace) {
 	}
 	t.tracer, err = tracer.NewTracer(config, t.resolver, eventCallback, trace.Spec.Node)
 	if err != nil {
-		trace.Status.OperationError = fmt.Sprintf("failed to create tracer: %s", err)
+		trace.Status.OperationError = fmt.Sprintf("failed to create tracer: %s", bpferror.Describe(err))
 		return
 	}
 
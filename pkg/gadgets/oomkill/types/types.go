@@ -27,6 +27,10 @@ type Event struct {
 	KilledComm    string `json:"kcomm,omitempty"`
 	Pages         uint64 `json:"pages,omitempty"`
 	MountNsID     uint64 `json:"mountnsid,omitempty"`
+
+	// KernelLog are the lines of the kernel log about the OOM kill, when
+	// the gadget pods are deployed with a kernel log source.
+	KernelLog []string `json:"kernelLog,omitempty"`
 }
 
 func Base(ev eventtypes.Event) Event {
@@ -196,6 +196,7 @@ func (t *Tracer) run() {
 			Ret:       ret,
 			Fd:        fd,
 			Err:       errval,
+			Flags:     int(eventC.flags),
 			Path:      C.GoString(&eventC.fname[0]),
 		}
 
@@ -203,6 +204,7 @@ func (t *Tracer) run() {
 		if container != nil {
 			event.Container = container.Name
 			event.Pod = container.Podname
+			event.Sandbox = container.Sandbox
 			event.Namespace = container.Namespace
 		}
 
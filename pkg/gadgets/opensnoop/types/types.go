@@ -28,6 +28,7 @@ type Event struct {
 	Fd        int    `json:"fd,omitempty"`
 	Ret       int    `json:"ret,omitempty"`
 	Err       int    `json:"err,omitempty"`
+	Flags     int    `json:"flags,omitempty"`
 	Path      string `json:"path,omitempty"`
 }
 
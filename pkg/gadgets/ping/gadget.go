@@ -0,0 +1,264 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ping
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	"github.com/kinvolk/inspektor-gadget/pkg/bpferror"
+	containerutils "github.com/kinvolk/inspektor-gadget/pkg/container-utils"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	pingtracer "github.com/kinvolk/inspektor-gadget/pkg/gadgets/ping/tracer"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/ping/types"
+	pb "github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/api"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/pubsub"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+type Trace struct {
+	resolver gadgets.Resolver
+	client   client.Client
+
+	started bool
+
+	tracer *pingtracer.Tracer
+
+	netnsHost uint64
+}
+
+type TraceFactory struct {
+	gadgets.BaseFactory
+
+	netnsHost uint64
+}
+
+func NewFactory() gadgets.TraceFactory {
+	netnsHost, _ := containerutils.GetNetNs(os.Getpid())
+	return &TraceFactory{
+		BaseFactory: gadgets.BaseFactory{DeleteTrace: deleteTrace},
+		netnsHost:   netnsHost,
+	}
+}
+
+func (f *TraceFactory) Description() string {
+	return `The ping gadget traces the ICMP echo requests sent and received by the pods, with the latency of their replies and their failures.`
+}
+
+func (f *TraceFactory) Parameters() []gadgets.GadgetParameter {
+	return []gadgets.GadgetParameter{
+		{
+			Name:        "timeout",
+			Description: "Time in seconds after which an echo request without reply is reported as failed",
+			Default:     strconv.Itoa(types.TimeoutDefault),
+		},
+	}
+}
+
+func (f *TraceFactory) OutputModesSupported() map[string]struct{} {
+	return map[string]struct{}{
+		"Stream": {},
+	}
+}
+
+func (f *TraceFactory) NewEvent() gadgets.Event {
+	return &types.Event{}
+}
+
+func deleteTrace(name string, t interface{}) {
+	trace := t.(*Trace)
+	if trace.started {
+		trace.resolver.Unsubscribe(genPubSubKey(name))
+		trace.tracer.Close()
+		trace.tracer = nil
+	}
+}
+
+func (f *TraceFactory) Operations() map[string]gadgets.TraceOperation {
+	n := func() interface{} {
+		return &Trace{
+			client:    f.Client,
+			resolver:  f.Resolver,
+			netnsHost: f.netnsHost,
+		}
+	}
+
+	return map[string]gadgets.TraceOperation{
+		"start": {
+			Doc: "Start ping",
+			Operation: func(name string, trace *gadgetv1alpha1.Trace) {
+				f.LookupOrCreate(name, n).(*Trace).Start(trace)
+			},
+		},
+		"stop": {
+			Doc: "Stop ping",
+			Operation: func(name string, trace *gadgetv1alpha1.Trace) {
+				f.LookupOrCreate(name, n).(*Trace).Stop(trace)
+			},
+		},
+	}
+}
+
+type pubSubKey string
+
+func genPubSubKey(name string) pubSubKey {
+	return pubSubKey(fmt.Sprintf("gadget/ping/%s", name))
+}
+
+func (t *Trace) publishMessage(
+	trace *gadgetv1alpha1.Trace,
+	eventType eventtypes.EventType,
+	key string,
+	msg string,
+) {
+	event := &types.Event{
+		Event: eventtypes.Event{
+			Type:    eventType,
+			Node:    trace.Spec.Node,
+			Message: msg,
+		},
+	}
+
+	t.publishEvent(trace, event, key)
+}
+
+func (t *Trace) publishEvent(
+	trace *gadgetv1alpha1.Trace,
+	event *types.Event,
+	key string,
+) {
+	keyParts := strings.SplitN(key, "/", 2)
+	if len(keyParts) == 2 {
+		event.Namespace = keyParts[0]
+		event.Pod = keyParts[1]
+	} else if key != "host" {
+		event.Type = eventtypes.ERR
+		event.Message = fmt.Sprintf("unknown key %s", key)
+	}
+
+	traceName := gadgets.TraceName(trace.ObjectMeta.Namespace, trace.ObjectMeta.Name)
+	t.resolver.PublishEvent(
+		traceName,
+		eventtypes.EventString(event),
+	)
+}
+
+func (t *Trace) Start(trace *gadgetv1alpha1.Trace) {
+	if t.started {
+		trace.Status.State = "Started"
+		return
+	}
+
+	timeout := types.TimeoutDefault
+
+	if val, ok := trace.Spec.Parameters["timeout"]; ok {
+		parsed, err := strconv.ParseUint(val, 10, 32)
+		if err != nil || parsed == 0 {
+			trace.Status.OperationError = fmt.Sprintf("%q is not valid for timeout", val)
+			return
+		}
+		timeout = int(parsed)
+	}
+
+	var err error
+	t.tracer, err = pingtracer.NewTracer(time.Duration(timeout) * time.Second)
+	if err != nil {
+		trace.Status.OperationError = fmt.Sprintf("Failed to start ping tracer: %s", bpferror.Describe(err))
+		return
+	}
+
+	eventCallback := func(key string) func(event types.Event) {
+		return func(event types.Event) {
+			t.publishEvent(trace, &event, key)
+		}
+	}
+
+	genKey := func(container *pb.ContainerDefinition) string {
+		if container.Netns == t.netnsHost {
+			return "host"
+		}
+		return container.Namespace + "/" + container.Podname
+	}
+
+	attachContainerFunc := func(container *pb.ContainerDefinition) error {
+		key := genKey(container)
+
+		err = t.tracer.Attach(key, container.Pid, eventCallback(key), trace.Spec.Node)
+		if err != nil {
+			t.publishMessage(trace, eventtypes.ERR, key, fmt.Sprintf("failed to attach tracer: %s", err))
+			return err
+		}
+		t.publishMessage(trace, eventtypes.DEBUG, key, "tracer attached")
+		return nil
+	}
+
+	detachContainerFunc := func(container *pb.ContainerDefinition) {
+		key := genKey(container)
+
+		err := t.tracer.Detach(key)
+		if err != nil {
+			t.publishMessage(trace, eventtypes.ERR, key, fmt.Sprintf("failed to detach tracer: %s", err))
+			return
+		}
+		t.publishMessage(trace, eventtypes.DEBUG, key, "tracer detached")
+	}
+
+	containerEventCallback := func(event pubsub.PubSubEvent) {
+		switch event.Type {
+		case pubsub.EventTypeAddContainer:
+			attachContainerFunc(&event.Container)
+		case pubsub.EventTypeRemoveContainer:
+			detachContainerFunc(&event.Container)
+		}
+	}
+
+	existingContainers := t.resolver.Subscribe(
+		genPubSubKey(trace.ObjectMeta.Namespace+"/"+trace.ObjectMeta.Name),
+		*gadgets.ContainerSelectorFromContainerFilter(trace.Spec.Filter),
+		containerEventCallback,
+	)
+
+	for _, c := range existingContainers {
+		err := attachContainerFunc(c)
+		if err != nil {
+			log.Warnf("Warning: couldn't attach BPF program: %s", err)
+			break
+		}
+	}
+	t.started = true
+
+	trace.Status.State = "Started"
+}
+
+func (t *Trace) Stop(trace *gadgetv1alpha1.Trace) {
+	if !t.started {
+		trace.Status.OperationError = "Not started"
+		return
+	}
+
+	t.resolver.Unsubscribe(genPubSubKey(trace.ObjectMeta.Namespace + "/" + trace.ObjectMeta.Name))
+	t.tracer.Close()
+	t.tracer = nil
+	t.started = false
+
+	trace.Status.State = "Stopped"
+}
@@ -0,0 +1,24 @@
+#ifndef GADGET_PING_COMMON_H
+#define GADGET_PING_COMMON_H
+
+struct event_t {
+	// Time the packet was seen, from bpf_ktime_get_ns()
+	unsigned long long timestamp;
+
+	// Addresses of the echo request. For ICMP errors, they are the ones of
+	// the request quoted in the error.
+	unsigned int saddr;
+	unsigned int daddr;
+
+	// Source of the ICMP error
+	unsigned int reporter;
+
+	unsigned short id;
+	unsigned short seq;
+
+	unsigned char type;
+	unsigned char code;
+	unsigned char pkt_type;
+};
+
+#endif
@@ -0,0 +1,89 @@
+// SPDX-License-Identifier: GPL-2.0
+/* Copyright (c) 2022 The Inspektor Gadget authors */
+
+#include <linux/bpf.h>
+#include <linux/if_ether.h>
+#include <linux/ip.h>
+#include <linux/in.h>
+#include <linux/icmp.h>
+
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_endian.h>
+
+#include "ping-common.h"
+
+/* llvm builtin functions that eBPF C program may use to
+ * emit BPF_LD_ABS and BPF_LD_IND instructions
+ */
+unsigned long long load_byte(void *skb,
+			     unsigned long long off) asm("llvm.bpf.load.byte");
+unsigned long long load_half(void *skb,
+			     unsigned long long off) asm("llvm.bpf.load.half");
+unsigned long long load_word(void *skb,
+			     unsigned long long off) asm("llvm.bpf.load.word");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_PERF_EVENT_ARRAY);
+} events SEC(".maps");
+
+SEC("socket1")
+int bpf_prog1(struct __sk_buff *skb)
+{
+	// Skip non-IP packets
+	if (load_half(skb, offsetof(struct ethhdr, h_proto)) != ETH_P_IP)
+		return 0;
+
+	// Skip non-ICMP packets
+	if (load_byte(skb, ETH_HLEN + offsetof(struct iphdr, protocol)) != IPPROTO_ICMP)
+		return 0;
+
+	__u32 ihl = (load_byte(skb, ETH_HLEN) & 0x0f) * 4;
+	__u32 icmp_off = ETH_HLEN + ihl;
+
+	struct event_t event = {0,};
+	event.timestamp = bpf_ktime_get_ns();
+	event.pkt_type = skb->pkt_type;
+	event.type = load_byte(skb, icmp_off + offsetof(struct icmphdr, type));
+	event.code = load_byte(skb, icmp_off + offsetof(struct icmphdr, code));
+
+	switch (event.type) {
+	case ICMP_ECHO:
+	case ICMP_ECHOREPLY:
+		event.saddr = load_word(skb, ETH_HLEN + offsetof(struct iphdr, saddr));
+		event.daddr = load_word(skb, ETH_HLEN + offsetof(struct iphdr, daddr));
+		event.id = load_half(skb, icmp_off + offsetof(struct icmphdr, un.echo.id));
+		event.seq = load_half(skb, icmp_off + offsetof(struct icmphdr, un.echo.sequence));
+		break;
+	case ICMP_DEST_UNREACH:
+	case ICMP_TIME_EXCEEDED: {
+		// The error quotes the IP header and the first 8 bytes of the
+		// packet that caused it: only report the errors caused by echo
+		// requests.
+		__u32 inner_off = icmp_off + sizeof(struct icmphdr);
+
+		if (load_byte(skb, inner_off + offsetof(struct iphdr, protocol)) != IPPROTO_ICMP)
+			return 0;
+
+		__u32 inner_ihl = (load_byte(skb, inner_off) & 0x0f) * 4;
+		__u32 inner_icmp_off = inner_off + inner_ihl;
+
+		if (load_byte(skb, inner_icmp_off + offsetof(struct icmphdr, type)) != ICMP_ECHO)
+			return 0;
+
+		event.reporter = load_word(skb, ETH_HLEN + offsetof(struct iphdr, saddr));
+		event.saddr = load_word(skb, inner_off + offsetof(struct iphdr, saddr));
+		event.daddr = load_word(skb, inner_off + offsetof(struct iphdr, daddr));
+		event.id = load_half(skb, inner_icmp_off + offsetof(struct icmphdr, un.echo.id));
+		event.seq = load_half(skb, inner_icmp_off + offsetof(struct icmphdr, un.echo.sequence));
+		break;
+	}
+	default:
+		return 0;
+	}
+
+	bpf_perf_event_output(skb, &events, BPF_F_CURRENT_CPU, &event, sizeof(event));
+
+	return 0;
+}
+
+char _license[] SEC("license") = "GPL";
@@ -0,0 +1,219 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/ping/types"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+// ICMP types, see
+// https://www.iana.org/assignments/icmp-parameters/icmp-parameters.xhtml#icmp-parameters-types
+const (
+	icmpEchoReply    = 0
+	icmpDestUnreach  = 3
+	icmpEcho         = 8
+	icmpTimeExceeded = 11
+)
+
+// packetOutgoing is the pkt_type of the packets sent from the network
+// namespace.
+const packetOutgoing = 4
+
+var destUnreachReasons = map[uint8]string{
+	0:  "network unreachable",
+	1:  "host unreachable",
+	2:  "protocol unreachable",
+	3:  "port unreachable",
+	4:  "fragmentation needed",
+	5:  "source route failed",
+	6:  "destination network unknown",
+	7:  "destination host unknown",
+	9:  "network administratively prohibited",
+	10: "host administratively prohibited",
+	13: "communication administratively prohibited",
+}
+
+var timeExceededReasons = map[uint8]string{
+	0: "ttl exceeded",
+	1: "fragment reassembly time exceeded",
+}
+
+// packet is an ICMP packet reported by the BPF program.
+type packet struct {
+	// timestamp is given by bpf_ktime_get_ns()
+	timestamp uint64
+
+	saddr    string
+	daddr    string
+	reporter string
+
+	id  uint16
+	seq uint16
+
+	icmpType uint8
+	code     uint8
+	pktType  uint8
+}
+
+// exchangeKey identifies an echo request and its reply.
+type exchangeKey struct {
+	saddr string
+	daddr string
+	id    uint16
+	seq   uint16
+}
+
+type request struct {
+	timestamp uint64
+	received  time.Time
+	direction string
+}
+
+// exchanges matches the echo requests with their replies or with the ICMP
+// errors they caused. Requests without any of them after timeout are
+// failures.
+type exchanges struct {
+	timeout time.Duration
+	node    string
+
+	mu      sync.Mutex
+	pending map[exchangeKey]request
+}
+
+func newExchanges(timeout time.Duration, node string) *exchanges {
+	return &exchanges{
+		timeout: timeout,
+		node:    node,
+		pending: make(map[exchangeKey]request),
+	}
+}
+
+func ipString(addr uint32) string {
+	return net.IPv4(byte(addr>>24), byte(addr>>16), byte(addr>>8), byte(addr)).String()
+}
+
+func failureReason(icmpType, code uint8) string {
+	reasons := destUnreachReasons
+	name := "destination unreachable"
+	if icmpType == icmpTimeExceeded {
+		reasons = timeExceededReasons
+		name = "time exceeded"
+	}
+
+	if reason, ok := reasons[code]; ok {
+		return reason
+	}
+	return fmt.Sprintf("%s (code %d)", name, code)
+}
+
+func (e *exchanges) newEvent(key exchangeKey, direction string) types.Event {
+	return types.Event{
+		Event: eventtypes.Event{
+			Type: eventtypes.NORMAL,
+			Node: e.node,
+		},
+		Direction: direction,
+		Saddr:     key.saddr,
+		Daddr:     key.daddr,
+		ID:        key.id,
+		Seq:       key.seq,
+	}
+}
+
+// add handles a packet and returns the event of the exchange it completes,
+// if any.
+func (e *exchanges) add(p packet, now time.Time) (types.Event, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	switch p.icmpType {
+	case icmpEcho:
+		key := exchangeKey{saddr: p.saddr, daddr: p.daddr, id: p.id, seq: p.seq}
+
+		// Packets on the loopback interface are seen twice: keep the
+		// first one.
+		if _, ok := e.pending[key]; ok {
+			return types.Event{}, false
+		}
+
+		direction := types.DirectionIn
+		if p.pktType == packetOutgoing {
+			direction = types.DirectionOut
+		}
+		e.pending[key] = request{
+			timestamp: p.timestamp,
+			received:  now,
+			direction: direction,
+		}
+	case icmpEchoReply:
+		key := exchangeKey{saddr: p.daddr, daddr: p.saddr, id: p.id, seq: p.seq}
+
+		req, ok := e.pending[key]
+		if !ok {
+			return types.Event{}, false
+		}
+		delete(e.pending, key)
+
+		event := e.newEvent(key, req.direction)
+		event.Latency = float64(p.timestamp-req.timestamp) / float64(time.Millisecond)
+		return event, true
+	case icmpDestUnreach, icmpTimeExceeded:
+		// The addresses are the ones of the request quoted in the
+		// error.
+		key := exchangeKey{saddr: p.saddr, daddr: p.daddr, id: p.id, seq: p.seq}
+
+		direction := types.DirectionOut
+		if req, ok := e.pending[key]; ok {
+			direction = req.direction
+			delete(e.pending, key)
+		}
+
+		event := e.newEvent(key, direction)
+		event.Failed = true
+		event.Reason = failureReason(p.icmpType, p.code)
+		event.Reporter = p.reporter
+		return event, true
+	}
+
+	return types.Event{}, false
+}
+
+// expire returns the events of the echo requests that didn't get any reply
+// within the timeout.
+func (e *exchanges) expire(now time.Time) []types.Event {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var events []types.Event
+	for key, req := range e.pending {
+		if now.Sub(req.received) < e.timeout {
+			continue
+		}
+		delete(e.pending, key)
+
+		event := e.newEvent(key, req.direction)
+		event.Failed = true
+		event.Reason = "timeout"
+		events = append(events, event)
+	}
+
+	return events
+}
@@ -0,0 +1,189 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/ping/types"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+func TestExchanges(t *testing.T) {
+	now := time.Now()
+	base := eventtypes.Event{Type: eventtypes.NORMAL, Node: "node1"}
+
+	request := packet{
+		timestamp: 1000000,
+		saddr:     "10.0.0.1",
+		daddr:     "10.0.0.2",
+		id:        42,
+		seq:       1,
+		icmpType:  icmpEcho,
+		pktType:   packetOutgoing,
+	}
+	reply := packet{
+		timestamp: 3500000,
+		saddr:     "10.0.0.2",
+		daddr:     "10.0.0.1",
+		id:        42,
+		seq:       1,
+		icmpType:  icmpEchoReply,
+	}
+	unreachable := packet{
+		saddr:    "10.0.0.1",
+		daddr:    "10.0.0.2",
+		reporter: "10.0.0.254",
+		id:       42,
+		seq:      1,
+		icmpType: icmpDestUnreach,
+		code:     1,
+	}
+
+	table := []struct {
+		description string
+		packets     []packet
+		events      []types.Event
+		expired     []types.Event
+	}{
+		{
+			description: "reply",
+			packets:     []packet{request, reply},
+			events: []types.Event{
+				{
+					Event:     base,
+					Direction: types.DirectionOut,
+					Saddr:     "10.0.0.1",
+					Daddr:     "10.0.0.2",
+					ID:        42,
+					Seq:       1,
+					Latency:   2.5,
+				},
+			},
+		},
+		{
+			description: "incoming request",
+			packets: []packet{
+				func() packet { p := request; p.pktType = 0; return p }(),
+				reply,
+			},
+			events: []types.Event{
+				{
+					Event:     base,
+					Direction: types.DirectionIn,
+					Saddr:     "10.0.0.1",
+					Daddr:     "10.0.0.2",
+					ID:        42,
+					Seq:       1,
+					Latency:   2.5,
+				},
+			},
+		},
+		{
+			description: "loopback duplicates",
+			packets:     []packet{request, request, reply, reply},
+			events: []types.Event{
+				{
+					Event:     base,
+					Direction: types.DirectionOut,
+					Saddr:     "10.0.0.1",
+					Daddr:     "10.0.0.2",
+					ID:        42,
+					Seq:       1,
+					Latency:   2.5,
+				},
+			},
+		},
+		{
+			description: "reply without request",
+			packets:     []packet{reply},
+		},
+		{
+			description: "icmp error",
+			packets:     []packet{request, unreachable},
+			events: []types.Event{
+				{
+					Event:     base,
+					Direction: types.DirectionOut,
+					Saddr:     "10.0.0.1",
+					Daddr:     "10.0.0.2",
+					ID:        42,
+					Seq:       1,
+					Failed:    true,
+					Reason:    "host unreachable",
+					Reporter:  "10.0.0.254",
+				},
+			},
+		},
+		{
+			description: "timeout",
+			packets:     []packet{request},
+			expired: []types.Event{
+				{
+					Event:     base,
+					Direction: types.DirectionOut,
+					Saddr:     "10.0.0.1",
+					Daddr:     "10.0.0.2",
+					ID:        42,
+					Seq:       1,
+					Failed:    true,
+					Reason:    "timeout",
+				},
+			},
+		},
+	}
+
+	for _, entry := range table {
+		e := newExchanges(5*time.Second, "node1")
+
+		var events []types.Event
+		for _, p := range entry.packets {
+			if event, ok := e.add(p, now); ok {
+				events = append(events, event)
+			}
+		}
+		if !reflect.DeepEqual(events, entry.events) {
+			t.Fatalf("%s: got events %+v, expected %+v", entry.description, events, entry.events)
+		}
+
+		if expired := e.expire(now.Add(time.Second)); len(expired) != 0 {
+			t.Fatalf("%s: requests expired before the timeout: %+v", entry.description, expired)
+		}
+		if expired := e.expire(now.Add(5 * time.Second)); !reflect.DeepEqual(expired, entry.expired) {
+			t.Fatalf("%s: got expired events %+v, expected %+v", entry.description, expired, entry.expired)
+		}
+	}
+}
+
+func TestFailureReason(t *testing.T) {
+	table := []struct {
+		icmpType uint8
+		code     uint8
+		expected string
+	}{
+		{icmpDestUnreach, 3, "port unreachable"},
+		{icmpTimeExceeded, 0, "ttl exceeded"},
+		{icmpDestUnreach, 15, "destination unreachable (code 15)"},
+		{icmpTimeExceeded, 7, "time exceeded (code 7)"},
+	}
+
+	for _, entry := range table {
+		if reason := failureReason(entry.icmpType, entry.code); reason != entry.expected {
+			t.Fatalf("type %d code %d: got %q, expected %q", entry.icmpType, entry.code, reason, entry.expected)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/perf"
+	"golang.org/x/sys/unix"
+
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/ping/types"
+	"github.com/kinvolk/inspektor-gadget/pkg/rawsock"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+//go:generate sh -c "GOOS=$(go env GOHOSTOS) GOARCH=$(go env GOHOSTARCH) go run github.com/cilium/ebpf/cmd/bpf2go -target bpfel -cc clang ping ./bpf/ping.c -- $CLANG_OS_FLAGS -I./bpf/ -target bpf -D__TARGET_ARCH_x86"
+
+// #include "bpf/ping-common.h"
+import "C"
+
+const (
+	BPFProgName     = "bpf_prog1"
+	BPFMapName      = "events"
+	BPFSocketAttach = 50
+)
+
+type link struct {
+	collection *ebpf.Collection
+	perfRd     *perf.Reader
+
+	sockFd int
+
+	exchanges *exchanges
+	done      chan struct{}
+
+	// users count how many users called Attach(). This can happen for two reasons:
+	// 1. several containers in a pod (sharing the netns)
+	// 2. pods with networkHost=true
+	users int
+}
+
+type Tracer struct {
+	spec    *ebpf.CollectionSpec
+	timeout time.Duration
+
+	// key: namespace/podname
+	// value: Tracelet
+	attachments map[string]*link
+}
+
+// NewTracer creates a tracer reporting the echo requests without reply
+// after timeout as failed.
+func NewTracer(timeout time.Duration) (*Tracer, error) {
+	spec, err := loadPing()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load asset: %w", err)
+	}
+
+	t := &Tracer{
+		spec:        spec,
+		timeout:     timeout,
+		attachments: make(map[string]*link),
+	}
+
+	return t, nil
+}
+
+func (t *Tracer) Attach(
+	key string,
+	pid uint32,
+	eventCallback func(types.Event),
+	node string,
+) error {
+	if l, ok := t.attachments[key]; ok {
+		l.users++
+		return nil
+	}
+
+	coll, err := ebpf.NewCollection(t.spec)
+	if err != nil {
+		return fmt.Errorf("failed to create BPF collection: %w", err)
+	}
+
+	rd, err := perf.NewReader(coll.Maps[BPFMapName], gadgets.PerfBufferPages*os.Getpagesize())
+	if err != nil {
+		coll.Close()
+		return fmt.Errorf("failed to get a perf reader: %w", err)
+	}
+
+	prog, ok := coll.Programs[BPFProgName]
+	if !ok {
+		rd.Close()
+		coll.Close()
+		return fmt.Errorf("failed to find BPF program %q", BPFProgName)
+	}
+
+	sockFd, err := rawsock.OpenRawSock(pid)
+	if err != nil {
+		rd.Close()
+		coll.Close()
+		return fmt.Errorf("failed to open raw socket: %w", err)
+	}
+
+	if err := syscall.SetsockoptInt(sockFd, syscall.SOL_SOCKET, BPFSocketAttach, prog.FD()); err != nil {
+		unix.Close(sockFd)
+		rd.Close()
+		coll.Close()
+		return fmt.Errorf("failed to attach BPF program: %w", err)
+	}
+
+	l := &link{
+		collection: coll,
+		sockFd:     sockFd,
+		perfRd:     rd,
+		exchanges:  newExchanges(t.timeout, node),
+		done:       make(chan struct{}),
+		users:      1,
+	}
+	t.attachments[key] = l
+
+	go t.listen(key, l, eventCallback, node)
+	go t.expire(l, eventCallback)
+
+	return nil
+}
+
+func parsePingEvent(rawSample []byte) (packet, error) {
+	if len(rawSample) < C.sizeof_struct_event_t {
+		return packet{}, fmt.Errorf("sample too short: %d bytes", len(rawSample))
+	}
+	pingEvent := (*C.struct_event_t)(unsafe.Pointer(&rawSample[0]))
+
+	return packet{
+		timestamp: uint64(pingEvent.timestamp),
+		saddr:     ipString(uint32(pingEvent.saddr)),
+		daddr:     ipString(uint32(pingEvent.daddr)),
+		reporter:  ipString(uint32(pingEvent.reporter)),
+		id:        uint16(pingEvent.id),
+		seq:       uint16(pingEvent.seq),
+		icmpType:  uint8(pingEvent._type),
+		code:      uint8(pingEvent.code),
+		pktType:   uint8(pingEvent.pkt_type),
+	}, nil
+}
+
+func (t *Tracer) listen(
+	key string,
+	l *link,
+	eventCallback func(types.Event),
+	node string,
+) {
+	for {
+		record, err := l.perfRd.Read()
+		if err != nil {
+			if errors.Is(err, perf.ErrClosed) {
+				return
+			}
+
+			msg := fmt.Sprintf("Error reading perf ring buffer (%s): %s", key, err)
+			eventCallback(types.Base(eventtypes.Err(msg, node)))
+			return
+		}
+
+		if record.LostSamples != 0 {
+			msg := fmt.Sprintf("lost %d samples (%s)", record.LostSamples, key)
+			eventCallback(types.Base(eventtypes.Warn(msg, node)))
+			continue
+		}
+
+		p, err := parsePingEvent(record.RawSample)
+		if err != nil {
+			msg := fmt.Sprintf("failed to parse event (%s): %s", key, err)
+			eventCallback(types.Base(eventtypes.Warn(msg, node)))
+			continue
+		}
+
+		if event, ok := l.exchanges.add(p, time.Now()); ok {
+			eventCallback(event)
+		}
+	}
+}
+
+// expire periodically reports the echo requests that didn't get any reply
+// within the timeout.
+func (t *Tracer) expire(l *link, eventCallback func(types.Event)) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.done:
+			return
+		case now := <-ticker.C:
+			for _, event := range l.exchanges.expire(now) {
+				eventCallback(event)
+			}
+		}
+	}
+}
+
+func (t *Tracer) releaseLink(key string, l *link) {
+	close(l.done)
+	l.perfRd.Close()
+	unix.Close(l.sockFd)
+	l.collection.Close()
+	delete(t.attachments, key)
+}
+
+func (t *Tracer) Detach(key string) error {
+	if l, ok := t.attachments[key]; ok {
+		l.users--
+		if l.users == 0 {
+			t.releaseLink(key, l)
+		}
+		return nil
+	} else {
+		return fmt.Errorf("key not attached: %q", key)
+	}
+}
+
+func (t *Tracer) Close() {
+	for key, l := range t.attachments {
+		t.releaseLink(key, l)
+	}
+}
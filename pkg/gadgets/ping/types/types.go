@@ -0,0 +1,61 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+const (
+	// DirectionOut is used for the echo requests sent from the pod.
+	DirectionOut = "out"
+
+	// DirectionIn is used for the echo requests received by the pod.
+	DirectionIn = "in"
+
+	// TimeoutDefault is the default time, in seconds, after which an echo
+	// request without reply is reported as failed.
+	TimeoutDefault = 5
+)
+
+type Event struct {
+	eventtypes.Event
+
+	// Direction tells if the pod sent or received the echo request.
+	Direction string `json:"direction,omitempty"`
+
+	// Saddr is the address of the host sending the echo request and
+	// Daddr the one of the host it's sent to.
+	Saddr string `json:"saddr,omitempty"`
+	Daddr string `json:"daddr,omitempty"`
+
+	ID      uint16  `json:"id"`
+	Seq     uint16  `json:"seq"`
+	Latency float64 `json:"lat_ms,omitempty"`
+
+	// Failed is set when no reply was received, either because an ICMP
+	// error was received instead or because of a timeout. Reason
+	// describes the failure and Reporter is the address of the host that
+	// sent the ICMP error, if any.
+	Failed   bool   `json:"failed,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+	Reporter string `json:"reporter,omitempty"`
+}
+
+func Base(ev eventtypes.Event) Event {
+	return Event{
+		Event: ev,
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgets
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// Capabilities needed to load eBPF programs, see capability(7). CAP_BPF
+// and CAP_PERFMON were split from CAP_SYS_ADMIN in Linux 5.8.
+const (
+	capSysAdmin = 21
+	capPerfmon  = 38
+	capBPF      = 39
+)
+
+// HasBPFPrivileges tells whether the current process has the capabilities
+// needed to load the eBPF programs of the gadgets: CAP_SYS_ADMIN, or
+// CAP_BPF and CAP_PERFMON.
+func HasBPFPrivileges() (bool, error) {
+	status, err := ioutil.ReadFile("/proc/self/status")
+	if err != nil {
+		return false, err
+	}
+	return hasBPFPrivileges(string(status))
+}
+
+func hasBPFPrivileges(status string) (bool, error) {
+	for _, line := range strings.Split(status, "\n") {
+		if !strings.HasPrefix(line, "CapEff:") {
+			continue
+		}
+		caps, err := strconv.ParseUint(strings.TrimSpace(strings.TrimPrefix(line, "CapEff:")), 16, 64)
+		if err != nil {
+			return false, fmt.Errorf("parsing effective capabilities: %w", err)
+		}
+		has := func(c uint) bool {
+			return caps&(1<<c) != 0
+		}
+		return has(capSysAdmin) || (has(capBPF) && has(capPerfmon)), nil
+	}
+	return false, fmt.Errorf("effective capabilities not found")
+}
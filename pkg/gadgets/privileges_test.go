@@ -0,0 +1,69 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgets
+
+import (
+	"testing"
+)
+
+func TestHasBPFPrivileges(t *testing.T) {
+	table := []struct {
+		description string
+		capEff      string
+		expected    bool
+	}{
+		{
+			description: "all capabilities",
+			capEff:      "000001ffffffffff",
+			expected:    true,
+		},
+		{
+			description: "CAP_SYS_ADMIN only",
+			capEff:      "0000000000200000",
+			expected:    true,
+		},
+		{
+			description: "CAP_BPF and CAP_PERFMON",
+			capEff:      "000000c000000000",
+			expected:    true,
+		},
+		{
+			description: "CAP_BPF without CAP_PERFMON",
+			capEff:      "0000008000000000",
+			expected:    false,
+		},
+		{
+			description: "default capabilities of a container",
+			capEff:      "00000000a80425fb",
+			expected:    false,
+		},
+	}
+
+	for _, entry := range table {
+		status := "Name:\tgadgettracerman\nCapInh:\t0000000000000000\nCapPrm:\t" + entry.capEff +
+			"\nCapEff:\t" + entry.capEff + "\nCapBnd:\t" + entry.capEff + "\n"
+		has, err := hasBPFPrivileges(status)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", entry.description, err)
+		}
+		if has != entry.expected {
+			t.Fatalf("%s: expected %v, got %v", entry.description, entry.expected, has)
+		}
+	}
+
+	if _, err := hasBPFPrivileges("Name:\tgadgettracerman\n"); err == nil {
+		t.Fatalf("expected an error without effective capabilities")
+	}
+}
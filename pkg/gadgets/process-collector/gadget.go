@@ -21,20 +21,30 @@ import (
 	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/process-collector/tracer"
+	processcollectortypes "github.com/kinvolk/inspektor-gadget/pkg/gadgets/process-collector/types"
 )
 
 type Trace struct {
-	resolver gadgets.Resolver
+	resolver   gadgets.Resolver
+	withoutBPF bool
 }
 
 type TraceFactory struct {
 	gadgets.BaseFactory
+
+	// withoutBPF tells to list the processes from /proc instead of the
+	// BPF iterator.
+	withoutBPF bool
 }
 
 func NewFactory() gadgets.TraceFactory {
 	return &TraceFactory{}
 }
 
+func (f *TraceFactory) SetWithoutBPF() {
+	f.withoutBPF = true
+}
+
 func (f *TraceFactory) Description() string {
 	return `The process-collector gadget gathers information about running processes`
 }
@@ -48,7 +58,8 @@ func (f *TraceFactory) OutputModesSupported() map[string]struct{} {
 func (f *TraceFactory) Operations() map[string]gadgets.TraceOperation {
 	n := func() interface{} {
 		return &Trace{
-			resolver: f.Resolver,
+			resolver:   f.Resolver,
+			withoutBPF: f.withoutBPF,
 		}
 	}
 
@@ -65,7 +76,14 @@ func (f *TraceFactory) Operations() map[string]gadgets.TraceOperation {
 }
 
 func (t *Trace) Collect(trace *gadgetv1alpha1.Trace) {
-	events, err := tracer.RunCollector(t.resolver, trace.Spec.Node, gadgets.TracePinPath(trace.ObjectMeta.Namespace, trace.ObjectMeta.Name))
+	var events []processcollectortypes.Event
+	var err error
+	if t.withoutBPF {
+		selector := gadgets.ContainerSelectorFromContainerFilter(trace.Spec.Filter)
+		events, err = tracer.RunProcfsCollector(t.resolver, trace.Spec.Node, selector)
+	} else {
+		events, err = tracer.RunCollector(t.resolver, trace.Spec.Node, gadgets.TracePinPath(trace.ObjectMeta.Namespace, trace.ObjectMeta.Name))
+	}
 	if err != nil {
 		trace.Status.OperationError = err.Error()
 		return
@@ -0,0 +1,119 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	containercollection "github.com/kinvolk/inspektor-gadget/pkg/container-collection"
+	processcollectortypes "github.com/kinvolk/inspektor-gadget/pkg/gadgets/process-collector/types"
+	pb "github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/api"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+const procRoot = "/proc"
+
+// RunProcfsCollector lists the processes of the containers selected by the
+// filter from /proc, without the BPF iterator. It's used when the gadget
+// pods don't have the privileges needed by eBPF: the mount namespace of the
+// processes can still be read with CAP_SYS_PTRACE.
+func RunProcfsCollector(resolver containercollection.ContainerResolver, node string, selector *pb.ContainerSelector) ([]processcollectortypes.Event, error) {
+	return runProcfsCollector(procRoot, resolver.GetContainersBySelector(selector), node)
+}
+
+func runProcfsCollector(root string, containers []*pb.ContainerDefinition, node string) ([]processcollectortypes.Event, error) {
+	byMntns := make(map[uint64]*pb.ContainerDefinition)
+	for _, c := range containers {
+		if c.Mntns != 0 {
+			byMntns[c.Mntns] = c
+		}
+	}
+
+	var events []processcollectortypes.Event
+	if len(byMntns) == 0 {
+		return events, nil
+	}
+
+	entries, err := ioutil.ReadDir(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list processes: %w", err)
+	}
+
+	for _, entry := range entries {
+		tgid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		// Errors are ignored from here: the process likely terminated
+		// in the meantime.
+		mntns, err := readMntns(filepath.Join(root, entry.Name()))
+		if err != nil {
+			continue
+		}
+		container, ok := byMntns[mntns]
+		if !ok {
+			continue
+		}
+
+		tasks, err := ioutil.ReadDir(filepath.Join(root, entry.Name(), "task"))
+		if err != nil {
+			continue
+		}
+		for _, task := range tasks {
+			pid, err := strconv.Atoi(task.Name())
+			if err != nil {
+				continue
+			}
+			comm, err := ioutil.ReadFile(filepath.Join(root, entry.Name(), "task", task.Name(), "comm"))
+			if err != nil {
+				continue
+			}
+
+			events = append(events, processcollectortypes.Event{
+				Event: eventtypes.Event{
+					Node:      node,
+					Namespace: container.Namespace,
+					Pod:       container.Podname,
+					Container: container.Name,
+				},
+				Tgid:      tgid,
+				Pid:       pid,
+				Command:   strings.TrimSuffix(string(comm), "\n"),
+				MountNsID: mntns,
+			})
+		}
+	}
+
+	return events, nil
+}
+
+// readMntns returns the inode of the mount namespace of a process from the
+// ns/mnt link of its directory in /proc, like "mnt:[4026531840]".
+func readMntns(dir string) (uint64, error) {
+	link, err := os.Readlink(filepath.Join(dir, "ns", "mnt"))
+	if err != nil {
+		return 0, err
+	}
+	if !strings.HasPrefix(link, "mnt:[") || !strings.HasSuffix(link, "]") {
+		return 0, fmt.Errorf("invalid mount namespace link %q", link)
+	}
+	return strconv.ParseUint(link[len("mnt:["):len(link)-1], 10, 64)
+}
@@ -0,0 +1,85 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	processcollectortypes "github.com/kinvolk/inspektor-gadget/pkg/gadgets/process-collector/types"
+	pb "github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/api"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+func writeProcess(t *testing.T, root, mntns string, tgid string, tasks map[string]string) {
+	t.Helper()
+
+	dir := filepath.Join(root, tgid)
+	if err := os.MkdirAll(filepath.Join(dir, "ns"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(mntns, filepath.Join(dir, "ns", "mnt")); err != nil {
+		t.Fatal(err)
+	}
+	for pid, comm := range tasks {
+		taskDir := filepath.Join(dir, "task", pid)
+		if err := os.MkdirAll(taskDir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(taskDir, "comm"), []byte(comm+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRunProcfsCollector(t *testing.T) {
+	root := t.TempDir()
+
+	writeProcess(t, root, "mnt:[4026531840]", "1", map[string]string{"1": "systemd"})
+	writeProcess(t, root, "mnt:[4026532500]", "42", map[string]string{"42": "nginx", "43": "nginx-worker"})
+	writeProcess(t, root, "invalid", "50", map[string]string{"50": "broken"})
+	if err := os.MkdirAll(filepath.Join(root, "sys"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	containers := []*pb.ContainerDefinition{
+		{Namespace: "default", Podname: "web", Name: "nginx", Mntns: 4026532500},
+	}
+
+	events, err := runProcfsCollector(root, containers, "node1")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	base := eventtypes.Event{Node: "node1", Namespace: "default", Pod: "web", Container: "nginx"}
+	expected := []processcollectortypes.Event{
+		{Event: base, Tgid: 42, Pid: 42, Command: "nginx", MountNsID: 4026532500},
+		{Event: base, Tgid: 42, Pid: 43, Command: "nginx-worker", MountNsID: 4026532500},
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, events)
+	}
+
+	events, err = runProcfsCollector(root, nil, "node1")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("expected no events without containers, got %+v", events)
+	}
+}
@@ -66,6 +66,7 @@ func RunCollector(resolver gadgets.Resolver, node, mntnsmap string) ([]processco
 	if err != nil {
 		return nil, fmt.Errorf("failed to create BPF collection: %w", err)
 	}
+	defer coll.Close()
 
 	dumpTask, ok := coll.Programs[BPFIterName]
 	if !ok {
@@ -77,6 +78,7 @@ func RunCollector(resolver gadgets.Resolver, node, mntnsmap string) ([]processco
 	if err != nil {
 		return nil, fmt.Errorf("failed to attach BPF iterator: %w", err)
 	}
+	defer dumpTaskIter.Close()
 
 	file, err := dumpTaskIter.Open()
 	if err != nil {
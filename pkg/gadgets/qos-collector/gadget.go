@@ -0,0 +1,104 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package qoscollector
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	containerutils "github.com/kinvolk/inspektor-gadget/pkg/container-utils"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/qos-collector/tracer"
+)
+
+type Trace struct {
+	resolver gadgets.Resolver
+
+	netnsHost uint64
+}
+
+type TraceFactory struct {
+	gadgets.BaseFactory
+
+	netnsHost uint64
+}
+
+func NewFactory() gadgets.TraceFactory {
+	netnsHost, _ := containerutils.GetNetNs(os.Getpid())
+	return &TraceFactory{
+		netnsHost: netnsHost,
+	}
+}
+
+// SetWithoutBPF does nothing: the gadget only reads the traffic control
+// configuration through netlink.
+func (f *TraceFactory) SetWithoutBPF() {}
+
+func (f *TraceFactory) Description() string {
+	return `The qos-collector gadget reads the traffic control configuration (qdiscs, classes and filters) of the network interfaces of the pods, on both sides of their veth pair and on the ifb interfaces their traffic is redirected to, to find out how their bandwidth is shaped`
+}
+
+func (f *TraceFactory) OutputModesSupported() map[string]struct{} {
+	return map[string]struct{}{
+		"Status": {},
+	}
+}
+
+func (f *TraceFactory) Operations() map[string]gadgets.TraceOperation {
+	n := func() interface{} {
+		return &Trace{
+			resolver:  f.Resolver,
+			netnsHost: f.netnsHost,
+		}
+	}
+
+	return map[string]gadgets.TraceOperation{
+		"collect": {
+			Doc: "Create a snapshot of the traffic control configuration of the pods. " +
+				"Once taken, the snapshot is not updated automatically. " +
+				"However one can call the collect operation again at any time to update the snapshot.",
+			Operation: func(name string, trace *gadgetv1alpha1.Trace) {
+				f.LookupOrCreate(name, n).(*Trace).Collect(trace)
+			},
+		},
+	}
+}
+
+func (t *Trace) Collect(trace *gadgetv1alpha1.Trace) {
+	selector := gadgets.ContainerSelectorFromContainerFilter(trace.Spec.Filter)
+
+	qos, err := tracer.RunCollector(t.resolver, trace.Spec.Node, selector, t.netnsHost)
+	if err != nil {
+		trace.Status.OperationError = err.Error()
+		return
+	}
+
+	if len(qos) == 0 {
+		trace.Status.OperationWarning = "No pod with its own network namespace matches the requested filter"
+		trace.Status.State = "Completed"
+		return
+	}
+
+	output, err := json.MarshalIndent(qos, "", " ")
+	if err != nil {
+		trace.Status.OperationError = fmt.Sprintf("failed marshalling qos: %s", err)
+		return
+	}
+
+	trace.Status.Output = string(output)
+	trace.Status.State = "Completed"
+}
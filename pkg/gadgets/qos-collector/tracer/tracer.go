@@ -0,0 +1,264 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/vishvananda/netlink"
+
+	containercollection "github.com/kinvolk/inspektor-gadget/pkg/container-collection"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/qos-collector/types"
+	pb "github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/api"
+	"github.com/kinvolk/inspektor-gadget/pkg/netnsenter"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+// RunCollector reads the traffic control configuration of the interfaces
+// of the pods of the containers selected by the filter. Each pod is
+// reported once, whatever the number of its containers, and the pods using
+// the network namespace of the host are left out.
+func RunCollector(resolver containercollection.ContainerResolver, node string,
+	selector *pb.ContainerSelector, netnsHost uint64) ([]types.QoS, error) {
+	qos := []types.QoS{}
+	seen := make(map[uint64]struct{})
+
+	for _, c := range resolver.GetContainersBySelector(selector) {
+		if c.Netns == 0 || c.Netns == netnsHost {
+			continue
+		}
+		if _, ok := seen[c.Netns]; ok {
+			continue
+		}
+		seen[c.Netns] = struct{}{}
+
+		interfaces, err := readPodInterfaces(int(c.Pid))
+		if err != nil {
+			return nil, fmt.Errorf("pod %s/%s: %w", c.Namespace, c.Podname, err)
+		}
+
+		qos = append(qos, types.QoS{
+			Event: eventtypes.Event{
+				Type:      eventtypes.NORMAL,
+				Node:      node,
+				Namespace: c.Namespace,
+				Pod:       c.Podname,
+			},
+			Netns:      c.Netns,
+			Interfaces: interfaces,
+		})
+	}
+
+	return qos, nil
+}
+
+// readPodInterfaces reads the configuration of the veth interface of the
+// network namespace of the process, of its peer on the host and of the ifb
+// interfaces the traffic of the peer is redirected to.
+func readPodInterfaces(pid int) ([]types.Interface, error) {
+	var podIface types.Interface
+	var hostIfindex int
+
+	err := netnsenter.NetnsEnter(pid, func() error {
+		podLink, err := podVeth()
+		if err != nil {
+			return err
+		}
+		hostIfindex = podLink.Attrs().ParentIndex
+
+		podIface, _, err = readInterface(podLink, types.SidePod)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading the interface of the pod: %w", err)
+	}
+
+	hostLink, err := netlink.LinkByIndex(hostIfindex)
+	if err != nil {
+		return nil, fmt.Errorf("getting the peer of the interface of the pod: %w", err)
+	}
+	hostIface, redirects, err := readInterface(hostLink, types.SideHost)
+	if err != nil {
+		return nil, fmt.Errorf("reading the host side interface of the pod: %w", err)
+	}
+
+	interfaces := []types.Interface{podIface, hostIface}
+
+	for _, ifindex := range redirects {
+		l, err := netlink.LinkByIndex(ifindex)
+		if err != nil || l.Type() != "ifb" {
+			continue
+		}
+		ifbIface, _, err := readInterface(l, types.SideIfb)
+		if err != nil {
+			return nil, fmt.Errorf("reading the ifb interface %s: %w", l.Attrs().Name, err)
+		}
+		interfaces = append(interfaces, ifbIface)
+	}
+
+	return interfaces, nil
+}
+
+// podVeth returns the veth interface of the current network namespace.
+func podVeth() (netlink.Link, error) {
+	links, err := netlink.LinkList()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, l := range links {
+		if _, ok := l.(*netlink.Veth); !ok {
+			continue
+		}
+		if l.Attrs().ParentIndex == 0 {
+			continue
+		}
+		return l, nil
+	}
+
+	return nil, errors.New("no veth interface found")
+}
+
+// readInterface reads the qdiscs, the htb classes and the filters of an
+// interface. It also returns the indexes of the interfaces the filters
+// redirect packets to.
+func readInterface(link netlink.Link, side string) (types.Interface, []int, error) {
+	iface := types.Interface{
+		Name:   link.Attrs().Name,
+		Side:   side,
+		Qdiscs: []types.Qdisc{},
+	}
+	var redirects []int
+
+	qdiscs, err := netlink.QdiscList(link)
+	if err != nil {
+		return iface, nil, fmt.Errorf("listing qdiscs of %s: %w", iface.Name, err)
+	}
+
+	for _, q := range qdiscs {
+		attrs := q.Attrs()
+		qdisc := types.Qdisc{
+			Kind:   q.Type(),
+			Handle: netlink.HandleStr(attrs.Handle),
+			Parent: netlink.HandleStr(attrs.Parent),
+		}
+		if tbf, ok := q.(*netlink.Tbf); ok {
+			qdisc.Rate = tbf.Rate
+		}
+		iface.Qdiscs = append(iface.Qdiscs, qdisc)
+
+		if _, ok := q.(*netlink.Htb); ok {
+			classes, err := netlink.ClassList(link, attrs.Handle)
+			if err != nil {
+				return iface, nil, fmt.Errorf("listing classes of %s: %w", iface.Name, err)
+			}
+			for _, c := range classes {
+				iface.Classes = append(iface.Classes, newClass(c))
+			}
+		}
+
+		for _, parent := range filterParents(q) {
+			filters, err := netlink.FilterList(link, parent)
+			if err != nil {
+				return iface, nil, fmt.Errorf("listing filters of %s: %w", iface.Name, err)
+			}
+			for _, f := range filters {
+				filter, ifindexes := newFilter(f)
+				iface.Filters = append(iface.Filters, filter)
+				redirects = append(redirects, ifindexes...)
+			}
+		}
+	}
+
+	return iface, redirects, nil
+}
+
+// filterParents returns the parents the filters of a qdisc are attached
+// to: the ingress and egress hooks for the ingress and clsact qdiscs, the
+// qdisc itself otherwise.
+func filterParents(q netlink.Qdisc) []uint32 {
+	switch q.Type() {
+	case "ingress":
+		return []uint32{netlink.HANDLE_MIN_INGRESS}
+	case "clsact":
+		return []uint32{netlink.HANDLE_MIN_INGRESS, netlink.HANDLE_MIN_EGRESS}
+	}
+
+	if q.Attrs().Handle == netlink.HANDLE_NONE {
+		// Default qdiscs created by the kernel, like the pfifo_fast
+		// ones, don't have filters.
+		return nil
+	}
+	return []uint32{q.Attrs().Handle}
+}
+
+func newClass(c netlink.Class) types.Class {
+	attrs := c.Attrs()
+	class := types.Class{
+		Kind:   c.Type(),
+		Handle: netlink.HandleStr(attrs.Handle),
+		Parent: netlink.HandleStr(attrs.Parent),
+	}
+	if htb, ok := c.(*netlink.HtbClass); ok {
+		class.Rate = htb.Rate
+		class.Ceil = htb.Ceil
+	}
+	return class
+}
+
+// newFilter describes a filter and returns the indexes of the interfaces
+// it redirects packets to.
+func newFilter(f netlink.Filter) (types.Filter, []int) {
+	attrs := f.Attrs()
+	filter := types.Filter{
+		Kind:     f.Type(),
+		Parent:   netlink.HandleStr(attrs.Parent),
+		Priority: attrs.Priority,
+	}
+
+	var actions []netlink.Action
+	switch f := f.(type) {
+	case *netlink.BpfFilter:
+		filter.Action = f.Name
+		if f.DirectAction {
+			filter.Action += " direct-action"
+		}
+		return filter, nil
+	case *netlink.U32:
+		actions = f.Actions
+	case *netlink.MatchAll:
+		actions = f.Actions
+	}
+
+	var descriptions []string
+	var redirects []int
+	for _, a := range actions {
+		if mirred, ok := a.(*netlink.MirredAction); ok {
+			name := fmt.Sprintf("ifindex %d", mirred.Ifindex)
+			if l, err := netlink.LinkByIndex(mirred.Ifindex); err == nil {
+				name = l.Attrs().Name
+			}
+			descriptions = append(descriptions, fmt.Sprintf("mirred %s to %s", mirred.MirredAction, name))
+			redirects = append(redirects, mirred.Ifindex)
+			continue
+		}
+		descriptions = append(descriptions, a.Type())
+	}
+	filter.Action = strings.Join(descriptions, ", ")
+
+	return filter, redirects
+}
@@ -0,0 +1,119 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+const (
+	// SidePod is the interface of the pod, in its network namespace.
+	SidePod = "pod"
+	// SideHost is the peer of the interface of the pod, on the host.
+	SideHost = "host"
+	// SideIfb is an ifb interface the traffic of the host side interface
+	// is redirected to, as the bandwidth CNI plugin does to shape the
+	// traffic sent by the pod.
+	SideIfb = "ifb"
+)
+
+// QoS contains the traffic control configuration of the interfaces of a
+// pod, as read on the node.
+type QoS struct {
+	eventtypes.Event
+
+	Netns      uint64      `json:"netns"`
+	Interfaces []Interface `json:"interfaces"`
+}
+
+// Interface is the traffic control configuration of a network interface.
+type Interface struct {
+	Name string `json:"name"`
+	Side string `json:"side"`
+
+	Qdiscs  []Qdisc  `json:"qdiscs"`
+	Classes []Class  `json:"classes,omitempty"`
+	Filters []Filter `json:"filters,omitempty"`
+}
+
+type Qdisc struct {
+	Kind   string `json:"kind"`
+	Handle string `json:"handle"`
+	Parent string `json:"parent"`
+
+	// Rate is the rate in bytes per second of the tbf qdiscs.
+	Rate uint64 `json:"rate,omitempty"`
+}
+
+// Class is a class of a classful qdisc. Rate and Ceil are the guaranteed
+// and maximum rates in bytes per second of the htb classes.
+type Class struct {
+	Kind   string `json:"kind"`
+	Handle string `json:"handle"`
+	Parent string `json:"parent"`
+	Rate   uint64 `json:"rate,omitempty"`
+	Ceil   uint64 `json:"ceil,omitempty"`
+}
+
+type Filter struct {
+	Kind     string `json:"kind"`
+	Parent   string `json:"parent"`
+	Priority uint16 `json:"priority"`
+
+	// Action describes what the filter does, e.g. the eBPF program it
+	// runs or the interface it redirects the packets to.
+	Action string `json:"action,omitempty"`
+}
+
+func Base(ev eventtypes.Event) QoS {
+	return QoS{
+		Event: ev,
+	}
+}
+
+// IngressRate returns the rate in bytes per second the traffic received by
+// the pod is shaped to, by a tbf qdisc on the host side interface, or 0.
+func (q *QoS) IngressRate() uint64 {
+	return q.minTbfRate(SideHost)
+}
+
+// EgressRate returns the rate in bytes per second the traffic sent by the
+// pod is shaped to, by a tbf qdisc on the interface of the pod or on the
+// ifb interface its traffic is redirected to, or 0.
+func (q *QoS) EgressRate() uint64 {
+	pod, ifb := q.minTbfRate(SidePod), q.minTbfRate(SideIfb)
+	if pod == 0 || (ifb != 0 && ifb < pod) {
+		return ifb
+	}
+	return pod
+}
+
+func (q *QoS) minTbfRate(side string) uint64 {
+	var rate uint64
+	for _, i := range q.Interfaces {
+		if i.Side != side {
+			continue
+		}
+		for _, qdisc := range i.Qdiscs {
+			if qdisc.Kind != "tbf" || qdisc.Rate == 0 {
+				continue
+			}
+			if rate == 0 || qdisc.Rate < rate {
+				rate = qdisc.Rate
+			}
+		}
+	}
+	return rate
+}
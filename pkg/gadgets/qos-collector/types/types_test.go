@@ -0,0 +1,80 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"testing"
+)
+
+func TestRates(t *testing.T) {
+	table := []struct {
+		description string
+		interfaces  []Interface
+		ingress     uint64
+		egress      uint64
+	}{
+		{
+			description: "no shaping",
+			interfaces: []Interface{
+				{Name: "eth0", Side: SidePod, Qdiscs: []Qdisc{{Kind: "noqueue", Handle: "0:0", Parent: "root"}}},
+				{Name: "veth0", Side: SideHost, Qdiscs: []Qdisc{{Kind: "noqueue", Handle: "0:0", Parent: "root"}}},
+			},
+		},
+		{
+			description: "bandwidth CNI plugin",
+			interfaces: []Interface{
+				{Name: "eth0", Side: SidePod, Qdiscs: []Qdisc{{Kind: "noqueue", Handle: "0:0", Parent: "root"}}},
+				{
+					Name: "veth0",
+					Side: SideHost,
+					Qdiscs: []Qdisc{
+						{Kind: "tbf", Handle: "1:0", Parent: "root", Rate: 1250000},
+						{Kind: "ingress", Handle: "ffff:0", Parent: "ingress"},
+					},
+					Filters: []Filter{{Kind: "u32", Parent: "ffff:fff2", Priority: 1, Action: "mirred egress redir to bwp0"}},
+				},
+				{Name: "bwp0", Side: SideIfb, Qdiscs: []Qdisc{{Kind: "tbf", Handle: "1:0", Parent: "root", Rate: 125000}}},
+			},
+			ingress: 1250000,
+			egress:  125000,
+		},
+		{
+			description: "egress shaped in the pod",
+			interfaces: []Interface{
+				{Name: "eth0", Side: SidePod, Qdiscs: []Qdisc{{Kind: "tbf", Handle: "1:0", Parent: "root", Rate: 250000}}},
+				{Name: "veth0", Side: SideHost, Qdiscs: []Qdisc{{Kind: "noqueue", Handle: "0:0", Parent: "root"}}},
+			},
+			egress: 250000,
+		},
+		{
+			description: "lowest egress rate",
+			interfaces: []Interface{
+				{Name: "eth0", Side: SidePod, Qdiscs: []Qdisc{{Kind: "tbf", Handle: "1:0", Parent: "root", Rate: 250000}}},
+				{Name: "bwp0", Side: SideIfb, Qdiscs: []Qdisc{{Kind: "tbf", Handle: "1:0", Parent: "root", Rate: 500000}}},
+			},
+			egress: 250000,
+		},
+	}
+
+	for _, entry := range table {
+		q := QoS{Interfaces: entry.interfaces}
+		if rate := q.IngressRate(); rate != entry.ingress {
+			t.Errorf("%s: expected ingress rate %d, got %d", entry.description, entry.ingress, rate)
+		}
+		if rate := q.EgressRate(); rate != entry.egress {
+			t.Errorf("%s: expected egress rate %d, got %d", entry.description, entry.egress, rate)
+		}
+	}
+}
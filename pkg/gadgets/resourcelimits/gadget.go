@@ -0,0 +1,174 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resourcelimits
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/resourcelimits/tracer"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/resourcelimits/types"
+)
+
+type Trace struct {
+	resolver   gadgets.Resolver
+	withoutBPF bool
+
+	started bool
+	tracer  *tracer.Tracer
+}
+
+type TraceFactory struct {
+	gadgets.BaseFactory
+
+	// withoutBPF tells to list the processes from /proc instead of the
+	// BPF iterator.
+	withoutBPF bool
+}
+
+func NewFactory() gadgets.TraceFactory {
+	return &TraceFactory{
+		BaseFactory: gadgets.BaseFactory{DeleteTrace: deleteTrace},
+	}
+}
+
+func (f *TraceFactory) SetWithoutBPF() {
+	f.withoutBPF = true
+}
+
+// ObservesSandboxes tells that the cgroups of the sandboxed containers are
+// on the host.
+func (f *TraceFactory) ObservesSandboxes() {}
+
+func (f *TraceFactory) Description() string {
+	return `The resource-limits gadget samples the CPU and memory usage of the
+containers and, when it is stopped, recommends their resources requests and
+limits based on the 95th and 99th percentiles of the observed usage.`
+}
+
+func (f *TraceFactory) Parameters() []gadgets.GadgetParameter {
+	return []gadgets.GadgetParameter{
+		{
+			Name:        types.IntervalParam,
+			Description: "Sampling interval in seconds",
+			Default:     strconv.Itoa(types.IntervalDefault),
+		},
+	}
+}
+
+func (f *TraceFactory) OutputModesSupported() map[string]struct{} {
+	return map[string]struct{}{
+		"Status": {},
+	}
+}
+
+func deleteTrace(name string, t interface{}) {
+	trace := t.(*Trace)
+	if trace.tracer != nil {
+		trace.tracer.Stop()
+	}
+}
+
+func (f *TraceFactory) Operations() map[string]gadgets.TraceOperation {
+	n := func() interface{} {
+		return &Trace{
+			resolver:   f.Resolver,
+			withoutBPF: f.withoutBPF,
+		}
+	}
+
+	return map[string]gadgets.TraceOperation{
+		"start": {
+			Doc: "Start sampling the containers usage",
+			Operation: func(name string, trace *gadgetv1alpha1.Trace) {
+				f.LookupOrCreate(name, n).(*Trace).Start(trace)
+			},
+		},
+		"stop": {
+			Doc: "Stop sampling and store the recommendations",
+			Operation: func(name string, trace *gadgetv1alpha1.Trace) {
+				f.LookupOrCreate(name, n).(*Trace).Stop(trace)
+			},
+		},
+	}
+}
+
+func (t *Trace) Start(trace *gadgetv1alpha1.Trace) {
+	if t.started {
+		trace.Status.State = "Started"
+		return
+	}
+
+	interval := types.IntervalDefault
+	if val, ok := trace.Spec.Parameters[types.IntervalParam]; ok {
+		var err error
+		interval, err = strconv.Atoi(val)
+		if err != nil || interval <= 0 {
+			trace.Status.OperationError = fmt.Sprintf("%q is not valid for %q: must be a positive number of seconds",
+				val, types.IntervalParam)
+			return
+		}
+	}
+
+	config := &tracer.Config{
+		MountnsMap: gadgets.TracePinPath(trace.ObjectMeta.Namespace, trace.ObjectMeta.Name),
+		Interval:   time.Duration(interval) * time.Second,
+	}
+	if t.withoutBPF {
+		config.MountnsMap = ""
+		config.Selector = gadgets.ContainerSelectorFromContainerFilter(trace.Spec.Filter)
+	}
+
+	var err error
+	t.tracer, err = tracer.NewTracer(config, t.resolver, trace.Spec.Node)
+	if err != nil {
+		trace.Status.OperationError = fmt.Sprintf("failed to create tracer: %s", err)
+		return
+	}
+
+	t.started = true
+
+	trace.Status.Output = ""
+	trace.Status.State = "Started"
+}
+
+func (t *Trace) Stop(trace *gadgetv1alpha1.Trace) {
+	if !t.started {
+		trace.Status.OperationError = "Not started"
+		return
+	}
+
+	t.tracer.Stop()
+	recommendations := t.tracer.Recommendations()
+	t.tracer = nil
+	t.started = false
+
+	if len(recommendations) == 0 {
+		trace.Status.OperationWarning = "No container matches the requested filter"
+	}
+
+	output, err := json.Marshal(recommendations)
+	if err != nil {
+		trace.Status.OperationError = fmt.Sprintf("failed marshalling recommendations: %s", err)
+		return
+	}
+
+	trace.Status.Output = string(output)
+	trace.Status.State = "Completed"
+}
@@ -0,0 +1,209 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/resourcelimits/types"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+// UserHZ is the frequency of the clock used by the kernel to report CPU
+// times in /proc/<pid>/stat.
+const UserHZ = 100
+
+// ProcessSample is the usage of a process at a given time.
+type ProcessSample struct {
+	Pid int
+
+	// Ticks is the CPU time used by the process since it started, in
+	// clock ticks.
+	Ticks uint64
+
+	// RSS is the resident set size of the process in bytes.
+	RSS uint64
+}
+
+// ReadProcessSample reads the current usage of a process from /proc.
+func ReadProcessSample(pid int) (ProcessSample, error) {
+	stat, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return ProcessSample{}, err
+	}
+	statm, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/statm", pid))
+	if err != nil {
+		return ProcessSample{}, err
+	}
+
+	ticks, err := parseStat(string(stat))
+	if err != nil {
+		return ProcessSample{}, fmt.Errorf("pid %d: %w", pid, err)
+	}
+	rss, err := parseStatm(string(statm), os.Getpagesize())
+	if err != nil {
+		return ProcessSample{}, fmt.Errorf("pid %d: %w", pid, err)
+	}
+
+	return ProcessSample{Pid: pid, Ticks: ticks, RSS: rss}, nil
+}
+
+// parseStat returns the CPU time, user and system, found in the content
+// of /proc/<pid>/stat.
+func parseStat(data string) (uint64, error) {
+	// The command can contain spaces and parentheses, skip it.
+	i := strings.LastIndexByte(data, ')')
+	if i == -1 {
+		return 0, fmt.Errorf("invalid stat format")
+	}
+
+	// Fields after the command, starting from the state (3rd field).
+	fields := strings.Fields(data[i+1:])
+	if len(fields) < 13 {
+		return 0, fmt.Errorf("invalid stat format: %d fields", len(fields))
+	}
+
+	utime, err := strconv.ParseUint(fields[11], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid utime: %w", err)
+	}
+	stime, err := strconv.ParseUint(fields[12], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid stime: %w", err)
+	}
+
+	return utime + stime, nil
+}
+
+// parseStatm returns the resident set size in bytes found in the content
+// of /proc/<pid>/statm.
+func parseStatm(data string, pageSize int) (uint64, error) {
+	fields := strings.Fields(data)
+	if len(fields) < 2 {
+		return 0, fmt.Errorf("invalid statm format")
+	}
+
+	resident, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid resident size: %w", err)
+	}
+
+	return resident * uint64(pageSize), nil
+}
+
+type containerUsage struct {
+	event eventtypes.Event
+
+	// cpu and memory are the samples in millicores and bytes. There is
+	// one CPU sample less than memory samples as it's computed from the
+	// difference between two samples.
+	cpu    []uint64
+	memory []uint64
+
+	// prevTicks are the CPU times of the processes at the last sample.
+	prevTicks map[int]uint64
+}
+
+// Aggregator keeps the usage samples of the containers.
+type Aggregator struct {
+	containers map[string]*containerUsage
+}
+
+func NewAggregator() *Aggregator {
+	return &Aggregator{
+		containers: make(map[string]*containerUsage),
+	}
+}
+
+// Add records the usage of the processes of a container. elapsed is the
+// time since the previous sample.
+func (a *Aggregator) Add(event eventtypes.Event, processes []ProcessSample, elapsed time.Duration) {
+	key := event.Namespace + "/" + event.Pod + "/" + event.Container
+
+	usage, ok := a.containers[key]
+	if !ok {
+		usage = &containerUsage{event: event}
+		a.containers[key] = usage
+	}
+
+	var rss, ticks uint64
+	currTicks := make(map[int]uint64, len(processes))
+
+	for _, p := range processes {
+		rss += p.RSS
+		currTicks[p.Pid] = p.Ticks
+
+		// Processes started since the previous sample used all their CPU
+		// time during the interval.
+		prev := usage.prevTicks[p.Pid]
+		if p.Ticks >= prev {
+			ticks += p.Ticks - prev
+		}
+	}
+
+	if usage.prevTicks != nil && elapsed > 0 {
+		millicores := float64(ticks) / UserHZ / elapsed.Seconds() * 1000
+		usage.cpu = append(usage.cpu, uint64(math.Round(millicores)))
+	}
+	usage.memory = append(usage.memory, rss)
+	usage.prevTicks = currTicks
+}
+
+// Recommendations returns the usage percentiles of each container and the
+// recommended resources: requests cover 95% of the samples and limits 99%
+// of them, with some headroom for the memory.
+func (a *Aggregator) Recommendations() []types.Recommendation {
+	recommendations := make([]types.Recommendation, 0, len(a.containers))
+
+	for _, usage := range a.containers {
+		r := types.Recommendation{
+			Event:   usage.event,
+			Samples: len(usage.memory),
+			CPU:     types.NewPercentiles(usage.cpu),
+			Memory:  types.NewPercentiles(usage.memory),
+		}
+
+		r.Requests = types.Resources{
+			CPU:    types.CPUQuantity(r.CPU.P95),
+			Memory: types.MemoryQuantity(r.Memory.P95),
+		}
+		r.Limits = types.Resources{
+			CPU:    types.CPUQuantity(r.CPU.P99),
+			Memory: types.MemoryQuantity(uint64(float64(r.Memory.P99) * types.MemoryLimitHeadroom)),
+		}
+
+		recommendations = append(recommendations, r)
+	}
+
+	sort.Slice(recommendations, func(i, j int) bool {
+		ri, rj := recommendations[i], recommendations[j]
+		if ri.Namespace != rj.Namespace {
+			return ri.Namespace < rj.Namespace
+		}
+		if ri.Pod != rj.Pod {
+			return ri.Pod < rj.Pod
+		}
+		return ri.Container < rj.Container
+	})
+
+	return recommendations
+}
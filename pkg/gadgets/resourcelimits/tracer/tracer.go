@@ -0,0 +1,167 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	processcollectortracer "github.com/kinvolk/inspektor-gadget/pkg/gadgets/process-collector/tracer"
+	processcollectortypes "github.com/kinvolk/inspektor-gadget/pkg/gadgets/process-collector/types"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/resourcelimits/types"
+	pb "github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/api"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+type Config struct {
+	MountnsMap string
+	Interval   time.Duration
+
+	// Selector selects the containers when MountnsMap is empty: their
+	// processes are then listed from /proc instead of the BPF iterator.
+	Selector *pb.ContainerSelector
+}
+
+// Tracer periodically lists the processes of the containers with the
+// process-collector BPF iterator, or from /proc when the gadget pods don't
+// have the privileges needed by eBPF, and samples their CPU and memory
+// usage from /proc.
+type Tracer struct {
+	config   *Config
+	resolver gadgets.Resolver
+	node     string
+
+	mu         sync.Mutex
+	aggregator *Aggregator
+	lastSample time.Time
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewTracer(config *Config, resolver gadgets.Resolver, node string) (*Tracer, error) {
+	t := &Tracer{
+		config:     config,
+		resolver:   resolver,
+		node:       node,
+		aggregator: NewAggregator(),
+		done:       make(chan struct{}),
+	}
+
+	// Take the first sample synchronously to report errors, e.g. the BPF
+	// iterator not being supported, when the trace is started.
+	if err := t.sample(); err != nil {
+		return nil, err
+	}
+
+	t.wg.Add(1)
+	go t.run()
+
+	return t, nil
+}
+
+func (t *Tracer) run() {
+	defer t.wg.Done()
+
+	ticker := time.NewTicker(t.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.done:
+			return
+		case <-ticker.C:
+			if err := t.sample(); err != nil {
+				log.Warnf("resource-limits: failed to sample containers usage: %s", err)
+			}
+		}
+	}
+}
+
+func (t *Tracer) sample() error {
+	var events []processcollectortypes.Event
+	var err error
+	if t.config.MountnsMap == "" && t.config.Selector != nil {
+		events, err = processcollectortracer.RunProcfsCollector(t.resolver, t.node, t.config.Selector)
+	} else {
+		events, err = processcollectortracer.RunCollector(t.resolver, t.node, t.config.MountnsMap)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to list processes: %w", err)
+	}
+
+	now := time.Now()
+
+	type container struct {
+		event     eventtypes.Event
+		processes []ProcessSample
+	}
+	containers := make(map[uint64]*container)
+
+	for _, event := range events {
+		// The iterator reports all the threads, the usage is only read
+		// once per process.
+		if event.Tgid != event.Pid {
+			continue
+		}
+
+		sample, err := ReadProcessSample(event.Pid)
+		if err != nil {
+			// The process likely terminated in the meantime.
+			log.Debugf("resource-limits: %s", err)
+			continue
+		}
+
+		c, ok := containers[event.MountNsID]
+		if !ok {
+			c = &container{event: event.Event}
+			containers[event.MountNsID] = c
+		}
+		c.processes = append(c.processes, sample)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var elapsed time.Duration
+	if !t.lastSample.IsZero() {
+		elapsed = now.Sub(t.lastSample)
+	}
+	t.lastSample = now
+
+	for _, c := range containers {
+		t.aggregator.Add(c.event, c.processes, elapsed)
+	}
+
+	return nil
+}
+
+// Recommendations returns the resources recommended for the containers
+// sampled so far.
+func (t *Tracer) Recommendations() []types.Recommendation {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.aggregator.Recommendations()
+}
+
+func (t *Tracer) Stop() {
+	close(t.done)
+	t.wg.Wait()
+}
@@ -0,0 +1,85 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/resourcelimits/types"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+func TestParseStat(t *testing.T) {
+	data := "1234 (my (weird) comm) S 1 1234 1234 0 -1 4194560 1000 0 0 0 250 50 0 0 20 0 1 0 100 1000000 200 18446744073709551615\n"
+
+	ticks, err := parseStat(data)
+	if err != nil {
+		t.Fatalf("Failed to parse stat: %s", err)
+	}
+	if ticks != 300 {
+		t.Fatalf("Expected 300 ticks, got %d", ticks)
+	}
+
+	if _, err := parseStat("1234 (comm) S 1"); err == nil {
+		t.Fatalf("Expected error with truncated stat")
+	}
+}
+
+func TestParseStatm(t *testing.T) {
+	rss, err := parseStatm("5000 256 100 10 0 300 0\n", 4096)
+	if err != nil {
+		t.Fatalf("Failed to parse statm: %s", err)
+	}
+	if rss != 256*4096 {
+		t.Fatalf("Expected %d bytes, got %d", 256*4096, rss)
+	}
+}
+
+func TestAggregator(t *testing.T) {
+	a := NewAggregator()
+	event := eventtypes.Event{Namespace: "default", Pod: "mypod", Container: "app"}
+
+	// 50 ticks in 1s are 500 millicores.
+	a.Add(event, []ProcessSample{{Pid: 1, Ticks: 1000, RSS: 100 * types.Mebibyte}}, 0)
+	a.Add(event, []ProcessSample{{Pid: 1, Ticks: 1050, RSS: 200 * types.Mebibyte}}, time.Second)
+	// A new process used 10 ticks and the first one 40.
+	a.Add(event, []ProcessSample{
+		{Pid: 1, Ticks: 1090, RSS: 200 * types.Mebibyte},
+		{Pid: 2, Ticks: 10, RSS: 100 * types.Mebibyte},
+	}, time.Second)
+
+	expected := []types.Recommendation{
+		{
+			Event:   event,
+			Samples: 3,
+			CPU:     types.Percentiles{P50: 500, P95: 500, P99: 500},
+			Memory:  types.Percentiles{P50: 200 * types.Mebibyte, P95: 300 * types.Mebibyte, P99: 300 * types.Mebibyte},
+			Requests: types.Resources{
+				CPU:    "500m",
+				Memory: "300Mi",
+			},
+			Limits: types.Resources{
+				CPU:    "500m",
+				Memory: "360Mi",
+			},
+		},
+	}
+
+	if r := a.Recommendations(); !reflect.DeepEqual(r, expected) {
+		t.Fatalf("Expected %+v, got %+v", expected, r)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+const (
+	// IntervalParam is the parameter giving the sampling interval in
+	// seconds.
+	IntervalParam   = "interval"
+	IntervalDefault = 5
+
+	// MemoryLimitHeadroom is applied to the memory limit: unlike CPU,
+	// exceeding it gets the container killed.
+	MemoryLimitHeadroom = 1.2
+
+	Mebibyte = 1024 * 1024
+)
+
+// Percentiles of the usage observed during the sampling period. CPU is
+// expressed in millicores and memory in bytes.
+type Percentiles struct {
+	P50 uint64 `json:"p50"`
+	P95 uint64 `json:"p95"`
+	P99 uint64 `json:"p99"`
+}
+
+// percentile returns the p-th percentile of values using the nearest-rank
+// method.
+func percentile(sorted []uint64, p float64) uint64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// NewPercentiles returns the percentiles of values.
+func NewPercentiles(values []uint64) Percentiles {
+	sorted := append([]uint64{}, values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	return Percentiles{
+		P50: percentile(sorted, 50),
+		P95: percentile(sorted, 95),
+		P99: percentile(sorted, 99),
+	}
+}
+
+// CPUQuantity formats millicores as a Kubernetes resource quantity.
+func CPUQuantity(millicores uint64) string {
+	if millicores == 0 {
+		millicores = 1
+	}
+	return fmt.Sprintf("%dm", millicores)
+}
+
+// MemoryQuantity formats bytes as a Kubernetes resource quantity, rounded
+// up to the next mebibyte.
+func MemoryQuantity(bytes uint64) string {
+	mib := (bytes + Mebibyte - 1) / Mebibyte
+	if mib == 0 {
+		mib = 1
+	}
+	return fmt.Sprintf("%dMi", mib)
+}
+
+// Resources are the requests or limits of a container, in the format of
+// the Kubernetes resource quantities.
+type Resources struct {
+	CPU    string `json:"cpu"`
+	Memory string `json:"memory"`
+}
+
+// Recommendation contains the usage of a container and the requests and
+// limits recommended for it.
+type Recommendation struct {
+	eventtypes.Event
+
+	Samples int         `json:"samples"`
+	CPU     Percentiles `json:"cpu"`
+	Memory  Percentiles `json:"memory"`
+
+	Requests Resources `json:"requests"`
+	Limits   Resources `json:"limits"`
+}
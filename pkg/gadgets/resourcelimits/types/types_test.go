@@ -0,0 +1,44 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"testing"
+)
+
+func TestPercentile(t *testing.T) {
+	values := []uint64{}
+	for i := uint64(1); i <= 100; i++ {
+		values = append(values, 101-i)
+	}
+
+	expected := Percentiles{P50: 50, P95: 95, P99: 99}
+	if p := NewPercentiles(values); p != expected {
+		t.Fatalf("Expected %+v, got %+v", expected, p)
+	}
+
+	if p := NewPercentiles(nil); p != (Percentiles{}) {
+		t.Fatalf("Expected zero percentiles without values, got %+v", p)
+	}
+}
+
+func TestQuantities(t *testing.T) {
+	if q := CPUQuantity(0); q != "1m" {
+		t.Fatalf("Expected 1m, got %s", q)
+	}
+	if q := MemoryQuantity(Mebibyte + 1); q != "2Mi" {
+		t.Fatalf("Expected 2Mi, got %s", q)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runqlat
+
+import (
+	"fmt"
+
+	"github.com/cilium/ebpf"
+
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	"github.com/kinvolk/inspektor-gadget/pkg/bpferror"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	runqlattracer "github.com/kinvolk/inspektor-gadget/pkg/gadgets/runqlat/tracer"
+)
+
+type Trace struct {
+	started bool
+	tracer  *runqlattracer.Tracer
+}
+
+type TraceFactory struct {
+	gadgets.BaseFactory
+}
+
+func NewFactory() gadgets.TraceFactory {
+	return &TraceFactory{
+		BaseFactory: gadgets.BaseFactory{DeleteTrace: deleteTrace},
+	}
+}
+
+func (f *TraceFactory) Description() string {
+	return `The runqlat gadget records the time the threads spend waiting on a run
+queue for their turn on a CPU (scheduler latency), giving the distribution as
+a histogram when it is stopped. The threads of the whole node are traced
+unless a filter selects containers.`
+}
+
+func (f *TraceFactory) OutputModesSupported() map[string]struct{} {
+	return map[string]struct{}{
+		"Status": {},
+	}
+}
+
+func (f *TraceFactory) Maps(name string) map[string]*ebpf.Map {
+	t, ok := f.LookupOrCreate(name, nil).(*Trace)
+	if !ok || !t.started {
+		return nil
+	}
+	return t.tracer.Maps()
+}
+
+func deleteTrace(name string, t interface{}) {
+	trace := t.(*Trace)
+	if trace.tracer != nil {
+		trace.tracer.Stop()
+	}
+}
+
+func (f *TraceFactory) Operations() map[string]gadgets.TraceOperation {
+	n := func() interface{} {
+		return &Trace{}
+	}
+
+	return map[string]gadgets.TraceOperation{
+		"start": {
+			Doc: "Start runqlat",
+			Operation: func(name string, trace *gadgetv1alpha1.Trace) {
+				f.LookupOrCreate(name, n).(*Trace).Start(trace)
+			},
+		},
+		"stop": {
+			Doc: "Stop runqlat and store results",
+			Operation: func(name string, trace *gadgetv1alpha1.Trace) {
+				f.LookupOrCreate(name, n).(*Trace).Stop(trace)
+			},
+		},
+	}
+}
+
+func (t *Trace) Start(trace *gadgetv1alpha1.Trace) {
+	if t.started {
+		trace.Status.State = "Started"
+		return
+	}
+
+	config := &runqlattracer.Config{}
+	if trace.Spec.Filter != nil {
+		config.MountnsMap = gadgets.TracePinPath(trace.ObjectMeta.Namespace, trace.ObjectMeta.Name)
+	}
+
+	tracer, err := runqlattracer.NewTracer(config)
+	if err != nil {
+		trace.Status.OperationError = fmt.Sprintf("Failed to start: %s", bpferror.Describe(err))
+		return
+	}
+
+	t.tracer = tracer
+	t.started = true
+
+	trace.Status.Output = ""
+	trace.Status.State = "Started"
+}
+
+func (t *Trace) Stop(trace *gadgetv1alpha1.Trace) {
+	if !t.started {
+		trace.Status.OperationError = "Not started"
+		return
+	}
+
+	histogram, err := t.tracer.Histogram()
+
+	t.tracer.Stop()
+	t.tracer = nil
+	t.started = false
+
+	if err != nil {
+		trace.Status.OperationError = fmt.Sprintf("Failed to read results: %s", err)
+		return
+	}
+
+	trace.Status.Output = histogram.String()
+	trace.Status.State = "Completed"
+}
@@ -0,0 +1,142 @@
+// SPDX-License-Identifier: GPL-2.0
+// Copyright (c) 2022 The Inspektor Gadget authors
+// Based on runqlat(8) from libbpf-tools, Copyright (c) 2020 Wenbo Zhang
+#include <vmlinux/vmlinux.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_tracing.h>
+#include "runqlat.h"
+
+#define MAX_ENTRIES	10240
+#define TASK_RUNNING	0
+
+const volatile bool filter_by_mnt_ns = false;
+
+/* When each thread was enqueued, by thread id */
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, u32);
+	__type(value, u64);
+} start SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_ARRAY);
+	__uint(max_entries, MAX_SLOTS);
+	__type(key, u32);
+	__type(value, u64);
+} hist SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, 1024);
+	__uint(key_size, sizeof(u64));
+	__uint(value_size, sizeof(u32));
+} mount_ns_set SEC(".maps");
+
+/* The state field was renamed __state in Linux 5.14 */
+struct task_struct___x {
+	unsigned int __state;
+} __attribute__((preserve_access_index));
+
+static __always_inline long get_task_state(struct task_struct *task)
+{
+	struct task_struct___x *t = (struct task_struct___x *)task;
+
+	if (bpf_core_field_exists(t->__state))
+		return BPF_CORE_READ(t, __state);
+	return BPF_CORE_READ(task, state);
+}
+
+static __always_inline bool filtered(struct task_struct *task)
+{
+	u64 mntns_id;
+
+	if (!filter_by_mnt_ns)
+		return false;
+
+	mntns_id = (u64) BPF_CORE_READ(task, nsproxy, mnt_ns, ns.inum);
+	return !bpf_map_lookup_elem(&mount_ns_set, &mntns_id);
+}
+
+static __always_inline int trace_enqueue(struct task_struct *task)
+{
+	u32 pid = BPF_CORE_READ(task, pid);
+	u64 ts;
+
+	/* The idle tasks aren't accounted */
+	if (pid == 0 || filtered(task))
+		return 0;
+
+	ts = bpf_ktime_get_ns();
+	bpf_map_update_elem(&start, &pid, &ts, BPF_ANY);
+	return 0;
+}
+
+static __always_inline u32 log2(u32 v)
+{
+	u32 shift, r;
+
+	r = (v > 0xFFFF) << 4; v >>= r;
+	shift = (v > 0xFF) << 3; v >>= shift; r |= shift;
+	shift = (v > 0xF) << 2; v >>= shift; r |= shift;
+	shift = (v > 0x3) << 1; v >>= shift; r |= shift;
+	r |= (v >> 1);
+
+	return r;
+}
+
+static __always_inline u32 log2l(u64 v)
+{
+	u32 hi = v >> 32;
+
+	if (hi)
+		return log2(hi) + 32;
+	return log2(v);
+}
+
+SEC("raw_tracepoint/sched_wakeup")
+int ig_sched_wakeup(struct bpf_raw_tracepoint_args *ctx)
+{
+	return trace_enqueue((struct task_struct *)ctx->args[0]);
+}
+
+SEC("raw_tracepoint/sched_wakeup_new")
+int ig_sched_wakeup_new(struct bpf_raw_tracepoint_args *ctx)
+{
+	return trace_enqueue((struct task_struct *)ctx->args[0]);
+}
+
+SEC("raw_tracepoint/sched_switch")
+int ig_sched_switch(struct bpf_raw_tracepoint_args *ctx)
+{
+	struct task_struct *prev = (struct task_struct *)ctx->args[1];
+	struct task_struct *next = (struct task_struct *)ctx->args[2];
+	u64 *tsp, delta;
+	u32 pid, slot;
+	u64 *countp;
+
+	/* A preempted task goes back to the run queue */
+	if (get_task_state(prev) == TASK_RUNNING)
+		trace_enqueue(prev);
+
+	pid = BPF_CORE_READ(next, pid);
+	tsp = bpf_map_lookup_elem(&start, &pid);
+	if (!tsp)
+		return 0;
+
+	delta = (bpf_ktime_get_ns() - *tsp) / 1000;
+	bpf_map_delete_elem(&start, &pid);
+
+	slot = log2l(delta);
+	if (slot >= MAX_SLOTS)
+		slot = MAX_SLOTS - 1;
+
+	countp = bpf_map_lookup_elem(&hist, &slot);
+	if (countp)
+		__sync_fetch_and_add(countp, 1);
+
+	return 0;
+}
+
+char LICENSE[] SEC("license") = "GPL";
@@ -0,0 +1,12 @@
+/* SPDX-License-Identifier: (LGPL-2.1 OR BSD-2-Clause) */
+#ifndef __RUNQLAT_H
+#define __RUNQLAT_H
+
+/*
+ * The run queue latencies are counted in power-of-two buckets of
+ * microseconds: slot i is [2^i, 2^(i+1) - 1], slot 0 also holds 0 and the
+ * last one all the latencies longer than 2^(MAX_SLOTS - 1) µs.
+ */
+#define MAX_SLOTS	26
+
+#endif /* __RUNQLAT_H */
@@ -0,0 +1,151 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/runqlat/types"
+	"github.com/kinvolk/inspektor-gadget/pkg/mapdump"
+)
+
+// #include <linux/types.h>
+// #include "./bpf/runqlat.h"
+import "C"
+
+//go:generate sh -c "GOOS=$(go env GOHOSTOS) GOARCH=$(go env GOHOSTARCH) go run github.com/cilium/ebpf/cmd/bpf2go -target bpfel -cc clang runqlat ./bpf/runqlat.bpf.c -- -I./bpf/ -I../../.. -target bpf -D__TARGET_ARCH_x86"
+
+type Config struct {
+	// MountnsMap is the path of the pinned map of the mount namespaces of
+	// the containers to trace. All the processes of the node are traced
+	// when it's empty.
+	// TODO: Make it a *ebpf.Map once
+	// https://github.com/cilium/ebpf/issues/515 and
+	// https://github.com/cilium/ebpf/issues/517 are fixed
+	MountnsMap string
+}
+
+type Tracer struct {
+	config        *Config
+	objs          runqlatObjects
+	wakeupLink    link.Link
+	wakeupNewLink link.Link
+	switchLink    link.Link
+}
+
+func NewTracer(config *Config) (*Tracer, error) {
+	t := &Tracer{
+		config: config,
+	}
+
+	if err := t.start(); err != nil {
+		t.Stop()
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (t *Tracer) Stop() {
+	t.wakeupLink = gadgets.CloseLink(t.wakeupLink)
+	t.wakeupNewLink = gadgets.CloseLink(t.wakeupNewLink)
+	t.switchLink = gadgets.CloseLink(t.switchLink)
+
+	t.objs.Close()
+}
+
+// Maps returns the BPF maps of the tracer, so they can be dumped for
+// debugging.
+func (t *Tracer) Maps() map[string]*ebpf.Map {
+	return mapdump.MapsOf(&t.objs)
+}
+
+func (t *Tracer) start() error {
+	spec, err := loadRunqlat()
+	if err != nil {
+		return fmt.Errorf("failed to load ebpf program: %w", err)
+	}
+
+	filterByMntNs := false
+	opts := ebpf.CollectionOptions{}
+
+	if t.config.MountnsMap != "" {
+		filterByMntNs = true
+		m := spec.Maps["mount_ns_set"]
+		m.Pinning = ebpf.PinByName
+		m.Name = filepath.Base(t.config.MountnsMap)
+		opts.Maps.PinPath = filepath.Dir(t.config.MountnsMap)
+	}
+
+	consts := map[string]interface{}{
+		"filter_by_mnt_ns": filterByMntNs,
+	}
+
+	if err := spec.RewriteConstants(consts); err != nil {
+		return fmt.Errorf("error RewriteConstants: %w", err)
+	}
+
+	if err := spec.LoadAndAssign(&t.objs, &opts); err != nil {
+		return fmt.Errorf("failed to load ebpf program: %w", err)
+	}
+
+	t.wakeupLink, err = link.AttachRawTracepoint(link.RawTracepointOptions{
+		Name:    "sched_wakeup",
+		Program: t.objs.IgSchedWakeup,
+	})
+	if err != nil {
+		return fmt.Errorf("error opening raw tracepoint: %w", err)
+	}
+
+	t.wakeupNewLink, err = link.AttachRawTracepoint(link.RawTracepointOptions{
+		Name:    "sched_wakeup_new",
+		Program: t.objs.IgSchedWakeupNew,
+	})
+	if err != nil {
+		return fmt.Errorf("error opening raw tracepoint: %w", err)
+	}
+
+	t.switchLink, err = link.AttachRawTracepoint(link.RawTracepointOptions{
+		Name:    "sched_switch",
+		Program: t.objs.IgSchedSwitch,
+	})
+	if err != nil {
+		return fmt.Errorf("error opening raw tracepoint: %w", err)
+	}
+
+	return nil
+}
+
+// Histogram returns the distribution of the run queue latencies recorded
+// since the tracer was started.
+func (t *Tracer) Histogram() (*types.Histogram, error) {
+	h := &types.Histogram{
+		Unit:  "usecs",
+		Slots: make([]uint64, C.MAX_SLOTS),
+	}
+
+	for i := range h.Slots {
+		if err := t.objs.Hist.Lookup(uint32(i), &h.Slots[i]); err != nil {
+			return nil, fmt.Errorf("error reading histogram: %w", err)
+		}
+	}
+
+	return h, nil
+}
@@ -0,0 +1,66 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// histogramStars is the width of the distribution column.
+const histogramStars = 40
+
+// Histogram is a distribution of values in power-of-two buckets: Slots[i]
+// counts the values in [2^i, 2^(i+1) - 1], Slots[0] also counts 0.
+type Histogram struct {
+	// Unit is the unit of the values, e.g. "usecs".
+	Unit  string
+	Slots []uint64
+}
+
+// String prints the histogram in the format of the BCC tools, so that it
+// can be humanized like the one of biolatency. It returns an empty string
+// when there is no value.
+func (h *Histogram) String() string {
+	last := -1
+	var max uint64
+	for i, count := range h.Slots {
+		if count > 0 {
+			last = i
+		}
+		if count > max {
+			max = count
+		}
+	}
+	if last < 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%5s%-19s : count    distribution\n", "", h.Unit)
+	for i := 0; i <= last; i++ {
+		low := uint64(1) << i
+		high := uint64(1)<<(i+1) - 1
+		if i == 0 {
+			low = 0
+		}
+
+		stars := int(h.Slots[i] * histogramStars / max)
+		fmt.Fprintf(&sb, "%10d -> %-10d : %-8d |%s%s|\n", low, high, h.Slots[i],
+			strings.Repeat("*", stars), strings.Repeat(" ", histogramStars-stars))
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,41 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"testing"
+)
+
+func TestHistogramString(t *testing.T) {
+	h := &Histogram{
+		Unit:  "usecs",
+		Slots: []uint64{3, 0, 8, 4, 0, 0},
+	}
+
+	expected := `     usecs               : count    distribution
+         0 -> 1          : 3        |***************                         |
+         2 -> 3          : 0        |                                        |
+         4 -> 7          : 8        |****************************************|
+         8 -> 15         : 4        |********************                    |
+`
+	if s := h.String(); s != expected {
+		t.Fatalf("unexpected histogram:\n%s\nexpected:\n%s", s, expected)
+	}
+
+	empty := &Histogram{Unit: "usecs", Slots: make([]uint64, 4)}
+	if s := empty.String(); s != "" {
+		t.Fatalf("empty histogram should be printed as an empty string, got %q", s)
+	}
+}
@@ -0,0 +1,130 @@
+// SPDX-License-Identifier: GPL-2.0
+// Copyright (c) 2022 The Inspektor Gadget authors
+// Based on runqslower(8) from libbpf-tools, Copyright (c) 2019 Facebook
+#include <vmlinux/vmlinux.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_tracing.h>
+#include "runqslower.h"
+
+#define MAX_ENTRIES	10240
+#define TASK_RUNNING	0
+
+const volatile bool filter_by_mnt_ns = false;
+const volatile __u64 min_us = 0;
+
+/* When each thread was enqueued, by thread id */
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, u32);
+	__type(value, u64);
+} start SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_PERF_EVENT_ARRAY);
+	__uint(key_size, sizeof(u32));
+	__uint(value_size, sizeof(u32));
+} events SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, 1024);
+	__uint(key_size, sizeof(u64));
+	__uint(value_size, sizeof(u32));
+} mount_ns_set SEC(".maps");
+
+/* The state field was renamed __state in Linux 5.14 */
+struct task_struct___x {
+	unsigned int __state;
+} __attribute__((preserve_access_index));
+
+static __always_inline long get_task_state(struct task_struct *task)
+{
+	struct task_struct___x *t = (struct task_struct___x *)task;
+
+	if (bpf_core_field_exists(t->__state))
+		return BPF_CORE_READ(t, __state);
+	return BPF_CORE_READ(task, state);
+}
+
+static __always_inline u64 get_mntns_id(struct task_struct *task)
+{
+	return (u64) BPF_CORE_READ(task, nsproxy, mnt_ns, ns.inum);
+}
+
+static __always_inline int trace_enqueue(struct task_struct *task)
+{
+	u32 pid = BPF_CORE_READ(task, pid);
+	u64 mntns_id;
+	u64 ts;
+
+	/* The idle tasks aren't accounted */
+	if (pid == 0)
+		return 0;
+
+	if (filter_by_mnt_ns) {
+		mntns_id = get_mntns_id(task);
+		if (!bpf_map_lookup_elem(&mount_ns_set, &mntns_id))
+			return 0;
+	}
+
+	ts = bpf_ktime_get_ns();
+	bpf_map_update_elem(&start, &pid, &ts, BPF_ANY);
+	return 0;
+}
+
+SEC("raw_tracepoint/sched_wakeup")
+int ig_sched_wakeup(struct bpf_raw_tracepoint_args *ctx)
+{
+	return trace_enqueue((struct task_struct *)ctx->args[0]);
+}
+
+SEC("raw_tracepoint/sched_wakeup_new")
+int ig_sched_wakeup_new(struct bpf_raw_tracepoint_args *ctx)
+{
+	return trace_enqueue((struct task_struct *)ctx->args[0]);
+}
+
+SEC("raw_tracepoint/sched_switch")
+int ig_sched_switch(struct bpf_raw_tracepoint_args *ctx)
+{
+	struct task_struct *prev = (struct task_struct *)ctx->args[1];
+	struct task_struct *next = (struct task_struct *)ctx->args[2];
+	struct event event = {};
+	u64 *tsp, delta_us;
+	u32 pid;
+
+	/* A preempted task goes back to the run queue */
+	if (get_task_state(prev) == TASK_RUNNING)
+		trace_enqueue(prev);
+
+	pid = BPF_CORE_READ(next, pid);
+	tsp = bpf_map_lookup_elem(&start, &pid);
+	if (!tsp)
+		return 0;
+
+	delta_us = (bpf_ktime_get_ns() - *tsp) / 1000;
+	bpf_map_delete_elem(&start, &pid);
+
+	if (delta_us < min_us)
+		return 0;
+
+	/*
+	 * prev is the task which ran on the CPU just before: the last one the
+	 * waiting task was queued behind.
+	 */
+	event.mntns_id = get_mntns_id(next);
+	event.prev_mntns_id = get_mntns_id(prev);
+	event.delta_us = delta_us;
+	event.pid = pid;
+	event.prev_pid = BPF_CORE_READ(prev, pid);
+	bpf_probe_read_kernel_str(&event.task, sizeof(event.task), next->comm);
+	bpf_probe_read_kernel_str(&event.prev_task, sizeof(event.prev_task), prev->comm);
+
+	bpf_perf_event_output(ctx, &events, BPF_F_CURRENT_CPU, &event, sizeof(event));
+
+	return 0;
+}
+
+char LICENSE[] SEC("license") = "GPL";
@@ -0,0 +1,17 @@
+/* SPDX-License-Identifier: (LGPL-2.1 OR BSD-2-Clause) */
+#ifndef __RUNQSLOWER_H
+#define __RUNQSLOWER_H
+
+#define TASK_COMM_LEN	16
+
+struct event {
+	__u64 mntns_id;
+	__u64 prev_mntns_id;
+	__u64 delta_us;
+	__u32 pid;
+	__u32 prev_pid;
+	char task[TASK_COMM_LEN];
+	char prev_task[TASK_COMM_LEN];
+};
+
+#endif /* __RUNQSLOWER_H */
@@ -0,0 +1,54 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+const (
+	// MinLatencyDefault is the default minimum time, in microseconds, a
+	// thread has to wait on the run queue to be reported.
+	MinLatencyDefault = uint(10000)
+)
+
+type Event struct {
+	eventtypes.Event
+
+	// MountNsID, Pid and Comm are the ones of the thread which waited on
+	// the run queue.
+	MountNsID uint64 `json:"mountnsid,omitempty"`
+	Pid       uint32 `json:"pid,omitempty"`
+	Comm      string `json:"comm,omitempty"`
+
+	// The Prev fields describe the thread which ran on the CPU just before
+	// the waiting thread was switched in.
+	PrevMountNsID uint64 `json:"prevmountnsid,omitempty"`
+	PrevPid       uint32 `json:"prevpid,omitempty"`
+	PrevComm      string `json:"prevcomm,omitempty"`
+	PrevNamespace string `json:"prevnamespace,omitempty"`
+	PrevPod       string `json:"prevpod,omitempty"`
+	PrevContainer string `json:"prevcontainer,omitempty"`
+
+	// Latency is the time in microseconds the thread waited on the run
+	// queue.
+	Latency uint64 `json:"latency,omitempty"`
+}
+
+func Base(ev eventtypes.Event) Event {
+	return Event{
+		Event: ev,
+	}
+}
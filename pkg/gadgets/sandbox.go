@@ -0,0 +1,77 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgets
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	containercollection "github.com/kinvolk/inspektor-gadget/pkg/container-collection"
+)
+
+// TraceFactoryObservingSandboxes is implemented by the gadgets that still
+// see the containers running in a sandbox like gVisor or Kata Containers,
+// because they read the cgroups or the traffic of the pods on the host.
+// The other gadgets run in the host kernel and can't see what happens
+// inside the sandbox: the controller warns when their traces select
+// sandboxed containers.
+type TraceFactoryObservingSandboxes interface {
+	ObservesSandboxes()
+}
+
+// maxSandboxedContainers is the number of sandboxed containers listed in
+// the warning of SandboxWarning.
+const maxSandboxedContainers = 3
+
+// SandboxWarning returns a warning telling which containers selected by
+// the trace run in a sandbox the gadget can't observe, or an empty string
+// if there isn't any.
+func SandboxWarning(factory TraceFactory, resolver containercollection.ContainerResolver,
+	trace *gadgetv1alpha1.Trace,
+) string {
+	if _, ok := factory.(TraceFactoryObservingSandboxes); ok {
+		return ""
+	}
+
+	bySandbox := map[string][]string{}
+	containers := resolver.GetContainersBySelector(ContainerSelectorFromContainerFilter(trace.Spec.Filter))
+	for _, c := range containers {
+		if c.Sandbox == "" {
+			continue
+		}
+		bySandbox[c.Sandbox] = append(bySandbox[c.Sandbox],
+			c.Namespace+"/"+c.Podname+"/"+c.Name)
+	}
+	if len(bySandbox) == 0 {
+		return ""
+	}
+
+	sandboxes := []string{}
+	for sandbox, names := range bySandbox {
+		sort.Strings(names)
+		if len(names) > maxSandboxedContainers {
+			names = append(names[:maxSandboxedContainers],
+				fmt.Sprintf("and %d more", len(names)-maxSandboxedContainers))
+		}
+		sandboxes = append(sandboxes, fmt.Sprintf("%s (%s)", sandbox, strings.Join(names, ", ")))
+	}
+	sort.Strings(sandboxes)
+
+	return fmt.Sprintf("gadget %q can't observe the containers running in a sandbox, "+
+		"no data is reported for the containers running in %s",
+		trace.Spec.Gadget, strings.Join(sandboxes, " and in "))
+}
@@ -0,0 +1,93 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgets
+
+import (
+	"testing"
+
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	containercollection "github.com/kinvolk/inspektor-gadget/pkg/container-collection"
+	pb "github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/api"
+)
+
+type blindFactory struct {
+	BaseFactory
+}
+
+type observingFactory struct {
+	BaseFactory
+}
+
+func (f *observingFactory) ObservesSandboxes() {}
+
+func TestSandboxWarning(t *testing.T) {
+	cc := &containercollection.ContainerCollection{}
+	for _, c := range []*pb.ContainerDefinition{
+		{Id: "1", Namespace: "default", Podname: "web", Name: "nginx"},
+		{Id: "2", Namespace: "default", Podname: "untrusted-1", Name: "app", Sandbox: "gvisor"},
+		{Id: "3", Namespace: "default", Podname: "untrusted-2", Name: "app", Sandbox: "gvisor"},
+		{Id: "4", Namespace: "other", Podname: "vm", Name: "app", Sandbox: "kata"},
+	} {
+		cc.AddContainer(c)
+	}
+
+	table := []struct {
+		description string
+		factory     TraceFactory
+		filter      *gadgetv1alpha1.ContainerFilter
+		expected    string
+	}{
+		{
+			description: "no sandboxed container selected",
+			factory:     &blindFactory{},
+			filter:      &gadgetv1alpha1.ContainerFilter{Podname: "web"},
+			expected:    "",
+		},
+		{
+			description: "sandboxed containers selected",
+			factory:     &blindFactory{},
+			filter:      nil,
+			expected: `gadget "execsnoop" can't observe the containers running in a sandbox, ` +
+				`no data is reported for the containers running in ` +
+				`gvisor (default/untrusted-1/app, default/untrusted-2/app) and in kata (other/vm/app)`,
+		},
+		{
+			description: "sandboxed containers selected by the sandbox filter",
+			factory:     &blindFactory{},
+			filter:      &gadgetv1alpha1.ContainerFilter{Sandbox: "kata"},
+			expected: `gadget "execsnoop" can't observe the containers running in a sandbox, ` +
+				`no data is reported for the containers running in kata (other/vm/app)`,
+		},
+		{
+			description: "gadget observing the sandboxes",
+			factory:     &observingFactory{},
+			filter:      nil,
+			expected:    "",
+		},
+	}
+
+	for _, entry := range table {
+		trace := &gadgetv1alpha1.Trace{
+			Spec: gadgetv1alpha1.TraceSpec{
+				Gadget: "execsnoop",
+				Filter: entry.filter,
+			},
+		}
+		warning := SandboxWarning(entry.factory, cc, trace)
+		if warning != entry.expected {
+			t.Fatalf("%s: expected %q, got %q", entry.description, entry.expected, warning)
+		}
+	}
+}
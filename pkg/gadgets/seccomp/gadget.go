@@ -31,6 +31,7 @@ import (
 	k8syaml "sigs.k8s.io/yaml"
 
 	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	"github.com/kinvolk/inspektor-gadget/pkg/bpferror"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
 	seccomptracer "github.com/kinvolk/inspektor-gadget/pkg/gadgets/seccomp/tracer"
 	pb "github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/api"
@@ -389,7 +390,7 @@ func (t *Trace) Start(trace *gadgetv1alpha1.Trace) {
 		var err error
 		traceSingleton.tracer, err = seccomptracer.NewTracer()
 		if err != nil {
-			trace.Status.OperationError = fmt.Sprintf("Failed to start seccomp tracer: %s", err)
+			trace.Status.OperationError = fmt.Sprintf("Failed to start seccomp tracer: %s", bpferror.Describe(err))
 			return
 		}
 
@@ -438,6 +439,10 @@ func (t *Trace) Generate(trace *gadgetv1alpha1.Trace) {
 		trace.Status.OperationError = "Seccomp gadget does not support filtering by labels"
 		return
 	}
+	if len(trace.Spec.Filter.Annotations) != 0 {
+		trace.Status.OperationError = "Seccomp gadget does not support filtering by annotations"
+		return
+	}
 
 	var mntns uint64
 	var containerName string
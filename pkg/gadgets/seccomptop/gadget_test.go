@@ -0,0 +1,93 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package seccomptop
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSeccompProfile(t *testing.T) {
+	localhost := "operator/default/log.json"
+
+	table := []struct {
+		description string
+		pod         *corev1.Pod
+		expected    string
+	}{
+		{
+			description: "not set",
+			pod: &corev1.Pod{
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+			},
+		},
+		{
+			description: "pod",
+			pod: &corev1.Pod{
+				Spec: corev1.PodSpec{
+					SecurityContext: &corev1.PodSecurityContext{
+						SeccompProfile: &corev1.SeccompProfile{
+							Type:             corev1.SeccompProfileTypeLocalhost,
+							LocalhostProfile: &localhost,
+						},
+					},
+					Containers: []corev1.Container{{Name: "app"}},
+				},
+			},
+			expected: "Localhost/operator/default/log.json",
+		},
+		{
+			description: "container overriding pod",
+			pod: &corev1.Pod{
+				Spec: corev1.PodSpec{
+					SecurityContext: &corev1.PodSecurityContext{
+						SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeUnconfined},
+					},
+					Containers: []corev1.Container{
+						{Name: "sidecar"},
+						{
+							Name: "app",
+							SecurityContext: &corev1.SecurityContext{
+								SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+							},
+						},
+					},
+				},
+			},
+			expected: "RuntimeDefault",
+		},
+		{
+			description: "annotations",
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						corev1.SeccompPodAnnotationKey:                     "runtime/default",
+						corev1.SeccompContainerAnnotationKeyPrefix + "app": "localhost/audit.json",
+					},
+				},
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+			},
+			expected: "localhost/audit.json",
+		},
+	}
+
+	for _, entry := range table {
+		if profile := seccompProfile(entry.pod, "app"); profile != entry.expected {
+			t.Fatalf("%s: expected %q, got %q", entry.description, entry.expected, profile)
+		}
+	}
+}
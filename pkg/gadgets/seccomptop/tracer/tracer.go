@@ -0,0 +1,203 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tracer counts the syscalls audited by the seccomp filters of the
+// containers, as reported by the audit-seccomp tracer, by container,
+// syscall and action of the filter.
+package tracer
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	auditseccomptracer "github.com/kinvolk/inspektor-gadget/pkg/gadgets/audit-seccomp/tracer"
+	auditseccomptypes "github.com/kinvolk/inspektor-gadget/pkg/gadgets/audit-seccomp/types"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/seccomptop/types"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/threshold"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+type Config struct {
+	MaxRows int
+	// Interval is how often the counts are reported and reset.
+	Interval time.Duration
+	SortBy   types.SortBy
+	// TODO: Make it a *ebpf.Map once
+	// https://github.com/cilium/ebpf/issues/515 and
+	// https://github.com/cilium/ebpf/issues/517 are fixed
+	MountnsMap    string
+	ContainersMap string
+	Node          string
+
+	// Profile returns the seccomp profile of a container, empty if
+	// unknown. It's called once per container.
+	Profile func(namespace, pod, container string) string
+
+	// Thresholds marks the rows crossing thresholds. These rows are
+	// reported even if they are not part of the first MaxRows ones.
+	Thresholds *threshold.Config
+}
+
+// key identifies a row.
+type key struct {
+	namespace string
+	pod       string
+	container string
+	syscall   string
+	code      string
+}
+
+// containerKey identifies a container whose profile was looked up.
+type containerKey struct {
+	namespace string
+	pod       string
+	container string
+}
+
+type Tracer struct {
+	config        *Config
+	auditTracer   *auditseccomptracer.Tracer
+	statsCallback func([]types.Stats)
+	errorCallback func(error)
+	done          chan bool
+
+	mu       sync.Mutex
+	counts   map[key]uint64
+	profiles map[containerKey]string
+}
+
+func NewTracer(config *Config, statsCallback func([]types.Stats), errorCallback func(error)) (*Tracer, error) {
+	t := newTracer(config, statsCallback, errorCallback)
+
+	var err error
+	t.auditTracer, err = auditseccomptracer.NewTracer(&auditseccomptracer.Config{
+		MountnsMap:    config.MountnsMap,
+		ContainersMap: config.ContainersMap,
+	}, t.add, config.Node)
+	if err != nil {
+		return nil, err
+	}
+
+	t.run()
+
+	return t, nil
+}
+
+func newTracer(config *Config, statsCallback func([]types.Stats), errorCallback func(error)) *Tracer {
+	return &Tracer{
+		config:        config,
+		statsCallback: statsCallback,
+		errorCallback: errorCallback,
+		done:          make(chan bool),
+		counts:        make(map[key]uint64),
+		profiles:      make(map[containerKey]string),
+	}
+}
+
+// add counts an event of the audit-seccomp tracer.
+func (t *Tracer) add(event auditseccomptypes.Event) {
+	switch event.Type {
+	case eventtypes.NORMAL:
+	case eventtypes.ERR:
+		t.errorCallback(errors.New(event.Message))
+		return
+	default:
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.counts[key{
+		namespace: event.Namespace,
+		pod:       event.Pod,
+		container: event.Container,
+		syscall:   event.Syscall,
+		code:      event.Code,
+	}]++
+}
+
+// nextStats returns the counts since the last call, sorted, and resets
+// them.
+func (t *Tracer) nextStats() []types.Stats {
+	t.mu.Lock()
+	counts := t.counts
+	t.counts = make(map[key]uint64)
+	t.mu.Unlock()
+
+	stats := make([]types.Stats, 0, len(counts))
+	for k, count := range counts {
+		stats = append(stats, types.Stats{
+			Node:      t.config.Node,
+			Namespace: k.namespace,
+			Pod:       k.pod,
+			Container: k.container,
+			Profile:   t.profile(k.namespace, k.pod, k.container),
+			Syscall:   k.syscall,
+			Code:      k.code,
+			Count:     count,
+		})
+	}
+
+	types.SortStats(stats, t.config.SortBy)
+
+	return stats
+}
+
+// profile returns the seccomp profile of a container, looked up only the
+// first time. It's only called from the goroutine of run.
+func (t *Tracer) profile(namespace, pod, container string) string {
+	if t.config.Profile == nil || pod == "" {
+		return ""
+	}
+
+	k := containerKey{namespace: namespace, pod: pod, container: container}
+	profile, ok := t.profiles[k]
+	if !ok {
+		profile = t.config.Profile(namespace, pod, container)
+		t.profiles[k] = profile
+	}
+	return profile
+}
+
+func (t *Tracer) run() {
+	ticker := time.NewTicker(t.config.Interval)
+
+	go func() {
+		for {
+			select {
+			case <-t.done:
+				ticker.Stop()
+				return
+			case <-ticker.C:
+				stats := t.nextStats()
+
+				rows := []types.Stats{}
+				for i := range stats {
+					stats[i].Alerts = t.config.Thresholds.Check(&stats[i], t.config.Interval)
+					if i < t.config.MaxRows || len(stats[i].Alerts) > 0 {
+						rows = append(rows, stats[i])
+					}
+				}
+				t.statsCallback(rows)
+			}
+		}
+	}()
+}
+
+func (t *Tracer) Stop() {
+	close(t.done)
+	t.auditTracer.Close()
+}
@@ -0,0 +1,87 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	auditseccomptypes "github.com/kinvolk/inspektor-gadget/pkg/gadgets/audit-seccomp/types"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/seccomptop/types"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+func auditEvent(pod, syscall, code string) auditseccomptypes.Event {
+	return auditseccomptypes.Event{
+		Event: eventtypes.Event{
+			Type:      eventtypes.NORMAL,
+			Namespace: "default",
+			Pod:       pod,
+			Container: "app",
+		},
+		Syscall: syscall,
+		Code:    code,
+	}
+}
+
+func TestNextStats(t *testing.T) {
+	lookups := 0
+	config := &Config{
+		Node: "node-1",
+		Profile: func(namespace, pod, container string) string {
+			lookups++
+			return "Localhost/" + pod + ".json"
+		},
+	}
+	var errs []error
+	tracer := newTracer(config, nil, func(err error) { errs = append(errs, err) })
+
+	tracer.add(auditEvent("web", "mkdir", "log"))
+	tracer.add(auditEvent("web", "mkdir", "log"))
+	tracer.add(auditEvent("web", "unshare", "kill_process"))
+	tracer.add(auditEvent("db", "mkdir", "log"))
+	tracer.add(auditseccomptypes.Base(eventtypes.Warn("lost 1 samples", "node-1")))
+	tracer.add(auditseccomptypes.Base(eventtypes.Err("reading failed", "node-1")))
+
+	expected := []types.Stats{
+		{Node: "node-1", Namespace: "default", Pod: "web", Container: "app", Profile: "Localhost/web.json", Syscall: "mkdir", Code: "log", Count: 2},
+		{Node: "node-1", Namespace: "default", Pod: "db", Container: "app", Profile: "Localhost/db.json", Syscall: "mkdir", Code: "log", Count: 1},
+		{Node: "node-1", Namespace: "default", Pod: "web", Container: "app", Profile: "Localhost/web.json", Syscall: "unshare", Code: "kill_process", Count: 1},
+	}
+	stats := tracer.nextStats()
+	// The rows with the same count are in no particular order.
+	if len(stats) == 3 && stats[1].Pod == "web" {
+		stats[1], stats[2] = stats[2], stats[1]
+	}
+	if !reflect.DeepEqual(stats, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, stats)
+	}
+	if !reflect.DeepEqual(errs, []error{errors.New("reading failed")}) {
+		t.Fatalf("expected the error to be reported, got %v", errs)
+	}
+
+	// The counts are reset and the profiles looked up once.
+	if stats := tracer.nextStats(); len(stats) != 0 {
+		t.Fatalf("expected the counts to be reset, got %+v", stats)
+	}
+	tracer.add(auditEvent("web", "mkdir", "log"))
+	if stats := tracer.nextStats(); len(stats) != 1 || stats[0].Count != 1 {
+		t.Fatalf("expected a single mkdir, got %+v", stats)
+	}
+	if lookups != 2 {
+		t.Fatalf("expected 2 profile lookups, got %d", lookups)
+	}
+}
@@ -0,0 +1,264 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package advisor tells how the traffic of pods would be affected by the
+// injection of the sidecar of a service mesh, based on the sockets of the
+// pods observed over time.
+package advisor
+
+import (
+	"fmt"
+	"net"
+	"sort"
+
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/sidecarinjection/types"
+	socketcollectortypes "github.com/kinvolk/inspektor-gadget/pkg/gadgets/socket-collector/types"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+// serverFirstProtocols are the well-known ports of the protocols where the
+// server sends the first bytes. The sidecars wait for the client to speak
+// first to detect the protocol, so these connections hang unless the port
+// is configured explicitly.
+var serverFirstProtocols = map[uint16]string{
+	21:   "FTP",
+	22:   "SSH",
+	25:   "SMTP",
+	110:  "POP3",
+	143:  "IMAP",
+	587:  "SMTP",
+	3306: "MySQL",
+	4222: "NATS",
+}
+
+// reservedPorts are the ports used by the sidecars in the pods.
+var reservedPorts = map[string]map[uint16]struct{}{
+	types.MeshIstio: {
+		15000: {}, 15001: {}, 15004: {}, 15006: {}, 15008: {},
+		15009: {}, 15020: {}, 15021: {}, 15053: {}, 15090: {},
+	},
+	types.MeshLinkerd: {
+		4140: {}, 4143: {}, 4190: {}, 4191: {},
+	},
+}
+
+// linkerdOpaquePorts are the ports Linkerd handles as opaque, i.e.
+// without protocol detection, in its default configuration.
+var linkerdOpaquePorts = map[uint16]struct{}{
+	25: {}, 587: {}, 3306: {}, 4444: {}, 5432: {}, 6379: {}, 9300: {}, 11211: {},
+}
+
+// Ephemeral ports are used by the unconnected UDP sockets of the clients,
+// e.g. the DNS resolvers: they are not UDP servers.
+const (
+	ephemeralPortFirst = 32768
+	ephemeralPortLast  = 60999
+)
+
+type flowKey struct {
+	direction string
+	protocol  string
+	port      uint16
+}
+
+type pod struct {
+	event eventtypes.Event
+
+	// listening are the ports the pod accepts traffic on, by protocol.
+	listening map[string]map[uint16]struct{}
+
+	// flows are the remote endpoints seen for each flow.
+	flows map[flowKey]map[string]struct{}
+}
+
+type Advisor struct {
+	meshes []string
+	pods   map[string]*pod
+}
+
+func NewAdvisor(meshes []string) *Advisor {
+	return &Advisor{
+		meshes: meshes,
+		pods:   make(map[string]*pod),
+	}
+}
+
+func isLoopback(addr string) bool {
+	ip := net.ParseIP(addr)
+	return ip != nil && ip.IsLoopback()
+}
+
+func (p *pod) addFlow(key flowKey, peer string) {
+	peers, ok := p.flows[key]
+	if !ok {
+		peers = make(map[string]struct{})
+		p.flows[key] = peers
+	}
+	if peer != "" {
+		peers[peer] = struct{}{}
+	}
+}
+
+// Observe adds a sample of the sockets of the pods. The listening sockets
+// are handled first, so that the connections accepted on them are seen as
+// inbound traffic. The traffic on the loopback interface stays in the pod
+// and is never intercepted, it's ignored.
+func (a *Advisor) Observe(sockets []socketcollectortypes.Event) {
+	for _, s := range sockets {
+		switch {
+		case s.Protocol == "TCP" && s.Status == "LISTEN":
+		case s.Protocol == "UDP" && s.Status == "INACTIVE":
+			if s.LocalPort >= ephemeralPortFirst && s.LocalPort <= ephemeralPortLast {
+				continue
+			}
+		default:
+			continue
+		}
+
+		if isLoopback(s.LocalAddress) {
+			continue
+		}
+
+		p := a.pod(s.Event)
+		if p.listening[s.Protocol] == nil {
+			p.listening[s.Protocol] = make(map[uint16]struct{})
+		}
+		p.listening[s.Protocol][s.LocalPort] = struct{}{}
+		p.addFlow(flowKey{types.DirectionInbound, s.Protocol, s.LocalPort}, "")
+	}
+
+	for _, s := range sockets {
+		if s.RemotePort == 0 || isLoopback(s.RemoteAddress) {
+			continue
+		}
+		if s.Status == "LISTEN" || s.Status == "CLOSE" || s.Status == "INACTIVE" {
+			continue
+		}
+
+		p := a.pod(s.Event)
+		if _, ok := p.listening[s.Protocol][s.LocalPort]; ok {
+			p.addFlow(flowKey{types.DirectionInbound, s.Protocol, s.LocalPort}, s.RemoteAddress)
+		} else {
+			p.addFlow(flowKey{types.DirectionOutbound, s.Protocol, s.RemotePort}, s.RemoteAddress)
+		}
+	}
+}
+
+func (a *Advisor) pod(event eventtypes.Event) *pod {
+	key := event.Namespace + "/" + event.Pod
+	p, ok := a.pods[key]
+	if !ok {
+		p = &pod{
+			event: eventtypes.Event{
+				Type:      eventtypes.NORMAL,
+				Node:      event.Node,
+				Namespace: event.Namespace,
+				Pod:       event.Pod,
+			},
+			listening: make(map[string]map[uint16]struct{}),
+			flows:     make(map[flowKey]map[string]struct{}),
+		}
+		a.pods[key] = p
+	}
+	return p
+}
+
+func excludeAnnotation(mesh, direction string) string {
+	switch {
+	case mesh == types.MeshIstio && direction == types.DirectionInbound:
+		return "traffic.sidecar.istio.io/excludeInboundPorts"
+	case mesh == types.MeshIstio:
+		return "traffic.sidecar.istio.io/excludeOutboundPorts"
+	case direction == types.DirectionInbound:
+		return "config.linkerd.io/skip-inbound-ports"
+	}
+	return "config.linkerd.io/skip-outbound-ports"
+}
+
+// classify returns the impact of injecting the sidecar of mesh on a flow.
+func classify(mesh string, key flowKey) (string, string) {
+	if key.protocol == "UDP" {
+		return types.ImpactBypassed, fmt.Sprintf("UDP is not proxied by %s", mesh)
+	}
+
+	if key.direction == types.DirectionInbound {
+		if _, ok := reservedPorts[mesh][key.port]; ok {
+			return types.ImpactConflict, fmt.Sprintf("port %d is used by the %s proxy", key.port, mesh)
+		}
+	}
+
+	protocol, ok := serverFirstProtocols[key.port]
+	if !ok {
+		return types.ImpactIntercepted, ""
+	}
+
+	switch mesh {
+	case types.MeshIstio:
+		return types.ImpactNeedsConfig, fmt.Sprintf(
+			"%s is a server-first protocol: declare the port as TCP in the service (tcp-* name or appProtocol: tcp) or use %s",
+			protocol, excludeAnnotation(mesh, key.direction))
+	case types.MeshLinkerd:
+		if _, ok := linkerdOpaquePorts[key.port]; ok {
+			return types.ImpactIntercepted, fmt.Sprintf("%s port is opaque by default", protocol)
+		}
+		return types.ImpactNeedsConfig, fmt.Sprintf(
+			"%s is a server-first protocol: add the port to config.linkerd.io/opaque-ports or use %s",
+			protocol, excludeAnnotation(mesh, key.direction))
+	}
+
+	return types.ImpactIntercepted, ""
+}
+
+// Findings returns the impact of the sidecars on each flow of the pods
+// observed so far, sorted by pod, mesh, direction, protocol and port.
+func (a *Advisor) Findings() []types.Finding {
+	findings := []types.Finding{}
+
+	for _, p := range a.pods {
+		for key, peers := range p.flows {
+			for _, mesh := range a.meshes {
+				impact, reason := classify(mesh, key)
+				findings = append(findings, types.Finding{
+					Event:     p.event,
+					Mesh:      mesh,
+					Direction: key.direction,
+					Protocol:  key.protocol,
+					Port:      key.port,
+					Peers:     len(peers),
+					Impact:    impact,
+					Reason:    reason,
+				})
+			}
+		}
+	}
+
+	sort.Slice(findings, func(i, j int) bool {
+		a, b := &findings[i], &findings[j]
+		switch {
+		case a.Namespace != b.Namespace:
+			return a.Namespace < b.Namespace
+		case a.Pod != b.Pod:
+			return a.Pod < b.Pod
+		case a.Mesh != b.Mesh:
+			return a.Mesh < b.Mesh
+		case a.Direction != b.Direction:
+			return a.Direction < b.Direction
+		case a.Protocol != b.Protocol:
+			return a.Protocol < b.Protocol
+		}
+		return a.Port < b.Port
+	})
+
+	return findings
+}
@@ -0,0 +1,154 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package advisor
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/sidecarinjection/types"
+	socketcollectortypes "github.com/kinvolk/inspektor-gadget/pkg/gadgets/socket-collector/types"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+func socket(proto, local string, localPort uint16, remote string, remotePort uint16, status string) socketcollectortypes.Event {
+	return socketcollectortypes.Event{
+		Event: eventtypes.Event{
+			Node:      "node-1",
+			Namespace: "shop",
+			Pod:       "db-0",
+		},
+		Protocol:      proto,
+		LocalAddress:  local,
+		LocalPort:     localPort,
+		RemoteAddress: remote,
+		RemotePort:    remotePort,
+		Status:        status,
+	}
+}
+
+type result struct {
+	mesh      string
+	direction string
+	protocol  string
+	port      uint16
+	peers     int
+	impact    string
+}
+
+func summarize(findings []types.Finding) []result {
+	results := []result{}
+	for _, f := range findings {
+		results = append(results, result{f.Mesh, f.Direction, f.Protocol, f.Port, f.Peers, f.Impact})
+	}
+	return results
+}
+
+func TestFindings(t *testing.T) {
+	table := []struct {
+		description string
+		meshes      []string
+		samples     [][]socketcollectortypes.Event
+		expected    []result
+	}{
+		{
+			description: "HTTP server and client",
+			meshes:      []string{types.MeshIstio},
+			samples: [][]socketcollectortypes.Event{
+				{
+					socket("TCP", "0.0.0.0", 8080, "0.0.0.0", 0, "LISTEN"),
+					socket("TCP", "10.0.0.5", 8080, "10.0.0.7", 51234, "ESTABLISHED"),
+					socket("TCP", "10.0.0.5", 41000, "10.96.0.10", 443, "ESTABLISHED"),
+				},
+				{
+					socket("TCP", "0.0.0.0", 8080, "0.0.0.0", 0, "LISTEN"),
+					socket("TCP", "10.0.0.5", 8080, "10.0.0.8", 40000, "ESTABLISHED"),
+				},
+			},
+			expected: []result{
+				{types.MeshIstio, types.DirectionInbound, "TCP", 8080, 2, types.ImpactIntercepted},
+				{types.MeshIstio, types.DirectionOutbound, "TCP", 443, 1, types.ImpactIntercepted},
+			},
+		},
+		{
+			description: "Server-first protocol",
+			meshes:      []string{types.MeshIstio, types.MeshLinkerd},
+			samples: [][]socketcollectortypes.Event{
+				{
+					socket("TCP", "0.0.0.0", 3306, "0.0.0.0", 0, "LISTEN"),
+					socket("TCP", "10.0.0.5", 42000, "10.0.0.9", 22, "ESTABLISHED"),
+				},
+			},
+			expected: []result{
+				{types.MeshIstio, types.DirectionInbound, "TCP", 3306, 0, types.ImpactNeedsConfig},
+				{types.MeshIstio, types.DirectionOutbound, "TCP", 22, 1, types.ImpactNeedsConfig},
+				{types.MeshLinkerd, types.DirectionInbound, "TCP", 3306, 0, types.ImpactIntercepted},
+				{types.MeshLinkerd, types.DirectionOutbound, "TCP", 22, 1, types.ImpactNeedsConfig},
+			},
+		},
+		{
+			description: "Port used by the proxy",
+			meshes:      []string{types.MeshIstio, types.MeshLinkerd},
+			samples: [][]socketcollectortypes.Event{
+				{
+					socket("TCP", "0.0.0.0", 15090, "0.0.0.0", 0, "LISTEN"),
+				},
+			},
+			expected: []result{
+				{types.MeshIstio, types.DirectionInbound, "TCP", 15090, 0, types.ImpactConflict},
+				{types.MeshLinkerd, types.DirectionInbound, "TCP", 15090, 0, types.ImpactIntercepted},
+			},
+		},
+		{
+			description: "UDP",
+			meshes:      []string{types.MeshLinkerd},
+			samples: [][]socketcollectortypes.Event{
+				{
+					socket("UDP", "0.0.0.0", 5353, "0.0.0.0", 0, "INACTIVE"),
+					socket("UDP", "0.0.0.0", 45678, "0.0.0.0", 0, "INACTIVE"),
+					socket("UDP", "10.0.0.5", 45679, "10.96.0.10", 53, "ACTIVE"),
+				},
+			},
+			expected: []result{
+				{types.MeshLinkerd, types.DirectionInbound, "UDP", 5353, 0, types.ImpactBypassed},
+				{types.MeshLinkerd, types.DirectionOutbound, "UDP", 53, 1, types.ImpactBypassed},
+			},
+		},
+		{
+			description: "Loopback traffic is ignored",
+			meshes:      []string{types.MeshIstio},
+			samples: [][]socketcollectortypes.Event{
+				{
+					socket("TCP", "127.0.0.1", 9000, "0.0.0.0", 0, "LISTEN"),
+					socket("TCP", "127.0.0.1", 43000, "127.0.0.1", 9000, "ESTABLISHED"),
+					socket("TCP", "127.0.0.1", 9000, "127.0.0.1", 43000, "ESTABLISHED"),
+				},
+			},
+			expected: []result{},
+		},
+	}
+
+	for _, entry := range table {
+		a := NewAdvisor(entry.meshes)
+		for _, sample := range entry.samples {
+			a.Observe(sample)
+		}
+
+		results := summarize(a.Findings())
+		if !reflect.DeepEqual(results, entry.expected) {
+			t.Fatalf("Failed test %q: got %+v, expected %+v", entry.description, results, entry.expected)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sidecarinjection
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/sidecarinjection/tracer"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/sidecarinjection/types"
+)
+
+type Trace struct {
+	resolver gadgets.Resolver
+
+	started bool
+	tracer  *tracer.Tracer
+}
+
+type TraceFactory struct {
+	gadgets.BaseFactory
+}
+
+func NewFactory() gadgets.TraceFactory {
+	return &TraceFactory{
+		BaseFactory: gadgets.BaseFactory{DeleteTrace: deleteTrace},
+	}
+}
+
+func (f *TraceFactory) Description() string {
+	return `The sidecar-injection gadget observes the sockets of the pods and, when
+it is stopped, reports how their traffic would be affected by injecting the
+sidecar of a service mesh: server-first protocols that need to be configured
+explicitly, ports used by the proxy and UDP traffic that isn't proxied.`
+}
+
+func (f *TraceFactory) Parameters() []gadgets.GadgetParameter {
+	return []gadgets.GadgetParameter{
+		{
+			Name:        types.IntervalParam,
+			Description: "Sampling interval in seconds",
+			Default:     strconv.Itoa(types.IntervalDefault),
+		},
+		{
+			Name:        types.MeshParam,
+			Description: "Comma-separated list of service meshes to check, all by default",
+			Values:      types.Meshes,
+		},
+	}
+}
+
+func (f *TraceFactory) OutputModesSupported() map[string]struct{} {
+	return map[string]struct{}{
+		"Status": {},
+	}
+}
+
+func deleteTrace(name string, t interface{}) {
+	trace := t.(*Trace)
+	if trace.tracer != nil {
+		trace.tracer.Stop()
+	}
+}
+
+func (f *TraceFactory) Operations() map[string]gadgets.TraceOperation {
+	n := func() interface{} {
+		return &Trace{
+			resolver: f.Resolver,
+		}
+	}
+
+	return map[string]gadgets.TraceOperation{
+		"start": {
+			Doc: "Start observing the sockets of the pods",
+			Operation: func(name string, trace *gadgetv1alpha1.Trace) {
+				f.LookupOrCreate(name, n).(*Trace).Start(trace)
+			},
+		},
+		"stop": {
+			Doc: "Stop observing and store the compatibility report",
+			Operation: func(name string, trace *gadgetv1alpha1.Trace) {
+				f.LookupOrCreate(name, n).(*Trace).Stop(trace)
+			},
+		},
+	}
+}
+
+func (t *Trace) Start(trace *gadgetv1alpha1.Trace) {
+	if t.started {
+		trace.Status.State = "Started"
+		return
+	}
+
+	interval := types.IntervalDefault
+	if val, ok := trace.Spec.Parameters[types.IntervalParam]; ok {
+		var err error
+		interval, err = strconv.Atoi(val)
+		if err != nil || interval <= 0 {
+			trace.Status.OperationError = fmt.Sprintf("%q is not valid for %q: must be a positive number of seconds",
+				val, types.IntervalParam)
+			return
+		}
+	}
+
+	meshes, err := types.ParseMeshes(trace.Spec.Parameters[types.MeshParam])
+	if err != nil {
+		trace.Status.OperationError = fmt.Sprintf("%q is not valid for %q: %s",
+			trace.Spec.Parameters[types.MeshParam], types.MeshParam, err)
+		return
+	}
+
+	config := &tracer.Config{
+		Selector: gadgets.ContainerSelectorFromContainerFilter(trace.Spec.Filter),
+		Interval: time.Duration(interval) * time.Second,
+		Meshes:   meshes,
+	}
+
+	t.tracer, err = tracer.NewTracer(config, t.resolver, trace.Spec.Node)
+	if err != nil {
+		trace.Status.OperationError = fmt.Sprintf("failed to create tracer: %s", err)
+		return
+	}
+
+	t.started = true
+
+	trace.Status.Output = ""
+	trace.Status.State = "Started"
+}
+
+func (t *Trace) Stop(trace *gadgetv1alpha1.Trace) {
+	if !t.started {
+		trace.Status.OperationError = "Not started"
+		return
+	}
+
+	t.tracer.Stop()
+	findings := t.tracer.Findings()
+	t.tracer = nil
+	t.started = false
+
+	if len(findings) == 0 {
+		trace.Status.OperationWarning = "No traffic observed in the pods matching the requested filter"
+	}
+
+	output, err := json.Marshal(findings)
+	if err != nil {
+		trace.Status.OperationError = fmt.Sprintf("failed marshalling findings: %s", err)
+		return
+	}
+
+	trace.Status.Output = string(output)
+	trace.Status.State = "Completed"
+}
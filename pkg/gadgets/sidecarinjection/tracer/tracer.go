@@ -0,0 +1,143 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/sidecarinjection/advisor"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/sidecarinjection/types"
+	socketcollectortracer "github.com/kinvolk/inspektor-gadget/pkg/gadgets/socket-collector/tracer"
+	socketcollectortypes "github.com/kinvolk/inspektor-gadget/pkg/gadgets/socket-collector/types"
+	pb "github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/api"
+)
+
+type Config struct {
+	Selector *pb.ContainerSelector
+	Interval time.Duration
+	Meshes   []string
+}
+
+// Tracer periodically lists the sockets of the selected pods with the
+// socket-collector BPF iterators and gives them to the advisor. The
+// connections shorter than the interval can be missed.
+type Tracer struct {
+	config   *Config
+	resolver gadgets.Resolver
+	node     string
+
+	mu      sync.Mutex
+	advisor *advisor.Advisor
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewTracer(config *Config, resolver gadgets.Resolver, node string) (*Tracer, error) {
+	t := &Tracer{
+		config:   config,
+		resolver: resolver,
+		node:     node,
+		advisor:  advisor.NewAdvisor(config.Meshes),
+		done:     make(chan struct{}),
+	}
+
+	// Take the first sample synchronously to report errors, e.g. the BPF
+	// iterators not being supported, when the trace is started.
+	if err := t.sample(); err != nil {
+		return nil, err
+	}
+
+	t.wg.Add(1)
+	go t.run()
+
+	return t, nil
+}
+
+func (t *Tracer) run() {
+	defer t.wg.Done()
+
+	ticker := time.NewTicker(t.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.done:
+			return
+		case <-ticker.C:
+			if err := t.sample(); err != nil {
+				log.Warnf("sidecar-injection: failed to sample sockets: %s", err)
+			}
+		}
+	}
+}
+
+// sample lists the sockets of each selected pod once: all the containers
+// of a pod share the same network namespace. An error is only returned if
+// no pod could be sampled, a pod can terminate in the meantime.
+func (t *Tracer) sample() error {
+	containers := t.resolver.GetContainersBySelector(t.config.Selector)
+
+	visitedPods := make(map[string]struct{})
+	sampled := 0
+	var firstErr error
+
+	for _, container := range containers {
+		key := container.Namespace + "/" + container.Podname
+		if _, ok := visitedPods[key]; ok || container.Pid == 0 {
+			continue
+		}
+		visitedPods[key] = struct{}{}
+
+		sockets, err := socketcollectortracer.RunCollector(container.Pid, container.Podname,
+			container.Namespace, t.node, socketcollectortypes.ALL)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("pod %q: %w", key, err)
+			}
+			continue
+		}
+		sampled++
+
+		t.mu.Lock()
+		t.advisor.Observe(sockets)
+		t.mu.Unlock()
+	}
+
+	if sampled == 0 && firstErr != nil {
+		return firstErr
+	}
+
+	return nil
+}
+
+// Findings returns the impact of the sidecars on the traffic observed so
+// far.
+func (t *Tracer) Findings() []types.Finding {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.advisor.Findings()
+}
+
+func (t *Tracer) Stop() {
+	close(t.done)
+	t.wg.Wait()
+}
@@ -0,0 +1,116 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"fmt"
+	"strings"
+
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+const (
+	// IntervalParam is the parameter giving the interval in seconds
+	// between two samples of the sockets of the pods.
+	IntervalParam   = "interval"
+	IntervalDefault = 1
+
+	// MeshParam is the parameter giving the comma-separated list of
+	// service meshes to check the compatibility with.
+	MeshParam = "mesh"
+)
+
+const (
+	MeshIstio   = "istio"
+	MeshLinkerd = "linkerd"
+)
+
+// Meshes are the service meshes supported by the advisor, in the order
+// they are reported.
+var Meshes = []string{MeshIstio, MeshLinkerd}
+
+// ParseMeshes parses the comma-separated list of meshes of MeshParam. An
+// empty list selects all the supported meshes.
+func ParseMeshes(list string) ([]string, error) {
+	if list == "" {
+		return Meshes, nil
+	}
+
+	meshes := []string{}
+	for _, mesh := range strings.Split(list, ",") {
+		mesh = strings.ToLower(strings.TrimSpace(mesh))
+		found := false
+		for _, m := range Meshes {
+			if m == mesh {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%q is not a supported mesh (%s)", mesh, strings.Join(Meshes, ", "))
+		}
+		meshes = append(meshes, mesh)
+	}
+
+	return meshes, nil
+}
+
+const (
+	DirectionInbound  = "inbound"
+	DirectionOutbound = "outbound"
+)
+
+const (
+	// ImpactIntercepted means that the traffic is handled by the
+	// sidecar without any change.
+	ImpactIntercepted = "intercepted"
+
+	// ImpactBypassed means that the traffic keeps working but isn't
+	// handled by the sidecar: it's neither encrypted nor subject to the
+	// policies of the mesh.
+	ImpactBypassed = "bypassed"
+
+	// ImpactNeedsConfig means that the traffic is broken by the sidecar
+	// unless the port is configured explicitly.
+	ImpactNeedsConfig = "needs-config"
+
+	// ImpactConflict means that the pod uses a port reserved by the
+	// sidecar.
+	ImpactConflict = "conflict"
+)
+
+// Finding describes how the traffic of a pod on a given port would be
+// affected by injecting the sidecar of a service mesh.
+type Finding struct {
+	eventtypes.Event
+
+	Mesh      string `json:"mesh"`
+	Direction string `json:"direction"`
+	Protocol  string `json:"protocol"`
+	Port      uint16 `json:"port"`
+
+	// Peers is the number of distinct remote addresses seen on this
+	// port. It's 0 for a listening port without connections.
+	Peers int `json:"peers"`
+
+	Impact string `json:"impact"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Compatible tells if the traffic keeps working once the sidecar is
+// injected, maybe without being handled by it.
+func (f *Finding) Compatible() bool {
+	return f.Impact == ImpactIntercepted || f.Impact == ImpactBypassed
+}
@@ -0,0 +1,36 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMeshes(t *testing.T) {
+	meshes, err := ParseMeshes("")
+	if err != nil || !reflect.DeepEqual(meshes, Meshes) {
+		t.Fatalf("Empty list: got %v, %v", meshes, err)
+	}
+
+	meshes, err = ParseMeshes("Linkerd")
+	if err != nil || !reflect.DeepEqual(meshes, []string{MeshLinkerd}) {
+		t.Fatalf("Single mesh: got %v, %v", meshes, err)
+	}
+
+	if _, err := ParseMeshes("istio,consul"); err == nil {
+		t.Fatalf("Unsupported mesh: expected an error")
+	}
+}
@@ -19,11 +19,14 @@ import (
 	"fmt"
 	"strconv"
 
+	"github.com/kinvolk/inspektor-gadget/pkg/bpferror"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/sigsnoop/tracer"
 
 	coretracer "github.com/kinvolk/inspektor-gadget/pkg/gadgets/sigsnoop/tracer/core"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/sigsnoop/types"
+	"github.com/kinvolk/inspektor-gadget/pkg/kernellog"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
 
 	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
 
@@ -31,7 +34,8 @@ import (
 )
 
 type Trace struct {
-	resolver gadgets.Resolver
+	resolver  gadgets.Resolver
+	kernelLog *kernellog.Log
 
 	started bool
 	tracer  tracer.Tracer
@@ -39,6 +43,8 @@ type Trace struct {
 
 type TraceFactory struct {
 	gadgets.BaseFactory
+
+	kernelLog *kernellog.Log
 }
 
 func NewFactory() gadgets.TraceFactory {
@@ -48,13 +54,35 @@ func NewFactory() gadgets.TraceFactory {
 }
 
 func (f *TraceFactory) Description() string {
-	return `sigsnoop traces all signals sent on the system.
+	return `sigsnoop traces all signals sent on the system.`
+}
+
+func (f *TraceFactory) Parameters() []gadgets.GadgetParameter {
+	return []gadgets.GadgetParameter{
+		{
+			Name:        "failed",
+			Description: "Trace only failed signal sending",
+			Default:     "false",
+		},
+		{
+			Name:        "signal",
+			Description: "Which particular signal to trace, all the signals by default",
+		},
+		{
+			Name:        "pid",
+			Description: "Which particular pid to trace, all the processes by default",
+		},
+		{
+			Name:        "direction",
+			Description: "Trace the signals sent, received or both by the selected containers",
+			Default:     string(tracer.DirectionAll),
+			Values:      tracer.Directions,
+		},
+	}
+}
 
-The following parameters are supported:
-- failed: Trace only failed signal sending (default to false).
-- signal: Which particular signal to trace (default to all).
-- pid: Which particular pid to trace (default to all).
-`
+func (f *TraceFactory) SetKernelLog(l *kernellog.Log) {
+	f.kernelLog = l
 }
 
 func (f *TraceFactory) OutputModesSupported() map[string]struct{} {
@@ -73,7 +101,8 @@ func deleteTrace(name string, t interface{}) {
 func (f *TraceFactory) Operations() map[string]gadgets.TraceOperation {
 	n := func() interface{} {
 		return &Trace{
-			resolver: f.Resolver,
+			resolver:  f.Resolver,
+			kernelLog: f.kernelLog,
 		}
 	}
 
@@ -101,7 +130,7 @@ func (t *Trace) Start(trace *gadgetv1alpha1.Trace) {
 
 	traceName := gadgets.TraceName(trace.ObjectMeta.Namespace, trace.ObjectMeta.Name)
 
-	eventCallback := func(event types.Event) {
+	publishEvent := func(event types.Event) {
 		r, err := json.Marshal(event)
 		if err != nil {
 			log.Warnf("Gadget %s: error marshalling event: %s", trace.Spec.Gadget, err)
@@ -110,6 +139,21 @@ func (t *Trace) Start(trace *gadgetv1alpha1.Trace) {
 		t.resolver.PublishEvent(traceName, string(r))
 	}
 
+	eventCallback := func(event types.Event) {
+		if t.kernelLog == nil || event.Type != eventtypes.NORMAL || event.Signal != "SIGSEGV" {
+			publishEvent(event)
+			return
+		}
+
+		// The kernel usually logs the fault before sending the signal but
+		// the message can take some time to be read, wait for it without
+		// blocking the next events.
+		go func() {
+			event.KernelLog = t.kernelLog.Find(kernellog.Segfault(event.TargetPid))
+			publishEvent(event)
+		}()
+	}
+
 	params := trace.Spec.Parameters
 
 	targetSignal := ""
@@ -139,6 +183,17 @@ func (t *Trace) Start(trace *gadgetv1alpha1.Trace) {
 		failedOnly = failedParsed
 	}
 
+	direction := tracer.DirectionAll
+	if val, ok := params["direction"]; ok {
+		switch d := tracer.Direction(val); d {
+		case tracer.DirectionAll, tracer.DirectionSent, tracer.DirectionReceived:
+			direction = d
+		default:
+			trace.Status.OperationError = fmt.Sprintf("%q is not valid for direction", val)
+			return
+		}
+	}
+
 	var err error
 
 	config := &tracer.Config{
@@ -146,10 +201,11 @@ func (t *Trace) Start(trace *gadgetv1alpha1.Trace) {
 		TargetPid:    targetPid,
 		TargetSignal: targetSignal,
 		FailedOnly:   failedOnly,
+		Direction:    direction,
 	}
 	t.tracer, err = coretracer.NewTracer(config, t.resolver, eventCallback, trace.Spec.Node)
 	if err != nil {
-		trace.Status.OperationError = fmt.Sprintf("failed to create tracer: %s", err)
+		trace.Status.OperationError = fmt.Sprintf("failed to create tracer: %s", bpferror.Describe(err))
 		return
 	}
 
@@ -7,10 +7,15 @@
 
 #define MAX_ENTRIES	10240
 
+/* From include/linux/sched/signal.h */
+#define SEND_SIG_NOINFO	((struct kernel_siginfo *) 0)
+#define SEND_SIG_PRIV	((struct kernel_siginfo *) 1)
+
 const volatile pid_t filtered_pid = 0;
 const volatile int target_signal = 0;
 const volatile bool failed_only = false;
 const volatile bool filter_by_mnt_ns = false;
+const volatile int direction = DIRECTION_ALL;
 
 struct {
 	__uint(type, BPF_MAP_TYPE_HASH);
@@ -32,6 +37,27 @@ struct {
 	__uint(value_size, sizeof(u32));
 } mount_ns_set SEC(".maps");
 
+/*
+ * selected returns whether the signal sent by a process in mntns_id to a
+ * process in tmntns_id is traced: the sender, the receiver, or any of them
+ * depending on the direction, has to be in a selected container.
+ */
+static __always_inline bool selected(u64 mntns_id, u64 tmntns_id)
+{
+	if (!filter_by_mnt_ns)
+		return true;
+
+	if (direction != DIRECTION_RECEIVED &&
+	    bpf_map_lookup_elem(&mount_ns_set, &mntns_id))
+		return true;
+
+	if (direction != DIRECTION_SENT && tmntns_id &&
+	    bpf_map_lookup_elem(&mount_ns_set, &tmntns_id))
+		return true;
+
+	return false;
+}
+
 static int probe_entry(pid_t tpid, int sig)
 {
 	struct event event = {};
@@ -40,10 +66,12 @@ static int probe_entry(pid_t tpid, int sig)
 	u64 mntns_id;
 	struct task_struct *task;
 
+	/*
+	 * The receiver isn't known yet, the containers are filtered on exit
+	 * once sig_trace() found it.
+	 */
 	task = (struct task_struct *) bpf_get_current_task();
 	mntns_id = (u64) BPF_CORE_READ(task, nsproxy, mnt_ns, ns.inum);
-	if (filter_by_mnt_ns && !bpf_map_lookup_elem(&mount_ns_set, &mntns_id))
-		return 0;
 
 	if (target_signal && sig != target_signal)
 		return 0;
@@ -76,6 +104,9 @@ static int probe_exit(void *ctx, int ret)
 	if (failed_only && ret >= 0)
 		goto cleanup;
 
+	if (!selected(eventp->mntns_id, eventp->tmntns_id))
+		goto cleanup;
+
 	eventp->ret = ret;
 	bpf_perf_event_output(ctx, &events, BPF_F_CURRENT_CPU, eventp, sizeof(*eventp));
 
@@ -129,30 +160,52 @@ int tgkill_exit(struct trace_event_raw_sys_exit *ctx)
 	return probe_exit(ctx, ctx->ret);
 }
 
-SEC("tracepoint/signal/signal_generate")
-int sig_trace(struct trace_event_raw_signal_generate *ctx)
+/*
+ * signal_generate is a raw tracepoint to get the receiver, not given by the
+ * arguments of the tracepoint:
+ * TP_PROTO(int sig, struct kernel_siginfo *info, struct task_struct *task,
+ *          int group, int result)
+ */
+SEC("raw_tracepoint/signal_generate")
+int sig_trace(struct bpf_raw_tracepoint_args *ctx)
 {
 	struct event event = {};
-	pid_t tpid = ctx->pid;
-	int ret = ctx->errno;
-	int sig = ctx->sig;
+	int sig = (int)ctx->args[0];
+	struct kernel_siginfo *info = (struct kernel_siginfo *)ctx->args[1];
+	struct task_struct *target = (struct task_struct *)ctx->args[2];
+	pid_t tpid;
+	int ret = 0;
 	__u64 pid_tgid;
-	__u32 pid;
-	u64 mntns_id;
+	__u32 pid, tid;
+	u64 mntns_id, tmntns_id;
 	struct task_struct *task;
+	struct event *eventp;
 
 	task = (struct task_struct *) bpf_get_current_task();
 	mntns_id = (u64) BPF_CORE_READ(task, nsproxy, mnt_ns, ns.inum);
-	if (filter_by_mnt_ns && !bpf_map_lookup_elem(&mount_ns_set, &mntns_id))
+	tmntns_id = (u64) BPF_CORE_READ(target, nsproxy, mnt_ns, ns.inum);
+	tpid = BPF_CORE_READ(target, pid);
+
+	/* Give the receiver to the kill syscall being traced, if any */
+	pid_tgid = bpf_get_current_pid_tgid();
+	tid = (__u32)pid_tgid;
+	eventp = bpf_map_lookup_elem(&values, &tid);
+	if (eventp && !eventp->tmntns_id)
+		eventp->tmntns_id = tmntns_id;
+
+	if (!selected(mntns_id, tmntns_id))
 		return 0;
 
+	/* Like the tracepoint, see TP_STORE_SIGINFO() */
+	if (info != SEND_SIG_NOINFO && info != SEND_SIG_PRIV)
+		ret = BPF_CORE_READ(info, si_errno);
+
 	if (failed_only && ret == 0)
 		return 0;
 
 	if (target_signal && sig != target_signal)
 		return 0;
 
-	pid_tgid = bpf_get_current_pid_tgid();
 	pid = pid_tgid >> 32;
 	if (filtered_pid && pid != filtered_pid)
 		return 0;
@@ -160,6 +213,7 @@ int sig_trace(struct trace_event_raw_signal_generate *ctx)
 	event.pid = pid;
 	event.tpid = tpid;
 	event.mntns_id = mntns_id;
+	event.tmntns_id = tmntns_id;
 	event.sig = sig;
 	event.ret = ret;
 	bpf_get_current_comm(event.comm, sizeof(event.comm));
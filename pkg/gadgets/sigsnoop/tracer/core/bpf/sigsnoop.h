@@ -4,10 +4,17 @@
 
 #define TASK_COMM_LEN	16
 
+/* Direction of the signals traced relative to the selected containers */
+#define DIRECTION_ALL		0
+#define DIRECTION_SENT		1
+#define DIRECTION_RECEIVED	2
+
 struct event {
 	__u32 pid;
 	__u32 tpid;
 	__u64 mntns_id;
+	/* Mount namespace of the receiver, 0 if the signal wasn't generated */
+	__u64 tmntns_id;
 	int sig;
 	int ret;
 	char comm[TASK_COMM_LEN];
@@ -136,11 +136,24 @@ func (t *Tracer) start() error {
 		return fmt.Errorf("cannot translate signal (%q) to int: %w", t.config.TargetSignal, err)
 	}
 
+	var direction int32
+	switch t.config.Direction {
+	case tracer.DirectionAll, "":
+		direction = C.DIRECTION_ALL
+	case tracer.DirectionSent:
+		direction = C.DIRECTION_SENT
+	case tracer.DirectionReceived:
+		direction = C.DIRECTION_RECEIVED
+	default:
+		return fmt.Errorf("invalid direction %q", t.config.Direction)
+	}
+
 	consts := map[string]interface{}{
 		"filter_by_mnt_ns": filterByMntNs,
 		"filtered_pid":     t.config.TargetPid,
 		"target_signal":    signal,
 		"failed_only":      t.config.FailedOnly,
+		"direction":        direction,
 	}
 
 	if err := spec.RewriteConstants(consts); err != nil {
@@ -187,9 +200,12 @@ func (t *Tracer) start() error {
 		return fmt.Errorf("error opening tracepoint: %w", err)
 	}
 
-	t.signalGenerateLink, err = link.Tracepoint("signal", "signal_generate", t.objs.SigTrace, nil)
+	t.signalGenerateLink, err = link.AttachRawTracepoint(link.RawTracepointOptions{
+		Name:    "signal_generate",
+		Program: t.objs.SigTrace,
+	})
 	if err != nil {
-		return fmt.Errorf("error opening tracepoint: %w", err)
+		return fmt.Errorf("error opening raw tracepoint: %w", err)
 	}
 
 	t.reader, err = perf.NewReader(t.objs.sigsnoopMaps.Events, gadgets.PerfBufferPages*os.Getpagesize())
@@ -235,15 +251,32 @@ func (t *Tracer) run() {
 			Retval:    int(eventC.ret),
 			MountNsID: uint64(eventC.mntns_id),
 			Comm:      C.GoString(&eventC.comm[0]),
+
+			TargetMountNsID: uint64(eventC.tmntns_id),
 		}
 
 		container := t.resolver.LookupContainerByMntns(event.MountNsID)
 		if container != nil {
 			event.Container = container.Name
 			event.Pod = container.Podname
+			event.Sandbox = container.Sandbox
 			event.Namespace = container.Namespace
 		}
 
+		if event.TargetMountNsID != 0 {
+			container := t.resolver.LookupContainerByMntns(event.TargetMountNsID)
+			if container != nil {
+				event.TargetContainer = container.Name
+				event.TargetPod = container.Podname
+				event.TargetNamespace = container.Namespace
+			}
+
+			event.Origin = types.OriginExternal
+			if event.TargetMountNsID == event.MountNsID {
+				event.Origin = types.OriginInternal
+			}
+		}
+
 		t.eventCallback(event)
 	}
 }
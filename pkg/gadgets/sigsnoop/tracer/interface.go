@@ -27,4 +27,22 @@ type Config struct {
 	TargetSignal string
 	TargetPid    int32
 	FailedOnly   bool
+
+	// Direction selects whether the signals sent, received or both by the
+	// containers selected by MountnsMap are traced.
+	Direction Direction
+}
+
+type Direction string
+
+const (
+	DirectionAll      Direction = "all"
+	DirectionSent     Direction = "sent"
+	DirectionReceived Direction = "received"
+)
+
+var Directions = []string{
+	string(DirectionAll),
+	string(DirectionSent),
+	string(DirectionReceived),
 }
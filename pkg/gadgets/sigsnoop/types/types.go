@@ -27,8 +27,31 @@ type Event struct {
 	Retval    int    `json:"ret,omitempty"`
 	Comm      string `json:"comm,omitempty"`
 	MountNsID uint64 `json:"mountnsid,omitempty"`
+
+	// Receiver of the signal, empty if it isn't in a container or if the
+	// signal wasn't generated, e.g. because the process didn't exist.
+	TargetNamespace string `json:"tnamespace,omitempty"`
+	TargetPod       string `json:"tpod,omitempty"`
+	TargetContainer string `json:"tcontainer,omitempty"`
+	TargetMountNsID uint64 `json:"tmountnsid,omitempty"`
+
+	// Origin tells whether the signal was sent from inside the mount
+	// namespace, i.e. the container, of the receiver or from outside, like
+	// the SIGTERM sent by the container runtime when stopping a container.
+	Origin Origin `json:"origin,omitempty"`
+
+	// KernelLog are the lines of the kernel log about the fault causing a
+	// SIGSEGV, when the gadget pods are deployed with a kernel log source.
+	KernelLog []string `json:"kernelLog,omitempty"`
 }
 
+type Origin string
+
+const (
+	OriginInternal Origin = "internal"
+	OriginExternal Origin = "external"
+)
+
 func Base(ev eventtypes.Event) Event {
 	return Event{
 		Event: ev,
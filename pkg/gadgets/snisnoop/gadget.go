@@ -24,6 +24,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	"github.com/kinvolk/inspektor-gadget/pkg/bpferror"
 	containerutils "github.com/kinvolk/inspektor-gadget/pkg/container-utils"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
 	snitracer "github.com/kinvolk/inspektor-gadget/pkg/gadgets/snisnoop/tracer"
@@ -58,6 +59,10 @@ func NewFactory() gadgets.TraceFactory {
 	}
 }
 
+// ObservesSandboxes tells that the traffic of the sandboxed pods goes through
+// their network namespace on the host.
+func (f *TraceFactory) ObservesSandboxes() {}
+
 func (f *TraceFactory) Description() string {
 	return `The snisnoop gadget retrieves Server Name Indication (SNI) from TLS requests.`
 }
@@ -68,6 +73,10 @@ func (f *TraceFactory) OutputModesSupported() map[string]struct{} {
 	}
 }
 
+func (f *TraceFactory) NewEvent() gadgets.Event {
+	return &types.Event{}
+}
+
 func deleteTrace(name string, t interface{}) {
 	trace := t.(*Trace)
 	if trace.started {
@@ -117,7 +126,7 @@ func (t *Trace) Start(trace *gadgetv1alpha1.Trace) {
 	var err error
 	t.tracer, err = snitracer.NewTracer()
 	if err != nil {
-		trace.Status.OperationError = fmt.Sprintf("Failed to start sni tracer: %s", err)
+		trace.Status.OperationError = fmt.Sprintf("Failed to start sni tracer: %s", bpferror.Describe(err))
 		return
 	}
 
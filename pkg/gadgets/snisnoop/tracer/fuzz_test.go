@@ -0,0 +1,46 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"testing"
+)
+
+// The fuzz targets are run with Go 1.18 or later:
+// go test -fuzz=FuzzParseSNIEvent ./pkg/gadgets/snisnoop/tracer/
+
+func FuzzParseSNIEvent(f *testing.F) {
+	f.Add([]byte("kinvolk.io\x00"))
+	f.Add([]byte("\x00"))
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, raw []byte) {
+		name, err := ParseSNIEvent(raw)
+		if err != nil {
+			return
+		}
+		if len(name) > 128 {
+			t.Fatalf("name %q longer than the event", name)
+		}
+		for _, c := range []byte(name) {
+			if c <= ' ' || c > '~' {
+				t.Fatalf("name %q contains the unprintable byte %d", name, c)
+			}
+		}
+	})
+}
@@ -15,15 +15,16 @@
 package tracer
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"os"
-	"strings"
 	"sync"
 	"syscall"
 
 	"github.com/cilium/ebpf"
 	"github.com/cilium/ebpf/perf"
+	log "github.com/sirupsen/logrus"
 	"golang.org/x/sys/unix"
 
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
@@ -134,16 +135,27 @@ func (t *Tracer) Attach(
 	return nil
 }
 
-func parseSNIEvent(rawSample []byte) (ret string) {
-	name := make([]byte, C.TLS_MAX_SERVER_NAME_LEN)
-	copy(name, rawSample)
+// ParseSNIEvent parses an event sent by the BPF program: the server name of
+// a TLS Client Hello, terminated by a NUL byte unless it fills the event.
+// The server names come from the packets seen on the network, so an error
+// is returned if they contain bytes which aren't printable ASCII characters,
+// which are not allowed in host names (RFC 6066 section 3).
+func ParseSNIEvent(rawSample []byte) (string, error) {
+	name := rawSample
+	if len(name) > C.TLS_MAX_SERVER_NAME_LEN {
+		name = name[:C.TLS_MAX_SERVER_NAME_LEN]
+	}
+	if i := bytes.IndexByte(name, 0); i >= 0 {
+		name = name[:i]
+	}
 
-	str := string(name)
-	i := strings.Index(str, "\x00")
-	if i > 0 {
-		return str[:i]
+	for _, c := range name {
+		if c <= ' ' || c > '~' {
+			return "", fmt.Errorf("invalid byte %d in server name", c)
+		}
 	}
-	return str
+
+	return string(name), nil
 }
 
 func (t *Tracer) listen(
@@ -169,7 +181,11 @@ func (t *Tracer) listen(
 			continue
 		}
 
-		name := parseSNIEvent(record.RawSample)
+		name, err := ParseSNIEvent(record.RawSample)
+		if err != nil {
+			log.Debugf("Ignoring malformed SNI event (%s): %s", key, err)
+			continue
+		}
 
 		if len(name) > 0 {
 			event := types.Event{
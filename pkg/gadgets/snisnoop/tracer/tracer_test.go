@@ -0,0 +1,68 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseSNIEvent(t *testing.T) {
+	long := bytes.Repeat([]byte{'a'}, 200)
+
+	table := []struct {
+		input  []byte
+		output string
+		err    bool
+	}{
+		{
+			input:  append([]byte("kinvolk.io\x00"), 0x42, 0x42),
+			output: "kinvolk.io",
+		},
+		{
+			input:  []byte("\x00kinvolk.io"),
+			output: "",
+		},
+		{
+			// The name is limited to the size of the event.
+			input:  long,
+			output: string(long[:128]),
+		},
+		{
+			input: []byte("kinvolk\x1b[31m.io\x00"),
+			err:   true,
+		},
+		{
+			input: []byte("kinvolk\xc3\xa9.io\x00"),
+			err:   true,
+		},
+	}
+
+	for _, entry := range table {
+		output, err := ParseSNIEvent(entry.input)
+		if entry.err {
+			if err == nil {
+				t.Fatalf("Parsing %q: expected an error, got %q", entry.input, output)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Failed to parse server name %q: %s", entry.input, err)
+		}
+		if output != entry.output {
+			t.Fatalf("Failed to parse server name: got %q, expected %q", output, entry.output)
+		}
+	}
+}
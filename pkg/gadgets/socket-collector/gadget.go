@@ -42,6 +42,17 @@ func (f *TraceFactory) Description() string {
 	return `The socket-collector gadget gathers information about TCP and UDP sockets.`
 }
 
+func (f *TraceFactory) Parameters() []gadgets.GadgetParameter {
+	return []gadgets.GadgetParameter{
+		{
+			Name:        "protocol",
+			Description: "Protocol of the sockets to collect",
+			Default:     "all",
+			Values:      []string{"all", "tcp", "udp"},
+		},
+	}
+}
+
 func (f *TraceFactory) OutputModesSupported() map[string]struct{} {
 	return map[string]struct{}{
 		"Status": {},
@@ -0,0 +1,169 @@
+// SPDX-License-Identifier: GPL-2.0
+// Copyright (c) 2022 The Inspektor Gadget authors
+// Based on solisten(8) from libbpf-tools, Copyright (c) 2021 Hengqi Chen
+#include <vmlinux/vmlinux.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_endian.h>
+#include <bpf/bpf_tracing.h>
+#include "solisten.h"
+
+/* Define here, because there are conflicts with include files */
+#define AF_INET		2
+#define AF_INET6	10
+#define SOCK_DGRAM	2
+
+const volatile bool filter_by_mnt_ns = false;
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, 10240);
+	__type(key, u32);
+	__type(value, struct args_t);
+} start SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_PERF_EVENT_ARRAY);
+	__uint(key_size, sizeof(u32));
+	__uint(value_size, sizeof(u32));
+} events SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, 1024);
+	__uint(key_size, sizeof(u64));
+	__uint(value_size, sizeof(u32));
+} mount_ns_set SEC(".maps");
+
+static __always_inline int probe_entry(struct socket *sock, int backlog)
+{
+	u32 tid = bpf_get_current_pid_tgid();
+	struct task_struct *task;
+	struct args_t args = {};
+	u64 mntns_id;
+
+	task = (struct task_struct*)bpf_get_current_task();
+	mntns_id = (u64) BPF_CORE_READ(task, nsproxy, mnt_ns, ns.inum);
+
+	if (filter_by_mnt_ns && !bpf_map_lookup_elem(&mount_ns_set, &mntns_id))
+		return 0;
+
+	args.sock = sock;
+	args.backlog = backlog;
+	bpf_map_update_elem(&start, &tid, &args, BPF_ANY);
+	return 0;
+}
+
+/* Only the sockets which start listening are reported: the failed calls
+ * didn't open anything. */
+static __always_inline int probe_exit(struct pt_regs *ctx, __u16 proto)
+{
+	u64 pid_tgid = bpf_get_current_pid_tgid();
+	u32 tid = pid_tgid;
+	struct task_struct *task;
+	struct event event = {};
+	struct args_t *ap;
+	struct sock *sk;
+	__u16 family;
+	__be32 saddr;
+
+	ap = bpf_map_lookup_elem(&start, &tid);
+	if (!ap)
+		return 0;
+
+	if (PT_REGS_RC(ctx) != 0)
+		goto cleanup;
+
+	sk = BPF_CORE_READ(ap->sock, sk);
+
+	event.port = BPF_CORE_READ(sk, __sk_common.skc_num);
+	/* The UDP sockets bound without a port get one when they send their
+	 * first datagram: they are clients. */
+	if (proto == PROTO_UDP && event.port == 0)
+		goto cleanup;
+
+	family = BPF_CORE_READ(sk, __sk_common.skc_family);
+	if (family == AF_INET) {
+		saddr = BPF_CORE_READ(sk, __sk_common.skc_rcv_saddr);
+		event.ver = 4;
+		__builtin_memcpy(&event.addr, &saddr, sizeof(saddr));
+	} else {
+		event.ver = 6;
+		BPF_CORE_READ_INTO(&event.addr, sk, __sk_common.skc_v6_rcv_saddr.in6_u.u6_addr8);
+	}
+
+	if (proto == PROTO_TCP) {
+		event.backlog = ap->backlog;
+		/* 16 bits long before Linux 5.5: let libbpf relocate its
+		 * size. */
+		event.max_backlog = BPF_CORE_READ_BITFIELD_PROBED(sk, sk_max_ack_backlog);
+	}
+
+	task = (struct task_struct*)bpf_get_current_task();
+	event.mntns_id = (u64) BPF_CORE_READ(task, nsproxy, mnt_ns, ns.inum);
+	event.pid = pid_tgid >> 32;
+	event.uid = (u32) bpf_get_current_uid_gid();
+	event.proto = proto;
+	bpf_get_current_comm(&event.comm, sizeof(event.comm));
+
+	bpf_perf_event_output(ctx, &events, BPF_F_CURRENT_CPU, &event, sizeof(event));
+
+cleanup:
+	bpf_map_delete_elem(&start, &tid);
+	return 0;
+}
+
+/* inet_listen() is the listen() of the TCP sockets, IPv4 and IPv6. */
+SEC("kprobe/inet_listen")
+int BPF_KPROBE(ig_inet_listen_e, struct socket *sock, int backlog)
+{
+	return probe_entry(sock, backlog);
+}
+
+SEC("kretprobe/inet_listen")
+int BPF_KRETPROBE(ig_inet_listen_x)
+{
+	return probe_exit(ctx, PROTO_TCP);
+}
+
+/* The UDP sockets don't listen(): they receive datagrams as soon as they
+ * are bound to a port. */
+static __always_inline int bind_entry(struct socket *sock)
+{
+	struct sock *sk;
+
+	if (BPF_CORE_READ(sock, type) != SOCK_DGRAM)
+		return 0;
+	/* sk_protocol was a bitfield before Linux 5.6 */
+	sk = BPF_CORE_READ(sock, sk);
+	if (BPF_CORE_READ_BITFIELD_PROBED(sk, sk_protocol) != PROTO_UDP)
+		return 0;
+
+	return probe_entry(sock, 0);
+}
+
+SEC("kprobe/inet_bind")
+int BPF_KPROBE(ig_inet_bind_e, struct socket *sock)
+{
+	return bind_entry(sock);
+}
+
+SEC("kretprobe/inet_bind")
+int BPF_KRETPROBE(ig_inet_bind_x)
+{
+	return probe_exit(ctx, PROTO_UDP);
+}
+
+SEC("kprobe/inet6_bind")
+int BPF_KPROBE(ig_inet6_bind_e, struct socket *sock)
+{
+	return bind_entry(sock);
+}
+
+SEC("kretprobe/inet6_bind")
+int BPF_KRETPROBE(ig_inet6_bind_x)
+{
+	return probe_exit(ctx, PROTO_UDP);
+}
+
+char LICENSE[] SEC("license") = "GPL";
@@ -0,0 +1,32 @@
+/* SPDX-License-Identifier: (LGPL-2.1 OR BSD-2-Clause) */
+#ifndef __SOLISTEN_H
+#define __SOLISTEN_H
+
+#define TASK_COMM_LEN 16
+
+/* IPPROTO_TCP and IPPROTO_UDP */
+#define PROTO_TCP 6
+#define PROTO_UDP 17
+
+struct args_t {
+	struct socket *sock;
+	int backlog;
+};
+
+/* The address is stored in the first 4 bytes of addr for IPv4 */
+struct event {
+	__u8 addr[16];
+	__u64 mntns_id;
+	__u32 pid;
+	__u32 uid;
+	/* Size of the accept backlog given to listen(), 0 for UDP */
+	int backlog;
+	/* Size of the accept backlog, capped by net.core.somaxconn */
+	__u32 max_backlog;
+	__u16 port;
+	__u16 proto; // PROTO_TCP or PROTO_UDP
+	__u8 ver; // 4 or 6
+	char comm[TASK_COMM_LEN];
+};
+
+#endif /* __SOLISTEN_H */
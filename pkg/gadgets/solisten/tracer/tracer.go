@@ -0,0 +1,216 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+// #include <linux/types.h>
+// #include "./bpf/solisten.h"
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+	"github.com/cilium/ebpf/perf"
+
+	containercollection "github.com/kinvolk/inspektor-gadget/pkg/container-collection"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/solisten/types"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+//go:generate sh -c "GOOS=$(go env GOHOSTOS) GOARCH=$(go env GOHOSTARCH) go run github.com/cilium/ebpf/cmd/bpf2go -target bpfel -cc clang solisten ./bpf/solisten.bpf.c -- -I./bpf/ -I../../.. -target bpf -D__TARGET_ARCH_x86"
+
+type Config struct {
+	// TODO: Make it a *ebpf.Map once
+	// https://github.com/cilium/ebpf/issues/515 and
+	// https://github.com/cilium/ebpf/issues/517 are fixed
+	MountnsMap string
+}
+
+var protocolNames = map[uint16]string{
+	C.PROTO_TCP: "TCP",
+	C.PROTO_UDP: "UDP",
+}
+
+type Tracer struct {
+	config        *Config
+	resolver      containercollection.ContainerResolver
+	eventCallback func(types.Event)
+	node          string
+
+	objs   solistenObjects
+	links  []link.Link
+	reader *perf.Reader
+}
+
+func NewTracer(config *Config, resolver containercollection.ContainerResolver,
+	eventCallback func(types.Event), node string) (*Tracer, error) {
+	t := &Tracer{
+		config:        config,
+		resolver:      resolver,
+		eventCallback: eventCallback,
+		node:          node,
+	}
+
+	if err := t.start(); err != nil {
+		t.Stop()
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (t *Tracer) Stop() {
+	for i := range t.links {
+		t.links[i] = gadgets.CloseLink(t.links[i])
+	}
+	t.links = nil
+
+	if t.reader != nil {
+		t.reader.Close()
+		t.reader = nil
+	}
+
+	t.objs.Close()
+}
+
+func (t *Tracer) start() error {
+	spec, err := loadSolisten()
+	if err != nil {
+		return fmt.Errorf("failed to load ebpf program: %w", err)
+	}
+
+	filterByMntNs := false
+	opts := ebpf.CollectionOptions{}
+
+	if t.config.MountnsMap != "" {
+		filterByMntNs = true
+		m := spec.Maps["mount_ns_set"]
+		m.Pinning = ebpf.PinByName
+		m.Name = filepath.Base(t.config.MountnsMap)
+		opts.Maps.PinPath = filepath.Dir(t.config.MountnsMap)
+	}
+
+	consts := map[string]interface{}{
+		"filter_by_mnt_ns": filterByMntNs,
+	}
+
+	if err := spec.RewriteConstants(consts); err != nil {
+		return fmt.Errorf("error RewriteConstants: %w", err)
+	}
+
+	if err := spec.LoadAndAssign(&t.objs, &opts); err != nil {
+		return fmt.Errorf("failed to load ebpf program: %w", err)
+	}
+
+	kprobes := []struct {
+		symbol string
+		prog   *ebpf.Program
+		ret    bool
+	}{
+		{"inet_listen", t.objs.IgInetListenE, false},
+		{"inet_listen", t.objs.IgInetListenX, true},
+		{"inet_bind", t.objs.IgInetBindE, false},
+		{"inet_bind", t.objs.IgInetBindX, true},
+		{"inet6_bind", t.objs.IgInet6BindE, false},
+		{"inet6_bind", t.objs.IgInet6BindX, true},
+	}
+
+	for _, kp := range kprobes {
+		var l link.Link
+		var err error
+		if kp.ret {
+			l, err = link.Kretprobe(kp.symbol, kp.prog, nil)
+		} else {
+			l, err = link.Kprobe(kp.symbol, kp.prog, nil)
+		}
+		if err != nil {
+			return fmt.Errorf("error opening kprobe %s: %w", kp.symbol, err)
+		}
+		t.links = append(t.links, l)
+	}
+
+	reader, err := perf.NewReader(t.objs.solistenMaps.Events, gadgets.PerfBufferPages*os.Getpagesize())
+	if err != nil {
+		return fmt.Errorf("error creating perf ring buffer: %w", err)
+	}
+	t.reader = reader
+
+	go t.run()
+
+	return nil
+}
+
+func (t *Tracer) run() {
+	for {
+		record, err := t.reader.Read()
+		if err != nil {
+			if errors.Is(err, perf.ErrClosed) {
+				// nothing to do, we're done
+				return
+			}
+
+			msg := fmt.Sprintf("Error reading perf ring buffer: %s", err)
+			t.eventCallback(types.Base(eventtypes.Err(msg, t.node)))
+			return
+		}
+
+		if record.LostSamples > 0 {
+			msg := fmt.Sprintf("lost %d samples", record.LostSamples)
+			t.eventCallback(types.Base(eventtypes.Warn(msg, t.node)))
+			continue
+		}
+
+		eventC := (*C.struct_event)(unsafe.Pointer(&record.RawSample[0]))
+
+		event := types.Event{
+			Event: eventtypes.Event{
+				Type: eventtypes.NORMAL,
+				Node: t.node,
+			},
+			MountNsID:  uint64(eventC.mntns_id),
+			Pid:        uint32(eventC.pid),
+			UID:        uint32(eventC.uid),
+			Comm:       C.GoString(&eventC.comm[0]),
+			Protocol:   protocolNames[uint16(eventC.proto)],
+			IPVersion:  int(eventC.ver),
+			Port:       uint16(eventC.port),
+			Backlog:    int(eventC.backlog),
+			MaxBacklog: uint32(eventC.max_backlog),
+		}
+
+		addr := C.GoBytes(unsafe.Pointer(&eventC.addr[0]), 16)
+		if event.IPVersion == 4 {
+			event.Addr = net.IP(addr[:4]).String()
+		} else {
+			event.Addr = net.IP(addr).String()
+		}
+
+		container := t.resolver.LookupContainerByMntns(event.MountNsID)
+		if container != nil {
+			event.Container = container.Name
+			event.Pod = container.Podname
+			event.Namespace = container.Namespace
+		}
+
+		t.eventCallback(event)
+	}
+}
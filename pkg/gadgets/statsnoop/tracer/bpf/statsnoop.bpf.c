@@ -0,0 +1,135 @@
+// SPDX-License-Identifier: GPL-2.0
+// Based on statsnoop(8) from libbpf-tools, Copyright (c) 2021 Hengqi Chen
+#include <vmlinux/vmlinux.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+#include "statsnoop.h"
+
+const volatile bool targ_failed = false;
+const volatile bool filter_by_mnt_ns = false;
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, 10240);
+	__type(key, u32);
+	__type(value, struct args_t);
+} start SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_PERF_EVENT_ARRAY);
+	__uint(key_size, sizeof(u32));
+	__uint(value_size, sizeof(u32));
+} events SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, 1024);
+	__uint(key_size, sizeof(u64));
+	__uint(value_size, sizeof(u32));
+} mount_ns_set SEC(".maps");
+
+static __always_inline int
+trace_enter(const char *pathname, int syscall)
+{
+	u32 pid = bpf_get_current_pid_tgid();
+	struct task_struct *task;
+	struct args_t args = {};
+	u64 mntns_id;
+
+	task = (struct task_struct*)bpf_get_current_task();
+	mntns_id = (u64) BPF_CORE_READ(task, nsproxy, mnt_ns, ns.inum);
+
+	if (filter_by_mnt_ns && !bpf_map_lookup_elem(&mount_ns_set, &mntns_id))
+		return 0;
+
+	args.pathname = pathname;
+	args.syscall = syscall;
+	bpf_map_update_elem(&start, &pid, &args, BPF_ANY);
+	return 0;
+}
+
+static __always_inline int
+trace_exit(struct trace_event_raw_sys_exit *ctx)
+{
+	u64 pid_tgid = bpf_get_current_pid_tgid();
+	u32 pid = pid_tgid;
+	struct task_struct *task;
+	struct event event = {};
+	struct args_t *ap;
+	int ret;
+
+	ap = bpf_map_lookup_elem(&start, &pid);
+	if (!ap)
+		return 0;	/* missed entry */
+
+	ret = ctx->ret;
+	if (targ_failed && ret >= 0)
+		goto cleanup;	/* want failed only */
+
+	task = (struct task_struct*)bpf_get_current_task();
+
+	event.pid = pid_tgid >> 32;
+	event.uid = bpf_get_current_uid_gid();
+	event.mntns_id = (u64) BPF_CORE_READ(task, nsproxy, mnt_ns, ns.inum);
+	event.ret = ret;
+	event.syscall = ap->syscall;
+	bpf_get_current_comm(&event.comm, sizeof(event.comm));
+	bpf_probe_read_user_str(&event.pathname, sizeof(event.pathname), ap->pathname);
+
+	bpf_perf_event_output(ctx, &events, BPF_F_CURRENT_CPU,
+			      &event, sizeof(event));
+
+cleanup:
+	bpf_map_delete_elem(&start, &pid);
+	return 0;
+}
+
+SEC("tracepoint/syscalls/sys_enter_newstat")
+int ig_stat_e(struct trace_event_raw_sys_enter *ctx)
+{
+	return trace_enter((const char *)ctx->args[0], SYSCALL_STAT);
+}
+
+SEC("tracepoint/syscalls/sys_enter_newlstat")
+int ig_lstat_e(struct trace_event_raw_sys_enter *ctx)
+{
+	return trace_enter((const char *)ctx->args[0], SYSCALL_LSTAT);
+}
+
+SEC("tracepoint/syscalls/sys_enter_newfstatat")
+int ig_fstatat_e(struct trace_event_raw_sys_enter *ctx)
+{
+	return trace_enter((const char *)ctx->args[1], SYSCALL_FSTATAT);
+}
+
+SEC("tracepoint/syscalls/sys_enter_statx")
+int ig_statx_e(struct trace_event_raw_sys_enter *ctx)
+{
+	return trace_enter((const char *)ctx->args[1], SYSCALL_STATX);
+}
+
+SEC("tracepoint/syscalls/sys_exit_newstat")
+int ig_stat_x(struct trace_event_raw_sys_exit *ctx)
+{
+	return trace_exit(ctx);
+}
+
+SEC("tracepoint/syscalls/sys_exit_newlstat")
+int ig_lstat_x(struct trace_event_raw_sys_exit *ctx)
+{
+	return trace_exit(ctx);
+}
+
+SEC("tracepoint/syscalls/sys_exit_newfstatat")
+int ig_fstatat_x(struct trace_event_raw_sys_exit *ctx)
+{
+	return trace_exit(ctx);
+}
+
+SEC("tracepoint/syscalls/sys_exit_statx")
+int ig_statx_x(struct trace_event_raw_sys_exit *ctx)
+{
+	return trace_exit(ctx);
+}
+
+char LICENSE[] SEC("license") = "GPL";
@@ -0,0 +1,30 @@
+/* SPDX-License-Identifier: (LGPL-2.1 OR BSD-2-Clause) */
+#ifndef __STATSNOOP_H
+#define __STATSNOOP_H
+
+#define TASK_COMM_LEN 16
+#define NAME_MAX 255
+
+enum syscall {
+	SYSCALL_STAT,
+	SYSCALL_LSTAT,
+	SYSCALL_FSTATAT,
+	SYSCALL_STATX,
+};
+
+struct args_t {
+	const char *pathname;
+	int syscall;
+};
+
+struct event {
+	__u32 pid;
+	__u32 uid;
+	__u64 mntns_id;
+	int ret;
+	int syscall;
+	char comm[TASK_COMM_LEN];
+	char pathname[NAME_MAX];
+};
+
+#endif /* __STATSNOOP_H */
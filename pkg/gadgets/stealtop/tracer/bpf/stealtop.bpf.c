@@ -0,0 +1,81 @@
+// SPDX-License-Identifier: GPL-2.0
+// Copyright (c) 2022 The Inspektor Gadget authors
+#include <vmlinux/vmlinux.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_tracing.h>
+#include "stealtop.h"
+
+#define MAX_ENTRIES	10240
+
+const volatile bool filter_by_mnt_ns = false;
+static __u64 zero_value = 0;
+
+/* Time in nanoseconds spent on each CPU, by mount namespace */
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, struct runtime_key);
+	__type(value, __u64);
+} runtimes SEC(".maps");
+
+/* When the task running on the CPU was switched in */
+struct {
+	__uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+	__uint(max_entries, 1);
+	__type(key, __u32);
+	__type(value, __u64);
+} oncpu_since SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, 1024);
+	__uint(key_size, sizeof(u64));
+	__uint(value_size, sizeof(u32));
+} mount_ns_set SEC(".maps");
+
+SEC("tracepoint/sched/sched_switch")
+int ig_sched_switch(struct trace_event_raw_sched_switch *ctx)
+{
+	struct task_struct *task = (struct task_struct*)bpf_get_current_task();
+	struct runtime_key key = {};
+	__u64 now = bpf_ktime_get_ns();
+	__u64 *sincep, *valuep;
+	__u32 zero = 0;
+
+	sincep = bpf_map_lookup_elem(&oncpu_since, &zero);
+	if (!sincep)
+		return 0;
+
+	/* The idle task isn't accounted */
+	if (*sincep == 0 || ctx->prev_pid == 0)
+		goto out;
+
+	/*
+	 * The task being switched out is still the current one. Its mount
+	 * namespace is 0 if it exited, and it isn't accounted either.
+	 */
+	key.mntns_id = (u64) BPF_CORE_READ(task, nsproxy, mnt_ns, ns.inum);
+	if (key.mntns_id == 0)
+		goto out;
+
+	if (filter_by_mnt_ns && !bpf_map_lookup_elem(&mount_ns_set, &key.mntns_id))
+		goto out;
+
+	key.cpu = bpf_get_smp_processor_id();
+
+	valuep = bpf_map_lookup_elem(&runtimes, &key);
+	if (!valuep) {
+		bpf_map_update_elem(&runtimes, &key, &zero_value, BPF_NOEXIST);
+		valuep = bpf_map_lookup_elem(&runtimes, &key);
+		if (!valuep)
+			goto out;
+	}
+	__sync_fetch_and_add(valuep, now - *sincep);
+
+out:
+	*sincep = now;
+	return 0;
+}
+
+char LICENSE[] SEC("license") = "GPL";
@@ -0,0 +1,12 @@
+/* SPDX-License-Identifier: (LGPL-2.1 OR BSD-2-Clause) */
+#ifndef __STEALTOP_H
+#define __STEALTOP_H
+
+/* The time spent on each CPU is aggregated by mount namespace. */
+struct runtime_key {
+	__u64 mntns_id;
+	__u32 cpu;
+	__u32 pad;
+};
+
+#endif /* __STEALTOP_H */
@@ -0,0 +1,271 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+	"unsafe"
+
+	containercollection "github.com/kinvolk/inspektor-gadget/pkg/container-collection"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/stealtop/types"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/threshold"
+	"github.com/kinvolk/inspektor-gadget/pkg/mapdump"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+)
+
+// #include <linux/types.h>
+// #include "./bpf/stealtop.h"
+import "C"
+
+//go:generate sh -c "GOOS=$(go env GOHOSTOS) GOARCH=$(go env GOHOSTARCH) go run github.com/cilium/ebpf/cmd/bpf2go -target bpfel -cc clang stealtop ./bpf/stealtop.bpf.c -- -I./bpf/ -I../../.. -target bpf -D__TARGET_ARCH_x86"
+
+// procStat gives the time stolen from each CPU. It isn't namespaced, so it
+// gives the ones of the node also in the gadget pod.
+const procStat = "/proc/stat"
+
+type Config struct {
+	MaxRows  int
+	Interval time.Duration
+	SortBy   types.SortBy
+	// TODO: Make it a *ebpf.Map once
+	// https://github.com/cilium/ebpf/issues/515 and
+	// https://github.com/cilium/ebpf/issues/517 are fixed
+	MountnsMap string
+	Node       string
+
+	// Thresholds marks the rows crossing thresholds. These rows are
+	// reported even if they are not part of the first MaxRows ones.
+	Thresholds *threshold.Config
+}
+
+type Tracer struct {
+	config        *Config
+	objs          stealtopObjects
+	switchLink    link.Link
+	resolver      containercollection.ContainerResolver
+	statsCallback func([]types.Stats)
+	errorCallback func(error)
+	done          chan bool
+
+	// prevSteals and prevTime are the steal times of the CPUs and when
+	// they were read at the beginning of the interval.
+	prevSteals map[int]time.Duration
+	prevTime   time.Time
+}
+
+func NewTracer(config *Config, resolver containercollection.ContainerResolver,
+	statsCallback func([]types.Stats), errorCallback func(error)) (*Tracer, error) {
+	t := &Tracer{
+		config:        config,
+		resolver:      resolver,
+		statsCallback: statsCallback,
+		errorCallback: errorCallback,
+		done:          make(chan bool),
+	}
+
+	if err := t.start(); err != nil {
+		t.Stop()
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (t *Tracer) Stop() {
+	close(t.done)
+
+	t.switchLink = gadgets.CloseLink(t.switchLink)
+
+	t.objs.Close()
+}
+
+// Maps returns the BPF maps of the tracer, so they can be dumped for
+// debugging.
+func (t *Tracer) Maps() map[string]*ebpf.Map {
+	return mapdump.MapsOf(&t.objs)
+}
+
+func readCPUSteal() (map[int]time.Duration, error) {
+	f, err := os.Open(procStat)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return types.ParseCPUSteal(f)
+}
+
+func (t *Tracer) start() error {
+	spec, err := loadStealtop()
+	if err != nil {
+		return fmt.Errorf("failed to load ebpf program: %w", err)
+	}
+
+	filterByMntNs := false
+
+	if t.config.MountnsMap != "" {
+		filterByMntNs = true
+		m := spec.Maps["mount_ns_set"]
+		m.Pinning = ebpf.PinByName
+		m.Name = filepath.Base(t.config.MountnsMap)
+	}
+
+	consts := map[string]interface{}{
+		"filter_by_mnt_ns": filterByMntNs,
+	}
+
+	if err := spec.RewriteConstants(consts); err != nil {
+		return fmt.Errorf("error RewriteConstants: %w", err)
+	}
+
+	opts := ebpf.CollectionOptions{
+		Maps: ebpf.MapOptions{
+			PinPath: filepath.Dir(t.config.MountnsMap),
+		},
+	}
+
+	if err := spec.LoadAndAssign(&t.objs, &opts); err != nil {
+		return fmt.Errorf("failed to load ebpf program: %w", err)
+	}
+
+	t.prevSteals, err = readCPUSteal()
+	if err != nil {
+		return fmt.Errorf("failed to read the steal time: %w", err)
+	}
+	t.prevTime = time.Now()
+
+	t.switchLink, err = link.Tracepoint("sched", "sched_switch", t.objs.IgSchedSwitch, nil)
+	if err != nil {
+		return fmt.Errorf("error opening tracepoint: %w", err)
+	}
+
+	t.run()
+
+	return nil
+}
+
+// nextRuntimes returns the time spent on each CPU by each mount namespace
+// since the previous call.
+func (t *Tracer) nextRuntimes() (map[uint64]map[int]time.Duration, error) {
+	runtimes := make(map[uint64]map[int]time.Duration)
+	keys := []C.struct_runtime_key{}
+	key := C.struct_runtime_key{}
+	entries := t.objs.Runtimes
+
+	err := entries.NextKey(nil, unsafe.Pointer(&key))
+	for err == nil {
+		keys = append(keys, key)
+		prev := key
+		err = entries.NextKey(unsafe.Pointer(&prev), unsafe.Pointer(&key))
+	}
+	if !errors.Is(err, ebpf.ErrKeyNotExist) {
+		return nil, fmt.Errorf("error getting next key: %w", err)
+	}
+
+	for i := range keys {
+		var ns uint64
+		if err := entries.Lookup(unsafe.Pointer(&keys[i]), unsafe.Pointer(&ns)); err != nil {
+			return nil, err
+		}
+		if err := entries.Delete(unsafe.Pointer(&keys[i])); err != nil {
+			return nil, err
+		}
+
+		mntnsID := uint64(keys[i].mntns_id)
+		if runtimes[mntnsID] == nil {
+			runtimes[mntnsID] = make(map[int]time.Duration)
+		}
+		runtimes[mntnsID][int(keys[i].cpu)] += time.Duration(ns)
+	}
+
+	return runtimes, nil
+}
+
+func (t *Tracer) nextStats() ([]types.Stats, error) {
+	stats := []types.Stats{}
+
+	runtimes, err := t.nextRuntimes()
+	if err != nil {
+		return nil, err
+	}
+
+	steals, err := readCPUSteal()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the steal time: %w", err)
+	}
+	now := time.Now()
+
+	fractions, nodeSteal := types.StolenFractions(t.prevSteals, steals, now.Sub(t.prevTime))
+	t.prevSteals = steals
+	t.prevTime = now
+
+	for mntnsID, cpus := range runtimes {
+		stat := types.Stats{
+			MountNsID: mntnsID,
+			Node:      t.config.Node,
+			NodeSteal: nodeSteal,
+		}
+		stat.SetSteal(cpus, fractions)
+
+		container := t.resolver.LookupContainerByMntns(stat.MountNsID)
+		if container != nil {
+			stat.Container = container.Name
+			stat.Pod = container.Podname
+			stat.Namespace = container.Namespace
+		}
+
+		stats = append(stats, stat)
+	}
+
+	types.SortStats(stats, t.config.SortBy)
+
+	return stats, nil
+}
+
+func (t *Tracer) run() {
+	ticker := time.NewTicker(t.config.Interval)
+
+	go func() {
+		for {
+			select {
+			case <-t.done:
+				ticker.Stop()
+				return
+			case <-ticker.C:
+				stats, err := t.nextStats()
+				if err != nil {
+					t.errorCallback(err)
+					return
+				}
+
+				rows := []types.Stats{}
+				for i := range stats {
+					stats[i].Alerts = t.config.Thresholds.Check(&stats[i], t.config.Interval)
+					if i < t.config.MaxRows || len(stats[i].Alerts) > 0 {
+						rows = append(rows, stats[i])
+					}
+				}
+				t.statsCallback(rows)
+			}
+		}
+	}()
+}
@@ -0,0 +1,230 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type SortBy int
+
+const (
+	STEAL SortBy = iota
+	STEALPCT
+	CPUTIME
+)
+
+const (
+	MaxRowsDefault  = 20
+	IntervalDefault = 1
+	SortByDefault   = STEAL
+)
+
+const (
+	IntervalParam = "interval"
+	MaxRowsParam  = "max_rows"
+	SortByParam   = "sort_by"
+)
+
+var SortBySlice = []string{
+	"steal",
+	"stealpct",
+	"cputime",
+}
+
+func (s SortBy) String() string {
+	if int(s) < 0 || int(s) >= len(SortBySlice) {
+		return "INVALID"
+	}
+
+	return SortBySlice[int(s)]
+}
+
+func ParseSortBy(sortby string) (SortBy, error) {
+	for i, v := range SortBySlice {
+		if v == sortby {
+			return SortBy(i), nil
+		}
+	}
+	return STEAL, fmt.Errorf("%q is not a valid sort by value", sortby)
+}
+
+// Event is the information the gadget sends to the client each capture
+// interval
+type Event struct {
+	Error string `json:"error,omitempty"`
+
+	// Warning is set when rows crossed the thresholds during the interval
+	// and the warnings are enabled.
+	Warning string `json:"warning,omitempty"`
+
+	// Node where the event comes from.
+	Node string `json:"node,omitempty"`
+
+	// Timestamp is when the interval ended, in nanoseconds since the
+	// epoch.
+	Timestamp int64 `json:"timestamp,omitempty"`
+
+	Stats []Stats `json:"stats,omitempty"`
+}
+
+// Stats represents the CPU time of a single container, i.e. a mount
+// namespace, and the part of it stolen by the hypervisor
+type Stats struct {
+	Node      string `json:"node,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Pod       string `json:"pod,omitempty"`
+	Container string `json:"container,omitempty"`
+
+	MountNsID uint64 `json:"mountnsid,omitempty"`
+
+	// CPUTime is the time in microseconds the container was running on
+	// the CPUs of the node, including the time stolen.
+	CPUTime uint64 `json:"cputime"`
+
+	// Steal is the estimation of the time in microseconds the container
+	// was waiting for the hypervisor to run the CPUs it was running on.
+	Steal uint64 `json:"steal"`
+
+	// StealPercent is the percentage of CPUTime that was stolen.
+	StealPercent float64 `json:"stealpct"`
+
+	// NodeSteal is the percentage of the time of all the CPUs of the
+	// node that was stolen during the interval.
+	NodeSteal float64 `json:"nodesteal"`
+
+	// Alerts are the thresholds crossed by the row during the interval.
+	Alerts []string `json:"alerts,omitempty"`
+}
+
+// SetSteal computes the time of the container stolen by the hypervisor from
+// the time it spent on each CPU and the fraction of the time of each CPU
+// that was stolen during the interval. The hypervisor doesn't tell which
+// task was running when it stole the CPU, so it's assumed that the time
+// stolen is spread over the tasks which ran on the CPU.
+func (s *Stats) SetSteal(runtimes map[int]time.Duration, stolenFractions map[int]float64) {
+	var cpuTime, steal time.Duration
+
+	for cpu, runtime := range runtimes {
+		cpuTime += runtime
+		steal += time.Duration(float64(runtime) * stolenFractions[cpu])
+	}
+
+	s.CPUTime = uint64(cpuTime.Microseconds())
+	s.Steal = uint64(steal.Microseconds())
+
+	s.StealPercent = 0
+	if cpuTime > 0 {
+		s.StealPercent = float64(steal) / float64(cpuTime) * 100
+	}
+}
+
+// UserHZ is the unit of the times of /proc/stat, in ticks per second. It's
+// 100 on all the architectures supported by Kubernetes.
+const UserHZ = 100
+
+// ParseCPUSteal returns the time stolen from each CPU since the boot, read
+// from r which has the format of /proc/stat.
+func ParseCPUSteal(r io.Reader) (map[int]time.Duration, error) {
+	steals := make(map[int]time.Duration)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		// cpu0 user nice system idle iowait irq softirq steal ...
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || fields[0] == "cpu" || !strings.HasPrefix(fields[0], "cpu") {
+			continue
+		}
+
+		cpu, err := strconv.Atoi(strings.TrimPrefix(fields[0], "cpu"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid CPU %q", fields[0])
+		}
+
+		// The steal time was added in 2.6.11
+		if len(fields) < 9 {
+			steals[cpu] = 0
+			continue
+		}
+
+		ticks, err := strconv.ParseUint(fields[8], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid steal time %q of %s", fields[8], fields[0])
+		}
+		steals[cpu] = time.Duration(ticks) * time.Second / UserHZ
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return steals, nil
+}
+
+// StolenFractions returns the fraction of the time of each CPU that was
+// stolen during interval, given the steal times read at its start and its
+// end, and the percentage of the time of all the CPUs that was stolen.
+func StolenFractions(before, after map[int]time.Duration, interval time.Duration) (map[int]float64, float64) {
+	fractions := make(map[int]float64)
+	if interval <= 0 {
+		return fractions, 0
+	}
+
+	var total float64
+	for cpu, steal := range after {
+		delta := steal - before[cpu]
+		if delta < 0 {
+			// The CPU was offline and put back online.
+			delta = 0
+		}
+
+		fraction := float64(delta) / float64(interval)
+		if fraction > 1 {
+			// The ticks are accounted with some delay.
+			fraction = 1
+		}
+
+		fractions[cpu] = fraction
+		total += fraction
+	}
+
+	if len(fractions) == 0 {
+		return fractions, 0
+	}
+
+	return fractions, total / float64(len(fractions)) * 100
+}
+
+func SortStats(stats []Stats, sortBy SortBy) {
+	sort.Slice(stats, func(i, j int) bool {
+		a := stats[i]
+		b := stats[j]
+
+		switch sortBy {
+		case STEALPCT:
+			return a.StealPercent > b.StealPercent
+		case CPUTIME:
+			return a.CPUTime > b.CPUTime
+		default:
+			return a.Steal > b.Steal
+		}
+	})
+}
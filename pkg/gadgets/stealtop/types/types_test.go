@@ -0,0 +1,79 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const procStat = `cpu  3200 10 1500 90000 100 0 50 300 0 0
+cpu0 1600 5 750 45000 50 0 25 100 0 0
+cpu1 1600 5 750 45000 50 0 25 200 0 0
+intr 123456 0 0
+ctxt 987654
+btime 1660000000
+`
+
+func TestParseCPUSteal(t *testing.T) {
+	steals, err := ParseCPUSteal(strings.NewReader(procStat))
+	if err != nil {
+		t.Fatalf("Failed to parse: %s", err)
+	}
+
+	expected := map[int]time.Duration{
+		0: time.Second,
+		1: 2 * time.Second,
+	}
+	if len(steals) != len(expected) {
+		t.Fatalf("Got %d CPUs, expected %d", len(steals), len(expected))
+	}
+	for cpu, steal := range expected {
+		if steals[cpu] != steal {
+			t.Fatalf("Got a steal time of %v for CPU %d, expected %v", steals[cpu], cpu, steal)
+		}
+	}
+
+	if _, err := ParseCPUSteal(strings.NewReader("cpux 1 2 3 4 5 6 7 8\n")); err == nil {
+		t.Fatalf("Invalid CPU accepted")
+	}
+}
+
+func TestSetSteal(t *testing.T) {
+	before := map[int]time.Duration{0: time.Second, 1: 2 * time.Second}
+	after := map[int]time.Duration{0: time.Second + 500*time.Millisecond, 1: 2 * time.Second}
+
+	fractions, nodeSteal := StolenFractions(before, after, time.Second)
+	if fractions[0] != 0.5 || fractions[1] != 0 {
+		t.Fatalf("Got fractions %v, expected 0.5 and 0", fractions)
+	}
+	if nodeSteal != 25 {
+		t.Fatalf("Got a node steal of %f, expected 25", nodeSteal)
+	}
+
+	var s Stats
+	s.SetSteal(map[int]time.Duration{
+		0: 200 * time.Millisecond,
+		1: 600 * time.Millisecond,
+	}, fractions)
+
+	if s.CPUTime != 800000 || s.Steal != 100000 {
+		t.Fatalf("Got a CPU time of %dus and a steal of %dus, expected 800000us and 100000us", s.CPUTime, s.Steal)
+	}
+	if s.StealPercent != 12.5 {
+		t.Fatalf("Got a steal of %f%%, expected 12.5%%", s.StealPercent)
+	}
+}
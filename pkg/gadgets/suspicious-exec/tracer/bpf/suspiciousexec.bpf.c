@@ -0,0 +1,91 @@
+// SPDX-License-Identifier: GPL-2.0
+// Copyright (c) 2022 The Inspektor Gadget authors
+#include <vmlinux/vmlinux.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+#include "suspiciousexec.h"
+
+#define S_IWOTH 00002
+
+#define TMPFS_MAGIC 0x01021994
+#define RAMFS_MAGIC 0x858458f6
+
+const volatile bool filter_by_mnt_ns = false;
+
+struct {
+	__uint(type, BPF_MAP_TYPE_PERF_EVENT_ARRAY);
+	__uint(key_size, sizeof(u32));
+	__uint(value_size, sizeof(u32));
+} events SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, 1024);
+	__uint(key_size, sizeof(u64));
+	__uint(value_size, sizeof(u32));
+} mount_ns_set SEC(".maps");
+
+/* sched_process_exec is hit once the new program is loaded: the file
+ * executed is the one of the new mm of the task. */
+SEC("tracepoint/sched/sched_process_exec")
+int ig_sched_exec(struct trace_event_raw_sched_process_exec *ctx)
+{
+	struct event event = {};
+	struct task_struct *task;
+	struct dentry *dentry;
+	struct inode *inode;
+	struct file *file;
+	unsigned int fname_off;
+	umode_t dir_mode;
+	u64 mntns_id;
+	u32 reasons = 0;
+	unsigned long magic;
+
+	task = (struct task_struct *) bpf_get_current_task();
+	mntns_id = (u64) BPF_CORE_READ(task, nsproxy, mnt_ns, ns.inum);
+
+	if (filter_by_mnt_ns && !bpf_map_lookup_elem(&mount_ns_set, &mntns_id))
+		return 0;
+
+	file = BPF_CORE_READ(task, mm, exe_file);
+	if (!file)
+		return 0;
+
+	inode = BPF_CORE_READ(file, f_inode);
+	dentry = BPF_CORE_READ(file, f_path.dentry);
+	dir_mode = BPF_CORE_READ(dentry, d_parent, d_inode, i_mode);
+
+	event.mode = BPF_CORE_READ(inode, i_mode);
+	magic = BPF_CORE_READ(inode, i_sb, s_magic);
+
+	if (dir_mode & S_IWOTH)
+		reasons |= REASON_WORLD_WRITABLE_DIR;
+	if (event.mode & S_IWOTH)
+		reasons |= REASON_WORLD_WRITABLE_FILE;
+	if (magic == TMPFS_MAGIC || magic == RAMFS_MAGIC)
+		reasons |= REASON_TMPFS;
+	/* Files removed after being opened and memfd files don't have any
+	 * link left. */
+	if (BPF_CORE_READ(inode, __i_nlink) == 0)
+		reasons |= REASON_DELETED;
+
+	if (!reasons)
+		return 0;
+
+	event.mntns_id = mntns_id;
+	event.reasons = reasons;
+	event.pid = bpf_get_current_pid_tgid() >> 32;
+	event.ppid = (u32) BPF_CORE_READ(task, real_parent, tgid);
+	event.uid = (u32) bpf_get_current_uid_gid();
+	bpf_get_current_comm(&event.comm, sizeof(event.comm));
+
+	fname_off = ctx->__data_loc_filename & 0xFFFF;
+	bpf_probe_read_str(&event.filename, sizeof(event.filename),
+			   (void *) ctx + fname_off);
+
+	bpf_perf_event_output(ctx, &events, BPF_F_CURRENT_CPU,
+			      &event, sizeof(event));
+	return 0;
+}
+
+char LICENSE[] SEC("license") = "GPL";
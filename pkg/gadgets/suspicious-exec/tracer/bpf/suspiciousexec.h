@@ -0,0 +1,25 @@
+/* SPDX-License-Identifier: (LGPL-2.1 OR BSD-2-Clause) */
+#ifndef __SUSPICIOUSEXEC_H
+#define __SUSPICIOUSEXEC_H
+
+#define TASK_COMM_LEN 16
+#define FILENAME_LEN 256
+
+/* Reasons making an execution suspicious, combined in event.reasons. */
+#define REASON_WORLD_WRITABLE_DIR	(1 << 0)
+#define REASON_WORLD_WRITABLE_FILE	(1 << 1)
+#define REASON_TMPFS			(1 << 2)
+#define REASON_DELETED			(1 << 3)
+
+struct event {
+	__u64 mntns_id;
+	__u32 pid;
+	__u32 ppid;
+	__u32 uid;
+	__u32 reasons;
+	__u32 mode;
+	char comm[TASK_COMM_LEN];
+	char filename[FILENAME_LEN];
+};
+
+#endif /* __SUSPICIOUSEXEC_H */
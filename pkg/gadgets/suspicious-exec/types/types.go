@@ -0,0 +1,70 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+// Reasons making an execution suspicious.
+const (
+	// ReasonWorldWritableDir means the executable is in a directory
+	// anyone can write to, like /tmp.
+	ReasonWorldWritableDir = "world-writable-dir"
+	// ReasonWorldWritableFile means anyone can modify the executable.
+	ReasonWorldWritableFile = "world-writable-file"
+	// ReasonTmpfs means the executable is on a tmpfs or ramfs, like
+	// /dev/shm, and doesn't come from the image of the container.
+	ReasonTmpfs = "tmpfs"
+	// ReasonDeleted means the executable was removed, or is a memfd,
+	// when it was executed.
+	ReasonDeleted = "deleted"
+)
+
+// MaxHashSize is the size, in bytes, above which the executables aren't
+// hashed.
+const MaxHashSize = 16 * 1024 * 1024
+
+type Event struct {
+	eventtypes.Event
+
+	MountNsID uint64 `json:"mountnsid,omitempty"`
+	Pid       uint32 `json:"pid,omitempty"`
+	Ppid      uint32 `json:"ppid,omitempty"`
+	UID       uint32 `json:"uid,omitempty"`
+	Comm      string `json:"pcomm,omitempty"`
+
+	// Path is the path of the executable given to execve(), as seen by
+	// the container.
+	Path string `json:"path,omitempty"`
+
+	// Reasons lists why the execution is suspicious, see the Reason*
+	// constants.
+	Reasons []string `json:"reasons,omitempty"`
+
+	// Mode is the mode of the executable, with its permissions.
+	Mode uint32 `json:"mode,omitempty"`
+
+	// Sha256 is the hash of the executable. It's empty when hashing is
+	// disabled, when the executable is bigger than MaxHashSize or when
+	// the process exited before it could be read.
+	Sha256 string `json:"sha256,omitempty"`
+}
+
+func Base(ev eventtypes.Event) Event {
+	return Event{
+		Event: ev,
+	}
+}
@@ -0,0 +1,162 @@
+// SPDX-License-Identifier: GPL-2.0
+#include <vmlinux/vmlinux.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+#include "syncsnoop.h"
+
+const volatile __u64 min_lat_ns = 0;
+const volatile bool filter_by_mnt_ns = false;
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, 10240);
+	__type(key, u32);
+	__type(value, struct start_t);
+} start SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_PERF_EVENT_ARRAY);
+	__uint(key_size, sizeof(u32));
+	__uint(value_size, sizeof(u32));
+} events SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, 1024);
+	__uint(key_size, sizeof(u64));
+	__uint(value_size, sizeof(u32));
+} mount_ns_set SEC(".maps");
+
+static __always_inline int
+trace_enter(int fd, int syscall)
+{
+	u32 tid = bpf_get_current_pid_tgid();
+	struct task_struct *task;
+	struct start_t s = {};
+	u64 mntns_id;
+
+	task = (struct task_struct*)bpf_get_current_task();
+	mntns_id = (u64) BPF_CORE_READ(task, nsproxy, mnt_ns, ns.inum);
+
+	if (filter_by_mnt_ns && !bpf_map_lookup_elem(&mount_ns_set, &mntns_id))
+		return 0;
+
+	s.ts = bpf_ktime_get_ns();
+	s.fd = fd;
+	s.syscall = syscall;
+	bpf_map_update_elem(&start, &tid, &s, BPF_ANY);
+	return 0;
+}
+
+/* read_file_name reads the name of the file opened as fd by the current
+ * task, the last component of its path only.
+ */
+static __always_inline void
+read_file_name(struct task_struct *task, int fd, char *buf, u32 size)
+{
+	struct fdtable *fdt;
+	struct file **fds;
+	struct file *file;
+	const unsigned char *name;
+
+	if (fd < 0)
+		return;
+
+	fdt = BPF_CORE_READ(task, files, fdt);
+	if ((unsigned int)fd >= BPF_CORE_READ(fdt, max_fds))
+		return;
+
+	fds = BPF_CORE_READ(fdt, fd);
+	if (bpf_probe_read_kernel(&file, sizeof(file), &fds[fd]) || !file)
+		return;
+
+	name = BPF_CORE_READ(file, f_path.dentry, d_name.name);
+	bpf_probe_read_kernel_str(buf, size, name);
+}
+
+static __always_inline int
+trace_exit(struct trace_event_raw_sys_exit *ctx)
+{
+	u64 pid_tgid = bpf_get_current_pid_tgid();
+	u32 tid = pid_tgid;
+	struct task_struct *task;
+	struct event event = {};
+	struct start_t *s;
+	u64 delta_ns;
+
+	s = bpf_map_lookup_elem(&start, &tid);
+	if (!s)
+		return 0;	/* missed entry */
+
+	delta_ns = bpf_ktime_get_ns() - s->ts;
+	if (delta_ns < min_lat_ns)
+		goto cleanup;
+
+	task = (struct task_struct*)bpf_get_current_task();
+
+	event.delta_us = delta_ns / 1000;
+	event.mntns_id = (u64) BPF_CORE_READ(task, nsproxy, mnt_ns, ns.inum);
+	event.pid = pid_tgid >> 32;
+	event.fd = s->fd;
+	event.ret = ctx->ret;
+	event.syscall = s->syscall;
+	bpf_get_current_comm(&event.comm, sizeof(event.comm));
+	read_file_name(task, s->fd, event.file, sizeof(event.file));
+
+	bpf_perf_event_output(ctx, &events, BPF_F_CURRENT_CPU,
+			      &event, sizeof(event));
+
+cleanup:
+	bpf_map_delete_elem(&start, &tid);
+	return 0;
+}
+
+SEC("tracepoint/syscalls/sys_enter_sync")
+int ig_sync_e(struct trace_event_raw_sys_enter *ctx)
+{
+	return trace_enter(-1, SYSCALL_SYNC);
+}
+
+SEC("tracepoint/syscalls/sys_enter_syncfs")
+int ig_syncfs_e(struct trace_event_raw_sys_enter *ctx)
+{
+	return trace_enter((int)ctx->args[0], SYSCALL_SYNCFS);
+}
+
+SEC("tracepoint/syscalls/sys_enter_fsync")
+int ig_fsync_e(struct trace_event_raw_sys_enter *ctx)
+{
+	return trace_enter((int)ctx->args[0], SYSCALL_FSYNC);
+}
+
+SEC("tracepoint/syscalls/sys_enter_fdatasync")
+int ig_fdatasync_e(struct trace_event_raw_sys_enter *ctx)
+{
+	return trace_enter((int)ctx->args[0], SYSCALL_FDATASYNC);
+}
+
+SEC("tracepoint/syscalls/sys_exit_sync")
+int ig_sync_x(struct trace_event_raw_sys_exit *ctx)
+{
+	return trace_exit(ctx);
+}
+
+SEC("tracepoint/syscalls/sys_exit_syncfs")
+int ig_syncfs_x(struct trace_event_raw_sys_exit *ctx)
+{
+	return trace_exit(ctx);
+}
+
+SEC("tracepoint/syscalls/sys_exit_fsync")
+int ig_fsync_x(struct trace_event_raw_sys_exit *ctx)
+{
+	return trace_exit(ctx);
+}
+
+SEC("tracepoint/syscalls/sys_exit_fdatasync")
+int ig_fdatasync_x(struct trace_event_raw_sys_exit *ctx)
+{
+	return trace_exit(ctx);
+}
+
+char LICENSE[] SEC("license") = "GPL";
@@ -0,0 +1,32 @@
+/* SPDX-License-Identifier: (LGPL-2.1 OR BSD-2-Clause) */
+#ifndef __SYNCSNOOP_H
+#define __SYNCSNOOP_H
+
+#define TASK_COMM_LEN 16
+#define NAME_MAX 255
+
+enum syscall {
+	SYSCALL_SYNC,
+	SYSCALL_SYNCFS,
+	SYSCALL_FSYNC,
+	SYSCALL_FDATASYNC,
+};
+
+struct start_t {
+	__u64 ts;
+	int fd;
+	int syscall;
+};
+
+struct event {
+	__u64 delta_us;
+	__u64 mntns_id;
+	__u32 pid;
+	int fd;
+	int ret;
+	int syscall;
+	char comm[TASK_COMM_LEN];
+	char file[NAME_MAX];
+};
+
+#endif /* __SYNCSNOOP_H */
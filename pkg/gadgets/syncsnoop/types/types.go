@@ -0,0 +1,51 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+const (
+	MinLatencyDefault = uint(0)
+)
+
+type Event struct {
+	eventtypes.Event
+
+	MountNsID uint64 `json:"mountnsid,omitempty"`
+	Pid       uint32 `json:"pid,omitempty"`
+	Comm      string `json:"pcomm,omitempty"`
+
+	// Syscall is the sync syscall called: "sync", "syncfs", "fsync" or
+	// "fdatasync".
+	Syscall string `json:"syscall,omitempty"`
+
+	// Fd and File are the file descriptor synced and the name of its
+	// file, except for sync() which syncs all the filesystems.
+	Fd   int    `json:"fd,omitempty"`
+	File string `json:"file,omitempty"`
+
+	// Latency is the time spent in the syscall, in µs.
+	Latency uint64 `json:"latency,omitempty"`
+	Ret     int    `json:"ret,omitempty"`
+	Err     int    `json:"err,omitempty"`
+}
+
+func Base(ev eventtypes.Event) Event {
+	return Event{
+		Event: ev,
+	}
+}
@@ -20,10 +20,10 @@ import (
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/kinvolk/inspektor-gadget/pkg/bpferror"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/tcpconnect/tracer"
 
-	coretracer "github.com/kinvolk/inspektor-gadget/pkg/gadgets/tcpconnect/tracer/core"
 	standardtracer "github.com/kinvolk/inspektor-gadget/pkg/gadgets/tcpconnect/tracer/standard"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/tcpconnect/types"
 
@@ -109,19 +109,13 @@ func (t *Trace) Start(trace *gadgetv1alpha1.Trace) {
 	config := &tracer.Config{
 		MountnsMap: gadgets.TracePinPath(trace.ObjectMeta.Namespace, trace.ObjectMeta.Name),
 	}
-	t.tracer, err = coretracer.NewTracer(config, t.resolver, eventCallback, trace.Spec.Node)
+	t.tracer, err = NewTracer(config, t.resolver, eventCallback, trace.Spec.Node)
 	if err != nil {
+		trace.Status.OperationError = fmt.Sprintf("failed to create tracer: %s", bpferror.Describe(err))
+		return
+	}
+	if _, ok := t.tracer.(*standardtracer.Tracer); ok {
 		trace.Status.OperationWarning = fmt.Sprint("failed to create core tracer. Falling back to standard one")
-
-		// fallback to standard tracer
-		log.Infof("Gadget %s: falling back to standard tracer. CO-RE tracer failed: %s",
-			trace.Spec.Gadget, err)
-
-		t.tracer, err = standardtracer.NewTracer(config, t.resolver, eventCallback, trace.Spec.Node)
-		if err != nil {
-			trace.Status.OperationError = fmt.Sprintf("failed to create tracer: %s", err)
-			return
-		}
 	}
 
 	t.started = true
@@ -0,0 +1,159 @@
+// SPDX-License-Identifier: GPL-2.0
+//
+// Based on tcpdrop(8) from BCC by Brendan Gregg
+#include <vmlinux/vmlinux.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_endian.h>
+#include <bpf/bpf_tracing.h>
+
+#include "tcpdrop.h"
+
+/* Define here, because there are conflicts with include files */
+#define AF_INET		2
+#define AF_INET6	10
+#define IPPROTO_TCP	6
+
+const volatile bool filter_by_netns = false;
+
+/* Value of SKB_DROP_REASON_NOT_SPECIFIED in the running kernel, used by
+ * kfree_skb() for the packets freed without a more precise reason. It
+ * isn't stable across kernel versions. */
+const volatile __u32 reason_not_specified = 0;
+
+/* The reason of the drops was added in Linux 5.17 */
+struct trace_event_raw_kfree_skb___reason {
+	int reason;
+} __attribute__((preserve_access_index));
+
+struct {
+	__uint(type, BPF_MAP_TYPE_PERF_EVENT_ARRAY);
+	__uint(key_size, sizeof(u32));
+	__uint(value_size, sizeof(u32));
+} events SEC(".maps");
+
+/* Network namespaces of the traced pods, filled by the userspace */
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, 1024);
+	__uint(key_size, sizeof(u32));
+	__uint(value_size, sizeof(u32));
+} netns_set SEC(".maps");
+
+/* The packets are mostly dropped when received, in softirq context: the
+ * current mount namespace says nothing about the container. Use the
+ * network namespace of the socket instead, or the one of the interface
+ * for the packets not associated to a socket yet. */
+static __always_inline u32 get_netns(const struct sock *sk,
+				     const struct sk_buff *skb)
+{
+	struct net_device *dev;
+
+	if (sk)
+		return BPF_CORE_READ(sk, __sk_common.skc_net.net, ns.inum);
+
+	dev = BPF_CORE_READ(skb, dev);
+	if (dev)
+		return BPF_CORE_READ(dev, nd_net.net, ns.inum);
+
+	return 0;
+}
+
+/* The addresses and ports are taken from the headers of the packet rather
+ * than from the socket, which isn't known for most of the received
+ * packets. */
+static __always_inline int report_drop(void *ctx, const struct sock *sk,
+				       const struct sk_buff *skb, __u32 reason)
+{
+	struct event event = {};
+	struct tcphdr tcph;
+	unsigned char *head;
+	u16 network_header, transport_header;
+	u8 version, protocol;
+	u32 netns;
+
+	netns = get_netns(sk, skb);
+	if (!netns)
+		return 0;
+	if (filter_by_netns && !bpf_map_lookup_elem(&netns_set, &netns))
+		return 0;
+
+	head = BPF_CORE_READ(skb, head);
+	network_header = BPF_CORE_READ(skb, network_header);
+	transport_header = BPF_CORE_READ(skb, transport_header);
+
+	/* The version is in the first 4 bits of both the IPv4 and IPv6
+	 * headers */
+	if (bpf_probe_read_kernel(&version, sizeof(version), head + network_header))
+		return 0;
+	version >>= 4;
+
+	if (version == 4) {
+		struct iphdr iph;
+
+		if (bpf_probe_read_kernel(&iph, sizeof(iph), head + network_header))
+			return 0;
+		protocol = iph.protocol;
+		event.af = AF_INET;
+		__builtin_memcpy(event.saddr, &iph.saddr, sizeof(iph.saddr));
+		__builtin_memcpy(event.daddr, &iph.daddr, sizeof(iph.daddr));
+	} else if (version == 6) {
+		struct ipv6hdr ip6h;
+
+		if (bpf_probe_read_kernel(&ip6h, sizeof(ip6h), head + network_header))
+			return 0;
+		/* Extension headers aren't supported */
+		protocol = ip6h.nexthdr;
+		event.af = AF_INET6;
+		__builtin_memcpy(event.saddr, &ip6h.saddr, sizeof(ip6h.saddr));
+		__builtin_memcpy(event.daddr, &ip6h.daddr, sizeof(ip6h.daddr));
+	} else {
+		return 0;
+	}
+
+	if (protocol != IPPROTO_TCP)
+		return 0;
+
+	if (bpf_probe_read_kernel(&tcph, sizeof(tcph), head + transport_header))
+		return 0;
+
+	event.netns = netns;
+	event.reason = reason;
+	event.sport = bpf_ntohs(tcph.source);
+	event.dport = bpf_ntohs(tcph.dest);
+	if (sk)
+		event.state = BPF_CORE_READ(sk, __sk_common.skc_state);
+
+	bpf_perf_event_output(ctx, &events, BPF_F_CURRENT_CPU, &event, sizeof(event));
+
+	return 0;
+}
+
+/* Before Linux 5.17, tcp_drop() freed the packets with __kfree_skb(),
+ * which doesn't hit the kfree_skb tracepoint. It's only attached on these
+ * kernels. */
+SEC("kprobe/tcp_drop")
+int BPF_KPROBE(ig_tcpdrop, struct sock *sk, struct sk_buff *skb)
+{
+	return report_drop(ctx, sk, skb, 0);
+}
+
+SEC("tracepoint/skb/kfree_skb")
+int ig_tcpdrop_kfree_skb(struct trace_event_raw_kfree_skb *ctx)
+{
+	struct trace_event_raw_kfree_skb___reason *ctx_reason = (void *) ctx;
+	struct sk_buff *skb = ctx->skbaddr;
+	__u32 reason = 0;
+
+	if (bpf_core_field_exists(ctx_reason->reason)) {
+		reason = BPF_CORE_READ(ctx_reason, reason);
+		/* Skip the packets freed without a precise reason: most of
+		 * them aren't drops of the TCP stack. */
+		if (reason == reason_not_specified)
+			return 0;
+	}
+
+	return report_drop(ctx, BPF_CORE_READ(skb, sk), skb, reason);
+}
+
+char LICENSE[] SEC("license") = "GPL";
@@ -0,0 +1,19 @@
+/* SPDX-License-Identifier: (LGPL-2.1 OR BSD-2-Clause) */
+#ifndef __TCPDROP_H
+#define __TCPDROP_H
+
+/* Addresses and ports are the ones of the dropped packet. Addresses are
+ * stored in the first 4 bytes of saddr and daddr for IPv4 */
+struct event {
+	__u8 saddr[16];
+	__u8 daddr[16];
+	__u32 netns;
+	__u32 reason; // 0 on kernels not giving the reason of the drops
+	__u16 af; // AF_INET or AF_INET6
+	__u16 sport;
+	__u16 dport;
+	__u8 state; // 0 when the packet isn't associated to a socket
+	__u8 pad;
+};
+
+#endif /* __TCPDROP_H */
@@ -0,0 +1,295 @@
+//go:build linux
+// +build linux
+
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+// #include <linux/types.h>
+// #include "./bpf/tcpdrop.h"
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"unsafe"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+	"github.com/cilium/ebpf/perf"
+	"golang.org/x/sys/unix"
+
+	"github.com/kinvolk/inspektor-gadget/pkg/dropreasons"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/tcpdrop/types"
+	pb "github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/api"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+//go:generate sh -c "GOOS=$(go env GOHOSTOS) GOARCH=$(go env GOHOSTARCH) go run github.com/cilium/ebpf/cmd/bpf2go -target bpfel -cc clang tcpdrop ./bpf/tcpdrop.bpf.c -- -I./bpf/ -I../../.. -target bpf -D__TARGET_ARCH_x86"
+
+// tcpStates are the names of the TCP states, as in include/net/tcp_states.h
+var tcpStates = map[uint8]string{
+	1:  "ESTABLISHED",
+	2:  "SYN_SENT",
+	3:  "SYN_RECV",
+	4:  "FIN_WAIT1",
+	5:  "FIN_WAIT2",
+	6:  "TIME_WAIT",
+	7:  "CLOSE",
+	8:  "CLOSE_WAIT",
+	9:  "LAST_ACK",
+	10: "LISTEN",
+	11: "CLOSING",
+	12: "NEW_SYN_RECV",
+}
+
+type Config struct {
+	// NetnsHost is the network namespace of the host. The drops of the
+	// pods using the host network can't be told apart from the ones of
+	// the host.
+	NetnsHost uint64
+}
+
+// pod is a traced pod, shared by its containers.
+type pod struct {
+	namespace  string
+	name       string
+	containers map[string]struct{}
+}
+
+type Tracer struct {
+	config        *Config
+	objs          tcpdropObjects
+	kprobeLink    link.Link
+	kfreeSkbLink  link.Link
+	reasons       map[uint32]string
+	reader        *perf.Reader
+	eventCallback func(types.Event)
+	node          string
+
+	mu sync.Mutex
+	// pods by network namespace
+	pods map[uint64]*pod
+}
+
+func NewTracer(c *Config, eventCallback func(types.Event), node string) (*Tracer, error) {
+	t := &Tracer{
+		config:        c,
+		eventCallback: eventCallback,
+		node:          node,
+		pods:          make(map[uint64]*pod),
+	}
+
+	if err := t.start(); err != nil {
+		t.Stop()
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (t *Tracer) Stop() {
+	t.kprobeLink = gadgets.CloseLink(t.kprobeLink)
+	t.kfreeSkbLink = gadgets.CloseLink(t.kfreeSkbLink)
+
+	if t.reader != nil {
+		t.reader.Close()
+		t.reader = nil
+	}
+
+	t.objs.Close()
+}
+
+func (t *Tracer) start() error {
+	var err error
+	t.reasons, err = dropreasons.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read the reasons of the drops: %w", err)
+	}
+
+	spec, err := loadTcpdrop()
+	if err != nil {
+		return fmt.Errorf("failed to load ebpf program: %w", err)
+	}
+
+	consts := map[string]interface{}{
+		"filter_by_netns": true,
+	}
+	for value, name := range t.reasons {
+		if name == "NOT_SPECIFIED" {
+			consts["reason_not_specified"] = value
+		}
+	}
+
+	if err := spec.RewriteConstants(consts); err != nil {
+		return fmt.Errorf("error RewriteConstants: %w", err)
+	}
+
+	if err := spec.LoadAndAssign(&t.objs, nil); err != nil {
+		return fmt.Errorf("failed to load ebpf program: %w", err)
+	}
+
+	// Before Linux 5.17, the kernel doesn't give the reason of the drops
+	// and tcp_drop() doesn't hit the kfree_skb tracepoint. Afterwards,
+	// tcp_drop() hits the tracepoint and would be reported twice.
+	if len(t.reasons) == 0 {
+		t.kprobeLink, err = link.Kprobe("tcp_drop", t.objs.IgTcpdrop, nil)
+		if err != nil {
+			return fmt.Errorf("error opening kprobe: %w", err)
+		}
+	}
+
+	t.kfreeSkbLink, err = link.Tracepoint("skb", "kfree_skb", t.objs.IgTcpdropKfreeSkb, nil)
+	if err != nil {
+		return fmt.Errorf("error opening tracepoint: %w", err)
+	}
+
+	reader, err := perf.NewReader(t.objs.tcpdropMaps.Events, gadgets.PerfBufferPages*os.Getpagesize())
+	if err != nil {
+		return fmt.Errorf("error creating perf ring buffer: %w", err)
+	}
+	t.reader = reader
+
+	go t.run()
+
+	return nil
+}
+
+// AddContainer starts tracing the drops in the network namespace
+// of the container's pod.
+func (t *Tracer) AddContainer(c *pb.ContainerDefinition) error {
+	if c.Netns == t.config.NetnsHost {
+		return errors.New("the pod uses the host network")
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if p, ok := t.pods[c.Netns]; ok {
+		p.containers[c.Name] = struct{}{}
+		return nil
+	}
+
+	if err := t.objs.NetnsSet.Put(uint32(c.Netns), uint32(0)); err != nil {
+		return fmt.Errorf("adding the network namespace of the pod: %w", err)
+	}
+
+	t.pods[c.Netns] = &pod{
+		namespace:  c.Namespace,
+		name:       c.Podname,
+		containers: map[string]struct{}{c.Name: {}},
+	}
+
+	return nil
+}
+
+func (t *Tracer) RemoveContainer(c *pb.ContainerDefinition) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.pods[c.Netns]
+	if !ok {
+		return
+	}
+
+	delete(p.containers, c.Name)
+	if len(p.containers) > 0 {
+		return
+	}
+	delete(t.pods, c.Netns)
+
+	if err := t.objs.NetnsSet.Delete(uint32(c.Netns)); err != nil && !errors.Is(err, ebpf.ErrKeyNotExist) {
+		msg := fmt.Sprintf("removing the network namespace of the pod %s/%s: %s", p.namespace, p.name, err)
+		t.eventCallback(types.Base(eventtypes.Warn(msg, t.node)))
+	}
+}
+
+// fillPod sets the pod of the event from its network namespace. The
+// container is only known when the pod has a single traced container as
+// they all share the network namespace.
+func (t *Tracer) fillPod(event *types.Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.pods[event.Netns]
+	if !ok {
+		return
+	}
+
+	event.Namespace = p.namespace
+	event.Pod = p.name
+	if len(p.containers) == 1 {
+		for name := range p.containers {
+			event.Container = name
+		}
+	}
+}
+
+func (t *Tracer) run() {
+	for {
+		record, err := t.reader.Read()
+		if err != nil {
+			if errors.Is(err, perf.ErrClosed) {
+				return
+			}
+
+			msg := fmt.Sprintf("Error reading perf ring buffer: %s", err)
+			t.eventCallback(types.Base(eventtypes.Err(msg, t.node)))
+			return
+		}
+
+		if record.LostSamples > 0 {
+			msg := fmt.Sprintf("lost %d samples", record.LostSamples)
+			t.eventCallback(types.Base(eventtypes.Warn(msg, t.node)))
+			continue
+		}
+
+		eventC := (*C.struct_event)(unsafe.Pointer(&record.RawSample[0]))
+
+		event := types.Event{
+			Event: eventtypes.Event{
+				Type: eventtypes.NORMAL,
+				Node: t.node,
+			},
+			Netns:  uint64(eventC.netns),
+			Sport:  uint16(eventC.sport),
+			Dport:  uint16(eventC.dport),
+			State:  tcpStates[uint8(eventC.state)],
+			Reason: dropreasons.Name(t.reasons, uint32(eventC.reason)),
+		}
+
+		saddr := C.GoBytes(unsafe.Pointer(&eventC.saddr[0]), 16)
+		daddr := C.GoBytes(unsafe.Pointer(&eventC.daddr[0]), 16)
+
+		switch eventC.af {
+		case unix.AF_INET:
+			event.IPVersion = 4
+			event.Saddr = net.IP(saddr[:4]).String()
+			event.Daddr = net.IP(daddr[:4]).String()
+		case unix.AF_INET6:
+			event.IPVersion = 6
+			event.Saddr = net.IP(saddr).String()
+			event.Daddr = net.IP(daddr).String()
+		}
+
+		t.fillPod(&event)
+
+		t.eventCallback(event)
+	}
+}
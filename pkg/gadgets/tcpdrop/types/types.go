@@ -0,0 +1,50 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+type Event struct {
+	eventtypes.Event
+
+	// Netns is the network namespace of the socket. The packets are
+	// mostly dropped when received, not in the context of the processes
+	// of the pod, so there is no pid or mount namespace.
+	Netns uint64 `json:"netns,omitempty"`
+
+	// The addresses and ports are the ones of the dropped packet: the
+	// source is the remote peer for the received packets.
+	IPVersion int    `json:"ipversion,omitempty"`
+	Saddr     string `json:"saddr,omitempty"`
+	Daddr     string `json:"daddr,omitempty"`
+	Sport     uint16 `json:"sport,omitempty"`
+	Dport     uint16 `json:"dport,omitempty"`
+
+	// State is the TCP state of the socket, e.g. ESTABLISHED. It's empty
+	// when the packet isn't associated to a socket yet.
+	State string `json:"state,omitempty"`
+
+	// Reason is the reason of the drop given by the kernel, e.g.
+	// TCP_CSUM. It's empty on kernels before 5.17.
+	Reason string `json:"reason,omitempty"`
+}
+
+func Base(ev eventtypes.Event) Event {
+	return Event{
+		Event: ev,
+	}
+}
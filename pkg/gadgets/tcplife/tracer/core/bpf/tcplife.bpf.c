@@ -0,0 +1,133 @@
+// SPDX-License-Identifier: GPL-2.0
+//
+// Based on tcplife(8) from BCC by Brendan Gregg
+#include <vmlinux/vmlinux.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_tracing.h>
+
+#include "tcplife.h"
+
+/* Define here, because there are conflicts with include files */
+#define AF_INET		2
+#define AF_INET6	10
+#define IPPROTO_TCP	6
+
+#define MAX_ENTRIES	10240
+
+const volatile bool filter_by_mnt_ns = false;
+
+/* The process a connection is attributed to */
+struct ident {
+	__u64 mntns_id;
+	__u32 pid;
+	char task[TASK_COMM_LEN];
+};
+
+/* Start of the connections, by socket */
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, struct sock *);
+	__type(value, __u64);
+} births SEC(".maps");
+
+/* Process of the connections, by socket */
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, struct sock *);
+	__type(value, struct ident);
+} idents SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_PERF_EVENT_ARRAY);
+	__uint(key_size, sizeof(u32));
+	__uint(value_size, sizeof(u32));
+} events SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, 1024);
+	__uint(key_size, sizeof(u64));
+	__uint(value_size, sizeof(u32));
+} mount_ns_set SEC(".maps");
+
+SEC("tracepoint/sock/inet_sock_set_state")
+int ig_tcplife(struct trace_event_raw_inet_sock_set_state *ctx)
+{
+	struct sock *sk = (struct sock *)ctx->skaddr;
+	struct event event = {};
+	struct ident ident = {};
+	struct ident *identp;
+	struct task_struct *task;
+	struct tcp_sock *tp;
+	__u64 ts, *start;
+	int newstate;
+
+	if (ctx->protocol != IPPROTO_TCP)
+		return 0;
+
+	newstate = ctx->newstate;
+	ts = bpf_ktime_get_ns();
+
+	/* The connection starts when leaving the CLOSE state, by connecting,
+	 * or when created by an incoming connection. */
+	if (newstate < TCP_FIN_WAIT1)
+		bpf_map_update_elem(&births, &sk, &ts, BPF_ANY);
+
+	/* These transitions are done by connect() and close(), in the
+	 * context of the process owning the socket: the ones done when
+	 * receiving packets happen in whatever task is running. */
+	if (newstate == TCP_SYN_SENT || newstate == TCP_FIN_WAIT1 ||
+	    newstate == TCP_LAST_ACK) {
+		task = (struct task_struct*)bpf_get_current_task();
+		ident.mntns_id = (u64) BPF_CORE_READ(task, nsproxy, mnt_ns, ns.inum);
+		ident.pid = bpf_get_current_pid_tgid() >> 32;
+		bpf_get_current_comm(&ident.task, sizeof(ident.task));
+		bpf_map_update_elem(&idents, &sk, &ident, BPF_ANY);
+	}
+
+	if (newstate != TCP_CLOSE)
+		return 0;
+
+	start = bpf_map_lookup_elem(&births, &sk);
+	identp = bpf_map_lookup_elem(&idents, &sk);
+	if (!start || !identp)
+		goto cleanup;
+
+	if (filter_by_mnt_ns &&
+	    !bpf_map_lookup_elem(&mount_ns_set, &identp->mntns_id))
+		goto cleanup;
+
+	event.af = ctx->family;
+	if (event.af == AF_INET) {
+		bpf_probe_read_kernel(&event.saddr, 4, ctx->saddr);
+		bpf_probe_read_kernel(&event.daddr, 4, ctx->daddr);
+	} else if (event.af == AF_INET6) {
+		bpf_probe_read_kernel(&event.saddr, 16, ctx->saddr_v6);
+		bpf_probe_read_kernel(&event.daddr, 16, ctx->daddr_v6);
+	} else {
+		goto cleanup;
+	}
+
+	tp = (struct tcp_sock *)sk;
+	event.rx_b = BPF_CORE_READ(tp, bytes_received);
+	event.tx_b = BPF_CORE_READ(tp, bytes_acked);
+	event.span_us = (ts - *start) / 1000;
+	event.sport = ctx->sport;
+	event.dport = ctx->dport;
+	event.mntns_id = identp->mntns_id;
+	event.pid = identp->pid;
+	__builtin_memcpy(&event.task, identp->task, sizeof(event.task));
+
+	bpf_perf_event_output(ctx, &events, BPF_F_CURRENT_CPU, &event, sizeof(event));
+
+cleanup:
+	bpf_map_delete_elem(&births, &sk);
+	bpf_map_delete_elem(&idents, &sk);
+
+	return 0;
+}
+
+char LICENSE[] SEC("license") = "GPL";
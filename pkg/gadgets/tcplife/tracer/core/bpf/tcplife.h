@@ -0,0 +1,23 @@
+/* SPDX-License-Identifier: (LGPL-2.1 OR BSD-2-Clause) */
+#ifndef __TCPLIFE_H
+#define __TCPLIFE_H
+
+#define TASK_COMM_LEN	16
+
+/* Addresses are stored in the first 4 bytes of saddr and daddr for
+ * IPv4 */
+struct event {
+	__u8 saddr[16];
+	__u8 daddr[16];
+	__u64 mntns_id;
+	__u64 span_us;
+	__u64 rx_b;
+	__u64 tx_b;
+	__u32 pid;
+	__u16 af; // AF_INET or AF_INET6
+	__u16 sport;
+	__u16 dport;
+	char task[TASK_COMM_LEN];
+};
+
+#endif /* __TCPLIFE_H */
@@ -0,0 +1,73 @@
+// SPDX-License-Identifier: GPL-2.0
+//
+// Based on tcpretrans(8) from BCC by Brendan Gregg
+#include <vmlinux/vmlinux.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_endian.h>
+#include <bpf/bpf_tracing.h>
+
+#include "tcpretrans.h"
+
+/* Define here, because there are conflicts with include files */
+#define AF_INET		2
+#define AF_INET6	10
+
+const volatile bool filter_by_netns = false;
+
+struct {
+	__uint(type, BPF_MAP_TYPE_PERF_EVENT_ARRAY);
+	__uint(key_size, sizeof(u32));
+	__uint(value_size, sizeof(u32));
+} events SEC(".maps");
+
+/* Network namespaces of the traced pods, filled by the userspace */
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, 1024);
+	__uint(key_size, sizeof(u32));
+	__uint(value_size, sizeof(u32));
+} netns_set SEC(".maps");
+
+/* The retransmissions are done by the timers or when receiving the
+ * acknowledgements, in the context of whatever task is running: the
+ * current mount namespace says nothing about the container. Use the
+ * network namespace of the socket instead. */
+SEC("tracepoint/tcp/tcp_retransmit_skb")
+int ig_tcpretrans(struct trace_event_raw_tcp_event_sk_skb *ctx)
+{
+	const struct sock *sk = ctx->skaddr;
+	struct event event = {};
+	u32 netns;
+
+	if (!sk)
+		return 0;
+
+	netns = BPF_CORE_READ(sk, __sk_common.skc_net.net, ns.inum);
+	if (filter_by_netns && !bpf_map_lookup_elem(&netns_set, &netns))
+		return 0;
+
+	event.af = BPF_CORE_READ(sk, __sk_common.skc_family);
+	if (event.af == AF_INET) {
+		BPF_CORE_READ_INTO(event.saddr, sk, __sk_common.skc_rcv_saddr);
+		BPF_CORE_READ_INTO(event.daddr, sk, __sk_common.skc_daddr);
+	} else if (event.af == AF_INET6) {
+		BPF_CORE_READ_INTO(event.saddr, sk,
+				   __sk_common.skc_v6_rcv_saddr.in6_u.u6_addr8);
+		BPF_CORE_READ_INTO(event.daddr, sk,
+				   __sk_common.skc_v6_daddr.in6_u.u6_addr8);
+	} else {
+		return 0;
+	}
+
+	event.netns = netns;
+	event.sport = BPF_CORE_READ(sk, __sk_common.skc_num);
+	event.dport = bpf_ntohs(BPF_CORE_READ(sk, __sk_common.skc_dport));
+	event.state = BPF_CORE_READ(sk, __sk_common.skc_state);
+
+	bpf_perf_event_output(ctx, &events, BPF_F_CURRENT_CPU, &event, sizeof(event));
+
+	return 0;
+}
+
+char LICENSE[] SEC("license") = "GPL";
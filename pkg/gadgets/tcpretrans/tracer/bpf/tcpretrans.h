@@ -0,0 +1,18 @@
+/* SPDX-License-Identifier: (LGPL-2.1 OR BSD-2-Clause) */
+#ifndef __TCPRETRANS_H
+#define __TCPRETRANS_H
+
+/* Addresses are stored in the first 4 bytes of saddr and daddr for
+ * IPv4 */
+struct event {
+	__u8 saddr[16];
+	__u8 daddr[16];
+	__u32 netns;
+	__u16 af; // AF_INET or AF_INET6
+	__u16 sport;
+	__u16 dport;
+	__u8 state;
+	__u8 pad;
+};
+
+#endif /* __TCPRETRANS_H */
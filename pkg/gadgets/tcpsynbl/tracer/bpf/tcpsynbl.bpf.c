@@ -0,0 +1,95 @@
+// SPDX-License-Identifier: GPL-2.0
+// Copyright (c) 2022 The Inspektor Gadget authors
+// Based on tcpsynbl(8) from BCC by Brendan Gregg.
+#include <vmlinux/vmlinux.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_tracing.h>
+#include "tcpsynbl.h"
+
+/* Define here, because there are conflicts with include files */
+#define AF_INET		2
+#define AF_INET6	10
+
+#define MAX_ENTRIES	10240
+
+const volatile bool filter_by_netns = false;
+static struct backlog_stat zero_value = {};
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, struct backlog_key);
+	__type(value, struct backlog_stat);
+} entries SEC(".maps");
+
+/* Network namespaces of the traced pods, filled by the userspace */
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, 1024);
+	__uint(key_size, sizeof(u32));
+	__uint(value_size, sizeof(u32));
+} netns_set SEC(".maps");
+
+/* The handshakes complete when the last ACK is received, in softirq
+ * context: the socket is attributed to the pods by its network namespace,
+ * not by the current mount namespace. */
+static __always_inline int count(const struct sock *sk)
+{
+	struct backlog_key key = {};
+	struct backlog_stat *valuep;
+	u32 backlog, limit;
+
+	key.netns = BPF_CORE_READ(sk, __sk_common.skc_net.net, ns.inum);
+	if (filter_by_netns && !bpf_map_lookup_elem(&netns_set, &key.netns))
+		return 0;
+
+	key.family = BPF_CORE_READ(sk, __sk_common.skc_family);
+	key.lport = BPF_CORE_READ(sk, __sk_common.skc_num);
+
+	/* sk_ack_backlog and sk_max_ack_backlog were 16 bits long before
+	 * Linux 5.5: let libbpf relocate their size. */
+	backlog = BPF_CORE_READ_BITFIELD_PROBED(sk, sk_ack_backlog);
+	limit = BPF_CORE_READ_BITFIELD_PROBED(sk, sk_max_ack_backlog);
+
+	valuep = bpf_map_lookup_elem(&entries, &key);
+	if (!valuep) {
+		bpf_map_update_elem(&entries, &key, &zero_value, BPF_NOEXIST);
+		valuep = bpf_map_lookup_elem(&entries, &key);
+		if (!valuep)
+			return 0;
+	}
+
+	__sync_fetch_and_add(&valuep->conns, 1);
+	/* Same check as sk_acceptq_is_full(): the kernel drops the
+	 * connection right after. */
+	if (backlog > limit)
+		__sync_fetch_and_add(&valuep->drops, 1);
+
+	/* Not atomic: a concurrent handshake can hide a peak of the same
+	 * socket, which is good enough for a periodic report. */
+	if (backlog > valuep->peak)
+		valuep->peak = backlog;
+	valuep->limit = limit;
+
+	return 0;
+}
+
+SEC("kprobe/tcp_v4_syn_recv_sock")
+int BPF_KPROBE(ig_tcp_v4_syn_recv_sock, const struct sock *sk)
+{
+	/* tcp_v6_syn_recv_sock() calls tcp_v4_syn_recv_sock() for the IPv4
+	 * connections to IPv6 sockets: they are already counted. */
+	if (BPF_CORE_READ(sk, __sk_common.skc_family) != AF_INET)
+		return 0;
+
+	return count(sk);
+}
+
+SEC("kprobe/tcp_v6_syn_recv_sock")
+int BPF_KPROBE(ig_tcp_v6_syn_recv_sock, const struct sock *sk)
+{
+	return count(sk);
+}
+
+char LICENSE[] SEC("license") = "GPL";
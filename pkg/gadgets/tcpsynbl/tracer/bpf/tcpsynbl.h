@@ -0,0 +1,25 @@
+/* SPDX-License-Identifier: (LGPL-2.1 OR BSD-2-Clause) */
+#ifndef __TCPSYNBL_H
+#define __TCPSYNBL_H
+
+/* A listening socket, identified by its network namespace, its family and
+ * its local port. */
+struct backlog_key {
+	__u32 netns;
+	__u16 family; // AF_INET or AF_INET6
+	__u16 lport;
+};
+
+struct backlog_stat {
+	/* Handshakes completed, i.e. connections to be queued in the accept
+	 * backlog. */
+	__u64 conns;
+	/* Connections dropped because the accept backlog was full. */
+	__u64 drops;
+	/* Largest backlog seen when a handshake completed. */
+	__u32 peak;
+	/* Maximum backlog of the socket, as given to listen(). */
+	__u32 limit;
+};
+
+#endif /* __TCPSYNBL_H */
@@ -0,0 +1,302 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+	"golang.org/x/sys/unix"
+
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/tcpsynbl/types"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/threshold"
+	pb "github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/api"
+	"github.com/kinvolk/inspektor-gadget/pkg/mapdump"
+)
+
+//go:generate sh -c "GOOS=$(go env GOHOSTOS) GOARCH=$(go env GOHOSTARCH) go run github.com/cilium/ebpf/cmd/bpf2go -target bpfel -cc clang tcpsynbl ./bpf/tcpsynbl.bpf.c -- -I./bpf/ -I../../.. -target bpf -D__TARGET_ARCH_x86"
+
+type Config struct {
+	MaxRows  int
+	Interval time.Duration
+	SortBy   types.SortBy
+	Node     string
+
+	// NetnsHost is the network namespace of the host. The sockets of the
+	// pods using the host network can't be told apart from the ones of
+	// the host.
+	NetnsHost uint64
+
+	// Thresholds marks the rows crossing thresholds. These rows are
+	// reported even if they are not part of the first MaxRows ones.
+	Thresholds *threshold.Config
+}
+
+// backlogKey and backlogStat are struct backlog_key and struct
+// backlog_stat of bpf/tcpsynbl.h.
+type backlogKey struct {
+	Netns  uint32
+	Family uint16
+	Lport  uint16
+}
+
+type backlogStat struct {
+	Conns uint64
+	Drops uint64
+	Peak  uint32
+	Limit uint32
+}
+
+// pod is a traced pod, shared by its containers.
+type pod struct {
+	namespace  string
+	name       string
+	containers map[string]struct{}
+}
+
+type Tracer struct {
+	config        *Config
+	objs          tcpsynblObjects
+	links         []link.Link
+	statsCallback func([]types.Stats)
+	errorCallback func(error)
+	done          chan bool
+
+	mu sync.Mutex
+	// pods by network namespace
+	pods map[uint64]*pod
+}
+
+func NewTracer(config *Config, statsCallback func([]types.Stats),
+	errorCallback func(error)) (*Tracer, error) {
+	t := &Tracer{
+		config:        config,
+		statsCallback: statsCallback,
+		errorCallback: errorCallback,
+		done:          make(chan bool),
+		pods:          make(map[uint64]*pod),
+	}
+
+	if err := t.start(); err != nil {
+		t.Stop()
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (t *Tracer) Stop() {
+	close(t.done)
+
+	for i := range t.links {
+		t.links[i] = gadgets.CloseLink(t.links[i])
+	}
+
+	t.objs.Close()
+}
+
+// Maps returns the BPF maps of the tracer, so they can be dumped for
+// debugging.
+func (t *Tracer) Maps() map[string]*ebpf.Map {
+	return mapdump.MapsOf(&t.objs)
+}
+
+func (t *Tracer) start() error {
+	spec, err := loadTcpsynbl()
+	if err != nil {
+		return fmt.Errorf("failed to load ebpf program: %w", err)
+	}
+
+	consts := map[string]interface{}{
+		"filter_by_netns": true,
+	}
+
+	if err := spec.RewriteConstants(consts); err != nil {
+		return fmt.Errorf("error RewriteConstants: %w", err)
+	}
+
+	if err := spec.LoadAndAssign(&t.objs, nil); err != nil {
+		return fmt.Errorf("failed to load ebpf program: %w", err)
+	}
+
+	kprobes := []struct {
+		symbol string
+		prog   *ebpf.Program
+	}{
+		{"tcp_v4_syn_recv_sock", t.objs.IgTcpV4SynRecvSock},
+		{"tcp_v6_syn_recv_sock", t.objs.IgTcpV6SynRecvSock},
+	}
+
+	for _, kp := range kprobes {
+		l, err := link.Kprobe(kp.symbol, kp.prog, nil)
+		if err != nil {
+			return fmt.Errorf("error opening kprobe %s: %w", kp.symbol, err)
+		}
+		t.links = append(t.links, l)
+	}
+
+	t.run()
+
+	return nil
+}
+
+// AddContainer starts tracing the listening sockets of the network
+// namespace of the container's pod.
+func (t *Tracer) AddContainer(c *pb.ContainerDefinition) error {
+	if c.Netns == t.config.NetnsHost {
+		return errors.New("the pod uses the host network")
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if p, ok := t.pods[c.Netns]; ok {
+		p.containers[c.Name] = struct{}{}
+		return nil
+	}
+
+	if err := t.objs.NetnsSet.Put(uint32(c.Netns), uint32(0)); err != nil {
+		return fmt.Errorf("adding the network namespace of the pod: %w", err)
+	}
+
+	t.pods[c.Netns] = &pod{
+		namespace:  c.Namespace,
+		name:       c.Podname,
+		containers: map[string]struct{}{c.Name: {}},
+	}
+
+	return nil
+}
+
+func (t *Tracer) RemoveContainer(c *pb.ContainerDefinition) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.pods[c.Netns]
+	if !ok {
+		return nil
+	}
+
+	delete(p.containers, c.Name)
+	if len(p.containers) > 0 {
+		return nil
+	}
+	delete(t.pods, c.Netns)
+
+	if err := t.objs.NetnsSet.Delete(uint32(c.Netns)); err != nil && !errors.Is(err, ebpf.ErrKeyNotExist) {
+		return fmt.Errorf("removing the network namespace of the pod %s/%s: %w", p.namespace, p.name, err)
+	}
+
+	return nil
+}
+
+// fillPod sets the pod of the stats from its network namespace. The
+// container is only known when the pod has a single traced container as
+// they all share the network namespace.
+func (t *Tracer) fillPod(stat *types.Stats) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.pods[stat.Netns]
+	if !ok {
+		return
+	}
+
+	stat.Namespace = p.namespace
+	stat.Pod = p.name
+	if len(p.containers) == 1 {
+		for name := range p.containers {
+			stat.Container = name
+		}
+	}
+}
+
+func (t *Tracer) nextStats() ([]types.Stats, error) {
+	stats := []types.Stats{}
+	keys := []backlogKey{}
+
+	var key backlogKey
+	var value backlogStat
+
+	iter := t.objs.Entries.Iterate()
+	for iter.Next(&key, &value) {
+		keys = append(keys, key)
+
+		stat := types.Stats{
+			Node:  t.config.Node,
+			Netns: uint64(key.Netns),
+			Port:  key.Lport,
+			Conns: value.Conns,
+			Drops: value.Drops,
+		}
+		switch key.Family {
+		case unix.AF_INET:
+			stat.IPVersion = 4
+		case unix.AF_INET6:
+			stat.IPVersion = 6
+		}
+		stat.SetBacklog(value.Peak, value.Limit)
+
+		t.fillPod(&stat)
+
+		stats = append(stats, stat)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("error reading the listening sockets: %w", err)
+	}
+
+	// The counters are reset every interval
+	for _, key := range keys {
+		t.objs.Entries.Delete(key)
+	}
+
+	types.SortStats(stats, t.config.SortBy)
+
+	return stats, nil
+}
+
+func (t *Tracer) run() {
+	ticker := time.NewTicker(t.config.Interval)
+
+	go func() {
+		for {
+			select {
+			case <-t.done:
+				ticker.Stop()
+				return
+			case <-ticker.C:
+				stats, err := t.nextStats()
+				if err != nil {
+					t.errorCallback(err)
+					return
+				}
+
+				rows := []types.Stats{}
+				for i := range stats {
+					stats[i].Alerts = t.config.Thresholds.Check(&stats[i], t.config.Interval)
+					if i < t.config.MaxRows || len(stats[i].Alerts) > 0 {
+						rows = append(rows, stats[i])
+					}
+				}
+				t.statsCallback(rows)
+			}
+		}
+	}()
+}
@@ -0,0 +1,150 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"fmt"
+	"sort"
+)
+
+type SortBy int
+
+const (
+	DROPS SortBy = iota
+	USAGE
+	CONNS
+)
+
+const (
+	MaxRowsDefault  = 20
+	IntervalDefault = 1
+	SortByDefault   = DROPS
+)
+
+const (
+	IntervalParam = "interval"
+	MaxRowsParam  = "max_rows"
+	SortByParam   = "sort_by"
+)
+
+var SortBySlice = []string{
+	"drops",
+	"usage",
+	"conns",
+}
+
+func (s SortBy) String() string {
+	if int(s) < 0 || int(s) >= len(SortBySlice) {
+		return "INVALID"
+	}
+
+	return SortBySlice[int(s)]
+}
+
+func ParseSortBy(sortby string) (SortBy, error) {
+	for i, v := range SortBySlice {
+		if v == sortby {
+			return SortBy(i), nil
+		}
+	}
+	return DROPS, fmt.Errorf("%q is not a valid sort by value", sortby)
+}
+
+// Event is the information the gadget sends to the client each capture
+// interval
+type Event struct {
+	Error string `json:"error,omitempty"`
+
+	// Warning is set when rows crossed the thresholds during the interval
+	// and the warnings are enabled.
+	Warning string `json:"warning,omitempty"`
+
+	// Node where the event comes from.
+	Node string `json:"node,omitempty"`
+
+	// Timestamp is when the interval ended, in nanoseconds since the
+	// epoch.
+	Timestamp int64 `json:"timestamp,omitempty"`
+
+	Stats []Stats `json:"stats,omitempty"`
+}
+
+// Stats represents the connections completed on a single listening socket
+// during the interval. The sockets are attributed to the pods by their
+// network namespace: the container is only known when the pod has a single
+// traced container.
+type Stats struct {
+	Node      string `json:"node,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Pod       string `json:"pod,omitempty"`
+	Container string `json:"container,omitempty"`
+
+	Netns     uint64 `json:"netns,omitempty"`
+	IPVersion int    `json:"ipversion,omitempty"`
+	Port      uint16 `json:"port,omitempty"`
+
+	// Conns is the number of handshakes completed on the socket.
+	Conns uint64 `json:"conns,omitempty"`
+	// Drops is the number of connections dropped because the accept
+	// backlog was full.
+	Drops uint64 `json:"drops,omitempty"`
+	// Peak is the largest number of connections waiting to be accepted
+	// seen when a handshake completed.
+	Peak uint32 `json:"peak,omitempty"`
+	// Limit is the size of the accept backlog, as given to listen() and
+	// capped by the net.core.somaxconn sysctl.
+	Limit uint32 `json:"limit,omitempty"`
+
+	// Usage is the percentage of the backlog used at the peak.
+	Usage float64 `json:"usage"`
+
+	// Alerts are the thresholds crossed by the row during the interval.
+	Alerts []string `json:"alerts,omitempty"`
+}
+
+// SetBacklog sets the peak and the limit of the backlog and computes its
+// usage. The peak is seen before the new connection is queued: the kernel
+// accepts it while the peak isn't greater than the limit, so the usage goes
+// beyond 100% only when connections are dropped.
+func (s *Stats) SetBacklog(peak, limit uint32) {
+	s.Peak = peak
+	s.Limit = limit
+
+	s.Usage = 0
+	if limit > 0 {
+		s.Usage = float64(peak) / float64(limit) * 100
+	}
+}
+
+func SortStats(stats []Stats, sortBy SortBy) {
+	sort.Slice(stats, func(i, j int) bool {
+		a := stats[i]
+		b := stats[j]
+
+		switch sortBy {
+		case USAGE:
+			return a.Usage > b.Usage
+		case CONNS:
+			return a.Conns > b.Conns
+		default:
+			// The sockets closest to overflow after the ones
+			// dropping connections.
+			if a.Drops != b.Drops {
+				return a.Drops > b.Drops
+			}
+			return a.Usage > b.Usage
+		}
+	})
+}
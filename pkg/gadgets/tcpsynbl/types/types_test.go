@@ -0,0 +1,80 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"testing"
+)
+
+func TestSetBacklog(t *testing.T) {
+	tests := []struct {
+		name        string
+		peak, limit uint32
+		usage       float64
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:  "half full",
+			peak:  64,
+			limit: 128,
+			usage: 50,
+		},
+		{
+			name:  "overflowing",
+			peak:  5,
+			limit: 4,
+			usage: 125,
+		},
+		{
+			name: "no limit",
+			peak: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var s Stats
+			s.SetBacklog(test.peak, test.limit)
+
+			if s.Peak != test.peak || s.Limit != test.limit {
+				t.Fatalf("got a peak of %d and a limit of %d, expected %d and %d",
+					s.Peak, s.Limit, test.peak, test.limit)
+			}
+			if s.Usage != test.usage {
+				t.Fatalf("got a usage of %f, expected %f", s.Usage, test.usage)
+			}
+		})
+	}
+}
+
+func TestSortStats(t *testing.T) {
+	stats := []Stats{
+		{Pod: "idle"},
+		{Pod: "busy", Conns: 1000, Usage: 90},
+		{Pod: "overflowing", Conns: 100, Drops: 10, Usage: 110},
+		{Pod: "quiet", Conns: 10, Usage: 10},
+	}
+
+	SortStats(stats, DROPS)
+
+	expected := []string{"overflowing", "busy", "quiet", "idle"}
+	for i, s := range stats {
+		if s.Pod != expected[i] {
+			t.Fatalf("got %q at %d, expected %q", s.Pod, i, expected[i])
+		}
+	}
+}
@@ -18,14 +18,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"strconv"
-	"strings"
 	"time"
 
+	"github.com/cilium/ebpf"
 	log "github.com/sirupsen/logrus"
 
+	"github.com/kinvolk/inspektor-gadget/pkg/bpferror"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
 	tcptoptracer "github.com/kinvolk/inspektor-gadget/pkg/gadgets/tcptop/tracer"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/tcptop/types"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/threshold"
 
 	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
 )
@@ -48,18 +50,38 @@ func NewFactory() gadgets.TraceFactory {
 }
 
 func (f *TraceFactory) Description() string {
-	t := `tcptop shows command generating TCP connections, with container details.
-
-The following parameters are supported:
-- %s: Output interval, in seconds. (default %d)
-- %s: Maximum rows to print. (default %d)
-- %s: The field to sort the results by (%s). (default %s)
-- %s: Only get events for this PID (default to all).
-- %s: Only get events for this IP version. (either 4 or 6, default to all)`
-	return fmt.Sprintf(t, types.IntervalParam, types.IntervalDefault,
-		types.MaxRowsParam, types.MaxRowsDefault,
-		types.SortByParam, strings.Join(types.SortBySlice, ","), types.SortByDefault,
-		types.PidParam, types.FamilyParam)
+	return `tcptop shows command generating TCP connections, with container details.`
+}
+
+func (f *TraceFactory) Parameters() []gadgets.GadgetParameter {
+	params := []gadgets.GadgetParameter{
+		{
+			Name:        types.IntervalParam,
+			Description: "Output interval, in seconds",
+			Default:     strconv.Itoa(types.IntervalDefault),
+		},
+		{
+			Name:        types.MaxRowsParam,
+			Description: "Maximum rows to print",
+			Default:     strconv.Itoa(types.MaxRowsDefault),
+		},
+		{
+			Name:        types.SortByParam,
+			Description: "The field to sort the results by",
+			Default:     types.SortByDefault.String(),
+			Values:      types.SortBySlice,
+		},
+		{
+			Name:        types.PidParam,
+			Description: "Only get events for this PID, all the processes by default",
+		},
+		{
+			Name:        types.FamilyParam,
+			Description: "Only get events for this IP version, all by default",
+			Values:      []string{"4", "6"},
+		},
+	}
+	return append(params, gadgets.ThresholdParameters()...)
 }
 
 func (f *TraceFactory) OutputModesSupported() map[string]struct{} {
@@ -68,6 +90,14 @@ func (f *TraceFactory) OutputModesSupported() map[string]struct{} {
 	}
 }
 
+func (f *TraceFactory) Maps(name string) map[string]*ebpf.Map {
+	t, ok := f.LookupOrCreate(name, nil).(*Trace)
+	if !ok || !t.started {
+		return nil
+	}
+	return t.tracer.Maps()
+}
+
 func deleteTrace(name string, t interface{}) {
 	trace := t.(*Trace)
 	if trace.tracer != nil {
@@ -159,6 +189,12 @@ func (t *Trace) Start(trace *gadgetv1alpha1.Trace) {
 		}
 	}
 
+	thresholds, err := threshold.ParseParameters(trace.Spec.Parameters, &types.Stats{})
+	if err != nil {
+		trace.Status.OperationError = err.Error()
+		return
+	}
+
 	config := &tcptoptracer.Config{
 		MaxRows:      maxRows,
 		Interval:     time.Second * time.Duration(intervalSeconds),
@@ -167,12 +203,31 @@ func (t *Trace) Start(trace *gadgetv1alpha1.Trace) {
 		TargetPid:    targetPid,
 		TargetFamily: targetFamily,
 		Node:         trace.Spec.Node,
+		Thresholds:   thresholds,
 	}
 
 	statsCallback := func(stats []types.Stats) {
 		ev := types.Event{
-			Node:  trace.Spec.Node,
-			Stats: stats,
+			Node:      trace.Spec.Node,
+			Timestamp: time.Now().UnixNano(),
+			Stats:     stats,
+		}
+
+		var alerted []types.Stats
+		for _, s := range stats {
+			if len(s.Alerts) > 0 {
+				alerted = append(alerted, s)
+			}
+		}
+		if len(alerted) > 0 {
+			ev.Warning = thresholds.Warning(len(alerted))
+			thresholds.Post(threshold.Alert{
+				Gadget:    trace.Spec.Gadget,
+				Trace:     trace.ObjectMeta.Namespace + "/" + trace.ObjectMeta.Name,
+				Node:      trace.Spec.Node,
+				Timestamp: ev.Timestamp,
+				Rows:      alerted,
+			})
 		}
 
 		r, err := json.Marshal(ev)
@@ -198,7 +253,7 @@ func (t *Trace) Start(trace *gadgetv1alpha1.Trace) {
 
 	tracer, err := tcptoptracer.NewTracer(config, t.resolver, statsCallback, errorCallback)
 	if err != nil {
-		trace.Status.OperationError = fmt.Sprintf("failed to create tracer: %s", err)
+		trace.Status.OperationError = fmt.Sprintf("failed to create tracer: %s", bpferror.Describe(err))
 		return
 	}
 
@@ -83,9 +83,17 @@ func ParseFilterByFamily(family string) (int32, error) {
 type Event struct {
 	Error string `json:"error,omitempty"`
 
+	// Warning is set when rows crossed the thresholds during the interval
+	// and the warnings are enabled.
+	Warning string `json:"warning,omitempty"`
+
 	// Node where the event comes from.
 	Node string `json:"node,omitempty"`
 
+	// Timestamp is when the interval ended, in nanoseconds since the
+	// epoch.
+	Timestamp int64 `json:"timestamp,omitempty"`
+
 	Stats []Stats `json:"stats,omitempty"`
 }
 
@@ -106,6 +114,9 @@ type Stats struct {
 	Family    uint16 `json:"family,omitempty"`
 	Sent      uint64 `json:"sent,omitempty"`
 	Received  uint64 `json:"received,omitempty"`
+
+	// Alerts are the thresholds crossed by the row during the interval.
+	Alerts []string `json:"alerts,omitempty"`
 }
 
 func SortStats(stats []Stats, sortBy SortBy) {
@@ -0,0 +1,165 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package threshold
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// WebhookTimeout is the maximum time spent posting an alert.
+const WebhookTimeout = 5 * time.Second
+
+// Config is the configuration of the thresholds of a trace.
+type Config struct {
+	Thresholds []Threshold
+
+	// Warn tells if a warning is sent with the intervals where thresholds
+	// are crossed.
+	Warn bool
+
+	// Webhook is the URL the alerts are posted to, if not empty.
+	Webhook string
+
+	client  *http.Client
+	posting int32
+}
+
+// Alert is posted to the webhook for each interval where thresholds are
+// crossed.
+type Alert struct {
+	Gadget    string `json:"gadget"`
+	Trace     string `json:"trace"`
+	Node      string `json:"node"`
+	Timestamp int64  `json:"timestamp"`
+
+	// Thresholds are the thresholds as given in the parameters.
+	Thresholds []string `json:"thresholds"`
+
+	// Rows are the rows of the interval that crossed a threshold.
+	Rows interface{} `json:"rows"`
+}
+
+// ParseParameters reads the configuration of the thresholds from the
+// parameters of a trace. row is a row of the output of the gadget, it's
+// used to validate the fields of the thresholds. It returns nil when no
+// threshold is set.
+func ParseParameters(params map[string]string, row interface{}) (*Config, error) {
+	val, ok := params[Param]
+	if !ok {
+		return nil, nil
+	}
+
+	thresholds, err := Parse(val)
+	if err != nil {
+		return nil, err
+	}
+	if err := Validate(thresholds, row); err != nil {
+		return nil, err
+	}
+
+	config := &Config{
+		Thresholds: thresholds,
+		client:     &http.Client{Timeout: WebhookTimeout},
+	}
+
+	if val, ok := params[WarnParam]; ok {
+		config.Warn, err = strconv.ParseBool(val)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not valid for %q", val, WarnParam)
+		}
+	}
+
+	if val, ok := params[WebhookParam]; ok && val != "" {
+		u, err := url.Parse(val)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return nil, fmt.Errorf("%q is not a valid http(s) URL for %q", val, WebhookParam)
+		}
+		config.Webhook = val
+	}
+
+	return config, nil
+}
+
+// Check returns the thresholds of the configuration crossed by row during
+// interval. It's safe to call on a nil configuration.
+func (c *Config) Check(row interface{}, interval time.Duration) []string {
+	if c == nil {
+		return nil
+	}
+	return Check(c.Thresholds, row, interval)
+}
+
+func (c *Config) thresholds() []string {
+	thresholds := make([]string, len(c.Thresholds))
+	for i, t := range c.Thresholds {
+		thresholds[i] = t.String()
+	}
+	return thresholds
+}
+
+// Warning returns the warning to send with an interval where count rows
+// crossed thresholds, or an empty string if warnings are disabled.
+func (c *Config) Warning(count int) string {
+	if c == nil || !c.Warn || count == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d rows crossed the thresholds %s", count, strings.Join(c.thresholds(), ","))
+}
+
+// Post posts alert to the webhook in the background. The alert is dropped
+// if the previous one is still being posted, so that a slow webhook can't
+// accumulate requests.
+func (c *Config) Post(alert Alert) {
+	if c == nil || c.Webhook == "" {
+		return
+	}
+	if !atomic.CompareAndSwapInt32(&c.posting, 0, 1) {
+		log.Warnf("Dropping alert of %s: the previous one is still being posted", alert.Trace)
+		return
+	}
+
+	alert.Thresholds = c.thresholds()
+
+	go func() {
+		defer atomic.StoreInt32(&c.posting, 0)
+
+		b, err := json.Marshal(alert)
+		if err != nil {
+			log.Warnf("Failed to marshal alert of %s: %s", alert.Trace, err)
+			return
+		}
+
+		resp, err := c.client.Post(c.Webhook, "application/json", bytes.NewReader(b))
+		if err != nil {
+			log.Warnf("Failed to post alert of %s: %s", alert.Trace, err)
+			return
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode >= 300 {
+			log.Warnf("Failed to post alert of %s: webhook returned %s", alert.Trace, resp.Status)
+		}
+	}()
+}
@@ -0,0 +1,262 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package threshold implements the thresholds of the top gadgets. The rows
+// of an interval whose value of a field crosses a threshold are marked and
+// can be reported with a warning or a webhook, which turns the top gadgets
+// into simple anomaly detectors.
+package threshold
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Param is the parameter giving the thresholds as a comma-separated
+	// list of expressions, see Parse.
+	Param = "threshold"
+
+	// WarnParam is the parameter telling if a warning is sent with the
+	// intervals where thresholds are crossed.
+	WarnParam = "threshold_warn"
+
+	// WebhookParam is the parameter giving the URL the alerts are posted
+	// to.
+	WebhookParam = "threshold_webhook"
+)
+
+type Operator string
+
+const (
+	Greater        Operator = ">"
+	GreaterOrEqual Operator = ">="
+	Less           Operator = "<"
+	LessOrEqual    Operator = "<="
+)
+
+// operators are sorted so that the two-character operators are tried first.
+var operators = []Operator{GreaterOrEqual, LessOrEqual, Greater, Less}
+
+// units are the multipliers accepted after the value of a threshold.
+var units = map[string]float64{
+	"":    1,
+	"k":   1e3,
+	"kb":  1e3,
+	"kib": 1 << 10,
+	"m":   1e6,
+	"mb":  1e6,
+	"mib": 1 << 20,
+	"g":   1e9,
+	"gb":  1e9,
+	"gib": 1 << 30,
+	"t":   1e12,
+	"tb":  1e12,
+	"tib": 1 << 40,
+}
+
+// Threshold is a limit on a numeric field of the rows of a top gadget.
+type Threshold struct {
+	// Field is the name of the field in the JSON output of the gadget.
+	Field string
+
+	Operator Operator
+
+	// Value is the limit in the unit of the field.
+	Value float64
+
+	// PerSecond is true when Value is a rate per second instead of per
+	// interval.
+	PerSecond bool
+
+	expr string
+}
+
+// Parse parses a comma-separated list of thresholds. Each threshold has
+// the form <field><operator><value>[<unit>][/interval|/s], e.g. sent>10MB
+// or wbytes>=1MiB/s. The operator is one of >, >=, < and <=. The unit is
+// one of k, M, G, T, which are powers of 1000 and can be followed by B, or
+// KiB, MiB, GiB, TiB, which are powers of 1024. The value is compared to
+// the value of the field over the whole interval unless it ends with /s.
+func Parse(s string) ([]Threshold, error) {
+	var thresholds []Threshold
+
+	for _, expr := range strings.Split(s, ",") {
+		expr = strings.TrimSpace(expr)
+		if expr == "" {
+			continue
+		}
+
+		t, err := parseThreshold(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid threshold %q: %w", expr, err)
+		}
+		thresholds = append(thresholds, t)
+	}
+
+	if len(thresholds) == 0 {
+		return nil, errors.New("no threshold given")
+	}
+
+	return thresholds, nil
+}
+
+func parseThreshold(expr string) (Threshold, error) {
+	t := Threshold{expr: expr}
+
+	for _, op := range operators {
+		if i := strings.Index(expr, string(op)); i != -1 {
+			t.Field = strings.TrimSpace(expr[:i])
+			t.Operator = op
+			expr = strings.TrimSpace(expr[i+len(op):])
+			break
+		}
+	}
+	if t.Operator == "" {
+		return t, errors.New("expected one of the operators >, >=, < and <=")
+	}
+	if t.Field == "" {
+		return t, errors.New("missing field")
+	}
+
+	if i := strings.LastIndex(expr, "/"); i != -1 {
+		switch strings.TrimSpace(expr[i+1:]) {
+		case "interval":
+		case "s":
+			t.PerSecond = true
+		default:
+			return t, fmt.Errorf("%q is not a valid period, use /interval or /s", expr[i:])
+		}
+		expr = strings.TrimSpace(expr[:i])
+	}
+
+	// The number is followed by the unit
+	i := strings.IndexFunc(expr, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i == -1 {
+		i = len(expr)
+	}
+
+	value, err := strconv.ParseFloat(expr[:i], 64)
+	if err != nil {
+		return t, fmt.Errorf("%q is not a valid number", expr[:i])
+	}
+
+	unit, ok := units[strings.ToLower(strings.TrimSpace(expr[i:]))]
+	if !ok {
+		return t, fmt.Errorf("%q is not a valid unit", expr[i:])
+	}
+
+	t.Value = value * unit
+
+	return t, nil
+}
+
+// String returns the threshold as it was given to Parse.
+func (t Threshold) String() string {
+	return t.expr
+}
+
+// Crossed tells if value, which was measured during interval, crosses the
+// threshold.
+func (t Threshold) Crossed(value float64, interval time.Duration) bool {
+	limit := t.Value
+	if t.PerSecond {
+		limit *= interval.Seconds()
+	}
+
+	switch t.Operator {
+	case Greater:
+		return value > limit
+	case GreaterOrEqual:
+		return value >= limit
+	case Less:
+		return value < limit
+	case LessOrEqual:
+		return value <= limit
+	}
+	return false
+}
+
+// field returns the numeric field of the struct pointed to by row whose
+// JSON name is name.
+func field(row interface{}, name string) (reflect.Value, bool) {
+	v := reflect.Indirect(reflect.ValueOf(row))
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+
+	for i := 0; i < v.NumField(); i++ {
+		tag := strings.Split(v.Type().Field(i).Tag.Get("json"), ",")[0]
+		if tag != name {
+			continue
+		}
+
+		f := v.Field(i)
+		switch f.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			return f, true
+		}
+		return reflect.Value{}, false
+	}
+
+	return reflect.Value{}, false
+}
+
+func toFloat(v reflect.Value) float64 {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint())
+	default:
+		return v.Float()
+	}
+}
+
+// Validate checks that the thresholds apply to numeric fields of row,
+// which is a row of the output of a top gadget.
+func Validate(thresholds []Threshold, row interface{}) error {
+	for _, t := range thresholds {
+		if _, ok := field(row, t.Field); !ok {
+			return fmt.Errorf("invalid threshold %q: %q is not a numeric field", t, t.Field)
+		}
+	}
+	return nil
+}
+
+// Check returns the thresholds crossed by row during interval. The
+// thresholds must have been validated against the type of row.
+func Check(thresholds []Threshold, row interface{}, interval time.Duration) []string {
+	var crossed []string
+
+	for _, t := range thresholds {
+		f, ok := field(row, t.Field)
+		if !ok {
+			continue
+		}
+		if t.Crossed(toFloat(f), interval) {
+			crossed = append(crossed, t.String())
+		}
+	}
+
+	return crossed
+}
@@ -0,0 +1,202 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package threshold
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type row struct {
+	Comm  string  `json:"comm,omitempty"`
+	Sent  uint64  `json:"sent,omitempty"`
+	Delta int32   `json:"delta"`
+	Ratio float64 `json:"ratio"`
+}
+
+func TestParse(t *testing.T) {
+	table := []struct {
+		expr     string
+		expected []Threshold
+	}{
+		{
+			expr: "sent>10MB",
+			expected: []Threshold{
+				{Field: "sent", Operator: Greater, Value: 10e6, expr: "sent>10MB"},
+			},
+		},
+		{
+			expr: "sent >= 1.5 KiB/s, delta<2",
+			expected: []Threshold{
+				{Field: "sent", Operator: GreaterOrEqual, Value: 1536, PerSecond: true, expr: "sent >= 1.5 KiB/s"},
+				{Field: "delta", Operator: Less, Value: 2, expr: "delta<2"},
+			},
+		},
+		{
+			expr: "sent<=3k/interval",
+			expected: []Threshold{
+				{Field: "sent", Operator: LessOrEqual, Value: 3000, expr: "sent<=3k/interval"},
+			},
+		},
+	}
+
+	for _, entry := range table {
+		thresholds, err := Parse(entry.expr)
+		if err != nil {
+			t.Fatalf("Failed to parse %q: %s", entry.expr, err)
+		}
+		if !reflect.DeepEqual(thresholds, entry.expected) {
+			t.Fatalf("Expected %+v for %q, got %+v", entry.expected, entry.expr, thresholds)
+		}
+	}
+
+	for _, expr := range []string{"", " , ", "sent", ">10", "sent>", "sent>10XB", "sent>10/m", "sent>1.2.3", "delta<-1"} {
+		if _, err := Parse(expr); err == nil {
+			t.Fatalf("Expected an error for %q", expr)
+		}
+	}
+}
+
+func TestCrossed(t *testing.T) {
+	table := []struct {
+		expr     string
+		value    float64
+		interval time.Duration
+		expected bool
+	}{
+		{"sent>10", 10, time.Second, false},
+		{"sent>=10", 10, time.Second, true},
+		{"sent<10", 10, time.Second, false},
+		{"sent<=10", 10, time.Second, true},
+		{"sent>1k/s", 4000, 5 * time.Second, false},
+		{"sent>1k/s", 6000, 5 * time.Second, true},
+		{"sent>1k/interval", 6000, 5 * time.Second, true},
+	}
+
+	for _, entry := range table {
+		thresholds, err := Parse(entry.expr)
+		if err != nil {
+			t.Fatalf("Failed to parse %q: %s", entry.expr, err)
+		}
+		if crossed := thresholds[0].Crossed(entry.value, entry.interval); crossed != entry.expected {
+			t.Fatalf("Expected %t for %v with %q during %s, got %t",
+				entry.expected, entry.value, entry.expr, entry.interval, crossed)
+		}
+	}
+}
+
+func TestValidateAndCheck(t *testing.T) {
+	thresholds, err := Parse("sent>1KiB,delta<0,ratio>=0.5")
+	if err != nil {
+		t.Fatalf("Failed to parse thresholds: %s", err)
+	}
+	if err := Validate(thresholds, &row{}); err != nil {
+		t.Fatalf("Failed to validate thresholds: %s", err)
+	}
+
+	for _, expr := range []string{"comm>1", "unknown>1"} {
+		invalid, err := Parse(expr)
+		if err != nil {
+			t.Fatalf("Failed to parse %q: %s", expr, err)
+		}
+		if err := Validate(invalid, &row{}); err == nil {
+			t.Fatalf("Expected an error validating %q", expr)
+		}
+	}
+
+	crossed := Check(thresholds, &row{Sent: 2048, Delta: -1, Ratio: 0.2}, time.Second)
+	expected := []string{"sent>1KiB", "delta<0"}
+	if !reflect.DeepEqual(crossed, expected) {
+		t.Fatalf("Expected %v, got %v", expected, crossed)
+	}
+
+	if crossed := Check(thresholds, &row{Sent: 1024, Ratio: 0.2}, time.Second); crossed != nil {
+		t.Fatalf("Expected no threshold crossed, got %v", crossed)
+	}
+}
+
+func TestParseParameters(t *testing.T) {
+	config, err := ParseParameters(map[string]string{}, &row{})
+	if err != nil || config != nil {
+		t.Fatalf("Expected no configuration without thresholds, got %+v, %v", config, err)
+	}
+	// A nil configuration doesn't report anything
+	if crossed := config.Check(&row{Sent: 1}, time.Second); crossed != nil {
+		t.Fatalf("Expected no threshold crossed, got %v", crossed)
+	}
+
+	config, err = ParseParameters(map[string]string{
+		Param:        "sent>1",
+		WarnParam:    "true",
+		WebhookParam: "https://example.com/alerts",
+	}, &row{})
+	if err != nil {
+		t.Fatalf("Failed to parse parameters: %s", err)
+	}
+	if !config.Warn || config.Webhook != "https://example.com/alerts" {
+		t.Fatalf("Unexpected configuration %+v", config)
+	}
+	if warning := config.Warning(2); warning != "2 rows crossed the thresholds sent>1" {
+		t.Fatalf("Unexpected warning %q", warning)
+	}
+	if warning := config.Warning(0); warning != "" {
+		t.Fatalf("Expected no warning without rows, got %q", warning)
+	}
+
+	for _, params := range []map[string]string{
+		{Param: "comm>1"},
+		{Param: "sent>1", WarnParam: "maybe"},
+		{Param: "sent>1", WebhookParam: "ftp://example.com"},
+	} {
+		if _, err := ParseParameters(params, &row{}); err == nil {
+			t.Fatalf("Expected an error for %v", params)
+		}
+	}
+}
+
+func TestPost(t *testing.T) {
+	alerts := make(chan Alert, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert Alert
+		if err := json.NewDecoder(r.Body).Decode(&alert); err != nil {
+			t.Errorf("Failed to decode alert: %s", err)
+		}
+		alerts <- alert
+	}))
+	defer server.Close()
+
+	config, err := ParseParameters(map[string]string{
+		Param:        "sent>1",
+		WebhookParam: server.URL,
+	}, &row{})
+	if err != nil {
+		t.Fatalf("Failed to parse parameters: %s", err)
+	}
+
+	config.Post(Alert{Gadget: "tcptop", Trace: "gadget/foo", Node: "node1", Rows: []row{{Sent: 2}}})
+
+	select {
+	case alert := <-alerts:
+		if alert.Trace != "gadget/foo" || !reflect.DeepEqual(alert.Thresholds, []string{"sent>1"}) {
+			t.Fatalf("Unexpected alert %+v", alert)
+		}
+	case <-time.After(WebhookTimeout):
+		t.Fatalf("Timeout waiting for the alert")
+	}
+}
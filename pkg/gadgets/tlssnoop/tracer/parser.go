@@ -0,0 +1,228 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	recordTypeHandshake = 0x16
+
+	handshakeTypeClientHello = 1
+	handshakeTypeServerHello = 2
+
+	extensionServerName        = 0
+	extensionSupportedVersions = 43
+
+	serverNameTypeHostName = 0
+)
+
+var errTruncated = errors.New("truncated handshake message")
+
+// handshake holds the fields of a ClientHello or a ServerHello message the
+// tracer is interested in.
+type handshake struct {
+	typ uint8
+
+	// serverName is only set for ClientHello messages.
+	serverName string
+
+	// version and cipher are only set for ServerHello messages.
+	version uint16
+	cipher  uint16
+}
+
+// cursor reads big-endian integers and length-prefixed vectors from a
+// buffer. Reads past the end of the buffer set err and return zero values.
+type cursor struct {
+	buf []byte
+	err error
+}
+
+func (c *cursor) bytes(n int) []byte {
+	if c.err != nil || n > len(c.buf) {
+		c.err = errTruncated
+		return nil
+	}
+	b := c.buf[:n]
+	c.buf = c.buf[n:]
+	return b
+}
+
+func (c *cursor) uint8() uint8 {
+	b := c.bytes(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (c *cursor) uint16() uint16 {
+	b := c.bytes(2)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint16(b)
+}
+
+func (c *cursor) vector8() []byte {
+	return c.bytes(int(c.uint8()))
+}
+
+func (c *cursor) vector16() []byte {
+	return c.bytes(int(c.uint16()))
+}
+
+// parseHandshake parses the TLS record at the beginning of a TCP payload.
+// It returns nil without error if the payload isn't a ClientHello or a
+// ServerHello. A ClientHello split across several TCP segments is parsed as
+// far as possible: the server name is only reported if it's part of the
+// first segment, which is the case in practice.
+func parseHandshake(payload []byte) (*handshake, error) {
+	if len(payload) < 9 || payload[0] != recordTypeHandshake || payload[1] != 3 {
+		return nil, nil
+	}
+
+	typ := payload[5]
+	if typ != handshakeTypeClientHello && typ != handshakeTypeServerHello {
+		return nil, nil
+	}
+
+	length := int(payload[6])<<16 | int(payload[7])<<8 | int(payload[8])
+	body := payload[9:]
+	if length < len(body) {
+		body = body[:length]
+	}
+
+	h := &handshake{typ: typ}
+	c := &cursor{buf: body}
+
+	// legacy_version, random and legacy_session_id
+	legacyVersion := c.uint16()
+	c.bytes(32)
+	c.vector8()
+
+	if typ == handshakeTypeClientHello {
+		c.vector16() // cipher_suites
+		c.vector8()  // legacy_compression_methods
+	} else {
+		h.version = legacyVersion
+		h.cipher = c.uint16()
+		c.uint8() // legacy_compression_method
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	// Extensions are optional before TLS 1.3.
+	if len(c.buf) == 0 {
+		return h, nil
+	}
+
+	extensions := &cursor{buf: c.vector16()}
+	if c.err != nil {
+		// Keep what can be parsed from a truncated ClientHello.
+		extensions.buf = c.buf
+	}
+
+	for len(extensions.buf) > 0 && extensions.err == nil {
+		extType := extensions.uint16()
+		data := &cursor{buf: extensions.vector16()}
+		if extensions.err != nil {
+			break
+		}
+
+		switch {
+		case extType == extensionServerName && typ == handshakeTypeClientHello:
+			list := &cursor{buf: data.vector16()}
+			for len(list.buf) > 0 && list.err == nil {
+				nameType := list.uint8()
+				name := list.vector16()
+				if list.err == nil && nameType == serverNameTypeHostName {
+					h.serverName = string(name)
+					break
+				}
+			}
+		case extType == extensionSupportedVersions && typ == handshakeTypeServerHello:
+			// The ServerHello of TLS 1.3 keeps 1.2 as legacy_version and
+			// puts the negotiated version in this extension.
+			if version := data.uint16(); data.err == nil {
+				h.version = version
+			}
+		}
+	}
+
+	return h, nil
+}
+
+// versionName returns the human readable name of a TLS version.
+func versionName(version uint16) string {
+	switch version {
+	case 0:
+		return ""
+	case tls.VersionSSL30:
+		return "SSL 3.0"
+	case tls.VersionTLS10:
+		return "TLS 1.0"
+	case tls.VersionTLS11:
+		return "TLS 1.1"
+	case tls.VersionTLS12:
+		return "TLS 1.2"
+	case tls.VersionTLS13:
+		return "TLS 1.3"
+	default:
+		return fmt.Sprintf("0x%04x", version)
+	}
+}
+
+// cipherName returns the IANA name of a cipher suite, or its hexadecimal
+// value if it's not known.
+func cipherName(cipher uint16) string {
+	if cipher == 0 {
+		return ""
+	}
+	return tls.CipherSuiteName(cipher)
+}
+
+var httpMethods = []string{
+	"GET", "HEAD", "POST", "PUT", "DELETE", "CONNECT", "OPTIONS", "TRACE", "PATCH",
+}
+
+// parseHTTPRequest returns the method of the plaintext HTTP/1.x request at
+// the beginning of a TCP payload, or an empty string if the payload doesn't
+// start with a request line.
+func parseHTTPRequest(payload []byte) string {
+	line := payload
+	if i := bytes.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+
+	for _, method := range httpMethods {
+		if !bytes.HasPrefix(line, []byte(method+" ")) {
+			continue
+		}
+		if bytes.Contains(line, []byte(" HTTP/1.")) {
+			return method
+		}
+		return ""
+	}
+
+	return ""
+}
@@ -0,0 +1,252 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/tlssnoop/types"
+	"github.com/kinvolk/inspektor-gadget/pkg/rawsock"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+const (
+	// HandshakeTimeout is the time after which a ClientHello without a
+	// ServerHello is reported without the negotiated version and cipher.
+	HandshakeTimeout = 10 * time.Second
+)
+
+type Config struct {
+	// Ports are the TCP ports where TLS is expected.
+	Ports map[uint16]struct{}
+}
+
+// connection identifies a TCP connection by its client and server
+// endpoints.
+type connection struct {
+	client string
+	server string
+}
+
+type pendingHello struct {
+	event     types.Event
+	timestamp time.Time
+}
+
+type link struct {
+	listener *rawsock.Listener
+
+	// users count how many users called Attach(). This can happen for two reasons:
+	// 1. several containers in a pod (sharing the netns)
+	// 2. pods with networkHost=true
+	users int
+}
+
+// Tracer parses the TLS handshakes seen on raw sockets opened in the
+// network namespaces of the containers. Unlike snisnoop, the packets are
+// parsed in user space: a ServerHello has to be matched with the
+// ClientHello of the same connection to report the server name together
+// with the negotiated version and cipher.
+type Tracer struct {
+	mu sync.Mutex
+
+	config *Config
+
+	// key: namespace/podname
+	// value: link
+	attachments map[string]*link
+}
+
+func NewTracer(config *Config) (*Tracer, error) {
+	if len(config.Ports) == 0 {
+		return nil, errors.New("no TLS port to trace")
+	}
+
+	t := &Tracer{
+		config:      config,
+		attachments: make(map[string]*link),
+	}
+
+	return t, nil
+}
+
+func (t *Tracer) Attach(
+	key string,
+	pid uint32,
+	eventCallback func(types.Event),
+	node string,
+) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if l, ok := t.attachments[key]; ok {
+		l.users++
+		return nil
+	}
+
+	listener, err := rawsock.NewListener(pid, rawsock.TCPFilter)
+	if err != nil {
+		return err
+	}
+
+	l := &link{
+		listener: listener,
+		users:    1,
+	}
+	t.attachments[key] = l
+
+	go t.listen(key, l, eventCallback, node)
+
+	return nil
+}
+
+func joinHostPort(ip net.IP, port uint16) string {
+	return net.JoinHostPort(ip.String(), strconv.Itoa(int(port)))
+}
+
+// listen reads the frames received on the raw socket until the link is
+// released.
+func (t *Tracer) listen(
+	key string,
+	l *link,
+	eventCallback func(types.Event),
+	node string,
+) {
+	pending := make(map[connection]pendingHello)
+
+	expireHellos := func() {
+		now := time.Now()
+		for conn, hello := range pending {
+			if now.Sub(hello.timestamp) >= HandshakeTimeout {
+				eventCallback(hello.event)
+				delete(pending, conn)
+			}
+		}
+	}
+	handleFrame := func(frame []byte, _ uint8) {
+		t.handleFrame(frame, pending, eventCallback, node)
+	}
+
+	if err := l.listener.Run(expireHellos, handleFrame); err != nil {
+		msg := fmt.Sprintf("failed to listen on raw socket (%s): %s", key, err)
+		eventCallback(types.Base(eventtypes.Err(msg, node)))
+	}
+}
+
+func (t *Tracer) handleFrame(
+	frame []byte,
+	pending map[connection]pendingHello,
+	eventCallback func(types.Event),
+	node string,
+) {
+	p, ok := rawsock.ParsePacket(frame)
+	if !ok || len(p.Payload) == 0 {
+		return
+	}
+
+	_, toServer := t.config.Ports[p.Dport]
+	_, fromServer := t.config.Ports[p.Sport]
+	if !toServer && !fromServer {
+		return
+	}
+
+	// Packets sent to the server are also used as-is when both ports are
+	// TLS ports.
+	clientAddr, clientPort := p.Saddr, p.Sport
+	serverAddr, serverPort := p.Daddr, p.Dport
+	if !toServer {
+		clientAddr, clientPort = p.Daddr, p.Dport
+		serverAddr, serverPort = p.Saddr, p.Sport
+	}
+
+	event := types.Event{
+		Event: eventtypes.Event{
+			Type: eventtypes.NORMAL,
+			Node: node,
+		},
+		Saddr: clientAddr.String(),
+		Sport: clientPort,
+		Daddr: serverAddr.String(),
+		Dport: serverPort,
+	}
+	conn := connection{
+		client: joinHostPort(clientAddr, clientPort),
+		server: joinHostPort(serverAddr, serverPort),
+	}
+
+	if toServer {
+		if method := parseHTTPRequest(p.Payload); method != "" {
+			event.Plaintext = true
+			event.Method = method
+			eventCallback(event)
+			return
+		}
+	}
+
+	h, err := parseHandshake(p.Payload)
+	if err != nil || h == nil {
+		return
+	}
+
+	switch {
+	case h.typ == handshakeTypeClientHello && toServer:
+		event.Name = h.serverName
+		pending[conn] = pendingHello{event: event, timestamp: time.Now()}
+	case h.typ == handshakeTypeServerHello && !toServer:
+		if hello, ok := pending[conn]; ok {
+			event.Name = hello.event.Name
+			delete(pending, conn)
+		}
+		event.Version = versionName(h.version)
+		event.Cipher = cipherName(h.cipher)
+		eventCallback(event)
+	}
+}
+
+// releaseLink stops the listener of the link. It must be called with t.mu
+// held.
+func (t *Tracer) releaseLink(key string, l *link) {
+	l.listener.Close()
+	delete(t.attachments, key)
+}
+
+func (t *Tracer) Detach(key string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if l, ok := t.attachments[key]; ok {
+		l.users--
+		if l.users == 0 {
+			t.releaseLink(key, l)
+		}
+		return nil
+	} else {
+		return fmt.Errorf("key not attached: %q", key)
+	}
+}
+
+func (t *Tracer) Close() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for key, l := range t.attachments {
+		t.releaseLink(key, l)
+	}
+}
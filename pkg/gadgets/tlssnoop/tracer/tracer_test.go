@@ -0,0 +1,164 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"io"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+func newCertificate(t *testing.T) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %s", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "example.com"},
+		DNSNames:     []string{"example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %s", err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// readRecord reads a TLS record from r.
+func readRecord(t *testing.T, r io.Reader) []byte {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(r, header); err != nil {
+		t.Fatalf("failed to read record header: %s", err)
+	}
+	body := make([]byte, binary.BigEndian.Uint16(header[3:5]))
+	if _, err := io.ReadFull(r, body); err != nil {
+		t.Fatalf("failed to read record: %s", err)
+	}
+	return append(header, body...)
+}
+
+// captureHellos starts a handshake between a client and a server with the
+// given maximum versions and returns the first record sent by each of them.
+func captureHellos(t *testing.T, maxVersion uint16) (clientHello, serverHello []byte) {
+	clientConn, clientWire := net.Pipe()
+	serverWire, serverConn := net.Pipe()
+	defer clientConn.Close()
+	defer serverConn.Close()
+	defer clientWire.Close()
+	defer serverWire.Close()
+
+	client := tls.Client(clientConn, &tls.Config{
+		ServerName:         "example.com",
+		InsecureSkipVerify: true,
+		MaxVersion:         maxVersion,
+	})
+	server := tls.Server(serverConn, &tls.Config{
+		Certificates: []tls.Certificate{newCertificate(t)},
+		MaxVersion:   maxVersion,
+	})
+	go client.Handshake()
+	go server.Handshake()
+
+	clientHello = readRecord(t, clientWire)
+	if _, err := serverWire.Write(clientHello); err != nil {
+		t.Fatalf("failed to forward ClientHello: %s", err)
+	}
+	serverHello = readRecord(t, serverWire)
+
+	return clientHello, serverHello
+}
+
+func TestParseHandshake(t *testing.T) {
+	for _, version := range []uint16{tls.VersionTLS12, tls.VersionTLS13} {
+		clientHello, serverHello := captureHellos(t, version)
+
+		h, err := parseHandshake(clientHello)
+		if err != nil || h == nil {
+			t.Fatalf("failed to parse ClientHello: %v", err)
+		}
+		if h.typ != handshakeTypeClientHello || h.serverName != "example.com" {
+			t.Fatalf("unexpected ClientHello: %+v", h)
+		}
+
+		// Only the beginning of a ClientHello split in several
+		// segments is seen.
+		h, err = parseHandshake(clientHello[:len(clientHello)-10])
+		if err != nil || h == nil || h.serverName != "example.com" {
+			t.Fatalf("failed to parse truncated ClientHello: %+v, %v", h, err)
+		}
+
+		h, err = parseHandshake(serverHello)
+		if err != nil || h == nil {
+			t.Fatalf("failed to parse ServerHello: %v", err)
+		}
+		if h.typ != handshakeTypeServerHello || h.version != version {
+			t.Fatalf("unexpected ServerHello for %s: %+v", versionName(version), h)
+		}
+		if cipherName(h.cipher) == "" {
+			t.Fatalf("no cipher in ServerHello: %+v", h)
+		}
+	}
+}
+
+func TestParseHandshakeIgnored(t *testing.T) {
+	table := [][]byte{
+		nil,
+		[]byte("GET / HTTP/1.1\r\n"),
+		// Application data record
+		{0x17, 0x03, 0x03, 0x00, 0x04, 0x01, 0x00, 0x00, 0x00},
+		// Finished handshake message
+		{0x16, 0x03, 0x03, 0x00, 0x04, 0x14, 0x00, 0x00, 0x00},
+	}
+
+	for i, payload := range table {
+		if h, err := parseHandshake(payload); h != nil || err != nil {
+			t.Fatalf("payload %d: expected to be ignored, got %+v, %v", i, h, err)
+		}
+	}
+}
+
+func TestParseHTTPRequest(t *testing.T) {
+	table := []struct {
+		payload string
+		method  string
+	}{
+		{"GET / HTTP/1.1\r\nHost: example.com\r\n\r\n", "GET"},
+		{"POST /api HTTP/1.0\r\n", "POST"},
+		{"HTTP/1.1 200 OK\r\n", ""},
+		{"GETTING / HTTP/1.1\r\n", ""},
+		{"GET /\r\n", ""},
+		{"\x16\x03\x01\x02\x00", ""},
+	}
+
+	for _, entry := range table {
+		if method := parseHTTPRequest([]byte(entry.payload)); method != entry.method {
+			t.Fatalf("parseHTTPRequest(%q) = %q, expected %q", entry.payload, method, entry.method)
+		}
+	}
+}
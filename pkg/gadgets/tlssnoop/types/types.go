@@ -0,0 +1,59 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+const (
+	// PortsParam is the trace parameter holding the comma-separated list
+	// of TCP ports where TLS is expected.
+	PortsParam = "ports"
+
+	// PortsDefault are the ports used when PortsParam isn't set.
+	PortsDefault = "443,6443,8443"
+)
+
+type Event struct {
+	eventtypes.Event
+
+	// Saddr and Sport identify the client side of the connection, Daddr
+	// and Dport the server side.
+	Saddr string `json:"saddr,omitempty"`
+	Sport uint16 `json:"sport,omitempty"`
+	Daddr string `json:"daddr,omitempty"`
+	Dport uint16 `json:"dport,omitempty"`
+
+	// Name is the Server Name Indication sent by the client.
+	Name string `json:"name,omitempty"`
+
+	// Version and Cipher are the TLS version and cipher suite negotiated
+	// by the server. They are empty if the server didn't answer the
+	// ClientHello.
+	Version string `json:"version,omitempty"`
+	Cipher  string `json:"cipher,omitempty"`
+
+	// Plaintext is set when a plaintext HTTP request was sent to one of
+	// the TLS ports. Method is the HTTP method of that request.
+	Plaintext bool   `json:"plaintext,omitempty"`
+	Method    string `json:"method,omitempty"`
+}
+
+func Base(ev eventtypes.Event) Event {
+	return Event{
+		Event: ev,
+	}
+}
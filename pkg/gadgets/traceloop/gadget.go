@@ -24,6 +24,7 @@ import (
 	"time"
 
 	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	"github.com/kinvolk/inspektor-gadget/pkg/bpferror"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
 )
 
@@ -135,7 +136,7 @@ func (t *Trace) Start(trace *gadgetv1alpha1.Trace) {
 	t.cmd.Stderr = os.Stderr
 	err := t.cmd.Start()
 	if err != nil {
-		trace.Status.OperationError = fmt.Sprintf("Failed to start: %s", err)
+		trace.Status.OperationError = fmt.Sprintf("Failed to start: %s", bpferror.Describe(err))
 		return
 	}
 	t.started = true
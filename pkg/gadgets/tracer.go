@@ -0,0 +1,45 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgets
+
+import (
+	log "github.com/sirupsen/logrus"
+)
+
+// Tracer is implemented by the tracers of all the gadgets. Tracers are
+// created from a configuration and a callback receiving the events, they
+// don't depend on the Trace custom resource and can be embedded in other
+// programs.
+type Tracer interface {
+	Stop()
+}
+
+// NewTracerWithFallback creates a tracer with newCore and, if the CO-RE
+// tracer can't be created, e.g. because the kernel doesn't provide BTF
+// information, with newStandard.
+func NewTracerWithFallback(name string, newCore, newStandard func() (Tracer, error)) (Tracer, error) {
+	t, err := newCore()
+	if err == nil {
+		return t, nil
+	}
+
+	log.Infof("Gadget %s: falling back to standard tracer. CO-RE tracer failed: %s", name, err)
+
+	t, err = newStandard()
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
@@ -0,0 +1,205 @@
+// SPDX-License-Identifier: GPL-2.0
+#include <vmlinux/vmlinux.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_endian.h>
+#include <bpf/bpf_tracing.h>
+
+#include "udpsnoop.h"
+
+/* Define here, because there are conflicts with include files */
+#define AF_INET		2
+#define AF_INET6	10
+#define MSG_TRUNC	0x20
+
+#define MAX_ENTRIES	10240
+
+const volatile bool filter_by_mnt_ns = false;
+
+struct call {
+	struct sock *sk;
+	struct msghdr *msg;
+};
+
+/* Calls in progress, by thread */
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, __u32);
+	__type(value, struct call);
+} calls SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_PERF_EVENT_ARRAY);
+	__uint(key_size, sizeof(u32));
+	__uint(value_size, sizeof(u32));
+} events SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, 1024);
+	__uint(key_size, sizeof(u64));
+	__uint(value_size, sizeof(u32));
+} mount_ns_set SEC(".maps");
+
+static __always_inline u64 get_mntns_id(void)
+{
+	struct task_struct *task = (struct task_struct*)bpf_get_current_task();
+
+	return (u64) BPF_CORE_READ(task, nsproxy, mnt_ns, ns.inum);
+}
+
+static int probe_entry(struct sock *sk, struct msghdr *msg)
+{
+	__u32 tid = (__u32)bpf_get_current_pid_tgid();
+	struct call call = {
+		.sk = sk,
+		.msg = msg,
+	};
+	u64 mntns_id;
+
+	mntns_id = get_mntns_id();
+	if (filter_by_mnt_ns && !bpf_map_lookup_elem(&mount_ns_set, &mntns_id))
+		return 0;
+
+	bpf_map_update_elem(&calls, &tid, &call, BPF_ANY);
+	return 0;
+}
+
+/* read_peer reads the address of the peer from the msg_name of the message,
+ * set by the sender of an unconnected socket or filled by recvmsg(). Its
+ * length isn't set yet when udp_recvmsg() returns, so the family of the
+ * address is used instead. */
+static bool read_peer(struct event *event, struct msghdr *msg)
+{
+	struct sockaddr_in6 sin6 = {};
+	struct sockaddr_in *sin = (struct sockaddr_in *)&sin6;
+	void *name;
+
+	name = BPF_CORE_READ(msg, msg_name);
+	if (!name || bpf_probe_read_kernel(&sin6, sizeof(sin6), name))
+		return false;
+
+	switch (sin6.sin6_family) {
+	case AF_INET:
+		if (event->af == AF_INET6) {
+			/* IPv4-mapped IPv6 address */
+			event->daddr[10] = 0xff;
+			event->daddr[11] = 0xff;
+			__builtin_memcpy(&event->daddr[12], &sin->sin_addr, 4);
+		} else {
+			__builtin_memcpy(event->daddr, &sin->sin_addr, 4);
+		}
+		event->dport = bpf_ntohs(sin->sin_port);
+		return true;
+	case AF_INET6:
+		__builtin_memcpy(event->daddr, &sin6.sin6_addr, 16);
+		event->dport = bpf_ntohs(sin6.sin6_port);
+		return true;
+	}
+
+	return false;
+}
+
+static int probe_exit(void *ctx, enum udp_op op, int ret)
+{
+	__u64 pid_tgid = bpf_get_current_pid_tgid();
+	__u32 tid = (__u32)pid_tgid;
+	struct event event = {};
+	struct call *callp;
+	struct msghdr *msg;
+	struct sock *sk;
+
+	callp = bpf_map_lookup_elem(&calls, &tid);
+	if (!callp)
+		return 0;
+	sk = callp->sk;
+	msg = callp->msg;
+	bpf_map_delete_elem(&calls, &tid);
+
+	event.af = BPF_CORE_READ(sk, __sk_common.skc_family);
+	if (event.af == AF_INET) {
+		BPF_CORE_READ_INTO(event.saddr, sk, __sk_common.skc_rcv_saddr);
+	} else if (event.af == AF_INET6) {
+		BPF_CORE_READ_INTO(event.saddr, sk,
+				   __sk_common.skc_v6_rcv_saddr.in6_u.u6_addr8);
+	} else {
+		return 0;
+	}
+	event.sport = BPF_CORE_READ(sk, __sk_common.skc_num);
+
+	/* The connected sockets don't need a msg_name, and it isn't filled
+	 * when recvmsg() fails. */
+	if ((op == RECV && ret < 0) || !read_peer(&event, msg)) {
+		if (event.af == AF_INET)
+			BPF_CORE_READ_INTO(event.daddr, sk, __sk_common.skc_daddr);
+		else
+			BPF_CORE_READ_INTO(event.daddr, sk,
+					   __sk_common.skc_v6_daddr.in6_u.u6_addr8);
+		event.dport = bpf_ntohs(BPF_CORE_READ(sk, __sk_common.skc_dport));
+	}
+
+	/* recvmsg() sets MSG_TRUNC when the datagram didn't fit in the
+	 * buffer: the rest of it is lost. */
+	if (op == RECV && ret >= 0)
+		event.truncated = !!(BPF_CORE_READ(msg, msg_flags) & MSG_TRUNC);
+
+	event.ret = ret;
+	event.op = op;
+	event.pid = pid_tgid >> 32;
+	event.mntns_id = get_mntns_id();
+	bpf_get_current_comm(&event.task, sizeof(event.task));
+
+	bpf_perf_event_output(ctx, &events, BPF_F_CURRENT_CPU, &event, sizeof(event));
+	return 0;
+}
+
+SEC("kprobe/udp_sendmsg")
+int BPF_KPROBE(ig_udp_sendmsg_e, struct sock *sk, struct msghdr *msg)
+{
+	return probe_entry(sk, msg);
+}
+
+SEC("kretprobe/udp_sendmsg")
+int BPF_KRETPROBE(ig_udp_sendmsg_x, int ret)
+{
+	return probe_exit(ctx, SEND, ret);
+}
+
+SEC("kprobe/udp_recvmsg")
+int BPF_KPROBE(ig_udp_recvmsg_e, struct sock *sk, struct msghdr *msg)
+{
+	return probe_entry(sk, msg);
+}
+
+SEC("kretprobe/udp_recvmsg")
+int BPF_KRETPROBE(ig_udp_recvmsg_x, int ret)
+{
+	return probe_exit(ctx, RECV, ret);
+}
+
+SEC("kprobe/udpv6_sendmsg")
+int BPF_KPROBE(ig_udp6_sendmsg_e, struct sock *sk, struct msghdr *msg)
+{
+	return probe_entry(sk, msg);
+}
+
+SEC("kretprobe/udpv6_sendmsg")
+int BPF_KRETPROBE(ig_udp6_sendmsg_x, int ret)
+{
+	return probe_exit(ctx, SEND, ret);
+}
+
+SEC("kprobe/udpv6_recvmsg")
+int BPF_KPROBE(ig_udp6_recvmsg_e, struct sock *sk, struct msghdr *msg)
+{
+	return probe_entry(sk, msg);
+}
+
+SEC("kretprobe/udpv6_recvmsg")
+int BPF_KRETPROBE(ig_udp6_recvmsg_x, int ret)
+{
+	return probe_exit(ctx, RECV, ret);
+}
+
+char LICENSE[] SEC("license") = "GPL";
@@ -0,0 +1,29 @@
+/* SPDX-License-Identifier: (LGPL-2.1 OR BSD-2-Clause) */
+#ifndef __UDPSNOOP_H
+#define __UDPSNOOP_H
+
+#define TASK_COMM_LEN	16
+
+enum udp_op {
+	SEND,
+	RECV,
+};
+
+/* Addresses are stored in the first 4 bytes of saddr and daddr for
+ * IPv4. saddr and sport are the local ones, daddr and dport the ones of
+ * the peer. */
+struct event {
+	__u8 saddr[16];
+	__u8 daddr[16];
+	__u64 mntns_id;
+	__s32 ret; // bytes sent or received, or -errno
+	__u32 pid;
+	__u16 af; // AF_INET or AF_INET6
+	__u16 sport;
+	__u16 dport;
+	__u8 op;
+	__u8 truncated;
+	char task[TASK_COMM_LEN];
+};
+
+#endif /* __UDPSNOOP_H */
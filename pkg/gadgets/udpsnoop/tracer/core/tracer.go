@@ -0,0 +1,231 @@
+//go:build linux
+// +build linux
+
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+// #include <linux/types.h>
+// #include "./bpf/udpsnoop.h"
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+	"github.com/cilium/ebpf/perf"
+	"golang.org/x/sys/unix"
+
+	containercollection "github.com/kinvolk/inspektor-gadget/pkg/container-collection"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/udpsnoop/tracer"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/udpsnoop/types"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+//go:generate sh -c "GOOS=$(go env GOHOSTOS) GOARCH=$(go env GOHOSTARCH) go run github.com/cilium/ebpf/cmd/bpf2go -no-global-types -target bpfel -cc clang udpsnoop ./bpf/udpsnoop.bpf.c -- -I./bpf/ -I../../../../ -target bpf -D__TARGET_ARCH_x86"
+
+type Tracer struct {
+	config        *tracer.Config
+	resolver      containercollection.ContainerResolver
+	eventCallback func(types.Event)
+	node          string
+
+	objs   udpsnoopObjects
+	links  []link.Link
+	reader *perf.Reader
+}
+
+func NewTracer(config *tracer.Config, resolver containercollection.ContainerResolver,
+	eventCallback func(types.Event), node string) (*Tracer, error) {
+	t := &Tracer{
+		config:        config,
+		resolver:      resolver,
+		eventCallback: eventCallback,
+		node:          node,
+	}
+
+	if err := t.start(); err != nil {
+		t.Stop()
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (t *Tracer) Stop() {
+	for i := range t.links {
+		t.links[i] = gadgets.CloseLink(t.links[i])
+	}
+	t.links = nil
+
+	if t.reader != nil {
+		t.reader.Close()
+		t.reader = nil
+	}
+
+	t.objs.Close()
+}
+
+func (t *Tracer) start() error {
+	spec, err := loadUdpsnoop()
+	if err != nil {
+		return fmt.Errorf("failed to load ebpf program: %w", err)
+	}
+
+	filterByMntNs := false
+
+	if t.config.MountnsMap != "" {
+		filterByMntNs = true
+		m := spec.Maps["mount_ns_set"]
+		m.Pinning = ebpf.PinByName
+		m.Name = filepath.Base(t.config.MountnsMap)
+	}
+
+	consts := map[string]interface{}{
+		"filter_by_mnt_ns": filterByMntNs,
+	}
+
+	if err := spec.RewriteConstants(consts); err != nil {
+		return fmt.Errorf("error RewriteConstants: %w", err)
+	}
+
+	opts := ebpf.CollectionOptions{
+		Maps: ebpf.MapOptions{
+			PinPath: filepath.Dir(t.config.MountnsMap),
+		},
+	}
+
+	if err := spec.LoadAndAssign(&t.objs, &opts); err != nil {
+		return fmt.Errorf("failed to load ebpf program: %w", err)
+	}
+
+	kprobes := []struct {
+		symbol string
+		entry  *ebpf.Program
+		exit   *ebpf.Program
+	}{
+		{"udp_sendmsg", t.objs.IgUdpSendmsgE, t.objs.IgUdpSendmsgX},
+		{"udp_recvmsg", t.objs.IgUdpRecvmsgE, t.objs.IgUdpRecvmsgX},
+		{"udpv6_sendmsg", t.objs.IgUdp6SendmsgE, t.objs.IgUdp6SendmsgX},
+		{"udpv6_recvmsg", t.objs.IgUdp6RecvmsgE, t.objs.IgUdp6RecvmsgX},
+	}
+	for _, k := range kprobes {
+		l, err := link.Kprobe(k.symbol, k.entry, nil)
+		if err != nil {
+			return fmt.Errorf("error attaching program: %w", err)
+		}
+		t.links = append(t.links, l)
+
+		l, err = link.Kretprobe(k.symbol, k.exit, nil)
+		if err != nil {
+			return fmt.Errorf("error attaching program: %w", err)
+		}
+		t.links = append(t.links, l)
+	}
+
+	t.reader, err = perf.NewReader(t.objs.udpsnoopMaps.Events, gadgets.PerfBufferPages*os.Getpagesize())
+	if err != nil {
+		return fmt.Errorf("error creating perf ring buffer: %w", err)
+	}
+
+	go t.run()
+
+	return nil
+}
+
+var ops = []string{"send", "recv"}
+
+// errorName returns the name of an errno, like EAGAIN, or its description if
+// it has no name.
+func errorName(errno unix.Errno) string {
+	if name := unix.ErrnoName(errno); name != "" {
+		return name
+	}
+	return errno.Error()
+}
+
+func (t *Tracer) run() {
+	for {
+		record, err := t.reader.Read()
+		if err != nil {
+			if errors.Is(err, perf.ErrClosed) {
+				// nothing to do, we're done
+				return
+			}
+			msg := fmt.Sprintf("Error reading perf ring buffer: %s", err)
+			t.eventCallback(types.Base(eventtypes.Err(msg, t.node)))
+			return
+		}
+
+		if record.LostSamples > 0 {
+			msg := fmt.Sprintf("lost %d samples", record.LostSamples)
+			t.eventCallback(types.Base(eventtypes.Warn(msg, t.node)))
+			continue
+		}
+
+		eventC := (*C.struct_event)(unsafe.Pointer(&record.RawSample[0]))
+
+		event := types.Event{
+			Event: eventtypes.Event{
+				Type: eventtypes.NORMAL,
+				Node: t.node,
+			},
+			MountNsID: uint64(eventC.mntns_id),
+			Pid:       uint32(eventC.pid),
+			Comm:      C.GoString(&eventC.task[0]),
+			Op:        ops[int(eventC.op)],
+			Sport:     uint16(eventC.sport),
+			Dport:     uint16(eventC.dport),
+			Truncated: eventC.truncated != 0,
+		}
+
+		if ret := int32(eventC.ret); ret < 0 {
+			event.Error = errorName(unix.Errno(-ret))
+		} else {
+			event.Bytes = uint32(ret)
+		}
+
+		saddr := C.GoBytes(unsafe.Pointer(&eventC.saddr[0]), 16)
+		daddr := C.GoBytes(unsafe.Pointer(&eventC.daddr[0]), 16)
+
+		switch eventC.af {
+		case unix.AF_INET:
+			event.IPVersion = 4
+			event.Saddr = net.IP(saddr[:4]).String()
+			event.Daddr = net.IP(daddr[:4]).String()
+		case unix.AF_INET6:
+			event.IPVersion = 6
+			event.Saddr = net.IP(saddr).String()
+			event.Daddr = net.IP(daddr).String()
+		}
+
+		container := t.resolver.LookupContainerByMntns(event.MountNsID)
+		if container != nil {
+			event.Container = container.Name
+			event.Pod = container.Podname
+			event.Sandbox = container.Sandbox
+			event.Namespace = container.Namespace
+		}
+
+		t.eventCallback(event)
+	}
+}
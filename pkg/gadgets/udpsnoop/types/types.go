@@ -0,0 +1,53 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+type Event struct {
+	eventtypes.Event
+
+	MountNsID uint64 `json:"mountnsid,omitempty"`
+	Pid       uint32 `json:"pid,omitempty"`
+	Comm      string `json:"comm,omitempty"`
+
+	// Op is "send" or "recv".
+	Op        string `json:"op,omitempty"`
+	IPVersion int    `json:"ipversion,omitempty"`
+
+	// Saddr and Sport are the local address and port, Daddr and Dport
+	// the ones of the peer.
+	Saddr string `json:"saddr,omitempty"`
+	Daddr string `json:"daddr,omitempty"`
+	Sport uint16 `json:"sport,omitempty"`
+	Dport uint16 `json:"dport,omitempty"`
+
+	// Bytes is the number of bytes sent or received. When the datagram
+	// received didn't fit in the buffer, Truncated is set and Bytes is
+	// the size of the datagram.
+	Bytes     uint32 `json:"bytes,omitempty"`
+	Truncated bool   `json:"truncated,omitempty"`
+
+	// Error is the error returned by the system call, e.g. EAGAIN.
+	Error string `json:"error,omitempty"`
+}
+
+func Base(ev eventtypes.Event) Event {
+	return Event{
+		Event: ev,
+	}
+}
@@ -0,0 +1,178 @@
+// SPDX-License-Identifier: GPL-2.0
+// Copyright (c) 2022 The Inspektor Gadget authors
+#include <vmlinux/vmlinux.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_tracing.h>
+
+#include "udptop.h"
+
+/* Taken from kernel include/linux/socket.h. */
+#define AF_INET		2	/* Internet IP Protocol 	*/
+#define AF_INET6	10	/* IP version 6			*/
+
+const volatile pid_t target_pid = -1;
+const volatile int target_family = -1;
+const volatile bool filter_by_mnt_ns = false;
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, 10240);
+	__type(key, struct ip_key_t);
+	__type(value, struct traffic_t);
+} ip_map SEC(".maps");
+
+/* Key of the datagram being sent by a thread, until udp_sendmsg() returns */
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, 10240);
+	__type(key, u32);
+	__type(value, struct ip_key_t);
+} start SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, 1024);
+	__uint(key_size, sizeof(u64));
+	__uint(value_size, sizeof(u32));
+} mount_ns_set SEC(".maps");
+
+static __always_inline bool fill_key(struct ip_key_t *ip_key, struct sock *sk)
+{
+	struct task_struct *task;
+	u64 mntns_id;
+	u16 family;
+	u32 pid;
+
+	pid = bpf_get_current_pid_tgid() >> 32;
+	if (target_pid != -1 && target_pid != pid)
+		return false;
+
+	family = BPF_CORE_READ(sk, __sk_common.skc_family);
+	if (target_family != -1 && target_family != family)
+		return false;
+
+	/* drop */
+	if (family != AF_INET && family != AF_INET6)
+		return false;
+
+	task = (struct task_struct*) bpf_get_current_task();
+	mntns_id = (u64) BPF_CORE_READ(task, nsproxy, mnt_ns, ns.inum);
+
+	if (filter_by_mnt_ns && !bpf_map_lookup_elem(&mount_ns_set, &mntns_id))
+		return false;
+
+	ip_key->pid = pid;
+	bpf_get_current_comm(&ip_key->name, sizeof(ip_key->name));
+	ip_key->family = family;
+	ip_key->mntnsid = mntns_id;
+
+	return true;
+}
+
+static __always_inline void count(struct ip_key_t *ip_key, bool receiving, size_t size)
+{
+	struct traffic_t *trafficp;
+
+	trafficp = bpf_map_lookup_elem(&ip_map, ip_key);
+	if (!trafficp) {
+		struct traffic_t zero = {};
+
+		bpf_map_update_elem(&ip_map, ip_key, &zero, BPF_NOEXIST);
+		trafficp = bpf_map_lookup_elem(&ip_map, ip_key);
+		if (!trafficp)
+			return;
+	}
+
+	if (receiving) {
+		__sync_fetch_and_add(&trafficp->received, size);
+		__sync_fetch_and_add(&trafficp->received_packets, 1);
+	} else {
+		__sync_fetch_and_add(&trafficp->sent, size);
+		__sync_fetch_and_add(&trafficp->sent_packets, 1);
+	}
+}
+
+static __always_inline int probe_send_entry(struct sock *sk)
+{
+	u32 tid = bpf_get_current_pid_tgid();
+	struct ip_key_t ip_key = {};
+
+	if (!fill_key(&ip_key, sk))
+		return 0;
+
+	bpf_map_update_elem(&start, &tid, &ip_key, BPF_ANY);
+	return 0;
+}
+
+/* Only the datagrams actually sent are counted: the sends failing, e.g.
+ * with EAGAIN when the socket buffer is full, would inflate the traffic. */
+static __always_inline int probe_send_exit(int ret)
+{
+	u32 tid = bpf_get_current_pid_tgid();
+	struct ip_key_t *ip_key;
+
+	ip_key = bpf_map_lookup_elem(&start, &tid);
+	if (!ip_key)
+		return 0;
+
+	if (ret > 0)
+		count(ip_key, false, ret);
+
+	bpf_map_delete_elem(&start, &tid);
+	return 0;
+}
+
+/* udpv6_sendmsg() calls udp_sendmsg() for the IPv4-mapped addresses, the
+ * socket being an AF_INET6 one: udp_sendmsg() only counts the AF_INET
+ * sockets so that these datagrams are counted once. */
+SEC("kprobe/udp_sendmsg")
+int BPF_KPROBE(ig_udp_sendmsg_e, struct sock *sk)
+{
+	if (BPF_CORE_READ(sk, __sk_common.skc_family) != AF_INET)
+		return 0;
+
+	return probe_send_entry(sk);
+}
+
+SEC("kretprobe/udp_sendmsg")
+int BPF_KRETPROBE(ig_udp_sendmsg_x, int ret)
+{
+	return probe_send_exit(ret);
+}
+
+SEC("kprobe/udpv6_sendmsg")
+int BPF_KPROBE(ig_udpv6_sendmsg_e, struct sock *sk)
+{
+	return probe_send_entry(sk);
+}
+
+SEC("kretprobe/udpv6_sendmsg")
+int BPF_KRETPROBE(ig_udpv6_sendmsg_x, int ret)
+{
+	return probe_send_exit(ret);
+}
+
+/*
+ * skb_consume_udp() is called by both udp_recvmsg() and udpv6_recvmsg()
+ * with the socket and the number of bytes copied to the user space, so we
+ * don't need to trace their entry and return. The length is negative when
+ * the datagram is only peeked at with MSG_PEEK: it will be counted when
+ * it's read.
+ */
+SEC("kprobe/skb_consume_udp")
+int BPF_KPROBE(ig_skb_consume_udp, struct sock *sk, struct sk_buff *skb, int len)
+{
+	struct ip_key_t ip_key = {};
+
+	if (len < 0)
+		return 0;
+
+	if (!fill_key(&ip_key, sk))
+		return 0;
+
+	count(&ip_key, true, len);
+	return 0;
+}
+
+char LICENSE[] SEC("license") = "GPL";
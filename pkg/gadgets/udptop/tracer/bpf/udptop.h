@@ -0,0 +1,24 @@
+/* SPDX-License-Identifier: (LGPL-2.1 OR BSD-2-Clause) */
+#ifndef __UDPTOP_H
+#define __UDPTOP_H
+
+#define TASK_COMM_LEN 16
+
+/* The UDP traffic is aggregated per process and IP version: the local and
+ * remote ports of UDP sockets change too often to be part of the key, e.g.
+ * the resolvers use a new socket for each query. */
+struct ip_key_t {
+	__u64 mntnsid;
+	__u32 pid;
+	char name[TASK_COMM_LEN];
+	__u16 family;
+};
+
+struct traffic_t {
+	__u64 sent;
+	__u64 received;
+	__u64 sent_packets;
+	__u64 received_packets;
+};
+
+#endif /* __UDPTOP_H */
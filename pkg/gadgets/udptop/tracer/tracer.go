@@ -0,0 +1,255 @@
+//go:build linux
+// +build linux
+
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+
+	containercollection "github.com/kinvolk/inspektor-gadget/pkg/container-collection"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/threshold"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/udptop/types"
+	"github.com/kinvolk/inspektor-gadget/pkg/mapdump"
+)
+
+//go:generate sh -c "GOOS=$(go env GOHOSTOS) GOARCH=$(go env GOHOSTARCH) go run github.com/cilium/ebpf/cmd/bpf2go -no-global-types -target bpfel -cc clang udptop ./bpf/udptop.bpf.c -- -I./bpf/ -I../../.. -target bpf -D__TARGET_ARCH_x86"
+
+type Config struct {
+	TargetPid    int32
+	TargetFamily int32
+	MaxRows      int
+	Interval     time.Duration
+	SortBy       types.SortBy
+	// TODO: Make it a *ebpf.Map once
+	// https://github.com/cilium/ebpf/issues/515 and
+	// https://github.com/cilium/ebpf/issues/517 are fixed
+	MountnsMap string
+	Node       string
+
+	// Thresholds marks the rows crossing thresholds. These rows are
+	// reported even if they are not part of the first MaxRows ones.
+	Thresholds *threshold.Config
+}
+
+// ipKey and traffic are struct ip_key_t and struct traffic_t of
+// bpf/udptop.h.
+type ipKey struct {
+	MntnsID uint64
+	Pid     uint32
+	Name    [16]byte
+	Family  uint16
+	_       [2]byte
+}
+
+type traffic struct {
+	Sent            uint64
+	Received        uint64
+	SentPackets     uint64
+	ReceivedPackets uint64
+}
+
+type Tracer struct {
+	config        *Config
+	objs          udptopObjects
+	links         []link.Link
+	resolver      containercollection.ContainerResolver
+	statsCallback func([]types.Stats)
+	errorCallback func(error)
+	done          chan bool
+}
+
+func NewTracer(config *Config, resolver containercollection.ContainerResolver,
+	statsCallback func([]types.Stats), errorCallback func(error),
+) (*Tracer, error) {
+	t := &Tracer{
+		config:        config,
+		resolver:      resolver,
+		statsCallback: statsCallback,
+		errorCallback: errorCallback,
+		done:          make(chan bool),
+	}
+
+	if err := t.start(); err != nil {
+		t.Stop()
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (t *Tracer) Stop() {
+	close(t.done)
+
+	for i := range t.links {
+		t.links[i] = gadgets.CloseLink(t.links[i])
+	}
+
+	t.objs.Close()
+}
+
+// Maps returns the BPF maps of the tracer, so they can be dumped for
+// debugging.
+func (t *Tracer) Maps() map[string]*ebpf.Map {
+	return mapdump.MapsOf(&t.objs)
+}
+
+func (t *Tracer) start() error {
+	spec, err := loadUdptop()
+	if err != nil {
+		return fmt.Errorf("failed to load ebpf program: %w", err)
+	}
+
+	filterByMntNs := false
+
+	if t.config.MountnsMap != "" {
+		filterByMntNs = true
+		m := spec.Maps["mount_ns_set"]
+		m.Pinning = ebpf.PinByName
+		m.Name = filepath.Base(t.config.MountnsMap)
+	}
+
+	consts := map[string]interface{}{
+		"filter_by_mnt_ns": filterByMntNs,
+		"target_pid":       t.config.TargetPid,
+		"target_family":    t.config.TargetFamily,
+	}
+
+	if err := spec.RewriteConstants(consts); err != nil {
+		return fmt.Errorf("error RewriteConstants: %w", err)
+	}
+
+	opts := ebpf.CollectionOptions{
+		Maps: ebpf.MapOptions{
+			PinPath: filepath.Dir(t.config.MountnsMap),
+		},
+	}
+
+	if err := spec.LoadAndAssign(&t.objs, &opts); err != nil {
+		return fmt.Errorf("failed to load ebpf program: %w", err)
+	}
+
+	kprobes := []struct {
+		symbol string
+		prog   *ebpf.Program
+		ret    bool
+	}{
+		{"udp_sendmsg", t.objs.IgUdpSendmsgE, false},
+		{"udp_sendmsg", t.objs.IgUdpSendmsgX, true},
+		{"udpv6_sendmsg", t.objs.IgUdpv6SendmsgE, false},
+		{"udpv6_sendmsg", t.objs.IgUdpv6SendmsgX, true},
+		{"skb_consume_udp", t.objs.IgSkbConsumeUdp, false},
+	}
+
+	for _, kp := range kprobes {
+		var l link.Link
+		if kp.ret {
+			l, err = link.Kretprobe(kp.symbol, kp.prog, nil)
+		} else {
+			l, err = link.Kprobe(kp.symbol, kp.prog, nil)
+		}
+		if err != nil {
+			return fmt.Errorf("error opening kprobe %s: %w", kp.symbol, err)
+		}
+		t.links = append(t.links, l)
+	}
+
+	t.run()
+
+	return nil
+}
+
+func (t *Tracer) nextStats() ([]types.Stats, error) {
+	stats := []types.Stats{}
+	keys := []ipKey{}
+
+	var key ipKey
+	var value traffic
+
+	iter := t.objs.IpMap.Iterate()
+	for iter.Next(&key, &value) {
+		keys = append(keys, key)
+
+		stat := types.Stats{
+			MountNsID:       key.MntnsID,
+			Pid:             int32(key.Pid),
+			Comm:            string(bytes.TrimRight(key.Name[:], "\x00")),
+			Family:          key.Family,
+			Sent:            value.Sent,
+			Received:        value.Received,
+			SentPackets:     value.SentPackets,
+			ReceivedPackets: value.ReceivedPackets,
+		}
+
+		container := t.resolver.LookupContainerByMntns(stat.MountNsID)
+		if container != nil {
+			stat.Container = container.Name
+			stat.Pod = container.Podname
+			stat.Namespace = container.Namespace
+			stat.Node = t.config.Node
+		}
+
+		stats = append(stats, stat)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("error reading the UDP traffic: %w", err)
+	}
+
+	// The counters are reset every interval
+	for _, key := range keys {
+		t.objs.IpMap.Delete(key)
+	}
+
+	types.SortStats(stats, t.config.SortBy)
+
+	return stats, nil
+}
+
+func (t *Tracer) run() {
+	ticker := time.NewTicker(t.config.Interval)
+
+	go func() {
+		for {
+			select {
+			case <-t.done:
+				ticker.Stop()
+				return
+			case <-ticker.C:
+				stats, err := t.nextStats()
+				if err != nil {
+					t.errorCallback(err)
+					return
+				}
+
+				rows := []types.Stats{}
+				for i := range stats {
+					stats[i].Alerts = t.config.Thresholds.Check(&stats[i], t.config.Interval)
+					if i < t.config.MaxRows || len(stats[i].Alerts) > 0 {
+						rows = append(rows, stats[i])
+					}
+				}
+				t.statsCallback(rows)
+			}
+		}
+	}()
+}
@@ -0,0 +1,145 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"fmt"
+	"sort"
+	"syscall"
+)
+
+type SortBy int
+
+const (
+	ALL SortBy = iota
+	SENT
+	RECEIVED
+)
+
+const (
+	SortByAll      = "all"
+	SortBySent     = "sent"
+	SortByReceived = "received"
+)
+
+var SortBySlice = []string{SortByAll, SortBySent, SortByReceived}
+
+const (
+	MaxRowsDefault  = 20
+	IntervalDefault = 1
+	SortByDefault   = ALL
+)
+
+const (
+	IntervalParam = "interval"
+	MaxRowsParam  = "max_rows"
+	SortByParam   = "sort_by"
+	PidParam      = "pid"
+	FamilyParam   = "family"
+)
+
+func (s SortBy) String() string {
+	if int(s) < 0 || int(s) >= len(SortBySlice) {
+		return "INVALID"
+	}
+
+	return SortBySlice[int(s)]
+}
+
+func ParseSortBy(sortby string) (SortBy, error) {
+	for i, v := range SortBySlice {
+		if v == sortby {
+			return SortBy(i), nil
+		}
+	}
+	return ALL, fmt.Errorf("%q is not a valid sort by value", sortby)
+}
+
+func ParseFilterByFamily(family string) (int32, error) {
+	switch family {
+	case "4":
+		return syscall.AF_INET, nil
+	case "6":
+		return syscall.AF_INET6, nil
+	default:
+		return -1, fmt.Errorf("IP version is either 4 or 6, %s was given", family)
+	}
+}
+
+// Event is the information the gadget sends to the client each capture
+// interval
+type Event struct {
+	Error string `json:"error,omitempty"`
+
+	// Warning is set when rows crossed the thresholds during the interval
+	// and the warnings are enabled.
+	Warning string `json:"warning,omitempty"`
+
+	// Node where the event comes from.
+	Node string `json:"node,omitempty"`
+
+	// Timestamp is when the interval ended, in nanoseconds since the
+	// epoch.
+	Timestamp int64 `json:"timestamp,omitempty"`
+
+	Stats []Stats `json:"stats,omitempty"`
+}
+
+// Stats represents the UDP traffic of a process during the interval
+type Stats struct {
+	Node      string `json:"node,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Pod       string `json:"pod,omitempty"`
+	Container string `json:"container,omitempty"`
+
+	MountNsID       uint64 `json:"mountnsid,omitempty"`
+	Pid             int32  `json:"pid,omitempty"`
+	Comm            string `json:"comm,omitempty"`
+	Family          uint16 `json:"family,omitempty"`
+	Sent            uint64 `json:"sent,omitempty"`
+	Received        uint64 `json:"received,omitempty"`
+	SentPackets     uint64 `json:"sentPackets,omitempty"`
+	ReceivedPackets uint64 `json:"receivedPackets,omitempty"`
+
+	// Alerts are the thresholds crossed by the row during the interval.
+	Alerts []string `json:"alerts,omitempty"`
+}
+
+// SortStats sorts the stats by the bytes sent, received or both, the
+// packets being used to break the ties.
+func SortStats(stats []Stats, sortBy SortBy) {
+	sort.SliceStable(stats, func(i, j int) bool {
+		a := stats[i]
+		b := stats[j]
+
+		switch sortBy {
+		case SENT:
+			if a.Sent != b.Sent {
+				return a.Sent > b.Sent
+			}
+			return a.SentPackets > b.SentPackets
+		case RECEIVED:
+			if a.Received != b.Received {
+				return a.Received > b.Received
+			}
+			return a.ReceivedPackets > b.ReceivedPackets
+		default:
+			if a.Sent+a.Received != b.Sent+b.Received {
+				return a.Sent+a.Received > b.Sent+b.Received
+			}
+			return a.SentPackets+a.ReceivedPackets > b.SentPackets+b.ReceivedPackets
+		}
+	})
+}
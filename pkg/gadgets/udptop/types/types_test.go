@@ -0,0 +1,48 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"testing"
+)
+
+func TestSortStats(t *testing.T) {
+	stats := []Stats{
+		{Comm: "a", Sent: 100, Received: 0, SentPackets: 1},
+		{Comm: "b", Sent: 10, Received: 500, SentPackets: 1, ReceivedPackets: 5},
+		{Comm: "c", Sent: 100, Received: 50, SentPackets: 10, ReceivedPackets: 1},
+	}
+
+	table := []struct {
+		sortBy   SortBy
+		expected string
+	}{
+		{ALL, "bca"},
+		{SENT, "cab"},
+		{RECEIVED, "bca"},
+	}
+
+	for _, entry := range table {
+		SortStats(stats, entry.sortBy)
+
+		order := ""
+		for _, stat := range stats {
+			order += stat.Comm
+		}
+		if order != entry.expected {
+			t.Fatalf("SortStats(%s) = %s, expected %s", entry.sortBy, order, entry.expected)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+// SPDX-License-Identifier: GPL-2.0
+#include <vmlinux/vmlinux.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_tracing.h>
+
+#include "uprobe.h"
+
+#define MAX_ENTRIES 10240
+
+struct {
+	__uint(type, BPF_MAP_TYPE_PERF_EVENT_ARRAY);
+	__uint(key_size, sizeof(u32));
+	__uint(value_size, sizeof(u32));
+} events SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, 1024);
+	__uint(key_size, sizeof(u64));
+	__uint(value_size, sizeof(u32));
+} mount_ns_set SEC(".maps");
+
+/* The event is too large for the stack */
+struct {
+	__uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+	__uint(max_entries, 1);
+	__type(key, u32);
+	__type(value, struct event_t);
+} heap SEC(".maps");
+
+/* Events of the calls in progress, between the entry and the return of the
+ * function, when the return value is printed */
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, u64);
+	__type(value, struct event_t);
+	__uint(map_flags, BPF_F_NO_PREALLOC);
+} calls SEC(".maps");
+
+const volatile bool filter_by_mnt_ns = false;
+
+/* Bit i is set if the argument i, or the return value for RET_INDEX, is
+ * printed as a string */
+const volatile u32 string_args = 0;
+
+/* The event is sent when the function returns rather than when it's called */
+const volatile bool with_ret = false;
+
+static __always_inline void read_string(struct event_t *event, int i, u64 ptr)
+{
+	if (string_args & (1 << i))
+		bpf_probe_read_user_str(event->strings[i], MAX_STRING_LEN, (void *) ptr);
+}
+
+SEC("uprobe/ig_uprobe_e")
+int BPF_KPROBE(ig_uprobe_e)
+{
+	u64 pid_tgid = bpf_get_current_pid_tgid();
+	struct task_struct *task;
+	struct event_t *event;
+	u64 mntns_id;
+	u32 zero = 0;
+
+	task = (struct task_struct *) bpf_get_current_task();
+	mntns_id = (u64) BPF_CORE_READ(task, nsproxy, mnt_ns, ns.inum);
+
+	if (filter_by_mnt_ns && !bpf_map_lookup_elem(&mount_ns_set, &mntns_id))
+		return 0;
+
+	event = bpf_map_lookup_elem(&heap, &zero);
+	if (!event)
+		return 0;
+
+	event->mntns_id = mntns_id;
+	event->pid = pid_tgid >> 32;
+	event->tid = (u32) pid_tgid;
+	bpf_get_current_comm(&event->comm, sizeof(event->comm));
+	event->ret = 0;
+
+	event->args[0] = PT_REGS_PARM1(ctx);
+	event->args[1] = PT_REGS_PARM2(ctx);
+	event->args[2] = PT_REGS_PARM3(ctx);
+	event->args[3] = PT_REGS_PARM4(ctx);
+	event->args[4] = PT_REGS_PARM5(ctx);
+
+	#pragma unroll
+	for (int i = 0; i < MAX_ARGS; i++)
+		read_string(event, i, event->args[i]);
+	event->strings[RET_INDEX][0] = '\0';
+
+	if (with_ret) {
+		bpf_map_update_elem(&calls, &pid_tgid, event, BPF_ANY);
+		return 0;
+	}
+
+	bpf_perf_event_output(ctx, &events, BPF_F_CURRENT_CPU, event, sizeof(*event));
+	return 0;
+}
+
+SEC("uretprobe/ig_uprobe_x")
+int BPF_KRETPROBE(ig_uprobe_x)
+{
+	u64 pid_tgid = bpf_get_current_pid_tgid();
+	struct event_t *event;
+
+	event = bpf_map_lookup_elem(&calls, &pid_tgid);
+	if (!event)
+		return 0;
+
+	event->ret = PT_REGS_RC(ctx);
+	read_string(event, RET_INDEX, event->ret);
+
+	bpf_perf_event_output(ctx, &events, BPF_F_CURRENT_CPU, event, sizeof(*event));
+	bpf_map_delete_elem(&calls, &pid_tgid);
+	return 0;
+}
+
+char LICENSE[] SEC("license") = "GPL";
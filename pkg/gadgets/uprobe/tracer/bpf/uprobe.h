@@ -0,0 +1,27 @@
+/* SPDX-License-Identifier: (LGPL-2.1 OR BSD-2-Clause) */
+#ifndef __UPROBE_H
+#define __UPROBE_H
+
+#define TASK_COMM_LEN 16
+
+/* Number of arguments of the function that can be printed */
+#define MAX_ARGS 5
+
+/* Index of the return value in strings and in the string_args mask */
+#define RET_INDEX MAX_ARGS
+
+#define MAX_STRING_LEN 64
+
+struct event_t {
+	__u64 mntns_id;
+	__u32 pid;
+	__u32 tid;
+	__u8 comm[TASK_COMM_LEN];
+	__u64 args[MAX_ARGS];
+	__u64 ret;
+	/* Strings pointed by the arguments and the return value, when they are
+	 * printed as strings */
+	__u8 strings[MAX_ARGS + 1][MAX_STRING_LEN];
+};
+
+#endif /* __UPROBE_H */
@@ -0,0 +1,203 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MaxArgs is the number of arguments of the traced function that can be
+// printed.
+const MaxArgs = 5
+
+// retIndex is the index of the return value in Template.StringArgs.
+const retIndex = MaxArgs
+
+// Template is the format of the output of the events. The placeholders
+// {arg0} to {arg4}, {ret} and {symbol} are replaced by the arguments, the
+// return value and the name of the traced function. The arguments and the
+// return value can be printed with the formats :d (signed integer, the
+// default), :u (unsigned integer), :x (hexadecimal) and :str (string they
+// point to), e.g. "open({arg0:str}, {arg1:x}) = {ret}". "{{" and "}}" print
+// the braces.
+type Template struct {
+	parts []templatePart
+
+	// StringArgs has the bit i set if the argument i, or the return value
+	// for bit MaxArgs, is printed as a string.
+	StringArgs uint32
+
+	// WithRet is true if the return value is printed, in which case the
+	// events are reported when the function returns.
+	WithRet bool
+}
+
+type templatePart struct {
+	// literal is printed as is when value is empty.
+	literal string
+
+	// value is "arg", "ret" or "symbol" and index the index of the
+	// argument.
+	value  string
+	index  int
+	format string
+}
+
+// DefaultTemplate prints the first three arguments in hexadecimal.
+const DefaultTemplate = "{symbol}({arg0:x}, {arg1:x}, {arg2:x})"
+
+// ParseTemplate parses the output template s.
+func ParseTemplate(s string) (*Template, error) {
+	t := &Template{}
+	var literal strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		if (c == '{' || c == '}') && i+1 < len(s) && s[i+1] == c {
+			literal.WriteByte(c)
+			i++
+			continue
+		}
+		if c == '}' {
+			return nil, fmt.Errorf("unexpected '}' at position %d, use '}}' to print it", i)
+		}
+		if c != '{' {
+			literal.WriteByte(c)
+			continue
+		}
+
+		end := strings.IndexByte(s[i:], '}')
+		if end == -1 {
+			return nil, fmt.Errorf("unterminated placeholder at position %d", i)
+		}
+
+		part, err := parsePlaceholder(s[i+1 : i+end])
+		if err != nil {
+			return nil, err
+		}
+
+		if literal.Len() > 0 {
+			t.parts = append(t.parts, templatePart{literal: literal.String()})
+			literal.Reset()
+		}
+		t.parts = append(t.parts, part)
+
+		switch {
+		case part.value == "ret":
+			t.WithRet = true
+			if part.format == "str" {
+				t.StringArgs |= 1 << retIndex
+			}
+		case part.value == "arg" && part.format == "str":
+			t.StringArgs |= 1 << part.index
+		}
+
+		i += end
+	}
+
+	if literal.Len() > 0 {
+		t.parts = append(t.parts, templatePart{literal: literal.String()})
+	}
+
+	return t, nil
+}
+
+func parsePlaceholder(p string) (templatePart, error) {
+	name, format := p, "d"
+	if i := strings.IndexByte(p, ':'); i != -1 {
+		name, format = p[:i], p[i+1:]
+	}
+
+	part := templatePart{format: format}
+
+	switch {
+	case name == "symbol":
+		if strings.Contains(p, ":") {
+			return part, fmt.Errorf("{symbol} doesn't take a format")
+		}
+		part.value = "symbol"
+		return part, nil
+	case name == "ret":
+		part.value = "ret"
+	case strings.HasPrefix(name, "arg"):
+		index, err := strconv.Atoi(name[len("arg"):])
+		if err != nil || index < 0 || index >= MaxArgs {
+			return part, fmt.Errorf("invalid placeholder {%s}: only arg0 to arg%d are supported", p, MaxArgs-1)
+		}
+		part.value = "arg"
+		part.index = index
+	default:
+		return part, fmt.Errorf("unknown placeholder {%s}: expected argN, ret or symbol", p)
+	}
+
+	switch format {
+	case "d", "u", "x", "str":
+	default:
+		return part, fmt.Errorf("unknown format %q in {%s}: expected d, u, x or str", format, p)
+	}
+
+	return part, nil
+}
+
+// callValues are the values of a call of the traced function.
+type callValues struct {
+	symbol  string
+	args    [MaxArgs]uint64
+	ret     uint64
+	strings [MaxArgs + 1]string
+}
+
+// format returns the output of the call v.
+func (t *Template) format(v *callValues) string {
+	var sb strings.Builder
+
+	for _, part := range t.parts {
+		var value uint64
+		var str string
+
+		switch part.value {
+		case "":
+			sb.WriteString(part.literal)
+			continue
+		case "symbol":
+			sb.WriteString(v.symbol)
+			continue
+		case "arg":
+			value, str = v.args[part.index], v.strings[part.index]
+		case "ret":
+			value, str = v.ret, v.strings[retIndex]
+		}
+
+		switch part.format {
+		case "d":
+			sb.WriteString(strconv.FormatInt(int64(value), 10))
+		case "u":
+			sb.WriteString(strconv.FormatUint(value, 10))
+		case "x":
+			sb.WriteString("0x" + strconv.FormatUint(value, 16))
+		case "str":
+			if value == 0 {
+				sb.WriteString("(null)")
+			} else {
+				sb.WriteString(strconv.Quote(str))
+			}
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,247 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+// #include <linux/types.h>
+// #include "./bpf/uprobe.h"
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+	"github.com/cilium/ebpf/perf"
+	containercollection "github.com/kinvolk/inspektor-gadget/pkg/container-collection"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/containerbinary"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/uprobe/types"
+	pb "github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/api"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+//go:generate sh -c "GOOS=$(go env GOHOSTOS) GOARCH=$(go env GOHOSTARCH) go run github.com/cilium/ebpf/cmd/bpf2go -target bpfel -cc clang uprobe ./bpf/uprobe.bpf.c -- -I./bpf/ -I../../.. -target bpf -D__TARGET_ARCH_x86"
+
+type Config struct {
+	// TODO: Make it a *ebpf.Map once
+	// https://github.com/cilium/ebpf/issues/515 and
+	// https://github.com/cilium/ebpf/issues/517 are fixed
+	MountnsMap string
+
+	// Binary is the path of the executable or library in the containers
+	// and Symbol the function traced in it.
+	Binary string
+	Symbol string
+
+	Template *Template
+}
+
+// probe is the uprobe, and the uretprobe if the return value is read,
+// attached to a binary.
+type probe []link.Link
+
+func (p probe) Close() {
+	for _, l := range p {
+		gadgets.CloseLink(l)
+	}
+}
+
+type Tracer struct {
+	config        *Config
+	objs          uprobeObjects
+	reader        *perf.Reader
+	resolver      containercollection.ContainerResolver
+	eventCallback func(types.Event)
+	node          string
+
+	attacher *containerbinary.Attacher
+}
+
+func NewTracer(c *Config, resolver containercollection.ContainerResolver, eventCallback func(types.Event), node string) (*Tracer, error) {
+	t := &Tracer{
+		config:        c,
+		resolver:      resolver,
+		eventCallback: eventCallback,
+		node:          node,
+	}
+	t.attacher = containerbinary.NewAttacher(c.Binary, t.attach)
+
+	if err := t.start(); err != nil {
+		t.Stop()
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (t *Tracer) Stop() {
+	t.attacher.Close()
+
+	if t.reader != nil {
+		t.reader.Close()
+		t.reader = nil
+	}
+
+	t.objs.Close()
+}
+
+func (t *Tracer) start() error {
+	spec, err := loadUprobe()
+	if err != nil {
+		return fmt.Errorf("failed to load ebpf program: %w", err)
+	}
+
+	filterByMntNs := false
+
+	if t.config.MountnsMap != "" {
+		filterByMntNs = true
+		m := spec.Maps["mount_ns_set"]
+		m.Pinning = ebpf.PinByName
+		m.Name = filepath.Base(t.config.MountnsMap)
+	}
+
+	consts := map[string]interface{}{
+		"filter_by_mnt_ns": filterByMntNs,
+		"string_args":      t.config.Template.StringArgs,
+		"with_ret":         t.config.Template.WithRet,
+	}
+
+	if err := spec.RewriteConstants(consts); err != nil {
+		return fmt.Errorf("error RewriteConstants: %w", err)
+	}
+
+	opts := ebpf.CollectionOptions{
+		Maps: ebpf.MapOptions{
+			PinPath: filepath.Dir(t.config.MountnsMap),
+		},
+	}
+
+	if err := spec.LoadAndAssign(&t.objs, &opts); err != nil {
+		return fmt.Errorf("failed to load ebpf program: %w", err)
+	}
+
+	reader, err := perf.NewReader(t.objs.uprobeMaps.Events, gadgets.PerfBufferPages*os.Getpagesize())
+	if err != nil {
+		return fmt.Errorf("error creating perf ring buffer: %w", err)
+	}
+	t.reader = reader
+
+	go t.run()
+
+	return nil
+}
+
+// AddContainer attaches the probes to the binary of the container if they
+// aren't attached to the same file yet. It returns
+// containerbinary.ErrBinaryNotFound if the container doesn't have the
+// binary.
+func (t *Tracer) AddContainer(c *pb.ContainerDefinition) error {
+	return t.attacher.AddContainer(c.Pid, c.Mntns)
+}
+
+func (t *Tracer) attach(path string) (containerbinary.Probes, error) {
+	ex, err := link.OpenExecutable(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", t.config.Binary, err)
+	}
+
+	entry, err := ex.Uprobe(t.config.Symbol, t.objs.IgUprobeE, nil)
+	if err != nil {
+		return nil, fmt.Errorf("attaching uprobe to %s in %s: %w", t.config.Symbol, t.config.Binary, err)
+	}
+	p := probe{entry}
+
+	if t.config.Template.WithRet {
+		ret, err := ex.Uretprobe(t.config.Symbol, t.objs.IgUprobeX, nil)
+		if err != nil {
+			p.Close()
+			return nil, fmt.Errorf("attaching uretprobe to %s in %s: %w", t.config.Symbol, t.config.Binary, err)
+		}
+		p = append(p, ret)
+	}
+
+	return p, nil
+}
+
+// RemoveContainer detaches the probes of the binary of the container when
+// no other container uses it.
+func (t *Tracer) RemoveContainer(c *pb.ContainerDefinition) {
+	t.attacher.RemoveContainer(c.Mntns)
+}
+
+// Attached returns the number of binaries the probes are attached to.
+func (t *Tracer) Attached() int {
+	return t.attacher.Attached()
+}
+
+func (t *Tracer) run() {
+	for {
+		record, err := t.reader.Read()
+		if err != nil {
+			if errors.Is(err, perf.ErrClosed) {
+				return
+			}
+
+			msg := fmt.Sprintf("Error reading perf ring buffer: %s", err)
+			t.eventCallback(types.Base(eventtypes.Err(msg, t.node)))
+			return
+		}
+
+		if record.LostSamples > 0 {
+			msg := fmt.Sprintf("lost %d samples", record.LostSamples)
+			t.eventCallback(types.Base(eventtypes.Warn(msg, t.node)))
+			continue
+		}
+
+		eventC := (*C.struct_event_t)(unsafe.Pointer(&record.RawSample[0]))
+
+		values := callValues{
+			symbol: t.config.Symbol,
+			ret:    uint64(eventC.ret),
+		}
+		for i := 0; i < MaxArgs; i++ {
+			values.args[i] = uint64(eventC.args[i])
+		}
+		for i := 0; i <= MaxArgs; i++ {
+			values.strings[i] = C.GoString((*C.char)(unsafe.Pointer(&eventC.strings[i][0])))
+		}
+
+		event := types.Event{
+			Event: eventtypes.Event{
+				Type: eventtypes.NORMAL,
+				Node: t.node,
+			},
+			Pid:       uint32(eventC.pid),
+			Tid:       uint32(eventC.tid),
+			Comm:      C.GoString((*C.char)(unsafe.Pointer(&eventC.comm[0]))),
+			MountNsID: uint64(eventC.mntns_id),
+			Output:    t.config.Template.format(&values),
+		}
+
+		container := t.resolver.LookupContainerByMntns(event.MountNsID)
+		if container != nil {
+			event.Container = container.Name
+			event.Pod = container.Podname
+			event.Sandbox = container.Sandbox
+			event.Namespace = container.Namespace
+		}
+
+		t.eventCallback(event)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"testing"
+)
+
+func TestTemplate(t *testing.T) {
+	table := []struct {
+		template   string
+		output     string
+		stringArgs uint32
+		withRet    bool
+		err        bool
+	}{
+		{
+			template: DefaultTemplate,
+			output:   "open(0x7f00, 0x241, 0x1a4)",
+		},
+		{
+			template:   "{symbol}({arg0:str}, {arg1:u}) = {ret}",
+			output:     `open("/etc/passwd", 577) = -1`,
+			stringArgs: 1 << 0,
+			withRet:    true,
+		},
+		{
+			template:   "{{{ret:str}}} {arg3:str}",
+			output:     `{"ok"} (null)`,
+			stringArgs: 1<<retIndex | 1<<3,
+			withRet:    true,
+		},
+		{template: "{arg5}", err: true},
+		{template: "{foo}", err: true},
+		{template: "{arg0:s}", err: true},
+		{template: "{symbol:x}", err: true},
+		{template: "{arg0", err: true},
+		{template: "arg0}", err: true},
+	}
+
+	values := &callValues{
+		symbol:  "open",
+		args:    [MaxArgs]uint64{0x7f00, 0x241, 0x1a4, 0, 0},
+		ret:     ^uint64(0),
+		strings: [MaxArgs + 1]string{"/etc/passwd", "", "", "", "", "ok"},
+	}
+
+	for _, entry := range table {
+		tmpl, err := ParseTemplate(entry.template)
+		if entry.err {
+			if err == nil {
+				t.Errorf("%q: expected an error", entry.template)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", entry.template, err)
+			continue
+		}
+
+		if output := tmpl.format(values); output != entry.output {
+			t.Errorf("%q: expected %q, got %q", entry.template, entry.output, output)
+		}
+		if tmpl.StringArgs != entry.stringArgs || tmpl.WithRet != entry.withRet {
+			t.Errorf("%q: unexpected string args %b or return %t", entry.template, tmpl.StringArgs, tmpl.WithRet)
+		}
+	}
+}
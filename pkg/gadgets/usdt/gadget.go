@@ -0,0 +1,326 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package usdt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	"github.com/kinvolk/inspektor-gadget/pkg/bpferror"
+	containerutils "github.com/kinvolk/inspektor-gadget/pkg/container-utils"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/containerbinary"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/usdt/tracer"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/usdt/types"
+	pb "github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/api"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/pubsub"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+type Trace struct {
+	resolver gadgets.Resolver
+
+	started bool
+	tracer  *tracer.Tracer
+}
+
+type TraceFactory struct {
+	gadgets.BaseFactory
+}
+
+func NewFactory() gadgets.TraceFactory {
+	return &TraceFactory{
+		BaseFactory: gadgets.BaseFactory{DeleteTrace: deleteTrace},
+	}
+}
+
+func (f *TraceFactory) Description() string {
+	return `The usdt gadget lists the USDT (user statically-defined tracing) probes of an executable or a shared library of the containers and traces the hits of one of them, printing its arguments.`
+}
+
+func (f *TraceFactory) Parameters() []gadgets.GadgetParameter {
+	return []gadgets.GadgetParameter{
+		{
+			Name:        "binary",
+			Description: "Absolute path, in the containers, of the executable or shared library containing the probes",
+			Required:    true,
+		},
+		{
+			Name:        "probe",
+			Description: "Probe to trace, as provider:name. Required by the start operation",
+		},
+		{
+			Name:        "string_args",
+			Description: "Comma-separated indexes of the arguments read as strings",
+		},
+	}
+}
+
+func (f *TraceFactory) OutputModesSupported() map[string]struct{} {
+	return map[string]struct{}{
+		"Stream": {},
+		"Status": {},
+	}
+}
+
+func deleteTrace(name string, t interface{}) {
+	trace := t.(*Trace)
+	if trace.started {
+		trace.resolver.Unsubscribe(genPubSubKey(name))
+		trace.tracer.Stop()
+		trace.tracer = nil
+	}
+}
+
+func (f *TraceFactory) Operations() map[string]gadgets.TraceOperation {
+	n := func() interface{} {
+		return &Trace{
+			resolver: f.Resolver,
+		}
+	}
+
+	return map[string]gadgets.TraceOperation{
+		"start": {
+			Doc: "Start usdt gadget",
+			Operation: func(name string, trace *gadgetv1alpha1.Trace) {
+				f.LookupOrCreate(name, n).(*Trace).Start(trace)
+			},
+		},
+		"stop": {
+			Doc: "Stop usdt gadget",
+			Operation: func(name string, trace *gadgetv1alpha1.Trace) {
+				f.LookupOrCreate(name, n).(*Trace).Stop(trace)
+			},
+		},
+		"list": {
+			Doc: "List the probes of the binary in the selected containers",
+			Operation: func(name string, trace *gadgetv1alpha1.Trace) {
+				f.LookupOrCreate(name, n).(*Trace).List(trace)
+			},
+		},
+	}
+}
+
+type pubSubKey string
+
+func genPubSubKey(name string) pubSubKey {
+	return pubSubKey(fmt.Sprintf("gadget/usdt/%s", name))
+}
+
+func parseStringArgs(value string) (uint32, error) {
+	var mask uint32
+	for _, s := range strings.Split(value, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		i, err := strconv.Atoi(s)
+		if err != nil || i < 0 || i >= tracer.MaxArgs {
+			return 0, fmt.Errorf("%q is not an argument index between 0 and %d", s, tracer.MaxArgs-1)
+		}
+		mask |= 1 << i
+	}
+	return mask, nil
+}
+
+func (t *Trace) Start(trace *gadgetv1alpha1.Trace) {
+	if t.started {
+		trace.Status.State = "Started"
+		return
+	}
+
+	params := trace.Spec.Parameters
+
+	binary := params["binary"]
+	if binary == "" || !filepath.IsAbs(binary) {
+		trace.Status.OperationError = "binary must be set to an absolute path"
+		return
+	}
+
+	parts := strings.SplitN(params["probe"], ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		trace.Status.OperationError = "probe must be set to provider:name"
+		return
+	}
+
+	stringArgs, err := parseStringArgs(params["string_args"])
+	if err != nil {
+		trace.Status.OperationError = fmt.Sprintf("invalid string_args: %s", err)
+		return
+	}
+
+	traceName := gadgets.TraceName(trace.ObjectMeta.Namespace, trace.ObjectMeta.Name)
+
+	eventCallback := func(event types.Event) {
+		r, err := json.Marshal(event)
+		if err != nil {
+			fmt.Printf("error marshalling event: %s\n", err)
+			return
+		}
+		t.resolver.PublishEvent(traceName, string(r))
+	}
+
+	config := &tracer.Config{
+		MountnsMap: gadgets.TracePinPath(trace.ObjectMeta.Namespace, trace.ObjectMeta.Name),
+		Binary:     filepath.Clean(binary),
+		Provider:   parts[0],
+		Name:       parts[1],
+		StringArgs: stringArgs,
+	}
+	t.tracer, err = tracer.NewTracer(config, t.resolver, eventCallback, trace.Spec.Node)
+	if err != nil {
+		trace.Status.OperationError = fmt.Sprintf("failed to create tracer: %s", bpferror.Describe(err))
+		return
+	}
+
+	addContainer := func(container *pb.ContainerDefinition) {
+		err := t.tracer.AddContainer(container)
+		if err != nil && !errors.Is(err, containerbinary.ErrBinaryNotFound) {
+			msg := fmt.Sprintf("failed to trace container %s/%s/%s: %s",
+				container.Namespace, container.Podname, container.Name, err)
+			eventCallback(types.Base(eventtypes.Warn(msg, trace.Spec.Node)))
+		}
+	}
+
+	containerEventCallback := func(event pubsub.PubSubEvent) {
+		switch event.Type {
+		case pubsub.EventTypeAddContainer:
+			addContainer(&event.Container)
+		case pubsub.EventTypeRemoveContainer:
+			t.tracer.RemoveContainer(&event.Container)
+		}
+	}
+
+	existingContainers := t.resolver.Subscribe(
+		genPubSubKey(trace.ObjectMeta.Namespace+"/"+trace.ObjectMeta.Name),
+		*gadgets.ContainerSelectorFromContainerFilter(trace.Spec.Filter),
+		containerEventCallback,
+	)
+
+	for _, c := range existingContainers {
+		addContainer(c)
+	}
+
+	if t.tracer.Attached() == 0 {
+		msg := fmt.Sprintf("%s not found in the selected containers, waiting for new ones", binary)
+		eventCallback(types.Base(eventtypes.Info(msg, trace.Spec.Node)))
+	}
+
+	t.started = true
+
+	trace.Status.State = "Started"
+}
+
+func (t *Trace) Stop(trace *gadgetv1alpha1.Trace) {
+	if !t.started {
+		trace.Status.OperationError = "Not started"
+		return
+	}
+
+	t.resolver.Unsubscribe(genPubSubKey(trace.ObjectMeta.Namespace + "/" + trace.ObjectMeta.Name))
+	t.tracer.Stop()
+	t.tracer = nil
+	t.started = false
+
+	trace.Status.State = "Stopped"
+}
+
+// List reads the probes of the binary of each selected container, through
+// its mount namespace.
+func (t *Trace) List(trace *gadgetv1alpha1.Trace) {
+	binary := trace.Spec.Parameters["binary"]
+	if binary == "" || !filepath.IsAbs(binary) {
+		trace.Status.OperationError = "binary must be set to an absolute path"
+		return
+	}
+
+	selector := gadgets.ContainerSelectorFromContainerFilter(trace.Spec.Filter)
+	containers := t.resolver.GetContainersBySelector(selector)
+	if len(containers) == 0 {
+		trace.Status.OperationWarning = "No container matches the requested filter"
+		trace.Status.State = "Completed"
+		return
+	}
+
+	probes := []types.Probe{}
+	warnings := []string{}
+
+	for _, c := range containers {
+		path, err := containerutils.ResolvePathInRoot(fmt.Sprintf("/proc/%d/root", c.Pid), filepath.Clean(binary))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err == nil {
+			var locations []tracer.Probe
+			locations, err = tracer.ReadProbes(path)
+			if err == nil {
+				probes = append(probes, summarize(trace.Spec.Node, c, locations)...)
+				continue
+			}
+		}
+		warnings = append(warnings, fmt.Sprintf("%s/%s/%s: %s", c.Namespace, c.Podname, c.Name, err))
+	}
+
+	if len(warnings) > 0 {
+		trace.Status.OperationWarning = fmt.Sprintf("failed to read the probes of some containers: %s",
+			strings.Join(warnings, "; "))
+	}
+
+	output, err := json.MarshalIndent(probes, "", " ")
+	if err != nil {
+		trace.Status.OperationError = fmt.Sprintf("failed marshalling probes: %s", err)
+		return
+	}
+
+	trace.Status.Output = string(output)
+	trace.Status.State = "Completed"
+}
+
+// summarize groups the locations of each probe of the binary of a
+// container.
+func summarize(node string, c *pb.ContainerDefinition, locations []tracer.Probe) []types.Probe {
+	probes := []types.Probe{}
+	index := map[string]int{}
+
+	for _, l := range locations {
+		key := l.Provider + ":" + l.Name
+		i, ok := index[key]
+		if !ok {
+			i = len(probes)
+			index[key] = i
+			probes = append(probes, types.Probe{
+				Node:      node,
+				Namespace: c.Namespace,
+				Pod:       c.Podname,
+				Container: c.Name,
+				Provider:  l.Provider,
+				Name:      l.Name,
+				Args:      l.Args,
+			})
+		}
+		probes[i].Locations++
+		if l.SemaphoreOffset != 0 {
+			probes[i].Semaphore = true
+		}
+	}
+
+	return probes
+}
@@ -0,0 +1,110 @@
+// SPDX-License-Identifier: GPL-2.0
+#include <vmlinux/vmlinux.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_tracing.h>
+
+#include "usdt.h"
+
+struct {
+	__uint(type, BPF_MAP_TYPE_PERF_EVENT_ARRAY);
+	__uint(key_size, sizeof(u32));
+	__uint(value_size, sizeof(u32));
+} events SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, 1024);
+	__uint(key_size, sizeof(u64));
+	__uint(value_size, sizeof(u32));
+} mount_ns_set SEC(".maps");
+
+/* The event is too large for the stack */
+struct {
+	__uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+	__uint(max_entries, 1);
+	__type(key, u32);
+	__type(value, struct event_t);
+} heap SEC(".maps");
+
+const volatile bool filter_by_mnt_ns = false;
+
+/* The arguments of the location of the probe the program is attached to.
+ * A program is loaded for each location, as they can be read differently. */
+const volatile u32 arg_count = 0;
+const volatile struct arg_spec arg_specs[MAX_ARGS] = {};
+
+/* Bit i is set if the argument i is read as a string */
+const volatile u32 string_args = 0;
+
+static __always_inline u64 read_arg(struct pt_regs *ctx, int i)
+{
+	const volatile struct arg_spec *spec = &arg_specs[i];
+	u64 value = 0;
+	int shift;
+
+	switch (spec->kind) {
+	case ARG_CONST:
+		return spec->value;
+	case ARG_REG:
+		bpf_probe_read_kernel(&value, sizeof(value), (void *) ctx + spec->reg_off);
+		break;
+	case ARG_REG_DEREF:
+		bpf_probe_read_kernel(&value, sizeof(value), (void *) ctx + spec->reg_off);
+		bpf_probe_read_user(&value, sizeof(value), (void *) (value + spec->value));
+		break;
+	default:
+		return 0;
+	}
+
+	/* Keep only the size of the argument, extending its sign if needed */
+	shift = 64 - spec->size * 8;
+	if (shift <= 0)
+		return value;
+	value <<= shift;
+	if (spec->is_signed)
+		return ((s64) value) >> shift;
+	return value >> shift;
+}
+
+SEC("uprobe/ig_usdt")
+int BPF_KPROBE(ig_usdt)
+{
+	u64 pid_tgid = bpf_get_current_pid_tgid();
+	struct task_struct *task;
+	struct event_t *event;
+	u64 mntns_id;
+	u32 zero = 0;
+
+	task = (struct task_struct *) bpf_get_current_task();
+	mntns_id = (u64) BPF_CORE_READ(task, nsproxy, mnt_ns, ns.inum);
+
+	if (filter_by_mnt_ns && !bpf_map_lookup_elem(&mount_ns_set, &mntns_id))
+		return 0;
+
+	event = bpf_map_lookup_elem(&heap, &zero);
+	if (!event)
+		return 0;
+
+	event->mntns_id = mntns_id;
+	event->pid = pid_tgid >> 32;
+	event->tid = (u32) pid_tgid;
+	bpf_get_current_comm(&event->comm, sizeof(event->comm));
+
+	#pragma unroll
+	for (int i = 0; i < MAX_ARGS; i++) {
+		event->strings[i][0] = '\0';
+		if (i >= arg_count) {
+			event->args[i] = 0;
+			continue;
+		}
+		event->args[i] = read_arg(ctx, i);
+		if (string_args & (1 << i))
+			bpf_probe_read_user_str(event->strings[i], MAX_STRING_LEN, (void *) event->args[i]);
+	}
+
+	bpf_perf_event_output(ctx, &events, BPF_F_CURRENT_CPU, event, sizeof(*event));
+	return 0;
+}
+
+char LICENSE[] SEC("license") = "GPL";
@@ -0,0 +1,39 @@
+/* SPDX-License-Identifier: (LGPL-2.1 OR BSD-2-Clause) */
+#ifndef __USDT_H
+#define __USDT_H
+
+#define TASK_COMM_LEN 16
+
+/* Number of arguments of the probe that can be read */
+#define MAX_ARGS 6
+
+#define MAX_STRING_LEN 64
+
+/* How an argument is read, see ArgKind in notes.go */
+#define ARG_CONST 0
+#define ARG_REG 1
+#define ARG_REG_DEREF 2
+
+struct arg_spec {
+	/* Constant, or offset added to the register for ARG_REG_DEREF */
+	__u64 value;
+	/* Offset of the register in struct pt_regs */
+	__u32 reg_off;
+	__u8 kind;
+	/* Size of the argument in bytes */
+	__u8 size;
+	__u8 is_signed;
+	__u8 pad;
+};
+
+struct event_t {
+	__u64 mntns_id;
+	__u32 pid;
+	__u32 tid;
+	__u8 comm[TASK_COMM_LEN];
+	__u64 args[MAX_ARGS];
+	/* Strings pointed by the arguments, when they are read as strings */
+	__u8 strings[MAX_ARGS][MAX_STRING_LEN];
+};
+
+#endif /* __USDT_H */
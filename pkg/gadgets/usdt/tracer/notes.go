@@ -0,0 +1,354 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Probe is a location of a USDT probe in a binary, as described by the
+// .note.stapsdt ELF notes. A probe can have several locations, e.g. when
+// the function using it is inlined.
+type Probe struct {
+	Provider string
+	Name     string
+
+	// Offset is the offset in the file of the probed instruction, where
+	// the uprobe is attached.
+	Offset uint64
+
+	// SemaphoreOffset is the offset in the file of the semaphore enabling
+	// the probe, or 0 if the probe is always enabled.
+	SemaphoreOffset uint64
+
+	// Args describes the arguments, e.g. "-4@%edi 8@%rax". ArgSpecs is
+	// nil and ArgsError set if they can't be read.
+	Args      string
+	ArgSpecs  []ArgSpec
+	ArgsError error
+}
+
+// ArgKind tells how an argument is read.
+type ArgKind uint8
+
+const (
+	// ArgConst is the constant ArgSpec.Value.
+	ArgConst ArgKind = iota
+
+	// ArgReg is the value of a register.
+	ArgReg
+
+	// ArgRegDeref is the value at the address of a register plus
+	// ArgSpec.Value.
+	ArgRegDeref
+)
+
+// ArgSpec describes how to read an argument of a probe.
+type ArgSpec struct {
+	Kind ArgKind
+
+	// Size is the size of the argument in bytes and Signed tells if it's
+	// a signed integer.
+	Size   int
+	Signed bool
+
+	// RegOffset is the offset of the register in struct pt_regs.
+	RegOffset uint32
+
+	Value int64
+}
+
+const (
+	stapsdtNoteName = "stapsdt"
+	stapsdtNoteType = 3
+)
+
+// x86RegOffsets are the offsets in struct pt_regs of the x86-64 registers,
+// by the names of their 64, 32, 16 and 8 bits parts.
+var x86RegOffsets = map[string]uint32{}
+
+func init() {
+	// The order of the registers in struct pt_regs.
+	regs := [][]string{
+		{"r15", "r15d", "r15w", "r15b"},
+		{"r14", "r14d", "r14w", "r14b"},
+		{"r13", "r13d", "r13w", "r13b"},
+		{"r12", "r12d", "r12w", "r12b"},
+		{"rbp", "ebp", "bp", "bpl"},
+		{"rbx", "ebx", "bx", "bl"},
+		{"r11", "r11d", "r11w", "r11b"},
+		{"r10", "r10d", "r10w", "r10b"},
+		{"r9", "r9d", "r9w", "r9b"},
+		{"r8", "r8d", "r8w", "r8b"},
+		{"rax", "eax", "ax", "al"},
+		{"rcx", "ecx", "cx", "cl"},
+		{"rdx", "edx", "dx", "dl"},
+		{"rsi", "esi", "si", "sil"},
+		{"rdi", "edi", "di", "dil"},
+		{"orig_rax"},
+		{"rip"},
+		{"cs"},
+		{"eflags"},
+		{"rsp", "esp", "sp", "spl"},
+	}
+
+	for i, names := range regs {
+		for _, name := range names {
+			x86RegOffsets[name] = uint32(i * 8)
+		}
+	}
+}
+
+// ReadProbes returns the locations of the USDT probes of the ELF file at
+// path.
+func ReadProbes(path string) ([]Probe, error) {
+	f, err := elf.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if f.Machine != elf.EM_X86_64 {
+		return nil, fmt.Errorf("unsupported architecture %s, only x86-64 is supported", f.Machine)
+	}
+
+	notes := f.Section(".note.stapsdt")
+	if notes == nil {
+		return nil, nil
+	}
+	data, err := notes.Data()
+	if err != nil {
+		return nil, fmt.Errorf("reading .note.stapsdt: %w", err)
+	}
+
+	// The addresses are relative to .stapsdt.base, which could have been
+	// moved after the notes were written, e.g. by prelink.
+	var baseAddr uint64
+	if base := f.Section(".stapsdt.base"); base != nil {
+		baseAddr = base.Addr
+	}
+
+	probes := []Probe{}
+	for len(data) > 0 {
+		var desc []byte
+		var noteType uint32
+		var name string
+
+		name, noteType, desc, data, err = nextNote(data, f.ByteOrder)
+		if err != nil {
+			return nil, err
+		}
+		if name != stapsdtNoteName || noteType != stapsdtNoteType {
+			continue
+		}
+
+		probe, pc, semaphore, err := parseNote(desc, f.ByteOrder, baseAddr)
+		if err != nil {
+			return nil, err
+		}
+
+		probe.Offset, err = addrToOffset(f, pc, true)
+		if err != nil {
+			return nil, fmt.Errorf("probe %s:%s: %w", probe.Provider, probe.Name, err)
+		}
+		if semaphore != 0 {
+			probe.SemaphoreOffset, err = addrToOffset(f, semaphore, false)
+			if err != nil {
+				return nil, fmt.Errorf("semaphore of probe %s:%s: %w", probe.Provider, probe.Name, err)
+			}
+		}
+
+		probes = append(probes, probe)
+	}
+
+	return probes, nil
+}
+
+func align4(n uint32) uint32 {
+	return (n + 3) &^ 3
+}
+
+// nextNote returns the name, type and description of the first ELF note of
+// data, and the data following it.
+func nextNote(data []byte, order binary.ByteOrder) (string, uint32, []byte, []byte, error) {
+	if len(data) < 12 {
+		return "", 0, nil, nil, errors.New("truncated ELF note")
+	}
+
+	nameSize := order.Uint32(data[0:4])
+	descSize := order.Uint32(data[4:8])
+	noteType := order.Uint32(data[8:12])
+	data = data[12:]
+
+	if uint64(len(data)) < uint64(align4(nameSize))+uint64(align4(descSize)) {
+		return "", 0, nil, nil, errors.New("truncated ELF note")
+	}
+
+	name := string(bytes.TrimRight(data[:nameSize], "\x00"))
+	data = data[align4(nameSize):]
+	desc := data[:descSize]
+	data = data[align4(descSize):]
+
+	return name, noteType, desc, data, nil
+}
+
+// parseNote parses the description of a stapsdt note: the addresses of
+// the probe, of .stapsdt.base and of the semaphore, followed by the
+// provider, the name and the arguments of the probe. It returns the probe
+// with the address of the probe and of its semaphore, adjusted with the
+// actual address of .stapsdt.base.
+func parseNote(desc []byte, order binary.ByteOrder, baseAddr uint64) (Probe, uint64, uint64, error) {
+	probe := Probe{}
+
+	if len(desc) < 3*8 {
+		return probe, 0, 0, errors.New("truncated stapsdt note")
+	}
+	pc := order.Uint64(desc[0:8])
+	base := order.Uint64(desc[8:16])
+	semaphore := order.Uint64(desc[16:24])
+
+	strs := strings.SplitN(string(desc[24:]), "\x00", 4)
+	if len(strs) < 3 {
+		return probe, 0, 0, errors.New("truncated stapsdt note")
+	}
+	probe.Provider, probe.Name, probe.Args = strs[0], strs[1], strs[2]
+
+	if baseAddr != 0 && base != 0 {
+		pc += baseAddr - base
+		if semaphore != 0 {
+			semaphore += baseAddr - base
+		}
+	}
+
+	probe.ArgSpecs, probe.ArgsError = parseArgs(probe.Args)
+
+	return probe, pc, semaphore, nil
+}
+
+// addrToOffset converts a virtual address of the file to an offset in the
+// file, using the loadable segment containing it.
+func addrToOffset(f *elf.File, addr uint64, executable bool) (uint64, error) {
+	for _, prog := range f.Progs {
+		if prog.Type != elf.PT_LOAD || (executable && prog.Flags&elf.PF_X == 0) {
+			continue
+		}
+		if addr >= prog.Vaddr && addr < prog.Vaddr+prog.Memsz {
+			return addr - prog.Vaddr + prog.Off, nil
+		}
+	}
+	return 0, fmt.Errorf("address 0x%x isn't in a loadable segment", addr)
+}
+
+// parseArgs parses the description of the arguments of a probe, e.g.
+// "-4@%edi 8@%rax -8@-24(%rbp) 4@$42".
+func parseArgs(args string) ([]ArgSpec, error) {
+	specs := []ArgSpec{}
+	for _, arg := range strings.Fields(args) {
+		spec, err := parseArg(arg)
+		if err != nil {
+			return nil, fmt.Errorf("argument %q: %w", arg, err)
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+func parseArg(arg string) (ArgSpec, error) {
+	spec := ArgSpec{Size: 8}
+
+	operand := arg
+	if i := strings.IndexByte(arg, '@'); i != -1 {
+		size, err := strconv.Atoi(arg[:i])
+		if err != nil {
+			return spec, fmt.Errorf("invalid size")
+		}
+		if size < 0 {
+			spec.Signed = true
+			size = -size
+		}
+		switch size {
+		case 1, 2, 4, 8:
+		default:
+			return spec, fmt.Errorf("unsupported size %d", size)
+		}
+		spec.Size = size
+		operand = arg[i+1:]
+	}
+
+	switch {
+	case strings.HasPrefix(operand, "$"):
+		value, err := strconv.ParseInt(operand[1:], 0, 64)
+		if err != nil {
+			return spec, fmt.Errorf("invalid constant")
+		}
+		spec.Kind = ArgConst
+		spec.Value = value
+	case strings.HasPrefix(operand, "%"):
+		offset, ok := x86RegOffsets[operand[1:]]
+		if !ok {
+			return spec, fmt.Errorf("unsupported register")
+		}
+		spec.Kind = ArgReg
+		spec.RegOffset = offset
+	case strings.HasSuffix(operand, ")"):
+		i := strings.IndexByte(operand, '(')
+		if i == -1 {
+			return spec, fmt.Errorf("invalid memory operand")
+		}
+
+		var value int64
+		if i > 0 {
+			var err error
+			value, err = strconv.ParseInt(operand[:i], 0, 64)
+			if err != nil {
+				return spec, fmt.Errorf("unsupported memory operand")
+			}
+		}
+
+		register := operand[i+1 : len(operand)-1]
+		offset, ok := x86RegOffsets[strings.TrimPrefix(register, "%")]
+		if !ok || !strings.HasPrefix(register, "%") {
+			return spec, fmt.Errorf("unsupported memory operand")
+		}
+
+		spec.Kind = ArgRegDeref
+		spec.RegOffset = offset
+		spec.Value = value
+	default:
+		return spec, fmt.Errorf("unsupported operand")
+	}
+
+	return spec, nil
+}
+
+// FormatArg formats the value of an argument read as a 64 bits integer,
+// according to its size and sign.
+func (s *ArgSpec) FormatArg(value uint64) string {
+	if s.Size < 8 {
+		value &= 1<<(uint(s.Size)*8) - 1
+	}
+	if !s.Signed {
+		return strconv.FormatUint(value, 10)
+	}
+
+	shift := uint(64 - s.Size*8)
+	return strconv.FormatInt(int64(value<<shift)>>shift, 10)
+}
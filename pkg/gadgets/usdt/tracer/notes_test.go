@@ -0,0 +1,158 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func TestParseArgs(t *testing.T) {
+	table := []struct {
+		args  string
+		specs []ArgSpec
+		err   bool
+	}{
+		{
+			args:  "",
+			specs: []ArgSpec{},
+		},
+		{
+			args: "-4@%edi 8@%rax 1@%sil",
+			specs: []ArgSpec{
+				{Kind: ArgReg, Size: 4, Signed: true, RegOffset: 14 * 8},
+				{Kind: ArgReg, Size: 8, RegOffset: 10 * 8},
+				{Kind: ArgReg, Size: 1, RegOffset: 13 * 8},
+			},
+		},
+		{
+			args: "-8@-24(%rbp) 8@(%rsp) 2@0x10(%r12)",
+			specs: []ArgSpec{
+				{Kind: ArgRegDeref, Size: 8, Signed: true, RegOffset: 4 * 8, Value: -24},
+				{Kind: ArgRegDeref, Size: 8, RegOffset: 19 * 8},
+				{Kind: ArgRegDeref, Size: 2, RegOffset: 3 * 8, Value: 0x10},
+			},
+		},
+		{
+			args: "4@$42 -4@$-1 %rdx",
+			specs: []ArgSpec{
+				{Kind: ArgConst, Size: 4, Value: 42},
+				{Kind: ArgConst, Size: 4, Signed: true, Value: -1},
+				{Kind: ArgReg, Size: 8, RegOffset: 12 * 8},
+			},
+		},
+		{args: "3@%rax", err: true},
+		{args: "x@%rax", err: true},
+		{args: "8@%xmm0", err: true},
+		{args: "8@foo(%rip)", err: true},
+		{args: "8@8(%rax,%rbx,4)", err: true},
+		{args: "8@rax", err: true},
+	}
+
+	for _, entry := range table {
+		specs, err := parseArgs(entry.args)
+		if entry.err {
+			if err == nil {
+				t.Fatalf("expected error for %q", entry.args)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %s", entry.args, err)
+		}
+		if !reflect.DeepEqual(specs, entry.specs) {
+			t.Fatalf("wrong specs for %q: got %+v, expected %+v", entry.args, specs, entry.specs)
+		}
+	}
+}
+
+func TestFormatArg(t *testing.T) {
+	table := []struct {
+		spec   ArgSpec
+		value  uint64
+		output string
+	}{
+		{ArgSpec{Size: 8}, ^uint64(0), "18446744073709551615"},
+		{ArgSpec{Size: 8, Signed: true}, ^uint64(0), "-1"},
+		{ArgSpec{Size: 4, Signed: true}, 0xfffffffe, "-2"},
+		{ArgSpec{Size: 4}, 0x1fffffffe, "4294967294"},
+		{ArgSpec{Size: 1, Signed: true}, 0x7f, "127"},
+		{ArgSpec{Size: 2, Signed: true}, 0x8000, "-32768"},
+	}
+
+	for _, entry := range table {
+		output := entry.spec.FormatArg(entry.value)
+		if output != entry.output {
+			t.Fatalf("wrong output for %+v and 0x%x: got %s, expected %s",
+				entry.spec, entry.value, output, entry.output)
+		}
+	}
+}
+
+func TestParseNotes(t *testing.T) {
+	order := binary.LittleEndian
+
+	note := func(name string, noteType uint32, desc []byte) []byte {
+		b := make([]byte, 12)
+		order.PutUint32(b[0:], uint32(len(name)+1))
+		order.PutUint32(b[4:], uint32(len(desc)))
+		order.PutUint32(b[8:], noteType)
+		b = append(b, name...)
+		b = append(b, make([]byte, align4(uint32(len(name)+1))-uint32(len(name)))...)
+		b = append(b, desc...)
+		return append(b, make([]byte, align4(uint32(len(desc)))-uint32(len(desc)))...)
+	}
+
+	desc := make([]byte, 24)
+	order.PutUint64(desc[0:], 0x1130)
+	order.PutUint64(desc[8:], 0x2004)
+	order.PutUint64(desc[16:], 0x4010)
+	desc = append(desc, "myapp\x00request\x00-4@%edi 8@%rsi\x00"...)
+
+	data := append(note("GNU", 1, []byte{1, 2, 3}), note(stapsdtNoteName, stapsdtNoteType, desc)...)
+
+	name, noteType, _, data, err := nextNote(data, order)
+	if err != nil || name != "GNU" || noteType != 1 {
+		t.Fatalf("wrong first note: %q %d %v", name, noteType, err)
+	}
+
+	name, noteType, noteDesc, data, err := nextNote(data, order)
+	if err != nil || name != stapsdtNoteName || noteType != stapsdtNoteType {
+		t.Fatalf("wrong second note: %q %d %v", name, noteType, err)
+	}
+	if len(data) != 0 {
+		t.Fatalf("%d bytes left after the notes", len(data))
+	}
+
+	// .stapsdt.base was moved by 0x100
+	probe, pc, semaphore, err := parseNote(noteDesc, order, 0x2104)
+	if err != nil {
+		t.Fatalf("parsing note: %s", err)
+	}
+	if probe.Provider != "myapp" || probe.Name != "request" || probe.Args != "-4@%edi 8@%rsi" {
+		t.Fatalf("wrong probe: %+v", probe)
+	}
+	if len(probe.ArgSpecs) != 2 || probe.ArgsError != nil {
+		t.Fatalf("wrong arguments: %+v %v", probe.ArgSpecs, probe.ArgsError)
+	}
+	if pc != 0x1230 || semaphore != 0x4110 {
+		t.Fatalf("wrong addresses: 0x%x 0x%x", pc, semaphore)
+	}
+
+	if _, _, _, _, err := nextNote([]byte{1, 2, 3}, order); err == nil {
+		t.Fatalf("expected error for truncated note")
+	}
+}
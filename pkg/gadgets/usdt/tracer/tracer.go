@@ -0,0 +1,331 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+// #include <linux/types.h>
+// #include "./bpf/usdt.h"
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+	"github.com/cilium/ebpf/perf"
+	containercollection "github.com/kinvolk/inspektor-gadget/pkg/container-collection"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/containerbinary"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/usdt/types"
+	pb "github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/api"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+//go:generate sh -c "GOOS=$(go env GOHOSTOS) GOARCH=$(go env GOHOSTARCH) go run github.com/cilium/ebpf/cmd/bpf2go -target bpfel -cc clang usdt ./bpf/usdt.bpf.c -- -I./bpf/ -I../../.. -target bpf -D__TARGET_ARCH_x86"
+
+const (
+	// MaxArgs is the number of arguments of a probe that can be read.
+	MaxArgs = C.MAX_ARGS
+
+	// maxLocations is the maximum number of locations of a probe in a
+	// binary that are traced, a program and a perf buffer being used for
+	// each of them.
+	maxLocations = 16
+
+	perfBufferPages = 16
+)
+
+// ErrProbeNotFound is returned by AddContainer when the binary of the
+// container doesn't have the probe.
+var ErrProbeNotFound = errors.New("probe not found in the binary")
+
+type Config struct {
+	// TODO: Make it a *ebpf.Map once
+	// https://github.com/cilium/ebpf/issues/515 and
+	// https://github.com/cilium/ebpf/issues/517 are fixed
+	MountnsMap string
+
+	// Binary is the path of the executable or library in the containers
+	// and Provider and Name identify the probe traced in it.
+	Binary   string
+	Provider string
+	Name     string
+
+	// StringArgs has the bit i set if the argument i is read as a string.
+	StringArgs uint32
+}
+
+// argSpec matches struct arg_spec of usdt.h.
+type argSpec struct {
+	Value    uint64
+	RegOff   uint32
+	Kind     uint8
+	Size     uint8
+	IsSigned uint8
+	Pad      uint8
+}
+
+// location is a location of the probe in a binary, with its own program
+// reading the arguments of this location.
+type location struct {
+	objs     usdtObjects
+	link     link.Link
+	reader   *perf.Reader
+	argSpecs []ArgSpec
+}
+
+func (l *location) close() {
+	if l.link != nil {
+		gadgets.CloseLink(l.link)
+	}
+	if l.reader != nil {
+		l.reader.Close()
+	}
+	l.objs.Close()
+}
+
+// probe is the locations of the probe attached in a binary.
+type probe []*location
+
+func (p probe) Close() {
+	for _, l := range p {
+		l.close()
+	}
+}
+
+type Tracer struct {
+	config        *Config
+	spec          *ebpf.CollectionSpec
+	resolver      containercollection.ContainerResolver
+	eventCallback func(types.Event)
+	node          string
+
+	attacher *containerbinary.Attacher
+}
+
+func NewTracer(c *Config, resolver containercollection.ContainerResolver, eventCallback func(types.Event), node string) (*Tracer, error) {
+	t := &Tracer{
+		config:        c,
+		resolver:      resolver,
+		eventCallback: eventCallback,
+		node:          node,
+	}
+	t.attacher = containerbinary.NewAttacher(c.Binary, t.attach)
+
+	spec, err := loadUsdt()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ebpf program: %w", err)
+	}
+
+	if t.config.MountnsMap != "" {
+		m := spec.Maps["mount_ns_set"]
+		m.Pinning = ebpf.PinByName
+		m.Name = filepath.Base(t.config.MountnsMap)
+	}
+	t.spec = spec
+
+	return t, nil
+}
+
+func (t *Tracer) Stop() {
+	t.attacher.Close()
+}
+
+// AddContainer attaches the probes to the binary of the container if they
+// aren't attached to the same file yet. It returns
+// containerbinary.ErrBinaryNotFound if the container doesn't have the
+// binary and ErrProbeNotFound if the binary doesn't have the probe.
+func (t *Tracer) AddContainer(c *pb.ContainerDefinition) error {
+	return t.attacher.AddContainer(c.Pid, c.Mntns)
+}
+
+func (t *Tracer) attach(path string) (containerbinary.Probes, error) {
+	probes, err := ReadProbes(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading the probes of %s: %w", t.config.Binary, err)
+	}
+
+	locations := []Probe{}
+	for _, p := range probes {
+		if p.Provider == t.config.Provider && p.Name == t.config.Name {
+			locations = append(locations, p)
+		}
+	}
+	if len(locations) == 0 {
+		return nil, ErrProbeNotFound
+	}
+	if len(locations) > maxLocations {
+		return nil, fmt.Errorf("probe %s:%s has %d locations in %s, at most %d are supported",
+			t.config.Provider, t.config.Name, len(locations), t.config.Binary, maxLocations)
+	}
+
+	ex, err := link.OpenExecutable(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", t.config.Binary, err)
+	}
+
+	var p probe
+	for _, loc := range locations {
+		l, err := t.attachLocation(ex, &loc)
+		if err != nil {
+			p.Close()
+			return nil, fmt.Errorf("attaching to probe %s:%s at 0x%x in %s: %w",
+				t.config.Provider, t.config.Name, loc.Offset, t.config.Binary, err)
+		}
+		p = append(p, l)
+	}
+
+	return p, nil
+}
+
+func (t *Tracer) attachLocation(ex *link.Executable, loc *Probe) (*location, error) {
+	if loc.ArgsError != nil {
+		return nil, loc.ArgsError
+	}
+	if len(loc.ArgSpecs) > MaxArgs {
+		return nil, fmt.Errorf("%d arguments, at most %d are supported", len(loc.ArgSpecs), MaxArgs)
+	}
+
+	var specs [MaxArgs]argSpec
+	for i, s := range loc.ArgSpecs {
+		specs[i] = argSpec{
+			Value:  uint64(s.Value),
+			RegOff: s.RegOffset,
+			Kind:   uint8(s.Kind),
+			Size:   uint8(s.Size),
+		}
+		if s.Signed {
+			specs[i].IsSigned = 1
+		}
+	}
+
+	spec := t.spec.Copy()
+
+	consts := map[string]interface{}{
+		"filter_by_mnt_ns": t.config.MountnsMap != "",
+		"arg_count":        uint32(len(loc.ArgSpecs)),
+		"arg_specs":        specs,
+		"string_args":      t.config.StringArgs,
+	}
+
+	if err := spec.RewriteConstants(consts); err != nil {
+		return nil, fmt.Errorf("error RewriteConstants: %w", err)
+	}
+
+	opts := ebpf.CollectionOptions{
+		Maps: ebpf.MapOptions{
+			PinPath: filepath.Dir(t.config.MountnsMap),
+		},
+	}
+
+	l := &location{argSpecs: loc.ArgSpecs}
+
+	if err := spec.LoadAndAssign(&l.objs, &opts); err != nil {
+		return nil, fmt.Errorf("failed to load ebpf program: %w", err)
+	}
+
+	reader, err := perf.NewReader(l.objs.usdtMaps.Events, perfBufferPages*os.Getpagesize())
+	if err != nil {
+		l.close()
+		return nil, fmt.Errorf("error creating perf ring buffer: %w", err)
+	}
+	l.reader = reader
+
+	// The offsets are given in the file, the symbol is only used to name
+	// the probe. The semaphore is incremented by the kernel while the
+	// probe is attached.
+	l.link, err = ex.Uprobe(t.config.Name, l.objs.IgUsdt, &link.UprobeOptions{
+		Offset:       loc.Offset,
+		RefCtrOffset: loc.SemaphoreOffset,
+	})
+	if err != nil {
+		l.close()
+		return nil, err
+	}
+
+	go t.run(l)
+
+	return l, nil
+}
+
+// RemoveContainer detaches the probes of the binary of the container when
+// no other container uses it.
+func (t *Tracer) RemoveContainer(c *pb.ContainerDefinition) {
+	t.attacher.RemoveContainer(c.Mntns)
+}
+
+// Attached returns the number of binaries the probes are attached to.
+func (t *Tracer) Attached() int {
+	return t.attacher.Attached()
+}
+
+func (t *Tracer) run(l *location) {
+	for {
+		record, err := l.reader.Read()
+		if err != nil {
+			if errors.Is(err, perf.ErrClosed) {
+				return
+			}
+
+			msg := fmt.Sprintf("Error reading perf ring buffer: %s", err)
+			t.eventCallback(types.Base(eventtypes.Err(msg, t.node)))
+			return
+		}
+
+		if record.LostSamples > 0 {
+			msg := fmt.Sprintf("lost %d samples", record.LostSamples)
+			t.eventCallback(types.Base(eventtypes.Warn(msg, t.node)))
+			continue
+		}
+
+		eventC := (*C.struct_event_t)(unsafe.Pointer(&record.RawSample[0]))
+
+		args := make([]string, len(l.argSpecs))
+		for i := range l.argSpecs {
+			if t.config.StringArgs&(1<<i) != 0 {
+				args[i] = C.GoString((*C.char)(unsafe.Pointer(&eventC.strings[i][0])))
+			} else {
+				args[i] = l.argSpecs[i].FormatArg(uint64(eventC.args[i]))
+			}
+		}
+
+		event := types.Event{
+			Event: eventtypes.Event{
+				Type: eventtypes.NORMAL,
+				Node: t.node,
+			},
+			Pid:       uint32(eventC.pid),
+			Tid:       uint32(eventC.tid),
+			Comm:      C.GoString((*C.char)(unsafe.Pointer(&eventC.comm[0]))),
+			MountNsID: uint64(eventC.mntns_id),
+			Provider:  t.config.Provider,
+			Name:      t.config.Name,
+			Args:      args,
+		}
+
+		container := t.resolver.LookupContainerByMntns(event.MountNsID)
+		if container != nil {
+			event.Container = container.Name
+			event.Pod = container.Podname
+			event.Sandbox = container.Sandbox
+			event.Namespace = container.Namespace
+		}
+
+		t.eventCallback(event)
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+type Event struct {
+	eventtypes.Event
+
+	Pid       uint32 `json:"pid,omitempty"`
+	Tid       uint32 `json:"tid,omitempty"`
+	Comm      string `json:"comm,omitempty"`
+	MountNsID uint64 `json:"mountnsid,omitempty"`
+
+	Provider string `json:"provider,omitempty"`
+	Name     string `json:"name,omitempty"`
+
+	// Args are the arguments of the probe, formatted as integers or as
+	// strings.
+	Args []string `json:"args,omitempty"`
+}
+
+func Base(ev eventtypes.Event) Event {
+	return Event{
+		Event: ev,
+	}
+}
+
+// Probe is a USDT probe of the binary of a container, returned by the list
+// operation.
+type Probe struct {
+	Node      string `json:"node,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Pod       string `json:"pod,omitempty"`
+	Container string `json:"container,omitempty"`
+
+	Provider string `json:"provider"`
+	Name     string `json:"name"`
+
+	// Args describes the arguments of the first location of the probe,
+	// e.g. "-4@%edi 8@%rax".
+	Args string `json:"args,omitempty"`
+
+	// Locations is the number of places where the probe is in the binary
+	// and Semaphore tells if the probe is only enabled while traced.
+	Locations int  `json:"locations"`
+	Semaphore bool `json:"semaphore,omitempty"`
+}
@@ -0,0 +1,126 @@
+// SPDX-License-Identifier: GPL-2.0
+// Copyright (c) 2022 The Inspektor Gadget authors
+// Based on vfsstat(8) from BCC by Brendan Gregg.
+#include <vmlinux/vmlinux.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_tracing.h>
+#include "vfsstat.h"
+
+/* The calls are counted by mount namespace, i.e. by container. */
+#define MAX_ENTRIES	10240
+
+const volatile pid_t target_pid = 0;
+const volatile bool filter_by_mnt_ns = false;
+static struct vfs_stat zero_value = {};
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, u64);
+	__type(value, struct vfs_stat);
+} entries SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, 1024);
+	__uint(key_size, sizeof(u64));
+	__uint(value_size, sizeof(u32));
+} mount_ns_set SEC(".maps");
+
+/* Unlike fstop, all the calls are counted, whatever the kind of file:
+ * reads and writes on pipes, sockets and devices too. */
+static __always_inline int probe_entry(enum op op)
+{
+	__u32 pid = bpf_get_current_pid_tgid() >> 32;
+	struct vfs_stat *valuep;
+	struct task_struct *task;
+	u64 mntns_id;
+
+	if (target_pid && target_pid != pid)
+		return 0;
+
+	task = (struct task_struct*)bpf_get_current_task();
+	mntns_id = (u64) BPF_CORE_READ(task, nsproxy, mnt_ns, ns.inum);
+
+	if (filter_by_mnt_ns && !bpf_map_lookup_elem(&mount_ns_set, &mntns_id))
+		return 0;
+
+	valuep = bpf_map_lookup_elem(&entries, &mntns_id);
+	if (!valuep) {
+		bpf_map_update_elem(&entries, &mntns_id, &zero_value, BPF_NOEXIST);
+		valuep = bpf_map_lookup_elem(&entries, &mntns_id);
+		if (!valuep)
+			return 0;
+	}
+
+	switch (op) {
+	case READ:
+		__sync_fetch_and_add(&valuep->reads, 1);
+		break;
+	case WRITE:
+		__sync_fetch_and_add(&valuep->writes, 1);
+		break;
+	case FSYNC:
+		__sync_fetch_and_add(&valuep->fsyncs, 1);
+		break;
+	case OPEN:
+		__sync_fetch_and_add(&valuep->opens, 1);
+		break;
+	case UNLINK:
+		__sync_fetch_and_add(&valuep->unlinks, 1);
+		break;
+	case MKDIR:
+		__sync_fetch_and_add(&valuep->mkdirs, 1);
+		break;
+	case RMDIR:
+		__sync_fetch_and_add(&valuep->rmdirs, 1);
+		break;
+	}
+
+	return 0;
+}
+
+SEC("kprobe/vfs_read")
+int BPF_KPROBE(ig_vfs_read_e)
+{
+	return probe_entry(READ);
+}
+
+SEC("kprobe/vfs_write")
+int BPF_KPROBE(ig_vfs_write_e)
+{
+	return probe_entry(WRITE);
+}
+
+SEC("kprobe/vfs_fsync_range")
+int BPF_KPROBE(ig_vfs_fsync_e)
+{
+	return probe_entry(FSYNC);
+}
+
+SEC("kprobe/vfs_open")
+int BPF_KPROBE(ig_vfs_open_e)
+{
+	return probe_entry(OPEN);
+}
+
+SEC("kprobe/vfs_unlink")
+int BPF_KPROBE(ig_vfs_unlink_e)
+{
+	return probe_entry(UNLINK);
+}
+
+SEC("kprobe/vfs_mkdir")
+int BPF_KPROBE(ig_vfs_mkdir_e)
+{
+	return probe_entry(MKDIR);
+}
+
+SEC("kprobe/vfs_rmdir")
+int BPF_KPROBE(ig_vfs_rmdir_e)
+{
+	return probe_entry(RMDIR);
+}
+
+char LICENSE[] SEC("license") = "GPL";
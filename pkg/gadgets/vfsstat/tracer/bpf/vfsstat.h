@@ -0,0 +1,25 @@
+/* SPDX-License-Identifier: (LGPL-2.1 OR BSD-2-Clause) */
+#ifndef __VFSSTAT_H
+#define __VFSSTAT_H
+
+enum op {
+	READ,
+	WRITE,
+	FSYNC,
+	OPEN,
+	UNLINK,
+	MKDIR,
+	RMDIR,
+};
+
+struct vfs_stat {
+	__u64 reads;
+	__u64 writes;
+	__u64 fsyncs;
+	__u64 opens;
+	__u64 unlinks;
+	__u64 mkdirs;
+	__u64 rmdirs;
+};
+
+#endif /* __VFSSTAT_H */
@@ -0,0 +1,34 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package volumemount
+
+import (
+	containercollection "github.com/kinvolk/inspektor-gadget/pkg/container-collection"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/mountsnoop"
+	mountsnooptracer "github.com/kinvolk/inspektor-gadget/pkg/gadgets/mountsnoop/tracer"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/volumemount/tracer"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/volumemount/types"
+)
+
+// NewTracer creates a volume-mount tracer calling eventCallback for the
+// mount operations on the volume directories below config.RootDir.
+func NewTracer(config *tracer.Config, resolver containercollection.ContainerResolver,
+	eventCallback func(types.Event), node string,
+) (mountsnooptracer.Tracer, error) {
+	// kubelet doesn't run in a container, so the mount operations are not
+	// filtered by mount namespace but by the target directory.
+	return mountsnoop.NewTracer(&mountsnooptracer.Config{}, resolver,
+		tracer.Filter(config, eventCallback), node)
+}
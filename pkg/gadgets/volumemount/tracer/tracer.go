@@ -0,0 +1,130 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	mountsnooptypes "github.com/kinvolk/inspektor-gadget/pkg/gadgets/mountsnoop/types"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/volumemount/types"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+// VolumePath describes a directory used by kubelet to mount a volume.
+type VolumePath struct {
+	Kind   string
+	PodUID string
+	Plugin string
+	Volume string
+}
+
+// ParsePath tells if path is one of the directories used by kubelet to
+// mount volumes and extracts the details encoded in it. See the Kind*
+// constants of the types package for the layouts handled.
+func ParsePath(rootDir, path string) (*VolumePath, bool) {
+	rel, err := filepath.Rel(filepath.Clean(rootDir), filepath.Clean(path))
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return nil, false
+	}
+
+	parts := strings.Split(rel, "/")
+
+	switch parts[0] {
+	case "pods":
+		if len(parts) < 5 {
+			return nil, false
+		}
+
+		switch parts[2] {
+		case "volumes":
+			return &VolumePath{
+				Kind:   types.KindPodVolume,
+				PodUID: parts[1],
+				// Plugin names are escaped by kubelet, e.g.
+				// kubernetes.io~csi.
+				Plugin: strings.ReplaceAll(parts[3], "~", "/"),
+				Volume: parts[4],
+			}, true
+		case "volume-subpaths":
+			return &VolumePath{
+				Kind:   types.KindSubPath,
+				PodUID: parts[1],
+				Volume: parts[3],
+			}, true
+		}
+	case "plugins":
+		// <root>/plugins/kubernetes.io/csi/<driver>/<hash>/globalmount
+		// <root>/plugins/kubernetes.io/csi/pv/<pv>/globalmount
+		// <root>/plugins/kubernetes.io/<plugin>/mounts/<volume>
+		if len(parts) < 5 {
+			return nil, false
+		}
+
+		volume := parts[len(parts)-1]
+		if volume == "globalmount" {
+			volume = parts[len(parts)-2]
+		}
+
+		return &VolumePath{
+			Kind:   types.KindGlobal,
+			Plugin: parts[1] + "/" + parts[2],
+			Volume: volume,
+		}, true
+	}
+
+	return nil, false
+}
+
+// Filter returns a callback for the mountsnoop tracers that reports to
+// eventCallback the mount operations performed on the kubelet volume
+// directories, i.e. by kubelet itself or by the CSI plugins.
+func Filter(config *Config, eventCallback func(types.Event)) func(mountsnooptypes.Event) {
+	return func(ev mountsnooptypes.Event) {
+		if ev.Type != eventtypes.NORMAL {
+			eventCallback(types.Base(ev.Event))
+			return
+		}
+
+		volumePath, ok := ParsePath(config.RootDir, ev.Target)
+		if !ok {
+			return
+		}
+
+		event := types.Event{
+			Event:     ev.Event,
+			Pid:       ev.Pid,
+			Comm:      ev.Comm,
+			Operation: ev.Operation,
+			Retval:    ev.Retval,
+			Latency:   ev.Latency,
+			Fs:        ev.Fs,
+			Source:    ev.Source,
+			Target:    ev.Target,
+			Flags:     ev.Flags,
+			Kind:      volumePath.Kind,
+			PodUID:    volumePath.PodUID,
+			Plugin:    volumePath.Plugin,
+			Volume:    volumePath.Volume,
+		}
+
+		if ev.Retval < 0 {
+			event.Error = syscall.Errno(-ev.Retval).Error()
+		}
+
+		eventCallback(event)
+	}
+}
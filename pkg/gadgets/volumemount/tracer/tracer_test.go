@@ -0,0 +1,94 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets/volumemount/types"
+)
+
+func TestParsePath(t *testing.T) {
+	table := []struct {
+		path     string
+		expected *VolumePath
+	}{
+		{
+			path: "/var/lib/kubelet/pods/0f9a3a2e-7c3b-4b56-9b0e-2d1c9a6d1e3a/volumes/kubernetes.io~csi/pvc-1234/mount",
+			expected: &VolumePath{
+				Kind:   types.KindPodVolume,
+				PodUID: "0f9a3a2e-7c3b-4b56-9b0e-2d1c9a6d1e3a",
+				Plugin: "kubernetes.io/csi",
+				Volume: "pvc-1234",
+			},
+		},
+		{
+			path: "/var/lib/kubelet/pods/0f9a3a2e/volumes/kubernetes.io~projected/kube-api-access-xyz",
+			expected: &VolumePath{
+				Kind:   types.KindPodVolume,
+				PodUID: "0f9a3a2e",
+				Plugin: "kubernetes.io/projected",
+				Volume: "kube-api-access-xyz",
+			},
+		},
+		{
+			path: "/var/lib/kubelet/pods/0f9a3a2e/volume-subpaths/config/nginx/0",
+			expected: &VolumePath{
+				Kind:   types.KindSubPath,
+				PodUID: "0f9a3a2e",
+				Volume: "config",
+			},
+		},
+		{
+			path: "/var/lib/kubelet/plugins/kubernetes.io/csi/pv/pvc-1234/globalmount",
+			expected: &VolumePath{
+				Kind:   types.KindGlobal,
+				Plugin: "kubernetes.io/csi",
+				Volume: "pvc-1234",
+			},
+		},
+		{
+			path: "/var/lib/kubelet/plugins/kubernetes.io/rbd/mounts/pool-image",
+			expected: &VolumePath{
+				Kind:   types.KindGlobal,
+				Plugin: "kubernetes.io/rbd",
+				Volume: "pool-image",
+			},
+		},
+		{
+			path: "/var/lib/kubelet/pods/0f9a3a2e/volumes",
+		},
+		{
+			path: "/var/lib/kubelet",
+		},
+		{
+			path: "/var/lib/kubeletfoo/pods/0f9a3a2e/volumes/kubernetes.io~csi/pvc-1234",
+		},
+		{
+			path: "/run/containerd/io.containerd.runtime.v2.task/k8s.io/abcd/rootfs",
+		},
+	}
+
+	for _, entry := range table {
+		volumePath, ok := ParsePath("/var/lib/kubelet", entry.path)
+		if ok != (entry.expected != nil) {
+			t.Fatalf("ParsePath(%q): expected ok to be %v", entry.path, entry.expected != nil)
+		}
+		if !reflect.DeepEqual(volumePath, entry.expected) {
+			t.Fatalf("ParsePath(%q): expected %+v, got %+v", entry.path, entry.expected, volumePath)
+		}
+	}
+}
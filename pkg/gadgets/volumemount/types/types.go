@@ -0,0 +1,71 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
+)
+
+const (
+	// RootDirParam is the directory where kubelet stores its data.
+	RootDirParam   = "root_dir"
+	RootDirDefault = "/var/lib/kubelet"
+)
+
+// Kinds of kubelet directories a volume can be mounted on.
+const (
+	// KindPodVolume is the directory of a volume in a pod:
+	// <root>/pods/<uid>/volumes/<plugin>/<volume>
+	KindPodVolume = "volume"
+
+	// KindSubPath is the directory of a subPath of a volume:
+	// <root>/pods/<uid>/volume-subpaths/<volume>/<container>/<index>
+	KindSubPath = "subpath"
+
+	// KindGlobal is the directory where a volume is mounted once per node
+	// before being bind-mounted into the pods, e.g. the staging directory
+	// of CSI plugins: <root>/plugins/<plugin>/...
+	KindGlobal = "global"
+)
+
+type Event struct {
+	eventtypes.Event
+
+	Pid       uint32   `json:"pid,omitempty"`
+	Comm      string   `json:"comm,omitempty"`
+	Operation string   `json:"operation,omitempty"`
+	Retval    int      `json:"ret,omitempty"`
+	Latency   uint64   `json:"latency,omitempty"`
+	Fs        string   `json:"fs,omitempty"`
+	Source    string   `json:"source,omitempty"`
+	Target    string   `json:"target,omitempty"`
+	Flags     []string `json:"flags,omitempty"`
+
+	// Error is the description of the error returned by the syscall, if
+	// any.
+	Error string `json:"error,omitempty"`
+
+	// Kind, PodUID, Plugin and Volume are extracted from the target path.
+	Kind   string `json:"kind,omitempty"`
+	PodUID string `json:"poduid,omitempty"`
+	Plugin string `json:"plugin,omitempty"`
+	Volume string `json:"volume,omitempty"`
+}
+
+func Base(ev eventtypes.Event) Event {
+	return Event{
+		Event: ev,
+	}
+}
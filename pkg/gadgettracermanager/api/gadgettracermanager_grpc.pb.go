@@ -20,10 +20,13 @@ const _ = grpc.SupportPackageIsVersion7
 type GadgetTracerManagerClient interface {
 	AddTracer(ctx context.Context, in *AddTracerRequest, opts ...grpc.CallOption) (*TracerID, error)
 	RemoveTracer(ctx context.Context, in *TracerID, opts ...grpc.CallOption) (*RemoveTracerResponse, error)
-	ReceiveStream(ctx context.Context, in *TracerID, opts ...grpc.CallOption) (GadgetTracerManager_ReceiveStreamClient, error)
+	ReceiveStream(ctx context.Context, in *ReceiveStreamRequest, opts ...grpc.CallOption) (GadgetTracerManager_ReceiveStreamClient, error)
 	AddContainer(ctx context.Context, in *ContainerDefinition, opts ...grpc.CallOption) (*AddContainerResponse, error)
 	RemoveContainer(ctx context.Context, in *ContainerDefinition, opts ...grpc.CallOption) (*RemoveContainerResponse, error)
 	DumpState(ctx context.Context, in *DumpStateRequest, opts ...grpc.CallOption) (*Dump, error)
+	CleanPins(ctx context.Context, in *CleanPinsRequest, opts ...grpc.CallOption) (*CleanPinsResponse, error)
+	DumpMaps(ctx context.Context, in *DumpMapsRequest, opts ...grpc.CallOption) (*DumpMapsResponse, error)
+	SetLogLevel(ctx context.Context, in *SetLogLevelRequest, opts ...grpc.CallOption) (*SetLogLevelResponse, error)
 }
 
 type gadgetTracerManagerClient struct {
@@ -52,7 +55,7 @@ func (c *gadgetTracerManagerClient) RemoveTracer(ctx context.Context, in *Tracer
 	return out, nil
 }
 
-func (c *gadgetTracerManagerClient) ReceiveStream(ctx context.Context, in *TracerID, opts ...grpc.CallOption) (GadgetTracerManager_ReceiveStreamClient, error) {
+func (c *gadgetTracerManagerClient) ReceiveStream(ctx context.Context, in *ReceiveStreamRequest, opts ...grpc.CallOption) (GadgetTracerManager_ReceiveStreamClient, error) {
 	stream, err := c.cc.NewStream(ctx, &GadgetTracerManager_ServiceDesc.Streams[0], "/gadgettracermanager.GadgetTracerManager/ReceiveStream", opts...)
 	if err != nil {
 		return nil, err
@@ -111,16 +114,46 @@ func (c *gadgetTracerManagerClient) DumpState(ctx context.Context, in *DumpState
 	return out, nil
 }
 
+func (c *gadgetTracerManagerClient) CleanPins(ctx context.Context, in *CleanPinsRequest, opts ...grpc.CallOption) (*CleanPinsResponse, error) {
+	out := new(CleanPinsResponse)
+	err := c.cc.Invoke(ctx, "/gadgettracermanager.GadgetTracerManager/CleanPins", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gadgetTracerManagerClient) DumpMaps(ctx context.Context, in *DumpMapsRequest, opts ...grpc.CallOption) (*DumpMapsResponse, error) {
+	out := new(DumpMapsResponse)
+	err := c.cc.Invoke(ctx, "/gadgettracermanager.GadgetTracerManager/DumpMaps", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gadgetTracerManagerClient) SetLogLevel(ctx context.Context, in *SetLogLevelRequest, opts ...grpc.CallOption) (*SetLogLevelResponse, error) {
+	out := new(SetLogLevelResponse)
+	err := c.cc.Invoke(ctx, "/gadgettracermanager.GadgetTracerManager/SetLogLevel", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // GadgetTracerManagerServer is the server API for GadgetTracerManager service.
 // All implementations must embed UnimplementedGadgetTracerManagerServer
 // for forward compatibility
 type GadgetTracerManagerServer interface {
 	AddTracer(context.Context, *AddTracerRequest) (*TracerID, error)
 	RemoveTracer(context.Context, *TracerID) (*RemoveTracerResponse, error)
-	ReceiveStream(*TracerID, GadgetTracerManager_ReceiveStreamServer) error
+	ReceiveStream(*ReceiveStreamRequest, GadgetTracerManager_ReceiveStreamServer) error
 	AddContainer(context.Context, *ContainerDefinition) (*AddContainerResponse, error)
 	RemoveContainer(context.Context, *ContainerDefinition) (*RemoveContainerResponse, error)
 	DumpState(context.Context, *DumpStateRequest) (*Dump, error)
+	CleanPins(context.Context, *CleanPinsRequest) (*CleanPinsResponse, error)
+	DumpMaps(context.Context, *DumpMapsRequest) (*DumpMapsResponse, error)
+	SetLogLevel(context.Context, *SetLogLevelRequest) (*SetLogLevelResponse, error)
 	mustEmbedUnimplementedGadgetTracerManagerServer()
 }
 
@@ -134,7 +167,7 @@ func (UnimplementedGadgetTracerManagerServer) AddTracer(context.Context, *AddTra
 func (UnimplementedGadgetTracerManagerServer) RemoveTracer(context.Context, *TracerID) (*RemoveTracerResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method RemoveTracer not implemented")
 }
-func (UnimplementedGadgetTracerManagerServer) ReceiveStream(*TracerID, GadgetTracerManager_ReceiveStreamServer) error {
+func (UnimplementedGadgetTracerManagerServer) ReceiveStream(*ReceiveStreamRequest, GadgetTracerManager_ReceiveStreamServer) error {
 	return status.Errorf(codes.Unimplemented, "method ReceiveStream not implemented")
 }
 func (UnimplementedGadgetTracerManagerServer) AddContainer(context.Context, *ContainerDefinition) (*AddContainerResponse, error) {
@@ -146,6 +179,15 @@ func (UnimplementedGadgetTracerManagerServer) RemoveContainer(context.Context, *
 func (UnimplementedGadgetTracerManagerServer) DumpState(context.Context, *DumpStateRequest) (*Dump, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DumpState not implemented")
 }
+func (UnimplementedGadgetTracerManagerServer) CleanPins(context.Context, *CleanPinsRequest) (*CleanPinsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CleanPins not implemented")
+}
+func (UnimplementedGadgetTracerManagerServer) DumpMaps(context.Context, *DumpMapsRequest) (*DumpMapsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DumpMaps not implemented")
+}
+func (UnimplementedGadgetTracerManagerServer) SetLogLevel(context.Context, *SetLogLevelRequest) (*SetLogLevelResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetLogLevel not implemented")
+}
 func (UnimplementedGadgetTracerManagerServer) mustEmbedUnimplementedGadgetTracerManagerServer() {}
 
 // UnsafeGadgetTracerManagerServer may be embedded to opt out of forward compatibility for this service.
@@ -196,7 +238,7 @@ func _GadgetTracerManager_RemoveTracer_Handler(srv interface{}, ctx context.Cont
 }
 
 func _GadgetTracerManager_ReceiveStream_Handler(srv interface{}, stream grpc.ServerStream) error {
-	m := new(TracerID)
+	m := new(ReceiveStreamRequest)
 	if err := stream.RecvMsg(m); err != nil {
 		return err
 	}
@@ -270,6 +312,60 @@ func _GadgetTracerManager_DumpState_Handler(srv interface{}, ctx context.Context
 	return interceptor(ctx, in, info, handler)
 }
 
+func _GadgetTracerManager_CleanPins_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CleanPinsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GadgetTracerManagerServer).CleanPins(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gadgettracermanager.GadgetTracerManager/CleanPins",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GadgetTracerManagerServer).CleanPins(ctx, req.(*CleanPinsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GadgetTracerManager_DumpMaps_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DumpMapsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GadgetTracerManagerServer).DumpMaps(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gadgettracermanager.GadgetTracerManager/DumpMaps",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GadgetTracerManagerServer).DumpMaps(ctx, req.(*DumpMapsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GadgetTracerManager_SetLogLevel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetLogLevelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GadgetTracerManagerServer).SetLogLevel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gadgettracermanager.GadgetTracerManager/SetLogLevel",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GadgetTracerManagerServer).SetLogLevel(ctx, req.(*SetLogLevelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // GadgetTracerManager_ServiceDesc is the grpc.ServiceDesc for GadgetTracerManager service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -297,6 +393,18 @@ var GadgetTracerManager_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "DumpState",
 			Handler:    _GadgetTracerManager_DumpState_Handler,
 		},
+		{
+			MethodName: "CleanPins",
+			Handler:    _GadgetTracerManager_CleanPins_Handler,
+		},
+		{
+			MethodName: "DumpMaps",
+			Handler:    _GadgetTracerManager_DumpMaps_Handler,
+		},
+		{
+			MethodName: "SetLogLevel",
+			Handler:    _GadgetTracerManager_SetLogLevel_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
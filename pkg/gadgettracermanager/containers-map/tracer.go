@@ -134,9 +134,14 @@ func (cm *ContainersMap) ContainersMapUpdater() pubsub.FuncNotify {
 	}
 }
 
+// Close unpins the containers map and releases its file descriptor.
 func (cm *ContainersMap) Close() {
 	if cm == nil {
 		return
 	}
 	os.Remove(filepath.Join(cm.pinPath, BPFMapName))
+	if cm.containersMap != nil {
+		cm.containersMap.Close()
+		cm.containersMap = nil
+	}
 }
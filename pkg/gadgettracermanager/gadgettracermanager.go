@@ -30,6 +30,9 @@ import (
 	pb "github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/api"
 	containersmap "github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/containers-map"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/pubsub"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/sink"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/stream"
+	"github.com/kinvolk/inspektor-gadget/pkg/mapdump"
 	"github.com/kinvolk/inspektor-gadget/pkg/runcfanotify"
 	tracercollection "github.com/kinvolk/inspektor-gadget/pkg/tracer-collection"
 	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
@@ -47,19 +50,34 @@ type GadgetTracerManager struct {
 	// node where this instance is running
 	nodeName string
 
+	// nodeLabels are the labels of the node added to the events, see
+	// Conf.NodeLabels.
+	nodeLabels map[string]string
+
 	// tracers
 	tracerCollection *tracercollection.TracerCollection
 
 	// withBPF tells whether GadgetTracerManager can run bpf() syscall.
 	// Normally, withBPF=true but it can be disabled so unit tests can run
-	// without being root.
+	// without being root, or when the gadget pods are deployed without the
+	// privileges needed by eBPF.
 	withBPF bool
 
 	// containersMap is the global map at /sys/fs/bpf/gadget/containers
 	// exposing container details for each mount namespace.
 	containersMap *containersmap.ContainersMap
+
+	// sinks are the sinks writing the events of each tracer
+	sinks map[string][]*sink.Runner
+
+	// dumpMaps dumps the BPF maps of a trace. It's set by the trace
+	// controller, see SetMapsDumper.
+	dumpMaps MapsDumper
 }
 
+// MapsDumper dumps the BPF maps of the trace namespace/name.
+type MapsDumper func(ctx context.Context, namespace, name string) (*mapdump.Snapshot, error)
+
 func (g *GadgetTracerManager) AddTracer(_ context.Context, req *pb.AddTracerRequest) (*pb.TracerID, error) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
@@ -85,43 +103,131 @@ func (g *GadgetTracerManager) AddTracer(_ context.Context, req *pb.AddTracerRequ
 
 func (g *GadgetTracerManager) RemoveTracer(_ context.Context, tracerID *pb.TracerID) (*pb.RemoveTracerResponse, error) {
 	g.mu.Lock()
-	defer g.mu.Unlock()
 
-	if err := g.tracerCollection.RemoveTracer(tracerID.Id); err != nil {
+	runners := g.sinks[tracerID.Id]
+	delete(g.sinks, tracerID.Id)
+
+	err := g.tracerCollection.RemoveTracer(tracerID.Id)
+	g.mu.Unlock()
+
+	// The sinks are stopped without holding the lock as it can take up to
+	// the time of writing a batch of events.
+	for _, r := range runners {
+		r.Stop()
+	}
+
+	if err != nil {
 		return nil, err
 	}
 
 	return &pb.RemoveTracerResponse{}, nil
 }
 
-func (g *GadgetTracerManager) ReceiveStream(tracerID *pb.TracerID, stream pb.GadgetTracerManager_ReceiveStreamServer) error {
-	if tracerID.Id == "" {
+// SetSinks makes the events of the given tracer written to the sinks of
+// configs, replacing its current sinks if their configurations differ. The
+// current sinks are kept if one of the new ones can't be created.
+func (g *GadgetTracerManager) SetSinks(tracerID string, trace sink.Trace, configs []sink.Config) error {
+	g.mu.Lock()
+
+	gadgetStream, err := g.tracerCollection.Stream(tracerID)
+	if err != nil {
+		g.mu.Unlock()
+		return fmt.Errorf("cannot find stream for tracer %q", tracerID)
+	}
+
+	previous := g.sinks[tracerID]
+	if sameSinks(previous, configs) {
+		g.mu.Unlock()
+		return nil
+	}
+
+	sinks := make([]sink.Sink, 0, len(configs))
+	for _, config := range configs {
+		s, err := sink.New(config, trace)
+		if err != nil {
+			g.mu.Unlock()
+			for _, s := range sinks {
+				s.Close()
+			}
+			return fmt.Errorf("creating %s sink: %w", config.Kind, err)
+		}
+		sinks = append(sinks, s)
+	}
+
+	runners := make([]*sink.Runner, 0, len(sinks))
+	for i, s := range sinks {
+		runners = append(runners, sink.Start(configs[i], s, gadgetStream))
+	}
+	if len(runners) == 0 {
+		delete(g.sinks, tracerID)
+	} else {
+		g.sinks[tracerID] = runners
+	}
+
+	g.mu.Unlock()
+
+	for _, r := range previous {
+		r.Stop()
+	}
+
+	return nil
+}
+
+func sameSinks(runners []*sink.Runner, configs []sink.Config) bool {
+	if len(runners) != len(configs) {
+		return false
+	}
+	for i, r := range runners {
+		if r.Config() != configs[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// SinkStatuses returns the health of the sinks of the given tracer.
+func (g *GadgetTracerManager) SinkStatuses(tracerID string) []sink.Status {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	statuses := []sink.Status{}
+	for _, r := range g.sinks[tracerID] {
+		statuses = append(statuses, r.Status())
+	}
+	return statuses
+}
+
+func (g *GadgetTracerManager) ReceiveStream(req *pb.ReceiveStreamRequest, stream pb.GadgetTracerManager_ReceiveStreamServer) error {
+	if req.Id == "" {
 		return fmt.Errorf("cannot find tracer: Id not set")
 	}
 
 	g.mu.Lock()
 
-	gadgetStream, err := g.tracerCollection.Stream(tracerID.Id)
+	gadgetStream, err := g.tracerCollection.Stream(req.Id)
 	if err != nil {
 		g.mu.Unlock()
-		return fmt.Errorf("cannot find stream for tracer %q", tracerID.Id)
+		return fmt.Errorf("cannot find stream for tracer %q", req.Id)
 	}
 
-	ch := gadgetStream.Subscribe()
+	ch := gadgetStream.SubscribePrevious(int(req.Previous))
 	defer gadgetStream.Unsubscribe(ch)
 
 	g.mu.Unlock()
 
 	for l := range ch {
-		if l.EventLost {
-			ev := eventtypes.Event{
-				Type:    eventtypes.ERR,
-				Node:    g.nodeName,
-				Message: "events lost in gadget tracer manager",
-			}
+		switch {
+		case l.Disconnected:
+			ev := eventtypes.Err(fmt.Sprintf("disconnected from gadget tracer manager for being too slow, %d events lost", l.LostCount), g.nodeName)
 			line, _ := json.Marshal(ev)
-			err := stream.Send(&pb.StreamData{Line: string(line)})
-			return err
+			return stream.Send(&pb.StreamData{Line: string(line)})
+		case l.EventLost:
+			ev := eventtypes.Warn(fmt.Sprintf("%d events lost in gadget tracer manager", l.LostCount), g.nodeName)
+			line, _ := json.Marshal(ev)
+			if err := stream.Send(&pb.StreamData{Line: string(line)}); err != nil {
+				return err
+			}
+			continue
 		}
 
 		line := &pb.StreamData{Line: l.Line}
@@ -143,10 +249,23 @@ func (g *GadgetTracerManager) PublishEvent(tracerID string, line string) error {
 		return fmt.Errorf("cannot find stream for tracer %q", tracerID)
 	}
 
-	stream.Publish(line)
+	stream.Publish(eventtypes.WithMetadata(line, g.nodeLabels))
 	return nil
 }
 
+// StreamStats returns the counters of the stream of the given tracer.
+func (g *GadgetTracerManager) StreamStats(tracerID string) (stream.Stats, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	gadgetStream, err := g.tracerCollection.Stream(tracerID)
+	if err != nil {
+		return stream.Stats{}, fmt.Errorf("cannot find stream for tracer %q", tracerID)
+	}
+
+	return gadgetStream.Stats(), nil
+}
+
 func (g *GadgetTracerManager) AddContainer(_ context.Context, containerDefinition *pb.ContainerDefinition) (*pb.AddContainerResponse, error) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
@@ -192,6 +311,18 @@ func (g *GadgetTracerManager) DumpState(_ context.Context, req *pb.DumpStateRequ
 	out += "List of tracers:\n"
 	out += g.tracerCollection.TracerDump()
 
+	out += "Tracer collection metrics:\n"
+	out += g.tracerCollection.StatsDump()
+
+	out += "List of stale pinned maps:\n"
+	stalePins, err := g.tracerCollection.StalePins()
+	if err != nil {
+		out += fmt.Sprintf("Error: %s\n", err)
+	}
+	for _, path := range stalePins {
+		out += fmt.Sprintf("%s\n", path)
+	}
+
 	out += "List of stacks:\n"
 	buf := make([]byte, 1<<20)
 	stacklen := runtime.Stack(buf, true)
@@ -200,21 +331,98 @@ func (g *GadgetTracerManager) DumpState(_ context.Context, req *pb.DumpStateRequ
 	return &pb.Dump{State: out}, nil
 }
 
+func (g *GadgetTracerManager) CleanPins(_ context.Context, req *pb.CleanPinsRequest) (*pb.CleanPinsResponse, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	removed, err := g.cleanPins()
+	if err != nil {
+		return nil, err
+	}
+	return &pb.CleanPinsResponse{Removed: removed}, nil
+}
+
+// SetMapsDumper sets the function dumping the BPF maps of the traces,
+// which is only available when the trace controller is running.
+func (g *GadgetTracerManager) SetMapsDumper(dumper MapsDumper) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.dumpMaps = dumper
+}
+
+func (g *GadgetTracerManager) DumpMaps(ctx context.Context, req *pb.DumpMapsRequest) (*pb.DumpMapsResponse, error) {
+	// The maps are dumped without holding g.mu: the dumper waits for the
+	// operations on the gadgets, which can add or remove tracers.
+	g.mu.Lock()
+	dumper := g.dumpMaps
+	g.mu.Unlock()
+
+	if dumper == nil {
+		return nil, fmt.Errorf("the trace controller isn't running")
+	}
+
+	snapshot, err := dumper(ctx, req.Namespace, req.Name)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal the maps: %w", err)
+	}
+	return &pb.DumpMapsResponse{Snapshot: b}, nil
+}
+
+func (g *GadgetTracerManager) SetLogLevel(_ context.Context, req *pb.SetLogLevelRequest) (*pb.SetLogLevelResponse, error) {
+	previous, err := gadgets.SetLogLevel(req.Gadget, req.Level)
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("Log level of gadget %s set to %s (was %s)", req.Gadget, req.Level, previous)
+	return &pb.SetLogLevelResponse{Previous: previous}, nil
+}
+
+// cleanPins removes the BPF maps pinned in gadgets.PinPath that don't
+// correspond to any tracer anymore.
+func (g *GadgetTracerManager) cleanPins() ([]string, error) {
+	removed, err := g.tracerCollection.CleanStalePins()
+	for _, path := range removed {
+		log.Infof("GadgetTracerManager: removed stale pinned map %s", path)
+	}
+	return removed, err
+}
+
 func newServer(conf *Conf) (*GadgetTracerManager, error) {
 	g := &GadgetTracerManager{
 		nodeName: conf.NodeName,
-		withBPF:  !conf.TestOnly,
+		withBPF:  !conf.TestOnly && !conf.WithoutBPF,
+		sinks:    make(map[string][]*sink.Runner),
 	}
 
-	tracerCollection, err := tracercollection.NewTracerCollection(gadgets.PinPath, gadgets.MountMapPrefix, !conf.TestOnly, &g.ContainerCollection)
+	if !conf.TestOnly && len(conf.NodeLabels) > 0 {
+		labels, err := getNodeLabels(conf.NodeName, conf.NodeLabels)
+		if err != nil {
+			log.Warnf("GadgetTracerManager: the node labels won't be added to the events: %s", err)
+		}
+		g.nodeLabels = labels
+	}
+
+	tracerCollection, err := tracercollection.NewTracerCollection(gadgets.PinPath, gadgets.MountMapPrefix, g.withBPF, &g.ContainerCollection)
 	if err != nil {
 		return nil, err
 	}
 	g.tracerCollection = tracerCollection
 
+	// Maps pinned by a previous instance, e.g. killed before removing its
+	// tracers, are not used by anyone: the traces still present will add
+	// their tracer again.
+	if _, err := g.cleanPins(); err != nil {
+		log.Warnf("GadgetTracerManager: failed to clean stale pinned maps: %s", err)
+	}
+
 	containerEventFuncs := []pubsub.FuncNotify{}
 
-	if !conf.TestOnly {
+	if g.withBPF {
 		if err := rlimit.RemoveMemlock(); err != nil {
 			return nil, err
 		}
@@ -286,6 +494,16 @@ type Conf struct {
 	HookMode            string
 	FallbackPodInformer bool
 	TestOnly            bool
+
+	// WithoutBPF is set when the gadget pods don't have the privileges
+	// needed by eBPF: the containers are still tracked, but no BPF map is
+	// created for them.
+	WithoutBPF bool
+
+	// NodeLabels lists the labels of the node added to the events, e.g.
+	// eventtypes.DefaultNodeLabels. The labels the node doesn't have are
+	// skipped.
+	NodeLabels []string
 }
 
 func NewServer(conf *Conf) (*GadgetTracerManager, error) {
@@ -295,5 +513,16 @@ func NewServer(conf *Conf) (*GadgetTracerManager, error) {
 // Close releases any resource that could be in use by the tracer manager, like
 // ebpf maps.
 func (m *GadgetTracerManager) Close() {
+	m.mu.Lock()
+	sinks := m.sinks
+	m.sinks = make(map[string][]*sink.Runner)
+	m.mu.Unlock()
+
+	for _, runners := range sinks {
+		for _, r := range runners {
+			r.Stop()
+		}
+	}
+
 	m.containersMap.Close()
 }
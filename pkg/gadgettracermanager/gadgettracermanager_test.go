@@ -21,6 +21,7 @@ import (
 	"testing"
 
 	pb "github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/api"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
 )
 
 func TestTracer(t *testing.T) {
@@ -468,3 +469,24 @@ func TestContainer(t *testing.T) {
 		t.Fatalf("Error while looking up containers in a non-existent namespace")
 	}
 }
+
+func TestFilterLabels(t *testing.T) {
+	labels := map[string]string{
+		"kubernetes.io/hostname":           "node1",
+		"topology.kubernetes.io/zone":      "eu-west-1a",
+		"node.kubernetes.io/instance-type": "m5.large",
+	}
+
+	filtered := filterLabels(labels, eventtypes.DefaultNodeLabels)
+	expected := map[string]string{
+		"topology.kubernetes.io/zone":      "eu-west-1a",
+		"node.kubernetes.io/instance-type": "m5.large",
+	}
+	if !reflect.DeepEqual(filtered, expected) {
+		t.Fatalf("Expected labels %v, got %v", expected, filtered)
+	}
+
+	if filtered := filterLabels(labels, []string{"missing"}); filtered != nil {
+		t.Fatalf("Expected no labels, got %v", filtered)
+	}
+}
@@ -38,6 +38,8 @@ type K8sClient struct {
 	nodeName      string
 	fieldSelector string
 	runtimeClient runtimeclient.ContainerRuntimeClient
+
+	sandboxDetector *containerutils.SandboxDetector
 }
 
 func NewK8sClient(nodeName string) (*K8sClient, error) {
@@ -73,6 +75,8 @@ func NewK8sClient(nodeName string) (*K8sClient, error) {
 		nodeName:      nodeName,
 		fieldSelector: fieldSelector,
 		runtimeClient: runtimeClient,
+
+		sandboxDetector: containerutils.NewSandboxDetector(clientset),
 	}, nil
 }
 
@@ -106,6 +110,13 @@ func (k *K8sClient) PodToContainers(pod *v1.Pod) []pb.ContainerDefinition {
 		labels = append(labels, &pb.Label{Key: k, Value: v})
 	}
 
+	annotations := []*pb.Label{}
+	for k, v := range pod.ObjectMeta.Annotations {
+		annotations = append(annotations, &pb.Label{Key: k, Value: v})
+	}
+
+	sandbox := k.sandboxDetector.PodSandbox(pod)
+
 	containerStatuses := append([]v1.ContainerStatus{}, pod.Status.InitContainerStatuses...)
 	containerStatuses = append(containerStatuses, pod.Status.ContainerStatuses...)
 
@@ -126,12 +137,15 @@ func (k *K8sClient) PodToContainers(pod *v1.Pod) []pb.ContainerDefinition {
 		}
 
 		containerDef := pb.ContainerDefinition{
-			Id:        idParts[1],
-			Namespace: pod.GetNamespace(),
-			Podname:   pod.GetName(),
-			Name:      s.Name,
-			Labels:    labels,
-			Pid:       uint32(pid),
+			Id:          idParts[1],
+			Namespace:   pod.GetNamespace(),
+			Podname:     pod.GetName(),
+			PodUid:      string(pod.GetUID()),
+			Sandbox:     sandbox,
+			Name:        s.Name,
+			Labels:      labels,
+			Annotations: annotations,
+			Pid:         uint32(pid),
 		}
 		containers = append(containers, containerDef)
 	}
@@ -126,12 +126,20 @@ func (p *PodInformer) notifyChans(key string) error {
 	}
 	defer p.queue.Forget(key)
 
+	// The reader of the channels might be gone once the informer is
+	// stopped: don't block the worker forever in this case.
 	if !exists {
-		p.deletedPodChan <- key
+		select {
+		case p.deletedPodChan <- key:
+		case <-p.stop:
+		}
 		return nil
 	}
 
-	p.createdPodChan <- obj.(*v1.Pod)
+	select {
+	case p.createdPodChan <- obj.(*v1.Pod):
+	case <-p.stop:
+	}
 	return nil
 }
 
@@ -0,0 +1,58 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgettracermanager
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kinvolk/inspektor-gadget/pkg/k8sutil"
+)
+
+// getNodeLabels returns the labels of the node which are in the allowed
+// list. They are only read at startup: the zone and the instance type of a
+// node don't change.
+func getNodeLabels(node string, allowed []string) (map[string]string, error) {
+	clientset, err := k8sutil.NewClientset("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the Kubernetes client: %w", err)
+	}
+
+	n, err := clientset.CoreV1().Nodes().Get(context.TODO(), node, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get node %q: %w", node, err)
+	}
+
+	return filterLabels(n.Labels, allowed), nil
+}
+
+// filterLabels returns the labels which are in the allowed list, or nil if
+// there isn't any.
+func filterLabels(labels map[string]string, allowed []string) map[string]string {
+	var filtered map[string]string
+	for _, key := range allowed {
+		value, ok := labels[key]
+		if !ok {
+			continue
+		}
+		if filtered == nil {
+			filtered = make(map[string]string)
+		}
+		filtered[key] = value
+	}
+	return filtered
+}
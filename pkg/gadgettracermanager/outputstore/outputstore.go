@@ -0,0 +1,185 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package outputstore stores on the nodes the outputs of the traces which
+// are too large to be written in the status of their Trace resource: etcd
+// rejects the objects larger than 1.5MiB by default.
+package outputstore
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+)
+
+// Dir is the directory of the node where the outputs are stored.
+var Dir = "/var/lib/gadget/output"
+
+const (
+	// MaxStatusSize is the size above which the output of a trace is
+	// stored on the node instead of its status, leaving room for the other
+	// fields of the Trace.
+	MaxStatusSize = 512 * 1024
+
+	// ChunkSize is the size of the files the outputs are split into.
+	ChunkSize = 1024 * 1024
+
+	// MaxChunks is the maximum number of chunks of an output, so that a
+	// gadget can't fill the disk of the node.
+	MaxChunks = 64
+)
+
+// ErrNotFound is returned when there is no output stored for a trace.
+var ErrNotFound = errors.New("no output stored")
+
+// Store stores the outputs of the traces in a directory per trace, split
+// into chunks of ChunkSize bytes.
+type Store struct {
+	dir string
+}
+
+func New(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+func (s *Store) traceDir(id string) (string, error) {
+	// The IDs starting with a dot are reserved for the temporary
+	// directories of Write.
+	if id == "" || strings.Contains(id, "/") || strings.HasPrefix(id, ".") {
+		return "", fmt.Errorf("invalid trace ID %q", id)
+	}
+	return filepath.Join(s.dir, id), nil
+}
+
+// Write stores the output of a trace, replacing the previous one, and
+// returns the reference to set in the status of the trace.
+func (s *Store) Write(id string, output string) (*gadgetv1alpha1.OutputReference, error) {
+	dir, err := s.traceDir(id)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := (len(output) + ChunkSize - 1) / ChunkSize
+	if chunks > MaxChunks {
+		return nil, fmt.Errorf("output of %d bytes exceeds the limit of %d bytes",
+			len(output), MaxChunks*ChunkSize)
+	}
+
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating %s: %w", s.dir, err)
+	}
+
+	// The chunks are written in a temporary directory renamed once
+	// complete, so that readers never see a partial output.
+	tmpDir, err := os.MkdirTemp(s.dir, "."+id+"-")
+	if err != nil {
+		return nil, fmt.Errorf("creating temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	for i := 0; i < chunks; i++ {
+		end := (i + 1) * ChunkSize
+		if end > len(output) {
+			end = len(output)
+		}
+		path := filepath.Join(tmpDir, chunkName(i))
+		if err := os.WriteFile(path, []byte(output[i*ChunkSize:end]), 0o600); err != nil {
+			return nil, fmt.Errorf("writing chunk %d: %w", i, err)
+		}
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, fmt.Errorf("removing previous output: %w", err)
+	}
+	if err := os.Rename(tmpDir, dir); err != nil {
+		return nil, fmt.Errorf("renaming %s: %w", tmpDir, err)
+	}
+
+	return &gadgetv1alpha1.OutputReference{
+		Size:   int64(len(output)),
+		Chunks: chunks,
+	}, nil
+}
+
+// Read writes the output of a trace to w.
+func (s *Store) Read(id string, w io.Writer) error {
+	dir, err := s.traceDir(id)
+	if err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+
+	for i, name := range names {
+		if name != chunkName(i) {
+			return fmt.Errorf("unexpected chunk %q", name)
+		}
+		if err := copyFile(w, filepath.Join(dir, name)); err != nil {
+			return fmt.Errorf("reading chunk %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// ReadString returns the output of a trace.
+func (s *Store) ReadString(id string) (string, error) {
+	var sb strings.Builder
+	if err := s.Read(id, &sb); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// Remove removes the output of a trace, if any.
+func (s *Store) Remove(id string) error {
+	dir, err := s.traceDir(id)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+func chunkName(i int) string {
+	return fmt.Sprintf("%04d", i)
+}
+
+func copyFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(w, f)
+	return err
+}
@@ -0,0 +1,91 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package outputstore
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	s := New(dir)
+
+	if _, err := s.ReadString("trace_gadget_foo"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	output := strings.Repeat("x", 2*ChunkSize+42)
+	ref, err := s.Write("trace_gadget_foo", output)
+	if err != nil {
+		t.Fatalf("writing output: %s", err)
+	}
+	if ref.Size != int64(len(output)) || ref.Chunks != 3 {
+		t.Fatalf("unexpected reference %+v", ref)
+	}
+
+	read, err := s.ReadString("trace_gadget_foo")
+	if err != nil {
+		t.Fatalf("reading output: %s", err)
+	}
+	if read != output {
+		t.Fatalf("read %d bytes, expected %d", len(read), len(output))
+	}
+
+	// A new output replaces the previous one.
+	ref, err = s.Write("trace_gadget_foo", "small")
+	if err != nil {
+		t.Fatalf("writing output: %s", err)
+	}
+	if ref.Chunks != 1 {
+		t.Fatalf("unexpected reference %+v", ref)
+	}
+	if read, _ := s.ReadString("trace_gadget_foo"); read != "small" {
+		t.Fatalf("unexpected output %q", read)
+	}
+
+	// No temporary directory is left behind.
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("reading %s: %s", dir, err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "trace_gadget_foo" {
+		t.Fatalf("unexpected entries %v", entries)
+	}
+
+	if err := s.Remove("trace_gadget_foo"); err != nil {
+		t.Fatalf("removing output: %s", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "trace_gadget_foo")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("output not removed: %v", err)
+	}
+}
+
+func TestStoreLimits(t *testing.T) {
+	s := New(t.TempDir())
+
+	if _, err := s.Write("trace_gadget_foo", strings.Repeat("x", MaxChunks*ChunkSize+1)); err == nil {
+		t.Fatal("expected an error writing an output exceeding the limit")
+	}
+
+	for _, id := range []string{"", "../foo", ".trace", "foo/bar"} {
+		if _, err := s.Write(id, "output"); err == nil {
+			t.Errorf("expected an error writing the output of %q", id)
+		}
+	}
+}
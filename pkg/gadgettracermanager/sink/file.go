@@ -0,0 +1,151 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kinvolk/inspektor-gadget/pkg/encryptedfile"
+)
+
+var (
+	// FileDir is the directory of the node where the files of the file
+	// sinks are written.
+	FileDir = "/var/log/gadget"
+
+	// HostRoot is where the root of the node is mounted in the gadget pod.
+	HostRoot = "/host"
+)
+
+type fileSink struct {
+	f *os.File
+
+	// w is f, or the writer encrypting the events written to f.
+	w io.Writer
+
+	// index is the index of f, nil if f is encrypted.
+	index       *os.File
+	lastIndexed time.Time
+	now         func() time.Time
+}
+
+func newFileSink(name, publicKey string) (*fileSink, error) {
+	dir := filepath.Join(HostRoot, FileDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating %s: %w", FileDir, err)
+	}
+
+	path := filepath.Join(dir, name)
+	if err := checkEncryption(path, publicKey != ""); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Join(FileDir, name), err)
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", filepath.Join(FileDir, name), err)
+	}
+
+	s := &fileSink{f: f, w: f, now: time.Now}
+
+	if publicKey == "" {
+		s.index, err = os.OpenFile(indexPath(path), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("opening the index of %s: %w", filepath.Join(FileDir, name), err)
+		}
+	} else {
+		pub, err := encryptedfile.ParsePublicKey([]byte(publicKey))
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if s.w, err = encryptedfile.NewWriter(f, pub); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("writing %s: %w", filepath.Join(FileDir, name), err)
+		}
+	}
+
+	return s, nil
+}
+
+// checkEncryption makes sure that the events aren't appended in plaintext
+// to an encrypted file, nor encrypted to a plaintext one.
+func checkEncryption(path string, encrypted bool) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	prefix := make([]byte, len(encryptedfile.Magic))
+	n, err := io.ReadFull(f, prefix)
+	if n == 0 {
+		return nil
+	}
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return err
+	}
+
+	switch isEncrypted := encryptedfile.IsEncrypted(prefix[:n]); {
+	case encrypted && !isEncrypted:
+		return errors.New("the file already contains plaintext events")
+	case !encrypted && isEncrypted:
+		return errors.New("the file is encrypted, a public key is needed to append events to it")
+	}
+
+	return nil
+}
+
+func (s *fileSink) Write(lines []string) error {
+	data := []byte(strings.Join(lines, "\n") + "\n")
+	n, err := s.w.Write(data)
+	if err != nil || s.index == nil {
+		return err
+	}
+
+	now := s.now()
+	if now.Sub(s.lastIndexed) < IndexInterval {
+		return nil
+	}
+
+	// The file is opened with O_APPEND, so the position after the write
+	// is right after the data, even if other traces append to the file.
+	end, err := s.f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("indexing the events: %w", err)
+	}
+	entry := indexEntry{time: now, offset: end - int64(n)}
+	if _, err := s.index.WriteString(entry.String()); err != nil {
+		return fmt.Errorf("indexing the events: %w", err)
+	}
+	s.lastIndexed = now
+
+	return nil
+}
+
+func (s *fileSink) Close() error {
+	if s.index != nil {
+		s.index.Close()
+	}
+	return s.f.Close()
+}
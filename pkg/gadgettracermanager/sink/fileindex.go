@@ -0,0 +1,166 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kinvolk/inspektor-gadget/pkg/encryptedfile"
+)
+
+// The files of the file sinks are split in segments of IndexInterval, whose
+// time and offset are appended to an index next to the file. It allows to
+// read only the events written since a given time, at the granularity of
+// the segments, without parsing the events.
+//
+//	index: entry...
+//	entry: time (Unix nanoseconds) | ' ' | offset | '\n'
+//
+// The index of the encrypted files isn't written, their records can't be
+// decrypted from the middle of the file.
+
+// IndexInterval is the minimum time between two entries of the index.
+const IndexInterval = 10 * time.Second
+
+// ErrNoIndex is returned when reading the events written since a time from
+// a file without index, e.g. an encrypted one.
+var ErrNoIndex = errors.New("the file has no index")
+
+// indexPath returns the path of the index of the file at path.
+func indexPath(path string) string {
+	return filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".index")
+}
+
+type indexEntry struct {
+	time   time.Time
+	offset int64
+}
+
+func (e indexEntry) String() string {
+	return fmt.Sprintf("%d %d\n", e.time.UnixNano(), e.offset)
+}
+
+func parseIndex(r io.Reader) ([]indexEntry, error) {
+	entries := []indexEntry{}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			// The last entry can be truncated if the node crashed
+			// while it was being written.
+			continue
+		}
+		nsec, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		offset, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, indexEntry{time: time.Unix(0, nsec), offset: offset})
+	}
+
+	return entries, scanner.Err()
+}
+
+// sinceOffset returns the offset of the segment containing the events
+// written at since, the events of the following segments being newer.
+func sinceOffset(entries []indexEntry, since time.Time) int64 {
+	offset := int64(0)
+	for _, e := range entries {
+		if e.time.After(since) {
+			break
+		}
+		offset = e.offset
+	}
+	return offset
+}
+
+// ReadFile copies to w the events of the file of a file sink written since
+// the given time, or all of them if since is zero. The events written in
+// the segment of since before it are included too.
+func ReadFile(name string, since time.Time, w io.Writer) error {
+	if filepath.Base(name) != name || name == "." || name == ".." {
+		return fmt.Errorf("%q is not a valid file name", name)
+	}
+
+	path := filepath.Join(HostRoot, FileDir, name)
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", filepath.Join(FileDir, name), err)
+	}
+	defer f.Close()
+
+	if !since.IsZero() {
+		offset, err := fileOffset(f, path, since)
+		if err != nil {
+			return fmt.Errorf("%s: %w", filepath.Join(FileDir, name), err)
+		}
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			return err
+		}
+	}
+
+	_, err = io.Copy(w, f)
+	return err
+}
+
+func fileOffset(f *os.File, path string, since time.Time) (int64, error) {
+	prefix := make([]byte, len(encryptedfile.Magic))
+	n, err := f.ReadAt(prefix, 0)
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+	if encryptedfile.IsEncrypted(prefix[:n]) {
+		return 0, fmt.Errorf("%w: it's encrypted, copy it from the node and decrypt it", ErrNoIndex)
+	}
+
+	index, err := os.Open(indexPath(path))
+	if os.IsNotExist(err) {
+		return 0, ErrNoIndex
+	} else if err != nil {
+		return 0, err
+	}
+	defer index.Close()
+
+	entries, err := parseIndex(index)
+	if err != nil {
+		return 0, fmt.Errorf("reading the index: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	offset := sinceOffset(entries, since)
+	if offset > info.Size() {
+		// The file was truncated, e.g. by a log rotation, after
+		// the index was written.
+		return 0, nil
+	}
+
+	return offset, nil
+}
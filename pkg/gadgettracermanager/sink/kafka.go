@@ -0,0 +1,91 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// kafkaSink produces the batches of events to a Kafka topic through a
+// Kafka REST proxy, with its v2 API, rather than with the Kafka protocol.
+// Each event is the JSON value of a record.
+type kafkaSink struct {
+	url    string
+	client *http.Client
+
+	// displayURL is used in the errors instead of the URL, which could
+	// contain credentials.
+	displayURL string
+}
+
+type kafkaRecord struct {
+	Value json.RawMessage `json:"value"`
+}
+
+type kafkaRecords struct {
+	Records []kafkaRecord `json:"records"`
+}
+
+func newKafkaSink(config Config) *kafkaSink {
+	return &kafkaSink{
+		url:        config.Target,
+		client:     &http.Client{Timeout: WebhookTimeout},
+		displayURL: config.DisplayTarget(),
+	}
+}
+
+func (s *kafkaSink) Write(lines []string) error {
+	records := kafkaRecords{Records: make([]kafkaRecord, 0, len(lines))}
+	for _, line := range lines {
+		records.Records = append(records.Records, kafkaRecord{Value: json.RawMessage(line)})
+	}
+
+	body, err := json.Marshal(records)
+	if err != nil {
+		return fmt.Errorf("encoding the records: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = s.displayURL
+		}
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("kafka REST proxy returned %s", resp.Status)
+	}
+	return nil
+}
+
+func (s *kafkaSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,108 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	eventsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "inspektor_gadget",
+			Name:      "trace_events_total",
+			Help:      "Number of events published by the traces with a prometheus sink, by type",
+		},
+		[]string{"namespace", "trace", "gadget", "node", "type"},
+	)
+
+	metricsMu         sync.Mutex
+	metricsRegistered bool
+)
+
+// RegisterMetrics registers the metrics of the prometheus sinks. Without
+// it, the creation of the prometheus sinks fails as their metrics wouldn't
+// be served.
+func RegisterMetrics(registerer prometheus.Registerer) error {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+
+	if err := registerer.Register(eventsTotal); err != nil {
+		return err
+	}
+	metricsRegistered = true
+
+	return nil
+}
+
+// prometheusSink counts the events by type.
+type prometheusSink struct {
+	trace Trace
+
+	// types are the types of events counted, to delete their counters
+	// when the sink is closed.
+	types map[string]struct{}
+}
+
+func newPrometheusSink(trace Trace) (*prometheusSink, error) {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+
+	if !metricsRegistered {
+		return nil, errors.New("the metrics aren't served by the gadget pods, deploy them with --metrics-address")
+	}
+
+	return &prometheusSink{
+		trace: trace,
+		types: make(map[string]struct{}),
+	}, nil
+}
+
+func (s *prometheusSink) labels(eventType string) prometheus.Labels {
+	return prometheus.Labels{
+		"namespace": s.trace.Namespace,
+		"trace":     s.trace.Name,
+		"gadget":    s.trace.Gadget,
+		"node":      s.trace.Node,
+		"type":      eventType,
+	}
+}
+
+func (s *prometheusSink) Write(lines []string) error {
+	for _, line := range lines {
+		var event struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			return err
+		}
+
+		eventsTotal.With(s.labels(event.Type)).Inc()
+		s.types[event.Type] = struct{}{}
+	}
+
+	return nil
+}
+
+func (s *prometheusSink) Close() error {
+	for eventType := range s.types {
+		eventsTotal.Delete(s.labels(eventType))
+	}
+	return nil
+}
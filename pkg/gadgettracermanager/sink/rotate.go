@@ -0,0 +1,169 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// rotatingFileSink writes the events to a local file, renamed to
+// <path>.1 once it reaches its maximum size, the previous ones being
+// shifted to <path>.2 and so on. It's used by the CLI to record events
+// for long periods without filling the disk.
+type rotatingFileSink struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+	compress   bool
+
+	f    *os.File
+	size int64
+}
+
+// NewRotatingFile returns a sink writing the events to the file at path.
+// When maxSize is positive, the file is rotated before exceeding it and
+// only the last maxBackups rotated files are kept, compressed with gzip if
+// compress is set.
+func NewRotatingFile(path string, maxSize int64, maxBackups int, compress bool) (Sink, error) {
+	s := &rotatingFileSink{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+		compress:   compress,
+	}
+
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *rotatingFileSink) open() error {
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	s.f = f
+	s.size = info.Size()
+
+	return nil
+}
+
+// backupPath returns the path of the n-th rotated file.
+func (s *rotatingFileSink) backupPath(n int) string {
+	path := fmt.Sprintf("%s.%d", s.path, n)
+	if s.compress {
+		path += ".gz"
+	}
+	return path
+}
+
+func (s *rotatingFileSink) rotate() error {
+	if err := s.f.Close(); err != nil {
+		return err
+	}
+	s.f = nil
+
+	if s.maxBackups <= 0 {
+		if err := os.Remove(s.path); err != nil {
+			return err
+		}
+		return s.open()
+	}
+
+	for n := s.maxBackups - 1; n > 0; n-- {
+		err := os.Rename(s.backupPath(n), s.backupPath(n+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	if s.compress {
+		if err := compressFile(s.path, s.backupPath(1)); err != nil {
+			return fmt.Errorf("compressing %s: %w", s.path, err)
+		}
+		if err := os.Remove(s.path); err != nil {
+			return err
+		}
+	} else if err := os.Rename(s.path, s.backupPath(1)); err != nil {
+		return err
+	}
+
+	return s.open()
+}
+
+func compressFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	w := gzip.NewWriter(out)
+	if _, err := io.Copy(w, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
+
+func (s *rotatingFileSink) Write(lines []string) error {
+	if s.f == nil {
+		// A previous rotation failed.
+		if err := s.open(); err != nil {
+			return err
+		}
+	}
+
+	data := []byte(strings.Join(lines, "\n") + "\n")
+	if s.maxSize > 0 && s.size > 0 && s.size+int64(len(data)) > s.maxSize {
+		if err := s.rotate(); err != nil {
+			return fmt.Errorf("rotating %s: %w", s.path, err)
+		}
+	}
+
+	n, err := s.f.Write(data)
+	s.size += int64(n)
+	return err
+}
+
+func (s *rotatingFileSink) Close() error {
+	if s.f == nil {
+		return nil
+	}
+	return s.f.Close()
+}
@@ -0,0 +1,202 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/stream"
+)
+
+// MaxBatchSize is the maximum number of events written at once to a sink.
+const MaxBatchSize = 100
+
+// Status is the health of a sink.
+type Status struct {
+	Kind   string
+	Target string
+
+	// Healthy tells if the last batch of events was written.
+	Healthy bool
+
+	EventsWritten uint64
+	EventsDropped uint64
+
+	// LastError is the last error writing the events, or why they were
+	// dropped.
+	LastError string
+}
+
+// Runner writes the lines published in a stream to a sink, from its own
+// goroutine so that a slow sink can't slow down the gadget or the other
+// sinks: the lines it can't keep up with are dropped.
+type Runner struct {
+	config Config
+	sink   Sink
+	stream *stream.GadgetStream
+
+	mu     sync.Mutex
+	status Status
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// Start starts writing the lines published in gadgetStream from now on to
+// sink.
+func Start(config Config, sink Sink, gadgetStream *stream.GadgetStream) *Runner {
+	r := &Runner{
+		config: config,
+		sink:   sink,
+		stream: gadgetStream,
+		status: Status{
+			Kind:    config.Kind,
+			Target:  config.DisplayTarget(),
+			Healthy: true,
+		},
+		done: make(chan struct{}),
+	}
+
+	// Subscribe before returning so that no line published after Start()
+	// is missed.
+	ch := gadgetStream.SubscribeSink()
+
+	r.wg.Add(1)
+	go r.run(ch)
+
+	return r
+}
+
+// Config returns the configuration of the sink.
+func (r *Runner) Config() Config {
+	return r.config
+}
+
+// Status returns the health of the sink.
+func (r *Runner) Status() Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.status
+}
+
+// Stop stops writing to the sink and closes it.
+func (r *Runner) Stop() {
+	close(r.done)
+	r.wg.Wait()
+	r.sink.Close()
+}
+
+// Drain waits for the lines published before the stream was closed to be
+// written, and closes the sink. Unlike Stop, it doesn't drop them: the
+// stream must have been closed.
+func (r *Runner) Drain() {
+	r.wg.Wait()
+	r.sink.Close()
+}
+
+func (r *Runner) run(ch chan stream.TimestampedLine) {
+	defer r.wg.Done()
+
+	for ch != nil {
+		if !r.consume(ch) {
+			r.stream.Unsubscribe(ch)
+			return
+		}
+
+		// The subscription is renewed when the sink was disconnected
+		// for being too slow.
+		ch = r.stream.SubscribeSink()
+	}
+}
+
+// consume writes the lines received on ch in batches. It returns true if
+// the sink was disconnected and has to subscribe again, false if it has to
+// stop.
+func (r *Runner) consume(ch chan stream.TimestampedLine) bool {
+	for {
+		var l stream.TimestampedLine
+		var ok bool
+
+		select {
+		case <-r.done:
+			return false
+		case l, ok = <-ch:
+		}
+
+		lines := []string{}
+		for {
+			if !ok {
+				// The stream was closed.
+				r.write(lines)
+				return false
+			}
+
+			switch {
+			case l.Disconnected:
+				r.write(lines)
+				r.dropped(l.LostCount)
+				return true
+			case l.EventLost:
+				r.dropped(l.LostCount)
+			default:
+				lines = append(lines, l.Line)
+			}
+
+			if len(lines) == MaxBatchSize {
+				break
+			}
+
+			select {
+			case l, ok = <-ch:
+				continue
+			default:
+			}
+			break
+		}
+
+		r.write(lines)
+	}
+}
+
+func (r *Runner) write(lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+
+	err := r.sink.Write(lines)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err != nil {
+		r.status.Healthy = false
+		r.status.EventsDropped += uint64(len(lines))
+		r.status.LastError = err.Error()
+		return
+	}
+
+	r.status.Healthy = true
+	r.status.EventsWritten += uint64(len(lines))
+}
+
+func (r *Runner) dropped(count uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.status.EventsDropped += count
+	r.status.LastError = fmt.Sprintf("%d events dropped because the sink was too slow", count)
+}
@@ -0,0 +1,142 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sink implements the outputs, besides the stream read by the
+// clients, to which the tracer manager sends the events of a trace. They
+// are configured with the parameters of the trace.
+package sink
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strconv"
+
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	"github.com/kinvolk/inspektor-gadget/pkg/encryptedfile"
+)
+
+const (
+	KindFile       = "file"
+	KindWebhook    = "webhook"
+	KindPrometheus = "prometheus"
+	KindKafka      = "kafka"
+)
+
+// Sink is an output to which the events of a trace are written.
+type Sink interface {
+	// Write writes a batch of events, each of them being a JSON line as
+	// published by the gadget.
+	Write(lines []string) error
+
+	Close() error
+}
+
+// Config is the configuration of a sink.
+type Config struct {
+	// Kind is KindFile, KindWebhook, KindPrometheus or KindKafka.
+	Kind string
+
+	// Target is the name of the file for KindFile, the URL for
+	// KindWebhook and the URL of the topic in a Kafka REST proxy for
+	// KindKafka.
+	Target string
+
+	// PublicKey is the PEM encoded public key the file of KindFile is
+	// encrypted for, it's written in plaintext if empty.
+	PublicKey string
+}
+
+// Trace identifies the trace whose events are written to a sink.
+type Trace struct {
+	Namespace string
+	Name      string
+	Gadget    string
+	Node      string
+}
+
+// ParseConfigs returns the configurations of the sinks given in the
+// parameters of a trace.
+func ParseConfigs(params map[string]string) ([]Config, error) {
+	configs := []Config{}
+
+	publicKey := params[gadgetv1alpha1.OutputPublicKeyParam]
+	if publicKey != "" {
+		if _, err := encryptedfile.ParsePublicKey([]byte(publicKey)); err != nil {
+			return nil, fmt.Errorf("invalid %q: %w", gadgetv1alpha1.OutputPublicKeyParam, err)
+		}
+	}
+
+	if val, ok := params[gadgetv1alpha1.SinkFileParam]; ok && val != "" {
+		if filepath.Base(val) != val || val == "." || val == ".." {
+			return nil, fmt.Errorf("%q is not valid for %q: expected a file name", val, gadgetv1alpha1.SinkFileParam)
+		}
+		configs = append(configs, Config{Kind: KindFile, Target: val, PublicKey: publicKey})
+	} else if publicKey != "" {
+		return nil, fmt.Errorf("%q is only used with %q", gadgetv1alpha1.OutputPublicKeyParam, gadgetv1alpha1.SinkFileParam)
+	}
+
+	if val, ok := params[gadgetv1alpha1.SinkWebhookParam]; ok && val != "" {
+		u, err := url.Parse(val)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return nil, fmt.Errorf("%q is not a valid http(s) URL for %q", val, gadgetv1alpha1.SinkWebhookParam)
+		}
+		configs = append(configs, Config{Kind: KindWebhook, Target: val})
+	}
+
+	if val, ok := params[gadgetv1alpha1.SinkPrometheusParam]; ok && val != "" {
+		enabled, err := strconv.ParseBool(val)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not valid for %q", val, gadgetv1alpha1.SinkPrometheusParam)
+		}
+		if enabled {
+			configs = append(configs, Config{Kind: KindPrometheus})
+		}
+	}
+
+	return configs, nil
+}
+
+// New creates the sink of config for the events of trace.
+func New(config Config, trace Trace) (Sink, error) {
+	switch config.Kind {
+	case KindFile:
+		return newFileSink(config.Target, config.PublicKey)
+	case KindWebhook:
+		return newWebhookSink(config, trace), nil
+	case KindPrometheus:
+		return newPrometheusSink(trace)
+	case KindKafka:
+		return newKafkaSink(config), nil
+	}
+	return nil, fmt.Errorf("unknown sink %q", config.Kind)
+}
+
+// DisplayTarget returns the target of config without the parts of the URL
+// of a webhook or a Kafka REST proxy that could contain credentials, i.e.
+// the user information and the query.
+func (c Config) DisplayTarget() string {
+	if c.Kind != KindWebhook && c.Kind != KindKafka {
+		return c.Target
+	}
+
+	u, err := url.Parse(c.Target)
+	if err != nil {
+		return ""
+	}
+	u.User = nil
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String()
+}
@@ -0,0 +1,77 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// WebhookTimeout is the maximum time spent posting a batch of events.
+const WebhookTimeout = 5 * time.Second
+
+// webhookSink posts the batches of events as newline-delimited JSON.
+type webhookSink struct {
+	url    string
+	trace  Trace
+	client *http.Client
+
+	// displayURL is used in the errors instead of the URL, which could
+	// contain credentials.
+	displayURL string
+}
+
+func newWebhookSink(config Config, trace Trace) *webhookSink {
+	return &webhookSink{
+		url:        config.Target,
+		trace:      trace,
+		client:     &http.Client{Timeout: WebhookTimeout},
+		displayURL: config.DisplayTarget(),
+	}
+}
+
+func (s *webhookSink) Write(lines []string) error {
+	req, err := http.NewRequest(http.MethodPost, s.url, strings.NewReader(strings.Join(lines, "\n")+"\n"))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	req.Header.Set("X-Inspektor-Gadget-Trace", s.trace.Namespace+"/"+s.trace.Name)
+	req.Header.Set("X-Inspektor-Gadget-Node", s.trace.Node)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = s.displayURL
+		}
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+func (s *webhookSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
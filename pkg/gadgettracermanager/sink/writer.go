@@ -0,0 +1,40 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"io"
+	"strings"
+)
+
+// writerSink writes the events to a writer, e.g. the standard output of the
+// CLI. The writer isn't closed with the sink.
+type writerSink struct {
+	w io.Writer
+}
+
+// NewWriter returns a sink writing the events to w, one per line.
+func NewWriter(w io.Writer) Sink {
+	return &writerSink{w: w}
+}
+
+func (s *writerSink) Write(lines []string) error {
+	_, err := io.WriteString(s.w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+func (s *writerSink) Close() error {
+	return nil
+}
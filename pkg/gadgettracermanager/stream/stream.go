@@ -22,12 +22,73 @@ import (
 const (
 	HistorySize    = 100
 	SubChannelSize = 250
+
+	// SlowSubscriberTimeout is the time after which a subscriber that
+	// doesn't read its queue fast enough to stop losing events is
+	// disconnected.
+	SlowSubscriberTimeout = 10 * time.Second
 )
 
+// TimestampedLine is an element of the queue of a subscriber. Besides the
+// lines published by the gadget, the queue can contain markers telling the
+// subscriber that it lost events or that it was disconnected.
 type TimestampedLine struct {
 	Line      string
 	Timestamp time.Time
+
+	// EventLost is set on a marker preceding the first line delivered
+	// after LostCount lines were dropped because the queue was full.
 	EventLost bool
+	LostCount uint64
+
+	// Disconnected is set on the last element of the queue of a subscriber
+	// disconnected because it was too slow. LostCount is the number of
+	// lines that it lost since the last delivered one.
+	Disconnected bool
+}
+
+// subscriber is the bounded queue of a subscriber. The last slot of the
+// queue is reserved for the disconnection marker, so that it can always be
+// delivered.
+type subscriber struct {
+	ch chan TimestampedLine
+
+	// sink is set for the subscribers that are sinks of the tracer manager
+	// rather than clients, they aren't counted in Stats.Subscribers.
+	sink bool
+
+	// lost is the number of lines dropped since the last delivered one and
+	// slowSince is when the first of them was dropped.
+	lost      uint64
+	slowSince time.Time
+}
+
+// push tries to enqueue line, preceded by a marker if lines were lost.
+// It returns false if the line was dropped.
+func (s *subscriber) push(line TimestampedLine) bool {
+	needed := 1
+	if s.lost > 0 {
+		needed = 2
+	}
+
+	if len(s.ch)+needed > cap(s.ch)-1 {
+		if s.lost == 0 {
+			s.slowSince = line.Timestamp
+		}
+		s.lost++
+		return false
+	}
+
+	if s.lost > 0 {
+		s.ch <- TimestampedLine{
+			Timestamp: line.Timestamp,
+			EventLost: true,
+			LostCount: s.lost,
+		}
+		s.lost = 0
+	}
+	s.ch <- line
+	return true
 }
 
 type GadgetStream struct {
@@ -36,18 +97,50 @@ type GadgetStream struct {
 	previousLines []TimestampedLine
 
 	// subs contains a list of subscribers
-	subs map[chan TimestampedLine]struct{}
+	subs map[chan TimestampedLine]*subscriber
 
 	closed bool
+
+	// slowSubscriberTimeout is SlowSubscriberTimeout, it can be changed
+	// in tests.
+	slowSubscriberTimeout time.Duration
+
+	// eventsEmitted and eventsDropped count the lines published and the
+	// lines that couldn't be delivered to a subscriber because its queue
+	// was full. disconnections counts the subscribers disconnected because
+	// they were too slow.
+	eventsEmitted  uint64
+	eventsDropped  uint64
+	disconnections uint64
+}
+
+// Stats are the counters of a GadgetStream. They tell if a gadget is
+// actually producing data and if its subscribers keep up with it.
+type Stats struct {
+	EventsEmitted  uint64
+	EventsDropped  uint64
+	Subscribers    int
+	Disconnections uint64
 }
 
 func NewGadgetStream() *GadgetStream {
 	return &GadgetStream{
-		subs: make(map[chan TimestampedLine]struct{}),
+		subs:                  make(map[chan TimestampedLine]*subscriber),
+		slowSubscriberTimeout: SlowSubscriberTimeout,
 	}
 }
 
+// Subscribe returns a channel receiving the lines published from now on,
+// preceded by the last HistorySize lines published before.
 func (g *GadgetStream) Subscribe() chan TimestampedLine {
+	return g.SubscribePrevious(-1)
+}
+
+// SubscribePrevious is like Subscribe but only sends the last previous
+// lines published before the subscription. For top gadgets, where each line
+// is a snapshot of an interval, it allows a late subscriber to see the
+// recent intervals. A negative value sends all the lines kept.
+func (g *GadgetStream) SubscribePrevious(previous int) chan TimestampedLine {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
@@ -55,11 +148,35 @@ func (g *GadgetStream) Subscribe() chan TimestampedLine {
 		return nil
 	}
 
+	lines := g.previousLines
+	if previous >= 0 && previous < len(lines) {
+		lines = lines[len(lines)-previous:]
+	}
+
 	ch := make(chan TimestampedLine, SubChannelSize)
-	for _, l := range g.previousLines {
+	for _, l := range lines {
 		ch <- l
 	}
-	g.subs[ch] = struct{}{}
+	g.subs[ch] = &subscriber{ch: ch}
+
+	return ch
+}
+
+// SubscribeSink is like SubscribePrevious(0) for the sinks sending the lines
+// to other outputs than the clients. They aren't counted as subscribers in
+// the stats.
+func (g *GadgetStream) SubscribeSink() chan TimestampedLine {
+	ch := g.SubscribePrevious(0)
+	if ch == nil {
+		return nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if sub, ok := g.subs[ch]; ok {
+		sub.sink = true
+	}
 
 	return ch
 }
@@ -79,6 +196,11 @@ func (g *GadgetStream) Unsubscribe(ch chan TimestampedLine) {
 	}
 }
 
+// Publish delivers line to all the subscribers. It never blocks: when the
+// queue of a subscriber is full, the line is dropped for it and the
+// subscriber is told how many lines it lost once it catches up. A
+// subscriber that keeps losing lines for longer than SlowSubscriberTimeout
+// is disconnected.
 func (g *GadgetStream) Publish(line string) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
@@ -98,19 +220,46 @@ func (g *GadgetStream) Publish(line string) {
 		g.previousLines = append([]TimestampedLine{}, g.previousLines[1:]...)
 	}
 	g.previousLines = append(g.previousLines, newLine)
+	g.eventsEmitted++
 
-	for ch := range g.subs {
-		queuedCount := len(ch)
-		switch {
-		case queuedCount == cap(ch):
-			// Channel full. There is nothing we can do.
+	for ch, sub := range g.subs {
+		if sub.push(newLine) {
 			continue
-		case queuedCount == cap(ch)-1:
-			// Channel almost full. Last chance to signal the problem.
-			ch <- TimestampedLine{EventLost: true}
-		case queuedCount < cap(ch)-1:
-			ch <- newLine
 		}
+
+		g.eventsDropped++
+
+		if newLine.Timestamp.Sub(sub.slowSince) >= g.slowSubscriberTimeout {
+			// The reserved slot is always free at this point.
+			ch <- TimestampedLine{
+				Timestamp:    newLine.Timestamp,
+				Disconnected: true,
+				LostCount:    sub.lost,
+			}
+			delete(g.subs, ch)
+			close(ch)
+			g.disconnections++
+		}
+	}
+}
+
+// Stats returns the counters of the stream.
+func (g *GadgetStream) Stats() Stats {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	subscribers := 0
+	for _, sub := range g.subs {
+		if !sub.sink {
+			subscribers++
+		}
+	}
+
+	return Stats{
+		EventsEmitted:  g.eventsEmitted,
+		EventsDropped:  g.eventsDropped,
+		Subscribers:    subscribers,
+		Disconnections: g.disconnections,
 	}
 }
 
@@ -0,0 +1,176 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stream
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestStreamStats(t *testing.T) {
+	g := NewGadgetStream()
+
+	ch := g.Subscribe()
+	defer g.Unsubscribe(ch)
+
+	for i := 0; i < SubChannelSize+10; i++ {
+		g.Publish("line")
+	}
+
+	stats := g.Stats()
+	if stats.EventsEmitted != SubChannelSize+10 {
+		t.Fatalf("Expected %d emitted events, got %d", SubChannelSize+10, stats.EventsEmitted)
+	}
+	// The last slot of the queue is reserved for the disconnection marker.
+	if stats.EventsDropped != 11 {
+		t.Fatalf("Expected 11 dropped events, got %d", stats.EventsDropped)
+	}
+	if stats.Subscribers != 1 {
+		t.Fatalf("Expected 1 subscriber, got %d", stats.Subscribers)
+	}
+}
+
+func TestStreamLostEvents(t *testing.T) {
+	g := NewGadgetStream()
+
+	ch := g.Subscribe()
+	defer g.Unsubscribe(ch)
+
+	for i := 0; i < SubChannelSize+10; i++ {
+		g.Publish("line")
+	}
+
+	// Make room for the marker and the next line.
+	<-ch
+	<-ch
+	g.Publish("last")
+
+	var lines []TimestampedLine
+	for len(ch) > 0 {
+		lines = append(lines, <-ch)
+	}
+
+	marker := lines[len(lines)-2]
+	if !marker.EventLost || marker.LostCount != 11 {
+		t.Fatalf("Expected a marker for 11 lost events, got %+v", marker)
+	}
+	if last := lines[len(lines)-1]; last.Line != "last" {
+		t.Fatalf("Expected last line after the marker, got %+v", last)
+	}
+}
+
+func TestStreamSlowSubscriber(t *testing.T) {
+	g := NewGadgetStream()
+	g.slowSubscriberTimeout = 10 * time.Millisecond
+
+	slow := g.Subscribe()
+	fast := g.Subscribe()
+	defer g.Unsubscribe(fast)
+
+	for i := 0; i < SubChannelSize; i++ {
+		g.Publish("line")
+		for len(fast) > 0 {
+			<-fast
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	g.Publish("line")
+
+	var last TimestampedLine
+	count := 0
+	for l := range slow {
+		last = l
+		count++
+	}
+	if !last.Disconnected {
+		t.Fatalf("Expected the slow subscriber to be disconnected, got %+v", last)
+	}
+	if count != SubChannelSize {
+		t.Fatalf("Expected %d elements before the channel is closed, got %d", SubChannelSize, count)
+	}
+
+	stats := g.Stats()
+	if stats.Subscribers != 1 || stats.Disconnections != 1 {
+		t.Fatalf("Expected 1 subscriber and 1 disconnection, got %+v", stats)
+	}
+
+	g.Publish("line")
+	if l := <-fast; l.EventLost || l.Line != "line" {
+		t.Fatalf("Expected the fast subscriber to be unaffected, got %+v", l)
+	}
+}
+
+func TestStreamSubscribePrevious(t *testing.T) {
+	g := NewGadgetStream()
+
+	for i := 0; i < 10; i++ {
+		g.Publish(strconv.Itoa(i))
+	}
+
+	table := []struct {
+		previous int
+		expected []string
+	}{
+		{previous: 0, expected: nil},
+		{previous: 3, expected: []string{"7", "8", "9"}},
+		{previous: 20, expected: []string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9"}},
+		{previous: -1, expected: []string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9"}},
+	}
+
+	for _, entry := range table {
+		ch := g.SubscribePrevious(entry.previous)
+
+		var lines []string
+	loop:
+		for {
+			select {
+			case l := <-ch:
+				lines = append(lines, l.Line)
+			default:
+				break loop
+			}
+		}
+		g.Unsubscribe(ch)
+
+		if !reflect.DeepEqual(lines, entry.expected) {
+			t.Fatalf("previous %d: expected %v, got %v", entry.previous, entry.expected, lines)
+		}
+	}
+}
+
+func TestStreamSubscribeSink(t *testing.T) {
+	g := NewGadgetStream()
+
+	g.Publish("before")
+
+	sink := g.SubscribeSink()
+	defer g.Unsubscribe(sink)
+
+	client := g.Subscribe()
+	defer g.Unsubscribe(client)
+
+	g.Publish("after")
+
+	// Sinks don't receive the lines published before they subscribed.
+	if l := <-sink; l.Line != "after" {
+		t.Fatalf("Expected line %q, got %q", "after", l.Line)
+	}
+
+	if stats := g.Stats(); stats.Subscribers != 1 {
+		t.Fatalf("Expected 1 subscriber, got %d", stats.Subscribers)
+	}
+}
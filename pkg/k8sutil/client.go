@@ -26,7 +26,10 @@ import (
 	"k8s.io/client-go/util/homedir"
 )
 
-func NewClientset(kubeconfigPath string) (*kubernetes.Clientset, error) {
+// NewConfig returns the configuration to access the cluster with the given
+// kubeconfig, or with the service account of the pod if kubeconfigPath is
+// empty and running in a pod, or with $HOME/.kube/config otherwise.
+func NewConfig(kubeconfigPath string) (*rest.Config, error) {
 	var config *rest.Config
 	var err error
 	if kubeconfigPath != "" {
@@ -47,6 +50,15 @@ func NewClientset(kubeconfigPath string) (*kubernetes.Clientset, error) {
 		return nil, err
 	}
 
+	return config, nil
+}
+
+func NewClientset(kubeconfigPath string) (*kubernetes.Clientset, error) {
+	config, err := NewConfig(kubeconfigPath)
+	if err != nil {
+		return nil, err
+	}
+
 	apiclientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,122 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernellog
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// maxJournalEntrySize is the maximum size of an entry printed by
+// journalctl.
+const maxJournalEntrySize = 1024 * 1024
+
+type journalSource struct {
+	cmd     *exec.Cmd
+	scanner *bufio.Scanner
+}
+
+// NewJournalSource returns a source reading the kernel messages logged
+// from now on in the journal of systemd-journald. journalctl is run
+// chrooted in root, the root of the host filesystem, to read the journal
+// with the version of journalctl that wrote it.
+func NewJournalSource(root string) (Source, error) {
+	args := []string{"journalctl", "--dmesg", "--follow", "--lines=0", "--output=json"}
+	if root != "" && root != "/" {
+		args = append([]string{"chroot", root}, args...)
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("running journalctl: %w", err)
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxJournalEntrySize)
+
+	return &journalSource{
+		cmd:     cmd,
+		scanner: scanner,
+	}, nil
+}
+
+func (s *journalSource) Read() (Message, error) {
+	if !s.scanner.Scan() {
+		if err := s.scanner.Err(); err != nil {
+			return Message{}, err
+		}
+		if err := s.cmd.Wait(); err != nil {
+			return Message{}, fmt.Errorf("journalctl: %w", err)
+		}
+		return Message{}, io.EOF
+	}
+
+	return parseJournalEntry(s.scanner.Bytes())
+}
+
+func (s *journalSource) Close() error {
+	return s.cmd.Process.Kill()
+}
+
+// parseJournalEntry parses an entry printed by journalctl --output=json.
+// The message is printed as an array of bytes when it isn't valid UTF-8.
+func parseJournalEntry(line []byte) (Message, error) {
+	var entry struct {
+		Message   json.RawMessage `json:"MESSAGE"`
+		Timestamp string          `json:"_SOURCE_MONOTONIC_TIMESTAMP"`
+		Monotonic string          `json:"__MONOTONIC_TIMESTAMP"`
+	}
+	if err := json.Unmarshal(line, &entry); err != nil {
+		return Message{}, fmt.Errorf("decoding journal entry: %w", err)
+	}
+
+	var text string
+	if err := json.Unmarshal(entry.Message, &text); err != nil {
+		var raw []byte
+		var bytes []int
+		if err := json.Unmarshal(entry.Message, &bytes); err != nil {
+			return Message{}, fmt.Errorf("decoding journal entry message %s: %w", entry.Message, err)
+		}
+		for _, b := range bytes {
+			raw = append(raw, byte(b))
+		}
+		text = string(raw)
+	}
+
+	// The timestamp of the kernel is only missing from the messages logged
+	// before journald started.
+	timestamp := entry.Timestamp
+	if timestamp == "" {
+		timestamp = entry.Monotonic
+	}
+	usec, err := strconv.ParseUint(timestamp, 10, 64)
+	if err != nil {
+		return Message{}, fmt.Errorf("invalid journal entry timestamp %q: %w", timestamp, err)
+	}
+
+	return Message{
+		Timestamp: time.Duration(usec) * time.Microsecond,
+		Text:      text,
+	}, nil
+}
@@ -0,0 +1,171 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package kernellog keeps the recent messages of the kernel log to attach
+// the ones about an event, like the report of the OOM killer, to it.
+package kernellog
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// retention is how long the messages are kept to be matched with the
+	// events.
+	retention = 30 * time.Second
+
+	// maxMessages is the maximum number of messages kept, to bound the
+	// memory used when the kernel logs a lot.
+	maxMessages = 4096
+
+	// findTimeout is how long Find waits for the messages about an event
+	// to be logged.
+	findTimeout = 2 * time.Second
+)
+
+// Message is a message of the kernel log.
+type Message struct {
+	// Timestamp is the time since boot the message was logged at.
+	Timestamp time.Duration
+
+	Text string
+}
+
+// Source reads the messages of the kernel log.
+type Source interface {
+	// Read returns the next message, blocking until one is logged.
+	Read() (Message, error)
+
+	// Close stops the source, making the pending Read fail.
+	Close() error
+}
+
+// Matcher returns the lines of messages about an event, or nil if they
+// aren't there.
+type Matcher func(messages []Message) []string
+
+// Log keeps the messages of the kernel log read from a source during the
+// retention time.
+type Log struct {
+	source  Source
+	timeout time.Duration
+
+	mu       sync.Mutex
+	closed   bool
+	messages []Message
+	received []time.Time
+
+	// logged is closed and replaced each time a message is logged.
+	logged chan struct{}
+
+	done chan struct{}
+}
+
+// New starts reading the messages of source.
+func New(source Source) *Log {
+	l := &Log{
+		source:  source,
+		timeout: findTimeout,
+		logged:  make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	go l.run()
+
+	return l
+}
+
+func (l *Log) run() {
+	defer close(l.done)
+
+	for {
+		msg, err := l.source.Read()
+		if err != nil {
+			l.mu.Lock()
+			closed := l.closed
+			l.mu.Unlock()
+
+			if !closed && !errors.Is(err, io.EOF) && !errors.Is(err, os.ErrClosed) {
+				log.Errorf("reading kernel log: %s", err)
+			}
+			return
+		}
+
+		l.add(msg, time.Now())
+	}
+}
+
+func (l *Log) add(msg Message, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.messages = append(l.messages, msg)
+	l.received = append(l.received, now)
+
+	expired := 0
+	for expired < len(l.received) && now.Sub(l.received[expired]) > retention {
+		expired++
+	}
+	if len(l.messages)-expired > maxMessages {
+		expired = len(l.messages) - maxMessages
+	}
+	l.messages = l.messages[expired:]
+	l.received = l.received[expired:]
+
+	close(l.logged)
+	l.logged = make(chan struct{})
+}
+
+// Find returns the lines returned by match on the messages kept, waiting
+// for them to be logged for up to two seconds. It returns nil if they
+// weren't logged in the meantime.
+func (l *Log) Find(match Matcher) []string {
+	timer := time.NewTimer(l.timeout)
+	defer timer.Stop()
+
+	for {
+		l.mu.Lock()
+		lines := match(l.messages)
+		logged := l.logged
+		l.mu.Unlock()
+
+		if lines != nil {
+			return lines
+		}
+
+		select {
+		case <-logged:
+		case <-timer.C:
+			return nil
+		case <-l.done:
+			return nil
+		}
+	}
+}
+
+// Close stops reading the messages.
+func (l *Log) Close() {
+	l.mu.Lock()
+	l.closed = true
+	l.mu.Unlock()
+
+	l.source.Close()
+	<-l.done
+}
@@ -0,0 +1,183 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernellog
+
+import (
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseKmsgRecord(t *testing.T) {
+	msg, err := parseKmsgRecord("6,1789,29480187,-;stress[4242]: segfault at 0 ip 000055d0 sp 00007ffe error 6 in stress[55d0+1000]\n SUBSYSTEM=cpu\n")
+	if err != nil {
+		t.Fatalf("parsing record: %s", err)
+	}
+
+	expected := Message{
+		Timestamp: 29480187 * time.Microsecond,
+		Text:      "stress[4242]: segfault at 0 ip 000055d0 sp 00007ffe error 6 in stress[55d0+1000]",
+	}
+	if msg != expected {
+		t.Fatalf("expected %+v, got %+v", expected, msg)
+	}
+
+	for _, record := range []string{"", "6,1789,29480187,-", "6,1789;foo", "6,1789,now,-;foo"} {
+		if _, err := parseKmsgRecord(record); err == nil {
+			t.Errorf("expected an error parsing %q", record)
+		}
+	}
+}
+
+func TestParseJournalEntry(t *testing.T) {
+	table := []struct {
+		entry    string
+		expected Message
+	}{
+		{
+			entry:    `{"MESSAGE":"oom-kill:constraint=CONSTRAINT_MEMCG,task=stress,pid=4242,uid=0","_SOURCE_MONOTONIC_TIMESTAMP":"1000042","__MONOTONIC_TIMESTAMP":"1000100"}`,
+			expected: Message{Timestamp: 1000042 * time.Microsecond, Text: "oom-kill:constraint=CONSTRAINT_MEMCG,task=stress,pid=4242,uid=0"},
+		},
+		{
+			entry:    `{"MESSAGE":[102,111,255],"__MONOTONIC_TIMESTAMP":"12"}`,
+			expected: Message{Timestamp: 12 * time.Microsecond, Text: "fo\xff"},
+		},
+	}
+
+	for _, entry := range table {
+		msg, err := parseJournalEntry([]byte(entry.entry))
+		if err != nil {
+			t.Fatalf("parsing %s: %s", entry.entry, err)
+		}
+		if msg != entry.expected {
+			t.Fatalf("parsing %s: expected %+v, got %+v", entry.entry, entry.expected, msg)
+		}
+	}
+
+	if _, err := parseJournalEntry([]byte(`{"MESSAGE":"foo"}`)); err == nil {
+		t.Fatalf("expected an error parsing an entry without timestamp")
+	}
+}
+
+var oomReport = []Message{
+	{Timestamp: 9 * time.Second, Text: "eth0: link up"},
+	{Timestamp: 10 * time.Second, Text: "stress invoked oom-killer: gfp_mask=0xcc0(GFP_KERNEL), order=0, oom_score_adj=997"},
+	{Timestamp: 10 * time.Second, Text: "CPU: 1 PID: 4243 Comm: stress Not tainted 5.15.0"},
+	{Timestamp: 10 * time.Second, Text: "[   4242]     0  4242   263193   262218  2162688        0           997 stress"},
+	{Timestamp: 10 * time.Second, Text: "oom-kill:constraint=CONSTRAINT_MEMCG,nodemask=(null),task=stress,pid=4242,uid=0"},
+	{Timestamp: 10 * time.Second, Text: "Memory cgroup out of memory: Killed process 4242 (stress) total-vm:1052772kB, anon-rss:1048284kB"},
+	{Timestamp: 11 * time.Second, Text: "stress[4300]: segfault at 0 ip 000055d0 sp 00007ffe error 6 in stress[55d0+1000]"},
+	{Timestamp: 11 * time.Second, Text: "Code: 48 89 e5 c7 00 00 00 00 00"},
+}
+
+func TestMatchers(t *testing.T) {
+	table := []struct {
+		description string
+		matcher     Matcher
+		expected    []string
+	}{
+		{
+			description: "OOM kill",
+			matcher:     OOMKill(4242),
+			expected: []string{
+				"stress invoked oom-killer: gfp_mask=0xcc0(GFP_KERNEL), order=0, oom_score_adj=997",
+				"oom-kill:constraint=CONSTRAINT_MEMCG,nodemask=(null),task=stress,pid=4242,uid=0",
+				"Memory cgroup out of memory: Killed process 4242 (stress) total-vm:1052772kB, anon-rss:1048284kB",
+			},
+		},
+		{
+			description: "OOM kill of another process",
+			matcher:     OOMKill(424),
+		},
+		{
+			description: "Segfault",
+			matcher:     Segfault(4300),
+			expected: []string{
+				"stress[4300]: segfault at 0 ip 000055d0 sp 00007ffe error 6 in stress[55d0+1000]",
+				"Code: 48 89 e5 c7 00 00 00 00 00",
+			},
+		},
+		{
+			description: "Segfault of another thread",
+			matcher:     Segfault(4242),
+		},
+	}
+
+	for _, entry := range table {
+		lines := entry.matcher(oomReport)
+		if !reflect.DeepEqual(lines, entry.expected) {
+			t.Errorf("%s: expected %q, got %q", entry.description, entry.expected, lines)
+		}
+	}
+}
+
+type fakeSource struct {
+	messages chan Message
+}
+
+func (s *fakeSource) Read() (Message, error) {
+	msg, ok := <-s.messages
+	if !ok {
+		return Message{}, io.EOF
+	}
+	return msg, nil
+}
+
+func (s *fakeSource) Close() error {
+	close(s.messages)
+	return nil
+}
+
+func TestFind(t *testing.T) {
+	source := &fakeSource{messages: make(chan Message)}
+	l := New(source)
+	defer l.Close()
+	l.timeout = 100 * time.Millisecond
+
+	if lines := l.Find(Segfault(4300)); lines != nil {
+		t.Fatalf("expected no lines before the segfault is logged, got %q", lines)
+	}
+
+	found := make(chan []string)
+	go func() {
+		found <- l.Find(OOMKill(4242))
+	}()
+	for _, msg := range oomReport {
+		source.messages <- msg
+	}
+
+	if lines := <-found; len(lines) != 3 {
+		t.Fatalf("expected the 3 lines of the OOM kill report, got %q", lines)
+	}
+}
+
+func TestRetention(t *testing.T) {
+	l := &Log{logged: make(chan struct{})}
+	now := time.Now()
+
+	l.add(oomReport[0], now.Add(-2*retention))
+	l.add(oomReport[1], now)
+	if len(l.messages) != 1 || l.messages[0] != oomReport[1] {
+		t.Fatalf("expected only the last message to be kept, got %+v", l.messages)
+	}
+
+	for i := 0; i < maxMessages+10; i++ {
+		l.add(oomReport[2], now)
+	}
+	if len(l.messages) != maxMessages || len(l.received) != maxMessages {
+		t.Fatalf("expected %d messages kept, got %d", maxMessages, len(l.messages))
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernellog
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// kmsgRecordSize is the maximum size of a record read from /dev/kmsg.
+const kmsgRecordSize = 8192
+
+type kmsgSource struct {
+	f   *os.File
+	buf []byte
+}
+
+// NewKmsgSource returns a source reading the messages logged from now on
+// in /dev/kmsg.
+func NewKmsgSource() (Source, error) {
+	f, err := os.Open("/dev/kmsg")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := f.Seek(0, io.SeekEnd); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("seeking to the end of /dev/kmsg: %w", err)
+	}
+
+	return &kmsgSource{
+		f:   f,
+		buf: make([]byte, kmsgRecordSize),
+	}, nil
+}
+
+func (s *kmsgSource) Read() (Message, error) {
+	for {
+		n, err := s.f.Read(s.buf)
+		if errors.Is(err, syscall.EPIPE) {
+			// The records not read yet were overwritten, the next read
+			// returns the oldest record still there.
+			continue
+		}
+		if err != nil {
+			return Message{}, err
+		}
+
+		msg, err := parseKmsgRecord(string(s.buf[:n]))
+		if err != nil {
+			return Message{}, err
+		}
+
+		return msg, nil
+	}
+}
+
+func (s *kmsgSource) Close() error {
+	return s.f.Close()
+}
+
+// parseKmsgRecord parses a record of /dev/kmsg, see
+// https://www.kernel.org/doc/Documentation/ABI/testing/dev-kmsg:
+// "<priority>,<sequence>,<timestamp>,<flags>[,...];<message>\n" followed
+// by the key/value pairs of the dictionary, prefixed with a space.
+func parseKmsgRecord(record string) (Message, error) {
+	parts := strings.SplitN(record, ";", 2)
+	if len(parts) != 2 {
+		return Message{}, fmt.Errorf("invalid kmsg record %q", record)
+	}
+
+	fields := strings.Split(parts[0], ",")
+	if len(fields) < 4 {
+		return Message{}, fmt.Errorf("invalid kmsg record prefix %q", parts[0])
+	}
+
+	usec, err := strconv.ParseUint(fields[2], 10, 64)
+	if err != nil {
+		return Message{}, fmt.Errorf("invalid kmsg record timestamp %q: %w", fields[2], err)
+	}
+
+	text := parts[1]
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		text = text[:i]
+	}
+
+	return Message{
+		Timestamp: time.Duration(usec) * time.Microsecond,
+		Text:      text,
+	}, nil
+}
@@ -0,0 +1,92 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernellog
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// reportDuration is how long before the line about the event a report of
+// the kernel can start.
+const reportDuration = time.Second
+
+// OOMKill matches the report of the OOM killer killing the process pid:
+// the line about the process invoking the OOM killer, the one about the
+// constraint and the one about the killed process. The list of tasks and
+// the memory usage in between are skipped.
+func OOMKill(pid uint32) Matcher {
+	killed := fmt.Sprintf("Killed process %d (", pid)
+	constraint := fmt.Sprintf(",pid=%d,", pid)
+
+	return func(messages []Message) []string {
+		for i := len(messages) - 1; i >= 0; i-- {
+			if !strings.Contains(messages[i].Text, killed) {
+				continue
+			}
+
+			lines := []string{messages[i].Text}
+			for j := i - 1; j >= 0; j-- {
+				if messages[i].Timestamp-messages[j].Timestamp > reportDuration {
+					break
+				}
+
+				text := messages[j].Text
+				if strings.HasPrefix(text, "oom-kill:") && strings.Contains(text, constraint) {
+					lines = append([]string{text}, lines...)
+				}
+				if strings.Contains(text, " invoked oom-killer: ") {
+					lines = append([]string{text}, lines...)
+					break
+				}
+			}
+
+			return lines
+		}
+
+		return nil
+	}
+}
+
+// Segfault matches the lines logged when the thread tid gets a SIGSEGV
+// because of a segmentation or a general protection fault: the line about
+// the fault followed by the code around the instruction pointer if the
+// kernel logged it.
+func Segfault(tid uint32) Matcher {
+	prefixes := []string{
+		fmt.Sprintf("[%d]: segfault at ", tid),
+		fmt.Sprintf("[%d] general protection fault ", tid),
+	}
+
+	return func(messages []Message) []string {
+		for i := len(messages) - 1; i >= 0; i-- {
+			for _, prefix := range prefixes {
+				if !strings.Contains(messages[i].Text, prefix) {
+					continue
+				}
+
+				lines := []string{messages[i].Text}
+				if i+1 < len(messages) && strings.HasPrefix(messages[i+1].Text, "Code: ") {
+					lines = append(lines, messages[i+1].Text)
+				}
+
+				return lines
+			}
+		}
+
+		return nil
+	}
+}
@@ -15,6 +15,8 @@
 package localgadgetmanager
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
@@ -22,12 +24,16 @@ import (
 	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
 	containercollection "github.com/kinvolk/inspektor-gadget/pkg/container-collection"
 	containerutils "github.com/kinvolk/inspektor-gadget/pkg/container-utils"
+	"github.com/kinvolk/inspektor-gadget/pkg/container-utils/docker"
 	gadgetcollection "github.com/kinvolk/inspektor-gadget/pkg/gadget-collection"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
 	pb "github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/api"
 	containersmap "github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/containers-map"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/pubsub"
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/stream"
+	"github.com/kinvolk/inspektor-gadget/pkg/runcfanotify"
 	tracercollection "github.com/kinvolk/inspektor-gadget/pkg/tracer-collection"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/sys/unix"
 
@@ -40,6 +46,10 @@ type LocalGadgetManager struct {
 
 	traceFactories map[string]gadgets.TraceFactory
 
+	// eventDecoders decode the events of the gadgets for StreamTyped, by
+	// gadget name.
+	eventDecoders map[string]gadgets.EventDecoder
+
 	// tracers
 	tracerCollection *tracercollection.TracerCollection
 	traceResources   map[string]*gadgetv1alpha1.Trace
@@ -235,7 +245,7 @@ func (l *LocalGadgetManager) PublishEvent(tracerID string, line string) error {
 		return fmt.Errorf("cannot find stream for tracer %q", tracerID)
 	}
 
-	gadgetStream.Publish(line)
+	gadgetStream.Publish(eventtypes.WithSchemaVersion(line))
 	return nil
 }
 
@@ -253,7 +263,7 @@ func (l *LocalGadgetManager) Stream(name string, stop chan struct{}) (chan strin
 		if stop == nil {
 			for len(ch) > 0 {
 				line := <-ch
-				out <- line.Line
+				out <- streamLine(line)
 			}
 			gadgetStream.Unsubscribe(ch)
 			close(out)
@@ -264,8 +274,12 @@ func (l *LocalGadgetManager) Stream(name string, stop chan struct{}) (chan strin
 					gadgetStream.Unsubscribe(ch)
 					close(out)
 					return
-				case line := <-ch:
-					out <- line.Line
+				case line, ok := <-ch:
+					if !ok {
+						close(out)
+						return
+					}
+					out <- streamLine(line)
 				}
 			}
 		}
@@ -273,6 +287,75 @@ func (l *LocalGadgetManager) Stream(name string, stop chan struct{}) (chan strin
 	return out, nil
 }
 
+// StreamTyped is like Stream but decodes the events into the type of the
+// events of the gadget, e.g. *dnstypes.Event for the dns gadget. The lines
+// that can't be decoded are replaced by events of type ERR.
+func (l *LocalGadgetManager) StreamTyped(name string, stop chan struct{}) (<-chan gadgets.Event, error) {
+	traceResource, ok := l.traceResources[name]
+	if !ok {
+		return nil, fmt.Errorf("cannot find trace %q", name)
+	}
+
+	decoder, ok := l.eventDecoders[traceResource.Spec.Gadget]
+	if !ok {
+		return nil, fmt.Errorf("gadget %q doesn't publish events that can be decoded", traceResource.Spec.Gadget)
+	}
+
+	lines, err := l.Stream(name, stop)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan gadgets.Event)
+
+	go func() {
+		defer close(out)
+
+		for line := range lines {
+			event, err := decoder(line)
+			if err != nil {
+				ev := eventtypes.Err(err.Error(), "")
+				event = &ev
+			}
+			out <- event
+		}
+	}()
+
+	return out, nil
+}
+
+// RegisterEventDecoder sets the decoder used by StreamTyped for the events
+// of gadget, replacing the one of the gadget if any. It allows the
+// embedders of the manager to decode the events of the gadgets that don't
+// implement gadgets.TraceFactoryWithEvents.
+func (l *LocalGadgetManager) RegisterEventDecoder(gadget string, decoder gadgets.EventDecoder) {
+	l.eventDecoders[gadget] = decoder
+}
+
+// streamLine returns the line to print for an element of the stream,
+// converting the markers of the stream into events.
+func streamLine(line stream.TimestampedLine) string {
+	var ev eventtypes.Event
+
+	switch {
+	case line.Disconnected:
+		ev = eventtypes.Err(fmt.Sprintf("disconnected for being too slow, %d events lost", line.LostCount), "")
+	case line.EventLost:
+		ev = eventtypes.Warn(fmt.Sprintf("%d events lost", line.LostCount), "")
+	default:
+		return line.Line
+	}
+
+	b, _ := json.Marshal(ev)
+	return string(b)
+}
+
+// Status returns the metrics of the tracers, like the number of containers
+// matching each of them.
+func (l *LocalGadgetManager) Status() string {
+	return l.tracerCollection.StatsDump()
+}
+
 func (l *LocalGadgetManager) Dump() string {
 	out := "List of containers:\n"
 	l.ContainerCollection.ContainerRange(func(c *pb.ContainerDefinition) {
@@ -286,9 +369,31 @@ func (l *LocalGadgetManager) Dump() string {
 		out += fmt.Sprintf("    %+v\n", traceResource)
 		out += fmt.Sprintf("    %+v\n", traceResource.Spec.Filter)
 	}
+	out += "Tracer collection metrics:\n"
+	out += l.tracerCollection.StatsDump()
 	return out
 }
 
+// Close stops all the traces and releases the resources held by the manager:
+// the container watchers, the tracer maps and the containers map. The manager
+// must not be used after calling Close.
+func (l *LocalGadgetManager) Close() {
+	for _, name := range l.ListTraces() {
+		if err := l.Delete(name); err != nil {
+			log.Warnf("failed to delete trace %q: %s", name, err)
+		}
+	}
+
+	// Stop the container watchers first so that the maps below are not
+	// updated anymore while they are being released.
+	l.ContainerCollection.ContainerCollectionClose()
+
+	if l.tracerCollection != nil {
+		l.tracerCollection.Close()
+	}
+	l.containersMap.Close()
+}
+
 // ensureBPFMount ensures /sys/fs/bpf is of type bpf. It is necessary to be able
 // to pin eBPF maps. TODO: Remove the need of using pinning, see issues #619 and
 // #620.
@@ -297,6 +402,12 @@ func ensureBPFMount() error {
 	var buf unix.Statfs_t
 
 	if err := unix.Statfs(bpfPinPath, &buf); err != nil {
+		if errors.Is(err, unix.ENOENT) {
+			// The directory is created by the kernel when it
+			// supports BPF.
+			return withWSL2Hint(fmt.Errorf("%s doesn't exist: the kernel was built without BPF support (CONFIG_BPF_SYSCALL)",
+				bpfPinPath))
+		}
 		return fmt.Errorf("error checking type of %s: %w", bpfPinPath, err)
 	}
 
@@ -311,17 +422,48 @@ func ensureBPFMount() error {
 	log.Infof("Remounting %q as bpf", bpfPinPath)
 
 	if err := unix.Mount("bpf", bpfPinPath, "bpf", 0, ""); err != nil {
+		if errors.Is(err, unix.EPERM) {
+			return fmt.Errorf("error remounting %s as bpf: %w: local-gadget has to run as root",
+				bpfPinPath, err)
+		}
 		return fmt.Errorf("error remounting %s as bpf: %w", bpfPinPath, err)
 	}
 
 	return nil
 }
 
+// withWSL2Hint adds to an error about a kernel feature missing how to
+// provide it on WSL2, whose default kernel lacks some of them.
+func withWSL2Hint(err error) error {
+	if containerutils.DetectWSL() != containerutils.WSL2 {
+		return err
+	}
+	return fmt.Errorf("%w\nhint: %s", err, containerutils.WSL2KernelHint)
+}
+
+// containerWatcher returns the option detecting the creation and the
+// termination of the containers: runc fanotify when the runc binary is
+// reachable, else the polling of the runtimes. The runc of Docker Desktop
+// runs in its own WSL2 distribution, out of reach of the others.
+func containerWatcher(runtimes []*containerutils.RuntimeConfig) (containercollection.ContainerCollectionOption, error) {
+	if runcfanotify.Supported() {
+		return containercollection.WithRuncFanotify(), nil
+	}
+
+	if containerutils.DetectWSL() != containerutils.WSL2 {
+		return nil, errors.New("runc fanotify isn't supported: runc wasn't found or fanotify isn't available")
+	}
+
+	log.Infof("runc wasn't found, polling the containers of the runtimes every second")
+	return containercollection.WithContainerRuntimePolling(runtimes), nil
+}
+
 func NewManager(runtimes []*containerutils.RuntimeConfig) (*LocalGadgetManager, error) {
 	l := &LocalGadgetManager{
 		traceFactories: gadgetcollection.TraceFactoriesForLocalGadget(),
 		traceResources: make(map[string]*gadgetv1alpha1.Trace),
 	}
+	l.eventDecoders = gadgets.EventDecoders(l.traceFactories)
 
 	var err error
 	l.tracerCollection, err = tracercollection.NewTracerCollection(gadgets.PinPath, gadgets.MountMapPrefix, true, &l.ContainerCollection)
@@ -337,6 +479,11 @@ func NewManager(runtimes []*containerutils.RuntimeConfig) (*LocalGadgetManager,
 		return nil, err
 	}
 
+	watcher, err := containerWatcher(runtimes)
+	if err != nil {
+		return nil, err
+	}
+
 	l.containersMap, err = containersmap.NewContainersMap(gadgets.PinPath)
 	if err != nil {
 		return nil, fmt.Errorf("error creating containers map: %w", err)
@@ -345,14 +492,30 @@ func NewManager(runtimes []*containerutils.RuntimeConfig) (*LocalGadgetManager,
 	containerEventFuncs = append(containerEventFuncs, l.containersMap.ContainersMapUpdater())
 	containerEventFuncs = append(containerEventFuncs, l.tracerCollection.TracerMapsUpdater())
 
-	err = l.ContainerCollection.ContainerCollectionInitialize(
+	opts := []containercollection.ContainerCollectionOption{
 		containercollection.WithPubSub(containerEventFuncs...),
 		containercollection.WithCgroupEnrichment(),
 		containercollection.WithLinuxNamespaceEnrichment(),
+	}
+
+	// The pods of the kind clusters run in docker containers, so they
+	// can only be there if docker is one of the runtimes. Their
+	// enrichment has to take place before the one of docker.
+	for _, r := range runtimes {
+		if r.Name == docker.Name {
+			opts = append(opts, containercollection.WithKindEnrichment(r))
+			break
+		}
+	}
+
+	opts = append(opts,
 		containercollection.WithMultipleContainerRuntimesEnrichment(runtimes),
-		containercollection.WithRuncFanotify(),
+		watcher,
 	)
+
+	err = l.ContainerCollection.ContainerCollectionInitialize(opts...)
 	if err != nil {
+		l.Close()
 		return nil, err
 	}
 
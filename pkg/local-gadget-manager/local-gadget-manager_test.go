@@ -23,6 +23,7 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"reflect"
 	"runtime"
 	"strconv"
 	"strings"
@@ -34,6 +35,7 @@ import (
 	"github.com/docker/docker/client"
 
 	containerutils "github.com/kinvolk/inspektor-gadget/pkg/container-utils"
+	audittypes "github.com/kinvolk/inspektor-gadget/pkg/gadgets/audit-seccomp/types"
 	dnstypes "github.com/kinvolk/inspektor-gadget/pkg/gadgets/dns/types"
 	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
 )
@@ -48,6 +50,7 @@ func TestBasic(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to start local gadget manager: %s", err)
 	}
+	defer localGadgetManager.Close()
 	gadgets := localGadgetManager.ListGadgets()
 	if len(gadgets) == 0 {
 		t.Fatalf("Failed to get any gadgets")
@@ -167,6 +170,7 @@ func TestSeccomp(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to start local gadget manager: %s", err)
 	}
+	defer localGadgetManager.Close()
 
 	initialFdList := currentFdList(t)
 
@@ -213,6 +217,7 @@ func TestAuditSeccomp(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to start local gadget manager: %s", err)
 	}
+	defer localGadgetManager.Close()
 
 	initialFdList := currentFdList(t)
 
@@ -252,6 +257,48 @@ func TestAuditSeccomp(t *testing.T) {
 	checkFdList(t, initialFdList, 5, 100*time.Millisecond)
 }
 
+func TestStreamTyped(t *testing.T) {
+	if !*rootTest {
+		t.Skip("skipping test requiring root.")
+	}
+	localGadgetManager, err := NewManager([]*containerutils.RuntimeConfig{{Name: "docker"}})
+	if err != nil {
+		t.Fatalf("Failed to start local gadget manager: %s", err)
+	}
+	defer localGadgetManager.Close()
+
+	containerName := "test-local-gadget-streamtyped001"
+	err = localGadgetManager.AddTracer("audit-seccomp", "my-tracer", containerName, "Stream")
+	if err != nil {
+		t.Fatalf("Failed to create tracer: %s", err)
+	}
+	err = localGadgetManager.Operation("my-tracer", "start")
+	if err != nil {
+		t.Fatalf("Failed to start the tracer: %s", err)
+	}
+
+	seccompProfile := `{"defaultAction":"SCMP_ACT_ALLOW","architectures":["SCMP_ARCH_X86_64"],"syscalls":[{"action":"SCMP_ACT_LOG","names":["unshare"]}]}`
+	runTestContainer(t, containerName, "docker.io/library/alpine", "unshare -i ; echo OK", seccompProfile)
+
+	ch, err := localGadgetManager.StreamTyped("my-tracer", nil)
+	if err != nil {
+		t.Fatalf("Failed to get stream: %s", err)
+	}
+	result := <-ch
+	event, ok := result.(*audittypes.Event)
+	if !ok {
+		t.Fatalf("Expected a *audittypes.Event, got %T: %+v", result, result)
+	}
+	if event.Container != containerName || event.Syscall != "unshare" || event.Code != "log" {
+		t.Fatalf("Failed to get correct Seccomp Audit: %+v", event)
+	}
+
+	err = localGadgetManager.Delete("my-tracer")
+	if err != nil {
+		t.Fatalf("Failed to delete tracer: %s", err)
+	}
+}
+
 func TestDNS(t *testing.T) {
 	if !*rootTest {
 		t.Skip("skipping test requiring root.")
@@ -260,6 +307,7 @@ func TestDNS(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to start local gadget manager: %s", err)
 	}
+	defer localGadgetManager.Close()
 
 	initialFdList := currentFdList(t)
 
@@ -301,7 +349,7 @@ func TestDNS(t *testing.T) {
 		},
 	}
 
-	if event != expectedEvent {
+	if !reflect.DeepEqual(event, expectedEvent) {
 		t.Fatalf("Received: %v, Expected: %v", event, expectedEvent)
 	}
 
@@ -324,7 +372,7 @@ func TestDNS(t *testing.T) {
 		QType:   "A",
 	}
 
-	if event != expectedEvent {
+	if !reflect.DeepEqual(event, expectedEvent) {
 		t.Fatalf("Received: %v, Expected: %v", event, expectedEvent)
 	}
 
@@ -345,7 +393,7 @@ func TestDNS(t *testing.T) {
 		},
 	}
 
-	if event != expectedEvent {
+	if !reflect.DeepEqual(event, expectedEvent) {
 		t.Fatalf("Received: %v, Expected: %v", event, expectedEvent)
 	}
 
@@ -371,6 +419,7 @@ func TestCollector(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to start local gadget manager: %s", err)
 	}
+	defer localGadgetManager.Close()
 
 	err = localGadgetManager.AddTracer("socket-collector", "my-tracer1", "my-container", "Status")
 	if err != nil {
@@ -0,0 +1,195 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package mapdump takes snapshots of the BPF maps of the gadgets, so that
+// they can be inspected offline, e.g. when reporting a bug. The snapshots
+// are encoded in JSON and rendered again with Fprint.
+package mapdump
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/cilium/ebpf"
+)
+
+// DefaultMaxEntries is the maximum number of entries dumped per map.
+const DefaultMaxEntries = 10000
+
+// Snapshot contains the maps of a trace on a node.
+type Snapshot struct {
+	Node      string    `json:"node"`
+	Namespace string    `json:"namespace"`
+	Trace     string    `json:"trace"`
+	Gadget    string    `json:"gadget"`
+	Time      time.Time `json:"time"`
+	Maps      []Map     `json:"maps"`
+}
+
+// Map is the content of a BPF map.
+type Map struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	KeySize    uint32 `json:"keySize"`
+	ValueSize  uint32 `json:"valueSize"`
+	MaxEntries uint32 `json:"maxEntries"`
+
+	// PerCPU is true when the map has a value per CPU: they are in the
+	// PerCPUValues field of the entries instead of Value.
+	PerCPU bool `json:"perCPU,omitempty"`
+
+	Entries []Entry `json:"entries"`
+
+	// Truncated is true when the map had more entries than the ones
+	// dumped.
+	Truncated bool `json:"truncated,omitempty"`
+
+	// Error is set when the map couldn't be dumped, e.g. because its
+	// type can't be iterated.
+	Error string `json:"error,omitempty"`
+}
+
+// Entry is an entry of a BPF map. Keys and values are kept as raw bytes,
+// in the byte order of the node.
+type Entry struct {
+	Key          []byte   `json:"key"`
+	Value        []byte   `json:"value,omitempty"`
+	PerCPUValues [][]byte `json:"perCPUValues,omitempty"`
+}
+
+// MapsOf returns the maps of the objects generated by bpf2go, keyed by
+// their name in the eBPF program. objs is a pointer to the objects or maps
+// struct, whose *ebpf.Map fields have an "ebpf" tag.
+func MapsOf(objs interface{}) map[string]*ebpf.Map {
+	maps := make(map[string]*ebpf.Map)
+	collectMaps(reflect.ValueOf(objs), maps)
+	return maps
+}
+
+var mapType = reflect.TypeOf((*ebpf.Map)(nil))
+
+func collectMaps(v reflect.Value, maps map[string]*ebpf.Map) {
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return
+	}
+
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		switch {
+		case field.Anonymous:
+			collectMaps(v.Field(i), maps)
+		case field.Type == mapType:
+			name := field.Tag.Get("ebpf")
+			m, _ := v.Field(i).Interface().(*ebpf.Map)
+			if name != "" && m != nil {
+				maps[name] = m
+			}
+		}
+	}
+}
+
+// Dump reads the maps, at most maxEntries entries per map. The maps are
+// sorted by name.
+func Dump(maps map[string]*ebpf.Map, maxEntries int) []Map {
+	names := make([]string, 0, len(maps))
+	for name := range maps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	dumped := make([]Map, 0, len(maps))
+	for _, name := range names {
+		dumped = append(dumped, dumpMap(name, maps[name], maxEntries))
+	}
+	return dumped
+}
+
+func dumpMap(name string, m *ebpf.Map, maxEntries int) Map {
+	d := Map{
+		Name:       name,
+		Type:       m.Type().String(),
+		KeySize:    m.KeySize(),
+		ValueSize:  m.ValueSize(),
+		MaxEntries: m.MaxEntries(),
+		PerCPU:     isPerCPU(m.Type()),
+		Entries:    []Entry{},
+	}
+
+	if !canIterate(m.Type()) {
+		d.Error = fmt.Sprintf("maps of type %s can't be dumped", d.Type)
+		return d
+	}
+
+	var key []byte
+	var value []byte
+	var perCPUValues [][]byte
+
+	iter := m.Iterate()
+	for {
+		var ok bool
+		if d.PerCPU {
+			ok = iter.Next(&key, &perCPUValues)
+		} else {
+			ok = iter.Next(&key, &value)
+		}
+		if !ok {
+			break
+		}
+		if len(d.Entries) == maxEntries {
+			d.Truncated = true
+			break
+		}
+
+		entry := Entry{Key: append([]byte{}, key...)}
+		if d.PerCPU {
+			entry.PerCPUValues = make([][]byte, len(perCPUValues))
+			for i, v := range perCPUValues {
+				entry.PerCPUValues[i] = append([]byte{}, v...)
+			}
+		} else {
+			entry.Value = append([]byte{}, value...)
+		}
+		d.Entries = append(d.Entries, entry)
+	}
+	if err := iter.Err(); err != nil {
+		d.Error = fmt.Sprintf("failed to iterate: %s", err)
+	}
+
+	return d
+}
+
+func isPerCPU(t ebpf.MapType) bool {
+	switch t {
+	case ebpf.PerCPUHash, ebpf.PerCPUArray, ebpf.LRUCPUHash:
+		return true
+	}
+	return false
+}
+
+// canIterate tells if the entries of the maps of type t are data worth
+// dumping: the file descriptors of program arrays and the perf buffers
+// aren't.
+func canIterate(t ebpf.MapType) bool {
+	switch t {
+	case ebpf.Hash, ebpf.Array, ebpf.PerCPUHash, ebpf.PerCPUArray,
+		ebpf.LRUHash, ebpf.LRUCPUHash, ebpf.LPMTrie:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mapdump
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/cilium/ebpf"
+)
+
+type testMaps struct {
+	Counts     *ebpf.Map `ebpf:"counts"`
+	MountNsSet *ebpf.Map `ebpf:"mount_ns_set"`
+}
+
+type testPrograms struct {
+	Entry *ebpf.Program `ebpf:"entry"`
+}
+
+type testObjects struct {
+	testMaps
+	testPrograms
+}
+
+func TestMapsOf(t *testing.T) {
+	counts := &ebpf.Map{}
+	objs := &testObjects{testMaps: testMaps{Counts: counts}}
+
+	maps := MapsOf(objs)
+	if len(maps) != 1 || maps["counts"] != counts {
+		t.Fatalf("expected only the counts map, got %v", maps)
+	}
+}
+
+func TestFprint(t *testing.T) {
+	s := &Snapshot{
+		Node:      "node-1",
+		Namespace: "gadget",
+		Trace:     "biotop-abcde",
+		Gadget:    "biotop",
+		Time:      time.Date(2022, 5, 4, 10, 0, 0, 0, time.UTC),
+		Maps: []Map{
+			{
+				Name:       "counts",
+				Type:       "Hash",
+				KeySize:    4,
+				ValueSize:  8,
+				MaxEntries: 10240,
+				Entries: []Entry{
+					{Key: []byte{1, 0, 0, 0}, Value: []byte{0x10, 0, 0, 0, 0, 0, 0, 0}},
+				},
+			},
+			{
+				Name:       "stats",
+				Type:       "PerCPUArray",
+				KeySize:    4,
+				ValueSize:  4,
+				MaxEntries: 1,
+				PerCPU:     true,
+				Entries: []Entry{
+					{Key: []byte{0, 0, 0, 0}, PerCPUValues: [][]byte{{1, 0, 0, 0}, {2, 0, 0, 0}}},
+				},
+			},
+			{
+				Name:       "events",
+				Type:       "PerfEventArray",
+				KeySize:    4,
+				ValueSize:  4,
+				MaxEntries: 4,
+				Entries:    []Entry{},
+				Error:      "maps of type PerfEventArray can't be dumped",
+			},
+			{
+				Name:       "start",
+				Type:       "Hash",
+				KeySize:    6,
+				ValueSize:  8,
+				MaxEntries: 1,
+				Entries: []Entry{
+					{Key: []byte{0xde, 0xad, 0xbe, 0xef, 0x01, 0x02}, Value: []byte{0, 1, 0, 0, 0, 0, 0, 0}},
+				},
+				Truncated: true,
+			},
+		},
+	}
+
+	expected := `Node node-1, trace gadget/biotop-abcde, gadget biotop, taken at 2022-05-04T10:00:00Z
+
+Map counts: Hash, key 4 bytes, value 8 bytes, 1/10240 entries
+  key:   01000000 (1)
+  value: 10000000 00000000 (16)
+
+Map stats: PerCPUArray, key 4 bytes, value 4 bytes, 1/1 entries
+  key:   00000000 (0)
+  cpu 0: 01000000 (1)
+  cpu 1: 02000000 (2)
+  sum:   3
+
+Map events: PerfEventArray, key 4 bytes, value 4 bytes, 0/4 entries
+  error: maps of type PerfEventArray can't be dumped
+
+Map start: Hash, key 6 bytes, value 8 bytes, 1/1 entries (truncated)
+  key:   deadbeef 0102
+  value: 00010000 00000000 (256)
+`
+
+	var buf bytes.Buffer
+	Fprint(&buf, s)
+	if buf.String() != expected {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mapdump
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Fprint renders the snapshot in a human-readable form. Keys and values
+// are printed in hexadecimal, followed by their decimal value when they
+// have the size of an integer. The values of the per-CPU maps are printed
+// for each CPU, and summed when they are integers.
+func Fprint(w io.Writer, s *Snapshot) {
+	fmt.Fprintf(w, "Node %s, trace %s/%s, gadget %s, taken at %s\n",
+		s.Node, s.Namespace, s.Trace, s.Gadget, s.Time.Format(time.RFC3339))
+
+	for _, m := range s.Maps {
+		fmt.Fprintf(w, "\nMap %s: %s, key %d bytes, value %d bytes, %d/%d entries",
+			m.Name, m.Type, m.KeySize, m.ValueSize, len(m.Entries), m.MaxEntries)
+		if m.Truncated {
+			fmt.Fprintf(w, " (truncated)")
+		}
+		fmt.Fprintln(w)
+		if m.Error != "" {
+			fmt.Fprintf(w, "  error: %s\n", m.Error)
+		}
+
+		for _, e := range m.Entries {
+			fmt.Fprintf(w, "  key:   %s\n", formatBytes(e.Key))
+			if !m.PerCPU {
+				fmt.Fprintf(w, "  value: %s\n", formatBytes(e.Value))
+				continue
+			}
+
+			var sum uint64
+			summable := true
+			for cpu, v := range e.PerCPUValues {
+				fmt.Fprintf(w, "  cpu %d: %s\n", cpu, formatBytes(v))
+				n, ok := integer(v)
+				summable = summable && ok
+				sum += n
+			}
+			if summable && len(e.PerCPUValues) > 0 {
+				fmt.Fprintf(w, "  sum:   %d\n", sum)
+			}
+		}
+	}
+}
+
+// formatBytes prints b in hexadecimal, in groups of 4 bytes, followed by
+// its decimal value when it has the size of an integer.
+func formatBytes(b []byte) string {
+	groups := []string{}
+	for i := 0; i < len(b); i += 4 {
+		end := i + 4
+		if end > len(b) {
+			end = len(b)
+		}
+		groups = append(groups, hex.EncodeToString(b[i:end]))
+	}
+	str := strings.Join(groups, " ")
+
+	if n, ok := integer(b); ok {
+		str += fmt.Sprintf(" (%d)", n)
+	}
+	return str
+}
+
+// integer decodes b as a little-endian integer if it has the size of one.
+// The maps are dumped on nodes which are little-endian, as the eBPF
+// programs of the gadgets are only built for them.
+func integer(b []byte) (uint64, bool) {
+	switch len(b) {
+	case 4:
+		return uint64(binary.LittleEndian.Uint32(b)), true
+	case 8:
+		return binary.LittleEndian.Uint64(b), true
+	}
+	return 0, false
+}
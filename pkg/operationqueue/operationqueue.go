@@ -0,0 +1,165 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package operationqueue implements the queue of the operations requested
+// on a trace. The operations are stored as a JSON list in the
+// gadget.kinvolk.io/operations annotation: the clients append them and the
+// trace controller applies and removes them in order. Both update the queue
+// with a JSON Patch testing its previous value, so a patch made on a queue
+// modified in the meantime fails instead of losing an operation.
+package operationqueue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	clientset "github.com/kinvolk/inspektor-gadget/pkg/client/clientset/versioned"
+)
+
+// Annotation is the annotation containing the queue.
+const Annotation = "gadget.kinvolk.io/operations"
+
+// maxRetries is the number of times Append tries to update a queue
+// modified concurrently.
+const maxRetries = 10
+
+// patchOperation is an operation of a JSON Patch, see RFC 6902.
+// Value isn't omitted when nil: a test against a missing value needs it.
+type patchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// annotationPath is the JSON Pointer of the annotation, see RFC 6901.
+var annotationPath = "/metadata/annotations/" + strings.ReplaceAll(Annotation, "/", "~1")
+
+// Parse returns the operations queued in the annotations of a trace.
+func Parse(annotations map[string]string) ([]string, error) {
+	value, ok := annotations[Annotation]
+	if !ok || value == "" {
+		return nil, nil
+	}
+
+	var operations []string
+	if err := json.Unmarshal([]byte(value), &operations); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation %q: %w", Annotation, value, err)
+	}
+	return operations, nil
+}
+
+// Pending tells whether operations are queued in the annotations of a
+// trace. An invalid queue is considered pending until the controller drops
+// it.
+func Pending(annotations map[string]string) bool {
+	operations, err := Parse(annotations)
+	return err != nil || len(operations) > 0
+}
+
+// AppendPatch returns the JSON Patch appending operation to the queue of a
+// trace having the given annotations.
+func AppendPatch(annotations map[string]string, operation string) ([]byte, error) {
+	operations, err := Parse(annotations)
+	if err != nil {
+		return nil, err
+	}
+	operations = append(operations, operation)
+
+	value, err := json.Marshal(operations)
+	if err != nil {
+		return nil, err
+	}
+
+	// Empty annotations are omitted from the trace, so they have to be
+	// added. This replaces the ones added in the meantime, if any: the
+	// test makes the patch fail in this case.
+	if len(annotations) == 0 {
+		return json.Marshal([]patchOperation{
+			{Op: "test", Path: "/metadata/annotations"},
+			{Op: "add", Path: "/metadata/annotations", Value: map[string]string{Annotation: string(value)}},
+		})
+	}
+
+	return json.Marshal([]patchOperation{
+		testCurrent(annotations),
+		{Op: "add", Path: annotationPath, Value: string(value)},
+	})
+}
+
+// PopPatch returns the JSON Patch removing the first operation of the
+// queue of a trace having the given annotations, or the whole queue if
+// it's invalid.
+func PopPatch(annotations map[string]string) ([]byte, error) {
+	operations, err := Parse(annotations)
+	if err != nil || len(operations) <= 1 {
+		return json.Marshal([]patchOperation{
+			testCurrent(annotations),
+			{Op: "remove", Path: annotationPath},
+		})
+	}
+
+	value, err := json.Marshal(operations[1:])
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal([]patchOperation{
+		testCurrent(annotations),
+		{Op: "replace", Path: annotationPath, Value: string(value)},
+	})
+}
+
+// testCurrent returns the JSON Patch operation checking that the queue
+// wasn't modified since the annotations were read. A missing queue is
+// tested against null.
+func testCurrent(annotations map[string]string) patchOperation {
+	test := patchOperation{Op: "test", Path: annotationPath}
+	if value, ok := annotations[Annotation]; ok {
+		test.Value = value
+	}
+	return test
+}
+
+// Append appends operation to the queue of a trace, reading the trace
+// again when the queue was modified concurrently.
+func Append(ctx context.Context, traceClient clientset.Interface, namespace, name, operation string) error {
+	traces := traceClient.GadgetV1alpha1().Traces(namespace)
+
+	var err error
+	for i := 0; i < maxRetries; i++ {
+		trace, getErr := traces.Get(ctx, name, metav1.GetOptions{})
+		if getErr != nil {
+			return getErr
+		}
+
+		patch, patchErr := AppendPatch(trace.ObjectMeta.Annotations, operation)
+		if patchErr != nil {
+			return patchErr
+		}
+
+		// A failed test is reported as an invalid patch.
+		_, err = traces.Patch(ctx, name, types.JSONPatchType, patch, metav1.PatchOptions{})
+		if err == nil || !apierrors.IsInvalid(err) {
+			return err
+		}
+	}
+
+	return fmt.Errorf("appending operation %q to trace %s: queue modified concurrently: %w", operation, name, err)
+}
@@ -0,0 +1,211 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operationqueue
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/types"
+	k8stesting "k8s.io/client-go/testing"
+
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	tracefake "github.com/kinvolk/inspektor-gadget/pkg/client/clientset/versioned/fake"
+)
+
+func TestPatches(t *testing.T) {
+	table := []struct {
+		description string
+		annotations map[string]string
+		append      string
+		pop         string
+	}{
+		{
+			description: "no annotations",
+			annotations: nil,
+			append: `[{"op":"test","path":"/metadata/annotations","value":null},` +
+				`{"op":"add","path":"/metadata/annotations","value":{"gadget.kinvolk.io/operations":"[\"stop\"]"}}]`,
+			pop: `[{"op":"test","path":"/metadata/annotations/gadget.kinvolk.io~1operations","value":null},` +
+				`{"op":"remove","path":"/metadata/annotations/gadget.kinvolk.io~1operations","value":null}]`,
+		},
+		{
+			description: "empty annotations",
+			annotations: map[string]string{},
+			append: `[{"op":"test","path":"/metadata/annotations","value":null},` +
+				`{"op":"add","path":"/metadata/annotations","value":{"gadget.kinvolk.io/operations":"[\"stop\"]"}}]`,
+			pop: `[{"op":"test","path":"/metadata/annotations/gadget.kinvolk.io~1operations","value":null},` +
+				`{"op":"remove","path":"/metadata/annotations/gadget.kinvolk.io~1operations","value":null}]`,
+		},
+		{
+			description: "empty queue",
+			annotations: map[string]string{"foo": "bar"},
+			append: `[{"op":"test","path":"/metadata/annotations/gadget.kinvolk.io~1operations","value":null},` +
+				`{"op":"add","path":"/metadata/annotations/gadget.kinvolk.io~1operations","value":"[\"stop\"]"}]`,
+			pop: `[{"op":"test","path":"/metadata/annotations/gadget.kinvolk.io~1operations","value":null},` +
+				`{"op":"remove","path":"/metadata/annotations/gadget.kinvolk.io~1operations","value":null}]`,
+		},
+		{
+			description: "pending operations",
+			annotations: map[string]string{Annotation: `["start","generate"]`},
+			append: `[{"op":"test","path":"/metadata/annotations/gadget.kinvolk.io~1operations","value":"[\"start\",\"generate\"]"},` +
+				`{"op":"add","path":"/metadata/annotations/gadget.kinvolk.io~1operations","value":"[\"start\",\"generate\",\"stop\"]"}]`,
+			pop: `[{"op":"test","path":"/metadata/annotations/gadget.kinvolk.io~1operations","value":"[\"start\",\"generate\"]"},` +
+				`{"op":"replace","path":"/metadata/annotations/gadget.kinvolk.io~1operations","value":"[\"generate\"]"}]`,
+		},
+	}
+
+	for _, entry := range table {
+		patch, err := AppendPatch(entry.annotations, "stop")
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", entry.description, err)
+		}
+		if string(patch) != entry.append {
+			t.Fatalf("%s: expected append patch %s, got %s", entry.description, entry.append, patch)
+		}
+
+		patch, err = PopPatch(entry.annotations)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", entry.description, err)
+		}
+		if string(patch) != entry.pop {
+			t.Fatalf("%s: expected pop patch %s, got %s", entry.description, entry.pop, patch)
+		}
+	}
+
+	invalid := map[string]string{Annotation: "stop"}
+	if _, err := AppendPatch(invalid, "stop"); err == nil {
+		t.Fatalf("expected an error appending to an invalid queue")
+	}
+	if !Pending(invalid) {
+		t.Fatalf("expected an invalid queue to be pending")
+	}
+}
+
+// newTraceClient returns a fake trace client. The fake clientset generated
+// uses the "gadget" group for the traces, so it's set up with a scheme
+// registering them in this group.
+func newTraceClient(traces ...runtime.Object) (*tracefake.Clientset, k8stesting.ObjectTracker) {
+	gv := schema.GroupVersion{Group: "gadget", Version: "v1alpha1"}
+
+	scheme := runtime.NewScheme()
+	scheme.AddKnownTypes(gv, &gadgetv1alpha1.Trace{}, &gadgetv1alpha1.TraceList{})
+	metav1.AddToGroupVersion(scheme, gv)
+	tracker := k8stesting.NewObjectTracker(scheme, serializer.NewCodecFactory(scheme).UniversalDecoder())
+	for _, trace := range traces {
+		tracker.Add(trace)
+	}
+
+	client := &tracefake.Clientset{}
+	client.AddReactor("*", "*", k8stesting.ObjectReaction(tracker))
+
+	return client, tracker
+}
+
+func queue(t *testing.T, client *tracefake.Clientset) []string {
+	t.Helper()
+
+	trace, err := client.GadgetV1alpha1().Traces("gadget").Get(context.TODO(), "trace", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	operations, err := Parse(trace.ObjectMeta.Annotations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return operations
+}
+
+func TestAppendAndPop(t *testing.T) {
+	ctx := context.TODO()
+	client, _ := newTraceClient(&gadgetv1alpha1.Trace{
+		ObjectMeta: metav1.ObjectMeta{Name: "trace", Namespace: "gadget"},
+	})
+
+	for _, operation := range []string{"start", "stop"} {
+		if err := Append(ctx, client, "gadget", "trace", operation); err != nil {
+			t.Fatalf("appending %q: %s", operation, err)
+		}
+	}
+	if ops := queue(t, client); !reflect.DeepEqual(ops, []string{"start", "stop"}) {
+		t.Fatalf("expected start and stop to be queued, got %v", ops)
+	}
+
+	traces := client.GadgetV1alpha1().Traces("gadget")
+	for _, expected := range [][]string{{"stop"}, nil} {
+		trace, err := traces.Get(ctx, "trace", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		patch, err := PopPatch(trace.ObjectMeta.Annotations)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := traces.Patch(ctx, "trace", types.JSONPatchType, patch, metav1.PatchOptions{}); err != nil {
+			t.Fatalf("popping: %s", err)
+		}
+		if ops := queue(t, client); !reflect.DeepEqual(ops, expected) {
+			t.Fatalf("expected %v to be queued, got %v", expected, ops)
+		}
+	}
+}
+
+func TestAppendConcurrent(t *testing.T) {
+	ctx := context.TODO()
+	client, tracker := newTraceClient(&gadgetv1alpha1.Trace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "trace",
+			Namespace:   "gadget",
+			Annotations: map[string]string{Annotation: `["start"]`},
+		},
+	})
+
+	// Another client appends an operation between the read and the
+	// patch of the first attempt, making the test of the patch fail.
+	gvr := schema.GroupVersionResource{Group: "gadget", Version: "v1alpha1", Resource: "traces"}
+	concurrent := true
+	client.PrependReactor("patch", "traces", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if !concurrent {
+			return false, nil, nil
+		}
+		concurrent = false
+
+		obj, err := tracker.Get(gvr, "gadget", "trace")
+		if err != nil {
+			return true, nil, err
+		}
+		trace := obj.(*gadgetv1alpha1.Trace)
+		trace.ObjectMeta.Annotations[Annotation] = `["start","generate"]`
+		if err := tracker.Update(gvr, trace, "gadget"); err != nil {
+			return true, nil, err
+		}
+
+		return true, nil, apierrors.NewGenericServerResponse(http.StatusUnprocessableEntity,
+			"patch", gvr.GroupResource(), "trace", "testing the value failed", 0, false)
+	})
+
+	if err := Append(ctx, client, "gadget", "trace", "stop"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if ops := queue(t, client); !reflect.DeepEqual(ops, []string{"start", "generate", "stop"}) {
+		t.Fatalf("expected the concurrent operation to be kept, got %v", ops)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package perfmap makes the perf maps written by the JIT compilers in the
+// containers available to the BCC profiler. Runtimes like Java with
+// perf-map-agent or Node.js with --perf-basic-prof write the symbols of the
+// code they generate to /tmp/perf-<pid>.map, <pid> being the PID of the
+// process in its PID namespace. BCC looks for this file in its own /tmp, so
+// the profiler is given a private /tmp with links to the files in the
+// filesystem of the containers.
+package perfmap
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const procRoot = "/proc"
+
+// Result describes the links created by Link.
+type Result struct {
+	// Linked lists the names of the perf maps linked.
+	Linked []string
+
+	// Ambiguous lists the names of the perf maps not linked because
+	// several processes have the same PID in their PID namespace.
+	Ambiguous []string
+}
+
+// Link creates in dir a link to the perf map of each process whose mount
+// namespace is in mntns, or of all the processes if mntns is nil. A perf
+// map name is ambiguous when several processes of different PID
+// namespaces have the same PID: it's not linked as BCC could then show the
+// symbols of another process.
+func Link(dir string, mntns map[uint64]struct{}) (*Result, error) {
+	return link(procRoot, dir, mntns)
+}
+
+func link(root, dir string, mntns map[uint64]struct{}) (*Result, error) {
+	entries, err := ioutil.ReadDir(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list processes: %w", err)
+	}
+
+	maps := make(map[string][]string)
+	for _, entry := range entries {
+		if _, err := strconv.Atoi(entry.Name()); err != nil {
+			continue
+		}
+		procDir := filepath.Join(root, entry.Name())
+
+		// Errors are ignored from here: the process likely terminated
+		// in the meantime.
+		if mntns != nil {
+			ns, err := readMntns(procDir)
+			if err != nil {
+				continue
+			}
+			if _, ok := mntns[ns]; !ok {
+				continue
+			}
+		}
+
+		nspid, err := readNSpid(procDir)
+		if err != nil {
+			continue
+		}
+		name := fmt.Sprintf("perf-%d.map", nspid)
+		path := filepath.Join(procDir, "root", "tmp", name)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		maps[name] = append(maps[name], path)
+	}
+
+	result := &Result{}
+	for name, paths := range maps {
+		if len(paths) > 1 {
+			result.Ambiguous = append(result.Ambiguous, name)
+			continue
+		}
+
+		linkPath := filepath.Join(dir, name)
+		if err := os.Remove(linkPath); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if err := os.Symlink(paths[0], linkPath); err != nil {
+			return nil, fmt.Errorf("failed to link perf map %q: %w", paths[0], err)
+		}
+		result.Linked = append(result.Linked, name)
+	}
+	sort.Strings(result.Linked)
+	sort.Strings(result.Ambiguous)
+
+	return result, nil
+}
+
+// readNSpid returns the PID of a process in its PID namespace, the last one
+// of the NSpid line of its status file. This line is missing before Linux
+// 4.1, the PID of the process in /proc is then used.
+func readNSpid(dir string) (int, error) {
+	status, err := ioutil.ReadFile(filepath.Join(dir, "status"))
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range strings.Split(string(status), "\n") {
+		if !strings.HasPrefix(line, "NSpid:") {
+			continue
+		}
+		fields := strings.Fields(strings.TrimPrefix(line, "NSpid:"))
+		if len(fields) == 0 {
+			return 0, fmt.Errorf("invalid NSpid line %q", line)
+		}
+		return strconv.Atoi(fields[len(fields)-1])
+	}
+	return strconv.Atoi(filepath.Base(dir))
+}
+
+// readMntns returns the inode of the mount namespace of a process from the
+// ns/mnt link of its directory in /proc, like "mnt:[4026531840]".
+func readMntns(dir string) (uint64, error) {
+	link, err := os.Readlink(filepath.Join(dir, "ns", "mnt"))
+	if err != nil {
+		return 0, err
+	}
+	if !strings.HasPrefix(link, "mnt:[") || !strings.HasSuffix(link, "]") {
+		return 0, fmt.Errorf("invalid mount namespace link %q", link)
+	}
+	return strconv.ParseUint(link[len("mnt:["):len(link)-1], 10, 64)
+}
@@ -0,0 +1,125 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package perfmap
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeProcess writes the /proc directory of a process, its root being a
+// directory with a perf map if perfMap isn't empty.
+func writeProcess(t *testing.T, root, pid, mntns, nspid, perfMap string) {
+	t.Helper()
+
+	dir := filepath.Join(root, pid)
+	tmp := filepath.Join(dir, "root", "tmp")
+	if err := os.MkdirAll(tmp, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "ns"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(mntns, filepath.Join(dir, "ns", "mnt")); err != nil {
+		t.Fatal(err)
+	}
+	status := fmt.Sprintf("Name:\tjava\nNSpid:\t%s\t%s\n", pid, nspid)
+	if err := ioutil.WriteFile(filepath.Join(dir, "status"), []byte(status), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if perfMap != "" {
+		path := filepath.Join(tmp, "perf-"+nspid+".map")
+		if err := ioutil.WriteFile(path, []byte(perfMap), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLink(t *testing.T) {
+	root := t.TempDir()
+
+	writeProcess(t, root, "100", "mnt:[4026532500]", "1", "7f0000001000 20 LFoo;bar\n")
+	writeProcess(t, root, "200", "mnt:[4026532600]", "1", "7f0000002000 20 LBaz;qux\n")
+	writeProcess(t, root, "300", "mnt:[4026532600]", "7", "7f0000003000 20 LazyCompile:*main\n")
+	writeProcess(t, root, "400", "mnt:[4026532700]", "9", "")
+
+	table := []struct {
+		description string
+		mntns       map[uint64]struct{}
+		expected    *Result
+		target      map[string]string
+	}{
+		{
+			description: "all the processes",
+			expected:    &Result{Linked: []string{"perf-7.map"}, Ambiguous: []string{"perf-1.map"}},
+			target:      map[string]string{"perf-7.map": "300"},
+		},
+		{
+			description: "selected processes",
+			mntns:       map[uint64]struct{}{4026532500: {}, 4026532700: {}},
+			expected:    &Result{Linked: []string{"perf-1.map"}},
+			target:      map[string]string{"perf-1.map": "100"},
+		},
+	}
+
+	for _, entry := range table {
+		dir := t.TempDir()
+
+		result, err := link(root, dir, entry.mntns)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", entry.description, err)
+		}
+		if !reflect.DeepEqual(result, entry.expected) {
+			t.Fatalf("%s: expected %+v, got %+v", entry.description, entry.expected, result)
+		}
+
+		for name, pid := range entry.target {
+			target, err := os.Readlink(filepath.Join(dir, name))
+			if err != nil {
+				t.Fatalf("%s: %s", entry.description, err)
+			}
+			expected := filepath.Join(root, pid, "root", "tmp", name)
+			if target != expected {
+				t.Fatalf("%s: expected %s to link to %s, got %s", entry.description, name, expected, target)
+			}
+		}
+	}
+}
+
+func TestReadNSpid(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "42")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without the NSpid line, the PID of the directory is used.
+	if err := ioutil.WriteFile(filepath.Join(dir, "status"), []byte("Name:\tnode\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if pid, err := readNSpid(dir); err != nil || pid != 42 {
+		t.Fatalf("expected 42, got %d (%v)", pid, err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "status"), []byte("Name:\tnode\nNSpid:\t42\t12\t1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if pid, err := readNSpid(dir); err != nil || pid != 1 {
+		t.Fatalf("expected 1, got %d (%v)", pid, err)
+	}
+}
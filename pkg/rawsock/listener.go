@@ -0,0 +1,136 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rawsock
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// MaxFrameLen is the maximum length of the frames read by a Listener.
+	MaxFrameLen = 65536
+
+	// pollTimeout is how often the listener checks if it was closed.
+	pollTimeout = time.Second
+)
+
+// TCPFilter is a classic BPF program only accepting TCP over IPv4 or IPv6,
+// so that the rest of the traffic isn't copied to user space.
+var TCPFilter = []unix.SockFilter{
+	// ldh [12] (EtherType)
+	{Code: unix.BPF_LD | unix.BPF_H | unix.BPF_ABS, K: 12},
+	// jeq #0x800, ipv4, next
+	{Code: unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K, Jt: 0, Jf: 2, K: EtherTypeIPv4},
+	// ipv4: ldb [23] (protocol)
+	{Code: unix.BPF_LD | unix.BPF_B | unix.BPF_ABS, K: EthernetHeaderLen + 9},
+	// jeq #6, accept, drop
+	{Code: unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K, Jt: 3, Jf: 4, K: ProtocolTCP},
+	// next: jeq #0x86dd, ipv6, drop
+	{Code: unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K, Jt: 0, Jf: 3, K: EtherTypeIPv6},
+	// ipv6: ldb [20] (next header)
+	{Code: unix.BPF_LD | unix.BPF_B | unix.BPF_ABS, K: EthernetHeaderLen + 6},
+	// jeq #6, accept, drop
+	{Code: unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K, Jt: 0, Jf: 1, K: ProtocolTCP},
+	// accept: ret #MaxFrameLen
+	{Code: unix.BPF_RET | unix.BPF_K, K: MaxFrameLen},
+	// drop: ret #0
+	{Code: unix.BPF_RET | unix.BPF_K, K: 0},
+}
+
+// Listener reads the frames received on a raw socket opened in the network
+// namespace of a process.
+type Listener struct {
+	sockFd int
+	done   chan struct{}
+}
+
+// NewListener opens a raw socket in the network namespace used by the pid
+// and attaches the classic BPF filter to it. Run must then be called to
+// read the frames and close the socket.
+func NewListener(pid uint32, filter []unix.SockFilter) (*Listener, error) {
+	sockFd, err := OpenRawSock(pid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open raw socket: %w", err)
+	}
+
+	prog := &unix.SockFprog{
+		Len:    uint16(len(filter)),
+		Filter: &filter[0],
+	}
+	if err := unix.SetsockoptSockFprog(sockFd, unix.SOL_SOCKET, unix.SO_ATTACH_FILTER, prog); err != nil {
+		unix.Close(sockFd)
+		return nil, fmt.Errorf("failed to attach socket filter: %w", err)
+	}
+
+	return &Listener{
+		sockFd: sockFd,
+		done:   make(chan struct{}),
+	}, nil
+}
+
+// Run reads the frames received on the raw socket until the listener is
+// closed. handleFrame is called for each frame with the type of the packet,
+// e.g. unix.PACKET_OUTGOING, the frame is only valid during the call. tick,
+// if not nil, is called each time before waiting for new frames. The socket
+// is closed here to be sure it's not used after being closed.
+func (l *Listener) Run(tick func(), handleFrame func(frame []byte, pktType uint8)) error {
+	defer unix.Close(l.sockFd)
+
+	frame := make([]byte, MaxFrameLen)
+	fds := []unix.PollFd{{Fd: int32(l.sockFd), Events: unix.POLLIN}}
+
+	for {
+		select {
+		case <-l.done:
+			return nil
+		default:
+		}
+
+		if tick != nil {
+			tick()
+		}
+
+		_, err := unix.Poll(fds, int(pollTimeout/time.Millisecond))
+		if err != nil && !errors.Is(err, unix.EINTR) {
+			return fmt.Errorf("failed to poll raw socket: %w", err)
+		}
+
+		for {
+			n, from, err := unix.Recvfrom(l.sockFd, frame, 0)
+			if err != nil {
+				if !errors.Is(err, unix.EAGAIN) && !errors.Is(err, unix.EINTR) {
+					return fmt.Errorf("failed to read raw socket: %w", err)
+				}
+				break
+			}
+
+			sll, ok := from.(*unix.SockaddrLinklayer)
+			if !ok {
+				continue
+			}
+
+			handleFrame(frame[:n], sll.Pkttype)
+		}
+	}
+}
+
+// Close stops the listener. Run returns within a second.
+func (l *Listener) Close() {
+	close(l.done)
+}
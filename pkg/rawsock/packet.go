@@ -0,0 +1,105 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rawsock
+
+import (
+	"encoding/binary"
+	"net"
+)
+
+const (
+	EthernetHeaderLen = 14
+
+	EtherTypeIPv4 = 0x0800
+	EtherTypeIPv6 = 0x86dd
+
+	IPv6HeaderLen = 40
+
+	ProtocolTCP = 6
+	ProtocolUDP = 17
+
+	TCPFlagFin = 0x01
+	TCPFlagSyn = 0x02
+	TCPFlagRst = 0x04
+	TCPFlagAck = 0x10
+)
+
+// Packet is a TCP segment captured on a raw socket.
+type Packet struct {
+	Saddr   net.IP
+	Daddr   net.IP
+	Sport   uint16
+	Dport   uint16
+	Seq     uint32
+	Flags   uint8
+	Payload []byte
+}
+
+// ParsePacket decodes the Ethernet, IP and TCP headers of a frame. It
+// returns false for frames that aren't TCP over IPv4 or IPv6. IPv6
+// extension headers are not supported.
+func ParsePacket(frame []byte) (*Packet, bool) {
+	if len(frame) < EthernetHeaderLen {
+		return nil, false
+	}
+
+	p := &Packet{}
+	var l4 []byte
+
+	ip := frame[EthernetHeaderLen:]
+	switch binary.BigEndian.Uint16(frame[12:14]) {
+	case EtherTypeIPv4:
+		if len(ip) < 20 || ip[0]>>4 != 4 || ip[9] != ProtocolTCP {
+			return nil, false
+		}
+		ihl := int(ip[0]&0x0f) * 4
+		total := int(binary.BigEndian.Uint16(ip[2:4]))
+		if ihl < 20 || total < ihl || total > len(ip) {
+			return nil, false
+		}
+		p.Saddr = net.IP(ip[12:16])
+		p.Daddr = net.IP(ip[16:20])
+		l4 = ip[ihl:total]
+	case EtherTypeIPv6:
+		if len(ip) < IPv6HeaderLen || ip[0]>>4 != 6 || ip[6] != ProtocolTCP {
+			return nil, false
+		}
+		payloadLen := int(binary.BigEndian.Uint16(ip[4:6]))
+		if IPv6HeaderLen+payloadLen > len(ip) {
+			return nil, false
+		}
+		p.Saddr = net.IP(ip[8:24])
+		p.Daddr = net.IP(ip[24:40])
+		l4 = ip[IPv6HeaderLen : IPv6HeaderLen+payloadLen]
+	default:
+		return nil, false
+	}
+
+	if len(l4) < 20 {
+		return nil, false
+	}
+	dataOffset := int(l4[12]>>4) * 4
+	if dataOffset < 20 || dataOffset > len(l4) {
+		return nil, false
+	}
+
+	p.Sport = binary.BigEndian.Uint16(l4[0:2])
+	p.Dport = binary.BigEndian.Uint16(l4[2:4])
+	p.Seq = binary.BigEndian.Uint32(l4[4:8])
+	p.Flags = l4[13]
+	p.Payload = l4[dataOffset:]
+
+	return p, true
+}
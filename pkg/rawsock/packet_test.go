@@ -0,0 +1,63 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rawsock
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+)
+
+func TestParsePacket(t *testing.T) {
+	payload := []byte("GET / HTTP/1.1\r\n")
+
+	tcp := make([]byte, 20)
+	binary.BigEndian.PutUint16(tcp[0:2], 34567)
+	binary.BigEndian.PutUint16(tcp[2:4], 443)
+	binary.BigEndian.PutUint32(tcp[4:8], 1000)
+	tcp[12] = 5 << 4
+	tcp[13] = TCPFlagFin
+	tcp = append(tcp, payload...)
+
+	ipv4 := make([]byte, 20)
+	ipv4[0] = 0x45
+	binary.BigEndian.PutUint16(ipv4[2:4], uint16(len(ipv4)+len(tcp)))
+	ipv4[9] = ProtocolTCP
+	copy(ipv4[12:16], net.ParseIP("10.0.0.1").To4())
+	copy(ipv4[16:20], net.ParseIP("10.0.0.2").To4())
+
+	frame := make([]byte, EthernetHeaderLen)
+	binary.BigEndian.PutUint16(frame[12:14], EtherTypeIPv4)
+	frame = append(frame, ipv4...)
+	frame = append(frame, tcp...)
+	// Ethernet padding must be ignored
+	frame = append(frame, 0, 0, 0, 0)
+
+	p, ok := ParsePacket(frame)
+	if !ok {
+		t.Fatalf("failed to parse packet")
+	}
+	if p.Saddr.String() != "10.0.0.1" || p.Daddr.String() != "10.0.0.2" ||
+		p.Sport != 34567 || p.Dport != 443 || p.Seq != 1000 || p.Flags != TCPFlagFin ||
+		string(p.Payload) != string(payload) {
+		t.Fatalf("unexpected packet: %+v", p)
+	}
+
+	// UDP
+	frame[EthernetHeaderLen+9] = 17
+	if _, ok := ParsePacket(frame); ok {
+		t.Fatalf("UDP packet shouldn't be parsed")
+	}
+}
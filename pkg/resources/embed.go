@@ -15,7 +15,7 @@
 package resources
 
 import (
-	_ "embed"
+	"embed"
 )
 
 //go:embed crd/bases/gadget.kinvolk.io_traces.yaml
@@ -23,3 +23,15 @@ var TracesCustomResource string
 
 //go:embed rbac/role.yaml
 var RbacRole string
+
+// DeployTemplate is the template of the resources deployed by kubectl
+// gadget deploy, besides the CRD.
+//
+//go:embed manifests/deploy.yaml
+var DeployTemplate string
+
+// releasesFS holds the manifests of the previous releases, see
+// manifests.go.
+//
+//go:embed manifests/releases
+var releasesFS embed.FS
@@ -0,0 +1,197 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/version"
+)
+
+// The manifests of the releases are embedded in kubectl-gadget so it can
+// deploy them, in addition to the ones of its own version:
+//
+//	manifests/releases/<version>/gadget.kinvolk.io_traces.yaml
+//	manifests/releases/<version>/deploy.yaml
+//	manifests/releases/SHA256SUMS
+//
+// They are added by make release-manifests when a release is tagged.
+// SHA256SUMS gives the digest of each of them, as printed by sha256sum,
+// which is checked when they are loaded.
+
+const (
+	releasesDir   = "manifests/releases"
+	crdFile       = "gadget.kinvolk.io_traces.yaml"
+	deployFile    = "deploy.yaml"
+	checksumsFile = "SHA256SUMS"
+)
+
+// Manifest is the CRD and the deployment template of a version of
+// Inspektor Gadget.
+type Manifest struct {
+	Version              string
+	TracesCustomResource string
+	DeployTemplate       string
+}
+
+// Digest returns the SHA-256 digest of the manifest, computed over the CRD
+// followed by the deployment template, in hexadecimal.
+func (m *Manifest) Digest() string {
+	h := sha256.New()
+	h.Write([]byte(m.TracesCustomResource))
+	h.Write([]byte(m.DeployTemplate))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// CurrentManifest returns the manifest built in kubectl-gadget, the one of
+// version.
+func CurrentManifest(version string) *Manifest {
+	return &Manifest{
+		Version:              version,
+		TracesCustomResource: TracesCustomResource,
+		DeployTemplate:       DeployTemplate,
+	}
+}
+
+// parseChecksums parses the checksums of the manifests of the releases, by
+// version.
+func parseChecksums(data []byte) (map[string]string, error) {
+	checksums := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid line %q in %s", line, checksumsFile)
+		}
+		checksums[fields[1]] = fields[0]
+	}
+	return checksums, scanner.Err()
+}
+
+// releaseManifests returns the manifests of the releases embedded in fsys,
+// from the oldest to the newest. It fails if one of them doesn't match its
+// checksum.
+func releaseManifests(fsys fs.FS) ([]*Manifest, error) {
+	data, err := fs.ReadFile(fsys, path.Join(releasesDir, checksumsFile))
+	if err != nil {
+		return nil, fmt.Errorf("reading checksums of the manifests: %w", err)
+	}
+	checksums, err := parseChecksums(data)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := fs.ReadDir(fsys, releasesDir)
+	if err != nil {
+		return nil, fmt.Errorf("listing manifests: %w", err)
+	}
+
+	manifests := []*Manifest{}
+	versions := make(map[*Manifest]*version.Version)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		name := entry.Name()
+		v, err := version.ParseSemantic(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid manifest version: %w", err)
+		}
+
+		dir := path.Join(releasesDir, name)
+		crd, err := fs.ReadFile(fsys, path.Join(dir, crdFile))
+		if err != nil {
+			return nil, fmt.Errorf("reading manifest %s: %w", name, err)
+		}
+		deploy, err := fs.ReadFile(fsys, path.Join(dir, deployFile))
+		if err != nil {
+			return nil, fmt.Errorf("reading manifest %s: %w", name, err)
+		}
+
+		m := &Manifest{
+			Version:              name,
+			TracesCustomResource: string(crd),
+			DeployTemplate:       string(deploy),
+		}
+		checksum, ok := checksums[name]
+		if !ok {
+			return nil, fmt.Errorf("no checksum for manifest %s in %s", name, checksumsFile)
+		}
+		if digest := m.Digest(); digest != checksum {
+			return nil, fmt.Errorf("manifest %s doesn't match its checksum: expected %s, got %s",
+				name, checksum, digest)
+		}
+		manifests = append(manifests, m)
+		versions[m] = v
+	}
+
+	sort.Slice(manifests, func(i, j int) bool {
+		return versions[manifests[i]].LessThan(versions[manifests[j]])
+	})
+
+	return manifests, nil
+}
+
+// Manifests returns the manifests embedded in kubectl-gadget: the ones of
+// the releases, from the oldest to the newest, followed by the one of
+// version, which kubectl-gadget was built from, unless it's a release
+// already listed.
+func Manifests(version string) ([]*Manifest, error) {
+	manifests, err := releaseManifests(releasesFS)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range manifests {
+		if m.Version == version {
+			return manifests, nil
+		}
+	}
+
+	return append(manifests, CurrentManifest(version)), nil
+}
+
+// LookupManifest returns the manifest of a version among the ones of
+// Manifests().
+func LookupManifest(version, currentVersion string) (*Manifest, error) {
+	manifests, err := Manifests(currentVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := []string{}
+	for _, m := range manifests {
+		if m.Version == version {
+			return m, nil
+		}
+		versions = append(versions, m.Version)
+	}
+
+	return nil, fmt.Errorf("no manifest embedded for version %q, available versions: %s",
+		version, strings.Join(versions, ", "))
+}
@@ -0,0 +1,120 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func releasesFSFor(t *testing.T, manifests []*Manifest, checksums string) fstest.MapFS {
+	t.Helper()
+
+	fsys := fstest.MapFS{
+		"manifests/releases/SHA256SUMS": &fstest.MapFile{Data: []byte(checksums)},
+	}
+	for _, m := range manifests {
+		dir := "manifests/releases/" + m.Version
+		fsys[dir+"/"+crdFile] = &fstest.MapFile{Data: []byte(m.TracesCustomResource)}
+		fsys[dir+"/"+deployFile] = &fstest.MapFile{Data: []byte(m.DeployTemplate)}
+	}
+	return fsys
+}
+
+func TestReleaseManifests(t *testing.T) {
+	manifests := []*Manifest{
+		{Version: "v0.10.0", TracesCustomResource: "crd-10", DeployTemplate: "deploy-10"},
+		{Version: "v0.9.1", TracesCustomResource: "crd-9", DeployTemplate: "deploy-9"},
+	}
+	checksums := ""
+	for _, m := range manifests {
+		checksums += fmt.Sprintf("%s  %s\n", m.Digest(), m.Version)
+	}
+
+	got, err := releaseManifests(releasesFSFor(t, manifests, checksums))
+	if err != nil {
+		t.Fatalf("releaseManifests() failed: %s", err)
+	}
+
+	// Sorted by version, not by name.
+	if len(got) != 2 || got[0].Version != "v0.9.1" || got[1].Version != "v0.10.0" {
+		t.Fatalf("unexpected manifests: %+v", got)
+	}
+	if got[0].DeployTemplate != "deploy-9" || got[0].TracesCustomResource != "crd-9" {
+		t.Fatalf("unexpected content for v0.9.1: %+v", got[0])
+	}
+}
+
+func TestReleaseManifestsChecksum(t *testing.T) {
+	manifests := []*Manifest{
+		{Version: "v0.9.1", TracesCustomResource: "crd-9", DeployTemplate: "deploy-9"},
+	}
+
+	tests := []struct {
+		name      string
+		checksums string
+		err       string
+	}{
+		{
+			name:      "mismatch",
+			checksums: strings.Repeat("0", 64) + "  v0.9.1\n",
+			err:       "doesn't match its checksum",
+		},
+		{
+			name:      "missing",
+			checksums: "",
+			err:       "no checksum",
+		},
+		{
+			name:      "invalid",
+			checksums: "v0.9.1\n",
+			err:       "invalid line",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := releaseManifests(releasesFSFor(t, manifests, test.checksums))
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("expected error containing %q, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestDigest(t *testing.T) {
+	// Same as: printf crd deploy | sha256sum
+	m := &Manifest{TracesCustomResource: "crd", DeployTemplate: "deploy"}
+	expected := "b3ca5e59a2422e1e12e78ea62772fc4e0fba7f5e261e67af86e7371a420ae49e"
+	if digest := m.Digest(); digest != expected {
+		t.Fatalf("expected digest %s, got %s", expected, digest)
+	}
+}
+
+func TestLookupManifest(t *testing.T) {
+	m, err := LookupManifest("v1.2.3", "v1.2.3")
+	if err != nil {
+		t.Fatalf("LookupManifest() failed: %s", err)
+	}
+	if m.DeployTemplate != DeployTemplate || m.TracesCustomResource != TracesCustomResource {
+		t.Fatalf("the current version should give the embedded manifest")
+	}
+
+	if _, err := LookupManifest("v0.0.1", "v1.2.3"); err == nil {
+		t.Fatalf("LookupManifest() should fail for a version not embedded")
+	}
+}
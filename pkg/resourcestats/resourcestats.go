@@ -0,0 +1,175 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package resourcestats summarizes the CPU and memory used by the gadget
+// pods depending on the gadgets running on their node, to recommend
+// resources for the gadget DaemonSet.
+package resourcestats
+
+import (
+	"sort"
+
+	resourcelimitstypes "github.com/kinvolk/inspektor-gadget/pkg/gadgets/resourcelimits/types"
+)
+
+// Annotation is the annotation of the gadget pods holding the NodeStats of
+// their node, in JSON.
+const Annotation = "inspektor-gadget.kinvolk.io/resource-stats"
+
+// Sample is the usage of a gadget pod at a given time, with the gadgets
+// that were running on its node. CPU is expressed in millicores and memory
+// in bytes.
+type Sample struct {
+	CPU     uint64
+	Memory  uint64
+	Gadgets []string
+}
+
+// GadgetCost is the additional usage observed when a gadget is running.
+type GadgetCost struct {
+	Gadget string `json:"gadget"`
+
+	// Samples is the number of samples taken while the gadget was running.
+	Samples int `json:"samples"`
+
+	// Isolated tells if samples without the gadget running were taken too.
+	// Otherwise, the cost of the gadget can't be told apart from the usage
+	// of the gadget pod itself and CPU and Memory are zero.
+	Isolated bool `json:"isolated"`
+
+	// CPU and Memory are the differences between the 95th percentiles of
+	// the usage with and without the gadget running.
+	CPU    uint64 `json:"cpu"`
+	Memory uint64 `json:"memory"`
+}
+
+// NodeStats is the usage of the gadget pod of a node.
+type NodeStats struct {
+	Node    string                          `json:"node"`
+	Samples int                             `json:"samples"`
+	CPU     resourcelimitstypes.Percentiles `json:"cpu"`
+	Memory  resourcelimitstypes.Percentiles `json:"memory"`
+	Gadgets []GadgetCost                    `json:"gadgets,omitempty"`
+}
+
+func cpuAndMemory(samples []*Sample) (cpu, memory []uint64) {
+	for _, s := range samples {
+		cpu = append(cpu, s.CPU)
+		memory = append(memory, s.Memory)
+	}
+	return cpu, memory
+}
+
+func difference(a, b uint64) uint64 {
+	if a < b {
+		return 0
+	}
+	return a - b
+}
+
+// Summarize returns the usage percentiles of the gadget pod of node and the
+// cost of each gadget.
+func Summarize(node string, samples []Sample) NodeStats {
+	all := make([]*Sample, 0, len(samples))
+	running := map[string]map[*Sample]struct{}{}
+
+	for i := range samples {
+		s := &samples[i]
+		all = append(all, s)
+		for _, gadget := range s.Gadgets {
+			if running[gadget] == nil {
+				running[gadget] = map[*Sample]struct{}{}
+			}
+			running[gadget][s] = struct{}{}
+		}
+	}
+
+	cpu, memory := cpuAndMemory(all)
+	stats := NodeStats{
+		Node:    node,
+		Samples: len(samples),
+		CPU:     resourcelimitstypes.NewPercentiles(cpu),
+		Memory:  resourcelimitstypes.NewPercentiles(memory),
+	}
+
+	for gadget, set := range running {
+		var with, without []*Sample
+		for _, s := range all {
+			if _, ok := set[s]; ok {
+				with = append(with, s)
+			} else {
+				without = append(without, s)
+			}
+		}
+
+		cost := GadgetCost{
+			Gadget:   gadget,
+			Samples:  len(with),
+			Isolated: len(without) > 0,
+		}
+		if cost.Isolated {
+			withCPU, withMemory := cpuAndMemory(with)
+			withoutCPU, withoutMemory := cpuAndMemory(without)
+			cost.CPU = difference(resourcelimitstypes.NewPercentiles(withCPU).P95,
+				resourcelimitstypes.NewPercentiles(withoutCPU).P95)
+			cost.Memory = difference(resourcelimitstypes.NewPercentiles(withMemory).P95,
+				resourcelimitstypes.NewPercentiles(withoutMemory).P95)
+		}
+
+		stats.Gadgets = append(stats.Gadgets, cost)
+	}
+
+	sort.Slice(stats.Gadgets, func(i, j int) bool {
+		return stats.Gadgets[i].Gadget < stats.Gadgets[j].Gadget
+	})
+
+	return stats
+}
+
+// Recommend returns the resources recommended for the gadget DaemonSet so
+// that the pods of all the nodes fit them: requests cover 95% of the samples
+// of the busiest node and limits 99% of them, with some headroom for the
+// memory, like the resource-limits gadget does for the workloads.
+func Recommend(nodes []NodeStats) (requests, limits resourcelimitstypes.Resources) {
+	var cpu, memory resourcelimitstypes.Percentiles
+
+	for _, n := range nodes {
+		if n.Samples == 0 {
+			continue
+		}
+		if n.CPU.P95 > cpu.P95 {
+			cpu.P95 = n.CPU.P95
+		}
+		if n.CPU.P99 > cpu.P99 {
+			cpu.P99 = n.CPU.P99
+		}
+		if n.Memory.P95 > memory.P95 {
+			memory.P95 = n.Memory.P95
+		}
+		if n.Memory.P99 > memory.P99 {
+			memory.P99 = n.Memory.P99
+		}
+	}
+
+	requests = resourcelimitstypes.Resources{
+		CPU:    resourcelimitstypes.CPUQuantity(cpu.P95),
+		Memory: resourcelimitstypes.MemoryQuantity(memory.P95),
+	}
+	limits = resourcelimitstypes.Resources{
+		CPU:    resourcelimitstypes.CPUQuantity(cpu.P99),
+		Memory: resourcelimitstypes.MemoryQuantity(uint64(float64(memory.P99) * resourcelimitstypes.MemoryLimitHeadroom)),
+	}
+
+	return requests, limits
+}
@@ -0,0 +1,88 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resourcestats
+
+import (
+	"reflect"
+	"testing"
+
+	resourcelimitstypes "github.com/kinvolk/inspektor-gadget/pkg/gadgets/resourcelimits/types"
+)
+
+const mebibyte = resourcelimitstypes.Mebibyte
+
+func TestSummarize(t *testing.T) {
+	samples := []Sample{
+		{CPU: 10, Memory: 50 * mebibyte},
+		{CPU: 10, Memory: 50 * mebibyte},
+		{CPU: 40, Memory: 80 * mebibyte, Gadgets: []string{"trace-exec"}},
+		{CPU: 50, Memory: 90 * mebibyte, Gadgets: []string{"trace-exec", "trace-open"}},
+	}
+
+	expected := NodeStats{
+		Node:    "node-1",
+		Samples: 4,
+		CPU:     resourcelimitstypes.Percentiles{P50: 10, P95: 50, P99: 50},
+		Memory:  resourcelimitstypes.Percentiles{P50: 50 * mebibyte, P95: 90 * mebibyte, P99: 90 * mebibyte},
+		Gadgets: []GadgetCost{
+			{Gadget: "trace-exec", Samples: 2, Isolated: true, CPU: 40, Memory: 40 * mebibyte},
+			{Gadget: "trace-open", Samples: 1, Isolated: true, CPU: 10, Memory: 10 * mebibyte},
+		},
+	}
+
+	if stats := Summarize("node-1", samples); !reflect.DeepEqual(stats, expected) {
+		t.Fatalf("Expected %+v, got %+v", expected, stats)
+	}
+}
+
+func TestSummarizeNotIsolated(t *testing.T) {
+	samples := []Sample{
+		{CPU: 10, Memory: 50 * mebibyte, Gadgets: []string{"trace-exec"}},
+	}
+
+	stats := Summarize("node-1", samples)
+	expected := []GadgetCost{{Gadget: "trace-exec", Samples: 1}}
+	if !reflect.DeepEqual(stats.Gadgets, expected) {
+		t.Fatalf("Expected %+v, got %+v", expected, stats.Gadgets)
+	}
+}
+
+func TestRecommend(t *testing.T) {
+	nodes := []NodeStats{
+		{
+			Node:    "node-1",
+			Samples: 10,
+			CPU:     resourcelimitstypes.Percentiles{P95: 40, P99: 100},
+			Memory:  resourcelimitstypes.Percentiles{P95: 60 * mebibyte, P99: 100 * mebibyte},
+		},
+		{
+			Node:    "node-2",
+			Samples: 10,
+			CPU:     resourcelimitstypes.Percentiles{P95: 60, P99: 80},
+			Memory:  resourcelimitstypes.Percentiles{P95: 50 * mebibyte, P99: 50 * mebibyte},
+		},
+	}
+
+	requests, limits := Recommend(nodes)
+
+	expectedRequests := resourcelimitstypes.Resources{CPU: "60m", Memory: "60Mi"}
+	expectedLimits := resourcelimitstypes.Resources{CPU: "100m", Memory: "120Mi"}
+	if requests != expectedRequests {
+		t.Fatalf("Expected requests %+v, got %+v", expectedRequests, requests)
+	}
+	if limits != expectedLimits {
+		t.Fatalf("Expected limits %+v, got %+v", expectedLimits, limits)
+	}
+}
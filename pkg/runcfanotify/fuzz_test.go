@@ -0,0 +1,54 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runcfanotify
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// The fuzz targets are run with Go 1.18 or later:
+// go test -fuzz=FuzzParseRuncCmdline ./pkg/runcfanotify/
+
+func FuzzParseRuncCmdline(f *testing.F) {
+	f.Add([]byte("runc\x00create\x00--bundle\x00/bundle\x00--pid-file\x00/bundle/init.pid\x00abc\x00"))
+	f.Add([]byte("runc\x00create\x00-b\x00/bundle\x00--pid-file=/pid"))
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, cmdline []byte) {
+		args, err := ParseRuncCmdline(cmdline)
+		if err != nil || args == nil {
+			return
+		}
+		if !filepath.IsAbs(args.BundleDir) || !filepath.IsAbs(args.PidFile) {
+			t.Fatalf("relative paths returned for %q: %+v", cmdline, args)
+		}
+	})
+}
+
+func FuzzParseContainerConfig(f *testing.F) {
+	f.Add([]byte(`{"ociVersion":"1.0.2","process":{"args":["sh"]},"mounts":[{"destination":"/proc"}]}`))
+	f.Add([]byte(`{}`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		config, err := ParseContainerConfig(data)
+		if err == nil && config == nil {
+			t.Fatalf("no config and no error for %q", data)
+		}
+	})
+}
@@ -0,0 +1,120 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runcfanotify
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	ocispec "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// The command line of runc and the config.json of the containers are
+// controlled by whoever can create containers, so their size is limited
+// before parsing them.
+const (
+	maxCmdlineSize = 64 * 1024
+	maxConfigSize  = 4 * 1024 * 1024
+)
+
+// RuncCreateArgs are the arguments of a "runc create" command needed to
+// monitor the container it creates.
+type RuncCreateArgs struct {
+	BundleDir string
+	PidFile   string
+}
+
+// ParseRuncCmdline parses the command line of a runc process, as read from
+// /proc/<pid>/cmdline: the arguments separated by NUL bytes. It returns nil
+// if it isn't a "runc create" command with a bundle and a PID file, and an
+// error if the command line is too large or the paths aren't absolute.
+func ParseRuncCmdline(cmdline []byte) (*RuncCreateArgs, error) {
+	if len(cmdline) > maxCmdlineSize {
+		return nil, fmt.Errorf("command line larger than %d bytes", maxCmdlineSize)
+	}
+
+	args := strings.Split(strings.TrimSuffix(string(cmdline), "\x00"), "\x00")
+	createFound := false
+	bundleDir := ""
+	pidFile := ""
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "create":
+			createFound = true
+		case (arg == "--bundle" || arg == "-b") && i+1 < len(args):
+			i++
+			bundleDir = args[i]
+		case strings.HasPrefix(arg, "--bundle="):
+			bundleDir = strings.TrimPrefix(arg, "--bundle=")
+		case arg == "--pid-file" && i+1 < len(args):
+			i++
+			pidFile = args[i]
+		case strings.HasPrefix(arg, "--pid-file="):
+			pidFile = strings.TrimPrefix(arg, "--pid-file=")
+		}
+	}
+
+	if !createFound || bundleDir == "" || pidFile == "" {
+		return nil, nil
+	}
+	for _, path := range []string{bundleDir, pidFile} {
+		if !filepath.IsAbs(path) {
+			return nil, fmt.Errorf("%q is not an absolute path", path)
+		}
+	}
+
+	return &RuncCreateArgs{
+		BundleDir: filepath.Clean(bundleDir),
+		PidFile:   filepath.Clean(pidFile),
+	}, nil
+}
+
+// ParseContainerConfig parses the config.json of a container, following
+// the OCI runtime specification.
+func ParseContainerConfig(data []byte) (*ocispec.Spec, error) {
+	if len(data) > maxConfigSize {
+		return nil, fmt.Errorf("config.json larger than %d bytes", maxConfigSize)
+	}
+
+	config := &ocispec.Spec{}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("failed to parse config.json: %w", err)
+	}
+	return config, nil
+}
+
+// readFile reads a file, failing if it's larger than max bytes.
+func readFile(path string, max int) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	b, err := ioutil.ReadAll(io.LimitReader(f, int64(max)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > max {
+		return nil, fmt.Errorf("%s larger than %d bytes", path, max)
+	}
+	return b, nil
+}
@@ -0,0 +1,99 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runcfanotify
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseRuncCmdline(t *testing.T) {
+	table := []struct {
+		cmdline string
+		args    *RuncCreateArgs
+		err     bool
+	}{
+		{
+			cmdline: "runc\x00--root\x00/run/containerd/runc/k8s.io\x00--log\x00/run/log.json\x00" +
+				"create\x00--bundle\x00/run/containerd/io.containerd.runtime.v2.task/k8s.io/abc\x00" +
+				"--pid-file\x00/run/containerd/io.containerd.runtime.v2.task/k8s.io/abc/init.pid\x00abc\x00",
+			args: &RuncCreateArgs{
+				BundleDir: "/run/containerd/io.containerd.runtime.v2.task/k8s.io/abc",
+				PidFile:   "/run/containerd/io.containerd.runtime.v2.task/k8s.io/abc/init.pid",
+			},
+		},
+		{
+			cmdline: "runc\x00create\x00-b\x00/bundle/\x00--pid-file=/bundle/../pid\x00abc",
+			args: &RuncCreateArgs{
+				BundleDir: "/bundle",
+				PidFile:   "/pid",
+			},
+		},
+		{
+			cmdline: "runc\x00start\x00--bundle\x00/bundle\x00--pid-file\x00/pid\x00abc",
+		},
+		{
+			cmdline: "runc\x00create\x00--bundle\x00/bundle\x00--pid-file",
+		},
+		{
+			cmdline: "",
+		},
+		{
+			cmdline: "runc\x00create\x00--bundle\x00bundle\x00--pid-file\x00/pid\x00abc",
+			err:     true,
+		},
+		{
+			cmdline: "runc\x00create\x00" + strings.Repeat("x", maxCmdlineSize),
+			err:     true,
+		},
+	}
+
+	for _, entry := range table {
+		args, err := ParseRuncCmdline([]byte(entry.cmdline))
+		if entry.err {
+			if err == nil {
+				t.Fatalf("Parsing %q: expected an error, got %+v", entry.cmdline, args)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Failed to parse %q: %s", entry.cmdline, err)
+		}
+		if !reflect.DeepEqual(args, entry.args) {
+			t.Fatalf("Parsing %q: expected %+v, got %+v", entry.cmdline, entry.args, args)
+		}
+	}
+}
+
+func TestParseContainerConfig(t *testing.T) {
+	config, err := ParseContainerConfig([]byte(`{"ociVersion":"1.0.2","mounts":[{"destination":"/proc","type":"proc","source":"proc"}]}`))
+	if err != nil {
+		t.Fatalf("Failed to parse config.json: %s", err)
+	}
+	if len(config.Mounts) != 1 || config.Mounts[0].Destination != "/proc" {
+		t.Fatalf("Unexpected mounts: %+v", config.Mounts)
+	}
+
+	for _, data := range []string{
+		``,
+		`{"mounts":{}}`,
+		`{"ociVersion":"` + strings.Repeat("x", maxConfigSize) + `"}`,
+	} {
+		if _, err := ParseContainerConfig([]byte(data)); err == nil {
+			t.Fatalf("Parsing %.40q: expected an error", data)
+		}
+	}
+}
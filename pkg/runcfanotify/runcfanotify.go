@@ -15,7 +15,6 @@
 package runcfanotify
 
 import (
-	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -69,6 +68,18 @@ type RuncNotifier struct {
 	// Value: dummy struct
 	containers map[string]struct{}
 	mu         sync.Mutex
+
+	// pidFileDirNotifies is the set of fanotify instances monitoring the
+	// pid file of a runc instance. They are closed by Close().
+	pidFileDirNotifies map[*fanotify.NotifyFD]struct{}
+
+	// wakeupFds is a pipe whose write end is closed by Close() to wake up
+	// the goroutines polling on pidfds.
+	wakeupFds [2]int
+
+	closed bool
+	done   chan struct{}
+	wg     sync.WaitGroup
 }
 
 // runcPaths is the list of paths where runc could be installed. Depending on
@@ -88,8 +99,11 @@ var runcPaths = []string{
 }
 
 // initFanotify initializes the fanotify API with the flags we need
+//
+// FAN_NONBLOCK makes the file descriptor pollable by the Go runtime, so that
+// closing it unblocks a pending GetEvent.
 func initFanotify() (*fanotify.NotifyFD, error) {
-	fanotifyFlags := uint(unix.FAN_CLOEXEC | unix.FAN_CLASS_CONTENT | unix.FAN_UNLIMITED_QUEUE | unix.FAN_UNLIMITED_MARKS)
+	fanotifyFlags := uint(unix.FAN_CLOEXEC | unix.FAN_CLASS_CONTENT | unix.FAN_UNLIMITED_QUEUE | unix.FAN_UNLIMITED_MARKS | unix.FAN_NONBLOCK)
 	openFlags := os.O_RDONLY | unix.O_LARGEFILE | unix.O_CLOEXEC
 	return fanotify.Initialize(fanotifyFlags, openFlags)
 }
@@ -123,12 +137,20 @@ func Supported() bool {
 // - runc must be installed in one of the paths listed by runcPaths
 func NewRuncNotifier(callback RuncNotifyFunc) (*RuncNotifier, error) {
 	n := &RuncNotifier{
-		callback:   callback,
-		containers: make(map[string]struct{}),
+		callback:           callback,
+		containers:         make(map[string]struct{}),
+		pidFileDirNotifies: make(map[*fanotify.NotifyFD]struct{}),
+		done:               make(chan struct{}),
+	}
+
+	if err := unix.Pipe2(n.wakeupFds[:], unix.O_CLOEXEC); err != nil {
+		return nil, fmt.Errorf("cannot create pipe: %w", err)
 	}
 
 	runcBinaryNotify, err := initFanotify()
 	if err != nil {
+		unix.Close(n.wakeupFds[0])
+		unix.Close(n.wakeupFds[1])
 		return nil, err
 	}
 	n.runcBinaryNotify = runcBinaryNotify
@@ -142,21 +164,51 @@ func NewRuncNotifier(callback RuncNotifyFunc) (*RuncNotifier, error) {
 		}
 	}
 
+	n.wg.Add(1)
 	go n.watchRunc()
 
 	return n, nil
 }
 
+// Close stops watching for new containers and for the termination of the
+// existing ones. It waits until all the goroutines of the notifier have
+// returned, so the callback is not called anymore once Close returns.
+func (n *RuncNotifier) Close() {
+	n.mu.Lock()
+	if n.closed {
+		n.mu.Unlock()
+		return
+	}
+	n.closed = true
+	close(n.done)
+
+	unix.Close(n.wakeupFds[1])
+	n.runcBinaryNotify.File.Close()
+	for pidFileDirNotify := range n.pidFileDirNotifies {
+		pidFileDirNotify.File.Close()
+	}
+	n.mu.Unlock()
+
+	n.wg.Wait()
+
+	unix.Close(n.wakeupFds[0])
+}
+
+// isClosed tells if Close has been called.
+func (n *RuncNotifier) isClosed() bool {
+	select {
+	case <-n.done:
+		return true
+	default:
+		return false
+	}
+}
+
 func commFromPid(pid int) string {
 	comm, _ := ioutil.ReadFile(fmt.Sprintf("/proc/%d/comm", pid))
 	return strings.TrimSuffix(string(comm), "\n")
 }
 
-func cmdlineFromPid(pid int) []string {
-	cmdline, _ := ioutil.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
-	return strings.Split(string(cmdline), "\x00")
-}
-
 // AddWatchContainerTermination watches a container for termination and
 // generates an event on the notifier. This is automatically called for new
 // containers detected by RuncNotifier, but it can also be called for
@@ -165,6 +217,10 @@ func (n *RuncNotifier) AddWatchContainerTermination(containerID string, containe
 	n.mu.Lock()
 	defer n.mu.Unlock()
 
+	if n.closed {
+		return fmt.Errorf("runc notifier closed")
+	}
+
 	if _, ok := n.containers[containerID]; ok {
 		// This container is already being watched for termination
 		return nil
@@ -175,6 +231,7 @@ func (n *RuncNotifier) AddWatchContainerTermination(containerID string, containe
 	if errno == unix.ENOSYS {
 		// pidfd_open not available. As a fallback, check if the
 		// process exists every second
+		n.wg.Add(1)
 		go n.watchContainerTerminationFallback(containerID, containerPID)
 		return nil
 	}
@@ -183,6 +240,7 @@ func (n *RuncNotifier) AddWatchContainerTermination(containerID string, containe
 	}
 
 	// watch for container termination with pidfd_open
+	n.wg.Add(1)
 	go n.watchContainerTermination(containerID, containerPID, int(pidfd))
 	return nil
 }
@@ -190,6 +248,7 @@ func (n *RuncNotifier) AddWatchContainerTermination(containerID string, containe
 // watchContainerTermination waits until the container terminates using
 // pidfd_open (Linux >= 5.3), then sends a notification.
 func (n *RuncNotifier) watchContainerTermination(containerID string, containerPID int, pidfd int) {
+	defer n.wg.Done()
 	defer func() {
 		n.mu.Lock()
 		defer n.mu.Unlock()
@@ -205,9 +264,17 @@ func (n *RuncNotifier) watchContainerTermination(containerID string, containerPI
 				Events:  unix.POLLIN,
 				Revents: 0,
 			},
+			{
+				Fd:      int32(n.wakeupFds[0]),
+				Events:  unix.POLLIN,
+				Revents: 0,
+			},
+		}
+		_, err := unix.Poll(fds, -1)
+		if n.isClosed() {
+			return
 		}
-		count, err := unix.Poll(fds, -1)
-		if err == nil && count == 1 {
+		if err == nil && fds[0].Revents != 0 {
 			n.callback(ContainerEvent{
 				Type:         EventTypeRemoveContainer,
 				ContainerID:  containerID,
@@ -221,6 +288,7 @@ func (n *RuncNotifier) watchContainerTermination(containerID string, containerPI
 // watchContainerTerminationFallback waits until the container terminates
 // *without* using pidfd_open so it works on older kernels, then sends a notification.
 func (n *RuncNotifier) watchContainerTerminationFallback(containerID string, containerPID int) {
+	defer n.wg.Done()
 	defer func() {
 		n.mu.Lock()
 		defer n.mu.Unlock()
@@ -228,7 +296,11 @@ func (n *RuncNotifier) watchContainerTerminationFallback(containerID string, con
 	}()
 
 	for {
-		time.Sleep(time.Second)
+		select {
+		case <-n.done:
+			return
+		case <-time.After(time.Second):
+		}
 		process, err := os.FindProcess(containerPID)
 		if err == nil {
 			// no signal is sent: signal 0 just check for the
@@ -301,6 +373,9 @@ func (n *RuncNotifier) watchPidFileIterate(pidFileDirNotify *fanotify.NotifyFD,
 	if err != nil {
 		return false, err
 	}
+	if containerPID <= 0 {
+		return false, fmt.Errorf("invalid pid %d in pid file", containerPID)
+	}
 
 	// Unfortunately, Linux 5.4 doesn't respect ignore masks
 	// See fix in Linux 5.9:
@@ -311,12 +386,11 @@ func (n *RuncNotifier) watchPidFileIterate(pidFileDirNotify *fanotify.NotifyFD,
 		return false, nil
 	}
 
-	bundleConfigJSON, err := ioutil.ReadFile(filepath.Join(bundleDir, "config.json"))
+	bundleConfigJSON, err := readFile(filepath.Join(bundleDir, "config.json"), maxConfigSize)
 	if err != nil {
 		return false, err
 	}
-	containerConfig := &ocispec.Spec{}
-	err = json.Unmarshal(bundleConfigJSON, containerConfig)
+	containerConfig, err := ParseContainerConfig(bundleConfigJSON)
 	if err != nil {
 		return false, err
 	}
@@ -339,10 +413,7 @@ func (n *RuncNotifier) watchPidFileIterate(pidFileDirNotify *fanotify.NotifyFD,
 }
 
 func (n *RuncNotifier) monitorRuncInstance(bundleDir string, pidFile string) error {
-	fanotifyFlags := uint(unix.FAN_CLOEXEC | unix.FAN_CLASS_CONTENT | unix.FAN_UNLIMITED_QUEUE | unix.FAN_UNLIMITED_MARKS)
-	openFlags := os.O_RDONLY | unix.O_LARGEFILE | unix.O_CLOEXEC
-
-	pidFileDirNotify, err := fanotify.Initialize(fanotifyFlags, openFlags)
+	pidFileDirNotify, err := initFanotify()
 	if err != nil {
 		return err
 	}
@@ -371,13 +442,31 @@ func (n *RuncNotifier) monitorRuncInstance(bundleDir string, pidFile string) err
 		return fmt.Errorf("cannot ignore %s: %w", configJSONPath, err)
 	}
 
+	n.mu.Lock()
+	if n.closed {
+		n.mu.Unlock()
+		pidFileDirNotify.File.Close()
+		return nil
+	}
+	n.pidFileDirNotifies[pidFileDirNotify] = struct{}{}
+	n.wg.Add(1)
+	n.mu.Unlock()
+
 	go func() {
+		defer n.wg.Done()
 		for {
 			stop, err := n.watchPidFileIterate(pidFileDirNotify, bundleDir, pidFile, pidFileDir)
+			if n.isClosed() {
+				return
+			}
 			if err != nil {
 				log.Errorf("error: %v\n", err)
 			}
 			if stop {
+				n.mu.Lock()
+				delete(n.pidFileDirNotifies, pidFileDirNotify)
+				n.mu.Unlock()
+
 				pidFileDirNotify.File.Close()
 				return
 			}
@@ -388,8 +477,12 @@ func (n *RuncNotifier) monitorRuncInstance(bundleDir string, pidFile string) err
 }
 
 func (n *RuncNotifier) watchRunc() {
+	defer n.wg.Done()
 	for {
 		stop, err := n.watchRuncIterate()
+		if n.isClosed() {
+			return
+		}
 		if err != nil {
 			log.Errorf("error: %v\n", err)
 		}
@@ -443,29 +536,17 @@ func (n *RuncNotifier) watchRuncIterate() (bool, error) {
 	}
 
 	// Parse runc command line
-	cmdlineArr := cmdlineFromPid(pid)
-	createFound := false
-	bundleDir := ""
-	pidFile := ""
-	for i := 0; i < len(cmdlineArr); i++ {
-		if cmdlineArr[i] == "create" {
-			createFound = true
-			continue
-		}
-		if cmdlineArr[i] == "--bundle" && i+1 < len(cmdlineArr) {
-			i++
-			bundleDir = cmdlineArr[i]
-			continue
-		}
-		if cmdlineArr[i] == "--pid-file" && i+1 < len(cmdlineArr) {
-			i++
-			pidFile = cmdlineArr[i]
-			continue
-		}
+	cmdline, err := readFile(fmt.Sprintf("/proc/%d/cmdline", pid), maxCmdlineSize)
+	if err != nil {
+		return false, fmt.Errorf("reading the command line of runc (pid %d): %w", pid, err)
+	}
+	args, err := ParseRuncCmdline(cmdline)
+	if err != nil {
+		return false, fmt.Errorf("parsing the command line of runc (pid %d): %w", pid, err)
 	}
 
-	if createFound && bundleDir != "" && pidFile != "" {
-		err := n.monitorRuncInstance(bundleDir, pidFile)
+	if args != nil {
+		err := n.monitorRuncInstance(args.BundleDir, args.PidFile)
 		if err != nil {
 			log.Errorf("error: %v\n", err)
 		}
@@ -0,0 +1,142 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package snapshotcache keeps the last output of the snapshot gadgets for
+// each node, with the content hash computed by the gadget pods, so that the
+// clients polling them, like dashboards, only transfer the outputs that
+// changed.
+package snapshotcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+)
+
+// ETag returns the content hash of the output of a trace.
+func ETag(output string) string {
+	sum := sha256.Sum256([]byte(output))
+	return hex.EncodeToString(sum[:16])
+}
+
+// SetETag sets the content hash of the output of a completed trace in its
+// status. The output is left out when its hash is the one of the
+// gadget.kinvolk.io/if-none-match annotation of the trace.
+func SetETag(trace *gadgetv1alpha1.Trace) {
+	trace.Status.OutputETag = ""
+	trace.Status.OutputNotModified = false
+
+	if trace.Status.State != "Completed" || trace.Status.OperationError != "" || trace.Status.Output == "" {
+		return
+	}
+
+	etag := ETag(trace.Status.Output)
+	trace.Status.OutputETag = etag
+
+	if trace.ObjectMeta.Annotations[gadgetv1alpha1.IfNoneMatchAnnotation] == etag {
+		trace.Status.Output = ""
+		trace.Status.OutputNotModified = true
+	}
+}
+
+type entry struct {
+	etag   string
+	output string
+
+	// used is the value of the clock of the cache when the entry was last
+	// used.
+	used uint64
+}
+
+// Cache keeps the last output of the traces by key, typically the gadget,
+// its filter and parameters, and the node.
+type Cache struct {
+	// max is the maximum number of outputs kept, the least recently used
+	// ones being removed first.
+	max int
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	clock   uint64
+}
+
+// New returns a cache keeping up to max outputs.
+func New(max int) *Cache {
+	return &Cache{
+		max:     max,
+		entries: make(map[string]*entry),
+	}
+}
+
+// Annotations returns the annotations to add to the trace of key, so that
+// the gadget pod leaves the output out if it didn't change since the one
+// cached. It's nil when no output is cached.
+func (c *Cache) Annotations(key string) map[string]string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	return map[string]string{gadgetv1alpha1.IfNoneMatchAnnotation: e.etag}
+}
+
+// Update caches the output of the trace of key, or restores it from the
+// cache if the gadget pod left it out because it didn't change. It returns
+// false if the output was left out but isn't cached anymore: the trace has
+// to be run again without the annotations.
+func (c *Cache) Update(key string, trace *gadgetv1alpha1.Trace) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.clock++
+	now := c.clock
+
+	if trace.Status.OutputNotModified {
+		e, ok := c.entries[key]
+		if !ok || e.etag != trace.Status.OutputETag {
+			return false
+		}
+		e.used = now
+		trace.Status.Output = e.output
+		trace.Status.OutputNotModified = false
+		return true
+	}
+
+	if trace.Status.OutputETag == "" {
+		delete(c.entries, key)
+		return true
+	}
+
+	c.entries[key] = &entry{
+		etag:   trace.Status.OutputETag,
+		output: trace.Status.Output,
+		used:   now,
+	}
+
+	for len(c.entries) > c.max {
+		oldest := ""
+		for k, e := range c.entries {
+			if oldest == "" || e.used < c.entries[oldest].used {
+				oldest = k
+			}
+		}
+		delete(c.entries, oldest)
+	}
+
+	return true
+}
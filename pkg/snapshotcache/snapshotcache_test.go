@@ -0,0 +1,102 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshotcache
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	gadgetv1alpha1 "github.com/kinvolk/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+)
+
+func completedTrace(output string, annotations map[string]string) *gadgetv1alpha1.Trace {
+	return &gadgetv1alpha1.Trace{
+		ObjectMeta: metav1.ObjectMeta{Annotations: annotations},
+		Status: gadgetv1alpha1.TraceStatus{
+			State:  "Completed",
+			Output: output,
+		},
+	}
+}
+
+func TestSetETag(t *testing.T) {
+	trace := completedTrace(`[{"pid":1}]`, nil)
+	SetETag(trace)
+	etag := trace.Status.OutputETag
+	if etag != ETag(`[{"pid":1}]`) || trace.Status.Output == "" || trace.Status.OutputNotModified {
+		t.Fatalf("expected the output with its ETag, got %+v", trace.Status)
+	}
+
+	trace = completedTrace(`[{"pid":1}]`, map[string]string{gadgetv1alpha1.IfNoneMatchAnnotation: etag})
+	SetETag(trace)
+	if trace.Status.OutputETag != etag || trace.Status.Output != "" || !trace.Status.OutputNotModified {
+		t.Fatalf("expected the output to be left out, got %+v", trace.Status)
+	}
+
+	trace = completedTrace(`[{"pid":2}]`, map[string]string{gadgetv1alpha1.IfNoneMatchAnnotation: etag})
+	SetETag(trace)
+	if trace.Status.OutputETag == etag || trace.Status.Output == "" || trace.Status.OutputNotModified {
+		t.Fatalf("expected the modified output, got %+v", trace.Status)
+	}
+
+	trace = completedTrace(`[{"pid":1}]`, nil)
+	trace.Status.State = "Started"
+	SetETag(trace)
+	if trace.Status.OutputETag != "" {
+		t.Fatalf("expected no ETag for a trace not completed, got %q", trace.Status.OutputETag)
+	}
+}
+
+func TestCache(t *testing.T) {
+	c := New(2)
+
+	if annotations := c.Annotations("node-1"); annotations != nil {
+		t.Fatalf("expected no annotations without output cached, got %v", annotations)
+	}
+
+	trace := completedTrace(`[{"pid":1}]`, nil)
+	SetETag(trace)
+	if !c.Update("node-1", trace) {
+		t.Fatalf("caching the output failed")
+	}
+
+	// The gadget pod leaves the same output out.
+	trace = completedTrace(`[{"pid":1}]`, c.Annotations("node-1"))
+	SetETag(trace)
+	if !trace.Status.OutputNotModified {
+		t.Fatalf("expected the output to be left out, got %+v", trace.Status)
+	}
+	if !c.Update("node-1", trace) || trace.Status.Output != `[{"pid":1}]` || trace.Status.OutputNotModified {
+		t.Fatalf("expected the output to be restored, got %+v", trace.Status)
+	}
+
+	// The least recently used output is removed first.
+	for _, node := range []string{"node-2", "node-3"} {
+		trace := completedTrace(`[{"node":"`+node+`"}]`, nil)
+		SetETag(trace)
+		c.Update(node, trace)
+	}
+	if c.Annotations("node-1") != nil || c.Annotations("node-3") == nil {
+		t.Fatalf("expected the output of node-1 to be removed")
+	}
+
+	trace = completedTrace("", nil)
+	trace.Status.OutputETag = ETag(`[{"pid":1}]`)
+	trace.Status.OutputNotModified = true
+	if c.Update("node-1", trace) {
+		t.Fatalf("expected a failure restoring an output not cached anymore")
+	}
+}
@@ -0,0 +1,295 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package symbolizer gives the names of the functions of the addresses of
+// the stacks recorded by the BPF programs: the kernel ones with
+// /proc/kallsyms, and the user space ones with the symbol tables of the
+// files mapped by the processes, read through their root so that the
+// files of the containers are found.
+package symbolizer
+
+import (
+	"bufio"
+	"debug/elf"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const procRoot = "/proc"
+
+type symbol struct {
+	name    string
+	address uint64
+}
+
+// symbols are sorted by address.
+type symbols []symbol
+
+// lookup returns the symbol containing address and the offset of address
+// in it, assuming each symbol runs until the next one.
+func (s symbols) lookup(address uint64) (string, uint64, bool) {
+	i := sort.Search(len(s), func(i int) bool {
+		return s[i].address > address
+	})
+	if i == 0 {
+		return "", 0, false
+	}
+	return s[i-1].name, address - s[i-1].address, true
+}
+
+func (s symbols) sort() {
+	sort.Slice(s, func(i, j int) bool {
+		return s[i].address < s[j].address
+	})
+}
+
+// Kernel resolves the addresses of the kernel.
+type Kernel struct {
+	symbols symbols
+}
+
+// NewKernel reads the symbols of the kernel from /proc/kallsyms.
+func NewKernel() (*Kernel, error) {
+	f, err := os.Open(filepath.Join(procRoot, "kallsyms"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return parseKallsyms(f)
+}
+
+func parseKallsyms(r io.Reader) (*Kernel, error) {
+	k := &Kernel{}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		// e.g. "ffffffff81000000 T _stext" or, for modules,
+		// "ffffffffc0a01000 t nf_nat_setup_info	[nf_nat]"
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		switch fields[1] {
+		case "T", "t", "W", "w":
+		default:
+			continue
+		}
+		address, err := strconv.ParseUint(fields[0], 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address in %q: %w", scanner.Text(), err)
+		}
+		// The addresses are all 0 when they are hidden by
+		// kernel.kptr_restrict.
+		if address == 0 {
+			continue
+		}
+		k.symbols = append(k.symbols, symbol{name: fields[2], address: address})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	k.symbols.sort()
+
+	return k, nil
+}
+
+// Resolve returns the name of the function containing address, and the
+// offset in it, e.g. "__kmalloc+0x1b4", or the address in hexadecimal when
+// it's not known.
+func (k *Kernel) Resolve(address uint64) string {
+	if name, offset, ok := k.symbols.lookup(address); ok {
+		return fmt.Sprintf("%s+0x%x", name, offset)
+	}
+	return fmt.Sprintf("0x%x", address)
+}
+
+// mapping is an executable mapping of a file in the address space of a
+// process.
+type mapping struct {
+	start  uint64
+	end    uint64
+	offset uint64
+	path   string
+}
+
+// elfFile is what is needed of a file to resolve the addresses it's
+// mapped at.
+type elfFile struct {
+	symbols symbols
+	loads   []elf.ProgHeader
+}
+
+// Process resolves the addresses of a process.
+type Process struct {
+	root     string
+	mappings []mapping
+
+	// files caches the files mapped by the process, by path. It's nil
+	// for the ones that can't be read.
+	files map[string]*elfFile
+}
+
+// NewProcess reads the mappings of the process from /proc/<pid>/maps. The
+// files mapped are read from /proc/<pid>/root when an address is resolved,
+// so that the ones of the containers are found.
+func NewProcess(pid uint32) (*Process, error) {
+	dir := filepath.Join(procRoot, strconv.FormatUint(uint64(pid), 10))
+
+	f, err := os.Open(filepath.Join(dir, "maps"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	mappings, err := parseMaps(f)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Process{
+		root:     filepath.Join(dir, "root"),
+		mappings: mappings,
+		files:    make(map[string]*elfFile),
+	}, nil
+}
+
+// parseMaps returns the executable mappings of files from the content of
+// /proc/<pid>/maps.
+func parseMaps(r io.Reader) ([]mapping, error) {
+	var mappings []mapping
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		// e.g. "7f3c1c028000-7f3c1c1bd000 r-xp 00028000 08:01 1054 /usr/lib/x86_64-linux-gnu/libc.so.6"
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 || !strings.HasPrefix(fields[5], "/") {
+			continue
+		}
+		if len(fields[1]) < 3 || fields[1][2] != 'x' {
+			continue
+		}
+
+		addresses := strings.SplitN(fields[0], "-", 2)
+		if len(addresses) != 2 {
+			return nil, fmt.Errorf("invalid address range in %q", scanner.Text())
+		}
+		start, err := strconv.ParseUint(addresses[0], 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address range in %q: %w", scanner.Text(), err)
+		}
+		end, err := strconv.ParseUint(addresses[1], 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address range in %q: %w", scanner.Text(), err)
+		}
+		offset, err := strconv.ParseUint(fields[2], 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid offset in %q: %w", scanner.Text(), err)
+		}
+
+		mappings = append(mappings, mapping{
+			start:  start,
+			end:    end,
+			offset: offset,
+			// The path can contain spaces, or end with " (deleted)"
+			path: strings.Join(fields[5:], " "),
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return mappings, nil
+}
+
+// Resolve returns the name of the function containing address and the
+// file it's in, e.g. "malloc+0x1a [libc.so.6]", or the address in
+// hexadecimal when it's not known.
+func (p *Process) Resolve(address uint64) string {
+	for _, m := range p.mappings {
+		if address < m.start || address >= m.end {
+			continue
+		}
+
+		file := p.file(m.path)
+		if file == nil {
+			break
+		}
+
+		// The address in the file, as in the symbol tables, is found
+		// from the offset in the file with the segment loaded from it.
+		fileOffset := address - m.start + m.offset
+		for _, prog := range file.loads {
+			if fileOffset < prog.Off || fileOffset >= prog.Off+prog.Filesz {
+				continue
+			}
+			vaddr := fileOffset - prog.Off + prog.Vaddr
+			if name, offset, ok := file.symbols.lookup(vaddr); ok {
+				return fmt.Sprintf("%s+0x%x [%s]", name, offset, filepath.Base(m.path))
+			}
+		}
+
+		return fmt.Sprintf("0x%x [%s]", address, filepath.Base(m.path))
+	}
+
+	return fmt.Sprintf("0x%x", address)
+}
+
+func (p *Process) file(path string) *elfFile {
+	if file, ok := p.files[path]; ok {
+		return file
+	}
+
+	// The file isn't read again when it fails, e.g. when it was deleted:
+	// its addresses are given in hexadecimal.
+	file, _ := readELF(filepath.Join(p.root, path))
+	p.files[path] = file
+
+	return file
+}
+
+func readELF(path string) (*elfFile, error) {
+	f, err := elf.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	file := &elfFile{}
+	for _, prog := range f.Progs {
+		if prog.Type == elf.PT_LOAD && prog.Flags&elf.PF_X != 0 {
+			file.loads = append(file.loads, prog.ProgHeader)
+		}
+	}
+
+	// The stripped files, like most of the shared libraries, only have
+	// the dynamic symbols.
+	syms, _ := f.Symbols()
+	dynsyms, _ := f.DynamicSymbols()
+	for _, s := range append(syms, dynsyms...) {
+		if elf.ST_TYPE(s.Info) != elf.STT_FUNC || s.Value == 0 {
+			continue
+		}
+		file.symbols = append(file.symbols, symbol{name: s.Name, address: s.Value})
+	}
+	file.symbols.sort()
+
+	return file, nil
+}
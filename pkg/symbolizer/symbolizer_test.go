@@ -0,0 +1,108 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package symbolizer
+
+import (
+	"debug/elf"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestKernelResolve(t *testing.T) {
+	kallsyms := `0000000000000000 A fixed_percpu_data
+ffffffff81000000 T _stext
+ffffffff81001000 t do_one_initcall
+ffffffff82000000 D init_task
+ffffffffc0a01000 t nf_nat_setup_info	[nf_nat]
+`
+	k, err := parseKallsyms(strings.NewReader(kallsyms))
+	if err != nil {
+		t.Fatalf("parseKallsyms failed: %s", err)
+	}
+
+	tests := map[uint64]string{
+		0xffffffff81000010: "_stext+0x10",
+		0xffffffff81001234: "do_one_initcall+0x234",
+		0xffffffffc0a01000: "nf_nat_setup_info+0x0",
+		0x1000:             "0x1000",
+	}
+	for address, expected := range tests {
+		if name := k.Resolve(address); name != expected {
+			t.Errorf("got %q for 0x%x, expected %q", name, address, expected)
+		}
+	}
+
+	if _, err := parseKallsyms(strings.NewReader("xyz T foo\n")); err == nil {
+		t.Errorf("parseKallsyms accepted an invalid address")
+	}
+}
+
+func TestParseMaps(t *testing.T) {
+	maps := `55d0c4a00000-55d0c4a28000 r--p 00000000 08:01 1054 /usr/bin/app
+55d0c4a28000-55d0c4b00000 r-xp 00028000 08:01 1054 /usr/bin/app
+7f3c1c028000-7f3c1c1bd000 r-xp 00028000 08:01 2048 /usr/lib/my lib.so (deleted)
+7ffd2b5f0000-7ffd2b611000 rw-p 00000000 00:00 0 [stack]
+7ffd2b7c3000-7ffd2b7c5000 r-xp 00000000 00:00 0 [vdso]
+`
+	mappings, err := parseMaps(strings.NewReader(maps))
+	if err != nil {
+		t.Fatalf("parseMaps failed: %s", err)
+	}
+
+	expected := []mapping{
+		{start: 0x55d0c4a28000, end: 0x55d0c4b00000, offset: 0x28000, path: "/usr/bin/app"},
+		{start: 0x7f3c1c028000, end: 0x7f3c1c1bd000, offset: 0x28000, path: "/usr/lib/my lib.so (deleted)"},
+	}
+	if !reflect.DeepEqual(mappings, expected) {
+		t.Fatalf("got mappings %+v, expected %+v", mappings, expected)
+	}
+}
+
+func TestProcessResolve(t *testing.T) {
+	libc := &elfFile{
+		symbols: symbols{
+			{name: "malloc", address: 0x9a0f0},
+			{name: "free", address: 0x9a6d0},
+		},
+		loads: []elf.ProgHeader{
+			{Type: elf.PT_LOAD, Flags: elf.PF_R | elf.PF_X, Off: 0x28000, Vaddr: 0x28000, Filesz: 0x195000},
+		},
+	}
+	p := &Process{
+		mappings: []mapping{
+			{start: 0x7f3c1c028000, end: 0x7f3c1c1bd000, offset: 0x28000, path: "/usr/lib/x86_64-linux-gnu/libc.so.6"},
+			{start: 0x55d0c4a28000, end: 0x55d0c4b00000, offset: 0x28000, path: "/usr/bin/app"},
+		},
+		files: map[string]*elfFile{
+			"/usr/lib/x86_64-linux-gnu/libc.so.6": libc,
+			// The file couldn't be read
+			"/usr/bin/app": nil,
+		},
+	}
+
+	tests := map[uint64]string{
+		0x7f3c1c09a10a: "malloc+0x1a [libc.so.6]",
+		0x7f3c1c09a6d0: "free+0x0 [libc.so.6]",
+		0x7f3c1c028010: "0x7f3c1c028010 [libc.so.6]",
+		0x55d0c4a28100: "0x55d0c4a28100",
+		0x10:           "0x10",
+	}
+	for address, expected := range tests {
+		if name := p.Resolve(address); name != expected {
+			t.Errorf("got %q for 0x%x, expected %q", name, address, expected)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tracecreator implements the mutating admission webhook recording
+// the user creating a Trace in its creator annotations, see
+// gadgets.CreatorAnnotation. The annotations are set from the user
+// authenticated by the API server, replacing the ones sent by the client,
+// and they can't be modified once the Trace is created.
+package tracecreator
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kinvolk/inspektor-gadget/pkg/gadgets"
+)
+
+// maxReviewSize limits the memory used to read a request.
+const maxReviewSize = 4 << 20
+
+// Webhook serves the admission reviews of the Traces.
+type Webhook struct {
+	// proxies are the users creating traces on behalf of other users,
+	// like the gadget-aggregator service account, which authenticates
+	// the users of its API. The creator annotations they set are kept.
+	proxies map[string]struct{}
+}
+
+// NewWebhook returns a webhook trusting the creator annotations set by the
+// given users.
+func NewWebhook(proxies []string) *Webhook {
+	w := &Webhook{proxies: make(map[string]struct{}, len(proxies))}
+	for _, p := range proxies {
+		w.proxies[p] = struct{}{}
+	}
+	return w
+}
+
+type patchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+func (w *Webhook) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(rw, fmt.Sprintf("method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+
+	var review admissionv1.AdmissionReview
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxReviewSize)).Decode(&review); err != nil {
+		http.Error(rw, fmt.Sprintf("decoding admission review: %s", err), http.StatusBadRequest)
+		return
+	}
+	if review.Request == nil {
+		http.Error(rw, "admission review without request", http.StatusBadRequest)
+		return
+	}
+
+	review.Response = w.Review(review.Request)
+	review.Request = nil
+
+	b, err := json.Marshal(&review)
+	if err != nil {
+		http.Error(rw, fmt.Sprintf("encoding admission review: %s", err), http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Write(b)
+}
+
+// Review returns the response to the admission request of a Trace: on
+// creation, the annotations are set to the user making the request and, on
+// update, they are set back to the ones of the Trace before the update.
+func (w *Webhook) Review(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	resp := &admissionv1.AdmissionResponse{
+		UID:     req.UID,
+		Allowed: true,
+	}
+
+	var object metav1.PartialObjectMetadata
+	if err := json.Unmarshal(req.Object.Raw, &object); err != nil {
+		return denied(resp, fmt.Errorf("decoding trace: %w", err))
+	}
+
+	var expected map[string]string
+	switch req.Operation {
+	case admissionv1.Create:
+		if _, ok := w.proxies[req.UserInfo.Username]; ok && object.Annotations[gadgets.CreatorAnnotation] != "" {
+			return resp
+		}
+		expected = map[string]string{
+			gadgets.CreatorAnnotation:       req.UserInfo.Username,
+			gadgets.CreatorGroupsAnnotation: strings.Join(req.UserInfo.Groups, ","),
+		}
+	case admissionv1.Update:
+		var old metav1.PartialObjectMetadata
+		if err := json.Unmarshal(req.OldObject.Raw, &old); err != nil {
+			return denied(resp, fmt.Errorf("decoding previous trace: %w", err))
+		}
+		expected = map[string]string{
+			gadgets.CreatorAnnotation:       old.Annotations[gadgets.CreatorAnnotation],
+			gadgets.CreatorGroupsAnnotation: old.Annotations[gadgets.CreatorGroupsAnnotation],
+		}
+	default:
+		return resp
+	}
+
+	patch := annotationsPatch(object.Annotations, expected)
+	if len(patch) == 0 {
+		return resp
+	}
+
+	b, err := json.Marshal(patch)
+	if err != nil {
+		return denied(resp, fmt.Errorf("encoding patch: %w", err))
+	}
+
+	patchType := admissionv1.PatchTypeJSONPatch
+	resp.Patch = b
+	resp.PatchType = &patchType
+
+	return resp
+}
+
+func denied(resp *admissionv1.AdmissionResponse, err error) *admissionv1.AdmissionResponse {
+	log.Errorf("reviewing trace: %s", err)
+
+	resp.Allowed = false
+	resp.Result = &metav1.Status{
+		Status:  metav1.StatusFailure,
+		Message: err.Error(),
+		Code:    http.StatusBadRequest,
+	}
+	return resp
+}
+
+// annotationsPatch returns the JSON Patch setting the given annotations,
+// removing the ones whose expected value is empty.
+func annotationsPatch(annotations, expected map[string]string) []patchOperation {
+	patch := []patchOperation{}
+
+	if annotations == nil {
+		values := map[string]string{}
+		for k, v := range expected {
+			if v != "" {
+				values[k] = v
+			}
+		}
+		if len(values) != 0 {
+			patch = append(patch, patchOperation{Op: "add", Path: "/metadata/annotations", Value: values})
+		}
+		return patch
+	}
+
+	for _, k := range []string{gadgets.CreatorAnnotation, gadgets.CreatorGroupsAnnotation} {
+		v, ok := annotations[k]
+		path := "/metadata/annotations/" + strings.ReplaceAll(k, "/", "~1")
+		switch {
+		case expected[k] == "" && ok:
+			patch = append(patch, patchOperation{Op: "remove", Path: path})
+		case expected[k] != "" && v != expected[k]:
+			patch = append(patch, patchOperation{Op: "add", Path: path, Value: expected[k]})
+		}
+	}
+
+	return patch
+}
@@ -0,0 +1,189 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracecreator
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const aggregator = "system:serviceaccount:gadget:gadget-aggregator"
+
+func traceObject(annotations string) runtime.RawExtension {
+	if annotations == "" {
+		return runtime.RawExtension{Raw: []byte(`{"metadata":{"name":"trace"}}`)}
+	}
+	return runtime.RawExtension{Raw: []byte(`{"metadata":{"name":"trace","annotations":` + annotations + `}}`)}
+}
+
+func TestReview(t *testing.T) {
+	alice := authenticationv1.UserInfo{Username: "alice", Groups: []string{"devs", "system:authenticated"}}
+
+	table := []struct {
+		description string
+		operation   admissionv1.Operation
+		user        authenticationv1.UserInfo
+		object      string
+		oldObject   string
+		expected    string
+	}{
+		{
+			description: "create without annotations",
+			operation:   admissionv1.Create,
+			user:        alice,
+			expected:    `[{"op":"add","path":"/metadata/annotations","value":{"gadget.kinvolk.io/creator":"alice","gadget.kinvolk.io/creator-groups":"devs,system:authenticated"}}]`,
+		},
+		{
+			description: "create with forged annotations",
+			operation:   admissionv1.Create,
+			user:        alice,
+			object:      `{"gadget.kinvolk.io/creator":"admin","gadget.kinvolk.io/creator-groups":"system:masters"}`,
+			expected: `[{"op":"add","path":"/metadata/annotations/gadget.kinvolk.io~1creator","value":"alice"},` +
+				`{"op":"add","path":"/metadata/annotations/gadget.kinvolk.io~1creator-groups","value":"devs,system:authenticated"}]`,
+		},
+		{
+			description: "create with the right annotations",
+			operation:   admissionv1.Create,
+			user:        alice,
+			object:      `{"gadget.kinvolk.io/creator":"alice","gadget.kinvolk.io/creator-groups":"devs,system:authenticated"}`,
+		},
+		{
+			description: "create without groups",
+			operation:   admissionv1.Create,
+			user:        authenticationv1.UserInfo{Username: "bob"},
+			object:      `{"gadget.kinvolk.io/creator-groups":"system:masters"}`,
+			expected: `[{"op":"add","path":"/metadata/annotations/gadget.kinvolk.io~1creator","value":"bob"},` +
+				`{"op":"remove","path":"/metadata/annotations/gadget.kinvolk.io~1creator-groups"}]`,
+		},
+		{
+			description: "create by a proxy on behalf of a user",
+			operation:   admissionv1.Create,
+			user:        authenticationv1.UserInfo{Username: aggregator},
+			object:      `{"gadget.kinvolk.io/creator":"alice"}`,
+		},
+		{
+			description: "create by a proxy on its own behalf",
+			operation:   admissionv1.Create,
+			user:        authenticationv1.UserInfo{Username: aggregator},
+			expected:    `[{"op":"add","path":"/metadata/annotations","value":{"gadget.kinvolk.io/creator":"` + aggregator + `"}}]`,
+		},
+		{
+			description: "update of the creator",
+			operation:   admissionv1.Update,
+			user:        alice,
+			object:      `{"gadget.kinvolk.io/creator":"admin"}`,
+			oldObject:   `{"gadget.kinvolk.io/creator":"bob"}`,
+			expected:    `[{"op":"add","path":"/metadata/annotations/gadget.kinvolk.io~1creator","value":"bob"}]`,
+		},
+		{
+			description: "update adding a creator",
+			operation:   admissionv1.Update,
+			user:        alice,
+			object:      `{"gadget.kinvolk.io/creator":"admin","gadget.kinvolk.io/operation":"start"}`,
+			expected:    `[{"op":"remove","path":"/metadata/annotations/gadget.kinvolk.io~1creator"}]`,
+		},
+		{
+			description: "update removing the annotations",
+			operation:   admissionv1.Update,
+			user:        alice,
+			oldObject:   `{"gadget.kinvolk.io/creator":"bob"}`,
+			expected:    `[{"op":"add","path":"/metadata/annotations","value":{"gadget.kinvolk.io/creator":"bob"}}]`,
+		},
+		{
+			description: "update of other annotations",
+			operation:   admissionv1.Update,
+			user:        alice,
+			object:      `{"gadget.kinvolk.io/creator":"bob","gadget.kinvolk.io/operation":"stop"}`,
+			oldObject:   `{"gadget.kinvolk.io/creator":"bob","gadget.kinvolk.io/operation":"start"}`,
+		},
+		{
+			description: "delete",
+			operation:   admissionv1.Delete,
+			user:        alice,
+		},
+	}
+
+	w := NewWebhook([]string{aggregator})
+
+	for _, entry := range table {
+		req := &admissionv1.AdmissionRequest{
+			UID:       types.UID("uid"),
+			Operation: entry.operation,
+			UserInfo:  entry.user,
+			Object:    traceObject(entry.object),
+			OldObject: traceObject(entry.oldObject),
+		}
+
+		resp := w.Review(req)
+		if !resp.Allowed || resp.UID != req.UID {
+			t.Fatalf("%s: unexpected response %+v", entry.description, resp)
+		}
+		if string(resp.Patch) != entry.expected {
+			t.Fatalf("%s: got patch %s, expected %s", entry.description, resp.Patch, entry.expected)
+		}
+		if (resp.PatchType != nil) != (entry.expected != "") {
+			t.Fatalf("%s: unexpected patch type %v", entry.description, resp.PatchType)
+		}
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	review := admissionv1.AdmissionReview{
+		Request: &admissionv1.AdmissionRequest{
+			UID:       types.UID("uid"),
+			Operation: admissionv1.Create,
+			UserInfo:  authenticationv1.UserInfo{Username: "alice"},
+			Object:    traceObject(""),
+		},
+	}
+	review.APIVersion = "admission.k8s.io/v1"
+	review.Kind = "AdmissionReview"
+
+	body, err := json.Marshal(&review)
+	if err != nil {
+		t.Fatalf("encoding review: %s", err)
+	}
+
+	rec := httptest.NewRecorder()
+	NewWebhook(nil).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var result admissionv1.AdmissionReview
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatalf("decoding response: %s", err)
+	}
+	if result.APIVersion != review.APIVersion || result.Kind != review.Kind {
+		t.Fatalf("unexpected type %s %s", result.APIVersion, result.Kind)
+	}
+	if result.Response == nil || !result.Response.Allowed || result.Response.UID != "uid" || len(result.Response.Patch) == 0 {
+		t.Fatalf("unexpected response %+v", result.Response)
+	}
+
+	rec = httptest.NewRecorder()
+	NewWebhook(nil).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte("{}"))))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("got status %d for a review without request", rec.Code)
+	}
+}
@@ -15,17 +15,24 @@
 package tracercollection
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/cilium/ebpf"
 	log "github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/proto"
 
 	containercollection "github.com/kinvolk/inspektor-gadget/pkg/container-collection"
 	pb "github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/api"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/pubsub"
 	"github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/stream"
+	eventtypes "github.com/kinvolk/inspektor-gadget/pkg/types"
 )
 
 const (
@@ -33,12 +40,53 @@ const (
 )
 
 type TracerCollection struct {
+	// mu protects the tracers map: it's also accessed by the container
+	// events handler returned by TracerMapsUpdater.
+	mu                  sync.RWMutex
 	tracers             map[string]tracer
 	containerCollection *containercollection.ContainerCollection
 
 	withEbpf  bool
 	pinPath   string
 	mapPrefix string
+
+	// selectorMatches counts how many times a container matched the
+	// container selector of a tracer. Accessed atomically.
+	selectorMatches uint64
+
+	// mapUpdateErrors counts the failed updates of the mount namespace
+	// set maps of the tracers. Accessed atomically.
+	mapUpdateErrors uint64
+
+	// reconciledContainers counts the containers added to the mount
+	// namespace set map of a tracer by the reconciliation pass done when
+	// the tracer is added. Accessed atomically.
+	reconciledContainers uint64
+}
+
+// Stats are the metrics of a TracerCollection. They are useful to
+// understand why a gadget doesn't see any events: a tracer whose selector
+// doesn't match any container, or maps that can't be updated.
+type Stats struct {
+	// Tracers is the number of active tracers.
+	Tracers int
+
+	// SelectorMatches is the number of times a container matched the
+	// container selector of a tracer, since the collection was created.
+	SelectorMatches uint64
+
+	// MapUpdateErrors is the number of failed updates of the mount
+	// namespace set maps, since the collection was created.
+	MapUpdateErrors uint64
+
+	// ReconciledContainers is the number of containers that were missed
+	// when adding a tracer and added by the reconciliation pass, since the
+	// collection was created.
+	ReconciledContainers uint64
+
+	// MatchingContainers is the number of containers currently matching
+	// the container selector of each tracer.
+	MatchingContainers map[string]int
 }
 
 type tracer struct {
@@ -67,6 +115,9 @@ func (tc *TracerCollection) TracerMapsUpdater() pubsub.FuncNotify {
 	}
 
 	return func(event pubsub.PubSubEvent) {
+		tc.mu.RLock()
+		defer tc.mu.RUnlock()
+
 		switch event.Type {
 		case pubsub.EventTypeAddContainer:
 			// Skip the pause container
@@ -76,10 +127,16 @@ func (tc *TracerCollection) TracerMapsUpdater() pubsub.FuncNotify {
 
 			for _, t := range tc.tracers {
 				if containercollection.ContainerSelectorMatches(&t.containerSelector, &event.Container) {
+					atomic.AddUint64(&tc.selectorMatches, 1)
+
 					mntnsC := uint64(event.Container.Mntns)
 					one := uint32(1)
 					if mntnsC != 0 {
-						t.mntnsSetMap.Put(mntnsC, one)
+						if err := t.mntnsSetMap.Put(mntnsC, one); err != nil {
+							atomic.AddUint64(&tc.mapUpdateErrors, 1)
+							log.Errorf("failed to add container %q to tracer %q: %s",
+								event.Container.Id, t.tracerID, err)
+						}
 					} else {
 						log.Errorf("new container with mntns=0")
 					}
@@ -90,17 +147,52 @@ func (tc *TracerCollection) TracerMapsUpdater() pubsub.FuncNotify {
 			for _, t := range tc.tracers {
 				if containercollection.ContainerSelectorMatches(&t.containerSelector, &event.Container) {
 					mntnsC := uint64(event.Container.Mntns)
-					t.mntnsSetMap.Delete(mntnsC)
+					err := t.mntnsSetMap.Delete(mntnsC)
+					if err != nil && !errors.Is(err, ebpf.ErrKeyNotExist) {
+						atomic.AddUint64(&tc.mapUpdateErrors, 1)
+						log.Errorf("failed to remove container %q from tracer %q: %s",
+							event.Container.Id, t.tracerID, err)
+					}
 				}
 			}
 		}
 	}
 }
 
+// AddTracer adds a tracer with the given container selector. If a tracer
+// with the same id already exists and its selector is different, the
+// selector is updated instead: this is how a running trace follows a
+// change of its filter, e.g. when it's rebound to a new pod. os.ErrExist
+// is returned if the tracer exists with the same selector.
 func (tc *TracerCollection) AddTracer(id string, containerSelector pb.ContainerSelector) error {
+	// The check and the insertion are done with the lock held: two calls
+	// with the same id would otherwise both create a tracer, the second
+	// one replacing the map of the first one, which would be leaked.
+	tc.mu.Lock()
 	if _, ok := tc.tracers[id]; ok {
-		return fmt.Errorf("tracer id %q: %w", id, os.ErrExist)
+		tc.mu.Unlock()
+		return tc.updateTracerSelector(id, containerSelector)
 	}
+	t, err := tc.newTracer(id, containerSelector)
+	if err == nil {
+		tc.tracers[id] = t
+	}
+	tc.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	if tc.withEbpf {
+		tc.reconcile(&t)
+	}
+
+	return nil
+}
+
+// newTracer creates a tracer and the mount namespace set map populated with
+// the containers matching its selector.
+func (tc *TracerCollection) newTracer(id string, containerSelector pb.ContainerSelector) (tracer, error) {
 	var mntnsSetMap *ebpf.Map
 	if tc.withEbpf {
 		mntnsSpec := &ebpf.MapSpec{
@@ -114,22 +206,109 @@ func (tc *TracerCollection) AddTracer(id string, containerSelector pb.ContainerS
 		var err error
 		mntnsSetMap, err = ebpf.NewMapWithOptions(mntnsSpec, ebpf.MapOptions{PinPath: tc.pinPath})
 		if err != nil {
-			return fmt.Errorf("error creating mntnsset map: %w", err)
+			return tracer{}, fmt.Errorf("error creating mntnsset map: %w", err)
 		}
 		tc.containerCollection.ContainerRangeWithSelector(&containerSelector, func(c *pb.ContainerDefinition) {
+			atomic.AddUint64(&tc.selectorMatches, 1)
+
 			one := uint32(1)
 			mntnsC := uint64(c.Mntns)
 			if mntnsC != 0 {
-				mntnsSetMap.Put(mntnsC, one)
+				if err := mntnsSetMap.Put(mntnsC, one); err != nil {
+					atomic.AddUint64(&tc.mapUpdateErrors, 1)
+					log.Errorf("failed to add container %q to tracer %q: %s", c.Id, id, err)
+				}
 			}
 		})
 	}
-	tc.tracers[id] = tracer{
+
+	return tracer{
 		tracerID:          id,
 		containerSelector: containerSelector,
 		mntnsSetMap:       mntnsSetMap,
 		gadgetStream:      stream.NewGadgetStream(),
+	}, nil
+}
+
+// reconcile is called once the tracer is registered and receives the
+// container events. It back-fills the mount namespace set map with the
+// containers created between the initial population of the map and the
+// registration of the tracer, which would be missed otherwise. It then
+// publishes a READY event for each container matching the selector, so that
+// the consumers of the stream know which containers are traced.
+func (tc *TracerCollection) reconcile(t *tracer) {
+	tc.containerCollection.ContainerRangeWithSelector(&t.containerSelector, func(c *pb.ContainerDefinition) {
+		mntnsC := uint64(c.Mntns)
+		if mntnsC == 0 {
+			return
+		}
+
+		var value uint32
+		if err := t.mntnsSetMap.Lookup(mntnsC, &value); err != nil {
+			if !errors.Is(err, ebpf.ErrKeyNotExist) {
+				log.Errorf("failed to look up container %q in tracer %q: %s", c.Id, t.tracerID, err)
+				return
+			}
+
+			one := uint32(1)
+			if err := t.mntnsSetMap.Put(mntnsC, one); err != nil {
+				atomic.AddUint64(&tc.mapUpdateErrors, 1)
+				log.Errorf("failed to add container %q to tracer %q: %s", c.Id, t.tracerID, err)
+				return
+			}
+
+			atomic.AddUint64(&tc.reconciledContainers, 1)
+			log.Debugf("container %q added to tracer %q by reconciliation", c.Id, t.tracerID)
+		}
+
+		event := eventtypes.Event{
+			Type:      eventtypes.READY,
+			Message:   "tracer attached to container",
+			Namespace: c.Namespace,
+			Pod:       c.Podname,
+			Container: c.Name,
+		}
+		t.gadgetStream.Publish(eventtypes.EventString(event))
+	})
+}
+
+// updateTracerSelector replaces the container selector of an existing
+// tracer. The containers that don't match the new selector are removed
+// from the mount namespace set map and the ones that now match are added
+// by the reconciliation pass.
+func (tc *TracerCollection) updateTracerSelector(id string, containerSelector pb.ContainerSelector) error {
+	tc.mu.Lock()
+	t, ok := tc.tracers[id]
+	if !ok {
+		tc.mu.Unlock()
+		return fmt.Errorf("unknown tracer %q", id)
+	}
+	if proto.Equal(&t.containerSelector, &containerSelector) {
+		tc.mu.Unlock()
+		return fmt.Errorf("tracer id %q: %w", id, os.ErrExist)
+	}
+	oldSelector := t.containerSelector
+	t.containerSelector = containerSelector
+	tc.tracers[id] = t
+	tc.mu.Unlock()
+
+	if !tc.withEbpf {
+		return nil
 	}
+
+	tc.containerCollection.ContainerRangeWithSelector(&oldSelector, func(c *pb.ContainerDefinition) {
+		if containercollection.ContainerSelectorMatches(&containerSelector, c) {
+			return
+		}
+		err := t.mntnsSetMap.Delete(uint64(c.Mntns))
+		if err != nil && !errors.Is(err, ebpf.ErrKeyNotExist) {
+			atomic.AddUint64(&tc.mapUpdateErrors, 1)
+			log.Errorf("failed to remove container %q from tracer %q: %s", c.Id, id, err)
+		}
+	})
+
+	tc.reconcile(&t)
+
 	return nil
 }
 
@@ -138,10 +317,14 @@ func (tc *TracerCollection) RemoveTracer(id string) error {
 		return fmt.Errorf("cannot remove tracer: id not set")
 	}
 
+	tc.mu.Lock()
 	t, ok := tc.tracers[id]
 	if !ok {
+		tc.mu.Unlock()
 		return fmt.Errorf("cannot remove tracer: unknown tracer %q", id)
 	}
+	delete(tc.tracers, id)
+	tc.mu.Unlock()
 
 	if t.mntnsSetMap != nil {
 		t.mntnsSetMap.Close()
@@ -153,11 +336,13 @@ func (tc *TracerCollection) RemoveTracer(id string) error {
 		os.Remove(filepath.Join(tc.pinPath, tc.mapPrefix+id))
 	}
 
-	delete(tc.tracers, id)
 	return nil
 }
 
 func (tc *TracerCollection) Stream(id string) (*stream.GadgetStream, error) {
+	tc.mu.RLock()
+	defer tc.mu.RUnlock()
+
 	t, ok := tc.tracers[id]
 	if !ok {
 		return nil, fmt.Errorf("unknown tracer %q", id)
@@ -166,10 +351,16 @@ func (tc *TracerCollection) Stream(id string) (*stream.GadgetStream, error) {
 }
 
 func (tc *TracerCollection) TracerCount() int {
+	tc.mu.RLock()
+	defer tc.mu.RUnlock()
+
 	return len(tc.tracers)
 }
 
 func (tc *TracerCollection) TracerDump() (out string) {
+	tc.mu.RLock()
+	defer tc.mu.RUnlock()
+
 	for i, t := range tc.tracers {
 		out += fmt.Sprintf("%v -> %q/%q (%s) Labels: \n",
 			i,
@@ -179,6 +370,10 @@ func (tc *TracerCollection) TracerDump() (out string) {
 		for _, l := range t.containerSelector.Labels {
 			out += fmt.Sprintf("                  %v: %v\n", l.Key, l.Value)
 		}
+		out += "        Annotations:\n"
+		for _, a := range t.containerSelector.Annotations {
+			out += fmt.Sprintf("                  %v: %v\n", a.Key, a.Value)
+		}
 		out += "        Matches:\n"
 		tc.containerCollection.ContainerRangeWithSelector(&t.containerSelector, func(c *pb.ContainerDefinition) {
 			out += fmt.Sprintf("        - %s/%s [Mntns=%v CgroupId=%v]\n", c.Namespace, c.Podname, c.Mntns, c.CgroupId)
@@ -187,10 +382,121 @@ func (tc *TracerCollection) TracerDump() (out string) {
 	return
 }
 
+// Stats returns the current metrics of the collection.
+func (tc *TracerCollection) Stats() Stats {
+	tc.mu.RLock()
+	defer tc.mu.RUnlock()
+
+	stats := Stats{
+		Tracers:              len(tc.tracers),
+		SelectorMatches:      atomic.LoadUint64(&tc.selectorMatches),
+		MapUpdateErrors:      atomic.LoadUint64(&tc.mapUpdateErrors),
+		ReconciledContainers: atomic.LoadUint64(&tc.reconciledContainers),
+		MatchingContainers:   make(map[string]int),
+	}
+
+	for id, t := range tc.tracers {
+		count := 0
+		tc.containerCollection.ContainerRangeWithSelector(&t.containerSelector, func(*pb.ContainerDefinition) {
+			count++
+		})
+		stats.MatchingContainers[id] = count
+	}
+
+	return stats
+}
+
+// StatsDump returns the metrics of the collection in a human readable
+// format.
+func (tc *TracerCollection) StatsDump() (out string) {
+	stats := tc.Stats()
+
+	out += fmt.Sprintf("Active tracers: %d\n", stats.Tracers)
+	out += fmt.Sprintf("Container selector matches: %d\n", stats.SelectorMatches)
+	out += fmt.Sprintf("Map update errors: %d\n", stats.MapUpdateErrors)
+	out += fmt.Sprintf("Reconciled containers: %d\n", stats.ReconciledContainers)
+
+	ids := make([]string, 0, len(stats.MatchingContainers))
+	for id := range stats.MatchingContainers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		out += fmt.Sprintf("    %s: %d matching containers\n", id, stats.MatchingContainers[id])
+	}
+	return
+}
+
 func (tc *TracerCollection) TracerExists(id string) bool {
+	tc.mu.RLock()
+	defer tc.mu.RUnlock()
+
 	_, ok := tc.tracers[id]
 	return ok
 }
 
+// StalePins returns the paths of the mount namespace set maps pinned in the
+// pin path that don't belong to any tracer of the collection.
+func (tc *TracerCollection) StalePins() ([]string, error) {
+	if !tc.withEbpf {
+		return nil, nil
+	}
+
+	entries, err := os.ReadDir(tc.pinPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read pin path %q: %w", tc.pinPath, err)
+	}
+
+	stale := []string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, tc.mapPrefix) {
+			continue
+		}
+		if tc.TracerExists(strings.TrimPrefix(name, tc.mapPrefix)) {
+			continue
+		}
+		stale = append(stale, filepath.Join(tc.pinPath, name))
+	}
+
+	return stale, nil
+}
+
+// CleanStalePins removes the mount namespace set maps pinned in the pin path
+// that don't belong to any tracer of the collection, e.g. because a previous
+// instance was killed before removing them. It returns the paths of the
+// removed pins.
+func (tc *TracerCollection) CleanStalePins() ([]string, error) {
+	stale, err := tc.StalePins()
+	if err != nil {
+		return nil, err
+	}
+
+	removed := []string{}
+	for _, path := range stale {
+		if err := os.Remove(path); err != nil {
+			return removed, fmt.Errorf("failed to remove stale pin %q: %w", path, err)
+		}
+		removed = append(removed, path)
+	}
+
+	return removed, nil
+}
+
+// Close removes all the tracers and their mount namespace set maps.
 func (tc *TracerCollection) Close() {
+	tc.mu.RLock()
+	ids := make([]string, 0, len(tc.tracers))
+	for id := range tc.tracers {
+		ids = append(ids, id)
+	}
+	tc.mu.RUnlock()
+
+	for _, id := range ids {
+		tc.RemoveTracer(id)
+	}
 }
@@ -0,0 +1,102 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracercollection
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	pb "github.com/kinvolk/inspektor-gadget/pkg/gadgettracermanager/api"
+)
+
+func TestCleanStalePins(t *testing.T) {
+	pinPath, err := ioutil.TempDir("", "tracer-collection-test")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(pinPath)
+
+	for _, name := range []string{"containers", "mntnsset_live", "mntnsset_stale"} {
+		if err := ioutil.WriteFile(filepath.Join(pinPath, name), nil, 0600); err != nil {
+			t.Fatalf("Failed to create %q: %s", name, err)
+		}
+	}
+
+	tc, err := NewTracerCollection(pinPath, "mntnsset_", true, nil)
+	if err != nil {
+		t.Fatalf("Failed to create tracer collection: %s", err)
+	}
+	tc.tracers["live"] = tracer{tracerID: "live"}
+
+	expected := []string{filepath.Join(pinPath, "mntnsset_stale")}
+
+	stale, err := tc.StalePins()
+	if err != nil {
+		t.Fatalf("Failed to list stale pins: %s", err)
+	}
+	if !reflect.DeepEqual(stale, expected) {
+		t.Fatalf("Stale pins %v, expected %v", stale, expected)
+	}
+
+	removed, err := tc.CleanStalePins()
+	if err != nil {
+		t.Fatalf("Failed to clean stale pins: %s", err)
+	}
+
+	if !reflect.DeepEqual(removed, expected) {
+		t.Fatalf("Removed %v, expected %v", removed, expected)
+	}
+
+	for _, name := range []string{"containers", "mntnsset_live"} {
+		if _, err := os.Stat(filepath.Join(pinPath, name)); err != nil {
+			t.Fatalf("%q should not be removed: %s", name, err)
+		}
+	}
+}
+
+func TestAddTracerUpdatesSelector(t *testing.T) {
+	tc, err := NewTracerCollection("", "mntnsset_", false, nil)
+	if err != nil {
+		t.Fatalf("Failed to create tracer collection: %s", err)
+	}
+
+	err = tc.AddTracer("trace", pb.ContainerSelector{Namespace: "default", Podname: "web-1", PodUid: "uid-1"})
+	if err != nil {
+		t.Fatalf("Failed to add tracer: %s", err)
+	}
+
+	err = tc.AddTracer("trace", pb.ContainerSelector{Namespace: "default", Podname: "web-1", PodUid: "uid-1"})
+	if !errors.Is(err, os.ErrExist) {
+		t.Fatalf("Adding the same tracer twice returned %v, expected %v", err, os.ErrExist)
+	}
+
+	err = tc.AddTracer("trace", pb.ContainerSelector{Namespace: "default", Podname: "web-2", PodUid: "uid-2"})
+	if err != nil {
+		t.Fatalf("Failed to update tracer selector: %s", err)
+	}
+
+	podname := tc.tracers["trace"].containerSelector.Podname
+	podUID := tc.tracers["trace"].containerSelector.PodUid
+	if podname != "web-2" || podUID != "uid-2" {
+		t.Fatalf("Selector not updated: got %s/%s", podname, podUID)
+	}
+	if tc.TracerCount() != 1 {
+		t.Fatalf("Expected 1 tracer, got %d", tc.TracerCount())
+	}
+}
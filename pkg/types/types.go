@@ -19,6 +19,21 @@ import (
 	"fmt"
 )
 
+// EventSchemaVersion is the version of the format of the events sent by the
+// gadgets. It must be increased when a field of an event is renamed,
+// removed or changes its meaning, so that kubectl-gadget can tell when its
+// renderers don't match the events of the gadget pods. Adding a field
+// doesn't require a new version.
+const EventSchemaVersion = 1
+
+// DefaultNodeLabels are the labels of the node added to the events by
+// default, see Event.NodeLabels: they tell when the traffic crosses zones,
+// or when an issue only happens on some types of instances.
+var DefaultNodeLabels = []string{
+	"topology.kubernetes.io/zone",
+	"node.kubernetes.io/instance-type",
+}
+
 type EventType string
 
 const (
@@ -40,13 +55,29 @@ const (
 
 	// Indicates the tracer in the node is now is able to produce events
 	READY EventType = "ready"
+
+	// Indicates the tracer in the node was created again after a restart
+	// of the gadget pod: the events in between were lost
+	RESUMED EventType = "resumed"
+)
+
+// Sandboxes the containers can run in. The processes of these containers
+// don't run on the host kernel, so the gadgets using eBPF can't see what
+// happens inside them.
+const (
+	SandboxGVisor = "gvisor"
+	SandboxKata   = "kata"
+
+	// SandboxNone selects the containers which don't run in a sandbox in
+	// the filters.
+	SandboxNone = "none"
 )
 
 type Event struct {
 	// Type indicates the kind of this event
 	Type EventType `json:"type"`
 
-	// Message when Type is ERR, WARN, DEBUG or INFO
+	// Message when Type is ERR, WARN, DEBUG, INFO or RESUMED
 	Message string `json:"message,omitempty"`
 
 	// Node where the event comes from
@@ -62,6 +93,29 @@ type Event struct {
 	// Container where the event comes from, or empty for host-level or
 	// pod-level event
 	Container string `json:"container,omitempty"`
+
+	// Sandbox the container runs in, like "gvisor" or "kata", or empty
+	// when it runs on the host kernel. The events of a sandboxed container
+	// come from the sandbox runtime, not from the processes inside it.
+	Sandbox string `json:"sandbox,omitempty"`
+
+	// NodeLabels are the labels of the node allowed by the -node-labels
+	// option of the gadget pods, DefaultNodeLabels unless set.
+	// Gadgets don't need to set them: they are added by WithMetadata when
+	// the event is published.
+	NodeLabels map[string]string `json:"nodeLabels,omitempty"`
+
+	// SchemaVersion is the EventSchemaVersion of the gadget pod that sent
+	// the event. Gadgets don't need to set it: it's added by
+	// WithSchemaVersion when the event is published.
+	SchemaVersion int `json:"schemaVersion,omitempty"`
+}
+
+// GetBaseEvent returns the common part of the events. The types of the
+// events of the gadgets embed Event, so it makes them implement
+// gadgets.Event.
+func (e *Event) GetBaseEvent() *Event {
+	return e
 }
 
 func Err(msg, node string) Event {
@@ -103,3 +157,48 @@ func EventString(i interface{}) string {
 	}
 	return string(b)
 }
+
+// WithSchemaVersion adds EventSchemaVersion to an event encoded as a JSON
+// object. The event is decoded and encoded again, without knowing its
+// gadget-specific type: the fields are kept as they are. Other lines and
+// events already having a schema version are returned unchanged.
+func WithSchemaVersion(line string) string {
+	return WithMetadata(line, nil)
+}
+
+// WithMetadata adds EventSchemaVersion and the labels of the node, if any,
+// to an event encoded as a JSON object, like WithSchemaVersion. The fields
+// already set by the gadget are kept.
+func WithMetadata(line string, nodeLabels map[string]string) string {
+	var event map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(line), &event); err != nil || event == nil {
+		return line
+	}
+
+	changed := false
+	if _, ok := event["schemaVersion"]; !ok {
+		version, err := json.Marshal(EventSchemaVersion)
+		if err != nil {
+			return line
+		}
+		event["schemaVersion"] = version
+		changed = true
+	}
+	if _, ok := event["nodeLabels"]; !ok && len(nodeLabels) > 0 {
+		labels, err := json.Marshal(nodeLabels)
+		if err != nil {
+			return line
+		}
+		event["nodeLabels"] = labels
+		changed = true
+	}
+	if !changed {
+		return line
+	}
+
+	b, err := json.Marshal(event)
+	if err != nil {
+		return line
+	}
+	return string(b)
+}
@@ -0,0 +1,122 @@
+// Copyright 2022 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestWithSchemaVersion(t *testing.T) {
+	version := fmt.Sprintf(`"schemaVersion":%d`, EventSchemaVersion)
+
+	table := []struct {
+		description string
+		line        string
+		expected    string
+	}{
+		{
+			description: "empty object",
+			line:        `{}`,
+			expected:    `{` + version + `}`,
+		},
+		{
+			description: "event",
+			line:        `{"type":"normal","node":"node1"}`,
+			expected:    `{"node":"node1",` + version + `,"type":"normal"}`,
+		},
+		{
+			description: "schemaVersion in a string",
+			line:        `{"message":"\"schemaVersion\":1","type":"err"}`,
+			expected:    `{"message":"\"schemaVersion\":1",` + version + `,"type":"err"}`,
+		},
+		{
+			description: "nested object",
+			line:        `{"type":"normal","args":{"schemaVersion":42}}`,
+			expected:    `{"args":{"schemaVersion":42},` + version + `,"type":"normal"}`,
+		},
+		{
+			description: "version already set",
+			line:        `{"schemaVersion":42,"type":"normal"}`,
+			expected:    `{"schemaVersion":42,"type":"normal"}`,
+		},
+		{
+			description: "not an object",
+			line:        `not json`,
+			expected:    `not json`,
+		},
+		{
+			description: "array",
+			line:        `[{"type":"normal"}]`,
+			expected:    `[{"type":"normal"}]`,
+		},
+	}
+
+	for _, entry := range table {
+		if output := WithSchemaVersion(entry.line); output != entry.expected {
+			t.Fatalf("%s: got %q, expected %q", entry.description, output, entry.expected)
+		}
+	}
+}
+
+func TestWithMetadata(t *testing.T) {
+	version := fmt.Sprintf(`"schemaVersion":%d`, EventSchemaVersion)
+	labels := map[string]string{
+		"topology.kubernetes.io/zone": "eu-west-1a",
+	}
+
+	table := []struct {
+		description string
+		line        string
+		labels      map[string]string
+		expected    string
+	}{
+		{
+			description: "event",
+			line:        `{"type":"normal","node":"node1"}`,
+			labels:      labels,
+			expected:    `{"node":"node1","nodeLabels":{"topology.kubernetes.io/zone":"eu-west-1a"},` + version + `,"type":"normal"}`,
+		},
+		{
+			description: "no labels",
+			line:        `{"type":"normal","node":"node1"}`,
+			expected:    `{"node":"node1",` + version + `,"type":"normal"}`,
+		},
+		{
+			description: "labels already set",
+			line:        `{"nodeLabels":{"zone":"a"},"schemaVersion":42,"type":"normal"}`,
+			labels:      labels,
+			expected:    `{"nodeLabels":{"zone":"a"},"schemaVersion":42,"type":"normal"}`,
+		},
+		{
+			description: "schema version already set",
+			line:        `{"schemaVersion":42,"type":"normal"}`,
+			labels:      labels,
+			expected:    `{"nodeLabels":{"topology.kubernetes.io/zone":"eu-west-1a"},"schemaVersion":42,"type":"normal"}`,
+		},
+		{
+			description: "not an object",
+			line:        `not json`,
+			labels:      labels,
+			expected:    `not json`,
+		},
+	}
+
+	for _, entry := range table {
+		if output := WithMetadata(entry.line, entry.labels); output != entry.expected {
+			t.Fatalf("%s: got %q, expected %q", entry.description, output, entry.expected)
+		}
+	}
+}
@@ -21,23 +21,49 @@ fi
 # We do not need to add network eBPF programs like dns or snisnoop here because
 # they do not rely on BTF (i.e., they do not include vmlinux.h).
 ${BTFHUB}/tools/btfgen.sh -a ${ARCH}                                                                \
+    -o ${INSPEKTOR_GADGET}/pkg/gadgets/audit-netns/tracer/auditnetns_bpfel.o                        \
     -o ${INSPEKTOR_GADGET}/pkg/gadgets/audit-seccomp/tracer/auditseccomp_bpfel.o                    \
     -o ${INSPEKTOR_GADGET}/pkg/gadgets/audit-seccomp/tracer/auditseccompwithfilters_bpfel.o         \
+    -o ${INSPEKTOR_GADGET}/pkg/gadgets/bashreadline/tracer/bashreadline_bpfel.o                     \
     -o ${INSPEKTOR_GADGET}/pkg/gadgets/bindsnoop/tracer/core/bindsnoop_bpfel.o                      \
+    -o ${INSPEKTOR_GADGET}/pkg/gadgets/biolatency/tracer/biolatency_bpfel.o                         \
+    -o ${INSPEKTOR_GADGET}/pkg/gadgets/biosnoop/tracer/core/biosnoop_bpfel.o                        \
     -o ${INSPEKTOR_GADGET}/pkg/gadgets/biotop/tracer/biotop_bpfel.o                                 \
+    -o ${INSPEKTOR_GADGET}/pkg/gadgets/cachestat/tracer/cachestat_bpfel.o                           \
+    -o ${INSPEKTOR_GADGET}/pkg/gadgets/dcstat/tracer/dcstat_bpfel.o                                 \
     -o ${INSPEKTOR_GADGET}/pkg/gadgets/execsnoop/tracer/core/execsnoop_bpfel.o                      \
     -o ${INSPEKTOR_GADGET}/pkg/gadgets/filetop/tracer/filetop_bpfel.o                               \
     -o ${INSPEKTOR_GADGET}/pkg/gadgets/fsslower/tracer/core/fsslower_bpfel.o                        \
+    -o ${INSPEKTOR_GADGET}/pkg/gadgets/fstop/tracer/fstop_bpfel.o                                   \
+    -o ${INSPEKTOR_GADGET}/pkg/gadgets/gethostlatency/tracer/gethostlatency_bpfel.o                 \
+    -o ${INSPEKTOR_GADGET}/pkg/gadgets/irqs/tracer/hardirqs_bpfel.o                                 \
+    -o ${INSPEKTOR_GADGET}/pkg/gadgets/irqs/tracer/softirqs_bpfel.o                                 \
+    -o ${INSPEKTOR_GADGET}/pkg/gadgets/memleak/tracer/memleak_bpfel.o                               \
     -o ${INSPEKTOR_GADGET}/pkg/gadgets/mountsnoop/tracer/core/mountsnoop_bpfel.o                    \
+    -o ${INSPEKTOR_GADGET}/pkg/gadgets/netdrops/tracer/netdrops_bpfel.o                             \
     -o ${INSPEKTOR_GADGET}/pkg/gadgets/oomkill/tracer/oomkill_bpfel.o                               \
     -o ${INSPEKTOR_GADGET}/pkg/gadgets/opensnoop/tracer/core/opensnoop_bpfel.o                      \
     -o ${INSPEKTOR_GADGET}/pkg/gadgets/process-collector/tracer/processcollector_bpfel.o            \
     -o ${INSPEKTOR_GADGET}/pkg/gadgets/process-collector/tracer/processcollectorwithfilters_bpfel.o \
+    -o ${INSPEKTOR_GADGET}/pkg/gadgets/runqlat/tracer/runqlat_bpfel.o                               \
+    -o ${INSPEKTOR_GADGET}/pkg/gadgets/runqslower/tracer/core/runqslower_bpfel.o                    \
     -o ${INSPEKTOR_GADGET}/pkg/gadgets/seccomp/tracer/seccomp_bpfel.o                               \
     -o ${INSPEKTOR_GADGET}/pkg/gadgets/sigsnoop/tracer/core/sigsnoop_bpfel.o                        \
     -o ${INSPEKTOR_GADGET}/pkg/gadgets/snisnoop/tracer/snisnoop_bpfel.o                             \
+    -o ${INSPEKTOR_GADGET}/pkg/gadgets/solisten/tracer/solisten_bpfel.o                                 \
+    -o ${INSPEKTOR_GADGET}/pkg/gadgets/statsnoop/tracer/statsnoop_bpfel.o                           \
+    -o ${INSPEKTOR_GADGET}/pkg/gadgets/stealtop/tracer/stealtop_bpfel.o                             \
+    -o ${INSPEKTOR_GADGET}/pkg/gadgets/suspicious-exec/tracer/suspiciousexec_bpfel.o                \
+    -o ${INSPEKTOR_GADGET}/pkg/gadgets/syncsnoop/tracer/syncsnoop_bpfel.o                           \
     -o ${INSPEKTOR_GADGET}/pkg/gadgets/tcpconnect/tracer/core/tcpconnect_bpfel.o                    \
+    -o ${INSPEKTOR_GADGET}/pkg/gadgets/tcpdrop/tracer/tcpdrop_bpfel.o                               \
+    -o ${INSPEKTOR_GADGET}/pkg/gadgets/tcplife/tracer/core/tcplife_bpfel.o                          \
+    -o ${INSPEKTOR_GADGET}/pkg/gadgets/tcpretrans/tracer/tcpretrans_bpfel.o                         \
+    -o ${INSPEKTOR_GADGET}/pkg/gadgets/tcpsynbl/tracer/tcpsynbl_bpfel.o                                 \
     -o ${INSPEKTOR_GADGET}/pkg/gadgets/tcptop/tracer/tcptop_bpfel.o                                 \
+    -o ${INSPEKTOR_GADGET}/pkg/gadgets/udpsnoop/tracer/core/udpsnoop_bpfel.o                        \
+    -o ${INSPEKTOR_GADGET}/pkg/gadgets/udptop/tracer/udptop_bpfel.o                                 \
+    -o ${INSPEKTOR_GADGET}/pkg/gadgets/vfsstat/tracer/vfsstat_bpfel.o                               \
     #
 
 mkdir -p ${OUTPUT}